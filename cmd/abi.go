@@ -0,0 +1,270 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/hyperledger/firefly-signer/pkg/abi"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/spf13/cobra"
+)
+
+var formattingModes = map[string]abi.FormattingMode{
+	"objects":         abi.FormatAsObjects,
+	"arrays":          abi.FormatAsFlatArrays,
+	"self-describing": abi.FormatAsSelfDescribingArrays,
+}
+
+var intSerializers = map[string]abi.IntSerializer{
+	"string": abi.Base10StringIntSerializer,
+	"hex":    abi.HexIntSerializer0xPrefix,
+	"number": abi.JSONNumberIntSerializer,
+	"auto":   abi.NumberIfFitsOrBase10StringIntSerializer,
+}
+
+var floatSerializers = map[string]abi.FloatSerializer{
+	"string": abi.Base10StringFloatSerializer,
+	"auto":   abi.NumberIfFitsOrBase10StringFloatSerializer,
+}
+
+var byteSerializers = map[string]abi.ByteSerializer{
+	"hex":    abi.HexByteSerializer,
+	"hex0x":  abi.HexByteSerializer0xPrefix,
+	"base64": abi.Base64ByteSerializer,
+}
+
+var addressSerializers = map[string]abi.AddressSerializer{
+	"hex":      abi.HexAddrSerializerPlain,
+	"hex0x":    abi.HexAddrSerializer0xPrefix,
+	"checksum": abi.ChecksumAddrSerializer,
+}
+
+type abiEncodeFlags struct {
+	outputs    bool
+	noSelector bool
+	packed     bool
+}
+
+type abiDecodeFlags struct {
+	outputs    bool
+	noSelector bool
+	topics     []string
+	format     string
+	ints       string
+	floats     string
+	bytes      string
+	addresses  string
+	pretty     bool
+}
+
+func abiCommand() *cobra.Command {
+	abiCmd := &cobra.Command{
+		Use:   "abi",
+		Short: "Encode and decode ABI data",
+		Long:  "",
+	}
+	abiCmd.AddCommand(abiEncodeCommand())
+	abiCmd.AddCommand(abiDecodeCommand())
+	return abiCmd
+}
+
+func abiEncodeCommand() *cobra.Command {
+	var flags abiEncodeFlags
+	encodeCmd := &cobra.Command{
+		Use:   "encode <abi file> <name> [params JSON]",
+		Short: "Encodes JSON parameters for a function, constructor or error in an ABI, and prints the data as hex",
+		Long: `Encodes JSON parameters (an object, or an array) for a function, constructor or error in an ABI,
+and prints the data as hex. The parameters are read from stdin if not supplied in the argument.
+The ABI file is either a JSON ABI array, or a compiled contract JSON with an "abi" field.
+The name can be the full signature, such as "transfer(address,uint256)", to select an overloaded
+function, and "constructor" selects the constructor. With --packed the parameters are tightly
+packed as Solidity's abi.encodePacked does, for hashing, with no selector.`,
+		Args: cobra.RangeArgs(2, 3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			e, err := readABIEntry(ctx, args[0], args[1])
+			if err != nil {
+				return err
+			}
+			params, err := readCommandArg(ctx, cmd, args, 2)
+			if err != nil {
+				return err
+			}
+			data, err := encodeABIData(ctx, e, []byte(params), &flags)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), ethtypes.HexBytes0xPrefix(data).String())
+			return nil
+		},
+	}
+	encodeCmd.Flags().BoolVar(&flags.outputs, "outputs", false, "Encode the outputs (return values) rather than the inputs")
+	encodeCmd.Flags().BoolVar(&flags.noSelector, "no-selector", false, "Do not prefix the encoded inputs with the function selector")
+	encodeCmd.Flags().BoolVar(&flags.packed, "packed", false, "Tightly pack the parameters as abi.encodePacked, with no function selector")
+	return encodeCmd
+}
+
+func encodeABIData(ctx context.Context, e *abi.Entry, params []byte, flags *abiEncodeFlags) ([]byte, error) {
+	switch {
+	case e.Type == abi.Event:
+		return nil, i18n.NewError(ctx, signermsgs.MsgABIEventEncodeUnsupported, e.Name)
+	case flags.packed && flags.outputs:
+		return e.Outputs.EncodePackedABIDataJSONCtx(ctx, params)
+	case flags.packed:
+		return e.EncodePackedJSONCtx(ctx, params)
+	case flags.outputs:
+		return e.Outputs.EncodeABIDataJSONCtx(ctx, params)
+	case flags.noSelector || e.Type == abi.Constructor:
+		return e.Inputs.EncodeABIDataJSONCtx(ctx, params)
+	default:
+		return e.EncodeCallDataJSONCtx(ctx, params)
+	}
+}
+
+func abiDecodeCommand() *cobra.Command {
+	var flags abiDecodeFlags
+	decodeCmd := &cobra.Command{
+		Use:   "decode <abi file> <name> [hex data]",
+		Short: "Decodes hex data for a function, constructor, error or event in an ABI, and prints the values as JSON",
+		Long: `Decodes hex data for a function, constructor, error or event in an ABI, and prints the values as JSON.
+The data is read from stdin if not supplied in the argument. For a function or error the data is
+expected to start with the selector, and for an event the topics are supplied with --topic.
+The ABI file is either a JSON ABI array, or a compiled contract JSON with an "abi" field.
+The name can be the full signature, such as "transfer(address,uint256)", to select an overloaded
+function, and "constructor" selects the constructor.`,
+		Args: cobra.RangeArgs(2, 3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			serializer, err := newDecodeSerializer(ctx, &flags)
+			if err != nil {
+				return err
+			}
+			e, err := readABIEntry(ctx, args[0], args[1])
+			if err != nil {
+				return err
+			}
+			input, err := readCommandArg(ctx, cmd, args, 2)
+			if err != nil {
+				return err
+			}
+			data, err := ethtypes.NewHexBytes0xPrefix(input)
+			if err != nil {
+				return i18n.NewError(ctx, signermsgs.MsgInvalidHexInput, err)
+			}
+			cv, err := decodeABIData(ctx, e, data, &flags)
+			if err != nil {
+				return err
+			}
+			b, err := serializer.SerializeJSONCtx(ctx, cv)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), string(b))
+			return nil
+		},
+	}
+	decodeCmd.Flags().BoolVar(&flags.outputs, "outputs", false, "Decode the outputs (return values) rather than the inputs")
+	decodeCmd.Flags().BoolVar(&flags.noSelector, "no-selector", false, "The data is not prefixed with the function selector")
+	decodeCmd.Flags().StringArrayVar(&flags.topics, "topic", nil, "Topic of the event log, in order starting with the signature hash (can be repeated)")
+	decodeCmd.Flags().StringVar(&flags.format, "format", "objects", "Format for parameters and tuples: objects, arrays or self-describing")
+	decodeCmd.Flags().StringVar(&flags.ints, "int", "string", "Format for integers: string, hex, number or auto (number if it fits in a JSON number)")
+	decodeCmd.Flags().StringVar(&flags.floats, "float", "string", "Format for fixed point numbers: string or auto (number if it fits in a JSON number)")
+	decodeCmd.Flags().StringVar(&flags.bytes, "bytes", "hex0x", "Format for bytes: hex, hex0x or base64")
+	decodeCmd.Flags().StringVar(&flags.addresses, "address", "hex0x", "Format for addresses: hex, hex0x or checksum")
+	decodeCmd.Flags().BoolVar(&flags.pretty, "pretty", false, "Indent the JSON output")
+	return decodeCmd
+}
+
+func decodeABIData(ctx context.Context, e *abi.Entry, data []byte, flags *abiDecodeFlags) (*abi.ComponentValue, error) {
+	switch {
+	case e.Type == abi.Event:
+		topics := make([]ethtypes.HexBytes0xPrefix, len(flags.topics))
+		for i, t := range flags.topics {
+			topic, err := ethtypes.NewHexBytes0xPrefix(t)
+			if err != nil {
+				return nil, i18n.NewError(ctx, signermsgs.MsgInvalidHexInput, err)
+			}
+			topics[i] = topic
+		}
+		return e.DecodeEventDataCtx(ctx, topics, data)
+	case flags.outputs:
+		return e.Outputs.DecodeABIDataCtx(ctx, data, 0)
+	case flags.noSelector || e.Type == abi.Constructor:
+		return e.Inputs.DecodeABIDataCtx(ctx, data, 0)
+	default:
+		return e.DecodeCallDataCtx(ctx, data)
+	}
+}
+
+func newDecodeSerializer(ctx context.Context, flags *abiDecodeFlags) (*abi.Serializer, error) {
+	format, err := selectOption(ctx, "format", flags.format, formattingModes)
+	if err != nil {
+		return nil, err
+	}
+	is, err := selectOption(ctx, "int", flags.ints, intSerializers)
+	if err != nil {
+		return nil, err
+	}
+	fs, err := selectOption(ctx, "float", flags.floats, floatSerializers)
+	if err != nil {
+		return nil, err
+	}
+	bs, err := selectOption(ctx, "bytes", flags.bytes, byteSerializers)
+	if err != nil {
+		return nil, err
+	}
+	as, err := selectOption(ctx, "address", flags.addresses, addressSerializers)
+	if err != nil {
+		return nil, err
+	}
+	return abi.NewSerializer().
+		SetFormattingMode(format).
+		SetIntSerializer(is).
+		SetFloatSerializer(fs).
+		SetByteSerializer(bs).
+		SetAddressSerializer(as).
+		SetPretty(flags.pretty), nil
+}
+
+// selectOption looks up the value of a command line flag in a set of named options
+func selectOption[T any](ctx context.Context, flag, value string, options map[string]T) (T, error) {
+	o, ok := options[value]
+	if !ok {
+		names := make([]string, 0, len(options))
+		for name := range options {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return o, i18n.NewError(ctx, signermsgs.MsgInvalidCommandOption, value, flag, strings.Join(names, ", "))
+	}
+	return o, nil
+}
+
+// readABIEntry reads an ABI file, and finds the entry with the given name or signature
+func readABIEntry(ctx context.Context, filename, name string) (*abi.Entry, error) {
+	a, err := readABIFile(ctx, filename)
+	if err != nil {
+		return nil, err
+	}
+	return a.EntryByNameCtx(ctx, name)
+}
@@ -0,0 +1,211 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testABI = `[
+	{"type": "constructor", "inputs": [{"name": "supply", "type": "uint256"}]},
+	{
+		"type": "function", "name": "transfer",
+		"inputs": [{"name": "to", "type": "address"}, {"name": "value", "type": "uint256"}],
+		"outputs": [{"name": "", "type": "bool"}]
+	},
+	{"type": "function", "name": "mint", "inputs": [{"name": "value", "type": "uint256"}]},
+	{"type": "function", "name": "mint", "inputs": [{"name": "to", "type": "address"}, {"name": "value", "type": "uint256"}]},
+	{"type": "function", "name": "data", "inputs": [{"name": "b", "type": "bytes"}, {"name": "f", "type": "fixed128x18"}]},
+	{"type": "error", "name": "Insufficient", "inputs": [{"name": "needed", "type": "uint256"}]},
+	{
+		"type": "event", "name": "Transfer",
+		"inputs": [
+			{"name": "from", "type": "address", "indexed": true},
+			{"name": "to", "type": "address", "indexed": true},
+			{"name": "value", "type": "uint256"}
+		]
+	},
+	{"type": "fallback"}
+]`
+
+const testTransferCallData = "0xa9059cbb" +
+	"000000000000000000000000497eedc4299dea2f2a364be10025d0ad0f702de3" +
+	"00000000000000000000000000000000000000000000000000000000000003e8"
+
+func TestABIEncodeFunction(t *testing.T) {
+	abiFile := writeTestFile(t, "abi.json", testABI)
+
+	out, err := runCommand(t, "", "abi", "encode", abiFile, "transfer", `{"to":"0x497eedc4299dea2f2a364be10025d0ad0f702de3","value":"1000"}`)
+	assert.NoError(t, err)
+	assert.Equal(t, testTransferCallData, out)
+
+	out, err = runCommand(t, `["0x497eedc4299dea2f2a364be10025d0ad0f702de3",1000]`, "abi", "encode", abiFile, "transfer(address,uint256)", "--no-selector")
+	assert.NoError(t, err)
+	assert.Equal(t, "0x"+testTransferCallData[10:], out)
+
+	out, err = runCommand(t, `[true]`, "abi", "encode", abiFile, "transfer", "--outputs")
+	assert.NoError(t, err)
+	assert.Equal(t, "0x0000000000000000000000000000000000000000000000000000000000000001", out)
+}
+
+func TestABIEncodeConstructorAndError(t *testing.T) {
+	abiFile := writeTestFile(t, "abi.json", testABI)
+
+	out, err := runCommand(t, "", "abi", "encode", abiFile, "constructor", `[1000]`)
+	assert.NoError(t, err)
+	assert.Equal(t, "0x00000000000000000000000000000000000000000000000000000000000003e8", out)
+
+	out, err = runCommand(t, "", "abi", "encode", abiFile, "Insufficient", `{"needed":1000}`)
+	assert.NoError(t, err)
+	assert.Equal(t, "0x91bcc56400000000000000000000000000000000000000000000000000000000000003e8", out)
+}
+
+func TestABIEncodePacked(t *testing.T) {
+	abiFile := writeTestFile(t, "abi.json", testABI)
+
+	out, err := runCommand(t, "", "abi", "encode", abiFile, "transfer", "--packed", `{"to":"0x497eedc4299dea2f2a364be10025d0ad0f702de3","value":"1000"}`)
+	assert.NoError(t, err)
+	assert.Equal(t, "0x497eedc4299dea2f2a364be10025d0ad0f702de3"+testTransferCallData[74:], out)
+
+	out, err = runCommand(t, `[true]`, "abi", "encode", abiFile, "transfer", "--outputs", "--packed")
+	assert.NoError(t, err)
+	assert.Equal(t, "0x01", out)
+}
+
+func TestABIEncodeFail(t *testing.T) {
+	abiFile := writeTestFile(t, "abi.json", testABI)
+
+	_, err := runCommand(t, "", "abi", "encode", abiFile, "Transfer", `[]`)
+	assert.Regexp(t, "FF22205", err)
+
+	_, err = runCommand(t, "", "abi", "encode", abiFile, "mint", `[]`)
+	assert.Regexp(t, "FF22204.*mint\\(uint256\\), mint\\(address,uint256\\)", err)
+
+	_, err = runCommand(t, "", "abi", "encode", abiFile, "burn", `[]`)
+	assert.Regexp(t, "FF22203", err)
+
+	_, err = runCommand(t, "", "abi", "encode", abiFile, "mint(uint256)", `{}`)
+	assert.Regexp(t, "FF22040", err)
+
+	_, err = runCommand(t, "", "abi", "encode", writeTestFile(t, "bad.json", "!json"), "transfer")
+	assert.Regexp(t, "FF22201", err)
+
+	_, err = runCommand(t, "", "abi", "encode", abiFile, "transfer")
+	assert.Error(t, err)
+}
+
+func TestABIDecodeFunction(t *testing.T) {
+	abiFile := writeTestFile(t, "abi.json", testABI)
+
+	out, err := runCommand(t, "", "abi", "decode", abiFile, "transfer", testTransferCallData)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"to":"0x497eedc4299dea2f2a364be10025d0ad0f702de3","value":"1000"}`, out)
+
+	out, err = runCommand(t, testTransferCallData[10:], "abi", "decode", abiFile, "transfer", "--no-selector",
+		"--format", "arrays", "--int", "number", "--address", "checksum")
+	assert.NoError(t, err)
+	assert.JSONEq(t, `["0x497EEdc4299Dea2f2A364Be10025d0aD0f702De3",1000]`, out)
+
+	out, err = runCommand(t, "", "abi", "decode", abiFile, "transfer", "--outputs", "--format", "self-describing",
+		"0x0000000000000000000000000000000000000000000000000000000000000001")
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[{"name":"0","type":"bool","value":true}]`, out)
+
+	out, err = runCommand(t, "", "abi", "decode", abiFile, "constructor", "--int", "hex", "--pretty",
+		"0x00000000000000000000000000000000000000000000000000000000000003e8")
+	assert.NoError(t, err)
+	assert.Equal(t, "{\n  \"supply\": \"0x3e8\"\n}", out)
+}
+
+func TestABIDecodeSerializerOptions(t *testing.T) {
+	abiFile := writeTestFile(t, "abi.json", testABI)
+
+	data, err := runCommand(t, "", "abi", "encode", abiFile, "data", `{"b":"0xfeedbeef","f":"1.5"}`)
+	assert.NoError(t, err)
+
+	out, err := runCommand(t, "", "abi", "decode", abiFile, "data", data)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"b":"0xfeedbeef","f":"1.5"}`, out)
+
+	out, err = runCommand(t, "", "abi", "decode", abiFile, "data", data, "--bytes", "base64", "--float", "auto")
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"b":"/u2+7w==","f":1.5}`, out)
+
+	out, err = runCommand(t, "", "abi", "decode", abiFile, "data", data, "--bytes", "hex")
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"b":"feedbeef","f":"1.5"}`, out)
+
+	out, err = runCommand(t, "", "abi", "decode", abiFile, "transfer", testTransferCallData, "--address", "hex", "--int", "auto")
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"to":"497eedc4299dea2f2a364be10025d0ad0f702de3","value":1000}`, out)
+}
+
+func TestABIDecodeEvent(t *testing.T) {
+	abiFile := writeTestFile(t, "abi.json", testABI)
+
+	out, err := runCommand(t, "", "abi", "decode", abiFile, "Transfer",
+		"--topic", "0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef",
+		"--topic", "0x0000000000000000000000001f185718734552d08278aa70f804580bab5fd2b4",
+		"--topic", "0x000000000000000000000000497eedc4299dea2f2a364be10025d0ad0f702de3",
+		"0x00000000000000000000000000000000000000000000000000000000000003e8")
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"from":"0x1f185718734552d08278aa70f804580bab5fd2b4",
+		"to":"0x497eedc4299dea2f2a364be10025d0ad0f702de3",
+		"value":"1000"
+	}`, out)
+
+	_, err = runCommand(t, "", "abi", "decode", abiFile, "Transfer", "--topic", "wrong", "0x")
+	assert.Regexp(t, "FF22206", err)
+
+	_, err = runCommand(t, "", "abi", "decode", abiFile, "Transfer", "0x")
+	assert.Error(t, err)
+}
+
+func TestABIDecodeFail(t *testing.T) {
+	abiFile := writeTestFile(t, "abi.json", testABI)
+
+	for _, opt := range []string{"format", "int", "float", "bytes", "address"} {
+		_, err := runCommand(t, "", "abi", "decode", abiFile, "transfer", testTransferCallData, "--"+opt, "wrong")
+		assert.Regexp(t, "FF22207.*--"+opt, err)
+	}
+
+	_, err := runCommand(t, "", "abi", "decode", abiFile, "burn", testTransferCallData)
+	assert.Regexp(t, "FF22203", err)
+
+	_, err = runCommand(t, "", "abi", "decode", abiFile, "transfer", "wrong")
+	assert.Regexp(t, "FF22206", err)
+
+	_, err = runCommand(t, "", "abi", "decode", abiFile, "transfer", "0x")
+	assert.Error(t, err)
+}
+
+func TestABIStdinFail(t *testing.T) {
+	abiFile := writeTestFile(t, "abi.json", testABI)
+	for _, command := range []string{"encode", "decode"} {
+		rootCmd.SetArgs([]string{"abi", command, abiFile, "transfer"})
+		rootCmd.SetIn(iotest.ErrReader(fmt.Errorf("pop")))
+		err := Execute()
+		assert.Regexp(t, "FF22199.*pop", err)
+	}
+	rootCmd.SetArgs([]string{})
+	rootCmd.SetIn(nil)
+}
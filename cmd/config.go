@@ -19,8 +19,15 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-signer/internal/rpcserver"
+	"github.com/hyperledger/firefly-signer/internal/signerconfig"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/hyperledger/firefly-signer/pkg/fswallet"
+	"github.com/hyperledger/firefly-signer/pkg/objectstore"
 	"github.com/spf13/cobra"
 )
 
@@ -43,7 +50,24 @@ nav_order: 2
 ---
 `
 
-func configCommand() *cobra.Command {
+const errorCodesReferenceHeader = `---
+layout: default
+title: pages.errors
+parent: Reference
+nav_order: 3
+---
+
+# Error Codes Reference
+
+Every error returned by the signer has a stable code for its class of failure, which automation can branch on.
+JSON/RPC errors have the code in their data (such as ` + "`" + `{"errorCode":"FFS-ABI-001","messageKey":"FF22047"}` + "`" + `),
+and admin API errors in their ` + "`" + `errorCode` + "`" + ` field. Go applications get it with ` + "`" + `errorcodes.CodeOf(err)` + "`" + `.
+
+Codes are never reused or renumbered, but the message keys in each class can change between releases.
+
+`
+
+func docsCommand() *cobra.Command {
 	versionCmd := &cobra.Command{
 		Use:   "docs",
 		Short: "Prints the config info as markdown",
@@ -57,3 +81,76 @@ func configCommand() *cobra.Command {
 	}
 	return versionCmd
 }
+
+func configCommand() *cobra.Command {
+	configCmd := &cobra.Command{
+		Use:   "config",
+		Short: "Validate the configuration",
+		Long:  "",
+	}
+	configCmd.AddCommand(configCheckCommand())
+	return configCmd
+}
+
+func configCheckCommand() *cobra.Command {
+	var noProbe bool
+	checkCmd := &cobra.Command{
+		Use:   "check",
+		Short: "Checks the configuration, reporting every problem found rather than just the first",
+		Long: `Checks the configuration, reporting every problem found rather than just the first.
+The paths in the file wallet configuration must exist, and the metadata templates must be valid.
+Password files that other users can read, and wallet directories other users can write to, are reported as warnings.
+Unless --no-probe is set, the backend is queried for its chain ID, which must match any configured chain ID.
+No ports are bound, so the check can be run alongside a running signer.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, err := readCommandConfig()
+			if err != nil {
+				return err
+			}
+			problems, warnings := checkConfig(ctx)
+			if !noProbe {
+				chainID, probeProblems := rpcserver.ProbeBackend(ctx)
+				problems = append(problems, probeProblems...)
+				if chainID >= 0 {
+					fmt.Fprintf(cmd.OutOrStdout(), "Backend chain ID: %d\n", chainID)
+				}
+			}
+			for _, warning := range warnings {
+				fmt.Fprintf(cmd.OutOrStdout(), "WARNING: %s\n", warning)
+			}
+			for _, problem := range problems {
+				fmt.Fprintf(cmd.OutOrStdout(), "ERROR: %s\n", problem)
+			}
+			if len(problems) > 0 {
+				return i18n.NewError(ctx, signermsgs.MsgConfigCheckFailed, len(problems))
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), "Configuration OK")
+			return nil
+		},
+	}
+	checkCmd.Flags().BoolVar(&noProbe, "no-probe", false, "do not connect to the backend")
+	return checkCmd
+}
+
+// checkConfig validates the whole configuration, without loading keys or binding ports
+func checkConfig(ctx context.Context) (problems, warnings []error) {
+	if !config.GetBool(signerconfig.FileWalletEnabled) {
+		problems = append(problems, i18n.NewError(ctx, signermsgs.MsgNoWalletEnabled))
+	} else {
+		problems, warnings = fswallet.CheckConfig(ctx, fswallet.ReadConfig(signerconfig.FileWalletConfig))
+		if osConf := objectstore.ReadConfig(signerconfig.FileWalletObjectStoreConfig); osConf.Enabled {
+			problems = append(problems, objectstore.CheckConfig(ctx, osConf)...)
+		}
+	}
+	return append(problems, rpcserver.CheckConfig(ctx)...), warnings
+}
+
+// configProblemsError combines every problem with the configuration into a single error
+func configProblemsError(ctx context.Context, problems []error) error {
+	msgs := make([]string, len(problems))
+	for i, problem := range problems {
+		msgs[i] = problem.Error()
+	}
+	return i18n.NewError(ctx, signermsgs.MsgConfigProblems, len(problems), strings.Join(msgs, "; "))
+}
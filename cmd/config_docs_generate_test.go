@@ -26,6 +26,7 @@ import (
 	"testing"
 
 	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/hyperledger/firefly-signer/pkg/errorcodes"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -41,3 +42,15 @@ func TestGenerateConfigDocs(t *testing.T) {
 	err = f.Close()
 	assert.NoError(t, err)
 }
+
+func TestGenerateErrorCodeDocs(t *testing.T) {
+	err := os.WriteFile(filepath.Join("..", "errors.md"), errorcodes.GenerateMarkdown(errorCodesReferenceHeader), 0644)
+	assert.NoError(t, err)
+}
+
+func TestGenerateTestVectors(t *testing.T) {
+	vectors, err := generateTestVectors(context.Background(), testVectorsGenerateCommand(), []string{filepath.Join("..", "test", "testvectors", "fixtures.yaml")})
+	assert.NoError(t, err)
+	err = os.WriteFile(filepath.Join("..", "test", "testvectors", "vectors.json"), append(vectors, '\n'), 0644)
+	assert.NoError(t, err)
+}
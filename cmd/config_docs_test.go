@@ -27,6 +27,7 @@ import (
 	"testing"
 
 	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/hyperledger/firefly-signer/pkg/errorcodes"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -44,3 +45,17 @@ func TestConfigDocsUpToDate(t *testing.T) {
 	configOnDiskHash.Write(configOnDisk)
 	assert.Equal(t, configOnDiskHash.Sum(nil), generatedConfigHash.Sum(nil), "The config reference docs generated by the code did not match the config.md file in git. Did you forget to run `make docs`?")
 }
+
+func TestErrorCodeDocsUpToDate(t *testing.T) {
+	errorsOnDisk, err := os.ReadFile(filepath.Join("..", "errors.md"))
+	assert.NoError(t, err)
+	assert.Equal(t, string(errorcodes.GenerateMarkdown(errorCodesReferenceHeader)), string(errorsOnDisk), "The error code reference docs generated by the code did not match the errors.md file in git. Did you forget to run `make docs`?")
+}
+
+func TestTestVectorsUpToDate(t *testing.T) {
+	vectors, err := generateTestVectors(context.Background(), testVectorsGenerateCommand(), []string{filepath.Join("..", "test", "testvectors", "fixtures.yaml")})
+	assert.NoError(t, err)
+	vectorsOnDisk, err := os.ReadFile(filepath.Join("..", "test", "testvectors", "vectors.json"))
+	assert.NoError(t, err)
+	assert.Equal(t, string(vectors)+"\n", string(vectorsOnDisk), "The test vectors generated by the code did not match the vectors.json file in git. Did you forget to run `make reference`?")
+}
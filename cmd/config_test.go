@@ -1,4 +1,4 @@
-// Copyright © 2022 Kaleido, Inc.
+// Copyright © 2024 Kaleido, Inc.
 //
 // SPDX-License-Identifier: Apache-2.0
 //
@@ -17,6 +17,12 @@
 package cmd
 
 import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -28,3 +34,79 @@ func TestConfigMarkdown(t *testing.T) {
 	err := rootCmd.Execute()
 	assert.NoError(t, err)
 }
+
+func newTestChainIDBackend(t *testing.T, chainID int64) string {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		err := json.NewDecoder(r.Body).Decode(&req)
+		assert.NoError(t, err)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"jsonrpc": "2.0",
+			"id":      req["id"],
+			"result":  fmt.Sprintf("0x%x", chainID),
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func TestConfigCheckOK(t *testing.T) {
+	configFile := writeTestTxConfig(t, fmt.Sprintf("backend:\n  url: %q\n  chainId: 1337\n", newTestChainIDBackend(t, 1337)))
+	out, err := runCommand(t, "", "config", "check", "-f", configFile)
+	assert.NoError(t, err)
+	assert.Equal(t, "Backend chain ID: 1337\nConfiguration OK", out)
+}
+
+func TestConfigCheckNoProbeWarnings(t *testing.T) {
+	passwordFile := path.Join(t.TempDir(), "password")
+	err := os.WriteFile(passwordFile, []byte("pwd"), 0640)
+	assert.NoError(t, err)
+	err = os.Chmod(passwordFile, 0640) // regardless of umask
+	assert.NoError(t, err)
+	configFile := writeTestTxConfig(t, fmt.Sprintf("  defaultPasswordFile: %q\n", passwordFile))
+	out, err := runCommand(t, "", "config", "check", "--no-probe", "-f", configFile)
+	assert.NoError(t, err)
+	assert.Regexp(t, "^WARNING: FF22219.*defaultPasswordFile.*\nConfiguration OK$", out)
+}
+
+func TestConfigCheckProblems(t *testing.T) {
+	configFile := writeTestTxConfig(t, `  metadata:
+    format: toml
+backend:
+  url: http://127.0.0.1:1
+  chainId: 1337
+  retry:
+    enabled: false
+gasEstimate:
+  multiplier: 0.5
+`)
+	out, err := runCommand(t, "", "config", "check", "-f", configFile)
+	assert.Regexp(t, "FF22221.*3", err)
+	assert.Regexp(t, "^ERROR: FF22217.*\nERROR: FF22130.*\nERROR: FF22021.*\n", out)
+}
+
+func TestConfigCheckObjectStore(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	configFile := writeTestTxConfig(t, `  objectStore:
+    enabled: true
+    endpoint: https://s3.us-east-1.amazonaws.com
+    serverSideEncryption:
+      mode: wrong
+`)
+	out, err := runCommand(t, "", "config", "check", "--no-probe", "-f", configFile)
+	assert.Regexp(t, "FF22221.*3", err)
+	assert.Regexp(t, "^ERROR: FF22307.*bucket.*\nERROR: FF22311.*\nERROR: FF22309.*wrong.*\n", out)
+}
+
+func TestConfigCheckNoWallet(t *testing.T) {
+	out, err := runCommand(t, "", "config", "check", "--no-probe", "-f", "../test/no-wallet.ffsigner.yaml")
+	assert.Regexp(t, "FF22221.*1", err)
+	assert.Regexp(t, "^ERROR: FF22017", out)
+}
+
+func TestConfigCheckBadConfig(t *testing.T) {
+	_, err := runCommand(t, "", "config", "check", "-f", "../test/bad-config.ffsigner.yaml")
+	assert.Regexp(t, "FF00101", err)
+}
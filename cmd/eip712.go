@@ -0,0 +1,144 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/hyperledger/firefly-signer/pkg/eip712"
+	"github.com/hyperledger/firefly-signer/pkg/ethsigner"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/secp256k1"
+	"github.com/spf13/cobra"
+)
+
+func eip712Command() *cobra.Command {
+	eip712Cmd := &cobra.Command{
+		Use:   "eip712",
+		Short: "Hash and sign EIP-712 typed data offline",
+		Long:  "",
+	}
+	eip712Cmd.AddCommand(eip712HashCommand())
+	eip712Cmd.AddCommand(eip712SignCommand())
+	return eip712Cmd
+}
+
+func eip712HashCommand() *cobra.Command {
+	hashCmd := &cobra.Command{
+		Use:   "hash [typed data JSON file]",
+		Short: "Prints the domain separator, struct hash and digest of EIP-712 typed data",
+		Long: `Prints the domain separator, struct hash and digest of EIP-712 typed data as JSON.
+The typed data is read as JSON from the file, or from stdin, in the same format as eth_signTypedData_v4.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			payload, err := readTypedData(ctx, cmd, args)
+			if err != nil {
+				return err
+			}
+			hashes, err := eip712.HashTypedDataV4(ctx, payload)
+			if err != nil {
+				return err
+			}
+			b, _ := json.MarshalIndent(hashes, "", "  ")
+			fmt.Fprintln(cmd.OutOrStdout(), string(b))
+			return nil
+		},
+	}
+	return hashCmd
+}
+
+type eip712SignFlags struct {
+	from         string
+	passwordFile string
+}
+
+func eip712SignCommand() *cobra.Command {
+	var flags eip712SignFlags
+	signCmd := &cobra.Command{
+		Use:   "sign [typed data JSON file]",
+		Short: "Signs EIP-712 typed data with a key from the file wallet",
+		Long: `Signs EIP-712 typed data with a key from the file wallet, and prints the hash and signature as JSON.
+The typed data is read as JSON from the file, or from stdin, in the same format as eth_signTypedData_v4.
+No HTTP server or backend is needed.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, err := readCommandConfig()
+			if err != nil {
+				return err
+			}
+			payload, err := readTypedData(ctx, cmd, args)
+			if err != nil {
+				return err
+			}
+			result, err := signTypedData(ctx, payload, &flags)
+			if err != nil {
+				return err
+			}
+			b, _ := json.MarshalIndent(result, "", "  ")
+			fmt.Fprintln(cmd.OutOrStdout(), string(b))
+			return nil
+		},
+	}
+	signCmd.Flags().StringVar(&flags.from, "from", "", "address to sign with")
+	signCmd.Flags().StringVarP(&flags.passwordFile, "password-file", "p", "", "file containing the password for the key, instead of the configured password files")
+	return signCmd
+}
+
+func readTypedData(ctx context.Context, cmd *cobra.Command, args []string) (*eip712.TypedData, error) {
+	input, err := readCommandInput(ctx, cmd, args)
+	if err != nil {
+		return nil, err
+	}
+	var payload eip712.TypedData
+	if err := json.Unmarshal(input, &payload); err != nil {
+		return nil, i18n.NewError(ctx, signermsgs.MsgInvalidTypedDataJSON, err)
+	}
+	return &payload, nil
+}
+
+func signTypedData(ctx context.Context, payload *eip712.TypedData, flags *eip712SignFlags) (*ethsigner.EIP712Result, error) {
+	if flags.from == "" {
+		return nil, i18n.NewError(ctx, signermsgs.MsgMissingFrom)
+	}
+	from, err := ethtypes.NewAddress(flags.from)
+	if err != nil {
+		return nil, err
+	}
+
+	var password []byte
+	if flags.passwordFile != "" {
+		if password, err = readPasswordFile(ctx, flags.passwordFile); err != nil {
+			return nil, err
+		}
+		defer secp256k1.ZeroizeBytes(password)
+	}
+	w, err := openCommandWallet(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer w.Close()
+	// As for transactions, unlocking loads the key even when the wallet requires keys to be unlocked
+	if err := w.Unlock(ctx, *from, password, 0); err != nil {
+		return nil, err
+	}
+	return w.SignTypedDataV4(ctx, *from, payload)
+}
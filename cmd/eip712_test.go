@@ -0,0 +1,130 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/hyperledger/firefly-signer/pkg/eip712"
+	"github.com/hyperledger/firefly-signer/pkg/ethsigner"
+	"github.com/hyperledger/firefly-signer/pkg/secp256k1"
+	"github.com/stretchr/testify/assert"
+)
+
+const testTypedData = `{
+	"types": {
+		"EIP712Domain": [
+			{"name": "name", "type": "string"},
+			{"name": "version", "type": "string"},
+			{"name": "chainId", "type": "uint256"},
+			{"name": "verifyingContract", "type": "address"}
+		],
+		"Person": [{"name": "name", "type": "string"}, {"name": "wallet", "type": "address"}],
+		"Mail": [{"name": "from", "type": "Person"}, {"name": "to", "type": "Person"}, {"name": "contents", "type": "string"}]
+	},
+	"primaryType": "Mail",
+	"domain": {
+		"name": "Ether Mail",
+		"version": "1",
+		"chainId": 1,
+		"verifyingContract": "0xCcCCccccCCCCcCCCCCCcCcCccCcCCCcCcccccccC"
+	},
+	"message": {
+		"from": {"name": "Cow", "wallet": "0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826"},
+		"to": {"name": "Bob", "wallet": "0xbBbBBBBbbBBBbbbBbbBbbbbBBbBbbbbBbBbbBBbB"},
+		"contents": "Hello, Bob!"
+	}
+}`
+
+func TestEIP712Hash(t *testing.T) {
+	out, err := runCommand(t, testTypedData, "eip712", "hash")
+	assert.NoError(t, err)
+
+	// Values from the example in the EIP-712 specification
+	var hashes eip712.TypedDataV4Hashes
+	err = json.Unmarshal([]byte(out), &hashes)
+	assert.NoError(t, err)
+	assert.Equal(t, "0xf2cee375fa42b42143804025fc449deafd50cc031ca257e0b194a650a912090f", hashes.DomainSeparator.String())
+	assert.Equal(t, "0xc52c0ee5d84264471806290a3f2c4cecfc5490626bf912d01f240d7a274b371e", hashes.StructHash.String())
+	assert.Equal(t, "0xbe609aee343fb3c4b28e1df9e632fca64fcfaede20f02e86244efddf30957bd2", hashes.Digest.String())
+
+	out, err = runCommand(t, "", "eip712", "hash", writeTestFile(t, "domain.json", `{"primaryType":"EIP712Domain"}`))
+	assert.NoError(t, err)
+	assert.NotContains(t, out, "structHash")
+}
+
+func TestEIP712HashFail(t *testing.T) {
+	_, err := runCommand(t, `{`, "eip712", "hash")
+	assert.Regexp(t, "FF22211", err)
+
+	_, err = runCommand(t, `{}`, "eip712", "hash")
+	assert.Regexp(t, "FF22080", err)
+}
+
+func TestEIP712Sign(t *testing.T) {
+	for _, extraYAML := range []string{"", "  requireUnlock: true\n"} {
+		configFile := writeTestTxConfig(t, extraYAML)
+		out, err := runCommand(t, testTypedData, "eip712", "sign", "-f", configFile, "--from", testTxAddr,
+			"--password-file", "../test/keystore_toml/1f185718734552d08278aa70f804580bab5fd2b4.pwd")
+		assert.NoError(t, err)
+
+		var result ethsigner.EIP712Result
+		err = json.Unmarshal([]byte(out), &result)
+		assert.NoError(t, err)
+		assert.Equal(t, "0xbe609aee343fb3c4b28e1df9e632fca64fcfaede20f02e86244efddf30957bd2", result.Hash.String())
+		sig, err := secp256k1.DecodeCompactRSV(context.Background(), result.SignatureRSV)
+		assert.NoError(t, err)
+		from, err := sig.RecoverDirect(result.Hash, -1)
+		assert.NoError(t, err)
+		assert.Equal(t, testTxAddr, from.String())
+	}
+}
+
+func TestEIP712SignObjectStoreBadConfig(t *testing.T) {
+	configFile := writeTestTxConfig(t, "  objectStore:\n    enabled: true\n")
+	_, err := runCommand(t, testTypedData, "eip712", "sign", "-f", configFile, "--from", testTxAddr)
+	assert.Regexp(t, "FF22307.*endpoint", err)
+}
+
+func TestEIP712SignFail(t *testing.T) {
+	configFile := writeTestTxConfig(t, "")
+	_, err := runCommand(t, testTypedData, "eip712", "sign", "-f", configFile)
+	assert.Regexp(t, "FF22020", err)
+
+	_, err = runCommand(t, testTypedData, "eip712", "sign", "-f", configFile, "--from", "wrong")
+	assert.Error(t, err)
+
+	_, err = runCommand(t, `{`, "eip712", "sign", "-f", configFile, "--from", testTxAddr)
+	assert.Regexp(t, "FF22211", err)
+
+	_, err = runCommand(t, testTypedData, "eip712", "sign", "-f", configFile, "--from", testTxAddr, "--password-file", "missing")
+	assert.Regexp(t, "FF22196", err)
+
+	_, err = runCommand(t, testTypedData, "eip712", "sign", "-f", configFile, "--from", "0x497eedc4299dea2f2a364be10025d0ad0f702de3")
+	assert.Regexp(t, "FF22014", err)
+
+	_, err = runCommand(t, testTypedData, "eip712", "sign", "-f", "../test/no-wallet.ffsigner.yaml", "--from", testTxAddr)
+	assert.Regexp(t, "FF22017", err)
+
+	_, err = runCommand(t, testTypedData, "eip712", "sign", "-f", "../test/bad-config.ffsigner.yaml", "--from", testTxAddr)
+	assert.Regexp(t, "FF00101", err)
+
+	_, err = runCommand(t, "", "eip712", "sign", "-f", configFile, "--from", testTxAddr, "missing.json")
+	assert.Regexp(t, "FF22199", err)
+}
@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"slices"
 	"syscall"
 
 	"github.com/hyperledger/firefly-common/pkg/config"
@@ -28,7 +29,7 @@ import (
 	"github.com/hyperledger/firefly-common/pkg/log"
 	"github.com/hyperledger/firefly-signer/internal/rpcserver"
 	"github.com/hyperledger/firefly-signer/internal/signerconfig"
-	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/hyperledger/firefly-signer/pkg/fips"
 	"github.com/hyperledger/firefly-signer/pkg/fswallet"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -50,7 +51,14 @@ var cfgFile string
 func init() {
 	rootCmd.PersistentFlags().StringVarP(&cfgFile, "config", "f", "", "config file")
 	rootCmd.AddCommand(versionCommand())
+	rootCmd.AddCommand(docsCommand())
 	rootCmd.AddCommand(configCommand())
+	rootCmd.AddCommand(keysCommand())
+	rootCmd.AddCommand(txCommand())
+	rootCmd.AddCommand(abiCommand())
+	rootCmd.AddCommand(eip712Command())
+	rootCmd.AddCommand(testVectorsCommand())
+	rootCmd.AddCommand(loadTestCommand())
 }
 
 func Execute() error {
@@ -81,33 +89,75 @@ func run() error {
 		return i18n.WrapError(ctx, err, i18n.MsgConfigFailed)
 	}
 
-	// Setup signal handling to cancel the context, which shuts down the API Server
-	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	// Setup signal handling to cancel the context, which shuts down the API Server.
+	// SIGHUP instead reloads the configuration, once the server is running, and SIGUSR1 toggles debug logging.
+	reloads := make(chan struct{}, 1)
+	signal.Notify(sigs, append([]os.Signal{syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP}, debugSignals...)...)
 	go func() {
-		sig := <-sigs
-		log.L(ctx).Infof("Shutting down due to %s", sig.String())
-		cancelCtx()
+		for sig := range sigs {
+			if slices.Contains(debugSignals, sig) {
+				toggleDebugLogging(ctx)
+				continue
+			}
+			if sig == syscall.SIGHUP {
+				select {
+				case reloads <- struct{}{}:
+				default: // a reload is already pending
+				}
+				continue
+			}
+			log.L(ctx).Infof("Shutting down due to %s", sig.String())
+			cancelCtx()
+			return
+		}
 	}()
 
-	if !config.GetBool(signerconfig.FileWalletEnabled) {
-		return i18n.NewError(ctx, signermsgs.MsgNoWalletEnabled)
+	fips.Configure(ctx, fips.ReadConfig(signerconfig.FIPSConfig))
+
+	// Report every problem with the configuration at once, rather than failing on the first
+	problems, warnings := checkConfig(ctx)
+	for _, warning := range warnings {
+		log.L(ctx).Warn(warning.Error())
 	}
-	fileWallet, err := fswallet.NewFilesystemWallet(ctx, fswallet.ReadConfig(signerconfig.FileWalletConfig))
+	if len(problems) > 0 {
+		return configProblemsError(ctx, problems)
+	}
+	fileWallet, err := newFileWallet(ctx, fswallet.ReadConfig(signerconfig.FileWalletConfig))
 	if err != nil {
 		return err
 	}
 
+	shutdownTracing := initTracing()
+	defer func() {
+		_ = shutdownTracing(context.Background())
+	}()
+
 	server, err := rpcserver.NewServer(ctx, fileWallet)
 	if err != nil {
 		return err
 	}
-	return runServer(server)
+	return runServer(ctx, server, reloads)
 }
 
-func runServer(server rpcserver.Server) error {
+func runServer(ctx context.Context, server rpcserver.Server, reloads <-chan struct{}) error {
 	err := server.Start()
 	if err == nil {
+		go reloadOnSignal(ctx, server, reloads)
 		err = server.WaitStop()
 	}
 	return err
 }
+
+func reloadOnSignal(ctx context.Context, server rpcserver.Server, reloads <-chan struct{}) {
+	for {
+		select {
+		case <-reloads:
+			log.L(ctx).Infof("Reloading configuration due to SIGHUP")
+			if err := server.Reload(ctx); err != nil {
+				log.L(ctx).Errorf("Configuration reload failed: %s", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -17,13 +17,17 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"path"
+	"syscall"
 	"testing"
 	"time"
 
 	"github.com/hyperledger/firefly-signer/mocks/rpcservermocks"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 const configDir = "../test/data/config"
@@ -92,7 +96,7 @@ func TestRunFailServer(t *testing.T) {
 
 	s := &rpcservermocks.Server{}
 	s.On("Start").Return(fmt.Errorf("pop"))
-	err := runServer(s)
+	err := runServer(context.Background(), s, nil)
 	assert.Regexp(t, err, "pop")
 
 }
@@ -102,7 +106,87 @@ func TestRunServerOK(t *testing.T) {
 	s := &rpcservermocks.Server{}
 	s.On("Start").Return(nil)
 	s.On("WaitStop").Return(nil)
-	err := runServer(s)
+	err := runServer(context.Background(), s, nil)
 	assert.NoError(t, err)
 
 }
+
+func TestRunServerReloadOnSignal(t *testing.T) {
+
+	ctx, cancelCtx := context.WithCancel(context.Background())
+	defer cancelCtx()
+
+	reloads := make(chan struct{})
+	reloaded := make(chan struct{})
+	s := &rpcservermocks.Server{}
+	s.On("Start").Return(nil)
+	s.On("Reload", ctx).Return(fmt.Errorf("pop")).Once()
+	s.On("Reload", ctx).Return(nil).Once().Run(func(args mock.Arguments) {
+		close(reloaded)
+	})
+	s.On("WaitStop").Return(nil).Run(func(args mock.Arguments) {
+		reloads <- struct{}{}
+		reloads <- struct{}{}
+		<-reloaded
+		cancelCtx()
+	})
+	err := runServer(ctx, s, reloads)
+	assert.NoError(t, err)
+
+	s.AssertExpectations(t)
+
+}
+
+func TestRunSIGHUPReload(t *testing.T) {
+
+	rootCmd.SetArgs([]string{"-f", "../test/firefly.ffsigner.yaml"})
+	defer rootCmd.SetArgs([]string{})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		err := Execute()
+		if err != nil {
+			assert.Error(t, err)
+		}
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	sigs <- syscall.SIGHUP
+	sigs <- syscall.SIGHUP
+	sigs <- syscall.SIGHUP
+	for _, sig := range debugSignals {
+		sigs <- sig // on
+		sigs <- sig // off
+	}
+	time.Sleep(10 * time.Millisecond)
+	sigs <- os.Kill
+
+	<-done
+
+}
+
+func TestRunConfigProblems(t *testing.T) {
+	configFile := writeTestTxConfig(t, "  metadata:\n    format: toml\ngasEstimate:\n  multiplier: 0.5\n")
+	rootCmd.SetArgs([]string{"-f", configFile})
+	defer rootCmd.SetArgs([]string{})
+
+	err := Execute()
+	assert.Regexp(t, "FF22212.*2 problem.*FF22217.*FF22130", err)
+
+}
+
+func TestRunConfigWarnings(t *testing.T) {
+	passwordFile := path.Join(t.TempDir(), "password")
+	err := os.WriteFile(passwordFile, []byte("pwd"), 0640)
+	assert.NoError(t, err)
+	err = os.Chmod(passwordFile, 0640) // regardless of umask
+	assert.NoError(t, err)
+	configFile := writeTestTxConfig(t, fmt.Sprintf("  defaultPasswordFile: %q\nserver:\n  address: ':::::::::'\nbackend:\n  chainId: 0\n", passwordFile))
+	rootCmd.SetArgs([]string{"-f", configFile})
+	defer rootCmd.SetArgs([]string{})
+
+	err = Execute()
+	assert.Regexp(t, "FF00151", err)
+
+}
@@ -0,0 +1,409 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-signer/internal/signerconfig"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/fips"
+	"github.com/hyperledger/firefly-signer/pkg/fswallet"
+	"github.com/hyperledger/firefly-signer/pkg/objectstore"
+	"github.com/hyperledger/firefly-signer/pkg/vanity"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// Allow the terminal to be mocked in tests
+var (
+	stdinIsTerminal      = func() bool { return term.IsTerminal(int(os.Stdin.Fd())) }
+	readTerminalPassword = func() ([]byte, error) { return term.ReadPassword(int(os.Stdin.Fd())) }
+)
+
+func keysCommand() *cobra.Command {
+	keysCmd := &cobra.Command{
+		Use:   "keys",
+		Short: "Manage the keys in the file wallet",
+		Long:  "",
+	}
+	keysCmd.AddCommand(keysCreateCommand())
+	keysCmd.AddCommand(keysPasswdCommand())
+	keysCmd.AddCommand(keysMigrateCommand())
+	keysCmd.AddCommand(keysVanityCommand())
+	return keysCmd
+}
+
+func keysCreateCommand() *cobra.Command {
+	var passwordFile string
+	var light bool
+	createCmd := &cobra.Command{
+		Use:   "create",
+		Short: "Generates a new key, and writes it into the file wallet using the configured layout",
+		Long: `Generates a new key, and writes it into the file wallet using the configured layout.
+The password is read from --password-file, prompted for on a terminal, or otherwise read from the first line of stdin.
+No password is needed if there is no password file for each key, as the default password file is used.
+Prints the address of the new key.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, err := readCommandConfig()
+			if err != nil {
+				return err
+			}
+			if !config.GetBool(signerconfig.FileWalletEnabled) {
+				return i18n.NewError(ctx, signermsgs.MsgNoWalletEnabled)
+			}
+			addr, err := fswallet.CreateKey(ctx, fswallet.ReadConfig(signerconfig.FileWalletConfig), &fswallet.CreateKeyOptions{
+				Password: func() ([]byte, error) {
+					return readNewPassword(ctx, cmd, bufio.NewReader(cmd.InOrStdin()), passwordFile)
+				},
+				LightScrypt: light,
+			})
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), addr)
+			return nil
+		},
+	}
+	createCmd.Flags().StringVarP(&passwordFile, "password-file", "p", "", "file containing the password for the new key")
+	createCmd.Flags().BoolVar(&light, "light", false, "use light scrypt parameters, which are quicker to decrypt but less resistant to brute force")
+	return createCmd
+}
+
+type keysPasswdFlags struct {
+	passwordFile    string
+	oldPasswordFile string
+	light           bool
+}
+
+func keysPasswdCommand() *cobra.Command {
+	var flags keysPasswdFlags
+	passwdCmd := &cobra.Command{
+		Use:   "passwd <address or keystore file>",
+		Short: "Re-encrypts a key under a new password",
+		Long: `Re-encrypts a key under a new password. The re-encrypted key is verified by decrypting it, before it replaces the original.
+For an address, the key is found in the file wallet using the configured layout, and the password file for the key
+is updated with the new password. The current password is read from --old-password-file, or otherwise from the
+configured password files.
+For a Keystore V3 file, no configuration is needed. The current password is read from --old-password-file,
+prompted for on a terminal, or otherwise read from the first line of stdin.
+The new password is read from --password-file, prompted for on a terminal, or otherwise read from the next line of stdin.
+Prints the address of the key.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			in := bufio.NewReader(cmd.InOrStdin())
+			addr, err := ethtypes.NewAddress(args[0])
+			if err == nil {
+				err = changeWalletPassword(cmd, in, addr, &flags)
+			} else {
+				addr, err = changeKeystoreFilePassword(cmd, in, args[0], &flags)
+			}
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), addr)
+			return nil
+		},
+	}
+	passwdCmd.Flags().StringVarP(&flags.passwordFile, "password-file", "p", "", "file containing the new password for the key")
+	passwdCmd.Flags().StringVar(&flags.oldPasswordFile, "old-password-file", "", "file containing the current password for the key")
+	passwdCmd.Flags().BoolVar(&flags.light, "light", false, "use light scrypt parameters, which are quicker to decrypt but less resistant to brute force")
+	return passwdCmd
+}
+
+func changeWalletPassword(cmd *cobra.Command, in *bufio.Reader, addr *ethtypes.Address0xHex, flags *keysPasswdFlags) error {
+	ctx, err := readCommandConfig()
+	if err != nil {
+		return err
+	}
+	if !config.GetBool(signerconfig.FileWalletEnabled) {
+		return i18n.NewError(ctx, signermsgs.MsgNoWalletEnabled)
+	}
+	options := &fswallet.ChangePasswordOptions{
+		NewPassword: func() ([]byte, error) {
+			return readNewPassword(ctx, cmd, in, flags.passwordFile)
+		},
+		LightScrypt: flags.light,
+	}
+	if flags.oldPasswordFile != "" {
+		options.OldPassword = func() ([]byte, error) {
+			return readPasswordFile(ctx, flags.oldPasswordFile)
+		}
+	}
+	return fswallet.ChangePassword(ctx, fswallet.ReadConfig(signerconfig.FileWalletConfig), *addr, options)
+}
+
+func changeKeystoreFilePassword(cmd *cobra.Command, in *bufio.Reader, filename string, flags *keysPasswdFlags) (*ethtypes.Address0xHex, error) {
+	ctx := context.Background()
+	oldPassword, err := readCurrentPassword(ctx, cmd, in, flags.oldPasswordFile)
+	if err != nil {
+		return nil, err
+	}
+	newPassword, err := readNewPassword(ctx, cmd, in, flags.passwordFile)
+	if err != nil {
+		return nil, err
+	}
+	return fswallet.ChangeKeystoreFilePassword(ctx, filename, oldPassword, newPassword, flags.light)
+}
+
+// readCurrentPassword reads a password from a file, from a terminal, or from the next line of stdin
+// when it is not a terminal
+func readCurrentPassword(ctx context.Context, cmd *cobra.Command, in *bufio.Reader, passwordFile string) ([]byte, error) {
+	if passwordFile != "" {
+		return readPasswordFile(ctx, passwordFile)
+	}
+	if !stdinIsTerminal() {
+		return readPasswordLine(in)
+	}
+	fmt.Fprint(cmd.ErrOrStderr(), "Current password: ")
+	password, err := readTerminalPassword()
+	fmt.Fprintln(cmd.ErrOrStderr())
+	return password, err
+}
+
+// readNewPassword reads a password from a file, from a terminal (prompting twice to confirm it),
+// or from the next line of stdin when it is not a terminal
+func readNewPassword(ctx context.Context, cmd *cobra.Command, in *bufio.Reader, passwordFile string) ([]byte, error) {
+	if passwordFile != "" {
+		return readPasswordFile(ctx, passwordFile)
+	}
+	if !stdinIsTerminal() {
+		return readPasswordLine(in)
+	}
+	fmt.Fprint(cmd.ErrOrStderr(), "Password: ")
+	password, err := readTerminalPassword()
+	fmt.Fprintln(cmd.ErrOrStderr())
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprint(cmd.ErrOrStderr(), "Confirm password: ")
+	confirm, err := readTerminalPassword()
+	fmt.Fprintln(cmd.ErrOrStderr())
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(password, confirm) {
+		return nil, i18n.NewError(ctx, signermsgs.MsgPasswordMismatch)
+	}
+	return password, nil
+}
+
+func readPasswordLine(in *bufio.Reader) ([]byte, error) {
+	line, err := in.ReadBytes('\n')
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	return bytes.TrimRight(line, "\r\n"), nil
+}
+
+// readPasswordFile reads a password from a file, ignoring any trailing newline
+func readPasswordFile(ctx context.Context, passwordFile string) ([]byte, error) {
+	password, err := os.ReadFile(passwordFile)
+	if err != nil {
+		return nil, i18n.WrapError(ctx, err, signermsgs.MsgReadPasswordFileFailed, passwordFile)
+	}
+	return bytes.TrimRight(password, "\r\n"), nil
+}
+
+func keysMigrateCommand() *cobra.Command {
+	migrateCmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Moves the files in the file wallet to match the configured directory sharding (fileWallet.filenames.shardPrefixLength)",
+		Long:  "",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, err := readCommandConfig()
+			if err != nil {
+				return err
+			}
+			if !config.GetBool(signerconfig.FileWalletEnabled) {
+				return i18n.NewError(ctx, signermsgs.MsgNoWalletEnabled)
+			}
+			moved, err := fswallet.MigrateLayout(ctx, fswallet.ReadConfig(signerconfig.FileWalletConfig))
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Moved %d files\n", moved)
+			return nil
+		},
+	}
+	return migrateCmd
+}
+
+// openCommandWallet opens the file wallet for commands other than the main server, without
+// a filesystem listener as the command only runs briefly
+func openCommandWallet(ctx context.Context) (fswallet.Wallet, error) {
+	if !config.GetBool(signerconfig.FileWalletEnabled) {
+		return nil, i18n.NewError(ctx, signermsgs.MsgNoWalletEnabled)
+	}
+	conf := fswallet.ReadConfig(signerconfig.FileWalletConfig)
+	conf.DisableListener = true
+	w, err := newFileWallet(ctx, conf)
+	if err != nil {
+		return nil, err
+	}
+	if err := w.Initialize(ctx); err != nil {
+		_ = w.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// newFileWallet returns the file wallet, synchronized from the object store when one is enabled
+func newFileWallet(ctx context.Context, conf *fswallet.Config) (fswallet.Wallet, error) {
+	if osConf := objectstore.ReadConfig(signerconfig.FileWalletObjectStoreConfig); osConf.Enabled {
+		return objectstore.NewWallet(ctx, osConf, conf)
+	}
+	return fswallet.NewFilesystemWallet(ctx, conf)
+}
+
+// readCommandInput reads the input of a command from the file named in the first argument, or from
+// stdin if there is no argument (or it is "-")
+func readCommandInput(ctx context.Context, cmd *cobra.Command, args []string) ([]byte, error) {
+	name := "stdin"
+	var b []byte
+	var err error
+	if len(args) > 0 && args[0] != "-" {
+		name = args[0]
+		b, err = os.ReadFile(name)
+	} else {
+		b, err = io.ReadAll(cmd.InOrStdin())
+	}
+	if err != nil {
+		return nil, i18n.WrapError(ctx, err, signermsgs.MsgReadInputFailed, name)
+	}
+	return b, nil
+}
+
+// readCommandArg returns the argument at the given index, or reads it from stdin if it is
+// not supplied (or is "-")
+func readCommandArg(ctx context.Context, cmd *cobra.Command, args []string, idx int) (string, error) {
+	if len(args) > idx && args[idx] != "-" {
+		return args[idx], nil
+	}
+	b, err := readCommandInput(ctx, cmd, nil)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+// readCommandConfig loads the configuration file for commands other than the main server
+func readCommandConfig() (context.Context, error) {
+	initConfig()
+	ctx := context.Background()
+	err := config.ReadConfig("ffsigner", cfgFile)
+	config.SetupLogging(ctx)
+	if err != nil {
+		return nil, i18n.WrapError(ctx, err, i18n.MsgConfigFailed)
+	}
+	fips.Configure(ctx, fips.ReadConfig(signerconfig.FIPSConfig))
+	return ctx, nil
+}
+
+type keysVanityFlags struct {
+	options             vanity.Options
+	passwordFile        string
+	light               bool
+	create2Deployer     string
+	create2InitCodeHash string
+}
+
+func keysVanityCommand() *cobra.Command {
+	var flags keysVanityFlags
+	vanityCmd := &cobra.Command{
+		Use:   "vanity",
+		Short: "Generates keys until one has an address matching a pattern, and writes it into the file wallet",
+		Long: `Generates keys in parallel until one has an address matching a pattern, and writes it into the file wallet
+using the configured layout, as for the create command. The pattern is matched against the lower case hex of the
+address, and every one of --prefix, --suffix and --regex that is set must match. Each extra hex character makes
+the search 16 times longer.
+With --create2-deployer and --create2-init-code-hash, searches for a salt instead, that gives a matching address to
+a contract deployed with CREATE2 by the deployer. No configuration is needed, and nothing is written to the wallet.
+Prints the address of the new key, or the salt and the address of the contract.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if flags.create2Deployer != "" || flags.create2InitCodeHash != "" {
+				return findCreate2Salt(cmd, &flags)
+			}
+			return createVanityKey(cmd, &flags)
+		},
+	}
+	vanityCmd.Flags().StringVar(&flags.options.Prefix, "prefix", "", "hex characters the address must start with")
+	vanityCmd.Flags().StringVar(&flags.options.Suffix, "suffix", "", "hex characters the address must end with")
+	vanityCmd.Flags().StringVar(&flags.options.Regexp, "regex", "", "regular expression the lower case hex of the address must match")
+	vanityCmd.Flags().IntVar(&flags.options.Workers, "workers", 0, "number of keys to generate in parallel (defaults to the number of CPUs)")
+	vanityCmd.Flags().StringVarP(&flags.passwordFile, "password-file", "p", "", "file containing the password for the new key")
+	vanityCmd.Flags().BoolVar(&flags.light, "light", false, "use light scrypt parameters, which are quicker to decrypt but less resistant to brute force")
+	vanityCmd.Flags().StringVar(&flags.create2Deployer, "create2-deployer", "", "address of the contract that deploys with CREATE2, to search for a salt")
+	vanityCmd.Flags().StringVar(&flags.create2InitCodeHash, "create2-init-code-hash", "", "keccak256 hash of the init code of the contract deployed with CREATE2")
+	return vanityCmd
+}
+
+func createVanityKey(cmd *cobra.Command, flags *keysVanityFlags) error {
+	ctx, err := readCommandConfig()
+	if err != nil {
+		return err
+	}
+	if !config.GetBool(signerconfig.FileWalletEnabled) {
+		return i18n.NewError(ctx, signermsgs.MsgNoWalletEnabled)
+	}
+	keypair, attempts, err := vanity.FindKey(ctx, &flags.options)
+	if err != nil {
+		return err
+	}
+	defer keypair.Zeroize()
+	fmt.Fprintf(cmd.ErrOrStderr(), "Found after %d attempts\n", attempts)
+	addr, err := fswallet.CreateKey(ctx, fswallet.ReadConfig(signerconfig.FileWalletConfig), &fswallet.CreateKeyOptions{
+		Password: func() ([]byte, error) {
+			return readNewPassword(ctx, cmd, bufio.NewReader(cmd.InOrStdin()), flags.passwordFile)
+		},
+		LightScrypt: flags.light,
+		KeyPair:     keypair,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(cmd.OutOrStdout(), addr)
+	return nil
+}
+
+func findCreate2Salt(cmd *cobra.Command, flags *keysVanityFlags) error {
+	ctx := context.Background()
+	deployer, err := ethtypes.NewAddress(flags.create2Deployer)
+	if err != nil {
+		return err
+	}
+	initCodeHash, err := ethtypes.NewHexBytes0xPrefix(flags.create2InitCodeHash)
+	if err != nil {
+		return err
+	}
+	result, attempts, err := vanity.FindCreate2Salt(ctx, &flags.options, deployer, initCodeHash)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.ErrOrStderr(), "Found after %d attempts\n", attempts)
+	fmt.Fprintf(cmd.OutOrStdout(), "%s %s\n", result.Salt, &result.Address)
+	return nil
+}
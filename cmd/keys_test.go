@@ -0,0 +1,366 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/hyperledger/firefly-signer/pkg/fips"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeTestKeysConfig(t *testing.T, walletDir string, shardPrefixLength int) string {
+	configFile := path.Join(t.TempDir(), "ffsigner.yaml")
+	err := os.WriteFile(configFile, []byte(fmt.Sprintf(`fileWallet:
+  path: %q
+  filenames:
+    primaryExt: ".key.json"
+    passwordExt: ".pwd"
+    shardPrefixLength: %d
+`, walletDir, shardPrefixLength)), 0600)
+	assert.NoError(t, err)
+	return configFile
+}
+
+func TestKeysMigrateOK(t *testing.T) {
+	walletDir := t.TempDir()
+	for _, ext := range []string{".key.json", ".pwd"} {
+		b, err := os.ReadFile("../test/keystore_toml/1f185718734552d08278aa70f804580bab5fd2b4" + ext)
+		assert.NoError(t, err)
+		err = os.WriteFile(path.Join(walletDir, "1f185718734552d08278aa70f804580bab5fd2b4"+ext), b, 0600)
+		assert.NoError(t, err)
+	}
+
+	out := new(bytes.Buffer)
+	rootCmd.SetArgs([]string{"keys", "migrate", "-f", writeTestKeysConfig(t, walletDir, 2)})
+	rootCmd.SetOut(out)
+	defer func() {
+		rootCmd.SetArgs([]string{})
+		rootCmd.SetOut(nil)
+	}()
+
+	err := Execute()
+	assert.NoError(t, err)
+	assert.Equal(t, "Moved 1 files\n", out.String())
+	assert.FileExists(t, path.Join(walletDir, "1f", "1f185718734552d08278aa70f804580bab5fd2b4.key.json"))
+	assert.FileExists(t, path.Join(walletDir, "1f", "1f185718734552d08278aa70f804580bab5fd2b4.pwd"))
+}
+
+func TestKeysMigrateFail(t *testing.T) {
+	rootCmd.SetArgs([]string{"keys", "migrate", "-f", writeTestKeysConfig(t, t.TempDir(), 10)})
+	defer rootCmd.SetArgs([]string{})
+
+	err := Execute()
+	assert.Regexp(t, "FF22092", err)
+}
+
+func TestKeysMigrateNoWallet(t *testing.T) {
+	rootCmd.SetArgs([]string{"keys", "migrate", "-f", "../test/no-wallet.ffsigner.yaml"})
+	defer rootCmd.SetArgs([]string{})
+
+	err := Execute()
+	assert.Regexp(t, "FF22017", err)
+}
+
+func TestKeysMigrateBadConfig(t *testing.T) {
+	rootCmd.SetArgs([]string{"keys", "migrate", "-f", "../test/bad-config.ffsigner.yaml"})
+	defer rootCmd.SetArgs([]string{})
+
+	err := Execute()
+	assert.Regexp(t, "FF00101", err)
+}
+
+func writeTestCreateKeysConfig(t *testing.T) (string, string) {
+	walletDir := t.TempDir()
+	configFile := path.Join(t.TempDir(), "ffsigner.yaml")
+	err := os.WriteFile(configFile, []byte(fmt.Sprintf(`fileWallet:
+  path: %q
+  filenames:
+    primaryExt: ".key.json"
+    passwordExt: ".pwd"
+`, walletDir)), 0600)
+	assert.NoError(t, err)
+	return configFile, walletDir
+}
+
+func runKeysCreate(t *testing.T, stdin string, args ...string) (string, error) {
+	out := new(bytes.Buffer)
+	rootCmd.SetArgs(append([]string{"keys", "create", "--light"}, args...))
+	rootCmd.SetIn(strings.NewReader(stdin))
+	rootCmd.SetOut(out)
+	defer func() {
+		rootCmd.SetArgs([]string{})
+		rootCmd.SetIn(nil)
+		rootCmd.SetOut(nil)
+	}()
+	err := Execute()
+	return strings.TrimSpace(out.String()), err
+}
+
+func mockTerminal(t *testing.T, passwords ...string) {
+	origIsTerminal, origReadPassword := stdinIsTerminal, readTerminalPassword
+	stdinIsTerminal = func() bool { return true }
+	readTerminalPassword = func() ([]byte, error) {
+		if len(passwords) == 0 {
+			return nil, fmt.Errorf("pop")
+		}
+		password := passwords[0]
+		passwords = passwords[1:]
+		return []byte(password), nil
+	}
+	t.Cleanup(func() {
+		stdinIsTerminal, readTerminalPassword = origIsTerminal, origReadPassword
+	})
+}
+
+func TestKeysCreatePasswordFile(t *testing.T) {
+	configFile, walletDir := writeTestCreateKeysConfig(t)
+	passwordFile := path.Join(t.TempDir(), "password")
+	err := os.WriteFile(passwordFile, []byte("correcthorsebatterystaple\n"), 0600)
+	assert.NoError(t, err)
+
+	addr, err := runKeysCreate(t, "", "-f", configFile, "--password-file", passwordFile)
+	assert.NoError(t, err)
+	password, err := os.ReadFile(path.Join(walletDir, strings.TrimPrefix(addr, "0x")+".pwd"))
+	assert.NoError(t, err)
+	assert.Equal(t, "correcthorsebatterystaple", string(password))
+}
+
+func TestKeysCreateFIPS(t *testing.T) {
+	configFile, walletDir := writeTestCreateKeysConfig(t)
+	f, err := os.OpenFile(configFile, os.O_APPEND|os.O_WRONLY, 0600)
+	assert.NoError(t, err)
+	_, err = f.WriteString("fips:\n  enabled: true\n")
+	assert.NoError(t, err)
+	f.Close()
+	defer fips.SetEnabled(false)
+	passwordFile := path.Join(t.TempDir(), "password")
+	err = os.WriteFile(passwordFile, []byte("correcthorsebatterystaple\n"), 0600)
+	assert.NoError(t, err)
+
+	addr, err := runKeysCreate(t, "", "-f", configFile, "--password-file", passwordFile)
+	assert.NoError(t, err)
+	b, err := os.ReadFile(path.Join(walletDir, strings.TrimPrefix(addr, "0x")+".key.json"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), `"kdf":"pbkdf2"`)
+}
+
+func TestKeysCreatePasswordFileMissing(t *testing.T) {
+	configFile, _ := writeTestCreateKeysConfig(t)
+	_, err := runKeysCreate(t, "", "-f", configFile, "--password-file", path.Join(t.TempDir(), "missing"))
+	assert.Regexp(t, "FF22196", err)
+}
+
+func TestKeysCreateStdin(t *testing.T) {
+	configFile, walletDir := writeTestCreateKeysConfig(t)
+	addr, err := runKeysCreate(t, "correcthorsebatterystaple\r\nignored", "-f", configFile, "--password-file", "")
+	assert.NoError(t, err)
+	password, err := os.ReadFile(path.Join(walletDir, strings.TrimPrefix(addr, "0x")+".pwd"))
+	assert.NoError(t, err)
+	assert.Equal(t, "correcthorsebatterystaple", string(password))
+}
+
+func TestKeysCreateStdinFail(t *testing.T) {
+	configFile, _ := writeTestCreateKeysConfig(t)
+	rootCmd.SetArgs([]string{"keys", "create", "-f", configFile, "--password-file", ""})
+	rootCmd.SetIn(iotest.ErrReader(fmt.Errorf("pop")))
+	defer func() {
+		rootCmd.SetArgs([]string{})
+		rootCmd.SetIn(nil)
+	}()
+	err := Execute()
+	assert.Regexp(t, "pop", err)
+}
+
+func TestKeysCreateTerminal(t *testing.T) {
+	configFile, _ := writeTestCreateKeysConfig(t)
+	mockTerminal(t, "correcthorsebatterystaple", "correcthorsebatterystaple")
+	addr, err := runKeysCreate(t, "", "-f", configFile, "--password-file", "")
+	assert.NoError(t, err)
+	assert.Regexp(t, "^0x[0-9a-f]{40}$", addr)
+}
+
+func TestKeysCreateTerminalMismatch(t *testing.T) {
+	configFile, _ := writeTestCreateKeysConfig(t)
+	mockTerminal(t, "correcthorsebatterystaple", "wrong")
+	_, err := runKeysCreate(t, "", "-f", configFile, "--password-file", "")
+	assert.Regexp(t, "FF22197", err)
+}
+
+func TestKeysCreateTerminalFail(t *testing.T) {
+	configFile, _ := writeTestCreateKeysConfig(t)
+	mockTerminal(t)
+	_, err := runKeysCreate(t, "", "-f", configFile, "--password-file", "")
+	assert.Regexp(t, "pop", err)
+}
+
+func TestKeysCreateTerminalConfirmFail(t *testing.T) {
+	configFile, _ := writeTestCreateKeysConfig(t)
+	mockTerminal(t, "correcthorsebatterystaple")
+	_, err := runKeysCreate(t, "", "-f", configFile, "--password-file", "")
+	assert.Regexp(t, "pop", err)
+}
+
+func TestKeysCreateNoWallet(t *testing.T) {
+	_, err := runKeysCreate(t, "", "-f", "../test/no-wallet.ffsigner.yaml")
+	assert.Regexp(t, "FF22017", err)
+}
+
+func TestKeysCreateBadConfig(t *testing.T) {
+	_, err := runKeysCreate(t, "", "-f", "../test/bad-config.ffsigner.yaml")
+	assert.Regexp(t, "FF00101", err)
+}
+
+func TestKeysPasswdAddress(t *testing.T) {
+	configFile, walletDir := writeTestCreateKeysConfig(t)
+	addr, err := runKeysCreate(t, "correcthorsebatterystaple", "-f", configFile, "--password-file", "")
+	assert.NoError(t, err)
+
+	out, err := runCommand(t, "tr0ub4dor&3\n", "keys", "passwd", addr, "-f", configFile, "--light")
+	assert.NoError(t, err)
+	assert.Equal(t, addr, out)
+	password, err := os.ReadFile(path.Join(walletDir, strings.TrimPrefix(addr, "0x")+".pwd"))
+	assert.NoError(t, err)
+	assert.Equal(t, "tr0ub4dor&3", string(password))
+
+	oldPasswordFile := writeTestFile(t, "old", "tr0ub4dor&3\n")
+	newPasswordFile := writeTestFile(t, "new", "correcthorsebatterystaple\n")
+	_, err = runCommand(t, "", "keys", "passwd", addr, "-f", configFile, "--light",
+		"--old-password-file", oldPasswordFile, "--password-file", newPasswordFile)
+	assert.NoError(t, err)
+	password, err = os.ReadFile(path.Join(walletDir, strings.TrimPrefix(addr, "0x")+".pwd"))
+	assert.NoError(t, err)
+	assert.Equal(t, "correcthorsebatterystaple", string(password))
+}
+
+func TestKeysPasswdAddressFail(t *testing.T) {
+	configFile, _ := writeTestCreateKeysConfig(t)
+	_, err := runCommand(t, "", "keys", "passwd", testTxAddr, "-f", configFile, "--old-password-file", path.Join(t.TempDir(), "missing"))
+	assert.Regexp(t, "FF22014", err)
+
+	_, err = runCommand(t, "", "keys", "passwd", testTxAddr, "-f", "../test/no-wallet.ffsigner.yaml")
+	assert.Regexp(t, "FF22017", err)
+
+	_, err = runCommand(t, "", "keys", "passwd", testTxAddr, "-f", "../test/bad-config.ffsigner.yaml")
+	assert.Regexp(t, "FF00101", err)
+}
+
+func TestKeysPasswdAddressOldPasswordFileMissing(t *testing.T) {
+	configFile, _ := writeTestCreateKeysConfig(t)
+	addr, err := runKeysCreate(t, "correcthorsebatterystaple", "-f", configFile, "--password-file", "")
+	assert.NoError(t, err)
+
+	_, err = runCommand(t, "", "keys", "passwd", addr, "-f", configFile, "--old-password-file", path.Join(t.TempDir(), "missing"))
+	assert.Regexp(t, "FF22196", err)
+}
+
+func TestKeysPasswdKeystoreFileStdin(t *testing.T) {
+	b, err := os.ReadFile("../test/keystore_toml/1f185718734552d08278aa70f804580bab5fd2b4.key.json")
+	assert.NoError(t, err)
+	password, err := os.ReadFile("../test/keystore_toml/1f185718734552d08278aa70f804580bab5fd2b4.pwd")
+	assert.NoError(t, err)
+	keyFile := writeTestFile(t, "key.json", string(b))
+
+	out, err := runCommand(t, string(password)+"\ntr0ub4dor&3\n", "keys", "passwd", keyFile, "--light")
+	assert.NoError(t, err)
+	assert.Equal(t, testTxAddr, out)
+
+	mockTerminal(t, "tr0ub4dor&3", "correcthorsebatterystaple", "correcthorsebatterystaple")
+	out, err = runCommand(t, "", "keys", "passwd", keyFile, "--light")
+	assert.NoError(t, err)
+	assert.Equal(t, testTxAddr, out)
+
+	_, err = runCommand(t, "", "keys", "passwd", keyFile, "--old-password-file", writeTestFile(t, "old", "wrong"), "--password-file", writeTestFile(t, "new", "new"))
+	assert.Regexp(t, "FF22210", err)
+}
+
+func TestKeysPasswdKeystoreFileTerminalFail(t *testing.T) {
+	keyFile := writeTestFile(t, "key.json", "{}")
+	mockTerminal(t)
+	_, err := runCommand(t, "", "keys", "passwd", keyFile)
+	assert.Regexp(t, "pop", err)
+
+	mockTerminal(t, "correcthorsebatterystaple")
+	_, err = runCommand(t, "", "keys", "passwd", keyFile)
+	assert.Regexp(t, "pop", err)
+}
+
+func TestKeysPasswdKeystoreFileStdinFail(t *testing.T) {
+	rootCmd.SetArgs([]string{"keys", "passwd", writeTestFile(t, "key.json", "{}")})
+	rootCmd.SetIn(iotest.ErrReader(fmt.Errorf("pop")))
+	defer func() {
+		rootCmd.SetArgs([]string{})
+		rootCmd.SetIn(nil)
+	}()
+	err := Execute()
+	assert.Regexp(t, "pop", err)
+}
+
+func TestKeysVanity(t *testing.T) {
+	configFile, walletDir := writeTestCreateKeysConfig(t)
+	addr, err := runCommand(t, "correcthorsebatterystaple\n", "keys", "vanity", "-f", configFile, "--light", "--prefix", "0", "--suffix", "a")
+	assert.NoError(t, err)
+	assert.Regexp(t, "^0x0[0-9a-f]{38}a$", addr)
+	assert.FileExists(t, path.Join(walletDir, strings.TrimPrefix(addr, "0x")+".key.json"))
+}
+
+func TestKeysVanityBadPattern(t *testing.T) {
+	configFile, _ := writeTestCreateKeysConfig(t)
+	_, err := runCommand(t, "", "keys", "vanity", "-f", configFile)
+	assert.Regexp(t, "FF22272", err)
+}
+
+func TestKeysVanityCreateKeyFail(t *testing.T) {
+	configFile, _ := writeTestCreateKeysConfig(t)
+	_, err := runCommand(t, "", "keys", "vanity", "-f", configFile, "--prefix", "0", "--password-file", path.Join(t.TempDir(), "missing"))
+	assert.Regexp(t, "FF22196", err)
+}
+
+func TestKeysVanityNoWallet(t *testing.T) {
+	_, err := runCommand(t, "", "keys", "vanity", "-f", "../test/no-wallet.ffsigner.yaml", "--prefix", "0")
+	assert.Regexp(t, "FF22017", err)
+}
+
+func TestKeysVanityBadConfig(t *testing.T) {
+	_, err := runCommand(t, "", "keys", "vanity", "-f", "../test/bad-config.ffsigner.yaml", "--prefix", "0")
+	assert.Regexp(t, "FF00101", err)
+}
+
+func TestKeysVanityCreate2(t *testing.T) {
+	out, err := runCommand(t, "", "keys", "vanity", "--prefix", "0",
+		"--create2-deployer", "0x4e59b44847b379578588920ca78fbf26c0b4956c",
+		"--create2-init-code-hash", "0xbc36789e7a1e281436464229828f817d6612f7b477d66591ff96a9e064bcc98a")
+	assert.NoError(t, err)
+	assert.Regexp(t, "^0x[0-9a-f]{64} 0x0[0-9a-f]{39}$", out)
+}
+
+func TestKeysVanityCreate2Errors(t *testing.T) {
+	_, err := runCommand(t, "", "keys", "vanity", "--prefix", "0", "--create2-deployer", "wrong")
+	assert.Regexp(t, "bad address", err)
+
+	_, err = runCommand(t, "", "keys", "vanity", "--prefix", "0", "--create2-deployer", "0x4e59b44847b379578588920ca78fbf26c0b4956c", "--create2-init-code-hash", "wrong")
+	assert.Error(t, err)
+
+	_, err = runCommand(t, "", "keys", "vanity", "--prefix", "0", "--create2-deployer", "0x4e59b44847b379578588920ca78fbf26c0b4956c", "--create2-init-code-hash", "0x00")
+	assert.Regexp(t, "FF22276", err)
+}
@@ -0,0 +1,154 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-signer/internal/signerconfig"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/fakechain"
+	"github.com/hyperledger/firefly-signer/pkg/loadtest"
+	"github.com/hyperledger/firefly-signer/pkg/secp256k1"
+	"github.com/spf13/cobra"
+)
+
+type loadTestFlags struct {
+	requests     int
+	duration     time.Duration
+	concurrency  int
+	from         []string
+	to           string
+	abiFile      string
+	method       string
+	params       string
+	submit       bool
+	chainID      int64
+	passwordFile string
+}
+
+func loadTestCommand() *cobra.Command {
+	var flags loadTestFlags
+	loadTestCmd := &cobra.Command{
+		Use:   "loadtest",
+		Short: "Measures the signing throughput of the file wallet, and prints the latency percentiles and allocations as JSON",
+		Long: `Signs transactions with keys from the file wallet at a configurable concurrency, and prints the throughput,
+latency percentiles and allocations per request as JSON, for capacity planning and to catch regressions.
+Every request resolves the signing key, ABI encodes the call with --abi, --method and --params (if set),
+and signs the transaction. With --submit, each signed transaction is also sent to an in-process fake node,
+so the cost of submission is included without a real node.
+The keys are those in the wallet, or the --from addresses, used in turn. The test stops after --requests,
+or after --duration if that is reached first.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, err := readCommandConfig()
+			if err != nil {
+				return err
+			}
+			result, err := runLoadTest(ctx, &flags)
+			if err != nil {
+				return err
+			}
+			b, _ := json.MarshalIndent(result, "", "  ")
+			fmt.Fprintln(cmd.OutOrStdout(), string(b))
+			return nil
+		},
+	}
+	loadTestCmd.Flags().IntVar(&flags.requests, "requests", loadtest.DefaultRequests, "number of transactions to sign")
+	loadTestCmd.Flags().DurationVar(&flags.duration, "duration", 0, "maximum time to run for, such as 30s")
+	loadTestCmd.Flags().IntVar(&flags.concurrency, "concurrency", 0, "number of requests in flight at once (defaults to the number of CPUs)")
+	loadTestCmd.Flags().StringArrayVar(&flags.from, "from", nil, "address to sign with (can be repeated - defaults to every key in the wallet)")
+	loadTestCmd.Flags().StringVar(&flags.to, "to", "", "address to send the transactions to")
+	loadTestCmd.Flags().StringVar(&flags.abiFile, "abi", "", "ABI file containing the method to call")
+	loadTestCmd.Flags().StringVar(&flags.method, "method", "", "name or signature of the method in the ABI to call")
+	loadTestCmd.Flags().StringVar(&flags.params, "params", "[]", "JSON parameters of the method call (an object, or an array)")
+	loadTestCmd.Flags().BoolVar(&flags.submit, "submit", false, "submit each signed transaction to an in-process fake node")
+	loadTestCmd.Flags().Int64Var(&flags.chainID, "chain-id", -1, "chain ID to sign for (default backend.chainId)")
+	loadTestCmd.Flags().StringVarP(&flags.passwordFile, "password-file", "p", "", "file containing the password for the keys, instead of the configured password files")
+	loadTestCmd.MarkFlagsRequiredTogether("abi", "method")
+	return loadTestCmd
+}
+
+func runLoadTest(ctx context.Context, flags *loadTestFlags) (*loadtest.Result, error) {
+	options := &loadtest.Options{
+		ChainID:     flags.chainID,
+		Params:      []byte(flags.params),
+		Concurrency: flags.concurrency,
+		Requests:    flags.requests,
+		Duration:    flags.duration,
+	}
+	if options.ChainID < 0 {
+		options.ChainID = config.GetInt64(signerconfig.BackendChainID)
+	}
+	if options.ChainID < 0 {
+		return nil, i18n.NewError(ctx, signermsgs.MsgChainIDRequired)
+	}
+	for _, from := range flags.from {
+		addr, err := ethtypes.NewAddress(from)
+		if err != nil {
+			return nil, err
+		}
+		options.From = append(options.From, addr)
+	}
+	if flags.to != "" {
+		var err error
+		if options.To, err = ethtypes.NewAddress(flags.to); err != nil {
+			return nil, err
+		}
+	}
+	if flags.abiFile != "" {
+		var err error
+		if options.Method, err = readABIEntry(ctx, flags.abiFile, flags.method); err != nil {
+			return nil, err
+		}
+	}
+	if flags.submit {
+		options.Backend = fakechain.New(&fakechain.Options{ChainID: options.ChainID})
+	}
+
+	w, err := openCommandWallet(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer w.Close()
+	options.Wallet = w
+	if flags.passwordFile != "" {
+		password, err := readPasswordFile(ctx, flags.passwordFile)
+		if err != nil {
+			return nil, err
+		}
+		defer secp256k1.ZeroizeBytes(password)
+		accounts := options.From
+		if len(accounts) == 0 {
+			if accounts, err = w.GetAccounts(ctx); err != nil {
+				return nil, err
+			}
+		}
+		for _, addr := range accounts {
+			if err := w.Unlock(ctx, *addr, password, 0); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return loadtest.Run(ctx, options)
+}
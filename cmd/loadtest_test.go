@@ -0,0 +1,94 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hyperledger/firefly-signer/pkg/loadtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func runLoadTestCommand(t *testing.T, args ...string) (*loadtest.Result, error) {
+	out, err := runCommand(t, "", append([]string{"loadtest"}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	var result loadtest.Result
+	err = json.Unmarshal([]byte(out), &result)
+	assert.NoError(t, err)
+	return &result, nil
+}
+
+func TestLoadTestSubmit(t *testing.T) {
+	configFile := writeTestTxConfig(t, "backend:\n  chainId: 1337\n")
+	abiFile := writeTestFile(t, "abi.json", testABI)
+	result, err := runLoadTestCommand(t, "-f", configFile,
+		"--requests", "20", "--concurrency", "2", "--submit",
+		"--to", "0x497eedc4299dea2f2a364be10025d0ad0f702de3",
+		"--abi", abiFile, "--method", "transfer",
+		"--params", `{"to":"0x497eedc4299dea2f2a364be10025d0ad0f702de3","value":"1000"}`)
+	assert.NoError(t, err)
+	assert.Equal(t, 20, result.Requests)
+	assert.Zero(t, result.Errors)
+	assert.Equal(t, 2, result.Concurrency)
+	assert.NotNil(t, result.Latency.P99)
+}
+
+func TestLoadTestUnlockFrom(t *testing.T) {
+	configFile := writeTestTxConfig(t, "  requireUnlock: true\n")
+	for _, from := range [][]string{{"--from", testTxAddr}, nil} {
+		result, err := runLoadTestCommand(t, append([]string{"-f", configFile, "--chain-id", "2024", "--requests", "5",
+			"--password-file", "../test/keystore_toml/1f185718734552d08278aa70f804580bab5fd2b4.pwd"}, from...)...)
+		assert.NoError(t, err)
+		assert.Equal(t, 5, result.Requests)
+		assert.Zero(t, result.Errors)
+	}
+}
+
+func TestLoadTestFail(t *testing.T) {
+	configFile := writeTestTxConfig(t, "")
+	abiFile := writeTestFile(t, "abi.json", testABI)
+
+	_, err := runLoadTestCommand(t, "-f", configFile)
+	assert.Regexp(t, "FF22198", err)
+
+	_, err = runLoadTestCommand(t, "-f", configFile, "--chain-id", "1", "--from", "wrong")
+	assert.Regexp(t, "bad address", err)
+
+	_, err = runLoadTestCommand(t, "-f", configFile, "--chain-id", "1", "--to", "wrong")
+	assert.Regexp(t, "bad address", err)
+
+	_, err = runLoadTestCommand(t, "-f", configFile, "--chain-id", "1", "--abi", abiFile, "--method", "burn")
+	assert.Regexp(t, "FF22203", err)
+
+	_, err = runLoadTestCommand(t, "-f", configFile, "--chain-id", "1", "--abi", abiFile)
+	assert.Regexp(t, "method", err)
+
+	_, err = runLoadTestCommand(t, "-f", configFile, "--chain-id", "1", "--password-file", "missing")
+	assert.Regexp(t, "FF22196", err)
+
+	_, err = runLoadTestCommand(t, "-f", configFile, "--chain-id", "1", "--password-file", configFile)
+	assert.Regexp(t, "FF22015", err)
+
+	_, err = runLoadTestCommand(t, "-f", "../test/no-wallet.ffsigner.yaml", "--chain-id", "1")
+	assert.Regexp(t, "FF22017", err)
+
+	_, err = runLoadTestCommand(t, "-f", "../test/bad-config.ffsigner.yaml")
+	assert.Regexp(t, "FF00101", err)
+}
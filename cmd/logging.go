@@ -0,0 +1,45 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+
+	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/hyperledger/firefly-common/pkg/log"
+	"github.com/hyperledger/firefly-signer/internal/signerconfig"
+	"github.com/hyperledger/firefly-signer/pkg/debuglog"
+)
+
+// debugLogging is true while debug logging is switched on by a signal
+var debugLogging bool
+
+// toggleDebugLogging switches to debug logging with every module enabled, or back to the logging in the
+// configuration, so a production signer can be investigated without restarting it
+func toggleDebugLogging(ctx context.Context) {
+	debugLogging = !debugLogging
+	if debugLogging {
+		log.SetLevel("debug")
+		debuglog.SetModules(debuglog.Modules)
+		log.L(ctx).Infof("Debug logging enabled for modules %v", debuglog.Modules)
+		return
+	}
+	log.SetLevel(config.GetString(config.LogLevel))
+	modules, _ := debuglog.ParseModules(ctx, config.GetStringSlice(signerconfig.LogModules)) // validated at startup
+	debuglog.SetModules(modules)
+	log.L(ctx).Infof("Debug logging disabled")
+}
@@ -0,0 +1,44 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/hyperledger/firefly-common/pkg/log"
+	"github.com/hyperledger/firefly-signer/internal/signerconfig"
+	"github.com/hyperledger/firefly-signer/pkg/debuglog"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToggleDebugLogging(t *testing.T) {
+	initConfig()
+	config.Set(signerconfig.LogModules, []string{"abi"})
+	defer debuglog.SetModules(nil)
+	defer log.SetLevel("info")
+
+	toggleDebugLogging(context.Background())
+	assert.Equal(t, logrus.DebugLevel, logrus.GetLevel())
+	assert.Equal(t, debuglog.Modules, debuglog.EnabledModules())
+
+	toggleDebugLogging(context.Background())
+	assert.Equal(t, logrus.InfoLevel, logrus.GetLevel())
+	assert.Equal(t, []debuglog.Module{debuglog.ABIDecode}, debuglog.EnabledModules())
+}
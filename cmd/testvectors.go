@@ -0,0 +1,72 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/firefly-signer/pkg/testvectors"
+	"github.com/spf13/cobra"
+)
+
+func testVectorsCommand() *cobra.Command {
+	testVectorsCmd := &cobra.Command{
+		Use:   "testvectors",
+		Short: "Generate golden test vectors, for other implementations to validate against",
+		Long:  "",
+	}
+	testVectorsCmd.AddCommand(testVectorsGenerateCommand())
+	return testVectorsCmd
+}
+
+func testVectorsGenerateCommand() *cobra.Command {
+	generateCmd := &cobra.Command{
+		Use:   "generate [fixtures file]",
+		Short: "Generates ABI encodings, RLP payloads, EIP-712 digests and signed transactions from YAML fixtures",
+		Long: `Generates ABI encodings, RLP payloads, EIP-712 digests and signed transactions from YAML (or JSON) fixtures,
+and prints the vectors as JSON. The fixtures are read from the file, or from stdin.
+See test/testvectors/fixtures.yaml for an example of every type of vector.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			b, err := generateTestVectors(context.Background(), cmd, args)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), string(b))
+			return nil
+		},
+	}
+	return generateCmd
+}
+
+func generateTestVectors(ctx context.Context, cmd *cobra.Command, args []string) ([]byte, error) {
+	input, err := readCommandInput(ctx, cmd, args)
+	if err != nil {
+		return nil, err
+	}
+	fixtures, err := testvectors.ParseFixtures(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	vectors, err := testvectors.Generate(ctx, fixtures)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(vectors, "", "  ")
+}
@@ -0,0 +1,50 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hyperledger/firefly-signer/pkg/testvectors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTestVectorsGenerate(t *testing.T) {
+	out, err := runCommand(t, "", "testvectors", "generate", "../test/testvectors/fixtures.yaml")
+	assert.NoError(t, err)
+	var vectors testvectors.Vectors
+	err = json.Unmarshal([]byte(out), &vectors)
+	assert.NoError(t, err)
+	assert.Equal(t, "0x2c7536e3605d9c16a7a3d7b1898e529396a65c23", vectors.Keys["alice"].String())
+	assert.Equal(t, "abi-erc20-transfer", vectors.Vectors[0].Name)
+
+	out, err = runCommand(t, `{"vectors":[{"name":"list","rlp":["0x01"]}]}`, "testvectors", "generate")
+	assert.NoError(t, err)
+	assert.Contains(t, out, `"encoded": "0xc101"`)
+}
+
+func TestTestVectorsGenerateFail(t *testing.T) {
+	_, err := runCommand(t, "", "testvectors", "generate", "../test/testvectors/missing.yaml")
+	assert.Regexp(t, "FF22199", err)
+
+	_, err = runCommand(t, `vectors: wrong`, "testvectors", "generate")
+	assert.Regexp(t, "FF22229", err)
+
+	_, err = runCommand(t, `{"vectors":[{"name":"empty"}]}`, "testvectors", "generate")
+	assert.Regexp(t, "FF22230", err)
+}
@@ -0,0 +1,53 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/hyperledger/firefly-signer/internal/signerconfig"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func TestInitTracingDisabled(t *testing.T) {
+	signerconfig.Reset()
+
+	shutdown := initTracing()
+	assert.NoError(t, shutdown(context.Background()))
+}
+
+func TestInitTracingEnabled(t *testing.T) {
+	origTP, origPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	defer func() {
+		otel.SetTracerProvider(origTP)
+		otel.SetTextMapPropagator(origPropagator)
+	}()
+
+	signerconfig.Reset()
+	config.Set(signerconfig.TracingEnabled, true)
+	config.Set(signerconfig.TracingEndpoint, "http://127.0.0.1:4318")
+
+	shutdown := initTracing()
+	assert.IsType(t, &sdktrace.TracerProvider{}, otel.GetTracerProvider())
+	assert.ElementsMatch(t, append(propagation.TraceContext{}.Fields(), propagation.Baggage{}.Fields()...), otel.GetTextMapPropagator().Fields())
+	assert.NoError(t, shutdown(context.Background()))
+}
@@ -0,0 +1,284 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-signer/internal/signerconfig"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/hyperledger/firefly-signer/pkg/abi"
+	"github.com/hyperledger/firefly-signer/pkg/ethsigner"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/fswallet"
+	"github.com/hyperledger/firefly-signer/pkg/secp256k1"
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/sha3"
+)
+
+func txCommand() *cobra.Command {
+	txCmd := &cobra.Command{
+		Use:   "tx",
+		Short: "Sign and inspect transactions offline",
+		Long:  "",
+	}
+	txCmd.AddCommand(txSignCommand())
+	txCmd.AddCommand(txDecodeCommand())
+	return txCmd
+}
+
+type txSignFlags struct {
+	from         string
+	chainID      int64
+	legacy       bool
+	passwordFile string
+}
+
+func txSignCommand() *cobra.Command {
+	var flags txSignFlags
+	signCmd := &cobra.Command{
+		Use:   "sign [transaction JSON file]",
+		Short: "Signs a transaction with a key from the file wallet, and prints the raw signed transaction as hex",
+		Long: `Signs a transaction with a key from the file wallet, and prints the raw signed transaction as hex.
+The transaction is read as JSON from the file, or from stdin, with the same fields as eth_sendTransaction.
+It is signed as an EIP-1559 transaction if maxFeePerGas or maxPriorityFeePerGas is set, otherwise as an
+EIP-155 legacy transaction (or an original legacy transaction without a chain ID, with --legacy).
+No HTTP server or backend is needed, so this can be used for air-gapped signing.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, err := readCommandConfig()
+			if err != nil {
+				return err
+			}
+			input, err := readCommandInput(ctx, cmd, args)
+			if err != nil {
+				return err
+			}
+			rawTx, err := signTransaction(ctx, input, &flags)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), rawTx)
+			return nil
+		},
+	}
+	signCmd.Flags().StringVar(&flags.from, "from", "", "address to sign with, if not set in the transaction")
+	signCmd.Flags().Int64Var(&flags.chainID, "chain-id", -1, "chain ID to sign for (default backend.chainId)")
+	signCmd.Flags().BoolVar(&flags.legacy, "legacy", false, "sign an original legacy transaction, without EIP-155 replay protection")
+	signCmd.Flags().StringVarP(&flags.passwordFile, "password-file", "p", "", "file containing the password for the key, instead of the configured password files")
+	return signCmd
+}
+
+func signTransaction(ctx context.Context, input []byte, flags *txSignFlags) (ethtypes.HexBytes0xPrefix, error) {
+	var tx ethsigner.Transaction
+	if err := json.Unmarshal(input, &tx); err != nil {
+		return nil, i18n.NewError(ctx, signermsgs.MsgInvalidTransactionJSON, err)
+	}
+	if flags.from != "" {
+		tx.From = json.RawMessage(fmt.Sprintf("%q", flags.from))
+	}
+	var from ethtypes.Address0xHex
+	if len(tx.From) == 0 {
+		return nil, i18n.NewError(ctx, signermsgs.MsgMissingFrom)
+	}
+	if err := json.Unmarshal(tx.From, &from); err != nil {
+		return nil, i18n.NewError(ctx, signermsgs.MsgInvalidTransactionJSON, err)
+	}
+	chainID := flags.chainID
+	if chainID < 0 {
+		chainID = config.GetInt64(signerconfig.BackendChainID)
+	}
+	if chainID < 0 && !flags.legacy {
+		return nil, i18n.NewError(ctx, signermsgs.MsgChainIDRequired)
+	}
+
+	var password []byte
+	if flags.passwordFile != "" {
+		var err error
+		if password, err = readPasswordFile(ctx, flags.passwordFile); err != nil {
+			return nil, err
+		}
+		defer secp256k1.ZeroizeBytes(password)
+	}
+	w, err := openCommandWallet(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer w.Close()
+	// Unlocking loads the key even when the wallet requires keys to be unlocked, and the key is
+	// zeroized when the wallet is closed
+	if err := w.Unlock(ctx, from, password, 0); err != nil {
+		return nil, err
+	}
+	if flags.legacy {
+		return signLegacyOriginal(ctx, w, from, &tx)
+	}
+	return w.Sign(ctx, &tx, chainID)
+}
+
+func signLegacyOriginal(ctx context.Context, w fswallet.Wallet, from ethtypes.Address0xHex, tx *ethsigner.Transaction) ([]byte, error) {
+	kv3, err := w.GetWalletFile(ctx, from)
+	if err != nil {
+		return nil, err
+	}
+	keypair := kv3.KeyPair()
+	defer keypair.Zeroize()
+	var signer secp256k1.Signer = keypair
+	if signerconfig.FileWalletConfig.GetBool(fswallet.ConfigExtraEntropy) {
+		signer = secp256k1.NewExtraEntropySigner(keypair)
+	}
+	return tx.SignLegacyOriginal(signer)
+}
+
+type decodedTransaction struct {
+	Type        byte                      `json:"type"`
+	ChainID     *int64                    `json:"chainId,omitempty"`
+	Hash        ethtypes.HexBytes0xPrefix `json:"hash"`
+	From        *ethtypes.Address0xHex    `json:"from"`
+	Transaction *ethsigner.Transaction    `json:"transaction"`
+	Call        *decodedCall              `json:"call,omitempty"`
+}
+
+type decodedCall struct {
+	Method string          `json:"method"`
+	Inputs json.RawMessage `json:"inputs"`
+}
+
+func txDecodeCommand() *cobra.Command {
+	var abiFiles []string
+	decodeCmd := &cobra.Command{
+		Use:   "decode [raw transaction hex]",
+		Short: "Decodes a raw signed transaction, recovering the sender and decoding the method call",
+		Long: `Decodes a raw signed transaction, supplied as hex in the argument or on stdin, and prints the fields,
+chain ID, hash and recovered sender as JSON. When ABI files are supplied (either a JSON ABI array,
+or a compiled contract JSON with an "abi" field), the method call is decoded from the data.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			input, err := readCommandArg(ctx, cmd, args, 0)
+			if err != nil {
+				return err
+			}
+			decoded, err := decodeTransaction(ctx, input, abiFiles)
+			if err != nil {
+				return err
+			}
+			b, _ := json.MarshalIndent(decoded, "", "  ")
+			fmt.Fprintln(cmd.OutOrStdout(), string(b))
+			return nil
+		},
+	}
+	decodeCmd.Flags().StringArrayVar(&abiFiles, "abi", nil, "ABI file to decode the method call with (can be repeated)")
+	return decodeCmd
+}
+
+func decodeTransaction(ctx context.Context, rawTxHex string, abiFiles []string) (*decodedTransaction, error) {
+	rawTx, err := ethtypes.NewHexBytes0xPrefix(rawTxHex)
+	if err != nil {
+		return nil, i18n.NewError(ctx, signermsgs.MsgInvalidRawTransactionHex, err)
+	}
+	chainID, err := ethsigner.RawTransactionChainID(ctx, rawTx)
+	if err != nil {
+		return nil, err
+	}
+	from, tx, err := ethsigner.RecoverRawTransaction(ctx, rawTx, chainID)
+	if err != nil {
+		return nil, err
+	}
+	hash := sha3.NewLegacyKeccak256()
+	hash.Write(rawTx)
+	decoded := &decodedTransaction{
+		Type:        ethsigner.TransactionTypeLegacy,
+		Hash:        hash.Sum(nil),
+		From:        from,
+		Transaction: tx.Transaction,
+	}
+	if rawTx[0] == ethsigner.TransactionType1559 {
+		decoded.Type = ethsigner.TransactionType1559
+	}
+	if chainID >= 0 {
+		decoded.ChainID = &chainID
+	}
+	for _, abiFile := range abiFiles {
+		a, err := readABIFile(ctx, abiFile)
+		if err != nil {
+			return nil, err
+		}
+		if decoded.Call, err = decodeCall(ctx, a, tx.Data); err != nil || decoded.Call != nil {
+			return decoded, err
+		}
+	}
+	return decoded, nil
+}
+
+// decodeCall decodes call data against the function in the ABI with a matching selector, if there is one
+func decodeCall(ctx context.Context, a abi.ABI, data []byte) (*decodedCall, error) {
+	if len(data) < 4 {
+		return nil, nil
+	}
+	for _, e := range a {
+		if !e.IsFunction() || !bytes.Equal(e.FunctionSelectorBytes(), data[0:4]) {
+			continue
+		}
+		cv, err := e.DecodeCallDataCtx(ctx, data)
+		if err != nil {
+			return nil, err
+		}
+		// Hex is 0x prefixed, to match the transaction fields
+		inputs, err := abi.NewSerializer().
+			SetByteSerializer(abi.HexByteSerializer0xPrefix).
+			SetAddressSerializer(abi.HexAddrSerializer0xPrefix).
+			SerializeJSONCtx(ctx, cv)
+		if err != nil {
+			return nil, err
+		}
+		method, _ := e.SignatureCtx(ctx)
+		return &decodedCall{Method: method, Inputs: inputs}, nil
+	}
+	return nil, nil
+}
+
+// readABIFile reads a JSON ABI array, or the "abi" field of a compiled contract JSON
+func readABIFile(ctx context.Context, filename string) (abi.ABI, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, i18n.NewError(ctx, signermsgs.MsgInvalidABIFile, filename, err)
+	}
+	var a abi.ABI
+	if bytes.HasPrefix(bytes.TrimSpace(b), []byte("{")) {
+		var compiled struct {
+			ABI abi.ABI `json:"abi"`
+		}
+		err = json.Unmarshal(b, &compiled)
+		a = compiled.ABI
+	} else {
+		err = json.Unmarshal(b, &a)
+	}
+	if err == nil {
+		err = a.ValidateCtx(ctx)
+	}
+	if err != nil {
+		return nil, i18n.NewError(ctx, signermsgs.MsgInvalidABIFile, filename, err)
+	}
+	return a, nil
+}
@@ -0,0 +1,378 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/hyperledger/firefly-signer/pkg/abi"
+	"github.com/hyperledger/firefly-signer/pkg/ethsigner"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/secp256k1"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+)
+
+const testTxAddr = "0x1f185718734552d08278aa70f804580bab5fd2b4"
+
+func writeTestTxConfig(t *testing.T, extraYAML string) string {
+	walletDir := t.TempDir()
+	for _, ext := range []string{".key.json", ".pwd"} {
+		b, err := os.ReadFile("../test/keystore_toml/1f185718734552d08278aa70f804580bab5fd2b4" + ext)
+		assert.NoError(t, err)
+		err = os.WriteFile(path.Join(walletDir, "1f185718734552d08278aa70f804580bab5fd2b4"+ext), b, 0600)
+		assert.NoError(t, err)
+	}
+	configFile := path.Join(t.TempDir(), "ffsigner.yaml")
+	err := os.WriteFile(configFile, []byte(fmt.Sprintf(`fileWallet:
+  path: %q
+  filenames:
+    primaryExt: ".key.json"
+    passwordExt: ".pwd"
+%s`, walletDir, extraYAML)), 0600)
+	assert.NoError(t, err)
+	return configFile
+}
+
+// resetFlags sets the flags of a command and its sub-commands back to their defaults, as cobra
+// retains them between executions
+func resetFlags(cmd *cobra.Command) {
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		if sv, ok := f.Value.(pflag.SliceValue); ok {
+			_ = sv.Replace(nil)
+		} else {
+			_ = f.Value.Set(f.DefValue)
+		}
+		f.Changed = false
+	})
+	for _, c := range cmd.Commands() {
+		resetFlags(c)
+	}
+}
+
+func runCommand(t *testing.T, stdin string, args ...string) (string, error) {
+	out := new(bytes.Buffer)
+	rootCmd.SetArgs(args)
+	rootCmd.SetIn(strings.NewReader(stdin))
+	rootCmd.SetOut(out)
+	defer func() {
+		rootCmd.SetArgs([]string{})
+		rootCmd.SetIn(nil)
+		rootCmd.SetOut(nil)
+		resetFlags(rootCmd)
+	}()
+	err := Execute()
+	return strings.TrimSpace(out.String()), err
+}
+
+func runTxSign(t *testing.T, stdin string, args ...string) (string, error) {
+	return runCommand(t, stdin, append([]string{"tx", "sign"}, args...)...)
+}
+
+func TestTxSignEIP1559(t *testing.T) {
+	configFile := writeTestTxConfig(t, "backend:\n  chainId: 1337\n")
+	txFile := path.Join(t.TempDir(), "tx.json")
+	err := os.WriteFile(txFile, []byte(`{
+		"from": "`+testTxAddr+`",
+		"nonce": "0x1",
+		"maxFeePerGas": "0x2540be400",
+		"maxPriorityFeePerGas": "0x3b9aca00",
+		"gas": "0x5208",
+		"to": "0x497eedc4299dea2f2a364be10025d0ad0f702de3",
+		"value": "0x100"
+	}`), 0600)
+	assert.NoError(t, err)
+
+	rawTx, err := runTxSign(t, "", txFile, "-f", configFile)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(rawTx, "0x02"))
+
+	from, tx, err := ethsigner.RecoverRawTransaction(context.Background(), ethtypes.MustNewHexBytes0xPrefix(rawTx), 1337)
+	assert.NoError(t, err)
+	assert.Equal(t, testTxAddr, from.String())
+	assert.Equal(t, int64(256), tx.Value.BigInt().Int64())
+}
+
+func TestTxSignEIP155Stdin(t *testing.T) {
+	configFile := writeTestTxConfig(t, "  requireUnlock: true\n")
+	rawTx, err := runTxSign(t, `{"nonce":"0x0","gasPrice":"0x0","gas":"0x5208","to":"0x497eedc4299dea2f2a364be10025d0ad0f702de3"}`,
+		"-", "-f", configFile, "--from", testTxAddr, "--chain-id", "2024",
+		"--password-file", "../test/keystore_toml/1f185718734552d08278aa70f804580bab5fd2b4.pwd")
+	assert.NoError(t, err)
+
+	from, _, err := ethsigner.RecoverLegacyRawTransaction(context.Background(), ethtypes.MustNewHexBytes0xPrefix(rawTx), 2024)
+	assert.NoError(t, err)
+	assert.Equal(t, testTxAddr, from.String())
+}
+
+func TestTxSignLegacyOriginal(t *testing.T) {
+	configFile := writeTestTxConfig(t, "")
+	for _, extraEntropy := range []bool{false, true} {
+		if extraEntropy {
+			configFile = writeTestTxConfig(t, "  extraEntropy: true\n")
+		}
+		rawTx, err := runTxSign(t, `{"from":"`+testTxAddr+`","nonce":"0x0","gasPrice":"0x0","gas":"0x5208"}`,
+			"-f", configFile, "--legacy")
+		assert.NoError(t, err)
+
+		from, _, err := ethsigner.RecoverLegacyRawTransaction(context.Background(), ethtypes.MustNewHexBytes0xPrefix(rawTx), -1)
+		assert.NoError(t, err)
+		assert.Equal(t, testTxAddr, from.String())
+	}
+}
+
+func TestTxSignBadJSON(t *testing.T) {
+	configFile := writeTestTxConfig(t, "")
+	_, err := runTxSign(t, `{`, "-f", configFile)
+	assert.Regexp(t, "FF22200", err)
+}
+
+func TestTxSignBadFrom(t *testing.T) {
+	configFile := writeTestTxConfig(t, "")
+	_, err := runTxSign(t, `{"from":"wrong"}`, "-f", configFile)
+	assert.Regexp(t, "FF22200", err)
+}
+
+func TestTxSignMissingFrom(t *testing.T) {
+	configFile := writeTestTxConfig(t, "")
+	_, err := runTxSign(t, `{}`, "-f", configFile)
+	assert.Regexp(t, "FF22020", err)
+}
+
+func TestTxSignNoChainID(t *testing.T) {
+	configFile := writeTestTxConfig(t, "")
+	_, err := runTxSign(t, `{"from":"`+testTxAddr+`"}`, "-f", configFile)
+	assert.Regexp(t, "FF22198", err)
+}
+
+func TestTxSignBadPasswordFile(t *testing.T) {
+	configFile := writeTestTxConfig(t, "")
+	_, err := runTxSign(t, `{"from":"`+testTxAddr+`"}`, "-f", configFile, "--legacy", "--password-file", path.Join(t.TempDir(), "missing"))
+	assert.Regexp(t, "FF22196", err)
+}
+
+func TestTxSignWrongPassword(t *testing.T) {
+	configFile := writeTestTxConfig(t, "")
+	passwordFile := path.Join(t.TempDir(), "password")
+	err := os.WriteFile(passwordFile, []byte("wrong"), 0600)
+	assert.NoError(t, err)
+	_, err = runTxSign(t, `{"from":"`+testTxAddr+`"}`, "-f", configFile, "--legacy", "--password-file", passwordFile)
+	assert.Regexp(t, "FF22015", err)
+}
+
+func TestTxSignUnknownKey(t *testing.T) {
+	configFile := writeTestTxConfig(t, "")
+	_, err := runTxSign(t, `{"from":"0x497eedc4299dea2f2a364be10025d0ad0f702de3"}`, "-f", configFile, "--legacy")
+	assert.Regexp(t, "FF22014", err)
+}
+
+func TestTxSignNoWallet(t *testing.T) {
+	_, err := runTxSign(t, `{"from":"`+testTxAddr+`"}`, "-f", "../test/no-wallet.ffsigner.yaml", "--legacy")
+	assert.Regexp(t, "FF22017", err)
+}
+
+func TestTxSignBadWalletConfig(t *testing.T) {
+	_, err := runTxSign(t, `{"from":"`+testTxAddr+`"}`, "-f", "../test/bad-wallet.ffsigner.yaml", "--legacy")
+	assert.Error(t, err)
+}
+
+func TestTxSignWalletInitFail(t *testing.T) {
+	configFile := path.Join(t.TempDir(), "ffsigner.yaml")
+	err := os.WriteFile(configFile, []byte(fmt.Sprintf("fileWallet:\n  path: %q\n", path.Join(t.TempDir(), "missing"))), 0600)
+	assert.NoError(t, err)
+	_, err = runTxSign(t, `{"from":"`+testTxAddr+`"}`, "-f", configFile, "--legacy")
+	assert.Regexp(t, "FF22013", err)
+}
+
+func TestTxSignMissingFile(t *testing.T) {
+	configFile := writeTestTxConfig(t, "")
+	_, err := runTxSign(t, "", path.Join(t.TempDir(), "missing"), "-f", configFile)
+	assert.Regexp(t, "FF22199", err)
+}
+
+func TestTxSignBadConfig(t *testing.T) {
+	_, err := runTxSign(t, "", "-f", "../test/bad-config.ffsigner.yaml")
+	assert.Regexp(t, "FF00101", err)
+}
+
+const testTransferABI = `[{
+	"type": "function",
+	"name": "transfer",
+	"inputs": [
+		{"name": "to", "type": "address"},
+		{"name": "value", "type": "uint256"}
+	],
+	"outputs": [{"name": "", "type": "bool"}]
+}]`
+
+func testTransferTx(t *testing.T) *ethsigner.Transaction {
+	var a abi.ABI
+	err := json.Unmarshal([]byte(testTransferABI), &a)
+	assert.NoError(t, err)
+	data, err := a.Functions()["transfer"].EncodeCallDataJSON([]byte(`{"to":"0x497eedc4299dea2f2a364be10025d0ad0f702de3","value":"1000"}`))
+	assert.NoError(t, err)
+	return &ethsigner.Transaction{
+		Nonce:    ethtypes.NewHexInteger64(1),
+		GasPrice: ethtypes.NewHexInteger64(0),
+		GasLimit: ethtypes.NewHexInteger64(100000),
+		To:       ethtypes.MustNewAddress("0x5d093e9b41911be5f5c4cf91b108bac5d130fa83"),
+		Value:    ethtypes.NewHexInteger64(0),
+		Data:     data,
+	}
+}
+
+func writeTestFile(t *testing.T, name, content string) string {
+	filename := path.Join(t.TempDir(), name)
+	err := os.WriteFile(filename, []byte(content), 0600)
+	assert.NoError(t, err)
+	return filename
+}
+
+func TestTxDecodeEIP1559WithABI(t *testing.T) {
+	keypair, err := secp256k1.GenerateSecp256k1KeyPair()
+	assert.NoError(t, err)
+	tx := testTransferTx(t)
+	tx.MaxFeePerGas = ethtypes.NewHexInteger64(1000000000)
+	rawTx, err := tx.SignEIP1559(keypair, 1337)
+	assert.NoError(t, err)
+
+	otherABI := writeTestFile(t, "other.json", `{"abi":[{"type":"function","name":"other"},{"type":"event","name":"Transfer"}]}`)
+	transferABI := writeTestFile(t, "transfer.json", testTransferABI)
+	out, err := runCommand(t, "", "tx", "decode", ethtypes.HexBytes0xPrefix(rawTx).String(), "--abi", otherABI, "--abi", transferABI, "--abi", "ignored-once-found")
+	assert.NoError(t, err)
+
+	var decoded decodedTransaction
+	err = json.Unmarshal([]byte(out), &decoded)
+	assert.NoError(t, err)
+	assert.Equal(t, ethsigner.TransactionType1559, decoded.Type)
+	assert.Equal(t, int64(1337), *decoded.ChainID)
+	assert.Equal(t, keypair.Address, *decoded.From)
+	assert.Len(t, decoded.Hash, 32)
+	assert.Equal(t, "transfer(address,uint256)", decoded.Call.Method)
+	assert.JSONEq(t, `{"to":"0x497eedc4299dea2f2a364be10025d0ad0f702de3","value":"1000"}`, string(decoded.Call.Inputs))
+}
+
+func TestTxDecodeLegacyStdin(t *testing.T) {
+	keypair, err := secp256k1.GenerateSecp256k1KeyPair()
+	assert.NoError(t, err)
+	tx := testTransferTx(t)
+
+	rawTx, err := tx.SignLegacyEIP155(keypair, 2024)
+	assert.NoError(t, err)
+	out, err := runCommand(t, ethtypes.HexBytes0xPrefix(rawTx).String()+"\n", "tx", "decode")
+	assert.NoError(t, err)
+	var decoded decodedTransaction
+	err = json.Unmarshal([]byte(out), &decoded)
+	assert.NoError(t, err)
+	assert.Equal(t, ethsigner.TransactionTypeLegacy, decoded.Type)
+	assert.Equal(t, int64(2024), *decoded.ChainID)
+	assert.Equal(t, keypair.Address, *decoded.From)
+	assert.Nil(t, decoded.Call)
+
+	rawTx, err = tx.SignLegacyOriginal(keypair)
+	assert.NoError(t, err)
+	out, err = runCommand(t, ethtypes.HexBytes0xPrefix(rawTx).String(), "tx", "decode", "-", "--abi", writeTestFile(t, "transfer.json", testTransferABI))
+	assert.NoError(t, err)
+	decoded = decodedTransaction{}
+	err = json.Unmarshal([]byte(out), &decoded)
+	assert.NoError(t, err)
+	assert.Nil(t, decoded.ChainID)
+	assert.Equal(t, keypair.Address, *decoded.From)
+	assert.Equal(t, "transfer(address,uint256)", decoded.Call.Method)
+}
+
+func TestTxDecodeNoData(t *testing.T) {
+	keypair, err := secp256k1.GenerateSecp256k1KeyPair()
+	assert.NoError(t, err)
+	tx := testTransferTx(t)
+	tx.Data = nil
+	rawTx, err := tx.SignLegacyEIP155(keypair, 2024)
+	assert.NoError(t, err)
+	out, err := runCommand(t, "", "tx", "decode", ethtypes.HexBytes0xPrefix(rawTx).String(), "--abi", writeTestFile(t, "transfer.json", testTransferABI))
+	assert.NoError(t, err)
+	assert.NotContains(t, out, "call")
+}
+
+func TestTxDecodeBadCallData(t *testing.T) {
+	keypair, err := secp256k1.GenerateSecp256k1KeyPair()
+	assert.NoError(t, err)
+	tx := testTransferTx(t)
+	tx.Data = tx.Data[0:8]
+	rawTx, err := tx.SignLegacyEIP155(keypair, 2024)
+	assert.NoError(t, err)
+	_, err = runCommand(t, "", "tx", "decode", ethtypes.HexBytes0xPrefix(rawTx).String(), "--abi", writeTestFile(t, "transfer.json", testTransferABI))
+	assert.Regexp(t, "FF22047", err)
+}
+
+func TestTxDecodeBadABIFiles(t *testing.T) {
+	keypair, err := secp256k1.GenerateSecp256k1KeyPair()
+	assert.NoError(t, err)
+	rawTx, err := testTransferTx(t).SignLegacyEIP155(keypair, 2024)
+	assert.NoError(t, err)
+	for _, abiFile := range []string{
+		path.Join(t.TempDir(), "missing"),
+		writeTestFile(t, "bad.json", `[`),
+		writeTestFile(t, "bad.json", `[{"type":"function","name":"bad","inputs":[{"type":"wrong"}]}]`),
+	} {
+		_, err = runCommand(t, "", "tx", "decode", ethtypes.HexBytes0xPrefix(rawTx).String(), "--abi", abiFile)
+		assert.Regexp(t, "FF22201", err)
+	}
+}
+
+func TestTxDecodeErrors(t *testing.T) {
+	_, err := runCommand(t, "", "tx", "decode", "wrong")
+	assert.Regexp(t, "FF22202", err)
+
+	_, err = runCommand(t, "", "tx", "decode", "0x01")
+	assert.Regexp(t, "FF22082", err)
+
+	_, err = runCommand(t, "", "tx", "decode", "0x"+strings.Repeat("00", 40))
+	assert.Regexp(t, "FF22082", err)
+}
+
+func TestTxDecodeRecoverFail(t *testing.T) {
+	tx := testTransferTx(t)
+	rawTx, err := tx.FinalizeLegacyOriginalWithSignature(tx.SignaturePayloadLegacyOriginal(), &secp256k1.SignatureData{
+		V: big.NewInt(27),
+		R: big.NewInt(0),
+		S: big.NewInt(1),
+	})
+	assert.NoError(t, err)
+	_, err = runCommand(t, "", "tx", "decode", ethtypes.HexBytes0xPrefix(rawTx).String())
+	assert.Error(t, err)
+}
+
+func TestTxDecodeStdinFail(t *testing.T) {
+	rootCmd.SetArgs([]string{"tx", "decode"})
+	rootCmd.SetIn(iotest.ErrReader(fmt.Errorf("pop")))
+	defer func() {
+		rootCmd.SetArgs([]string{})
+		rootCmd.SetIn(nil)
+	}()
+	err := Execute()
+	assert.Regexp(t, "FF22199.*pop", err)
+}
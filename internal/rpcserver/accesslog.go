@@ -0,0 +1,236 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-common/pkg/log"
+	"github.com/hyperledger/firefly-signer/internal/signerconfig"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/hyperledger/firefly-signer/pkg/rpcauth"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	accessLogSummary = "summary"
+	accessLogFull    = "full"
+
+	redacted = "[REDACTED]"
+)
+
+// The params of these methods are redacted by position, as they carry signed payloads or passphrases
+// that are not in named fields. An empty list redacts every param. Methods are included even if we
+// do not handle them ourselves, as they are passed through to the backend.
+var redactedParams = map[string][]int{
+	"eth_sendRawTransaction":   {},
+	"personal_importRawKey":    {},
+	"personal_unlockAccount":   {1},
+	"personal_sendTransaction": {1},
+	"personal_signTransaction": {1},
+	"personal_sign":            {2},
+}
+
+// The results of these methods are signed payloads, or signatures that could be replayed
+var redactedResults = map[string]bool{
+	"eth_signTransaction":      true,
+	"personal_signTransaction": true,
+	"eth_sign":                 true,
+	"personal_sign":            true,
+	"eth_signTypedData":        true,
+	"eth_signTypedData_v3":     true,
+	"eth_signTypedData_v4":     true,
+}
+
+// Any field (at any depth) with a name containing one of these, after lower-casing and removing
+// separators, is redacted - as is a "raw" field, which holds the signed payload in eth_signTransaction
+var sensitiveFieldNames = []string{"passphrase", "password", "privatekey", "secret", "mnemonic", "seed"}
+
+var fieldNameSeparators = strings.NewReplacer("_", "", "-", "")
+
+// Correlation IDs supplied by clients are only accepted if they cannot be used to inject into the logs
+var validCorrelationID = regexp.MustCompile(`^[A-Za-z0-9._:/+=-]{1,128}$`)
+
+// accessLogger writes a JSON entry for every JSON/RPC request, independent of the format of the main log
+type accessLogger struct {
+	logger            *logrus.Logger
+	full              bool
+	correlationHeader string
+}
+
+func newAccessLogger(ctx context.Context) (*accessLogger, error) {
+	al := &accessLogger{
+		logger:            logrus.New(),
+		correlationHeader: config.GetString(signerconfig.AccessLogCorrelationHeader),
+	}
+	switch verbosity := config.GetString(signerconfig.AccessLogVerbosity); verbosity {
+	case accessLogSummary:
+	case accessLogFull:
+		al.full = true
+	default:
+		return nil, i18n.NewError(ctx, signermsgs.MsgUnknownAccessLogVerbosity, verbosity)
+	}
+	al.logger.SetOutput(os.Stdout)
+	al.logger.SetFormatter(&logrus.JSONFormatter{TimestampFormat: time.RFC3339Nano})
+	return al, nil
+}
+
+type correlationIDContextKey struct{}
+
+func withCorrelationID(ctx context.Context, correlationID string) context.Context {
+	if correlationID == "" {
+		return ctx
+	}
+	return log.WithLogField(context.WithValue(ctx, correlationIDContextKey{}, correlationID), "correlationId", correlationID)
+}
+
+func getCorrelationID(ctx context.Context) string {
+	correlationID, _ := ctx.Value(correlationIDContextKey{}).(string)
+	return correlationID
+}
+
+// correlated takes the correlation ID of each request from its header, generating one if the client did not
+// supply a valid one. It is echoed back in the response, and included in every log entry for the request.
+func (s *rpcServer) correlated(handler http.HandlerFunc) http.HandlerFunc {
+	if s.accessLog == nil {
+		return handler
+	}
+	header := s.accessLog.correlationHeader
+	return func(w http.ResponseWriter, r *http.Request) {
+		correlationID := r.Header.Get(header)
+		if !validCorrelationID.MatchString(correlationID) {
+			correlationID = fftypes.NewUUID().String()
+		}
+		w.Header().Set(header, correlationID)
+		handler(w, r.WithContext(withCorrelationID(r.Context(), correlationID)))
+	}
+}
+
+// accessLogged runs the processor, then records the request in the access log. The method and params are
+// captured first, as an eth_sendTransaction is rewritten to an eth_sendRawTransaction as it is processed.
+func (s *rpcServer) accessLogged(ctx context.Context, rpcReq *rpcbackend.RPCRequest, processor rpcProcessor) (*rpcbackend.RPCResponse, error) {
+	if s.accessLog == nil {
+		return processor(ctx, rpcReq)
+	}
+	method, params, startTime := rpcReq.Method, rpcReq.Params, time.Now()
+	rpcRes, err := processor(ctx, rpcReq)
+	s.accessLog.record(ctx, rpcReq.ID, method, params, rpcRes, err, time.Since(startTime))
+	return rpcRes, err
+}
+
+func (al *accessLogger) record(ctx context.Context, id *fftypes.JSONAny, method string, params []*fftypes.JSONAny, rpcRes *rpcbackend.RPCResponse, err error, duration time.Duration) {
+	fields := logrus.Fields{
+		"method":     method,
+		"outcome":    rpcOutcome(rpcRes, err),
+		"durationMs": float64(duration.Microseconds()) / 1000,
+	}
+	if id != nil {
+		fields["id"] = logValue(id)
+	}
+	if correlationID := getCorrelationID(ctx); correlationID != "" {
+		fields["correlationId"] = correlationID
+	}
+	if identity := rpcauth.GetIdentity(ctx); identity != nil {
+		fields["identity"] = identity.ID
+	}
+	if rpcRes != nil && rpcRes.Error != nil {
+		fields["errorCode"] = rpcRes.Error.Code
+		fields["error"] = rpcRes.Error.Message
+	}
+	if al.full {
+		fields["params"] = redactParams(method, params)
+		if rpcRes != nil && rpcRes.Result != nil {
+			if redactedResults[method] {
+				fields["result"] = redacted
+			} else {
+				fields["result"] = redactFields(logValue(rpcRes.Result))
+			}
+		}
+	}
+	al.logger.WithFields(fields).Info("JSON/RPC request")
+}
+
+func redactParams(method string, params []*fftypes.JSONAny) []interface{} {
+	positions, byPosition := redactedParams[method]
+	values := make([]interface{}, len(params))
+	for i, p := range params {
+		if byPosition && (len(positions) == 0 || slices.Contains(positions, i)) {
+			values[i] = redacted
+		} else {
+			values[i] = redactFields(logValue(p))
+		}
+	}
+	return values
+}
+
+// logValue decodes JSON to be re-encoded in the access log, preserving large numbers
+func logValue(j *fftypes.JSONAny) interface{} {
+	if j == nil {
+		return nil
+	}
+	var v interface{}
+	decoder := json.NewDecoder(bytes.NewReader(j.Bytes()))
+	decoder.UseNumber()
+	if err := decoder.Decode(&v); err != nil {
+		// We cannot tell what is in it, so it is not safe to log
+		return redacted
+	}
+	return v
+}
+
+func isSensitiveField(name string) bool {
+	name = fieldNameSeparators.Replace(strings.ToLower(name))
+	if name == "raw" {
+		return true
+	}
+	for _, s := range sensitiveFieldNames {
+		if strings.Contains(name, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func redactFields(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for name, fv := range v {
+			if isSensitiveField(name) {
+				v[name] = redacted
+			} else {
+				v[name] = redactFields(fv)
+			}
+		}
+	case []interface{}:
+		for i, iv := range v {
+			v[i] = redactFields(iv)
+		}
+	}
+	return v
+}
@@ -0,0 +1,227 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-signer/internal/signerconfig"
+	"github.com/hyperledger/firefly-signer/mocks/ethsignermocks"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/rpcauth"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func setTestAccessLogConf() {
+	config.Set(signerconfig.AccessLogEnabled, true)
+	config.Set(signerconfig.AccessLogVerbosity, "full")
+}
+
+// captureAccessLog returns a function that parses the access log entries written so far
+func captureAccessLog(s *rpcServer) func(t *testing.T) []map[string]interface{} {
+	buf := &bytes.Buffer{}
+	s.accessLog.logger.SetOutput(buf)
+	return func(t *testing.T) []map[string]interface{} {
+		var entries []map[string]interface{}
+		scanner := bufio.NewScanner(bytes.NewReader(buf.Bytes()))
+		for scanner.Scan() {
+			var entry map[string]interface{}
+			err := json.Unmarshal(scanner.Bytes(), &entry)
+			assert.NoError(t, err)
+			entries = append(entries, entry)
+		}
+		return entries
+	}
+}
+
+func TestAccessLogBadVerbosity(t *testing.T) {
+	signerconfig.Reset()
+	setTestAccessLogConf()
+	config.Set(signerconfig.AccessLogVerbosity, "wrong")
+
+	_, err := NewServer(context.Background(), &ethsignermocks.Wallet{})
+	assert.Regexp(t, "FF22140", err)
+}
+
+func TestAccessLogHTTPCorrelation(t *testing.T) {
+	url, s, done := newTestServer(t, setTestAccessLogConf)
+	defer done()
+	entries := captureAccessLog(s)
+
+	w := s.wallet.(*ethsignermocks.Wallet)
+	w.On("GetAccounts", mock.Anything).Return([]*ethtypes.Address0xHex{}, nil)
+	startTestServerNoBackend(t, s)
+
+	post := func(correlationID string) *http.Response {
+		req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(`{"jsonrpc":"2.0","id":"req1","method":"eth_accounts"}`))
+		assert.NoError(t, err)
+		req.Header.Set("X-Request-ID", correlationID)
+		res, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		return res
+	}
+
+	res := post("client-id:1234")
+	assert.Equal(t, "client-id:1234", res.Header.Get("X-Request-ID"))
+
+	// An ID that could be used to inject into the logs is replaced
+	res = post(`"}{"`)
+	generatedID := res.Header.Get("X-Request-ID")
+	assert.Regexp(t, "^[0-9a-f-]{36}$", generatedID)
+
+	logged := entries(t)
+	assert.Len(t, logged, 2)
+	assert.Equal(t, "client-id:1234", logged[0]["correlationId"])
+	assert.Equal(t, "req1", logged[0]["id"])
+	assert.Equal(t, "eth_accounts", logged[0]["method"])
+	assert.Equal(t, "success", logged[0]["outcome"])
+	assert.Equal(t, []interface{}{}, logged[0]["params"])
+	assert.Equal(t, []interface{}{}, logged[0]["result"])
+	assert.NotNil(t, logged[0]["durationMs"])
+	assert.Equal(t, generatedID, logged[1]["correlationId"])
+}
+
+func TestAccessLogWSCorrelation(t *testing.T) {
+	url, s, done := newTestServer(t, setTestAccessLogConf)
+	defer done()
+	entries := captureAccessLog(s)
+
+	w := s.wallet.(*ethsignermocks.Wallet)
+	w.On("GetAccounts", mock.Anything).Return([]*ethtypes.Address0xHex{}, nil)
+	startTestServerNoBackend(t, s)
+
+	conn, res, err := websocket.DefaultDialer.Dial(strings.Replace(url, "http://", "ws://", 1), http.Header{
+		"X-Request-ID": []string{"conn1"},
+	})
+	assert.NoError(t, err)
+	defer conn.Close()
+	assert.Equal(t, "conn1", res.Header.Get("X-Request-ID"))
+
+	// Every request on the connection shares its correlation ID
+	for i := 1; i <= 2; i++ {
+		rpcRes := wsRoundTrip(t, conn, fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"method":"eth_accounts"}`, i))
+		assert.Equal(t, float64(i), rpcRes["id"])
+	}
+	logged := entries(t)
+	assert.Len(t, logged, 2)
+	for _, entry := range logged {
+		assert.Equal(t, "conn1", entry["correlationId"])
+	}
+}
+
+func TestAccessLogRedaction(t *testing.T) {
+	_, s, done := newTestServer(t, setTestAccessLogConf)
+	defer done()
+	entries := captureAccessLog(s)
+
+	ctx := rpcauth.WithIdentity(withCorrelationID(s.ctx, "corr1"), &rpcauth.Identity{ID: "tenantA"})
+	respond := func(result string) rpcProcessor {
+		return func(ctx context.Context, rpcReq *rpcbackend.RPCRequest) (*rpcbackend.RPCResponse, error) {
+			// The processor can rewrite the request, as eth_sendTransaction does
+			rpcReq.Method = "rewritten"
+			rpcReq.Params = nil
+			return &rpcbackend.RPCResponse{JSONRpc: "2.0", ID: rpcReq.ID, Result: fftypes.JSONAnyPtr(result)}, nil
+		}
+	}
+	request := func(method string, params ...string) *rpcbackend.RPCRequest {
+		rpcReq := &rpcbackend.RPCRequest{JSONRpc: "2.0", ID: fftypes.JSONAnyPtr("1"), Method: method}
+		for _, p := range params {
+			rpcReq.Params = append(rpcReq.Params, fftypes.JSONAnyPtr(p))
+		}
+		return rpcReq
+	}
+
+	_, err := s.accessLogged(ctx, request("personal_unlockAccount", `"0xfb075bb99f2aa4c49955bf703509a227d7a12248"`, `"pass"`, `60`), respond("true"))
+	assert.NoError(t, err)
+	_, err = s.accessLogged(ctx, request("eth_sendRawTransaction", `"0xf86c0a"`), respond(`"0x1234"`))
+	assert.NoError(t, err)
+	_, err = s.accessLogged(ctx, request("eth_signTransaction", `{"from":"0xfb075bb99f2aa4c49955bf703509a227d7a12248"}`), respond(`"0xf86c0a"`))
+	assert.NoError(t, err)
+	_, err = s.accessLogged(ctx, request("custom_method",
+		`{"privateKey":"0x11","nested":{"Pass_Phrase":"p","value":12345678901234567890123},"list":[{"client-secret":"s"}]}`),
+		respond(`{"raw":"0xf86c0a","tx":{"nonce":"0x0"}}`))
+	assert.NoError(t, err)
+	_, err = s.accessLogged(ctx, request("eth_call", `not json`), func(ctx context.Context, rpcReq *rpcbackend.RPCRequest) (*rpcbackend.RPCResponse, error) {
+		err := fmt.Errorf("pop")
+		return rpcbackend.RPCErrorResponse(err, rpcReq.ID, rpcbackend.RPCCodeInvalidRequest), err
+	})
+	assert.Regexp(t, "pop", err)
+
+	logged := entries(t)
+	assert.Len(t, logged, 5)
+	for _, entry := range logged {
+		assert.Equal(t, "corr1", entry["correlationId"])
+		assert.Equal(t, "tenantA", entry["identity"])
+		assert.Equal(t, float64(1), entry["id"])
+	}
+	assert.Equal(t, "personal_unlockAccount", logged[0]["method"])
+	assert.Equal(t, []interface{}{"0xfb075bb99f2aa4c49955bf703509a227d7a12248", redacted, float64(60)}, logged[0]["params"])
+	assert.Equal(t, true, logged[0]["result"])
+	assert.Equal(t, []interface{}{redacted}, logged[1]["params"])
+	assert.Equal(t, "0x1234", logged[1]["result"])
+	assert.Equal(t, redacted, logged[2]["result"])
+	assert.Equal(t, []interface{}{map[string]interface{}{
+		"privateKey": redacted,
+		"nested":     map[string]interface{}{"Pass_Phrase": redacted, "value": 12345678901234567890123.0},
+		"list":       []interface{}{map[string]interface{}{"client-secret": redacted}},
+	}}, logged[3]["params"])
+	assert.Equal(t, map[string]interface{}{"raw": redacted, "tx": map[string]interface{}{"nonce": "0x0"}}, logged[3]["result"])
+	assert.Equal(t, []interface{}{redacted}, logged[4]["params"])
+	assert.Equal(t, "rpc_error", logged[4]["outcome"])
+	assert.Equal(t, float64(rpcbackend.RPCCodeInvalidRequest), logged[4]["errorCode"])
+	assert.Equal(t, "pop", logged[4]["error"])
+	assert.NotContains(t, logged[4], "result")
+}
+
+func TestAccessLogSummary(t *testing.T) {
+	_, s, done := newTestServer(t, setTestAccessLogConf, func() {
+		config.Set(signerconfig.AccessLogVerbosity, "summary")
+	})
+	defer done()
+	entries := captureAccessLog(s)
+
+	_, err := s.accessLogged(s.ctx, &rpcbackend.RPCRequest{Method: "eth_chainId"}, func(ctx context.Context, rpcReq *rpcbackend.RPCRequest) (*rpcbackend.RPCResponse, error) {
+		return &rpcbackend.RPCResponse{Result: fftypes.JSONAnyPtr(`"0x1"`)}, nil
+	})
+	assert.NoError(t, err)
+
+	logged := entries(t)
+	assert.Len(t, logged, 1)
+	assert.Equal(t, "eth_chainId", logged[0]["method"])
+	assert.NotContains(t, logged[0], "id")
+	assert.NotContains(t, logged[0], "correlationId")
+	assert.NotContains(t, logged[0], "identity")
+	assert.NotContains(t, logged[0], "params")
+	assert.NotContains(t, logged[0], "result")
+}
+
+func TestAccessLogNilValue(t *testing.T) {
+	assert.Nil(t, logValue(nil))
+}
@@ -0,0 +1,115 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-signer/internal/signerconfig"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/hyperledger/firefly-signer/pkg/approval"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+)
+
+const (
+	metricAddressSignOperationsTotal   = "address_sign_operations_total"
+	metricAddressSignDurationSeconds   = "address_sign_duration_seconds"
+	metricAddressPolicyRejectionsTotal = "address_policy_rejections_total"
+
+	metricLabelAddress   = "address"
+	metricLabelOperation = "operation"
+	metricLabelReason    = "reason"
+
+	addressLabelFull      = "full"
+	addressLabelTruncated = "truncated"
+	addressLabelHashed    = "hashed"
+
+	signOpTransaction     = approval.OperationTransaction
+	signOpPersonalMessage = approval.OperationPersonalMessage
+	signOpDigest          = approval.OperationDigest
+	signOpTypedData       = approval.OperationTypedData
+
+	// Raw transactions are signed elsewhere, so are only recorded when rejected by a policy check
+	signOpRawTransaction = "raw_transaction"
+
+	// The address label when the address of a request could not be parsed
+	addressLabelUnknown = "unknown"
+)
+
+// addressLabeler turns an address into the value of the address label, which can be truncated or hashed to
+// limit the number of series when a signer has many keys
+type addressLabeler func(addr *ethtypes.Address0xHex) string
+
+func newAddressLabeler(ctx context.Context) (addressLabeler, error) {
+	mode := signerconfig.MetricsConfig.GetString(signerconfig.MetricsConfAddressesLabel)
+	length := signerconfig.MetricsConfig.GetInt(signerconfig.MetricsConfAddressesLabelLength)
+	if mode != addressLabelFull && (length < 1 || length > 40) {
+		return nil, i18n.NewError(ctx, signermsgs.MsgBadAddressMetricsLabel, mode, length)
+	}
+	switch mode {
+	case addressLabelFull:
+		return func(addr *ethtypes.Address0xHex) string {
+			return addr.String()
+		}, nil
+	case addressLabelTruncated:
+		return func(addr *ethtypes.Address0xHex) string {
+			return addr.String()[0 : 2+length]
+		}, nil
+	case addressLabelHashed:
+		return func(addr *ethtypes.Address0xHex) string {
+			hash := sha256.Sum256(addr[:])
+			return hex.EncodeToString(hash[:])[0:length]
+		}, nil
+	default:
+		return nil, i18n.NewError(ctx, signermsgs.MsgBadAddressMetricsLabel, mode, length)
+	}
+}
+
+func (m *rpcMetrics) initAddressMetrics(ctx context.Context) (err error) {
+	if m.addressLabel, err = newAddressLabeler(ctx); err != nil {
+		return err
+	}
+	m.wallet.NewCounterMetricWithLabels(ctx, metricAddressSignOperationsTotal, "Number of signing operations by address, operation and outcome", []string{metricLabelAddress, metricLabelOperation, metricLabelOutcome}, false)
+	m.wallet.NewHistogramMetricWithLabels(ctx, metricAddressSignDurationSeconds, "Duration of signing operations by address and operation", nil, []string{metricLabelAddress, metricLabelOperation}, false)
+	m.wallet.NewCounterMetricWithLabels(ctx, metricAddressPolicyRejectionsTotal, "Number of signing requests rejected by a policy check, by address and the error code of the rejection", []string{metricLabelAddress, metricLabelReason}, false)
+	return nil
+}
+
+func (m *rpcMetrics) metricAddress(addr *ethtypes.Address0xHex) string {
+	if addr == nil {
+		return addressLabelUnknown
+	}
+	return m.addressLabel(addr)
+}
+
+func (m *rpcMetrics) addressSignOperation(ctx context.Context, addr *ethtypes.Address0xHex, operation, outcome string, startTime time.Time) {
+	address := m.metricAddress(addr)
+	m.wallet.IncCounterMetricWithLabels(ctx, metricAddressSignOperationsTotal, map[string]string{metricLabelAddress: address, metricLabelOperation: operation, metricLabelOutcome: outcome}, nil)
+	m.wallet.ObserveHistogramMetricWithLabels(ctx, metricAddressSignDurationSeconds, time.Since(startTime).Seconds(), map[string]string{metricLabelAddress: address, metricLabelOperation: operation}, nil)
+}
+
+func (m *rpcMetrics) policyRejection(ctx context.Context, addr *ethtypes.Address0xHex, err error) {
+	reason := outcomeError
+	if ffe, ok := err.(i18n.FFError); ok {
+		reason = string(ffe.MessageKey())
+	}
+	m.wallet.IncCounterMetricWithLabels(ctx, metricAddressPolicyRejectionsTotal, map[string]string{metricLabelAddress: m.metricAddress(addr), metricLabelReason: reason}, nil)
+}
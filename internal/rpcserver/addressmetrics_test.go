@@ -0,0 +1,140 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-signer/internal/signerconfig"
+	"github.com/hyperledger/firefly-signer/mocks/ethsignermocks"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const testAddressMetricsAddr = "0xfb075bb99f2aa4c49955bf703509a227d7a12248"
+
+func setTestAddressMetricsConf(label string, length int) func() {
+	return func() {
+		signerconfig.MetricsConfig.Set(signerconfig.MetricsConfAddressesEnabled, true)
+		signerconfig.MetricsConfig.Set(signerconfig.MetricsConfAddressesLabel, label)
+		signerconfig.MetricsConfig.Set(signerconfig.MetricsConfAddressesLabelLength, length)
+	}
+}
+
+func TestAddressLabeler(t *testing.T) {
+	ctx := context.Background()
+	addr := ethtypes.MustNewAddress(testAddressMetricsAddr)
+	for _, tc := range []struct {
+		label    string
+		length   int
+		expected string
+	}{
+		{label: addressLabelFull, length: 0, expected: testAddressMetricsAddr},
+		{label: addressLabelTruncated, length: 8, expected: "0xfb075bb9"},
+		{label: addressLabelTruncated, length: 40, expected: testAddressMetricsAddr},
+		{label: addressLabelHashed, length: 12, expected: "d10e12b01ce9"},
+	} {
+		signerconfig.Reset()
+		setTestAddressMetricsConf(tc.label, tc.length)()
+		labeler, err := newAddressLabeler(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.expected, labeler(addr))
+	}
+}
+
+func TestAddressLabelerBadConfig(t *testing.T) {
+	ctx := context.Background()
+	for _, tc := range []struct {
+		label  string
+		length int
+	}{
+		{label: "unknown", length: 8},
+		{label: addressLabelTruncated, length: 0},
+		{label: addressLabelHashed, length: 41},
+	} {
+		signerconfig.Reset()
+		setTestAddressMetricsConf(tc.label, tc.length)()
+		_, err := newAddressLabeler(ctx)
+		assert.Regexp(t, "FF22157", err)
+	}
+}
+
+func TestAddressMetricsBadConfig(t *testing.T) {
+	signerconfig.Reset()
+	signerconfig.ServerConfig.Set("port", 0)
+	signerconfig.MetricsConfig.Set(signerconfig.MetricsConfEnabled, true)
+	setTestAddressMetricsConf("unknown", 8)()
+
+	_, err := NewServer(context.Background(), &ethsignermocks.Wallet{})
+	assert.Regexp(t, "FF22157", err)
+}
+
+func TestAddressMetricsEndToEnd(t *testing.T) {
+	w := &ethsignermocks.Wallet{}
+	_, metricsURL, s, bm, done := newTestMetricsServer(t, w, setTestTxPolicyConf, setTestEthSignConf, setTestAddressMetricsConf(addressLabelTruncated, 8))
+	defer done()
+	startTestServerNoBackend(t, s)
+
+	bm.On("SyncRequest", mock.Anything, mock.Anything).Return(&rpcbackend.RPCResponse{
+		JSONRpc: "2.0",
+		ID:      fftypes.JSONAnyPtr(`1`),
+		Result:  fftypes.JSONAnyPtr(`"0x61ca9c99c1d752fb3bda568b8566edf33ba93585c64a970566e6dfb540a5cbc1"`),
+	}, nil)
+	w.On("Sign", mock.Anything, mock.Anything, int64(12345)).Return([]byte{0x01}, nil)
+
+	// A signed transaction, one rejected by the value policy, and one from an address that does not parse
+	for _, txJSON := range []string{
+		fmt.Sprintf(`{"from":"%s","to":"%s","nonce":"0x1","value":"0x1"}`, testAddressMetricsAddr, testAllowedDestination),
+		fmt.Sprintf(`{"from":"%s","to":"%s","nonce":"0x1","value":"0x100000"}`, testAddressMetricsAddr, testAllowedDestination),
+		fmt.Sprintf(`{"from":"my-key","to":"%s","nonce":"0x1","value":"0x1"}`, testAllowedDestination),
+	} {
+		_, _ = s.processRPC(s.ctx, testRPCRequest(1, "eth_sendTransaction", txJSON))
+	}
+
+	wps := &ethsignermocks.WalletPersonalSign{}
+	s.wallet = wps
+	wps.On("SignPersonalMessage", mock.Anything, mock.Anything, mock.Anything).Return(nil, fmt.Errorf("pop"))
+	_, err := s.processRPC(s.ctx, personalSignTestRequest())
+	assert.Regexp(t, "pop", err)
+
+	wds := &ethsignermocks.WalletDigestSign{}
+	s.wallet = wds
+	wds.On("SignDigest", mock.Anything, mock.Anything, mock.Anything).Return(ethtypes.MustNewHexBytes0xPrefix("0xaabbcc"), nil)
+	_, err = s.processRPC(s.ctx, ethSignTestRequest())
+	assert.NoError(t, err)
+
+	// Rejections without an error code are recorded with a generic reason
+	_, _ = s.rejectedByPolicy(s.ctx, &signingRequest{operation: signOpTransaction}, nil, fmt.Errorf("pop"))
+
+	metrics := scrapeTestMetrics(t, metricsURL)
+	for _, expected := range []string{
+		`ff_signer_wallet_address_sign_operations_total{address="0xfb075bb9",ff_component="ffsigner",operation="transaction",outcome="success"} 1`,
+		`ff_signer_wallet_address_sign_operations_total{address="unknown",ff_component="ffsigner",operation="transaction",outcome="success"} 1`,
+		`ff_signer_wallet_address_sign_operations_total{address="0xfb075bb9",ff_component="ffsigner",operation="personal_message",outcome="error"} 1`,
+		`ff_signer_wallet_address_sign_operations_total{address="0xfb075bb9",ff_component="ffsigner",operation="digest",outcome="success"} 1`,
+		`ff_signer_wallet_address_sign_duration_seconds_count{address="0xfb075bb9",ff_component="ffsigner",operation="transaction"} 1`,
+		`ff_signer_wallet_address_policy_rejections_total{address="0xfb075bb9",ff_component="ffsigner",reason="FF22154"} 1`,
+		`ff_signer_wallet_address_policy_rejections_total{address="unknown",ff_component="ffsigner",reason="error"} 1`,
+	} {
+		assert.Contains(t, metrics, expected)
+	}
+}
@@ -0,0 +1,315 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"context"
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+	"net/url"
+
+	"github.com/gorilla/mux"
+	"github.com/hyperledger/firefly-common/pkg/httpserver"
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-common/pkg/log"
+	"github.com/hyperledger/firefly-signer/internal/signerconfig"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/hyperledger/firefly-signer/pkg/errorcodes"
+	"github.com/hyperledger/firefly-signer/pkg/ethsigner"
+	"github.com/hyperledger/firefly-signer/pkg/fips"
+	"github.com/hyperledger/firefly-signer/pkg/nonces"
+	"github.com/hyperledger/firefly-signer/pkg/rpcauth"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+)
+
+// Each admin operation is authorized as a method with this name, when role based access control is enabled
+const (
+	adminOpStatus          = "admin_status"
+	adminOpRefreshWallet   = "admin_refreshWallet"
+	adminOpNonces          = "admin_nonces"
+	adminOpCircuitBreakers = "admin_circuitBreakers"
+	adminOpFlushCaches     = "admin_flushCaches"
+	adminOpLogging         = "admin_logging"
+	adminOpDebug           = "admin_debug"
+	adminOpKeyCeremony     = "admin_keyCeremony"
+)
+
+type adminHandler func(ctx context.Context) (interface{}, error)
+
+// adminInputHandler handles an admin operation with a request body
+type adminInputHandler func(ctx context.Context, body []byte) (interface{}, error)
+
+// adminPathHandler handles an admin operation on the resource identified by the variables of its path, with any
+// request body
+type adminPathHandler func(ctx context.Context, vars map[string]string, body []byte) (interface{}, error)
+
+type adminError struct {
+	Error string `json:"error"`
+	*errorcodes.Data
+}
+
+type adminWalletStatus struct {
+	Accounts    int                   `json:"accounts"`
+	SignerCache *ethsigner.CacheStats `json:"signerCache,omitempty"` // only for wallets that cache keys
+}
+
+type adminCircuitBreakerStatus struct {
+	URL string `json:"url"`
+	*rpcbackend.CircuitBreakerStatus
+}
+
+type adminNoncesStatus struct {
+	Addresses int `json:"addresses"`
+}
+
+type adminResponseCacheStatus struct {
+	Entries int `json:"entries"`
+}
+
+type adminStatus struct {
+	Wallet          *adminWalletStatus           `json:"wallet"`
+	Nonces          *adminNoncesStatus           `json:"nonces,omitempty"`
+	CircuitBreakers []*adminCircuitBreakerStatus `json:"circuitBreakers,omitempty"`
+	ResponseCache   *adminResponseCacheStatus    `json:"responseCache,omitempty"`
+	FIPS            *fips.Status                 `json:"fips"`
+}
+
+type adminFlushResult struct {
+	ResponseCacheEntries int `json:"responseCacheEntries"`
+}
+
+// backendCircuitBreaker is the circuit breaker of one backend URL, for its status to be reported
+type backendCircuitBreaker struct {
+	url     string
+	breaker rpcbackend.CircuitBreaker
+}
+
+// initAdmin creates the admin server, which is on its own listener so it can be firewalled separately to
+// the JSON/RPC server. It uses the same authenticators, so requires authentication to be enabled.
+func (s *rpcServer) initAdmin(ctx context.Context) (err error) {
+	if s.authenticators == nil {
+		return i18n.NewError(ctx, signermsgs.MsgAdminRequiresAuth)
+	}
+	if signerconfig.AdminConfig.GetBool(signerconfig.AdminConfKeyCeremonyEnabled) {
+		// Dual control is only meaningful when not every authenticated identity can take part
+		if s.authorizer.Load() == nil {
+			return i18n.NewError(ctx, signermsgs.MsgKeyCeremonyRequiresRBAC)
+		}
+		if s.keyCeremonies, err = newKeyCeremonies(ctx, s.persistence); err != nil {
+			return err
+		}
+	}
+	s.adminServerDone = make(chan error)
+	s.adminServer, err = httpserver.NewHTTPServer(ctx, "admin", s.adminRouter(), s.adminServerDone, signerconfig.AdminConfig, signerconfig.CorsConfig)
+	return err
+}
+
+func (s *rpcServer) adminRouter() *mux.Router {
+	r := mux.NewRouter()
+	s.adminRoute(r, http.MethodGet, "/status", adminOpStatus, s.adminGetStatus)
+	s.adminRoute(r, http.MethodPost, "/wallet/refresh", adminOpRefreshWallet, s.adminRefreshWallet)
+	s.adminRoute(r, http.MethodGet, "/nonces", adminOpNonces, s.adminGetNonces)
+	s.adminRoute(r, http.MethodGet, "/circuitbreakers", adminOpCircuitBreakers, s.adminGetCircuitBreakers)
+	s.adminRoute(r, http.MethodPost, "/caches/flush", adminOpFlushCaches, s.adminFlushCaches)
+	s.adminRoute(r, http.MethodGet, "/logging", adminOpLogging, s.adminGetLogging)
+	s.adminInputRoute(r, http.MethodPut, "/logging", adminOpLogging, s.adminSetLogging)
+	if s.keyCeremonies != nil {
+		s.adminPathRoute(r, http.MethodPost, "/keys/ceremonies", adminOpKeyCeremony, s.adminProposeKeyCeremony)
+		s.adminRoute(r, http.MethodGet, "/keys/ceremonies", adminOpKeyCeremony, s.adminListKeyCeremonies)
+		s.adminPathRoute(r, http.MethodGet, "/keys/ceremonies/{id}", adminOpKeyCeremony, s.adminGetKeyCeremony)
+		s.adminPathRoute(r, http.MethodPost, "/keys/ceremonies/{id}/approve", adminOpKeyCeremony, s.adminApproveKeyCeremony)
+		s.adminPathRoute(r, http.MethodPost, "/keys/ceremonies/{id}/reject", adminOpKeyCeremony, s.adminRejectKeyCeremony)
+		s.adminPathRoute(r, http.MethodPost, "/keys/ceremonies/{id}/execute", adminOpKeyCeremony, s.adminExecuteKeyCeremony)
+	}
+	if signerconfig.AdminConfig.GetBool(signerconfig.AdminConfDebugEnabled) {
+		s.adminDebugRoutes(r)
+	}
+	return r
+}
+
+func (s *rpcServer) adminRoute(r *mux.Router, method, path, operation string, handler adminHandler) {
+	r.Path(path).Methods(method).HandlerFunc(localized(s.adminAuthorized(operation, func(w http.ResponseWriter, req *http.Request) {
+		ctx := req.Context()
+		result, err := handler(ctx)
+		s.replyAdmin(ctx, w, result, err)
+	})))
+}
+
+func (s *rpcServer) adminInputRoute(r *mux.Router, method, path, operation string, handler adminInputHandler) {
+	s.adminPathRoute(r, method, path, operation, func(ctx context.Context, _ map[string]string, body []byte) (interface{}, error) {
+		return handler(ctx, body)
+	})
+}
+
+func (s *rpcServer) adminPathRoute(r *mux.Router, method, path, operation string, handler adminPathHandler) {
+	r.Path(path).Methods(method).HandlerFunc(localized(s.adminAuthorized(operation, func(w http.ResponseWriter, req *http.Request) {
+		ctx := req.Context()
+		b, tooLarge, err := s.readRequestBody(ctx, w, req)
+		switch {
+		case tooLarge != nil:
+			s.replyAdminError(ctx, w, tooLarge)
+		case err != nil:
+			s.replyAdminError(ctx, w, i18n.NewError(ctx, signermsgs.MsgInvalidAdminRequest, err))
+		default:
+			result, err := handler(ctx, mux.Vars(req), b)
+			s.replyAdmin(ctx, w, result, err)
+		}
+	})))
+}
+
+// adminDebugRoutes serves the profiles of the Go runtime and the exported variables, so the performance of
+// a running signer can be investigated. The paths are those the pprof and expvar tools expect.
+func (s *rpcServer) adminDebugRoutes(r *mux.Router) {
+	r.Path("/debug/pprof/cmdline").HandlerFunc(s.adminAuthorized(adminOpDebug, pprof.Cmdline))
+	r.Path("/debug/pprof/profile").HandlerFunc(s.adminAuthorized(adminOpDebug, pprof.Profile))
+	r.Path("/debug/pprof/symbol").HandlerFunc(s.adminAuthorized(adminOpDebug, pprof.Symbol))
+	r.Path("/debug/pprof/trace").HandlerFunc(s.adminAuthorized(adminOpDebug, pprof.Trace))
+	r.PathPrefix("/debug/pprof/").HandlerFunc(s.adminAuthorized(adminOpDebug, pprof.Index))
+	r.Path("/debug/vars").HandlerFunc(s.adminAuthorized(adminOpDebug, expvar.Handler().ServeHTTP))
+}
+
+// adminAuthorized authenticates the caller of an admin operation, and authorizes them for the operation
+// when role based access control is enabled, before passing the request to the handler
+func (s *rpcServer) adminAuthorized(operation string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		ctx := req.Context()
+		identity, err := rpcauth.Authenticate(ctx, s.authenticators, req)
+		if err != nil {
+			s.replyAdminError(ctx, w, err)
+			return
+		}
+		if authorizer := s.authorizer.Load(); authorizer != nil {
+			if err := authorizer.AuthorizeMethod(ctx, identity, operation); err != nil {
+				s.replyAdminError(ctx, w, err)
+				return
+			}
+		}
+		ctx = rpcauth.WithIdentity(ctx, identity)
+		log.L(ctx).Infof("Admin operation %s by '%s'", operation, identity.ID)
+		handler(w, req.WithContext(ctx))
+	}
+}
+
+func (s *rpcServer) replyAdmin(ctx context.Context, w http.ResponseWriter, result interface{}, err error) {
+	if err != nil {
+		s.replyAdminError(ctx, w, err)
+		return
+	}
+	s.replyRPC(ctx, w, result, http.StatusOK)
+}
+
+func (s *rpcServer) replyAdminError(ctx context.Context, w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	if ffe, ok := err.(i18n.FFError); ok {
+		status = ffe.HTTPStatus()
+	}
+	s.replyRPC(ctx, w, &adminError{Error: err.Error(), Data: errorcodes.DataOf(err)}, status)
+}
+
+func (s *rpcServer) adminGetStatus(ctx context.Context) (interface{}, error) {
+	walletStatus, err := s.adminWalletStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+	status := &adminStatus{
+		Wallet:          walletStatus,
+		CircuitBreakers: s.circuitBreakerStatus(),
+		FIPS:            fips.GetStatus(),
+	}
+	if s.nonceManager != nil {
+		status.Nonces = &adminNoncesStatus{Addresses: len(s.nonceManager.Addresses())}
+	}
+	if s.responseCache != nil {
+		status.ResponseCache = &adminResponseCacheStatus{Entries: s.responseCache.size()}
+	}
+	return status, nil
+}
+
+func (s *rpcServer) adminWalletStatus(ctx context.Context) (*adminWalletStatus, error) {
+	accounts, err := s.wallet.GetAccounts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	status := &adminWalletStatus{Accounts: len(accounts)}
+	if cacheStatsWallet, ok := s.wallet.(ethsigner.WalletCacheStats); ok {
+		status.SignerCache = cacheStatsWallet.SignerCacheStats()
+	}
+	return status, nil
+}
+
+func (s *rpcServer) adminRefreshWallet(ctx context.Context) (interface{}, error) {
+	if err := s.wallet.Refresh(ctx); err != nil {
+		return nil, err
+	}
+	return s.adminWalletStatus(ctx)
+}
+
+// adminGetNonces returns the status of every address a nonce has been assigned for, which queries the
+// transaction counts of each from the backend
+func (s *rpcServer) adminGetNonces(ctx context.Context) (interface{}, error) {
+	if s.nonceManager == nil {
+		return nil, i18n.NewError(ctx, signermsgs.MsgNonceManagementDisabled)
+	}
+	addrs := s.nonceManager.Addresses()
+	statuses := make([]*nonces.Status, len(addrs))
+	for i, addr := range addrs {
+		status, err := s.nonceManager.Status(ctx, addr)
+		if err != nil {
+			return nil, err
+		}
+		statuses[i] = status
+	}
+	return statuses, nil
+}
+
+func (s *rpcServer) adminGetCircuitBreakers(_ context.Context) (interface{}, error) {
+	return s.circuitBreakerStatus(), nil
+}
+
+func (s *rpcServer) circuitBreakerStatus() []*adminCircuitBreakerStatus {
+	circuitBreakers := s.wsCircuitBreakers
+	if s.httpBackend != nil {
+		circuitBreakers = s.httpBackend.current.Load().circuitBreakers
+	}
+	statuses := make([]*adminCircuitBreakerStatus, len(circuitBreakers))
+	for i, cb := range circuitBreakers {
+		statuses[i] = &adminCircuitBreakerStatus{
+			URL:                  redactURL(cb.url),
+			CircuitBreakerStatus: cb.breaker.CircuitBreakerStatus(),
+		}
+	}
+	return statuses
+}
+
+// redactURL removes any password from a backend URL, so it is not returned by the admin API
+func redactURL(u string) string {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return ""
+	}
+	return parsed.Redacted()
+}
+
+func (s *rpcServer) adminFlushCaches(ctx context.Context) (interface{}, error) {
+	result := &adminFlushResult{}
+	if s.responseCache != nil {
+		result.ResponseCacheEntries = s.responseCache.clear()
+		log.L(ctx).Infof("Flushed %d response cache entries", result.ResponseCacheEntries)
+	}
+	return result, nil
+}
@@ -0,0 +1,114 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"context"
+	"time"
+
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-common/pkg/log"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/hyperledger/firefly-signer/pkg/approval"
+	"github.com/hyperledger/firefly-signer/pkg/rpcauth"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+	"github.com/hyperledger/firefly-signer/pkg/tokens"
+)
+
+// SetApprovalHook sets the hook that must approve every signature before it is released. It must be set before
+// the service is started.
+func (s *rpcServer) SetApprovalHook(hook approval.Hook) {
+	s.approvalHook = hook
+}
+
+// approveSigning asks the approval hook to approve a signing request, with the content to sign, waiting for the
+// decision when the hook defers it. The error response is returned unless it is approved.
+func (s *rpcServer) approveSigning(ctx context.Context, rpcReq *rpcbackend.RPCRequest, req *signingRequest, content *approval.Request) (*rpcbackend.RPCResponse, error) {
+	if s.approvalHook == nil {
+		return nil, nil
+	}
+	content.ID = fftypes.NewUUID().String()
+	content.Method = req.method
+	content.Operation = req.operation
+	content.ChainID = s.chainIDFor(ctx)
+	content.From = req.from
+	if req.txn != nil {
+		// A copy, as the signer assigns the nonce of the transaction once it is approved
+		txn := *req.txn
+		content.Transaction = &txn
+	}
+	content.Expires = time.Now().Add(s.approvalTimeout)
+	if identity := rpcauth.GetIdentity(ctx); identity != nil {
+		content.Identity = identity.ID
+	}
+	if req.txn != nil && req.txn.To != nil {
+		content.TokenCall, _ = tokens.DecodeCall(ctx, req.txn.Data)
+	}
+
+	decision, err := s.approvalHook.ReviewSigning(ctx, content)
+	if err != nil {
+		err = i18n.NewError(ctx, signermsgs.MsgSigningApprovalFailed, err)
+		return rpcbackend.RPCErrorResponse(err, rpcReq.ID, rpcbackend.RPCCodeInternalError), err
+	}
+	if decision != nil && decision.Outcome == approval.Defer && decision.Pending != nil {
+		log.L(ctx).Infof("Signing request %s from %s deferred for approval", content.ID, content.From)
+		if decision, err = s.awaitApproval(ctx, content, decision.Pending); err != nil {
+			code := rpcbackend.RPCCodeTimeout
+			if ctx.Err() != nil {
+				code = rpcbackend.RPCCodeInternalError
+			}
+			return s.rejectedByPolicy(ctx, req, rpcbackend.RPCErrorResponse(err, rpcReq.ID, code), err)
+		}
+	}
+
+	switch {
+	case decision != nil && decision.Outcome == approval.Approve:
+		return nil, nil
+	case decision != nil && decision.Outcome == approval.Reject:
+		err := i18n.NewError(ctx, signermsgs.MsgSigningRejected, decision.Reason)
+		return s.rejectedByPolicy(ctx, req, rpcbackend.RPCErrorResponse(err, rpcReq.ID, rpcbackend.RPCCodeInvalidRequest), err)
+	default:
+		var outcome approval.Outcome
+		if decision != nil {
+			outcome = decision.Outcome
+		}
+		err := i18n.NewError(ctx, signermsgs.MsgSigningApprovalBadDecision, outcome)
+		return rpcbackend.RPCErrorResponse(err, rpcReq.ID, rpcbackend.RPCCodeInternalError), err
+	}
+}
+
+// awaitApproval waits for the final decision on a deferred request, until it expires or the request is canceled,
+// when a hook that can be told withdraws it
+func (s *rpcServer) awaitApproval(ctx context.Context, content *approval.Request, pending <-chan *approval.Decision) (*approval.Decision, error) {
+	timer := time.NewTimer(time.Until(content.Expires))
+	defer timer.Stop()
+	var err error
+	select {
+	case decision := <-pending:
+		return decision, nil
+	case <-timer.C:
+		err = i18n.NewError(ctx, signermsgs.MsgSigningApprovalTimedOut, s.approvalTimeout)
+	case <-ctx.Done():
+		err = i18n.NewError(ctx, signermsgs.MsgSigningApprovalCanceled)
+	}
+	log.L(ctx).Warnf("Signing request %s from %s was not approved: %s", content.ID, content.From, err)
+	if hc, ok := s.approvalHook.(approval.HookCancelable); ok {
+		hc.CancelSigning(context.WithoutCancel(ctx), content, err)
+	}
+	return nil, err
+}
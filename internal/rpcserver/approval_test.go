@@ -0,0 +1,233 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/firefly-signer/mocks/ethsignermocks"
+	"github.com/hyperledger/firefly-signer/pkg/approval"
+	"github.com/hyperledger/firefly-signer/pkg/eip712"
+	"github.com/hyperledger/firefly-signer/pkg/ethsigner"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/rpcauth"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type testApprovalHook struct {
+	review   func(req *approval.Request) (*approval.Decision, error)
+	requests []*approval.Request
+}
+
+func (h *testApprovalHook) ReviewSigning(ctx context.Context, req *approval.Request) (*approval.Decision, error) {
+	h.requests = append(h.requests, req)
+	return h.review(req)
+}
+
+type testCancelableApprovalHook struct {
+	testApprovalHook
+	canceled chan error
+}
+
+func (h *testCancelableApprovalHook) CancelSigning(ctx context.Context, req *approval.Request, err error) {
+	h.canceled <- err
+}
+
+func TestApprovalTransactionApproved(t *testing.T) {
+	s, bm, done := newTestService(t)
+	defer done()
+	hook := &testApprovalHook{review: func(req *approval.Request) (*approval.Decision, error) {
+		return approval.Approved(), nil
+	}}
+	var svc Service = s
+	svc.SetApprovalHook(hook)
+
+	bm.On("CallRPC", mock.Anything, mock.Anything, "eth_getTransactionCount", mock.Anything, "pending").Run(func(args mock.Arguments) {
+		*(args[1].(**ethtypes.HexInteger)) = ethtypes.NewHexInteger64(5)
+	}).Return(nil)
+	w := s.wallet.(*ethsignermocks.Wallet)
+	w.On("Sign", mock.Anything, mock.Anything, int64(-1)).Return([]byte{0xaa, 0xbb}, nil)
+
+	ctx := rpcauth.WithIdentity(context.Background(), &rpcauth.Identity{ID: "tenantA"})
+	signed, err := s.SignTransaction(ctx, &ethsigner.Transaction{
+		From: json.RawMessage(`"` + testServiceAddr + `"`),
+		To:   ethtypes.MustNewAddress("0x497eedc4299dea2f2a364be10025d0ad0f702de3"),
+		// ERC-20 transfer
+		Data: ethtypes.MustNewHexBytes0xPrefix("0xa9059cbb" +
+			"0000000000000000000000003c99f2a4b366d46bcf2277639a135a6d1288eceb" +
+			"00000000000000000000000000000000000000000000000000000000000003e8"),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "0xaabb", signed.String())
+
+	assert.Len(t, hook.requests, 1)
+	req := hook.requests[0]
+	assert.NotEmpty(t, req.ID)
+	assert.Equal(t, "eth_signTransaction", req.Method)
+	assert.Equal(t, approval.OperationTransaction, req.Operation)
+	assert.Equal(t, "tenantA", req.Identity)
+	assert.Equal(t, int64(-1), req.ChainID)
+	assert.Equal(t, testServiceAddr, req.From.String())
+	// The nonce is only assigned once it is approved
+	assert.Nil(t, req.Transaction.Nonce)
+	bm.AssertCalled(t, "CallRPC", mock.Anything, mock.Anything, "eth_getTransactionCount", mock.Anything, "pending")
+	assert.Equal(t, "transfer", req.TokenCall.Entry.Name)
+	assert.WithinDuration(t, time.Now().Add(5*time.Minute), req.Expires, time.Minute)
+}
+
+func TestApprovalTransactionRejected(t *testing.T) {
+	s, bm, done := newTestService(t)
+	defer done()
+	s.SetApprovalHook(&testApprovalHook{review: func(req *approval.Request) (*approval.Decision, error) {
+		return approval.Rejected("not today"), nil
+	}})
+
+	_, err := s.SignTransaction(context.Background(), &ethsigner.Transaction{
+		From: json.RawMessage(`"` + testServiceAddr + `"`),
+	})
+	assert.Regexp(t, "FF22283.*not today", err)
+	s.wallet.(*ethsignermocks.Wallet).AssertNotCalled(t, "Sign", mock.Anything, mock.Anything, mock.Anything)
+	// No nonce is assigned to a rejected transaction
+	bm.AssertNotCalled(t, "CallRPC", mock.Anything, mock.Anything, "eth_getTransactionCount", mock.Anything, "pending")
+}
+
+func TestApprovalPersonalSignDeferred(t *testing.T) {
+	_, s, done := newTestServer(t)
+	defer done()
+	s.SetApprovalHook(&testApprovalHook{review: func(req *approval.Request) (*approval.Decision, error) {
+		assert.Equal(t, approval.OperationPersonalMessage, req.Operation)
+		assert.Equal(t, "Hello World", string(req.Message))
+		d, decide := approval.Deferred()
+		go func() {
+			decide(approval.Approved())
+			decide(approval.Rejected("ignored"))
+		}()
+		return d, nil
+	}})
+
+	w := &ethsignermocks.WalletPersonalSign{}
+	s.wallet = w
+	w.On("SignPersonalMessage", mock.Anything, mock.Anything, []byte("Hello World")).
+		Return(ethtypes.MustNewHexBytes0xPrefix("0xaabbcc"), nil)
+
+	rpcRes, err := s.processRPC(s.ctx, personalSignTestRequest())
+	assert.NoError(t, err)
+	assert.Equal(t, `"0xaabbcc"`, rpcRes.Result.String())
+}
+
+func TestApprovalEthSignDeferredRejected(t *testing.T) {
+	_, s, done := newTestServer(t, setTestEthSignConf)
+	defer done()
+	s.SetApprovalHook(&testApprovalHook{review: func(req *approval.Request) (*approval.Decision, error) {
+		assert.Equal(t, approval.OperationDigest, req.Operation)
+		assert.Equal(t, testEthSignDigest, req.Message.String())
+		d, decide := approval.Deferred()
+		decide(approval.Rejected("four eyes said no"))
+		return d, nil
+	}})
+	s.wallet = &ethsignermocks.WalletDigestSign{}
+
+	rpcRes, err := s.processRPC(s.ctx, ethSignTestRequest())
+	assert.Regexp(t, "FF22283.*four eyes said no", err)
+	assert.Equal(t, int64(rpcbackend.RPCCodeInvalidRequest), rpcRes.Error.Code)
+}
+
+func TestApprovalDeferredTimeout(t *testing.T) {
+	_, s, done := newTestServer(t)
+	defer done()
+	s.approvalTimeout = 10 * time.Millisecond
+	hook := &testCancelableApprovalHook{
+		testApprovalHook: testApprovalHook{review: func(req *approval.Request) (*approval.Decision, error) {
+			d, _ := approval.Deferred()
+			return d, nil
+		}},
+		canceled: make(chan error, 1),
+	}
+	s.SetApprovalHook(hook)
+	s.wallet = &ethsignermocks.WalletPersonalSign{}
+
+	rpcRes, err := s.processRPC(s.ctx, personalSignTestRequest())
+	assert.Regexp(t, "FF22284.*10ms", err)
+	assert.Equal(t, int64(rpcbackend.RPCCodeTimeout), rpcRes.Error.Code)
+	assert.Regexp(t, "FF22284", <-hook.canceled)
+}
+
+func TestApprovalDeferredCanceled(t *testing.T) {
+	s, _, done := newTestService(t)
+	defer done()
+	ctx, cancelCtx := context.WithCancel(context.Background())
+	s.SetApprovalHook(&testApprovalHook{review: func(req *approval.Request) (*approval.Decision, error) {
+		assert.Equal(t, approval.OperationTypedData, req.Operation)
+		assert.NotNil(t, req.TypedData)
+		d, _ := approval.Deferred()
+		cancelCtx()
+		return d, nil
+	}})
+	s.wallet = &testTypedDataWallet{Wallet: &ethsignermocks.Wallet{}}
+
+	_, err := s.SignTypedData(ctx, *ethtypes.MustNewAddress(testServiceAddr), &eip712.TypedData{})
+	assert.Regexp(t, "FF22285", err)
+}
+
+func TestApprovalHookFailed(t *testing.T) {
+	_, s, done := newTestServer(t)
+	defer done()
+	s.SetApprovalHook(&testApprovalHook{review: func(req *approval.Request) (*approval.Decision, error) {
+		return nil, fmt.Errorf("pop")
+	}})
+	s.wallet = &ethsignermocks.WalletPersonalSign{}
+
+	rpcRes, err := s.processRPC(s.ctx, personalSignTestRequest())
+	assert.Regexp(t, "FF22286.*pop", err)
+	assert.Equal(t, int64(rpcbackend.RPCCodeInternalError), rpcRes.Error.Code)
+}
+
+func TestApprovalBadDecisions(t *testing.T) {
+	_, s, done := newTestServer(t)
+	defer done()
+	s.wallet = &ethsignermocks.WalletPersonalSign{}
+
+	for _, decision := range []func() *approval.Decision{
+		func() *approval.Decision { return nil },
+		func() *approval.Decision { return &approval.Decision{Outcome: "maybe"} },
+		func() *approval.Decision { return &approval.Decision{Outcome: approval.Defer} },
+		func() *approval.Decision {
+			d, decide := approval.Deferred()
+			decide(nil)
+			return d
+		},
+		func() *approval.Decision {
+			d, decide := approval.Deferred()
+			again, _ := approval.Deferred()
+			decide(again)
+			return d
+		},
+	} {
+		s.SetApprovalHook(&testApprovalHook{review: func(req *approval.Request) (*approval.Decision, error) {
+			return decision(), nil
+		}})
+		rpcRes, err := s.processRPC(s.ctx, personalSignTestRequest())
+		assert.Regexp(t, "FF22287", err)
+		assert.Equal(t, int64(rpcbackend.RPCCodeInternalError), rpcRes.Error.Code)
+	}
+}
@@ -0,0 +1,219 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/hyperledger/firefly-common/pkg/ffresty"
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-common/pkg/log"
+	"github.com/hyperledger/firefly-signer/internal/signerconfig"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/hyperledger/firefly-signer/pkg/ethsigner"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/rpcauth"
+	"golang.org/x/crypto/sha3"
+)
+
+const (
+	auditEventSigned     = "signed"
+	auditEventSignFailed = "sign_failed"
+	auditEventRejected   = "rejected"
+)
+
+// auditEvent is posted to the webhook for every signing operation and policy rejection
+type auditEvent struct {
+	ID            *fftypes.UUID          `json:"id"`
+	Time          *fftypes.FFTime        `json:"time"`
+	Type          string                 `json:"type"`
+	Method        string                 `json:"method"`
+	Operation     string                 `json:"operation"`
+	Identity      string                 `json:"identity,omitempty"`
+	CorrelationID string                 `json:"correlationId,omitempty"`
+	ChainID       int64                  `json:"chainId"`
+	From          *ethtypes.Address0xHex `json:"from,omitempty"`
+	Transaction   *auditTransaction      `json:"transaction,omitempty"`
+	Reason        string                 `json:"reason,omitempty"`
+	Error         string                 `json:"error,omitempty"`
+}
+
+// auditTransaction summarizes a transaction, with the function selector rather than the full calldata
+type auditTransaction struct {
+	Hash                 ethtypes.HexBytes0xPrefix `json:"hash,omitempty"`
+	To                   *ethtypes.Address0xHex    `json:"to,omitempty"`
+	Value                *ethtypes.HexInteger      `json:"value,omitempty"`
+	Nonce                *ethtypes.HexInteger      `json:"nonce,omitempty"`
+	Gas                  *ethtypes.HexInteger      `json:"gas,omitempty"`
+	GasPrice             *ethtypes.HexInteger      `json:"gasPrice,omitempty"`
+	MaxFeePerGas         *ethtypes.HexInteger      `json:"maxFeePerGas,omitempty"`
+	MaxPriorityFeePerGas *ethtypes.HexInteger      `json:"maxPriorityFeePerGas,omitempty"`
+	Selector             ethtypes.HexBytes0xPrefix `json:"selector,omitempty"`
+	DataLength           int                       `json:"dataLength"`
+}
+
+// auditWebhook delivers audit events to a webhook in the background, in the order they occurred, so a slow
+// or unavailable webhook never delays signing. Events are dropped when the queue is full.
+type auditWebhook struct {
+	client          *resty.Client
+	secret          []byte
+	signatureHeader string
+	events          chan *auditEvent
+	done            chan struct{}
+}
+
+func newAuditWebhook(ctx context.Context) (*auditWebhook, error) {
+	conf := signerconfig.AuditWebhookConfig
+	if conf.GetString(ffresty.HTTPConfigURL) == "" {
+		return nil, i18n.NewError(ctx, signermsgs.MsgAuditWebhookNoURL)
+	}
+	queueSize := conf.GetInt(signerconfig.AuditWebhookConfQueueSize)
+	if queueSize <= 0 {
+		return nil, i18n.NewError(ctx, signermsgs.MsgAuditWebhookBadQueueSize, queueSize)
+	}
+	client, err := ffresty.New(ctx, conf)
+	if err != nil {
+		return nil, err
+	}
+	aw := &auditWebhook{
+		client:          client,
+		signatureHeader: conf.GetString(signerconfig.AuditWebhookConfSignatureHeader),
+		events:          make(chan *auditEvent, queueSize),
+	}
+	if secret := conf.GetString(signerconfig.AuditWebhookConfSecret); secret != "" {
+		aw.secret = []byte(secret)
+	}
+	return aw, nil
+}
+
+func (aw *auditWebhook) start(ctx context.Context) {
+	aw.done = make(chan struct{})
+	go aw.run(ctx)
+}
+
+func (aw *auditWebhook) run(ctx context.Context) {
+	defer close(aw.done)
+	for {
+		select {
+		case event := <-aw.events:
+			aw.deliver(ctx, event)
+		case <-ctx.Done():
+			log.L(ctx).Debugf("Audit webhook stopped with %d events undelivered", len(aw.events))
+			return
+		}
+	}
+}
+
+// deliver posts an event, with retries as configured for the webhook. An event that cannot be delivered
+// is logged, so it is not lost entirely.
+func (aw *auditWebhook) deliver(ctx context.Context, event *auditEvent) {
+	body, _ := json.Marshal(event)
+	req := aw.client.R().
+		SetContext(ctx).
+		SetHeader("Content-Type", "application/json").
+		SetBody(body)
+	if aw.secret != nil {
+		mac := hmac.New(sha256.New, aw.secret)
+		mac.Write(body)
+		req.SetHeader(aw.signatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	res, err := req.Post("")
+	if err == nil && !res.IsSuccess() {
+		err = i18n.NewError(ctx, signermsgs.MsgAuditWebhookFailed, res.StatusCode())
+	}
+	if err != nil {
+		log.L(ctx).Errorf("Failed to deliver audit event %s: %s", body, err)
+	}
+}
+
+func (aw *auditWebhook) emit(ctx context.Context, event *auditEvent) {
+	select {
+	case aw.events <- event:
+	default:
+		b, _ := json.Marshal(event)
+		log.L(ctx).Errorf("Audit webhook queue full - dropped audit event %s", b)
+	}
+}
+
+func (aw *auditWebhook) newEvent(ctx context.Context, eventType string, chainID int64, req *signingRequest) *auditEvent {
+	event := &auditEvent{
+		ID:            fftypes.NewUUID(),
+		Time:          fftypes.Now(),
+		Type:          eventType,
+		Method:        req.method,
+		Operation:     req.operation,
+		CorrelationID: getCorrelationID(ctx),
+		ChainID:       chainID,
+		From:          req.from,
+	}
+	if identity := rpcauth.GetIdentity(ctx); identity != nil {
+		event.Identity = identity.ID
+	}
+	if req.txn != nil {
+		event.Transaction = summarizeTransaction(req.txn)
+	}
+	return event
+}
+
+func (aw *auditWebhook) signOperation(ctx context.Context, chainID int64, req *signingRequest, signed []byte, err error) {
+	if err != nil {
+		event := aw.newEvent(ctx, auditEventSignFailed, chainID, req)
+		event.Error = err.Error()
+		aw.emit(ctx, event)
+		return
+	}
+	event := aw.newEvent(ctx, auditEventSigned, chainID, req)
+	if event.Transaction != nil {
+		hash := sha3.NewLegacyKeccak256()
+		hash.Write(signed)
+		event.Transaction.Hash = hash.Sum(nil)
+	}
+	aw.emit(ctx, event)
+}
+
+func (aw *auditWebhook) rejected(ctx context.Context, chainID int64, req *signingRequest, err error) {
+	event := aw.newEvent(ctx, auditEventRejected, chainID, req)
+	event.Reason = outcomeError
+	if ffe, ok := err.(i18n.FFError); ok {
+		event.Reason = string(ffe.MessageKey())
+	}
+	event.Error = err.Error()
+	aw.emit(ctx, event)
+}
+
+func summarizeTransaction(txn *ethsigner.Transaction) *auditTransaction {
+	summary := &auditTransaction{
+		To:                   txn.To,
+		Value:                txn.Value,
+		Nonce:                txn.Nonce,
+		Gas:                  txn.GasLimit,
+		GasPrice:             txn.GasPrice,
+		MaxFeePerGas:         txn.MaxFeePerGas,
+		MaxPriorityFeePerGas: txn.MaxPriorityFeePerGas,
+		DataLength:           len(txn.Data),
+	}
+	if len(txn.Data) >= 4 {
+		summary.Selector = txn.Data[0:4]
+	}
+	return summary
+}
@@ -0,0 +1,241 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hyperledger/firefly-common/pkg/ffresty"
+	"github.com/hyperledger/firefly-common/pkg/fftls"
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-signer/internal/signerconfig"
+	"github.com/hyperledger/firefly-signer/mocks/ethsignermocks"
+	"github.com/hyperledger/firefly-signer/mocks/rpcbackendmocks"
+	"github.com/hyperledger/firefly-signer/pkg/rpcauth"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type testAuditWebhook struct {
+	events    chan *auditEvent
+	status    int
+	failFirst bool
+}
+
+// newTestAuditWebhook starts a webhook receiver, which checks the signature of every event it receives
+func newTestAuditWebhook(t *testing.T) (*testAuditWebhook, *httptest.Server) {
+	tw := &testAuditWebhook{events: make(chan *auditEvent, 10), status: http.StatusNoContent}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if tw.failFirst {
+			tw.failFirst = false
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		mac := hmac.New(sha256.New, []byte("topsecret"))
+		mac.Write(body)
+		assert.Equal(t, "sha256="+hex.EncodeToString(mac.Sum(nil)), r.Header.Get("X-Signer-Signature"))
+		var event auditEvent
+		err = json.Unmarshal(body, &event)
+		assert.NoError(t, err)
+		w.WriteHeader(tw.status)
+		tw.events <- &event
+	}))
+	return tw, server
+}
+
+func setTestAuditWebhookConf(url string) func() {
+	return func() {
+		signerconfig.AuditWebhookConfig.Set(signerconfig.AuditWebhookConfEnabled, true)
+		signerconfig.AuditWebhookConfig.Set(signerconfig.AuditWebhookConfSecret, "topsecret")
+		signerconfig.AuditWebhookConfig.Set(ffresty.HTTPConfigURL, url)
+		signerconfig.AuditWebhookConfig.Set(ffresty.HTTPConfigRetryInitDelay, "1ms")
+	}
+}
+
+func newTestAuditWebhookServer(t *testing.T) (*rpcServer, *testAuditWebhook, func()) {
+	tw, webhook := newTestAuditWebhook(t)
+	_, s, done := newTestServer(t, setTestAuditWebhookConf(webhook.URL))
+	startTestServerNoBackend(t, s)
+	return s, tw, func() {
+		done()
+		webhook.Close()
+	}
+}
+
+func TestAuditWebhookSignedTransaction(t *testing.T) {
+	s, tw, done := newTestAuditWebhookServer(t)
+	defer done()
+
+	w := s.wallet.(*ethsignermocks.Wallet)
+	w.On("Sign", mock.Anything, mock.Anything, int64(12345)).Return([]byte{0x01}, nil)
+	bm := s.backend.(*rpcbackendmocks.Backend)
+	bm.On("SyncRequest", mock.Anything, mock.Anything).Return(&rpcbackend.RPCResponse{Result: fftypes.JSONAnyPtr(`"0x1234"`)}, nil)
+
+	ctx := withCorrelationID(rpcauth.WithIdentity(s.ctx, &rpcauth.Identity{ID: "app1"}), "corr1")
+	_, err := s.processRPC(ctx, &rpcbackend.RPCRequest{
+		ID:     fftypes.JSONAnyPtr("1"),
+		Method: "eth_sendTransaction",
+		Params: []*fftypes.JSONAny{fftypes.JSONAnyPtr(`{
+			"from": "0xfb075bb99f2aa4c49955bf703509a227d7a12248",
+			"to": "0x497eedc4299dea2f2a364be10025d0ad0f702de3",
+			"nonce": "0x5",
+			"gas": "0x5208",
+			"value": "0x64",
+			"data": "0xa9059cbb0000"
+		}`)},
+	})
+	assert.NoError(t, err)
+
+	event := <-tw.events
+	assert.Equal(t, auditEventSigned, event.Type)
+	assert.Equal(t, "eth_sendTransaction", event.Method)
+	assert.Equal(t, signOpTransaction, event.Operation)
+	assert.Equal(t, "app1", event.Identity)
+	assert.Equal(t, "corr1", event.CorrelationID)
+	assert.Equal(t, int64(12345), event.ChainID)
+	assert.Equal(t, "0xfb075bb99f2aa4c49955bf703509a227d7a12248", event.From.String())
+	assert.Equal(t, "0x497eedc4299dea2f2a364be10025d0ad0f702de3", event.Transaction.To.String())
+	assert.Equal(t, int64(100), event.Transaction.Value.Int64())
+	assert.Equal(t, int64(5), event.Transaction.Nonce.Int64())
+	assert.Equal(t, "0xa9059cbb", event.Transaction.Selector.String())
+	assert.Equal(t, 6, event.Transaction.DataLength)
+	// keccak256 of the signed transaction
+	assert.Equal(t, "0x5fe7f977e71dba2ea1a68e21057beebb9be2ac30c6410aa38d4f3fbe41dcffd2", event.Transaction.Hash.String())
+}
+
+func TestAuditWebhookRejected(t *testing.T) {
+	s, tw, done := newTestAuditWebhookServer(t)
+	defer done()
+
+	_, err := s.processRPC(s.ctx, &rpcbackend.RPCRequest{
+		ID:     fftypes.JSONAnyPtr("1"),
+		Method: "eth_sendTransaction",
+		Params: []*fftypes.JSONAny{fftypes.JSONAnyPtr(`{"from": "0xfb075bb99f2aa4c49955bf703509a227d7a12248", "chainId": "0x1"}`)},
+	})
+	assert.Regexp(t, "FF22139", err)
+
+	event := <-tw.events
+	assert.Equal(t, auditEventRejected, event.Type)
+	assert.Equal(t, "FF22139", event.Reason)
+	assert.Regexp(t, "FF22139", event.Error)
+	assert.Empty(t, event.Identity)
+	assert.Nil(t, event.Transaction.Hash)
+
+	s.auditWebhook.rejected(s.ctx, 12345, &signingRequest{method: "personal_sign", operation: signOpPersonalMessage}, fmt.Errorf("pop"))
+	event = <-tw.events
+	assert.Equal(t, outcomeError, event.Reason)
+	assert.Nil(t, event.Transaction)
+}
+
+func TestAuditWebhookSignFailed(t *testing.T) {
+	s, tw, done := newTestAuditWebhookServer(t)
+	defer done()
+
+	w := &ethsignermocks.WalletPersonalSign{}
+	s.wallet = w
+	w.On("SignPersonalMessage", mock.Anything, mock.Anything, mock.Anything).Return(nil, fmt.Errorf("pop"))
+
+	_, err := s.processRPC(s.ctx, personalSignTestRequest())
+	assert.Regexp(t, "pop", err)
+
+	event := <-tw.events
+	assert.Equal(t, auditEventSignFailed, event.Type)
+	assert.Equal(t, "personal_sign", event.Method)
+	assert.Equal(t, signOpPersonalMessage, event.Operation)
+	assert.Equal(t, "pop", event.Error)
+}
+
+func TestAuditWebhookRetry(t *testing.T) {
+	s, tw, done := newTestAuditWebhookServer(t)
+	defer done()
+	tw.failFirst = true
+
+	s.auditWebhook.rejected(s.ctx, 12345, &signingRequest{method: "personal_sign", operation: signOpPersonalMessage}, fmt.Errorf("pop"))
+	event := <-tw.events
+	assert.Equal(t, auditEventRejected, event.Type)
+}
+
+func TestAuditWebhookDeliveryFailed(t *testing.T) {
+	tw, webhook := newTestAuditWebhook(t)
+	defer webhook.Close()
+	tw.status = http.StatusBadRequest
+	signerconfig.Reset()
+	setTestAuditWebhookConf(webhook.URL)()
+	signerconfig.AuditWebhookConfig.Set(ffresty.HTTPConfigRetryEnabled, false)
+	aw, err := newAuditWebhook(context.Background())
+	assert.NoError(t, err)
+
+	// A failure is only logged
+	aw.deliver(context.Background(), &auditEvent{Type: auditEventSigned})
+	assert.Equal(t, auditEventSigned, (<-tw.events).Type)
+
+	webhook.Close()
+	aw.deliver(context.Background(), &auditEvent{Type: auditEventSigned})
+}
+
+func TestAuditWebhookQueueFull(t *testing.T) {
+	signerconfig.Reset()
+	setTestAuditWebhookConf("http://127.0.0.1:1")()
+	signerconfig.AuditWebhookConfig.Set(signerconfig.AuditWebhookConfSecret, "")
+	signerconfig.AuditWebhookConfig.Set(signerconfig.AuditWebhookConfQueueSize, 1)
+	aw, err := newAuditWebhook(context.Background())
+	assert.NoError(t, err)
+	assert.Nil(t, aw.secret)
+
+	aw.emit(context.Background(), &auditEvent{Type: auditEventSigned})
+	aw.emit(context.Background(), &auditEvent{Type: auditEventRejected})
+	assert.Len(t, aw.events, 1)
+
+	// Undelivered events are discarded when the server stops
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	aw.start(ctx)
+	<-aw.done
+}
+
+func TestAuditWebhookBadConfig(t *testing.T) {
+	signerconfig.Reset()
+	signerconfig.AuditWebhookConfig.Set(signerconfig.AuditWebhookConfEnabled, true)
+	_, err := NewServer(context.Background(), &ethsignermocks.Wallet{})
+	assert.Regexp(t, "FF22166", err)
+
+	signerconfig.Reset()
+	setTestAuditWebhookConf("http://127.0.0.1:1")()
+	signerconfig.AuditWebhookConfig.Set(signerconfig.AuditWebhookConfQueueSize, 0)
+	_, err = NewServer(context.Background(), &ethsignermocks.Wallet{})
+	assert.Regexp(t, "FF22167", err)
+
+	signerconfig.Reset()
+	setTestAuditWebhookConf("https://127.0.0.1:1")()
+	tlsConf := signerconfig.AuditWebhookConfig.SubSection("tls")
+	tlsConf.Set(fftls.HTTPConfTLSEnabled, true)
+	tlsConf.Set(fftls.HTTPConfTLSCAFile, "!!!!!badness")
+	_, err = newAuditWebhook(context.Background())
+	assert.Regexp(t, "FF00153", err)
+}
@@ -0,0 +1,134 @@
+// Copyright © 2023 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-signer/internal/signerconfig"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/rpcauth"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+)
+
+func (s *rpcServer) initAuth(ctx context.Context) (err error) {
+	conf := rpcauth.ReadConfig(signerconfig.AuthConfig)
+	if conf.Enabled {
+		if s.authenticators, err = rpcauth.NewAuthenticators(ctx, conf); err != nil {
+			return err
+		}
+	}
+	authorizer, methodFilter, err := s.newAccessControl(ctx, conf)
+	if err != nil {
+		return err
+	}
+	s.authorizer.Store(authorizer)
+	s.methodFilter.Store(methodFilter)
+	return nil
+}
+
+// newAccessControl builds the role based access control and method filter from the configuration. They
+// can be replaced on a reload, so each request uses whichever was current when it was checked.
+func (s *rpcServer) newAccessControl(ctx context.Context, conf *rpcauth.Config) (authorizer *rpcauth.Authorizer, methodFilter *rpcauth.MethodFilter, err error) {
+	if conf.RBAC.Enabled {
+		if s.authenticators == nil {
+			return nil, nil, i18n.NewError(ctx, signermsgs.MsgRBACRequiresAuth)
+		}
+		if authorizer, err = rpcauth.NewAuthorizer(ctx, conf.RBAC.Policies); err != nil {
+			return nil, nil, err
+		}
+	}
+	methodsConf := rpcauth.ReadMethodsConfig(signerconfig.MethodsConfig)
+	if len(methodsConf.Allow) > 0 || len(methodsConf.Deny) > 0 || len(methodsConf.Overrides) > 0 {
+		if methodFilter, err = rpcauth.NewMethodFilter(ctx, methodsConf); err != nil {
+			return nil, nil, err
+		}
+	}
+	return authorizer, methodFilter, nil
+}
+
+// authenticated rejects requests (including WebSocket upgrades) that do not carry valid credentials,
+// before they reach the handler. The identity of the caller is available to the handler in the
+// request context via rpcauth.GetIdentity
+func (s *rpcServer) authenticated(handler http.HandlerFunc) http.HandlerFunc {
+	if s.authenticators == nil {
+		return handler
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		identity, err := rpcauth.Authenticate(ctx, s.authenticators, r)
+		if err != nil {
+			s.replyRPC(ctx, w, rpcbackend.RPCErrorResponse(err, nil, rpcbackend.RPCCodeUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		handler(w, r.WithContext(rpcauth.WithIdentity(ctx, identity)))
+	}
+}
+
+// filterMethod rejects methods that are not allowed for the caller, when a method filter is configured
+func (s *rpcServer) filterMethod(ctx context.Context, rpcReq *rpcbackend.RPCRequest) (*rpcbackend.RPCResponse, error) {
+	methodFilter := s.methodFilter.Load()
+	if methodFilter == nil {
+		return nil, nil
+	}
+	if err := methodFilter.CheckMethod(ctx, rpcauth.GetIdentity(ctx), rpcReq.Method); err != nil {
+		return rpcbackend.RPCErrorResponse(err, rpcReq.ID, rpcbackend.RPCCodeMethodNotFound), err
+	}
+	return nil, nil
+}
+
+// privilegedMethods change the state of keys or nonces for every caller, such as locking keys so nothing can be
+// signed, or resetting the next nonce of an address, so are only served when role based access control grants them
+var privilegedMethods = map[string]bool{
+	"personal_unlockAccount":   true,
+	"personal_lockAccount":     true,
+	"ffsigner_lockAllAccounts": true,
+	"ffsigner_resyncNonce":     true,
+	"ffsigner_fillNonceGaps":   true,
+}
+
+// authorizeMethod checks the caller is granted the use of the method, when role based access control is enabled.
+// Without it, privileged methods are refused.
+func (s *rpcServer) authorizeMethod(ctx context.Context, rpcReq *rpcbackend.RPCRequest) (*rpcbackend.RPCResponse, error) {
+	authorizer := s.authorizer.Load()
+	if authorizer == nil {
+		if privilegedMethods[rpcReq.Method] {
+			err := i18n.NewError(ctx, signermsgs.MsgPrivilegedMethodRequiresRBAC, rpcReq.Method)
+			return rpcbackend.RPCErrorResponse(err, rpcReq.ID, rpcbackend.RPCCodeUnauthorized), err
+		}
+		return nil, nil
+	}
+	if err := authorizer.AuthorizeMethod(ctx, rpcauth.GetIdentity(ctx), rpcReq.Method); err != nil {
+		return rpcbackend.RPCErrorResponse(err, rpcReq.ID, rpcbackend.RPCCodeUnauthorized), err
+	}
+	return nil, nil
+}
+
+// authorizeAddress checks the caller is granted the use of the key for the address, when role based access control is enabled
+func (s *rpcServer) authorizeAddress(ctx context.Context, rpcReq *rpcbackend.RPCRequest, addr *ethtypes.Address0xHex) (*rpcbackend.RPCResponse, error) {
+	authorizer := s.authorizer.Load()
+	if authorizer == nil {
+		return nil, nil
+	}
+	if err := authorizer.AuthorizeAddress(ctx, rpcauth.GetIdentity(ctx), addr); err != nil {
+		return rpcbackend.RPCErrorResponse(err, rpcReq.ID, rpcbackend.RPCCodeUnauthorized), err
+	}
+	return nil, nil
+}
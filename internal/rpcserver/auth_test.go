@@ -0,0 +1,353 @@
+// Copyright © 2023 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-signer/internal/signerconfig"
+	"github.com/hyperledger/firefly-signer/mocks/ethsignermocks"
+	"github.com/hyperledger/firefly-signer/mocks/rpcbackendmocks"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/rpcauth"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func setTestAPIKeyConf() {
+	viper.SetConfigType("yaml")
+	_ = viper.ReadConfig(strings.NewReader(`
+auth:
+  enabled: true
+  apiKeys:
+  - id: client1
+    key: secret1
+`))
+}
+
+func postTestRPC(t *testing.T, url, apiKey, body string) (int, *rpcbackend.RPCResponse) {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader([]byte(body)))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	if apiKey != "" {
+		req.Header.Set(rpcauth.DefaultAPIKeyHeader, apiKey)
+	}
+	res, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer res.Body.Close()
+	var rpcRes rpcbackend.RPCResponse
+	err = json.NewDecoder(res.Body).Decode(&rpcRes)
+	assert.NoError(t, err)
+	return res.StatusCode, &rpcRes
+}
+
+func TestAuthHTTP(t *testing.T) {
+	url, s, done := newTestServer(t, setTestAPIKeyConf)
+	defer done()
+
+	w := s.wallet.(*ethsignermocks.Wallet)
+	w.On("GetAccounts", mock.MatchedBy(func(ctx context.Context) bool {
+		identity := rpcauth.GetIdentity(ctx)
+		return identity != nil && identity.ID == "client1" && identity.Authenticator == "apikey"
+	})).Return([]*ethtypes.Address0xHex{}, nil)
+	startTestServerNoBackend(t, s)
+
+	body := `{"jsonrpc":"2.0","id":1,"method":"eth_accounts"}`
+
+	status, rpcRes := postTestRPC(t, url, "", body)
+	assert.Equal(t, http.StatusUnauthorized, status)
+	assert.Equal(t, int64(rpcbackend.RPCCodeUnauthorized), rpcRes.Error.Code)
+	assert.Regexp(t, "FF22101", rpcRes.Error.Message)
+
+	status, rpcRes = postTestRPC(t, url, "wrong", body)
+	assert.Equal(t, http.StatusUnauthorized, status)
+	assert.Equal(t, int64(rpcbackend.RPCCodeUnauthorized), rpcRes.Error.Code)
+	assert.Regexp(t, "FF22102", rpcRes.Error.Message)
+
+	status, rpcRes = postTestRPC(t, url, "secret1", body)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Nil(t, rpcRes.Error)
+	assert.Equal(t, "[]", rpcRes.Result.String())
+
+	w.AssertExpectations(t)
+}
+
+func TestAuthWebSocket(t *testing.T) {
+	url, s, done := newTestServer(t, setTestAPIKeyConf)
+	defer done()
+	startTestServerNoBackend(t, s)
+	wsURL := strings.Replace(url, "http://", "ws://", 1)
+
+	_, res, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, http.Header{
+		rpcauth.DefaultAPIKeyHeader: []string{"secret1"},
+	})
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	c := waitServerWSConnection(s)
+	assert.Equal(t, "client1", rpcauth.GetIdentity(c.ctx).ID)
+}
+
+func TestAuthDisabled(t *testing.T) {
+	_, s, done := newTestServer(t)
+	defer done()
+	assert.Nil(t, s.authenticators)
+}
+
+func TestAuthBadConfig(t *testing.T) {
+	signerconfig.Reset()
+	signerconfig.AuthConfig.Set(rpcauth.ConfigEnabled, true)
+
+	_, err := NewServer(context.Background(), &ethsignermocks.Wallet{})
+	assert.Regexp(t, "FF22103", err)
+}
+
+func setTestRBACConf() {
+	viper.SetConfigType("yaml")
+	_ = viper.ReadConfig(strings.NewReader(`
+auth:
+  enabled: true
+  apiKeys:
+  - id: tenantA
+    key: secretA
+  rbac:
+    enabled: true
+    policies:
+    - identities:
+      - tenantA
+      methods:
+      - eth_*
+      - personal_*
+      - ffsigner_getPublicKey
+      addresses:
+      - "0xfb075bb99f2aa4c49955bf703509a227d7a12248"
+`))
+}
+
+func newTestRBACServer(t *testing.T) (context.Context, *rpcServer, func()) {
+	_, s, done := newTestServer(t, setTestRBACConf)
+	assert.NotNil(t, s.authorizer.Load())
+	return rpcauth.WithIdentity(s.ctx, &rpcauth.Identity{ID: "tenantA"}), s, done
+}
+
+// grantTestOperator enables role based access control granting every method and address to an operator, who
+// makes the requests of the server context, as privileged methods must be granted explicitly
+func grantTestOperator(t *testing.T, s *rpcServer) {
+	authorizer, err := rpcauth.NewAuthorizer(s.ctx, []*rpcauth.Policy{
+		{Identities: []string{"operator"}, Methods: []string{"*"}, Addresses: []string{"*"}},
+	})
+	assert.NoError(t, err)
+	s.authorizer.Store(authorizer)
+	s.ctx = rpcauth.WithIdentity(s.ctx, &rpcauth.Identity{ID: "operator"})
+}
+
+func assertUnauthorized(t *testing.T, rpcRes *rpcbackend.RPCResponse, err error, errRegexp string) {
+	assert.Regexp(t, errRegexp, err)
+	assert.Equal(t, int64(rpcbackend.RPCCodeUnauthorized), rpcRes.Error.Code)
+}
+
+func TestRBACRequiresAuth(t *testing.T) {
+	signerconfig.Reset()
+	signerconfig.AuthConfig.Set(rpcauth.ConfigRBACEnabled, true)
+
+	_, err := NewServer(context.Background(), &ethsignermocks.Wallet{})
+	assert.Regexp(t, "FF22109", err)
+}
+
+func TestRBACBadPolicy(t *testing.T) {
+	signerconfig.Reset()
+	setTestRBACConf()
+	viper.Set("auth.rbac.policies", []interface{}{map[string]interface{}{"methods": []string{"*"}}})
+
+	_, err := NewServer(context.Background(), &ethsignermocks.Wallet{})
+	assert.Regexp(t, "FF22112", err)
+}
+
+func TestRBACMethodDenied(t *testing.T) {
+	ctx, s, done := newTestRBACServer(t)
+	defer done()
+
+	rpcRes, err := s.processRPC(ctx, &rpcbackend.RPCRequest{
+		ID:     fftypes.JSONAnyPtr("1"),
+		Method: "ffsigner_lockAllAccounts",
+	})
+	assertUnauthorized(t, rpcRes, err, "FF22110.*ffsigner_lockAllAccounts.*tenantA")
+
+	rpcRes, err = s.processRPC(s.ctx, &rpcbackend.RPCRequest{
+		ID:     fftypes.JSONAnyPtr("1"),
+		Method: "eth_chainId",
+	})
+	assertUnauthorized(t, rpcRes, err, "FF22110")
+}
+
+func TestRBACSendTransaction(t *testing.T) {
+	ctx, s, done := newTestRBACServer(t)
+	defer done()
+
+	bm := s.backend.(*rpcbackendmocks.Backend)
+	bm.On("CallRPC", mock.Anything, mock.Anything, "eth_getTransactionCount", mock.Anything, "pending").Return(&rpcbackend.RPCError{Message: "pop"})
+
+	rpcRes, err := s.processRPC(ctx, &rpcbackend.RPCRequest{
+		ID:     fftypes.JSONAnyPtr("1"),
+		Method: "eth_sendTransaction",
+		Params: []*fftypes.JSONAny{fftypes.JSONAnyPtr(`{"from": "0x3c99f2a4b366d46bcf2277639a135a6d1288eceb"}`)},
+	})
+	assertUnauthorized(t, rpcRes, err, "FF22111.*0x3c99f2a4b366d46bcf2277639a135a6d1288eceb.*tenantA")
+
+	rpcRes, err = s.processRPC(ctx, &rpcbackend.RPCRequest{
+		ID:     fftypes.JSONAnyPtr("1"),
+		Method: "eth_sendTransaction",
+		Params: []*fftypes.JSONAny{fftypes.JSONAnyPtr(`{"from": "bad address"}`)},
+	})
+	assert.Regexp(t, "FF22023", err)
+	assert.Equal(t, int64(rpcbackend.RPCCodeInvalidRequest), rpcRes.Error.Code)
+
+	// An authorized address proceeds to signing
+	_, err = s.processRPC(ctx, &rpcbackend.RPCRequest{
+		ID:     fftypes.JSONAnyPtr("1"),
+		Method: "eth_sendTransaction",
+		Params: []*fftypes.JSONAny{fftypes.JSONAnyPtr(`{"from": "0xFB075BB99F2AA4C49955BF703509A227D7A12248"}`)},
+	})
+	assert.Regexp(t, "pop", err)
+	bm.AssertExpectations(t)
+}
+
+func TestRBACAddressMethods(t *testing.T) {
+	ctx, s, done := newTestRBACServer(t)
+	defer done()
+	s.wallet = &ethsignermocks.WalletUnlockable{}
+
+	for _, method := range []string{"personal_unlockAccount", "personal_lockAccount"} {
+		rpcRes, err := s.processRPC(ctx, &rpcbackend.RPCRequest{
+			ID:     fftypes.JSONAnyPtr("1"),
+			Method: method,
+			Params: []*fftypes.JSONAny{fftypes.JSONAnyPtr(`"0x3c99f2a4b366d46bcf2277639a135a6d1288eceb"`)},
+		})
+		assertUnauthorized(t, rpcRes, err, "FF22111")
+	}
+
+	s.wallet = &ethsignermocks.WalletPublicKeys{}
+	rpcRes, err := s.processRPC(ctx, &rpcbackend.RPCRequest{
+		ID:     fftypes.JSONAnyPtr("1"),
+		Method: "ffsigner_getPublicKey",
+		Params: []*fftypes.JSONAny{fftypes.JSONAnyPtr(`"0x3c99f2a4b366d46bcf2277639a135a6d1288eceb"`)},
+	})
+	assertUnauthorized(t, rpcRes, err, "FF22111")
+}
+
+func TestRBACWebSocketSubscribe(t *testing.T) {
+	s, conn, _, _, done := newTestWSBackendServer(t)
+	defer done()
+
+	// The connection has no identity, so is not granted anything
+	authorizer, err := rpcauth.NewAuthorizer(s.ctx, []*rpcauth.Policy{
+		{Identities: []string{"tenantA"}, Methods: []string{"*"}},
+	})
+	assert.NoError(t, err)
+	s.authorizer.Store(authorizer)
+
+	err = conn.WriteJSON(&rpcbackend.RPCRequest{
+		ID:     fftypes.JSONAnyPtr("1"),
+		Method: "eth_subscribe",
+		Params: []*fftypes.JSONAny{fftypes.JSONAnyPtr(`"newHeads"`)},
+	})
+	assert.NoError(t, err)
+	var rpcRes rpcbackend.RPCResponse
+	err = conn.ReadJSON(&rpcRes)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(rpcbackend.RPCCodeUnauthorized), rpcRes.Error.Code)
+	assert.Regexp(t, "FF22110.*eth_subscribe", rpcRes.Error.Message)
+}
+
+func TestRBACEthAccountsFiltered(t *testing.T) {
+	ctx, s, done := newTestRBACServer(t)
+	defer done()
+
+	w := s.wallet.(*ethsignermocks.Wallet)
+	w.On("GetAccounts", mock.Anything).Return([]*ethtypes.Address0xHex{
+		ethtypes.MustNewAddress("0x3c99f2a4b366d46bcf2277639a135a6d1288eceb"),
+		ethtypes.MustNewAddress("0xfb075bb99f2aa4c49955bf703509a227d7a12248"),
+	}, nil)
+
+	rpcRes, err := s.processRPC(ctx, &rpcbackend.RPCRequest{
+		ID:     fftypes.JSONAnyPtr("1"),
+		Method: "eth_requestAccounts",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, `["0xfb075bb99f2aa4c49955bf703509a227d7a12248"]`, rpcRes.Result.String())
+}
+
+func setTestMethodsConf() {
+	viper.SetConfigType("yaml")
+	_ = viper.ReadConfig(strings.NewReader(`
+methods:
+  deny:
+  - debug_*
+  - admin_*
+  overrides:
+  - identities:
+    - operator
+    allow:
+    - "*"
+`))
+}
+
+func TestMethodFilter(t *testing.T) {
+	_, s, done := newTestServer(t, setTestMethodsConf)
+	defer done()
+	assert.NotNil(t, s.methodFilter.Load())
+
+	// Denied methods are never passed to the backend
+	rpcRes, err := s.processRPC(s.ctx, &rpcbackend.RPCRequest{
+		ID:     fftypes.JSONAnyPtr("1"),
+		Method: "debug_traceTransaction",
+	})
+	assert.Regexp(t, "FF22121.*debug_traceTransaction", err)
+	assert.Equal(t, int64(rpcbackend.RPCCodeMethodNotFound), rpcRes.Error.Code)
+
+	bm := s.backend.(*rpcbackendmocks.Backend)
+	bm.On("SyncRequest", mock.Anything, mock.Anything).Return(&rpcbackend.RPCResponse{Result: fftypes.JSONAnyPtr(`"ok"`)}, nil)
+	ctx := rpcauth.WithIdentity(s.ctx, &rpcauth.Identity{ID: "operator"})
+	rpcRes, err = s.processRPC(ctx, &rpcbackend.RPCRequest{
+		ID:     fftypes.JSONAnyPtr("1"),
+		Method: "debug_traceTransaction",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, `"ok"`, rpcRes.Result.String())
+	bm.AssertExpectations(t)
+}
+
+func TestMethodFilterBadConfig(t *testing.T) {
+	signerconfig.Reset()
+	viper.Set("methods.deny", []string{"["})
+
+	_, err := NewServer(context.Background(), &ethsignermocks.Wallet{})
+	assert.Regexp(t, "FF22119", err)
+}
@@ -0,0 +1,119 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"time"
+
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-common/pkg/log"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+)
+
+func (s *rpcServer) queryChainID(ctx context.Context) (int64, error) {
+	var chainID ethtypes.HexInteger
+	if rpcErr := s.backend.CallRPC(ctx, &chainID, "eth_chainId"); rpcErr != nil {
+		return -1, i18n.WrapError(ctx, rpcErr.Error(), signermsgs.MsgQueryChainID)
+	}
+	return chainID.BigInt().Int64(), nil
+}
+
+// initChainID queries the chain ID of the backend at startup, using it for signing if one is not configured,
+// and otherwise checking the configured one matches
+func (s *rpcServer) initChainID(ctx context.Context) error {
+	if s.chainID >= 0 && !s.chainIDValidation {
+		return nil
+	}
+	backendChainID, err := s.queryChainID(ctx)
+	if err != nil {
+		return err
+	}
+	if s.chainID >= 0 && backendChainID != s.chainID {
+		return i18n.NewError(ctx, signermsgs.MsgChainIDMismatch, s.chainID, backendChainID)
+	}
+	s.chainID = backendChainID
+	if s.chainIDValidation && s.chainIDValidationInterval > 0 {
+		s.chainIDMonitorDone = make(chan struct{})
+		go s.runChainIDMonitor()
+	}
+	return nil
+}
+
+// runChainIDMonitor revalidates the chain ID of the backend periodically, as the backend might be
+// reconfigured (or fail over to a node on a different chain) while we are running
+func (s *rpcServer) runChainIDMonitor() {
+	defer close(s.chainIDMonitorDone)
+	ticker := time.NewTicker(s.chainIDValidationInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.ctx.Done():
+			log.L(s.ctx).Debugf("Chain ID monitor stopped")
+			return
+		case <-ticker.C:
+			s.validateChainID(s.ctx)
+		}
+	}
+}
+
+func (s *rpcServer) validateChainID(ctx context.Context) {
+	backendChainID, err := s.queryChainID(ctx)
+	if err != nil {
+		log.L(ctx).Warnf("Failed to revalidate chain ID: %s", err)
+		return
+	}
+	s.backendChainID.Store(backendChainID)
+	mismatch := backendChainID != s.chainID
+	if s.chainIDMismatch.Swap(mismatch) != mismatch {
+		if mismatch {
+			log.L(ctx).Errorf("Chain ID of the backend changed to %d, from %d. Transactions are refused until it matches again", backendChainID, s.chainID)
+		} else {
+			log.L(ctx).Infof("Chain ID of the backend matches %d again", s.chainID)
+		}
+	}
+}
+
+// checkChainID refuses to sign a transaction when the backend is on a different chain to the signer, or
+// the transaction has a chain ID that differs
+func (s *rpcServer) checkChainID(ctx context.Context, rpcReq *rpcbackend.RPCRequest, txnJSON []byte) (*rpcbackend.RPCResponse, error) {
+	// Only the chain ID of the backend is revalidated while we are running
+	if s.chainIDMismatch.Load() && getChainRoute(ctx) == nil {
+		err := i18n.NewError(ctx, signermsgs.MsgChainIDMismatchRefused, s.backendChainID.Load(), s.chainID)
+		return rpcbackend.RPCErrorResponse(err, rpcReq.ID, rpcbackend.RPCCodeInternalError), err
+	}
+	if txnJSON == nil {
+		return nil, nil
+	}
+	var txn struct {
+		ChainID *ethtypes.HexInteger `json:"chainId"`
+	}
+	if err := json.Unmarshal(txnJSON, &txn); err != nil {
+		err := i18n.WrapError(ctx, err, signermsgs.MsgInvalidTransaction)
+		return rpcbackend.RPCErrorResponse(err, rpcReq.ID, rpcbackend.RPCCodeParseError), err
+	}
+	chainID := s.chainIDFor(ctx)
+	if txn.ChainID != nil && txn.ChainID.BigInt().Cmp(big.NewInt(chainID)) != 0 {
+		err := i18n.NewError(ctx, signermsgs.MsgTxnChainIDMismatch, txn.ChainID.BigInt(), chainID)
+		return rpcbackend.RPCErrorResponse(err, rpcReq.ID, rpcbackend.RPCCodeInvalidRequest), err
+	}
+	return nil, nil
+}
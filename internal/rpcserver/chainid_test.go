@@ -0,0 +1,142 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-signer/internal/signerconfig"
+	"github.com/hyperledger/firefly-signer/mocks/ethsignermocks"
+	"github.com/hyperledger/firefly-signer/mocks/rpcbackendmocks"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/nonces"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func mockChainID(bm *rpcbackendmocks.Backend, chainID int64) *mock.Call {
+	return bm.On("CallRPC", mock.Anything, mock.Anything, "eth_chainId").Run(func(args mock.Arguments) {
+		args[1].(*ethtypes.HexInteger).BigInt().SetInt64(chainID)
+	}).Return(nil)
+}
+
+func TestStartChainIDMismatch(t *testing.T) {
+	_, s, done := newTestServer(t, func() {
+		config.Set(signerconfig.BackendChainID, 12345)
+	})
+	defer done()
+
+	mockChainID(s.backend.(*rpcbackendmocks.Backend), 1)
+	err := s.Start()
+	assert.Regexp(t, "FF22137.*12,345.*1", err)
+}
+
+func TestStartChainIDValidated(t *testing.T) {
+	_, s, done := newTestServer(t, func() {
+		config.Set(signerconfig.BackendChainID, 12345)
+		config.Set(signerconfig.BackendChainIDValidationInterval, "1ms")
+	})
+	defer done()
+
+	mockChainID(s.backend.(*rpcbackendmocks.Backend), 12345)
+	s.wallet.(*ethsignermocks.Wallet).On("Initialize", mock.Anything).Return(nil)
+	err := s.Start()
+	assert.NoError(t, err)
+	assert.NotNil(t, s.chainIDMonitorDone)
+
+	// The monitor revalidates periodically
+	for s.backendChainID.Load() != 12345 {
+		time.Sleep(1 * time.Millisecond)
+	}
+}
+
+func TestStartChainIDNotValidated(t *testing.T) {
+	_, s, done := newTestServer(t, func() {
+		config.Set(signerconfig.BackendChainID, 12345)
+		config.Set(signerconfig.BackendChainIDValidationEnabled, false)
+	})
+	defer done()
+
+	s.wallet.(*ethsignermocks.Wallet).On("Initialize", mock.Anything).Return(nil)
+	err := s.Start()
+	assert.NoError(t, err)
+	assert.Nil(t, s.chainIDMonitorDone)
+	assert.Equal(t, int64(12345), s.chainID)
+}
+
+func TestValidateChainID(t *testing.T) {
+	_, s, done := newTestServer(t)
+	defer done()
+	s.chainID = 12345
+	bm := s.backend.(*rpcbackendmocks.Backend)
+
+	// A failure to query leaves the state unchanged
+	bm.On("CallRPC", mock.Anything, mock.Anything, "eth_chainId").Return(&rpcbackend.RPCError{Message: "pop"}).Once()
+	s.validateChainID(s.ctx)
+	assert.False(t, s.chainIDMismatch.Load())
+
+	mockChainID(bm, 1).Once()
+	s.validateChainID(s.ctx)
+	assert.True(t, s.chainIDMismatch.Load())
+
+	// Transactions are refused while the chain ID differs
+	rpcRes, err := s.processRPC(s.ctx, feesTestRequest(""))
+	assert.Regexp(t, "FF22138", err)
+	assert.Equal(t, int64(rpcbackend.RPCCodeInternalError), rpcRes.Error.Code)
+	s.nonceManager = nonces.NewManager(nonces.NewMemoryStore(), s.transactionCount, &nonces.Config{})
+	grantTestOperator(t, s)
+	_, err = s.processRPC(s.ctx, nonceAdminRequest("ffsigner_fillNonceGaps"))
+	assert.Regexp(t, "FF22138", err)
+
+	mockChainID(bm, 1).Once()
+	s.validateChainID(s.ctx)
+	assert.True(t, s.chainIDMismatch.Load())
+
+	mockChainID(bm, 12345).Once()
+	s.validateChainID(s.ctx)
+	assert.False(t, s.chainIDMismatch.Load())
+	bm.AssertExpectations(t)
+}
+
+func TestTransactionChainIDMismatch(t *testing.T) {
+	_, s, done := newTestServer(t)
+	defer done()
+	s.chainID = 12345
+
+	rpcRes, err := s.processRPC(s.ctx, feesTestRequest(`, "chainId": "0x1"`))
+	assert.Regexp(t, "FF22139", err)
+	assert.Equal(t, int64(rpcbackend.RPCCodeInvalidRequest), rpcRes.Error.Code)
+
+	rpcRes, err = s.processRPC(s.ctx, feesTestRequest(`, "chainId": "wrong"`))
+	assert.Regexp(t, "FF22023", err)
+	assert.Equal(t, int64(rpcbackend.RPCCodeParseError), rpcRes.Error.Code)
+
+	// A matching chain ID is accepted
+	w := s.wallet.(*ethsignermocks.Wallet)
+	w.On("Sign", mock.Anything, mock.Anything, int64(12345)).Return(nil, fmt.Errorf("pop")).Once()
+	_, err = s.processRPC(s.ctx, &rpcbackend.RPCRequest{
+		ID:     fftypes.JSONAnyPtr("1"),
+		Method: "eth_sendTransaction",
+		Params: []*fftypes.JSONAny{fftypes.JSONAnyPtr(`{"from": "0xfb075bb99f2aa4c49955bf703509a227d7a12248", "nonce": "0x0", "chainId": "0x3039"}`)},
+	})
+	assert.Regexp(t, "pop", err)
+}
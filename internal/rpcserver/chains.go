@@ -0,0 +1,224 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-common/pkg/log"
+	"github.com/hyperledger/firefly-signer/internal/signerconfig"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+)
+
+// chainRoute is an additional chain served by the signer, with its own backend and the chain ID that
+// transactions routed to it are signed for
+type chainRoute struct {
+	name    string
+	chainID int64
+	backend rpcbackend.Backend
+}
+
+// chainRoutes select the chain each request is routed to. Requests that select no chain are routed to
+// the backend, so a signer with no additional chains behaves exactly as it did before they existed.
+type chainRoutes struct {
+	header string
+	byName map[string]*chainRoute
+	byID   map[int64]*chainRoute
+}
+
+// initChains builds a backend for each additional chain, and routes every backend call by the chain of
+// the request. It does nothing when no additional chains are configured.
+func (s *rpcServer) initChains(ctx context.Context) error {
+	size := signerconfig.ChainNetworksConfig.ArraySize()
+	if size == 0 {
+		return nil
+	}
+	cr := &chainRoutes{
+		header: signerconfig.ChainsConfig.GetString(signerconfig.ChainsConfHeader),
+		byName: make(map[string]*chainRoute, size),
+		byID:   make(map[int64]*chainRoute, size),
+	}
+	for i := 0; i < size; i++ {
+		entry := signerconfig.ChainNetworksConfig.ArrayEntry(i)
+		route := &chainRoute{
+			name:    entry.GetString(signerconfig.ChainsConfName),
+			chainID: entry.GetInt64(signerconfig.ChainsConfChainID),
+		}
+		url := entry.GetString(signerconfig.ChainsConfURL)
+		switch {
+		case route.name == "":
+			return i18n.NewError(ctx, signermsgs.MsgBadChainNetwork, i, "no name")
+		case cr.byName[route.name] != nil:
+			return i18n.NewError(ctx, signermsgs.MsgBadChainNetwork, i, "duplicate name")
+		case route.chainID < 0:
+			return i18n.NewError(ctx, signermsgs.MsgBadChainNetwork, i, "no chainId")
+		case cr.byID[route.chainID] != nil:
+			return i18n.NewError(ctx, signermsgs.MsgBadChainNetwork, i, "duplicate chainId")
+		case url == "" || isStreamingURL(url):
+			// Subscriptions are only supported on the backend, so there is no need for a persistent connection
+			return i18n.NewError(ctx, signermsgs.MsgBadChainNetwork, i, "url must be an HTTP URL")
+		}
+		gen, err := s.newHTTPBackend(ctx, []string{url})
+		if err != nil {
+			return err
+		}
+		route.backend = gen.backend
+		cr.byName[route.name] = route
+		cr.byID[route.chainID] = route
+	}
+	s.chains = cr
+	s.backend = &chainRoutedBackend{defaultBackend: s.backend}
+	return nil
+}
+
+// validateChains checks the backend of each additional chain is on the configured chain, once the chain ID
+// of the backend is known
+func (s *rpcServer) validateChains(ctx context.Context) error {
+	for _, route := range s.chains.byName {
+		if err := s.validateChain(ctx, route); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *rpcServer) validateChain(ctx context.Context, route *chainRoute) error {
+	if route.chainID == s.chainID {
+		return i18n.NewError(ctx, signermsgs.MsgChainNetworkConflict, route.name, route.chainID)
+	}
+	if !s.chainIDValidation {
+		return nil
+	}
+	var chainID ethtypes.HexInteger
+	if rpcErr := route.backend.CallRPC(ctx, &chainID, "eth_chainId"); rpcErr != nil {
+		return i18n.WrapError(ctx, rpcErr.Error(), signermsgs.MsgQueryChainID)
+	}
+	if chainID.BigInt().Int64() != route.chainID {
+		return i18n.NewError(ctx, signermsgs.MsgChainIDMismatch, route.chainID, chainID.BigInt().Int64())
+	}
+	log.L(ctx).Infof("Routing requests for chain '%s' to a backend with chain ID %d", route.name, route.chainID)
+	return nil
+}
+
+// lookup finds a chain by name or chain ID. Selecting the chain ID of the backend is allowed, and
+// returns a nil route.
+func (cr *chainRoutes) lookup(selector string, backendChainID int64) (*chainRoute, bool) {
+	if route := cr.byName[selector]; route != nil {
+		return route, true
+	}
+	chainID, err := strconv.ParseInt(selector, 0, 64)
+	if err != nil {
+		return nil, false
+	}
+	if route := cr.byID[chainID]; route != nil {
+		return route, true
+	}
+	return nil, chainID == backendChainID
+}
+
+type chainRouteContextKey struct{}
+
+func withChainRoute(ctx context.Context, route *chainRoute) context.Context {
+	return context.WithValue(ctx, chainRouteContextKey{}, route)
+}
+
+// getChainRoute returns the additional chain the request is routed to, or nil for the backend
+func getChainRoute(ctx context.Context) *chainRoute {
+	route, _ := ctx.Value(chainRouteContextKey{}).(*chainRoute)
+	return route
+}
+
+// chainIDFor returns the chain ID transactions are signed for, which is that of the chain the request is routed to
+func (s *rpcServer) chainIDFor(ctx context.Context) int64 {
+	if route := getChainRoute(ctx); route != nil {
+		return route.chainID
+	}
+	return s.chainID
+}
+
+// chainRouted records the chain selected in the URL path or the chain header in the request context
+func (s *rpcServer) chainRouted(handler http.HandlerFunc) http.HandlerFunc {
+	if s.chains == nil {
+		return handler
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		selector := mux.Vars(r)["chain"]
+		if selector == "" {
+			selector = r.Header.Get(s.chains.header)
+		}
+		if selector != "" {
+			route, ok := s.chains.lookup(selector, s.chainID)
+			if !ok {
+				err := i18n.NewError(ctx, signermsgs.MsgUnknownChain, selector)
+				s.replyRPC(ctx, w, rpcbackend.RPCErrorResponse(err, nil, rpcbackend.RPCCodeInvalidRequest), http.StatusNotFound)
+				return
+			}
+			ctx = withChainRoute(ctx, route)
+		}
+		handler(w, r.WithContext(ctx))
+	}
+}
+
+// routeByTransactionChainID routes a transaction that selects no chain by its chainId, when it is the chain
+// ID of an additional chain. Any other chainId is left to be checked against the chain ID of the backend.
+func (s *rpcServer) routeByTransactionChainID(ctx context.Context, txnJSON []byte) context.Context {
+	if s.chains == nil || getChainRoute(ctx) != nil {
+		return ctx
+	}
+	var txn struct {
+		ChainID *ethtypes.HexInteger `json:"chainId"`
+	}
+	if json.Unmarshal(txnJSON, &txn) != nil || txn.ChainID == nil || !txn.ChainID.BigInt().IsInt64() {
+		return ctx
+	}
+	if route := s.chains.byID[txn.ChainID.BigInt().Int64()]; route != nil {
+		return withChainRoute(ctx, route)
+	}
+	return ctx
+}
+
+// chainRoutedBackend passes each call to the backend of the chain the request is routed to
+type chainRoutedBackend struct {
+	defaultBackend rpcbackend.Backend
+}
+
+func (cb *chainRoutedBackend) backendFor(ctx context.Context) rpcbackend.Backend {
+	if route := getChainRoute(ctx); route != nil {
+		return route.backend
+	}
+	return cb.defaultBackend
+}
+
+func (cb *chainRoutedBackend) CallRPC(ctx context.Context, result interface{}, method string, params ...interface{}) *rpcbackend.RPCError {
+	return cb.backendFor(ctx).CallRPC(ctx, result, method, params...)
+}
+
+func (cb *chainRoutedBackend) SyncRequest(ctx context.Context, rpcReq *rpcbackend.RPCRequest) (*rpcbackend.RPCResponse, error) {
+	return cb.backendFor(ctx).SyncRequest(ctx, rpcReq)
+}
+
+func (cb *chainRoutedBackend) BatchRequest(ctx context.Context, rpcReqs []*rpcbackend.RPCRequest) ([]*rpcbackend.RPCResponse, error) {
+	return cb.backendFor(ctx).BatchRequest(ctx, rpcReqs)
+}
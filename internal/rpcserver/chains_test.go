@@ -0,0 +1,314 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/hyperledger/firefly-common/pkg/ffresty"
+	"github.com/hyperledger/firefly-common/pkg/fftls"
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-common/pkg/httpserver"
+	"github.com/hyperledger/firefly-signer/internal/signerconfig"
+	"github.com/hyperledger/firefly-signer/mocks/ethsignermocks"
+	"github.com/hyperledger/firefly-signer/mocks/rpcbackendmocks"
+	"github.com/hyperledger/firefly-signer/pkg/ethsigner"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/nonces"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func setTestChainConf(networks ...string) func() {
+	return func() {
+		viper.SetConfigType("yaml")
+		_ = viper.ReadConfig(strings.NewReader("chains:\n  networks:\n  - " + strings.Join(networks, "\n  - ") + "\n"))
+	}
+}
+
+func testChainNetwork(name string, chainID int64) string {
+	return fmt.Sprintf("{name: '%s', chainId: %d, url: 'http://127.0.0.1:1'}", name, chainID)
+}
+
+// newTestChainsServer starts a server with the backend on chain 12345, and an additional chain "other" on chain 2222
+func newTestChainsServer(t *testing.T) (string, *rpcServer, *rpcbackendmocks.Backend, *rpcbackendmocks.Backend, func()) {
+	url, s, done := newTestServer(t, setTestChainConf(testChainNetwork("other", 2222)))
+	defaultBackend := s.backend.(*rpcbackendmocks.Backend)
+	chainBackend := &rpcbackendmocks.Backend{}
+	s.chains.byName["other"].backend = chainBackend
+	s.backend = &chainRoutedBackend{defaultBackend: defaultBackend}
+	startTestServerNoBackend(t, s)
+	return url, s, defaultBackend, chainBackend, done
+}
+
+func postTestChainRPC(t *testing.T, url string, header http.Header, body string) (int, *rpcbackend.RPCResponse) {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader([]byte(body)))
+	assert.NoError(t, err)
+	req.Header = header
+	res, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer res.Body.Close()
+	var rpcRes rpcbackend.RPCResponse
+	err = json.NewDecoder(res.Body).Decode(&rpcRes)
+	assert.NoError(t, err)
+	return res.StatusCode, &rpcRes
+}
+
+func mockTestBlockNumber(bm *rpcbackendmocks.Backend, blockNumber string) {
+	bm.On("SyncRequest", mock.Anything, mock.MatchedBy(func(rpcReq *rpcbackend.RPCRequest) bool {
+		return rpcReq.Method == "eth_blockNumber"
+	})).Return(&rpcbackend.RPCResponse{Result: fftypes.JSONAnyPtr(blockNumber)}, nil)
+}
+
+func TestChainsConfigErrors(t *testing.T) {
+	for _, tc := range []struct {
+		reason  string
+		entries []string
+	}{
+		{"no name", []string{testChainNetwork("", 2222)}},
+		{"duplicate name", []string{testChainNetwork("other", 2222), testChainNetwork("other", 3333)}},
+		{"no chainId", []string{"{name: other, url: 'http://127.0.0.1:1'}"}},
+		{"duplicate chainId", []string{testChainNetwork("other", 2222), testChainNetwork("another", 2222)}},
+		{"url must be an HTTP URL", []string{"{name: other, chainId: 2222, url: 'ws://127.0.0.1:1'}"}},
+		{"url must be an HTTP URL", []string{"{name: other, chainId: 2222}"}},
+	} {
+		signerconfig.Reset()
+		setTestChainConf(tc.entries...)()
+		signerconfig.ServerConfig.Set(httpserver.HTTPConfPort, 0)
+		_, err := NewServer(context.Background(), &ethsignermocks.Wallet{})
+		assert.Regexp(t, "FF22160.*"+tc.reason, err)
+	}
+}
+
+func TestChainsBadBackendConfig(t *testing.T) {
+	signerconfig.Reset()
+	setTestChainConf(testChainNetwork("other", 2222))()
+	signerconfig.BackendConfig.Set(ffresty.HTTPConfigURL, "ws://127.0.0.1:1")
+	signerconfig.ServerConfig.Set(httpserver.HTTPConfPort, 0)
+	s := &rpcServer{ctx: context.Background()}
+	tlsConf := signerconfig.BackendConfig.SubSection("tls")
+	tlsConf.Set(fftls.HTTPConfTLSEnabled, true)
+	tlsConf.Set(fftls.HTTPConfTLSCAFile, "!!!!!badness")
+	err := s.initChains(context.Background())
+	assert.Regexp(t, "FF00153", err)
+}
+
+func TestChainsNotConfigured(t *testing.T) {
+	_, s, done := newTestServer(t)
+	defer done()
+	assert.Nil(t, s.chains)
+	assert.Equal(t, context.Background(), s.routeByTransactionChainID(context.Background(), []byte(`{"chainId":"0x8ae"}`)))
+}
+
+func TestChainsRouteByHeaderAndPath(t *testing.T) {
+	url, _, defaultBackend, chainBackend, done := newTestChainsServer(t)
+	defer done()
+	mockTestBlockNumber(defaultBackend, `"0x1"`)
+	mockTestBlockNumber(chainBackend, `"0x2"`)
+
+	body := `{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber"}`
+	for _, tc := range []struct {
+		path     string
+		selector string
+		result   string
+	}{
+		{"", "", `"0x1"`},
+		{"", "12345", `"0x1"`},
+		{"", "other", `"0x2"`},
+		{"", "0x8ae", `"0x2"`},
+		{"/chains/other", "", `"0x2"`},
+		{"/chains/2222", "", `"0x2"`},
+		{"/chains/12345", "other", `"0x1"`},
+	} {
+		header := http.Header{"Content-Type": []string{"application/json"}}
+		if tc.selector != "" {
+			header.Set("X-Chain", tc.selector)
+		}
+		status, rpcRes := postTestChainRPC(t, url+tc.path, header, body)
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, tc.result, rpcRes.Result.String())
+	}
+}
+
+func TestChainsUnknownChain(t *testing.T) {
+	url, _, _, _, done := newTestChainsServer(t)
+	defer done()
+
+	body := `{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber"}`
+	status, rpcRes := postTestChainRPC(t, url+"/chains/unknown", http.Header{}, body)
+	assert.Equal(t, http.StatusNotFound, status)
+	assert.Regexp(t, "FF22161.*unknown", rpcRes.Error.Message)
+
+	status, rpcRes = postTestChainRPC(t, url, http.Header{"X-Chain": []string{"9999"}}, body)
+	assert.Equal(t, http.StatusNotFound, status)
+	assert.Regexp(t, "FF22161.*9999", rpcRes.Error.Message)
+}
+
+func TestChainsSendTransactionRoutedByChainID(t *testing.T) {
+	_, s, defaultBackend, chainBackend, done := newTestChainsServer(t)
+	defer done()
+	s.nonceManager = nonces.NewManager(nonces.NewMemoryStore(), s.transactionCount, &nonces.Config{})
+
+	// The nonce comes from the node of the chain, rather than the nonce manager of the backend
+	chainBackend.On("CallRPC", mock.Anything, mock.Anything, "eth_getTransactionCount", mock.Anything, "pending").Run(func(args mock.Arguments) {
+		*(args[1].(**ethtypes.HexInteger)) = ethtypes.NewHexIntegerU64(3)
+	}).Return(nil).Once()
+	chainBackend.On("SyncRequest", mock.Anything, mock.MatchedBy(func(rpcReq *rpcbackend.RPCRequest) bool {
+		return rpcReq.Method == "eth_sendRawTransaction"
+	})).Return(&rpcbackend.RPCResponse{Result: fftypes.JSONAnyPtr(`"0x1234"`)}, nil).Once()
+	w := s.wallet.(*ethsignermocks.Wallet)
+	w.On("Sign", mock.Anything, mock.MatchedBy(func(txn *ethsigner.Transaction) bool {
+		return txn.Nonce.Int64() == 3
+	}), int64(2222)).Return([]byte{0x01}, nil).Once()
+
+	rpcRes, err := s.processRPC(s.ctx, &rpcbackend.RPCRequest{
+		ID:     fftypes.JSONAnyPtr("1"),
+		Method: "eth_sendTransaction",
+		Params: []*fftypes.JSONAny{fftypes.JSONAnyPtr(`{"from":"0xfb075bb99f2aa4c49955bf703509a227d7a12248","chainId":"0x8ae"}`)},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, `"0x1234"`, rpcRes.Result.String())
+	w.AssertExpectations(t)
+	chainBackend.AssertExpectations(t)
+	defaultBackend.AssertExpectations(t)
+}
+
+func TestChainsSendTransactionChainIDMismatch(t *testing.T) {
+	_, s, _, _, done := newTestChainsServer(t)
+	defer done()
+
+	// A transaction routed to a chain must be for that chain
+	ctx := withChainRoute(s.ctx, s.chains.byName["other"])
+	_, err := s.processRPC(ctx, &rpcbackend.RPCRequest{
+		ID:     fftypes.JSONAnyPtr("1"),
+		Method: "eth_sendTransaction",
+		Params: []*fftypes.JSONAny{fftypes.JSONAnyPtr(`{"from":"0xfb075bb99f2aa4c49955bf703509a227d7a12248","chainId":"0x3039"}`)},
+	})
+	assert.Regexp(t, "FF22139.*12345.*2,222", err)
+
+	// A chain that is not routed is left to the check against the backend
+	ctx = s.routeByTransactionChainID(s.ctx, []byte(`{"chainId":"0x270f"}`))
+	assert.Nil(t, getChainRoute(ctx))
+	ctx = s.routeByTransactionChainID(s.ctx, []byte(`!!! not JSON`))
+	assert.Nil(t, getChainRoute(ctx))
+}
+
+func TestChainsResponseCacheBypassed(t *testing.T) {
+	_, s, _, chainBackend, done := newTestChainsServer(t)
+	defer done()
+	config.Set(signerconfig.ResponseCacheEnabled, true)
+	rc, err := newResponseCache(s.ctx)
+	assert.NoError(t, err)
+	s.responseCache = rc
+	chainBackend.On("SyncRequest", mock.Anything, mock.Anything).Return(&rpcbackend.RPCResponse{Result: fftypes.JSONAnyPtr(`"0x2"`)}, nil).Twice()
+
+	ctx := withChainRoute(s.ctx, s.chains.byName["other"])
+	for i := 0; i < 2; i++ {
+		_, err := s.processRPC(ctx, testRPCRequest(1, "eth_getCode", `"0xfb075bb99f2aa4c49955bf703509a227d7a12248"`, `"0x1"`))
+		assert.NoError(t, err)
+	}
+	chainBackend.AssertExpectations(t)
+}
+
+func TestChainsWebSocketRouted(t *testing.T) {
+	url, _, _, chainBackend, done := newTestChainsServer(t)
+	defer done()
+	mockTestBlockNumber(chainBackend, `"0x2"`)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+url[len("http"):]+"/chains/other", nil)
+	assert.NoError(t, err)
+	defer conn.Close()
+	res := wsRoundTrip(t, conn, `{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber"}`)
+	assert.Equal(t, "0x2", res["result"])
+}
+
+func TestChainsValidate(t *testing.T) {
+	_, s, done := newTestServer(t, setTestChainConf(testChainNetwork("other", 2222)))
+	defer done()
+	chainBackend := &rpcbackendmocks.Backend{}
+	s.chains.byName["other"].backend = chainBackend
+
+	// The chain ID of an additional chain must not be the chain ID of the backend
+	s.chainID = 2222
+	err := s.validateChains(s.ctx)
+	assert.Regexp(t, "FF22162.*other", err)
+
+	s.chainID = 12345
+	s.chainIDValidation = false
+	err = s.validateChains(s.ctx)
+	assert.NoError(t, err)
+
+	s.chainIDValidation = true
+	chainBackend.On("CallRPC", mock.Anything, mock.Anything, "eth_chainId").Return(&rpcbackend.RPCError{Message: "pop"}).Once()
+	err = s.validateChains(s.ctx)
+	assert.Regexp(t, "pop", err)
+
+	chainBackend.On("CallRPC", mock.Anything, mock.Anything, "eth_chainId").Run(func(args mock.Arguments) {
+		args[1].(*ethtypes.HexInteger).BigInt().SetInt64(3333)
+	}).Return(nil).Once()
+	err = s.validateChains(s.ctx)
+	assert.Regexp(t, "FF22137.*2,222.*3,333", err)
+
+	chainBackend.On("CallRPC", mock.Anything, mock.Anything, "eth_chainId").Run(func(args mock.Arguments) {
+		args[1].(*ethtypes.HexInteger).BigInt().SetInt64(2222)
+	}).Return(nil).Once()
+	err = s.validateChains(s.ctx)
+	assert.NoError(t, err)
+}
+
+func TestChainsStartValidateFail(t *testing.T) {
+	_, s, done := newTestServer(t, setTestChainConf(testChainNetwork("other", 12345)))
+	defer done()
+	s.chainID = 12345
+	s.chainIDValidation = false
+	err := s.Start()
+	assert.Regexp(t, "FF22162", err)
+}
+
+func TestChainRoutedBackend(t *testing.T) {
+	defaultBackend := &rpcbackendmocks.Backend{}
+	chainBackend := &rpcbackendmocks.Backend{}
+	cb := &chainRoutedBackend{defaultBackend: defaultBackend}
+	ctx := withChainRoute(context.Background(), &chainRoute{name: "other", chainID: 2222, backend: chainBackend})
+
+	chainBackend.On("CallRPC", ctx, mock.Anything, "eth_chainId").Return(nil).Once()
+	chainBackend.On("SyncRequest", ctx, mock.Anything).Return(&rpcbackend.RPCResponse{}, nil).Once()
+	chainBackend.On("BatchRequest", ctx, mock.Anything).Return([]*rpcbackend.RPCResponse{}, nil).Once()
+	defaultBackend.On("BatchRequest", context.Background(), mock.Anything).Return([]*rpcbackend.RPCResponse{}, nil).Once()
+
+	var chainID ethtypes.HexInteger
+	assert.Nil(t, cb.CallRPC(ctx, &chainID, "eth_chainId"))
+	_, err := cb.SyncRequest(ctx, &rpcbackend.RPCRequest{})
+	assert.NoError(t, err)
+	_, err = cb.BatchRequest(ctx, []*rpcbackend.RPCRequest{})
+	assert.NoError(t, err)
+	_, err = cb.BatchRequest(context.Background(), []*rpcbackend.RPCRequest{})
+	assert.NoError(t, err)
+
+	chainBackend.AssertExpectations(t)
+	defaultBackend.AssertExpectations(t)
+}
@@ -0,0 +1,145 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"context"
+	"sort"
+
+	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-signer/internal/signerconfig"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/hyperledger/firefly-signer/pkg/rpcauth"
+)
+
+// newCheckServer returns a server with only the state needed to validate the configuration. It is never
+// started, so binds no ports, and its background routines stop when the returned function is called.
+func newCheckServer(ctx context.Context) *rpcServer {
+	s := &rpcServer{
+		chainID: config.GetInt64(signerconfig.BackendChainID),
+	}
+	s.ctx, s.cancelCtx = context.WithCancel(ctx)
+	return s
+}
+
+// CheckConfig validates the configuration of the server without creating it, so no ports are bound and
+// nothing is sent to the backend. Every problem found is returned, rather than just the first.
+func CheckConfig(ctx context.Context) (problems []error) {
+	s := newCheckServer(ctx)
+	defer s.cancelCtx()
+	check := func(err error) {
+		if err != nil {
+			problems = append(problems, err)
+		}
+	}
+
+	if multiplier := config.GetFloat64(signerconfig.GasEstimateMultiplier); multiplier < 1 {
+		check(i18n.NewError(ctx, signermsgs.MsgBadGasEstimateMultiplier, multiplier))
+	}
+	_, err := configLogModules(ctx)
+	check(err)
+	if config.GetBool(signerconfig.FeesEnabled) {
+		_, err = s.newFeeEstimator(ctx)
+		check(err)
+	}
+	_, err = newFeeCaps(ctx)
+	check(err)
+	_, err = newTxPolicy(ctx)
+	check(err)
+
+	check(s.initBackend(ctx))
+	check(s.initChains(ctx))
+	check(s.initAuth(ctx))
+
+	if config.GetBool(signerconfig.AccessLogEnabled) {
+		_, err = newAccessLogger(ctx)
+		check(err)
+	}
+	_, err = newMethodTimeouts(ctx)
+	check(err)
+	if config.GetBool(signerconfig.ResponseCacheEnabled) {
+		_, err = newResponseCache(ctx)
+		check(err)
+	}
+	if config.GetBool(signerconfig.SenderQueueEnabled) {
+		_, err = newSenderQueues(ctx)
+		check(err)
+	}
+	if config.GetBool(signerconfig.ResubmitEnabled) {
+		_, err = newResubmitter(ctx)
+		check(err)
+	}
+	if signerconfig.MetricsConfig.GetBool(signerconfig.MetricsConfEnabled) {
+		_, err = newAddressLabeler(ctx)
+		check(err)
+	}
+	if signerconfig.AuditWebhookConfig.GetBool(signerconfig.AuditWebhookConfEnabled) {
+		_, err = newAuditWebhook(ctx)
+		check(err)
+	}
+
+	if signerconfig.AdminConfig.GetBool(signerconfig.AdminConfEnabled) && !rpcauth.ReadConfig(signerconfig.AuthConfig).Enabled {
+		check(i18n.NewError(ctx, signermsgs.MsgAdminRequiresAuth))
+	}
+	if !signerconfig.ServerConfig.GetBool(signerconfig.ServerConfTCPEnabled) && signerconfig.ServerConfig.GetString(signerconfig.ServerConfUnixSocketPath) == "" {
+		check(i18n.NewError(ctx, signermsgs.MsgNoServerListeners))
+	}
+	return problems
+}
+
+// ProbeBackend connects to the backend, and the backend of each additional chain, to check each is reachable
+// and on the configured chain. The chain ID of the backend is returned, when it could be queried. Problems with
+// the configuration of the backends are reported by CheckConfig, so are not repeated here.
+func ProbeBackend(ctx context.Context) (chainID int64, problems []error) {
+	s := newCheckServer(ctx)
+	defer s.cancelCtx()
+	if s.initBackend(ctx) != nil {
+		return -1, nil
+	}
+	if s.wsBackend != nil {
+		if err := s.wsBackend.Connect(s.ctx); err != nil {
+			return -1, []error{err}
+		}
+		defer s.wsBackend.Close()
+	}
+
+	chainID, err := s.queryChainID(ctx)
+	if err != nil {
+		problems = append(problems, err)
+	} else if s.chainID >= 0 && chainID != s.chainID {
+		problems = append(problems, i18n.NewError(ctx, signermsgs.MsgChainIDMismatch, s.chainID, chainID))
+	}
+	if s.chainID < 0 {
+		s.chainID = chainID
+	}
+
+	s.chainIDValidation = true
+	if s.initChains(ctx) == nil && s.chains != nil {
+		names := make([]string, 0, len(s.chains.byName))
+		for name := range s.chains.byName {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if err := s.validateChain(ctx, s.chains.byName[name]); err != nil {
+				problems = append(problems, err)
+			}
+		}
+	}
+	return chainID, problems
+}
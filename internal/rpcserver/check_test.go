@@ -0,0 +1,186 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/hyperledger/firefly-common/pkg/ffresty"
+	"github.com/hyperledger/firefly-common/pkg/wsclient"
+	"github.com/hyperledger/firefly-signer/internal/signerconfig"
+	"github.com/hyperledger/firefly-signer/pkg/rpcauth"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestChainIDBackend starts an HTTP JSON/RPC backend that only answers eth_chainId
+func newTestChainIDBackend(t *testing.T, chainID int64) string {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		err := json.NewDecoder(r.Body).Decode(&req)
+		assert.NoError(t, err)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"jsonrpc": "2.0",
+			"id":      req["id"],
+			"result":  fmt.Sprintf("0x%x", chainID),
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func TestCheckConfigOK(t *testing.T) {
+	signerconfig.Reset()
+	problems := CheckConfig(context.Background())
+	assert.Empty(t, problems)
+}
+
+func TestCheckConfigReportsAllProblems(t *testing.T) {
+	signerconfig.Reset()
+	config.Set(signerconfig.GasEstimateMultiplier, 0.5)
+	config.Set(signerconfig.LogModules, []string{"wrong"})
+	config.Set(signerconfig.FeesEnabled, true)
+	config.Set(signerconfig.FeesStrategy, "wrong")
+	config.Set(signerconfig.FeeCapsPolicy, "wrong")
+	config.Set(signerconfig.TxPolicyMaxValue, "wrong")
+	config.Set(signerconfig.BackendFailoverURLs, []string{"ws://127.0.0.1:1"})
+	setTestChainConf(testChainNetwork("", 2222))()
+	signerconfig.AuthConfig.Set(rpcauth.ConfigRBACEnabled, true)
+	config.Set(signerconfig.AccessLogEnabled, true)
+	config.Set(signerconfig.AccessLogVerbosity, "wrong")
+	config.Set(signerconfig.ResponseCacheEnabled, true)
+	config.Set(signerconfig.ResponseCacheTTL, "0s")
+	config.Set(signerconfig.SenderQueueEnabled, true)
+	config.Set(signerconfig.SenderQueueMaxPending, 0)
+	config.Set(signerconfig.ResubmitEnabled, true)
+	config.Set(signerconfig.ResubmitPolicy, "wrong")
+	signerconfig.MetricsConfig.Set(signerconfig.MetricsConfEnabled, true)
+	signerconfig.MetricsConfig.Set(signerconfig.MetricsConfAddressesLabel, "wrong")
+	signerconfig.AuditWebhookConfig.Set(signerconfig.AuditWebhookConfEnabled, true)
+	signerconfig.AdminConfig.Set(signerconfig.AdminConfEnabled, true)
+	signerconfig.ServerConfig.Set(signerconfig.ServerConfTCPEnabled, false)
+
+	problems := CheckConfig(context.Background())
+	expected := []string{
+		"FF22130", // gas estimate multiplier
+		"FF22223", // debug log module
+		"FF22131", // fee strategy
+		"FF22135", // fee cap policy
+		"FF22151", // transaction policy
+		"FF22100", // failover URL schemes
+		"FF22160", // chain network
+		"FF22109", // RBAC without auth
+		"FF22140", // access log verbosity
+		"FF22147", // response cache
+		"FF22171", // sender queue
+		"FF22172", // resubmit policy
+		"FF22157", // address metrics label
+		"FF22166", // audit webhook URL
+		"FF22156", // admin without auth
+		"FF22141", // no listeners
+	}
+	assert.Len(t, problems, len(expected))
+	for i, errCode := range expected {
+		if i < len(problems) {
+			assert.Regexp(t, errCode, problems[i])
+		}
+	}
+}
+
+func TestProbeBackendOK(t *testing.T) {
+	signerconfig.Reset()
+	signerconfig.BackendConfig.Set(ffresty.HTTPConfigURL, newTestChainIDBackend(t, 12345))
+	config.Set(signerconfig.BackendChainID, 12345)
+	setTestChainConf(
+		fmt.Sprintf("{name: other, chainId: 2222, url: '%s'}", newTestChainIDBackend(t, 2222)),
+	)()
+
+	chainID, problems := ProbeBackend(context.Background())
+	assert.Empty(t, problems)
+	assert.Equal(t, int64(12345), chainID)
+}
+
+func TestProbeBackendMismatches(t *testing.T) {
+	signerconfig.Reset()
+	signerconfig.BackendConfig.Set(ffresty.HTTPConfigURL, newTestChainIDBackend(t, 12345))
+	config.Set(signerconfig.BackendChainID, 1)
+	setTestChainConf(
+		fmt.Sprintf("{name: a, chainId: 1, url: '%s'}", newTestChainIDBackend(t, 1)),
+		fmt.Sprintf("{name: b, chainId: 2222, url: '%s'}", newTestChainIDBackend(t, 3333)),
+	)()
+
+	chainID, problems := ProbeBackend(context.Background())
+	assert.Equal(t, int64(12345), chainID)
+	assert.Len(t, problems, 3)
+	assert.Regexp(t, "FF22137.*1 .*12,345", problems[0])
+	assert.Regexp(t, "FF22162.*a", problems[1])
+	assert.Regexp(t, "FF22137.*2,222.*3,333", problems[2])
+}
+
+func TestProbeBackendUnreachable(t *testing.T) {
+	signerconfig.Reset()
+	signerconfig.BackendConfig.Set(ffresty.HTTPConfigURL, "http://127.0.0.1:1")
+	signerconfig.BackendConfig.Set(ffresty.HTTPConfigRetryEnabled, false)
+
+	chainID, problems := ProbeBackend(context.Background())
+	assert.Equal(t, int64(-1), chainID)
+	assert.Len(t, problems, 1)
+	assert.Regexp(t, "FF22021", problems[0])
+}
+
+func TestProbeBackendBadConfig(t *testing.T) {
+	signerconfig.Reset()
+	config.Set(signerconfig.BackendFailoverURLs, []string{"ws://127.0.0.1:1"})
+
+	chainID, problems := ProbeBackend(context.Background())
+	assert.Equal(t, int64(-1), chainID)
+	assert.Empty(t, problems)
+}
+
+func TestProbeBackendWebSocketConnectFail(t *testing.T) {
+	signerconfig.Reset()
+	signerconfig.BackendConfig.Set(ffresty.HTTPConfigURL, "ws://127.0.0.1:1")
+	signerconfig.BackendConfig.Set(wsclient.WSConfigKeyInitialConnectAttempts, 0)
+
+	chainID, problems := ProbeBackend(context.Background())
+	assert.Equal(t, int64(-1), chainID)
+	assert.Len(t, problems, 1)
+	assert.Regexp(t, "FF00148", problems[0])
+}
+
+func TestProbeBackendWebSocket(t *testing.T) {
+	signerconfig.Reset()
+	toServer, fromServer, url, closeWS := wsclient.NewTestWSServer(nil)
+	defer closeWS()
+	signerconfig.BackendConfig.Set(ffresty.HTTPConfigURL, url)
+
+	go func() {
+		msg := <-toServer
+		assert.JSONEq(t, `{"jsonrpc":"2.0","id":"000000001","method":"eth_chainId"}`, msg)
+		fromServer <- `{"jsonrpc":"2.0","id":"000000001","result":"0x3039"}`
+	}()
+
+	chainID, problems := ProbeBackend(context.Background())
+	assert.Empty(t, problems)
+	assert.Equal(t, int64(12345), chainID)
+}
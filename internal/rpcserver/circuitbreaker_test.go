@@ -0,0 +1,101 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/hyperledger/firefly-common/pkg/ffresty"
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-common/pkg/httpserver"
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-signer/internal/signerconfig"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/hyperledger/firefly-signer/mocks/ethsignermocks"
+	"github.com/hyperledger/firefly-signer/mocks/rpcbackendmocks"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func setTestCircuitBreakerConf() {
+	config.Set(signerconfig.BackendCircuitBreakerEnabled, true)
+	config.Set(signerconfig.BackendCircuitBreakerMinimumRequests, 1)
+}
+
+func TestCircuitBreakerOpensForHTTPBackend(t *testing.T) {
+	signerconfig.Reset()
+	setTestCircuitBreakerConf()
+	signerconfig.BackendConfig.Set(ffresty.HTTPConfigURL, "http://127.0.0.1:1")
+	signerconfig.BackendConfig.Set(ffresty.HTTPConfigRetryEnabled, false)
+	signerconfig.ServerConfig.Set(httpserver.HTTPConfPort, 0)
+	signerconfig.ServerConfig.Set(httpserver.HTTPConfAddress, "127.0.0.1")
+
+	ss, err := NewServer(context.Background(), &ethsignermocks.Wallet{})
+	assert.NoError(t, err)
+	defer ss.Stop()
+	s := ss.(*rpcServer)
+
+	// The first request fails on the backend, and opens the circuit for the next
+	_, err = s.backend.SyncRequest(context.Background(), &rpcbackend.RPCRequest{Method: "eth_blockNumber"})
+	assert.Regexp(t, "FF22012", err)
+	rpcRes, err := s.backend.SyncRequest(context.Background(), &rpcbackend.RPCRequest{Method: "eth_blockNumber"})
+	assert.Regexp(t, "FF22150", err)
+	assert.Equal(t, int64(rpcbackend.RPCCodeCircuitOpen), rpcRes.Error.Code)
+}
+
+func TestCircuitBreakerWebSocketBackend(t *testing.T) {
+	signerconfig.Reset()
+	setTestCircuitBreakerConf()
+	signerconfig.BackendConfig.Set(ffresty.HTTPConfigURL, "ws://127.0.0.1:1")
+	signerconfig.ServerConfig.Set(httpserver.HTTPConfPort, 0)
+	signerconfig.ServerConfig.Set(httpserver.HTTPConfAddress, "127.0.0.1")
+
+	ss, err := NewServer(context.Background(), &ethsignermocks.Wallet{})
+	assert.NoError(t, err)
+	defer ss.Stop()
+	s := ss.(*rpcServer)
+	assert.Equal(t, rpcbackend.Backend(s.wsBackend), s.backend)
+	assert.Equal(t, 1, backendCircuitBreakerOptions().MinimumRequests)
+}
+
+func TestCircuitOpenServiceUnavailable(t *testing.T) {
+	url, s, done := newTestServer(t)
+	defer done()
+	startTestServerNoBackend(t, s)
+
+	bm := s.backend.(*rpcbackendmocks.Backend)
+	bm.On("SyncRequest", mock.Anything, mock.Anything).Return(
+		rpcbackend.RPCErrorResponse(i18n.NewError(context.Background(), signermsgs.MsgCircuitBreakerOpen), fftypes.JSONAnyPtr("1"), rpcbackend.RPCCodeCircuitOpen),
+		i18n.NewError(context.Background(), signermsgs.MsgCircuitBreakerOpen),
+	)
+
+	body := []byte(`{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber"}`)
+	res, err := http.Post(url, "application/json", bytes.NewReader(body))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+	var rpcRes rpcbackend.RPCResponse
+	err = json.NewDecoder(res.Body).Decode(&rpcRes)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(rpcbackend.RPCCodeCircuitOpen), rpcRes.Error.Code)
+	assert.Regexp(t, "FF22150", rpcRes.Error.Message)
+}
@@ -0,0 +1,68 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-common/pkg/log"
+	"github.com/hyperledger/firefly-signer/internal/signerconfig"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/hyperledger/firefly-signer/pkg/contract"
+	"github.com/hyperledger/firefly-signer/pkg/ens"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+)
+
+// initENS creates the resolver of ENS names in the to address of transactions, which calls the registry through
+// the backend - so on the chain each transaction is routed to
+func (s *rpcServer) initENS(ctx context.Context) (err error) {
+	registry, err := ethtypes.NewAddress(config.GetString(signerconfig.ENSRegistry))
+	if err != nil {
+		return i18n.NewError(ctx, signermsgs.MsgENSInvalidRegistry, config.GetString(signerconfig.ENSRegistry), err)
+	}
+	conf := &ens.Config{RPC: s.backend, Registry: registry}
+	if config.GetBool(signerconfig.ENSCCIPReadEnabled) {
+		conf.CCIPRead = &contract.CCIPReadOptions{AllowedHosts: config.GetStringSlice(signerconfig.ENSCCIPReadAllowedHosts)}
+	}
+	s.ens, err = ens.NewResolver(ctx, conf)
+	return err
+}
+
+// resolveENSTo replaces an ENS name in the to address of the transaction of the request with the address it
+// resolves to, so the transaction is checked and signed with the address
+func (s *rpcServer) resolveENSTo(ctx context.Context, rpcReq *rpcbackend.RPCRequest) (*rpcbackend.RPCResponse, error) {
+	// Anything that is not a transaction with a name in the to address is left to the parsing that follows
+	var txn map[string]json.RawMessage
+	var to string
+	if json.Unmarshal(rpcReq.Params[0].Bytes(), &txn) != nil || json.Unmarshal(txn["to"], &to) != nil || !ens.IsName(to) {
+		return nil, nil
+	}
+	addr, err := s.ens.Resolve(ctx, to)
+	if err != nil {
+		return rpcbackend.RPCErrorResponse(err, rpcReq.ID, rpcbackend.RPCCodeInvalidRequest), err
+	}
+	log.L(ctx).Infof("Resolved ENS name '%s' in the to address of the transaction to %s", to, addr)
+	txn["to"], _ = json.Marshal(addr)
+	b, _ := json.Marshal(txn)
+	rpcReq.Params[0] = fftypes.JSONAnyPtrBytes(b)
+	return nil, nil
+}
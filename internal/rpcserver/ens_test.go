@@ -0,0 +1,135 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-signer/internal/signerconfig"
+	"github.com/hyperledger/firefly-signer/mocks/ethsignermocks"
+	"github.com/hyperledger/firefly-signer/pkg/ens"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/fakechain"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func setTestENSConf() {
+	config.Set(signerconfig.ENSEnabled, true)
+}
+
+// newTestENSChain is a chain where every name has the same resolver, which resolves every name to the same address
+func newTestENSChain(t *testing.T, s *rpcServer, addr string) {
+	chain := fakechain.New(nil)
+	chain.Handle("eth_call", func(ctx context.Context, params []*fftypes.JSONAny) (interface{}, *rpcbackend.RPCError) {
+		var call rpcbackend.CallRequest
+		assert.NoError(t, json.Unmarshal(params[0].Bytes(), &call))
+		switch call.Data[0:4].String() {
+		case "0x0178b8bf": // resolver(bytes32)
+			return ethtypes.MustNewHexBytes0xPrefix("0x000000000000000000000000231b0ee14048e9dccd1d247744d114a4eb5e8e63"), nil
+		case "0x01ffc9a7": // supportsInterface(bytes4)
+			return ethtypes.MustNewHexBytes0xPrefix("0x0000000000000000000000000000000000000000000000000000000000000000"), nil
+		default: // addr(bytes32)
+			return ethtypes.MustNewHexBytes0xPrefix("0x000000000000000000000000" + addr), nil
+		}
+	})
+	var err error
+	s.ens, err = ens.NewResolver(context.Background(), &ens.Config{RPC: chain})
+	assert.NoError(t, err)
+}
+
+func TestENSInitBadRegistry(t *testing.T) {
+	signerconfig.Reset()
+	config.Set(signerconfig.ENSEnabled, true)
+	config.Set(signerconfig.ENSRegistry, "not an address")
+	_, err := NewServer(context.Background(), &ethsignermocks.Wallet{})
+	assert.Regexp(t, "FF22260", err)
+}
+
+func TestENSInitCCIPRead(t *testing.T) {
+	s, _, done := newTestFillServer(t, setTestENSConf, func() {
+		config.Set(signerconfig.ENSCCIPReadEnabled, true)
+		config.Set(signerconfig.ENSCCIPReadAllowedHosts, []string{"gateway.example.com"})
+	})
+	defer done()
+	assert.NotNil(t, s.ens)
+}
+
+func TestENSFillTransaction(t *testing.T) {
+	s, bm, done := newTestFillServer(t, setTestENSConf)
+	defer done()
+	assert.NotNil(t, s.ens)
+	newTestENSChain(t, s, "497eedc4299dea2f2a364be10025d0ad0f702de3")
+
+	bm.On("CallRPC", mock.Anything, mock.Anything, "eth_getTransactionCount", mock.Anything, "pending").Run(func(args mock.Arguments) {
+		*(args[1].(**ethtypes.HexInteger)) = ethtypes.NewHexIntegerU64(10)
+	}).Return(nil)
+
+	rpcRes, err := s.processRPC(s.ctx, fillTestRequest(`{
+		"from": "0xfb075bb99f2aa4c49955bf703509a227d7a12248",
+		"to": "Vitalik.eth",
+		"gas": "0x5208",
+		"gasPrice": "0x3b9aca00"
+	}`))
+	assert.NoError(t, err)
+
+	var filled struct {
+		Tx map[string]interface{} `json:"tx"`
+	}
+	err = json.Unmarshal(rpcRes.Result.Bytes(), &filled)
+	assert.NoError(t, err)
+	assert.Equal(t, "0x497eedc4299dea2f2a364be10025d0ad0f702de3", filled.Tx["to"])
+}
+
+func TestENSAddressesUnchanged(t *testing.T) {
+	s, bm, done := newTestFillServer(t, setTestENSConf)
+	defer done()
+
+	bm.On("CallRPC", mock.Anything, mock.Anything, "eth_getTransactionCount", mock.Anything, "pending").Return(&rpcbackend.RPCError{Message: "pop"})
+
+	for _, txn := range []string{
+		`{"from": "0xfb075bb99f2aa4c49955bf703509a227d7a12248", "to": "0x497eedc4299dea2f2a364be10025d0ad0f702de3"}`,
+		`{"from": "0xfb075bb99f2aa4c49955bf703509a227d7a12248"}`,
+	} {
+		req := fillTestRequest(txn)
+		_, err := s.processRPC(s.ctx, req)
+		assert.Regexp(t, "pop", err)
+		assert.JSONEq(t, txn, req.Params[0].String())
+	}
+
+	// Not a transaction object is rejected by the parsing that follows
+	_, err := s.processRPC(s.ctx, fillTestRequest(`[]`))
+	assert.Regexp(t, "FF22023", err)
+}
+
+func TestENSNameNotResolved(t *testing.T) {
+	s, _, done := newTestFillServer(t, setTestENSConf)
+	defer done()
+	newTestENSChain(t, s, "0000000000000000000000000000000000000000")
+
+	rpcRes, err := s.processRPC(s.ctx, fillTestRequest(`{
+		"from": "0xfb075bb99f2aa4c49955bf703509a227d7a12248",
+		"to": "nobody.eth"
+	}`))
+	assert.Regexp(t, "FF22257.*nobody.eth", err)
+	assert.Equal(t, int64(rpcbackend.RPCCodeInvalidRequest), rpcRes.Error.Code)
+}
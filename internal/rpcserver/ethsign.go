@@ -0,0 +1,105 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-common/pkg/log"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/hyperledger/firefly-signer/pkg/approval"
+	"github.com/hyperledger/firefly-signer/pkg/ethsigner"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/rpcauth"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+)
+
+// processEthSign follows the legacy eth_sign signature of address, then a 32 byte digest that is signed
+// directly with no prefix. As this allows a caller to sign anything (including a transaction) it is only
+// available when dangerousMethods.ethSign is enabled, and every attempt is audit logged.
+func (s *rpcServer) processEthSign(ctx context.Context, rpcReq *rpcbackend.RPCRequest) (rpcRes *rpcbackend.RPCResponse, err error) {
+	defer func() { auditEthSign(ctx, rpcReq, err) }()
+
+	if !s.ethSignEnabled {
+		err := i18n.NewError(ctx, signermsgs.MsgEthSignDisabled)
+		return rpcbackend.RPCErrorResponse(err, rpcReq.ID, rpcbackend.RPCCodeMethodNotFound), err
+	}
+	w, ok := s.wallet.(ethsigner.WalletDigestSign)
+	if !ok {
+		err := i18n.NewError(ctx, signermsgs.MsgDigestSignNotSupported)
+		return rpcbackend.RPCErrorResponse(err, rpcReq.ID, rpcbackend.RPCCodeInvalidRequest), err
+	}
+	if len(rpcReq.Params) < 2 {
+		err := i18n.NewError(ctx, signermsgs.MsgInvalidParamCount, 2, len(rpcReq.Params))
+		return rpcbackend.RPCErrorResponse(err, rpcReq.ID, rpcbackend.RPCCodeInvalidRequest), err
+	}
+
+	var addr ethtypes.Address0xHex
+	if errRes, err := s.unmarshalParam(ctx, rpcReq, 0, &addr); err != nil {
+		return errRes, err
+	}
+	var digest ethtypes.HexBytes0xPrefix
+	if errRes, err := s.unmarshalParam(ctx, rpcReq, 1, &digest); err != nil {
+		return errRes, err
+	}
+	if len(digest) != 32 {
+		err := i18n.NewError(ctx, signermsgs.MsgInvalidDigestLength, len(digest))
+		return rpcbackend.RPCErrorResponse(err, rpcReq.ID, rpcbackend.RPCCodeInvalidRequest), err
+	}
+	setSpanFrom(ctx, rpcReq.Params[0].Bytes())
+	req := &signingRequest{method: rpcReq.Method, operation: signOpDigest, from: &addr}
+	if errRes, err := s.authorizeAddress(ctx, rpcReq, &addr); err != nil {
+		return s.rejectedByPolicy(ctx, req, errRes, err)
+	}
+	if errRes, err := s.checkRateLimit(ctx, rpcReq, rateLimitSigning); err != nil {
+		return s.rejectedByPolicy(ctx, req, errRes, err)
+	}
+	if errRes, err := s.approveSigning(ctx, rpcReq, req, &approval.Request{Message: digest}); err != nil {
+		return errRes, err
+	}
+
+	startTime := time.Now()
+	sig, err := w.SignDigest(ctx, addr, digest)
+	s.signOperation(ctx, req, startTime, nil, err)
+	if err != nil {
+		return rpcbackend.RPCErrorResponse(err, rpcReq.ID, rpcbackend.RPCCodeInternalError), err
+	}
+	b, _ := json.Marshal(sig)
+	return &rpcbackend.RPCResponse{
+		JSONRpc: "2.0",
+		ID:      rpcReq.ID,
+		Result:  fftypes.JSONAnyPtrBytes(b),
+	}, nil
+}
+
+// auditEthSign logs every eth_sign request, including those that are rejected, with the caller and params
+func auditEthSign(ctx context.Context, rpcReq *rpcbackend.RPCRequest, err error) {
+	caller := "anonymous"
+	if identity := rpcauth.GetIdentity(ctx); identity != nil {
+		caller = identity.ID
+	}
+	outcome := "signed"
+	if err != nil {
+		outcome = err.Error()
+	}
+	params, _ := json.Marshal(rpcReq.Params)
+	log.L(ctx).WithField("audit", rpcReq.Method).Warnf("AUDIT eth_sign caller='%s' params=%s outcome='%s'", caller, params, outcome)
+}
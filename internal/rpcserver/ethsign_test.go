@@ -0,0 +1,177 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-signer/internal/signerconfig"
+	"github.com/hyperledger/firefly-signer/mocks/ethsignermocks"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/rpcauth"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const testEthSignDigest = "0xa1de988600a42c4b4ab089b619297c17d53cffae5d5120d82d8a92d0bb3b78f2"
+
+func setTestEthSignConf() {
+	config.Set(signerconfig.DangerousMethodsEthSign, true)
+}
+
+func ethSignTestRequest() *rpcbackend.RPCRequest {
+	return &rpcbackend.RPCRequest{
+		ID:     fftypes.JSONAnyPtr("1"),
+		Method: "eth_sign",
+		Params: []*fftypes.JSONAny{
+			fftypes.JSONAnyPtr(`"0xfb075bb99f2aa4c49955bf703509a227d7a12248"`),
+			fftypes.JSONAnyPtr(fmt.Sprintf(`"%s"`, testEthSignDigest)),
+		},
+	}
+}
+
+func TestEthSignOK(t *testing.T) {
+
+	_, s, done := newTestServer(t, setTestEthSignConf)
+	defer done()
+	logHook := logtest.NewGlobal()
+	defer logrus.StandardLogger().ReplaceHooks(logrus.LevelHooks{})
+
+	w := &ethsignermocks.WalletDigestSign{}
+	s.wallet = w
+	w.On("SignDigest", mock.Anything, *ethtypes.MustNewAddress("0xfb075bb99f2aa4c49955bf703509a227d7a12248"), []byte(ethtypes.MustNewHexBytes0xPrefix(testEthSignDigest))).
+		Return(ethtypes.MustNewHexBytes0xPrefix("0xaabbcc"), nil)
+
+	ctx := rpcauth.WithIdentity(s.ctx, &rpcauth.Identity{ID: "tenantA"})
+	rpcRes, err := s.processRPC(ctx, ethSignTestRequest())
+	assert.NoError(t, err)
+	assert.Equal(t, `"0xaabbcc"`, rpcRes.Result.String())
+	w.AssertExpectations(t)
+
+	audit := logHook.LastEntry()
+	assert.Equal(t, "eth_sign", audit.Data["audit"])
+	assert.Regexp(t, "caller='tenantA'.*0xfb075bb99f2aa4c49955bf703509a227d7a12248.*"+testEthSignDigest+".*outcome='signed'", audit.Message)
+
+}
+
+func TestEthSignFail(t *testing.T) {
+
+	_, metricsURL, s, _, done := newTestMetricsServer(t, &ethsignermocks.Wallet{})
+	defer done()
+	startTestServerNoBackend(t, s)
+	s.ethSignEnabled = true
+
+	w := &ethsignermocks.WalletDigestSign{}
+	s.wallet = w
+	w.On("SignDigest", mock.Anything, mock.Anything, mock.Anything).Return(nil, fmt.Errorf("pop"))
+
+	rpcRes, err := s.processRPC(s.ctx, ethSignTestRequest())
+	assert.Regexp(t, "pop", err)
+	assert.Equal(t, int64(rpcbackend.RPCCodeInternalError), rpcRes.Error.Code)
+	assert.Contains(t, scrapeTestMetrics(t, metricsURL), `ff_signer_wallet_sign_operations_total{ff_component="ffsigner",outcome="error",wallet="ethsignermocks"} 1`)
+
+}
+
+func TestEthSignBadParams(t *testing.T) {
+
+	_, s, done := newTestServer(t, setTestEthSignConf)
+	defer done()
+
+	s.wallet = &ethsignermocks.WalletDigestSign{}
+
+	req := ethSignTestRequest()
+	req.Params = req.Params[0:1]
+	_, err := s.processRPC(s.ctx, req)
+	assert.Regexp(t, "FF22019", err)
+
+	req = ethSignTestRequest()
+	req.Params[0] = fftypes.JSONAnyPtr(`"bad address"`)
+	_, err = s.processRPC(s.ctx, req)
+	assert.Regexp(t, "FF22011", err)
+
+	req = ethSignTestRequest()
+	req.Params[1] = fftypes.JSONAnyPtr(`"not hex"`)
+	_, err = s.processRPC(s.ctx, req)
+	assert.Regexp(t, "FF22011", err)
+
+	req = ethSignTestRequest()
+	req.Params[1] = fftypes.JSONAnyPtr(`"0x48656c6c6f20576f726c64"`)
+	_, err = s.processRPC(s.ctx, req)
+	assert.Regexp(t, "FF22116", err)
+
+}
+
+func TestEthSignNotSupported(t *testing.T) {
+
+	_, s, done := newTestServer(t, setTestEthSignConf)
+	defer done()
+
+	_, err := s.processRPC(s.ctx, ethSignTestRequest())
+	assert.Regexp(t, "FF22118", err)
+
+}
+
+func TestEthSignDisabledByDefault(t *testing.T) {
+
+	_, s, done := newTestServer(t)
+	defer done()
+	logHook := logtest.NewGlobal()
+	defer logrus.StandardLogger().ReplaceHooks(logrus.LevelHooks{})
+
+	// Neither the wallet nor the backend are called, but the attempt is audited
+	s.wallet = &ethsignermocks.WalletDigestSign{}
+	rpcRes, err := s.processRPC(s.ctx, ethSignTestRequest())
+	assert.Regexp(t, "FF22117", err)
+	assert.Equal(t, int64(rpcbackend.RPCCodeMethodNotFound), rpcRes.Error.Code)
+
+	audit := logHook.LastEntry()
+	assert.Equal(t, "eth_sign", audit.Data["audit"])
+	assert.Regexp(t, "caller='anonymous'.*outcome='FF22117", audit.Message)
+
+}
+
+func TestEthSignRBAC(t *testing.T) {
+
+	ctx, s, done := newTestRBACServer(t)
+	defer done()
+	s.ethSignEnabled = true
+
+	s.wallet = &ethsignermocks.WalletDigestSign{}
+	req := ethSignTestRequest()
+	req.Params[0] = fftypes.JSONAnyPtr(`"0x3c99f2a4b366d46bcf2277639a135a6d1288eceb"`)
+	rpcRes, err := s.processRPC(ctx, req)
+	assertUnauthorized(t, rpcRes, err, "FF22111")
+
+}
+
+func TestEthSignRateLimit(t *testing.T) {
+
+	_, s, done := newTestServer(t, setTestRateLimitConf, setTestEthSignConf)
+	defer done()
+
+	s.wallet = &ethsignermocks.WalletDigestSign{}
+	rpcRes, err := s.processRPC(withRateLimitKey(s.ctx, "identity:tenantA"), ethSignTestRequest())
+	assert.Regexp(t, "FF22113.*signing", err)
+	assert.Equal(t, int64(rpcbackend.RPCCodeLimitExceeded), rpcRes.Error.Code)
+
+}
@@ -0,0 +1,138 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-common/pkg/log"
+	"github.com/hyperledger/firefly-signer/internal/signerconfig"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/hyperledger/firefly-signer/pkg/ethsigner"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+)
+
+const (
+	feeCapPolicyReject = "reject"
+	feeCapPolicyClamp  = "clamp"
+)
+
+// feeCaps are hard limits on the fees of every transaction we sign, to protect against fat-finger mistakes
+// (by the caller, or in the fees we populate) that could spend a large amount on a single transaction
+type feeCaps struct {
+	maxGasPrice  *big.Int
+	maxFeePerGas *big.Int
+	maxTotalFee  *big.Int
+	clamp        bool
+}
+
+// newFeeCaps returns nil if no caps are configured
+func newFeeCaps(ctx context.Context) (*feeCaps, error) {
+	fc := &feeCaps{}
+	var err error
+	if fc.maxGasPrice, err = parseFeeCap(ctx, signerconfig.FeeCapsMaxGasPrice); err != nil {
+		return nil, err
+	}
+	if fc.maxFeePerGas, err = parseFeeCap(ctx, signerconfig.FeeCapsMaxFeePerGas); err != nil {
+		return nil, err
+	}
+	if fc.maxTotalFee, err = parseFeeCap(ctx, signerconfig.FeeCapsMaxTotalFee); err != nil {
+		return nil, err
+	}
+	switch policy := config.GetString(signerconfig.FeeCapsPolicy); policy {
+	case feeCapPolicyReject:
+	case feeCapPolicyClamp:
+		fc.clamp = true
+	default:
+		return nil, i18n.NewError(ctx, signermsgs.MsgUnknownFeeCapPolicy, policy)
+	}
+	if fc.maxGasPrice == nil && fc.maxFeePerGas == nil && fc.maxTotalFee == nil {
+		return nil, nil
+	}
+	return fc, nil
+}
+
+func parseFeeCap(ctx context.Context, key config.RootKey) (*big.Int, error) {
+	s := config.GetString(key)
+	if s == "" {
+		return nil, nil
+	}
+	i, ok := new(big.Int).SetString(s, 0)
+	if !ok || i.Sign() < 0 {
+		return nil, i18n.NewError(ctx, signermsgs.MsgBadFeeCap, key, s)
+	}
+	return i, nil
+}
+
+// apply checks the fees of the transaction against the caps, before it is signed. With the clamp policy
+// fees over a cap are reduced to the cap, rather than the transaction being rejected.
+func (fc *feeCaps) apply(ctx context.Context, txn *ethsigner.Transaction) error {
+	if err := fc.capFee(ctx, "gasPrice", txn.GasPrice, fc.maxGasPrice); err != nil {
+		return err
+	}
+	if err := fc.capFee(ctx, "maxFeePerGas", txn.MaxFeePerGas, fc.maxFeePerGas); err != nil {
+		return err
+	}
+
+	// The most the transaction can cost is the gas limit at the highest price per gas it allows
+	pricePerGas := txn.GasPrice
+	if pricePerGas == nil {
+		pricePerGas = txn.MaxFeePerGas
+	}
+	gasLimit := txn.GasLimit.BigInt()
+	if fc.maxTotalFee != nil && pricePerGas != nil && gasLimit.Sign() > 0 {
+		totalFee := new(big.Int).Mul(gasLimit, pricePerGas.BigInt())
+		if totalFee.Cmp(fc.maxTotalFee) > 0 {
+			if !fc.clamp {
+				return i18n.NewError(ctx, signermsgs.MsgFeeCapExceeded, "total fee", totalFee.String(), fc.maxTotalFee.String())
+			}
+			maxPricePerGas := new(big.Int).Div(fc.maxTotalFee, gasLimit)
+			log.L(ctx).Warnf("Clamped price per gas of %s wei to %s wei, for the total fee cap of %s wei", pricePerGas.BigInt(), maxPricePerGas, fc.maxTotalFee)
+			pricePerGas.BigInt().Set(maxPricePerGas)
+		}
+	}
+
+	// The priority fee can never be more than the max fee, so must come down with it
+	if txn.MaxPriorityFeePerGas != nil && txn.MaxFeePerGas != nil && fc.clamp &&
+		txn.MaxPriorityFeePerGas.BigInt().Cmp(txn.MaxFeePerGas.BigInt()) > 0 {
+		txn.MaxPriorityFeePerGas.BigInt().Set(txn.MaxFeePerGas.BigInt())
+	}
+	return nil
+}
+
+func (fc *feeCaps) capFee(ctx context.Context, name string, fee *ethtypes.HexInteger, maxFee *big.Int) error {
+	if fee == nil || maxFee == nil || fee.BigInt().Cmp(maxFee) <= 0 {
+		return nil
+	}
+	if !fc.clamp {
+		return i18n.NewError(ctx, signermsgs.MsgFeeCapExceeded, name, fee.BigInt().String(), maxFee.String())
+	}
+	log.L(ctx).Warnf("Clamped %s of %s wei to the cap of %s wei", name, fee.BigInt(), maxFee)
+	fee.BigInt().Set(maxFee)
+	return nil
+}
+
+// check applies the caps to a transaction that is already signed, so is rejected whatever the policy,
+// as its fees cannot be changed without signing it again
+func (fc *feeCaps) check(ctx context.Context, txn *ethsigner.Transaction) error {
+	rejectOnly := *fc
+	rejectOnly.clamp = false
+	return rejectOnly.apply(ctx, txn)
+}
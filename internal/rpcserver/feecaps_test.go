@@ -0,0 +1,161 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/hyperledger/firefly-signer/internal/signerconfig"
+	"github.com/hyperledger/firefly-signer/mocks/ethsignermocks"
+	"github.com/hyperledger/firefly-signer/pkg/ethsigner"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newTestFeeCaps(t *testing.T, policy string) *feeCaps {
+	signerconfig.Reset()
+	config.Set(signerconfig.FeeCapsMaxGasPrice, "1000")
+	config.Set(signerconfig.FeeCapsMaxFeePerGas, "0x7d0")
+	config.Set(signerconfig.FeeCapsMaxTotalFee, "42000000")
+	config.Set(signerconfig.FeeCapsPolicy, policy)
+	fc, err := newFeeCaps(context.Background())
+	assert.NoError(t, err)
+	return fc
+}
+
+func TestFeeCapsNotConfigured(t *testing.T) {
+	signerconfig.Reset()
+	fc, err := newFeeCaps(context.Background())
+	assert.NoError(t, err)
+	assert.Nil(t, fc)
+}
+
+func TestFeeCapsBadConfig(t *testing.T) {
+	for errCode, setConf := range map[string]func(){
+		"FF22134.*maxGasPrice":  func() { config.Set(signerconfig.FeeCapsMaxGasPrice, "wrong") },
+		"FF22134.*maxFeePerGas": func() { config.Set(signerconfig.FeeCapsMaxFeePerGas, "-1") },
+		"FF22134.*maxTotalFee":  func() { config.Set(signerconfig.FeeCapsMaxTotalFee, "0xzz") },
+		"FF22135":               func() { config.Set(signerconfig.FeeCapsPolicy, "wrong") },
+	} {
+		signerconfig.Reset()
+		setConf()
+		_, err := NewServer(context.Background(), &ethsignermocks.Wallet{})
+		assert.Regexp(t, errCode, err)
+	}
+}
+
+func TestFeeCapsReject(t *testing.T) {
+	ctx := context.Background()
+	fc := newTestFeeCaps(t, "reject")
+
+	err := fc.apply(ctx, &ethsigner.Transaction{GasPrice: ethtypes.NewHexIntegerU64(1001)})
+	assert.Regexp(t, "FF22136.*gasPrice of 1001 wei exceeds the cap of 1000 wei", err)
+
+	err = fc.apply(ctx, &ethsigner.Transaction{MaxFeePerGas: ethtypes.NewHexIntegerU64(2001)})
+	assert.Regexp(t, "FF22136.*maxFeePerGas of 2001 wei", err)
+
+	err = fc.apply(ctx, &ethsigner.Transaction{GasPrice: ethtypes.NewHexIntegerU64(1000), GasLimit: ethtypes.NewHexIntegerU64(42001)})
+	assert.Regexp(t, "FF22136.*total fee of 42001000 wei exceeds the cap of 42000000 wei", err)
+
+	// Within the caps, or without a gas limit to calculate the total fee from
+	for _, txn := range []*ethsigner.Transaction{
+		{},
+		{GasPrice: ethtypes.NewHexIntegerU64(1000)},
+		{GasPrice: ethtypes.NewHexIntegerU64(1000), GasLimit: ethtypes.NewHexIntegerU64(42000)},
+		{MaxFeePerGas: ethtypes.NewHexIntegerU64(2000), MaxPriorityFeePerGas: ethtypes.NewHexIntegerU64(3000), GasLimit: ethtypes.NewHexIntegerU64(21000)},
+	} {
+		err = fc.apply(ctx, txn)
+		assert.NoError(t, err)
+	}
+}
+
+func TestFeeCapsClamp(t *testing.T) {
+	ctx := context.Background()
+	fc := newTestFeeCaps(t, "clamp")
+
+	txn := &ethsigner.Transaction{GasPrice: ethtypes.NewHexIntegerU64(5000)}
+	err := fc.apply(ctx, txn)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1000), txn.GasPrice.Int64())
+
+	txn = &ethsigner.Transaction{
+		MaxFeePerGas:         ethtypes.NewHexIntegerU64(5000),
+		MaxPriorityFeePerGas: ethtypes.NewHexIntegerU64(3000),
+	}
+	err = fc.apply(ctx, txn)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2000), txn.MaxFeePerGas.Int64())
+	assert.Equal(t, int64(2000), txn.MaxPriorityFeePerGas.Int64())
+
+	// The total fee cap limits the price per gas, for the gas limit
+	txn = &ethsigner.Transaction{
+		MaxFeePerGas:         ethtypes.NewHexIntegerU64(2000),
+		MaxPriorityFeePerGas: ethtypes.NewHexIntegerU64(1500),
+		GasLimit:             ethtypes.NewHexIntegerU64(42000),
+	}
+	err = fc.apply(ctx, txn)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1000), txn.MaxFeePerGas.Int64())
+	assert.Equal(t, int64(1000), txn.MaxPriorityFeePerGas.Int64())
+	assert.Equal(t, big.NewInt(42000000), new(big.Int).Mul(txn.MaxFeePerGas.BigInt(), txn.GasLimit.BigInt()))
+}
+
+func TestFeeCapsSendTransaction(t *testing.T) {
+	_, s, done := newTestServer(t, func() {
+		config.Set(signerconfig.FeeCapsMaxGasPrice, "1000")
+	})
+	defer done()
+	assert.NotNil(t, s.feeCaps)
+
+	rpcRes, err := s.processRPC(s.ctx, feesTestRequest(`, "gasPrice": "0x3e9"`))
+	assert.Regexp(t, "FF22136", err)
+	assert.Equal(t, int64(rpcbackend.RPCCodeInvalidRequest), rpcRes.Error.Code)
+}
+
+func TestFeeCapsFillNonceGaps(t *testing.T) {
+	s, bm, w, done := newTestNonceAdminServer(t)
+	defer done()
+	s.chainID = 0
+
+	config.Set(signerconfig.FeeCapsMaxGasPrice, "999")
+	var err error
+	s.feeCaps, err = newFeeCaps(s.ctx)
+	assert.NoError(t, err)
+
+	mockTxCount(bm, "latest", 10)
+	mockTxCount(bm, "pending", 11)
+	mockGasPrice(bm)
+	_, err = s.processRPC(s.ctx, nonceAdminRequest("ffsigner_fillNonceGaps"))
+	assert.Regexp(t, "FF22127.*FF22136", err)
+
+	s.feeCaps.clamp = true
+	mockTxCount(bm, "latest", 10)
+	mockTxCount(bm, "pending", 11)
+	mockGasPrice(bm)
+	w.On("Sign", mock.Anything, mock.MatchedBy(func(txn *ethsigner.Transaction) bool {
+		return txn.GasPrice.Int64() == 999
+	}), mock.Anything).Return(nil, fmt.Errorf("pop")).Once()
+	_, err = s.processRPC(s.ctx, nonceAdminRequest("ffsigner_fillNonceGaps"))
+	assert.Regexp(t, "FF22127.*pop", err)
+	w.AssertExpectations(t)
+}
@@ -0,0 +1,114 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/hyperledger/firefly-signer/pkg/ethsigner"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+)
+
+// filledTransaction is the result of eth_fillTransaction, in the same form as go-ethereum
+type filledTransaction struct {
+	Raw ethtypes.HexBytes0xPrefix `json:"raw"` // the RLP encoded payload that is signed
+	Tx  *filledTransactionFields  `json:"tx"`
+}
+
+type filledTransactionFields struct {
+	Type    ethtypes.HexUint64   `json:"type"`
+	ChainID *ethtypes.HexInteger `json:"chainId"`
+	*ethsigner.Transaction
+}
+
+// processEthFillTransaction completes a transaction exactly as eth_sendTransaction would before signing it,
+// returning it unsigned so it can be reviewed before it is sent. The nonce is not reserved, so is the next
+// nonce for the address at the time of the request.
+func (s *rpcServer) processEthFillTransaction(ctx context.Context, rpcReq *rpcbackend.RPCRequest) (*rpcbackend.RPCResponse, error) {
+	_, _, filled, errRes, err := s.fillTransaction(ctx, rpcReq)
+	if err != nil {
+		return errRes, err
+	}
+	b, _ := json.Marshal(filled)
+	return &rpcbackend.RPCResponse{
+		JSONRpc: "2.0",
+		ID:      rpcReq.ID,
+		Result:  fftypes.JSONAnyPtrBytes(b),
+	}, nil
+}
+
+// fillTransaction applies the checks and population of eth_sendTransaction, and the next nonce of the address if
+// the nonce is not set, returning the context routed by the chain ID of the transaction
+func (s *rpcServer) fillTransaction(ctx context.Context, rpcReq *rpcbackend.RPCRequest) (context.Context, *txnRequest, *filledTransaction, *rpcbackend.RPCResponse, error) {
+	ctx, req, errRes, err := s.checkTransaction(ctx, rpcReq)
+	if err != nil {
+		return ctx, nil, nil, errRes, err
+	}
+	if errRes, err := s.populateTransaction(ctx, rpcReq, req); err != nil {
+		return ctx, nil, nil, errRes, err
+	}
+
+	txn := req.txn
+	if txn.Nonce == nil {
+		if req.fromErr != nil {
+			err := i18n.WrapError(ctx, req.fromErr, signermsgs.MsgInvalidTransaction)
+			return ctx, nil, nil, rpcbackend.RPCErrorResponse(err, rpcReq.ID, rpcbackend.RPCCodeInvalidRequest), err
+		}
+		if errRes, err := s.nextNonce(ctx, rpcReq, *req.from, txn); err != nil {
+			return ctx, nil, nil, errRes, err
+		}
+	}
+
+	chainID := s.chainIDFor(ctx)
+	payload := txn.SignaturePayload(chainID).Bytes()
+	txType := ethtypes.HexUint64(ethsigner.TransactionTypeLegacy)
+	if payload[0] == ethsigner.TransactionType1559 {
+		// Legacy transactions have no type byte, and start with an RLP list prefix
+		txType = ethtypes.HexUint64(ethsigner.TransactionType1559)
+	}
+	return ctx, req, &filledTransaction{
+		Raw: payload,
+		Tx: &filledTransactionFields{
+			Type:        txType,
+			ChainID:     ethtypes.NewHexInteger64(chainID),
+			Transaction: txn,
+		},
+	}, nil, nil
+}
+
+// nextNonce populates the nonce the next transaction from the address would be assigned, without assigning it
+func (s *rpcServer) nextNonce(ctx context.Context, rpcReq *rpcbackend.RPCRequest, from ethtypes.Address0xHex, txn *ethsigner.Transaction) (*rpcbackend.RPCResponse, error) {
+	if s.nonceManager != nil && getChainRoute(ctx) == nil {
+		status, err := s.nonceManager.Status(ctx, from)
+		if err != nil {
+			return rpcbackend.RPCErrorResponse(err, rpcReq.ID, rpcbackend.RPCCodeInternalError), err
+		}
+		// The node is ahead of the nonce manager if transactions were submitted by other means
+		next := max(status.Next, status.Pending)
+		txn.Nonce = ethtypes.NewHexIntegerU64(next.Uint64())
+		return nil, nil
+	}
+	if rpcErr := s.backend.CallRPC(ctx, &txn.Nonce, "eth_getTransactionCount", &from, "pending"); rpcErr != nil {
+		return rpcbackend.RPCErrorResponse(rpcErr.Error(), rpcReq.ID, rpcbackend.RPCCodeInternalError), rpcErr.Error()
+	}
+	return nil, nil
+}
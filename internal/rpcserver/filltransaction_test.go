@@ -0,0 +1,161 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-signer/internal/signerconfig"
+	"github.com/hyperledger/firefly-signer/mocks/rpcbackendmocks"
+	"github.com/hyperledger/firefly-signer/pkg/ethsigner"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/nonces"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func fillTestRequest(txnJSON string) *rpcbackend.RPCRequest {
+	return &rpcbackend.RPCRequest{
+		ID:     fftypes.JSONAnyPtr("1"),
+		Method: "eth_fillTransaction",
+		Params: []*fftypes.JSONAny{fftypes.JSONAnyPtr(txnJSON)},
+	}
+}
+
+func newTestFillServer(t *testing.T, confSetters ...func()) (*rpcServer, *rpcbackendmocks.Backend, func()) {
+	_, s, done := newTestServer(t, confSetters...)
+	s.chainID = 12345
+	return s, s.backend.(*rpcbackendmocks.Backend), done
+}
+
+func TestFillTransactionLegacy(t *testing.T) {
+	s, bm, done := newTestFillServer(t)
+	defer done()
+
+	bm.On("CallRPC", mock.Anything, mock.Anything, "eth_getTransactionCount", mock.Anything, "pending").Run(func(args mock.Arguments) {
+		*(args[1].(**ethtypes.HexInteger)) = ethtypes.NewHexIntegerU64(10)
+	}).Return(nil)
+
+	rpcRes, err := s.processRPC(s.ctx, fillTestRequest(`{
+		"from": "0xfb075bb99f2aa4c49955bf703509a227d7a12248",
+		"to": "0x497eedc4299dea2f2a364be10025d0ad0f702de3",
+		"gas": "0x5208",
+		"gasPrice": "0x3b9aca00"
+	}`))
+	assert.NoError(t, err)
+
+	var filled struct {
+		Raw ethtypes.HexBytes0xPrefix `json:"raw"`
+		Tx  map[string]interface{}    `json:"tx"`
+	}
+	err = json.Unmarshal(rpcRes.Result.Bytes(), &filled)
+	assert.NoError(t, err)
+	assert.Equal(t, "0x0", filled.Tx["type"])
+	assert.Equal(t, "0x3039", filled.Tx["chainId"])
+	assert.Equal(t, "0xa", filled.Tx["nonce"])
+	assert.Equal(t, "0x5208", filled.Tx["gas"])
+	assert.Equal(t, "0xfb075bb99f2aa4c49955bf703509a227d7a12248", filled.Tx["from"])
+
+	txn := &ethsigner.Transaction{
+		Nonce:    ethtypes.NewHexIntegerU64(10),
+		GasPrice: ethtypes.NewHexIntegerU64(1000000000),
+		GasLimit: ethtypes.NewHexIntegerU64(21000),
+		To:       ethtypes.MustNewAddress("0x497eedc4299dea2f2a364be10025d0ad0f702de3"),
+	}
+	assert.Equal(t, ethtypes.HexBytes0xPrefix(txn.SignaturePayloadLegacyEIP155(12345).Bytes()), filled.Raw)
+}
+
+func TestFillTransactionEIP1559(t *testing.T) {
+	s, bm, done := newTestFillServer(t, func() {
+		config.Set(signerconfig.GasEstimateEnabled, true)
+	})
+	defer done()
+
+	bm.On("CallRPC", mock.Anything, mock.Anything, "eth_estimateGas", mock.Anything).Run(func(args mock.Arguments) {
+		*(args[1].(*ethtypes.HexInteger)) = *ethtypes.NewHexIntegerU64(30000)
+	}).Return(nil)
+
+	// The nonce is left unchanged when the transaction has one
+	rpcRes, err := s.processRPC(s.ctx, fillTestRequest(`{
+		"from": "0xfb075bb99f2aa4c49955bf703509a227d7a12248",
+		"nonce": "0x5",
+		"maxFeePerGas": "0x77359400",
+		"maxPriorityFeePerGas": "0x3b9aca00"
+	}`))
+	assert.NoError(t, err)
+
+	var filled filledTransaction
+	err = json.Unmarshal(rpcRes.Result.Bytes(), &filled)
+	assert.NoError(t, err)
+	assert.Equal(t, ethtypes.HexUint64(2), filled.Tx.Type)
+	assert.Equal(t, int64(5), filled.Tx.Nonce.Int64())
+	assert.Equal(t, int64(36000), filled.Tx.GasLimit.Int64()) // with the default multiplier
+	assert.Equal(t, ethtypes.HexBytes0xPrefix(filled.Tx.SignaturePayloadEIP1559(12345).Bytes()), filled.Raw)
+	bm.AssertExpectations(t)
+}
+
+func TestFillTransactionNonceManager(t *testing.T) {
+	s, bm, done := newTestFillServer(t)
+	defer done()
+	s.nonceManager = nonces.NewManager(nonces.NewMemoryStore(), s.transactionCount, &nonces.Config{ReconcileInterval: time.Hour, StallTimeout: time.Hour})
+
+	// The pending count of the node is ahead of the nonce manager, which has assigned no nonces
+	mockTxCount(bm, "latest", 3)
+	mockTxCount(bm, "pending", 4)
+	rpcRes, err := s.processRPC(s.ctx, fillTestRequest(`{"from": "0xfb075bb99f2aa4c49955bf703509a227d7a12248", "gasPrice": "0x1"}`))
+	assert.NoError(t, err)
+	var filled filledTransaction
+	err = json.Unmarshal(rpcRes.Result.Bytes(), &filled)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), filled.Tx.Nonce.Int64())
+
+	bm.On("CallRPC", mock.Anything, mock.Anything, "eth_getTransactionCount", mock.Anything, "latest").Return(&rpcbackend.RPCError{Message: "pop"}).Once()
+	rpcRes, err = s.processRPC(s.ctx, fillTestRequest(`{"from": "0xfb075bb99f2aa4c49955bf703509a227d7a12248"}`))
+	assert.Regexp(t, "pop", err)
+	assert.Equal(t, int64(rpcbackend.RPCCodeInternalError), rpcRes.Error.Code)
+	bm.AssertExpectations(t)
+}
+
+func TestFillTransactionFail(t *testing.T) {
+	s, bm, done := newTestFillServer(t, func() {
+		config.Set(signerconfig.FeeCapsMaxGasPrice, "1000")
+	})
+	defer done()
+
+	rpcRes, err := s.processRPC(s.ctx, &rpcbackend.RPCRequest{ID: fftypes.JSONAnyPtr("1"), Method: "eth_fillTransaction"})
+	assert.Regexp(t, "FF22019", err)
+	assert.Equal(t, int64(rpcbackend.RPCCodeInvalidRequest), rpcRes.Error.Code)
+
+	rpcRes, err = s.processRPC(s.ctx, fillTestRequest(`{"from": "0xfb075bb99f2aa4c49955bf703509a227d7a12248", "gasPrice": "0x10000"}`))
+	assert.Regexp(t, "FF22136", err)
+	assert.Equal(t, int64(rpcbackend.RPCCodeInvalidRequest), rpcRes.Error.Code)
+
+	rpcRes, err = s.processRPC(s.ctx, fillTestRequest(`{"from": "my-key"}`))
+	assert.Regexp(t, "FF22023", err)
+	assert.Equal(t, int64(rpcbackend.RPCCodeInvalidRequest), rpcRes.Error.Code)
+
+	bm.On("CallRPC", mock.Anything, mock.Anything, "eth_getTransactionCount", mock.Anything, "pending").Return(&rpcbackend.RPCError{Message: "pop"})
+	rpcRes, err = s.processRPC(s.ctx, fillTestRequest(fmt.Sprintf(`{"from": "%s"}`, "0xfb075bb99f2aa4c49955bf703509a227d7a12248")))
+	assert.Regexp(t, "pop", err)
+	assert.Equal(t, int64(rpcbackend.RPCCodeInternalError), rpcRes.Error.Code)
+}
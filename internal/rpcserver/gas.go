@@ -0,0 +1,176 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/big"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/hyperledger/firefly-common/pkg/ffresty"
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-common/pkg/log"
+	"github.com/hyperledger/firefly-signer/internal/signerconfig"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/hyperledger/firefly-signer/pkg/ethsigner"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/gasoracle"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+)
+
+// estimateGas populates the gas limit of the transaction with eth_estimateGas. The multiplier is applied
+// as a safety margin, as the gas used can change between the estimate and the transaction being mined,
+// but never takes the gas limit above the cap.
+func (s *rpcServer) estimateGas(ctx context.Context, rpcReq *rpcbackend.RPCRequest, txn *ethsigner.Transaction) (*rpcbackend.RPCResponse, error) {
+	var estimate ethtypes.HexInteger
+	if rpcErr := s.backend.CallRPC(ctx, &estimate, "eth_estimateGas", newCallObject(txn)); rpcErr != nil {
+		reason := revertReason(ctx, rpcErr)
+		log.L(ctx).Warnf("Gas estimation of transaction failed: %s", reason)
+		err := i18n.NewError(ctx, signermsgs.MsgGasEstimateFailed, reason)
+		return backendErrorResponse(rpcReq, rpcErr, err), err
+	}
+
+	gas := uint64(math.Ceil(float64(estimate.Uint64()) * s.gasEstimateMultiplier))
+	if s.gasEstimateCap > 0 && gas > s.gasEstimateCap {
+		if estimate.Uint64() > s.gasEstimateCap {
+			err := i18n.NewError(ctx, signermsgs.MsgGasEstimateExceedsCap, estimate.Uint64(), s.gasEstimateCap)
+			return rpcbackend.RPCErrorResponse(err, rpcReq.ID, rpcbackend.RPCCodeInvalidRequest), err
+		}
+		gas = s.gasEstimateCap
+	}
+	log.L(ctx).Debugf("Estimated gas %d, populated gas limit %d", estimate.Uint64(), gas)
+	txn.GasLimit = ethtypes.NewHexIntegerU64(gas)
+	return nil, nil
+}
+
+// backendRPC calls the backend the server has at the time of each call, so the node source of fees is
+// routed to the chain of each request
+type backendRPC struct {
+	s *rpcServer
+}
+
+func (b *backendRPC) CallRPC(ctx context.Context, result interface{}, method string, params ...interface{}) *rpcbackend.RPCError {
+	return b.s.backend.CallRPC(ctx, result, method, params...)
+}
+
+// newFeeEstimator combines the gas price sources of fees.oracles, or estimates the fees from the node when
+// there are none
+func (s *rpcServer) newFeeEstimator(ctx context.Context) (*gasoracle.Estimator, error) {
+	size := signerconfig.FeeOraclesConfig.ArraySize()
+	sources := make([]*gasoracle.WeightedSource, 0, size)
+	if size == 0 {
+		node, err := s.newNodeFeeSource(ctx)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, &gasoracle.WeightedSource{Source: node, Weight: 1})
+	}
+	for i := 0; i < size; i++ {
+		entry := signerconfig.FeeOraclesConfig.ArrayEntry(i)
+		source, err := s.newFeeSource(ctx, i, entry)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, &gasoracle.WeightedSource{Source: source, Weight: entry.GetFloat64(signerconfig.FeeOraclesConfWeight)})
+	}
+	return gasoracle.NewEstimator(ctx, &gasoracle.EstimatorOptions{
+		Sources:           sources,
+		Mode:              gasoracle.Mode(config.GetString(signerconfig.FeesOracleMode)),
+		BaseFeeMultiplier: config.GetFloat64(signerconfig.FeesBaseFeeMultiplier),
+	})
+}
+
+func (s *rpcServer) newNodeFeeSource(ctx context.Context) (gasoracle.Source, error) {
+	return gasoracle.NewNodeSource(ctx, &backendRPC{s: s}, gasoracle.NodeOptions{
+		Strategy:          gasoracle.NodeStrategy(config.GetString(signerconfig.FeesStrategy)),
+		HistoryBlocks:     config.GetInt(signerconfig.FeesHistoryBlocks),
+		HistoryPercentile: config.GetFloat64(signerconfig.FeesHistoryPercentile),
+	})
+}
+
+func (s *rpcServer) newFeeSource(ctx context.Context, i int, entry config.Section) (gasoracle.Source, error) {
+	oracleType := entry.GetString(signerconfig.FeeOraclesConfType)
+	if oracleType == "node" {
+		return s.newNodeFeeSource(ctx)
+	}
+	newSource, ok := map[string]func(context.Context, *resty.Client, gasoracle.OracleOptions) (gasoracle.Source, error){
+		"etherscan":   gasoracle.NewEtherscanSource,
+		"blocknative": gasoracle.NewBlocknativeSource,
+		"gasStation":  gasoracle.NewGasStationSource,
+	}[oracleType]
+	if !ok {
+		return nil, i18n.NewError(ctx, signermsgs.MsgGasOracleBadConfig, i, fmt.Sprintf("unknown type '%s'", oracleType))
+	}
+	url := entry.GetString(signerconfig.FeeOraclesConfURL)
+	if url == "" {
+		return nil, i18n.NewError(ctx, signermsgs.MsgGasOracleBadConfig, i, "url is required")
+	}
+	client := ffresty.NewWithConfig(ctx, ffresty.Config{
+		URL: url,
+		HTTPConfig: ffresty.HTTPConfig{
+			HTTPRequestTimeout: fftypes.FFDuration(entry.GetDuration(signerconfig.FeeOraclesConfRequestTimeout)),
+			HTTPHeaders:        entry.GetObject(signerconfig.FeeOraclesConfHeaders),
+		},
+	})
+	return newSource(ctx, client, gasoracle.OracleOptions{
+		Speed:  gasoracle.Speed(entry.GetString(signerconfig.FeeOraclesConfSpeed)),
+		APIKey: entry.GetString(signerconfig.FeeOraclesConfAPIKey),
+	})
+}
+
+// populateFees sets any EIP-1559 fees the transaction does not have, from the base fee and priority fee of the
+// gas price sources. The max fee allows for the base fee rising before the transaction is mined, and only the
+// fees actually charged are paid. Chains without EIP-1559 have no base fee, so we set the legacy gas price
+// instead. A transaction with a legacy gas price is left unchanged.
+func (s *rpcServer) populateFees(ctx context.Context, rpcReq *rpcbackend.RPCRequest, txn *ethsigner.Transaction) (*rpcbackend.RPCResponse, error) {
+	if txn.GasPrice != nil || (txn.MaxFeePerGas != nil && txn.MaxPriorityFeePerGas != nil) {
+		return nil, nil
+	}
+
+	fees, err := s.fees.Fees(ctx)
+	if err != nil {
+		return rpcbackend.RPCErrorResponse(err, rpcReq.ID, rpcbackend.RPCCodeInternalError), err
+	}
+
+	baseFee := fees.BaseFee
+	if baseFee == nil || baseFee.Sign() == 0 {
+		if txn.MaxFeePerGas != nil || txn.MaxPriorityFeePerGas != nil || fees.GasPrice == nil {
+			return nil, nil
+		}
+		log.L(ctx).Debugf("No base fee, populated gas price %s", fees.GasPrice)
+		txn.GasPrice = (*ethtypes.HexInteger)(fees.GasPrice)
+		return nil, nil
+	}
+
+	if txn.MaxPriorityFeePerGas == nil {
+		priorityFee := fees.MaxPriorityFeePerGas
+		if priorityFee == nil {
+			// Not every source suggests a priority fee
+			priorityFee = new(big.Int)
+		}
+		txn.MaxPriorityFeePerGas = (*ethtypes.HexInteger)(priorityFee)
+	}
+	if txn.MaxFeePerGas == nil {
+		txn.MaxFeePerGas = (*ethtypes.HexInteger)(s.fees.MaxFeePerGas(baseFee, txn.MaxPriorityFeePerGas.BigInt()))
+	}
+	log.L(ctx).Debugf("Base fee %s, populated maxFeePerGas %s and maxPriorityFeePerGas %s", baseFee, txn.MaxFeePerGas.BigInt(), txn.MaxPriorityFeePerGas.BigInt())
+	return nil, nil
+}
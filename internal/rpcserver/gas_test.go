@@ -0,0 +1,454 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-signer/internal/signerconfig"
+	"github.com/hyperledger/firefly-signer/mocks/ethsignermocks"
+	"github.com/hyperledger/firefly-signer/mocks/rpcbackendmocks"
+	"github.com/hyperledger/firefly-signer/pkg/ethsigner"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/gasoracle"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func setTestGasEstimateConf() {
+	config.Set(signerconfig.GasEstimateEnabled, true)
+}
+
+func gasEstimateTestRequest(gas string) *rpcbackend.RPCRequest {
+	return &rpcbackend.RPCRequest{
+		ID:     fftypes.JSONAnyPtr("1"),
+		Method: "eth_sendTransaction",
+		Params: []*fftypes.JSONAny{fftypes.JSONAnyPtr(`{
+			"from": "0xfb075bb99f2aa4c49955bf703509a227d7a12248",
+			"to": "0x3c99f2a4b366d46bcf2277639a135a6d1288eceb",
+			"value": "0x64",
+			"data": "0xfeedbeef",
+			"nonce": "0x0"` + gas + `
+		}`)},
+	}
+}
+
+func mockEstimateGas(bm *rpcbackendmocks.Backend, estimate uint64) {
+	bm.On("CallRPC", mock.Anything, mock.Anything, "eth_estimateGas", mock.MatchedBy(func(call *callObject) bool {
+		return string(call.From) == `"0xfb075bb99f2aa4c49955bf703509a227d7a12248"` &&
+			call.To.String() == "0x3c99f2a4b366d46bcf2277639a135a6d1288eceb" &&
+			call.Value.Int64() == 100 &&
+			call.Data.String() == "0xfeedbeef"
+	})).Run(func(args mock.Arguments) {
+		*(args[1].(*ethtypes.HexInteger)) = *ethtypes.NewHexIntegerU64(estimate)
+	}).Return(nil).Once()
+}
+
+// mockSignGasLimit fails the signing, as we only need to check the gas limit that was populated
+func mockSignGasLimit(w *ethsignermocks.Wallet, gas int64) {
+	w.On("Sign", mock.Anything, mock.MatchedBy(func(txn *ethsigner.Transaction) bool {
+		return txn.GasLimit.Int64() == gas
+	}), mock.Anything).Return(nil, fmt.Errorf("pop")).Once()
+}
+
+func TestGasEstimatePopulated(t *testing.T) {
+	_, s, done := newTestServer(t, setTestGasEstimateConf)
+	defer done()
+
+	bm := s.backend.(*rpcbackendmocks.Backend)
+	w := s.wallet.(*ethsignermocks.Wallet)
+	mockEstimateGas(bm, 21001)
+	mockSignGasLimit(w, 25202)
+
+	_, err := s.processRPC(s.ctx, gasEstimateTestRequest(""))
+	assert.Regexp(t, "pop", err)
+	bm.AssertExpectations(t)
+	w.AssertExpectations(t)
+}
+
+func TestGasEstimateNotRequired(t *testing.T) {
+	_, s, done := newTestServer(t, setTestGasEstimateConf)
+	defer done()
+
+	w := s.wallet.(*ethsignermocks.Wallet)
+	mockSignGasLimit(w, 50000)
+
+	_, err := s.processRPC(s.ctx, gasEstimateTestRequest(`, "gas": "0xc350"`))
+	assert.Regexp(t, "pop", err)
+	w.AssertExpectations(t)
+}
+
+func TestGasEstimateCapped(t *testing.T) {
+	_, s, done := newTestServer(t, setTestGasEstimateConf, func() {
+		config.Set(signerconfig.GasEstimateCap, 22000)
+	})
+	defer done()
+
+	bm := s.backend.(*rpcbackendmocks.Backend)
+	w := s.wallet.(*ethsignermocks.Wallet)
+	mockEstimateGas(bm, 21000)
+	mockSignGasLimit(w, 22000)
+
+	_, err := s.processRPC(s.ctx, gasEstimateTestRequest(""))
+	assert.Regexp(t, "pop", err)
+	w.AssertExpectations(t)
+}
+
+func TestGasEstimateExceedsCap(t *testing.T) {
+	_, s, done := newTestServer(t, setTestGasEstimateConf, func() {
+		config.Set(signerconfig.GasEstimateCap, 20000)
+	})
+	defer done()
+
+	bm := s.backend.(*rpcbackendmocks.Backend)
+	mockEstimateGas(bm, 21000)
+
+	rpcRes, err := s.processRPC(s.ctx, gasEstimateTestRequest(""))
+	assert.Regexp(t, "FF22129.*21,000.*20,000", err)
+	assert.Equal(t, int64(rpcbackend.RPCCodeInvalidRequest), rpcRes.Error.Code)
+}
+
+func TestGasEstimateFailed(t *testing.T) {
+	_, s, done := newTestServer(t, setTestGasEstimateConf)
+	defer done()
+
+	bm := s.backend.(*rpcbackendmocks.Backend)
+	bm.On("CallRPC", mock.Anything, mock.Anything, "eth_estimateGas", mock.Anything).Return(&rpcbackend.RPCError{
+		Code:    3,
+		Message: "execution reverted",
+		Data:    *testRevertData(t, panicErrorABI[0], 0x01),
+	})
+
+	rpcRes, err := s.processRPC(s.ctx, gasEstimateTestRequest(""))
+	assert.Regexp(t, `FF22128.*Panic\("1"\)`, err)
+	assert.Equal(t, int64(3), rpcRes.Error.Code)
+}
+
+func TestGasEstimateBadMultiplier(t *testing.T) {
+	signerconfig.Reset()
+	config.Set(signerconfig.GasEstimateMultiplier, 0.5)
+
+	_, err := NewServer(context.Background(), &ethsignermocks.Wallet{})
+	assert.Regexp(t, "FF22130", err)
+}
+
+func setTestFeesConf() {
+	config.Set(signerconfig.FeesEnabled, true)
+}
+
+func feesTestRequest(fees string) *rpcbackend.RPCRequest {
+	return &rpcbackend.RPCRequest{
+		ID:     fftypes.JSONAnyPtr("1"),
+		Method: "eth_sendTransaction",
+		Params: []*fftypes.JSONAny{fftypes.JSONAnyPtr(`{
+			"from": "0xfb075bb99f2aa4c49955bf703509a227d7a12248",
+			"nonce": "0x0",
+			"gas": "0x5208"` + fees + `
+		}`)},
+	}
+}
+
+func mockFeeHistory(bm *rpcbackendmocks.Backend, blocks uint64, percentiles []float64, result string) {
+	bm.On("CallRPC", mock.Anything, mock.Anything, "eth_feeHistory", ethtypes.HexUint64(blocks), "latest", percentiles).Run(func(args mock.Arguments) {
+		err := json.Unmarshal([]byte(result), args[1])
+		if err != nil {
+			panic(err)
+		}
+	}).Return(nil).Once()
+}
+
+func mockSignFees(w *ethsignermocks.Wallet, check func(txn *ethsigner.Transaction) bool) {
+	w.On("Sign", mock.Anything, mock.MatchedBy(check), mock.Anything).Return(nil, fmt.Errorf("pop")).Once()
+}
+
+func TestFeesFeeHistory(t *testing.T) {
+	_, s, done := newTestServer(t, setTestFeesConf)
+	defer done()
+
+	bm := s.backend.(*rpcbackendmocks.Backend)
+	w := s.wallet.(*ethsignermocks.Wallet)
+	mockFeeHistory(bm, 10, []float64{50}, `{
+		"baseFeePerGas": ["0x64", "0xc8"],
+		"reward": [["0xa"], ["0x14"], []]
+	}`)
+	mockSignFees(w, func(txn *ethsigner.Transaction) bool {
+		return txn.GasPrice == nil &&
+			txn.MaxPriorityFeePerGas.Int64() == 15 &&
+			txn.MaxFeePerGas.Int64() == 415
+	})
+
+	_, err := s.processRPC(s.ctx, feesTestRequest(""))
+	assert.Regexp(t, "pop", err)
+	bm.AssertExpectations(t)
+	w.AssertExpectations(t)
+}
+
+func TestFeesMaxPriorityFeePerGas(t *testing.T) {
+	_, s, done := newTestServer(t, setTestFeesConf, func() {
+		config.Set(signerconfig.FeesStrategy, "maxPriorityFeePerGas")
+		config.Set(signerconfig.FeesBaseFeeMultiplier, 1.5)
+	})
+	defer done()
+
+	bm := s.backend.(*rpcbackendmocks.Backend)
+	w := s.wallet.(*ethsignermocks.Wallet)
+	mockFeeHistory(bm, 1, []float64{}, `{"baseFeePerGas": ["0x64", "0xc8"]}`)
+	bm.On("CallRPC", mock.Anything, mock.Anything, "eth_maxPriorityFeePerGas").Run(func(args mock.Arguments) {
+		*(args[1].(*ethtypes.HexInteger)) = *ethtypes.NewHexIntegerU64(7)
+	}).Return(nil).Once()
+	mockSignFees(w, func(txn *ethsigner.Transaction) bool {
+		return txn.MaxPriorityFeePerGas.Int64() == 7 &&
+			txn.MaxFeePerGas.Int64() == 307
+	})
+
+	_, err := s.processRPC(s.ctx, feesTestRequest(""))
+	assert.Regexp(t, "pop", err)
+	bm.AssertExpectations(t)
+	w.AssertExpectations(t)
+}
+
+func TestFeesPartial(t *testing.T) {
+	_, s, done := newTestServer(t, setTestFeesConf)
+	defer done()
+
+	bm := s.backend.(*rpcbackendmocks.Backend)
+	w := s.wallet.(*ethsignermocks.Wallet)
+	mockFeeHistory(bm, 10, []float64{50}, `{"baseFeePerGas": ["0x64"], "reward": [["0xa"]]}`)
+	mockSignFees(w, func(txn *ethsigner.Transaction) bool {
+		return txn.MaxPriorityFeePerGas.Int64() == 1 &&
+			txn.MaxFeePerGas.Int64() == 201
+	})
+
+	_, err := s.processRPC(s.ctx, feesTestRequest(`, "maxPriorityFeePerGas": "0x1"`))
+	assert.Regexp(t, "pop", err)
+	w.AssertExpectations(t)
+}
+
+func TestFeesNotRequired(t *testing.T) {
+	_, s, done := newTestServer(t, setTestFeesConf)
+	defer done()
+
+	w := s.wallet.(*ethsignermocks.Wallet)
+	mockSignFees(w, func(txn *ethsigner.Transaction) bool {
+		return txn.GasPrice.Int64() == 5 && txn.MaxFeePerGas == nil
+	})
+	_, err := s.processRPC(s.ctx, feesTestRequest(`, "gasPrice": "0x5"`))
+	assert.Regexp(t, "pop", err)
+
+	mockSignFees(w, func(txn *ethsigner.Transaction) bool {
+		return txn.GasPrice == nil && txn.MaxFeePerGas.Int64() == 3 && txn.MaxPriorityFeePerGas.Int64() == 2
+	})
+	_, err = s.processRPC(s.ctx, feesTestRequest(`, "maxFeePerGas": "0x3", "maxPriorityFeePerGas": "0x2"`))
+	assert.Regexp(t, "pop", err)
+	w.AssertExpectations(t)
+}
+
+func TestFeesLegacyChain(t *testing.T) {
+	_, s, done := newTestServer(t, setTestFeesConf)
+	defer done()
+
+	bm := s.backend.(*rpcbackendmocks.Backend)
+	w := s.wallet.(*ethsignermocks.Wallet)
+	mockFeeHistory(bm, 10, []float64{50}, `{"baseFeePerGas": [], "reward": []}`)
+	mockGasPrice(bm)
+	mockSignFees(w, func(txn *ethsigner.Transaction) bool {
+		return txn.GasPrice.Int64() == 1000 && txn.MaxFeePerGas == nil && txn.MaxPriorityFeePerGas == nil
+	})
+	_, err := s.processRPC(s.ctx, feesTestRequest(""))
+	assert.Regexp(t, "pop", err)
+
+	// With some EIP-1559 fees specified, we leave them for the node to reject
+	mockFeeHistory(bm, 10, []float64{50}, `{"baseFeePerGas": ["0x0", "0x0"], "reward": [["0x0"]]}`)
+	mockGasPrice(bm)
+	mockSignFees(w, func(txn *ethsigner.Transaction) bool {
+		return txn.GasPrice == nil && txn.MaxFeePerGas.Int64() == 3 && txn.MaxPriorityFeePerGas == nil
+	})
+	_, err = s.processRPC(s.ctx, feesTestRequest(`, "maxFeePerGas": "0x3"`))
+	assert.Regexp(t, "pop", err)
+
+	bm.AssertExpectations(t)
+	w.AssertExpectations(t)
+}
+
+func TestFeesFail(t *testing.T) {
+	_, s, done := newTestServer(t, setTestFeesConf)
+	defer done()
+
+	bm := s.backend.(*rpcbackendmocks.Backend)
+	bm.On("CallRPC", mock.Anything, mock.Anything, "eth_feeHistory", mock.Anything, mock.Anything, mock.Anything).Return(&rpcbackend.RPCError{Message: "pop"}).Once()
+	rpcRes, err := s.processRPC(s.ctx, feesTestRequest(""))
+	assert.Regexp(t, "pop", err)
+	assert.Equal(t, int64(rpcbackend.RPCCodeInternalError), rpcRes.Error.Code)
+
+	mockFeeHistory(bm, 10, []float64{50}, `{"baseFeePerGas": []}`)
+	bm.On("CallRPC", mock.Anything, mock.Anything, "eth_gasPrice").Return(&rpcbackend.RPCError{Message: "pop"}).Once()
+	_, err = s.processRPC(s.ctx, feesTestRequest(""))
+	assert.Regexp(t, "pop", err)
+
+	config.Set(signerconfig.FeesStrategy, "maxPriorityFeePerGas")
+	s.fees, err = s.newFeeEstimator(s.ctx)
+	assert.NoError(t, err)
+	mockFeeHistory(bm, 1, []float64{}, `{"baseFeePerGas": ["0x64"]}`)
+	bm.On("CallRPC", mock.Anything, mock.Anything, "eth_maxPriorityFeePerGas").Return(&rpcbackend.RPCError{Message: "pop"}).Once()
+	_, err = s.processRPC(s.ctx, feesTestRequest(""))
+	assert.Regexp(t, "pop", err)
+
+	bm.AssertExpectations(t)
+}
+
+func TestFeesBadConfig(t *testing.T) {
+	for errCode, setConf := range map[string]func(){
+		"FF22131": func() { config.Set(signerconfig.FeesStrategy, "wrong") },
+		"FF22132": func() { config.Set(signerconfig.FeesHistoryPercentile, 101) },
+		"FF22133": func() { config.Set(signerconfig.FeesBaseFeeMultiplier, 0.9) },
+	} {
+		signerconfig.Reset()
+		setTestFeesConf()
+		setConf()
+		_, err := NewServer(context.Background(), &ethsignermocks.Wallet{})
+		assert.Regexp(t, errCode, err)
+	}
+}
+
+func setTestFeeOraclesConf(oracles ...string) func() {
+	return func() {
+		viper.SetConfigType("yaml")
+		_ = viper.ReadConfig(strings.NewReader("fees:\n  oracles:\n  - " + strings.Join(oracles, "\n  - ") + "\n"))
+		setTestFeesConf()
+	}
+}
+
+func TestFeesOracleFallback(t *testing.T) {
+	gasStation := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "test", r.Header.Get("X-Test"))
+		_, _ = w.Write([]byte(`{"fast": {"maxPriorityFee": 0.00000002}, "estimatedBaseFee": 0.0000001}`))
+	}))
+	defer gasStation.Close()
+
+	_, s, done := newTestServer(t, setTestFeeOraclesConf(
+		"{type: gasStation, url: 'http://localhost:1', requestTimeout: 1s}",
+		"{type: gasStation, url: '"+gasStation.URL+"', speed: fast, headers: {X-Test: test}}",
+		"{type: node}",
+	))
+	defer done()
+
+	w := s.wallet.(*ethsignermocks.Wallet)
+	mockSignFees(w, func(txn *ethsigner.Transaction) bool {
+		return txn.MaxPriorityFeePerGas.Int64() == 20 &&
+			txn.MaxFeePerGas.Int64() == 220
+	})
+
+	_, err := s.processRPC(s.ctx, feesTestRequest(""))
+	assert.Regexp(t, "pop", err)
+	w.AssertExpectations(t)
+}
+
+func TestFeesOracleWeighted(t *testing.T) {
+	etherscan := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "gasoracle", r.URL.Query().Get("action"))
+		assert.Equal(t, "key1", r.URL.Query().Get("apikey"))
+		_, _ = w.Write([]byte(`{"status": "1", "message": "OK", "result": {"SafeGasPrice": "0.000000250", "ProposeGasPrice": "0.000000300", "FastGasPrice": "0.000000350", "suggestBaseFee": "0.000000290"}}`))
+	}))
+	defer etherscan.Close()
+
+	_, s, done := newTestServer(t, setTestFeeOraclesConf(
+		"{type: etherscan, url: '"+etherscan.URL+"', apiKey: key1, weight: 3}",
+		"{type: node}",
+	), func() {
+		config.Set(signerconfig.FeesOracleMode, "weighted")
+	})
+	defer done()
+
+	bm := s.backend.(*rpcbackendmocks.Backend)
+	w := s.wallet.(*ethsignermocks.Wallet)
+	mockFeeHistory(bm, 10, []float64{50}, `{"baseFeePerGas": ["0x64", "0xfa"], "reward": [["0x1e"]]}`)
+	mockSignFees(w, func(txn *ethsigner.Transaction) bool {
+		// Base fee (290*3 + 250)/4 = 280, and priority fee (10*3 + 30)/4 = 15
+		return txn.MaxPriorityFeePerGas.Int64() == 15 &&
+			txn.MaxFeePerGas.Int64() == 575
+	})
+
+	_, err := s.processRPC(s.ctx, feesTestRequest(""))
+	assert.Regexp(t, "pop", err)
+	bm.AssertExpectations(t)
+	w.AssertExpectations(t)
+}
+
+func TestFeesOracleBadConfig(t *testing.T) {
+	for errCode, oracle := range map[string]string{
+		"FF22271.*unknown type 'wrong'": "{type: wrong}",
+		"FF22271.*url is required":      "{type: etherscan}",
+		"FF22270":                       "{type: blocknative, url: 'http://localhost:1', speed: wrong}",
+		"FF22268":                       "{type: node, weight: 0}",
+		"FF22131":                       "{type: node}",
+	} {
+		signerconfig.Reset()
+		setTestFeeOraclesConf(oracle)()
+		if errCode == "FF22131" {
+			config.Set(signerconfig.FeesStrategy, "wrong")
+		}
+		_, err := NewServer(context.Background(), &ethsignermocks.Wallet{})
+		assert.Regexp(t, errCode, err)
+	}
+
+	signerconfig.Reset()
+	setTestFeesConf()
+	config.Set(signerconfig.FeesOracleMode, "wrong")
+	_, err := NewServer(context.Background(), &ethsignermocks.Wallet{})
+	assert.Regexp(t, "FF22269", err)
+}
+
+type testBaseFeeSource struct{}
+
+func (ts *testBaseFeeSource) Name() string {
+	return "baseFeeOnly"
+}
+
+func (ts *testBaseFeeSource) Fees(ctx context.Context) (*gasoracle.Fees, error) {
+	return &gasoracle.Fees{BaseFee: big.NewInt(100)}, nil
+}
+
+func TestFeesNoPriorityFee(t *testing.T) {
+	_, s, done := newTestServer(t, setTestFeesConf)
+	defer done()
+
+	var err error
+	s.fees, err = gasoracle.NewEstimator(s.ctx, &gasoracle.EstimatorOptions{
+		Sources: []*gasoracle.WeightedSource{{Source: &testBaseFeeSource{}, Weight: 1}},
+	})
+	assert.NoError(t, err)
+
+	w := s.wallet.(*ethsignermocks.Wallet)
+	mockSignFees(w, func(txn *ethsigner.Transaction) bool {
+		return txn.MaxPriorityFeePerGas.Int64() == 0 &&
+			txn.MaxFeePerGas.Int64() == 200
+	})
+	_, err = s.processRPC(s.ctx, feesTestRequest(""))
+	assert.Regexp(t, "pop", err)
+	w.AssertExpectations(t)
+}
@@ -0,0 +1,458 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-common/pkg/log"
+	"github.com/hyperledger/firefly-signer/internal/signerconfig"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/fswallet"
+	"github.com/hyperledger/firefly-signer/pkg/keystorev3"
+	"github.com/hyperledger/firefly-signer/pkg/persistence"
+	"github.com/hyperledger/firefly-signer/pkg/rpcauth"
+)
+
+const (
+	keyCeremonyImport = "import"
+	keyCeremonyExport = "export"
+
+	keyCeremonyPending   = "pending"
+	keyCeremonyApproved  = "approved"
+	keyCeremonyCompleted = "completed"
+	keyCeremonyRejected  = "rejected"
+	keyCeremonyExpired   = "expired"
+
+	keyCeremonyActionPropose       = "proposed"
+	keyCeremonyActionApprove       = "approved"
+	keyCeremonyActionReject        = "rejected"
+	keyCeremonyActionExecute       = "executed"
+	keyCeremonyActionExecuteFailed = "execute_failed"
+
+	// The proposer is the first of the two identities that must approve a ceremony
+	keyCeremonyApprovals = 2
+
+	// The collection of the persistence each ceremony is a record in
+	keyCeremonyCollection = "keyCeremonies"
+)
+
+// keyCeremonyEvent is the audit record of an action on a key ceremony
+type keyCeremonyEvent struct {
+	Time     *fftypes.FFTime `json:"time"`
+	Action   string          `json:"action"`
+	Identity string          `json:"identity"`
+	Error    string          `json:"error,omitempty"`
+}
+
+// keyCeremony is the import or export of a key under dual control. It is proposed by one identity, and must be
+// approved by a second before the proposer can execute it.
+type keyCeremony struct {
+	ID        *fftypes.UUID          `json:"id"`
+	Operation string                 `json:"operation"`
+	Address   *ethtypes.Address0xHex `json:"address"`
+	Status    string                 `json:"status"`
+	Proposer  string                 `json:"proposer"`
+	Approvals []string               `json:"approvals"`
+	// The identities of the approvals, including any claims they were authorized with, so each can be
+	// authorized again before the ceremony is executed
+	ApprovalIdentities []*rpcauth.Identity `json:"approvalIdentities,omitempty"`
+	Created            *fftypes.FFTime     `json:"created"`
+	Expires            *fftypes.FFTime     `json:"expires"`
+	History            []*keyCeremonyEvent `json:"history"`
+	Keystore           json.RawMessage     `json:"keystore,omitempty"` // the encrypted key to import, until it is imported
+}
+
+type keyCeremonyProposal struct {
+	Operation string                 `json:"operation"`
+	Address   *ethtypes.Address0xHex `json:"address,omitempty"`  // the key to export
+	Keystore  json.RawMessage        `json:"keystore,omitempty"` // the Keystore V3 file to import
+}
+
+type keyCeremonyExecution struct {
+	// Password decrypts the keystore to import, or encrypts the exported keystore
+	Password string `json:"password"`
+	// KeyPassword is the password of an imported key in the wallet, when the wallet has a password file for each
+	// key. The password of the keystore is used when it is not set.
+	KeyPassword string `json:"keyPassword,omitempty"`
+}
+
+// keyCeremonyResult is a key ceremony returned by the admin API, which only includes a keystore when it is exported
+type keyCeremonyResult struct {
+	*keyCeremony
+	ApprovalIdentities []*rpcauth.Identity `json:"approvalIdentities,omitempty"` // never returned
+	Keystore           json.RawMessage     `json:"keystore,omitempty"`
+}
+
+// keyExporter is implemented by wallets that can return the keys they hold, such as the file wallet
+type keyExporter interface {
+	GetWalletFile(ctx context.Context, addr ethtypes.Address0xHex) (keystorev3.WalletFile, error)
+}
+
+// keyCeremonies holds every key ceremony, each persisted as its own record so they survive restarts
+type keyCeremonies struct {
+	mux        sync.Mutex
+	store      persistence.Store
+	expiry     time.Duration
+	walletConf *fswallet.Config
+	ceremonies map[fftypes.UUID]*keyCeremony
+}
+
+// newKeyCeremonies loads the ceremonies from their own directory if one is configured, otherwise from the
+// shared persistence of the server
+func newKeyCeremonies(ctx context.Context, shared persistence.Store) (_ *keyCeremonies, err error) {
+	conf := signerconfig.AdminConfig
+	kc := &keyCeremonies{
+		store:      shared,
+		expiry:     conf.GetDuration(signerconfig.AdminConfKeyCeremonyExpiry),
+		walletConf: fswallet.ReadConfig(signerconfig.FileWalletConfig),
+		ceremonies: make(map[fftypes.UUID]*keyCeremony),
+	}
+	if !config.GetBool(signerconfig.FileWalletEnabled) {
+		return nil, i18n.NewError(ctx, signermsgs.MsgKeyCeremonyNoFileWallet)
+	}
+	if path := conf.GetString(signerconfig.AdminConfKeyCeremonyPath); path != "" {
+		if kc.store, err = persistence.NewFileStore(ctx, path); err != nil {
+			return nil, i18n.NewError(ctx, signermsgs.MsgKeyCeremonyLoadFailed, err)
+		}
+	}
+	if kc.store == nil {
+		return nil, i18n.NewError(ctx, signermsgs.MsgKeyCeremonyNoPath)
+	}
+	ids, err := kc.store.List(ctx, keyCeremonyCollection)
+	if err != nil {
+		return nil, i18n.NewError(ctx, signermsgs.MsgKeyCeremonyLoadFailed, err)
+	}
+	for _, id := range ids {
+		var c keyCeremony
+		b, _, err := kc.store.Get(ctx, keyCeremonyCollection, id)
+		if err == nil {
+			err = json.Unmarshal(b, &c)
+		}
+		if err == nil && c.ID == nil {
+			err = i18n.NewError(ctx, signermsgs.MsgKeyCeremonyInvalid, id)
+		}
+		if err != nil {
+			return nil, i18n.NewError(ctx, signermsgs.MsgKeyCeremonyLoadFailed, err)
+		}
+		kc.ceremonies[*c.ID] = &c
+	}
+	log.L(ctx).Infof("Loaded %d key ceremonies", len(kc.ceremonies))
+	return kc, nil
+}
+
+// get returns a ceremony with the lock held, with its status as it is now
+func (kc *keyCeremonies) get(ctx context.Context, id string) (*keyCeremony, error) {
+	uuid, err := fftypes.ParseUUID(ctx, id)
+	if err != nil {
+		return nil, i18n.NewError(ctx, signermsgs.MsgKeyCeremonyNotFound, id)
+	}
+	c := kc.ceremonies[*uuid]
+	if c == nil {
+		return nil, i18n.NewError(ctx, signermsgs.MsgKeyCeremonyNotFound, id)
+	}
+	if (c.Status == keyCeremonyPending || c.Status == keyCeremonyApproved) && time.Now().After(*c.Expires.Time()) {
+		c.Status = keyCeremonyExpired
+		c.Keystore = nil
+	}
+	return c, nil
+}
+
+// update applies a change to a copy of a ceremony with the lock held, recording the action in its history, and
+// only replaces the ceremony once the change is persisted
+func (kc *keyCeremonies) update(ctx context.Context, c *keyCeremony, action string, actionErr error, change func(c *keyCeremony)) (*keyCeremony, error) {
+	var updated keyCeremony
+	b, _ := json.Marshal(c)
+	_ = json.Unmarshal(b, &updated)
+	change(&updated)
+
+	event := &keyCeremonyEvent{Time: fftypes.Now(), Action: action, Identity: rpcauth.GetIdentity(ctx).ID}
+	outcome := updated.Status
+	if actionErr != nil {
+		event.Error = actionErr.Error()
+		outcome = actionErr.Error()
+	}
+	updated.History = append(updated.History, event)
+	log.L(ctx).WithField("audit", "key_ceremony").Warnf("AUDIT key ceremony %s %s caller='%s' operation=%s address=%s outcome='%s'",
+		updated.ID, action, event.Identity, updated.Operation, updated.Address, outcome)
+
+	b, _ = json.MarshalIndent(&updated, "", "  ")
+	if err := kc.store.Put(ctx, keyCeremonyCollection, updated.ID.String(), b); err != nil {
+		return nil, i18n.NewError(ctx, signermsgs.MsgKeyCeremonyStoreFailed, updated.ID, err)
+	}
+	kc.ceremonies[*updated.ID] = &updated
+	return &updated, nil
+}
+
+func (s *rpcServer) adminProposeKeyCeremony(ctx context.Context, _ map[string]string, body []byte) (interface{}, error) {
+	var proposal keyCeremonyProposal
+	if err := json.Unmarshal(body, &proposal); err != nil {
+		return nil, i18n.NewError(ctx, signermsgs.MsgKeyCeremonyInvalid, err)
+	}
+	c := &keyCeremony{
+		ID:        fftypes.NewUUID(),
+		Operation: proposal.Operation,
+		Status:    keyCeremonyPending,
+		Created:   fftypes.Now(),
+	}
+	expires := fftypes.FFTime(time.Now().Add(s.keyCeremonies.expiry))
+	c.Expires = &expires
+	switch proposal.Operation {
+	case keyCeremonyExport:
+		if proposal.Address == nil {
+			return nil, i18n.NewError(ctx, signermsgs.MsgKeyCeremonyInvalid, "address")
+		}
+		c.Address = proposal.Address
+	case keyCeremonyImport:
+		var keystore struct {
+			Address *ethtypes.Address0xHex `json:"address"`
+		}
+		if err := json.Unmarshal(proposal.Keystore, &keystore); err != nil || keystore.Address == nil {
+			return nil, i18n.NewError(ctx, signermsgs.MsgKeyCeremonyInvalid, "keystore")
+		}
+		c.Address = keystore.Address
+		c.Keystore = proposal.Keystore
+	default:
+		return nil, i18n.NewError(ctx, signermsgs.MsgKeyCeremonyInvalid, "operation")
+	}
+
+	if _, err := s.keyCeremonyAuthorizer(ctx); err != nil {
+		return nil, err
+	}
+	kc := s.keyCeremonies
+	kc.mux.Lock()
+	defer kc.mux.Unlock()
+	c, err := kc.update(ctx, c, keyCeremonyActionPropose, nil, func(c *keyCeremony) {
+		identity := rpcauth.GetIdentity(ctx)
+		c.Proposer = identity.ID
+		c.Approvals = []string{identity.ID}
+		c.ApprovalIdentities = []*rpcauth.Identity{identity}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &keyCeremonyResult{keyCeremony: c}, nil
+}
+
+func (s *rpcServer) adminListKeyCeremonies(ctx context.Context) (interface{}, error) {
+	kc := s.keyCeremonies
+	kc.mux.Lock()
+	defer kc.mux.Unlock()
+	results := make([]*keyCeremonyResult, 0, len(kc.ceremonies))
+	for id := range kc.ceremonies {
+		c, _ := kc.get(ctx, id.String())
+		results = append(results, &keyCeremonyResult{keyCeremony: c})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Created.Time().Before(*results[j].Created.Time())
+	})
+	return results, nil
+}
+
+func (s *rpcServer) adminGetKeyCeremony(ctx context.Context, vars map[string]string, _ []byte) (interface{}, error) {
+	kc := s.keyCeremonies
+	kc.mux.Lock()
+	defer kc.mux.Unlock()
+	c, err := kc.get(ctx, vars["id"])
+	if err != nil {
+		return nil, err
+	}
+	return &keyCeremonyResult{keyCeremony: c}, nil
+}
+
+// adminApproveKeyCeremony records the approval of an identity that has not already approved the ceremony
+func (s *rpcServer) adminApproveKeyCeremony(ctx context.Context, vars map[string]string, _ []byte) (interface{}, error) {
+	kc := s.keyCeremonies
+	kc.mux.Lock()
+	defer kc.mux.Unlock()
+	c, err := kc.get(ctx, vars["id"])
+	if err != nil {
+		return nil, err
+	}
+	if c.Status != keyCeremonyPending {
+		return nil, i18n.NewError(ctx, signermsgs.MsgKeyCeremonyWrongStatus, c.ID, c.Status, keyCeremonyActionApprove)
+	}
+	identity := rpcauth.GetIdentity(ctx)
+	for _, approver := range c.Approvals {
+		if approver == identity.ID {
+			return nil, i18n.NewError(ctx, signermsgs.MsgKeyCeremonyDuplicateApproval, identity.ID, c.ID)
+		}
+	}
+	if err := s.authorizeKeyCeremonyApprovals(ctx, c); err != nil {
+		return nil, err
+	}
+	c, err = kc.update(ctx, c, keyCeremonyActionApprove, nil, func(c *keyCeremony) {
+		c.Approvals = append(c.Approvals, identity.ID)
+		c.ApprovalIdentities = append(c.ApprovalIdentities, identity)
+		if len(c.Approvals) >= keyCeremonyApprovals {
+			c.Status = keyCeremonyApproved
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &keyCeremonyResult{keyCeremony: c}, nil
+}
+
+// adminRejectKeyCeremony stops a ceremony that has not been executed, and can be called by any identity
+func (s *rpcServer) adminRejectKeyCeremony(ctx context.Context, vars map[string]string, _ []byte) (interface{}, error) {
+	kc := s.keyCeremonies
+	kc.mux.Lock()
+	defer kc.mux.Unlock()
+	c, err := kc.get(ctx, vars["id"])
+	if err != nil {
+		return nil, err
+	}
+	if c.Status != keyCeremonyPending && c.Status != keyCeremonyApproved {
+		return nil, i18n.NewError(ctx, signermsgs.MsgKeyCeremonyWrongStatus, c.ID, c.Status, keyCeremonyActionReject)
+	}
+	c, err = kc.update(ctx, c, keyCeremonyActionReject, nil, func(c *keyCeremony) {
+		c.Status = keyCeremonyRejected
+		c.Keystore = nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &keyCeremonyResult{keyCeremony: c}, nil
+}
+
+// adminExecuteKeyCeremony performs an approved import or export for the proposer. A failure, such as a wrong
+// password, is recorded but leaves the ceremony approved, so it can be executed again.
+func (s *rpcServer) adminExecuteKeyCeremony(ctx context.Context, vars map[string]string, body []byte) (interface{}, error) {
+	kc := s.keyCeremonies
+	kc.mux.Lock()
+	defer kc.mux.Unlock()
+	c, err := kc.get(ctx, vars["id"])
+	if err != nil {
+		return nil, err
+	}
+	if c.Status != keyCeremonyApproved {
+		return nil, i18n.NewError(ctx, signermsgs.MsgKeyCeremonyWrongStatus, c.ID, c.Status, keyCeremonyActionExecute)
+	}
+	if identity := rpcauth.GetIdentity(ctx).ID; identity != c.Proposer {
+		return nil, i18n.NewError(ctx, signermsgs.MsgKeyCeremonyNotProposer, c.ID, c.Proposer)
+	}
+	if err := s.authorizeKeyCeremonyApprovals(ctx, c); err != nil {
+		return nil, err
+	}
+	var execution keyCeremonyExecution
+	if err := json.Unmarshal(body, &execution); err != nil {
+		return nil, i18n.NewError(ctx, signermsgs.MsgKeyCeremonyInvalid, err)
+	}
+	if execution.Password == "" {
+		return nil, i18n.NewError(ctx, signermsgs.MsgEmptyPassword)
+	}
+
+	var exported json.RawMessage
+	if c.Operation == keyCeremonyExport {
+		exported, err = s.exportKey(ctx, c, execution.Password)
+	} else {
+		err = s.importKey(ctx, c, &execution)
+	}
+	if err != nil {
+		if _, recordErr := kc.update(ctx, c, keyCeremonyActionExecuteFailed, err, func(c *keyCeremony) {}); recordErr != nil {
+			log.L(ctx).Errorf("Failed to record the failure of key ceremony %s: %s", c.ID, recordErr)
+		}
+		return nil, err
+	}
+	c, err = kc.update(ctx, c, keyCeremonyActionExecute, nil, func(c *keyCeremony) {
+		c.Status = keyCeremonyCompleted
+		c.Keystore = nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &keyCeremonyResult{keyCeremony: c, Keystore: exported}, nil
+}
+
+// keyCeremonyAuthorizer returns the role based access control that key ceremonies require, which a reload
+// of the configuration might have disabled since the server started
+func (s *rpcServer) keyCeremonyAuthorizer(ctx context.Context) (*rpcauth.Authorizer, error) {
+	authorizer := s.authorizer.Load()
+	if authorizer == nil {
+		return nil, i18n.NewError(ctx, signermsgs.MsgKeyCeremonyRequiresRBAC)
+	}
+	return authorizer, nil
+}
+
+// authorizeKeyCeremonyApprovals checks the proposer and every approver of a ceremony are still granted the
+// operation, as a policy might have been removed since they approved it. Ceremonies persisted before the
+// identities were recorded are authorized by the ID of each approver alone.
+func (s *rpcServer) authorizeKeyCeremonyApprovals(ctx context.Context, c *keyCeremony) error {
+	authorizer, err := s.keyCeremonyAuthorizer(ctx)
+	if err != nil {
+		return err
+	}
+	for i, approver := range c.Approvals {
+		identity := &rpcauth.Identity{ID: approver}
+		if i < len(c.ApprovalIdentities) && c.ApprovalIdentities[i] != nil && c.ApprovalIdentities[i].ID == approver {
+			identity = c.ApprovalIdentities[i]
+		}
+		if err := authorizer.AuthorizeMethod(ctx, identity, adminOpKeyCeremony); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exportKey returns the key as a Keystore V3 file encrypted with the password, rather than the password it is
+// stored with in the wallet
+func (s *rpcServer) exportKey(ctx context.Context, c *keyCeremony, password string) (json.RawMessage, error) {
+	w, ok := s.wallet.(keyExporter)
+	if !ok {
+		return nil, i18n.NewError(ctx, signermsgs.MsgKeyExportNotSupported)
+	}
+	kv3, err := w.GetWalletFile(ctx, *c.Address)
+	if err != nil {
+		return nil, err
+	}
+	defer kv3.Zeroize()
+	exported := keystorev3.NewWalletFileStandard(password, kv3.KeyPair())
+	defer exported.Zeroize()
+	return exported.JSON(), nil
+}
+
+// importKey writes the key from the keystore into the file wallet, and refreshes the wallet so it can sign with it
+func (s *rpcServer) importKey(ctx context.Context, c *keyCeremony, execution *keyCeremonyExecution) error {
+	kv3, err := keystorev3.ReadWalletFile(c.Keystore, []byte(execution.Password))
+	if err != nil {
+		return i18n.NewError(ctx, signermsgs.MsgKeystoreDecryptFailed, c.Address, err)
+	}
+	defer kv3.Zeroize()
+	if kv3.KeyPair().Address != *c.Address {
+		return i18n.NewError(ctx, signermsgs.MsgKeyCeremonyAddressMismatch, kv3.KeyPair().Address, c.Address)
+	}
+	keyPassword := execution.KeyPassword
+	if keyPassword == "" {
+		keyPassword = execution.Password
+	}
+	if _, err := fswallet.CreateKey(ctx, s.keyCeremonies.walletConf, &fswallet.CreateKeyOptions{
+		KeyPair:  kv3.KeyPair(),
+		Password: func() ([]byte, error) { return []byte(keyPassword), nil },
+	}); err != nil {
+		return err
+	}
+	return s.wallet.Refresh(ctx)
+}
@@ -0,0 +1,466 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/hyperledger/firefly-signer/internal/signerconfig"
+	"github.com/hyperledger/firefly-signer/mocks/ethsignermocks"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/fswallet"
+	"github.com/hyperledger/firefly-signer/pkg/keystorev3"
+	"github.com/hyperledger/firefly-signer/pkg/persistence"
+	"github.com/hyperledger/firefly-signer/pkg/rpcauth"
+	"github.com/hyperledger/firefly-signer/pkg/secp256k1"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const (
+	testAliceKey = "secretAlice"
+	testBobKey   = "secretBob"
+)
+
+func setTestKeyCeremonyConf(t *testing.T) (string, string) {
+	ceremonyDir, walletDir := t.TempDir(), t.TempDir()
+	viper.SetConfigType("yaml")
+	_ = viper.ReadConfig(strings.NewReader(fmt.Sprintf(`
+auth:
+  enabled: true
+  apiKeys:
+  - id: alice
+    key: %s
+  - id: bob
+    key: %s
+  rbac:
+    enabled: true
+    policies:
+    - identities:
+      - alice
+      - bob
+      methods:
+      - admin_keyCeremony
+admin:
+  enabled: true
+  address: 127.0.0.1
+  port: 0
+  keyCeremony:
+    enabled: true
+    path: %q
+fileWallet:
+  path: %q
+  filenames:
+    primaryExt: ".key.json"
+    passwordExt: ".pwd"
+`, testAliceKey, testBobKey, ceremonyDir, walletDir)))
+	return ceremonyDir, walletDir
+}
+
+func newTestKeyCeremonyServer(t *testing.T) (*rpcServer, string, func()) {
+	var ceremonyDir string
+	_, s, done := newTestServer(t, func() { ceremonyDir, _ = setTestKeyCeremonyConf(t) })
+	assert.NotNil(t, s.keyCeremonies)
+	w, err := fswallet.NewFilesystemWallet(context.Background(), fswallet.ReadConfig(signerconfig.FileWalletConfig))
+	assert.NoError(t, err)
+	s.wallet = w
+	return s, ceremonyDir, done
+}
+
+func createTestWalletKey(t *testing.T, s *rpcServer) *ethtypes.Address0xHex {
+	addr, err := fswallet.CreateKey(context.Background(), fswallet.ReadConfig(signerconfig.FileWalletConfig), &fswallet.CreateKeyOptions{
+		LightScrypt: true,
+		Password:    func() ([]byte, error) { return []byte("walletpw"), nil },
+	})
+	assert.NoError(t, err)
+	err = s.wallet.Refresh(context.Background())
+	assert.NoError(t, err)
+	return addr
+}
+
+func keyCeremonyRequest(t *testing.T, s *rpcServer, apiKey, path string, body interface{}, expectedStatus int) *keyCeremony {
+	var result keyCeremony
+	var errRes adminError
+	method := http.MethodPost
+	if body == nil {
+		method = http.MethodGet
+	}
+	server := adminTestInputRequest
+	status := server(t, s, method, path, apiKey, body, &result)
+	if status != expectedStatus {
+		server(t, s, method, path, apiKey, body, &errRes)
+		assert.Equal(t, expectedStatus, status, errRes.Error)
+	}
+	return &result
+}
+
+func keyCeremonyError(t *testing.T, s *rpcServer, apiKey, path string, body interface{}, expectedStatus int, errRegexp string) {
+	var errRes adminError
+	status := adminTestInputRequest(t, s, http.MethodPost, path, apiKey, body, &errRes)
+	assert.Equal(t, expectedStatus, status)
+	assert.Regexp(t, errRegexp, errRes.Error)
+}
+
+func TestKeyCeremonyExport(t *testing.T) {
+	s, _, done := newTestKeyCeremonyServer(t)
+	defer done()
+	addr := createTestWalletKey(t, s)
+
+	c := keyCeremonyRequest(t, s, testAliceKey, "/keys/ceremonies", map[string]interface{}{
+		"operation": "export",
+		"address":   addr,
+	}, http.StatusOK)
+	assert.Equal(t, keyCeremonyPending, c.Status)
+	assert.Equal(t, "alice", c.Proposer)
+	assert.Equal(t, []string{"alice"}, c.Approvals)
+	path := "/keys/ceremonies/" + c.ID.String()
+
+	// The proposer cannot approve again, or execute before a second identity approves
+	keyCeremonyError(t, s, testAliceKey, path+"/approve", struct{}{}, http.StatusForbidden, "FF22295.*alice")
+	keyCeremonyError(t, s, testAliceKey, path+"/execute", map[string]string{"password": "exportpw"}, http.StatusConflict, "FF22294.*pending")
+
+	c = keyCeremonyRequest(t, s, testBobKey, path+"/approve", struct{}{}, http.StatusOK)
+	assert.Equal(t, keyCeremonyApproved, c.Status)
+	assert.Equal(t, []string{"alice", "bob"}, c.Approvals)
+
+	// Only the proposer executes, with a password for the exported key
+	keyCeremonyError(t, s, testBobKey, path+"/execute", map[string]string{"password": "exportpw"}, http.StatusForbidden, "FF22296.*alice")
+	keyCeremonyError(t, s, testAliceKey, path+"/execute", map[string]string{}, http.StatusInternalServerError, "FF22195")
+	keyCeremonyError(t, s, testAliceKey, path+"/execute", "!!!", http.StatusBadRequest, "FF22292")
+
+	c = keyCeremonyRequest(t, s, testAliceKey, path+"/execute", map[string]string{"password": "exportpw"}, http.StatusOK)
+	assert.Equal(t, keyCeremonyCompleted, c.Status)
+	kv3, err := keystorev3.ReadWalletFile(c.Keystore, []byte("exportpw"))
+	assert.NoError(t, err)
+	assert.Equal(t, *addr, kv3.KeyPair().Address)
+
+	keyCeremonyError(t, s, testAliceKey, path+"/execute", map[string]string{"password": "exportpw"}, http.StatusConflict, "FF22294.*completed")
+	keyCeremonyError(t, s, testBobKey, path+"/reject", struct{}{}, http.StatusConflict, "FF22294.*completed")
+
+	// The keystore is only returned once, and the history records every action
+	c = keyCeremonyRequest(t, s, testBobKey, path, nil, http.StatusOK)
+	assert.Empty(t, c.Keystore)
+	actions := []string{}
+	for _, e := range c.History {
+		actions = append(actions, e.Identity+":"+e.Action)
+	}
+	assert.Equal(t, []string{"alice:proposed", "bob:approved", "alice:executed"}, actions)
+}
+
+func TestKeyCeremonyApproversAuthorized(t *testing.T) {
+	s, _, done := newTestKeyCeremonyServer(t)
+	defer done()
+	addr := createTestWalletKey(t, s)
+
+	c := keyCeremonyRequest(t, s, testAliceKey, "/keys/ceremonies", map[string]interface{}{
+		"operation": "export",
+		"address":   addr,
+	}, http.StatusOK)
+	path := "/keys/ceremonies/" + c.ID.String()
+	c = keyCeremonyRequest(t, s, testBobKey, path+"/approve", struct{}{}, http.StatusOK)
+	assert.Empty(t, c.ApprovalIdentities)
+	assert.Equal(t, "bob", s.keyCeremonies.ceremonies[*c.ID].ApprovalIdentities[1].ID)
+
+	// Bob loses the operation after approving, so the proposer cannot execute the ceremony
+	authorizer, err := rpcauth.NewAuthorizer(context.Background(), []*rpcauth.Policy{
+		{Identities: []string{"alice"}, Methods: []string{adminOpKeyCeremony}},
+	})
+	assert.NoError(t, err)
+	s.authorizer.Store(authorizer)
+	keyCeremonyError(t, s, testAliceKey, path+"/execute", map[string]string{"password": "exportpw"}, http.StatusForbidden, "FF22110.*bob")
+
+	// Ceremonies persisted without the identities of their approvals are authorized by ID
+	s.keyCeremonies.ceremonies[*c.ID].ApprovalIdentities = nil
+	keyCeremonyError(t, s, testAliceKey, path+"/execute", map[string]string{"password": "exportpw"}, http.StatusForbidden, "FF22110.*bob")
+
+	// Nor can the ceremony be approved when the proposer has lost the operation
+	c = keyCeremonyRequest(t, s, testAliceKey, "/keys/ceremonies", map[string]interface{}{
+		"operation": "export",
+		"address":   addr,
+	}, http.StatusOK)
+	authorizer, err = rpcauth.NewAuthorizer(context.Background(), []*rpcauth.Policy{
+		{Identities: []string{"bob"}, Methods: []string{adminOpKeyCeremony}},
+	})
+	assert.NoError(t, err)
+	s.authorizer.Store(authorizer)
+	keyCeremonyError(t, s, testBobKey, "/keys/ceremonies/"+c.ID.String()+"/approve", struct{}{}, http.StatusForbidden, "FF22110.*alice")
+
+	// Ceremonies stop when role based access control is disabled by a reload
+	s.authorizer.Store(nil)
+	keyCeremonyError(t, s, testAliceKey, "/keys/ceremonies", map[string]interface{}{"operation": "export", "address": addr}, http.StatusInternalServerError, "FF22324")
+	keyCeremonyError(t, s, testBobKey, "/keys/ceremonies/"+c.ID.String()+"/approve", struct{}{}, http.StatusInternalServerError, "FF22324")
+	keyCeremonyError(t, s, testAliceKey, path+"/execute", map[string]string{"password": "exportpw"}, http.StatusInternalServerError, "FF22324")
+}
+
+func TestKeyCeremonyImport(t *testing.T) {
+	s, ceremonyDir, done := newTestKeyCeremonyServer(t)
+	defer done()
+
+	keypair, err := secp256k1.GenerateSecp256k1KeyPair()
+	assert.NoError(t, err)
+	keystore := json.RawMessage(keystorev3.NewWalletFileLight("importpw", keypair).JSON())
+
+	c := keyCeremonyRequest(t, s, testAliceKey, "/keys/ceremonies", map[string]interface{}{
+		"operation": "import",
+		"keystore":  keystore,
+	}, http.StatusOK)
+	assert.Equal(t, keypair.Address, *c.Address)
+	assert.Empty(t, c.Keystore)
+	path := "/keys/ceremonies/" + c.ID.String()
+	keyCeremonyRequest(t, s, testBobKey, path+"/approve", struct{}{}, http.StatusOK)
+
+	// A failure leaves the ceremony approved, so it can be executed again
+	keyCeremonyError(t, s, testAliceKey, path+"/execute", map[string]string{"password": "wrong"}, http.StatusInternalServerError, "FF22210")
+	c = keyCeremonyRequest(t, s, testAliceKey, path, nil, http.StatusOK)
+	assert.Equal(t, keyCeremonyApproved, c.Status)
+	assert.Equal(t, keyCeremonyActionExecuteFailed, c.History[2].Action)
+	assert.Regexp(t, "FF22210", c.History[2].Error)
+
+	c = keyCeremonyRequest(t, s, testAliceKey, path+"/execute", map[string]string{"password": "importpw", "keyPassword": "walletpw"}, http.StatusOK)
+	assert.Equal(t, keyCeremonyCompleted, c.Status)
+	kv3, err := s.wallet.(fswallet.Wallet).GetWalletFile(context.Background(), keypair.Address)
+	assert.NoError(t, err)
+	assert.Equal(t, keypair.PrivateKeyBytes(), kv3.PrivateKey())
+
+	// The ceremonies are persisted, without the keystore once it is imported
+	kc, err := newKeyCeremonies(context.Background(), nil)
+	assert.NoError(t, err)
+	assert.Len(t, kc.ceremonies, 1)
+	assert.Empty(t, kc.ceremonies[*c.ID].Keystore)
+	b, err := os.ReadFile(filepath.Join(ceremonyDir, keyCeremonyCollection, c.ID.String()))
+	assert.NoError(t, err)
+	assert.NotContains(t, string(b), "importpw")
+
+	// The key is already in the wallet, so cannot be imported again
+	c = keyCeremonyRequest(t, s, testBobKey, "/keys/ceremonies", map[string]interface{}{
+		"operation": "import",
+		"keystore":  keystore,
+	}, http.StatusOK)
+	path = "/keys/ceremonies/" + c.ID.String()
+	keyCeremonyRequest(t, s, testAliceKey, path+"/approve", struct{}{}, http.StatusOK)
+	keyCeremonyError(t, s, testBobKey, path+"/execute", map[string]string{"password": "importpw"}, http.StatusInternalServerError, "FF22191")
+}
+
+func TestKeyCeremonyImportAddressMismatch(t *testing.T) {
+	s, _, done := newTestKeyCeremonyServer(t)
+	defer done()
+
+	keypair, err := secp256k1.GenerateSecp256k1KeyPair()
+	assert.NoError(t, err)
+	var keystore map[string]interface{}
+	err = json.Unmarshal(keystorev3.NewWalletFileLight("importpw", keypair).JSON(), &keystore)
+	assert.NoError(t, err)
+	keystore["address"] = "0x497eedc4299dea2f2a364be10025d0ad0f702de3"
+
+	c := keyCeremonyRequest(t, s, testAliceKey, "/keys/ceremonies", map[string]interface{}{
+		"operation": "import",
+		"keystore":  keystore,
+	}, http.StatusOK)
+	path := "/keys/ceremonies/" + c.ID.String()
+	keyCeremonyRequest(t, s, testBobKey, path+"/approve", struct{}{}, http.StatusOK)
+	keyCeremonyError(t, s, testAliceKey, path+"/execute", map[string]string{"password": "importpw"}, http.StatusBadRequest, "FF22297")
+}
+
+func TestKeyCeremonyImportRefreshFail(t *testing.T) {
+	s, _, done := newTestKeyCeremonyServer(t)
+	defer done()
+	w := &ethsignermocks.Wallet{}
+	w.On("Refresh", mock.Anything).Return(fmt.Errorf("pop"))
+	s.wallet = w
+
+	keypair, err := secp256k1.GenerateSecp256k1KeyPair()
+	assert.NoError(t, err)
+	c := keyCeremonyRequest(t, s, testAliceKey, "/keys/ceremonies", map[string]interface{}{
+		"operation": "import",
+		"keystore":  json.RawMessage(keystorev3.NewWalletFileLight("importpw", keypair).JSON()),
+	}, http.StatusOK)
+	path := "/keys/ceremonies/" + c.ID.String()
+	keyCeremonyRequest(t, s, testBobKey, path+"/approve", struct{}{}, http.StatusOK)
+	keyCeremonyError(t, s, testAliceKey, path+"/execute", map[string]string{"password": "importpw"}, http.StatusInternalServerError, "pop")
+}
+
+func TestKeyCeremonyExportFail(t *testing.T) {
+	s, _, done := newTestKeyCeremonyServer(t)
+	defer done()
+
+	c := keyCeremonyRequest(t, s, testAliceKey, "/keys/ceremonies", map[string]interface{}{
+		"operation": "export",
+		"address":   "0x497eedc4299dea2f2a364be10025d0ad0f702de3",
+	}, http.StatusOK)
+	path := "/keys/ceremonies/" + c.ID.String()
+	keyCeremonyRequest(t, s, testBobKey, path+"/approve", struct{}{}, http.StatusOK)
+	keyCeremonyError(t, s, testAliceKey, path+"/execute", map[string]string{"password": "exportpw"}, http.StatusNotFound, "FF22014")
+
+	s.wallet = &ethsignermocks.Wallet{}
+	keyCeremonyError(t, s, testAliceKey, path+"/execute", map[string]string{"password": "exportpw"}, http.StatusInternalServerError, "FF22298")
+}
+
+func TestKeyCeremonyRejectListExpire(t *testing.T) {
+	s, _, done := newTestKeyCeremonyServer(t)
+	defer done()
+
+	rejected := keyCeremonyRequest(t, s, testAliceKey, "/keys/ceremonies", map[string]interface{}{
+		"operation": "export",
+		"address":   "0x497eedc4299dea2f2a364be10025d0ad0f702de3",
+	}, http.StatusOK)
+	c := keyCeremonyRequest(t, s, testBobKey, "/keys/ceremonies/"+rejected.ID.String()+"/reject", struct{}{}, http.StatusOK)
+	assert.Equal(t, keyCeremonyRejected, c.Status)
+	keyCeremonyError(t, s, testBobKey, "/keys/ceremonies/"+rejected.ID.String()+"/approve", struct{}{}, http.StatusConflict, "FF22294.*rejected")
+
+	s.keyCeremonies.expiry = -1 * time.Second
+	expired := keyCeremonyRequest(t, s, testAliceKey, "/keys/ceremonies", map[string]interface{}{
+		"operation": "export",
+		"address":   "0x497eedc4299dea2f2a364be10025d0ad0f702de3",
+	}, http.StatusOK)
+	keyCeremonyError(t, s, testBobKey, "/keys/ceremonies/"+expired.ID.String()+"/approve", struct{}{}, http.StatusConflict, "FF22294.*expired")
+
+	var list []*keyCeremony
+	status := adminTestRequest(t, s, http.MethodGet, "/keys/ceremonies", testAliceKey, &list)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Len(t, list, 2)
+	assert.Equal(t, keyCeremonyRejected, list[0].Status)
+	assert.Equal(t, keyCeremonyExpired, list[1].Status)
+}
+
+func TestKeyCeremonyBadRequests(t *testing.T) {
+	s, _, done := newTestKeyCeremonyServer(t)
+	defer done()
+
+	keyCeremonyError(t, s, testAliceKey, "/keys/ceremonies", "!!!", http.StatusBadRequest, "FF22292")
+	keyCeremonyError(t, s, testAliceKey, "/keys/ceremonies", map[string]string{"operation": "delete"}, http.StatusBadRequest, "FF22292.*operation")
+	keyCeremonyError(t, s, testAliceKey, "/keys/ceremonies", map[string]string{"operation": "export"}, http.StatusBadRequest, "FF22292.*address")
+	keyCeremonyError(t, s, testAliceKey, "/keys/ceremonies", map[string]string{"operation": "import"}, http.StatusBadRequest, "FF22292.*keystore")
+
+	for _, path := range []string{"/keys/ceremonies/not-a-uuid", "/keys/ceremonies/9e2bb43b-6a8e-4b7a-8a3f-3f1a9b1d7c11"} {
+		var errRes adminError
+		status := adminTestRequest(t, s, http.MethodGet, path, testAliceKey, &errRes)
+		assert.Equal(t, http.StatusNotFound, status)
+		assert.Regexp(t, "FF22293", errRes.Error)
+		keyCeremonyError(t, s, testAliceKey, path+"/approve", struct{}{}, http.StatusNotFound, "FF22293")
+		keyCeremonyError(t, s, testAliceKey, path+"/reject", struct{}{}, http.StatusNotFound, "FF22293")
+		keyCeremonyError(t, s, testAliceKey, path+"/execute", struct{}{}, http.StatusNotFound, "FF22293")
+	}
+}
+
+func TestKeyCeremonyStoreFail(t *testing.T) {
+	s, ceremonyDir, done := newTestKeyCeremonyServer(t)
+	defer done()
+
+	c := keyCeremonyRequest(t, s, testAliceKey, "/keys/ceremonies", map[string]interface{}{
+		"operation": "export",
+		"address":   "0x497eedc4299dea2f2a364be10025d0ad0f702de3",
+	}, http.StatusOK)
+	path := "/keys/ceremonies/" + c.ID.String()
+	keyCeremonyRequest(t, s, testBobKey, path+"/approve", struct{}{}, http.StatusOK)
+	rejected := keyCeremonyRequest(t, s, testAliceKey, "/keys/ceremonies", map[string]interface{}{
+		"operation": "export",
+		"address":   "0x497eedc4299dea2f2a364be10025d0ad0f702de3",
+	}, http.StatusOK)
+
+	// Persistence that fails every write, as its directory has been replaced with a file
+	brokenDir := filepath.Join(ceremonyDir, "broken")
+	broken, err := persistence.NewFileStore(context.Background(), brokenDir)
+	assert.NoError(t, err)
+	err = os.Remove(brokenDir)
+	assert.NoError(t, err)
+	err = os.WriteFile(brokenDir, []byte{}, 0600)
+	assert.NoError(t, err)
+	store := s.keyCeremonies.store
+	s.keyCeremonies.store = broken
+	body := map[string]interface{}{"operation": "export", "address": "0x497eedc4299dea2f2a364be10025d0ad0f702de3"}
+	keyCeremonyError(t, s, testAliceKey, "/keys/ceremonies", body, http.StatusInternalServerError, "FF22290")
+	keyCeremonyError(t, s, testBobKey, "/keys/ceremonies/"+rejected.ID.String()+"/approve", struct{}{}, http.StatusInternalServerError, "FF22290")
+	keyCeremonyError(t, s, testBobKey, "/keys/ceremonies/"+rejected.ID.String()+"/reject", struct{}{}, http.StatusInternalServerError, "FF22290")
+	// The failure is returned even when it cannot be recorded
+	keyCeremonyError(t, s, testAliceKey, path+"/execute", map[string]string{"password": "exportpw"}, http.StatusNotFound, "FF22014")
+
+	addr := createTestWalletKey(t, s)
+	s.keyCeremonies.store = store
+	c = keyCeremonyRequest(t, s, testAliceKey, "/keys/ceremonies", map[string]interface{}{"operation": "export", "address": addr}, http.StatusOK)
+	path = "/keys/ceremonies/" + c.ID.String()
+	keyCeremonyRequest(t, s, testBobKey, path+"/approve", struct{}{}, http.StatusOK)
+	s.keyCeremonies.store = broken
+	keyCeremonyError(t, s, testAliceKey, path+"/execute", map[string]string{"password": "exportpw"}, http.StatusInternalServerError, "FF22290")
+}
+
+func TestKeyCeremonyConfig(t *testing.T) {
+	ctx := context.Background()
+
+	signerconfig.Reset()
+	ceremonyDir, _ := setTestKeyCeremonyConf(t)
+	collectionDir := filepath.Join(ceremonyDir, keyCeremonyCollection)
+	err := os.MkdirAll(filepath.Join(collectionDir, "ignored"), 0700)
+	assert.NoError(t, err)
+	err = os.WriteFile(filepath.Join(collectionDir, "partial.tmp"), []byte("ignored"), 0600)
+	assert.NoError(t, err)
+	kc, err := newKeyCeremonies(ctx, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, kc.ceremonies)
+
+	err = os.WriteFile(filepath.Join(collectionDir, "bad"), []byte("!!!"), 0600)
+	assert.NoError(t, err)
+	_, err = newKeyCeremonies(ctx, nil)
+	assert.Regexp(t, "FF22291", err)
+
+	err = os.WriteFile(filepath.Join(collectionDir, "bad"), []byte("{}"), 0600)
+	assert.NoError(t, err)
+	_, err = newKeyCeremonies(ctx, nil)
+	assert.Regexp(t, "FF22291.*FF22292", err)
+
+	err = os.WriteFile(filepath.Join(ceremonyDir, "file"), []byte{}, 0600)
+	assert.NoError(t, err)
+	signerconfig.AdminConfig.Set(signerconfig.AdminConfKeyCeremonyPath, filepath.Join(ceremonyDir, "file"))
+	_, err = newKeyCeremonies(ctx, nil)
+	assert.Regexp(t, "FF22291.*FF22299", err)
+
+	// The shared persistence is used when there is no path, and its collection cannot be a file
+	signerconfig.AdminConfig.Set(signerconfig.AdminConfKeyCeremonyPath, "")
+	shared, err := persistence.NewFileStore(ctx, t.TempDir())
+	assert.NoError(t, err)
+	kc, err = newKeyCeremonies(ctx, shared)
+	assert.NoError(t, err)
+	assert.Equal(t, shared, kc.store)
+	shared, err = persistence.NewFileStore(ctx, ceremonyDir)
+	assert.NoError(t, err)
+	err = os.RemoveAll(collectionDir)
+	assert.NoError(t, err)
+	err = os.WriteFile(collectionDir, []byte{}, 0600)
+	assert.NoError(t, err)
+	_, err = newKeyCeremonies(ctx, shared)
+	assert.Regexp(t, "FF22291.*FF22303", err)
+
+	_, err = NewServer(ctx, &ethsignermocks.Wallet{})
+	assert.Regexp(t, "FF22288", err)
+
+	config.Set("auth.rbac.enabled", false)
+	_, err = NewServer(ctx, &ethsignermocks.Wallet{})
+	assert.Regexp(t, "FF22324", err)
+
+	config.Set(signerconfig.FileWalletEnabled, false)
+	_, err = newKeyCeremonies(ctx, nil)
+	assert.Regexp(t, "FF22289", err)
+}
@@ -0,0 +1,106 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-common/pkg/log"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+)
+
+// readRequestBody reads an HTTP request body, up to the maximum request size. The tooLarge error
+// is returned when the body exceeds the maximum, rather than the error reading it.
+func (s *rpcServer) readRequestBody(ctx context.Context, w http.ResponseWriter, r *http.Request) (b []byte, tooLarge error, err error) {
+	body := r.Body
+	if s.maxRequestSize > 0 {
+		body = http.MaxBytesReader(w, r.Body, s.maxRequestSize)
+	}
+	b, err = io.ReadAll(body)
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return nil, i18n.NewError(ctx, signermsgs.MsgRequestTooLarge, s.maxRequestSize), nil
+	}
+	return b, nil, err
+}
+
+// checkJSONDepth rejects a request nested more deeply than the maximum, before it is parsed.
+// Parsing deeply nested JSON is expensive, and nothing the proxy handles needs more than a few levels.
+func (s *rpcServer) checkJSONDepth(ctx context.Context, b []byte) error {
+	if s.maxJSONDepth > 0 && jsonDepthExceeds(b, s.maxJSONDepth) {
+		return i18n.NewError(ctx, signermsgs.MsgJSONTooDeep, s.maxJSONDepth)
+	}
+	return nil
+}
+
+// checkBatchSize rejects a batch with more requests than the maximum, before any of them are processed
+func (s *rpcServer) checkBatchSize(ctx context.Context, size int) error {
+	if s.maxBatchSize > 0 && size > s.maxBatchSize {
+		return i18n.NewError(ctx, signermsgs.MsgBatchTooLarge, size, s.maxBatchSize)
+	}
+	return nil
+}
+
+func (s *rpcServer) rpcLimitErrorResponse(ctx context.Context, err error) *rpcbackend.RPCResponse {
+	log.L(ctx).Errorf("Request rejected: %s", err)
+	return rpcbackend.RPCErrorResponse(
+		err,
+		fftypes.JSONAnyPtr("1"), // we do not parse the request ID
+		rpcbackend.RPCCodeInvalidRequest,
+	)
+}
+
+func (s *rpcServer) replyRPCLimitError(ctx context.Context, w http.ResponseWriter, err error, status int) {
+	s.replyRPC(ctx, w, s.rpcLimitErrorResponse(ctx, err), status)
+}
+
+// jsonDepthExceeds scans JSON for objects and arrays nested more deeply than the maximum, ignoring any
+// brackets within strings. The JSON is not otherwise validated, as it is parsed afterwards.
+func jsonDepthExceeds(b []byte, maxDepth int) bool {
+	depth := 0
+	inString := false
+	escaped := false
+	for _, c := range b {
+		switch {
+		case escaped:
+			escaped = false
+		case inString:
+			switch c {
+			case '\\':
+				escaped = true
+			case '"':
+				inString = false
+			}
+		case c == '"':
+			inString = true
+		case c == '{' || c == '[':
+			depth++
+			if depth > maxDepth {
+				return true
+			}
+		case c == '}' || c == ']':
+			depth--
+		}
+	}
+	return false
+}
@@ -0,0 +1,136 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-signer/internal/signerconfig"
+	"github.com/hyperledger/firefly-signer/mocks/rpcbackendmocks"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func setTestLimitsConf() {
+	signerconfig.ServerConfig.Set(signerconfig.ServerConfMaxRequestSize, "1Kb")
+	signerconfig.ServerConfig.Set(signerconfig.ServerConfMaxBatchSize, 2)
+	signerconfig.ServerConfig.Set(signerconfig.ServerConfMaxJSONDepth, 4)
+}
+
+func postTestLimitsRPC(t *testing.T, url, body string) (int, *rpcbackend.RPCResponse) {
+	res, err := http.Post(url, "application/json", bytes.NewReader([]byte(body)))
+	assert.NoError(t, err)
+	defer res.Body.Close()
+	var rpcRes rpcbackend.RPCResponse
+	err = json.NewDecoder(res.Body).Decode(&rpcRes)
+	assert.NoError(t, err)
+	return res.StatusCode, &rpcRes
+}
+
+func TestRequestTooLarge(t *testing.T) {
+	url, s, done := newTestServer(t, setTestLimitsConf)
+	defer done()
+	startTestServerNoBackend(t, s)
+
+	body := `{"jsonrpc":"2.0","id":1,"method":"eth_call","params":["` + strings.Repeat("00", 1024) + `"]}`
+	status, rpcRes := postTestLimitsRPC(t, url, body)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, status)
+	assert.Equal(t, int64(rpcbackend.RPCCodeInvalidRequest), rpcRes.Error.Code)
+	assert.Regexp(t, "FF22163.*1,024", rpcRes.Error.Message)
+}
+
+func TestRequestSizeUnlimited(t *testing.T) {
+	url, s, done := newTestServer(t, setTestLimitsConf)
+	defer done()
+	startTestServerNoBackend(t, s)
+	s.maxRequestSize = 0
+
+	bm := s.backend.(*rpcbackendmocks.Backend)
+	bm.On("SyncRequest", mock.Anything, mock.Anything).Return(&rpcbackend.RPCResponse{Result: fftypes.JSONAnyPtr(`"0x"`)}, nil)
+
+	body := `{"jsonrpc":"2.0","id":1,"method":"eth_call","params":["` + strings.Repeat("00", 1024) + `"]}`
+	status, rpcRes := postTestLimitsRPC(t, url, body)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, `"0x"`, rpcRes.Result.String())
+}
+
+func TestRequestJSONTooDeep(t *testing.T) {
+	url, s, done := newTestServer(t, setTestLimitsConf)
+	defer done()
+	startTestServerNoBackend(t, s)
+
+	// The request object and params array are two levels, leaving two for the params
+	status, rpcRes := postTestLimitsRPC(t, url, `{"jsonrpc":"2.0","id":1,"method":"eth_call","params":[[[{}]]]}`)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, int64(rpcbackend.RPCCodeInvalidRequest), rpcRes.Error.Code)
+	assert.Regexp(t, "FF22165.*4", rpcRes.Error.Message)
+
+	bm := s.backend.(*rpcbackendmocks.Backend)
+	bm.On("SyncRequest", mock.Anything, mock.Anything).Return(&rpcbackend.RPCResponse{Result: fftypes.JSONAnyPtr(`"0x"`)}, nil)
+	status, _ = postTestLimitsRPC(t, url, `{"jsonrpc":"2.0","id":1,"method":"eth_call","params":[[{"data":"[[[[{{{{"}]]}`)
+	assert.Equal(t, http.StatusOK, status)
+}
+
+func TestRequestBatchTooLarge(t *testing.T) {
+	url, s, done := newTestServer(t, setTestLimitsConf)
+	defer done()
+	startTestServerNoBackend(t, s)
+
+	status, rpcRes := postTestLimitsRPC(t, url, `[
+		{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber"},
+		{"jsonrpc":"2.0","id":2,"method":"eth_blockNumber"},
+		{"jsonrpc":"2.0","id":3,"method":"eth_blockNumber"}
+	]`)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, int64(rpcbackend.RPCCodeInvalidRequest), rpcRes.Error.Code)
+	assert.Regexp(t, "FF22164.*3.*2", rpcRes.Error.Message)
+	s.backend.(*rpcbackendmocks.Backend).AssertNotCalled(t, "SyncRequest", mock.Anything, mock.Anything)
+}
+
+func TestWSRequestLimits(t *testing.T) {
+	url, s, done := newTestServer(t, setTestLimitsConf)
+	defer done()
+	startTestServerNoBackend(t, s)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+url[len("http"):], nil)
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	res := wsRoundTrip(t, conn, `{"jsonrpc":"2.0","id":1,"method":"eth_call","params":[[[{}]]]}`)
+	assert.Regexp(t, "FF22165", res["error"].(map[string]interface{})["message"])
+
+	res = wsRoundTrip(t, conn, `[
+		{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber"},
+		{"jsonrpc":"2.0","id":2,"method":"eth_blockNumber"},
+		{"jsonrpc":"2.0","id":3,"method":"eth_blockNumber"}
+	]`)
+	assert.Regexp(t, "FF22164", res["error"].(map[string]interface{})["message"])
+}
+
+func TestJSONDepthExceeds(t *testing.T) {
+	assert.False(t, jsonDepthExceeds([]byte(`{"a":[1,{"b":2}]}`), 3))
+	assert.True(t, jsonDepthExceeds([]byte(`{"a":[1,{"b":[]}]}`), 3))
+	assert.False(t, jsonDepthExceeds([]byte(`[{"a":"[[[\"[[[\\"},{"b":"{{{"}]`), 2))
+	assert.False(t, jsonDepthExceeds([]byte(`[][][][]`), 1))
+}
@@ -0,0 +1,48 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/hyperledger/firefly-signer/pkg/translations"
+)
+
+type acceptLanguageContextKey struct{}
+
+// withAcceptLanguage returns a context where errors are in the language that best matches the Accept-Language
+// header of the client, from the registered translations. The header is kept so a WebSocket connection can
+// carry it over from the upgrade request.
+func withAcceptLanguage(ctx context.Context, acceptLanguage string) context.Context {
+	if acceptLanguage == "" {
+		return ctx
+	}
+	return translations.WithAcceptLanguage(context.WithValue(ctx, acceptLanguageContextKey{}, acceptLanguage), acceptLanguage)
+}
+
+func getAcceptLanguage(ctx context.Context) string {
+	acceptLanguage, _ := ctx.Value(acceptLanguageContextKey{}).(string)
+	return acceptLanguage
+}
+
+// localized selects the language of each request from its Accept-Language header
+func localized(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handler(w, r.WithContext(withAcceptLanguage(r.Context(), r.Header.Get("Accept-Language"))))
+	}
+}
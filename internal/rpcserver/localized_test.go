@@ -0,0 +1,97 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/hyperledger/firefly-signer/pkg/translations"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/language"
+)
+
+func init() {
+	translations.MustRegister(&translations.Pack{
+		Language: language.Dutch,
+		Messages: map[string]string{
+			string(signermsgs.MsgInvalidRequest):       "Ongeldige aanvraaggegevens",
+			string(signermsgs.MsgAuthenticationFailed): "Authenticatie mislukt",
+		},
+	})
+}
+
+func TestLocalizedHTTPErrors(t *testing.T) {
+	url, s, done := newTestServer(t)
+	defer done()
+	startTestServerNoBackend(t, s)
+
+	post := func(acceptLanguage string) map[string]interface{} {
+		req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader([]byte(`[`)))
+		assert.NoError(t, err)
+		req.Header.Set("Accept-Language", acceptLanguage)
+		res, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		defer res.Body.Close()
+		var rpcRes map[string]interface{}
+		assert.NoError(t, json.NewDecoder(res.Body).Decode(&rpcRes))
+		return rpcRes["error"].(map[string]interface{})
+	}
+	assert.Equal(t, "FF22018: Ongeldige aanvraaggegevens", post("nl-BE,nl;q=0.9")["message"])
+	assert.Equal(t, "FF22018: Invalid request data", post("en")["message"])
+	assert.Equal(t, "FF22018: Invalid request data", post("")["message"])
+}
+
+func TestLocalizedWSErrors(t *testing.T) {
+	url, s, done := newTestServer(t)
+	defer done()
+	startTestServerNoBackend(t, s)
+
+	// The language of the upgrade request applies to every request on the connection
+	conn, _, err := websocket.DefaultDialer.Dial(strings.Replace(url, "http://", "ws://", 1), http.Header{
+		"Accept-Language": []string{"nl"},
+	})
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	res := wsRoundTrip(t, conn, `!!! not JSON`)
+	assert.Equal(t, "FF22018: Ongeldige aanvraaggegevens", res["error"].(map[string]interface{})["message"])
+}
+
+func TestLocalizedAdminErrors(t *testing.T) {
+	_, s, done := newTestServer(t, setTestAdminConf)
+	defer done()
+
+	server := httptest.NewServer(s.adminRouter())
+	defer server.Close()
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/status", nil)
+	assert.NoError(t, err)
+	req.Header.Set("Accept-Language", "nl")
+	req.Header.Set("X-API-Key", "wrong")
+	res, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer res.Body.Close()
+	var errRes adminError
+	assert.NoError(t, json.NewDecoder(res.Body).Decode(&errRes))
+	assert.Equal(t, "FF22102: Authenticatie mislukt", errRes.Error)
+}
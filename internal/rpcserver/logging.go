@@ -0,0 +1,86 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-common/pkg/log"
+	"github.com/hyperledger/firefly-signer/internal/signerconfig"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/hyperledger/firefly-signer/pkg/debuglog"
+	"github.com/sirupsen/logrus"
+)
+
+// adminLogging is the logging configuration in effect. When it is set, an empty level or a missing
+// list of modules leaves that part unchanged.
+type adminLogging struct {
+	Level   string            `json:"level,omitempty"`
+	Modules []debuglog.Module `json:"modules"`
+}
+
+// configLogModules returns the modules with debug logging enabled in the configuration
+func configLogModules(ctx context.Context) ([]debuglog.Module, error) {
+	return debuglog.ParseModules(ctx, config.GetStringSlice(signerconfig.LogModules))
+}
+
+func (s *rpcServer) adminGetLogging(_ context.Context) (interface{}, error) {
+	return &adminLogging{
+		Level:   logrus.GetLevel().String(),
+		Modules: debuglog.EnabledModules(),
+	}, nil
+}
+
+// adminSetLogging changes the log level and debug modules until the next restart or configuration reload,
+// so a production signer can be investigated without restarting it
+func (s *rpcServer) adminSetLogging(ctx context.Context, body []byte) (interface{}, error) {
+	var req adminLogging
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, i18n.NewError(ctx, signermsgs.MsgInvalidAdminRequest, err)
+	}
+	level := strings.ToLower(req.Level)
+	switch level {
+	case "", "error", "info", "debug", "trace":
+	default:
+		return nil, i18n.NewError(ctx, signermsgs.MsgInvalidLogLevel, req.Level)
+	}
+	var modules []debuglog.Module
+	if req.Modules != nil {
+		names := make([]string, len(req.Modules))
+		for i, module := range req.Modules {
+			names[i] = string(module)
+		}
+		var err error
+		if modules, err = debuglog.ParseModules(ctx, names); err != nil {
+			return nil, err
+		}
+	}
+
+	if level != "" {
+		log.SetLevel(level)
+	}
+	if req.Modules != nil {
+		debuglog.SetModules(modules)
+	}
+	result, _ := s.adminGetLogging(ctx)
+	log.L(ctx).Infof("Logging changed to level=%s modules=%v", logrus.GetLevel(), debuglog.EnabledModules())
+	return result, nil
+}
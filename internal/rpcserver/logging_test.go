@@ -0,0 +1,166 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-common/pkg/log"
+	"github.com/hyperledger/firefly-signer/internal/signerconfig"
+	"github.com/hyperledger/firefly-signer/mocks/ethsignermocks"
+	"github.com/hyperledger/firefly-signer/pkg/debuglog"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type errorReader struct{}
+
+func (errorReader) Read([]byte) (int, error) {
+	return 0, fmt.Errorf("pop")
+}
+
+func TestAdminLogging(t *testing.T) {
+	_, s, done := newTestServer(t, setTestAdminConf)
+	defer done()
+	defer log.SetLevel("info")
+	defer debuglog.SetModules(nil)
+
+	var result adminLogging
+	httpStatus := adminTestRequest(t, s, http.MethodGet, "/logging", testAdminAPIKey, &result)
+	assert.Equal(t, http.StatusOK, httpStatus)
+	assert.Equal(t, adminLogging{Level: "info", Modules: []debuglog.Module{}}, result)
+
+	httpStatus = adminTestInputRequest(t, s, http.MethodPut, "/logging", testAdminAPIKey, map[string]interface{}{
+		"level":   "DEBUG",
+		"modules": []string{"rpc"},
+	}, &result)
+	assert.Equal(t, http.StatusOK, httpStatus)
+	assert.Equal(t, adminLogging{Level: "debug", Modules: []debuglog.Module{debuglog.RPCWire}}, result)
+	assert.Equal(t, logrus.DebugLevel, logrus.GetLevel())
+	assert.True(t, debuglog.Enabled(debuglog.RPCWire))
+
+	// The level is unchanged when it is not set
+	httpStatus = adminTestInputRequest(t, s, http.MethodPut, "/logging", testAdminAPIKey, map[string]interface{}{
+		"modules": []string{},
+	}, &result)
+	assert.Equal(t, http.StatusOK, httpStatus)
+	assert.Equal(t, adminLogging{Level: "debug", Modules: []debuglog.Module{}}, result)
+
+	// The modules are unchanged when they are not set
+	debuglog.SetModules([]debuglog.Module{debuglog.ABIDecode})
+	httpStatus = adminTestInputRequest(t, s, http.MethodPut, "/logging", testAdminAPIKey, map[string]interface{}{
+		"level": "info",
+	}, &result)
+	assert.Equal(t, http.StatusOK, httpStatus)
+	assert.Equal(t, adminLogging{Level: "info", Modules: []debuglog.Module{debuglog.ABIDecode}}, result)
+}
+
+func TestAdminSetLoggingInvalid(t *testing.T) {
+	_, s, done := newTestServer(t, setTestAdminConf)
+	defer done()
+
+	var errRes adminError
+	httpStatus := adminTestInputRequest(t, s, http.MethodPut, "/logging", testAdminAPIKey, map[string]interface{}{
+		"level":   "loud",
+		"modules": []string{"rpc"},
+	}, &errRes)
+	assert.Equal(t, http.StatusBadRequest, httpStatus)
+	assert.Regexp(t, "FF22224.*loud", errRes.Error)
+
+	httpStatus = adminTestInputRequest(t, s, http.MethodPut, "/logging", testAdminAPIKey, map[string]interface{}{
+		"level":   "trace",
+		"modules": []string{"wrong"},
+	}, &errRes)
+	assert.Equal(t, http.StatusBadRequest, httpStatus)
+	assert.Regexp(t, "FF22223.*wrong", errRes.Error)
+
+	httpStatus = adminTestInputRequest(t, s, http.MethodPut, "/logging", testAdminAPIKey, `{"level":`, &errRes)
+	assert.Equal(t, http.StatusBadRequest, httpStatus)
+	assert.Regexp(t, "FF22225", errRes.Error)
+
+	// Nothing was changed
+	assert.Equal(t, logrus.InfoLevel, logrus.GetLevel())
+	assert.Empty(t, debuglog.EnabledModules())
+}
+
+func TestAdminSetLoggingTooLarge(t *testing.T) {
+	_, s, done := newTestServer(t, setTestAdminConf)
+	defer done()
+	s.maxRequestSize = 5
+
+	var errRes adminError
+	httpStatus := adminTestInputRequest(t, s, http.MethodPut, "/logging", testAdminAPIKey, `{"level":"trace"}`, &errRes)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, httpStatus)
+	assert.Regexp(t, "FF22163", errRes.Error)
+}
+
+func TestAdminSetLoggingReadFail(t *testing.T) {
+	_, s, done := newTestServer(t, setTestAdminConf)
+	defer done()
+
+	req := httptest.NewRequest(http.MethodPut, "/logging", errorReader{})
+	req.Header.Set("X-API-Key", testAdminAPIKey)
+	res := httptest.NewRecorder()
+	s.adminRouter().ServeHTTP(res, req)
+	assert.Equal(t, http.StatusBadRequest, res.Code)
+	assert.Regexp(t, "FF22225.*pop", res.Body.String())
+}
+
+func TestNewServerBadLogModules(t *testing.T) {
+	signerconfig.Reset()
+	config.Set(signerconfig.LogModules, []string{"wrong"})
+
+	_, err := newServer(context.Background(), &ethsignermocks.Wallet{}, nil, false)
+	assert.Regexp(t, "FF22223", err)
+}
+
+func TestNewServerLogModules(t *testing.T) {
+	defer debuglog.SetModules(nil)
+	_, _, done := newTestServer(t, func() {
+		config.Set(signerconfig.LogModules, []string{"abi"})
+	})
+	defer done()
+
+	assert.Equal(t, []debuglog.Module{debuglog.ABIDecode}, debuglog.EnabledModules())
+}
+
+func TestReloadLogModules(t *testing.T) {
+	_, s, done := newTestServer(t)
+	defer done()
+	defer debuglog.SetModules(nil)
+
+	w := s.wallet.(*ethsignermocks.Wallet)
+	w.On("Refresh", mock.Anything).Return(nil)
+
+	config.Set(signerconfig.LogModules, []string{"rpc"})
+	_, err := s.processRPC(s.ctx, &rpcbackend.RPCRequest{ID: fftypes.JSONAnyPtr("1"), Method: "ffsigner_reloadConfig"})
+	assert.NoError(t, err)
+	assert.Equal(t, []debuglog.Module{debuglog.RPCWire}, debuglog.EnabledModules())
+
+	config.Set(signerconfig.LogModules, []string{"wrong"})
+	_, err = s.processRPC(s.ctx, &rpcbackend.RPCRequest{ID: fftypes.JSONAnyPtr("2"), Method: "ffsigner_reloadConfig"})
+	assert.Regexp(t, "FF22145.*FF22223", err)
+	assert.Equal(t, []debuglog.Module{debuglog.RPCWire}, debuglog.EnabledModules())
+}
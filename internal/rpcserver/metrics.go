@@ -0,0 +1,235 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"context"
+	"net/http"
+	"path"
+	"reflect"
+	"regexp"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/hyperledger/firefly-common/pkg/metric"
+	"github.com/hyperledger/firefly-signer/internal/signerconfig"
+	"github.com/hyperledger/firefly-signer/pkg/ethsigner"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	metricsComponentName = "ffsigner"
+
+	metricsSubsystemServer  = "signer_rpc_server"
+	metricsSubsystemBackend = "signer_rpc_backend"
+	metricsSubsystemWallet  = "signer_wallet"
+
+	metricRequestsTotal          = "requests_total"
+	metricRequestDurationSeconds = "request_duration_seconds"
+	metricRequestsInFlight       = "requests_in_flight"
+	metricSignOperationsTotal    = "sign_operations_total"
+	metricSignerCacheItems       = "signer_cache_items"
+	metricSignerCacheHits        = "signer_cache_hits"
+	metricSignerCacheMisses      = "signer_cache_misses"
+	metricRateLimitedTotal       = "rate_limited_total"
+	metricResponseCacheHitsTotal = "response_cache_hits_total"
+
+	metricLabelMethod  = "method"
+	metricLabelOutcome = "outcome"
+	metricLabelWallet  = "wallet"
+	metricLabelLimit   = "limit"
+
+	outcomeSuccess     = "success"
+	outcomeRPCError    = "rpc_error"
+	outcomeUnavailable = "unavailable"
+	outcomeError       = "error"
+)
+
+// Method names come from callers, so we only use well-formed ones as label values
+var metricMethodRegex = regexp.MustCompile(`^[a-zA-Z0-9_]{1,64}$`)
+
+type rpcMetrics struct {
+	registry         metric.MetricsRegistry
+	server           metric.MetricsManager
+	backend          metric.MetricsManager
+	wallet           metric.MetricsManager
+	walletName       string
+	serverInFlight   atomic.Int64
+	backendInFlight  atomic.Int64
+	cacheStatsWallet ethsigner.WalletCacheStats
+	addressLabel     addressLabeler // only set when per-address metrics are enabled
+}
+
+// metricsBackend records the outcome and latency of every call to the backend
+type metricsBackend struct {
+	rpcbackend.Backend
+	metrics *rpcMetrics
+}
+
+func newRPCMetrics(ctx context.Context, registry metric.MetricsRegistry, wallet ethsigner.Wallet) (m *rpcMetrics, err error) {
+	m = &rpcMetrics{
+		registry:   registry,
+		walletName: walletName(wallet),
+	}
+	m.cacheStatsWallet, _ = wallet.(ethsigner.WalletCacheStats)
+	if m.server, err = registry.NewMetricsManagerForSubsystem(ctx, metricsSubsystemServer); err != nil {
+		return nil, err
+	}
+	if m.backend, err = registry.NewMetricsManagerForSubsystem(ctx, metricsSubsystemBackend); err != nil {
+		return nil, err
+	}
+	if m.wallet, err = registry.NewMetricsManagerForSubsystem(ctx, metricsSubsystemWallet); err != nil {
+		return nil, err
+	}
+
+	for _, mm := range []metric.MetricsManager{m.server, m.backend} {
+		mm.NewCounterMetricWithLabels(ctx, metricRequestsTotal, "Number of JSON/RPC requests by method and outcome", []string{metricLabelMethod, metricLabelOutcome}, false)
+		mm.NewHistogramMetricWithLabels(ctx, metricRequestDurationSeconds, "Duration of JSON/RPC requests by method", nil, []string{metricLabelMethod}, false)
+		mm.NewGaugeMetric(ctx, metricRequestsInFlight, "Number of JSON/RPC requests in-flight", false)
+	}
+	m.server.NewCounterMetricWithLabels(ctx, metricRateLimitedTotal, "Number of JSON/RPC requests rejected by a rate limit, by the limit exceeded", []string{metricLabelLimit}, false)
+	m.server.NewCounterMetricWithLabels(ctx, metricResponseCacheHitsTotal, "Number of JSON/RPC requests answered from the response cache, by method", []string{metricLabelMethod}, false)
+	m.wallet.NewCounterMetricWithLabels(ctx, metricSignOperationsTotal, "Number of signing operations by wallet and outcome", []string{metricLabelWallet, metricLabelOutcome}, false)
+	if m.cacheStatsWallet != nil {
+		m.wallet.NewGaugeMetricWithLabels(ctx, metricSignerCacheItems, "Number of signing keys held in the wallet cache", []string{metricLabelWallet}, false)
+		m.wallet.NewGaugeMetricWithLabels(ctx, metricSignerCacheHits, "Number of signing key lookups served from the wallet cache", []string{metricLabelWallet}, false)
+		m.wallet.NewGaugeMetricWithLabels(ctx, metricSignerCacheMisses, "Number of signing key lookups that missed the wallet cache", []string{metricLabelWallet}, false)
+	}
+	if signerconfig.MetricsConfig.GetBool(signerconfig.MetricsConfAddressesEnabled) {
+		if err := m.initAddressMetrics(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// walletName is the name of the package implementing the wallet, such as "fswallet"
+func walletName(wallet ethsigner.Wallet) string {
+	t := reflect.TypeOf(wallet)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return path.Base(t.PkgPath())
+}
+
+func metricMethod(method string) string {
+	if metricMethodRegex.MatchString(method) {
+		return method
+	}
+	return "invalid"
+}
+
+func rpcOutcome(rpcRes *rpcbackend.RPCResponse, err error) string {
+	switch {
+	case err == nil:
+		return outcomeSuccess
+	case rpcbackend.IsBackendUnavailable(err):
+		return outcomeUnavailable
+	case rpcRes != nil && rpcRes.Error != nil && rpcRes.Error.Code != int64(rpcbackend.RPCCodeInternalError):
+		return outcomeRPCError
+	default:
+		return outcomeError
+	}
+}
+
+func (m *rpcMetrics) requestStart(ctx context.Context, mm metric.MetricsManager, inFlight *atomic.Int64) time.Time {
+	mm.SetGaugeMetric(ctx, metricRequestsInFlight, float64(inFlight.Add(1)), nil)
+	return time.Now()
+}
+
+func (m *rpcMetrics) requestComplete(ctx context.Context, mm metric.MetricsManager, inFlight *atomic.Int64, method, outcome string, startTime time.Time) {
+	mm.SetGaugeMetric(ctx, metricRequestsInFlight, float64(inFlight.Add(-1)), nil)
+	method = metricMethod(method)
+	mm.IncCounterMetricWithLabels(ctx, metricRequestsTotal, map[string]string{metricLabelMethod: method, metricLabelOutcome: outcome}, nil)
+	mm.ObserveHistogramMetricWithLabels(ctx, metricRequestDurationSeconds, time.Since(startTime).Seconds(), map[string]string{metricLabelMethod: method}, nil)
+}
+
+// signOperation records the outcome of signing for the address, which is nil if it could not be parsed
+func (m *rpcMetrics) signOperation(ctx context.Context, addr *ethtypes.Address0xHex, operation string, startTime time.Time, err error) {
+	outcome := outcomeSuccess
+	if err != nil {
+		outcome = outcomeError
+	}
+	m.wallet.IncCounterMetricWithLabels(ctx, metricSignOperationsTotal, map[string]string{metricLabelWallet: m.walletName, metricLabelOutcome: outcome}, nil)
+	if m.addressLabel != nil {
+		m.addressSignOperation(ctx, addr, operation, outcome, startTime)
+	}
+}
+
+func (m *rpcMetrics) rateLimited(ctx context.Context, limit string) {
+	m.server.IncCounterMetricWithLabels(ctx, metricRateLimitedTotal, map[string]string{metricLabelLimit: limit}, nil)
+}
+
+func (m *rpcMetrics) responseCacheHit(ctx context.Context, method string) {
+	m.server.IncCounterMetricWithLabels(ctx, metricResponseCacheHitsTotal, map[string]string{metricLabelMethod: method}, nil)
+}
+
+// updateCacheStats is called on each scrape, as the wallet maintains the statistics itself
+func (m *rpcMetrics) updateCacheStats(ctx context.Context) {
+	if m.cacheStatsWallet == nil {
+		return
+	}
+	stats := m.cacheStatsWallet.SignerCacheStats()
+	labels := map[string]string{metricLabelWallet: m.walletName}
+	m.wallet.SetGaugeMetricWithLabels(ctx, metricSignerCacheItems, float64(stats.Items), labels, nil)
+	m.wallet.SetGaugeMetricWithLabels(ctx, metricSignerCacheHits, float64(stats.Hits), labels, nil)
+	m.wallet.SetGaugeMetricWithLabels(ctx, metricSignerCacheMisses, float64(stats.Misses), labels, nil)
+}
+
+func (m *rpcMetrics) router(ctx context.Context) *mux.Router {
+	h, err := m.registry.HTTPHandler(ctx, promhttp.HandlerOpts{})
+	if err != nil {
+		panic(err) // only possible if no metrics have been registered
+	}
+	r := mux.NewRouter()
+	r.Path(signerconfig.MetricsConfig.GetString(signerconfig.MetricsConfPath)).Methods(http.MethodGet).HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		m.updateCacheStats(req.Context())
+		h.ServeHTTP(w, req)
+	})
+	return r
+}
+
+func (mb *metricsBackend) CallRPC(ctx context.Context, result interface{}, method string, params ...interface{}) *rpcbackend.RPCError {
+	m := mb.metrics
+	startTime := m.requestStart(ctx, m.backend, &m.backendInFlight)
+	rpcErr := mb.Backend.CallRPC(ctx, result, method, params...)
+	outcome := outcomeSuccess
+	if rpcErr != nil {
+		outcome = rpcOutcome(&rpcbackend.RPCResponse{Error: rpcErr}, rpcErr.Error())
+	}
+	m.requestComplete(ctx, m.backend, &m.backendInFlight, method, outcome, startTime)
+	return rpcErr
+}
+
+func (mb *metricsBackend) SyncRequest(ctx context.Context, rpcReq *rpcbackend.RPCRequest) (*rpcbackend.RPCResponse, error) {
+	m := mb.metrics
+	startTime := m.requestStart(ctx, m.backend, &m.backendInFlight)
+	rpcRes, err := mb.Backend.SyncRequest(ctx, rpcReq)
+	m.requestComplete(ctx, m.backend, &m.backendInFlight, rpcReq.Method, rpcOutcome(rpcRes, err), startTime)
+	return rpcRes, err
+}
+
+func (mb *metricsBackend) BatchRequest(ctx context.Context, rpcReqs []*rpcbackend.RPCRequest) ([]*rpcbackend.RPCResponse, error) {
+	m := mb.metrics
+	startTime := m.requestStart(ctx, m.backend, &m.backendInFlight)
+	rpcResponses, err := mb.Backend.BatchRequest(ctx, rpcReqs)
+	m.requestComplete(ctx, m.backend, &m.backendInFlight, "batch", rpcOutcome(nil, err), startTime)
+	return rpcResponses, err
+}
@@ -0,0 +1,232 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-common/pkg/httpserver"
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-common/pkg/metric"
+	"github.com/hyperledger/firefly-signer/internal/signerconfig"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/hyperledger/firefly-signer/mocks/ethsignermocks"
+	"github.com/hyperledger/firefly-signer/mocks/rpcbackendmocks"
+	"github.com/hyperledger/firefly-signer/pkg/ethsigner"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func freeTestPort(t *testing.T) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer ln.Close()
+	return strings.Split(ln.Addr().String(), ":")[1]
+}
+
+func newTestMetricsServer(t *testing.T, w ethsigner.Wallet, confSetters ...func()) (string, string, *rpcServer, *rpcbackendmocks.Backend, func()) {
+	signerconfig.Reset()
+	for _, setConf := range confSetters {
+		setConf()
+	}
+	serverPort := freeTestPort(t)
+	metricsPort := freeTestPort(t)
+	signerconfig.ServerConfig.Set(httpserver.HTTPConfPort, serverPort)
+	signerconfig.ServerConfig.Set(httpserver.HTTPConfAddress, "127.0.0.1")
+	signerconfig.MetricsConfig.Set(signerconfig.MetricsConfEnabled, true)
+	signerconfig.MetricsConfig.Set(httpserver.HTTPConfPort, metricsPort)
+	signerconfig.MetricsConfig.Set(httpserver.HTTPConfAddress, "127.0.0.1")
+
+	ss, err := NewServer(context.Background(), w)
+	assert.NoError(t, err)
+	s := ss.(*rpcServer)
+	bm := &rpcbackendmocks.Backend{}
+	s.backend = &metricsBackend{Backend: bm, metrics: s.metrics}
+
+	return fmt.Sprintf("http://127.0.0.1:%s", serverPort),
+		fmt.Sprintf("http://127.0.0.1:%s/metrics", metricsPort),
+		s, bm,
+		func() {
+			s.Stop()
+			_ = s.WaitStop()
+		}
+}
+
+func scrapeTestMetrics(t *testing.T, metricsURL string) string {
+	res, err := http.Get(metricsURL)
+	assert.NoError(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	b, err := io.ReadAll(res.Body)
+	assert.NoError(t, err)
+	return string(b)
+}
+
+func TestMetricsEndToEnd(t *testing.T) {
+	w := &ethsignermocks.WalletCacheStats{}
+	w.On("Initialize", mock.Anything).Return(nil)
+	w.On("Sign", mock.Anything, mock.Anything, int64(12345)).Return([]byte{0x01}, nil).Once()
+	w.On("Sign", mock.Anything, mock.Anything, int64(12345)).Return(nil, fmt.Errorf("pop")).Once()
+	w.On("SignerCacheStats").Return(&ethsigner.CacheStats{Items: 1, Hits: 2, Misses: 3})
+
+	url, metricsURL, s, bm, done := newTestMetricsServer(t, w)
+	defer done()
+	s.chainID = 12345
+	s.chainIDValidation = false
+
+	bm.On("SyncRequest", mock.Anything, mock.MatchedBy(func(rpcReq *rpcbackend.RPCRequest) bool {
+		return rpcReq.Method == "eth_sendRawTransaction"
+	})).Return(&rpcbackend.RPCResponse{
+		JSONRpc: "2.0",
+		ID:      fftypes.JSONAnyPtr(`1`),
+		Result:  fftypes.JSONAnyPtr(`"0x61ca9c99c1d752fb3bda568b8566edf33ba93585c64a970566e6dfb540a5cbc1"`),
+	}, nil)
+	bm.On("SyncRequest", mock.Anything, mock.MatchedBy(func(rpcReq *rpcbackend.RPCRequest) bool {
+		return rpcReq.Method == "eth_call"
+	})).Return(&rpcbackend.RPCResponse{
+		JSONRpc: "2.0",
+		ID:      fftypes.JSONAnyPtr(`2`),
+		Error:   &rpcbackend.RPCError{Code: -32000, Message: "reverted"},
+	}, fmt.Errorf("reverted"))
+	unavailableErr := i18n.NewError(context.Background(), signermsgs.MsgRPCRequestFailed, "pop")
+	bm.On("SyncRequest", mock.Anything, mock.MatchedBy(func(rpcReq *rpcbackend.RPCRequest) bool {
+		return rpcReq.Method == "bad method!"
+	})).Return(rpcbackend.RPCErrorResponse(fmt.Errorf("pop"), fftypes.JSONAnyPtr(`4`), rpcbackend.RPCCodeInternalError), fmt.Errorf("pop"))
+	bm.On("SyncRequest", mock.Anything, mock.MatchedBy(func(rpcReq *rpcbackend.RPCRequest) bool {
+		return rpcReq.Method == "eth_blockNumber"
+	})).Return(rpcbackend.RPCErrorResponse(unavailableErr, fftypes.JSONAnyPtr(`3`), rpcbackend.RPCCodeInternalError), unavailableErr)
+
+	err := s.Start()
+	assert.NoError(t, err)
+
+	c := resty.New().SetBaseURL(url)
+	for _, body := range []string{
+		`{"jsonrpc":"2.0","id":1,"method":"eth_sendTransaction","params":[{"from":"0xfb075bb99f2aa4c49955bf703509a227d7a12248","nonce":"0x1"}]}`,
+		`{"jsonrpc":"2.0","id":1,"method":"eth_sendTransaction","params":[{"from":"0xfb075bb99f2aa4c49955bf703509a227d7a12248","nonce":"0x1"}]}`,
+		`{"jsonrpc":"2.0","id":2,"method":"eth_call","params":[]}`,
+		`{"jsonrpc":"2.0","id":3,"method":"eth_blockNumber"}`,
+		`{"jsonrpc":"2.0","id":4,"method":"bad method!"}`,
+	} {
+		_, err := c.R().SetBody(body).Post("")
+		assert.NoError(t, err)
+	}
+
+	metrics := scrapeTestMetrics(t, metricsURL)
+	for _, expected := range []string{
+		`ff_signer_rpc_server_requests_total{ff_component="ffsigner",method="eth_sendTransaction",outcome="success"} 1`,
+		`ff_signer_rpc_server_requests_total{ff_component="ffsigner",method="eth_sendTransaction",outcome="error"} 1`,
+		`ff_signer_rpc_server_requests_total{ff_component="ffsigner",method="eth_call",outcome="rpc_error"} 1`,
+		`ff_signer_rpc_server_requests_total{ff_component="ffsigner",method="eth_blockNumber",outcome="unavailable"} 1`,
+		`ff_signer_rpc_server_request_duration_seconds_count{ff_component="ffsigner",method="eth_call"} 1`,
+		`ff_signer_rpc_server_requests_in_flight{ff_component="ffsigner"} 0`,
+		`ff_signer_rpc_backend_requests_total{ff_component="ffsigner",method="eth_sendRawTransaction",outcome="success"} 1`,
+		`ff_signer_rpc_backend_requests_total{ff_component="ffsigner",method="eth_call",outcome="rpc_error"} 1`,
+		`ff_signer_rpc_backend_requests_total{ff_component="ffsigner",method="invalid",outcome="error"} 1`,
+		`ff_signer_wallet_sign_operations_total{ff_component="ffsigner",outcome="success",wallet="ethsignermocks"} 1`,
+		`ff_signer_wallet_sign_operations_total{ff_component="ffsigner",outcome="error",wallet="ethsignermocks"} 1`,
+		`ff_signer_wallet_signer_cache_items{ff_component="ffsigner",wallet="ethsignermocks"} 1`,
+		`ff_signer_wallet_signer_cache_hits{ff_component="ffsigner",wallet="ethsignermocks"} 2`,
+		`ff_signer_wallet_signer_cache_misses{ff_component="ffsigner",wallet="ethsignermocks"} 3`,
+	} {
+		assert.Contains(t, metrics, expected)
+	}
+}
+
+func TestMetricsBackendCallRPCAndBatch(t *testing.T) {
+	w := &ethsignermocks.Wallet{}
+	_, _, s, bm, done := newTestMetricsServer(t, w)
+	defer done()
+
+	bm.On("CallRPC", mock.Anything, mock.Anything, "net_version").Run(func(args mock.Arguments) {
+		hi := args[1].(*ethtypes.HexInteger)
+		hi.BigInt().SetInt64(12345)
+	}).Return(nil).Once()
+	bm.On("CallRPC", mock.Anything, mock.Anything, "net_version").Return(&rpcbackend.RPCError{Code: -32000, Message: "pop"})
+	bm.On("BatchRequest", mock.Anything, mock.Anything).Return([]*rpcbackend.RPCResponse{}, nil)
+
+	ctx := context.Background()
+	var chainID ethtypes.HexInteger
+	rpcErr := s.backend.CallRPC(ctx, &chainID, "net_version")
+	assert.Nil(t, rpcErr)
+	rpcErr = s.backend.CallRPC(ctx, &chainID, "net_version")
+	assert.Regexp(t, "pop", rpcErr.Message)
+	_, err := s.backend.BatchRequest(ctx, []*rpcbackend.RPCRequest{})
+	assert.NoError(t, err)
+
+	// No cache stats for a wallet that does not provide them
+	s.metrics.updateCacheStats(ctx)
+}
+
+func TestNewRPCMetricsDuplicateSubsystems(t *testing.T) {
+	ctx := context.Background()
+	w := &ethsignermocks.Wallet{}
+	for i, subsystem := range []string{metricsSubsystemServer, metricsSubsystemBackend, metricsSubsystemWallet} {
+		registry := metric.NewPrometheusMetricsRegistry(fmt.Sprintf("test%d", i))
+		_, err := registry.NewMetricsManagerForSubsystem(ctx, subsystem)
+		assert.NoError(t, err)
+		_, err = newRPCMetrics(ctx, registry, w)
+		assert.Regexp(t, "FF00", err)
+	}
+}
+
+func TestInitMetricsFail(t *testing.T) {
+	ctx := context.Background()
+	registry := metric.NewPrometheusMetricsRegistry("test")
+	_, err := registry.NewMetricsManagerForSubsystem(ctx, metricsSubsystemServer)
+	assert.NoError(t, err)
+
+	s := &rpcServer{wallet: &ethsignermocks.Wallet{}}
+	err = s.initMetrics(ctx, registry)
+	assert.Regexp(t, "FF00", err)
+}
+
+func TestMetricsRouterEmptyRegistry(t *testing.T) {
+	m := &rpcMetrics{registry: metric.NewPrometheusMetricsRegistry("test")}
+	assert.Panics(t, func() {
+		_ = m.router(context.Background())
+	})
+}
+
+func TestInitMetricsBadConfig(t *testing.T) {
+	signerconfig.Reset()
+	signerconfig.ServerConfig.Set(httpserver.HTTPConfPort, 0)
+	signerconfig.ServerConfig.Set(httpserver.HTTPConfAddress, "127.0.0.1")
+	signerconfig.MetricsConfig.Set(signerconfig.MetricsConfEnabled, true)
+	signerconfig.MetricsConfig.Set(httpserver.HTTPConfAddress, ":::::")
+
+	_, err := NewServer(context.Background(), &ethsignermocks.Wallet{})
+	assert.Regexp(t, "FF00151", err)
+}
+
+func TestWalletName(t *testing.T) {
+	assert.Equal(t, "ethsignermocks", walletName(&ethsignermocks.Wallet{}))
+	assert.Equal(t, "rpcserver", walletName(testValueWallet{}))
+}
+
+type testValueWallet struct {
+	ethsigner.Wallet
+}
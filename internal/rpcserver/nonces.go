@@ -0,0 +1,210 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-common/pkg/log"
+	"github.com/hyperledger/firefly-signer/internal/signerconfig"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/hyperledger/firefly-signer/pkg/approval"
+	"github.com/hyperledger/firefly-signer/pkg/ethsigner"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/nonces"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+)
+
+// A plain transfer of zero value, which is used to fill a nonce gap
+const gapFillGasLimit = 21000
+
+type nonceGapFill struct {
+	Nonce           ethtypes.HexUint64        `json:"nonce"`
+	TransactionHash ethtypes.HexBytes0xPrefix `json:"transactionHash"`
+}
+
+func (s *rpcServer) initNonces(ctx context.Context) error {
+	conf := nonces.ReadConfig(signerconfig.NoncesConfig)
+	if !conf.Enabled {
+		return nil
+	}
+	store := nonces.NewMemoryStore()
+	switch {
+	case conf.Path != "":
+		var err error
+		if store, err = nonces.NewFileStore(ctx, conf.Path); err != nil {
+			return err
+		}
+	case s.persistence != nil:
+		store = nonces.NewPersistentStore(s.persistence)
+	default:
+		log.L(ctx).Warn("Nonces are not persisted, as neither nonces.path nor persistence.path is set")
+	}
+	s.nonceManager = nonces.NewManager(store, s.transactionCount, conf)
+	s.nonceMonitorInterval = conf.MonitorInterval
+	return nil
+}
+
+func (s *rpcServer) runNonceMonitor() {
+	defer close(s.nonceMonitorDone)
+	s.nonceManager.Monitor(s.ctx, s.nonceMonitorInterval)
+}
+
+func (s *rpcServer) transactionCount(ctx context.Context, addr ethtypes.Address0xHex, blockTag string) (uint64, error) {
+	var txCount ethtypes.HexInteger
+	if rpcErr := s.backend.CallRPC(ctx, &txCount, "eth_getTransactionCount", &addr, blockTag); rpcErr != nil {
+		return 0, rpcErr.Error()
+	}
+	return txCount.Uint64(), nil
+}
+
+// nonceAdminAddress checks nonce management is enabled, and returns the (authorized) address that is
+// the only parameter of each of the nonce admin methods
+func (s *rpcServer) nonceAdminAddress(ctx context.Context, rpcReq *rpcbackend.RPCRequest) (*ethtypes.Address0xHex, *rpcbackend.RPCResponse, error) {
+	if s.nonceManager == nil {
+		err := i18n.NewError(ctx, signermsgs.MsgNonceManagementDisabled)
+		return nil, rpcbackend.RPCErrorResponse(err, rpcReq.ID, rpcbackend.RPCCodeMethodNotFound), err
+	}
+	if len(rpcReq.Params) < 1 {
+		err := i18n.NewError(ctx, signermsgs.MsgInvalidParamCount, 1, len(rpcReq.Params))
+		return nil, rpcbackend.RPCErrorResponse(err, rpcReq.ID, rpcbackend.RPCCodeInvalidRequest), err
+	}
+	var addr ethtypes.Address0xHex
+	if errRes, err := s.unmarshalParam(ctx, rpcReq, 0, &addr); err != nil {
+		return nil, errRes, err
+	}
+	if errRes, err := s.authorizeAddress(ctx, rpcReq, &addr); err != nil {
+		return nil, errRes, err
+	}
+	return &addr, nil, nil
+}
+
+func jsonResult(rpcReq *rpcbackend.RPCRequest, result interface{}) *rpcbackend.RPCResponse {
+	b, _ := json.Marshal(result)
+	return &rpcbackend.RPCResponse{
+		JSONRpc: "2.0",
+		ID:      rpcReq.ID,
+		Result:  fftypes.JSONAnyPtrBytes(b),
+	}
+}
+
+func (s *rpcServer) processNonceStatus(ctx context.Context, rpcReq *rpcbackend.RPCRequest) (*rpcbackend.RPCResponse, error) {
+	addr, errRes, err := s.nonceAdminAddress(ctx, rpcReq)
+	if err != nil {
+		return errRes, err
+	}
+	status, err := s.nonceManager.Status(ctx, *addr)
+	if err != nil {
+		return rpcbackend.RPCErrorResponse(err, rpcReq.ID, rpcbackend.RPCCodeInternalError), err
+	}
+	return jsonResult(rpcReq, status), nil
+}
+
+func (s *rpcServer) processResyncNonce(ctx context.Context, rpcReq *rpcbackend.RPCRequest) (*rpcbackend.RPCResponse, error) {
+	addr, errRes, err := s.nonceAdminAddress(ctx, rpcReq)
+	if err != nil {
+		return errRes, err
+	}
+	status, err := s.nonceManager.Resync(ctx, *addr)
+	if err != nil {
+		return rpcbackend.RPCErrorResponse(err, rpcReq.ID, rpcbackend.RPCCodeInternalError), err
+	}
+	return jsonResult(rpcReq, status), nil
+}
+
+// processFillNonceGaps submits a zero value transaction from the address to itself, for each nonce that has
+// been assigned but that the node has no transaction for, so that the transactions behind it can be mined.
+// The pending count is queried again after each one, as the node might already have later transactions queued,
+// which become pending once the gap before them is filled (and must not be replaced).
+func (s *rpcServer) processFillNonceGaps(ctx context.Context, rpcReq *rpcbackend.RPCRequest) (*rpcbackend.RPCResponse, error) {
+	addr, errRes, err := s.nonceAdminAddress(ctx, rpcReq)
+	if err != nil {
+		return errRes, err
+	}
+	if errRes, err := s.checkRateLimit(ctx, rpcReq, rateLimitSigning); err != nil {
+		return errRes, err
+	}
+	if errRes, err := s.checkChainID(ctx, rpcReq, nil); err != nil {
+		return errRes, err
+	}
+	status, err := s.nonceManager.Status(ctx, *addr)
+	if err != nil {
+		return rpcbackend.RPCErrorResponse(err, rpcReq.ID, rpcbackend.RPCCodeInternalError), err
+	}
+
+	filled := []*nonceGapFill{}
+	if status.Gap {
+		var gasPrice ethtypes.HexInteger
+		if rpcErr := s.backend.CallRPC(ctx, &gasPrice, "eth_gasPrice"); rpcErr != nil {
+			return rpcbackend.RPCErrorResponse(rpcErr.Error(), rpcReq.ID, rpcbackend.RPCCodeInternalError), rpcErr.Error()
+		}
+		for nonce := status.Pending.Uint64(); nonce < status.Next.Uint64(); {
+			fill, errRes, err := s.fillNonceGap(ctx, rpcReq, addr, nonce, &gasPrice)
+			if err != nil {
+				return errRes, err
+			}
+			filled = append(filled, fill)
+			pending, err := s.transactionCount(ctx, *addr, "pending")
+			if err != nil {
+				return rpcbackend.RPCErrorResponse(err, rpcReq.ID, rpcbackend.RPCCodeInternalError), err
+			}
+			nonce = max(nonce+1, pending)
+		}
+	}
+	return jsonResult(rpcReq, filled), nil
+}
+
+// fillNonceGap signs and submits the transaction that fills one nonce, once it is approved as any other signature
+func (s *rpcServer) fillNonceGap(ctx context.Context, rpcReq *rpcbackend.RPCRequest, addr *ethtypes.Address0xHex, nonce uint64, gasPrice *ethtypes.HexInteger) (*nonceGapFill, *rpcbackend.RPCResponse, error) {
+	txn := &ethsigner.Transaction{
+		From:     json.RawMessage(fmt.Sprintf(`"%s"`, addr)),
+		To:       addr,
+		Nonce:    ethtypes.NewHexIntegerU64(nonce),
+		GasPrice: gasPrice,
+		GasLimit: ethtypes.NewHexIntegerU64(gapFillGasLimit),
+		Value:    ethtypes.NewHexIntegerU64(0),
+	}
+	if s.feeCaps != nil {
+		if err := s.feeCaps.apply(ctx, txn); err != nil {
+			err = i18n.NewError(ctx, signermsgs.MsgNonceGapFillFailed, nonce, addr, err)
+			return nil, rpcbackend.RPCErrorResponse(err, rpcReq.ID, rpcbackend.RPCCodeInternalError), err
+		}
+	}
+	req := &signingRequest{method: "ffsigner_fillNonceGaps", operation: signOpTransaction, from: addr, txn: txn}
+	if errRes, err := s.approveSigning(ctx, rpcReq, req, &approval.Request{}); err != nil {
+		return nil, errRes, err
+	}
+	startTime := time.Now()
+	signed, err := s.wallet.Sign(ctx, txn, s.chainIDFor(ctx))
+	s.signOperation(ctx, req, startTime, signed, err)
+	if err != nil {
+		err = i18n.NewError(ctx, signermsgs.MsgNonceGapFillFailed, nonce, addr, err)
+		return nil, rpcbackend.RPCErrorResponse(err, rpcReq.ID, rpcbackend.RPCCodeInternalError), err
+	}
+	var txHash ethtypes.HexBytes0xPrefix
+	if rpcErr := s.backend.CallRPC(ctx, &txHash, "eth_sendRawTransaction", ethtypes.HexBytes0xPrefix(signed)); rpcErr != nil {
+		err = i18n.NewError(ctx, signermsgs.MsgNonceGapFillFailed, nonce, addr, rpcErr.Error())
+		return nil, rpcbackend.RPCErrorResponse(err, rpcReq.ID, rpcbackend.RPCCodeInternalError), err
+	}
+	log.L(ctx).Infof("Filled nonce gap at %d for %s with transaction %s", nonce, addr, txHash)
+	return &nonceGapFill{Nonce: ethtypes.HexUint64(nonce), TransactionHash: txHash}, nil, nil
+}
@@ -0,0 +1,520 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-signer/internal/signerconfig"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/hyperledger/firefly-signer/mocks/ethsignermocks"
+	"github.com/hyperledger/firefly-signer/mocks/rpcbackendmocks"
+	"github.com/hyperledger/firefly-signer/pkg/approval"
+	"github.com/hyperledger/firefly-signer/pkg/ethsigner"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/nonces"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newTestNonceServer(t *testing.T) (*rpcServer, *rpcbackendmocks.Backend, *ethsignermocks.Wallet, func()) {
+	_, s, done := newTestServer(t)
+	bm := s.backend.(*rpcbackendmocks.Backend)
+	bm.On("CallRPC", mock.Anything, mock.Anything, "eth_getTransactionCount", mock.Anything, "pending").Run(func(args mock.Arguments) {
+		*(args[1].(*ethtypes.HexInteger)) = *ethtypes.NewHexIntegerU64(10)
+	}).Return(nil).Once()
+	s.nonceManager = nonces.NewManager(nonces.NewMemoryStore(), s.transactionCount, &nonces.Config{ReconcileInterval: time.Hour, StallTimeout: time.Hour})
+	grantTestOperator(t, s)
+	return s, bm, s.wallet.(*ethsignermocks.Wallet), done
+}
+
+func nonceTestRequest() *rpcbackend.RPCRequest {
+	return &rpcbackend.RPCRequest{
+		ID:     fftypes.JSONAnyPtr("1"),
+		Method: "eth_sendTransaction",
+		Params: []*fftypes.JSONAny{fftypes.JSONAnyPtr(`{"from": "0xfb075bb99f2aa4c49955bf703509a227d7a12248"}`)},
+	}
+}
+
+func mockSignNonce(w *ethsignermocks.Wallet, nonce int64, err error) {
+	w.On("Sign", mock.Anything, mock.MatchedBy(func(txn *ethsigner.Transaction) bool {
+		return txn.Nonce.Int64() == nonce
+	}), mock.Anything).Return([]byte{0x01}, err).Once()
+}
+
+func TestNonceManagerAssignsNonces(t *testing.T) {
+	s, bm, w, done := newTestNonceServer(t)
+	defer done()
+
+	mockSignNonce(w, 10, nil)
+	mockSignNonce(w, 11, nil)
+	bm.On("SyncRequest", mock.Anything, mock.Anything).Return(&rpcbackend.RPCResponse{Result: fftypes.JSONAnyPtr(`"0x1234"`)}, nil)
+
+	for i := 0; i < 2; i++ {
+		_, err := s.processRPC(s.ctx, nonceTestRequest())
+		assert.NoError(t, err)
+	}
+	w.AssertExpectations(t)
+	bm.AssertExpectations(t)
+}
+
+func TestNonceManagerReturnsNonceOnFailure(t *testing.T) {
+	s, bm, w, done := newTestNonceServer(t)
+	defer done()
+
+	// A signing failure returns the nonce
+	mockSignNonce(w, 10, fmt.Errorf("pop"))
+	_, err := s.processRPC(s.ctx, nonceTestRequest())
+	assert.Regexp(t, "pop", err)
+
+	// As does a rejection by the node
+	mockSignNonce(w, 10, nil)
+	bm.On("SyncRequest", mock.Anything, mock.Anything).Return(&rpcbackend.RPCResponse{
+		Error: &rpcbackend.RPCError{Code: -32000, Message: "insufficient funds"},
+	}, fmt.Errorf("insufficient funds")).Once()
+	_, err = s.processRPC(s.ctx, nonceTestRequest())
+	assert.Regexp(t, "insufficient funds", err)
+
+	// But not a failure to reach the node, as the transaction might have been submitted
+	mockSignNonce(w, 10, nil)
+	unavailableErr := i18n.NewError(context.Background(), signermsgs.MsgRPCRequestFailed, "pop")
+	bm.On("SyncRequest", mock.Anything, mock.Anything).Return(rpcbackend.RPCErrorResponse(unavailableErr, nil, rpcbackend.RPCCodeInternalError), unavailableErr).Once()
+	_, err = s.processRPC(s.ctx, nonceTestRequest())
+	assert.Regexp(t, "FF22012", err)
+
+	mockSignNonce(w, 11, fmt.Errorf("pop"))
+	_, err = s.processRPC(s.ctx, nonceTestRequest())
+	assert.Regexp(t, "pop", err)
+
+	w.AssertExpectations(t)
+	bm.AssertExpectations(t)
+}
+
+func TestNonceManagerApprovalRejected(t *testing.T) {
+	s, bm, w, done := newTestNonceServer(t)
+	defer done()
+	reject := true
+	s.SetApprovalHook(&testApprovalHook{review: func(req *approval.Request) (*approval.Decision, error) {
+		if reject {
+			return approval.Rejected("not today"), nil
+		}
+		return approval.Approved(), nil
+	}})
+
+	// A rejected transaction is not assigned a nonce, so the next one gets it
+	_, err := s.processRPC(s.ctx, nonceTestRequest())
+	assert.Regexp(t, "FF22283", err)
+
+	reject = false
+	mockSignNonce(w, 10, nil)
+	bm.On("SyncRequest", mock.Anything, mock.Anything).Return(&rpcbackend.RPCResponse{Result: fftypes.JSONAnyPtr(`"0x1234"`)}, nil)
+	_, err = s.processRPC(s.ctx, nonceTestRequest())
+	assert.NoError(t, err)
+	w.AssertExpectations(t)
+}
+
+func TestNonceManagerAssignFail(t *testing.T) {
+	_, s, done := newTestServer(t)
+	defer done()
+
+	bm := s.backend.(*rpcbackendmocks.Backend)
+	bm.On("CallRPC", mock.Anything, mock.Anything, "eth_getTransactionCount", mock.Anything, "pending").Return(&rpcbackend.RPCError{Message: "pop"})
+	s.nonceManager = nonces.NewManager(nonces.NewMemoryStore(), s.transactionCount, &nonces.Config{ReconcileInterval: time.Hour})
+
+	rpcRes, err := s.processRPC(s.ctx, nonceTestRequest())
+	assert.Regexp(t, "pop", err)
+	assert.Equal(t, int64(rpcbackend.RPCCodeInternalError), rpcRes.Error.Code)
+}
+
+func TestInitNonces(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "nonces")
+	_, s, done := newTestServer(t, func() {
+		signerconfig.NoncesConfig.Set(nonces.ConfigEnabled, true)
+		signerconfig.NoncesConfig.Set(nonces.ConfigPath, dir)
+	})
+	defer done()
+	assert.NotNil(t, s.nonceManager)
+	assert.DirExists(t, dir)
+
+	_, s, done2 := newTestServer(t, func() {
+		signerconfig.NoncesConfig.Set(nonces.ConfigEnabled, true)
+	})
+	defer done2()
+	assert.NotNil(t, s.nonceManager)
+}
+
+func TestInitNoncesBadPath(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "file")
+	err := os.WriteFile(file, []byte{}, 0600)
+	assert.NoError(t, err)
+
+	signerconfig.Reset()
+	signerconfig.NoncesConfig.Set(nonces.ConfigEnabled, true)
+	signerconfig.NoncesConfig.Set(nonces.ConfigPath, file)
+	_, err = NewServer(context.Background(), &ethsignermocks.Wallet{})
+	assert.Regexp(t, "FF22123", err)
+}
+
+func mockTxCount(bm *rpcbackendmocks.Backend, blockTag string, count uint64) {
+	bm.On("CallRPC", mock.Anything, mock.Anything, "eth_getTransactionCount", mock.Anything, blockTag).Run(func(args mock.Arguments) {
+		*(args[1].(*ethtypes.HexInteger)) = *ethtypes.NewHexIntegerU64(count)
+	}).Return(nil).Once()
+}
+
+// newTestNonceAdminServer has nonces 10, 11 and 12 assigned for the test address
+func newTestNonceAdminServer(t *testing.T) (*rpcServer, *rpcbackendmocks.Backend, *ethsignermocks.Wallet, func()) {
+	s, bm, w, done := newTestNonceServer(t)
+	for i := 0; i < 3; i++ {
+		_, err := s.nonceManager.AssignNonce(s.ctx, *ethtypes.MustNewAddress(testNonceAddr))
+		assert.NoError(t, err)
+	}
+	return s, bm, w, done
+}
+
+const testNonceAddr = "0xfb075bb99f2aa4c49955bf703509a227d7a12248"
+
+func nonceAdminRequest(method string) *rpcbackend.RPCRequest {
+	return &rpcbackend.RPCRequest{
+		ID:     fftypes.JSONAnyPtr("1"),
+		Method: method,
+		Params: []*fftypes.JSONAny{fftypes.JSONAnyPtr(`"` + testNonceAddr + `"`)},
+	}
+}
+
+func TestNonceAdminDisabled(t *testing.T) {
+	_, s, done := newTestServer(t)
+	defer done()
+	grantTestOperator(t, s)
+
+	for _, method := range []string{"ffsigner_nonceStatus", "ffsigner_resyncNonce", "ffsigner_fillNonceGaps"} {
+		rpcRes, err := s.processRPC(s.ctx, nonceAdminRequest(method))
+		assert.Regexp(t, "FF22126", err)
+		assert.Equal(t, int64(rpcbackend.RPCCodeMethodNotFound), rpcRes.Error.Code)
+	}
+}
+
+func TestNonceAdminBadParams(t *testing.T) {
+	s, _, _, done := newTestNonceServer(t)
+	defer done()
+
+	rpcRes, err := s.processRPC(s.ctx, &rpcbackend.RPCRequest{
+		ID:     fftypes.JSONAnyPtr("1"),
+		Method: "ffsigner_nonceStatus",
+	})
+	assert.Regexp(t, "FF22019", err)
+	assert.Equal(t, int64(rpcbackend.RPCCodeInvalidRequest), rpcRes.Error.Code)
+
+	rpcRes, err = s.processRPC(s.ctx, &rpcbackend.RPCRequest{
+		ID:     fftypes.JSONAnyPtr("1"),
+		Method: "ffsigner_nonceStatus",
+		Params: []*fftypes.JSONAny{fftypes.JSONAnyPtr(`"wrong"`)},
+	})
+	assert.Regexp(t, "FF22011", err)
+	assert.Equal(t, int64(rpcbackend.RPCCodeInvalidRequest), rpcRes.Error.Code)
+}
+
+func TestNonceAdminUnauthorized(t *testing.T) {
+	ctx, s, done := newTestRBACServer(t)
+	defer done()
+	s.nonceManager = nonces.NewManager(nonces.NewMemoryStore(), s.transactionCount, &nonces.Config{})
+
+	req := &rpcbackend.RPCRequest{
+		ID:     fftypes.JSONAnyPtr("1"),
+		Method: "ffsigner_nonceStatus",
+		Params: []*fftypes.JSONAny{fftypes.JSONAnyPtr(`"0x497eedc4299dea2f2a364be10025d0ad0f702de3"`)},
+	}
+	rpcRes, err := s.processRPC(ctx, req)
+	assertUnauthorized(t, rpcRes, err, "FF22110")
+
+	// The address is also checked, for policies that grant the method
+	rpcRes, err = s.processNonceStatus(ctx, req)
+	assertUnauthorized(t, rpcRes, err, "FF22111")
+}
+
+func TestNonceStatusAndResync(t *testing.T) {
+	s, bm, _, done := newTestNonceAdminServer(t)
+	defer done()
+
+	// The node only has the first transaction
+	mockTxCount(bm, "latest", 10)
+	mockTxCount(bm, "pending", 11)
+	rpcRes, err := s.processRPC(s.ctx, nonceAdminRequest("ffsigner_nonceStatus"))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"address": "`+testNonceAddr+`",
+		"next": "0xd",
+		"mined": "0xa",
+		"pending": "0xb",
+		"gap": true,
+		"stalled": false
+	}`, rpcRes.Result.String())
+
+	mockTxCount(bm, "latest", 10)
+	mockTxCount(bm, "pending", 11)
+	rpcRes, err = s.processRPC(s.ctx, nonceAdminRequest("ffsigner_resyncNonce"))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"address": "`+testNonceAddr+`",
+		"next": "0xb",
+		"mined": "0xa",
+		"pending": "0xb",
+		"gap": false,
+		"stalled": false
+	}`, rpcRes.Result.String())
+
+	nonce, err := s.nonceManager.AssignNonce(s.ctx, *ethtypes.MustNewAddress(testNonceAddr))
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(11), nonce)
+	bm.AssertExpectations(t)
+}
+
+func TestNonceStatusAndResyncFail(t *testing.T) {
+	s, bm, _, done := newTestNonceAdminServer(t)
+	defer done()
+
+	bm.On("CallRPC", mock.Anything, mock.Anything, "eth_getTransactionCount", mock.Anything, "latest").Return(&rpcbackend.RPCError{Message: "pop"})
+	for _, method := range []string{"ffsigner_nonceStatus", "ffsigner_resyncNonce", "ffsigner_fillNonceGaps"} {
+		rpcRes, err := s.processRPC(s.ctx, nonceAdminRequest(method))
+		assert.Regexp(t, "pop", err)
+		assert.Equal(t, int64(rpcbackend.RPCCodeInternalError), rpcRes.Error.Code)
+	}
+}
+
+func mockGasPrice(bm *rpcbackendmocks.Backend) {
+	bm.On("CallRPC", mock.Anything, mock.Anything, "eth_gasPrice").Run(func(args mock.Arguments) {
+		*(args[1].(*ethtypes.HexInteger)) = *ethtypes.NewHexIntegerU64(1000)
+	}).Return(nil).Once()
+}
+
+func mockSignGapFill(w *ethsignermocks.Wallet, nonce int64, err error) {
+	w.On("Sign", mock.Anything, mock.MatchedBy(func(txn *ethsigner.Transaction) bool {
+		return txn.Nonce.Int64() == nonce &&
+			txn.To.String() == testNonceAddr &&
+			txn.Value.Int64() == 0 &&
+			txn.GasLimit.Int64() == gapFillGasLimit &&
+			txn.GasPrice.Int64() == 1000
+	}), int64(0)).Return([]byte{byte(nonce)}, err).Once()
+}
+
+func mockSendRawTransaction(bm *rpcbackendmocks.Backend, rpcErr *rpcbackend.RPCError) {
+	bm.On("CallRPC", mock.Anything, mock.Anything, "eth_sendRawTransaction", mock.Anything).Run(func(args mock.Arguments) {
+		*(args[1].(*ethtypes.HexBytes0xPrefix)) = ethtypes.HexBytes0xPrefix(args[3].(ethtypes.HexBytes0xPrefix))
+	}).Return(rpcErr).Once()
+}
+
+func TestFillNonceGaps(t *testing.T) {
+	s, bm, w, done := newTestNonceAdminServer(t)
+	defer done()
+	s.chainID = 0
+
+	// The node is missing 11, but already has 12 queued, which becomes pending once 11 is filled
+	mockTxCount(bm, "latest", 10)
+	mockTxCount(bm, "pending", 11)
+	mockGasPrice(bm)
+	mockSignGapFill(w, 11, nil)
+	mockSendRawTransaction(bm, nil)
+	mockTxCount(bm, "pending", 13)
+
+	rpcRes, err := s.processRPC(s.ctx, nonceAdminRequest("ffsigner_fillNonceGaps"))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[{"nonce": "0xb", "transactionHash": "0x0b"}]`, rpcRes.Result.String())
+	w.AssertExpectations(t)
+	bm.AssertExpectations(t)
+}
+
+func TestFillNonceGapsEach(t *testing.T) {
+	s, bm, w, done := newTestNonceAdminServer(t)
+	defer done()
+	s.chainID = 0
+
+	mockTxCount(bm, "latest", 10)
+	mockTxCount(bm, "pending", 11)
+	mockGasPrice(bm)
+	mockSignGapFill(w, 11, nil)
+	mockSendRawTransaction(bm, nil)
+	mockTxCount(bm, "pending", 11)
+	mockSignGapFill(w, 12, nil)
+	mockSendRawTransaction(bm, nil)
+	mockTxCount(bm, "pending", 13)
+
+	rpcRes, err := s.processRPC(s.ctx, nonceAdminRequest("ffsigner_fillNonceGaps"))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"nonce": "0xb", "transactionHash": "0x0b"},
+		{"nonce": "0xc", "transactionHash": "0x0c"}
+	]`, rpcRes.Result.String())
+	w.AssertExpectations(t)
+	bm.AssertExpectations(t)
+}
+
+func TestFillNonceGapsApproval(t *testing.T) {
+	s, bm, w, done := newTestNonceAdminServer(t)
+	defer done()
+	s.chainID = 0
+	hook := &testApprovalHook{review: func(req *approval.Request) (*approval.Decision, error) {
+		return approval.Rejected("not today"), nil
+	}}
+	s.SetApprovalHook(hook)
+
+	mockTxCount(bm, "latest", 10)
+	mockTxCount(bm, "pending", 11)
+	mockGasPrice(bm)
+
+	_, err := s.processRPC(s.ctx, nonceAdminRequest("ffsigner_fillNonceGaps"))
+	assert.Regexp(t, "FF22283.*not today", err)
+	assert.Len(t, hook.requests, 1)
+	assert.Equal(t, "ffsigner_fillNonceGaps", hook.requests[0].Method)
+	assert.Equal(t, int64(11), hook.requests[0].Transaction.Nonce.Int64())
+	w.AssertNotCalled(t, "Sign", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestFillNonceGapsChainRouted(t *testing.T) {
+	s, bm, w, done := newTestNonceAdminServer(t)
+	defer done()
+	ctx := withChainRoute(s.ctx, &chainRoute{name: "other", chainID: 2222, backend: bm})
+
+	// The filler transaction is signed for the chain the request is routed to
+	mockTxCount(bm, "latest", 10)
+	mockTxCount(bm, "pending", 12)
+	mockGasPrice(bm)
+	w.On("Sign", mock.Anything, mock.MatchedBy(func(txn *ethsigner.Transaction) bool {
+		return txn.Nonce.Int64() == 12
+	}), int64(2222)).Return([]byte{12}, nil).Once()
+	mockSendRawTransaction(bm, nil)
+	mockTxCount(bm, "pending", 13)
+
+	rpcRes, err := s.processRPC(ctx, nonceAdminRequest("ffsigner_fillNonceGaps"))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[{"nonce": "0xc", "transactionHash": "0x0c"}]`, rpcRes.Result.String())
+	w.AssertExpectations(t)
+	bm.AssertExpectations(t)
+}
+
+func TestNonceAdminRequiresRBAC(t *testing.T) {
+	s, _, _, done := newTestNonceAdminServer(t)
+	defer done()
+	s.authorizer.Store(nil)
+
+	for _, method := range []string{"ffsigner_resyncNonce", "ffsigner_fillNonceGaps"} {
+		rpcRes, err := s.processRPC(s.ctx, nonceAdminRequest(method))
+		assertUnauthorized(t, rpcRes, err, "FF22326.*"+method)
+	}
+}
+
+func TestFillNonceGapsNoGap(t *testing.T) {
+	s, bm, _, done := newTestNonceAdminServer(t)
+	defer done()
+
+	mockTxCount(bm, "latest", 10)
+	mockTxCount(bm, "pending", 13)
+	rpcRes, err := s.processRPC(s.ctx, nonceAdminRequest("ffsigner_fillNonceGaps"))
+	assert.NoError(t, err)
+	assert.Equal(t, `[]`, rpcRes.Result.String())
+	bm.AssertExpectations(t)
+}
+
+func TestFillNonceGapsFail(t *testing.T) {
+	s, bm, w, done := newTestNonceAdminServer(t)
+	defer done()
+	s.chainID = 0
+
+	mockGap := func() {
+		mockTxCount(bm, "latest", 10)
+		mockTxCount(bm, "pending", 11)
+	}
+
+	mockGap()
+	bm.On("CallRPC", mock.Anything, mock.Anything, "eth_gasPrice").Return(&rpcbackend.RPCError{Message: "pop"}).Once()
+	_, err := s.processRPC(s.ctx, nonceAdminRequest("ffsigner_fillNonceGaps"))
+	assert.Regexp(t, "pop", err)
+
+	mockGap()
+	mockGasPrice(bm)
+	mockSignGapFill(w, 11, fmt.Errorf("pop"))
+	_, err = s.processRPC(s.ctx, nonceAdminRequest("ffsigner_fillNonceGaps"))
+	assert.Regexp(t, "FF22127.*11.*pop", err)
+
+	mockGap()
+	mockGasPrice(bm)
+	mockSignGapFill(w, 11, nil)
+	mockSendRawTransaction(bm, &rpcbackend.RPCError{Message: "nonce too low"})
+	_, err = s.processRPC(s.ctx, nonceAdminRequest("ffsigner_fillNonceGaps"))
+	assert.Regexp(t, "FF22127.*nonce too low", err)
+
+	mockGap()
+	mockGasPrice(bm)
+	mockSignGapFill(w, 11, nil)
+	mockSendRawTransaction(bm, nil)
+	bm.On("CallRPC", mock.Anything, mock.Anything, "eth_getTransactionCount", mock.Anything, "pending").Return(&rpcbackend.RPCError{Message: "pop"}).Once()
+	rpcRes, err := s.processRPC(s.ctx, nonceAdminRequest("ffsigner_fillNonceGaps"))
+	assert.Regexp(t, "pop", err)
+	assert.Equal(t, int64(rpcbackend.RPCCodeInternalError), rpcRes.Error.Code)
+
+	w.AssertExpectations(t)
+	bm.AssertExpectations(t)
+}
+
+func TestFillNonceGapsMetrics(t *testing.T) {
+	_, metricsURL, s, bm, done := newTestMetricsServer(t, &ethsignermocks.Wallet{})
+	defer done()
+	startTestServerNoBackend(t, s)
+	s.chainID = 0
+	grantTestOperator(t, s)
+	w := s.wallet.(*ethsignermocks.Wallet)
+
+	mockTxCount(bm, "pending", 10)
+	s.nonceManager = nonces.NewManager(nonces.NewMemoryStore(), s.transactionCount, &nonces.Config{ReconcileInterval: time.Hour})
+	_, err := s.nonceManager.AssignNonce(s.ctx, *ethtypes.MustNewAddress(testNonceAddr))
+	assert.NoError(t, err)
+
+	mockTxCount(bm, "latest", 10)
+	mockTxCount(bm, "pending", 10)
+	mockGasPrice(bm)
+	mockSignGapFill(w, 10, fmt.Errorf("pop"))
+	_, err = s.processRPC(s.ctx, nonceAdminRequest("ffsigner_fillNonceGaps"))
+	assert.Regexp(t, "FF22127", err)
+	assert.Contains(t, scrapeTestMetrics(t, metricsURL), `ff_signer_wallet_sign_operations_total{ff_component="ffsigner",outcome="error",wallet="ethsignermocks"} 1`)
+}
+
+func TestFillNonceGapsRateLimited(t *testing.T) {
+	s, _, _, done := newTestNonceAdminServer(t)
+	defer done()
+	setTestRateLimitConf()
+	s.rateLimiter = newRateLimiter()
+
+	rpcRes, err := s.processRPC(withRateLimitKey(s.ctx, "identity:tenantA"), nonceAdminRequest("ffsigner_fillNonceGaps"))
+	assert.Regexp(t, "FF22113", err)
+	assert.Equal(t, int64(rpcbackend.RPCCodeLimitExceeded), rpcRes.Error.Code)
+}
+
+func TestNonceMonitorStartStop(t *testing.T) {
+	_, s, done := newTestServer(t, func() {
+		signerconfig.NoncesConfig.Set(nonces.ConfigEnabled, true)
+		signerconfig.NoncesConfig.Set(nonces.ConfigMonitorInterval, "1ms")
+	})
+	startTestServerNoBackend(t, s)
+	assert.NotNil(t, s.nonceMonitorDone)
+	done()
+}
@@ -0,0 +1,35 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"context"
+
+	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/hyperledger/firefly-signer/internal/signerconfig"
+	"github.com/hyperledger/firefly-signer/pkg/persistence"
+)
+
+// initPersistence uses the store of an embedding application if it provides one, otherwise a directory if one
+// is configured. Without either there is no shared persistence, and each component falls back to its own.
+func (s *rpcServer) initPersistence(ctx context.Context, store persistence.Store) (err error) {
+	s.persistence = store
+	if path := config.GetString(signerconfig.PersistencePath); s.persistence == nil && path != "" {
+		s.persistence, err = persistence.NewFileStore(ctx, path)
+	}
+	return err
+}
@@ -0,0 +1,75 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/hyperledger/firefly-signer/internal/signerconfig"
+	"github.com/hyperledger/firefly-signer/mocks/ethsignermocks"
+	"github.com/hyperledger/firefly-signer/mocks/rpcbackendmocks"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/nonces"
+	"github.com/hyperledger/firefly-signer/pkg/persistence"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInitPersistencePath(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "state")
+	_, s, done := newTestServer(t, func() {
+		config.Set(signerconfig.PersistencePath, dir)
+		signerconfig.NoncesConfig.Set(nonces.ConfigEnabled, true)
+	})
+	defer done()
+	assert.NotNil(t, s.persistence)
+	assert.DirExists(t, dir)
+
+	// Nonces are persisted in the shared persistence
+	bm := &rpcbackendmocks.Backend{}
+	s.backend = bm
+	mockTxCount(bm, "pending", 5)
+	nonce, err := s.nonceManager.AssignNonce(context.Background(), *ethtypes.MustNewAddress(testServiceAddr))
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(5), nonce)
+	b, err := os.ReadFile(filepath.Join(dir, nonces.PersistenceCollection, ethtypes.MustNewAddress(testServiceAddr).String()))
+	assert.NoError(t, err)
+	assert.Equal(t, "6", string(b))
+}
+
+func TestInitPersistenceEmbedded(t *testing.T) {
+	signerconfig.Reset()
+	config.Set(signerconfig.PersistencePath, filepath.Join(t.TempDir(), "ignored"))
+	store := persistence.NewMemoryStore()
+	ss, err := NewService(context.Background(), &ethsignermocks.Wallet{}, store)
+	assert.NoError(t, err)
+	assert.Equal(t, store, ss.(*rpcServer).persistence)
+}
+
+func TestInitPersistenceBadPath(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "file")
+	err := os.WriteFile(file, []byte{}, 0600)
+	assert.NoError(t, err)
+
+	signerconfig.Reset()
+	config.Set(signerconfig.PersistencePath, file)
+	_, err = NewServer(context.Background(), &ethsignermocks.Wallet{})
+	assert.Regexp(t, "FF22299", err)
+}
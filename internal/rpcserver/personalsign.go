@@ -0,0 +1,82 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/hyperledger/firefly-signer/pkg/approval"
+	"github.com/hyperledger/firefly-signer/pkg/ethsigner"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+)
+
+// processPersonalSign follows the personal_sign signature of hex encoded message, then address.
+// It is never passed to the backend, even when disabled, as the backend does not hold our keys.
+func (s *rpcServer) processPersonalSign(ctx context.Context, rpcReq *rpcbackend.RPCRequest) (*rpcbackend.RPCResponse, error) {
+	if !s.personalSignEnabled {
+		err := i18n.NewError(ctx, signermsgs.MsgPersonalSignDisabled)
+		return rpcbackend.RPCErrorResponse(err, rpcReq.ID, rpcbackend.RPCCodeMethodNotFound), err
+	}
+	w, ok := s.wallet.(ethsigner.WalletPersonalSign)
+	if !ok {
+		err := i18n.NewError(ctx, signermsgs.MsgPersonalSignNotSupported)
+		return rpcbackend.RPCErrorResponse(err, rpcReq.ID, rpcbackend.RPCCodeInvalidRequest), err
+	}
+	if len(rpcReq.Params) < 2 {
+		err := i18n.NewError(ctx, signermsgs.MsgInvalidParamCount, 2, len(rpcReq.Params))
+		return rpcbackend.RPCErrorResponse(err, rpcReq.ID, rpcbackend.RPCCodeInvalidRequest), err
+	}
+
+	var message ethtypes.HexBytes0xPrefix
+	if errRes, err := s.unmarshalParam(ctx, rpcReq, 0, &message); err != nil {
+		return errRes, err
+	}
+	var addr ethtypes.Address0xHex
+	if errRes, err := s.unmarshalParam(ctx, rpcReq, 1, &addr); err != nil {
+		return errRes, err
+	}
+	setSpanFrom(ctx, rpcReq.Params[1].Bytes())
+	req := &signingRequest{method: rpcReq.Method, operation: signOpPersonalMessage, from: &addr}
+	if errRes, err := s.authorizeAddress(ctx, rpcReq, &addr); err != nil {
+		return s.rejectedByPolicy(ctx, req, errRes, err)
+	}
+	if errRes, err := s.checkRateLimit(ctx, rpcReq, rateLimitSigning); err != nil {
+		return s.rejectedByPolicy(ctx, req, errRes, err)
+	}
+	if errRes, err := s.approveSigning(ctx, rpcReq, req, &approval.Request{Message: message}); err != nil {
+		return errRes, err
+	}
+
+	startTime := time.Now()
+	sig, err := w.SignPersonalMessage(ctx, addr, message)
+	s.signOperation(ctx, req, startTime, nil, err)
+	if err != nil {
+		return rpcbackend.RPCErrorResponse(err, rpcReq.ID, rpcbackend.RPCCodeInternalError), err
+	}
+	b, _ := json.Marshal(sig)
+	return &rpcbackend.RPCResponse{
+		JSONRpc: "2.0",
+		ID:      rpcReq.ID,
+		Result:  fftypes.JSONAnyPtrBytes(b),
+	}, nil
+}
@@ -0,0 +1,150 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-signer/internal/signerconfig"
+	"github.com/hyperledger/firefly-signer/mocks/ethsignermocks"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func personalSignTestRequest() *rpcbackend.RPCRequest {
+	return &rpcbackend.RPCRequest{
+		ID:     fftypes.JSONAnyPtr("1"),
+		Method: "personal_sign",
+		Params: []*fftypes.JSONAny{
+			fftypes.JSONAnyPtr(`"0x48656c6c6f20576f726c64"`),
+			fftypes.JSONAnyPtr(`"0xfb075bb99f2aa4c49955bf703509a227d7a12248"`),
+		},
+	}
+}
+
+func TestPersonalSignOK(t *testing.T) {
+
+	_, s, done := newTestServer(t)
+	defer done()
+
+	w := &ethsignermocks.WalletPersonalSign{}
+	s.wallet = w
+	w.On("SignPersonalMessage", mock.Anything, *ethtypes.MustNewAddress("0xfb075bb99f2aa4c49955bf703509a227d7a12248"), []byte("Hello World")).
+		Return(ethtypes.MustNewHexBytes0xPrefix("0xaabbcc"), nil)
+
+	rpcRes, err := s.processRPC(s.ctx, personalSignTestRequest())
+	assert.NoError(t, err)
+	assert.Equal(t, `"0xaabbcc"`, rpcRes.Result.String())
+	w.AssertExpectations(t)
+
+}
+
+func TestPersonalSignFail(t *testing.T) {
+
+	_, metricsURL, s, _, done := newTestMetricsServer(t, &ethsignermocks.Wallet{})
+	defer done()
+	startTestServerNoBackend(t, s)
+
+	w := &ethsignermocks.WalletPersonalSign{}
+	s.wallet = w
+	w.On("SignPersonalMessage", mock.Anything, mock.Anything, mock.Anything).Return(nil, fmt.Errorf("pop"))
+
+	rpcRes, err := s.processRPC(s.ctx, personalSignTestRequest())
+	assert.Regexp(t, "pop", err)
+	assert.Equal(t, int64(rpcbackend.RPCCodeInternalError), rpcRes.Error.Code)
+	assert.Contains(t, scrapeTestMetrics(t, metricsURL), `ff_signer_wallet_sign_operations_total{ff_component="ffsigner",outcome="error",wallet="ethsignermocks"} 1`)
+
+}
+
+func TestPersonalSignBadParams(t *testing.T) {
+
+	_, s, done := newTestServer(t)
+	defer done()
+
+	s.wallet = &ethsignermocks.WalletPersonalSign{}
+
+	req := personalSignTestRequest()
+	req.Params = req.Params[0:1]
+	_, err := s.processRPC(s.ctx, req)
+	assert.Regexp(t, "FF22019", err)
+
+	req = personalSignTestRequest()
+	req.Params[0] = fftypes.JSONAnyPtr(`"not hex"`)
+	_, err = s.processRPC(s.ctx, req)
+	assert.Regexp(t, "FF22011", err)
+
+	req = personalSignTestRequest()
+	req.Params[1] = fftypes.JSONAnyPtr(`"bad address"`)
+	_, err = s.processRPC(s.ctx, req)
+	assert.Regexp(t, "FF22011", err)
+
+}
+
+func TestPersonalSignNotSupported(t *testing.T) {
+
+	_, s, done := newTestServer(t)
+	defer done()
+
+	_, err := s.processRPC(s.ctx, personalSignTestRequest())
+	assert.Regexp(t, "FF22115", err)
+
+}
+
+func TestPersonalSignDisabled(t *testing.T) {
+
+	_, s, done := newTestServer(t, func() {
+		config.Set(signerconfig.PersonalSignEnabled, false)
+	})
+	defer done()
+
+	// Neither the wallet nor the backend are called
+	s.wallet = &ethsignermocks.WalletPersonalSign{}
+	rpcRes, err := s.processRPC(s.ctx, personalSignTestRequest())
+	assert.Regexp(t, "FF22114", err)
+	assert.Equal(t, int64(rpcbackend.RPCCodeMethodNotFound), rpcRes.Error.Code)
+
+}
+
+func TestPersonalSignRBAC(t *testing.T) {
+
+	ctx, s, done := newTestRBACServer(t)
+	defer done()
+
+	s.wallet = &ethsignermocks.WalletPersonalSign{}
+	req := personalSignTestRequest()
+	req.Params[1] = fftypes.JSONAnyPtr(`"0x3c99f2a4b366d46bcf2277639a135a6d1288eceb"`)
+	rpcRes, err := s.processRPC(ctx, req)
+	assertUnauthorized(t, rpcRes, err, "FF22111")
+
+}
+
+func TestPersonalSignRateLimit(t *testing.T) {
+
+	_, s, done := newTestServer(t, setTestRateLimitConf)
+	defer done()
+
+	s.wallet = &ethsignermocks.WalletPersonalSign{}
+	rpcRes, err := s.processRPC(withRateLimitKey(s.ctx, "identity:tenantA"), personalSignTestRequest())
+	assert.Regexp(t, "FF22113.*signing", err)
+	assert.Equal(t, int64(rpcbackend.RPCCodeLimitExceeded), rpcRes.Error.Code)
+
+}
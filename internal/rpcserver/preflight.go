@@ -0,0 +1,91 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-common/pkg/log"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/hyperledger/firefly-signer/pkg/abi"
+	"github.com/hyperledger/firefly-signer/pkg/ethsigner"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+)
+
+// The Error(string) revert reason is always decoded, and we add the Panic(uint256) raised by Solidity for assertion failures
+var panicErrorABI = abi.ABI{
+	{Type: abi.Error, Name: "Panic", Inputs: abi.ParameterArray{{Name: "code", Type: "uint256"}}},
+}
+
+// callObject is the transaction call object of eth_call and eth_estimateGas
+type callObject struct {
+	From  json.RawMessage           `json:"from,omitempty"`
+	To    *ethtypes.Address0xHex    `json:"to,omitempty"`
+	Value *ethtypes.HexInteger      `json:"value,omitempty"`
+	Data  ethtypes.HexBytes0xPrefix `json:"data,omitempty"`
+}
+
+// preflightTransaction simulates the transaction with eth_call before it is signed, so that a transaction
+// that would revert is rejected without being submitted (and spending gas). The backend's error code and
+// data are returned to the caller, along with the decoded revert reason.
+func (s *rpcServer) preflightTransaction(ctx context.Context, rpcReq *rpcbackend.RPCRequest, txn *ethsigner.Transaction) (*rpcbackend.RPCResponse, error) {
+	var result ethtypes.HexBytes0xPrefix
+	rpcErr := s.backend.CallRPC(ctx, &result, "eth_call", newCallObject(txn), s.preflightBlockTag)
+	if rpcErr == nil {
+		return nil, nil
+	}
+	reason := revertReason(ctx, rpcErr)
+	log.L(ctx).Warnf("Pre-flight simulation of transaction failed: %s", reason)
+	err := i18n.NewError(ctx, signermsgs.MsgPreflightFailed, reason)
+	return backendErrorResponse(rpcReq, rpcErr, err), err
+}
+
+func newCallObject(txn *ethsigner.Transaction) *callObject {
+	return &callObject{
+		From:  txn.From,
+		To:    txn.To,
+		Value: txn.Value,
+		Data:  txn.Data,
+	}
+}
+
+// backendErrorResponse returns our error to the caller, with the code and data of the error from the backend
+func backendErrorResponse(rpcReq *rpcbackend.RPCRequest, rpcErr *rpcbackend.RPCError, err error) *rpcbackend.RPCResponse {
+	return &rpcbackend.RPCResponse{
+		JSONRpc: "2.0",
+		ID:      rpcReq.ID,
+		Error: &rpcbackend.RPCError{
+			Code:    rpcErr.Code,
+			Message: err.Error(),
+			Data:    rpcErr.Data,
+		},
+	}
+}
+
+// revertReason decodes the revert data in the error from the backend, falling back to the error message
+func revertReason(ctx context.Context, rpcErr *rpcbackend.RPCError) string {
+	var revertData ethtypes.HexBytes0xPrefix
+	if err := json.Unmarshal(rpcErr.Data.Bytes(), &revertData); err == nil && len(revertData) > 0 {
+		if reason, ok := panicErrorABI.ErrorStringCtx(ctx, revertData); ok {
+			return reason
+		}
+	}
+	return rpcErr.Message
+}
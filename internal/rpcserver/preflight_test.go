@@ -0,0 +1,150 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-signer/internal/signerconfig"
+	"github.com/hyperledger/firefly-signer/mocks/rpcbackendmocks"
+	"github.com/hyperledger/firefly-signer/pkg/abi"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func setTestPreflightConf() {
+	config.Set(signerconfig.PreflightEnabled, true)
+}
+
+func preflightTestRequest() *rpcbackend.RPCRequest {
+	return &rpcbackend.RPCRequest{
+		ID:     fftypes.JSONAnyPtr("1"),
+		Method: "eth_sendTransaction",
+		Params: []*fftypes.JSONAny{fftypes.JSONAnyPtr(`{
+			"from": "0xfb075bb99f2aa4c49955bf703509a227d7a12248",
+			"to": "0x3c99f2a4b366d46bcf2277639a135a6d1288eceb",
+			"value": "0x64",
+			"data": "0xfeedbeef",
+			"gas": "0x5208"
+		}`)},
+	}
+}
+
+func testRevertData(t *testing.T, e *abi.Entry, values ...interface{}) *fftypes.JSONAny {
+	revertData, err := e.EncodeCallDataValues(values)
+	assert.NoError(t, err)
+	return fftypes.JSONAnyPtr(fmt.Sprintf(`"%s"`, ethtypes.HexBytes0xPrefix(revertData)))
+}
+
+func TestPreflightOK(t *testing.T) {
+
+	_, s, done := newTestServer(t, setTestPreflightConf)
+	defer done()
+
+	bm := s.backend.(*rpcbackendmocks.Backend)
+	bm.On("CallRPC", mock.Anything, mock.Anything, "eth_call", mock.MatchedBy(func(call *callObject) bool {
+		return string(call.From) == `"0xfb075bb99f2aa4c49955bf703509a227d7a12248"` &&
+			call.To.String() == "0x3c99f2a4b366d46bcf2277639a135a6d1288eceb" &&
+			call.Value.Int64() == 100 &&
+			call.Data.String() == "0xfeedbeef"
+	}), "pending").Return(nil)
+	// Having passed pre-flight, we continue to the nonce query
+	bm.On("CallRPC", mock.Anything, mock.Anything, "eth_getTransactionCount", mock.Anything, "pending").Return(&rpcbackend.RPCError{Message: "pop"})
+
+	_, err := s.processRPC(s.ctx, preflightTestRequest())
+	assert.Regexp(t, "pop", err)
+	bm.AssertExpectations(t)
+
+}
+
+func TestPreflightRevertReason(t *testing.T) {
+
+	_, s, done := newTestServer(t, setTestPreflightConf)
+	defer done()
+
+	errorEntry := &abi.Entry{Type: abi.Error, Name: "Error", Inputs: abi.ParameterArray{{Name: "reason", Type: "string"}}}
+	revertData := testRevertData(t, errorEntry, "Not enough funds")
+	bm := s.backend.(*rpcbackendmocks.Backend)
+	bm.On("CallRPC", mock.Anything, mock.Anything, "eth_call", mock.Anything, "pending").Return(&rpcbackend.RPCError{
+		Code:    3,
+		Message: "execution reverted: Not enough funds",
+		Data:    *revertData,
+	})
+
+	rpcRes, err := s.processRPC(s.ctx, preflightTestRequest())
+	assert.Regexp(t, `FF22122.*Error\("Not enough funds"\)`, err)
+	assert.Equal(t, int64(3), rpcRes.Error.Code)
+	assert.Equal(t, revertData.String(), rpcRes.Error.Data.String())
+	bm.AssertExpectations(t)
+
+}
+
+func TestPreflightPanic(t *testing.T) {
+
+	_, s, done := newTestServer(t, setTestPreflightConf)
+	defer done()
+
+	bm := s.backend.(*rpcbackendmocks.Backend)
+	bm.On("CallRPC", mock.Anything, mock.Anything, "eth_call", mock.Anything, "pending").Return(&rpcbackend.RPCError{
+		Code:    3,
+		Message: "execution reverted",
+		Data:    *testRevertData(t, panicErrorABI[0], 0x11),
+	})
+
+	_, err := s.processRPC(s.ctx, preflightTestRequest())
+	assert.Regexp(t, `FF22122.*Panic\("17"\)`, err)
+
+}
+
+func TestPreflightNoRevertData(t *testing.T) {
+
+	_, s, done := newTestServer(t, setTestPreflightConf)
+	defer done()
+
+	bm := s.backend.(*rpcbackendmocks.Backend)
+	bm.On("CallRPC", mock.Anything, mock.Anything, "eth_call", mock.Anything, "pending").Return(&rpcbackend.RPCError{
+		Code:    -32000,
+		Message: "insufficient funds for transfer",
+	})
+
+	rpcRes, err := s.processRPC(s.ctx, preflightTestRequest())
+	assert.Regexp(t, "FF22122.*insufficient funds for transfer", err)
+	assert.Equal(t, int64(-32000), rpcRes.Error.Code)
+
+}
+
+func TestPreflightUnknownRevertData(t *testing.T) {
+
+	_, s, done := newTestServer(t, setTestPreflightConf)
+	defer done()
+
+	bm := s.backend.(*rpcbackendmocks.Backend)
+	bm.On("CallRPC", mock.Anything, mock.Anything, "eth_call", mock.Anything, "pending").Return(&rpcbackend.RPCError{
+		Code:    3,
+		Message: "execution reverted",
+		Data:    *fftypes.JSONAnyPtr(`"0x12345678"`),
+	})
+
+	_, err := s.processRPC(s.ctx, preflightTestRequest())
+	assert.Regexp(t, "FF22122.*execution reverted", err)
+
+}
@@ -0,0 +1,56 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+)
+
+// previewedTransaction is the result of ffsigner_previewTransaction
+type previewedTransaction struct {
+	filledTransaction
+	SigningHash ethtypes.HexBytes0xPrefix `json:"signingHash"` // the keccak256 hash of the payload, that the key signs
+}
+
+// processPreviewTransaction fills a transaction as eth_fillTransaction does, and adds the hash that is signed,
+// so an external approval system can register the exact transaction it expects before it is sent with
+// eth_sendTransaction - with every field, including the nonce, as returned.
+//
+// Nothing is signed, so the request needs no approval and does not count against the signing rate limit.
+// The hash the transaction will have on chain is the hash of it signed, so is only known once it is sent.
+func (s *rpcServer) processPreviewTransaction(ctx context.Context, rpcReq *rpcbackend.RPCRequest) (*rpcbackend.RPCResponse, error) {
+	_, _, filled, errRes, err := s.fillTransaction(ctx, rpcReq)
+	if err != nil {
+		return errRes, err
+	}
+	preview := &previewedTransaction{
+		filledTransaction: *filled,
+		SigningHash:       hashTransaction(filled.Raw),
+	}
+
+	b, _ := json.Marshal(preview)
+	return &rpcbackend.RPCResponse{
+		JSONRpc: "2.0",
+		ID:      rpcReq.ID,
+		Result:  fftypes.JSONAnyPtrBytes(b),
+	}, nil
+}
@@ -0,0 +1,89 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-signer/mocks/ethsignermocks"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+	"github.com/hyperledger/firefly-signer/pkg/secp256k1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/sha3"
+)
+
+func previewTestRequest(txnJSON string) *rpcbackend.RPCRequest {
+	return &rpcbackend.RPCRequest{
+		ID:     fftypes.JSONAnyPtr("1"),
+		Method: "ffsigner_previewTransaction",
+		Params: []*fftypes.JSONAny{fftypes.JSONAnyPtr(txnJSON)},
+	}
+}
+
+func keccak256(b []byte) ethtypes.HexBytes0xPrefix {
+	hash := sha3.NewLegacyKeccak256()
+	hash.Write(b)
+	return hash.Sum(nil)
+}
+
+func TestPreviewTransactionHash(t *testing.T) {
+	s, bm, done := newTestFillServer(t)
+	defer done()
+	kp, err := secp256k1.GenerateSecp256k1KeyPair()
+	assert.NoError(t, err)
+	bm.On("CallRPC", mock.Anything, mock.Anything, "eth_getTransactionCount", mock.Anything, "pending").Run(func(args mock.Arguments) {
+		*(args[1].(**ethtypes.HexInteger)) = ethtypes.NewHexIntegerU64(10)
+	}).Return(nil)
+
+	rpcRes, err := s.processRPC(s.ctx, previewTestRequest(fmt.Sprintf(`{
+		"from": "%s",
+		"to": "0x497eedc4299dea2f2a364be10025d0ad0f702de3",
+		"gas": "0x5208",
+		"maxFeePerGas": "0x77359400",
+		"maxPriorityFeePerGas": "0x3b9aca00"
+	}`, kp.Address)))
+	assert.NoError(t, err)
+
+	var preview previewedTransaction
+	err = json.Unmarshal(rpcRes.Result.Bytes(), &preview)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(10), preview.Tx.Nonce.Int64())
+	assert.Equal(t, ethtypes.HexUint64(2), preview.Tx.Type)
+	assert.Equal(t, ethtypes.HexBytes0xPrefix(preview.Tx.SignaturePayloadEIP1559(12345).Bytes()), preview.Raw)
+	assert.Equal(t, keccak256(preview.Raw), preview.SigningHash)
+
+	// Nothing is signed for a preview
+	s.wallet.(*ethsignermocks.Wallet).AssertNotCalled(t, "Sign", mock.Anything, mock.Anything, mock.Anything)
+	var raw map[string]interface{}
+	err = json.Unmarshal(rpcRes.Result.Bytes(), &raw)
+	assert.NoError(t, err)
+	assert.NotContains(t, raw, "hash")
+}
+
+func TestPreviewTransactionFail(t *testing.T) {
+	s, _, done := newTestFillServer(t)
+	defer done()
+
+	rpcRes, err := s.processRPC(s.ctx, &rpcbackend.RPCRequest{ID: fftypes.JSONAnyPtr("1"), Method: "ffsigner_previewTransaction"})
+	assert.Regexp(t, "FF22019", err)
+	assert.Equal(t, int64(rpcbackend.RPCCodeInvalidRequest), rpcRes.Error.Code)
+}
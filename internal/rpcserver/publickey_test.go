@@ -0,0 +1,116 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-signer/mocks/ethsignermocks"
+	"github.com/hyperledger/firefly-signer/pkg/ethsigner"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestGetPublicKeyOK(t *testing.T) {
+
+	_, s, done := newTestServer(t)
+	defer done()
+
+	w := &ethsignermocks.WalletPublicKeys{}
+	s.wallet = w
+	w.On("GetPublicKey", mock.Anything, *ethtypes.MustNewAddress("0xfb075bb99f2aa4c49955bf703509a227d7a12248")).Return(&ethsigner.PublicKeyResult{
+		Address:      *ethtypes.MustNewAddress("0xfb075bb99f2aa4c49955bf703509a227d7a12248"),
+		Compressed:   ethtypes.MustNewHexBytes0xPrefix("0x02aabb"),
+		Uncompressed: ethtypes.MustNewHexBytes0xPrefix("0x04aabbcc"),
+	}, nil)
+
+	rpcRes, err := s.processRPC(s.ctx, &rpcbackend.RPCRequest{
+		ID:     fftypes.JSONAnyPtr("1"),
+		Method: "ffsigner_getPublicKey",
+		Params: []*fftypes.JSONAny{
+			fftypes.JSONAnyPtr(`"0xfb075bb99f2aa4c49955bf703509a227d7a12248"`),
+		},
+	})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"address": "0xfb075bb99f2aa4c49955bf703509a227d7a12248",
+		"compressed": "0x02aabb",
+		"uncompressed": "0x04aabbcc"
+	}`, rpcRes.Result.String())
+
+}
+
+func TestGetPublicKeyFail(t *testing.T) {
+
+	_, s, done := newTestServer(t)
+	defer done()
+
+	w := &ethsignermocks.WalletPublicKeys{}
+	s.wallet = w
+	w.On("GetPublicKey", mock.Anything, mock.Anything).Return(nil, fmt.Errorf("pop"))
+
+	_, err := s.processRPC(s.ctx, &rpcbackend.RPCRequest{
+		ID:     fftypes.JSONAnyPtr("1"),
+		Method: "ffsigner_getPublicKey",
+		Params: []*fftypes.JSONAny{
+			fftypes.JSONAnyPtr(`"0xfb075bb99f2aa4c49955bf703509a227d7a12248"`),
+		},
+	})
+	assert.Regexp(t, "pop", err)
+
+}
+
+func TestGetPublicKeyBadParams(t *testing.T) {
+
+	_, s, done := newTestServer(t)
+	defer done()
+
+	s.wallet = &ethsignermocks.WalletPublicKeys{}
+
+	_, err := s.processRPC(s.ctx, &rpcbackend.RPCRequest{
+		ID:     fftypes.JSONAnyPtr("1"),
+		Method: "ffsigner_getPublicKey",
+	})
+	assert.Regexp(t, "FF22019", err)
+
+	_, err = s.processRPC(s.ctx, &rpcbackend.RPCRequest{
+		ID:     fftypes.JSONAnyPtr("1"),
+		Method: "ffsigner_getPublicKey",
+		Params: []*fftypes.JSONAny{
+			fftypes.JSONAnyPtr(`"bad address"`),
+		},
+	})
+	assert.Regexp(t, "FF22011", err)
+
+}
+
+func TestGetPublicKeyNotSupported(t *testing.T) {
+
+	_, s, done := newTestServer(t)
+	defer done()
+
+	_, err := s.processRPC(s.ctx, &rpcbackend.RPCRequest{
+		ID:     fftypes.JSONAnyPtr("1"),
+		Method: "ffsigner_getPublicKey",
+	})
+	assert.Regexp(t, "FF22096", err)
+
+}
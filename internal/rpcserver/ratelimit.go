@@ -0,0 +1,161 @@
+// Copyright © 2023 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-common/pkg/log"
+	"github.com/hyperledger/firefly-signer/internal/signerconfig"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/hyperledger/firefly-signer/pkg/rpcauth"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+	"golang.org/x/time/rate"
+)
+
+const (
+	rateLimitRequests = "requests"
+	rateLimitSigning  = "signing"
+)
+
+// rateLimiter holds a token bucket for requests, and one for signing operations, for each client
+type rateLimiter struct {
+	requestsLimit rate.Limit
+	requestsBurst int
+	signingLimit  rate.Limit
+	signingBurst  int
+	idleTimeout   time.Duration
+
+	mux       sync.Mutex
+	clients   map[string]*clientLimiters
+	lastSweep time.Time
+}
+
+type clientLimiters struct {
+	requests *rate.Limiter
+	signing  *rate.Limiter
+	lastUsed time.Time
+}
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{
+		requestsLimit: rateLimit(config.GetFloat64(signerconfig.RateLimitRequestsPerSecond)),
+		requestsBurst: config.GetInt(signerconfig.RateLimitRequestsBurst),
+		signingLimit:  rateLimit(config.GetFloat64(signerconfig.RateLimitSigningPerSecond)),
+		signingBurst:  config.GetInt(signerconfig.RateLimitSigningBurst),
+		idleTimeout:   config.GetDuration(signerconfig.RateLimitIdleTimeout),
+		clients:       make(map[string]*clientLimiters),
+		lastSweep:     time.Now(),
+	}
+}
+
+func rateLimit(perSecond float64) rate.Limit {
+	if perSecond <= 0 {
+		return rate.Inf
+	}
+	return rate.Limit(perSecond)
+}
+
+func (rl *rateLimiter) limiters(key string) *clientLimiters {
+	rl.mux.Lock()
+	defer rl.mux.Unlock()
+
+	now := time.Now()
+	if now.Sub(rl.lastSweep) > rl.idleTimeout {
+		// Clients identified by source IP come and go, so we discard the state of idle clients
+		for k, cl := range rl.clients {
+			if now.Sub(cl.lastUsed) > rl.idleTimeout {
+				delete(rl.clients, k)
+			}
+		}
+		rl.lastSweep = now
+	}
+
+	cl := rl.clients[key]
+	if cl == nil {
+		cl = &clientLimiters{
+			requests: rate.NewLimiter(rl.requestsLimit, rl.requestsBurst),
+			signing:  rate.NewLimiter(rl.signingLimit, rl.signingBurst),
+		}
+		rl.clients[key] = cl
+	}
+	cl.lastUsed = now
+	return cl
+}
+
+func (rl *rateLimiter) allow(key, limit string) bool {
+	cl := rl.limiters(key)
+	if limit == rateLimitSigning {
+		return cl.signing.Allow()
+	}
+	return cl.requests.Allow()
+}
+
+type rateLimitKeyContextKey struct{}
+
+func withRateLimitKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, rateLimitKeyContextKey{}, key)
+}
+
+func getRateLimitKey(ctx context.Context) string {
+	key, _ := ctx.Value(rateLimitKeyContextKey{}).(string)
+	return key
+}
+
+// rateLimitKeyed records the key the caller is rate limited by in the request context, which is
+// the authenticated identity when there is one, and otherwise the source IP address
+func (s *rpcServer) rateLimitKeyed(handler http.HandlerFunc) http.HandlerFunc {
+	if s.rateLimiter == nil {
+		return handler
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		var key string
+		if identity := rpcauth.GetIdentity(r.Context()); identity != nil {
+			key = "identity:" + identity.ID
+		} else {
+			host, _, err := net.SplitHostPort(r.RemoteAddr)
+			if err != nil {
+				host = r.RemoteAddr
+			}
+			key = "ip:" + host
+		}
+		handler(w, r.WithContext(withRateLimitKey(r.Context(), key)))
+	}
+}
+
+// checkRateLimit consumes a token from the caller's bucket for the limit, when rate limiting is enabled
+func (s *rpcServer) checkRateLimit(ctx context.Context, rpcReq *rpcbackend.RPCRequest, limit string) (*rpcbackend.RPCResponse, error) {
+	if s.rateLimiter == nil {
+		return nil, nil
+	}
+	key := getRateLimitKey(ctx)
+	if !s.rateLimiter.allow(key, limit) {
+		log.L(ctx).Warnf("Rate limit exceeded for %s by '%s'", limit, key)
+		if s.metrics != nil {
+			s.metrics.rateLimited(ctx, limit)
+		}
+		err := i18n.NewError(ctx, signermsgs.MsgRateLimitExceeded, limit)
+		return rpcbackend.RPCErrorResponse(err, rpcReq.ID, rpcbackend.RPCCodeLimitExceeded), err
+	}
+	return nil, nil
+}
@@ -0,0 +1,158 @@
+// Copyright © 2023 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-signer/internal/signerconfig"
+	"github.com/hyperledger/firefly-signer/mocks/ethsignermocks"
+	"github.com/hyperledger/firefly-signer/mocks/rpcbackendmocks"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/rpcauth"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/time/rate"
+)
+
+func setTestRateLimitConf() {
+	config.Set(signerconfig.RateLimitEnabled, true)
+	config.Set(signerconfig.RateLimitRequestsPerSecond, 0.001)
+	config.Set(signerconfig.RateLimitRequestsBurst, 1)
+	config.Set(signerconfig.RateLimitSigningPerSecond, 0.001)
+	config.Set(signerconfig.RateLimitSigningBurst, 0)
+}
+
+func TestRateLimitRequestsHTTP(t *testing.T) {
+	url, s, done := newTestServer(t, setTestRateLimitConf)
+	defer done()
+
+	w := s.wallet.(*ethsignermocks.Wallet)
+	w.On("GetAccounts", mock.Anything).Return([]*ethtypes.Address0xHex{}, nil).Once()
+	startTestServerNoBackend(t, s)
+
+	body := []byte(`{"jsonrpc":"2.0","id":1,"method":"eth_accounts"}`)
+	res, err := http.Post(url, "application/json", bytes.NewReader(body))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	res, err = http.Post(url, "application/json", bytes.NewReader(body))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusTooManyRequests, res.StatusCode)
+	var rpcRes rpcbackend.RPCResponse
+	err = json.NewDecoder(res.Body).Decode(&rpcRes)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(rpcbackend.RPCCodeLimitExceeded), rpcRes.Error.Code)
+	assert.Regexp(t, "FF22113.*requests", rpcRes.Error.Message)
+
+	w.AssertExpectations(t)
+}
+
+func TestRateLimitSigning(t *testing.T) {
+	_, s, done := newTestServer(t, setTestRateLimitConf)
+	defer done()
+
+	rpcRes, err := s.processRPC(withRateLimitKey(s.ctx, "identity:tenantA"), &rpcbackend.RPCRequest{
+		ID:     fftypes.JSONAnyPtr("1"),
+		Method: "eth_sendTransaction",
+		Params: []*fftypes.JSONAny{fftypes.JSONAnyPtr(`{"from": "0xfb075bb99f2aa4c49955bf703509a227d7a12248"}`)},
+	})
+	assert.Regexp(t, "FF22113.*signing", err)
+	assert.Equal(t, int64(rpcbackend.RPCCodeLimitExceeded), rpcRes.Error.Code)
+	s.backend.(*rpcbackendmocks.Backend).AssertExpectations(t)
+}
+
+func TestRateLimitMetrics(t *testing.T) {
+	_, metricsURL, s, _, done := newTestMetricsServer(t, &ethsignermocks.Wallet{})
+	defer done()
+	startTestServerNoBackend(t, s)
+	setTestRateLimitConf()
+	s.rateLimiter = newRateLimiter()
+
+	_, err := s.checkRateLimit(s.ctx, &rpcbackend.RPCRequest{ID: fftypes.JSONAnyPtr("1")}, rateLimitSigning)
+	assert.Regexp(t, "FF22113", err)
+
+	assert.Contains(t, scrapeTestMetrics(t, metricsURL), `ff_signer_rpc_server_rate_limited_total{ff_component="ffsigner",limit="signing"} 1`)
+}
+
+func TestRateLimitKeyed(t *testing.T) {
+	s := &rpcServer{rateLimiter: &rateLimiter{}}
+	var key string
+	handler := s.rateLimitKeyed(func(w http.ResponseWriter, r *http.Request) {
+		key = getRateLimitKey(r.Context())
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.RemoteAddr = "10.0.0.1:12345"
+	handler(httptest.NewRecorder(), req)
+	assert.Equal(t, "ip:10.0.0.1", key)
+
+	req.RemoteAddr = "pipe"
+	handler(httptest.NewRecorder(), req)
+	assert.Equal(t, "ip:pipe", key)
+
+	req = req.WithContext(rpcauth.WithIdentity(req.Context(), &rpcauth.Identity{ID: "tenantA"}))
+	handler(httptest.NewRecorder(), req)
+	assert.Equal(t, "identity:tenantA", key)
+}
+
+func TestRateLimitWebSocketKey(t *testing.T) {
+	url, s, done := newTestServer(t, setTestRateLimitConf)
+	defer done()
+	startTestServerNoBackend(t, s)
+
+	conn, _, err := websocket.DefaultDialer.Dial(strings.Replace(url, "http://", "ws://", 1), nil)
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	c := waitServerWSConnection(s)
+	assert.Equal(t, "ip:127.0.0.1", getRateLimitKey(c.ctx))
+}
+
+func TestRateLimiterUnlimitedAndIdleSweep(t *testing.T) {
+	signerconfig.Reset()
+	config.Set(signerconfig.RateLimitRequestsPerSecond, 0)
+	rl := newRateLimiter()
+	assert.Equal(t, rate.Inf, rl.requestsLimit)
+	assert.True(t, rl.allow("ip:10.0.0.1", rateLimitRequests))
+	assert.True(t, rl.allow("ip:10.0.0.2", rateLimitSigning))
+	assert.Len(t, rl.clients, 2)
+
+	rl.clients["ip:10.0.0.1"].lastUsed = time.Now().Add(-2 * rl.idleTimeout)
+	rl.lastSweep = time.Now().Add(-2 * rl.idleTimeout)
+	assert.True(t, rl.allow("ip:10.0.0.3", rateLimitRequests))
+	assert.Len(t, rl.clients, 2)
+	assert.Nil(t, rl.clients["ip:10.0.0.1"])
+}
+
+func TestRateLimitDisabled(t *testing.T) {
+	s := &rpcServer{}
+	errRes, err := s.checkRateLimit(context.Background(), &rpcbackend.RPCRequest{}, rateLimitRequests)
+	assert.Nil(t, errRes)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,149 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-common/pkg/log"
+	"github.com/hyperledger/firefly-signer/internal/signerconfig"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/hyperledger/firefly-signer/pkg/debuglog"
+	"github.com/hyperledger/firefly-signer/pkg/rpcauth"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+)
+
+// switchableBackend lets the HTTP backend be replaced on a reload. Requests in flight complete on the
+// backend they started on, which is only retired once they have all finished.
+type switchableBackend struct {
+	current atomic.Pointer[backendGeneration]
+}
+
+type backendGeneration struct {
+	backend rpcbackend.Backend
+	cancel  context.CancelFunc
+	mux     sync.RWMutex // read locked for the duration of each request
+	retired bool
+
+	circuitBreakers []*backendCircuitBreaker // when enabled, for each of the URLs
+}
+
+// acquire returns the current backend, read locked so it cannot be retired until the caller unlocks it
+func (sb *switchableBackend) acquire() *backendGeneration {
+	for {
+		gen := sb.current.Load()
+		gen.mux.RLock()
+		if !gen.retired {
+			return gen
+		}
+		// We lost a race with a reload, so try again with the new backend
+		gen.mux.RUnlock()
+	}
+}
+
+func (sb *switchableBackend) swap(gen *backendGeneration) {
+	old := sb.current.Swap(gen)
+	go old.retire()
+}
+
+func (gen *backendGeneration) retire() {
+	gen.mux.Lock()
+	defer gen.mux.Unlock()
+	gen.retired = true
+	gen.cancel()
+}
+
+func (sb *switchableBackend) CallRPC(ctx context.Context, result interface{}, method string, params ...interface{}) *rpcbackend.RPCError {
+	gen := sb.acquire()
+	defer gen.mux.RUnlock()
+	return gen.backend.CallRPC(ctx, result, method, params...)
+}
+
+func (sb *switchableBackend) SyncRequest(ctx context.Context, rpcReq *rpcbackend.RPCRequest) (*rpcbackend.RPCResponse, error) {
+	gen := sb.acquire()
+	defer gen.mux.RUnlock()
+	return gen.backend.SyncRequest(ctx, rpcReq)
+}
+
+func (sb *switchableBackend) BatchRequest(ctx context.Context, rpcReqs []*rpcbackend.RPCRequest) ([]*rpcbackend.RPCResponse, error) {
+	gen := sb.acquire()
+	defer gen.mux.RUnlock()
+	return gen.backend.BatchRequest(ctx, rpcReqs)
+}
+
+// Reload applies changes to the configuration file without a restart. The access control policies, method
+// filter, backend URLs and log level are reloaded, and the wallet is refreshed to pick up new keys. Other
+// settings require a restart. Requests in flight complete with the configuration they started with.
+func (s *rpcServer) Reload(ctx context.Context) error {
+	s.reloadMux.Lock()
+	defer s.reloadMux.Unlock()
+
+	if err := signerconfig.ReloadConfigFile(); err != nil {
+		return i18n.WrapError(ctx, err, signermsgs.MsgConfigReloadFailed)
+	}
+
+	// Everything is built before anything is applied, so an invalid configuration changes nothing
+	authorizer, methodFilter, err := s.newAccessControl(ctx, rpcauth.ReadConfig(signerconfig.AuthConfig))
+	if err != nil {
+		return i18n.WrapError(ctx, err, signermsgs.MsgConfigReloadFailed)
+	}
+	logModules, err := configLogModules(ctx)
+	if err != nil {
+		return i18n.WrapError(ctx, err, signermsgs.MsgConfigReloadFailed)
+	}
+	var newBackend *backendGeneration
+	urls := backendURLs()
+	if !slices.Equal(urls, s.backendURLs) {
+		if s.httpBackend == nil || slices.ContainsFunc(urls, isStreamingURL) {
+			return i18n.WrapError(ctx, i18n.NewError(ctx, signermsgs.MsgBackendReloadUnsupported), signermsgs.MsgConfigReloadFailed)
+		}
+		if newBackend, err = s.newHTTPBackend(ctx, urls); err != nil {
+			return i18n.WrapError(ctx, err, signermsgs.MsgConfigReloadFailed)
+		}
+	}
+
+	s.authorizer.Store(authorizer)
+	s.methodFilter.Store(methodFilter)
+	if newBackend != nil {
+		// The URLs are not logged, as they can contain credentials
+		log.L(ctx).Infof("Switching to %d new backend URL(s)", len(urls))
+		s.httpBackend.swap(newBackend)
+		s.backendURLs = urls
+		if s.responseCache != nil {
+			s.responseCache.clear()
+		}
+	}
+	log.SetLevel(config.GetString(config.LogLevel))
+	debuglog.SetModules(logModules)
+	if err := s.wallet.Refresh(ctx); err != nil {
+		return err
+	}
+	log.L(ctx).Infof("Configuration reloaded")
+	return nil
+}
+
+func (s *rpcServer) processReloadConfig(ctx context.Context, rpcReq *rpcbackend.RPCRequest) (*rpcbackend.RPCResponse, error) {
+	if err := s.Reload(ctx); err != nil {
+		return rpcbackend.RPCErrorResponse(err, rpcReq.ID, rpcbackend.RPCCodeInternalError), err
+	}
+	return trueResult(rpcReq), nil
+}
@@ -0,0 +1,291 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/hyperledger/firefly-common/pkg/ffresty"
+	"github.com/hyperledger/firefly-common/pkg/fftls"
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-common/pkg/httpserver"
+	"github.com/hyperledger/firefly-common/pkg/log"
+	"github.com/hyperledger/firefly-signer/internal/signerconfig"
+	"github.com/hyperledger/firefly-signer/mocks/ethsignermocks"
+	"github.com/hyperledger/firefly-signer/mocks/rpcbackendmocks"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestReloadMethodsAndLogLevel(t *testing.T) {
+	_, s, done := newTestServer(t, setTestMethodsConf)
+	defer done()
+	defer log.SetLevel("info")
+
+	w := s.wallet.(*ethsignermocks.Wallet)
+	w.On("Refresh", mock.Anything).Return(nil)
+	bm := s.backend.(*rpcbackendmocks.Backend)
+	bm.On("SyncRequest", mock.Anything, mock.Anything).Return(&rpcbackend.RPCResponse{Result: fftypes.JSONAnyPtr(`"ok"`)}, nil)
+
+	_, err := s.processRPC(s.ctx, &rpcbackend.RPCRequest{ID: fftypes.JSONAnyPtr("1"), Method: "eth_blockNumber"})
+	assert.NoError(t, err)
+
+	viper.Set("methods.deny", []string{"eth_*"})
+	config.Set(config.LogLevel, "debug")
+	rpcRes, err := s.processRPC(s.ctx, &rpcbackend.RPCRequest{ID: fftypes.JSONAnyPtr("2"), Method: "ffsigner_reloadConfig"})
+	assert.NoError(t, err)
+	assert.Equal(t, "true", rpcRes.Result.String())
+	assert.Equal(t, logrus.DebugLevel, logrus.GetLevel())
+
+	_, err = s.processRPC(s.ctx, &rpcbackend.RPCRequest{ID: fftypes.JSONAnyPtr("3"), Method: "eth_blockNumber"})
+	assert.Regexp(t, "FF22121.*eth_blockNumber", err)
+	w.AssertExpectations(t)
+}
+
+func TestReloadInvalidConfigKeepsPrevious(t *testing.T) {
+	_, s, done := newTestServer(t, setTestMethodsConf)
+	defer done()
+
+	filter := s.methodFilter.Load()
+	viper.Set("methods.deny", []string{"["})
+	rpcRes, err := s.processRPC(s.ctx, &rpcbackend.RPCRequest{ID: fftypes.JSONAnyPtr("1"), Method: "ffsigner_reloadConfig"})
+	assert.Regexp(t, "FF22145.*FF22119", err)
+	assert.Equal(t, int64(rpcbackend.RPCCodeInternalError), rpcRes.Error.Code)
+	assert.Same(t, filter, s.methodFilter.Load())
+}
+
+func TestReloadRBACWithoutAuthentication(t *testing.T) {
+	_, s, done := newTestServer(t)
+	defer done()
+
+	viper.Set("auth.rbac.enabled", true)
+	err := s.Reload(s.ctx)
+	assert.Regexp(t, "FF22145.*FF22109", err)
+	assert.Nil(t, s.authorizer.Load())
+}
+
+func TestReloadRBACPolicies(t *testing.T) {
+	ctx, s, done := newTestRBACServer(t)
+	defer done()
+
+	w := s.wallet.(*ethsignermocks.Wallet)
+	w.On("Refresh", mock.Anything).Return(nil)
+	rpcReq := &rpcbackend.RPCRequest{ID: fftypes.JSONAnyPtr("1"), Method: "eth_accounts"}
+	_, err := s.authorizeMethod(ctx, rpcReq)
+	assert.NoError(t, err)
+
+	_ = viper.ReadConfig(strings.NewReader(`
+auth:
+  enabled: true
+  apiKeys:
+  - id: tenantA
+    key: secretA
+  rbac:
+    enabled: true
+    policies:
+    - identities:
+      - tenantA
+      methods:
+      - ffsigner_getPublicKey
+`))
+	err = s.Reload(s.ctx)
+	assert.NoError(t, err)
+	rpcRes, err := s.authorizeMethod(ctx, rpcReq)
+	assertUnauthorized(t, rpcRes, err, "FF22110")
+}
+
+func TestReloadConfigFileFail(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "ffsigner.yaml")
+	err := os.WriteFile(configFile, []byte("{}"), 0600)
+	assert.NoError(t, err)
+	defer viper.Reset() // the config file is otherwise retained over a reset of the config
+	_, s, done := newTestServer(t, func() {
+		err := config.ReadConfig("ffsigner", configFile)
+		assert.NoError(t, err)
+	})
+	defer done()
+
+	err = os.WriteFile(configFile, []byte("!badness"), 0600)
+	assert.NoError(t, err)
+	err = s.Reload(s.ctx)
+	assert.Regexp(t, "FF22145", err)
+}
+
+func TestReloadWalletRefreshFail(t *testing.T) {
+	_, s, done := newTestServer(t)
+	defer done()
+
+	w := s.wallet.(*ethsignermocks.Wallet)
+	w.On("Refresh", mock.Anything).Return(fmt.Errorf("pop"))
+	err := s.Reload(s.ctx)
+	assert.Regexp(t, "pop", err)
+}
+
+func newTestNamedBackend(t *testing.T, name string, block chan struct{}) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var rpcReqs []*rpcbackend.RPCRequest
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		isBatch := body[0] == '['
+		if !isBatch {
+			body = append(append([]byte{'['}, body...), ']')
+		}
+		err = json.Unmarshal(body, &rpcReqs)
+		assert.NoError(t, err)
+		if block != nil {
+			block <- struct{}{}
+			<-block
+		}
+		rpcRess := make([]*rpcbackend.RPCResponse, len(rpcReqs))
+		for i, rpcReq := range rpcReqs {
+			rpcRess[i] = &rpcbackend.RPCResponse{
+				JSONRpc: "2.0",
+				ID:      rpcReq.ID,
+				Result:  fftypes.JSONAnyPtr(fmt.Sprintf(`"%s"`, name)),
+			}
+		}
+		w.Header().Add("Content-Type", "application/json")
+		if isBatch {
+			_ = json.NewEncoder(w).Encode(rpcRess)
+		} else {
+			_ = json.NewEncoder(w).Encode(rpcRess[0])
+		}
+	}))
+}
+
+func TestReloadBackendURLs(t *testing.T) {
+	block := make(chan struct{})
+	backend1 := newTestNamedBackend(t, "backend1", block)
+	defer backend1.Close()
+	backend2 := newTestNamedBackend(t, "backend2", nil)
+	defer backend2.Close()
+
+	signerconfig.Reset()
+	signerconfig.BackendConfig.Set(ffresty.HTTPConfigURL, backend1.URL)
+	setTestResponseCacheConf()
+	signerconfig.ServerConfig.Set(httpserver.HTTPConfPort, 0)
+	signerconfig.ServerConfig.Set(httpserver.HTTPConfAddress, "127.0.0.1")
+
+	w := &ethsignermocks.Wallet{}
+	w.On("Refresh", mock.Anything).Return(nil)
+	ss, err := NewServer(context.Background(), w)
+	assert.NoError(t, err)
+	defer ss.Stop()
+	s := ss.(*rpcServer)
+	oldGen := s.httpBackend.current.Load()
+
+	// Reloading with the same URLs keeps the existing backend
+	err = s.Reload(s.ctx)
+	assert.NoError(t, err)
+	assert.Same(t, oldGen, s.httpBackend.current.Load())
+
+	// Start a request that will be in flight during the switch
+	inFlight := make(chan *rpcbackend.RPCResponse)
+	go func() {
+		rpcRes, err := s.backend.SyncRequest(s.ctx, &rpcbackend.RPCRequest{ID: fftypes.JSONAnyPtr("1"), Method: "eth_blockNumber"})
+		assert.NoError(t, err)
+		inFlight <- rpcRes
+	}()
+	<-block
+
+	s.responseCache.put(s.ctx, "eth_chainId:null", fftypes.JSONAnyPtr(`"0x1"`))
+	signerconfig.BackendConfig.Set(ffresty.HTTPConfigURL, backend2.URL)
+	err = s.Reload(s.ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{backend2.URL}, s.backendURLs)
+	assert.Empty(t, s.responseCache.entries)
+
+	// New requests go to the new backend
+	var result string
+	rpcErr := s.backend.CallRPC(s.ctx, &result, "eth_blockNumber")
+	assert.Nil(t, rpcErr)
+	assert.Equal(t, "backend2", result)
+	rpcRess, err := s.backend.BatchRequest(s.ctx, []*rpcbackend.RPCRequest{{ID: fftypes.JSONAnyPtr("2"), Method: "eth_blockNumber"}})
+	assert.NoError(t, err)
+	assert.Equal(t, `"backend2"`, rpcRess[0].Result.String())
+
+	// The request in flight completes on the old backend, which is then retired
+	block <- struct{}{}
+	assert.Equal(t, `"backend1"`, (<-inFlight).Result.String())
+	assert.Eventually(t, func() bool {
+		oldGen.mux.RLock()
+		defer oldGen.mux.RUnlock()
+		return oldGen.retired
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestSwitchableBackendAcquireRetired(t *testing.T) {
+	sb := &switchableBackend{}
+	retired := &backendGeneration{retired: true}
+	current := &backendGeneration{}
+	sb.current.Store(retired)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		sb.current.Store(current)
+	}()
+	gen := sb.acquire()
+	defer gen.mux.RUnlock()
+	assert.Same(t, current, gen)
+}
+
+func TestReloadBackendBadTLSConfig(t *testing.T) {
+	_, s, done := newTestServer(t)
+	defer done()
+
+	tlsConf := signerconfig.BackendConfig.SubSection("tls")
+	tlsConf.Set(fftls.HTTPConfTLSEnabled, true)
+	tlsConf.Set(fftls.HTTPConfTLSCAFile, "!!!!!badness")
+	signerconfig.BackendConfig.Set(ffresty.HTTPConfigURL, "https://127.0.0.1:1")
+	err := s.Reload(s.ctx)
+	assert.Regexp(t, "FF22145.*FF00153", err)
+}
+
+func TestReloadBackendToWebSocketUnsupported(t *testing.T) {
+	_, s, done := newTestServer(t)
+	defer done()
+
+	signerconfig.BackendConfig.Set(ffresty.HTTPConfigURL, "ws://127.0.0.1:1")
+	err := s.Reload(s.ctx)
+	assert.Regexp(t, "FF22145.*FF22146", err)
+}
+
+func TestReloadWebSocketBackendUnsupported(t *testing.T) {
+	signerconfig.Reset()
+	signerconfig.BackendConfig.Set(ffresty.HTTPConfigURL, "ws://127.0.0.1:1")
+
+	ss, err := NewServer(context.Background(), &ethsignermocks.Wallet{})
+	assert.NoError(t, err)
+	defer ss.Stop()
+
+	signerconfig.BackendConfig.Set(ffresty.HTTPConfigURL, "ws://127.0.0.1:2")
+	err = ss.Reload(context.Background())
+	assert.Regexp(t, "FF22145.*FF22146", err)
+}
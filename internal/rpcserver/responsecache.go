@@ -0,0 +1,226 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-common/pkg/log"
+	"github.com/hyperledger/firefly-signer/internal/signerconfig"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+)
+
+// responseCache holds the results of read methods for a short time, so clients polling for the same data
+// do not each cause a request to the backend. Only results that cannot change are cached - those for a
+// fixed block, and the receipts of transactions in blocks deep enough that they will not be reorganized.
+type responseCache struct {
+	ttl                  time.Duration
+	maxEntries           int
+	receiptConfirmations uint64
+
+	mux        sync.Mutex
+	entries    map[string]*cachedResult
+	lastSweep  time.Time
+	headBlock  uint64
+	headExpiry time.Time
+}
+
+type cachedResult struct {
+	result *fftypes.JSONAny
+	expiry time.Time
+}
+
+func newResponseCache(ctx context.Context) (*responseCache, error) {
+	rc := &responseCache{
+		ttl:                  config.GetDuration(signerconfig.ResponseCacheTTL),
+		maxEntries:           config.GetInt(signerconfig.ResponseCacheMaxEntries),
+		receiptConfirmations: config.GetUint64(signerconfig.ResponseCacheReceiptConfirmations),
+		entries:              make(map[string]*cachedResult),
+		lastSweep:            time.Now(),
+	}
+	if rc.ttl <= 0 || rc.maxEntries <= 0 {
+		return nil, i18n.NewError(ctx, signermsgs.MsgBadResponseCacheConfig)
+	}
+	return rc, nil
+}
+
+// responseCacheKey returns the key a request is cached under, or false if its result is never cached.
+// The key includes the params exactly as supplied, so requests must be identical to share a result.
+func responseCacheKey(rpcReq *rpcbackend.RPCRequest) (string, bool) {
+	switch rpcReq.Method {
+	case "eth_chainId", "eth_getCode", "eth_getTransactionReceipt":
+	case "eth_call":
+		// The result of a call depends on the state of the chain, so is only cached for a fixed block
+		if len(rpcReq.Params) < 2 || !isFixedBlock(rpcReq.Params[1]) {
+			return "", false
+		}
+	default:
+		return "", false
+	}
+	b, _ := json.Marshal(rpcReq.Params)
+	return rpcReq.Method + ":" + string(b), true
+}
+
+// isFixedBlock is true for a block number or hash, rather than a tag like "latest" that moves with the chain.
+// EIP-1898 also allows a block to be referenced by an object, containing its hash or number.
+func isFixedBlock(param *fftypes.JSONAny) bool {
+	var blockRef string
+	if err := json.Unmarshal(param.Bytes(), &blockRef); err != nil {
+		var blockObj struct {
+			BlockHash   string `json:"blockHash"`
+			BlockNumber string `json:"blockNumber"`
+		}
+		if err := json.Unmarshal(param.Bytes(), &blockObj); err != nil {
+			return false
+		}
+		blockRef = blockObj.BlockHash
+		if blockRef == "" {
+			blockRef = blockObj.BlockNumber
+		}
+	}
+	return len(blockRef) > 2 && strings.HasPrefix(blockRef, "0x")
+}
+
+func (rc *responseCache) get(key string) *fftypes.JSONAny {
+	rc.mux.Lock()
+	defer rc.mux.Unlock()
+	entry := rc.entries[key]
+	if entry == nil || time.Now().After(entry.expiry) {
+		return nil
+	}
+	return entry.result
+}
+
+func (rc *responseCache) put(ctx context.Context, key string, result *fftypes.JSONAny) {
+	rc.mux.Lock()
+	defer rc.mux.Unlock()
+
+	now := time.Now()
+	if now.Sub(rc.lastSweep) > rc.ttl || len(rc.entries) >= rc.maxEntries {
+		for k, entry := range rc.entries {
+			if now.After(entry.expiry) {
+				delete(rc.entries, k)
+			}
+		}
+		rc.lastSweep = now
+	}
+	if len(rc.entries) >= rc.maxEntries {
+		log.L(ctx).Debugf("Response cache is full with %d entries", len(rc.entries))
+		return
+	}
+	rc.entries[key] = &cachedResult{result: result, expiry: now.Add(rc.ttl)}
+}
+
+// clear discards every cached result, such as when the backend is switched to different URLs, and
+// returns how many there were
+func (rc *responseCache) clear() int {
+	rc.mux.Lock()
+	defer rc.mux.Unlock()
+	cleared := len(rc.entries)
+	rc.entries = make(map[string]*cachedResult)
+	rc.headExpiry = time.Time{}
+	return cleared
+}
+
+func (rc *responseCache) size() int {
+	rc.mux.Lock()
+	defer rc.mux.Unlock()
+	return len(rc.entries)
+}
+
+// processCachedRequest passes a request to the backend, unless an identical request has a cached result
+func (s *rpcServer) processCachedRequest(ctx context.Context, rpcReq *rpcbackend.RPCRequest) (*rpcbackend.RPCResponse, error) {
+	rc := s.responseCache
+	key, cacheable := responseCacheKey(rpcReq)
+	if !cacheable {
+		return s.backend.SyncRequest(ctx, rpcReq)
+	}
+	if result := rc.get(key); result != nil {
+		log.L(ctx).Debugf("Returning cached result for %s", rpcReq.Method)
+		if s.metrics != nil {
+			s.metrics.responseCacheHit(ctx, rpcReq.Method)
+		}
+		return &rpcbackend.RPCResponse{
+			JSONRpc: "2.0",
+			ID:      rpcReq.ID,
+			Result:  result,
+		}, nil
+	}
+	rpcRes, err := s.backend.SyncRequest(ctx, rpcReq)
+	if err == nil && s.resultCacheable(ctx, rpcReq, rpcRes.Result) {
+		rc.put(ctx, key, rpcRes.Result)
+	}
+	return rpcRes, err
+}
+
+func (s *rpcServer) resultCacheable(ctx context.Context, rpcReq *rpcbackend.RPCRequest, result *fftypes.JSONAny) bool {
+	switch rpcReq.Method {
+	case "eth_getCode":
+		// Deployed code is immutable, but an address with no code yet might be about to have a contract deployed to it
+		if len(rpcReq.Params) > 1 && isFixedBlock(rpcReq.Params[1]) {
+			return true
+		}
+		var code ethtypes.HexBytes0xPrefix
+		return json.Unmarshal(result.Bytes(), &code) == nil && len(code) > 0
+	case "eth_getTransactionReceipt":
+		// The receipt is null until the transaction is mined, and can change if its block is reorganized out of the chain
+		var receipt struct {
+			BlockNumber *ethtypes.HexUint64 `json:"blockNumber"`
+		}
+		if json.Unmarshal(result.Bytes(), &receipt) != nil || receipt.BlockNumber == nil {
+			return false
+		}
+		if s.responseCache.receiptConfirmations == 0 {
+			return true
+		}
+		headBlock, ok := s.responseCache.getHeadBlock(ctx, s.backend)
+		return ok && receipt.BlockNumber.Uint64()+s.responseCache.receiptConfirmations <= headBlock
+	default:
+		return true
+	}
+}
+
+// getHeadBlock returns the latest block number, which is itself cached for the TTL
+func (rc *responseCache) getHeadBlock(ctx context.Context, backend rpcbackend.Backend) (uint64, bool) {
+	rc.mux.Lock()
+	headBlock, headExpiry := rc.headBlock, rc.headExpiry
+	rc.mux.Unlock()
+	if time.Now().Before(headExpiry) {
+		return headBlock, true
+	}
+
+	var blockNumber ethtypes.HexUint64
+	if rpcErr := backend.CallRPC(ctx, &blockNumber, "eth_blockNumber"); rpcErr != nil {
+		log.L(ctx).Warnf("Failed to query the latest block, so the receipt is not cached: %s", rpcErr.Message)
+		return 0, false
+	}
+
+	rc.mux.Lock()
+	defer rc.mux.Unlock()
+	rc.headBlock = blockNumber.Uint64()
+	rc.headExpiry = time.Now().Add(rc.ttl)
+	return rc.headBlock, true
+}
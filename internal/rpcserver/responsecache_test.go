@@ -0,0 +1,275 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-signer/internal/signerconfig"
+	"github.com/hyperledger/firefly-signer/mocks/ethsignermocks"
+	"github.com/hyperledger/firefly-signer/mocks/rpcbackendmocks"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func setTestResponseCacheConf() {
+	config.Set(signerconfig.ResponseCacheEnabled, true)
+}
+
+func newTestResponseCacheServer(t *testing.T) (*rpcServer, *rpcbackendmocks.Backend, func()) {
+	_, s, done := newTestServer(t, setTestResponseCacheConf)
+	assert.NotNil(t, s.responseCache)
+	return s, s.backend.(*rpcbackendmocks.Backend), done
+}
+
+func testRPCRequest(id int, method string, params ...string) *rpcbackend.RPCRequest {
+	rpcReq := &rpcbackend.RPCRequest{
+		ID:     fftypes.JSONAnyPtr(fmt.Sprintf("%d", id)),
+		Method: method,
+	}
+	for _, p := range params {
+		rpcReq.Params = append(rpcReq.Params, fftypes.JSONAnyPtr(p))
+	}
+	return rpcReq
+}
+
+func mockSyncResult(bm *rpcbackendmocks.Backend, method, result string) *mock.Call {
+	return bm.On("SyncRequest", mock.Anything, mock.MatchedBy(func(rpcReq *rpcbackend.RPCRequest) bool {
+		return rpcReq.Method == method
+	})).Return(&rpcbackend.RPCResponse{JSONRpc: "2.0", Result: fftypes.JSONAnyPtr(result)}, nil)
+}
+
+func mockBlockNumber(bm *rpcbackendmocks.Backend, blockNumber uint64) *mock.Call {
+	return bm.On("CallRPC", mock.Anything, mock.Anything, "eth_blockNumber").Run(func(args mock.Arguments) {
+		*(args[1].(*ethtypes.HexUint64)) = ethtypes.HexUint64(blockNumber)
+	}).Return(nil)
+}
+
+func assertRPCResults(t *testing.T, s *rpcServer, expected string, rpcReqs ...*rpcbackend.RPCRequest) {
+	for _, rpcReq := range rpcReqs {
+		rpcRes, err := s.processRPC(s.ctx, rpcReq)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, rpcRes.Result.String())
+	}
+}
+
+func TestResponseCacheChainID(t *testing.T) {
+	s, bm, done := newTestResponseCacheServer(t)
+	defer done()
+
+	mockSyncResult(bm, "eth_chainId", `"0x3039"`).Once()
+	assertRPCResults(t, s, `"0x3039"`, testRPCRequest(1, "eth_chainId"))
+
+	// The cached result is returned with the ID of the new request
+	rpcRes, err := s.processRPC(s.ctx, testRPCRequest(2, "eth_chainId"))
+	assert.NoError(t, err)
+	assert.Equal(t, "2", rpcRes.ID.String())
+	assert.Equal(t, "2.0", rpcRes.JSONRpc)
+	assert.Equal(t, `"0x3039"`, rpcRes.Result.String())
+	bm.AssertExpectations(t)
+}
+
+func TestResponseCacheEthCallFixedBlock(t *testing.T) {
+	s, bm, done := newTestResponseCacheServer(t)
+	defer done()
+
+	tx := `{"to":"0x497eedc4299dea2f2a364be10025d0ad0f702de3","data":"0x70a08231"}`
+	mockSyncResult(bm, "eth_call", `"0x01"`).Times(3)
+	assertRPCResults(t, s, `"0x01"`,
+		testRPCRequest(1, "eth_call", tx, `"0x10"`),
+		testRPCRequest(2, "eth_call", tx, `"0x10"`),
+		testRPCRequest(3, "eth_call", tx, `{"blockHash":"0xe670ec64341771606e55d6b4ca35a1a6b75ee3d5145a99d05921026d1527331"}`),
+		testRPCRequest(4, "eth_call", tx, `{"blockHash":"0xe670ec64341771606e55d6b4ca35a1a6b75ee3d5145a99d05921026d1527331"}`),
+		testRPCRequest(5, "eth_call", tx, `{"blockNumber":"0x10"}`),
+		testRPCRequest(6, "eth_call", tx, `{"blockNumber":"0x10"}`),
+	)
+	bm.AssertExpectations(t)
+}
+
+func TestResponseCacheEthCallMovingBlock(t *testing.T) {
+	s, bm, done := newTestResponseCacheServer(t)
+	defer done()
+
+	tx := `{"to":"0x497eedc4299dea2f2a364be10025d0ad0f702de3","data":"0x70a08231"}`
+	mockSyncResult(bm, "eth_call", `"0x01"`).Times(8)
+	for i := 0; i < 2; i++ {
+		assertRPCResults(t, s, `"0x01"`,
+			testRPCRequest(1, "eth_call", tx),
+			testRPCRequest(2, "eth_call", tx, `"latest"`),
+			testRPCRequest(3, "eth_call", tx, `{"blockHash":""}`),
+			testRPCRequest(4, "eth_call", tx, `[]`),
+		)
+	}
+	bm.AssertExpectations(t)
+}
+
+func TestResponseCacheGetCode(t *testing.T) {
+	s, bm, done := newTestResponseCacheServer(t)
+	defer done()
+
+	addr := `"0x497eedc4299dea2f2a364be10025d0ad0f702de3"`
+	mockSyncResult(bm, "eth_getCode", `"0x"`).Times(3)
+	assertRPCResults(t, s, `"0x"`,
+		// No code yet at the latest block is not cached
+		testRPCRequest(1, "eth_getCode", addr, `"latest"`),
+		testRPCRequest(2, "eth_getCode", addr, `"latest"`),
+		// Anything at a fixed block is
+		testRPCRequest(3, "eth_getCode", addr, `"0x10"`),
+		testRPCRequest(4, "eth_getCode", addr, `"0x10"`),
+	)
+	mockSyncResult(bm, "eth_getCode", `"0x6080"`).Once()
+	assertRPCResults(t, s, `"0x6080"`,
+		testRPCRequest(5, "eth_getCode", addr),
+		testRPCRequest(6, "eth_getCode", addr),
+	)
+	bm.AssertExpectations(t)
+}
+
+func TestResponseCacheReceiptConfirmations(t *testing.T) {
+	s, bm, done := newTestResponseCacheServer(t)
+	defer done()
+
+	txHash := `"0x61ca9c99c1d752fb3bda568b8566edf33ba93585c64a970566e6dfb540a5cbc1"`
+
+	// Not cached until it is mined
+	mockSyncResult(bm, "eth_getTransactionReceipt", `null`).Twice()
+	assertRPCResults(t, s, `null`,
+		testRPCRequest(1, "eth_getTransactionReceipt", txHash),
+		testRPCRequest(2, "eth_getTransactionReceipt", txHash),
+	)
+
+	// Not cached until it has enough confirmations, with the head block queried once
+	bm.ExpectedCalls = nil
+	mockSyncResult(bm, "eth_getTransactionReceipt", `{"blockNumber":"0x64"}`).Twice()
+	mockBlockNumber(bm, 0x64+11).Once()
+	assertRPCResults(t, s, `{"blockNumber":"0x64"}`,
+		testRPCRequest(3, "eth_getTransactionReceipt", txHash),
+		testRPCRequest(4, "eth_getTransactionReceipt", txHash),
+	)
+	bm.AssertExpectations(t)
+
+	// Cached once it does
+	s.responseCache.headExpiry = time.Time{}
+	mockSyncResult(bm, "eth_getTransactionReceipt", `{"blockNumber":"0x64"}`).Once()
+	mockBlockNumber(bm, 0x64+12).Once()
+	assertRPCResults(t, s, `{"blockNumber":"0x64"}`,
+		testRPCRequest(5, "eth_getTransactionReceipt", txHash),
+		testRPCRequest(6, "eth_getTransactionReceipt", txHash),
+	)
+	bm.AssertExpectations(t)
+}
+
+func TestResponseCacheReceiptHeadBlockFail(t *testing.T) {
+	s, bm, done := newTestResponseCacheServer(t)
+	defer done()
+
+	txHash := `"0x61ca9c99c1d752fb3bda568b8566edf33ba93585c64a970566e6dfb540a5cbc1"`
+	mockSyncResult(bm, "eth_getTransactionReceipt", `{"blockNumber":"0x64"}`).Twice()
+	bm.On("CallRPC", mock.Anything, mock.Anything, "eth_blockNumber").Return(&rpcbackend.RPCError{Message: "pop"}).Twice()
+	assertRPCResults(t, s, `{"blockNumber":"0x64"}`,
+		testRPCRequest(1, "eth_getTransactionReceipt", txHash),
+		testRPCRequest(2, "eth_getTransactionReceipt", txHash),
+	)
+	bm.AssertExpectations(t)
+}
+
+func TestResponseCacheReceiptInstantFinality(t *testing.T) {
+	s, bm, done := newTestResponseCacheServer(t)
+	defer done()
+	s.responseCache.receiptConfirmations = 0
+
+	txHash := `"0x61ca9c99c1d752fb3bda568b8566edf33ba93585c64a970566e6dfb540a5cbc1"`
+	mockSyncResult(bm, "eth_getTransactionReceipt", `{"blockNumber":"0x64"}`).Once()
+	assertRPCResults(t, s, `{"blockNumber":"0x64"}`,
+		testRPCRequest(1, "eth_getTransactionReceipt", txHash),
+		testRPCRequest(2, "eth_getTransactionReceipt", txHash),
+	)
+	bm.AssertExpectations(t)
+}
+
+func TestResponseCacheErrorsAndOtherMethodsNotCached(t *testing.T) {
+	s, bm, done := newTestResponseCacheServer(t)
+	defer done()
+
+	bm.On("SyncRequest", mock.Anything, mock.MatchedBy(func(rpcReq *rpcbackend.RPCRequest) bool {
+		return rpcReq.Method == "eth_chainId"
+	})).Return(&rpcbackend.RPCResponse{Error: &rpcbackend.RPCError{Message: "pop"}}, fmt.Errorf("pop")).Twice()
+	mockSyncResult(bm, "eth_blockNumber", `"0x10"`).Twice()
+	for i := 0; i < 2; i++ {
+		_, err := s.processRPC(s.ctx, testRPCRequest(1, "eth_chainId"))
+		assert.Regexp(t, "pop", err)
+		assertRPCResults(t, s, `"0x10"`, testRPCRequest(2, "eth_blockNumber"))
+	}
+	bm.AssertExpectations(t)
+}
+
+func TestResponseCacheExpiryAndMaxEntries(t *testing.T) {
+	signerconfig.Reset()
+	config.Set(signerconfig.ResponseCacheMaxEntries, 1)
+	rc, err := newResponseCache(context.Background())
+	assert.NoError(t, err)
+
+	rc.put(context.Background(), "a", fftypes.JSONAnyPtr(`"a"`))
+	assert.Equal(t, `"a"`, rc.get("a").String())
+
+	// Full, so not cached
+	rc.put(context.Background(), "b", fftypes.JSONAnyPtr(`"b"`))
+	assert.Nil(t, rc.get("b"))
+
+	// Expired entries are not returned, and are swept to make room
+	rc.entries["a"].expiry = time.Now().Add(-1 * time.Second)
+	assert.Nil(t, rc.get("a"))
+	rc.put(context.Background(), "b", fftypes.JSONAnyPtr(`"b"`))
+	assert.Equal(t, `"b"`, rc.get("b").String())
+	assert.Len(t, rc.entries, 1)
+
+	rc.clear()
+	assert.Nil(t, rc.get("b"))
+}
+
+func TestResponseCacheBadConfig(t *testing.T) {
+	signerconfig.Reset()
+	setTestResponseCacheConf()
+	config.Set(signerconfig.ResponseCacheTTL, "0s")
+
+	_, err := NewServer(context.Background(), &ethsignermocks.Wallet{})
+	assert.Regexp(t, "FF22147", err)
+}
+
+func TestResponseCacheMetrics(t *testing.T) {
+	_, metricsURL, s, bm, done := newTestMetricsServer(t, &ethsignermocks.Wallet{})
+	defer done()
+	startTestServerNoBackend(t, s)
+	setTestResponseCacheConf()
+	rc, err := newResponseCache(s.ctx)
+	assert.NoError(t, err)
+	s.responseCache = rc
+
+	mockSyncResult(bm, "eth_chainId", `"0x3039"`).Once()
+	assertRPCResults(t, s, `"0x3039"`,
+		testRPCRequest(1, "eth_chainId"),
+		testRPCRequest(2, "eth_chainId"),
+	)
+	assert.Contains(t, scrapeTestMetrics(t, metricsURL), `ff_signer_rpc_server_response_cache_hits_total{ff_component="ffsigner",method="eth_chainId"} 1`)
+}
@@ -0,0 +1,237 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-common/pkg/log"
+	"github.com/hyperledger/firefly-signer/internal/signerconfig"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/hyperledger/firefly-signer/pkg/approval"
+	"github.com/hyperledger/firefly-signer/pkg/ethsigner"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+	"golang.org/x/crypto/sha3"
+)
+
+const (
+	resubmitPolicyRebroadcast = "rebroadcast"
+	resubmitPolicyFeeBump     = "feeBump"
+)
+
+// trackedTransaction is a transaction we signed and submitted, which is tracked until it is mined. Its
+// fields other than the key are only accessed by the resubmitter, once it is tracked.
+type trackedTransaction struct {
+	key       string
+	route     *chainRoute
+	from      ethtypes.Address0xHex
+	txn       *ethsigner.Transaction
+	signed    ethtypes.HexBytes0xPrefix
+	submitted time.Time
+	attempts  int
+}
+
+// resubmitter tracks the transactions we submit, and resubmits those that are not mined within the delay -
+// either by sending the same signed transaction to the node again, or by signing it again with higher fees
+type resubmitter struct {
+	feeBump        bool
+	delay          time.Duration
+	interval       time.Duration
+	feeBumpPercent int64
+	maxAttempts    int
+	maxTracked     int
+
+	mux     sync.Mutex
+	tracked map[string]*trackedTransaction // keyed by chain ID, address and nonce, so a replacement replaces
+	done    chan struct{}
+}
+
+func newResubmitter(ctx context.Context) (*resubmitter, error) {
+	r := &resubmitter{
+		delay:          config.GetDuration(signerconfig.ResubmitDelay),
+		interval:       config.GetDuration(signerconfig.ResubmitInterval),
+		feeBumpPercent: config.GetInt64(signerconfig.ResubmitFeeBumpPercent),
+		maxAttempts:    config.GetInt(signerconfig.ResubmitMaxAttempts),
+		maxTracked:     config.GetInt(signerconfig.ResubmitMaxTracked),
+		tracked:        make(map[string]*trackedTransaction),
+	}
+	switch policy := config.GetString(signerconfig.ResubmitPolicy); policy {
+	case resubmitPolicyRebroadcast:
+	case resubmitPolicyFeeBump:
+		r.feeBump = true
+	default:
+		return nil, i18n.NewError(ctx, signermsgs.MsgUnknownResubmitPolicy, policy)
+	}
+	switch {
+	case r.delay <= 0 || r.interval <= 0:
+		return nil, i18n.NewError(ctx, signermsgs.MsgBadResubmitConfig, "delay and interval must be greater than zero")
+	case r.feeBump && r.feeBumpPercent < 1:
+		return nil, i18n.NewError(ctx, signermsgs.MsgBadResubmitConfig, "feeBumpPercent must be at least 1")
+	case r.maxAttempts < 1 || r.maxTracked < 1:
+		return nil, i18n.NewError(ctx, signermsgs.MsgBadResubmitConfig, "maxAttempts and maxTracked must be greater than zero")
+	}
+	return r, nil
+}
+
+// track starts tracking a transaction once it is submitted, replacing any transaction it replaces
+func (r *resubmitter) track(ctx context.Context, chainID int64, from ethtypes.Address0xHex, txn *ethsigner.Transaction, signed []byte) {
+	tt := &trackedTransaction{
+		key:       fmt.Sprintf("%d:%s:%s", chainID, from, txn.Nonce.BigInt()),
+		route:     getChainRoute(ctx),
+		from:      from,
+		txn:       txn,
+		signed:    signed,
+		submitted: time.Now(),
+	}
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	if r.tracked[tt.key] == nil && len(r.tracked) >= r.maxTracked {
+		log.L(ctx).Warnf("Not tracking transaction from %s with nonce %s for resubmission, as %d transactions are already tracked", from, txn.Nonce.BigInt(), len(r.tracked))
+		return
+	}
+	r.tracked[tt.key] = tt
+}
+
+// due returns the tracked transactions that have gone the delay without being mined, since they were last submitted
+func (r *resubmitter) due(now time.Time) []*trackedTransaction {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	var due []*trackedTransaction
+	for _, tt := range r.tracked {
+		if now.Sub(tt.submitted) >= r.delay {
+			due = append(due, tt)
+		}
+	}
+	return due
+}
+
+func (r *resubmitter) untrack(tt *trackedTransaction) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	// The transaction might have been replaced by one submitted by the client since
+	if r.tracked[tt.key] == tt {
+		delete(r.tracked, tt.key)
+	}
+}
+
+func (s *rpcServer) runResubmitter() {
+	defer close(s.resubmitter.done)
+	ticker := time.NewTicker(s.resubmitter.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.ctx.Done():
+			log.L(s.ctx).Debugf("Resubmitter stopped")
+			return
+		case <-ticker.C:
+			for _, tt := range s.resubmitter.due(time.Now()) {
+				s.resubmitIfStuck(s.ctx, tt)
+			}
+		}
+	}
+}
+
+// resubmitIfStuck resubmits a transaction unless a transaction from the address with its nonce has been mined,
+// which is either the transaction itself or one that replaced it
+func (s *rpcServer) resubmitIfStuck(ctx context.Context, tt *trackedTransaction) {
+	ctx = withChainRoute(ctx, tt.route)
+	r := s.resubmitter
+	nonce := tt.txn.Nonce.BigInt()
+	var txCount ethtypes.HexInteger
+	if rpcErr := s.backend.CallRPC(ctx, &txCount, "eth_getTransactionCount", &tt.from, "latest"); rpcErr != nil {
+		log.L(ctx).Warnf("Failed to check whether transaction from %s with nonce %s is mined: %s", tt.from, nonce, rpcErr.Message)
+		return
+	}
+	if txCount.BigInt().Cmp(nonce) > 0 {
+		log.L(ctx).Debugf("Transaction from %s with nonce %s is mined", tt.from, nonce)
+		r.untrack(tt)
+		return
+	}
+	if tt.attempts >= r.maxAttempts {
+		log.L(ctx).Errorf("Abandoned resubmitting transaction %s from %s with nonce %s, as it is not mined after %d attempts", hashTransaction(tt.signed), tt.from, nonce, tt.attempts)
+		r.untrack(tt)
+		return
+	}
+
+	tt.attempts++
+	tt.submitted = time.Now()
+	previousHash := hashTransaction(tt.signed)
+	if r.feeBump {
+		s.bumpFees(ctx, tt)
+	}
+	var txHash ethtypes.HexBytes0xPrefix
+	if rpcErr := s.backend.CallRPC(ctx, &txHash, "eth_sendRawTransaction", tt.signed); rpcErr != nil {
+		// The node might reject a rebroadcast it already has, or a fee bump that is not enough to replace it
+		log.L(ctx).Warnf("Resubmission %d/%d of transaction %s from %s with nonce %s failed: %s", tt.attempts, r.maxAttempts, previousHash, tt.from, nonce, rpcErr.Message)
+		return
+	}
+	log.L(ctx).Infof("Resubmitted transaction %s from %s with nonce %s as %s (attempt %d/%d)", previousHash, tt.from, nonce, txHash, tt.attempts, r.maxAttempts)
+}
+
+// bumpFees signs the transaction again with its fees increased. The transaction is rebroadcast unchanged if
+// the fees cannot be bumped - such as when the bumped fees exceed the fee caps.
+func (s *rpcServer) bumpFees(ctx context.Context, tt *trackedTransaction) {
+	bumped := *tt.txn
+	bumped.GasPrice = s.resubmitter.bumpFee(tt.txn.GasPrice)
+	bumped.MaxFeePerGas = s.resubmitter.bumpFee(tt.txn.MaxFeePerGas)
+	bumped.MaxPriorityFeePerGas = s.resubmitter.bumpFee(tt.txn.MaxPriorityFeePerGas)
+	if s.feeCaps != nil {
+		if err := s.feeCaps.check(ctx, &bumped); err != nil {
+			log.L(ctx).Warnf("Rebroadcasting transaction from %s with nonce %s without a fee bump: %s", tt.from, tt.txn.Nonce.BigInt(), err)
+			return
+		}
+	}
+	// The fee bump is a new signature, so it is approved as any other - and rebroadcast unchanged when it is not
+	req := &signingRequest{method: "ffsigner_resubmit", operation: signOpTransaction, from: &tt.from, txn: &bumped}
+	if _, err := s.approveSigning(ctx, &rpcbackend.RPCRequest{Method: req.method}, req, &approval.Request{}); err != nil {
+		log.L(ctx).Warnf("Rebroadcasting transaction from %s with nonce %s without a fee bump: %s", tt.from, tt.txn.Nonce.BigInt(), err)
+		return
+	}
+	startTime := time.Now()
+	signed, err := s.wallet.Sign(ctx, &bumped, s.chainIDFor(ctx))
+	s.signOperation(ctx, req, startTime, signed, err)
+	if err != nil {
+		log.L(ctx).Warnf("Rebroadcasting transaction from %s with nonce %s without a fee bump: %s", tt.from, tt.txn.Nonce.BigInt(), err)
+		return
+	}
+	tt.txn = &bumped
+	tt.signed = signed
+}
+
+// bumpFee increases a fee by the percentage, rounding up so small fees still increase
+func (r *resubmitter) bumpFee(fee *ethtypes.HexInteger) *ethtypes.HexInteger {
+	if fee == nil {
+		return nil
+	}
+	bumped := new(big.Int).Mul(fee.BigInt(), big.NewInt(100+r.feeBumpPercent))
+	bumped.Add(bumped, big.NewInt(99))
+	bumped.Div(bumped, big.NewInt(100))
+	return (*ethtypes.HexInteger)(bumped)
+}
+
+func hashTransaction(signed []byte) ethtypes.HexBytes0xPrefix {
+	hash := sha3.NewLegacyKeccak256()
+	hash.Write(signed)
+	return hash.Sum(nil)
+}
@@ -0,0 +1,249 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-signer/internal/signerconfig"
+	"github.com/hyperledger/firefly-signer/mocks/ethsignermocks"
+	"github.com/hyperledger/firefly-signer/mocks/rpcbackendmocks"
+	"github.com/hyperledger/firefly-signer/pkg/approval"
+	"github.com/hyperledger/firefly-signer/pkg/ethsigner"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const resubmitTestFrom = "0xfb075bb99f2aa4c49955bf703509a227d7a12248"
+
+func setTestResubmitConf() {
+	config.Set(signerconfig.ResubmitEnabled, true)
+	config.Set(signerconfig.ResubmitMaxAttempts, 2)
+	config.Set(signerconfig.ResubmitMaxTracked, 2)
+}
+
+func newTestResubmitServer(t *testing.T, confSetters ...func()) (*rpcServer, *rpcbackendmocks.Backend, *ethsignermocks.Wallet, func()) {
+	_, s, done := newTestServer(t, append([]func(){setTestResubmitConf}, confSetters...)...)
+	assert.NotNil(t, s.resubmitter)
+	s.chainID = 12345
+	return s, s.backend.(*rpcbackendmocks.Backend), s.wallet.(*ethsignermocks.Wallet), done
+}
+
+// trackTestTransaction tracks a transaction that is due for resubmission
+func trackTestTransaction(s *rpcServer, nonce uint64, txn *ethsigner.Transaction) *trackedTransaction {
+	txn.Nonce = ethtypes.NewHexIntegerU64(nonce)
+	s.resubmitter.track(s.ctx, s.chainID, *ethtypes.MustNewAddress(resubmitTestFrom), txn, []byte{0x01})
+	tt := s.resubmitter.tracked[fmt.Sprintf("12345:%s:%d", resubmitTestFrom, nonce)]
+	tt.submitted = time.Now().Add(-1 * time.Hour)
+	return tt
+}
+
+func mockResubmitTransactionCount(bm *rpcbackendmocks.Backend, count uint64) {
+	bm.On("CallRPC", mock.Anything, mock.Anything, "eth_getTransactionCount", mock.Anything, "latest").Run(func(args mock.Arguments) {
+		*(args[1].(*ethtypes.HexInteger)) = *ethtypes.NewHexIntegerU64(count)
+	}).Return(nil).Once()
+}
+
+func mockResubmitSend(bm *rpcbackendmocks.Backend, signed ethtypes.HexBytes0xPrefix, rpcErr *rpcbackend.RPCError) {
+	bm.On("CallRPC", mock.Anything, mock.Anything, "eth_sendRawTransaction", signed).Run(func(args mock.Arguments) {
+		*(args[1].(*ethtypes.HexBytes0xPrefix)) = hashTransaction(signed)
+	}).Return(rpcErr).Once()
+}
+
+func TestResubmitBadConfig(t *testing.T) {
+	for _, tc := range []struct {
+		errCode string
+		setConf func()
+	}{
+		{"FF22172", func() { config.Set(signerconfig.ResubmitPolicy, "wrong") }},
+		{"FF22173.*delay", func() { config.Set(signerconfig.ResubmitDelay, "0") }},
+		{"FF22173.*feeBumpPercent", func() { config.Set(signerconfig.ResubmitFeeBumpPercent, 0) }},
+		{"FF22173.*maxAttempts", func() { config.Set(signerconfig.ResubmitMaxTracked, 0) }},
+	} {
+		signerconfig.Reset()
+		setTestResubmitConf()
+		tc.setConf()
+		_, err := NewServer(context.Background(), &ethsignermocks.Wallet{})
+		assert.Regexp(t, tc.errCode, err)
+	}
+}
+
+func TestResubmitTracksSentTransactions(t *testing.T) {
+	s, bm, w, done := newTestResubmitServer(t)
+	defer done()
+
+	w.On("Sign", mock.Anything, mock.Anything, int64(12345)).Return([]byte{0x01}, nil)
+	bm.On("SyncRequest", mock.Anything, mock.Anything).Return(&rpcbackend.RPCResponse{Result: fftypes.JSONAnyPtr(`"0x1234"`)}, nil)
+	sendTestTransaction := func(nonce int) {
+		_, err := s.processRPC(s.ctx, &rpcbackend.RPCRequest{
+			ID:     fftypes.JSONAnyPtr("1"),
+			Method: "eth_sendTransaction",
+			Params: []*fftypes.JSONAny{fftypes.JSONAnyPtr(fmt.Sprintf(`{"from": "%s", "nonce": "%d"}`, resubmitTestFrom, nonce))},
+		})
+		assert.NoError(t, err)
+	}
+
+	sendTestTransaction(1)
+	sendTestTransaction(2)
+	assert.Len(t, s.resubmitter.tracked, 2)
+	assert.Equal(t, ethtypes.HexBytes0xPrefix{0x01}, s.resubmitter.tracked["12345:"+resubmitTestFrom+":1"].signed)
+
+	// A replacement replaces the tracked transaction, but no more transactions are tracked over the limit
+	replaced := s.resubmitter.tracked["12345:"+resubmitTestFrom+":2"]
+	sendTestTransaction(2)
+	sendTestTransaction(3)
+	assert.Len(t, s.resubmitter.tracked, 2)
+	assert.NotSame(t, replaced, s.resubmitter.tracked["12345:"+resubmitTestFrom+":2"])
+
+	// The transaction that was replaced cannot remove the replacement
+	s.resubmitter.untrack(replaced)
+	assert.Len(t, s.resubmitter.tracked, 2)
+}
+
+func TestResubmitMined(t *testing.T) {
+	s, bm, _, done := newTestResubmitServer(t)
+	defer done()
+
+	tt := trackTestTransaction(s, 1, &ethsigner.Transaction{})
+	assert.Equal(t, []*trackedTransaction{tt}, s.resubmitter.due(time.Now()))
+
+	bm.On("CallRPC", mock.Anything, mock.Anything, "eth_getTransactionCount", mock.Anything, "latest").Return(&rpcbackend.RPCError{Message: "pop"}).Once()
+	s.resubmitIfStuck(s.ctx, tt)
+	assert.Len(t, s.resubmitter.tracked, 1)
+
+	mockResubmitTransactionCount(bm, 2)
+	s.resubmitIfStuck(s.ctx, tt)
+	assert.Empty(t, s.resubmitter.tracked)
+	bm.AssertExpectations(t)
+}
+
+func TestResubmitRebroadcast(t *testing.T) {
+	s, bm, _, done := newTestResubmitServer(t, func() {
+		config.Set(signerconfig.ResubmitPolicy, resubmitPolicyRebroadcast)
+	})
+	defer done()
+
+	tt := trackTestTransaction(s, 1, &ethsigner.Transaction{GasPrice: ethtypes.NewHexIntegerU64(100)})
+	assert.Empty(t, s.resubmitter.due(time.Now().Add(-2*time.Hour)))
+
+	mockResubmitTransactionCount(bm, 1)
+	mockResubmitSend(bm, ethtypes.HexBytes0xPrefix{0x01}, nil)
+	s.resubmitIfStuck(s.ctx, tt)
+	assert.Equal(t, 1, tt.attempts)
+	assert.Empty(t, s.resubmitter.due(time.Now()))
+
+	mockResubmitTransactionCount(bm, 1)
+	mockResubmitSend(bm, ethtypes.HexBytes0xPrefix{0x01}, &rpcbackend.RPCError{Message: "already known"})
+	s.resubmitIfStuck(s.ctx, tt)
+	assert.Equal(t, 2, tt.attempts)
+	assert.Equal(t, int64(100), tt.txn.GasPrice.Int64())
+
+	// Abandoned after the maximum attempts
+	mockResubmitTransactionCount(bm, 1)
+	s.resubmitIfStuck(s.ctx, tt)
+	assert.Empty(t, s.resubmitter.tracked)
+	bm.AssertExpectations(t)
+}
+
+func TestResubmitFeeBump(t *testing.T) {
+	s, bm, w, done := newTestResubmitServer(t)
+	defer done()
+
+	tt := trackTestTransaction(s, 1, &ethsigner.Transaction{
+		MaxFeePerGas:         ethtypes.NewHexIntegerU64(1000),
+		MaxPriorityFeePerGas: ethtypes.NewHexIntegerU64(1),
+	})
+	w.On("Sign", mock.Anything, mock.MatchedBy(func(txn *ethsigner.Transaction) bool {
+		return txn.MaxFeePerGas.Int64() == 1100 && txn.MaxPriorityFeePerGas.Int64() == 2 && txn.GasPrice == nil
+	}), int64(12345)).Return([]byte{0x02}, nil).Once()
+	mockResubmitTransactionCount(bm, 1)
+	mockResubmitSend(bm, ethtypes.HexBytes0xPrefix{0x02}, nil)
+	s.resubmitIfStuck(s.ctx, tt)
+	assert.Equal(t, ethtypes.HexBytes0xPrefix{0x02}, tt.signed)
+	assert.Equal(t, int64(1100), tt.txn.MaxFeePerGas.Int64())
+
+	// The transaction is rebroadcast unchanged when it cannot be signed again
+	tt.submitted = time.Now().Add(-1 * time.Hour)
+	w.On("Sign", mock.Anything, mock.Anything, int64(12345)).Return(nil, fmt.Errorf("pop")).Once()
+	mockResubmitTransactionCount(bm, 1)
+	mockResubmitSend(bm, ethtypes.HexBytes0xPrefix{0x02}, nil)
+	s.resubmitIfStuck(s.ctx, tt)
+	assert.Equal(t, int64(1100), tt.txn.MaxFeePerGas.Int64())
+	bm.AssertExpectations(t)
+	w.AssertExpectations(t)
+}
+
+func TestResubmitFeeBumpRejected(t *testing.T) {
+	s, bm, w, done := newTestResubmitServer(t)
+	defer done()
+	hook := &testApprovalHook{review: func(req *approval.Request) (*approval.Decision, error) {
+		return approval.Rejected("not today"), nil
+	}}
+	s.SetApprovalHook(hook)
+
+	// The fee bump is a new signature, so the transaction is rebroadcast unchanged when it is not approved
+	tt := trackTestTransaction(s, 1, &ethsigner.Transaction{GasPrice: ethtypes.NewHexIntegerU64(100)})
+	mockResubmitTransactionCount(bm, 1)
+	mockResubmitSend(bm, ethtypes.HexBytes0xPrefix{0x01}, nil)
+	s.resubmitIfStuck(s.ctx, tt)
+	assert.Equal(t, int64(100), tt.txn.GasPrice.Int64())
+	assert.Len(t, hook.requests, 1)
+	assert.Equal(t, "ffsigner_resubmit", hook.requests[0].Method)
+	assert.Equal(t, int64(110), hook.requests[0].Transaction.GasPrice.Int64())
+	w.AssertNotCalled(t, "Sign", mock.Anything, mock.Anything, mock.Anything)
+	bm.AssertExpectations(t)
+}
+
+func TestResubmitFeeBumpOverCap(t *testing.T) {
+	s, bm, _, done := newTestResubmitServer(t, func() {
+		config.Set(signerconfig.FeeCapsMaxGasPrice, "105")
+	})
+	defer done()
+
+	// No fee bump is signed over the caps, even with the clamp policy
+	tt := trackTestTransaction(s, 1, &ethsigner.Transaction{GasPrice: ethtypes.NewHexIntegerU64(100)})
+	mockResubmitTransactionCount(bm, 1)
+	mockResubmitSend(bm, ethtypes.HexBytes0xPrefix{0x01}, nil)
+	s.resubmitIfStuck(s.ctx, tt)
+	assert.Equal(t, int64(100), tt.txn.GasPrice.Int64())
+	assert.Equal(t, 1, tt.attempts)
+	bm.AssertExpectations(t)
+}
+
+func TestResubmitterLoop(t *testing.T) {
+	s, bm, _, done := newTestResubmitServer(t, func() {
+		config.Set(signerconfig.ResubmitInterval, "1ms")
+	})
+	defer done()
+
+	trackTestTransaction(s, 1, &ethsigner.Transaction{})
+	mockResubmitTransactionCount(bm, 2)
+	startTestServerNoBackend(t, s)
+	for tracked := 1; tracked > 0; {
+		s.resubmitter.mux.Lock()
+		tracked = len(s.resubmitter.tracked)
+		s.resubmitter.mux.Unlock()
+		time.Sleep(1 * time.Millisecond)
+	}
+}
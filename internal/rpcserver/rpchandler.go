@@ -19,7 +19,6 @@ package rpcserver
 import (
 	"context"
 	"encoding/json"
-	"io"
 	"net/http"
 	"strconv"
 	"unicode"
@@ -28,20 +27,32 @@ import (
 	"github.com/hyperledger/firefly-common/pkg/i18n"
 	"github.com/hyperledger/firefly-common/pkg/log"
 	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/hyperledger/firefly-signer/pkg/debuglog"
 	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
 )
 
+type rpcProcessor func(ctx context.Context, rpcReq *rpcbackend.RPCRequest) (*rpcbackend.RPCResponse, error)
+
 func (s *rpcServer) rpcHandler(w http.ResponseWriter, r *http.Request) {
 
-	ctx := r.Context() // will include logging ID from FireFly server framework
+	ctx := extractTraceContext(r) // will include logging ID from FireFly server framework
 
-	b, err := io.ReadAll(r.Body)
+	b, tooLarge, err := s.readRequestBody(ctx, w, r)
+	if tooLarge != nil {
+		s.replyRPCLimitError(ctx, w, tooLarge, http.StatusRequestEntityTooLarge)
+		return
+	}
 	if err != nil {
 		s.replyRPCParseError(ctx, w, b)
 		return
 	}
 
-	log.L(ctx).Tracef("RPC --> %s", b)
+	debuglog.Logf(ctx, debuglog.RPCWire, "RPC --> %s", b)
+
+	if err := s.checkJSONDepth(ctx, b); err != nil {
+		s.replyRPCLimitError(ctx, w, err, http.StatusBadRequest)
+		return
+	}
 
 	if s.sniffFirstByte(b) == '[' {
 		s.handleRPCBatch(ctx, w, b)
@@ -56,7 +67,18 @@ func (s *rpcServer) rpcHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	rpcResponse, err := s.processRPC(ctx, &rpcRequest)
 	if err != nil {
-		s.replyRPC(ctx, w, rpcResponse, http.StatusInternalServerError)
+		status := http.StatusInternalServerError
+		if rpcResponse != nil && rpcResponse.Error != nil {
+			switch rpcResponse.Error.Code {
+			case int64(rpcbackend.RPCCodeLimitExceeded):
+				status = http.StatusTooManyRequests
+			case int64(rpcbackend.RPCCodeTimeout):
+				status = http.StatusGatewayTimeout
+			case int64(rpcbackend.RPCCodeCircuitOpen):
+				status = http.StatusServiceUnavailable
+			}
+		}
+		s.replyRPC(ctx, w, rpcResponse, status)
 		return
 	}
 	s.replyRPC(ctx, w, rpcResponse, http.StatusOK)
@@ -64,19 +86,22 @@ func (s *rpcServer) rpcHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *rpcServer) replyRPCParseError(ctx context.Context, w http.ResponseWriter, b []byte) {
+	s.replyRPC(ctx, w, s.rpcParseErrorResponse(ctx, b), http.StatusBadRequest)
+}
+
+func (s *rpcServer) rpcParseErrorResponse(ctx context.Context, b []byte) *rpcbackend.RPCResponse {
 	log.L(ctx).Errorf("Request could not be parsed: %s", b)
-	rpcError := rpcbackend.RPCErrorResponse(
+	return rpcbackend.RPCErrorResponse(
 		i18n.NewError(ctx, signermsgs.MsgInvalidRequest),
 		fftypes.JSONAnyPtr("1"), // we couldn't parse the request ID
 		rpcbackend.RPCCodeInvalidRequest,
 	)
-	s.replyRPC(ctx, w, rpcError, http.StatusBadRequest)
 }
 
 func (s *rpcServer) replyRPC(ctx context.Context, w http.ResponseWriter, result interface{}, status int) {
 	w.Header().Set("Content-Type", "application/json")
 	b, _ := json.Marshal(result)
-	log.L(ctx).Tracef("RPC <-- %s", b)
+	debuglog.Logf(ctx, debuglog.RPCWire, "RPC <-- %s", b)
 	w.Header().Set("Content-Length", strconv.Itoa(len(b)))
 	w.WriteHeader(status)
 	_, _ = w.Write(b)
@@ -104,25 +129,37 @@ func (s *rpcServer) handleRPCBatch(ctx context.Context, w http.ResponseWriter, b
 		s.replyRPCParseError(ctx, w, batchBytes)
 		return
 	}
+	if err := s.checkBatchSize(ctx, len(rpcArray)); err != nil {
+		s.replyRPCLimitError(ctx, w, err, http.StatusBadRequest)
+		return
+	}
+
+	rpcResponses, failed := s.processRPCBatch(ctx, rpcArray, s.processRPC)
+	status := http.StatusOK
+	if failed {
+		status = http.StatusInternalServerError
+	}
+	s.replyRPC(ctx, w, rpcResponses, status)
+}
 
+func (s *rpcServer) processRPCBatch(ctx context.Context, rpcArray []*rpcbackend.RPCRequest, processor rpcProcessor) (rpcResponses []*rpcbackend.RPCResponse, failed bool) {
 	// Kick off a routine to fill in each
-	rpcResponses := make([]*rpcbackend.RPCResponse, len(rpcArray))
+	rpcResponses = make([]*rpcbackend.RPCResponse, len(rpcArray))
 	results := make(chan error)
 	for i, r := range rpcArray {
 		responseNumber := i
 		rpcReq := r
 		go func() {
 			var err error
-			rpcResponses[responseNumber], err = s.processRPC(ctx, rpcReq)
+			rpcResponses[responseNumber], err = processor(ctx, rpcReq)
 			results <- err
 		}()
 	}
-	status := 200
 	for range rpcArray {
 		err := <-results
 		if err != nil {
-			status = http.StatusInternalServerError
+			failed = true
 		}
 	}
-	s.replyRPC(ctx, w, rpcResponses, status)
+	return rpcResponses, failed
 }
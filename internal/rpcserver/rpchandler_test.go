@@ -41,6 +41,7 @@ func TestSignAndSendTransactionWithNonce(t *testing.T) {
 	url, s, done := newTestServer(t)
 	defer done()
 	s.chainID = 1
+	s.chainIDValidation = false
 
 	w := s.wallet.(*ethsignermocks.Wallet)
 	w.On("Initialize", mock.Anything).Return(nil)
@@ -98,6 +99,7 @@ func TestSignAndSendTransactionWithoutNonce(t *testing.T) {
 	url, s, done := newTestServer(t)
 	defer done()
 	s.chainID = 1
+	s.chainIDValidation = false
 
 	w := s.wallet.(*ethsignermocks.Wallet)
 	w.On("Initialize", mock.Anything).Return(nil)
@@ -159,6 +161,7 @@ func TestServeJSONRPCFail(t *testing.T) {
 	url, s, done := newTestServer(t)
 	defer done()
 	s.chainID = 1
+	s.chainIDValidation = false
 
 	w := s.wallet.(*ethsignermocks.Wallet)
 	w.On("Initialize", mock.Anything).Return(nil)
@@ -210,6 +213,7 @@ func TestServeJSONRPCBatchOK(t *testing.T) {
 	url, s, done := newTestServer(t)
 	defer done()
 	s.chainID = 1
+	s.chainIDValidation = false
 
 	w := s.wallet.(*ethsignermocks.Wallet)
 	w.On("Initialize", mock.Anything).Return(nil)
@@ -289,6 +293,7 @@ func TestServeJSONRPCBatchOneFailed(t *testing.T) {
 	url, s, done := newTestServer(t)
 	defer done()
 	s.chainID = 1
+	s.chainIDValidation = false
 
 	w := s.wallet.(*ethsignermocks.Wallet)
 	w.On("Initialize", mock.Anything).Return(nil)
@@ -357,6 +362,7 @@ func TestServeJSONRPCBatchBadArray(t *testing.T) {
 	url, s, done := newTestServer(t)
 	defer done()
 	s.chainID = 1
+	s.chainIDValidation = false
 
 	w := s.wallet.(*ethsignermocks.Wallet)
 	w.On("Initialize", mock.Anything).Return(nil)
@@ -376,7 +382,8 @@ func TestServeJSONRPCBatchBadArray(t *testing.T) {
 			"id": 1,
 			"error": {
 				"code": -32600,
-				"message": "FF22018: Invalid request data"
+				"message": "FF22018: Invalid request data",
+				"data": {"errorCode": "FFS-RPC-001", "messageKey": "FF22018"}
 			}
 		}
 	`)
@@ -388,6 +395,7 @@ func TestServeJSONRPCBatchEmptyData(t *testing.T) {
 	url, s, done := newTestServer(t)
 	defer done()
 	s.chainID = 1
+	s.chainIDValidation = false
 
 	w := s.wallet.(*ethsignermocks.Wallet)
 	w.On("Initialize", mock.Anything).Return(nil)
@@ -407,7 +415,8 @@ func TestServeJSONRPCBatchEmptyData(t *testing.T) {
 			"id": 1,
 			"error": {
 				"code": -32600,
-				"message": "FF22018: Invalid request data"	
+				"message": "FF22018: Invalid request data",
+				"data": {"errorCode": "FFS-RPC-001", "messageKey": "FF22018"}
 			}
 		}
 	`)
@@ -419,6 +428,7 @@ func TestServeJSONRPCBatchBadJSON(t *testing.T) {
 	url, s, done := newTestServer(t)
 	defer done()
 	s.chainID = 1
+	s.chainIDValidation = false
 
 	w := s.wallet.(*ethsignermocks.Wallet)
 	w.On("Initialize", mock.Anything).Return(nil)
@@ -438,7 +448,8 @@ func TestServeJSONRPCBatchBadJSON(t *testing.T) {
 			"id": 1,
 			"error": {
 				"code": -32600,
-				"message": "FF22018: Invalid request data"	
+				"message": "FF22018: Invalid request data",
+				"data": {"errorCode": "FFS-RPC-001", "messageKey": "FF22018"}
 			}
 		}
 	`)
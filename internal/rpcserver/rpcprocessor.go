@@ -20,36 +20,118 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/hyperledger/firefly-common/pkg/fftypes"
 	"github.com/hyperledger/firefly-common/pkg/i18n"
 	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/hyperledger/firefly-signer/pkg/approval"
 	"github.com/hyperledger/firefly-signer/pkg/ethsigner"
 	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/rpcauth"
 	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
 )
 
-func (s *rpcServer) processRPC(ctx context.Context, rpcReq *rpcbackend.RPCRequest) (*rpcbackend.RPCResponse, error) {
+func (s *rpcServer) processRPC(ctx context.Context, rpcReq *rpcbackend.RPCRequest) (rpcRes *rpcbackend.RPCResponse, err error) {
+	method := rpcReq.Method // might be updated as we process the request
+	ctx, span := s.startServerSpan(ctx, method)
+	defer func() { endServerSpan(span, rpcRes, err) }()
+
+	if s.metrics == nil {
+		return s.accessLogged(ctx, rpcReq, s.routeRPCWithTimeout)
+	}
+	m := s.metrics
+	startTime := m.requestStart(ctx, m.server, &m.serverInFlight)
+	rpcRes, err = s.accessLogged(ctx, rpcReq, s.routeRPCWithTimeout)
+	m.requestComplete(ctx, m.server, &m.serverInFlight, method, rpcOutcome(rpcRes, err), startTime)
+	return rpcRes, err
+}
+
+func (s *rpcServer) routeRPC(ctx context.Context, rpcReq *rpcbackend.RPCRequest) (*rpcbackend.RPCResponse, error) {
 	if rpcReq.ID == nil {
 		err := i18n.NewError(ctx, signermsgs.MsgMissingRequestID)
 		return rpcbackend.RPCErrorResponse(err, rpcReq.ID, rpcbackend.RPCCodeInvalidRequest), err
 	}
 
+	if errRes, err := s.admitRequest(ctx, rpcReq); err != nil {
+		return errRes, err
+	}
+
 	switch rpcReq.Method {
-	case "eth_accounts", "personal_accounts":
+	case "eth_accounts", "eth_requestAccounts", "personal_accounts":
 		return s.processEthAccounts(ctx, rpcReq)
 	case "eth_sendTransaction":
 		return s.processEthSendTransaction(ctx, rpcReq)
+	case "eth_fillTransaction":
+		return s.processEthFillTransaction(ctx, rpcReq)
+	case "ffsigner_previewTransaction":
+		return s.processPreviewTransaction(ctx, rpcReq)
+	case "eth_sendRawTransaction":
+		if s.rawTxPolicyEnabled {
+			return s.processEthSendRawTransaction(ctx, rpcReq)
+		}
+		return s.backend.SyncRequest(ctx, rpcReq)
+	case "personal_unlockAccount":
+		return s.processPersonalUnlockAccount(ctx, rpcReq)
+	case "personal_lockAccount":
+		return s.processPersonalLockAccount(ctx, rpcReq)
+	case "ffsigner_lockAllAccounts":
+		return s.processLockAllAccounts(ctx, rpcReq)
+	case "personal_sign":
+		return s.processPersonalSign(ctx, rpcReq)
+	case "eth_sign":
+		return s.processEthSign(ctx, rpcReq)
+	case "ffsigner_getPublicKey":
+		return s.processGetPublicKey(ctx, rpcReq)
+	case "ffsigner_nonceStatus":
+		return s.processNonceStatus(ctx, rpcReq)
+	case "ffsigner_resyncNonce":
+		return s.processResyncNonce(ctx, rpcReq)
+	case "ffsigner_fillNonceGaps":
+		return s.processFillNonceGaps(ctx, rpcReq)
+	case "ffsigner_reloadConfig":
+		return s.processReloadConfig(ctx, rpcReq)
+	case "eth_subscribe", "eth_unsubscribe":
+		// Only supported over a WebSocket client connection, which intercepts these before we get here
+		err := i18n.NewError(ctx, signermsgs.MsgSubscriptionsNotSupported)
+		return rpcbackend.RPCErrorResponse(err, rpcReq.ID, rpcbackend.RPCCodeInvalidRequest), err
 	default:
+		// Only results from the backend are cached, as the cache is not partitioned by chain
+		if s.responseCache != nil && getChainRoute(ctx) == nil {
+			return s.processCachedRequest(ctx, rpcReq)
+		}
 		return s.backend.SyncRequest(ctx, rpcReq)
 	}
 }
 
+// admitRequest applies the rate limit, method filter and access control checks that every request must pass
+func (s *rpcServer) admitRequest(ctx context.Context, rpcReq *rpcbackend.RPCRequest) (*rpcbackend.RPCResponse, error) {
+	if errRes, err := s.checkRateLimit(ctx, rpcReq, rateLimitRequests); err != nil {
+		return errRes, err
+	}
+	if errRes, err := s.filterMethod(ctx, rpcReq); err != nil {
+		return errRes, err
+	}
+	return s.authorizeMethod(ctx, rpcReq)
+}
+
+// processEthAccounts answers from the wallet, as the backend node knows nothing of our keys. When role
+// based access control is enabled, only the addresses the caller may sign with are returned.
 func (s *rpcServer) processEthAccounts(ctx context.Context, rpcReq *rpcbackend.RPCRequest) (*rpcbackend.RPCResponse, error) {
 	accounts, err := s.wallet.GetAccounts(ctx)
 	if err != nil {
 		return rpcbackend.RPCErrorResponse(err, rpcReq.ID, rpcbackend.RPCCodeInternalError), err
 	}
+	if authorizer := s.authorizer.Load(); authorizer != nil {
+		identity := rpcauth.GetIdentity(ctx)
+		allowed := make([]*ethtypes.Address0xHex, 0, len(accounts))
+		for _, addr := range accounts {
+			if authorizer.AddressAllowed(identity, addr) {
+				allowed = append(allowed, addr)
+			}
+		}
+		accounts = allowed
+	}
 	b, _ := json.Marshal(&accounts)
 	return &rpcbackend.RPCResponse{
 		JSONRpc: "2.0",
@@ -58,50 +140,226 @@ func (s *rpcServer) processEthAccounts(ctx context.Context, rpcReq *rpcbackend.R
 	}, nil
 }
 
-func (s *rpcServer) processEthSendTransaction(ctx context.Context, rpcReq *rpcbackend.RPCRequest) (*rpcbackend.RPCResponse, error) {
+// txnRequest is the transaction of an eth_sendTransaction, eth_fillTransaction or ffsigner_previewTransaction request,
+// once it has been checked
+type txnRequest struct {
+	*signingRequest
+	fromErr error // set when the from address did not parse, so from is nil
+}
 
+// checkTransaction parses the transaction of an eth_sendTransaction or eth_fillTransaction request, and applies the
+// chain ID, access control and transaction policy checks. The returned context is routed by the chain ID of the transaction.
+func (s *rpcServer) checkTransaction(ctx context.Context, rpcReq *rpcbackend.RPCRequest) (context.Context, *txnRequest, *rpcbackend.RPCResponse, error) {
 	if len(rpcReq.Params) < 1 {
 		err := i18n.NewError(ctx, signermsgs.MsgInvalidParamCount, 1, len(rpcReq.Params))
-		return rpcbackend.RPCErrorResponse(err, rpcReq.ID, rpcbackend.RPCCodeInvalidRequest), err
+		return ctx, nil, rpcbackend.RPCErrorResponse(err, rpcReq.ID, rpcbackend.RPCCodeInvalidRequest), err
+	}
+
+	if s.ens != nil {
+		// The name is resolved on the chain the transaction is for
+		ctx = s.routeByTransactionChainID(ctx, rpcReq.Params[0].Bytes())
+		if errRes, err := s.resolveENSTo(ctx, rpcReq); err != nil {
+			return ctx, nil, errRes, err
+		}
+	}
+
+	if s.txValidation != nil {
+		if err := s.txValidation.validate(ctx, rpcReq.Params[0].Bytes()); err != nil {
+			return ctx, nil, rpcbackend.RPCErrorResponse(err, rpcReq.ID, rpcbackend.RPCCodeInvalidRequest), err
+		}
 	}
 
 	var txn ethsigner.Transaction
-	err := json.Unmarshal(rpcReq.Params[0].Bytes(), &txn)
-	if err != nil {
+	if err := json.Unmarshal(rpcReq.Params[0].Bytes(), &txn); err != nil {
 		err := i18n.WrapError(ctx, err, signermsgs.MsgInvalidTransaction)
-		return rpcbackend.RPCErrorResponse(err, rpcReq.ID, rpcbackend.RPCCodeParseError), err
+		return ctx, nil, rpcbackend.RPCErrorResponse(err, rpcReq.ID, rpcbackend.RPCCodeParseError), err
 	}
 
 	if txn.From == nil {
 		err := i18n.NewError(ctx, signermsgs.MsgMissingFrom)
-		return rpcbackend.RPCErrorResponse(err, rpcReq.ID, rpcbackend.RPCCodeInvalidRequest), err
+		return ctx, nil, rpcbackend.RPCErrorResponse(err, rpcReq.ID, rpcbackend.RPCCodeInvalidRequest), err
 	}
+	setSpanFrom(ctx, txn.From)
+	ctx = s.routeByTransactionChainID(ctx, rpcReq.Params[0].Bytes())
 
-	// We have trivial nonce management built-in for sequential signing API calls, by making a JSON/RPC request
-	// to the up-stream node. This should not be relied upon for production use cases.
-	// See FireFly Transaction Manager, or FireFly EthConnect, for more advanced nonce management capabilities.
-	if txn.Nonce == nil {
-		var from ethtypes.Address0xHex
-		err := json.Unmarshal(txn.From, &from)
-		if err != nil {
-			return nil, err
+	// Some wallets select keys by other identifiers, so the from address is only required to parse
+	// where it is used - from is nil when it did not parse
+	var from *ethtypes.Address0xHex
+	var parsedFrom ethtypes.Address0xHex
+	fromErr := json.Unmarshal(txn.From, &parsedFrom)
+	if fromErr == nil {
+		from = &parsedFrom
+	}
+	req := &signingRequest{method: rpcReq.Method, operation: signOpTransaction, from: from, txn: &txn}
+
+	if errRes, err := s.checkChainID(ctx, rpcReq, rpcReq.Params[0].Bytes()); err != nil {
+		errRes, err = s.rejectedByPolicy(ctx, req, errRes, err)
+		return ctx, nil, errRes, err
+	}
+
+	if s.authorizer.Load() != nil {
+		if fromErr != nil {
+			err := i18n.WrapError(ctx, fromErr, signermsgs.MsgInvalidTransaction)
+			return ctx, nil, rpcbackend.RPCErrorResponse(err, rpcReq.ID, rpcbackend.RPCCodeInvalidRequest), err
 		}
-		rpcErr := s.backend.CallRPC(ctx, &txn.Nonce, "eth_getTransactionCount", &from, "pending")
-		if rpcErr != nil {
-			return rpcbackend.RPCErrorResponse(rpcErr.Error(), rpcReq.ID, rpcbackend.RPCCodeInternalError), rpcErr.Error()
+		if errRes, err := s.authorizeAddress(ctx, rpcReq, from); err != nil {
+			errRes, err = s.rejectedByPolicy(ctx, req, errRes, err)
+			return ctx, nil, errRes, err
+		}
+	}
+
+	if s.txPolicy != nil {
+		if err := s.txPolicy.check(ctx, &txn); err != nil {
+			errRes, err := s.rejectedByPolicy(ctx, req, rpcbackend.RPCErrorResponse(err, rpcReq.ID, rpcbackend.RPCCodeInvalidRequest), err)
+			return ctx, nil, errRes, err
+		}
+	}
+	return ctx, &txnRequest{signingRequest: req, fromErr: fromErr}, nil, nil
+}
+
+// populateTransaction simulates the transaction, and populates its gas limit and fees, as configured
+func (s *rpcServer) populateTransaction(ctx context.Context, rpcReq *rpcbackend.RPCRequest, req *txnRequest) (*rpcbackend.RPCResponse, error) {
+	if s.preflightEnabled {
+		if errRes, err := s.preflightTransaction(ctx, rpcReq, req.txn); err != nil {
+			return errRes, err
+		}
+	}
+
+	if s.gasEstimateEnabled && req.txn.GasLimit == nil {
+		if errRes, err := s.estimateGas(ctx, rpcReq, req.txn); err != nil {
+			return errRes, err
+		}
+	}
+
+	if s.fees != nil {
+		if errRes, err := s.populateFees(ctx, rpcReq, req.txn); err != nil {
+			return errRes, err
+		}
+	}
+
+	if s.feeCaps != nil {
+		if err := s.feeCaps.apply(ctx, req.txn); err != nil {
+			return s.rejectedByPolicy(ctx, req.signingRequest, rpcbackend.RPCErrorResponse(err, rpcReq.ID, rpcbackend.RPCCodeInvalidRequest), err)
+		}
+	}
+	return nil, nil
+}
+
+// signedTransaction is a transaction that passed every check, and was signed with its nonce assigned
+type signedTransaction struct {
+	ctx    context.Context // routed by the chain ID of the transaction
+	req    *txnRequest
+	signed ethtypes.HexBytes0xPrefix
+	// release must be called once the outcome of submitting the transaction is known, returning the nonce if the
+	// transaction was definitely not submitted, and passing the turn to the next transaction from the address
+	release func(returnNonce bool)
+}
+
+// signTransaction applies the checks and population of an eth_sendTransaction request, assigns the nonce if it is
+// not set, and signs the transaction
+func (s *rpcServer) signTransaction(ctx context.Context, rpcReq *rpcbackend.RPCRequest) (*signedTransaction, *rpcbackend.RPCResponse, error) {
+	ctx, req, errRes, err := s.checkTransaction(ctx, rpcReq)
+	if err != nil {
+		return nil, errRes, err
+	}
+	txn, from := req.txn, req.from
+
+	if errRes, err := s.checkRateLimit(ctx, rpcReq, rateLimitSigning); err != nil {
+		errRes, err = s.rejectedByPolicy(ctx, req.signingRequest, errRes, err)
+		return nil, errRes, err
+	}
+
+	if errRes, err := s.populateTransaction(ctx, rpcReq, req); err != nil {
+		return nil, errRes, err
+	}
+	if txn.Nonce == nil && req.fromErr != nil {
+		return nil, nil, req.fromErr
+	}
+
+	// The approver sees the transaction as it will be signed - except for a nonce the signer assigns, which is
+	// only assigned once it is approved, so a slow or rejected approval does not hold up or leave a gap in the
+	// nonces of the address
+	if errRes, err := s.approveSigning(ctx, rpcReq, req.signingRequest, &approval.Request{}); err != nil {
+		return nil, errRes, err
+	}
+
+	// Transactions from the same address take turns from here until they are submitted, so their nonces are
+	// assigned and reach the node in the order they arrived
+	done := func() {}
+	if s.senderQueues != nil && from != nil {
+		if done, err = s.senderQueues.waitTurn(ctx, *from); err != nil {
+			code := rpcbackend.RPCCodeLimitExceeded
+			if ctx.Err() != nil {
+				code = rpcbackend.RPCCodeInternalError
+			}
+			return nil, rpcbackend.RPCErrorResponse(err, rpcReq.ID, code), err
+		}
+	}
+
+	// A nonce assigned by the nonce manager is returned if the transaction is definitely not submitted
+	returnNonce := func() {}
+	if txn.Nonce == nil {
+		// Nonces are only managed locally for the backend, as the nonce manager is not partitioned by chain
+		if s.nonceManager != nil && getChainRoute(ctx) == nil {
+			nonce, err := s.nonceManager.AssignNonce(ctx, *from)
+			if err != nil {
+				done()
+				return nil, rpcbackend.RPCErrorResponse(err, rpcReq.ID, rpcbackend.RPCCodeInternalError), err
+			}
+			txn.Nonce = ethtypes.NewHexIntegerU64(nonce)
+			returnNonce = func() { s.nonceManager.ReturnNonce(ctx, *from, nonce) }
+		} else {
+			// Without the nonce manager we have trivial nonce management built-in for sequential signing API calls,
+			// by making a JSON/RPC request to the up-stream node. This should not be relied upon for production use cases.
+			// See FireFly Transaction Manager, or FireFly EthConnect, for more advanced nonce management capabilities.
+			rpcErr := s.backend.CallRPC(ctx, &txn.Nonce, "eth_getTransactionCount", from, "pending")
+			if rpcErr != nil {
+				done()
+				return nil, rpcbackend.RPCErrorResponse(rpcErr.Error(), rpcReq.ID, rpcbackend.RPCCodeInternalError), rpcErr.Error()
+			}
 		}
 	}
 
 	// Sign the transaction
 	var hexData ethtypes.HexBytes0xPrefix
-	hexData, err = s.wallet.Sign(ctx, &txn, s.chainID)
+	startTime := time.Now()
+	hexData, err = s.wallet.Sign(ctx, txn, s.chainIDFor(ctx))
+	s.signOperation(ctx, req.signingRequest, startTime, hexData, err)
 	if err != nil {
-		return rpcbackend.RPCErrorResponse(err, rpcReq.ID, rpcbackend.RPCCodeInternalError), err
+		returnNonce()
+		done()
+		return nil, rpcbackend.RPCErrorResponse(err, rpcReq.ID, rpcbackend.RPCCodeInternalError), err
 	}
+	return &signedTransaction{
+		ctx:    ctx,
+		req:    req,
+		signed: hexData,
+		release: func(shouldReturnNonce bool) {
+			if shouldReturnNonce {
+				returnNonce()
+			}
+			done()
+		},
+	}, nil, nil
+}
+
+func (s *rpcServer) processEthSendTransaction(ctx context.Context, rpcReq *rpcbackend.RPCRequest) (*rpcbackend.RPCResponse, error) {
+	st, errRes, err := s.signTransaction(ctx, rpcReq)
+	if err != nil {
+		return errRes, err
+	}
+	ctx = st.ctx
 
 	// Progress with the original request, now updated with a raw transaction fully signed
 	rpcReq.Method = "eth_sendRawTransaction"
-	rpcReq.Params = []*fftypes.JSONAny{fftypes.JSONAnyPtr(fmt.Sprintf(`"%s"`, hexData))}
-	return s.backend.SyncRequest(ctx, rpcReq)
+	rpcReq.Params = []*fftypes.JSONAny{fftypes.JSONAnyPtr(fmt.Sprintf(`"%s"`, st.signed))}
+	rpcRes, err := s.backend.SyncRequest(ctx, rpcReq)
+	// The node rejected the transaction if it could be reached, so the nonce can be used again. When the node
+	// could not be reached we cannot know if the transaction was submitted, so the nonce stays assigned.
+	st.release(err != nil && !rpcbackend.IsBackendUnavailable(err))
+	if err == nil && s.resubmitter != nil && st.req.from != nil {
+		s.resubmitter.track(ctx, s.chainIDFor(ctx), *st.req.from, st.req.txn, st.signed)
+	}
+	return rpcRes, err
 
 }
@@ -0,0 +1,96 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"context"
+	"sync"
+
+	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-signer/internal/signerconfig"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+)
+
+// senderQueues give transactions from each address their turn to be signed and submitted one at a time, in
+// the order they arrived, so nonces are assigned and reach the node in order. Each address has its own queue,
+// so transactions from different addresses are still processed concurrently.
+type senderQueues struct {
+	maxPending int
+
+	mux    sync.Mutex
+	queues map[ethtypes.Address0xHex][]chan struct{} // the head of each queue holds the turn
+}
+
+func newSenderQueues(ctx context.Context) (*senderQueues, error) {
+	maxPending := config.GetInt(signerconfig.SenderQueueMaxPending)
+	if maxPending <= 0 {
+		return nil, i18n.NewError(ctx, signermsgs.MsgBadSenderQueueMaxPending, maxPending)
+	}
+	return &senderQueues{
+		maxPending: maxPending,
+		queues:     make(map[ethtypes.Address0xHex][]chan struct{}),
+	}, nil
+}
+
+// waitTurn waits for the turn of a transaction from the address, returning a function that must be
+// called to pass the turn to the next transaction once it is submitted
+func (sq *senderQueues) waitTurn(ctx context.Context, addr ethtypes.Address0xHex) (done func(), err error) {
+	sq.mux.Lock()
+	queue := sq.queues[addr]
+	if len(queue) > sq.maxPending {
+		sq.mux.Unlock()
+		return nil, i18n.NewError(ctx, signermsgs.MsgSenderQueueFull, addr)
+	}
+	turn := make(chan struct{})
+	if len(queue) == 0 {
+		close(turn)
+	}
+	sq.queues[addr] = append(queue, turn)
+	sq.mux.Unlock()
+
+	done = func() { sq.leave(addr, turn) }
+	select {
+	case <-turn:
+		return done, nil
+	case <-ctx.Done():
+		done()
+		return nil, i18n.NewError(ctx, signermsgs.MsgSenderQueueWaitCanceled, addr)
+	}
+}
+
+// leave removes a transaction from the queue, passing the turn on if it held it
+func (sq *senderQueues) leave(addr ethtypes.Address0xHex, turn chan struct{}) {
+	sq.mux.Lock()
+	defer sq.mux.Unlock()
+	queue := sq.queues[addr]
+	for i, t := range queue {
+		if t == turn {
+			queue = append(queue[:i:i], queue[i+1:]...)
+			if i == 0 && len(queue) > 0 {
+				close(queue[0])
+			}
+			break
+		}
+	}
+	if len(queue) == 0 {
+		delete(sq.queues, addr)
+	} else {
+		sq.queues[addr] = queue
+	}
+}
@@ -0,0 +1,160 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-signer/internal/signerconfig"
+	"github.com/hyperledger/firefly-signer/mocks/ethsignermocks"
+	"github.com/hyperledger/firefly-signer/mocks/rpcbackendmocks"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func setTestSenderQueueConf() {
+	config.Set(signerconfig.SenderQueueEnabled, true)
+	config.Set(signerconfig.SenderQueueMaxPending, 2)
+}
+
+func newTestSenderQueues(t *testing.T) *senderQueues {
+	signerconfig.Reset()
+	setTestSenderQueueConf()
+	sq, err := newSenderQueues(context.Background())
+	assert.NoError(t, err)
+	return sq
+}
+
+// queueTestTurn queues a transaction, waiting until it is in the queue before returning
+func queueTestTurn(ctx context.Context, sq *senderQueues, addr ethtypes.Address0xHex, n int, turns chan int) chan func() {
+	dones := make(chan func(), 1)
+	go func() {
+		done, err := sq.waitTurn(ctx, addr)
+		if err != nil {
+			turns <- -n
+			return
+		}
+		turns <- n
+		dones <- done
+	}()
+	for queued := 0; queued < n; {
+		sq.mux.Lock()
+		queued = len(sq.queues[addr])
+		sq.mux.Unlock()
+		time.Sleep(1 * time.Millisecond)
+	}
+	return dones
+}
+
+func TestSenderQueueTurnsInOrder(t *testing.T) {
+	sq := newTestSenderQueues(t)
+	addr := *ethtypes.MustNewAddress("0xfb075bb99f2aa4c49955bf703509a227d7a12248")
+	other := *ethtypes.MustNewAddress("0x497eedc4299dea2f2a364be10025d0ad0f702de3")
+
+	done1, err := sq.waitTurn(context.Background(), addr)
+	assert.NoError(t, err)
+	turns := make(chan int, 3)
+	dones2 := queueTestTurn(context.Background(), sq, addr, 2, turns)
+	dones3 := queueTestTurn(context.Background(), sq, addr, 3, turns)
+
+	// Other addresses do not wait
+	doneOther, err := sq.waitTurn(context.Background(), other)
+	assert.NoError(t, err)
+	doneOther()
+
+	// The queue is full
+	_, err = sq.waitTurn(context.Background(), addr)
+	assert.Regexp(t, "FF22169", err)
+
+	done1()
+	assert.Equal(t, 2, <-turns)
+	(<-dones2)()
+	assert.Equal(t, 3, <-turns)
+	(<-dones3)()
+	assert.Empty(t, sq.queues)
+}
+
+func TestSenderQueueWaitCanceled(t *testing.T) {
+	sq := newTestSenderQueues(t)
+	addr := *ethtypes.MustNewAddress("0xfb075bb99f2aa4c49955bf703509a227d7a12248")
+
+	done1, err := sq.waitTurn(context.Background(), addr)
+	assert.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	turns := make(chan int, 2)
+	queueTestTurn(ctx, sq, addr, 2, turns)
+	dones3 := queueTestTurn(context.Background(), sq, addr, 3, turns)
+
+	// A canceled transaction leaves the queue without taking its turn
+	cancel()
+	assert.Equal(t, -2, <-turns)
+	done1()
+	assert.Equal(t, 3, <-turns)
+	(<-dones3)()
+	assert.Empty(t, sq.queues)
+}
+
+func TestSenderQueueBadConfig(t *testing.T) {
+	signerconfig.Reset()
+	setTestSenderQueueConf()
+	config.Set(signerconfig.SenderQueueMaxPending, 0)
+	_, err := NewServer(context.Background(), &ethsignermocks.Wallet{})
+	assert.Regexp(t, "FF22171", err)
+}
+
+func TestSenderQueueSendTransaction(t *testing.T) {
+	_, s, done := newTestServer(t, setTestSenderQueueConf)
+	defer done()
+	assert.NotNil(t, s.senderQueues)
+
+	w := s.wallet.(*ethsignermocks.Wallet)
+	w.On("Sign", mock.Anything, mock.Anything, mock.Anything).Return([]byte{0x01}, nil)
+	bm := s.backend.(*rpcbackendmocks.Backend)
+	bm.On("SyncRequest", mock.Anything, mock.Anything).Return(&rpcbackend.RPCResponse{Result: fftypes.JSONAnyPtr(`"0x1234"`)}, nil)
+	txReq := func() *rpcbackend.RPCRequest {
+		return &rpcbackend.RPCRequest{
+			ID:     fftypes.JSONAnyPtr("1"),
+			Method: "eth_sendTransaction",
+			Params: []*fftypes.JSONAny{fftypes.JSONAnyPtr(`{"from": "0xfb075bb99f2aa4c49955bf703509a227d7a12248", "nonce": "0x1"}`)},
+		}
+	}
+
+	_, err := s.processRPC(s.ctx, txReq())
+	assert.NoError(t, err)
+	assert.Empty(t, s.senderQueues.queues)
+
+	// Fill the queue for the address
+	addr := *ethtypes.MustNewAddress("0xfb075bb99f2aa4c49955bf703509a227d7a12248")
+	turn := make(chan struct{})
+	s.senderQueues.queues[addr] = []chan struct{}{turn, make(chan struct{}), make(chan struct{})}
+	rpcRes, err := s.processRPC(s.ctx, txReq())
+	assert.Regexp(t, "FF22169", err)
+	assert.Equal(t, int64(rpcbackend.RPCCodeLimitExceeded), rpcRes.Error.Code)
+
+	s.senderQueues.queues[addr] = []chan struct{}{turn}
+	ctx, cancel := context.WithCancel(s.ctx)
+	cancel()
+	rpcRes, err = s.processRPC(ctx, txReq())
+	assert.Regexp(t, "FF22170", err)
+	assert.Equal(t, int64(rpcbackend.RPCCodeInternalError), rpcRes.Error.Code)
+}
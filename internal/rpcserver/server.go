@@ -18,17 +18,32 @@ package rpcserver
 
 import (
 	"context"
+	"crypto/tls"
 	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
 	"github.com/hyperledger/firefly-common/pkg/config"
 	"github.com/hyperledger/firefly-common/pkg/ffresty"
 	"github.com/hyperledger/firefly-common/pkg/httpserver"
 	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-common/pkg/metric"
+	"github.com/hyperledger/firefly-common/pkg/wsclient"
 	"github.com/hyperledger/firefly-signer/internal/signerconfig"
 	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/hyperledger/firefly-signer/pkg/approval"
+	"github.com/hyperledger/firefly-signer/pkg/debuglog"
+	"github.com/hyperledger/firefly-signer/pkg/ens"
 	"github.com/hyperledger/firefly-signer/pkg/ethsigner"
-	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/fswallet"
+	"github.com/hyperledger/firefly-signer/pkg/gasoracle"
+	"github.com/hyperledger/firefly-signer/pkg/nonces"
+	"github.com/hyperledger/firefly-signer/pkg/persistence"
+	"github.com/hyperledger/firefly-signer/pkg/rpcauth"
 	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
 )
 
@@ -36,46 +51,458 @@ type Server interface {
 	Start() error
 	Stop()
 	WaitStop() error
+	Reload(ctx context.Context) error
 }
 
 func NewServer(ctx context.Context, wallet ethsigner.Wallet) (ss Server, err error) {
-
-	httpClient, err := ffresty.New(ctx, signerconfig.BackendConfig)
+	s, err := newServer(ctx, wallet, nil, true)
 	if err != nil {
 		return nil, err
 	}
+	return s, nil
+}
+
+// newServer creates the server, with the listeners of the JSON/RPC, admin and metrics servers unless it is
+// embedded in-process. The store is the persistence of an embedding application, if it provides one.
+func newServer(ctx context.Context, wallet ethsigner.Wallet, store persistence.Store, listen bool) (_ *rpcServer, err error) {
+
 	s := &rpcServer{
-		backend:       rpcbackend.NewRPCClient(httpClient),
 		apiServerDone: make(chan error),
 		wallet:        wallet,
+		wsConnections: make(map[string]*wsConnection),
+		wsUpgrader:    newWSUpgrader(),
 		chainID:       config.GetInt64(signerconfig.BackendChainID),
+
+		wsPingInterval:   signerconfig.ServerConfig.GetDuration(signerconfig.ServerConfWSPingInterval),
+		wsMaxMessageSize: signerconfig.ServerConfig.GetByteSize(signerconfig.ServerConfWSMaxMessageSize),
+
+		maxRequestSize: signerconfig.ServerConfig.GetByteSize(signerconfig.ServerConfMaxRequestSize),
+		maxBatchSize:   signerconfig.ServerConfig.GetInt(signerconfig.ServerConfMaxBatchSize),
+		maxJSONDepth:   signerconfig.ServerConfig.GetInt(signerconfig.ServerConfMaxJSONDepth),
+
+		chainIDValidation:         config.GetBool(signerconfig.BackendChainIDValidationEnabled),
+		chainIDValidationInterval: config.GetDuration(signerconfig.BackendChainIDValidationInterval),
+
+		personalSignEnabled: config.GetBool(signerconfig.PersonalSignEnabled),
+		ethSignEnabled:      config.GetBool(signerconfig.DangerousMethodsEthSign),
+		requireUnlock:       config.GetBool(signerconfig.FileWalletEnabled) && signerconfig.FileWalletConfig.GetBool(fswallet.ConfigRequireUnlock),
+		preflightEnabled:    config.GetBool(signerconfig.PreflightEnabled),
+		preflightBlockTag:   config.GetString(signerconfig.PreflightBlockTag),
+
+		gasEstimateEnabled:    config.GetBool(signerconfig.GasEstimateEnabled),
+		gasEstimateMultiplier: config.GetFloat64(signerconfig.GasEstimateMultiplier),
+		gasEstimateCap:        config.GetUint64(signerconfig.GasEstimateCap),
+
+		rawTxPolicyEnabled:      config.GetBool(signerconfig.TxPolicyRawTransactionsEnabled),
+		rawTxManagedSendersOnly: config.GetBool(signerconfig.TxPolicyRawTransactionsManagedSendersOnly),
+
+		approvalTimeout: config.GetDuration(signerconfig.SigningApprovalTimeout),
+	}
+	if s.gasEstimateMultiplier < 1 {
+		return nil, i18n.NewError(ctx, signermsgs.MsgBadGasEstimateMultiplier, s.gasEstimateMultiplier)
+	}
+	logModules, err := configLogModules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	debuglog.SetModules(logModules)
+	if config.GetBool(signerconfig.FeesEnabled) {
+		if s.fees, err = s.newFeeEstimator(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if s.feeCaps, err = newFeeCaps(ctx); err != nil {
+		return nil, err
+	}
+	if s.txPolicy, err = newTxPolicy(ctx); err != nil {
+		return nil, err
+	}
+	if config.GetBool(signerconfig.TxValidationEnabled) {
+		s.txValidation = newTxValidation()
 	}
 	s.ctx, s.cancelCtx = context.WithCancel(ctx)
 
-	s.apiServer, err = httpserver.NewHTTPServer(ctx, "server", s.router(), s.apiServerDone, signerconfig.ServerConfig, signerconfig.CorsConfig)
-	if err != nil {
+	if err := s.initBackend(ctx); err != nil {
+		return nil, err
+	}
+
+	if err := s.initChains(ctx); err != nil {
+		return nil, err
+	}
+
+	if config.GetBool(signerconfig.ENSEnabled) {
+		if err := s.initENS(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.initAuth(ctx); err != nil {
+		return nil, err
+	}
+
+	if config.GetBool(signerconfig.AccessLogEnabled) {
+		if s.accessLog, err = newAccessLogger(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	if config.GetBool(signerconfig.RateLimitEnabled) {
+		s.rateLimiter = newRateLimiter()
+	}
+
+	if s.methodTimeouts, err = newMethodTimeouts(ctx); err != nil {
+		return nil, err
+	}
+
+	if config.GetBool(signerconfig.ResponseCacheEnabled) {
+		if s.responseCache, err = newResponseCache(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	if config.GetBool(signerconfig.SenderQueueEnabled) {
+		if s.senderQueues, err = newSenderQueues(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	if config.GetBool(signerconfig.ResubmitEnabled) {
+		if s.resubmitter, err = newResubmitter(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	if listen && signerconfig.MetricsConfig.GetBool(signerconfig.MetricsConfEnabled) {
+		if err := s.initMetrics(ctx, metric.NewPrometheusMetricsRegistry(metricsComponentName)); err != nil {
+			return nil, err
+		}
+	}
+
+	if signerconfig.AuditWebhookConfig.GetBool(signerconfig.AuditWebhookConfEnabled) {
+		if s.auditWebhook, err = newAuditWebhook(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.initPersistence(ctx, store); err != nil {
+		return nil, err
+	}
+
+	if err := s.initNonces(ctx); err != nil {
+		return nil, err
+	}
+
+	if !listen {
+		return s, nil
+	}
+
+	if signerconfig.AdminConfig.GetBool(signerconfig.AdminConfEnabled) {
+		if err := s.initAdmin(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.initListeners(ctx); err != nil {
 		return nil, err
 	}
 
 	return s, err
 }
 
+// initListeners creates the TCP listener (unless disabled), and the UNIX domain socket listener (when configured)
+func (s *rpcServer) initListeners(ctx context.Context) (err error) {
+	tcpEnabled := signerconfig.ServerConfig.GetBool(signerconfig.ServerConfTCPEnabled)
+	unixSocketEnabled := signerconfig.ServerConfig.GetString(signerconfig.ServerConfUnixSocketPath) != ""
+	if !tcpEnabled && !unixSocketEnabled {
+		return i18n.NewError(ctx, signermsgs.MsgNoServerListeners)
+	}
+	router := s.router()
+	if tcpEnabled {
+		s.apiServer, err = httpserver.NewHTTPServer(ctx, "server", router, s.apiServerDone, signerconfig.ServerConfig, signerconfig.CorsConfig)
+		if err != nil {
+			return err
+		}
+	}
+	if unixSocketEnabled {
+		s.unixSocketServer, err = newUnixSocketServer(ctx, signerconfig.ServerConfig, router)
+	}
+	return err
+}
+
+func isWebSocketURL(u string) bool {
+	u = strings.ToLower(u)
+	return strings.HasPrefix(u, "ws://") || strings.HasPrefix(u, "wss://")
+}
+
+const ipcURLPrefix = "unix://"
+
+// isIPCURL is true for a unix:// URL, with the path of the IPC socket of a co-located node
+func isIPCURL(u string) bool {
+	return strings.HasPrefix(strings.ToLower(u), ipcURLPrefix)
+}
+
+// isStreamingURL is true for a backend with a persistent connection, which supports subscriptions
+func isStreamingURL(u string) bool {
+	return isWebSocketURL(u) || isIPCURL(u)
+}
+
+func backendURLs() []string {
+	return append([]string{signerconfig.BackendConfig.GetString(ffresty.HTTPConfigURL)}, config.GetStringSlice(signerconfig.BackendFailoverURLs)...)
+}
+
+func backendFailoverOptions() rpcbackend.FailoverOptions {
+	return rpcbackend.FailoverOptions{
+		HealthCheckInterval: config.GetDuration(signerconfig.BackendFailoverHealthCheckInterval),
+		HealthCheckTimeout:  config.GetDuration(signerconfig.BackendFailoverHealthCheckTimeout),
+		HealthCheckMethod:   config.GetString(signerconfig.BackendFailoverHealthCheckMethod),
+		StickySubscriptions: config.GetBool(signerconfig.BackendFailoverStickySubscriptions),
+	}
+}
+
+func backendCircuitBreakerOptions() rpcbackend.CircuitBreakerOptions {
+	return rpcbackend.CircuitBreakerOptions{
+		FailureRateThreshold: config.GetFloat64(signerconfig.BackendCircuitBreakerFailureRateThreshold),
+		MinimumRequests:      config.GetInt(signerconfig.BackendCircuitBreakerMinimumRequests),
+		Window:               config.GetDuration(signerconfig.BackendCircuitBreakerWindow),
+		SlowCallThreshold:    config.GetDuration(signerconfig.BackendCircuitBreakerSlowCallThreshold),
+		OpenDuration:         config.GetDuration(signerconfig.BackendCircuitBreakerOpenDuration),
+		HalfOpenProbes:       config.GetInt(signerconfig.BackendCircuitBreakerHalfOpenProbes),
+	}
+}
+
+// initBackend builds a backend for the primary URL, and for each failover URL. When there are
+// failover URLs they are combined into a single backend that routes to whichever is healthy.
+func (s *rpcServer) initBackend(ctx context.Context) error {
+	// The scheme of the backend URL determines whether we connect over HTTP, WebSockets or IPC.
+	// WebSocket and IPC backends can be mixed, as both support subscriptions.
+	urls := backendURLs()
+	isStreaming := isStreamingURL(urls[0])
+	for _, backendURL := range urls[1:] {
+		if isStreamingURL(backendURL) != isStreaming {
+			return i18n.NewError(ctx, signermsgs.MsgFailoverMixedSchemes, backendURL)
+		}
+	}
+	s.backendURLs = urls
+
+	if !isStreaming {
+		gen, err := s.newHTTPBackend(ctx, urls)
+		if err != nil {
+			return err
+		}
+		s.httpBackend = &switchableBackend{}
+		s.httpBackend.current.Store(gen)
+		s.backend = s.httpBackend
+		return nil
+	}
+
+	wsBackends := make([]rpcbackend.WebSocketRPCClient, 0, len(urls))
+	for i, backendURL := range urls {
+		wsConf, err := wsclient.GenerateConfig(ctx, signerconfig.BackendConfig)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			wsConf.HTTPURL = backendURL
+			wsConf.WebSocketURL = ""
+		}
+		var wsBackend rpcbackend.WebSocketRPCClient
+		if isIPCURL(backendURL) {
+			// The connection to an IPC socket is retried with the same settings as a WebSocket
+			wsBackend = rpcbackend.NewIPCRPCClient(&rpcbackend.IPCConfig{
+				Path:                   backendURL[len(ipcURLPrefix):],
+				ConnectionTimeout:      wsConf.ConnectionTimeout,
+				InitialConnectAttempts: wsConf.InitialConnectAttempts,
+				InitialDelay:           wsConf.InitialDelay,
+				MaximumDelay:           wsConf.MaximumDelay,
+			})
+		} else {
+			wsConf.TLSClientConfig = withBackendServerName(wsConf.TLSClientConfig)
+			wsBackend = rpcbackend.NewWSRPCClient(wsConf)
+		}
+		if config.GetBool(signerconfig.BackendCircuitBreakerEnabled) {
+			wsBackend = rpcbackend.NewCircuitBreakerWSRPCClient(s.ctx, backendCircuitBreakerOptions(), wsBackend)
+			s.wsCircuitBreakers = append(s.wsCircuitBreakers, &backendCircuitBreaker{url: backendURL, breaker: wsBackend.(rpcbackend.CircuitBreaker)})
+		}
+		wsBackends = append(wsBackends, wsBackend)
+	}
+	if len(wsBackends) == 1 {
+		s.wsBackend = wsBackends[0]
+	} else {
+		s.wsBackend = rpcbackend.NewFailoverWSRPCClient(s.ctx, backendFailoverOptions(), wsBackends...)
+	}
+	s.backend = s.wsBackend
+	return nil
+}
+
+// newHTTPBackend builds a backend for each HTTP URL, combined into a failover backend when there is more
+// than one. Its background routines (for batching and health checks) run until the generation is retired.
+func (s *rpcServer) newHTTPBackend(ctx context.Context, urls []string) (*backendGeneration, error) {
+	genCtx, cancel := context.WithCancel(s.ctx)
+	backends := make([]rpcbackend.Backend, 0, len(urls))
+	var circuitBreakers []*backendCircuitBreaker
+	for _, backendURL := range urls {
+		httpConf, err := ffresty.GenerateConfig(ctx, signerconfig.BackendConfig)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		httpConf.URL = backendURL
+		httpConf.TLSClientConfig = withBackendServerName(httpConf.TLSClientConfig)
+		options := rpcClientOptions(genCtx, httpConf)
+		backend := rpcbackend.NewRPCClientWithOption(ffresty.NewWithConfig(ctx, *httpConf), options)
+		if config.GetBool(signerconfig.BackendCircuitBreakerEnabled) {
+			// Each URL has its own circuit breaker, so an open circuit also steers failover away from it
+			backend = rpcbackend.NewCircuitBreakerBackend(genCtx, backendCircuitBreakerOptions(), backend)
+			circuitBreakers = append(circuitBreakers, &backendCircuitBreaker{url: backendURL, breaker: backend.(rpcbackend.CircuitBreaker)})
+		}
+		backends = append(backends, backend)
+	}
+	gen := &backendGeneration{backend: backends[0], cancel: cancel, circuitBreakers: circuitBreakers}
+	if len(backends) > 1 {
+		gen.backend = rpcbackend.NewFailoverBackend(genCtx, backendFailoverOptions(), backends...)
+	}
+	return gen, nil
+}
+
+// withBackendServerName applies any override of the server name expected in the certificate
+// of the backend, which might differ from the host in the URL (such as when connecting by IP)
+func withBackendServerName(tlsConfig *tls.Config) *tls.Config {
+	serverName := config.GetString(signerconfig.BackendTLSServerName)
+	if serverName == "" {
+		return tlsConfig
+	}
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	tlsConfig.ServerName = serverName
+	return tlsConfig
+}
+
+func rpcClientOptions(dispatcherCtx context.Context, httpConf *ffresty.Config) rpcbackend.RPCClientOptions {
+	options := rpcbackend.RPCClientOptions{}
+	if httpConf.Retry {
+		// We apply the retry settings with awareness of which methods are safe to retry,
+		// rather than letting Resty retry every request
+		options.RetryOptions = &rpcbackend.RPCClientRetryOptions{
+			MaxRetries:        httpConf.RetryCount,
+			InitialDelay:      time.Duration(httpConf.RetryInitialDelay),
+			MaximumDelay:      time.Duration(httpConf.RetryMaximumDelay),
+			IdempotentMethods: config.GetStringSlice(signerconfig.BackendRetryIdempotentMethods),
+		}
+		httpConf.Retry = false
+	}
+	if config.GetBool(signerconfig.BackendBatchEnabled) {
+		options.BatchOptions = &rpcbackend.RPCClientBatchOptions{
+			BatchDispatcherContext:      dispatcherCtx,
+			BatchSize:                   config.GetInt(signerconfig.BackendBatchSize),
+			BatchTimeout:                config.GetDuration(signerconfig.BackendBatchTimeout),
+			BatchMaxDispatchConcurrency: config.GetInt(signerconfig.BackendBatchDispatchConcurrency),
+		}
+	}
+	return options
+}
+
+func (s *rpcServer) initMetrics(ctx context.Context, registry metric.MetricsRegistry) (err error) {
+	s.metrics, err = newRPCMetrics(ctx, registry, s.wallet)
+	if err != nil {
+		return err
+	}
+	s.backend = &metricsBackend{Backend: s.backend, metrics: s.metrics}
+	s.metricsServerDone = make(chan error)
+	s.metricsServer, err = httpserver.NewHTTPServer(ctx, "metrics", s.metrics.router(ctx), s.metricsServerDone, signerconfig.MetricsConfig, signerconfig.CorsConfig)
+	return err
+}
+
 type rpcServer struct {
 	ctx       context.Context
 	cancelCtx func()
 	backend   rpcbackend.Backend
+	wsBackend rpcbackend.WebSocketRPCClient // only set when the backend is a WebSocket
+
+	httpBackend *switchableBackend // only set when the backend is HTTP, so can be replaced on a reload
+	backendURLs []string
+	reloadMux   sync.Mutex
+
+	wsCircuitBreakers []*backendCircuitBreaker // only set for a WebSocket backend, as HTTP ones are per generation
+
+	started          bool
+	apiServer        httpserver.HTTPServer // only set when listening on TCP
+	apiServerDone    chan error
+	unixSocketServer *unixSocketServer // only set when listening on a UNIX domain socket
+
+	authenticators []rpcauth.Authenticator              // only set when authentication is enabled
+	authorizer     atomic.Pointer[rpcauth.Authorizer]   // only set when role based access control is enabled
+	methodFilter   atomic.Pointer[rpcauth.MethodFilter] // only set when methods are allowed or denied
+	rateLimiter    *rateLimiter                         // only set when rate limiting is enabled
+	persistence    persistence.Store                    // only set when there is shared persistence
+	nonceManager   nonces.Manager                       // only set when local nonce management is enabled
+	accessLog      *accessLogger                        // only set when access logging is enabled
+	responseCache  *responseCache                       // only set when response caching is enabled
+	methodTimeouts *methodTimeouts                      // only set when timeouts are configured
+	chains         *chainRoutes                         // only set when additional chains are configured
+	senderQueues   *senderQueues                        // only set when the sender queue is enabled
+	resubmitter    *resubmitter                         // only set when resubmission is enabled
 
-	started       bool
-	apiServer     httpserver.HTTPServer
-	apiServerDone chan error
+	approvalHook    approval.Hook // only set when an embedding application sets one
+	approvalTimeout time.Duration
+
+	nonceMonitorInterval time.Duration
+	nonceMonitorDone     chan struct{}
+
+	metrics           *rpcMetrics // only set when metrics are enabled
+	metricsServer     httpserver.HTTPServer
+	metricsServerDone chan error
+	auditWebhook      *auditWebhook  // only set when the audit webhook is enabled
+	keyCeremonies     *keyCeremonies // only set when key ceremonies are enabled on the admin server
+	adminServer       httpserver.HTTPServer
+	adminServerDone   chan error
+
+	wsUpgrader       websocket.Upgrader
+	wsConnMux        sync.Mutex
+	wsConnections    map[string]*wsConnection
+	wsPingInterval   time.Duration
+	wsMaxMessageSize int64
+
+	maxRequestSize int64
+	maxBatchSize   int
+	maxJSONDepth   int
 
 	chainID int64
 	wallet  ethsigner.Wallet
+
+	chainIDValidation         bool
+	chainIDValidationInterval time.Duration
+	chainIDMonitorDone        chan struct{}
+	chainIDMismatch           atomic.Bool  // set while the revalidated chain ID of the backend differs
+	backendChainID            atomic.Int64 // the last revalidated chain ID of the backend
+
+	personalSignEnabled bool
+	ethSignEnabled      bool
+	requireUnlock       bool // keys must be unlocked with their passphrase, rather than the configured password files
+	preflightEnabled    bool
+	preflightBlockTag   string
+
+	gasEstimateEnabled    bool
+	gasEstimateMultiplier float64
+	gasEstimateCap        uint64
+	fees                  *gasoracle.Estimator // only set when fee population is enabled
+	feeCaps               *feeCaps             // only set when fee caps are configured
+
+	txValidation            *txValidation // only set when transaction validation is enabled
+	ens                     *ens.Resolver // only set when ENS names are resolved in the to address of transactions
+	txPolicy                *txPolicy     // only set when transaction policies are configured
+	rawTxPolicyEnabled      bool
+	rawTxManagedSendersOnly bool
 }
 
 func (s *rpcServer) router() *mux.Router {
 	mux := mux.NewRouter()
-	mux.Path("/").Methods(http.MethodPost).Handler(http.HandlerFunc(s.rpcHandler))
+	mux.Path("/").Methods(http.MethodPost).Handler(localized(s.correlated(s.authenticated(s.chainRouted(s.rateLimitKeyed(s.rpcHandler))))))
+	mux.Path("/").Methods(http.MethodGet).Handler(localized(s.correlated(s.authenticated(s.chainRouted(s.rateLimitKeyed(s.wsHandler))))))
+	if s.chains != nil {
+		mux.Path("/chains/{chain}").Methods(http.MethodPost).Handler(localized(s.correlated(s.authenticated(s.chainRouted(s.rateLimitKeyed(s.rpcHandler))))))
+		mux.Path("/chains/{chain}").Methods(http.MethodGet).Handler(localized(s.correlated(s.authenticated(s.chainRouted(s.rateLimitKeyed(s.wsHandler))))))
+	}
 	return mux
 }
 
@@ -84,32 +511,94 @@ func (s *rpcServer) runAPIServer() {
 }
 
 func (s *rpcServer) Start() error {
-	if s.chainID < 0 {
-		var chainID ethtypes.HexInteger
-		rpcErr := s.backend.CallRPC(s.ctx, &chainID, "net_version")
-		if rpcErr != nil {
-			return i18n.WrapError(s.ctx, rpcErr.Error(), signermsgs.MsgQueryChainID)
+	if s.wsBackend != nil {
+		// Reconnects are then handled automatically, with any in-flight requests failed
+		if err := s.wsBackend.Connect(s.ctx); err != nil {
+			return err
+		}
+	}
+
+	if err := s.initChainID(s.ctx); err != nil {
+		return err
+	}
+	if s.chains != nil {
+		if err := s.validateChains(s.ctx); err != nil {
+			return err
 		}
-		s.chainID = chainID.BigInt().Int64()
 	}
 
 	err := s.wallet.Initialize(s.ctx)
 	if err != nil {
 		return err
 	}
-	go s.runAPIServer()
+	if s.apiServer != nil {
+		go s.runAPIServer()
+	}
+	if s.unixSocketServer != nil {
+		go s.unixSocketServer.serve(s.ctx)
+	}
+	if s.metricsServer != nil {
+		go s.metricsServer.ServeHTTP(s.ctx)
+	}
+	if s.adminServer != nil {
+		go s.adminServer.ServeHTTP(s.ctx)
+	}
+	if s.auditWebhook != nil {
+		s.auditWebhook.start(s.ctx)
+	}
+	if s.nonceManager != nil && s.nonceMonitorInterval > 0 {
+		s.nonceMonitorDone = make(chan struct{})
+		go s.runNonceMonitor()
+	}
+	if s.resubmitter != nil {
+		s.resubmitter.done = make(chan struct{})
+		go s.runResubmitter()
+	}
 	s.started = true
 	return nil
 }
 
 func (s *rpcServer) Stop() {
+	s.closeAllWSConnections()
+	if s.wsBackend != nil {
+		s.wsBackend.Close()
+	}
 	s.cancelCtx()
 }
 
 func (s *rpcServer) WaitStop() (err error) {
 	if s.started {
 		s.started = false
-		err = <-s.apiServerDone
+		if s.apiServer != nil {
+			err = <-s.apiServerDone
+		}
+		if s.unixSocketServer != nil {
+			if unixSocketErr := <-s.unixSocketServer.done; err == nil {
+				err = unixSocketErr
+			}
+		}
+		if s.metricsServer != nil {
+			if metricsErr := <-s.metricsServerDone; err == nil {
+				err = metricsErr
+			}
+		}
+		if s.adminServer != nil {
+			if adminErr := <-s.adminServerDone; err == nil {
+				err = adminErr
+			}
+		}
+		if s.nonceMonitorDone != nil {
+			<-s.nonceMonitorDone
+		}
+		if s.chainIDMonitorDone != nil {
+			<-s.chainIDMonitorDone
+		}
+		if s.auditWebhook != nil {
+			<-s.auditWebhook.done
+		}
+		if s.resubmitter != nil {
+			<-s.resubmitter.done
+		}
 	}
 	return err
 }
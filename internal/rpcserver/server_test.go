@@ -18,13 +18,31 @@ package rpcserver
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
 	"fmt"
+	"math/big"
 	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/hyperledger/firefly-common/pkg/ffresty"
 	"github.com/hyperledger/firefly-common/pkg/fftls"
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
 	"github.com/hyperledger/firefly-common/pkg/httpserver"
+	"github.com/hyperledger/firefly-common/pkg/wsclient"
 	"github.com/hyperledger/firefly-signer/internal/signerconfig"
 	"github.com/hyperledger/firefly-signer/mocks/ethsignermocks"
 	"github.com/hyperledger/firefly-signer/mocks/rpcbackendmocks"
@@ -34,8 +52,11 @@ import (
 	"github.com/stretchr/testify/mock"
 )
 
-func newTestServer(t *testing.T) (string, *rpcServer, func()) {
+func newTestServer(t *testing.T, confSetters ...func()) (string, *rpcServer, func()) {
 	signerconfig.Reset()
+	for _, setConf := range confSetters {
+		setConf()
+	}
 
 	ln, err := net.Listen("tcp", "127.0.0.1:0")
 	assert.NoError(t, err)
@@ -80,7 +101,7 @@ func TestStartStop(t *testing.T) {
 	defer done()
 
 	bm := s.backend.(*rpcbackendmocks.Backend)
-	bm.On("CallRPC", mock.Anything, mock.Anything, "net_version").Run(func(args mock.Arguments) {
+	bm.On("CallRPC", mock.Anything, mock.Anything, "eth_chainId").Run(func(args mock.Arguments) {
 		hi := args[1].(*ethtypes.HexInteger)
 		hi.BigInt().SetInt64(12345)
 	}).Return(nil)
@@ -100,7 +121,7 @@ func TestStartFailChainID(t *testing.T) {
 	defer done()
 
 	bm := s.backend.(*rpcbackendmocks.Backend)
-	bm.On("CallRPC", mock.Anything, mock.Anything, "net_version").Run(func(args mock.Arguments) {
+	bm.On("CallRPC", mock.Anything, mock.Anything, "eth_chainId").Run(func(args mock.Arguments) {
 		hi := args[1].(*ethtypes.HexInteger)
 		hi.BigInt().SetInt64(12345)
 	}).Return(&rpcbackend.RPCError{Message: "pop"})
@@ -116,7 +137,7 @@ func TestStartFailInitialize(t *testing.T) {
 	defer done()
 
 	bm := s.backend.(*rpcbackendmocks.Backend)
-	bm.On("CallRPC", mock.Anything, mock.Anything, "net_version").Run(func(args mock.Arguments) {
+	bm.On("CallRPC", mock.Anything, mock.Anything, "eth_chainId").Run(func(args mock.Arguments) {
 		hi := args[1].(*ethtypes.HexInteger)
 		hi.BigInt().SetInt64(12345)
 	}).Return(nil)
@@ -136,3 +157,476 @@ func TestBadConfig(t *testing.T) {
 	assert.Error(t, err)
 
 }
+
+func TestStartStopWebSocketBackend(t *testing.T) {
+	signerconfig.Reset()
+
+	toServer, fromServer, url, closeWS := wsclient.NewTestWSServer(nil)
+	defer closeWS()
+	go func() {
+		msg := <-toServer
+		assert.JSONEq(t, `{"jsonrpc":"2.0","id":"000000001","method":"eth_chainId"}`, msg)
+		fromServer <- `{"jsonrpc":"2.0","id":"000000001","result":"0x3039"}`
+	}()
+
+	signerconfig.BackendConfig.Set(ffresty.HTTPConfigURL, url)
+	signerconfig.ServerConfig.Set(httpserver.HTTPConfPort, 0)
+	signerconfig.ServerConfig.Set(httpserver.HTTPConfAddress, "127.0.0.1")
+
+	w := &ethsignermocks.Wallet{}
+	w.On("Initialize", mock.Anything).Return(nil)
+	ss, err := NewServer(context.Background(), w)
+	assert.NoError(t, err)
+	s := ss.(*rpcServer)
+	assert.NotNil(t, s.wsBackend)
+
+	err = s.Start()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(12345), s.chainID)
+
+	s.Stop()
+	_ = s.WaitStop()
+}
+
+func TestStartStopIPCBackend(t *testing.T) {
+	signerconfig.Reset()
+
+	ipcPath := filepath.Join(t.TempDir(), "geth.ipc")
+	ln, err := net.Listen("unix", ipcPath)
+	assert.NoError(t, err)
+	defer ln.Close()
+	// The connection stays open until the test ends, so the client does not reconnect
+	nodeDone := make(chan struct{})
+	defer close(nodeDone)
+	go func() {
+		conn, err := ln.Accept()
+		assert.NoError(t, err)
+		defer conn.Close()
+		var rpcReq rpcbackend.RPCRequest
+		err = json.NewDecoder(conn).Decode(&rpcReq)
+		assert.NoError(t, err)
+		assert.Equal(t, "eth_chainId", rpcReq.Method)
+		_ = json.NewEncoder(conn).Encode(&rpcbackend.RPCResponse{
+			JSONRpc: "2.0",
+			ID:      rpcReq.ID,
+			Result:  fftypes.JSONAnyPtr(`"0x3039"`),
+		})
+		<-nodeDone
+	}()
+
+	signerconfig.BackendConfig.Set(ffresty.HTTPConfigURL, "unix://"+ipcPath)
+	signerconfig.ServerConfig.Set(httpserver.HTTPConfPort, 0)
+	signerconfig.ServerConfig.Set(httpserver.HTTPConfAddress, "127.0.0.1")
+
+	w := &ethsignermocks.Wallet{}
+	w.On("Initialize", mock.Anything).Return(nil)
+	ss, err := NewServer(context.Background(), w)
+	assert.NoError(t, err)
+	s := ss.(*rpcServer)
+	assert.NotNil(t, s.wsBackend)
+
+	err = s.Start()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(12345), s.chainID)
+
+	s.Stop()
+	_ = s.WaitStop()
+}
+
+func TestStartWebSocketBackendConnectFail(t *testing.T) {
+	signerconfig.Reset()
+	signerconfig.BackendConfig.Set(ffresty.HTTPConfigURL, "ws://127.0.0.1:1")
+	signerconfig.BackendConfig.Set(wsclient.WSConfigKeyInitialConnectAttempts, 0)
+	signerconfig.ServerConfig.Set(httpserver.HTTPConfPort, 0)
+	signerconfig.ServerConfig.Set(httpserver.HTTPConfAddress, "127.0.0.1")
+
+	ss, err := NewServer(context.Background(), &ethsignermocks.Wallet{})
+	assert.NoError(t, err)
+	defer ss.Stop()
+
+	err = ss.Start()
+	assert.Regexp(t, "FF00148", err)
+}
+
+func TestBadWebSocketTLSConfig(t *testing.T) {
+	signerconfig.Reset()
+	signerconfig.BackendConfig.Set(ffresty.HTTPConfigURL, "wss://127.0.0.1:1")
+	tlsConf := signerconfig.BackendConfig.SubSection("tls")
+	tlsConf.Set(fftls.HTTPConfTLSEnabled, true)
+	tlsConf.Set(fftls.HTTPConfTLSCAFile, "!!!!!badness")
+
+	_, err := NewServer(context.Background(), &ethsignermocks.Wallet{})
+	assert.Regexp(t, "FF00153", err)
+}
+
+func TestNewServerBatchEnabled(t *testing.T) {
+	signerconfig.Reset()
+	config.Set(signerconfig.BackendBatchEnabled, true)
+	signerconfig.ServerConfig.Set(httpserver.HTTPConfPort, 0)
+	signerconfig.ServerConfig.Set(httpserver.HTTPConfAddress, "127.0.0.1")
+
+	ss, err := NewServer(context.Background(), &ethsignermocks.Wallet{})
+	assert.NoError(t, err)
+	defer ss.Stop()
+	assert.IsType(t, &rpcbackend.RPCClient{}, ss.(*rpcServer).httpBackend.current.Load().backend)
+}
+
+func TestBadHTTPBackendTLSConfig(t *testing.T) {
+	signerconfig.Reset()
+	signerconfig.BackendConfig.Set(ffresty.HTTPConfigURL, "https://127.0.0.1:1")
+	tlsConf := signerconfig.BackendConfig.SubSection("tls")
+	tlsConf.Set(fftls.HTTPConfTLSEnabled, true)
+	tlsConf.Set(fftls.HTTPConfTLSCAFile, "!!!!!badness")
+
+	_, err := NewServer(context.Background(), &ethsignermocks.Wallet{})
+	assert.Regexp(t, "FF00153", err)
+}
+
+func TestStartHTTPBackendFailover(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var rpcReq rpcbackend.RPCRequest
+		err := json.NewDecoder(r.Body).Decode(&rpcReq)
+		assert.NoError(t, err)
+		assert.Equal(t, "eth_chainId", rpcReq.Method)
+		w.Header().Add("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(&rpcbackend.RPCResponse{
+			JSONRpc: "2.0",
+			ID:      rpcReq.ID,
+			Result:  fftypes.JSONAnyPtr(`"12345"`),
+		})
+	}))
+	defer server.Close()
+
+	signerconfig.Reset()
+	signerconfig.BackendConfig.Set(ffresty.HTTPConfigURL, "http://127.0.0.1:1")
+	signerconfig.BackendConfig.Set(ffresty.HTTPConfigRetryEnabled, false)
+	config.Set(signerconfig.BackendFailoverURLs, []string{server.URL})
+	signerconfig.ServerConfig.Set(httpserver.HTTPConfPort, 0)
+	signerconfig.ServerConfig.Set(httpserver.HTTPConfAddress, "127.0.0.1")
+
+	w := &ethsignermocks.Wallet{}
+	w.On("Initialize", mock.Anything).Return(nil)
+	ss, err := NewServer(context.Background(), w)
+	assert.NoError(t, err)
+	s := ss.(*rpcServer)
+	assert.Nil(t, s.wsBackend)
+
+	err = s.Start()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(12345), s.chainID)
+
+	s.Stop()
+	err = s.WaitStop()
+	assert.NoError(t, err)
+}
+
+func TestNewServerWebSocketBackendFailover(t *testing.T) {
+	signerconfig.Reset()
+	signerconfig.BackendConfig.Set(ffresty.HTTPConfigURL, "ws://127.0.0.1:1")
+	config.Set(signerconfig.BackendFailoverURLs, []string{"wss://127.0.0.1:2"})
+	config.Set(signerconfig.BackendFailoverStickySubscriptions, true)
+	signerconfig.ServerConfig.Set(httpserver.HTTPConfPort, 0)
+	signerconfig.ServerConfig.Set(httpserver.HTTPConfAddress, "127.0.0.1")
+
+	ss, err := NewServer(context.Background(), &ethsignermocks.Wallet{})
+	assert.NoError(t, err)
+	defer ss.Stop()
+	s := ss.(*rpcServer)
+	assert.NotNil(t, s.wsBackend)
+	assert.Equal(t, rpcbackend.Backend(s.wsBackend), s.backend)
+}
+
+func TestNewServerWebSocketIPCBackendFailover(t *testing.T) {
+	signerconfig.Reset()
+	signerconfig.BackendConfig.Set(ffresty.HTTPConfigURL, "ws://127.0.0.1:1")
+	config.Set(signerconfig.BackendFailoverURLs, []string{"unix:///tmp/geth.ipc"})
+	config.Set(signerconfig.BackendCircuitBreakerEnabled, true)
+	signerconfig.ServerConfig.Set(httpserver.HTTPConfPort, 0)
+	signerconfig.ServerConfig.Set(httpserver.HTTPConfAddress, "127.0.0.1")
+
+	ss, err := NewServer(context.Background(), &ethsignermocks.Wallet{})
+	assert.NoError(t, err)
+	defer ss.Stop()
+	s := ss.(*rpcServer)
+	assert.Len(t, s.wsCircuitBreakers, 2)
+	assert.Equal(t, "unix:///tmp/geth.ipc", s.wsCircuitBreakers[1].url)
+}
+
+func TestNewServerFailoverMixedSchemes(t *testing.T) {
+	signerconfig.Reset()
+	signerconfig.BackendConfig.Set(ffresty.HTTPConfigURL, "http://127.0.0.1:1")
+	config.Set(signerconfig.BackendFailoverURLs, []string{"ws://127.0.0.1:2"})
+
+	_, err := NewServer(context.Background(), &ethsignermocks.Wallet{})
+	assert.Regexp(t, "FF22100.*ws://127.0.0.1:2", err)
+
+	config.Set(signerconfig.BackendFailoverURLs, []string{"unix:///tmp/geth.ipc"})
+	_, err = NewServer(context.Background(), &ethsignermocks.Wallet{})
+	assert.Regexp(t, "FF22100.*unix:///tmp/geth.ipc", err)
+}
+
+func TestStartHTTPBackendRetry(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var rpcReq rpcbackend.RPCRequest
+		err := json.NewDecoder(r.Body).Decode(&rpcReq)
+		assert.NoError(t, err)
+		w.Header().Add("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(&rpcbackend.RPCResponse{
+			JSONRpc: "2.0",
+			ID:      rpcReq.ID,
+			Result:  fftypes.JSONAnyPtr(`"12345"`),
+		})
+	}))
+	defer server.Close()
+
+	signerconfig.Reset()
+	signerconfig.BackendConfig.Set(ffresty.HTTPConfigURL, server.URL)
+	signerconfig.BackendConfig.Set(ffresty.HTTPConfigRetryEnabled, true)
+	signerconfig.BackendConfig.Set(ffresty.HTTPConfigRetryInitDelay, "1ms")
+	signerconfig.ServerConfig.Set(httpserver.HTTPConfPort, 0)
+	signerconfig.ServerConfig.Set(httpserver.HTTPConfAddress, "127.0.0.1")
+
+	w := &ethsignermocks.Wallet{}
+	w.On("Initialize", mock.Anything).Return(nil)
+	ss, err := NewServer(context.Background(), w)
+	assert.NoError(t, err)
+	s := ss.(*rpcServer)
+
+	err = s.Start()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(12345), s.chainID)
+	assert.Equal(t, 2, attempts)
+
+	s.Stop()
+	err = s.WaitStop()
+	assert.NoError(t, err)
+}
+
+type testCertificate struct {
+	cert     *x509.Certificate
+	key      *ecdsa.PrivateKey
+	certFile string
+	keyFile  string
+}
+
+// newTestCertificate issues a certificate signed by the parent (or self-signed when nil), and writes it to dir.
+// The certificate is valid for 127.0.0.1, unless DNS names are supplied.
+func newTestCertificate(t *testing.T, dir, cn string, parent *testCertificate, dnsNames ...string) *testCertificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn, Organization: []string{"firefly"}},
+		NotBefore:    time.Now().Add(-1 * time.Hour),
+		NotAfter:     time.Now().Add(1 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	if len(dnsNames) > 0 {
+		template.DNSNames = dnsNames
+	} else {
+		template.IPAddresses = []net.IP{net.ParseIP("127.0.0.1")}
+	}
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	assert.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+
+	tc := &testCertificate{
+		cert:     cert,
+		key:      key,
+		certFile: filepath.Join(dir, cn+".crt"),
+		keyFile:  filepath.Join(dir, cn+".key"),
+	}
+	err = os.WriteFile(tc.certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	assert.NoError(t, err)
+	err = os.WriteFile(tc.keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+	assert.NoError(t, err)
+	return tc
+}
+
+func (tc *testCertificate) tlsCertificate() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{tc.cert.Raw}, PrivateKey: tc.key}
+}
+
+func TestServerMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCertificate(t, dir, "ca", nil)
+	serverCert := newTestCertificate(t, dir, "server", ca)
+	allowedClient := newTestCertificate(t, dir, "allowed-client", ca)
+	otherClient := newTestCertificate(t, dir, "other-client", ca)
+
+	signerconfig.Reset()
+	serverPort := freeTestPort(t)
+	signerconfig.ServerConfig.Set(httpserver.HTTPConfPort, serverPort)
+	signerconfig.ServerConfig.Set(httpserver.HTTPConfAddress, "127.0.0.1")
+	tlsConf := signerconfig.ServerConfig.SubSection("tls")
+	tlsConf.Set(fftls.HTTPConfTLSEnabled, true)
+	tlsConf.Set(fftls.HTTPConfTLSCertFile, serverCert.certFile)
+	tlsConf.Set(fftls.HTTPConfTLSKeyFile, serverCert.keyFile)
+	tlsConf.Set(fftls.HTTPConfTLSCAFile, ca.certFile)
+	tlsConf.Set(fftls.HTTPConfTLSClientAuth, true)
+	tlsConf.Set(fftls.HTTPConfTLSRequiredDNAttributes, map[string]interface{}{"cn": "allowed-.*"})
+
+	w := &ethsignermocks.Wallet{}
+	w.On("Initialize", mock.Anything).Return(nil)
+	ss, err := NewServer(context.Background(), w)
+	assert.NoError(t, err)
+	s := ss.(*rpcServer)
+	bm := &rpcbackendmocks.Backend{}
+	s.backend = bm
+	bm.On("CallRPC", mock.Anything, mock.Anything, "eth_chainId").Run(func(args mock.Arguments) {
+		hi := args[1].(*ethtypes.HexInteger)
+		hi.BigInt().SetInt64(12345)
+	}).Return(nil)
+	bm.On("SyncRequest", mock.Anything, mock.Anything).Return(&rpcbackend.RPCResponse{
+		JSONRpc: "2.0",
+		ID:      fftypes.JSONAnyPtr("1"),
+		Result:  fftypes.JSONAnyPtr(`"0x12345"`),
+	}, nil)
+	err = s.Start()
+	assert.NoError(t, err)
+	defer func() {
+		s.Stop()
+		_ = s.WaitStop()
+	}()
+
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(ca.cert)
+	post := func(clientCerts ...tls.Certificate) (*http.Response, error) {
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+			RootCAs:      rootCAs,
+			Certificates: clientCerts,
+		}}}
+		return client.Post(fmt.Sprintf("https://127.0.0.1:%s", serverPort), "application/json",
+			strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber"}`))
+	}
+
+	res, err := post(allowedClient.tlsCertificate())
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		res.Body.Close()
+	}
+
+	// No client certificate
+	_, err = post()
+	assert.Error(t, err)
+
+	// Client certificate with a subject that does not match
+	_, err = post(otherClient.tlsCertificate())
+	assert.Error(t, err)
+}
+
+func TestStartHTTPBackendTLSServerNameAndClientCert(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCertificate(t, dir, "ca", nil)
+	nodeCert := newTestCertificate(t, dir, "node", ca, "node.example.com")
+	clientCert := newTestCertificate(t, dir, "signer", ca)
+
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca.cert)
+	node := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "signer", r.TLS.PeerCertificates[0].Subject.CommonName)
+		assert.Equal(t, "node.example.com", r.TLS.ServerName)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":"000000001","result":"0x3039"}`))
+	}))
+	node.TLS = &tls.Config{
+		Certificates: []tls.Certificate{nodeCert.tlsCertificate()},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientCAs,
+	}
+	node.StartTLS()
+	defer node.Close()
+
+	signerconfig.Reset()
+	signerconfig.ServerConfig.Set(httpserver.HTTPConfPort, 0)
+	signerconfig.ServerConfig.Set(httpserver.HTTPConfAddress, "127.0.0.1")
+	signerconfig.BackendConfig.Set(ffresty.HTTPConfigURL, node.URL)
+	tlsConf := signerconfig.BackendConfig.SubSection("tls")
+	tlsConf.Set(fftls.HTTPConfTLSEnabled, true)
+	tlsConf.Set(fftls.HTTPConfTLSCAFile, ca.certFile)
+	tlsConf.Set(fftls.HTTPConfTLSCertFile, clientCert.certFile)
+	tlsConf.Set(fftls.HTTPConfTLSKeyFile, clientCert.keyFile)
+	config.Set(signerconfig.BackendTLSServerName, "node.example.com")
+
+	w := &ethsignermocks.Wallet{}
+	w.On("Initialize", mock.Anything).Return(nil)
+	ss, err := NewServer(context.Background(), w)
+	assert.NoError(t, err)
+	s := ss.(*rpcServer)
+	err = s.Start()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(12345), s.chainID)
+	s.Stop()
+	_ = s.WaitStop()
+}
+
+func TestWithBackendServerName(t *testing.T) {
+	signerconfig.Reset()
+	assert.Nil(t, withBackendServerName(nil))
+
+	config.Set(signerconfig.BackendTLSServerName, "node.example.com")
+	tlsConfig := withBackendServerName(nil)
+	assert.Equal(t, "node.example.com", tlsConfig.ServerName)
+	assert.Equal(t, uint16(tls.VersionTLS12), tlsConfig.MinVersion)
+}
+
+func TestServerCORS(t *testing.T) {
+	url, s, done := newTestServer(t, setTestAPIKeyConf, func() {
+		signerconfig.CorsConfig.Set(httpserver.CorsAllowedOrigins, []string{"https://dapp.example.com"})
+		signerconfig.CorsConfig.Set(httpserver.CorsAllowedMethods, []string{http.MethodPost})
+		signerconfig.CorsConfig.Set(httpserver.CorsAllowedHeaders, []string{"Content-Type", "X-API-Key"})
+		signerconfig.CorsConfig.Set(httpserver.CorsMaxAge, 300)
+	})
+	defer done()
+	startTestServerNoBackend(t, s)
+
+	preflight := func(origin string) *http.Response {
+		req, err := http.NewRequest(http.MethodOptions, url, nil)
+		assert.NoError(t, err)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		req.Header.Set("Access-Control-Request-Headers", "content-type,x-api-key")
+		res, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		res.Body.Close()
+		return res
+	}
+
+	// Preflight requests are answered before authentication, as browsers do not send credentials on them
+	res := preflight("https://dapp.example.com")
+	assert.Equal(t, http.StatusNoContent, res.StatusCode)
+	assert.Equal(t, "https://dapp.example.com", res.Header.Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, http.MethodPost, res.Header.Get("Access-Control-Allow-Methods"))
+	assert.Equal(t, "content-type, x-api-key", strings.ToLower(res.Header.Get("Access-Control-Allow-Headers")))
+	assert.Equal(t, "300", res.Header.Get("Access-Control-Max-Age"))
+
+	res = preflight("https://other.example.com")
+	assert.Empty(t, res.Header.Get("Access-Control-Allow-Origin"))
+
+	// Responses to the actual request, including errors, carry the CORS headers for the browser
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"eth_accounts"}`))
+	assert.NoError(t, err)
+	req.Header.Set("Origin", "https://dapp.example.com")
+	res, err = http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	assert.Equal(t, "https://dapp.example.com", res.Header.Get("Access-Control-Allow-Origin"))
+}
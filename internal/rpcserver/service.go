@@ -0,0 +1,131 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/hyperledger/firefly-signer/pkg/approval"
+	"github.com/hyperledger/firefly-signer/pkg/eip712"
+	"github.com/hyperledger/firefly-signer/pkg/ethsigner"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/persistence"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+)
+
+// Service is the signer embedded in a Go application, rather than served over JSON/RPC. Requests are processed
+// just as the server processes them - with the access control of the identity in the context, the policies, nonce
+// management and backend - but no ports are bound.
+type Service interface {
+	Start() error
+	Stop()
+	WaitStop() error
+	ChainID() int64
+	SyncRequest(ctx context.Context, rpcReq *rpcbackend.RPCRequest) (*rpcbackend.RPCResponse, error)
+	SignTransaction(ctx context.Context, txn *ethsigner.Transaction) (ethtypes.HexBytes0xPrefix, error)
+	SignTypedData(ctx context.Context, from ethtypes.Address0xHex, payload *eip712.TypedData) (*ethsigner.EIP712Result, error)
+	SetApprovalHook(hook approval.Hook)
+}
+
+// NewService creates the signer to embed in-process. There are no JSON/RPC, admin or metrics servers, so their
+// configuration is ignored. State that survives restarts is persisted to the store when it is set, instead of to
+// persistence.path.
+func NewService(ctx context.Context, wallet ethsigner.Wallet, store persistence.Store) (Service, error) {
+	s, err := newServer(ctx, wallet, store, false)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func newInProcessRequest(method string, params ...*fftypes.JSONAny) *rpcbackend.RPCRequest {
+	return &rpcbackend.RPCRequest{
+		JSONRpc: "2.0",
+		ID:      fftypes.JSONAnyPtr(`"` + fftypes.NewUUID().String() + `"`),
+		Method:  method,
+		Params:  params,
+	}
+}
+
+// ChainID is the chain ID transactions are signed for, which is known once the service has started
+func (s *rpcServer) ChainID() int64 {
+	return s.chainID
+}
+
+// SyncRequest processes a JSON/RPC request as if it was received by the server, assigning an ID if it has none
+func (s *rpcServer) SyncRequest(ctx context.Context, rpcReq *rpcbackend.RPCRequest) (*rpcbackend.RPCResponse, error) {
+	if rpcReq.ID == nil {
+		rpcReq.ID = newInProcessRequest(rpcReq.Method).ID
+	}
+	return s.processRPC(ctx, rpcReq)
+}
+
+// SignTransaction checks, populates and signs a transaction exactly as eth_sendTransaction does, but returns the signed
+// transaction rather than submitting it. An assigned nonce stays assigned, so the caller must submit the transaction.
+func (s *rpcServer) SignTransaction(ctx context.Context, txn *ethsigner.Transaction) (ethtypes.HexBytes0xPrefix, error) {
+	b, err := json.Marshal(txn)
+	if err != nil {
+		return nil, i18n.WrapError(ctx, err, signermsgs.MsgInvalidTransaction)
+	}
+	rpcReq := newInProcessRequest("eth_signTransaction", fftypes.JSONAnyPtrBytes(b))
+	if _, err := s.admitRequest(ctx, rpcReq); err != nil {
+		return nil, err
+	}
+	st, _, err := s.signTransaction(ctx, rpcReq)
+	if err != nil {
+		return nil, err
+	}
+	st.release(false)
+	return st.signed, nil
+}
+
+// SignTypedData signs EIP-712 typed data, with the same access control and rate limits as other signing requests
+func (s *rpcServer) SignTypedData(ctx context.Context, from ethtypes.Address0xHex, payload *eip712.TypedData) (*ethsigner.EIP712Result, error) {
+	w, ok := s.wallet.(ethsigner.WalletTypedData)
+	if !ok {
+		return nil, i18n.NewError(ctx, signermsgs.MsgTypedDataNotSupported)
+	}
+	rpcReq := newInProcessRequest("eth_signTypedData_v4")
+	if _, err := s.admitRequest(ctx, rpcReq); err != nil {
+		return nil, err
+	}
+	req := &signingRequest{method: rpcReq.Method, operation: signOpTypedData, from: &from}
+	if errRes, err := s.authorizeAddress(ctx, rpcReq, &from); err != nil {
+		_, err = s.rejectedByPolicy(ctx, req, errRes, err)
+		return nil, err
+	}
+	if errRes, err := s.checkRateLimit(ctx, rpcReq, rateLimitSigning); err != nil {
+		_, err = s.rejectedByPolicy(ctx, req, errRes, err)
+		return nil, err
+	}
+	if _, err := s.approveSigning(ctx, rpcReq, req, &approval.Request{TypedData: payload}); err != nil {
+		return nil, err
+	}
+
+	startTime := time.Now()
+	result, err := w.SignTypedDataV4(ctx, from, payload)
+	s.signOperation(ctx, req, startTime, nil, err)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,245 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/hyperledger/firefly-signer/internal/signerconfig"
+	"github.com/hyperledger/firefly-signer/mocks/ethsignermocks"
+	"github.com/hyperledger/firefly-signer/mocks/rpcbackendmocks"
+	"github.com/hyperledger/firefly-signer/pkg/eip712"
+	"github.com/hyperledger/firefly-signer/pkg/ethsigner"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/nonces"
+	"github.com/hyperledger/firefly-signer/pkg/rpcauth"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const testServiceAddr = "0xfb075bb99f2aa4c49955bf703509a227d7a12248"
+
+// testTypedDataWallet adds typed data signing to the mock wallet
+type testTypedDataWallet struct {
+	*ethsignermocks.Wallet
+	result *ethsigner.EIP712Result
+	err    error
+}
+
+func (w *testTypedDataWallet) SignTypedDataV4(ctx context.Context, from ethtypes.Address0xHex, payload *eip712.TypedData) (*ethsigner.EIP712Result, error) {
+	return w.result, w.err
+}
+
+func newTestService(t *testing.T, confSetters ...func()) (*rpcServer, *rpcbackendmocks.Backend, func()) {
+	signerconfig.Reset()
+	// There are no listeners in-process, so their configuration is ignored
+	signerconfig.ServerConfig.Set(signerconfig.ServerConfTCPEnabled, false)
+	for _, setConf := range confSetters {
+		setConf()
+	}
+	ss, err := NewService(context.Background(), &ethsignermocks.Wallet{}, nil)
+	assert.NoError(t, err)
+	s := ss.(*rpcServer)
+	assert.Nil(t, s.apiServer)
+	bm := &rpcbackendmocks.Backend{}
+	s.backend = bm
+	return s, bm, func() {
+		s.Stop()
+		_ = s.WaitStop()
+	}
+}
+
+func TestNewServiceBadConfig(t *testing.T) {
+	signerconfig.Reset()
+	config.Set(signerconfig.GasEstimateMultiplier, 0.5)
+	_, err := NewService(context.Background(), &ethsignermocks.Wallet{}, nil)
+	assert.Regexp(t, "FF22130", err)
+}
+
+func TestServiceStartAndSyncRequest(t *testing.T) {
+	s, bm, done := newTestService(t)
+	defer done()
+
+	bm.On("CallRPC", mock.Anything, mock.Anything, "eth_chainId").Run(func(args mock.Arguments) {
+		args[1].(*ethtypes.HexInteger).BigInt().SetInt64(12345)
+	}).Return(nil)
+	w := s.wallet.(*ethsignermocks.Wallet)
+	w.On("Initialize", mock.Anything).Return(nil)
+	w.On("GetAccounts", mock.Anything).Return([]*ethtypes.Address0xHex{ethtypes.MustNewAddress(testServiceAddr)}, nil)
+	err := s.Start()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(12345), s.ChainID())
+
+	rpcRes, err := s.SyncRequest(context.Background(), &rpcbackend.RPCRequest{JSONRpc: "2.0", Method: "eth_accounts"})
+	assert.NoError(t, err)
+	assert.NotNil(t, rpcRes.ID)
+	assert.JSONEq(t, `["`+testServiceAddr+`"]`, rpcRes.Result.String())
+}
+
+func TestServiceSignTransaction(t *testing.T) {
+	s, bm, done := newTestService(t)
+	defer done()
+
+	bm.On("CallRPC", mock.Anything, mock.Anything, "eth_getTransactionCount", mock.Anything, "pending").Run(func(args mock.Arguments) {
+		*(args[1].(**ethtypes.HexInteger)) = ethtypes.NewHexInteger64(5)
+	}).Return(nil)
+	w := s.wallet.(*ethsignermocks.Wallet)
+	w.On("Sign", mock.Anything, mock.MatchedBy(func(txn *ethsigner.Transaction) bool {
+		return txn.Nonce.Int64() == 5
+	}), int64(-1)).Return([]byte{0xaa, 0xbb}, nil)
+
+	signed, err := s.SignTransaction(context.Background(), &ethsigner.Transaction{
+		From: json.RawMessage(`"` + testServiceAddr + `"`),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "0xaabb", signed.String())
+}
+
+func TestServiceSignTransactionNonceStaysAssigned(t *testing.T) {
+	s, bm, done := newTestService(t)
+	defer done()
+	s.nonceManager = nonces.NewManager(nonces.NewMemoryStore(), s.transactionCount, &nonces.Config{ReconcileInterval: time.Hour, StallTimeout: time.Hour})
+
+	bm.On("CallRPC", mock.Anything, mock.Anything, "eth_getTransactionCount", mock.Anything, "pending").Run(func(args mock.Arguments) {
+		*(args[1].(*ethtypes.HexInteger)) = *ethtypes.NewHexInteger64(10)
+	}).Return(nil).Once()
+	w := s.wallet.(*ethsignermocks.Wallet)
+	var signedNonces []int64
+	w.On("Sign", mock.Anything, mock.Anything, int64(-1)).Run(func(args mock.Arguments) {
+		signedNonces = append(signedNonces, args[1].(*ethsigner.Transaction).Nonce.Int64())
+	}).Return([]byte{0xaa}, nil)
+
+	for i := 0; i < 2; i++ {
+		_, err := s.SignTransaction(context.Background(), &ethsigner.Transaction{
+			From: json.RawMessage(`"` + testServiceAddr + `"`),
+		})
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, []int64{10, 11}, signedNonces)
+	bm.AssertExpectations(t)
+}
+
+func TestServiceSignTransactionBadTransaction(t *testing.T) {
+	s, _, done := newTestService(t)
+	defer done()
+
+	_, err := s.SignTransaction(context.Background(), &ethsigner.Transaction{
+		From: json.RawMessage(`!!`),
+	})
+	assert.Regexp(t, "FF22023", err)
+}
+
+func TestServiceSignTransactionMissingFrom(t *testing.T) {
+	s, _, done := newTestService(t)
+	defer done()
+
+	_, err := s.SignTransaction(context.Background(), &ethsigner.Transaction{})
+	assert.Regexp(t, "FF22020", err)
+}
+
+func TestServiceSignTransactionMethodDenied(t *testing.T) {
+	s, _, done := newTestService(t, func() {
+		viper.Set("methods.deny", []string{"eth_sign*"})
+	})
+	defer done()
+
+	_, err := s.SignTransaction(context.Background(), &ethsigner.Transaction{
+		From: json.RawMessage(`"` + testServiceAddr + `"`),
+	})
+	assert.Regexp(t, "FF22121.*eth_signTransaction", err)
+}
+
+func TestServiceSignTransactionValidated(t *testing.T) {
+	s, _, done := newTestService(t, func() {
+		config.Set(signerconfig.TxValidationEnabled, true)
+	})
+	defer done()
+
+	_, err := s.SignTransaction(context.Background(), &ethsigner.Transaction{
+		From:         json.RawMessage(`"` + testServiceAddr + `"`),
+		GasPrice:     ethtypes.NewHexIntegerU64(1),
+		MaxFeePerGas: ethtypes.NewHexIntegerU64(1),
+	})
+	assert.Regexp(t, "FF22245", err)
+}
+
+func TestServiceSignTypedData(t *testing.T) {
+	s, _, done := newTestService(t)
+	defer done()
+
+	result := &ethsigner.EIP712Result{Hash: ethtypes.MustNewHexBytes0xPrefix("0x1234")}
+	s.wallet = &testTypedDataWallet{Wallet: &ethsignermocks.Wallet{}, result: result}
+	res, err := s.SignTypedData(context.Background(), *ethtypes.MustNewAddress(testServiceAddr), &eip712.TypedData{})
+	assert.NoError(t, err)
+	assert.Equal(t, result, res)
+}
+
+func TestServiceSignTypedDataFail(t *testing.T) {
+	s, _, done := newTestService(t)
+	defer done()
+
+	s.wallet = &testTypedDataWallet{Wallet: &ethsignermocks.Wallet{}, err: fmt.Errorf("pop")}
+	_, err := s.SignTypedData(context.Background(), *ethtypes.MustNewAddress(testServiceAddr), &eip712.TypedData{})
+	assert.Regexp(t, "pop", err)
+}
+
+func TestServiceSignTypedDataNotSupported(t *testing.T) {
+	s, _, done := newTestService(t)
+	defer done()
+
+	_, err := s.SignTypedData(context.Background(), *ethtypes.MustNewAddress(testServiceAddr), &eip712.TypedData{})
+	assert.Regexp(t, "FF22222", err)
+}
+
+func TestServiceSignTypedDataMethodDenied(t *testing.T) {
+	s, _, done := newTestService(t, func() {
+		viper.Set("methods.deny", []string{"eth_sign*"})
+	})
+	defer done()
+
+	s.wallet = &testTypedDataWallet{Wallet: &ethsignermocks.Wallet{}}
+	_, err := s.SignTypedData(context.Background(), *ethtypes.MustNewAddress(testServiceAddr), &eip712.TypedData{})
+	assert.Regexp(t, "FF22121.*eth_signTypedData_v4", err)
+}
+
+func TestServiceSignTypedDataAddressNotAuthorized(t *testing.T) {
+	s, _, done := newTestService(t, setTestRBACConf)
+	defer done()
+
+	s.wallet = &testTypedDataWallet{Wallet: &ethsignermocks.Wallet{}}
+	ctx := rpcauth.WithIdentity(context.Background(), &rpcauth.Identity{ID: "tenantA"})
+	_, err := s.SignTypedData(ctx, *ethtypes.MustNewAddress("0x1f185718734552d08278aa70f804580bab5fd2b4"), &eip712.TypedData{})
+	assert.Regexp(t, "FF22111", err)
+}
+
+func TestServiceSignTypedDataRateLimited(t *testing.T) {
+	s, _, done := newTestService(t, setTestRateLimitConf, func() {
+		config.Set(signerconfig.RateLimitRequestsBurst, 10)
+	})
+	defer done()
+
+	s.wallet = &testTypedDataWallet{Wallet: &ethsignermocks.Wallet{}}
+	_, err := s.SignTypedData(context.Background(), *ethtypes.MustNewAddress(testServiceAddr), &eip712.TypedData{})
+	assert.Regexp(t, "FF22113.*signing", err)
+}
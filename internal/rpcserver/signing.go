@@ -0,0 +1,56 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"context"
+	"time"
+
+	"github.com/hyperledger/firefly-signer/pkg/ethsigner"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+)
+
+// signingRequest describes a request to sign with a key in the wallet, for the metrics and audit events
+// recorded about its outcome
+type signingRequest struct {
+	method    string
+	operation string
+	from      *ethtypes.Address0xHex // nil if it could not be parsed
+	txn       *ethsigner.Transaction // only set for transactions
+}
+
+// signOperation records the outcome of signing, where signed is the signed transaction or signature
+func (s *rpcServer) signOperation(ctx context.Context, req *signingRequest, startTime time.Time, signed []byte, err error) {
+	if s.metrics != nil {
+		s.metrics.signOperation(ctx, req.from, req.operation, startTime, err)
+	}
+	if s.auditWebhook != nil {
+		s.auditWebhook.signOperation(ctx, s.chainIDFor(ctx), req, signed, err)
+	}
+}
+
+// rejectedByPolicy records a signing request that failed a policy check, and returns the rejection
+func (s *rpcServer) rejectedByPolicy(ctx context.Context, req *signingRequest, errRes *rpcbackend.RPCResponse, err error) (*rpcbackend.RPCResponse, error) {
+	if s.metrics != nil && s.metrics.addressLabel != nil {
+		s.metrics.policyRejection(ctx, req.from, err)
+	}
+	if s.auditWebhook != nil {
+		s.auditWebhook.rejected(ctx, s.chainIDFor(ctx), req, err)
+	}
+	return errRes, err
+}
@@ -0,0 +1,107 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"context"
+	"errors"
+	"path"
+	"time"
+
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-common/pkg/log"
+	"github.com/hyperledger/firefly-signer/internal/signerconfig"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+)
+
+// methodTimeouts bound the time spent on each JSON/RPC request, so slow methods (such as debug and
+// trace methods) cannot pile up holding connections to the backend. The first override matching the
+// method applies, and otherwise the default.
+type methodTimeouts struct {
+	defaultTimeout time.Duration
+	overrides      []*methodTimeout
+}
+
+type methodTimeout struct {
+	methods []string
+	timeout time.Duration
+}
+
+// newMethodTimeouts returns nil if no timeouts are configured
+func newMethodTimeouts(ctx context.Context) (*methodTimeouts, error) {
+	mt := &methodTimeouts{
+		defaultTimeout: signerconfig.TimeoutsConfig.GetDuration(signerconfig.TimeoutsConfDefault),
+		overrides:      make([]*methodTimeout, signerconfig.TimeoutOverridesConfig.ArraySize()),
+	}
+	for i := range mt.overrides {
+		entry := signerconfig.TimeoutOverridesConfig.ArrayEntry(i)
+		o := &methodTimeout{
+			methods: entry.GetStringSlice(signerconfig.TimeoutsConfMethods),
+			timeout: entry.GetDuration(signerconfig.TimeoutsConfTimeout),
+		}
+		if len(o.methods) == 0 {
+			return nil, i18n.NewError(ctx, signermsgs.MsgInvalidTimeoutOverride, i, "no methods")
+		}
+		if o.timeout <= 0 {
+			return nil, i18n.NewError(ctx, signermsgs.MsgInvalidTimeoutOverride, i, "timeout must be greater than zero")
+		}
+		for _, pattern := range o.methods {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, i18n.NewError(ctx, signermsgs.MsgInvalidTimeoutOverride, i, i18n.NewError(ctx, signermsgs.MsgInvalidMethodPattern, pattern, err))
+			}
+		}
+		mt.overrides[i] = o
+	}
+	if mt.defaultTimeout <= 0 && len(mt.overrides) == 0 {
+		return nil, nil
+	}
+	return mt, nil
+}
+
+func (mt *methodTimeouts) timeoutFor(method string) time.Duration {
+	for _, o := range mt.overrides {
+		for _, pattern := range o.methods {
+			if match, _ := path.Match(pattern, method); match {
+				return o.timeout
+			}
+		}
+	}
+	return mt.defaultTimeout
+}
+
+// routeRPCWithTimeout routes the request with the timeout for its method, which cancels any call to the
+// backend in flight when it expires. The context is also canceled if the client disconnects.
+func (s *rpcServer) routeRPCWithTimeout(ctx context.Context, rpcReq *rpcbackend.RPCRequest) (*rpcbackend.RPCResponse, error) {
+	var timeout time.Duration
+	if s.methodTimeouts != nil {
+		timeout = s.methodTimeouts.timeoutFor(rpcReq.Method)
+	}
+	if timeout <= 0 {
+		return s.routeRPC(ctx, rpcReq)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	rpcRes, err := s.routeRPC(ctx, rpcReq)
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		log.L(ctx).Warnf("Request for %s timed out after %s: %s", rpcReq.Method, timeout, err)
+		err = i18n.NewError(ctx, signermsgs.MsgRequestTimedOut, rpcReq.Method, timeout)
+		return rpcbackend.RPCErrorResponse(err, rpcReq.ID, rpcbackend.RPCCodeTimeout), err
+	}
+	return rpcRes, err
+}
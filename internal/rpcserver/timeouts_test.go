@@ -0,0 +1,176 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-signer/internal/signerconfig"
+	"github.com/hyperledger/firefly-signer/mocks/ethsignermocks"
+	"github.com/hyperledger/firefly-signer/mocks/rpcbackendmocks"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func setTestTimeoutsConf() {
+	viper.SetConfigType("yaml")
+	_ = viper.ReadConfig(strings.NewReader(`
+timeouts:
+  default: 1m
+  overrides:
+  - methods:
+    - debug_*
+    - trace_*
+    timeout: 10ms
+`))
+}
+
+// mockSlowBackend blocks each request until its context is done, notifying the test when it starts
+func mockSlowBackend(bm *rpcbackendmocks.Backend) chan context.Context {
+	started := make(chan context.Context, 1)
+	bm.On("SyncRequest", mock.Anything, mock.Anything).Return(func(ctx context.Context, rpcReq *rpcbackend.RPCRequest) (*rpcbackend.RPCResponse, error) {
+		started <- ctx
+		<-ctx.Done()
+		err := fmt.Errorf("pop")
+		return rpcbackend.RPCErrorResponse(err, rpcReq.ID, rpcbackend.RPCCodeInternalError), err
+	})
+	return started
+}
+
+func TestMethodTimeoutsConfig(t *testing.T) {
+	signerconfig.Reset()
+	setTestTimeoutsConf()
+
+	mt, err := newMethodTimeouts(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 10*time.Millisecond, mt.timeoutFor("debug_traceTransaction"))
+	assert.Equal(t, 10*time.Millisecond, mt.timeoutFor("trace_block"))
+	assert.Equal(t, 1*time.Minute, mt.timeoutFor("eth_call"))
+}
+
+func TestMethodTimeoutsNotConfigured(t *testing.T) {
+	signerconfig.Reset()
+
+	mt, err := newMethodTimeouts(context.Background())
+	assert.NoError(t, err)
+	assert.Nil(t, mt)
+}
+
+func TestMethodTimeoutsBadConfig(t *testing.T) {
+	for _, conf := range []struct {
+		yaml   string
+		errMsg string
+	}{
+		{yaml: "timeout: 1s", errMsg: "FF22148.*0.*no methods"},
+		{yaml: "methods: [debug_*]", errMsg: "FF22148.*0.*greater than zero"},
+		{yaml: "{methods: ['['], timeout: 1s}", errMsg: "FF22148.*0.*FF22119"},
+	} {
+		signerconfig.Reset()
+		viper.SetConfigType("yaml")
+		err := viper.ReadConfig(strings.NewReader("timeouts:\n  overrides:\n  - " + conf.yaml + "\n"))
+		assert.NoError(t, err)
+
+		_, err = NewServer(context.Background(), &ethsignermocks.Wallet{})
+		assert.Regexp(t, conf.errMsg, err)
+	}
+}
+
+func TestMethodTimeoutExpires(t *testing.T) {
+	url, s, done := newTestServer(t, setTestTimeoutsConf)
+	defer done()
+	startTestServerNoBackend(t, s)
+	mockSlowBackend(s.backend.(*rpcbackendmocks.Backend))
+
+	body := []byte(`{"jsonrpc":"2.0","id":1,"method":"debug_traceTransaction"}`)
+	res, err := http.Post(url, "application/json", bytes.NewReader(body))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusGatewayTimeout, res.StatusCode)
+	var rpcRes rpcbackend.RPCResponse
+	err = json.NewDecoder(res.Body).Decode(&rpcRes)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(rpcbackend.RPCCodeTimeout), rpcRes.Error.Code)
+	assert.Regexp(t, "FF22149.*debug_traceTransaction.*10ms", rpcRes.Error.Message)
+}
+
+func TestMethodTimeoutOtherErrors(t *testing.T) {
+	_, s, done := newTestServer(t, setTestTimeoutsConf)
+	defer done()
+
+	bm := s.backend.(*rpcbackendmocks.Backend)
+	bm.On("SyncRequest", mock.Anything, mock.Anything).Return(nil, fmt.Errorf("pop"))
+	_, err := s.processRPC(s.ctx, &rpcbackend.RPCRequest{ID: fftypes.JSONAnyPtr("1"), Method: "debug_traceTransaction"})
+	assert.Regexp(t, "pop", err)
+}
+
+func TestClientDisconnectCancelsBackendCall(t *testing.T) {
+	url, s, done := newTestServer(t)
+	defer done()
+	startTestServerNoBackend(t, s)
+	started := mockSlowBackend(s.backend.(*rpcbackendmocks.Backend))
+
+	ctx, cancelCtx := context.WithCancel(context.Background())
+	body := []byte(`{"jsonrpc":"2.0","id":1,"method":"debug_traceTransaction"}`)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	assert.NoError(t, err)
+	clientDone := make(chan struct{})
+	go func() {
+		defer close(clientDone)
+		_, err := http.DefaultClient.Do(req)
+		assert.Error(t, err)
+	}()
+
+	// The backend call is canceled when the client goes away
+	backendCtx := <-started
+	cancelCtx()
+	<-clientDone
+	select {
+	case <-backendCtx.Done():
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "backend call not canceled")
+	}
+}
+
+func TestWSClientDisconnectCancelsBackendCall(t *testing.T) {
+	url, s, done := newTestServer(t)
+	defer done()
+	startTestServerNoBackend(t, s)
+	started := mockSlowBackend(s.backend.(*rpcbackendmocks.Backend))
+
+	conn, _, err := websocket.DefaultDialer.Dial(strings.Replace(url, "http://", "ws://", 1), nil)
+	assert.NoError(t, err)
+	err = conn.WriteMessage(websocket.TextMessage, []byte(`{"jsonrpc":"2.0","id":1,"method":"debug_traceTransaction"}`))
+	assert.NoError(t, err)
+
+	backendCtx := <-started
+	conn.Close()
+	select {
+	case <-backendCtx.Done():
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "backend call not canceled")
+	}
+}
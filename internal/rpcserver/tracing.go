@@ -0,0 +1,70 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/hyperledger/firefly-signer/internal/rpcserver"
+
+const (
+	attrChainID = attribute.Key("eth.chain_id")
+	attrFrom    = attribute.Key("eth.from")
+)
+
+// extractTraceContext continues any trace propagated by the caller in the HTTP headers (such as traceparent)
+func extractTraceContext(r *http.Request) context.Context {
+	return otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+}
+
+func (s *rpcServer) startServerSpan(ctx context.Context, method string) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, metricMethod(method),
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			semconv.RPCSystemKey.String("jsonrpc"),
+			semconv.RPCMethodKey.String(method),
+			attrChainID.Int64(s.chainIDFor(ctx)),
+		),
+	)
+}
+
+func endServerSpan(span trace.Span, rpcRes *rpcbackend.RPCResponse, err error) {
+	if rpcRes != nil && rpcRes.Error != nil && rpcRes.Error.Code != 0 {
+		span.SetAttributes(semconv.RPCJsonrpcErrorCodeKey.Int64(rpcRes.Error.Code))
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// setSpanFrom annotates the current span with the address the request is signing for
+func setSpanFrom(ctx context.Context, from []byte) {
+	trace.SpanFromContext(ctx).SetAttributes(attrFrom.String(strings.ToLower(strings.Trim(string(from), `"`))))
+}
@@ -0,0 +1,113 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-signer/mocks/ethsignermocks"
+	"github.com/hyperledger/firefly-signer/mocks/rpcbackendmocks"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const testTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+
+func newTestTracing(t *testing.T) *tracetest.SpanRecorder {
+	sr := tracetest.NewSpanRecorder()
+	origTP, origPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(origTP)
+		otel.SetTextMapPropagator(origPropagator)
+	})
+	return sr
+}
+
+func postTracedRPC(s *rpcServer, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req.Header.Set("traceparent", fmt.Sprintf("00-%s-00f067aa0ba902b7-01", testTraceID))
+	res := httptest.NewRecorder()
+	s.rpcHandler(res, req)
+	return res
+}
+
+func TestTracingSendTransaction(t *testing.T) {
+	sr := newTestTracing(t)
+
+	_, s, done := newTestServer(t)
+	defer done()
+	s.chainID = 12345
+
+	w := s.wallet.(*ethsignermocks.Wallet)
+	w.On("Sign", mock.Anything, mock.Anything, int64(12345)).Return([]byte{0x01}, nil)
+	bm := s.backend.(*rpcbackendmocks.Backend)
+	bm.On("SyncRequest", mock.MatchedBy(func(ctx context.Context) bool {
+		// The backend is called in the context of the server span
+		return trace.SpanContextFromContext(ctx).TraceID().String() == testTraceID
+	}), mock.Anything).Return(&rpcbackend.RPCResponse{
+		JSONRpc: "2.0",
+		ID:      fftypes.JSONAnyPtr(`1`),
+		Result:  fftypes.JSONAnyPtr(`"0x61ca9c99c1d752fb3bda568b8566edf33ba93585c64a970566e6dfb540a5cbc1"`),
+	}, nil)
+
+	res := postTracedRPC(s, `{"jsonrpc":"2.0","id":1,"method":"eth_sendTransaction","params":[{"from":"0xFB075BB99F2AA4C49955BF703509A227D7A12248","nonce":"0x1"}]}`)
+	assert.Equal(t, http.StatusOK, res.Code)
+
+	spans := sr.Ended()
+	assert.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, "eth_sendTransaction", span.Name())
+	assert.Equal(t, trace.SpanKindServer, span.SpanKind())
+	assert.Equal(t, testTraceID, span.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", span.Parent().SpanID().String())
+	assert.Contains(t, span.Attributes(), attribute.String("rpc.method", "eth_sendTransaction"))
+	assert.Contains(t, span.Attributes(), attribute.Int64("eth.chain_id", 12345))
+	assert.Contains(t, span.Attributes(), attribute.String("eth.from", "0xfb075bb99f2aa4c49955bf703509a227d7a12248"))
+	assert.Equal(t, codes.Unset, span.Status().Code)
+}
+
+func TestTracingRPCError(t *testing.T) {
+	sr := newTestTracing(t)
+
+	_, s, done := newTestServer(t)
+	defer done()
+
+	res := postTracedRPC(s, `{"jsonrpc":"2.0","id":1,"method":"eth_sendTransaction","params":[]}`)
+	assert.Equal(t, http.StatusInternalServerError, res.Code)
+
+	spans := sr.Ended()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	assert.Regexp(t, "FF22019", spans[0].Status().Description)
+	assert.Contains(t, spans[0].Attributes(), attribute.Int64("rpc.jsonrpc.error_code", int64(rpcbackend.RPCCodeInvalidRequest)))
+}
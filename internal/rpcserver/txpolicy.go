@@ -0,0 +1,142 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+
+	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-signer/internal/signerconfig"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/hyperledger/firefly-signer/pkg/ethsigner"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+)
+
+// txPolicy restricts where transactions can be sent, and how much value they can carry
+type txPolicy struct {
+	destinations  map[ethtypes.Address0xHex]bool // nil when any destination is allowed
+	noDeployments bool
+	maxValue      *big.Int
+}
+
+// newTxPolicy returns nil if no restrictions are configured
+func newTxPolicy(ctx context.Context) (*txPolicy, error) {
+	tp := &txPolicy{
+		noDeployments: !config.GetBool(signerconfig.TxPolicyAllowDeployments),
+	}
+	if destinations := config.GetStringSlice(signerconfig.TxPolicyAllowedDestinations); len(destinations) > 0 {
+		tp.destinations = make(map[ethtypes.Address0xHex]bool, len(destinations))
+		for _, s := range destinations {
+			var addr ethtypes.Address0xHex
+			if err := addr.SetString(s); err != nil {
+				return nil, i18n.NewError(ctx, signermsgs.MsgBadTxPolicy, signerconfig.TxPolicyAllowedDestinations, s)
+			}
+			tp.destinations[addr] = true
+		}
+	}
+	if s := config.GetString(signerconfig.TxPolicyMaxValue); s != "" {
+		i, ok := new(big.Int).SetString(s, 0)
+		if !ok || i.Sign() < 0 {
+			return nil, i18n.NewError(ctx, signermsgs.MsgBadTxPolicy, signerconfig.TxPolicyMaxValue, s)
+		}
+		tp.maxValue = i
+	}
+	if tp.destinations == nil && !tp.noDeployments && tp.maxValue == nil {
+		return nil, nil
+	}
+	return tp, nil
+}
+
+func (tp *txPolicy) check(ctx context.Context, txn *ethsigner.Transaction) error {
+	switch {
+	case txn.To == nil && tp.noDeployments:
+		return i18n.NewError(ctx, signermsgs.MsgTxDeploymentNotAllowed)
+	case txn.To != nil && tp.destinations != nil && !tp.destinations[*txn.To]:
+		return i18n.NewError(ctx, signermsgs.MsgTxDestinationNotAllowed, txn.To)
+	case txn.Value != nil && tp.maxValue != nil && txn.Value.BigInt().Cmp(tp.maxValue) > 0:
+		return i18n.NewError(ctx, signermsgs.MsgTxValueExceeded, txn.Value.BigInt().String(), tp.maxValue.String())
+	}
+	return nil
+}
+
+// processEthSendRawTransaction decodes a transaction that was signed elsewhere, and recovers its sender, so
+// it passes the same checks as one we sign before it is forwarded to the backend
+func (s *rpcServer) processEthSendRawTransaction(ctx context.Context, rpcReq *rpcbackend.RPCRequest) (*rpcbackend.RPCResponse, error) {
+	if len(rpcReq.Params) != 1 {
+		err := i18n.NewError(ctx, signermsgs.MsgInvalidParamCount, 1, len(rpcReq.Params))
+		return rpcbackend.RPCErrorResponse(err, rpcReq.ID, rpcbackend.RPCCodeInvalidRequest), err
+	}
+	var rawTx ethtypes.HexBytes0xPrefix
+	if err := json.Unmarshal(rpcReq.Params[0].Bytes(), &rawTx); err != nil {
+		err := i18n.WrapError(ctx, err, signermsgs.MsgInvalidTransaction)
+		return rpcbackend.RPCErrorResponse(err, rpcReq.ID, rpcbackend.RPCCodeParseError), err
+	}
+
+	if errRes, err := s.checkChainID(ctx, rpcReq, nil); err != nil {
+		return errRes, err
+	}
+
+	// Recovery fails for a transaction signed for a different chain
+	from, txn, err := ethsigner.RecoverRawTransaction(ctx, rawTx, s.chainIDFor(ctx))
+	if err != nil {
+		return rpcbackend.RPCErrorResponse(err, rpcReq.ID, rpcbackend.RPCCodeInvalidRequest), err
+	}
+	setSpanFrom(ctx, []byte(from.String()))
+	req := &signingRequest{method: rpcReq.Method, operation: signOpRawTransaction, from: from, txn: txn.Transaction}
+
+	if s.rawTxManagedSendersOnly {
+		if errRes, err := s.checkManagedSender(ctx, rpcReq, from); err != nil {
+			return s.rejectedByPolicy(ctx, req, errRes, err)
+		}
+	}
+
+	if errRes, err := s.authorizeAddress(ctx, rpcReq, from); err != nil {
+		return s.rejectedByPolicy(ctx, req, errRes, err)
+	}
+
+	if s.txPolicy != nil {
+		if err := s.txPolicy.check(ctx, txn.Transaction); err != nil {
+			return s.rejectedByPolicy(ctx, req, rpcbackend.RPCErrorResponse(err, rpcReq.ID, rpcbackend.RPCCodeInvalidRequest), err)
+		}
+	}
+
+	if s.feeCaps != nil {
+		if err := s.feeCaps.check(ctx, txn.Transaction); err != nil {
+			return s.rejectedByPolicy(ctx, req, rpcbackend.RPCErrorResponse(err, rpcReq.ID, rpcbackend.RPCCodeInvalidRequest), err)
+		}
+	}
+
+	return s.backend.SyncRequest(ctx, rpcReq)
+}
+
+func (s *rpcServer) checkManagedSender(ctx context.Context, rpcReq *rpcbackend.RPCRequest, from *ethtypes.Address0xHex) (*rpcbackend.RPCResponse, error) {
+	accounts, err := s.wallet.GetAccounts(ctx)
+	if err != nil {
+		return rpcbackend.RPCErrorResponse(err, rpcReq.ID, rpcbackend.RPCCodeInternalError), err
+	}
+	for _, addr := range accounts {
+		if *addr == *from {
+			return nil, nil
+		}
+	}
+	err = i18n.NewError(ctx, signermsgs.MsgUnmanagedSender, from)
+	return rpcbackend.RPCErrorResponse(err, rpcReq.ID, rpcbackend.RPCCodeInvalidRequest), err
+}
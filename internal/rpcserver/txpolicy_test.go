@@ -0,0 +1,228 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-signer/internal/signerconfig"
+	"github.com/hyperledger/firefly-signer/mocks/ethsignermocks"
+	"github.com/hyperledger/firefly-signer/mocks/rpcbackendmocks"
+	"github.com/hyperledger/firefly-signer/pkg/ethsigner"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/rpcauth"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+	"github.com/hyperledger/firefly-signer/pkg/secp256k1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const testAllowedDestination = "0x497eedc4299dea2f2a364be10025d0ad0f702de3"
+
+func setTestTxPolicyConf() {
+	config.Set(signerconfig.TxPolicyAllowedDestinations, []string{testAllowedDestination})
+	config.Set(signerconfig.TxPolicyAllowDeployments, false)
+	config.Set(signerconfig.TxPolicyMaxValue, "1000")
+	config.Set(signerconfig.TxPolicyRawTransactionsEnabled, true)
+}
+
+func newTestRawTxServer(t *testing.T, confSetters ...func()) (*rpcServer, *rpcbackendmocks.Backend, *secp256k1.KeyPair, func()) {
+	_, s, done := newTestServer(t, append([]func(){setTestTxPolicyConf}, confSetters...)...)
+	s.chainID = 1337
+	kp, err := secp256k1.GenerateSecp256k1KeyPair()
+	assert.NoError(t, err)
+	return s, s.backend.(*rpcbackendmocks.Backend), kp, done
+}
+
+func testRawTxRequest(t *testing.T, kp *secp256k1.KeyPair, chainID int64, txn *ethsigner.Transaction) *rpcbackend.RPCRequest {
+	rawTx, err := txn.SignEIP1559(kp, chainID)
+	assert.NoError(t, err)
+	return testRPCRequest(1, "eth_sendRawTransaction", fmt.Sprintf(`"%s"`, ethtypes.HexBytes0xPrefix(rawTx)))
+}
+
+func testAllowedTx(value int64) *ethsigner.Transaction {
+	return &ethsigner.Transaction{
+		Nonce:        ethtypes.NewHexIntegerU64(0),
+		GasLimit:     ethtypes.NewHexIntegerU64(21000),
+		MaxFeePerGas: ethtypes.NewHexIntegerU64(2000),
+		To:           ethtypes.MustNewAddress(testAllowedDestination),
+		Value:        ethtypes.NewHexInteger64(value),
+	}
+}
+
+func TestTxPolicyNotConfigured(t *testing.T) {
+	signerconfig.Reset()
+	tp, err := newTxPolicy(context.Background())
+	assert.NoError(t, err)
+	assert.Nil(t, tp)
+}
+
+func TestTxPolicyBadConfig(t *testing.T) {
+	for errCode, setConf := range map[string]func(){
+		"FF22151.*allowedDestinations": func() { config.Set(signerconfig.TxPolicyAllowedDestinations, []string{"wrong"}) },
+		"FF22151.*maxValue":            func() { config.Set(signerconfig.TxPolicyMaxValue, "-1") },
+	} {
+		signerconfig.Reset()
+		setConf()
+		_, err := NewServer(context.Background(), &ethsignermocks.Wallet{})
+		assert.Regexp(t, errCode, err)
+	}
+}
+
+func TestTxPolicyCheck(t *testing.T) {
+	ctx := context.Background()
+	signerconfig.Reset()
+	setTestTxPolicyConf()
+	tp, err := newTxPolicy(ctx)
+	assert.NoError(t, err)
+
+	err = tp.check(ctx, &ethsigner.Transaction{})
+	assert.Regexp(t, "FF22153", err)
+
+	err = tp.check(ctx, &ethsigner.Transaction{To: ethtypes.MustNewAddress("0xfb075bb99f2aa4c49955bf703509a227d7a12248")})
+	assert.Regexp(t, "FF22152.*0xfb075bb99f2aa4c49955bf703509a227d7a12248", err)
+
+	err = tp.check(ctx, testAllowedTx(1001))
+	assert.Regexp(t, "FF22154.*1001 wei exceeds the maximum of 1000 wei", err)
+
+	err = tp.check(ctx, testAllowedTx(1000))
+	assert.NoError(t, err)
+	err = tp.check(ctx, &ethsigner.Transaction{To: ethtypes.MustNewAddress(testAllowedDestination)})
+	assert.NoError(t, err)
+}
+
+func TestTxPolicySendTransaction(t *testing.T) {
+	_, s, done := newTestServer(t, setTestTxPolicyConf)
+	defer done()
+
+	rpcRes, err := s.processRPC(s.ctx, feesTestRequest(`, "to": "0xfb075bb99f2aa4c49955bf703509a227d7a12248"`))
+	assert.Regexp(t, "FF22152", err)
+	assert.Equal(t, int64(rpcbackend.RPCCodeInvalidRequest), rpcRes.Error.Code)
+}
+
+func TestRawTxPolicyDisabled(t *testing.T) {
+	_, s, done := newTestServer(t)
+	defer done()
+
+	bm := s.backend.(*rpcbackendmocks.Backend)
+	bm.On("SyncRequest", mock.Anything, mock.MatchedBy(func(rpcReq *rpcbackend.RPCRequest) bool {
+		return rpcReq.Method == "eth_sendRawTransaction"
+	})).Return(&rpcbackend.RPCResponse{Result: fftypes.JSONAnyPtr(`"0x1234"`)}, nil)
+
+	// Not decoded, so even an invalid transaction goes to the backend
+	_, err := s.processRPC(s.ctx, testRPCRequest(1, "eth_sendRawTransaction", `"0x00"`))
+	assert.NoError(t, err)
+	bm.AssertExpectations(t)
+}
+
+func TestRawTxPolicyForwarded(t *testing.T) {
+	s, bm, kp, done := newTestRawTxServer(t, func() {
+		config.Set(signerconfig.TxPolicyRawTransactionsManagedSendersOnly, true)
+		config.Set(signerconfig.FeeCapsMaxFeePerGas, "2000")
+	})
+	defer done()
+
+	w := s.wallet.(*ethsignermocks.Wallet)
+	w.On("GetAccounts", mock.Anything).Return([]*ethtypes.Address0xHex{
+		ethtypes.MustNewAddress(testAllowedDestination), &kp.Address,
+	}, nil)
+	rpcReq := testRawTxRequest(t, kp, 1337, testAllowedTx(1000))
+	bm.On("SyncRequest", mock.Anything, rpcReq).Return(&rpcbackend.RPCResponse{Result: fftypes.JSONAnyPtr(`"0x1234"`)}, nil)
+
+	rpcRes, err := s.processRPC(s.ctx, rpcReq)
+	assert.NoError(t, err)
+	assert.Equal(t, `"0x1234"`, rpcRes.Result.String())
+	bm.AssertExpectations(t)
+}
+
+func TestRawTxPolicyInvalidRequests(t *testing.T) {
+	s, _, kp, done := newTestRawTxServer(t)
+	defer done()
+
+	rpcRes, err := s.processRPC(s.ctx, testRPCRequest(1, "eth_sendRawTransaction"))
+	assert.Regexp(t, "FF22019", err)
+	assert.Equal(t, int64(rpcbackend.RPCCodeInvalidRequest), rpcRes.Error.Code)
+
+	rpcRes, err = s.processRPC(s.ctx, testRPCRequest(1, "eth_sendRawTransaction", "12345"))
+	assert.Regexp(t, "FF22023", err)
+	assert.Equal(t, int64(rpcbackend.RPCCodeParseError), rpcRes.Error.Code)
+
+	rpcRes, err = s.processRPC(s.ctx, testRPCRequest(1, "eth_sendRawTransaction", `"0x"`))
+	assert.Regexp(t, "FF22081", err)
+	assert.Equal(t, int64(rpcbackend.RPCCodeInvalidRequest), rpcRes.Error.Code)
+
+	// Signed for a different chain
+	_, err = s.processRPC(s.ctx, testRawTxRequest(t, kp, 1, testAllowedTx(0)))
+	assert.Regexp(t, "FF22086", err)
+
+	s.chainIDMismatch.Store(true)
+	_, err = s.processRPC(s.ctx, testRawTxRequest(t, kp, 1337, testAllowedTx(0)))
+	assert.Regexp(t, "FF22138", err)
+}
+
+func TestRawTxPolicyRejected(t *testing.T) {
+	s, _, kp, done := newTestRawTxServer(t, func() {
+		config.Set(signerconfig.FeeCapsMaxFeePerGas, "1999")
+		config.Set(signerconfig.FeeCapsPolicy, "clamp")
+	})
+	defer done()
+
+	rpcRes, err := s.processRPC(s.ctx, testRawTxRequest(t, kp, 1337, testAllowedTx(1001)))
+	assert.Regexp(t, "FF22154", err)
+	assert.Equal(t, int64(rpcbackend.RPCCodeInvalidRequest), rpcRes.Error.Code)
+
+	// Fee caps cannot be clamped on a signed transaction
+	rpcRes, err = s.processRPC(s.ctx, testRawTxRequest(t, kp, 1337, testAllowedTx(0)))
+	assert.Regexp(t, "FF22136.*maxFeePerGas", err)
+	assert.Equal(t, int64(rpcbackend.RPCCodeInvalidRequest), rpcRes.Error.Code)
+}
+
+func TestRawTxPolicyUnmanagedSender(t *testing.T) {
+	s, _, kp, done := newTestRawTxServer(t, func() {
+		config.Set(signerconfig.TxPolicyRawTransactionsManagedSendersOnly, true)
+	})
+	defer done()
+
+	w := s.wallet.(*ethsignermocks.Wallet)
+	w.On("GetAccounts", mock.Anything).Return([]*ethtypes.Address0xHex{
+		ethtypes.MustNewAddress(testAllowedDestination),
+	}, nil).Once()
+	rpcRes, err := s.processRPC(s.ctx, testRawTxRequest(t, kp, 1337, testAllowedTx(0)))
+	assert.Regexp(t, "FF22155.*"+kp.Address.String(), err)
+	assert.Equal(t, int64(rpcbackend.RPCCodeInvalidRequest), rpcRes.Error.Code)
+
+	w.On("GetAccounts", mock.Anything).Return(nil, fmt.Errorf("pop")).Once()
+	rpcRes, err = s.processRPC(s.ctx, testRawTxRequest(t, kp, 1337, testAllowedTx(0)))
+	assert.Regexp(t, "pop", err)
+	assert.Equal(t, int64(rpcbackend.RPCCodeInternalError), rpcRes.Error.Code)
+}
+
+func TestRawTxPolicyUnauthorizedSender(t *testing.T) {
+	_, s, done := newTestServer(t, setTestTxPolicyConf, setTestRBACConf)
+	defer done()
+	s.chainID = 1337
+	kp, err := secp256k1.GenerateSecp256k1KeyPair()
+	assert.NoError(t, err)
+
+	ctx := rpcauth.WithIdentity(s.ctx, &rpcauth.Identity{ID: "tenantA"})
+	rpcRes, err := s.processRPC(ctx, testRawTxRequest(t, kp, 1337, testAllowedTx(0)))
+	assertUnauthorized(t, rpcRes, err, "FF22111")
+}
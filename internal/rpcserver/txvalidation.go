@@ -0,0 +1,142 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-signer/internal/signerconfig"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// txSchema describes the transaction object of an eth_sendTransaction request, as the signer reads it.
+// Quantities can be JSON numbers, or hex or decimal strings, as ethtypes.HexInteger accepts.
+var txSchema = jsonschema.MustCompileString("transaction.json", `{
+	"type": "object",
+	"properties": {
+		"to": {
+			"type": ["string", "null"],
+			"pattern": "^(0x)?[0-9a-fA-F]{40}$"
+		},
+		"data": {
+			"type": ["string", "null"],
+			"pattern": "^(0x)?([0-9a-fA-F]{2})*$"
+		},
+		"nonce": { "$ref": "#/$defs/quantity" },
+		"gas": { "$ref": "#/$defs/quantity" },
+		"gasPrice": { "$ref": "#/$defs/quantity" },
+		"maxFeePerGas": { "$ref": "#/$defs/quantity" },
+		"maxPriorityFeePerGas": { "$ref": "#/$defs/quantity" },
+		"value": { "$ref": "#/$defs/quantity" },
+		"chainId": { "$ref": "#/$defs/quantity" }
+	},
+	"$defs": {
+		"quantity": {
+			"type": ["string", "integer", "null"],
+			"pattern": "^(0x[0-9a-fA-F]+|[0-9]+)$",
+			"minimum": 0
+		}
+	}
+}`)
+
+// txFields are the fields the signer uses from a transaction, with the error for a badly formatted value of each
+var txFields = map[string]i18n.ErrorMessageKey{
+	"from":                 "", // not checked, as some wallets select keys by other identifiers
+	"to":                   signermsgs.MsgTxInvalidAddressField,
+	"data":                 signermsgs.MsgTxInvalidDataField,
+	"nonce":                signermsgs.MsgTxInvalidQuantityField,
+	"gas":                  signermsgs.MsgTxInvalidQuantityField,
+	"gasPrice":             signermsgs.MsgTxInvalidQuantityField,
+	"maxFeePerGas":         signermsgs.MsgTxInvalidQuantityField,
+	"maxPriorityFeePerGas": signermsgs.MsgTxInvalidQuantityField,
+	"value":                signermsgs.MsgTxInvalidQuantityField,
+	"chainId":              signermsgs.MsgTxInvalidQuantityField,
+}
+
+// txValidation checks the JSON of a transaction against txSchema before it is parsed, so a malformed transaction
+// is rejected with an error naming the field - before the wallet is involved
+type txValidation struct {
+	allowUnknownFields bool
+}
+
+func newTxValidation() *txValidation {
+	return &txValidation{
+		allowUnknownFields: config.GetBool(signerconfig.TxValidationAllowUnknownFields),
+	}
+}
+
+func (tv *txValidation) validate(ctx context.Context, txnJSON []byte) error {
+	var v interface{}
+	d := json.NewDecoder(bytes.NewReader(txnJSON))
+	d.UseNumber()
+	if err := d.Decode(&v); err != nil {
+		return i18n.WrapError(ctx, err, signermsgs.MsgInvalidTransaction)
+	}
+	if err := txSchema.Validate(v); err != nil {
+		return schemaViolation(ctx, err, v)
+	}
+
+	txn := v.(map[string]interface{})
+	if !tv.allowUnknownFields {
+		var unknown []string
+		for field := range txn {
+			if _, ok := txFields[field]; !ok {
+				unknown = append(unknown, field)
+			}
+		}
+		if len(unknown) > 0 {
+			sort.Strings(unknown)
+			return i18n.NewError(ctx, signermsgs.MsgTxUnknownFields, strings.Join(unknown, ", "))
+		}
+	}
+	if txn["gasPrice"] != nil && (txn["maxFeePerGas"] != nil || txn["maxPriorityFeePerGas"] != nil) {
+		return i18n.NewError(ctx, signermsgs.MsgTxMixedFeeFields)
+	}
+	return nil
+}
+
+// schemaViolation returns the error for the first invalid field, in alphabetical order so the error is
+// deterministic when more than one field is invalid
+func schemaViolation(ctx context.Context, err error, v interface{}) error {
+	var ve *jsonschema.ValidationError
+	_ = errors.As(err, &ve)
+	var fields []string
+	var collect func(ve *jsonschema.ValidationError)
+	collect = func(ve *jsonschema.ValidationError) {
+		if field := strings.TrimPrefix(ve.InstanceLocation, "/"); field != "" {
+			fields = append(fields, field)
+		}
+		for _, cause := range ve.Causes {
+			collect(cause)
+		}
+	}
+	collect(ve)
+	if len(fields) == 0 {
+		return i18n.NewError(ctx, signermsgs.MsgTxNotObject)
+	}
+	sort.Strings(fields)
+	field := fields[0]
+	return i18n.NewError(ctx, txFields[field], field, v.(map[string]interface{})[field])
+}
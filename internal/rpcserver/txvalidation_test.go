@@ -0,0 +1,98 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"testing"
+
+	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/hyperledger/firefly-signer/internal/signerconfig"
+	"github.com/hyperledger/firefly-signer/mocks/rpcbackendmocks"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func setTestTxValidationConf() {
+	config.Set(signerconfig.TxValidationEnabled, true)
+}
+
+func TestTxValidationValidTransaction(t *testing.T) {
+	_, s, done := newTestServer(t, setTestTxValidationConf)
+	defer done()
+	s.chainID = 1337
+
+	bm := s.backend.(*rpcbackendmocks.Backend)
+	bm.On("CallRPC", mock.Anything, mock.Anything, "eth_getTransactionCount", mock.Anything, "pending").Return(&rpcbackend.RPCError{Message: "pop"})
+
+	_, err := s.processRPC(s.ctx, testRPCRequest(1, "eth_sendTransaction", `{
+		"from": "0xfb075bb99f2aa4c49955bf703509a227d7a12248",
+		"to": "3c99f2a4b366d46bcf2277639a135a6d1288eceb",
+		"gas": 21000,
+		"maxFeePerGas": "0x3b9aca00",
+		"maxPriorityFeePerGas": "1000000",
+		"value": null,
+		"data": "0xfeedbeef",
+		"chainId": "0x539"
+	}`))
+	assert.Regexp(t, "pop", err)
+	bm.AssertExpectations(t)
+}
+
+func TestTxValidationRejected(t *testing.T) {
+	_, s, done := newTestServer(t, setTestTxValidationConf)
+	defer done()
+	s.chainID = 1337
+
+	for _, tc := range []struct {
+		txn   string
+		error string
+	}{
+		{`["not", "an", "object"]`, "FF22249"},
+		{`{"from": "0x01", "input": "0xfeedbeef", "accessList": []}`, "FF22244.*accessList, input"},
+		{`{"from": "0x01", "gasPrice": "0x1", "maxFeePerGas": "0x1"}`, "FF22245"},
+		{`{"from": "0x01", "gasPrice": "0x1", "maxPriorityFeePerGas": "0x1"}`, "FF22245"},
+		{`{"from": "0x01", "gas": -1}`, "FF22246.*'gas'"},
+		{`{"from": "0x01", "gas": 1.5}`, "FF22246.*'gas'"},
+		{`{"from": "0x01", "value": "0xfg"}`, "FF22246.*'value'.*0xfg"},
+		{`{"from": "0x01", "nonce": true}`, "FF22246.*'nonce'"},
+		{`{"from": "0x01", "to": "0x3c99f2a4b366d46bcf2277639a135a6d1288ec"}`, "FF22247.*'to'"},
+		{`{"from": "0x01", "data": "0xfee"}`, "FF22248.*'data'"},
+		{`{"from": "0x01", "to": 12345, "data": "0xfee", "value": "-1"}`, "FF22248.*'data'"},
+		{`{"from": "0x01",`, "FF22023"},
+	} {
+		rpcRes, err := s.processRPC(s.ctx, testRPCRequest(1, "eth_sendTransaction", tc.txn))
+		assert.Regexp(t, tc.error, err, tc.txn)
+		assert.Equal(t, int64(rpcbackend.RPCCodeInvalidRequest), rpcRes.Error.Code, tc.txn)
+	}
+}
+
+func TestTxValidationAllowUnknownFields(t *testing.T) {
+	_, s, done := newTestServer(t, setTestTxValidationConf, func() {
+		config.Set(signerconfig.TxValidationAllowUnknownFields, true)
+	})
+	defer done()
+
+	_, err := s.processRPC(s.ctx, testRPCRequest(1, "eth_sendTransaction", `{"from": "0x01", "input": "0xfeedbeef", "gasPrice": "0x1", "maxFeePerGas": "0x1"}`))
+	assert.Regexp(t, "FF22245", err)
+}
+
+func TestTxValidationDisabled(t *testing.T) {
+	_, s, done := newTestServer(t)
+	defer done()
+	assert.Nil(t, s.txValidation)
+}
@@ -0,0 +1,117 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/hyperledger/firefly-common/pkg/httpserver"
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-common/pkg/log"
+	"github.com/hyperledger/firefly-signer/internal/signerconfig"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+)
+
+// unixSocketServer serves the JSON/RPC API on a UNIX domain socket, for sidecar deployments where the
+// only client is a co-located process. The HTTP server framework only listens on TCP, so this is a
+// minimal equivalent with the same timeouts, and without TLS or CORS (which do not apply).
+type unixSocketServer struct {
+	path            string
+	listener        net.Listener
+	server          *http.Server
+	shutdownTimeout time.Duration
+	done            chan error
+}
+
+func newUnixSocketServer(ctx context.Context, conf config.Section, handler http.Handler) (*unixSocketServer, error) {
+	path := conf.GetString(signerconfig.ServerConfUnixSocketPath)
+	modeStr := conf.GetString(signerconfig.ServerConfUnixSocketMode)
+	mode, err := strconv.ParseUint(modeStr, 8, 32)
+	if err != nil || mode > 0777 {
+		return nil, i18n.NewError(ctx, signermsgs.MsgBadUnixSocketMode, modeStr)
+	}
+
+	// A socket left behind by a previous run that did not shut down cleanly is replaced,
+	// but we never remove anything else that happens to be at the path
+	if fi, err := os.Lstat(path); err == nil {
+		if fi.Mode().Type() != fs.ModeSocket {
+			return nil, i18n.NewError(ctx, signermsgs.MsgUnixSocketPathInUse, path)
+		}
+		// If it cannot be removed, the listen below fails
+		log.L(ctx).Warnf("Removing stale UNIX domain socket %s", path)
+		_ = os.Remove(path)
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, i18n.WrapError(ctx, err, signermsgs.MsgUnixSocketListenFailed, path)
+	}
+	// This also rejects Linux abstract sockets (with a path starting '@'), which cannot have permissions
+	if err := os.Chmod(path, os.FileMode(mode)); err != nil {
+		listener.Close()
+		return nil, i18n.WrapError(ctx, err, signermsgs.MsgUnixSocketListenFailed, path)
+	}
+	log.L(ctx).Infof("server listening on UNIX domain socket %s", path)
+
+	return &unixSocketServer{
+		path:     path,
+		listener: listener,
+		server: &http.Server{
+			Handler:           handler,
+			ReadTimeout:       conf.GetDuration(httpserver.HTTPConfReadTimeout),
+			ReadHeaderTimeout: conf.GetDuration(httpserver.HTTPConfReadTimeout),
+			WriteTimeout:      conf.GetDuration(httpserver.HTTPConfWriteTimeout),
+		},
+		shutdownTimeout: conf.GetDuration(httpserver.HTTPConfShutdownTimeout),
+		done:            make(chan error),
+	}, nil
+}
+
+// serve runs until the context is cancelled, then reports the outcome on the done channel. The
+// socket file is removed when the listener is closed.
+func (us *unixSocketServer) serve(ctx context.Context) {
+	serverEnded := make(chan struct{})
+	shutdownErr := make(chan error, 1)
+	go func() {
+		select {
+		case <-ctx.Done():
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), us.shutdownTimeout)
+			defer cancel()
+			shutdownErr <- us.server.Shutdown(shutdownCtx)
+		case <-serverEnded:
+			shutdownErr <- nil
+		}
+	}()
+
+	err := us.server.Serve(us.listener)
+	if errors.Is(err, http.ErrServerClosed) {
+		err = nil
+	}
+	close(serverEnded)
+	if sErr := <-shutdownErr; err == nil {
+		err = sErr
+	}
+	log.L(ctx).Infof("UNIX domain socket server complete")
+	us.done <- err
+}
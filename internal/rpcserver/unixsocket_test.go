@@ -0,0 +1,166 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-signer/internal/signerconfig"
+	"github.com/hyperledger/firefly-signer/mocks/ethsignermocks"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func setTestUnixSocketConf(path string) {
+	signerconfig.Reset()
+	signerconfig.ServerConfig.Set(signerconfig.ServerConfTCPEnabled, false)
+	signerconfig.ServerConfig.Set(signerconfig.ServerConfUnixSocketPath, path)
+	config.Set(signerconfig.BackendChainID, 12345)
+	config.Set(signerconfig.BackendChainIDValidationEnabled, false)
+}
+
+func unixSocketClient(path string) *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", path)
+			},
+		},
+	}
+}
+
+func TestUnixSocketServe(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "signer.sock")
+	setTestUnixSocketConf(path)
+
+	w := &ethsignermocks.Wallet{}
+	w.On("Initialize", mock.Anything).Return(nil)
+	w.On("GetAccounts", mock.Anything).Return([]*ethtypes.Address0xHex{}, nil)
+	ss, err := NewServer(context.Background(), w)
+	assert.NoError(t, err)
+	s := ss.(*rpcServer)
+	assert.Nil(t, s.apiServer)
+
+	fi, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), fi.Mode().Perm())
+
+	err = s.Start()
+	assert.NoError(t, err)
+
+	res, err := unixSocketClient(path).Post("http://unix/", "application/json", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"eth_accounts"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	var rpcRes rpcbackend.RPCResponse
+	err = json.NewDecoder(res.Body).Decode(&rpcRes)
+	assert.NoError(t, err)
+	assert.Equal(t, fftypes.JSONAnyPtr("[]"), rpcRes.Result)
+
+	s.Stop()
+	err = s.WaitStop()
+	assert.NoError(t, err)
+
+	// The socket is removed on shutdown
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestUnixSocketAndTCP(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "signer.sock")
+	_, s, done := newTestServer(t, func() {
+		signerconfig.ServerConfig.Set(signerconfig.ServerConfUnixSocketPath, path)
+		signerconfig.ServerConfig.Set(signerconfig.ServerConfUnixSocketMode, "0660")
+	})
+	defer done()
+	assert.NotNil(t, s.apiServer)
+	assert.NotNil(t, s.unixSocketServer)
+
+	fi, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0660), fi.Mode().Perm())
+	startTestServerNoBackend(t, s)
+}
+
+func TestUnixSocketReplacesStaleSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "signer.sock")
+	stale, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+	assert.NoError(t, err)
+	stale.SetUnlinkOnClose(false)
+	stale.Close()
+
+	setTestUnixSocketConf(path)
+	ss, err := NewServer(context.Background(), &ethsignermocks.Wallet{})
+	assert.NoError(t, err)
+	ss.(*rpcServer).unixSocketServer.listener.Close()
+}
+
+func TestUnixSocketPathInUse(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "signer.sock")
+	err := os.WriteFile(path, []byte("not a socket"), 0600)
+	assert.NoError(t, err)
+
+	setTestUnixSocketConf(path)
+	_, err = NewServer(context.Background(), &ethsignermocks.Wallet{})
+	assert.Regexp(t, "FF22144", err)
+
+	// The file is left alone
+	b, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "not a socket", string(b))
+}
+
+func TestUnixSocketBadConfig(t *testing.T) {
+	for errCode, setConf := range map[string]func(){
+		"FF22141": func() { setTestUnixSocketConf("") },
+		"FF22142": func() {
+			setTestUnixSocketConf(filepath.Join(t.TempDir(), "signer.sock"))
+			signerconfig.ServerConfig.Set(signerconfig.ServerConfUnixSocketMode, "rw-------")
+		},
+		"FF22142.*1777": func() {
+			setTestUnixSocketConf(filepath.Join(t.TempDir(), "signer.sock"))
+			signerconfig.ServerConfig.Set(signerconfig.ServerConfUnixSocketMode, "1777")
+		},
+		"FF22143.*missing": func() { setTestUnixSocketConf(filepath.Join(t.TempDir(), "missing", "signer.sock")) },
+		"FF22143.*@":       func() { setTestUnixSocketConf("@" + fftypes.NewUUID().String()) },
+	} {
+		setConf()
+		_, err := NewServer(context.Background(), &ethsignermocks.Wallet{})
+		assert.Regexp(t, errCode, err)
+	}
+}
+
+func TestUnixSocketServeFail(t *testing.T) {
+	setTestUnixSocketConf(filepath.Join(t.TempDir(), "signer.sock"))
+	ss, err := NewServer(context.Background(), &ethsignermocks.Wallet{})
+	assert.NoError(t, err)
+	us := ss.(*rpcServer).unixSocketServer
+	us.listener.Close()
+
+	go us.serve(context.Background())
+	assert.Error(t, <-us.done)
+}
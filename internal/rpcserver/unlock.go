@@ -0,0 +1,134 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/hyperledger/firefly-signer/pkg/ethsigner"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+)
+
+func (s *rpcServer) unlockableWallet(ctx context.Context, rpcReq *rpcbackend.RPCRequest, minParams int) (ethsigner.WalletUnlockable, *rpcbackend.RPCResponse, error) {
+	w, ok := s.wallet.(ethsigner.WalletUnlockable)
+	if !ok {
+		err := i18n.NewError(ctx, signermsgs.MsgWalletUnlockNotSupported)
+		return nil, rpcbackend.RPCErrorResponse(err, rpcReq.ID, rpcbackend.RPCCodeInvalidRequest), err
+	}
+	if len(rpcReq.Params) < minParams {
+		err := i18n.NewError(ctx, signermsgs.MsgInvalidParamCount, minParams, len(rpcReq.Params))
+		return nil, rpcbackend.RPCErrorResponse(err, rpcReq.ID, rpcbackend.RPCCodeInvalidRequest), err
+	}
+	return w, nil, nil
+}
+
+func (s *rpcServer) unmarshalParam(ctx context.Context, rpcReq *rpcbackend.RPCRequest, idx int, v interface{}) (*rpcbackend.RPCResponse, error) {
+	if err := json.Unmarshal(rpcReq.Params[idx].Bytes(), v); err != nil {
+		err := i18n.NewError(ctx, signermsgs.MsgInvalidParam, idx, rpcReq.Method, err)
+		return rpcbackend.RPCErrorResponse(err, rpcReq.ID, rpcbackend.RPCCodeInvalidRequest), err
+	}
+	return nil, nil
+}
+
+func trueResult(rpcReq *rpcbackend.RPCRequest) *rpcbackend.RPCResponse {
+	return &rpcbackend.RPCResponse{
+		JSONRpc: "2.0",
+		ID:      rpcReq.ID,
+		Result:  fftypes.JSONAnyPtr("true"),
+	}
+}
+
+// processPersonalUnlockAccount follows the personal_unlockAccount signature of address, passphrase
+// and optional duration in seconds. A null/omitted passphrase uses the password configured in the wallet,
+// unless the wallet requires keys to be unlocked, when only the passphrase of the key can unlock it.
+func (s *rpcServer) processPersonalUnlockAccount(ctx context.Context, rpcReq *rpcbackend.RPCRequest) (*rpcbackend.RPCResponse, error) {
+	w, errRes, err := s.unlockableWallet(ctx, rpcReq, 1)
+	if err != nil {
+		return errRes, err
+	}
+
+	var addr ethtypes.Address0xHex
+	if errRes, err := s.unmarshalParam(ctx, rpcReq, 0, &addr); err != nil {
+		return errRes, err
+	}
+	if errRes, err := s.authorizeAddress(ctx, rpcReq, &addr); err != nil {
+		return errRes, err
+	}
+	var password *string
+	if len(rpcReq.Params) > 1 {
+		if errRes, err := s.unmarshalParam(ctx, rpcReq, 1, &password); err != nil {
+			return errRes, err
+		}
+	}
+	if password == nil && s.requireUnlock {
+		err := i18n.NewError(ctx, signermsgs.MsgUnlockPassphraseRequired, addr)
+		return rpcbackend.RPCErrorResponse(err, rpcReq.ID, rpcbackend.RPCCodeInvalidRequest), err
+	}
+	var durationSecs *ethtypes.HexUint64
+	if len(rpcReq.Params) > 2 {
+		if errRes, err := s.unmarshalParam(ctx, rpcReq, 2, &durationSecs); err != nil {
+			return errRes, err
+		}
+	}
+
+	var passwordBytes []byte
+	if password != nil {
+		passwordBytes = []byte(*password)
+	}
+	ttl := time.Duration(durationSecs.Uint64OrZero()) * time.Second
+	if err := w.Unlock(ctx, addr, passwordBytes, ttl); err != nil {
+		return rpcbackend.RPCErrorResponse(err, rpcReq.ID, rpcbackend.RPCCodeInternalError), err
+	}
+	return trueResult(rpcReq), nil
+}
+
+func (s *rpcServer) processPersonalLockAccount(ctx context.Context, rpcReq *rpcbackend.RPCRequest) (*rpcbackend.RPCResponse, error) {
+	w, errRes, err := s.unlockableWallet(ctx, rpcReq, 1)
+	if err != nil {
+		return errRes, err
+	}
+
+	var addr ethtypes.Address0xHex
+	if errRes, err := s.unmarshalParam(ctx, rpcReq, 0, &addr); err != nil {
+		return errRes, err
+	}
+	if errRes, err := s.authorizeAddress(ctx, rpcReq, &addr); err != nil {
+		return errRes, err
+	}
+	if err := w.Lock(ctx, addr); err != nil {
+		return rpcbackend.RPCErrorResponse(err, rpcReq.ID, rpcbackend.RPCCodeInternalError), err
+	}
+	return trueResult(rpcReq), nil
+}
+
+func (s *rpcServer) processLockAllAccounts(ctx context.Context, rpcReq *rpcbackend.RPCRequest) (*rpcbackend.RPCResponse, error) {
+	w, errRes, err := s.unlockableWallet(ctx, rpcReq, 0)
+	if err != nil {
+		return errRes, err
+	}
+
+	if err := w.LockAll(ctx); err != nil {
+		return rpcbackend.RPCErrorResponse(err, rpcReq.ID, rpcbackend.RPCCodeInternalError), err
+	}
+	return trueResult(rpcReq), nil
+}
@@ -0,0 +1,284 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-signer/mocks/ethsignermocks"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newTestUnlockServer(t *testing.T) (context.Context, *rpcServer, func()) {
+	_, s, done := newTestServer(t)
+	grantTestOperator(t, s)
+	return s.ctx, s, done
+}
+
+func TestPersonalUnlockAccountOK(t *testing.T) {
+
+	ctx, s, done := newTestUnlockServer(t)
+	defer done()
+
+	w := &ethsignermocks.WalletUnlockable{}
+	s.wallet = w
+	w.On("Unlock", mock.Anything, *ethtypes.MustNewAddress("0xfb075bb99f2aa4c49955bf703509a227d7a12248"), []byte("pass"), 30*time.Second).Return(nil)
+
+	rpcRes, err := s.processRPC(ctx, &rpcbackend.RPCRequest{
+		ID:     fftypes.JSONAnyPtr("1"),
+		Method: "personal_unlockAccount",
+		Params: []*fftypes.JSONAny{
+			fftypes.JSONAnyPtr(`"0xfb075bb99f2aa4c49955bf703509a227d7a12248"`),
+			fftypes.JSONAnyPtr(`"pass"`),
+			fftypes.JSONAnyPtr(`30`),
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, `true`, rpcRes.Result.String())
+	w.AssertExpectations(t)
+
+}
+
+func TestPersonalUnlockAccountConfiguredPassword(t *testing.T) {
+
+	ctx, s, done := newTestUnlockServer(t)
+	defer done()
+
+	w := &ethsignermocks.WalletUnlockable{}
+	s.wallet = w
+	w.On("Unlock", mock.Anything, mock.Anything, []byte(nil), time.Duration(0)).Return(nil)
+
+	_, err := s.processRPC(ctx, &rpcbackend.RPCRequest{
+		ID:     fftypes.JSONAnyPtr("1"),
+		Method: "personal_unlockAccount",
+		Params: []*fftypes.JSONAny{
+			fftypes.JSONAnyPtr(`"0xfb075bb99f2aa4c49955bf703509a227d7a12248"`),
+			fftypes.JSONAnyPtr(`null`),
+		},
+	})
+	assert.NoError(t, err)
+	w.AssertExpectations(t)
+
+}
+
+func TestPersonalUnlockAccountRequiresPassphrase(t *testing.T) {
+
+	ctx, s, done := newTestUnlockServer(t)
+	defer done()
+
+	s.wallet = &ethsignermocks.WalletUnlockable{}
+	s.requireUnlock = true
+
+	for _, params := range [][]string{
+		{`"0xfb075bb99f2aa4c49955bf703509a227d7a12248"`},
+		{`"0xfb075bb99f2aa4c49955bf703509a227d7a12248"`, `null`, `30`},
+	} {
+		rpcReq := &rpcbackend.RPCRequest{
+			ID:     fftypes.JSONAnyPtr("1"),
+			Method: "personal_unlockAccount",
+		}
+		for _, p := range params {
+			rpcReq.Params = append(rpcReq.Params, fftypes.JSONAnyPtr(p))
+		}
+		rpcRes, err := s.processRPC(ctx, rpcReq)
+		assert.Regexp(t, "FF22325.*0xfb075bb99f2aa4c49955bf703509a227d7a12248", err)
+		assert.Equal(t, int64(rpcbackend.RPCCodeInvalidRequest), rpcRes.Error.Code)
+	}
+
+}
+
+func TestLockUnlockRequireRBAC(t *testing.T) {
+
+	_, s, done := newTestServer(t)
+	defer done()
+
+	w := &ethsignermocks.WalletUnlockable{}
+	s.wallet = w
+
+	for _, method := range []string{"personal_unlockAccount", "personal_lockAccount", "ffsigner_lockAllAccounts"} {
+		rpcRes, err := s.processRPC(s.ctx, &rpcbackend.RPCRequest{
+			ID:     fftypes.JSONAnyPtr("1"),
+			Method: method,
+			Params: []*fftypes.JSONAny{fftypes.JSONAnyPtr(`"0xfb075bb99f2aa4c49955bf703509a227d7a12248"`), fftypes.JSONAnyPtr(`"pass"`)},
+		})
+		assertUnauthorized(t, rpcRes, err, "FF22326.*"+method)
+	}
+	w.AssertExpectations(t)
+
+}
+
+func TestPersonalUnlockAccountFail(t *testing.T) {
+
+	ctx, s, done := newTestUnlockServer(t)
+	defer done()
+
+	w := &ethsignermocks.WalletUnlockable{}
+	s.wallet = w
+	w.On("Unlock", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(fmt.Errorf("pop"))
+
+	_, err := s.processRPC(ctx, &rpcbackend.RPCRequest{
+		ID:     fftypes.JSONAnyPtr("1"),
+		Method: "personal_unlockAccount",
+		Params: []*fftypes.JSONAny{
+			fftypes.JSONAnyPtr(`"0xfb075bb99f2aa4c49955bf703509a227d7a12248"`),
+		},
+	})
+	assert.Regexp(t, "pop", err)
+
+}
+
+func TestPersonalUnlockAccountBadParams(t *testing.T) {
+
+	ctx, s, done := newTestUnlockServer(t)
+	defer done()
+
+	s.wallet = &ethsignermocks.WalletUnlockable{}
+
+	for _, params := range [][]string{
+		{},
+		{`"bad address"`},
+		{`"0xfb075bb99f2aa4c49955bf703509a227d7a12248"`, `{}`},
+		{`"0xfb075bb99f2aa4c49955bf703509a227d7a12248"`, `"pass"`, `"bad duration"`},
+	} {
+		rpcReq := &rpcbackend.RPCRequest{
+			ID:     fftypes.JSONAnyPtr("1"),
+			Method: "personal_unlockAccount",
+		}
+		for _, p := range params {
+			rpcReq.Params = append(rpcReq.Params, fftypes.JSONAnyPtr(p))
+		}
+		_, err := s.processRPC(ctx, rpcReq)
+		assert.Regexp(t, "FF22019|FF22011", err)
+	}
+
+}
+
+func TestPersonalUnlockAccountNotSupported(t *testing.T) {
+
+	ctx, s, done := newTestUnlockServer(t)
+	defer done()
+
+	_, err := s.processRPC(ctx, &rpcbackend.RPCRequest{
+		ID:     fftypes.JSONAnyPtr("1"),
+		Method: "personal_unlockAccount",
+	})
+	assert.Regexp(t, "FF22095", err)
+
+	_, err = s.processRPC(ctx, &rpcbackend.RPCRequest{
+		ID:     fftypes.JSONAnyPtr("1"),
+		Method: "ffsigner_lockAllAccounts",
+	})
+	assert.Regexp(t, "FF22095", err)
+
+}
+
+func TestPersonalLockAccountOK(t *testing.T) {
+
+	ctx, s, done := newTestUnlockServer(t)
+	defer done()
+
+	w := &ethsignermocks.WalletUnlockable{}
+	s.wallet = w
+	w.On("Lock", mock.Anything, *ethtypes.MustNewAddress("0xfb075bb99f2aa4c49955bf703509a227d7a12248")).Return(nil)
+
+	rpcRes, err := s.processRPC(ctx, &rpcbackend.RPCRequest{
+		ID:     fftypes.JSONAnyPtr("1"),
+		Method: "personal_lockAccount",
+		Params: []*fftypes.JSONAny{
+			fftypes.JSONAnyPtr(`"0xfb075bb99f2aa4c49955bf703509a227d7a12248"`),
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, `true`, rpcRes.Result.String())
+
+}
+
+func TestPersonalLockAccountFail(t *testing.T) {
+
+	ctx, s, done := newTestUnlockServer(t)
+	defer done()
+
+	w := &ethsignermocks.WalletUnlockable{}
+	s.wallet = w
+	w.On("Lock", mock.Anything, mock.Anything).Return(fmt.Errorf("pop"))
+
+	_, err := s.processRPC(ctx, &rpcbackend.RPCRequest{
+		ID:     fftypes.JSONAnyPtr("1"),
+		Method: "personal_lockAccount",
+		Params: []*fftypes.JSONAny{
+			fftypes.JSONAnyPtr(`"0xfb075bb99f2aa4c49955bf703509a227d7a12248"`),
+		},
+	})
+	assert.Regexp(t, "pop", err)
+
+}
+
+func TestPersonalLockAccountBadParams(t *testing.T) {
+
+	ctx, s, done := newTestUnlockServer(t)
+	defer done()
+
+	s.wallet = &ethsignermocks.WalletUnlockable{}
+
+	_, err := s.processRPC(ctx, &rpcbackend.RPCRequest{
+		ID:     fftypes.JSONAnyPtr("1"),
+		Method: "personal_lockAccount",
+	})
+	assert.Regexp(t, "FF22019", err)
+
+	_, err = s.processRPC(ctx, &rpcbackend.RPCRequest{
+		ID:     fftypes.JSONAnyPtr("1"),
+		Method: "personal_lockAccount",
+		Params: []*fftypes.JSONAny{
+			fftypes.JSONAnyPtr(`"bad address"`),
+		},
+	})
+	assert.Regexp(t, "FF22011", err)
+
+}
+
+func TestLockAllAccounts(t *testing.T) {
+
+	ctx, s, done := newTestUnlockServer(t)
+	defer done()
+
+	w := &ethsignermocks.WalletUnlockable{}
+	s.wallet = w
+	w.On("LockAll", mock.Anything).Return(nil).Once()
+	w.On("LockAll", mock.Anything).Return(fmt.Errorf("pop"))
+
+	rpcRes, err := s.processRPC(ctx, &rpcbackend.RPCRequest{
+		ID:     fftypes.JSONAnyPtr("1"),
+		Method: "ffsigner_lockAllAccounts",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, `true`, rpcRes.Result.String())
+
+	_, err = s.processRPC(ctx, &rpcbackend.RPCRequest{
+		ID:     fftypes.JSONAnyPtr("1"),
+		Method: "ffsigner_lockAllAccounts",
+	})
+	assert.Regexp(t, "pop", err)
+
+}
@@ -0,0 +1,399 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	ws "github.com/gorilla/websocket"
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-common/pkg/httpserver"
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-common/pkg/log"
+	"github.com/hyperledger/firefly-signer/internal/signerconfig"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/hyperledger/firefly-signer/pkg/debuglog"
+	"github.com/hyperledger/firefly-signer/pkg/rpcauth"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+	"github.com/rs/cors"
+)
+
+// Sent to the client when a subscription has been re-established on the backend after a reconnect,
+// as notifications might have been missed while the backend was disconnected
+const resubscribedNotificationMethod = "ffsigner_resubscribed"
+
+type wsConnection struct {
+	ctx       context.Context
+	cancelCtx context.CancelFunc
+	id        string
+	server    *rpcServer
+	conn      *ws.Conn
+	closeMux  sync.Mutex
+	closed    bool
+	send      chan interface{}
+	closing   chan struct{}
+	subsMux   sync.Mutex
+	subs      map[string]rpcbackend.Subscription
+}
+
+type rpcSubscriptionParams struct {
+	Subscription string           `json:"subscription"`
+	Result       *fftypes.JSONAny `json:"result,omitempty"`
+}
+
+type rpcSubscriptionNotification struct {
+	JSONRpc string                `json:"jsonrpc"`
+	Method  string                `json:"method"`
+	Params  rpcSubscriptionParams `json:"params"`
+}
+
+// newWSUpgrader accepts connections from browser based dApps on the same origins that CORS allows HTTP
+// requests from. Without CORS, only same-origin browser connections are accepted. Clients that are not
+// browsers do not send an Origin header, so are always accepted.
+func newWSUpgrader() ws.Upgrader {
+	var corsOrigins *cors.Cors
+	if signerconfig.CorsConfig.GetBool(httpserver.CorsEnabled) {
+		corsOrigins = cors.New(cors.Options{AllowedOrigins: signerconfig.CorsConfig.GetStringSlice(httpserver.CorsAllowedOrigins)})
+	}
+	return ws.Upgrader{
+		CheckOrigin: func(r *http.Request) bool {
+			origin := r.Header.Get("Origin")
+			if origin == "" {
+				return true
+			}
+			if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+				return true
+			}
+			return corsOrigins != nil && corsOrigins.OriginAllowed(r)
+		},
+	}
+}
+
+func (s *rpcServer) wsHandler(w http.ResponseWriter, r *http.Request) {
+	// The upgrader writes its own response, so any correlation ID is passed to it to be echoed back
+	var responseHeader http.Header
+	if correlationID := getCorrelationID(r.Context()); correlationID != "" {
+		responseHeader = http.Header{}
+		responseHeader.Set(s.accessLog.correlationHeader, correlationID)
+	}
+	conn, err := s.wsUpgrader.Upgrade(w, r, responseHeader)
+	if err != nil {
+		// The upgrader has already replied with an HTTP error
+		log.L(r.Context()).Errorf("WebSocket upgrade failed: %s", err)
+		return
+	}
+	// The HTTP server read/write timeouts are still set on the hijacked connection
+	_ = conn.SetReadDeadline(time.Time{})
+	_ = conn.SetWriteDeadline(time.Time{})
+	s.newWSConnection(r.Context(), conn)
+}
+
+func (s *rpcServer) newWSConnection(reqCtx context.Context, conn *ws.Conn) *wsConnection {
+	id := fftypes.NewUUID().String()
+	c := &wsConnection{
+		id:      id,
+		server:  s,
+		conn:    conn,
+		send:    make(chan interface{}),
+		closing: make(chan struct{}),
+		subs:    make(map[string]rpcbackend.Subscription),
+	}
+	// The connection outlives the upgrade request, so the identity of the caller is carried over from it,
+	// along with the correlation ID and language that apply to every request on the connection
+	ctx := withRateLimitKey(rpcauth.WithIdentity(s.ctx, rpcauth.GetIdentity(reqCtx)), getRateLimitKey(reqCtx))
+	ctx = withChainRoute(withCorrelationID(ctx, getCorrelationID(reqCtx)), getChainRoute(reqCtx))
+	ctx = withAcceptLanguage(ctx, getAcceptLanguage(reqCtx))
+	c.ctx, c.cancelCtx = context.WithCancel(log.WithLogField(ctx, "wsc", id))
+	conn.SetReadLimit(s.wsMaxMessageSize)
+	conn.SetPongHandler(func(string) error {
+		c.extendReadDeadline()
+		return nil
+	})
+
+	s.wsConnMux.Lock()
+	s.wsConnections[id] = c
+	s.wsConnMux.Unlock()
+
+	go c.listen()
+	go c.sender()
+	return c
+}
+
+func (s *rpcServer) wsConnectionClosed(c *wsConnection) {
+	s.wsConnMux.Lock()
+	defer s.wsConnMux.Unlock()
+	delete(s.wsConnections, c.id)
+}
+
+func (s *rpcServer) closeAllWSConnections() {
+	s.wsConnMux.Lock()
+	conns := make([]*wsConnection, 0, len(s.wsConnections))
+	for _, c := range s.wsConnections {
+		conns = append(conns, c)
+	}
+	s.wsConnMux.Unlock()
+	for _, c := range conns {
+		c.close()
+	}
+}
+
+func (c *wsConnection) close() {
+	c.closeMux.Lock()
+	if c.closed {
+		c.closeMux.Unlock()
+		return
+	}
+	c.closed = true
+	c.conn.Close()
+	close(c.closing)
+	c.cancelCtx()
+	c.closeMux.Unlock()
+
+	// Subscriptions are owned by the client connection, so are removed from the backend along with it
+	c.subsMux.Lock()
+	subs := c.subs
+	c.subs = make(map[string]rpcbackend.Subscription)
+	c.subsMux.Unlock()
+	for clientSubID, sub := range subs {
+		if rpcErr := sub.Unsubscribe(c.server.ctx); rpcErr != nil {
+			log.L(c.ctx).Warnf("Failed to unsubscribe %s on close: %s", clientSubID, rpcErr.Message)
+		}
+	}
+
+	c.server.wsConnectionClosed(c)
+	log.L(c.ctx).Infof("Disconnected")
+}
+
+// extendReadDeadline gives the client two ping intervals to send a message, or respond to a ping,
+// before the connection is considered dead
+func (c *wsConnection) extendReadDeadline() {
+	if c.server.wsPingInterval > 0 {
+		_ = c.conn.SetReadDeadline(time.Now().Add(2 * c.server.wsPingInterval))
+	}
+}
+
+func (c *wsConnection) sender() {
+	defer c.close()
+	var pings <-chan time.Time
+	if c.server.wsPingInterval > 0 {
+		ticker := time.NewTicker(c.server.wsPingInterval)
+		defer ticker.Stop()
+		pings = ticker.C
+	}
+	for {
+		select {
+		case payload := <-c.send:
+			if err := c.conn.WriteJSON(payload); err != nil {
+				log.L(c.ctx).Errorf("Send failed - closing connection: %s", err)
+				return
+			}
+		case <-pings:
+			if err := c.conn.WriteControl(ws.PingMessage, nil, time.Now().Add(c.server.wsPingInterval)); err != nil {
+				log.L(c.ctx).Errorf("Ping failed - closing connection: %s", err)
+				return
+			}
+		case <-c.closing:
+			return
+		}
+	}
+}
+
+func (c *wsConnection) sendPayload(payload interface{}) {
+	select {
+	case c.send <- payload:
+	case <-c.closing:
+		log.L(c.ctx).Warnf("Discarding message for closed connection")
+	}
+}
+
+func (c *wsConnection) listen() {
+	defer c.close()
+	log.L(c.ctx).Infof("Connected")
+	for {
+		c.extendReadDeadline()
+		_, b, err := c.conn.ReadMessage()
+		if err != nil {
+			log.L(c.ctx).Errorf("Error: %s", err)
+			return
+		}
+		// Requests are processed in parallel, with the client correlating the responses by ID
+		go c.handleMessage(b)
+	}
+}
+
+func (c *wsConnection) handleMessage(b []byte) {
+	debuglog.Logf(c.ctx, debuglog.RPCWire, "RPC --> %s", b)
+
+	if err := c.server.checkJSONDepth(c.ctx, b); err != nil {
+		c.sendPayload(c.server.rpcLimitErrorResponse(c.ctx, err))
+		return
+	}
+
+	if c.server.sniffFirstByte(b) == '[' {
+		var rpcArray []*rpcbackend.RPCRequest
+		err := json.Unmarshal(b, &rpcArray)
+		if err != nil || len(rpcArray) == 0 {
+			c.sendPayload(c.server.rpcParseErrorResponse(c.ctx, b))
+			return
+		}
+		if err := c.server.checkBatchSize(c.ctx, len(rpcArray)); err != nil {
+			c.sendPayload(c.server.rpcLimitErrorResponse(c.ctx, err))
+			return
+		}
+		rpcResponses, _ := c.server.processRPCBatch(c.ctx, rpcArray, c.processRPC)
+		c.sendPayload(rpcResponses)
+		return
+	}
+
+	var rpcRequest rpcbackend.RPCRequest
+	if err := json.Unmarshal(b, &rpcRequest); err != nil {
+		c.sendPayload(c.server.rpcParseErrorResponse(c.ctx, b))
+		return
+	}
+	rpcResponse, _ := c.processRPC(c.ctx, &rpcRequest)
+	c.sendPayload(rpcResponse)
+}
+
+func (c *wsConnection) processRPC(ctx context.Context, rpcReq *rpcbackend.RPCRequest) (*rpcbackend.RPCResponse, error) {
+	// Subscriptions are only supported on the backend, not on additional chains
+	if c.server.wsBackend != nil && rpcReq.ID != nil && getChainRoute(ctx) == nil {
+		switch rpcReq.Method {
+		case "eth_subscribe", "eth_unsubscribe":
+			return c.server.accessLogged(ctx, rpcReq, c.processSubscription)
+		}
+	}
+	return c.server.processRPC(ctx, rpcReq)
+}
+
+func (c *wsConnection) processSubscription(ctx context.Context, rpcReq *rpcbackend.RPCRequest) (*rpcbackend.RPCResponse, error) {
+	if errRes, err := c.server.admitRequest(ctx, rpcReq); err != nil {
+		return errRes, err
+	}
+	if rpcReq.Method == "eth_subscribe" {
+		return c.processSubscribe(ctx, rpcReq)
+	}
+	return c.processUnsubscribe(ctx, rpcReq)
+}
+
+// processSubscribe multiplexes the client subscription onto the backend WebSocket. The ID we give
+// the client is stable, even though the backend subscription ID changes on each reconnect.
+func (c *wsConnection) processSubscribe(ctx context.Context, rpcReq *rpcbackend.RPCRequest) (*rpcbackend.RPCResponse, error) {
+	params := make([]interface{}, len(rpcReq.Params))
+	for i, p := range rpcReq.Params {
+		params[i] = p
+	}
+	sub, rpcErr := c.server.wsBackend.Subscribe(c.ctx, params...)
+	if rpcErr != nil {
+		return &rpcbackend.RPCResponse{
+			JSONRpc: "2.0",
+			ID:      rpcReq.ID,
+			Error:   rpcErr,
+		}, rpcErr.Error()
+	}
+
+	clientSubID := "0x" + hex.EncodeToString(sub.LocalID()[:])
+	c.subsMux.Lock()
+	if c.ctx.Err() != nil {
+		// The connection closed while we were subscribing, so nobody else will clean this up
+		c.subsMux.Unlock()
+		_ = sub.Unsubscribe(c.server.ctx)
+		err := i18n.NewError(ctx, signermsgs.MsgRequestCanceledContext, rpcReq.ID.String())
+		return rpcbackend.RPCErrorResponse(err, rpcReq.ID, rpcbackend.RPCCodeInternalError), err
+	}
+	c.subs[clientSubID] = sub
+	c.subsMux.Unlock()
+	log.L(ctx).Infof("Client subscription %s created", clientSubID)
+
+	go c.forwardNotifications(clientSubID, sub)
+
+	b, _ := json.Marshal(clientSubID)
+	return &rpcbackend.RPCResponse{
+		JSONRpc: "2.0",
+		ID:      rpcReq.ID,
+		Result:  fftypes.JSONAnyPtrBytes(b),
+	}, nil
+}
+
+func (c *wsConnection) processUnsubscribe(ctx context.Context, rpcReq *rpcbackend.RPCRequest) (*rpcbackend.RPCResponse, error) {
+	if len(rpcReq.Params) < 1 {
+		err := i18n.NewError(ctx, signermsgs.MsgInvalidParamCount, 1, len(rpcReq.Params))
+		return rpcbackend.RPCErrorResponse(err, rpcReq.ID, rpcbackend.RPCCodeInvalidRequest), err
+	}
+	var clientSubID string
+	if errRes, err := c.server.unmarshalParam(ctx, rpcReq, 0, &clientSubID); err != nil {
+		return errRes, err
+	}
+
+	c.subsMux.Lock()
+	sub := c.subs[clientSubID]
+	delete(c.subs, clientSubID)
+	c.subsMux.Unlock()
+	if sub == nil {
+		return &rpcbackend.RPCResponse{
+			JSONRpc: "2.0",
+			ID:      rpcReq.ID,
+			Result:  fftypes.JSONAnyPtr("false"),
+		}, nil
+	}
+
+	if rpcErr := sub.Unsubscribe(ctx); rpcErr != nil {
+		return &rpcbackend.RPCResponse{
+			JSONRpc: "2.0",
+			ID:      rpcReq.ID,
+			Error:   rpcErr,
+		}, rpcErr.Error()
+	}
+	log.L(ctx).Infof("Client subscription %s removed", clientSubID)
+	return trueResult(rpcReq), nil
+}
+
+func (c *wsConnection) forwardNotifications(clientSubID string, sub rpcbackend.Subscription) {
+	for {
+		select {
+		case n, ok := <-sub.Notifications():
+			if !ok {
+				return // unsubscribed
+			}
+			c.sendPayload(&rpcSubscriptionNotification{
+				JSONRpc: "2.0",
+				Method:  "eth_subscription",
+				Params: rpcSubscriptionParams{
+					Subscription: clientSubID,
+					Result:       n.Result,
+				},
+			})
+		case serverSubID := <-sub.Resubscribed():
+			log.L(c.ctx).Infof("Client subscription %s re-established on backend (serverId=%s)", clientSubID, serverSubID)
+			c.sendPayload(&rpcSubscriptionNotification{
+				JSONRpc: "2.0",
+				Method:  resubscribedNotificationMethod,
+				Params: rpcSubscriptionParams{
+					Subscription: clientSubID,
+				},
+			})
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
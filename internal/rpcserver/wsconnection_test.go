@@ -0,0 +1,551 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/hyperledger/firefly-common/pkg/ffresty"
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-common/pkg/httpserver"
+	"github.com/hyperledger/firefly-common/pkg/wsclient"
+	"github.com/hyperledger/firefly-signer/internal/signerconfig"
+	"github.com/hyperledger/firefly-signer/mocks/ethsignermocks"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type testSub struct {
+	localID        *fftypes.UUID
+	notifications  chan *rpcbackend.RPCSubscriptionNotification
+	resubscribed   chan string
+	unsubscribeErr *rpcbackend.RPCError
+}
+
+func (ts *testSub) LocalID() *fftypes.UUID { return ts.localID }
+func (ts *testSub) Notifications() chan *rpcbackend.RPCSubscriptionNotification {
+	return ts.notifications
+}
+func (ts *testSub) Resubscribed() chan string { return ts.resubscribed }
+func (ts *testSub) Unsubscribe(ctx context.Context) *rpcbackend.RPCError {
+	return ts.unsubscribeErr
+}
+
+type closedAfterSubscribeCtx struct {
+	context.Context
+}
+
+func (ctx *closedAfterSubscribeCtx) Err() error { return context.Canceled }
+
+// newTestWSBackendServer starts a server with a WebSocket backend, driven by the
+// returned toServer/fromServer channels, and connects a WebSocket client to it
+func newTestWSBackendServer(t *testing.T) (*rpcServer, *websocket.Conn, chan string, chan string, func()) {
+	signerconfig.Reset()
+
+	toServer, fromServer, url, closeWS := wsclient.NewTestWSServer(nil)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	serverPort := strings.Split(ln.Addr().String(), ":")[1]
+	ln.Close()
+	signerconfig.ServerConfig.Set(httpserver.HTTPConfPort, serverPort)
+	signerconfig.ServerConfig.Set(httpserver.HTTPConfAddress, "127.0.0.1")
+	signerconfig.BackendConfig.Set(ffresty.HTTPConfigURL, url)
+	config.Set(signerconfig.BackendChainID, 12345)
+	config.Set(signerconfig.BackendChainIDValidationEnabled, false)
+
+	w := &ethsignermocks.Wallet{}
+	w.On("Initialize", mock.Anything).Return(nil)
+	ss, err := NewServer(context.Background(), w)
+	assert.NoError(t, err)
+	s := ss.(*rpcServer)
+	err = s.Start()
+	assert.NoError(t, err)
+
+	conn, _, err := websocket.DefaultDialer.Dial(fmt.Sprintf("ws://127.0.0.1:%s/", serverPort), nil)
+	assert.NoError(t, err)
+
+	return s, conn, toServer, fromServer, func() {
+		conn.Close()
+		s.Stop()
+		_ = s.WaitStop()
+		closeWS()
+	}
+}
+
+func startTestServerNoBackend(t *testing.T, s *rpcServer) {
+	s.chainID = 12345
+	s.chainIDValidation = false
+	s.wallet.(*ethsignermocks.Wallet).On("Initialize", mock.Anything).Return(nil)
+	err := s.Start()
+	assert.NoError(t, err)
+}
+
+// waitServerWSConnection returns the server side of our client connection
+func waitServerWSConnection(s *rpcServer) (c *wsConnection) {
+	for c == nil {
+		s.wsConnMux.Lock()
+		for _, c = range s.wsConnections {
+		}
+		s.wsConnMux.Unlock()
+		time.Sleep(1 * time.Millisecond)
+	}
+	return c
+}
+
+func wsRoundTrip(t *testing.T, conn *websocket.Conn, req string) map[string]interface{} {
+	err := conn.WriteMessage(websocket.TextMessage, []byte(req))
+	assert.NoError(t, err)
+	return wsReadJSON(t, conn)
+}
+
+func wsReadJSON(t *testing.T, conn *websocket.Conn) map[string]interface{} {
+	var res map[string]interface{}
+	err := conn.ReadJSON(&res)
+	assert.NoError(t, err)
+	return res
+}
+
+func TestWSSubscribeNotifyUnsubscribe(t *testing.T) {
+
+	s, conn, toServer, fromServer, done := newTestWSBackendServer(t)
+	defer done()
+
+	go func() {
+		msg := <-toServer
+		assert.JSONEq(t, `{"jsonrpc":"2.0","id":"000000001","method":"eth_subscribe","params":["newHeads"]}`, msg)
+		fromServer <- `{"jsonrpc":"2.0","id":"000000001","result":"0x9ce59a13059e417087c02d3236a0b1cc"}`
+	}()
+	res := wsRoundTrip(t, conn, `{"jsonrpc":"2.0","id":1,"method":"eth_subscribe","params":["newHeads"]}`)
+	assert.Equal(t, float64(1), res["id"])
+	clientSubID := res["result"].(string)
+	assert.Regexp(t, "^0x[0-9a-f]{32}$", clientSubID)
+	assert.NotEqual(t, "0x9ce59a13059e417087c02d3236a0b1cc", clientSubID)
+
+	fromServer <- `{"jsonrpc":"2.0","method":"eth_subscription","params":{"subscription":"0x9ce59a13059e417087c02d3236a0b1cc","result":{"number":"0x1348c9"}}}`
+	notification := wsReadJSON(t, conn)
+	assert.Equal(t, "eth_subscription", notification["method"])
+	assert.Nil(t, notification["id"])
+	params := notification["params"].(map[string]interface{})
+	assert.Equal(t, clientSubID, params["subscription"])
+	assert.Equal(t, "0x1348c9", params["result"].(map[string]interface{})["number"])
+
+	go func() {
+		msg := <-toServer
+		assert.JSONEq(t, `{"jsonrpc":"2.0","id":"000000002","method":"eth_unsubscribe","params":["0x9ce59a13059e417087c02d3236a0b1cc"]}`, msg)
+		fromServer <- `{"jsonrpc":"2.0","id":"000000002","result":true}`
+	}()
+	res = wsRoundTrip(t, conn, fmt.Sprintf(`{"jsonrpc":"2.0","id":2,"method":"eth_unsubscribe","params":["%s"]}`, clientSubID))
+	assert.Equal(t, true, res["result"])
+	assert.Empty(t, s.wsBackend.Subscriptions())
+
+	// A second unsubscribe does not find it
+	res = wsRoundTrip(t, conn, fmt.Sprintf(`{"jsonrpc":"2.0","id":3,"method":"eth_unsubscribe","params":["%s"]}`, clientSubID))
+	assert.Equal(t, false, res["result"])
+
+}
+
+func TestWSSubscribeCleanedUpOnClose(t *testing.T) {
+
+	s, conn, toServer, fromServer, done := newTestWSBackendServer(t)
+	defer done()
+
+	go func() {
+		<-toServer
+		fromServer <- `{"jsonrpc":"2.0","id":"000000001","result":"0x9ce59a13059e417087c02d3236a0b1cc"}`
+	}()
+	res := wsRoundTrip(t, conn, `{"jsonrpc":"2.0","id":1,"method":"eth_subscribe","params":["newHeads"]}`)
+	assert.NotEmpty(t, res["result"])
+	assert.Len(t, s.wsBackend.Subscriptions(), 1)
+
+	unsubscribed := make(chan struct{})
+	go func() {
+		msg := <-toServer
+		assert.JSONEq(t, `{"jsonrpc":"2.0","id":"000000002","method":"eth_unsubscribe","params":["0x9ce59a13059e417087c02d3236a0b1cc"]}`, msg)
+		fromServer <- `{"jsonrpc":"2.0","id":"000000002","result":true}`
+		close(unsubscribed)
+	}()
+	conn.Close()
+	<-unsubscribed
+
+}
+
+func TestWSSubscribeBackendError(t *testing.T) {
+
+	_, conn, toServer, fromServer, done := newTestWSBackendServer(t)
+	defer done()
+
+	go func() {
+		<-toServer
+		fromServer <- `{"jsonrpc":"2.0","id":"000000001","error":{"code":-32000,"message":"pop"}}`
+	}()
+	res := wsRoundTrip(t, conn, `{"jsonrpc":"2.0","id":1,"method":"eth_subscribe","params":["unknown"]}`)
+	assert.Equal(t, "pop", res["error"].(map[string]interface{})["message"])
+
+}
+
+func TestWSUnsubscribeBadParams(t *testing.T) {
+
+	_, conn, _, _, done := newTestWSBackendServer(t)
+	defer done()
+
+	res := wsRoundTrip(t, conn, `{"jsonrpc":"2.0","id":1,"method":"eth_unsubscribe"}`)
+	assert.Regexp(t, "FF22019", res["error"].(map[string]interface{})["message"])
+
+	res = wsRoundTrip(t, conn, `{"jsonrpc":"2.0","id":1,"method":"eth_unsubscribe","params":[false]}`)
+	assert.Regexp(t, "FF22011", res["error"].(map[string]interface{})["message"])
+
+}
+
+func TestWSPassthroughAndBatch(t *testing.T) {
+
+	_, conn, toServer, fromServer, done := newTestWSBackendServer(t)
+	defer done()
+
+	go func() {
+		msg := <-toServer
+		assert.JSONEq(t, `{"jsonrpc":"2.0","id":"000000001","method":"eth_blockNumber"}`, msg)
+		fromServer <- `{"jsonrpc":"2.0","id":"000000001","result":"0x12345"}`
+	}()
+	res := wsRoundTrip(t, conn, `{"jsonrpc":"2.0","id":"abc","method":"eth_blockNumber"}`)
+	assert.Equal(t, "abc", res["id"])
+	assert.Equal(t, "0x12345", res["result"])
+
+	err := conn.WriteMessage(websocket.TextMessage, []byte(`[{"jsonrpc":"2.0","id":1,"method":"eth_unsubscribe","params":["0x12345"]}]`))
+	assert.NoError(t, err)
+	var batchRes []*rpcbackend.RPCResponse
+	err = conn.ReadJSON(&batchRes)
+	assert.NoError(t, err)
+	assert.Len(t, batchRes, 1)
+	assert.Equal(t, "false", batchRes[0].Result.String())
+
+}
+
+func TestWSParseErrors(t *testing.T) {
+
+	_, conn, _, _, done := newTestWSBackendServer(t)
+	defer done()
+
+	res := wsRoundTrip(t, conn, `!!! not JSON`)
+	assert.Regexp(t, "FF22018", res["error"].(map[string]interface{})["message"])
+
+	res = wsRoundTrip(t, conn, `[]`)
+	assert.Regexp(t, "FF22018", res["error"].(map[string]interface{})["message"])
+
+}
+
+func TestWSSubscribeNoWebSocketBackend(t *testing.T) {
+
+	url, s, done := newTestServer(t)
+	defer done()
+	startTestServerNoBackend(t, s)
+
+	conn, _, err := websocket.DefaultDialer.Dial(strings.Replace(url, "http://", "ws://", 1), nil)
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	res := wsRoundTrip(t, conn, `{"jsonrpc":"2.0","id":1,"method":"eth_subscribe","params":["newHeads"]}`)
+	assert.Regexp(t, "FF22097", res["error"].(map[string]interface{})["message"])
+
+}
+
+func TestWSUpgradeFail(t *testing.T) {
+
+	url, s, done := newTestServer(t)
+	defer done()
+	startTestServerNoBackend(t, s)
+
+	res, err := http.Get(url)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+}
+
+func TestWSForwardResubscribed(t *testing.T) {
+
+	s, conn, _, _, done := newTestWSBackendServer(t)
+	defer done()
+
+	c := waitServerWSConnection(s)
+
+	sub := &testSub{
+		localID:       fftypes.NewUUID(),
+		notifications: make(chan *rpcbackend.RPCSubscriptionNotification),
+		resubscribed:  make(chan string, 1),
+	}
+	go c.forwardNotifications("0x12345", sub)
+
+	sub.resubscribed <- "0x67890"
+	notification := wsReadJSON(t, conn)
+	assert.Equal(t, "ffsigner_resubscribed", notification["method"])
+	assert.Equal(t, map[string]interface{}{"subscription": "0x12345"}, notification["params"])
+
+	close(sub.notifications)
+
+}
+
+func TestWSSubscribeAfterClose(t *testing.T) {
+
+	s, _, toServer, fromServer, done := newTestWSBackendServer(t)
+	defer done()
+
+	// The connection appears closed as soon as the subscribe returns
+	c := &wsConnection{
+		ctx:     &closedAfterSubscribeCtx{Context: context.Background()},
+		server:  s,
+		closing: make(chan struct{}),
+		subs:    make(map[string]rpcbackend.Subscription),
+	}
+
+	go func() {
+		<-toServer
+		fromServer <- `{"jsonrpc":"2.0","id":"000000001","result":"0x9ce59a13059e417087c02d3236a0b1cc"}`
+		msg := <-toServer
+		assert.JSONEq(t, `{"jsonrpc":"2.0","id":"000000002","method":"eth_unsubscribe","params":["0x9ce59a13059e417087c02d3236a0b1cc"]}`, msg)
+		fromServer <- `{"jsonrpc":"2.0","id":"000000002","result":true}`
+	}()
+	_, err := c.processSubscribe(s.ctx, &rpcbackend.RPCRequest{
+		ID:     fftypes.JSONAnyPtr("1"),
+		Method: "eth_subscribe",
+		Params: []*fftypes.JSONAny{fftypes.JSONAnyPtr(`"newHeads"`)},
+	})
+	assert.Regexp(t, "FF22063", err)
+	assert.Empty(t, c.subs)
+
+}
+
+func TestWSUnsubscribeFail(t *testing.T) {
+
+	s, _, _, _, done := newTestWSBackendServer(t)
+	defer done()
+
+	c := waitServerWSConnection(s)
+	c.subs["0x12345"] = &testSub{
+		unsubscribeErr: &rpcbackend.RPCError{Message: "pop"},
+	}
+	_, err := c.processUnsubscribe(s.ctx, &rpcbackend.RPCRequest{
+		ID:     fftypes.JSONAnyPtr("1"),
+		Method: "eth_unsubscribe",
+		Params: []*fftypes.JSONAny{fftypes.JSONAnyPtr(`"0x12345"`)},
+	})
+	assert.Regexp(t, "pop", err)
+
+}
+
+func TestWSSendFailClosesConnection(t *testing.T) {
+
+	s, conn, _, _, done := newTestWSBackendServer(t)
+	defer done()
+
+	// Drive the sender with our client connection, after closing the underlying socket
+	c := &wsConnection{
+		server:  s,
+		conn:    conn,
+		send:    make(chan interface{}),
+		closing: make(chan struct{}),
+		subs:    make(map[string]rpcbackend.Subscription),
+	}
+	c.ctx, c.cancelCtx = context.WithCancel(s.ctx)
+	conn.NetConn().Close()
+	go c.sender()
+	c.sendPayload(map[string]interface{}{})
+	<-c.ctx.Done()
+
+}
+
+func TestWSSendClosed(t *testing.T) {
+
+	c := &wsConnection{
+		ctx:     context.Background(),
+		closing: make(chan struct{}),
+	}
+	close(c.closing)
+	c.sendPayload(map[string]interface{}{})
+
+}
+
+func TestWSSubscriptionNotificationJSON(t *testing.T) {
+
+	b, err := json.Marshal(&rpcSubscriptionNotification{
+		JSONRpc: "2.0",
+		Method:  "eth_subscription",
+		Params: rpcSubscriptionParams{
+			Subscription: "0x12345",
+			Result:       fftypes.JSONAnyPtr(`{}`),
+		},
+	})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"jsonrpc":"2.0","method":"eth_subscription","params":{"subscription":"0x12345","result":{}}}`, string(b))
+
+}
+
+func TestWSCheckOrigin(t *testing.T) {
+
+	checkOrigin := func(origin string) bool {
+		req, err := http.NewRequest(http.MethodGet, "http://localhost:8545/", nil)
+		assert.NoError(t, err)
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		return newWSUpgrader().CheckOrigin(req)
+	}
+
+	// CORS allows all origins by default
+	signerconfig.Reset()
+	assert.True(t, checkOrigin("https://dapp.example.com"))
+
+	signerconfig.CorsConfig.Set(httpserver.CorsAllowedOrigins, []string{"https://*.example.com"})
+	assert.True(t, checkOrigin(""))
+	assert.True(t, checkOrigin("http://localhost:8545"))
+	assert.True(t, checkOrigin("https://dapp.example.com"))
+	assert.False(t, checkOrigin("https://dapp.example.org"))
+
+	signerconfig.CorsConfig.Set(httpserver.CorsEnabled, false)
+	assert.True(t, checkOrigin(""))
+	assert.True(t, checkOrigin("http://localhost:8545"))
+	assert.False(t, checkOrigin("https://dapp.example.com"))
+	assert.False(t, checkOrigin("::invalid"))
+
+}
+
+func TestWSCrossOriginRejected(t *testing.T) {
+
+	url, s, done := newTestServer(t, func() {
+		signerconfig.CorsConfig.Set(httpserver.CorsAllowedOrigins, []string{"https://dapp.example.com"})
+	})
+	defer done()
+	startTestServerNoBackend(t, s)
+	wsURL := strings.Replace(url, "http://", "ws://", 1)
+
+	_, res, err := websocket.DefaultDialer.Dial(wsURL, http.Header{"Origin": []string{"https://dapp.example.org"}})
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusForbidden, res.StatusCode)
+
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, http.Header{"Origin": []string{"https://dapp.example.com"}})
+	assert.NoError(t, err)
+	conn.Close()
+
+}
+
+func TestWSPingKeepsAlive(t *testing.T) {
+
+	url, s, done := newTestServer(t, func() {
+		signerconfig.ServerConfig.Set(signerconfig.ServerConfWSPingInterval, "10ms")
+	})
+	defer done()
+	startTestServerNoBackend(t, s)
+
+	conn, _, err := websocket.DefaultDialer.Dial(strings.Replace(url, "http://", "ws://", 1), nil)
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	// Our client responds to pings while it is reading, which keeps the connection alive
+	pings := make(chan struct{}, 10)
+	conn.SetPingHandler(func(data string) error {
+		select {
+		case pings <- struct{}{}:
+		default:
+		}
+		return conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(time.Second))
+	})
+	go func() {
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+	for i := 0; i < 5; i++ {
+		<-pings
+	}
+	s.wsConnMux.Lock()
+	assert.Len(t, s.wsConnections, 1)
+	s.wsConnMux.Unlock()
+
+}
+
+func TestWSDeadClientDisconnected(t *testing.T) {
+
+	url, s, done := newTestServer(t, func() {
+		signerconfig.ServerConfig.Set(signerconfig.ServerConfWSPingInterval, "10ms")
+	})
+	defer done()
+	startTestServerNoBackend(t, s)
+
+	// Our client never reads, so never responds to the pings
+	conn, _, err := websocket.DefaultDialer.Dial(strings.Replace(url, "http://", "ws://", 1), nil)
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	c := waitServerWSConnection(s)
+	<-c.ctx.Done()
+
+}
+
+func TestWSMaxMessageSize(t *testing.T) {
+
+	url, s, done := newTestServer(t, func() {
+		signerconfig.ServerConfig.Set(signerconfig.ServerConfWSMaxMessageSize, "64b")
+	})
+	defer done()
+	startTestServerNoBackend(t, s)
+
+	conn, _, err := websocket.DefaultDialer.Dial(strings.Replace(url, "http://", "ws://", 1), nil)
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	err = conn.WriteMessage(websocket.TextMessage, []byte(`{"jsonrpc":"2.0","id":1,"method":"eth_accounts","params":["`+strings.Repeat("a", 64)+`"]}`))
+	assert.NoError(t, err)
+	_, _, err = conn.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseMessageTooBig))
+
+}
+
+func TestWSPingFailClosesConnection(t *testing.T) {
+
+	url, s, done := newTestServer(t, func() {
+		signerconfig.ServerConfig.Set(signerconfig.ServerConfWSPingInterval, "1ms")
+	})
+	defer done()
+	startTestServerNoBackend(t, s)
+	conn, _, err := websocket.DefaultDialer.Dial(strings.Replace(url, "http://", "ws://", 1), nil)
+	assert.NoError(t, err)
+
+	// Drive the sender with our client connection, after closing the underlying socket
+	c := &wsConnection{
+		server:  s,
+		conn:    conn,
+		send:    make(chan interface{}),
+		closing: make(chan struct{}),
+		subs:    make(map[string]rpcbackend.Subscription),
+	}
+	c.ctx, c.cancelCtx = context.WithCancel(s.ctx)
+	conn.NetConn().Close()
+	go c.sender()
+	<-c.ctx.Done()
+
+}
@@ -18,21 +18,259 @@ package signerconfig
 
 import (
 	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/hyperledger/firefly-common/pkg/ffresty"
 	"github.com/hyperledger/firefly-common/pkg/httpserver"
 	"github.com/hyperledger/firefly-common/pkg/wsclient"
+	"github.com/hyperledger/firefly-signer/pkg/fips"
 	"github.com/hyperledger/firefly-signer/pkg/fswallet"
+	"github.com/hyperledger/firefly-signer/pkg/nonces"
+	"github.com/hyperledger/firefly-signer/pkg/objectstore"
+	"github.com/hyperledger/firefly-signer/pkg/rpcauth"
 	"github.com/spf13/viper"
 )
 
 var ffc = config.AddRootKey
 
 var (
-	// BackendChainID optionally set the Chain ID manually (usually queries network ID)
+	// BackendChainID optionally set the Chain ID manually (otherwise queried with eth_chainId)
 	BackendChainID = ffc("backend.chainId")
+	// BackendChainIDValidationEnabled checks a configured Chain ID matches the backend, at startup and periodically
+	BackendChainIDValidationEnabled = ffc("backend.chainIdValidation.enabled")
+	// BackendChainIDValidationInterval how often the Chain ID of the backend is revalidated after startup
+	BackendChainIDValidationInterval = ffc("backend.chainIdValidation.interval")
+	// BackendBatchEnabled coalesces concurrent requests to an HTTP backend into JSON/RPC batches
+	BackendBatchEnabled = ffc("backend.batch.enabled")
+	// BackendBatchSize the maximum number of requests in a batch
+	BackendBatchSize = ffc("backend.batch.size")
+	// BackendBatchTimeout the maximum time to wait for a batch to fill before dispatching it
+	BackendBatchTimeout = ffc("backend.batch.timeout")
+	// BackendBatchDispatchConcurrency the maximum number of batches in-flight to the backend
+	BackendBatchDispatchConcurrency = ffc("backend.batch.dispatchConcurrency")
+	// BackendTLSServerName overrides the server name used for SNI and certificate verification of the backend
+	BackendTLSServerName = ffc("backend.tls.serverName")
+	// BackendRetryIdempotentMethods the JSON/RPC methods that are safe to retry on any failure, when backend.retry is enabled
+	BackendRetryIdempotentMethods = ffc("backend.retry.idempotentMethods")
+	// BackendFailoverURLs additional backend URLs to fail over to, in priority order
+	BackendFailoverURLs = ffc("backend.failover.urls")
+	// BackendFailoverHealthCheckInterval how often each backend is health checked
+	BackendFailoverHealthCheckInterval = ffc("backend.failover.healthCheck.interval")
+	// BackendFailoverHealthCheckTimeout the maximum time for a backend to respond to a health check
+	BackendFailoverHealthCheckTimeout = ffc("backend.failover.healthCheck.timeout")
+	// BackendFailoverHealthCheckMethod the JSON/RPC method used for health checks
+	BackendFailoverHealthCheckMethod = ffc("backend.failover.healthCheck.method")
+	// BackendFailoverStickySubscriptions keeps subscriptions on a healthy backend, rather than moving them to a recovered higher priority one
+	BackendFailoverStickySubscriptions = ffc("backend.failover.stickySubscriptions")
+	// BackendCircuitBreakerEnabled fails requests fast while a backend is failing, rather than waiting on it
+	BackendCircuitBreakerEnabled = ffc("backend.circuitBreaker.enabled")
+	// BackendCircuitBreakerFailureRateThreshold the fraction of requests in a window that must fail for the circuit to open
+	BackendCircuitBreakerFailureRateThreshold = ffc("backend.circuitBreaker.failureRateThreshold")
+	// BackendCircuitBreakerMinimumRequests the number of requests in a window before the circuit can open
+	BackendCircuitBreakerMinimumRequests = ffc("backend.circuitBreaker.minimumRequests")
+	// BackendCircuitBreakerWindow the period over which failures are counted
+	BackendCircuitBreakerWindow = ffc("backend.circuitBreaker.window")
+	// BackendCircuitBreakerSlowCallThreshold requests taking at least this long count as failures
+	BackendCircuitBreakerSlowCallThreshold = ffc("backend.circuitBreaker.slowCallThreshold")
+	// BackendCircuitBreakerOpenDuration how long the circuit stays open before probing the backend
+	BackendCircuitBreakerOpenDuration = ffc("backend.circuitBreaker.openDuration")
+	// BackendCircuitBreakerHalfOpenProbes the number of probe requests that must succeed to close the circuit
+	BackendCircuitBreakerHalfOpenProbes = ffc("backend.circuitBreaker.halfOpenProbes")
+	// LogModules the modules with detailed debug logging enabled regardless of the log level (see pkg/debuglog)
+	LogModules = ffc("log.modules")
+	// TracingEnabled enables OpenTelemetry tracing, with spans exported over OTLP/HTTP
+	TracingEnabled = ffc("tracing.enabled")
+	// TracingEndpoint the OTLP/HTTP endpoint URL to export spans to
+	TracingEndpoint = ffc("tracing.endpoint")
+	// TracingServiceName the service name recorded on exported spans
+	TracingServiceName = ffc("tracing.serviceName")
+	// TracingSampleRatio the fraction of new traces to sample
+	TracingSampleRatio = ffc("tracing.sampleRatio")
+	// RateLimitEnabled enables per-client rate limiting, keyed by the authenticated identity or source IP
+	RateLimitEnabled = ffc("rateLimit.enabled")
+	// RateLimitRequestsPerSecond the sustained rate of JSON/RPC requests allowed per client (0 for unlimited)
+	RateLimitRequestsPerSecond = ffc("rateLimit.requestsPerSecond")
+	// RateLimitRequestsBurst the number of JSON/RPC requests a client can make in a burst above the sustained rate
+	RateLimitRequestsBurst = ffc("rateLimit.requestsBurst")
+	// RateLimitSigningPerSecond the sustained rate of signing operations allowed per client (0 for unlimited)
+	RateLimitSigningPerSecond = ffc("rateLimit.signingPerSecond")
+	// RateLimitSigningBurst the number of signing operations a client can make in a burst above the sustained rate
+	RateLimitSigningBurst = ffc("rateLimit.signingBurst")
+	// RateLimitIdleTimeout how long the rate limit state of an idle client is retained
+	RateLimitIdleTimeout = ffc("rateLimit.idleTimeout")
+	// PersonalSignEnabled whether personal_sign requests are signed by the wallet
+	PersonalSignEnabled = ffc("personalSign.enabled")
+	// DangerousMethodsEthSign whether eth_sign requests are signed by the wallet, as a raw digest
+	DangerousMethodsEthSign = ffc("dangerousMethods.ethSign")
+	// PreflightEnabled simulates each eth_sendTransaction with eth_call before signing, rejecting it if it would revert
+	PreflightEnabled = ffc("preflight.enabled")
+	// PreflightBlockTag the block the pre-flight eth_call is made against
+	PreflightBlockTag = ffc("preflight.blockTag")
+	// GasEstimateEnabled populates the gas limit of each eth_sendTransaction without one, using eth_estimateGas
+	GasEstimateEnabled = ffc("gasEstimate.enabled")
+	// GasEstimateMultiplier the safety margin applied to the estimated gas
+	GasEstimateMultiplier = ffc("gasEstimate.multiplier")
+	// GasEstimateCap the maximum gas limit that is populated from an estimate (0 for no cap)
+	GasEstimateCap = ffc("gasEstimate.cap")
+	// FeesEnabled populates the fees of each eth_sendTransaction without them, from the recent fees of the chain
+	FeesEnabled = ffc("fees.enabled")
+	// FeesStrategy how the priority fee is chosen - "feeHistory" or "maxPriorityFeePerGas"
+	FeesStrategy = ffc("fees.strategy")
+	// FeesHistoryBlocks the number of recent blocks the priority fee is chosen from, with the feeHistory strategy
+	FeesHistoryBlocks = ffc("fees.feeHistory.blocks")
+	// FeesHistoryPercentile the percentile of the priority fees in each block that is used, with the feeHistory strategy
+	FeesHistoryPercentile = ffc("fees.feeHistory.percentile")
+	// FeesBaseFeeMultiplier the multiple of the base fee of the next block that the max fee allows for
+	FeesBaseFeeMultiplier = ffc("fees.baseFeeMultiplier")
+	// FeesOracleMode how the fees of the gas price sources are combined - "fallback" or "weighted"
+	FeesOracleMode = ffc("fees.oracleMode")
+	// FeeCapsMaxGasPrice the maximum legacy gasPrice of a transaction, in wei
+	FeeCapsMaxGasPrice = ffc("feeCaps.maxGasPrice")
+	// FeeCapsMaxFeePerGas the maximum EIP-1559 maxFeePerGas of a transaction, in wei
+	FeeCapsMaxFeePerGas = ffc("feeCaps.maxFeePerGas")
+	// FeeCapsMaxTotalFee the maximum total fee of a transaction (gas limit multiplied by the price per gas), in wei
+	FeeCapsMaxTotalFee = ffc("feeCaps.maxTotalFee")
+	// FeeCapsPolicy whether a transaction over a cap is rejected, or clamped to the cap - "reject" or "clamp"
+	FeeCapsPolicy = ffc("feeCaps.policy")
+	// TxPolicyAllowedDestinations the only addresses transactions may be sent to, when set
+	TxPolicyAllowedDestinations = ffc("txPolicy.allowedDestinations")
+	// TxPolicyAllowDeployments whether transactions with no destination, which deploy contracts, are allowed
+	TxPolicyAllowDeployments = ffc("txPolicy.allowDeployments")
+	// TxPolicyMaxValue the maximum value of a transaction, in wei
+	TxPolicyMaxValue = ffc("txPolicy.maxValue")
+	// TxPolicyRawTransactionsEnabled decodes each eth_sendRawTransaction, and applies the transaction policies before forwarding it
+	TxPolicyRawTransactionsEnabled = ffc("txPolicy.rawTransactions.enabled")
+	// TxPolicyRawTransactionsManagedSendersOnly rejects raw transactions that are not signed by a key in the wallet
+	TxPolicyRawTransactionsManagedSendersOnly = ffc("txPolicy.rawTransactions.managedSendersOnly")
+	// TxValidationEnabled checks each eth_sendTransaction against a schema before it is parsed
+	TxValidationEnabled = ffc("txValidation.enabled")
+	// TxValidationAllowUnknownFields allows fields in transactions that the signer does not use
+	TxValidationAllowUnknownFields = ffc("txValidation.allowUnknownFields")
+	// ENSEnabled resolves ENS names in the to address of transactions
+	ENSEnabled = ffc("ens.enabled")
+	// ENSRegistry the address of the ENS registry names are resolved with
+	ENSRegistry = ffc("ens.registry")
+	// ENSCCIPReadEnabled follows the offchain lookups (EIP-3668) of resolvers that answer from a gateway
+	ENSCCIPReadEnabled = ffc("ens.ccipRead.enabled")
+	// ENSCCIPReadAllowedHosts the hosts of the gateways offchain lookups can fetch from
+	ENSCCIPReadAllowedHosts = ffc("ens.ccipRead.allowedHosts")
+	// AccessLogEnabled writes a structured JSON access log entry for every JSON/RPC request
+	AccessLogEnabled = ffc("accessLog.enabled")
+	// AccessLogVerbosity what is included in each access log entry - "summary" or "full" (with the redacted params and result)
+	AccessLogVerbosity = ffc("accessLog.verbosity")
+	// AccessLogCorrelationHeader the HTTP header carrying the correlation ID of a request, which is echoed back to the client
+	AccessLogCorrelationHeader = ffc("accessLog.correlationHeader")
+	// ResponseCacheEnabled caches the results of read methods that cannot change, for a short time
+	ResponseCacheEnabled = ffc("responseCache.enabled")
+	// ResponseCacheTTL how long a result is cached for
+	ResponseCacheTTL = ffc("responseCache.ttl")
+	// ResponseCacheMaxEntries the maximum number of results cached at once
+	ResponseCacheMaxEntries = ffc("responseCache.maxEntries")
+	// ResponseCacheReceiptConfirmations how many blocks deep a transaction must be before its receipt is cached
+	ResponseCacheReceiptConfirmations = ffc("responseCache.receiptConfirmations")
+	// SenderQueueEnabled serializes the signing and submission of transactions from each address
+	SenderQueueEnabled = ffc("senderQueue.enabled")
+	// SenderQueueMaxPending the most transactions from one address waiting for their turn, before further ones are rejected
+	SenderQueueMaxPending = ffc("senderQueue.maxPending")
+	// SigningApprovalTimeout how long a signing request deferred by the approval hook waits for its decision
+	SigningApprovalTimeout = ffc("signingApproval.timeout")
+	// PersistencePath the directory state that survives restarts is persisted in, when the component has no path of its own
+	PersistencePath = ffc("persistence.path")
+	// ResubmitEnabled resubmits transactions we signed that are not mined after a delay
+	ResubmitEnabled = ffc("resubmit.enabled")
+	// ResubmitPolicy how a stuck transaction is resubmitted - "rebroadcast" or "feeBump"
+	ResubmitPolicy = ffc("resubmit.policy")
+	// ResubmitDelay how long a transaction can go without being mined before it is resubmitted
+	ResubmitDelay = ffc("resubmit.delay")
+	// ResubmitInterval how often submitted transactions are checked
+	ResubmitInterval = ffc("resubmit.interval")
+	// ResubmitFeeBumpPercent the percentage the fees of a transaction are increased by on each fee bump
+	ResubmitFeeBumpPercent = ffc("resubmit.feeBumpPercent")
+	// ResubmitMaxAttempts the most times a transaction is resubmitted, before it is no longer tracked
+	ResubmitMaxAttempts = ffc("resubmit.maxAttempts")
+	// ResubmitMaxTracked the most transactions tracked at once
+	ResubmitMaxTracked = ffc("resubmit.maxTracked")
 	// FileWalletEnabled if the Keystore V3 wallet is enabled
 	FileWalletEnabled = ffc("fileWallet.enabled")
 )
 
+const (
+	// ServerConfTCPEnabled whether the JSON/RPC server listens on TCP, which can be disabled when it is only served on a UNIX domain socket
+	ServerConfTCPEnabled = "tcpEnabled"
+	// ServerConfUnixSocketPath the path of a UNIX domain socket the JSON/RPC server also listens on
+	ServerConfUnixSocketPath = "unixSocket.path"
+	// ServerConfUnixSocketMode the file permissions of the UNIX domain socket, in octal
+	ServerConfUnixSocketMode = "unixSocket.mode"
+	// ServerConfWSPingInterval how often WebSocket clients are pinged, to detect and close dead connections
+	ServerConfWSPingInterval = "ws.pingInterval"
+	// ServerConfWSMaxMessageSize the largest message accepted from a WebSocket client
+	ServerConfWSMaxMessageSize = "ws.maxMessageSize"
+	// ServerConfMaxRequestSize the largest HTTP request body accepted by the JSON/RPC server
+	ServerConfMaxRequestSize = "maxRequestSize"
+	// ServerConfMaxBatchSize the most requests accepted in a single JSON/RPC batch (0 for no limit)
+	ServerConfMaxBatchSize = "maxBatchSize"
+	// ServerConfMaxJSONDepth the deepest nesting of JSON objects and arrays accepted in a request (0 for no limit)
+	ServerConfMaxJSONDepth = "maxJSONDepth"
+	// TimeoutsConfDefault the timeout of JSON/RPC methods with no override (0 for no timeout)
+	TimeoutsConfDefault = "default"
+	// TimeoutsConfOverrides the array of per-method timeout overrides
+	TimeoutsConfOverrides = "overrides"
+	// TimeoutsConfMethods the JSON/RPC method patterns a timeout override applies to
+	TimeoutsConfMethods = "methods"
+	// TimeoutsConfTimeout the timeout of the methods matching an override
+	TimeoutsConfTimeout = "timeout"
+	// ChainsConfHeader the HTTP header a request selects the chain it is routed to with
+	ChainsConfHeader = "header"
+	// ChainsConfNetworks the array of additional chains, each with its own backend
+	ChainsConfNetworks = "networks"
+	// ChainsConfName the name of a chain, which selects it in the header or URL path
+	ChainsConfName = "name"
+	// ChainsConfChainID the chain ID transactions routed to a chain are signed for
+	ChainsConfChainID = "chainId"
+	// ChainsConfURL the HTTP URL of the backend of a chain
+	ChainsConfURL = "url"
+	// FeeOraclesConfType the type of a gas price source - "node", "etherscan", "blocknative" or "gasStation"
+	FeeOraclesConfType = "type"
+	// FeeOraclesConfURL the URL of the API of a gas oracle
+	FeeOraclesConfURL = "url"
+	// FeeOraclesConfAPIKey the API key of a gas oracle
+	FeeOraclesConfAPIKey = "apiKey"
+	// FeeOraclesConfHeaders additional HTTP headers sent to a gas oracle
+	FeeOraclesConfHeaders = "headers"
+	// FeeOraclesConfRequestTimeout the timeout of requests to a gas oracle
+	FeeOraclesConfRequestTimeout = "requestTimeout"
+	// FeeOraclesConfSpeed the tier of fees used from a gas oracle - "safe", "standard" or "fast"
+	FeeOraclesConfSpeed = "speed"
+	// FeeOraclesConfWeight the weight of a gas price source, in the weighted oracle mode
+	FeeOraclesConfWeight = "weight"
+	// AuditWebhookConfEnabled whether audit events are posted to a webhook
+	AuditWebhookConfEnabled = "enabled"
+	// AuditWebhookConfSecret the secret audit events are signed with, using HMAC-SHA256
+	AuditWebhookConfSecret = "secret"
+	// AuditWebhookConfSignatureHeader the HTTP header carrying the signature of each audit event
+	AuditWebhookConfSignatureHeader = "signatureHeader"
+	// AuditWebhookConfQueueSize the most audit events queued for delivery, before further events are dropped
+	AuditWebhookConfQueueSize = "queueSize"
+	// MetricsConfEnabled whether the metrics server is enabled
+	MetricsConfEnabled = "enabled"
+	// MetricsConfPath the path on the metrics server where metrics are served
+	MetricsConfPath = "path"
+	// MetricsConfAddressesEnabled whether signing metrics are recorded per address
+	MetricsConfAddressesEnabled = "addresses.enabled"
+	// MetricsConfAddressesLabel how the address is labelled - "full", "truncated" or "hashed"
+	MetricsConfAddressesLabel = "addresses.label"
+	// MetricsConfAddressesLabelLength the number of hex characters in truncated and hashed labels
+	MetricsConfAddressesLabelLength = "addresses.labelLength"
+	// AdminConfEnabled whether the admin server is enabled
+	AdminConfEnabled = "enabled"
+	// AdminConfDebugEnabled whether the pprof and expvar debug endpoints are served on the admin server
+	AdminConfDebugEnabled = "debug.enabled"
+	// AdminConfKeyCeremonyEnabled whether keys can be imported and exported on the admin server, with the approval of two identities
+	AdminConfKeyCeremonyEnabled = "keyCeremony.enabled"
+	// AdminConfKeyCeremonyPath the directory the state of key ceremonies is persisted in
+	AdminConfKeyCeremonyPath = "keyCeremony.path"
+	// AdminConfKeyCeremonyExpiry how long a key ceremony can take from when it is proposed to when it is executed
+	AdminConfKeyCeremonyExpiry = "keyCeremony.expiry"
+)
+
 var ServerConfig config.Section
 
 var CorsConfig config.Section
@@ -41,8 +279,103 @@ var BackendConfig config.Section
 
 var FileWalletConfig config.Section
 
+var FileWalletObjectStoreConfig config.Section
+
+var MetricsConfig config.Section
+
+var AuditWebhookConfig config.Section
+
+var AdminConfig config.Section
+
+var AuthConfig config.Section
+
+var MethodsConfig config.Section
+
+var NoncesConfig config.Section
+
+var FIPSConfig config.Section
+
+var TimeoutsConfig config.Section
+
+var TimeoutOverridesConfig config.ArraySection
+
+var ChainsConfig config.Section
+
+var ChainNetworksConfig config.ArraySection
+
+var FeeOraclesConfig config.ArraySection
+
 func setDefaults() {
 	viper.SetDefault(string(BackendChainID), -1)
+	viper.SetDefault(string(BackendChainIDValidationEnabled), true)
+	viper.SetDefault(string(BackendChainIDValidationInterval), "1m")
+	viper.SetDefault(string(BackendBatchEnabled), false)
+	viper.SetDefault(string(BackendBatchSize), 500)
+	viper.SetDefault(string(BackendBatchTimeout), "50ms")
+	viper.SetDefault(string(BackendBatchDispatchConcurrency), 50)
+	viper.SetDefault(string(BackendRetryIdempotentMethods), []string{})
+	viper.SetDefault(string(BackendFailoverURLs), []string{})
+	viper.SetDefault(string(BackendFailoverHealthCheckInterval), "5s")
+	viper.SetDefault(string(BackendFailoverHealthCheckTimeout), "2s")
+	viper.SetDefault(string(BackendFailoverHealthCheckMethod), "eth_blockNumber")
+	viper.SetDefault(string(BackendFailoverStickySubscriptions), false)
+	viper.SetDefault(string(BackendCircuitBreakerEnabled), false)
+	viper.SetDefault(string(BackendCircuitBreakerFailureRateThreshold), 0.5)
+	viper.SetDefault(string(BackendCircuitBreakerMinimumRequests), 20)
+	viper.SetDefault(string(BackendCircuitBreakerWindow), "30s")
+	viper.SetDefault(string(BackendCircuitBreakerSlowCallThreshold), "0s")
+	viper.SetDefault(string(BackendCircuitBreakerOpenDuration), "30s")
+	viper.SetDefault(string(BackendCircuitBreakerHalfOpenProbes), 3)
+	viper.SetDefault(string(LogModules), []string{})
+	viper.SetDefault(string(TracingEnabled), false)
+	viper.SetDefault(string(TracingServiceName), "ffsigner")
+	viper.SetDefault(string(TracingSampleRatio), 1.0)
+	viper.SetDefault(string(RateLimitEnabled), false)
+	viper.SetDefault(string(RateLimitRequestsPerSecond), 100)
+	viper.SetDefault(string(RateLimitRequestsBurst), 200)
+	viper.SetDefault(string(RateLimitSigningPerSecond), 10)
+	viper.SetDefault(string(RateLimitSigningBurst), 20)
+	viper.SetDefault(string(RateLimitIdleTimeout), "5m")
+	viper.SetDefault(string(PersonalSignEnabled), true)
+	viper.SetDefault(string(DangerousMethodsEthSign), false)
+	viper.SetDefault(string(PreflightEnabled), false)
+	viper.SetDefault(string(PreflightBlockTag), "pending")
+	viper.SetDefault(string(GasEstimateEnabled), false)
+	viper.SetDefault(string(GasEstimateMultiplier), 1.2)
+	viper.SetDefault(string(GasEstimateCap), 0)
+	viper.SetDefault(string(FeesEnabled), false)
+	viper.SetDefault(string(FeesStrategy), "feeHistory")
+	viper.SetDefault(string(FeesHistoryBlocks), 10)
+	viper.SetDefault(string(FeesHistoryPercentile), 50)
+	viper.SetDefault(string(FeesBaseFeeMultiplier), 2)
+	viper.SetDefault(string(FeesOracleMode), "fallback")
+	viper.SetDefault(string(FeeCapsPolicy), "reject")
+	viper.SetDefault(string(TxPolicyAllowedDestinations), []string{})
+	viper.SetDefault(string(TxPolicyAllowDeployments), true)
+	viper.SetDefault(string(TxPolicyRawTransactionsEnabled), false)
+	viper.SetDefault(string(TxPolicyRawTransactionsManagedSendersOnly), false)
+	viper.SetDefault(string(TxValidationEnabled), false)
+	viper.SetDefault(string(TxValidationAllowUnknownFields), false)
+	viper.SetDefault(string(ENSEnabled), false)
+	viper.SetDefault(string(ENSRegistry), "0x00000000000C2E074eC69A0dFb2997BA6C7d2e1e")
+	viper.SetDefault(string(ENSCCIPReadEnabled), false)
+	viper.SetDefault(string(AccessLogEnabled), false)
+	viper.SetDefault(string(AccessLogVerbosity), "summary")
+	viper.SetDefault(string(AccessLogCorrelationHeader), "X-Request-ID")
+	viper.SetDefault(string(ResponseCacheEnabled), false)
+	viper.SetDefault(string(ResponseCacheTTL), "2s")
+	viper.SetDefault(string(ResponseCacheMaxEntries), 10000)
+	viper.SetDefault(string(ResponseCacheReceiptConfirmations), 12)
+	viper.SetDefault(string(SenderQueueEnabled), false)
+	viper.SetDefault(string(SenderQueueMaxPending), 100)
+	viper.SetDefault(string(SigningApprovalTimeout), "5m")
+	viper.SetDefault(string(ResubmitEnabled), false)
+	viper.SetDefault(string(ResubmitPolicy), "feeBump")
+	viper.SetDefault(string(ResubmitDelay), "2m")
+	viper.SetDefault(string(ResubmitInterval), "15s")
+	viper.SetDefault(string(ResubmitFeeBumpPercent), 10)
+	viper.SetDefault(string(ResubmitMaxAttempts), 5)
+	viper.SetDefault(string(ResubmitMaxTracked), 10000)
 	viper.SetDefault(string(FileWalletEnabled), true)
 }
 
@@ -51,6 +384,14 @@ func Reset() {
 
 	ServerConfig = config.RootSection("server")
 	httpserver.InitHTTPConfig(ServerConfig, 8545)
+	ServerConfig.AddKnownKey(ServerConfTCPEnabled, true)
+	ServerConfig.AddKnownKey(ServerConfUnixSocketPath)
+	ServerConfig.AddKnownKey(ServerConfUnixSocketMode, "0600")
+	ServerConfig.AddKnownKey(ServerConfWSPingInterval, "30s")
+	ServerConfig.AddKnownKey(ServerConfWSMaxMessageSize, "16Mb")
+	ServerConfig.AddKnownKey(ServerConfMaxRequestSize, "16Mb")
+	ServerConfig.AddKnownKey(ServerConfMaxBatchSize, 1000)
+	ServerConfig.AddKnownKey(ServerConfMaxJSONDepth, 64)
 
 	CorsConfig = config.RootSection("cors")
 	httpserver.InitCORSConfig(CorsConfig)
@@ -60,5 +401,74 @@ func Reset() {
 
 	FileWalletConfig = config.RootSection("fileWallet")
 	fswallet.InitConfig(FileWalletConfig)
+	FileWalletObjectStoreConfig = FileWalletConfig.SubSection("objectStore")
+	objectstore.InitConfig(FileWalletObjectStoreConfig)
+
+	MetricsConfig = config.RootSection("metrics")
+	httpserver.InitHTTPConfig(MetricsConfig, 6000)
+	MetricsConfig.AddKnownKey(MetricsConfEnabled, false)
+	MetricsConfig.AddKnownKey(MetricsConfPath, "/metrics")
+	MetricsConfig.AddKnownKey(MetricsConfAddressesEnabled, false)
+	MetricsConfig.AddKnownKey(MetricsConfAddressesLabel, "full")
+	MetricsConfig.AddKnownKey(MetricsConfAddressesLabelLength, 8)
+
+	AuditWebhookConfig = config.RootSection("auditWebhook")
+	ffresty.InitConfig(AuditWebhookConfig)
+	AuditWebhookConfig.SetDefault(ffresty.HTTPConfigRetryEnabled, true)
+	AuditWebhookConfig.AddKnownKey(AuditWebhookConfEnabled, false)
+	AuditWebhookConfig.AddKnownKey(AuditWebhookConfSecret)
+	AuditWebhookConfig.AddKnownKey(AuditWebhookConfSignatureHeader, "X-Signer-Signature")
+	AuditWebhookConfig.AddKnownKey(AuditWebhookConfQueueSize, 1000)
+
+	AdminConfig = config.RootSection("admin")
+	httpserver.InitHTTPConfig(AdminConfig, 6001)
+	AdminConfig.AddKnownKey(AdminConfEnabled, false)
+	AdminConfig.AddKnownKey(AdminConfDebugEnabled, false)
+	AdminConfig.AddKnownKey(AdminConfKeyCeremonyEnabled, false)
+	AdminConfig.AddKnownKey(AdminConfKeyCeremonyPath)
+	AdminConfig.AddKnownKey(AdminConfKeyCeremonyExpiry, "24h")
+
+	AuthConfig = config.RootSection("auth")
+	rpcauth.InitConfig(AuthConfig)
+
+	MethodsConfig = config.RootSection("methods")
+	rpcauth.InitMethodsConfig(MethodsConfig)
+
+	NoncesConfig = config.RootSection("nonces")
+	nonces.InitConfig(NoncesConfig)
+
+	FIPSConfig = config.RootSection("fips")
+	fips.InitConfig(FIPSConfig)
+
+	TimeoutsConfig = config.RootSection("timeouts")
+	TimeoutsConfig.AddKnownKey(TimeoutsConfDefault, "0s")
+	TimeoutOverridesConfig = TimeoutsConfig.SubArray(TimeoutsConfOverrides)
+	TimeoutOverridesConfig.AddKnownKey(TimeoutsConfMethods)
+	TimeoutOverridesConfig.AddKnownKey(TimeoutsConfTimeout)
+
+	ChainsConfig = config.RootSection("chains")
+	ChainsConfig.AddKnownKey(ChainsConfHeader, "X-Chain")
+	ChainNetworksConfig = ChainsConfig.SubArray(ChainsConfNetworks)
+	ChainNetworksConfig.AddKnownKey(ChainsConfName)
+	ChainNetworksConfig.AddKnownKey(ChainsConfChainID, -1)
+	ChainNetworksConfig.AddKnownKey(ChainsConfURL)
+
+	FeeOraclesConfig = config.RootArray("fees.oracles")
+	FeeOraclesConfig.AddKnownKey(FeeOraclesConfType)
+	FeeOraclesConfig.AddKnownKey(FeeOraclesConfURL)
+	FeeOraclesConfig.AddKnownKey(FeeOraclesConfAPIKey)
+	FeeOraclesConfig.AddKnownKey(FeeOraclesConfHeaders)
+	FeeOraclesConfig.AddKnownKey(FeeOraclesConfRequestTimeout, "10s")
+	FeeOraclesConfig.AddKnownKey(FeeOraclesConfSpeed, "standard")
+	FeeOraclesConfig.AddKnownKey(FeeOraclesConfWeight, 1)
+
+}
 
+// ReloadConfigFile re-reads the config file the configuration was originally read from (if any), to
+// pick up changes made since. Settings from environment variables continue to take precedence.
+func ReloadConfigFile() error {
+	if viper.ConfigFileUsed() == "" {
+		return nil
+	}
+	return viper.ReadInConfig()
 }
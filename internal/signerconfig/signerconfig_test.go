@@ -17,9 +17,12 @@
 package signerconfig
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -30,3 +33,27 @@ func TestInitConfigOK(t *testing.T) {
 
 	assert.True(t, config.GetBool(FileWalletEnabled))
 }
+
+func TestReloadConfigFile(t *testing.T) {
+	Reset()
+
+	// Nothing to do when the config was not read from a file
+	assert.NoError(t, ReloadConfigFile())
+
+	configFile := filepath.Join(t.TempDir(), "ffsigner.yaml")
+	defer viper.Reset() // the config file is otherwise retained over a reset of the config
+	err := os.WriteFile(configFile, []byte("log:\n  level: info\n"), 0600)
+	assert.NoError(t, err)
+	err = config.ReadConfig("ffsigner", configFile)
+	assert.NoError(t, err)
+	assert.Equal(t, "info", config.GetString(config.LogLevel))
+
+	err = os.WriteFile(configFile, []byte("log:\n  level: debug\n"), 0600)
+	assert.NoError(t, err)
+	assert.NoError(t, ReloadConfigFile())
+	assert.Equal(t, "debug", config.GetString(config.LogLevel))
+
+	err = os.WriteFile(configFile, []byte("!badness"), 0600)
+	assert.NoError(t, err)
+	assert.Error(t, ReloadConfigFile())
+}
@@ -27,30 +27,237 @@ var ffc = func(key, translation, fieldType string) i18n.ConfigMessageKey {
 
 //revive:disable
 var (
-	ConfigFileWalletEnabled                      = ffc("config.fileWallet.enabled", "Whether the Keystore V3 filesystem wallet is enabled", "boolean")
-	ConfigFileWalletPath                         = ffc("config.fileWallet.path", "Path on the filesystem where the metadata files (and/or key files) are located", "string")
-	ConfigFileWalletFilenamesPrimaryBatchRegex   = ffc("config.fileWallet.filenames.primaryMatchRegex", "Regular expression run against key/metadata filenames to extract the address (takes precedence over primaryExt)", "regexp")
-	ConfigFileWalletFilenamesWith0xPrefix        = ffc("config.fileWallet.filenames.with0xPrefix", "When true and passwordExt is used, password filenames will be generated with an 0x prefix", "boolean")
-	ConfigFileWalletFilenamesPrimaryExt          = ffc("config.fileWallet.filenames.primaryExt", "Extension for key/metadata files named by <ADDRESS>.<EXT>", "string")
-	ConfigFileWalletFilenamesPasswordExt         = ffc("config.fileWallet.filenames.passwordExt", "Optional to use to look up password files, that sit next to the key files directly. Alternative to metadata when you have a password per keystore", "string")
-	ConfigFileWalletFilenamesPasswordPath        = ffc("config.fileWallet.filenames.passwordPath", "Optional directory in which to look for the password files, when passwordExt is configured. Default is the wallet directory", "string")
-	ConfigFileWalletFilenamesPasswordTrimSpace   = ffc("config.fileWallet.filenames.passwordTrimSpace", "Whether to trim leading/trailing whitespace (such as a newline) from the password when loaded from file", "boolean")
-	ConfigFileWalletDefaultPasswordFile          = ffc("config.fileWallet.defaultPasswordFile", "Optional default password file to use, if one is not specified individually for the key (via metadata, or file extension)", "string")
-	ConfigFileWalletDisableListener              = ffc("config.fileWallet.disableListener", "Disable the filesystem listener that automatically detects the creation of new keystore files", "boolean")
-	ConfigFileWalletSignerCacheSize              = ffc("config.fileWallet.signerCacheSize", "Maximum of signing keys to hold in memory", "number")
-	ConfigFileWalletSignerCacheTTL               = ffc("config.fileWallet.signerCacheTTL", "How long ot leave an unused signing key in memory", "duration")
-	ConfigFileWalletMetadataFormat               = ffc("config.fileWallet.metadata.format", "Set this if the primary key file is a metadata file. Supported formats: auto (from extension) / filename / toml / yaml / json (please quote \"0x...\" strings in YAML)", "string")
-	ConfigFileWalletMetadataKeyFileProperty      = ffc("config.fileWallet.metadata.keyFileProperty", "Go template to look up the key-file path from the metadata. Example: '{{ index .signing \"key-file\" }}'", "go-template")
-	ConfigFileWalletMetadataPasswordFileProperty = ffc("config.fileWallet.metadata.passwordFileProperty", "Go template to look up the password-file path from the metadata", "go-template")
-
-	ConfigServerAddress      = ffc("config.server.address", "Local address for the JSON/RPC server to listen on", "string")
-	ConfigServerPort         = ffc("config.server.port", "Port for the JSON/RPC server to listen on", "number")
-	ConfigAPIPublicURL       = ffc("config.server.publicURL", "External address callers should access API over", "string")
-	ConfigServerReadTimeout  = ffc("config.server.readTimeout", "The maximum time to wait when reading from an HTTP connection", "duration")
-	ConfigServerWriteTimeout = ffc("config.server.writeTimeout", "The maximum time to wait when writing to a HTTP connection", "duration")
-	ConfigAPIShutdownTimeout = ffc("config.server.shutdownTimeout", "The maximum amount of time to wait for any open HTTP requests to finish before shutting down the HTTP server", i18n.TimeDurationType)
-
-	ConfigBackendChainID  = ffc("config.backend.chainId", "Optionally set the Chain ID of the blockchain. Otherwise the Network ID will be queried, and used as the Chain ID in signing", "number")
-	ConfigBackendURL      = ffc("config.backend.url", "URL for the backend JSON/RPC server / blockchain node", "url")
+	ConfigFileWalletEnabled                       = ffc("config.fileWallet.enabled", "Whether the Keystore V3 filesystem wallet is enabled", "boolean")
+	ConfigFileWalletPath                          = ffc("config.fileWallet.path", "Path on the filesystem where the metadata files (and/or key files) are located", "string")
+	ConfigFileWalletFilenamesPrimaryBatchRegex    = ffc("config.fileWallet.filenames.primaryMatchRegex", "Regular expression run against key/metadata filenames to extract the address (takes precedence over primaryExt)", "regexp")
+	ConfigFileWalletFilenamesWith0xPrefix         = ffc("config.fileWallet.filenames.with0xPrefix", "When true and passwordExt is used, password filenames will be generated with an 0x prefix", "boolean")
+	ConfigFileWalletFilenamesPrimaryExt           = ffc("config.fileWallet.filenames.primaryExt", "Extension for key/metadata files named by <ADDRESS>.<EXT>", "string")
+	ConfigFileWalletFilenamesPasswordExt          = ffc("config.fileWallet.filenames.passwordExt", "Optional to use to look up password files, that sit next to the key files directly. Alternative to metadata when you have a password per keystore", "string")
+	ConfigFileWalletFilenamesPasswordPath         = ffc("config.fileWallet.filenames.passwordPath", "Optional directory in which to look for the password files, when passwordExt is configured. Default is the wallet directory", "string")
+	ConfigFileWalletFilenamesShardPrefixLength    = ffc("config.fileWallet.filenames.shardPrefixLength", "When non-zero, key/metadata files (and password files when passwordPath is not set) are stored in sub-directories named by this many leading hex characters of the address, to avoid very large directories. Use 'ffsigner keys migrate' to move existing files", "number")
+	ConfigFileWalletFilenamesPasswordTrimSpace    = ffc("config.fileWallet.filenames.passwordTrimSpace", "Whether to trim leading/trailing whitespace (such as a newline) from the password when loaded from file", "boolean")
+	ConfigFileWalletDefaultPasswordFile           = ffc("config.fileWallet.defaultPasswordFile", "Optional default password file to use, if one is not specified individually for the key (via metadata, or file extension)", "string")
+	ConfigFileWalletDisableListener               = ffc("config.fileWallet.disableListener", "Disable the filesystem listener that automatically detects the creation of new keystore files", "boolean")
+	ConfigFileWalletSignerCacheSize               = ffc("config.fileWallet.signerCacheSize", "Maximum of signing keys to hold in memory", "number")
+	ConfigFileWalletSignerCacheTTL                = ffc("config.fileWallet.signerCacheTTL", "How long ot leave an unused signing key in memory", "duration")
+	ConfigFileWalletRequireUnlock                 = ffc("config.fileWallet.requireUnlock", "When true, keys can only be used for signing after they have been unlocked with personal_unlockAccount, which requires the passphrase of the key. Keys are not loaded automatically, so an operator must unlock them after every restart", "boolean")
+	ConfigFileWalletUnlockTTL                     = ffc("config.fileWallet.unlockTTL", "How long a key stays unlocked when personal_unlockAccount is called without a duration", "duration")
+	ConfigFileWalletExtraEntropy                  = ffc("config.fileWallet.extraEntropy", "When true, random entropy is mixed into the RFC 6979 deterministic nonce of every signature (per section 3.6), for deployments whose policy does not allow fully deterministic nonces", "boolean")
+	ConfigFileWalletAllowInsecurePermissions      = ffc("config.fileWallet.allowInsecurePermissions", "When true, key and password files that other users can access (or that are owned by another user) are loaded rather than refused. Checks the mode bits and owner on Linux/macOS, and the access control list on Windows", "boolean")
+	ConfigFileWalletMetadataFormat                = ffc("config.fileWallet.metadata.format", "Set this if the primary key file is a metadata file. Supported formats: auto (from extension) / filename / toml / yaml / json (please quote \"0x...\" strings in YAML)", "string")
+	ConfigFileWalletMetadataKeyFileProperty       = ffc("config.fileWallet.metadata.keyFileProperty", "Go template to look up the key-file path from the metadata. Example: '{{ index .signing \"key-file\" }}'", "go-template")
+	ConfigFileWalletMetadataPasswordFileProperty  = ffc("config.fileWallet.metadata.passwordFileProperty", "Go template to look up the password-file path from the metadata", "go-template")
+	ConfigFileWalletObjectStoreEnabled            = ffc("config.fileWallet.objectStore.enabled", "When true, the key and metadata files of the file wallet are synchronized from an S3-compatible object store into the wallet path, which acts as a local cache", "boolean")
+	ConfigFileWalletObjectStoreEndpoint           = ffc("config.fileWallet.objectStore.endpoint", "The URL of the object store, such as https://s3.us-east-1.amazonaws.com", "string")
+	ConfigFileWalletObjectStoreRegion             = ffc("config.fileWallet.objectStore.region", "The region used to sign requests to the object store", "string")
+	ConfigFileWalletObjectStoreBucket             = ffc("config.fileWallet.objectStore.bucket", "The bucket containing the key and metadata files", "string")
+	ConfigFileWalletObjectStorePrefix             = ffc("config.fileWallet.objectStore.prefix", "The prefix of every object in the bucket", "string")
+	ConfigFileWalletObjectStoreNamespace          = ffc("config.fileWallet.objectStore.namespace", "The namespace of this signer under the prefix, so one bucket can hold the keys of many signers. The objects below <prefix>/<namespace>/ are laid out as the files of the wallet path", "string")
+	ConfigFileWalletObjectStorePathStyle          = ffc("config.fileWallet.objectStore.pathStyle", "When true, the bucket is in the path of each request rather than the host name, as required by many S3-compatible stores", "boolean")
+	ConfigFileWalletObjectStoreAccessKeyID        = ffc("config.fileWallet.objectStore.accessKeyID", "The access key ID of the object store. Defaults to the AWS_ACCESS_KEY_ID environment variable", "string")
+	ConfigFileWalletObjectStoreSecretAccessKey    = ffc("config.fileWallet.objectStore.secretAccessKey", "The secret access key of the object store. Defaults to the AWS_SECRET_ACCESS_KEY environment variable", "string")
+	ConfigFileWalletObjectStoreSessionToken       = ffc("config.fileWallet.objectStore.sessionToken", "The session token of temporary credentials. Defaults to the AWS_SESSION_TOKEN environment variable", "string")
+	ConfigFileWalletObjectStoreSSEMode            = ffc("config.fileWallet.objectStore.serverSideEncryption.mode", "The server-side encryption every object must be stored with - AES256, aws:kms or customer (SSE-C). Objects stored without it are refused. Empty to accept any object", "string")
+	ConfigFileWalletObjectStoreSSEKMSKeyID        = ffc("config.fileWallet.objectStore.serverSideEncryption.kmsKeyID", "The ID or ARN of the KMS key every object must be encrypted with, when the mode is aws:kms", "string")
+	ConfigFileWalletObjectStoreSSECustomerKeyFile = ffc("config.fileWallet.objectStore.serverSideEncryption.customerKeyFile", "A file containing the base64 encoded 256 bit key the objects are encrypted with, when the mode is customer", "string")
+	ConfigFileWalletObjectStoreRefreshInterval    = ffc("config.fileWallet.objectStore.refreshInterval", "How often to synchronize the wallet from the object store. Zero to only synchronize on startup, and when the wallet is refreshed", "duration")
+	ConfigFileWalletObjectStoreRequestTimeout     = ffc("config.fileWallet.objectStore.requestTimeout", "The timeout of each request to the object store", "duration")
+
+	ConfigServerAddress          = ffc("config.server.address", "Local address for the JSON/RPC server to listen on", "string")
+	ConfigServerPort             = ffc("config.server.port", "Port for the JSON/RPC server to listen on", "number")
+	ConfigAPIPublicURL           = ffc("config.server.publicURL", "External address callers should access API over", "string")
+	ConfigServerReadTimeout      = ffc("config.server.readTimeout", "The maximum time to wait when reading from an HTTP connection", "duration")
+	ConfigServerWriteTimeout     = ffc("config.server.writeTimeout", "The maximum time to wait when writing to a HTTP connection", "duration")
+	ConfigAPIShutdownTimeout     = ffc("config.server.shutdownTimeout", "The maximum amount of time to wait for any open HTTP requests to finish before shutting down the HTTP server", i18n.TimeDurationType)
+	ConfigServerTCPEnabled       = ffc("config.server.tcpEnabled", "When false, the JSON/RPC server does not listen on TCP, and is only served on the UNIX domain socket. For sidecar deployments where no network surface is wanted", "boolean")
+	ConfigServerUnixSocketPath   = ffc("config.server.unixSocket.path", "The path of a UNIX domain socket the JSON/RPC server listens on, in addition to TCP. Any stale socket left at the path is replaced on startup", "string")
+	ConfigServerUnixSocketMode   = ffc("config.server.unixSocket.mode", "The file permissions of the UNIX domain socket, in octal, which control which local users can connect to it", "string")
+	ConfigServerWSPingInterval   = ffc("config.server.ws.pingInterval", "How often WebSocket clients are pinged. A client that does not respond within two intervals is disconnected, so its subscriptions are cleaned up. Set to zero to disable", i18n.TimeDurationType)
+	ConfigServerWSMaxMessageSize = ffc("config.server.ws.maxMessageSize", "The largest message accepted from a WebSocket client. A client sending a larger message is disconnected", i18n.ByteSizeType)
+	ConfigServerMaxRequestSize   = ffc("config.server.maxRequestSize", "The largest HTTP request body accepted by the JSON/RPC server. Larger requests are rejected with HTTP 413 before they are parsed", i18n.ByteSizeType)
+	ConfigServerMaxBatchSize     = ffc("config.server.maxBatchSize", "The most requests accepted in a single JSON/RPC batch, over HTTP or WebSocket. Larger batches are rejected without processing any of their requests. Set to zero for no limit", i18n.IntType)
+	ConfigServerMaxJSONDepth     = ffc("config.server.maxJSONDepth", "The deepest nesting of JSON objects and arrays accepted in a request, over HTTP or WebSocket. Deeper requests are rejected before they are parsed. Set to zero for no limit", i18n.IntType)
+
+	ConfigAuditWebhookEnabled         = ffc("config.auditWebhook.enabled", "Posts an audit event to the webhook URL for every signing operation and policy rejection, with the caller and a summary of what was signed. Events are delivered in the background, so never delay signing", "boolean")
+	ConfigAuditWebhookSecret          = ffc("config.auditWebhook.secret", "A secret each audit event is signed with using HMAC-SHA256, so the receiver can verify it came from the signer. The hex encoded signature is sent in the signature header, prefixed with 'sha256='", "string")
+	ConfigAuditWebhookSignatureHeader = ffc("config.auditWebhook.signatureHeader", "The HTTP header carrying the signature of each audit event, when a secret is configured", "string")
+	ConfigAuditWebhookURL             = ffc("config.auditWebhook.url", "The URL audit events are posted to as JSON. Delivery is retried as configured in auditWebhook.retry, and an event that cannot be delivered is written to the log", "url")
+	ConfigAuditWebhookProxyURL        = ffc("config.auditWebhook.proxy.url", "Optional HTTP proxy URL", "url")
+	ConfigAuditWebhookQueueSize       = ffc("config.auditWebhook.queueSize", "The most audit events queued for delivery. Events are dropped with an error logged when the queue is full, such as while the webhook is unavailable", i18n.IntType)
+
+	ConfigMetricsEnabled              = ffc("config.metrics.enabled", "Enables the metrics server, which serves Prometheus metrics on a separate listener to the JSON/RPC server", "boolean")
+	ConfigMetricsPath                 = ffc("config.metrics.path", "The path on the metrics server where metrics are served", "string")
+	ConfigMetricsAddressesEnabled     = ffc("config.metrics.addresses.enabled", "When true, signing operations, their latency, and policy rejections are also recorded per signing address", "boolean")
+	ConfigMetricsAddressesLabel       = ffc("config.metrics.addresses.label", "How the address is labelled on per-address metrics. 'full' uses the whole address, 'truncated' only its first labelLength hex characters, and 'hashed' the first labelLength hex characters of its SHA-256 hash. Truncated and hashed labels limit the number of distinct series when there are many addresses", "string")
+	ConfigMetricsAddressesLabelLength = ffc("config.metrics.addresses.labelLength", "The number of hex characters (1-40) kept in truncated and hashed address labels", "number")
+	ConfigMetricsAddress              = ffc("config.metrics.address", "Local address for the metrics server to listen on", "string")
+	ConfigMetricsPort                 = ffc("config.metrics.port", "Port for the metrics server to listen on", "number")
+	ConfigMetricsPublicURL            = ffc("config.metrics.publicURL", "External address callers should access the metrics server over", "string")
+	ConfigMetricsReadTimeout          = ffc("config.metrics.readTimeout", "The maximum time to wait when reading from an HTTP connection", "duration")
+	ConfigMetricsWriteTimeout         = ffc("config.metrics.writeTimeout", "The maximum time to wait when writing to a HTTP connection", "duration")
+	ConfigMetricsShutdownTimeout      = ffc("config.metrics.shutdownTimeout", "The maximum amount of time to wait for any open HTTP requests to finish before shutting down the metrics server", i18n.TimeDurationType)
+
+	ConfigAdminEnabled            = ffc("config.admin.enabled", "Enables the admin server, which serves operations to refresh the wallet and inspect the runtime status of the signer on a separate listener to the JSON/RPC server. Requires auth to be enabled, and when auth.rbac is enabled each operation must be granted as a method named admin_*", "boolean")
+	ConfigAdminDebugEnabled       = ffc("config.admin.debug.enabled", "Serves the Go runtime profiles of net/http/pprof under /debug/pprof/ and the variables of expvar at /debug/vars on the admin server, authorized as the admin_debug operation. CPU profiles and traces must be shorter than the writeTimeout of the admin server", "boolean")
+	ConfigAdminKeyCeremonyEnabled = ffc("config.admin.keyCeremony.enabled", "Serves key ceremonies on the admin server, to import a key into the file wallet or export one from it under dual control. Each ceremony is proposed by one identity and must be approved by a second, different, identity before the proposer can execute it. Requires auth.rbac, and the proposer and every approver must be granted the admin_keyCeremony operation, which is checked again when the ceremony is approved and executed", "boolean")
+	ConfigAdminKeyCeremonyPath    = ffc("config.admin.keyCeremony.path", "The directory the state and audit history of each key ceremony is persisted in, so ceremonies survive restarts. Defaults to persistence.path", "string")
+	ConfigAdminKeyCeremonyExpiry  = ffc("config.admin.keyCeremony.expiry", "How long a key ceremony can take from when it is proposed to when it is executed, after which it expires", i18n.TimeDurationType)
+	ConfigAdminAddress            = ffc("config.admin.address", "Local address for the admin server to listen on", "string")
+	ConfigAdminPort               = ffc("config.admin.port", "Port for the admin server to listen on", "number")
+	ConfigAdminPublicURL          = ffc("config.admin.publicURL", "External address callers should access the admin server over", "string")
+	ConfigAdminReadTimeout        = ffc("config.admin.readTimeout", "The maximum time to wait when reading from an HTTP connection", "duration")
+	ConfigAdminWriteTimeout       = ffc("config.admin.writeTimeout", "The maximum time to wait when writing to a HTTP connection", "duration")
+	ConfigAdminShutdownTimeout    = ffc("config.admin.shutdownTimeout", "The maximum amount of time to wait for any open HTTP requests to finish before shutting down the admin server", i18n.TimeDurationType)
+
+	ConfigBackendChainID  = ffc("config.backend.chainId", "Optionally set the Chain ID of the blockchain. Otherwise the Chain ID is queried with eth_chainId, and used in signing", "number")
+	ConfigBackendURL      = ffc("config.backend.url", "URL for the backend JSON/RPC server / blockchain node. Use a ws:// or wss:// URL to connect over a WebSocket, which is automatically reconnected using the retry and ws settings. Use a unix:// URL with the path of the IPC socket of a co-located node (such as unix:///data/geth.ipc) to connect over IPC, which is reconnected in the same way", "url")
 	ConfigBackendProxyURL = ffc("config.backend.proxy.url", "Optional HTTP proxy URL", "url")
+
+	ConfigBackendChainIDValidationEnabled  = ffc("config.backend.chainIdValidation.enabled", "When true, a configured Chain ID is checked against eth_chainId of the backend at startup (failing startup if they differ) and periodically, with transactions refused while they differ. Protects against cross-chain replay after a misconfiguration", "boolean")
+	ConfigBackendChainIDValidationInterval = ffc("config.backend.chainIdValidation.interval", "How often the Chain ID of the backend is revalidated after startup. Set to zero to only validate at startup", i18n.TimeDurationType)
+
+	ConfigBackendTLSServerName = ffc("config.backend.tls.serverName", "Overrides the server name sent in the TLS handshake (SNI) and verified against the certificate of the backend, for nodes whose certificate does not match the host in the URL. Applies to all backend URLs", "string")
+
+	ConfigBackendBatchEnabled             = ffc("config.backend.batch.enabled", "Coalesce concurrent requests to an HTTP backend into JSON/RPC batch requests, to reduce round trips. Not used for WebSocket backends", "boolean")
+	ConfigBackendBatchSize                = ffc("config.backend.batch.size", "The maximum number of requests in a batch, which is dispatched as soon as it is full", "number")
+	ConfigBackendBatchTimeout             = ffc("config.backend.batch.timeout", "The maximum time the first request in a batch waits for others to join it, before the batch is dispatched", "duration")
+	ConfigBackendBatchDispatchConcurrency = ffc("config.backend.batch.dispatchConcurrency", "The maximum number of batches in-flight to the backend at once", "number")
+
+	ConfigBackendRetryIdempotentMethods = ffc("config.backend.retry.idempotentMethods", "The read-only JSON/RPC methods that are safe to retry after any failure to get a response from an HTTP backend. Other methods, such as eth_sendRawTransaction, are only retried when the connection to the backend could not be established. Defaults to the standard read methods such as eth_call and eth_getTransactionReceipt", i18n.ArrayStringType)
+
+	ConfigBackendFailoverURLs                       = ffc("config.backend.failover.urls", "Additional backend URLs to fail over to, in priority order after backend.url. They share all other backend settings, and must use the same kind of connection (HTTP, or WebSocket/IPC) as backend.url", i18n.ArrayStringType)
+	ConfigBackendFailoverHealthCheckInterval        = ffc("config.backend.failover.healthCheck.interval", "How often each backend is health checked, when failover URLs are configured", "duration")
+	ConfigBackendFailoverHealthCheckTimeout         = ffc("config.backend.failover.healthCheck.timeout", "The maximum time a backend has to respond to a health check, before it is marked unhealthy", "duration")
+	ConfigBackendFailoverHealthCheckMethod          = ffc("config.backend.failover.healthCheck.method", "A JSON/RPC method with no parameters, which must succeed for a backend to be considered healthy", "string")
+	ConfigBackendFailoverStickySubscriptions        = ffc("config.backend.failover.stickySubscriptions", "Keep each subscription on the WebSocket backend it is on while that backend is healthy, rather than moving it back to a higher priority backend when that one recovers", "boolean")
+	ConfigBackendCircuitBreakerEnabled              = ffc("config.backend.circuitBreaker.enabled", "Fail requests fast with a distinct JSON/RPC error code while a backend is failing, rather than waiting on it. Each backend URL has its own circuit breaker", "boolean")
+	ConfigBackendCircuitBreakerFailureRateThreshold = ffc("config.backend.circuitBreaker.failureRateThreshold", "The fraction (0-1) of requests in a window that must fail because the backend is unavailable, for the circuit to open", i18n.FloatType)
+	ConfigBackendCircuitBreakerMinimumRequests      = ffc("config.backend.circuitBreaker.minimumRequests", "The number of requests there must be in a window before the circuit can open", "number")
+	ConfigBackendCircuitBreakerWindow               = ffc("config.backend.circuitBreaker.window", "The period over which failures are counted, with the counts reset at the end of each window", "duration")
+	ConfigBackendCircuitBreakerSlowCallThreshold    = ffc("config.backend.circuitBreaker.slowCallThreshold", "Requests that take at least this long count as failures. Zero disables the check", "duration")
+	ConfigBackendCircuitBreakerOpenDuration         = ffc("config.backend.circuitBreaker.openDuration", "How long the circuit stays open before a few probe requests are let through to the backend", "duration")
+	ConfigBackendCircuitBreakerHalfOpenProbes       = ffc("config.backend.circuitBreaker.halfOpenProbes", "The number of probe requests that must all succeed for the circuit to close again. A single failed probe reopens it", "number")
+
+	ConfigLogModules         = ffc("config.log.modules", "Modules with detailed debug logging, which is logged at info level regardless of log.level - rpc for every JSON/RPC payload sent and received, and abi for each value decoded from ABI data. Can be changed at runtime with the admin API", i18n.ArrayStringType)
+	ConfigTracingEnabled     = ffc("config.tracing.enabled", "Enables OpenTelemetry tracing of JSON/RPC requests, continuing any W3C trace context received in HTTP headers and propagating it to HTTP backends", "boolean")
+	ConfigTracingEndpoint    = ffc("config.tracing.endpoint", "The URL of the OTLP/HTTP endpoint spans are exported to. When not set, the standard OTEL_EXPORTER_OTLP_* environment variables are used", "url")
+	ConfigTracingServiceName = ffc("config.tracing.serviceName", "The service name recorded on all exported spans", "string")
+	ConfigTracingSampleRatio = ffc("config.tracing.sampleRatio", "The fraction of new traces to sample, between 0 and 1. Requests that are part of a trace propagated by the caller follow the caller's sampling decision", i18n.FloatType)
+
+	ConfigRateLimitEnabled           = ffc("config.rateLimit.enabled", "Enables per-client rate limiting of JSON/RPC requests and signing operations. Clients are identified by their authenticated identity when authentication is enabled, or otherwise by their source IP address", "boolean")
+	ConfigRateLimitRequestsPerSecond = ffc("config.rateLimit.requestsPerSecond", "The sustained rate of JSON/RPC requests allowed for each client. Each request in a batch counts separately. Set to 0 for no limit", i18n.FloatType)
+	ConfigRateLimitRequestsBurst     = ffc("config.rateLimit.requestsBurst", "The maximum number of JSON/RPC requests a client can make at once, before being limited to the sustained rate", i18n.IntType)
+	ConfigRateLimitSigningPerSecond  = ffc("config.rateLimit.signingPerSecond", "The sustained rate of signing operations allowed for each client. Set to 0 for no limit", i18n.FloatType)
+	ConfigRateLimitSigningBurst      = ffc("config.rateLimit.signingBurst", "The maximum number of signing operations a client can make at once, before being limited to the sustained rate", i18n.IntType)
+	ConfigRateLimitIdleTimeout       = ffc("config.rateLimit.idleTimeout", "How long the rate limit state of a client is retained after its last request", i18n.TimeDurationType)
+
+	ConfigPersonalSignEnabled = ffc("config.personalSign.enabled", "When true, personal_sign requests are signed with the EIP-191 message prefix by the wallet. Set to false in high-security deployments to reject personal_sign, so keys can only be used to sign transactions", "boolean")
+
+	ConfigDangerousMethodsEthSign = ffc("config.dangerousMethods.ethSign", "When true, the legacy eth_sign method signs any 32 byte digest supplied by the caller. This is dangerous, as the digest could be the hash of a transaction, so it is off by default. Every use is audit logged", "boolean")
+
+	ConfigPreflightEnabled  = ffc("config.preflight.enabled", "When true, each eth_sendTransaction is simulated with eth_call (with the same from, to, data and value) before it is signed. A transaction that would revert is rejected with the decoded revert reason, rather than being submitted", "boolean")
+	ConfigPreflightBlockTag = ffc("config.preflight.blockTag", "The block tag the pre-flight eth_call is made against", "string")
+
+	ConfigGasEstimateEnabled    = ffc("config.gasEstimate.enabled", "When true, the gas limit of each eth_sendTransaction that does not specify one is populated with eth_estimateGas before it is signed. A transaction the node cannot estimate is rejected, with the decoded revert reason", "boolean")
+	ConfigGasEstimateMultiplier = ffc("config.gasEstimate.multiplier", "The estimated gas is multiplied by this safety margin (rounding up), as the gas used can change before the transaction is mined. Must be at least 1", "number")
+	ConfigGasEstimateCap        = ffc("config.gasEstimate.cap", "The maximum gas limit populated from an estimate, after the multiplier is applied. A transaction estimated to need more gas than this is rejected. Set to 0 for no cap", "number")
+
+	ConfigFeesEnabled               = ffc("config.fees.enabled", "When true, the EIP-1559 maxFeePerGas and maxPriorityFeePerGas of each eth_sendTransaction that does not specify its fees are populated from the recent fees of the chain. On chains without EIP-1559 the legacy gasPrice is populated from eth_gasPrice instead", "boolean")
+	ConfigFeesStrategy              = ffc("config.fees.strategy", "How the priority fee is chosen. 'feeHistory' averages a percentile of the priority fees paid in recent blocks, from eth_feeHistory. 'maxPriorityFeePerGas' uses the suggestion of the node, from eth_maxPriorityFeePerGas", "string")
+	ConfigFeesHistoryBlocks         = ffc("config.fees.feeHistory.blocks", "The number of recent blocks the priority fee is chosen from, with the feeHistory strategy", "number")
+	ConfigFeesHistoryPercentile     = ffc("config.fees.feeHistory.percentile", "The percentile (0-100) of the priority fees paid in each recent block that is used, with the feeHistory strategy", "number")
+	ConfigFeesBaseFeeMultiplier     = ffc("config.fees.baseFeeMultiplier", "The maxFeePerGas is the base fee of the next block multiplied by this, plus the priority fee, to allow for the base fee rising before the transaction is mined. Must be at least 1", "number")
+	ConfigFeesOracleMode            = ffc("config.fees.oracleMode", "How the fees of the sources in fees.oracles are combined. 'fallback' uses the first source, in order, that returns fees. 'weighted' queries every source, and averages each fee over the sources that return it by their weights", "string")
+	ConfigFeesOraclesType           = ffc("config.fees.oracles[].type", "The type of gas price source. 'node' estimates the fees from the backend, with fees.strategy. 'etherscan' is the gas oracle of an Etherscan compatible API, 'blocknative' the Blocknative gas price API, and 'gasStation' a gas station with the v2 API of the Polygon gas station. When no sources are configured, the fees are estimated from the backend", "string")
+	ConfigFeesOraclesURL            = ffc("config.fees.oracles[].url", "The URL of the API of the gas oracle, such as https://api.etherscan.io/api, https://api.blocknative.com/gasprices/blockprices or https://gasstation.polygon.technology/v2. Not used by the node source", "url")
+	ConfigFeesOraclesAPIKey         = ffc("config.fees.oracles[].apiKey", "The API key of the gas oracle, sent as the apikey query parameter to Etherscan and the Authorization header to Blocknative", "string")
+	ConfigFeesOraclesHeaders        = ffc("config.fees.oracles[].headers", "Additional HTTP headers sent to the gas oracle", i18n.MapStringStringType)
+	ConfigFeesOraclesRequestTimeout = ffc("config.fees.oracles[].requestTimeout", "The timeout of requests to the gas oracle", i18n.TimeDurationType)
+	ConfigFeesOraclesSpeed          = ffc("config.fees.oracles[].speed", "The tier of fees used from the gas oracle - 'safe', 'standard' or 'fast'", "string")
+	ConfigFeesOraclesWeight         = ffc("config.fees.oracles[].weight", "The weight of the source in the average of the fees, with the weighted oracleMode. Must be greater than zero", "number")
+
+	ConfigFeeCapsMaxGasPrice  = ffc("config.feeCaps.maxGasPrice", "The maximum legacy gasPrice of any transaction that is signed, in wei (decimal, or hex with a 0x prefix). No cap when not set", "string")
+	ConfigFeeCapsMaxFeePerGas = ffc("config.feeCaps.maxFeePerGas", "The maximum EIP-1559 maxFeePerGas of any transaction that is signed, in wei (decimal, or hex with a 0x prefix). No cap when not set", "string")
+	ConfigFeeCapsMaxTotalFee  = ffc("config.feeCaps.maxTotalFee", "The maximum total fee of any transaction that is signed - the gas limit multiplied by the gasPrice or maxFeePerGas - in wei (decimal, or hex with a 0x prefix). No cap when not set", "string")
+	ConfigFeeCapsPolicy       = ffc("config.feeCaps.policy", "What happens to a transaction over a fee cap. 'reject' fails the request, and 'clamp' reduces the fees to the cap before signing", "string")
+
+	ConfigTxPolicyAllowedDestinations               = ffc("config.txPolicy.allowedDestinations", "The only addresses transactions may be sent to. Any destination is allowed when not set", i18n.ArrayStringType)
+	ConfigTxPolicyAllowDeployments                  = ffc("config.txPolicy.allowDeployments", "Whether transactions with no destination, which deploy a contract, are allowed", "boolean")
+	ConfigTxPolicyMaxValue                          = ffc("config.txPolicy.maxValue", "The maximum value of any transaction, in wei (decimal, or hex with a 0x prefix). No maximum when not set", "string")
+	ConfigTxPolicyRawTransactionsEnabled            = ffc("config.txPolicy.rawTransactions.enabled", "When true, each eth_sendRawTransaction is decoded and its sender recovered, and it must pass the same checks as eth_sendTransaction before it is forwarded - the chain ID, access control on the sender, the transaction policies and the fee caps. Fee caps always reject a raw transaction, as it cannot be changed without being signed again", "boolean")
+	ConfigTxPolicyRawTransactionsManagedSendersOnly = ffc("config.txPolicy.rawTransactions.managedSendersOnly", "When true, raw transactions are rejected unless their sender is a key in the wallet", "boolean")
+	ConfigTxValidationEnabled                       = ffc("config.txValidation.enabled", "When true, the transaction of each eth_sendTransaction, eth_fillTransaction and ffsigner_previewTransaction, and of each SignTransaction of the embedded service, is checked against a schema before it is parsed - rejecting unknown fields, gasPrice set together with the EIP-1559 fee fields, and badly formatted quantities, addresses and data with an error naming the field", "boolean")
+	ConfigTxValidationAllowUnknownFields            = ffc("config.txValidation.allowUnknownFields", "When true, fields the signer does not use (such as 'input', 'type' or 'accessList') are allowed in transactions, rather than rejected. They are ignored when the transaction is signed", "boolean")
+
+	ConfigENSEnabled              = ffc("config.ens.enabled", "When true, the to address of each eth_sendTransaction, eth_fillTransaction and ffsigner_previewTransaction can be an ENS name, such as 'vitalik.eth', which is resolved to an address with the backend before the transaction is checked. ENSIP-10 wildcard resolvers are supported", "boolean")
+	ConfigENSRegistry             = ffc("config.ens.registry", "The address of the ENS registry, on the chain of the backend", "string")
+	ConfigENSCCIPReadEnabled      = ffc("config.ens.ccipRead.enabled", "When true, resolvers that answer from an offchain gateway with an OffchainLookup revert (EIP-3668 CCIP-Read) are followed, fetching the answer from the gateway over HTTPS", "boolean")
+	ConfigENSCCIPReadAllowedHosts = ffc("config.ens.ccipRead.allowedHosts", "The hosts of the gateways that offchain lookups can fetch from. Any host can be fetched from when empty, and as the resolver chooses the gateway, this should be set where the signer can reach hosts that are not public", i18n.ArrayStringType)
+
+	ConfigAccessLogEnabled           = ffc("config.accessLog.enabled", "When true, a structured JSON access log entry is written to stdout for every JSON/RPC request, with the method, outcome, duration, caller and correlation ID. Raw signed payloads, and passphrases and other key material, are always redacted", "boolean")
+	ConfigAccessLogVerbosity         = ffc("config.accessLog.verbosity", "What is included in each access log entry. 'summary' logs the request without its params or result, and 'full' also logs the params and result, after redaction", "string")
+	ConfigAccessLogCorrelationHeader = ffc("config.accessLog.correlationHeader", "The HTTP header carrying the correlation ID of a request, which is included in every log entry for the request and echoed back to the client in the response. A new ID is generated when the client does not supply a valid one", "string")
+
+	ConfigResponseCacheEnabled              = ffc("config.responseCache.enabled", "When true, the results of read methods that cannot change are cached for a short time, to reduce the load on the backend from clients polling for the same data. The chain ID, eth_call and eth_getCode at a fixed block number or hash, deployed contract code, and the receipts of transactions in blocks with enough confirmations are cached. Requests are still authorized and rate limited before a cached result is returned", "boolean")
+	ConfigResponseCacheTTL                  = ffc("config.responseCache.ttl", "How long a result is cached for", i18n.TimeDurationType)
+	ConfigResponseCacheMaxEntries           = ffc("config.responseCache.maxEntries", "The maximum number of results cached at once. Further results are not cached until older ones expire", i18n.IntType)
+	ConfigResponseCacheReceiptConfirmations = ffc("config.responseCache.receiptConfirmations", "How many blocks must be mined on top of the block containing a transaction before its receipt is cached, so receipts that could be reorganized out of the chain are not. Set to 0 on chains with instant finality", i18n.IntType)
+
+	ConfigSenderQueueEnabled    = ffc("config.senderQueue.enabled", "When true, eth_sendTransaction requests from the same address are signed and submitted one at a time, in the order they arrived, so their nonces are assigned and reach the node in order. Requests from different addresses are still processed concurrently", "boolean")
+	ConfigSenderQueueMaxPending = ffc("config.senderQueue.maxPending", "The most requests from one address waiting for their turn. Further requests are rejected with JSON/RPC error -32005 until the queue drains. Requests also stop waiting when they time out, or the client disconnects", i18n.IntType)
+
+	ConfigPersistencePath = ffc("config.persistence.path", "A directory shared by every component that persists state across restarts - the next nonce for each address, and admin key ceremonies - unless the component sets a path of its own. An application embedding the signer can provide its own persistence instead, such as a database", "string")
+
+	ConfigSigningApprovalTimeout = ffc("config.signingApproval.timeout", "How long a signing request deferred by the signing approval hook of an embedding application waits for the decision, before it is rejected. The request also stops waiting when it times out, or the client disconnects", i18n.TimeDurationType)
+
+	ConfigResubmitEnabled        = ffc("config.resubmit.enabled", "When true, transactions signed and submitted by eth_sendTransaction are tracked until they are mined, and resubmitted when they are not mined within the delay. Every resubmission is logged, and posted to the audit webhook when fees are bumped", "boolean")
+	ConfigResubmitPolicy         = ffc("config.resubmit.policy", "How a stuck transaction is resubmitted. 'rebroadcast' sends the same signed transaction to the node again, for transactions dropped from its pool. 'feeBump' signs the transaction again with higher fees, subject to any feeCaps, so it replaces the stuck transaction", "string")
+	ConfigResubmitDelay          = ffc("config.resubmit.delay", "How long a transaction can go without being mined after it is submitted (or last resubmitted) before it is resubmitted", i18n.TimeDurationType)
+	ConfigResubmitInterval       = ffc("config.resubmit.interval", "How often tracked transactions are checked with eth_getTransactionCount, to find those that have been mined and those that are stuck", i18n.TimeDurationType)
+	ConfigResubmitFeeBumpPercent = ffc("config.resubmit.feeBumpPercent", "The percentage the gasPrice, or maxFeePerGas and maxPriorityFeePerGas, are increased by on each fee bump. Most nodes require at least 10 to replace a transaction in their pool", i18n.IntType)
+	ConfigResubmitMaxAttempts    = ffc("config.resubmit.maxAttempts", "The most times a transaction is resubmitted. A transaction that is still not mined after this is logged as abandoned, and no longer tracked", i18n.IntType)
+	ConfigResubmitMaxTracked     = ffc("config.resubmit.maxTracked", "The most transactions tracked at once. Transactions submitted while this many are tracked are not resubmitted if they get stuck", i18n.IntType)
+
+	ConfigTimeoutsDefault          = ffc("config.timeouts.default", "The maximum time the proxy spends on a JSON/RPC request whose method has no override, after which any call to the backend is canceled and the request fails with error code -32002. Set to 0 for no timeout. The requestTimeout of the backend also applies to each call to the backend", i18n.TimeDurationType)
+	ConfigTimeoutsOverridesMethods = ffc("config.timeouts.overrides[].methods", "The JSON/RPC methods the timeout applies to. Supports wildcard patterns such as 'debug_*'. The first override matching a method is used", i18n.ArrayStringType)
+	ConfigTimeoutsOverridesTimeout = ffc("config.timeouts.overrides[].timeout", "The maximum time the proxy spends on a request for one of the methods", i18n.TimeDurationType)
+
+	ConfigChainsHeader          = ffc("config.chains.header", "The HTTP header a request can select the chain it is routed to with, by the name or chain ID of the chain. Chains can also be selected with a /chains/{chain} URL path, and eth_sendTransaction requests that select no chain are routed by the chainId of the transaction", "string")
+	ConfigChainsNetworksName    = ffc("config.chains.networks[].name", "The unique name of an additional chain served by the signer. Requests that select no chain are routed to the backend, and signed for backend.chainId", "string")
+	ConfigChainsNetworksChainID = ffc("config.chains.networks[].chainId", "The chain ID of the chain, which transactions routed to it are signed for. The backend is checked to have this chain ID at startup, unless backend.chainIdValidation.enabled is false", "number")
+	ConfigChainsNetworksURL     = ffc("config.chains.networks[].url", "The HTTP URL of the node for the chain, which shares all other settings of the backend. Subscriptions, local nonce management and the response cache only apply to the backend", "url")
+
+	ConfigFipsEnabled = ffc("config.fips.enabled", "When true, new keys are encrypted with PBKDF2-HMAC-SHA256 and AES-128-CTR, which are FIPS approved, and key files encrypted with scrypt are refused. The compliance status is logged at startup. Always on when built with the fips build tag. The approved algorithms use a FIPS validated module when built with GOEXPERIMENT=boringcrypto", "boolean")
+
+	ConfigNoncesEnabled             = ffc("config.nonces.enabled", "When true, nonces are assigned locally for each signing address, for eth_sendTransaction requests that do not specify a nonce. This ensures concurrent requests for the same address never collide", "boolean")
+	ConfigNoncesPath                = ffc("config.nonces.path", "A directory where the next nonce for each address is persisted, so nonces are not reused after a restart. When not set, nonces are persisted in persistence.path if that is set, otherwise they are only held in memory and are reconciled from the node after a restart", "string")
+	ConfigNoncesReconcileInterval   = ffc("config.nonces.reconcileInterval", "How often the local nonce of an address is reconciled with its pending transaction count from eth_getTransactionCount, to pick up transactions submitted by other means", i18n.TimeDurationType)
+	ConfigNoncesMonitorInterval     = ffc("config.nonces.monitor.interval", "How often every address nonces have been assigned for is checked for gaps (assigned nonces the node has no transaction for) and stalls, which are logged as warnings. Set to zero to disable", i18n.TimeDurationType)
+	ConfigNoncesMonitorStallTimeout = ffc("config.nonces.monitor.stallTimeout", "How long an address can have transactions waiting to be mined, with none mined, before it is reported as stalled", i18n.TimeDurationType)
+
+	ConfigAuthEnabled                = ffc("config.auth.enabled", "Requires every JSON/RPC request, and every WebSocket connection, to be authenticated before any wallet access. Requests without valid credentials are rejected with HTTP 401", "boolean")
+	ConfigAuthAPIKeyHeader           = ffc("config.auth.apiKeyHeader", "The HTTP header callers supply their API key in", "string")
+	ConfigAuthAPIKeysID              = ffc("config.auth.apiKeys[].id", "The identity of callers using this API key", "string")
+	ConfigAuthAPIKeysKey             = ffc("config.auth.apiKeys[].key", "The secret value of the API key", "string")
+	ConfigAuthJWTJWKSURL             = ffc("config.auth.jwt.jwksURL", "The URL of the JSON Web Key Set (JWKS) used to verify the signatures of JWT bearer tokens. JWT authentication is enabled when this is set", "url")
+	ConfigAuthJWTIssuer              = ffc("config.auth.jwt.issuer", "The issuer (iss claim) JWTs must have, when set", "string")
+	ConfigAuthJWTAudience            = ffc("config.auth.jwt.audience", "An audience (aud claim) JWTs must include, when set", "string")
+	ConfigAuthJWTIdentityClaim       = ffc("config.auth.jwt.identityClaim", "The JWT claim that identifies the caller", "string")
+	ConfigAuthJWTJWKSRefreshInterval = ffc("config.auth.jwt.jwksRefreshInterval", "How often the JWKS is refreshed. It is also refreshed when a JWT is signed by an unknown key", i18n.TimeDurationType)
+	ConfigAuthJWTLeeway              = ffc("config.auth.jwt.leeway", "The allowed clock skew when checking the expiry and not-before times of JWTs", i18n.TimeDurationType)
+	ConfigAuthAuthenticators         = ffc("config.auth.authenticators", "The names of custom authenticators, registered with rpcauth.RegisterAuthenticator, to try after the API key and JWT authenticators", i18n.ArrayStringType)
+	ConfigAuthRBACEnabled            = ffc("config.auth.rbac.enabled", "Enables role based access control, so each authenticated identity may only use the JSON/RPC methods and signing addresses granted to it by the policies. Everything else is denied", "boolean")
+	ConfigAuthRBACPoliciesIdentities = ffc("config.auth.rbac.policies[].identities", "The IDs of the identities (API key IDs, or the identity claim of a JWT) the policy applies to, or '*' for all authenticated identities", i18n.ArrayStringType)
+	ConfigAuthRBACPoliciesClaim      = ffc("config.auth.rbac.policies[].claim", "A JWT claim, such as a groups or roles claim, used to select the identities the policy applies to", "string")
+	ConfigAuthRBACPoliciesClaimVals  = ffc("config.auth.rbac.policies[].claimValues", "The policy applies to identities whose claim has, or is an array containing, one of these values", i18n.ArrayStringType)
+	ConfigAuthRBACPoliciesMethods    = ffc("config.auth.rbac.policies[].methods", "The JSON/RPC methods granted by the policy. Supports wildcard patterns such as 'eth_*', or '*' for all methods", i18n.ArrayStringType)
+	ConfigAuthRBACPoliciesAddresses  = ffc("config.auth.rbac.policies[].addresses", "The addresses whose keys may be used by eth_sendTransaction and the other methods that take an address, or '*' for all addresses", i18n.ArrayStringType)
+
+	ConfigMethodsAllow                = ffc("config.methods.allow", "The JSON/RPC methods that are allowed. When set, all other methods are rejected. Supports wildcard patterns such as 'eth_*'", i18n.ArrayStringType)
+	ConfigMethodsDeny                 = ffc("config.methods.deny", "The JSON/RPC methods that are rejected, such as 'debug_*', 'admin_*' and 'txpool_*'. Takes precedence over the allowed methods", i18n.ArrayStringType)
+	ConfigMethodsOverridesIdentities  = ffc("config.methods.overrides[].identities", "The IDs of the authenticated identities whose allowed and denied methods are replaced by this override", i18n.ArrayStringType)
+	ConfigMethodsOverridesClaim       = ffc("config.methods.overrides[].claim", "A JWT claim used to select the identities the override applies to", "string")
+	ConfigMethodsOverridesClaimValues = ffc("config.methods.overrides[].claimValues", "The override applies to identities whose claim has, or is an array containing, one of these values", i18n.ArrayStringType)
+	ConfigMethodsOverridesAllow       = ffc("config.methods.overrides[].allow", "The JSON/RPC methods allowed for the matching identities, in place of methods.allow", i18n.ArrayStringType)
+	ConfigMethodsOverridesDeny        = ffc("config.methods.overrides[].deny", "The JSON/RPC methods rejected for the matching identities, in place of methods.deny", i18n.ArrayStringType)
 )
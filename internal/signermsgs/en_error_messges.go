@@ -27,84 +27,319 @@ var ffe = func(key, translation string, statusHint ...int) i18n.ErrorMessageKey
 
 //revive:disable
 var (
-	MsgInvalidOutputType           = ffe("FF22010", "Invalid output type: %s")
-	MsgInvalidParam                = ffe("FF22011", "Invalid parameter at position %d for method %s: %s")
-	MsgRPCRequestFailed            = ffe("FF22012", "Backend RPC request failed: %s")
-	MsgReadDirFile                 = ffe("FF22013", "Directory listing failed")
-	MsgWalletNotAvailable          = ffe("FF22014", "Wallet for address '%s' not available", 404)
-	MsgWalletFailed                = ffe("FF22015", "Wallet for address '%s' could not be initialized")
-	MsgBadGoTemplate               = ffe("FF22016", "Bad go template for '%s' - try something like '{{ index .signing \"key-file\" }}' syntax")
-	MsgNoWalletEnabled             = ffe("FF22017", "No wallets enabled in configuration")
-	MsgInvalidRequest              = ffe("FF22018", "Invalid request data")
-	MsgInvalidParamCount           = ffe("FF22019", "Invalid number of parameters: expected=%d received=%d")
-	MsgMissingFrom                 = ffe("FF22020", "Missing 'from' address")
-	MsgQueryChainID                = ffe("FF22021", "Failed to query Chain ID")
-	MsgSigningFailed               = ffe("FF22022", "Signing failed: %s")
-	MsgInvalidTransaction          = ffe("FF22023", "Invalid eth_sendTransaction input")
-	MsgMissingRequestID            = ffe("FF22024", "Invalid JSON/RPC request. Must set request ID")
-	MsgUnsupportedABIType          = ffe("FF22025", "Unsupported elementary type '%s' in ABI type '%s'")
-	MsgUnsupportedABISuffix        = ffe("FF22026", "Unsupported type suffix '%s' in ABI type '%s' - expected %s")
-	MsgMissingABISuffix            = ffe("FF22027", "Missing type suffix in ABI type '%s' - expected %s")
-	MsgInvalidABISuffix            = ffe("FF22028", "Invalid suffix in ABI type '%s' - expected %s")
-	MsgInvalidABIArraySpec         = ffe("FF22029", "Invalid array suffix in ABI type '%s'")
-	MsgInvalidIntegerABIInput      = ffe("FF22030", "Unable to parse '%v' of type %T as integer for component %s")
-	MsgInvalidFloatABIInput        = ffe("FF22031", "Unable to parse '%v' of type %T as floating point number for component %s")
-	MsgInvalidStringABIInput       = ffe("FF22032", "Unable to parse '%v' of type %T as string for component %s")
-	MsgInvalidBoolABIInput         = ffe("FF22033", "Unable to parse '%v' of type %T as boolean for component %s")
-	MsgInvalidHexABIInput          = ffe("FF22034", "Unable to parse input of type %T as hex for component %s")
-	MsgMustBeSliceABIInput         = ffe("FF22035", "Unable to parse input of type %T for component %s - must be an array")
-	MsgFixedLengthABIArrayMismatch = ffe("FF22036", "Input array is length %d, and required fixed array length is %d for component %s")
-	MsgTupleABIArrayMismatch       = ffe("FF22037", "Input array is length %d, and required tuple component count is %d for component %s")
-	MsgTupleABINotArrayOrMap       = ffe("FF22038", "Input type %T is not array or map for component %s")
-	MsgMissingInputKeyABITuple     = ffe("FF22040", "Input map missing key '%s' required for tuple component %s")
-	MsgBadABITypeComponent         = ffe("FF22041", "Bad ABI type component: %d")
-	MsgWrongTypeComponentABIEncode = ffe("FF22042", "Incorrect type expected=%s found=%T for ABI encoding of component %s")
-	MsgInsufficientDataABIEncode   = ffe("FF22043", "Insufficient data elements on input expected=%d found=%d for ABI encoding of component %s")
-	MsgNumberTooLargeABIEncode     = ffe("FF22044", "Numeric value does not fit in bit length %d for ABI encoding of component %s")
-	MsgNotEnoughBytesABIArrayCount = ffe("FF22045", "Insufficient bytes to read array index for component %s")
-	MsgABIArrayCountTooLarge       = ffe("FF22046", "Array index %s too large for component %s")
-	MsgNotEnoughBytesABIValue      = ffe("FF22047", "Insufficient bytes to read %s value %s")
-	MsgNotEnoughBytesABISignature  = ffe("FF22048", "Insufficient bytes to read signature")
-	MsgIncorrectABISignatureID     = ffe("FF22049", "Incorrect ID for signature %s expected=%s found=%s")
-	MsgUnknownABIElementaryType    = ffe("FF22050", "Unknown elementary type %s for component %s")
-	MsgUnknownTupleSerializer      = ffe("FF22051", "Unknown tuple serialization option %d")
-	MsgInvalidFFIDetailsSchema     = ffe("FF22052", "Invalid FFI details schema for '%s'")
-	MsgEventsInsufficientTopics    = ffe("FF22053", "Ran out of topics for indexed fields at field %d of %s")
-	MsgEventSignatureMismatch      = ffe("FF22054", "Event signature mismatch for '%s': expected='%s' found='%s'")
-	MsgFFITypeMismatch             = ffe("FF22055", "Input type '%s' is not valid for ABI type '%s'")
-	MsgBadRegularExpression        = ffe("FF22056", "Bad regular expression for /%s/: %s")
-	MsgMissingRegexpCaptureGroup   = ffe("FF22057", "Regular expression is missing a capture group (subexpression) for address: /%s/")
-	MsgAddressMismatch             = ffe("FF22059", "Address '%s' loaded from wallet file does not match requested lookup address / filename '%s'")
-	MsgFailedToStartListener       = ffe("FF22060", "Failed to start filesystem listener: %s")
-	MsgDecodeNotTuple              = ffe("FF22061", "Decode can only be called against a root tuple component type=%d")
-	MsgNegativeUnsignedABIEncode   = ffe("FF22062", "Negative numeric value is invalid for component %s")
-	MsgRequestCanceledContext      = ffe("FF22063", "Request with id %s failed due to canceled context")
-	MsgInvalidSigner               = ffe("FF22064", "Invalid signer")
-	MsgResultParseFailed           = ffe("FF22065", "Failed to parse result (expected=%T): %s")
-	MsgSubscribeResponseInvalid    = ffe("FF22066", "Subscription response invalid")
-	MsgWebSocketReconnected        = ffe("FF22067", "WebSocket reconnected during JSON/RPC call")
-	MsgContextCancelledWSConnect   = ffe("FF22068", "Context canceled while connecting WebSocket")
-	MsgNotElementary               = ffe("FF22069", "Not elementary type: %s")
-	MsgEIP712UnknownABICompType    = ffe("FF22070", "Unknown ABI component type: %s")
-	MsgEIP712UnsupportedStrType    = ffe("FF22071", "Unsupported type: %s")
-	MsgEIP712UnsupportedABIType    = ffe("FF22072", "ABI type not supported by EIP-712 encoding: %s")
-	MsgEIP712TypeNotFound          = ffe("FF22073", "Type '%s' not found in type map")
-	MsgEIP712PrimaryNotTuple       = ffe("FF22074", "Type primary type must be a struct/tuple: %s")
-	MsgEIP712BadInternalType       = ffe("FF22075", "Failed to extract struct name from ABI internalType '%s'")
-	MsgEIP712ValueNotMap           = ffe("FF22076", "Value for struct '%s' not a map (%T)")
-	MsgEIP712InvalidArraySuffix    = ffe("FF22077", "Type '%s' has invalid array suffix")
-	MsgEIP712ValueNotArray         = ffe("FF22078", "Value for '%s' not an array (%T)")
-	MsgEIP712InvalidArrayLen       = ffe("FF22079", "Value for '%s' must have %d entries (found %d)")
-	MsgEIP712PrimaryTypeRequired   = ffe("FF22080", "Primary type must be specified")
-	MsgEmptyTransactionBytes       = ffe("FF22081", "Transaction payload is empty")
-	MsgUnsupportedTransactionType  = ffe("FF22082", "Unsupported transaction type 0x%02x")
-	MsgInvalidLegacyTransaction    = ffe("FF22083", "Transaction payload invalid (legacy): %v")
-	MsgInvalidEIP1559Transaction   = ffe("FF22084", "Transaction payload invalid (EIP-1559): %v")
-	MsgInvalidEIP155TransactionV   = ffe("FF22085", "Invalid V value from EIP-155 transaction (chainId=%d)")
-	MsgInvalidChainID              = ffe("FF22086", "Invalid chainId expected=%d actual=%d")
-	MsgSigningInvalidCompactRSV    = ffe("FF22087", "Invalid signature data (compact R,S,V) length=%d (expected=65)")
-	MsgInvalidNumberString         = ffe("FF22088", "Invalid integer string '%s'")
-	MsgInvalidIntPrecisionLoss     = ffe("FF22089", "String %s cannot be converted to integer without losing precision")
-	MsgInvalidUint64PrecisionLoss  = ffe("FF22090", "String %s cannot be converted to a uint64 without losing precision")
-	MsgInvalidJSONTypeForBigInt    = ffe("FF22091", "JSON parsed '%T' cannot be converted to an integer")
+	MsgInvalidOutputType               = ffe("FF22010", "Invalid output type: %s")
+	MsgInvalidParam                    = ffe("FF22011", "Invalid parameter at position %d for method %s: %s")
+	MsgRPCRequestFailed                = ffe("FF22012", "Backend RPC request failed: %s")
+	MsgReadDirFile                     = ffe("FF22013", "Directory listing failed")
+	MsgWalletNotAvailable              = ffe("FF22014", "Wallet for address '%s' not available", 404)
+	MsgWalletFailed                    = ffe("FF22015", "Wallet for address '%s' could not be initialized")
+	MsgBadGoTemplate                   = ffe("FF22016", "Bad go template for '%s' - try something like '{{ index .signing \"key-file\" }}' syntax")
+	MsgNoWalletEnabled                 = ffe("FF22017", "No wallets enabled in configuration")
+	MsgInvalidRequest                  = ffe("FF22018", "Invalid request data")
+	MsgInvalidParamCount               = ffe("FF22019", "Invalid number of parameters: expected=%d received=%d")
+	MsgMissingFrom                     = ffe("FF22020", "Missing 'from' address")
+	MsgQueryChainID                    = ffe("FF22021", "Failed to query Chain ID")
+	MsgSigningFailed                   = ffe("FF22022", "Signing failed: %s")
+	MsgInvalidTransaction              = ffe("FF22023", "Invalid eth_sendTransaction input")
+	MsgMissingRequestID                = ffe("FF22024", "Invalid JSON/RPC request. Must set request ID")
+	MsgUnsupportedABIType              = ffe("FF22025", "Unsupported elementary type '%s' in ABI type '%s'")
+	MsgUnsupportedABISuffix            = ffe("FF22026", "Unsupported type suffix '%s' in ABI type '%s' - expected %s")
+	MsgMissingABISuffix                = ffe("FF22027", "Missing type suffix in ABI type '%s' - expected %s")
+	MsgInvalidABISuffix                = ffe("FF22028", "Invalid suffix in ABI type '%s' - expected %s")
+	MsgInvalidABIArraySpec             = ffe("FF22029", "Invalid array suffix in ABI type '%s'")
+	MsgInvalidIntegerABIInput          = ffe("FF22030", "Unable to parse '%v' of type %T as integer for component %s")
+	MsgInvalidFloatABIInput            = ffe("FF22031", "Unable to parse '%v' of type %T as floating point number for component %s")
+	MsgInvalidStringABIInput           = ffe("FF22032", "Unable to parse '%v' of type %T as string for component %s")
+	MsgInvalidBoolABIInput             = ffe("FF22033", "Unable to parse '%v' of type %T as boolean for component %s")
+	MsgInvalidHexABIInput              = ffe("FF22034", "Unable to parse input of type %T as hex for component %s")
+	MsgMustBeSliceABIInput             = ffe("FF22035", "Unable to parse input of type %T for component %s - must be an array")
+	MsgFixedLengthABIArrayMismatch     = ffe("FF22036", "Input array is length %d, and required fixed array length is %d for component %s")
+	MsgTupleABIArrayMismatch           = ffe("FF22037", "Input array is length %d, and required tuple component count is %d for component %s")
+	MsgTupleABINotArrayOrMap           = ffe("FF22038", "Input type %T is not array or map for component %s")
+	MsgMissingInputKeyABITuple         = ffe("FF22040", "Input map missing key '%s' required for tuple component %s")
+	MsgBadABITypeComponent             = ffe("FF22041", "Bad ABI type component: %d")
+	MsgWrongTypeComponentABIEncode     = ffe("FF22042", "Incorrect type expected=%s found=%T for ABI encoding of component %s")
+	MsgInsufficientDataABIEncode       = ffe("FF22043", "Insufficient data elements on input expected=%d found=%d for ABI encoding of component %s")
+	MsgNumberTooLargeABIEncode         = ffe("FF22044", "Numeric value does not fit in bit length %d for ABI encoding of component %s")
+	MsgNotEnoughBytesABIArrayCount     = ffe("FF22045", "Insufficient bytes to read array index for component %s")
+	MsgABIArrayCountTooLarge           = ffe("FF22046", "Array index %s too large for component %s")
+	MsgNotEnoughBytesABIValue          = ffe("FF22047", "Insufficient bytes to read %s value %s")
+	MsgNotEnoughBytesABISignature      = ffe("FF22048", "Insufficient bytes to read signature")
+	MsgIncorrectABISignatureID         = ffe("FF22049", "Incorrect ID for signature %s expected=%s found=%s")
+	MsgUnknownABIElementaryType        = ffe("FF22050", "Unknown elementary type %s for component %s")
+	MsgUnknownTupleSerializer          = ffe("FF22051", "Unknown tuple serialization option %d")
+	MsgInvalidFFIDetailsSchema         = ffe("FF22052", "Invalid FFI details schema for '%s'")
+	MsgEventsInsufficientTopics        = ffe("FF22053", "Ran out of topics for indexed fields at field %d of %s")
+	MsgEventSignatureMismatch          = ffe("FF22054", "Event signature mismatch for '%s': expected='%s' found='%s'")
+	MsgFFITypeMismatch                 = ffe("FF22055", "Input type '%s' is not valid for ABI type '%s'")
+	MsgBadRegularExpression            = ffe("FF22056", "Bad regular expression for /%s/: %s")
+	MsgMissingRegexpCaptureGroup       = ffe("FF22057", "Regular expression is missing a capture group (subexpression) for address: /%s/")
+	MsgAddressMismatch                 = ffe("FF22059", "Address '%s' loaded from wallet file does not match requested lookup address / filename '%s'")
+	MsgFailedToStartListener           = ffe("FF22060", "Failed to start filesystem listener: %s")
+	MsgDecodeNotTuple                  = ffe("FF22061", "Decode can only be called against a root tuple component type=%d")
+	MsgNegativeUnsignedABIEncode       = ffe("FF22062", "Negative numeric value is invalid for component %s")
+	MsgRequestCanceledContext          = ffe("FF22063", "Request with id %s failed due to canceled context")
+	MsgInvalidSigner                   = ffe("FF22064", "Invalid signer")
+	MsgResultParseFailed               = ffe("FF22065", "Failed to parse result (expected=%T): %s")
+	MsgSubscribeResponseInvalid        = ffe("FF22066", "Subscription response invalid")
+	MsgWebSocketReconnected            = ffe("FF22067", "WebSocket reconnected during JSON/RPC call")
+	MsgContextCancelledWSConnect       = ffe("FF22068", "Context canceled while connecting WebSocket")
+	MsgNotElementary                   = ffe("FF22069", "Not elementary type: %s")
+	MsgEIP712UnknownABICompType        = ffe("FF22070", "Unknown ABI component type: %s")
+	MsgEIP712UnsupportedStrType        = ffe("FF22071", "Unsupported type: %s")
+	MsgEIP712UnsupportedABIType        = ffe("FF22072", "ABI type not supported by EIP-712 encoding: %s")
+	MsgEIP712TypeNotFound              = ffe("FF22073", "Type '%s' not found in type map")
+	MsgEIP712PrimaryNotTuple           = ffe("FF22074", "Type primary type must be a struct/tuple: %s")
+	MsgEIP712BadInternalType           = ffe("FF22075", "Failed to extract struct name from ABI internalType '%s'")
+	MsgEIP712ValueNotMap               = ffe("FF22076", "Value for struct '%s' not a map (%T)")
+	MsgEIP712InvalidArraySuffix        = ffe("FF22077", "Type '%s' has invalid array suffix")
+	MsgEIP712ValueNotArray             = ffe("FF22078", "Value for '%s' not an array (%T)")
+	MsgEIP712InvalidArrayLen           = ffe("FF22079", "Value for '%s' must have %d entries (found %d)")
+	MsgEIP712PrimaryTypeRequired       = ffe("FF22080", "Primary type must be specified")
+	MsgEmptyTransactionBytes           = ffe("FF22081", "Transaction payload is empty")
+	MsgUnsupportedTransactionType      = ffe("FF22082", "Unsupported transaction type 0x%02x")
+	MsgInvalidLegacyTransaction        = ffe("FF22083", "Transaction payload invalid (legacy): %v")
+	MsgInvalidEIP1559Transaction       = ffe("FF22084", "Transaction payload invalid (EIP-1559): %v")
+	MsgInvalidEIP155TransactionV       = ffe("FF22085", "Invalid V value from EIP-155 transaction (chainId=%d)")
+	MsgInvalidChainID                  = ffe("FF22086", "Invalid chainId expected=%d actual=%d")
+	MsgSigningInvalidCompactRSV        = ffe("FF22087", "Invalid signature data (compact R,S,V) length=%d (expected=65)")
+	MsgInvalidNumberString             = ffe("FF22088", "Invalid integer string '%s'")
+	MsgInvalidIntPrecisionLoss         = ffe("FF22089", "String %s cannot be converted to integer without losing precision")
+	MsgInvalidUint64PrecisionLoss      = ffe("FF22090", "String %s cannot be converted to a uint64 without losing precision")
+	MsgInvalidJSONTypeForBigInt        = ffe("FF22091", "JSON parsed '%T' cannot be converted to an integer")
+	MsgInvalidShardPrefixLength        = ffe("FF22092", "Invalid shard prefix length %d (must be between 0 and %d)")
+	MsgMigrateWalletFileFailed         = ffe("FF22093", "Failed to move wallet file '%s' to '%s'")
+	MsgWalletLocked                    = ffe("FF22094", "Wallet for address '%s' is locked", 403)
+	MsgWalletUnlockNotSupported        = ffe("FF22095", "The configured wallet does not support unlocking and locking keys")
+	MsgPublicKeysNotSupported          = ffe("FF22096", "The configured wallet does not support retrieving public keys")
+	MsgSubscriptionsNotSupported       = ffe("FF22097", "Subscriptions are only supported on WebSocket connections, with a WebSocket backend")
+	MsgBatchResponseMissing            = ffe("FF22098", "No response was returned in the batch for request %s")
+	MsgBatchDispatcherStopped          = ffe("FF22099", "Request with id %s failed as the batch dispatcher has stopped")
+	MsgFailoverMixedSchemes            = ffe("FF22100", "Backend failover URL '%s' must use the same kind of connection (HTTP, or WebSocket/IPC) as the primary backend URL")
+	MsgUnauthenticated                 = ffe("FF22101", "Authentication required", 401)
+	MsgAuthenticationFailed            = ffe("FF22102", "Authentication failed", 401)
+	MsgNoAuthenticators                = ffe("FF22103", "Authentication is enabled, but no API keys, JWT JWKS URL, or authenticators are configured")
+	MsgUnknownAuthenticator            = ffe("FF22104", "Unknown authenticator '%s'")
+	MsgJWKSFetchFailed                 = ffe("FF22105", "Failed to fetch JWKS from '%s': %s")
+	MsgAPIKeyMissingFields             = ffe("FF22106", "API key entry %d must have both an id and a key")
+	MsgJWTUnknownKeyID                 = ffe("FF22107", "No JWKS key found for key ID '%s'")
+	MsgJWTMissingIdentityClaim         = ffe("FF22108", "JWT does not contain a string '%s' claim identifying the caller")
+	MsgRBACRequiresAuth                = ffe("FF22109", "Authentication must be enabled (auth.enabled) to use role based access control")
+	MsgMethodNotAuthorized             = ffe("FF22110", "Method '%s' is not authorized for '%s'", 403)
+	MsgAddressNotAuthorized            = ffe("FF22111", "Address '%s' is not authorized for '%s'", 403)
+	MsgInvalidRBACPolicy               = ffe("FF22112", "Access control policy %d is invalid: %s")
+	MsgRateLimitExceeded               = ffe("FF22113", "Rate limit exceeded for %s", 429)
+	MsgPersonalSignDisabled            = ffe("FF22114", "personal_sign is disabled on this server")
+	MsgPersonalSignNotSupported        = ffe("FF22115", "The configured wallet does not support personal_sign")
+	MsgInvalidDigestLength             = ffe("FF22116", "Invalid digest length %d (expected=32)")
+	MsgEthSignDisabled                 = ffe("FF22117", "eth_sign is disabled on this server")
+	MsgDigestSignNotSupported          = ffe("FF22118", "The configured wallet does not support eth_sign")
+	MsgInvalidMethodPattern            = ffe("FF22119", "Invalid method pattern '%s': %s")
+	MsgInvalidMethodOverride           = ffe("FF22120", "Method override %d is invalid: %s")
+	MsgMethodNotAllowed                = ffe("FF22121", "Method '%s' is not allowed", 403)
+	MsgPreflightFailed                 = ffe("FF22122", "Transaction rejected, as it failed when simulated with eth_call: %s")
+	MsgNonceStoreInitFailed            = ffe("FF22123", "Failed to initialize nonce store directory '%s': %s")
+	MsgNonceStoreReadFailed            = ffe("FF22124", "Failed to read the next nonce for '%s': %s")
+	MsgNonceStoreWriteFailed           = ffe("FF22125", "Failed to persist the next nonce for '%s': %s")
+	MsgNonceManagementDisabled         = ffe("FF22126", "Local nonce management is not enabled on this server", 404)
+	MsgNonceGapFillFailed              = ffe("FF22127", "Failed to fill the gap at nonce %d for '%s': %s")
+	MsgGasEstimateFailed               = ffe("FF22128", "Transaction rejected, as the gas could not be estimated with eth_estimateGas: %s")
+	MsgGasEstimateExceedsCap           = ffe("FF22129", "Transaction rejected, as the estimated gas %d exceeds the cap of %d")
+	MsgBadGasEstimateMultiplier        = ffe("FF22130", "Invalid gas estimate multiplier %v (must be at least 1)")
+	MsgUnknownFeeStrategy              = ffe("FF22131", "Unknown fee strategy '%s'")
+	MsgBadFeeHistoryPercentile         = ffe("FF22132", "Invalid fee history percentile %v (must be between 0 and 100)")
+	MsgBadBaseFeeMultiplier            = ffe("FF22133", "Invalid base fee multiplier %v (must be at least 1)")
+	MsgBadFeeCap                       = ffe("FF22134", "Invalid fee cap %s '%s'")
+	MsgUnknownFeeCapPolicy             = ffe("FF22135", "Unknown fee cap policy '%s'")
+	MsgFeeCapExceeded                  = ffe("FF22136", "Transaction rejected, as its %s of %s wei exceeds the cap of %s wei")
+	MsgChainIDMismatch                 = ffe("FF22137", "Configured chain ID %d does not match the chain ID %d of the backend")
+	MsgChainIDMismatchRefused          = ffe("FF22138", "Transaction refused, as the chain ID %d of the backend does not match the chain ID %d of the signer")
+	MsgTxnChainIDMismatch              = ffe("FF22139", "Transaction chain ID %d does not match the chain ID %d of the signer")
+	MsgUnknownAccessLogVerbosity       = ffe("FF22140", "Unknown access log verbosity '%s' - must be 'summary' or 'full'")
+	MsgNoServerListeners               = ffe("FF22141", "The JSON/RPC server must listen on TCP, or on a UNIX domain socket")
+	MsgBadUnixSocketMode               = ffe("FF22142", "Invalid UNIX domain socket mode '%s' - must be octal file permissions such as 0600")
+	MsgUnixSocketListenFailed          = ffe("FF22143", "Failed to listen on UNIX domain socket '%s'")
+	MsgUnixSocketPathInUse             = ffe("FF22144", "Cannot listen on UNIX domain socket '%s', as something other than a socket exists at that path")
+	MsgConfigReloadFailed              = ffe("FF22145", "Configuration reload failed, so the previous configuration remains in effect")
+	MsgBackendReloadUnsupported        = ffe("FF22146", "Changes to the backend URLs require a restart when the backend is a WebSocket or IPC socket")
+	MsgBadResponseCacheConfig          = ffe("FF22147", "Invalid response cache configuration - ttl and maxEntries must be greater than zero")
+	MsgInvalidTimeoutOverride          = ffe("FF22148", "Timeout override %d is invalid: %s")
+	MsgRequestTimedOut                 = ffe("FF22149", "Request for method '%s' timed out after %s")
+	MsgCircuitBreakerOpen              = ffe("FF22150", "Backend is unavailable, as its circuit breaker is open after too many failed requests")
+	MsgBadTxPolicy                     = ffe("FF22151", "Invalid transaction policy %s '%s'")
+	MsgTxDestinationNotAllowed         = ffe("FF22152", "Transaction rejected, as its destination '%s' is not allowed")
+	MsgTxDeploymentNotAllowed          = ffe("FF22153", "Transaction rejected, as contract deployments are not allowed")
+	MsgTxValueExceeded                 = ffe("FF22154", "Transaction rejected, as its value of %s wei exceeds the maximum of %s wei")
+	MsgUnmanagedSender                 = ffe("FF22155", "Transaction rejected, as its sender '%s' is not a key managed by this signer")
+	MsgAdminRequiresAuth               = ffe("FF22156", "Authentication must be enabled (auth.enabled) to use the admin server")
+	MsgBadAddressMetricsLabel          = ffe("FF22157", "Invalid per-address metrics label '%s' with length %d - must be 'full', 'truncated' or 'hashed', with a length of 1-40")
+	MsgIPCConnectFailed                = ffe("FF22158", "Failed to connect to IPC socket '%s'")
+	MsgIPCNotConnected                 = ffe("FF22159", "Not connected to IPC socket '%s'")
+	MsgBadChainNetwork                 = ffe("FF22160", "Invalid chain network %d: %s")
+	MsgUnknownChain                    = ffe("FF22161", "Unknown chain '%s'", 404)
+	MsgChainNetworkConflict            = ffe("FF22162", "Chain '%s' has the chain ID %d of the backend")
+	MsgRequestTooLarge                 = ffe("FF22163", "Request exceeds the maximum size of %d bytes", 413)
+	MsgBatchTooLarge                   = ffe("FF22164", "Batch of %d requests exceeds the maximum of %d", 400)
+	MsgJSONTooDeep                     = ffe("FF22165", "Request exceeds the maximum JSON nesting depth of %d", 400)
+	MsgAuditWebhookNoURL               = ffe("FF22166", "A URL is required for the audit webhook")
+	MsgAuditWebhookBadQueueSize        = ffe("FF22167", "Invalid audit webhook queue size %d - must be greater than zero")
+	MsgAuditWebhookFailed              = ffe("FF22168", "Audit webhook returned HTTP status %d")
+	MsgSenderQueueFull                 = ffe("FF22169", "Too many transactions from %s are waiting to be submitted", 429)
+	MsgSenderQueueWaitCanceled         = ffe("FF22170", "Request canceled while waiting to submit a transaction from %s")
+	MsgBadSenderQueueMaxPending        = ffe("FF22171", "Invalid sender queue maxPending %d - must be greater than zero")
+	MsgUnknownResubmitPolicy           = ffe("FF22172", "Unknown resubmit policy '%s' - must be 'rebroadcast' or 'feeBump'")
+	MsgBadResubmitConfig               = ffe("FF22173", "Invalid resubmit configuration: %s")
+	MsgReceiptWaitTimeout              = ffe("FF22174", "Timed out waiting for the receipt of transaction %s")
+	MsgSigningInvalidEIP2098           = ffe("FF22175", "Invalid signature data (EIP-2098 compact) length=%d (expected=64)")
+	MsgSigningHighSEIP2098             = ffe("FF22176", "Signature S value is in the upper half of the curve order, so cannot be encoded in EIP-2098 compact form")
+	MsgInvalidPublicKey                = ffe("FF22177", "Invalid secp256k1 public key: %s")
+	MsgSignatureHighS                  = ffe("FF22178", "Signature S value is in the upper half of the curve order, so is malleable and invalid under EIP-2")
+	MsgInvalidExtraEntropy             = ffe("FF22179", "Extra entropy for signing must be %d bytes (length=%d)")
+	MsgSignerMismatch                  = ffe("FF22180", "Message was signed by '%s', not '%s'")
+	MsgInvalidRecoveryID               = ffe("FF22181", "Invalid signature recovery ID %d (must be 0 or 1)")
+	MsgInvalidSignatureV               = ffe("FF22182", "invalid V value in signature (chain ID = %d, V = %s)")
+	MsgInvalidEIP155V                  = ffe("FF22183", "Invalid EIP-155 V value %s")
+	MsgSchnorrSignFailed               = ffe("FF22184", "BIP-340 Schnorr signing failed: %s")
+	MsgInvalidSchnorrPublicKey         = ffe("FF22185", "Invalid BIP-340 x-only public key: %s")
+	MsgInvalidSchnorrSignature         = ffe("FF22186", "Invalid BIP-340 Schnorr signature: %s")
+	MsgSchnorrVerifyFailed             = ffe("FF22187", "BIP-340 Schnorr signature verification failed")
+	MsgInvalidDERSignature             = ffe("FF22188", "Invalid DER encoded ECDSA signature: %s")
+	MsgSigningInvalidCompactRS         = ffe("FF22189", "Invalid compact R,S signature length %d (must be 64)")
+	MsgSignatureNotFromPublicKey       = ffe("FF22190", "Signature was not produced by the supplied public key")
+	MsgWriteWalletFileFailed           = ffe("FF22191", "Failed to write wallet file %s")
+	MsgCreateKeyFilenameMismatch       = ffe("FF22192", "Cannot create key file %s, as it does not match the configured filenames")
+	MsgCreateKeyMetadataTemplate       = ffe("FF22193", "Cannot create a metadata file, as %s is not a simple reference to a field such as {{ .signing.keyFile }} or {{ index .signing \"key-file\" }}")
+	MsgCreateKeyNoPassword             = ffe("FF22194", "Cannot create a key, as there is no password file configured for each key and no default password file")
+	MsgEmptyPassword                   = ffe("FF22195", "The password must not be empty")
+	MsgReadPasswordFileFailed          = ffe("FF22196", "Failed to read password file %s")
+	MsgPasswordMismatch                = ffe("FF22197", "The passwords do not match")
+	MsgChainIDRequired                 = ffe("FF22198", "A chain ID is required, with --chain-id or backend.chainId")
+	MsgReadInputFailed                 = ffe("FF22199", "Failed to read input from %s")
+	MsgInvalidTransactionJSON          = ffe("FF22200", "Invalid transaction JSON: %s")
+	MsgInvalidABIFile                  = ffe("FF22201", "Invalid ABI file %s: %s")
+	MsgInvalidRawTransactionHex        = ffe("FF22202", "Invalid raw transaction hex: %s")
+	MsgABIEntryNotFound                = ffe("FF22203", "No entry named '%s' found in the ABI")
+	MsgABIEntryAmbiguous               = ffe("FF22204", "Multiple entries named '%s' found in the ABI - use the full signature to select one: %s")
+	MsgABIEventEncodeUnsupported       = ffe("FF22205", "Encoding is not supported for event '%s'")
+	MsgInvalidHexInput                 = ffe("FF22206", "Invalid hex input: %s")
+	MsgInvalidCommandOption            = ffe("FF22207", "Invalid value '%s' for --%s - must be one of: %s")
+	MsgChangePasswordNoPasswordFile    = ffe("FF22208", "The key for address %s uses the default password file, so its password cannot be changed without breaking the other keys")
+	MsgChangePasswordVerifyFailed      = ffe("FF22209", "Failed to verify the re-encrypted key file %s: %s")
+	MsgKeystoreDecryptFailed           = ffe("FF22210", "Failed to decrypt key file %s: %s")
+	MsgInvalidTypedDataJSON            = ffe("FF22211", "Invalid EIP-712 typed data JSON: %s")
+	MsgConfigProblems                  = ffe("FF22212", "The configuration has %d problem(s): %s")
+	MsgWalletPathNotSet                = ffe("FF22213", "The path of the file wallet (fileWallet.path) is not set")
+	MsgConfigPathNotAccessible         = ffe("FF22214", "Cannot access '%s' configured in %s: %s")
+	MsgConfigPathNotDirectory          = ffe("FF22215", "'%s' configured in %s is not a directory")
+	MsgConfigPathIsDirectory           = ffe("FF22216", "'%s' configured in %s is a directory, not a file")
+	MsgMetadataKeyFilePropertyRequired = ffe("FF22217", "A template for the key file (metadata.keyFileProperty) is required when the metadata format is '%s'")
+	MsgUnknownMetadataFormat           = ffe("FF22218", "Unknown metadata format '%s' - supported formats are auto, filename, toml, yaml and json")
+	MsgFileReadableByOthers            = ffe("FF22219", "'%s' configured in %s can be read by other users (mode %s)")
+	MsgDirectoryWritableByOthers       = ffe("FF22220", "'%s' configured in %s can be written by other users (mode %s)")
+	MsgConfigCheckFailed               = ffe("FF22221", "The configuration check found %d problem(s)")
+	MsgTypedDataNotSupported           = ffe("FF22222", "The configured wallet does not support signing EIP-712 typed data")
+	MsgUnknownDebugLogModule           = ffe("FF22223", "Unknown debug log module '%s'. Modules: %s", 400)
+	MsgInvalidLogLevel                 = ffe("FF22224", "Invalid log level '%s'. Levels: error, info, debug, trace", 400)
+	MsgInvalidAdminRequest             = ffe("FF22225", "Invalid admin request: %s", 400)
+	MsgUnknownMessageKey               = ffe("FF22226", "Unknown message key '%s' in the '%s' message pack", 400)
+	MsgMessageInsertsMismatch          = ffe("FF22227", "Message '%s' in the '%s' message pack has %d inserts, where the original has %d", 400)
+	MsgFakeChainMethodNotFound         = ffe("FF22228", "Method '%s' is not supported by the fake chain")
+	MsgInvalidTestVectorFixtures       = ffe("FF22229", "Invalid test vector fixtures: %s", 400)
+	MsgTestVectorKind                  = ffe("FF22230", "Test vector '%s' must have exactly one of abi, rlp, eip712 or transaction", 400)
+	MsgTestVectorUnknownKey            = ffe("FF22231", "Test vector '%s' uses key '%s', which is not in the keys of the fixtures", 400)
+	MsgTestVectorInvalidKey            = ffe("FF22232", "Key '%s' of the test vector fixtures is not a valid 32 byte private key", 400)
+	MsgTestVectorKeyRequired           = ffe("FF22233", "Test vector '%s' must have a key to sign the transaction", 400)
+	MsgTestVectorInvalidTxType         = ffe("FF22234", "Invalid transaction type '%s'. Types: legacy, eip155, eip1559", 400)
+	MsgTestVectorInvalidRLP            = ffe("FF22235", "Invalid RLP value '%v' - must be a hex string, an integer or a list", 400)
+	MsgTestVectorFailed                = ffe("FF22236", "Test vector '%s' failed: %s", 400)
+	MsgTestVectorIncomplete            = ffe("FF22237", "Test vector '%s' is missing its '%s'", 400)
+	MsgFFIBytesLengthMismatch          = ffe("FF22238", "Value for type '%s' must be exactly %d bytes, but was %d bytes", 400)
+	MsgFFIAddressChecksumMismatch      = ffe("FF22239", "Address '%s' does not match its EIP-55 checksum encoding '%s'", 400)
+	MsgFFIAddressChecksumRequired      = ffe("FF22240", "Address '%s' must use the EIP-55 mixed-case checksum encoding '%s'", 400)
+	MsgFFIUnknownFormat                = ffe("FF22241", "Unknown format '%s' in FFI details - no format validator is registered with that name")
+	MsgFFIValueViolations              = ffe("FF22242", "%d values are invalid for type '%s'", 400)
+	MsgFFIInvalidAddress               = ffe("FF22243", "Invalid address '%v': %s", 400)
+	MsgTxUnknownFields                 = ffe("FF22244", "Unknown fields in transaction: %s", 400)
+	MsgTxMixedFeeFields                = ffe("FF22245", "Transaction cannot set gasPrice together with the EIP-1559 fee fields maxFeePerGas and maxPriorityFeePerGas", 400)
+	MsgTxInvalidQuantityField          = ffe("FF22246", "Transaction field '%s' must be a non-negative integer, as a JSON number or a hex or decimal string: %v", 400)
+	MsgTxInvalidAddressField           = ffe("FF22247", "Transaction field '%s' must be a 20 byte hex address: %v", 400)
+	MsgTxInvalidDataField              = ffe("FF22248", "Transaction field '%s' must be hex encoded bytes: %v", 400)
+	MsgTxNotObject                     = ffe("FF22249", "Transaction must be a JSON object", 400)
+	MsgTokenUnknownStandard            = ffe("FF22250", "Unknown token standard '%s'. Standards: erc20, erc721, erc1155, erc4626, erc2612", 400)
+	MsgTokenEventNotRecognized         = ffe("FF22251", "Event with signature hash '%s' and %d topics is not an event of token standards %s", 404)
+	MsgTokenCallNotRecognized          = ffe("FF22252", "Function selector '%s' is not a function of token standards %s", 404)
+	MsgContractCallFailed              = ffe("FF22253", "Call to %s on contract %s failed: %s", 400)
+	MsgContractNoWallet                = ffe("FF22254", "A wallet is required to submit transactions to contract %s")
+	MsgContractNotFunction             = ffe("FF22255", "'%s' is not a function in the ABI of contract %s", 400)
+	MsgENSInvalidName                  = ffe("FF22256", "Invalid ENS name '%s': %s", 400)
+	MsgENSNameNotResolved              = ffe("FF22257", "ENS name '%s' does not resolve to an address", 404)
+	MsgENSNoReverseName                = ffe("FF22258", "No ENS name is set for address %s", 404)
+	MsgENSReverseNameMismatch          = ffe("FF22259", "ENS name '%s' set for address %s resolves to %s", 404)
+	MsgENSInvalidRegistry              = ffe("FF22260", "Invalid ENS registry address '%s': %s")
+	MsgContractDeployFailed            = ffe("FF22261", "Deployment of contract failed: %s", 400)
+	MsgContractDeployReverted          = ffe("FF22262", "Deployment transaction %s reverted", 400)
+	MsgContractNoCode                  = ffe("FF22263", "No contract code at address %s after deployment transaction %s", 400)
+	MsgGasOracleRequestFailed          = ffe("FF22264", "Request to gas price source '%s' failed: %s")
+	MsgGasOracleBadResponse            = ffe("FF22265", "Gas price source '%s' returned an invalid response: %s")
+	MsgGasOracleAllFailed              = ffe("FF22266", "All gas price sources failed: %s")
+	MsgGasOracleNoSources              = ffe("FF22267", "At least one gas price source is required")
+	MsgGasOracleBadWeight              = ffe("FF22268", "Invalid weight %f for gas price source '%s' - must be greater than zero")
+	MsgGasOracleUnknownMode            = ffe("FF22269", "Unknown gas price source mode '%s' - must be 'fallback' or 'weighted'")
+	MsgGasOracleUnknownSpeed           = ffe("FF22270", "Unknown speed '%s' for gas price source '%s' - must be 'safe', 'standard' or 'fast'")
+	MsgGasOracleBadConfig              = ffe("FF22271", "Invalid gas price source %d in fees.oracles: %s")
+	MsgVanityNoPattern                 = ffe("FF22272", "A prefix, suffix or regular expression is required to search for a vanity address")
+	MsgVanityInvalidPattern            = ffe("FF22273", "Invalid vanity address %s '%s' - must be at most 40 hex characters")
+	MsgVanityInvalidRegexp             = ffe("FF22274", "Invalid vanity address regular expression '%s': %s")
+	MsgVanitySearchCanceled            = ffe("FF22275", "Vanity address search canceled after %d attempts")
+	MsgVanityInvalidInitCodeHash       = ffe("FF22276", "The CREATE2 init code hash must be 32 bytes, not %d")
+	MsgTraceInvalid                    = ffe("FF22277", "Invalid trace of transaction %s: %s")
+	MsgCCIPReadSenderMismatch          = ffe("FF22278", "The OffchainLookup of %s on %s has the sender %s, which is not the contract")
+	MsgCCIPReadTooManyLookups          = ffe("FF22279", "The call of %s on %s exceeded the limit of %d offchain lookups")
+	MsgCCIPReadURLNotAllowed           = ffe("FF22280", "Gateway URL '%s' is not allowed")
+	MsgCCIPReadGatewayFailed           = ffe("FF22281", "Gateway '%s' failed: %s")
+	MsgCCIPReadFailed                  = ffe("FF22282", "The offchain lookup of %s on %s failed: %s")
+	MsgSigningRejected                 = ffe("FF22283", "Signing request rejected by the approver: %s", 403)
+	MsgSigningApprovalTimedOut         = ffe("FF22284", "Signing request was not approved within %s", 403)
+	MsgSigningApprovalCanceled         = ffe("FF22285", "Signing request canceled while waiting for approval")
+	MsgSigningApprovalFailed           = ffe("FF22286", "Signing approval hook failed: %s")
+	MsgSigningApprovalBadDecision      = ffe("FF22287", "Invalid decision '%s' from the signing approval hook")
+	MsgKeyCeremonyNoPath               = ffe("FF22288", "admin.keyCeremony.path or persistence.path must be set when key ceremonies are enabled")
+	MsgKeyCeremonyNoFileWallet         = ffe("FF22289", "Key ceremonies require the file wallet to be enabled")
+	MsgKeyCeremonyStoreFailed          = ffe("FF22290", "Failed to persist key ceremony %s: %s")
+	MsgKeyCeremonyLoadFailed           = ffe("FF22291", "Failed to load key ceremonies: %s")
+	MsgKeyCeremonyInvalid              = ffe("FF22292", "Invalid key ceremony request: %s", 400)
+	MsgKeyCeremonyNotFound             = ffe("FF22293", "Key ceremony %s not found", 404)
+	MsgKeyCeremonyWrongStatus          = ffe("FF22294", "Key ceremony %s is %s, so cannot be %s", 409)
+	MsgKeyCeremonyDuplicateApproval    = ffe("FF22295", "'%s' has already approved key ceremony %s - it must also be approved by a different identity", 403)
+	MsgKeyCeremonyNotProposer          = ffe("FF22296", "Key ceremony %s can only be executed by '%s', who proposed it", 403)
+	MsgKeyCeremonyAddressMismatch      = ffe("FF22297", "The keystore decrypted to the key for %s, not %s", 400)
+	MsgKeyExportNotSupported           = ffe("FF22298", "The wallet does not support exporting keys")
+	MsgPersistenceInitFailed           = ffe("FF22299", "Failed to initialize persistence directory '%s': %s")
+	MsgPersistenceInvalidKey           = ffe("FF22300", "Invalid persistence collection or key '%s'")
+	MsgPersistenceReadFailed           = ffe("FF22301", "Failed to read '%s' from persistence collection '%s': %s")
+	MsgPersistenceWriteFailed          = ffe("FF22302", "Failed to write '%s' to persistence collection '%s': %s")
+	MsgPersistenceListFailed           = ffe("FF22303", "Failed to list persistence collection '%s': %s")
+	MsgFileInsecure                    = ffe("FF22304", "Refusing to load '%s', as %s. Restrict its permissions, or set fileWallet.allowInsecurePermissions")
+	MsgFilePermissionsCheckFailed      = ffe("FF22305", "Failed to check the permissions of '%s': %s")
+	MsgLoadTestNoAccounts              = ffe("FF22306", "No accounts to sign with, as the wallet has no keys", 400)
+	MsgObjectStoreSettingRequired      = ffe("FF22307", "The object store of the file wallet requires fileWallet.objectStore.%s to be set")
+	MsgObjectStoreBadEndpoint          = ffe("FF22308", "Invalid object store endpoint '%s'")
+	MsgObjectStoreBadSSEMode           = ffe("FF22309", "Invalid server-side encryption mode '%s' - must be AES256, aws:kms or customer")
+	MsgObjectStoreCustomerKeyInvalid   = ffe("FF22310", "The customer key file '%s' must contain a base64 encoded 256 bit key")
+	MsgObjectStoreNoCredentials        = ffe("FF22311", "No credentials for the object store - set fileWallet.objectStore.accessKeyID and secretAccessKey, or AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+	MsgObjectStoreRequestFailed        = ffe("FF22312", "Object store request %s '%s' failed")
+	MsgObjectStoreRequestStatus        = ffe("FF22313", "Object store request %s '%s' failed with status %d: %s")
+	MsgObjectStoreListInvalid          = ffe("FF22314", "Invalid response listing the object store: %s")
+	MsgObjectStoreUnencrypted          = ffe("FF22315", "Refusing to load object '%s' from the object store, as it is not stored with %s server-side encryption")
+	MsgObjectStoreKMSKeyMismatch       = ffe("FF22316", "Refusing to load object '%s' from the object store, as it is encrypted with KMS key '%s' rather than '%s'")
+	MsgObjectStoreCacheWriteFailed     = ffe("FF22317", "Failed to write object '%s' to the local cache '%s'")
+	MsgPackedEncodingUnsupported       = ffe("FF22318", "Tightly packed encoding (abi.encodePacked) does not support %s, for component %s")
+	MsgHumanReadableABIExpected        = ffe("FF22319", "Invalid human-readable ABI '%s' - expected %s at offset %d")
+	MsgHumanReadableABIBadChar         = ffe("FF22320", "Invalid human-readable ABI '%s' - unexpected character '%c' at offset %d")
+	MsgUnknownErrorSelector            = ffe("FF22321", "No error in the ABI matches the selector '%s' of the revert data")
+	MsgEventNoSignatureTopic           = ffe("FF22322", "The log has no topics, so the signature of its event is unknown - anonymous events must be decoded with their ABI entry")
+	MsgUnknownEventSignature           = ffe("FF22323", "No event in the ABI matches the signature topic '%s' with %d topics")
+	MsgKeyCeremonyRequiresRBAC         = ffe("FF22324", "Key ceremonies require role based access control (auth.rbac), so only identities granted admin_keyCeremony can propose, approve and execute them")
+	MsgUnlockPassphraseRequired        = ffe("FF22325", "A passphrase is required to unlock '%s', as fileWallet.requireUnlock is set", 400)
+	MsgPrivilegedMethodRequiresRBAC    = ffe("FF22326", "Method '%s' must be granted with role based access control (auth.rbac), as it affects the keys or nonces of every caller", 403)
 )
@@ -48,8 +48,16 @@ var (
 	EIP712ResultR            = ffm("EIP712Result.r", "The R value of the ECDSA signature as a 32byte hex encoded array")
 	EIP712ResultS            = ffm("EIP712Result.s", "The S value of the ECDSA signature as a 32byte hex encoded array")
 
-	TypedDataDomain      = ffm("TypedData.domain", "The data to encode into the EIP712Domain as part fo signing the transaction")
-	TypedDataMessage     = ffm("TypedData.message", "The data to encode into primaryType structure, with nested values for any sub-structures")
-	TypedDataTypes       = ffm("TypedData.types", "Array of types to use when encoding, which must include the primaryType and the EIP712Domain (noting the primary type can be EIP712Domain if the message is empty)")
-	TypedDataPrimaryType = ffm("TypedData.primaryType", "The primary type to begin encoding the EIP-712 hash from in the list of types, using the input message (unless set directly to EIP712Domain, in which case the message can be omitted)")
+	PublicKeyResultAddress      = ffm("PublicKeyResult.address", "The Ethereum address derived from the public key")
+	PublicKeyResultCompressed   = ffm("PublicKeyResult.compressed", "The 33 byte compressed SEC1 encoding of the secp256k1 public key")
+	PublicKeyResultUncompressed = ffm("PublicKeyResult.uncompressed", "The 65 byte uncompressed SEC1 encoding of the secp256k1 public key (including the 0x04 prefix byte)")
+	PublicKeyResultXOnly        = ffm("PublicKeyResult.xOnly", "The 32 byte BIP-340 x-only encoding of the public key, used to verify Schnorr signatures")
+
+	TypedDataDomain                  = ffm("TypedData.domain", "The data to encode into the EIP712Domain as part fo signing the transaction")
+	TypedDataMessage                 = ffm("TypedData.message", "The data to encode into primaryType structure, with nested values for any sub-structures")
+	TypedDataTypes                   = ffm("TypedData.types", "Array of types to use when encoding, which must include the primaryType and the EIP712Domain (noting the primary type can be EIP712Domain if the message is empty)")
+	TypedDataV4HashesDomainSeparator = ffm("TypedDataV4Hashes.domainSeparator", "The hash of the EIP712Domain, which is the domain separator")
+	TypedDataV4HashesStructHash      = ffm("TypedDataV4Hashes.structHash", "The hash of the message encoded as the primary type, omitted when the primary type is EIP712Domain")
+	TypedDataV4HashesDigest          = ffm("TypedDataV4Hashes.digest", "The EIP-712 digest that is signed, which is the hash of the 0x1901 prefix, domain separator and struct hash")
+	TypedDataPrimaryType             = ffm("TypedData.primaryType", "The primary type to begin encoding the EIP-712 hash from in the list of types, using the input message (unless set directly to EIP712Domain, in which case the message can be omitted)")
 )
@@ -14,6 +14,36 @@ type Backend struct {
 	mock.Mock
 }
 
+// BatchRequest provides a mock function with given fields: ctx, rpcReqs
+func (_m *Backend) BatchRequest(ctx context.Context, rpcReqs []*rpcbackend.RPCRequest) ([]*rpcbackend.RPCResponse, error) {
+	ret := _m.Called(ctx, rpcReqs)
+
+	if len(ret) == 0 {
+		panic("no return value specified for BatchRequest")
+	}
+
+	var r0 []*rpcbackend.RPCResponse
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []*rpcbackend.RPCRequest) ([]*rpcbackend.RPCResponse, error)); ok {
+		return rf(ctx, rpcReqs)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []*rpcbackend.RPCRequest) []*rpcbackend.RPCResponse); ok {
+		r0 = rf(ctx, rpcReqs)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*rpcbackend.RPCResponse)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []*rpcbackend.RPCRequest) error); ok {
+		r1 = rf(ctx, rpcReqs)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // CallRPC provides a mock function with given fields: ctx, result, method, params
 func (_m *Backend) CallRPC(ctx context.Context, result interface{}, method string, params ...interface{}) *rpcbackend.RPCError {
 	var _ca []interface{}
@@ -21,6 +51,10 @@ func (_m *Backend) CallRPC(ctx context.Context, result interface{}, method strin
 	_ca = append(_ca, params...)
 	ret := _m.Called(_ca...)
 
+	if len(ret) == 0 {
+		panic("no return value specified for CallRPC")
+	}
+
 	var r0 *rpcbackend.RPCError
 	if rf, ok := ret.Get(0).(func(context.Context, interface{}, string, ...interface{}) *rpcbackend.RPCError); ok {
 		r0 = rf(ctx, result, method, params...)
@@ -37,6 +71,10 @@ func (_m *Backend) CallRPC(ctx context.Context, result interface{}, method strin
 func (_m *Backend) SyncRequest(ctx context.Context, rpcReq *rpcbackend.RPCRequest) (*rpcbackend.RPCResponse, error) {
 	ret := _m.Called(ctx, rpcReq)
 
+	if len(ret) == 0 {
+		panic("no return value specified for SyncRequest")
+	}
+
 	var r0 *rpcbackend.RPCResponse
 	var r1 error
 	if rf, ok := ret.Get(0).(func(context.Context, *rpcbackend.RPCRequest) (*rpcbackend.RPCResponse, error)); ok {
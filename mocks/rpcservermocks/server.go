@@ -2,17 +2,43 @@
 
 package rpcservermocks
 
-import mock "github.com/stretchr/testify/mock"
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+)
 
 // Server is an autogenerated mock type for the Server type
 type Server struct {
 	mock.Mock
 }
 
-// Start provides a mock function with given fields:
+// Reload provides a mock function with given fields: ctx
+func (_m *Server) Reload(ctx context.Context) error {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Reload")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context) error); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Start provides a mock function with no fields
 func (_m *Server) Start() error {
 	ret := _m.Called()
 
+	if len(ret) == 0 {
+		panic("no return value specified for Start")
+	}
+
 	var r0 error
 	if rf, ok := ret.Get(0).(func() error); ok {
 		r0 = rf()
@@ -23,15 +49,19 @@ func (_m *Server) Start() error {
 	return r0
 }
 
-// Stop provides a mock function with given fields:
+// Stop provides a mock function with no fields
 func (_m *Server) Stop() {
 	_m.Called()
 }
 
-// WaitStop provides a mock function with given fields:
+// WaitStop provides a mock function with no fields
 func (_m *Server) WaitStop() error {
 	ret := _m.Called()
 
+	if len(ret) == 0 {
+		panic("no return value specified for WaitStop")
+	}
+
 	var r0 error
 	if rf, ok := ret.Get(0).(func() error); ok {
 		r0 = rf()
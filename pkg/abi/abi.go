@@ -286,6 +286,42 @@ func (a ABI) Functions() map[string]*Entry {
 	return m
 }
 
+// EntryByName finds the entry with the given name, or full signature such as "mint(address,uint256)" to select
+// one of several overloaded entries with the same name. The name "constructor" returns the constructor.
+func (a ABI) EntryByName(name string) (*Entry, error) {
+	return a.EntryByNameCtx(context.Background(), name)
+}
+
+func (a ABI) EntryByNameCtx(ctx context.Context, name string) (*Entry, error) {
+	var matches []*Entry
+	for _, e := range a {
+		if name == string(Constructor) && e.Type == Constructor {
+			return e, nil
+		}
+		if e.Name == "" {
+			continue
+		}
+		if sig, _ := e.SignatureCtx(ctx); sig == name {
+			return e, nil
+		}
+		if e.Name == name {
+			matches = append(matches, e)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, i18n.NewError(ctx, signermsgs.MsgABIEntryNotFound, name)
+	case 1:
+		return matches[0], nil
+	default:
+		sigs := make([]string, len(matches))
+		for i, e := range matches {
+			sigs[i], _ = e.SignatureCtx(ctx)
+		}
+		return nil, i18n.NewError(ctx, signermsgs.MsgABIEntryAmbiguous, name, strings.Join(sigs, ", "))
+	}
+}
+
 func (a ABI) Constructor() *Entry {
 	for _, e := range a {
 		if e.Type == Constructor {
@@ -336,6 +372,73 @@ func (a ABI) ParseErrorCtx(ctx context.Context, revertData []byte) (*Entry, *Com
 	return nil, nil, false
 }
 
+// DecodeError matches the selector at the start of the revert data against the errors in the ABI,
+// and the Error(string) and Panic(uint256) errors built into Solidity, then decodes the arguments.
+// Unlike ParseError, the reason the data could not be decoded is returned - such as a selector
+// that matches no error, which usually means the error is declared by another contract in the call.
+func (a ABI) DecodeError(revertData []byte) (*Entry, *ComponentValue, error) {
+	return a.DecodeErrorCtx(context.Background(), revertData)
+}
+
+func (a ABI) DecodeErrorCtx(ctx context.Context, revertData []byte) (*Entry, *ComponentValue, error) {
+	if len(revertData) < 4 {
+		return nil, nil, i18n.NewError(ctx, signermsgs.MsgNotEnoughBytesABISignature)
+	}
+	selector := revertData[0:4]
+	// Errors declared in the ABI take precedence over the built-in errors of the same signature
+	builtin := ABI{
+		{Type: Error, Name: "Error", Inputs: ParameterArray{{Name: "reason", Type: "string"}}},
+		{Type: Error, Name: "Panic", Inputs: ParameterArray{{Name: "code", Type: "uint256"}}},
+	}
+	for _, entries := range []ABI{a, builtin} {
+		for _, e := range entries {
+			if e.Type != Error {
+				continue
+			}
+			id, err := e.GenerateFunctionSelectorCtx(ctx)
+			if err != nil || !bytes.Equal(id, selector) {
+				continue
+			}
+			cv, err := e.Inputs.DecodeABIDataCtx(ctx, revertData, 4)
+			if err != nil {
+				return nil, nil, err
+			}
+			return e, cv, nil
+		}
+	}
+	return nil, nil, i18n.NewError(ctx, signermsgs.MsgUnknownErrorSelector, ethtypes.HexBytes0xPrefix(selector))
+}
+
+// DecodeEvent matches the first topic of a log against the signature hashes of the events in the ABI,
+// and decodes the indexed inputs from the remaining topics and the other inputs from the data.
+// Events with the same signature that differ in which inputs are indexed, such as the Transfer
+// events of ERC-20 and ERC-721, are told apart by the number of topics. Anonymous events have no
+// signature topic, so cannot be matched - use DecodeEventData on their entry.
+func (a ABI) DecodeEvent(topics []ethtypes.HexBytes0xPrefix, data ethtypes.HexBytes0xPrefix) (*Entry, *ComponentValue, error) {
+	return a.DecodeEventCtx(context.Background(), topics, data)
+}
+
+func (a ABI) DecodeEventCtx(ctx context.Context, topics []ethtypes.HexBytes0xPrefix, data ethtypes.HexBytes0xPrefix) (*Entry, *ComponentValue, error) {
+	if len(topics) == 0 {
+		return nil, nil, i18n.NewError(ctx, signermsgs.MsgEventNoSignatureTopic)
+	}
+	for _, e := range a {
+		if e.Type != Event || e.Anonymous || e.IndexedInputCount()+1 != len(topics) {
+			continue
+		}
+		sigHash, err := e.SignatureHashCtx(ctx)
+		if err != nil || !bytes.Equal(sigHash, topics[0]) {
+			continue
+		}
+		cv, err := e.DecodeEventDataCtx(ctx, topics, data)
+		if err != nil {
+			return nil, nil, err
+		}
+		return e, cv, nil
+	}
+	return nil, nil, i18n.NewError(ctx, signermsgs.MsgUnknownEventSignature, topics[0], len(topics))
+}
+
 func (a ABI) ErrorString(revertData []byte) (string, bool) {
 	return a.ErrorStringCtx(context.Background(), revertData)
 }
@@ -492,6 +595,32 @@ func (pa ParameterArray) EncodeABIDataValuesCtx(ctx context.Context, v interface
 	return cv.EncodeABIDataCtx(ctx)
 }
 
+// EncodePackedABIDataJSON is a helper to go all the way from JSON data to tightly packed bytes, as abi.encodePacked
+func (pa ParameterArray) EncodePackedABIDataJSON(jsonData []byte) ([]byte, error) {
+	return pa.EncodePackedABIDataJSONCtx(context.Background(), jsonData)
+}
+
+func (pa ParameterArray) EncodePackedABIDataJSONCtx(ctx context.Context, jsonData []byte) ([]byte, error) {
+	cv, err := pa.ParseJSONCtx(ctx, jsonData)
+	if err != nil {
+		return nil, err
+	}
+	return cv.EncodePackedABIDataCtx(ctx)
+}
+
+// EncodePackedABIDataValues goes all the way from interface inputs, to tightly packed bytes as abi.encodePacked
+func (pa ParameterArray) EncodePackedABIDataValues(v interface{}) ([]byte, error) {
+	return pa.EncodePackedABIDataValuesCtx(context.Background(), v)
+}
+
+func (pa ParameterArray) EncodePackedABIDataValuesCtx(ctx context.Context, v interface{}) ([]byte, error) {
+	cv, err := pa.ParseExternalDataCtx(ctx, v)
+	if err != nil {
+		return nil, err
+	}
+	return cv.EncodePackedABIDataCtx(ctx)
+}
+
 // String returns the signature string. If a Validate needs to be initiated, and that
 // parse fails, then the error is logged, but is not returned
 func (e *Entry) String() string {
@@ -582,6 +711,34 @@ func (e *Entry) EncodeCallDataValuesCtx(ctx context.Context, values interface{})
 	return e.EncodeCallDataCtx(ctx, cv)
 }
 
+// EncodePacked serializes the inputs of the entry with the tight packing of abi.encodePacked, without a
+// function selector. See ComponentValue.EncodePackedABIData for the types that are supported.
+func (e *Entry) EncodePacked(cv *ComponentValue) ([]byte, error) {
+	return e.EncodePackedCtx(context.Background(), cv)
+}
+
+func (e *Entry) EncodePackedCtx(ctx context.Context, cv *ComponentValue) ([]byte, error) {
+	return cv.EncodePackedABIDataCtx(ctx)
+}
+
+// EncodePackedJSON is a helper to go straight from a JSON input, to the tightly packed inputs
+func (e *Entry) EncodePackedJSON(jsonData []byte) ([]byte, error) {
+	return e.EncodePackedJSONCtx(context.Background(), jsonData)
+}
+
+func (e *Entry) EncodePackedJSONCtx(ctx context.Context, jsonData []byte) ([]byte, error) {
+	return e.Inputs.EncodePackedABIDataJSONCtx(ctx, jsonData)
+}
+
+// EncodePackedValues is a helper to go straight from an interface input, such as a map or array, to the tightly packed inputs
+func (e *Entry) EncodePackedValues(values interface{}) ([]byte, error) {
+	return e.EncodePackedValuesCtx(context.Background(), values)
+}
+
+func (e *Entry) EncodePackedValuesCtx(ctx context.Context, values interface{}) ([]byte, error) {
+	return e.Inputs.EncodePackedABIDataValuesCtx(ctx, values)
+}
+
 // EncodeCallData serializes the inputs of the entry, prefixed with the function selector
 func (e *Entry) EncodeCallData(cv *ComponentValue) ([]byte, error) {
 	return e.EncodeCallDataCtx(context.Background(), cv)
@@ -673,11 +830,26 @@ func (e *Entry) topicToValue(ctx context.Context, topicIdx int, topic ethtypes.H
 	}, nil
 }
 
+// IndexedInputCount returns the number of indexed inputs of an event, each of which is a topic of its logs
+func (e *Entry) IndexedInputCount() int {
+	count := 0
+	for _, input := range e.Inputs {
+		if input.Indexed {
+			count++
+		}
+	}
+	return count
+}
+
 // DecodeEventData takes the array of topics, and the event data, and builds a component value tree that parses
 // the values against the ABI definition in the entry. Values are extracted from either the topic or data per
 // the rules defined here: https://docs.soliditylang.org/en/v0.8.15/abi-spec.html
 //
 // If the event is non-anonymous, the signature hash of the event must match the first topic (or an error is thrown).
+//
+// Indexed inputs of a type that fits in 32 bytes are decoded from their topic. For all other types - strings,
+// bytes, arrays and tuples - the topic is the keccak hash of the value, which is returned as bytes
+// in place of the input, as the value itself cannot be recovered from the log.
 func (e *Entry) DecodeEventData(topics []ethtypes.HexBytes0xPrefix, data ethtypes.HexBytes0xPrefix) (*ComponentValue, error) {
 	return e.DecodeEventDataCtx(context.Background(), topics, data)
 }
@@ -17,6 +17,7 @@
 package abi
 
 import (
+	"context"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
@@ -938,6 +939,78 @@ func TestDecodeEventBadData(t *testing.T) {
 	assert.Regexp(t, "FF22047", err)
 }
 
+func TestDecodeEventFromABI(t *testing.T) {
+	transferSig := "0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"
+	a := ABI{
+		{Type: Function, Name: "transfer", Inputs: ParameterArray{{Name: "amount", Type: "uint256"}}},
+		{Type: Event, Name: "Broken", Inputs: ParameterArray{{Name: "bad", Type: "wrong", Indexed: true}}},
+		{Type: Event, Name: "Transfer", Anonymous: true, Inputs: ParameterArray{
+			{Name: "from", Type: "address", Indexed: true},
+		}},
+		{Type: Event, Name: "Transfer", Inputs: ParameterArray{
+			{Name: "from", Type: "address", Indexed: true},
+			{Name: "to", Type: "address", Indexed: true},
+			{Name: "value", Type: "uint256"},
+		}},
+		{Type: Event, Name: "Transfer", Inputs: ParameterArray{
+			{Name: "from", Type: "address", Indexed: true},
+			{Name: "to", Type: "address", Indexed: true},
+			{Name: "tokenId", Type: "uint256", Indexed: true},
+		}},
+	}
+	from := ethtypes.MustNewHexBytes0xPrefix("0x0000000000000000000000000000000000000000000000000000000000000000")
+	to := ethtypes.MustNewHexBytes0xPrefix("0x000000000000000000000000fb075bb99f2aa4c49955bf703509a227d7a12248")
+	amount := ethtypes.MustNewHexBytes0xPrefix("0x000000000000000000000000000000000000000000000000000000000000091d")
+
+	e, v, err := a.DecodeEvent([]ethtypes.HexBytes0xPrefix{
+		ethtypes.MustNewHexBytes0xPrefix(transferSig), from, to,
+	}, amount)
+	require.NoError(t, err)
+	assert.Equal(t, a[3], e)
+	j, err := v.JSON()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"from": "0000000000000000000000000000000000000000",
+		"to": "fb075bb99f2aa4c49955bf703509a227d7a12248",
+		"value": "2333"
+	}`, string(j))
+
+	e, v, err = a.DecodeEvent([]ethtypes.HexBytes0xPrefix{
+		ethtypes.MustNewHexBytes0xPrefix(transferSig), from, to, amount,
+	}, ethtypes.HexBytes0xPrefix{})
+	require.NoError(t, err)
+	assert.Equal(t, a[4], e)
+	j, err = v.JSON()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"from": "0000000000000000000000000000000000000000",
+		"to": "fb075bb99f2aa4c49955bf703509a227d7a12248",
+		"tokenId": "2333"
+	}`, string(j))
+
+	_, _, err = a.DecodeEvent([]ethtypes.HexBytes0xPrefix{}, amount)
+	assert.Regexp(t, "FF22322", err)
+
+	_, _, err = a.DecodeEvent([]ethtypes.HexBytes0xPrefix{
+		ethtypes.MustNewHexBytes0xPrefix(transferSig), from,
+	}, amount)
+	assert.Regexp(t, "FF22323.*"+transferSig+".*2 topics", err)
+
+	_, _, err = a.DecodeEvent([]ethtypes.HexBytes0xPrefix{
+		ethtypes.MustNewHexBytes0xPrefix(transferSig), from, to,
+	}, ethtypes.MustNewHexBytes0xPrefix("0x"))
+	assert.Regexp(t, "FF22047", err)
+}
+
+func TestEventIndexedInputCount(t *testing.T) {
+	e := &Entry{Type: Event, Name: "Approval", Inputs: ParameterArray{
+		{Name: "owner", Type: "address", Indexed: true},
+		{Name: "spender", Type: "address", Indexed: true},
+		{Name: "value", Type: "uint256"},
+	}}
+	assert.Equal(t, 2, e.IndexedInputCount())
+}
+
 func TestGetConstructor(t *testing.T) {
 	a := testABI(t, sampleABI1)
 	c := a.Constructor()
@@ -1074,6 +1147,50 @@ func TestErrorString(t *testing.T) {
 
 }
 
+func TestDecodeError(t *testing.T) {
+
+	customErrABI := ABI{
+		{Type: Function, Name: "transfer", Inputs: ParameterArray{{Name: "amount", Type: "uint256"}}},
+		{Type: Error, Name: "Broken", Inputs: ParameterArray{{Name: "bad", Type: "wrong"}}},
+		{Type: Error, Name: "InsufficientBalance", Inputs: ParameterArray{
+			{Name: "available", Type: "uint256"},
+			{Name: "required", Type: "uint256"},
+		}},
+	}
+
+	revertData, err := customErrABI[2].EncodeCallDataJSON([]byte(`{"available":10,"required":20}`))
+	require.NoError(t, err)
+
+	e, cv, err := customErrABI.DecodeError(revertData)
+	require.NoError(t, err)
+	assert.Equal(t, "InsufficientBalance", e.Name)
+	res, err := NewSerializer().SerializeJSON(cv)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"available":"10","required":"20"}`, string(res))
+
+	e, cv, err = customErrABI.DecodeError(ethtypes.MustNewHexBytes0xPrefix(`0x08c379a0` +
+		`0000000000000000000000000000000000000000000000000000000000000020` +
+		`000000000000000000000000000000000000000000000000000000000000001a` +
+		`4e6f7420656e6f7567682045746865722070726f76696465642e000000000000`))
+	require.NoError(t, err)
+	assert.Equal(t, `Error("Not enough Ether provided.")`, FormatErrorStringCtx(context.Background(), e, cv))
+
+	e, cv, err = customErrABI.DecodeError(ethtypes.MustNewHexBytes0xPrefix(`0x4e487b71` +
+		`0000000000000000000000000000000000000000000000000000000000000011`))
+	require.NoError(t, err)
+	assert.Equal(t, `Panic("17")`, FormatErrorStringCtx(context.Background(), e, cv))
+
+	_, _, err = customErrABI.DecodeError(ethtypes.MustNewHexBytes0xPrefix(`0x112233`))
+	assert.Regexp(t, "FF22048", err)
+
+	_, _, err = customErrABI.DecodeError(ethtypes.MustNewHexBytes0xPrefix(`0x11223344`))
+	assert.Regexp(t, "FF22321.*0x11223344", err)
+
+	_, _, err = customErrABI.DecodeError(revertData[0:8])
+	assert.Regexp(t, "FF22047", err)
+
+}
+
 func TestUnnamedInputOutput(t *testing.T) {
 
 	sampleABI := ABI{
@@ -1096,3 +1213,31 @@ func TestUnnamedInputOutput(t *testing.T) {
 	require.JSONEq(t, `{"0":"12345","1":"test"}`, string(res))
 
 }
+
+func TestEntryByName(t *testing.T) {
+	a := ABI{
+		{Type: Constructor, Inputs: ParameterArray{{Type: "string"}}},
+		{Type: Function, Name: "mint", Inputs: ParameterArray{{Type: "uint256"}}},
+		{Type: Function, Name: "mint", Inputs: ParameterArray{{Type: "address"}, {Type: "uint256"}}},
+		{Type: Event, Name: "Minted", Inputs: ParameterArray{{Type: "uint256"}}},
+		{Type: Fallback},
+	}
+
+	e, err := a.EntryByName("constructor")
+	assert.NoError(t, err)
+	assert.Equal(t, Constructor, e.Type)
+
+	e, err = a.EntryByName("mint(address,uint256)")
+	assert.NoError(t, err)
+	assert.Len(t, e.Inputs, 2)
+
+	e, err = a.EntryByName("Minted")
+	assert.NoError(t, err)
+	assert.Equal(t, Event, e.Type)
+
+	_, err = a.EntryByName("mint")
+	assert.Regexp(t, "FF22204.*mint\\(uint256\\), mint\\(address,uint256\\)", err)
+
+	_, err = a.EntryByName("burn")
+	assert.Regexp(t, "FF22203", err)
+}
@@ -23,10 +23,14 @@ import (
 
 	"github.com/hyperledger/firefly-common/pkg/i18n"
 	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/hyperledger/firefly-signer/pkg/debuglog"
 )
 
 // walkTupleABIBytes is the main entry point to the logic, decoding a list of parameters at a position
 func walkTupleABIBytes(ctx context.Context, block []byte, offset int, component *typeComponent) (headBytesRead int, cv *ComponentValue, err error) {
+	if debuglog.Active(debuglog.ABIDecode) {
+		debuglog.Logf(ctx, debuglog.ABIDecode, "Decoding %s from %d bytes at offset %d", component, len(block), offset)
+	}
 	return walkDynamicChildArrayABIBytes(ctx, "tup", "", block, offset, offset, component, component.tupleChildren)
 }
 
@@ -52,6 +56,9 @@ func decodeABIElement(ctx context.Context, breadcrumbs string, block []byte, hea
 		if err != nil {
 			return -1, nil, err
 		}
+		if debuglog.Active(debuglog.ABIDecode) {
+			debuglog.Logf(ctx, debuglog.ABIDecode, "Decoded %s %s: %v", breadcrumbs, component, cv.Value)
+		}
 		// So we move the position beyond the data length of the element
 		return 32, cv, err
 	case FixedArrayComponent:
@@ -22,6 +22,9 @@ import (
 	"math/big"
 	"testing"
 
+	"github.com/hyperledger/firefly-signer/pkg/debuglog"
+	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -48,6 +51,34 @@ func TestExampleABIDecode1(t *testing.T) {
 
 }
 
+func TestABIDecodeDebugLog(t *testing.T) {
+	debuglog.SetModules([]debuglog.Module{debuglog.ABIDecode})
+	defer debuglog.SetModules(nil)
+	hook := logtest.NewGlobal()
+	defer logrus.StandardLogger().ReplaceHooks(logrus.LevelHooks{})
+
+	f := &Entry{
+		Name: "baz",
+		Inputs: ParameterArray{
+			{Type: "uint32"},
+			{Type: "bool"},
+		},
+	}
+	d, err := hex.DecodeString("cdcd77c0" +
+		"0000000000000000000000000000000000000000000000000000000000000045" +
+		"0000000000000000000000000000000000000000000000000000000000000001")
+	assert.NoError(t, err)
+
+	_, err = f.DecodeCallData(d)
+	assert.NoError(t, err)
+
+	entries := hook.AllEntries()
+	assert.Len(t, entries, 3)
+	assert.Equal(t, "Decoding (uint32,bool) from 68 bytes at offset 4", entries[0].Message)
+	assert.Regexp(t, `Decoded \[tup,i:0,b:4\] uint32: 69`, entries[1].Message)
+	assert.Regexp(t, `Decoded \[tup,i:1,b:36\] bool: 1`, entries[2].Message)
+}
+
 func TestExampleABIDecode2(t *testing.T) {
 
 	f := &Entry{
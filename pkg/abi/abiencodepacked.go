@@ -0,0 +1,98 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package abi
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+)
+
+// EncodePackedABIData encodes the value with the non-standard tight packing of Solidity's abi.encodePacked,
+// for computing hashes such as those of signed messages and CREATE2 salts. Types shorter than 32 bytes
+// are not padded, and dynamic types are encoded in place without a length. The elements of arrays are
+// padded to 32 bytes. As in Solidity, tuples (other than the parameters themselves), nested arrays and
+// arrays of dynamic types are not supported. The encoding is ambiguous, so cannot be decoded.
+func (cv *ComponentValue) EncodePackedABIData() ([]byte, error) {
+	return cv.EncodePackedABIDataCtx(context.Background())
+}
+
+func (cv *ComponentValue) EncodePackedABIDataCtx(ctx context.Context) ([]byte, error) {
+	if cv == nil || cv.Component == nil {
+		return nil, i18n.NewError(ctx, signermsgs.MsgBadABITypeComponent, "nil")
+	}
+	if cv.Component.(*typeComponent).cType != TupleComponent {
+		return cv.encodePackedABIData(ctx, "", false)
+	}
+	// The parameters of an entry are a tuple, which are packed one after another
+	var data []byte
+	for i, child := range cv.Children {
+		cData, err := child.encodePackedABIData(ctx, fmt.Sprintf("[%d]", i), false)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, cData...)
+	}
+	return data, nil
+}
+
+func (cv *ComponentValue) encodePackedABIData(ctx context.Context, desc string, inArray bool) ([]byte, error) {
+	tc := cv.Component.(*typeComponent)
+	switch tc.cType {
+	case ElementaryComponent:
+		data, dynamic, err := tc.elementaryType.encodeABIData(ctx, desc, tc, cv.Value)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case inArray && dynamic:
+			return nil, i18n.NewError(ctx, signermsgs.MsgPackedEncodingUnsupported, "arrays of dynamic types", desc)
+		case inArray:
+			// The elements of arrays keep the padding of the standard encoding
+			return data, nil
+		case dynamic:
+			// Strip the length prefix, and the padding after the data
+			return data[32 : 32+new(big.Int).SetBytes(data[0:32]).Int64()], nil
+		case tc.elementaryType.name == BaseTypeBytes || tc.elementaryType.name == BaseTypeFunction:
+			// Fixed bytes are left aligned, and M is the number of bytes
+			return data[0:tc.m], nil
+		default:
+			// Numbers (including addresses and booleans) are right aligned, and M is the number of bits
+			return data[32-tc.m/8:], nil
+		}
+	case FixedArrayComponent, DynamicArrayComponent:
+		if inArray {
+			return nil, i18n.NewError(ctx, signermsgs.MsgPackedEncodingUnsupported, "nested arrays", desc)
+		}
+		var data []byte
+		for i, child := range cv.Children {
+			cData, err := child.encodePackedABIData(ctx, fmt.Sprintf("%s[%d]", desc, i), true)
+			if err != nil {
+				return nil, err
+			}
+			data = append(data, cData...)
+		}
+		return data, nil
+	case TupleComponent:
+		return nil, i18n.NewError(ctx, signermsgs.MsgPackedEncodingUnsupported, "tuples", desc)
+	default:
+		return nil, i18n.NewError(ctx, signermsgs.MsgBadABITypeComponent, tc.cType)
+	}
+}
@@ -0,0 +1,172 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package abi
+
+import (
+	"context"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/sha3"
+)
+
+func TestEncodePackedSolidityExample(t *testing.T) {
+
+	// The example from the Solidity documentation of the non-standard packed mode
+	f := &Entry{
+		Inputs: ParameterArray{
+			{Type: "int16"},
+			{Type: "bytes1"},
+			{Type: "uint16"},
+			{Type: "string"},
+		},
+	}
+
+	data, err := f.EncodePackedJSON([]byte(`[-1, "0x42", 3, "Hello, world!"]`))
+	assert.NoError(t, err)
+	assert.Equal(t, "ffff42000348656c6c6f2c20776f726c6421", hex.EncodeToString(data))
+
+}
+
+func TestEncodePackedTypes(t *testing.T) {
+
+	f := &Entry{
+		Inputs: ParameterArray{
+			{Name: "a", Type: "address"},
+			{Name: "b", Type: "bool"},
+			{Name: "c", Type: "uint8[]"},
+			{Name: "d", Type: "bytes"},
+			{Name: "e", Type: "bytes2[1]"},
+			{Name: "f", Type: "int24"},
+			{Name: "g", Type: "ufixed16x1"},
+			{Name: "h", Type: "function"},
+		},
+	}
+
+	data, err := f.EncodePackedValues(map[string]interface{}{
+		"a": "0x1f185718734552d08278aa70f804580bab5fd2b4",
+		"b": true,
+		"c": []interface{}{1, 2},
+		"d": "0x0102",
+		"e": []interface{}{"0xabcd"},
+		"f": -2,
+		"g": "1.5",
+		"h": "0x1f185718734552d08278aa70f804580bab5fd2b412345678",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "1f185718734552d08278aa70f804580bab5fd2b4"+
+		"01"+
+		"0000000000000000000000000000000000000000000000000000000000000001"+
+		"0000000000000000000000000000000000000000000000000000000000000002"+
+		"0102"+
+		"abcd000000000000000000000000000000000000000000000000000000000000"+
+		"fffffe"+
+		"000f"+
+		"1f185718734552d08278aa70f804580bab5fd2b412345678",
+		hex.EncodeToString(data))
+
+}
+
+func TestEncodePackedCreate2Address(t *testing.T) {
+
+	// Example 0 of EIP-1014 - keccak256(0xff ++ address ++ salt ++ keccak256(init_code))[12:]
+	pa := ParameterArray{
+		{Type: "bytes1"},
+		{Type: "address"},
+		{Type: "bytes32"},
+		{Type: "bytes32"},
+	}
+	initCodeHash := sha3.NewLegacyKeccak256()
+	initCodeHash.Write([]byte{0x00})
+
+	data, err := pa.EncodePackedABIDataValues([]interface{}{
+		"0xff",
+		"0x0000000000000000000000000000000000000000",
+		"0x0000000000000000000000000000000000000000000000000000000000000000",
+		initCodeHash.Sum(nil),
+	})
+	assert.NoError(t, err)
+	assert.Len(t, data, 85)
+	hash := sha3.NewLegacyKeccak256()
+	hash.Write(data)
+	assert.Equal(t, "4d1a2e2bb4f88f0250f26ffff098b0b30b26bf38", hex.EncodeToString(hash.Sum(nil)[12:]))
+
+}
+
+func TestEncodePackedSingleValue(t *testing.T) {
+
+	pa := ParameterArray{{Type: "uint16[2]"}}
+	cv, err := pa.ParseJSON([]byte(`[[1, 2]]`))
+	assert.NoError(t, err)
+
+	data, err := cv.Children[0].EncodePackedABIData()
+	assert.NoError(t, err)
+	assert.Len(t, data, 64)
+
+	data, err = (&Entry{Inputs: pa}).EncodePacked(cv)
+	assert.NoError(t, err)
+	assert.Len(t, data, 64)
+
+	data, err = pa.EncodePackedABIDataJSON([]byte(`[[1, 2]]`))
+	assert.NoError(t, err)
+	assert.Len(t, data, 64)
+
+}
+
+func TestEncodePackedUnsupported(t *testing.T) {
+
+	for _, test := range []struct {
+		param  *Parameter
+		value  string
+		errMsg string
+	}{
+		{&Parameter{Type: "tuple", Components: ParameterArray{{Type: "uint8"}}}, `[[1]]`, "FF22318.*tuples.*\\[0\\]"},
+		{&Parameter{Type: "uint8[][]"}, `[[[1]]]`, "FF22318.*nested arrays.*\\[0\\]\\[0\\]"},
+		{&Parameter{Type: "string[]"}, `[["a"]]`, "FF22318.*arrays of dynamic types.*\\[0\\]\\[0\\]"},
+	} {
+		pa := ParameterArray{test.param}
+		cv, err := pa.ParseJSON([]byte(test.value))
+		assert.NoError(t, err)
+		_, err = cv.EncodePackedABIData()
+		assert.Regexp(t, test.errMsg, err)
+	}
+
+}
+
+func TestEncodePackedErrors(t *testing.T) {
+	ctx := context.Background()
+
+	_, err := (*ComponentValue)(nil).EncodePackedABIDataCtx(ctx)
+	assert.Regexp(t, "FF22041", err)
+
+	_, err = (&ComponentValue{Component: &typeComponent{cType: TupleComponent}, Children: []*ComponentValue{
+		{Component: &typeComponent{cType: ElementaryComponent, elementaryType: ElementaryTypeUint.(*elementaryTypeInfo)}, Value: "wrong"},
+	}}).EncodePackedABIDataCtx(ctx)
+	assert.Regexp(t, "FF22042", err)
+
+	_, err = (&ComponentValue{Component: &typeComponent{cType: DynamicArrayComponent}, Children: []*ComponentValue{
+		{Component: &typeComponent{cType: ComponentType(99)}},
+	}}).EncodePackedABIDataCtx(ctx)
+	assert.Regexp(t, "FF22041", err)
+
+	f := &Entry{Inputs: ParameterArray{{Type: "uint8"}}}
+	_, err = f.EncodePackedJSON([]byte(`{`))
+	assert.Error(t, err)
+	_, err = f.EncodePackedValues([]interface{}{"wrong"})
+	assert.Error(t, err)
+}
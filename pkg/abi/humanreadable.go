@@ -0,0 +1,348 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package abi
+
+import (
+	"context"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+)
+
+type hrTokenType int
+
+const (
+	hrTokenEOF hrTokenType = iota
+	hrTokenIdentifier
+	hrTokenNumber
+	hrTokenPunctuation
+)
+
+type hrToken struct {
+	tType  hrTokenType
+	text   string
+	offset int
+}
+
+// hrParser is a recursive descent parser for a single human-readable ABI fragment
+type hrParser struct {
+	ctx      context.Context
+	fragment string
+	tokens   []*hrToken
+	pos      int
+}
+
+// hrTypeAliases are the shorthand types Solidity accepts, which are expanded to the canonical type in the ABI
+var hrTypeAliases = map[string]string{
+	"uint":   "uint256",
+	"int":    "int256",
+	"fixed":  "fixed128x18",
+	"ufixed": "ufixed128x18",
+	"byte":   "bytes1",
+}
+
+// ParseHumanReadable parses a human-readable ABI fragment, in the format used by ethers.js, into an entry. For example:
+//
+//	function transfer(address to, uint256 amount) returns (bool)
+//	function balanceOf(address owner) view returns (uint256)
+//	event Transfer(address indexed from, address indexed to, uint256 value)
+//	error InsufficientBalance(uint256 available, uint256 required)
+//	constructor(string name, string symbol)
+//
+// The function keyword is optional, tuples are written as "tuple(...)" or just "(...)", and data locations
+// (memory/calldata/storage) and visibility (external/public) are ignored. Functions without a state
+// mutability are nonpayable. The entry is validated before it is returned.
+func ParseHumanReadable(fragment string) (*Entry, error) {
+	return ParseHumanReadableCtx(context.Background(), fragment)
+}
+
+func ParseHumanReadableCtx(ctx context.Context, fragment string) (*Entry, error) {
+	p, err := newHRParser(ctx, fragment)
+	if err != nil {
+		return nil, err
+	}
+	e, err := p.parseEntry()
+	if err != nil {
+		return nil, err
+	}
+	if err := e.ValidateCtx(ctx); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// MustParseHumanReadable parses a human-readable ABI fragment, and panics if it is invalid - for declaring
+// entries inline in Go code
+func MustParseHumanReadable(fragment string) *Entry {
+	e, err := ParseHumanReadable(fragment)
+	if err != nil {
+		panic(err)
+	}
+	return e
+}
+
+// ParseHumanReadableABI parses an ABI from a list of human-readable fragments, such as:
+//
+//	abi.ParseHumanReadableABI(
+//		"function transfer(address to, uint256 amount) returns (bool)",
+//		"event Transfer(address indexed from, address indexed to, uint256 value)",
+//	)
+func ParseHumanReadableABI(fragments ...string) (ABI, error) {
+	return ParseHumanReadableABICtx(context.Background(), fragments...)
+}
+
+func ParseHumanReadableABICtx(ctx context.Context, fragments ...string) (ABI, error) {
+	a := make(ABI, len(fragments))
+	for i, fragment := range fragments {
+		e, err := ParseHumanReadableCtx(ctx, fragment)
+		if err != nil {
+			return nil, err
+		}
+		a[i] = e
+	}
+	return a, nil
+}
+
+// MustParseHumanReadableABI parses an ABI from a list of human-readable fragments, and panics if any is invalid
+func MustParseHumanReadableABI(fragments ...string) ABI {
+	a, err := ParseHumanReadableABI(fragments...)
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
+func newHRParser(ctx context.Context, fragment string) (*hrParser, error) {
+	p := &hrParser{ctx: ctx, fragment: fragment}
+	for i := 0; i < len(fragment); {
+		ch := fragment[i]
+		start := i
+		switch {
+		case ch == ' ' || ch == '\t' || ch == '\r' || ch == '\n':
+			i++
+			continue
+		case isHRIdentifierChar(ch) && (ch < '0' || ch > '9'):
+			for i < len(fragment) && isHRIdentifierChar(fragment[i]) {
+				i++
+			}
+			p.tokens = append(p.tokens, &hrToken{tType: hrTokenIdentifier, text: fragment[start:i], offset: start})
+		case ch >= '0' && ch <= '9':
+			for i < len(fragment) && fragment[i] >= '0' && fragment[i] <= '9' {
+				i++
+			}
+			p.tokens = append(p.tokens, &hrToken{tType: hrTokenNumber, text: fragment[start:i], offset: start})
+		case strings.IndexByte("(),[];", ch) >= 0:
+			i++
+			p.tokens = append(p.tokens, &hrToken{tType: hrTokenPunctuation, text: fragment[start:i], offset: start})
+		default:
+			r, _ := utf8.DecodeRuneInString(fragment[i:])
+			return nil, i18n.NewError(ctx, signermsgs.MsgHumanReadableABIBadChar, fragment, r, i)
+		}
+	}
+	p.tokens = append(p.tokens, &hrToken{tType: hrTokenEOF, offset: len(fragment)})
+	return p, nil
+}
+
+func isHRIdentifierChar(ch byte) bool {
+	return (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || (ch >= '0' && ch <= '9') || ch == '_' || ch == '$'
+}
+
+func (p *hrParser) peek() *hrToken {
+	return p.tokens[p.pos]
+}
+
+func (p *hrParser) next() *hrToken {
+	t := p.tokens[p.pos]
+	if t.tType != hrTokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *hrParser) isPunctuation(ch string) bool {
+	t := p.peek()
+	return t.tType == hrTokenPunctuation && t.text == ch
+}
+
+func (p *hrParser) expected(what string) error {
+	return i18n.NewError(p.ctx, signermsgs.MsgHumanReadableABIExpected, p.fragment, what, p.peek().offset)
+}
+
+func (p *hrParser) expectPunctuation(ch string) error {
+	if !p.isPunctuation(ch) {
+		return p.expected("'" + ch + "'")
+	}
+	p.next()
+	return nil
+}
+
+func (p *hrParser) parseEntry() (e *Entry, err error) {
+	e = &Entry{}
+	t := p.peek()
+	if t.tType != hrTokenIdentifier {
+		return nil, p.expected("a keyword or function name")
+	}
+	switch EntryType(t.text) {
+	case Function, Event, Error:
+		p.next()
+		e.Type = EntryType(t.text)
+		if p.peek().tType != hrTokenIdentifier {
+			return nil, p.expected("a name")
+		}
+		e.Name = p.next().text
+	case Constructor, Fallback, Receive:
+		p.next()
+		e.Type = EntryType(t.text)
+	default:
+		// The function keyword is optional
+		e.Type = Function
+		e.Name = p.next().text
+	}
+	if e.Inputs, err = p.parseParameters(e.Type == Event); err != nil {
+		return nil, err
+	}
+	if e.Type == Function {
+		e.Outputs = ParameterArray{}
+	}
+	if err := p.parseModifiers(e); err != nil {
+		return nil, err
+	}
+	if p.isPunctuation(";") {
+		p.next()
+	}
+	if p.peek().tType != hrTokenEOF {
+		return nil, p.expected("the end of the fragment")
+	}
+	return e, nil
+}
+
+// parseModifiers parses everything after the parameters - the state mutability and outputs of functions,
+// and whether an event is anonymous
+func (p *hrParser) parseModifiers(e *Entry) (err error) {
+	for p.peek().tType == hrTokenIdentifier {
+		t := p.peek()
+		switch {
+		case e.IsFunction() && (t.text == string(Pure) || t.text == string(View) || t.text == string(Payable) || t.text == string(NonPayable)):
+			e.StateMutability = StateMutability(t.text)
+		case e.IsFunction() && t.text == "constant":
+			e.StateMutability = View
+		case e.IsFunction() && (t.text == "external" || t.text == "public"):
+			// Visibility is not part of the ABI
+		case e.Type == Function && t.text == "returns":
+			p.next()
+			if e.Outputs, err = p.parseParameters(false); err != nil {
+				return err
+			}
+			continue
+		case e.Type == Event && t.text == "anonymous":
+			e.Anonymous = true
+		default:
+			return p.expected("a modifier")
+		}
+		p.next()
+	}
+	switch {
+	case e.StateMutability != "" || !e.IsFunction():
+	case e.Type == Receive:
+		e.StateMutability = Payable
+	default:
+		e.StateMutability = NonPayable
+	}
+	return nil
+}
+
+func (p *hrParser) parseParameters(allowIndexed bool) (ParameterArray, error) {
+	if err := p.expectPunctuation("("); err != nil {
+		return nil, err
+	}
+	params := ParameterArray{}
+	if p.isPunctuation(")") {
+		p.next()
+		return params, nil
+	}
+	for {
+		param, err := p.parseParameter(allowIndexed)
+		if err != nil {
+			return nil, err
+		}
+		params = append(params, param)
+		switch {
+		case p.isPunctuation(","):
+			p.next()
+		case p.isPunctuation(")"):
+			p.next()
+			return params, nil
+		default:
+			return nil, p.expected("',' or ')'")
+		}
+	}
+}
+
+// parseParameter parses a type, followed by the optional indexed keyword (events only), data location and name
+func (p *hrParser) parseParameter(allowIndexed bool) (*Parameter, error) {
+	param := &Parameter{}
+	if err := p.parseType(param); err != nil {
+		return nil, err
+	}
+	for p.peek().tType == hrTokenIdentifier && param.Name == "" {
+		t := p.next().text
+		switch {
+		case allowIndexed && t == "indexed" && !param.Indexed:
+			param.Indexed = true
+		case t == "memory" || t == "calldata" || t == "storage":
+			// The data location is not part of the ABI
+		default:
+			param.Name = t
+		}
+	}
+	return param, nil
+}
+
+func (p *hrParser) parseType(param *Parameter) (err error) {
+	if t := p.peek(); t.tType == hrTokenIdentifier && t.text == "tuple" && p.tokens[p.pos+1].text == "(" {
+		p.next()
+	}
+	switch t := p.peek(); {
+	case p.isPunctuation("("):
+		param.Type = "tuple"
+		if param.Components, err = p.parseParameters(false); err != nil {
+			return err
+		}
+	case t.tType == hrTokenIdentifier:
+		p.next()
+		param.Type = t.text
+		if alias, ok := hrTypeAliases[t.text]; ok {
+			param.Type = alias
+		}
+	default:
+		return p.expected("a type")
+	}
+	for p.isPunctuation("[") {
+		p.next()
+		length := ""
+		if p.peek().tType == hrTokenNumber {
+			length = p.next().text
+		}
+		if err := p.expectPunctuation("]"); err != nil {
+			return err
+		}
+		param.Type += "[" + length + "]"
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package abi
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseHumanReadableERC20(t *testing.T) {
+
+	a, err := ParseHumanReadableABI(
+		"function transfer(address to, uint256 amount) returns (bool)",
+		"function balanceOf(address owner) view returns (uint256)",
+		"event Transfer(address indexed from, address indexed to, uint256 value)",
+	)
+	assert.NoError(t, err)
+
+	b, err := json.Marshal(a)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[
+		{
+			"type": "function", "name": "transfer", "stateMutability": "nonpayable",
+			"inputs": [{"name": "to", "type": "address"}, {"name": "amount", "type": "uint256"}],
+			"outputs": [{"name": "", "type": "bool"}]
+		},
+		{
+			"type": "function", "name": "balanceOf", "stateMutability": "view",
+			"inputs": [{"name": "owner", "type": "address"}],
+			"outputs": [{"name": "", "type": "uint256"}]
+		},
+		{
+			"type": "event", "name": "Transfer",
+			"inputs": [
+				{"name": "from", "type": "address", "indexed": true},
+				{"name": "to", "type": "address", "indexed": true},
+				{"name": "value", "type": "uint256"}
+			],
+			"outputs": null
+		}
+	]`, string(b))
+
+	assert.Equal(t, "0xa9059cbb", a[0].FunctionSelectorBytes().String())
+	assert.Equal(t, "0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef", a[2].SignatureHashBytes().String())
+
+}
+
+func TestParseHumanReadableTuples(t *testing.T) {
+
+	e, err := ParseHumanReadable("function submit((uint256 id, address[] to)[2] orders, tuple(bytes32 a) calldata extra) payable")
+	assert.NoError(t, err)
+	assert.Equal(t, Payable, e.StateMutability)
+	assert.Equal(t, "tuple[2]", e.Inputs[0].Type)
+	assert.Equal(t, "orders", e.Inputs[0].Name)
+	assert.Equal(t, "address[]", e.Inputs[0].Components[1].Type)
+	assert.Equal(t, "to", e.Inputs[0].Components[1].Name)
+	assert.Equal(t, "tuple", e.Inputs[1].Type)
+	assert.Equal(t, "extra", e.Inputs[1].Name)
+	sig, err := e.Signature()
+	assert.NoError(t, err)
+	assert.Equal(t, "submit((uint256,address[])[2],(bytes32))", sig)
+
+}
+
+func TestParseHumanReadableVariants(t *testing.T) {
+
+	for fragment, expected := range map[string]string{
+		"function f(uint a, int b, byte c, fixed d, ufixed[] e)":         "f(uint256,int256,bytes1,fixed128x18,ufixed128x18[])",
+		"f(string memory s) external constant returns (bytes memory);":   "f(string)",
+		"  function\tg ( ) public pure ":                                 "g()",
+		"error InsufficientBalance(uint256 available, uint256 required)": "InsufficientBalance(uint256,uint256)",
+		"event Anon(uint256 indexed, bytes32) anonymous":                 "Anon(uint256,bytes32)",
+		"event E(address indexed indexed)":                               "E(address)",
+		"constructor(string name, string symbol) payable":                "(string,string)",
+		"fallback() external":                                            "()",
+		"receive() external payable":                                     "()",
+		"function $_a1()":                                                "$_a1()",
+	} {
+		e, err := ParseHumanReadable(fragment)
+		assert.NoError(t, err, fragment)
+		sig, err := e.Signature()
+		assert.NoError(t, err)
+		assert.Equal(t, expected, sig, fragment)
+	}
+
+	e := MustParseHumanReadable("f(string memory s) external constant returns (bytes memory);")
+	assert.Equal(t, Function, e.Type)
+	assert.Equal(t, View, e.StateMutability)
+	assert.Equal(t, "s", e.Inputs[0].Name)
+	assert.Equal(t, "bytes", e.Outputs[0].Type)
+
+	e = MustParseHumanReadable("event Anon(uint256 indexed, bytes32) anonymous")
+	assert.True(t, e.Anonymous)
+	assert.True(t, e.Inputs[0].Indexed)
+	assert.Empty(t, e.Inputs[0].Name)
+
+	e = MustParseHumanReadable("event E(address indexed indexed)")
+	assert.True(t, e.Inputs[0].Indexed)
+	assert.Equal(t, "indexed", e.Inputs[0].Name)
+
+	e = MustParseHumanReadable("function f(address indexed)")
+	assert.False(t, e.Inputs[0].Indexed)
+	assert.Equal(t, "indexed", e.Inputs[0].Name)
+
+	assert.Equal(t, NonPayable, MustParseHumanReadable("fallback() external").StateMutability)
+	assert.Equal(t, Payable, MustParseHumanReadable("receive() external").StateMutability)
+	assert.Empty(t, MustParseHumanReadable("error E()").StateMutability)
+	assert.Nil(t, MustParseHumanReadable("error E()").Outputs)
+
+}
+
+func TestParseHumanReadableErrors(t *testing.T) {
+
+	for fragment, errMsg := range map[string]string{
+		"":                            "FF22319.*a keyword or function name at offset 0",
+		"function":                    "FF22319.*a name at offset 8",
+		"function f":                  "FF22319.*'\\(' at offset 10",
+		"function f(":                 "FF22319.*a type at offset 11",
+		"function f(uint256":          "FF22319.*',' or '\\)' at offset 18",
+		"function f(uint256 a b)":     "FF22319.*',' or '\\)' at offset 21",
+		"function f(uint256[1)":       "FF22319.*'\\]' at offset 20",
+		"function f(tuple(uint256 a)": "FF22319.*',' or '\\)' at offset 27",
+		"function f(()":               "FF22319.*',' or '\\)' at offset 13",
+		"function f() anonymous":      "FF22319.*a modifier at offset 13",
+		"event E() view":              "FF22319.*a modifier at offset 10",
+		"function f() returns":        "FF22319.*'\\(' at offset 20",
+		"function f() returns (bool":  "FF22319.*',' or '\\)' at offset 26",
+		"function f(); g()":           "FF22319.*the end of the fragment at offset 14",
+		"function f() (":              "FF22319.*the end of the fragment at offset 13",
+		"function f(uint256 é)":       "FF22320.*'é' at offset 19",
+		"function f(foo)":             "FF22025",
+		"function f((uint256 a b))":   "FF22319.*',' or '\\)' at offset 22",
+	} {
+		_, err := ParseHumanReadable(fragment)
+		assert.Regexp(t, errMsg, err, fragment)
+	}
+
+	_, err := ParseHumanReadableABI("function f()", "function")
+	assert.Regexp(t, "FF22319", err)
+
+	assert.Panics(t, func() {
+		_ = MustParseHumanReadable("function")
+	})
+	assert.Panics(t, func() {
+		_ = MustParseHumanReadableABI("function")
+	})
+	assert.Len(t, MustParseHumanReadableABI("function f()"), 1)
+
+}
@@ -0,0 +1,123 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package approval is the hook the signer calls before it releases any signature, so signing can require the
+// approval of a person or system other than the caller - such as a second operator in a four-eyes workflow.
+//
+// The hook can approve or reject a request straight away, or defer it while an approval is sought. A deferred
+// request waits in the signer until the decision arrives, or until it times out and is rejected.
+//
+// Every signature is reviewed, including those the signer makes itself: the fee bumps of transactions it
+// resubmits (with the method ffsigner_resubmit), and the empty transactions that fill nonce gaps.
+package approval
+
+import (
+	"context"
+	"time"
+
+	"github.com/hyperledger/firefly-signer/pkg/eip712"
+	"github.com/hyperledger/firefly-signer/pkg/ethsigner"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/tokens"
+)
+
+// Outcome is the decision of the hook on a signing request
+type Outcome string
+
+const (
+	// Approve releases the signature
+	Approve Outcome = "approve"
+	// Reject fails the signing request, with the reason of the decision
+	Reject Outcome = "reject"
+	// Defer holds the signing request until the final decision is sent on the pending channel of the decision
+	Defer Outcome = "defer"
+)
+
+const (
+	// OperationTransaction is a transaction, which is set on the request
+	OperationTransaction = "transaction"
+	// OperationTypedData is EIP-712 typed data, which is set on the request
+	OperationTypedData = "typed_data"
+	// OperationPersonalMessage is a message signed with the EIP-191 prefix, which is set on the request
+	OperationPersonalMessage = "personal_message"
+	// OperationDigest is a 32 byte digest signed as it is with eth_sign, which is set on the request as the message
+	OperationDigest = "digest"
+)
+
+// Request is a request to sign, with everything that will be signed. It must not be modified by the hook.
+type Request struct {
+	ID        string `json:"id"` // unique to each request, to correlate the decisions of deferred requests
+	Method    string `json:"method"`
+	Operation string `json:"operation"`
+	Identity  string `json:"identity,omitempty"` // the authenticated caller, when authentication is enabled
+	ChainID   int64  `json:"chainId"`
+	// From is nil for a transaction from a key the wallet selects by another identifier, set as the from of the transaction
+	From *ethtypes.Address0xHex `json:"from,omitempty"`
+	// Transaction is the transaction to sign, with its gas and fees populated. The nonce is unset when the signer
+	// assigns it, which it only does once the transaction is approved
+	Transaction *ethsigner.Transaction `json:"transaction,omitempty"`
+	// TokenCall is the decoded call data of the transaction, when it calls a function of a standard token interface
+	TokenCall *tokens.Decoded           `json:"-"`
+	TypedData *eip712.TypedData         `json:"typedData,omitempty"`
+	Message   ethtypes.HexBytes0xPrefix `json:"message,omitempty"`
+	// Expires is when a deferred request is rejected if no decision has arrived
+	Expires time.Time `json:"expires"`
+}
+
+// Decision is the decision of the hook on a request
+type Decision struct {
+	Outcome Outcome
+	Reason  string // recorded with the rejection of the request
+	// Pending receives the final decision on a deferred request, which must approve or reject it
+	Pending <-chan *Decision
+}
+
+// Hook is called before each signature is released. The context is the context of the signing request.
+// An error fails the request, just as a rejection does.
+type Hook interface {
+	ReviewSigning(ctx context.Context, req *Request) (*Decision, error)
+}
+
+// HookCancelable is implemented by hooks that need to know when a deferred request stops waiting for its
+// decision - because it timed out, or its caller went away - so it can be withdrawn from whoever was asked to
+// approve it. Any decision sent afterwards is ignored.
+type HookCancelable interface {
+	Hook
+	CancelSigning(ctx context.Context, req *Request, err error)
+}
+
+// Approved returns a decision that approves a request
+func Approved() *Decision {
+	return &Decision{Outcome: Approve}
+}
+
+// Rejected returns a decision that rejects a request, for the reason
+func Rejected(reason string) *Decision {
+	return &Decision{Outcome: Reject, Reason: reason}
+}
+
+// Deferred returns a decision that defers a request, and the function to call with the final decision.
+// The function never blocks, and only the first call has any effect.
+func Deferred() (*Decision, func(*Decision)) {
+	pending := make(chan *Decision, 1)
+	decide := func(d *Decision) {
+		select {
+		case pending <- d:
+		default:
+		}
+	}
+	return &Decision{Outcome: Defer, Pending: pending}, decide
+}
@@ -0,0 +1,38 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package approval
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecisions(t *testing.T) {
+	assert.Equal(t, Approve, Approved().Outcome)
+
+	rejected := Rejected("no")
+	assert.Equal(t, Reject, rejected.Outcome)
+	assert.Equal(t, "no", rejected.Reason)
+
+	deferred, decide := Deferred()
+	assert.Equal(t, Defer, deferred.Outcome)
+	decide(Approved())
+	decide(Rejected("too late"))
+	assert.Equal(t, Approve, (<-deferred.Pending).Outcome)
+	assert.Empty(t, deferred.Pending)
+}
@@ -0,0 +1,234 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contract
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-common/pkg/log"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/hyperledger/firefly-signer/pkg/abi"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+)
+
+const (
+	// DefaultCCIPReadMaxLookups is the number of offchain lookups followed in one call, as EIP-3668 recommends
+	DefaultCCIPReadMaxLookups = 4
+	// DefaultCCIPReadMaxResponseSize is the largest response accepted from a gateway
+	DefaultCCIPReadMaxResponseSize = 1024 * 1024
+	defaultCCIPReadTimeout         = 10 * time.Second
+)
+
+// CCIPReadOptions enables EIP-3668 (CCIP-Read) in Call. When a contract reverts with OffchainLookup, the answer is
+// fetched from one of the gateway URLs in the revert, and passed to the callback function of the contract - whose
+// outputs are the outputs of the call.
+type CCIPReadOptions struct {
+	// AllowedHosts are the hosts of the gateway URLs that can be fetched - any host when empty. As the contract
+	// chooses the URLs, set this where the signer can reach hosts that should not be reachable from the chain.
+	AllowedHosts []string
+	// AllowHTTP allows gateway URLs with the http scheme, otherwise only https URLs are fetched
+	AllowHTTP bool
+	// MaxLookups is the number of offchain lookups followed in one call, defaulting to DefaultCCIPReadMaxLookups
+	MaxLookups int
+	// MaxResponseSize is the largest response accepted from a gateway, defaulting to DefaultCCIPReadMaxResponseSize
+	MaxResponseSize int64
+	// HTTPClient fetches from the gateways, defaulting to a client with a 10 second timeout
+	HTTPClient *http.Client
+}
+
+// offchainLookupError is the revert of EIP-3668
+var offchainLookupError = &abi.Entry{Type: abi.Error, Name: "OffchainLookup", Inputs: abi.ParameterArray{
+	{Name: "sender", Type: "address"},
+	{Name: "urls", Type: "string[]"},
+	{Name: "callData", Type: "bytes"},
+	{Name: "callbackFunction", Type: "bytes4"},
+	{Name: "extraData", Type: "bytes"},
+}}
+
+var callbackInputs = abi.ParameterArray{{Name: "response", Type: "bytes"}, {Name: "extraData", Type: "bytes"}}
+
+type offchainLookup struct {
+	sender           ethtypes.Address0xHex
+	urls             []string
+	callData         ethtypes.HexBytes0xPrefix
+	callbackFunction []byte
+	extraData        []byte
+}
+
+type gatewayRequest struct {
+	Data   ethtypes.HexBytes0xPrefix `json:"data"`
+	Sender string                    `json:"sender"`
+}
+
+type gatewayResponse struct {
+	Data ethtypes.HexBytes0xPrefix `json:"data"`
+}
+
+func newCCIPRead(options *CCIPReadOptions) *CCIPReadOptions {
+	if options == nil {
+		return nil
+	}
+	ccip := *options
+	if ccip.MaxLookups <= 0 {
+		ccip.MaxLookups = DefaultCCIPReadMaxLookups
+	}
+	if ccip.MaxResponseSize <= 0 {
+		ccip.MaxResponseSize = DefaultCCIPReadMaxResponseSize
+	}
+	if ccip.HTTPClient == nil {
+		ccip.HTTPClient = &http.Client{Timeout: defaultCCIPReadTimeout}
+	}
+	return &ccip
+}
+
+// call makes the eth_call, following any offchain lookups the contract reverts with when CCIP-Read is enabled
+func (c *Client) call(ctx context.Context, e *abi.Entry, req *rpcbackend.CallRequest, block string) (ethtypes.HexBytes0xPrefix, error) {
+	for lookups := 0; ; lookups++ {
+		result, rpcErr := c.eth.Call(ctx, req, block)
+		if rpcErr == nil {
+			return result, nil
+		}
+		lookup := c.parseOffchainLookup(ctx, rpcErr)
+		if lookup == nil {
+			return nil, c.callFailed(ctx, e, rpcErr)
+		}
+		if lookups >= c.ccipRead.MaxLookups {
+			return nil, i18n.NewError(ctx, signermsgs.MsgCCIPReadTooManyLookups, e.String(), c.address, c.ccipRead.MaxLookups)
+		}
+		// A lookup can only be for the contract that was called, so it cannot ask for the answer to a call to another contract
+		if lookup.sender != c.address {
+			return nil, i18n.NewError(ctx, signermsgs.MsgCCIPReadSenderMismatch, e.String(), c.address, &lookup.sender)
+		}
+		response, err := c.ccipReadFetch(ctx, lookup)
+		if err != nil {
+			return nil, i18n.NewError(ctx, signermsgs.MsgCCIPReadFailed, e.String(), c.address, err)
+		}
+		// Two byte arrays always encode
+		callbackData, _ := callbackInputs.EncodeABIDataValuesCtx(ctx, []interface{}{[]byte(response), lookup.extraData})
+		req = &rpcbackend.CallRequest{
+			From: req.From,
+			To:   req.To,
+			Data: append(append([]byte{}, lookup.callbackFunction...), callbackData...),
+		}
+	}
+}
+
+// parseOffchainLookup returns the offchain lookup the call reverted with, or nil if CCIP-Read is not enabled,
+// or the call reverted for any other reason
+func (c *Client) parseOffchainLookup(ctx context.Context, rpcErr *rpcbackend.RPCError) *offchainLookup {
+	var revertData ethtypes.HexBytes0xPrefix
+	if c.ccipRead == nil || json.Unmarshal(rpcErr.Data.Bytes(), &revertData) != nil {
+		return nil
+	}
+	cv, err := offchainLookupError.DecodeCallDataCtx(ctx, revertData)
+	if err != nil {
+		return nil
+	}
+	lookup := &offchainLookup{
+		callData:         cv.Children[2].Value.([]byte),
+		callbackFunction: cv.Children[3].Value.([]byte),
+		extraData:        cv.Children[4].Value.([]byte),
+	}
+	cv.Children[0].Value.(*big.Int).FillBytes(lookup.sender[:])
+	for _, u := range cv.Children[1].Children {
+		lookup.urls = append(lookup.urls, u.Value.(string))
+	}
+	return lookup
+}
+
+// ccipReadFetch fetches the answer from each gateway in turn, until one answers. As EIP-3668 defines, a client
+// error from a gateway ends the lookup, whereas other failures move on to the next gateway.
+func (c *Client) ccipReadFetch(ctx context.Context, lookup *offchainLookup) (ethtypes.HexBytes0xPrefix, error) {
+	failures := make([]string, 0, len(lookup.urls))
+	for _, gatewayURL := range lookup.urls {
+		response, clientErr, err := c.ccipReadGateway(ctx, gatewayURL, lookup)
+		if err == nil {
+			return response, nil
+		}
+		log.L(ctx).Warnf("CCIP-Read gateway failed: %s", err)
+		if clientErr {
+			return nil, err
+		}
+		failures = append(failures, err.Error())
+	}
+	return nil, i18n.NewError(ctx, signermsgs.MsgCCIPReadGatewayFailed, strings.Join(lookup.urls, ", "), strings.Join(failures, "; "))
+}
+
+func (c *Client) ccipReadGateway(ctx context.Context, gatewayURL string, lookup *offchainLookup) (response ethtypes.HexBytes0xPrefix, clientErr bool, err error) {
+	// URLs with a {data} parameter are fetched with GET, and others with a POST of the data as JSON
+	sender := strings.ToLower(lookup.sender.String())
+	get := strings.Contains(gatewayURL, "{data}")
+	u, err := url.Parse(strings.ReplaceAll(strings.ReplaceAll(gatewayURL, "{sender}", sender), "{data}", lookup.callData.String()))
+	if err != nil || !c.ccipReadAllowed(u) {
+		return nil, false, i18n.NewError(ctx, signermsgs.MsgCCIPReadURLNotAllowed, gatewayURL)
+	}
+	var req *http.Request
+	if get {
+		req, _ = http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	} else {
+		body, _ := json.Marshal(&gatewayRequest{Data: lookup.callData, Sender: sender})
+		req, _ = http.NewRequestWithContext(ctx, http.MethodPost, u.String(), bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+	}
+	res, err := c.ccipRead.HTTPClient.Do(req)
+	if err != nil {
+		return nil, false, i18n.NewError(ctx, signermsgs.MsgCCIPReadGatewayFailed, gatewayURL, err)
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(res.Body, c.ccipRead.MaxResponseSize+1))
+	switch {
+	case err != nil:
+		return nil, false, i18n.NewError(ctx, signermsgs.MsgCCIPReadGatewayFailed, gatewayURL, err)
+	case res.StatusCode >= 400 && res.StatusCode < 500:
+		return nil, true, i18n.NewError(ctx, signermsgs.MsgCCIPReadGatewayFailed, gatewayURL, res.Status)
+	case res.StatusCode != http.StatusOK:
+		return nil, false, i18n.NewError(ctx, signermsgs.MsgCCIPReadGatewayFailed, gatewayURL, res.Status)
+	case int64(len(body)) > c.ccipRead.MaxResponseSize:
+		return nil, false, i18n.NewError(ctx, signermsgs.MsgCCIPReadGatewayFailed, gatewayURL, fmt.Sprintf("response larger than %d bytes", c.ccipRead.MaxResponseSize))
+	}
+	var gr gatewayResponse
+	if err := json.Unmarshal(body, &gr); err != nil {
+		return nil, false, i18n.NewError(ctx, signermsgs.MsgCCIPReadGatewayFailed, gatewayURL, err)
+	}
+	return gr.Data, false, nil
+}
+
+func (c *Client) ccipReadAllowed(u *url.URL) bool {
+	if u.Scheme != "https" && (u.Scheme != "http" || !c.ccipRead.AllowHTTP) {
+		return false
+	}
+	if len(c.ccipRead.AllowedHosts) == 0 {
+		return true
+	}
+	for _, host := range c.ccipRead.AllowedHosts {
+		if strings.EqualFold(host, u.Hostname()) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,231 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contract
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-signer/pkg/abi"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/fakechain"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+	"github.com/stretchr/testify/assert"
+)
+
+const testCallbackSelector = "0x12345678"
+
+func newTestCCIPClient(t *testing.T, options *CCIPReadOptions) (*Client, *fakechain.Chain) {
+	a, err := abi.ParseABI([]byte(testContractABI))
+	assert.NoError(t, err)
+	chain := fakechain.New(nil)
+	c, err := NewClient(context.Background(), &Config{
+		Address:  testContractAddress,
+		ABI:      a,
+		RPC:      chain,
+		CCIPRead: options,
+	})
+	assert.NoError(t, err)
+	return c, chain
+}
+
+func offchainLookupRevert(t *testing.T, sender string, urls ...string) *rpcbackend.RPCError {
+	return revertError(t, abi.ABI{offchainLookupError}, "OffchainLookup",
+		sender, urls, "0xc0ffee", testCallbackSelector, "0xe1e1")
+}
+
+// handleLookup reverts the call of the function with an offchain lookup, and answers the callback with the data
+// of the gateway response
+func handleLookup(t *testing.T, chain *fakechain.Chain, urls ...string) {
+	chain.Handle("eth_call", func(ctx context.Context, params []*fftypes.JSONAny) (interface{}, *rpcbackend.RPCError) {
+		var call rpcbackend.CallRequest
+		assert.NoError(t, json.Unmarshal(params[0].Bytes(), &call))
+		if !strings.HasPrefix(call.Data.String(), testCallbackSelector) {
+			return nil, offchainLookupRevert(t, testContractAddress.String(), urls...)
+		}
+		cv, err := callbackInputs.DecodeABIData(call.Data, 4)
+		assert.NoError(t, err)
+		assert.Equal(t, []byte{0xe1, 0xe1}, cv.Children[1].Value)
+		return ethtypes.HexBytes0xPrefix(cv.Children[0].Value.([]byte)), nil
+	})
+}
+
+func newTestGateway(t *testing.T, handler http.HandlerFunc) *httptest.Server {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func gatewayAnswer(w http.ResponseWriter, value int) {
+	_, _ = fmt.Fprintf(w, `{"data": "0x%064x"}`, value)
+}
+
+func TestCallCCIPReadGet(t *testing.T) {
+	gateway := newTestGateway(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodGet, r.Method)
+		assert.Equal(t, "/"+testContractAddress.String()+"/0xc0ffee.json", r.URL.Path)
+		gatewayAnswer(w, 1000)
+	})
+	c, chain := newTestCCIPClient(t, &CCIPReadOptions{AllowHTTP: true, AllowedHosts: []string{"127.0.0.1"}})
+	handleLookup(t, chain, gateway.URL+"/{sender}/{data}.json")
+
+	cv, err := c.Call(context.Background(), "totalSupply", nil)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"0": "1000"}`, serialize(t, cv))
+}
+
+func TestCallCCIPReadPostAfterServerError(t *testing.T) {
+	failed := newTestGateway(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	gateway := newTestGateway(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.Equal(t, "/"+testContractAddress.String(), r.URL.Path)
+		var req gatewayRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "0xc0ffee", req.Data.String())
+		assert.Equal(t, testContractAddress.String(), req.Sender)
+		gatewayAnswer(w, 42)
+	})
+	c, chain := newTestCCIPClient(t, &CCIPReadOptions{AllowHTTP: true})
+	handleLookup(t, chain, failed.URL+"/{data}", gateway.URL+"/{sender}")
+
+	cv, err := c.Call(context.Background(), "totalSupply", nil)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"0": "42"}`, serialize(t, cv))
+}
+
+func TestCallCCIPReadClientErrorEndsLookup(t *testing.T) {
+	notFound := newTestGateway(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	gateway := newTestGateway(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Fail(t, "not called")
+	})
+	c, chain := newTestCCIPClient(t, &CCIPReadOptions{AllowHTTP: true})
+	handleLookup(t, chain, notFound.URL, gateway.URL)
+
+	_, err := c.Call(context.Background(), "totalSupply", nil)
+	assert.Regexp(t, "FF22282.*totalSupply.*FF22281.*404", err)
+}
+
+func TestCallCCIPReadAllGatewaysFail(t *testing.T) {
+	badJSON := newTestGateway(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{!!!`))
+	})
+	tooLarge := newTestGateway(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(strings.Repeat(" ", 100)))
+	})
+	truncated := newTestGateway(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "100")
+		_, _ = w.Write([]byte(`{`))
+	})
+	closed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	closed.Close()
+	c, chain := newTestCCIPClient(t, &CCIPReadOptions{AllowHTTP: true, MaxResponseSize: 50, AllowedHosts: []string{"127.0.0.1"}})
+	handleLookup(t, chain,
+		"https://example.com/{data}",
+		"ftp://127.0.0.1/{data}",
+		"::not a url",
+		badJSON.URL,
+		tooLarge.URL,
+		truncated.URL,
+		closed.URL,
+	)
+
+	_, err := c.Call(context.Background(), "totalSupply", nil)
+	assert.Regexp(t, "FF22282.*FF22281.*FF22280.*example.com.*FF22280.*ftp.*FF22280.*not a url.*FF22281.*invalid.*FF22281.*larger than 50 bytes.*FF22281.*EOF.*FF22281.*refused", err)
+}
+
+func TestCallCCIPReadHTTPNotAllowed(t *testing.T) {
+	gateway := newTestGateway(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Fail(t, "not called")
+	})
+	c, chain := newTestCCIPClient(t, &CCIPReadOptions{})
+	handleLookup(t, chain, gateway.URL)
+
+	_, err := c.Call(context.Background(), "totalSupply", nil)
+	assert.Regexp(t, "FF22280", err)
+}
+
+func TestCallCCIPReadSenderMismatch(t *testing.T) {
+	c, chain := newTestCCIPClient(t, &CCIPReadOptions{})
+	chain.Handle("eth_call", func(ctx context.Context, params []*fftypes.JSONAny) (interface{}, *rpcbackend.RPCError) {
+		return nil, offchainLookupRevert(t, "0x1f185718734552d08278aa70f804580bab5fd2b4", "https://example.com")
+	})
+
+	_, err := c.Call(context.Background(), "totalSupply", nil)
+	assert.Regexp(t, "FF22278.*0x1f185718734552d08278aa70f804580bab5fd2b4", err)
+}
+
+func TestCallCCIPReadTooManyLookups(t *testing.T) {
+	lookups := 0
+	gateway := newTestGateway(t, func(w http.ResponseWriter, r *http.Request) {
+		lookups++
+		gatewayAnswer(w, 1)
+	})
+	c, chain := newTestCCIPClient(t, &CCIPReadOptions{AllowHTTP: true, MaxLookups: 2})
+	chain.Handle("eth_call", func(ctx context.Context, params []*fftypes.JSONAny) (interface{}, *rpcbackend.RPCError) {
+		return nil, offchainLookupRevert(t, testContractAddress.String(), gateway.URL)
+	})
+
+	_, err := c.Call(context.Background(), "totalSupply", nil)
+	assert.Regexp(t, "FF22279.*2", err)
+	assert.Equal(t, 2, lookups)
+}
+
+func TestCallCCIPReadDisabled(t *testing.T) {
+	c, chain := newTestCCIPClient(t, nil)
+	chain.Handle("eth_call", func(ctx context.Context, params []*fftypes.JSONAny) (interface{}, *rpcbackend.RPCError) {
+		return nil, offchainLookupRevert(t, testContractAddress.String(), "https://example.com")
+	})
+
+	_, err := c.Call(context.Background(), "totalSupply", nil)
+	assert.Regexp(t, "FF22253.*execution reverted", err)
+}
+
+func TestCallCCIPReadOtherRevert(t *testing.T) {
+	c, chain := newTestCCIPClient(t, &CCIPReadOptions{})
+	chain.Handle("eth_call", func(ctx context.Context, params []*fftypes.JSONAny) (interface{}, *rpcbackend.RPCError) {
+		return nil, &rpcbackend.RPCError{Code: -32000, Message: "pop", Data: *fftypes.JSONAnyPtr(`{}`)}
+	})
+
+	_, err := c.Call(context.Background(), "totalSupply", nil)
+	assert.Regexp(t, "FF22253.*pop", err)
+
+	chain.Handle("eth_call", func(ctx context.Context, params []*fftypes.JSONAny) (interface{}, *rpcbackend.RPCError) {
+		return nil, revertError(t, c.ABI(), "InsufficientBalance", 10, 20)
+	})
+	_, err = c.Call(context.Background(), "totalSupply", nil)
+	assert.Regexp(t, `FF22253.*InsufficientBalance\("10","20"\)`, err)
+}
+
+func TestClientAtSharesCCIPRead(t *testing.T) {
+	c, _ := newTestCCIPClient(t, &CCIPReadOptions{})
+	at := c.At(*ethtypes.MustNewAddress("0x1f185718734552d08278aa70f804580bab5fd2b4"))
+	assert.Same(t, c.ccipRead, at.ccipRead)
+	assert.Equal(t, DefaultCCIPReadMaxLookups, at.ccipRead.MaxLookups)
+	assert.Equal(t, int64(DefaultCCIPReadMaxResponseSize), at.ccipRead.MaxResponseSize)
+	assert.NotNil(t, at.ccipRead.HTTPClient)
+}
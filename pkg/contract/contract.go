@@ -0,0 +1,292 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package contract is a client for a deployed contract, that calls its functions by name with the encoding
+// and decoding of their inputs and outputs done by the contract's ABI.
+package contract
+
+import (
+	"context"
+	"encoding/json"
+	"sync/atomic"
+
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/hyperledger/firefly-signer/pkg/abi"
+	"github.com/hyperledger/firefly-signer/pkg/ethsigner"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+)
+
+// Config is the contract a Client calls, and how it calls it
+type Config struct {
+	Address ethtypes.Address0xHex
+	ABI     abi.ABI
+	RPC     rpcbackend.RPC   // such as a Backend, or a signer.Service
+	Wallet  ethsigner.Wallet // signs the transactions of Transact - not required to only Call
+	ChainID int64            // the chain ID transactions are signed for - queried with eth_chainId when zero
+	// CCIPRead follows the offchain lookups of EIP-3668 in Call - not followed when nil
+	CCIPRead *CCIPReadOptions
+}
+
+// CallOptions are the optional parameters of Call
+type CallOptions struct {
+	From  *ethtypes.Address0xHex
+	Value *ethtypes.HexInteger
+	Block string // defaults to "latest"
+}
+
+// TransactOptions are the optional parameters of Transact. Any of the nonce, gas limit and fees that are
+// not set are populated from the chain - with eth_getTransactionCount, eth_estimateGas and eth_gasPrice.
+// Setting the EIP-1559 fees signs an EIP-1559 transaction, otherwise a legacy EIP-155 transaction is signed.
+type TransactOptions struct {
+	Value                *ethtypes.HexInteger
+	Nonce                *ethtypes.HexInteger
+	GasLimit             *ethtypes.HexInteger
+	GasPrice             *ethtypes.HexInteger
+	MaxFeePerGas         *ethtypes.HexInteger
+	MaxPriorityFeePerGas *ethtypes.HexInteger
+}
+
+// Client calls the functions of one contract. Inputs can be any value the ABI encoder accepts for the
+// inputs of the function - typically an array of values in order, or a map of values by input name.
+type Client struct {
+	address  ethtypes.Address0xHex
+	abi      abi.ABI
+	rpc      rpcbackend.RPC
+	eth      *rpcbackend.EthClient
+	wallet   ethsigner.Wallet
+	ccipRead *CCIPReadOptions
+	chainID  atomic.Int64
+}
+
+// NewClient returns a client for the contract, checking its ABI is valid
+func NewClient(ctx context.Context, conf *Config) (*Client, error) {
+	if err := conf.ABI.ValidateCtx(ctx); err != nil {
+		return nil, err
+	}
+	c := &Client{
+		address:  conf.Address,
+		abi:      conf.ABI,
+		rpc:      conf.RPC,
+		eth:      rpcbackend.NewEthClient(conf.RPC),
+		wallet:   conf.Wallet,
+		ccipRead: newCCIPRead(conf.CCIPRead),
+	}
+	c.chainID.Store(conf.ChainID)
+	return c, nil
+}
+
+// At returns a client for another deployment of the same contract, sharing the ABI, RPC, wallet and CCIP-Read options of this client
+func (c *Client) At(address ethtypes.Address0xHex) *Client {
+	at := &Client{
+		address:  address,
+		abi:      c.abi,
+		rpc:      c.rpc,
+		eth:      c.eth,
+		wallet:   c.wallet,
+		ccipRead: c.ccipRead,
+	}
+	at.chainID.Store(c.chainID.Load())
+	return at
+}
+
+// Address is the address of the contract
+func (c *Client) Address() ethtypes.Address0xHex {
+	return c.address
+}
+
+// ABI is the ABI of the contract
+func (c *Client) ABI() abi.ABI {
+	return c.abi
+}
+
+// Function finds a function of the contract by name, or by full signature for overloaded functions
+func (c *Client) Function(ctx context.Context, name string) (*abi.Entry, error) {
+	e, err := c.abi.EntryByNameCtx(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if !e.IsFunction() {
+		return nil, i18n.NewError(ctx, signermsgs.MsgContractNotFunction, name, c.address)
+	}
+	return e, nil
+}
+
+// Call reads from the contract with eth_call, and decodes the outputs of the function. A revert is returned
+// as an error with the decoded revert reason, including any custom errors in the ABI of the contract.
+// With CCIP-Read enabled, an OffchainLookup revert is followed, and the outputs of the callback function are returned.
+func (c *Client) Call(ctx context.Context, function string, inputs interface{}, options ...*CallOptions) (*abi.ComponentValue, error) {
+	e, callData, err := c.encodeCall(ctx, function, inputs)
+	if err != nil {
+		return nil, err
+	}
+	opts := &CallOptions{}
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	block := opts.Block
+	if block == "" {
+		block = "latest"
+	}
+	result, err := c.call(ctx, e, &rpcbackend.CallRequest{
+		From:  opts.From,
+		To:    &c.address,
+		Value: opts.Value,
+		Data:  callData,
+	}, block)
+	if err != nil {
+		return nil, err
+	}
+	return e.Outputs.DecodeABIDataCtx(ctx, result, 0)
+}
+
+// BuildTransaction returns the transaction that Transact would sign, with the nonce, gas limit and
+// fees populated from the chain where they are not set in the options
+func (c *Client) BuildTransaction(ctx context.Context, from ethtypes.Address0xHex, function string, inputs interface{}, options ...*TransactOptions) (*ethsigner.Transaction, error) {
+	e, callData, err := c.encodeCall(ctx, function, inputs)
+	if err != nil {
+		return nil, err
+	}
+	opts := &TransactOptions{}
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	return c.buildTransaction(ctx, e, from, &c.address, callData, opts)
+}
+
+// Transact builds a transaction calling the function, signs it with the wallet, and submits it with
+// eth_sendRawTransaction - returning the transaction hash.
+func (c *Client) Transact(ctx context.Context, from ethtypes.Address0xHex, function string, inputs interface{}, options ...*TransactOptions) (ethtypes.HexBytes0xPrefix, error) {
+	if c.wallet == nil {
+		return nil, i18n.NewError(ctx, signermsgs.MsgContractNoWallet, c.address)
+	}
+	txn, err := c.BuildTransaction(ctx, from, function, inputs, options...)
+	if err != nil {
+		return nil, err
+	}
+	return c.send(ctx, txn)
+}
+
+// buildTransaction builds a transaction calling the function or constructor e, populating the nonce, gas
+// limit and fees from the chain where they are not set in the options
+func (c *Client) buildTransaction(ctx context.Context, e *abi.Entry, from ethtypes.Address0xHex, to *ethtypes.Address0xHex, data []byte, opts *TransactOptions) (*ethsigner.Transaction, error) {
+	fromJSON, _ := json.Marshal(&from)
+	txn := &ethsigner.Transaction{
+		From:                 fromJSON,
+		To:                   to,
+		Value:                opts.Value,
+		Nonce:                opts.Nonce,
+		GasLimit:             opts.GasLimit,
+		GasPrice:             opts.GasPrice,
+		MaxFeePerGas:         opts.MaxFeePerGas,
+		MaxPriorityFeePerGas: opts.MaxPriorityFeePerGas,
+		Data:                 data,
+	}
+	if txn.Nonce == nil {
+		nonce, rpcErr := c.eth.GetTransactionCount(ctx, from, "pending")
+		if rpcErr != nil {
+			return nil, rpcErr.Error()
+		}
+		txn.Nonce = ethtypes.NewHexIntegerU64(nonce.Uint64())
+	}
+	if txn.GasLimit == nil {
+		// A transaction that would revert fails to estimate, so is reported here with its revert reason
+		var err error
+		if txn.GasLimit, err = c.estimateGas(ctx, e, from, txn); err != nil {
+			return nil, err
+		}
+	}
+	if txn.GasPrice == nil && txn.MaxFeePerGas == nil && txn.MaxPriorityFeePerGas == nil {
+		if rpcErr := c.rpc.CallRPC(ctx, &txn.GasPrice, "eth_gasPrice"); rpcErr != nil {
+			return nil, rpcErr.Error()
+		}
+	}
+	return txn, nil
+}
+
+// send signs the transaction with the wallet, and submits it with eth_sendRawTransaction
+func (c *Client) send(ctx context.Context, txn *ethsigner.Transaction) (ethtypes.HexBytes0xPrefix, error) {
+	chainID, err := c.getChainID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	signed, err := c.wallet.Sign(ctx, txn, chainID)
+	if err != nil {
+		return nil, err
+	}
+	var txHash ethtypes.HexBytes0xPrefix
+	if rpcErr := c.rpc.CallRPC(ctx, &txHash, "eth_sendRawTransaction", ethtypes.HexBytes0xPrefix(signed)); rpcErr != nil {
+		return nil, rpcErr.Error()
+	}
+	return txHash, nil
+}
+
+func (c *Client) encodeCall(ctx context.Context, function string, inputs interface{}) (*abi.Entry, ethtypes.HexBytes0xPrefix, error) {
+	e, err := c.Function(ctx, function)
+	if err != nil {
+		return nil, nil, err
+	}
+	if inputs == nil {
+		inputs = []interface{}{}
+	}
+	callData, err := e.EncodeCallDataValuesCtx(ctx, inputs)
+	if err != nil {
+		return nil, nil, err
+	}
+	return e, callData, nil
+}
+
+func (c *Client) estimateGas(ctx context.Context, e *abi.Entry, from ethtypes.Address0xHex, txn *ethsigner.Transaction) (*ethtypes.HexInteger, error) {
+	gas, rpcErr := c.eth.EstimateGas(ctx, &rpcbackend.CallRequest{
+		From:  &from,
+		To:    txn.To,
+		Value: txn.Value,
+		Data:  txn.Data,
+	})
+	if rpcErr != nil {
+		return nil, c.callFailed(ctx, e, rpcErr)
+	}
+	return gas, nil
+}
+
+func (c *Client) getChainID(ctx context.Context) (int64, error) {
+	if chainID := c.chainID.Load(); chainID != 0 {
+		return chainID, nil
+	}
+	var chainID ethtypes.HexInteger
+	if rpcErr := c.rpc.CallRPC(ctx, &chainID, "eth_chainId"); rpcErr != nil {
+		return -1, rpcErr.Error()
+	}
+	c.chainID.Store(chainID.BigInt().Int64())
+	return chainID.BigInt().Int64(), nil
+}
+
+// callFailed decodes the revert reason from the error data against the errors in the ABI, falling back to the
+// message of the error
+func (c *Client) callFailed(ctx context.Context, e *abi.Entry, rpcErr *rpcbackend.RPCError) error {
+	reason := rpcErr.Message
+	var revertData ethtypes.HexBytes0xPrefix
+	if err := json.Unmarshal(rpcErr.Data.Bytes(), &revertData); err == nil && len(revertData) > 0 {
+		if decoded, ok := c.abi.ErrorStringCtx(ctx, revertData); ok {
+			reason = decoded
+		}
+	}
+	if e.Type == abi.Constructor {
+		return i18n.NewError(ctx, signermsgs.MsgContractDeployFailed, reason)
+	}
+	return i18n.NewError(ctx, signermsgs.MsgContractCallFailed, e.String(), c.address, reason)
+}
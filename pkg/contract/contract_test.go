@@ -0,0 +1,360 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contract
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-signer/pkg/abi"
+	"github.com/hyperledger/firefly-signer/pkg/ethsigner"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/fakechain"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+	"github.com/hyperledger/firefly-signer/pkg/secp256k1"
+	"github.com/stretchr/testify/assert"
+)
+
+const testContractABI = `[
+	{
+		"type": "function",
+		"name": "balanceOf",
+		"stateMutability": "view",
+		"inputs": [{"name": "account", "type": "address"}],
+		"outputs": [{"name": "balance", "type": "uint256"}]
+	},
+	{
+		"type": "function",
+		"name": "transfer",
+		"stateMutability": "nonpayable",
+		"inputs": [{"name": "to", "type": "address"}, {"name": "value", "type": "uint256"}],
+		"outputs": [{"type": "bool"}]
+	},
+	{
+		"type": "function",
+		"name": "totalSupply",
+		"stateMutability": "view",
+		"inputs": [],
+		"outputs": [{"type": "uint256"}]
+	},
+	{
+		"type": "error",
+		"name": "InsufficientBalance",
+		"inputs": [{"name": "available", "type": "uint256"}, {"name": "required", "type": "uint256"}]
+	},
+	{
+		"type": "event",
+		"name": "Transfer",
+		"inputs": [{"name": "from", "type": "address", "indexed": true}, {"name": "to", "type": "address", "indexed": true}, {"name": "value", "type": "uint256"}]
+	}
+]`
+
+var testContractAddress = *ethtypes.MustNewAddress("0x497eedc4299dea2f2a364be10025d0ad0f702de3")
+
+// testWallet signs with a single key
+type testWallet struct {
+	kp *secp256k1.KeyPair
+}
+
+func (w *testWallet) Sign(_ context.Context, txn *ethsigner.Transaction, chainID int64) ([]byte, error) {
+	return txn.Sign(w.kp, chainID)
+}
+
+func (w *testWallet) Initialize(context.Context) error { return nil }
+func (w *testWallet) GetAccounts(context.Context) ([]*ethtypes.Address0xHex, error) {
+	return []*ethtypes.Address0xHex{&w.kp.Address}, nil
+}
+func (w *testWallet) Refresh(context.Context) error { return nil }
+func (w *testWallet) Close() error                  { return nil }
+
+func newTestClient(t *testing.T, chainID int64) (*Client, *fakechain.Chain, *testWallet) {
+	a, err := abi.ParseABI([]byte(testContractABI))
+	assert.NoError(t, err)
+	kp, err := secp256k1.GenerateSecp256k1KeyPair()
+	assert.NoError(t, err)
+	w := &testWallet{kp: kp}
+	chain := fakechain.New(nil)
+	c, err := NewClient(context.Background(), &Config{
+		Address: testContractAddress,
+		ABI:     a,
+		RPC:     chain,
+		Wallet:  w,
+		ChainID: chainID,
+	})
+	assert.NoError(t, err)
+	return c, chain, w
+}
+
+func serialize(t *testing.T, cv *abi.ComponentValue) string {
+	b, err := abi.NewSerializer().SerializeJSON(cv)
+	assert.NoError(t, err)
+	return string(b)
+}
+
+func revertError(t *testing.T, a abi.ABI, name string, values ...interface{}) *rpcbackend.RPCError {
+	e, err := a.EntryByName(name)
+	assert.NoError(t, err)
+	revertData, err := e.EncodeCallDataValues(values)
+	assert.NoError(t, err)
+	return &rpcbackend.RPCError{
+		Code:    3,
+		Message: "execution reverted",
+		Data:    *fftypes.JSONAnyPtr(fmt.Sprintf(`"%s"`, ethtypes.HexBytes0xPrefix(revertData))),
+	}
+}
+
+func TestNewClientInvalidABI(t *testing.T) {
+	_, err := NewClient(context.Background(), &Config{
+		ABI: abi.ABI{{Type: abi.Function, Name: "bad", Inputs: abi.ParameterArray{{Type: "uint7"}}}},
+	})
+	assert.Regexp(t, "FF22028", err)
+}
+
+func TestClientAccessors(t *testing.T) {
+	c, _, _ := newTestClient(t, 1337)
+	assert.Equal(t, testContractAddress, c.Address())
+	assert.Len(t, c.ABI(), 5)
+}
+
+func TestClientAt(t *testing.T) {
+	c, _, w := newTestClient(t, 1337)
+	other := c.At(w.kp.Address)
+	assert.Equal(t, w.kp.Address, other.Address())
+	assert.Equal(t, testContractAddress, c.Address())
+	assert.Len(t, other.ABI(), 5)
+	assert.Equal(t, int64(1337), other.chainID.Load())
+}
+
+func TestCall(t *testing.T) {
+	c, chain, _ := newTestClient(t, 1337)
+	ctx := context.Background()
+
+	chain.Handle("eth_call", func(ctx context.Context, params []*fftypes.JSONAny) (interface{}, *rpcbackend.RPCError) {
+		var call rpcbackend.CallRequest
+		assert.NoError(t, json.Unmarshal(params[0].Bytes(), &call))
+		assert.Equal(t, testContractAddress, *call.To)
+		assert.Equal(t, "0x70a08231000000000000000000000000497eedc4299dea2f2a364be10025d0ad0f702de3", call.Data.String())
+		assert.Equal(t, `"latest"`, params[1].String())
+		return ethtypes.MustNewHexBytes0xPrefix("0x00000000000000000000000000000000000000000000000000000000000003e8"), nil
+	})
+	cv, err := c.Call(ctx, "balanceOf", []interface{}{testContractAddress.String()})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"balance": "1000"}`, serialize(t, cv))
+
+	cv, err = c.Call(ctx, "balanceOf", map[string]interface{}{"account": testContractAddress.String()})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"balance": "1000"}`, serialize(t, cv))
+}
+
+func TestCallOptions(t *testing.T) {
+	c, chain, w := newTestClient(t, 1337)
+
+	chain.Handle("eth_call", func(ctx context.Context, params []*fftypes.JSONAny) (interface{}, *rpcbackend.RPCError) {
+		var call rpcbackend.CallRequest
+		assert.NoError(t, json.Unmarshal(params[0].Bytes(), &call))
+		assert.Equal(t, w.kp.Address, *call.From)
+		assert.Equal(t, int64(5), call.Value.Int64())
+		assert.Equal(t, "0x18160ddd", call.Data.String())
+		assert.Equal(t, `"pending"`, params[1].String())
+		return ethtypes.MustNewHexBytes0xPrefix("0x000000000000000000000000000000000000000000000000000000000000002a"), nil
+	})
+	cv, err := c.Call(context.Background(), "totalSupply", nil, &CallOptions{
+		From:  &w.kp.Address,
+		Value: ethtypes.NewHexInteger64(5),
+		Block: "pending",
+	})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"0": "42"}`, serialize(t, cv))
+}
+
+func TestCallRevertCustomError(t *testing.T) {
+	c, chain, _ := newTestClient(t, 1337)
+
+	chain.Handle("eth_call", func(ctx context.Context, params []*fftypes.JSONAny) (interface{}, *rpcbackend.RPCError) {
+		return nil, revertError(t, c.ABI(), "InsufficientBalance", 10, 20)
+	})
+	_, err := c.Call(context.Background(), "totalSupply", nil)
+	assert.Regexp(t, `FF22253.*totalSupply\(\).*0x497eedc4299dea2f2a364be10025d0ad0f702de3.*InsufficientBalance\("10","20"\)`, err)
+}
+
+func TestCallRevertNoData(t *testing.T) {
+	c, chain, _ := newTestClient(t, 1337)
+
+	chain.Handle("eth_call", func(ctx context.Context, params []*fftypes.JSONAny) (interface{}, *rpcbackend.RPCError) {
+		return nil, &rpcbackend.RPCError{Code: -32000, Message: "pop"}
+	})
+	_, err := c.Call(context.Background(), "totalSupply", nil)
+	assert.Regexp(t, "FF22253.*pop", err)
+}
+
+func TestCallUnknownRevertData(t *testing.T) {
+	c, chain, _ := newTestClient(t, 1337)
+
+	chain.Handle("eth_call", func(ctx context.Context, params []*fftypes.JSONAny) (interface{}, *rpcbackend.RPCError) {
+		return nil, &rpcbackend.RPCError{Code: 3, Message: "execution reverted", Data: *fftypes.JSONAnyPtr(`"0xfeedbeef"`)}
+	})
+	_, err := c.Call(context.Background(), "totalSupply", nil)
+	assert.Regexp(t, "FF22253.*execution reverted", err)
+}
+
+func TestCallBadOutput(t *testing.T) {
+	c, chain, _ := newTestClient(t, 1337)
+
+	chain.Handle("eth_call", func(ctx context.Context, params []*fftypes.JSONAny) (interface{}, *rpcbackend.RPCError) {
+		return ethtypes.MustNewHexBytes0xPrefix("0x01"), nil
+	})
+	_, err := c.Call(context.Background(), "totalSupply", nil)
+	assert.Regexp(t, "FF22047", err)
+}
+
+func TestCallFunctionErrors(t *testing.T) {
+	c, _, _ := newTestClient(t, 1337)
+	ctx := context.Background()
+
+	_, err := c.Call(ctx, "burn", nil)
+	assert.Regexp(t, "FF22203", err)
+
+	_, err = c.Call(ctx, "Transfer", nil)
+	assert.Regexp(t, "FF22255.*Transfer", err)
+
+	_, err = c.Call(ctx, "balanceOf", []interface{}{"not an address"})
+	assert.Regexp(t, "FF22034", err)
+}
+
+func TestTransact(t *testing.T) {
+	c, chain, w := newTestClient(t, 0)
+	ctx := context.Background()
+	chain.SetNonce(w.kp.Address, 5)
+
+	for i := 0; i < 2; i++ {
+		txHash, err := c.Transact(ctx, w.kp.Address, "transfer", []interface{}{testContractAddress.String(), 100})
+		assert.NoError(t, err)
+		receipt, rpcErr := rpcbackend.NewEthClient(chain).GetTransactionReceipt(ctx, txHash)
+		assert.Nil(t, rpcErr)
+		assert.True(t, receipt.Succeeded())
+	}
+
+	txns := chain.Transactions()
+	assert.Len(t, txns, 2)
+	assert.Equal(t, w.kp.Address, *txns[0].From)
+	assert.Equal(t, testContractAddress, *txns[0].To)
+	assert.Equal(t, int64(5), txns[0].Nonce.Int64())
+	assert.Equal(t, int64(6), txns[1].Nonce.Int64())
+	assert.Equal(t, int64(100000), txns[0].GasLimit.Int64())
+	assert.Equal(t, int64(1000000000), txns[0].GasPrice.Int64())
+	assert.Equal(t, "0xa9059cbb000000000000000000000000497eedc4299dea2f2a364be10025d0ad0f702de30000000000000000000000000000000000000000000000000000000000000064", txns[0].Data.String())
+	assert.Equal(t, int64(1337), c.chainID.Load())
+}
+
+func TestTransactEIP1559Options(t *testing.T) {
+	c, chain, w := newTestClient(t, 1337)
+	ctx := context.Background()
+
+	_, err := c.Transact(ctx, w.kp.Address, "transfer(address,uint256)", []interface{}{testContractAddress.String(), 100}, &TransactOptions{
+		Nonce:                ethtypes.NewHexInteger64(0),
+		GasLimit:             ethtypes.NewHexInteger64(50000),
+		MaxFeePerGas:         ethtypes.NewHexInteger64(2000000000),
+		MaxPriorityFeePerGas: ethtypes.NewHexInteger64(1000000000),
+		Value:                ethtypes.NewHexInteger64(0),
+	})
+	assert.NoError(t, err)
+
+	txns := chain.Transactions()
+	assert.Len(t, txns, 1)
+	assert.Equal(t, int64(50000), txns[0].GasLimit.Int64())
+	assert.Equal(t, int64(2000000000), txns[0].MaxFeePerGas.Int64())
+	assert.Nil(t, txns[0].GasPrice)
+}
+
+func TestTransactNoWallet(t *testing.T) {
+	c, _, w := newTestClient(t, 1337)
+	c.wallet = nil
+	_, err := c.Transact(context.Background(), w.kp.Address, "transfer", nil)
+	assert.Regexp(t, "FF22254", err)
+}
+
+func TestTransactBadFunction(t *testing.T) {
+	c, _, w := newTestClient(t, 1337)
+	_, err := c.Transact(context.Background(), w.kp.Address, "burn", nil)
+	assert.Regexp(t, "FF22203", err)
+}
+
+func TestTransactNonceFail(t *testing.T) {
+	c, chain, w := newTestClient(t, 1337)
+	chain.Handle("eth_getTransactionCount", func(ctx context.Context, params []*fftypes.JSONAny) (interface{}, *rpcbackend.RPCError) {
+		return nil, &rpcbackend.RPCError{Code: -32000, Message: "pop"}
+	})
+	_, err := c.Transact(context.Background(), w.kp.Address, "transfer", []interface{}{testContractAddress.String(), 100})
+	assert.Regexp(t, "pop", err)
+}
+
+func TestTransactEstimateRevert(t *testing.T) {
+	c, chain, w := newTestClient(t, 1337)
+	chain.Handle("eth_estimateGas", func(ctx context.Context, params []*fftypes.JSONAny) (interface{}, *rpcbackend.RPCError) {
+		var call rpcbackend.CallRequest
+		assert.NoError(t, json.Unmarshal(params[0].Bytes(), &call))
+		assert.Equal(t, w.kp.Address, *call.From)
+		return nil, revertError(t, c.ABI(), "InsufficientBalance", 10, 100)
+	})
+	_, err := c.Transact(context.Background(), w.kp.Address, "transfer", []interface{}{testContractAddress.String(), 100})
+	assert.Regexp(t, `FF22253.*transfer\(address,uint256\).*InsufficientBalance\("10","100"\)`, err)
+	assert.Empty(t, chain.Transactions())
+}
+
+func TestTransactGasPriceFail(t *testing.T) {
+	c, chain, w := newTestClient(t, 1337)
+	chain.Handle("eth_gasPrice", func(ctx context.Context, params []*fftypes.JSONAny) (interface{}, *rpcbackend.RPCError) {
+		return nil, &rpcbackend.RPCError{Code: -32000, Message: "pop"}
+	})
+	_, err := c.Transact(context.Background(), w.kp.Address, "transfer", []interface{}{testContractAddress.String(), 100})
+	assert.Regexp(t, "pop", err)
+}
+
+func TestTransactChainIDFail(t *testing.T) {
+	c, chain, w := newTestClient(t, 0)
+	chain.Handle("eth_chainId", func(ctx context.Context, params []*fftypes.JSONAny) (interface{}, *rpcbackend.RPCError) {
+		return nil, &rpcbackend.RPCError{Code: -32000, Message: "pop"}
+	})
+	_, err := c.Transact(context.Background(), w.kp.Address, "transfer", []interface{}{testContractAddress.String(), 100})
+	assert.Regexp(t, "pop", err)
+}
+
+func TestTransactSignFail(t *testing.T) {
+	c, _, w := newTestClient(t, 1337)
+	c.wallet = &failingWallet{testWallet: w}
+	_, err := c.Transact(context.Background(), w.kp.Address, "transfer", []interface{}{testContractAddress.String(), 100})
+	assert.Regexp(t, "pop", err)
+}
+
+func TestTransactSendFail(t *testing.T) {
+	// The fake chain rejects transactions signed for a different chain ID
+	c, chain, w := newTestClient(t, 12345)
+	_, err := c.Transact(context.Background(), w.kp.Address, "transfer", []interface{}{testContractAddress.String(), 100})
+	assert.Error(t, err)
+	assert.Empty(t, chain.Transactions())
+}
+
+type failingWallet struct {
+	*testWallet
+}
+
+func (w *failingWallet) Sign(context.Context, *ethsigner.Transaction, int64) ([]byte, error) {
+	return nil, fmt.Errorf("pop")
+}
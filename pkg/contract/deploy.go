@@ -0,0 +1,85 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contract
+
+import (
+	"context"
+
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/hyperledger/firefly-signer/pkg/abi"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+)
+
+// DeployOptions are the optional parameters of Deploy
+type DeployOptions struct {
+	TransactOptions
+	Receipt rpcbackend.ReceiptWaiterOptions // the confirmations the deployment needs, and how long to wait for them
+}
+
+// Deploy deploys a new instance of the contract, from its creation bytecode and the inputs of the constructor
+// in the ABI. The deployment transaction is built, signed and submitted as Transact does, then once it is mined
+// the code at the contract address is checked - returning a client for the deployed contract, and the receipt
+// of the deployment. The address of this client is not used, so it can be the zero address.
+func (c *Client) Deploy(ctx context.Context, from ethtypes.Address0xHex, bytecode []byte, inputs interface{}, options ...*DeployOptions) (*Client, *rpcbackend.Receipt, error) {
+	if c.wallet == nil {
+		return nil, nil, i18n.NewError(ctx, signermsgs.MsgContractNoWallet, c.address)
+	}
+	opts := &DeployOptions{}
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	constructor := c.abi.Constructor()
+	if constructor == nil {
+		constructor = &abi.Entry{Type: abi.Constructor}
+	}
+	if inputs == nil {
+		inputs = []interface{}{}
+	}
+	args, err := constructor.Inputs.EncodeABIDataValuesCtx(ctx, inputs)
+	if err != nil {
+		return nil, nil, err
+	}
+	data := append(append([]byte{}, bytecode...), args...)
+
+	txn, err := c.buildTransaction(ctx, constructor, from, nil, data, &opts.TransactOptions)
+	if err != nil {
+		return nil, nil, err
+	}
+	txHash, err := c.send(ctx, txn)
+	if err != nil {
+		return nil, nil, err
+	}
+	receipt, err := rpcbackend.NewReceiptWaiter(c.rpc, &opts.Receipt).WaitForReceipt(ctx, txHash)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !receipt.Succeeded() || receipt.ContractAddress == nil {
+		return nil, receipt, i18n.NewError(ctx, signermsgs.MsgContractDeployReverted, txHash)
+	}
+
+	// A constructor that returns no runtime code deploys an empty account, which cannot be called
+	code, rpcErr := c.eth.GetCode(ctx, *receipt.ContractAddress, "latest")
+	if rpcErr != nil {
+		return nil, receipt, rpcErr.Error()
+	}
+	if len(code) == 0 {
+		return nil, receipt, i18n.NewError(ctx, signermsgs.MsgContractNoCode, receipt.ContractAddress, txHash)
+	}
+	return c.At(*receipt.ContractAddress), receipt, nil
+}
@@ -0,0 +1,167 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contract
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-signer/pkg/abi"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/fakechain"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+	"github.com/hyperledger/firefly-signer/pkg/secp256k1"
+	"github.com/stretchr/testify/assert"
+)
+
+var testBytecode = ethtypes.MustNewHexBytes0xPrefix("0x6080604052348015600f57600080fd5b50")
+
+const testConstructorABI = `[
+	{
+		"type": "constructor",
+		"inputs": [{"name": "supply", "type": "uint256"}]
+	},
+	{
+		"type": "error",
+		"name": "SupplyTooLarge",
+		"inputs": [{"name": "max", "type": "uint256"}]
+	}
+]`
+
+func newTestDeployClient(t *testing.T, options *fakechain.Options) (*Client, *fakechain.Chain, *testWallet) {
+	a, err := abi.ParseABI([]byte(testConstructorABI))
+	assert.NoError(t, err)
+	kp, err := secp256k1.GenerateSecp256k1KeyPair()
+	assert.NoError(t, err)
+	w := &testWallet{kp: kp}
+	chain := fakechain.New(options)
+	chain.Handle("eth_getCode", func(ctx context.Context, params []*fftypes.JSONAny) (interface{}, *rpcbackend.RPCError) {
+		assert.Equal(t, `"latest"`, params[1].String())
+		return ethtypes.MustNewHexBytes0xPrefix("0x6080"), nil
+	})
+	c, err := NewClient(context.Background(), &Config{ABI: a, RPC: chain, Wallet: w, ChainID: 1337})
+	assert.NoError(t, err)
+	return c, chain, w
+}
+
+func TestDeploy(t *testing.T) {
+	c, chain, w := newTestDeployClient(t, nil)
+
+	deployed, receipt, err := c.Deploy(context.Background(), w.kp.Address, testBytecode, []interface{}{1000})
+	assert.NoError(t, err)
+	assert.True(t, receipt.Succeeded())
+	assert.Equal(t, *receipt.ContractAddress, deployed.Address())
+	assert.Equal(t, c.ABI(), deployed.ABI())
+
+	txns := chain.Transactions()
+	assert.Len(t, txns, 1)
+	assert.Nil(t, txns[0].To)
+	assert.Equal(t, testBytecode.String()+"00000000000000000000000000000000000000000000000000000000000003e8", txns[0].Data.String())
+	assert.Equal(t, int64(100000), txns[0].GasLimit.Int64())
+	// The bytecode is not modified
+	assert.Equal(t, "0x6080604052348015600f57600080fd5b50", testBytecode.String())
+}
+
+func TestDeployNoConstructor(t *testing.T) {
+	c, chain, w := newTestDeployClient(t, nil)
+	c.abi = abi.ABI{}
+
+	_, _, err := c.Deploy(context.Background(), w.kp.Address, testBytecode, nil, &DeployOptions{
+		TransactOptions: TransactOptions{GasLimit: ethtypes.NewHexInteger64(50000)},
+		Receipt:         rpcbackend.ReceiptWaiterOptions{Confirmations: 0},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, testBytecode.String(), chain.Transactions()[0].Data.String())
+}
+
+func TestDeployNoWallet(t *testing.T) {
+	c, _, w := newTestDeployClient(t, nil)
+	c.wallet = nil
+	_, _, err := c.Deploy(context.Background(), w.kp.Address, testBytecode, []interface{}{1000})
+	assert.Regexp(t, "FF22254", err)
+}
+
+func TestDeployBadInputs(t *testing.T) {
+	c, _, w := newTestDeployClient(t, nil)
+	_, _, err := c.Deploy(context.Background(), w.kp.Address, testBytecode, []interface{}{"not a number"})
+	assert.Regexp(t, "FF22030", err)
+}
+
+func TestDeployEstimateRevert(t *testing.T) {
+	c, chain, w := newTestDeployClient(t, nil)
+	chain.Handle("eth_estimateGas", func(ctx context.Context, params []*fftypes.JSONAny) (interface{}, *rpcbackend.RPCError) {
+		var call rpcbackend.CallRequest
+		assert.NoError(t, json.Unmarshal(params[0].Bytes(), &call))
+		assert.Nil(t, call.To)
+		return nil, revertError(t, c.ABI(), "SupplyTooLarge", 100)
+	})
+	_, _, err := c.Deploy(context.Background(), w.kp.Address, testBytecode, []interface{}{1000})
+	assert.Regexp(t, `FF22261.*SupplyTooLarge\("100"\)`, err)
+}
+
+func TestDeploySendFail(t *testing.T) {
+	c, chain, w := newTestDeployClient(t, nil)
+	chain.Handle("eth_sendRawTransaction", func(ctx context.Context, params []*fftypes.JSONAny) (interface{}, *rpcbackend.RPCError) {
+		return nil, &rpcbackend.RPCError{Code: -32000, Message: "pop"}
+	})
+	_, _, err := c.Deploy(context.Background(), w.kp.Address, testBytecode, []interface{}{1000})
+	assert.Regexp(t, "pop", err)
+}
+
+func TestDeployReceiptTimeout(t *testing.T) {
+	c, _, w := newTestDeployClient(t, &fakechain.Options{ManualMining: true})
+	_, _, err := c.Deploy(context.Background(), w.kp.Address, testBytecode, []interface{}{1000}, &DeployOptions{
+		Receipt: rpcbackend.ReceiptWaiterOptions{PollInterval: time.Millisecond, Timeout: 10 * time.Millisecond},
+	})
+	assert.Regexp(t, "FF22174", err)
+}
+
+func TestDeployReverted(t *testing.T) {
+	c, chain, w := newTestDeployClient(t, nil)
+	chain.Handle("eth_getTransactionReceipt", func(ctx context.Context, params []*fftypes.JSONAny) (interface{}, *rpcbackend.RPCError) {
+		return &rpcbackend.Receipt{
+			BlockNumber: ethtypes.NewHexInteger64(1),
+			Status:      ethtypes.NewHexInteger64(0),
+		}, nil
+	})
+	_, receipt, err := c.Deploy(context.Background(), w.kp.Address, testBytecode, []interface{}{1000})
+	assert.Regexp(t, "FF22262", err)
+	assert.False(t, receipt.Succeeded())
+}
+
+func TestDeployGetCodeFail(t *testing.T) {
+	c, chain, w := newTestDeployClient(t, nil)
+	chain.Handle("eth_getCode", func(ctx context.Context, params []*fftypes.JSONAny) (interface{}, *rpcbackend.RPCError) {
+		return nil, &rpcbackend.RPCError{Code: -32000, Message: "pop"}
+	})
+	_, receipt, err := c.Deploy(context.Background(), w.kp.Address, testBytecode, []interface{}{1000})
+	assert.Regexp(t, "pop", err)
+	assert.True(t, receipt.Succeeded())
+}
+
+func TestDeployNoCode(t *testing.T) {
+	c, chain, w := newTestDeployClient(t, nil)
+	chain.Handle("eth_getCode", func(ctx context.Context, params []*fftypes.JSONAny) (interface{}, *rpcbackend.RPCError) {
+		return ethtypes.HexBytes0xPrefix{}, nil
+	})
+	_, receipt, err := c.Deploy(context.Background(), w.kp.Address, testBytecode, []interface{}{1000})
+	assert.Regexp(t, "FF22263", err)
+	assert.NotNil(t, receipt.ContractAddress)
+}
@@ -0,0 +1,111 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package debuglog switches on the detailed debug logging of individual modules at runtime, such as the
+// JSON/RPC payloads on the wire, without raising the log level of everything else.
+package debuglog
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-common/pkg/log"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/sirupsen/logrus"
+)
+
+// Module is a component with detailed debug logging that can be enabled on its own
+type Module string
+
+const (
+	// RPCWire logs every JSON/RPC payload sent to backends, and received from clients
+	RPCWire Module = "rpc"
+	// ABIDecode logs each value as ABI data is decoded, with its position in the data
+	ABIDecode Module = "abi"
+)
+
+// Modules lists every module, in the order they are reported
+var Modules = []Module{RPCWire, ABIDecode}
+
+var enabled = map[Module]*atomic.Bool{
+	RPCWire:   {},
+	ABIDecode: {},
+}
+
+// ParseModules checks every name is a module, so a list can be validated before it is applied
+func ParseModules(ctx context.Context, names []string) ([]Module, error) {
+	modules := make([]Module, 0, len(names))
+	for _, name := range names {
+		module := Module(strings.ToLower(strings.TrimSpace(name)))
+		if _, ok := enabled[module]; !ok {
+			return nil, i18n.NewError(ctx, signermsgs.MsgUnknownDebugLogModule, name, moduleNames())
+		}
+		modules = append(modules, module)
+	}
+	return modules, nil
+}
+
+// SetModules enables the debug logging of exactly the modules in the list, and disables it for the rest
+func SetModules(modules []Module) {
+	for _, module := range Modules {
+		enabled[module].Store(false)
+	}
+	for _, module := range modules {
+		enabled[module].Store(true)
+	}
+}
+
+// EnabledModules lists the modules with debug logging enabled
+func EnabledModules() []Module {
+	modules := []Module{}
+	for _, module := range Modules {
+		if Enabled(module) {
+			modules = append(modules, module)
+		}
+	}
+	return modules
+}
+
+// Enabled is true if the debug logging of the module has been switched on
+func Enabled(module Module) bool {
+	return enabled[module].Load()
+}
+
+// Active is true if the messages of the module will be logged, because the module is enabled or the
+// log level is trace, so callers can skip building messages that would be discarded
+func Active(module Module) bool {
+	return Enabled(module) || logrus.IsLevelEnabled(logrus.TraceLevel)
+}
+
+// Logf logs a detailed message of the module. When the module is enabled the message is logged at info level
+// with a "module" field, regardless of the log level. Otherwise it is only logged when the log level is trace.
+func Logf(ctx context.Context, module Module, format string, args ...interface{}) {
+	if Enabled(module) {
+		log.L(ctx).WithField("module", string(module)).Infof(format, args...)
+	} else {
+		log.L(ctx).Tracef(format, args...)
+	}
+}
+
+func moduleNames() string {
+	names := make([]string, len(Modules))
+	for i, module := range Modules {
+		names[i] = string(module)
+	}
+	return strings.Join(names, ", ")
+}
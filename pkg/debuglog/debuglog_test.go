@@ -0,0 +1,76 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package debuglog
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hyperledger/firefly-common/pkg/log"
+	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseModules(t *testing.T) {
+	modules, err := ParseModules(context.Background(), []string{"RPC", " abi "})
+	assert.NoError(t, err)
+	assert.Equal(t, []Module{RPCWire, ABIDecode}, modules)
+
+	_, err = ParseModules(context.Background(), []string{"rpc", "wrong"})
+	assert.Regexp(t, "FF22223.*wrong.*rpc, abi", err)
+}
+
+func TestSetModules(t *testing.T) {
+	defer SetModules(nil)
+
+	assert.Empty(t, EnabledModules())
+	SetModules([]Module{ABIDecode})
+	assert.True(t, Enabled(ABIDecode))
+	assert.False(t, Enabled(RPCWire))
+	assert.Equal(t, []Module{ABIDecode}, EnabledModules())
+
+	SetModules([]Module{RPCWire})
+	assert.Equal(t, []Module{RPCWire}, EnabledModules())
+}
+
+func TestLogf(t *testing.T) {
+	defer SetModules(nil)
+	defer log.SetLevel("info")
+	hook := logtest.NewGlobal()
+	defer logrus.StandardLogger().ReplaceHooks(logrus.LevelHooks{})
+	ctx := context.Background()
+
+	log.SetLevel("info")
+	assert.False(t, Active(RPCWire))
+	Logf(ctx, RPCWire, "discarded")
+	assert.Empty(t, hook.AllEntries())
+
+	SetModules([]Module{RPCWire})
+	assert.True(t, Active(RPCWire))
+	Logf(ctx, RPCWire, "wire %d", 1)
+	assert.Equal(t, "wire 1", hook.LastEntry().Message)
+	assert.Equal(t, logrus.InfoLevel, hook.LastEntry().Level)
+	assert.Equal(t, "rpc", hook.LastEntry().Data["module"])
+
+	SetModules(nil)
+	log.SetLevel("trace")
+	assert.True(t, Active(ABIDecode))
+	Logf(ctx, ABIDecode, "decode %d", 2)
+	assert.Equal(t, "decode 2", hook.LastEntry().Message)
+	assert.Equal(t, logrus.TraceLevel, hook.LastEntry().Level)
+}
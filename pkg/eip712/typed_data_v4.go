@@ -50,7 +50,24 @@ type TypeSet map[string]Type
 
 const EIP712Domain = "EIP712Domain"
 
+// TypedDataV4Hashes contains the hashes that make up the EIP-712 digest of a typed data payload
+type TypedDataV4Hashes struct {
+	DomainSeparator ethtypes.HexBytes0xPrefix `ffstruct:"TypedDataV4Hashes" json:"domainSeparator"`
+	StructHash      ethtypes.HexBytes0xPrefix `ffstruct:"TypedDataV4Hashes" json:"structHash,omitempty"`
+	Digest          ethtypes.HexBytes0xPrefix `ffstruct:"TypedDataV4Hashes" json:"digest"`
+}
+
 func EncodeTypedDataV4(ctx context.Context, payload *TypedData) (encoded ethtypes.HexBytes0xPrefix, err error) {
+	hashes, err := HashTypedDataV4(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	return hashes.Digest, nil
+}
+
+// HashTypedDataV4 returns the domain separator, the hash of the message (unless the primary type is
+// EIP712Domain), and the digest that is signed
+func HashTypedDataV4(ctx context.Context, payload *TypedData) (hashes *TypedDataV4Hashes, err error) {
 	// Add empty EIP712Domain type specification if missing
 	if payload.Types == nil {
 		payload.Types = TypeSet{}
@@ -64,31 +81,33 @@ func EncodeTypedDataV4(ctx context.Context, payload *TypedData) (encoded ethtype
 	if payload.PrimaryType == "" {
 		return nil, i18n.NewError(ctx, signermsgs.MsgEIP712PrimaryTypeRequired)
 	}
+	hashes = &TypedDataV4Hashes{}
 
 	// Start with the EIP-712 prefix
 	buf := new(bytes.Buffer)
 	buf.Write([]byte{0x19, 0x01})
 
 	// Encode EIP712Domain from message
-	domainHash, err := hashStruct(ctx, EIP712Domain, payload.Domain, payload.Types, "domain")
+	hashes.DomainSeparator, err = hashStruct(ctx, EIP712Domain, payload.Domain, payload.Types, "domain")
 	if err != nil {
 		return nil, err
 	}
-	buf.Write(domainHash)
+	buf.Write(hashes.DomainSeparator)
 
 	// If that wasn't the primary type, encode the primary type
 	if payload.PrimaryType != EIP712Domain {
 		// Encode the hash
-		structHash, err := hashStruct(ctx, payload.PrimaryType, payload.Message, payload.Types, "")
+		hashes.StructHash, err = hashStruct(ctx, payload.PrimaryType, payload.Message, payload.Types, "")
 		if err != nil {
 			return nil, err
 		}
-		buf.Write(structHash)
+		buf.Write(hashes.StructHash)
 	}
 
-	encoded = buf.Bytes()
+	encoded := buf.Bytes()
 	log.L(ctx).Tracef("Encoded EIP-712: %s", encoded)
-	return keccak256(encoded), nil
+	hashes.Digest = keccak256(encoded)
+	return hashes, nil
 }
 
 // A map from type names to types is encoded per encodeType:
@@ -86,6 +86,14 @@ func TestMessage_ExampleFromEIP712Spec(t *testing.T) {
 	hs, err := HashStruct(ctx, p.PrimaryType, p.Message, p.Types)
 	assert.NoError(t, err)
 	assert.Equal(t, "0xc52c0ee5d84264471806290a3f2c4cecfc5490626bf912d01f240d7a274b371e", hs.String())
+
+	ds, err := HashStruct(ctx, EIP712Domain, p.Domain, p.Types)
+	assert.NoError(t, err)
+	hashes, err := HashTypedDataV4(ctx, &p)
+	assert.NoError(t, err)
+	assert.Equal(t, ds, hashes.DomainSeparator)
+	assert.Equal(t, hs, hashes.StructHash)
+	assert.Equal(t, ed, hashes.Digest)
 }
 
 func TestMessage_EmptyMessage(t *testing.T) {
@@ -102,6 +110,11 @@ func TestMessage_EmptyMessage(t *testing.T) {
 	ed, err := EncodeTypedDataV4(ctx, &p)
 	assert.NoError(t, err)
 	assert.Equal(t, "0x8d4a3f4082945b7879e2b55f181c31a77c8c0a464b70669458abbaaf99de4c38", ed.String())
+
+	hashes, err := HashTypedDataV4(ctx, &p)
+	assert.NoError(t, err)
+	assert.Nil(t, hashes.StructHash)
+	assert.Equal(t, ed, hashes.Digest)
 }
 
 func TestMessage_EmptyDomain(t *testing.T) {
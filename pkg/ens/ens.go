@@ -0,0 +1,274 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ens resolves Ethereum Name Service (ENS) names to addresses, and addresses to their primary
+// names, by calling the ENS registry and resolver contracts through a JSON/RPC backend.
+package ens
+
+import (
+	"context"
+	"math/big"
+	"strings"
+
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/hyperledger/firefly-signer/pkg/abi"
+	"github.com/hyperledger/firefly-signer/pkg/contract"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+	"golang.org/x/crypto/sha3"
+)
+
+// DefaultRegistry is the address of the ENS registry, which is the same on mainnet and the public testnets
+const DefaultRegistry = "0x00000000000C2E074eC69A0dFb2997BA6C7d2e1e"
+
+// extendedResolverInterfaceID is the ERC-165 interface ID of resolve(bytes,bytes), from ENSIP-10
+var extendedResolverInterfaceID = []byte{0x90, 0x61, 0xb9, 0x23}
+
+var registryABI = abi.ABI{
+	{Type: abi.Function, Name: "resolver", StateMutability: "view",
+		Inputs:  abi.ParameterArray{{Name: "node", Type: "bytes32"}},
+		Outputs: abi.ParameterArray{{Type: "address"}}},
+}
+
+var resolverABI = abi.ABI{
+	{Type: abi.Function, Name: "supportsInterface", StateMutability: "view",
+		Inputs:  abi.ParameterArray{{Name: "interfaceID", Type: "bytes4"}},
+		Outputs: abi.ParameterArray{{Type: "bool"}}},
+	{Type: abi.Function, Name: "addr", StateMutability: "view",
+		Inputs:  abi.ParameterArray{{Name: "node", Type: "bytes32"}},
+		Outputs: abi.ParameterArray{{Type: "address"}}},
+	{Type: abi.Function, Name: "name", StateMutability: "view",
+		Inputs:  abi.ParameterArray{{Name: "node", Type: "bytes32"}},
+		Outputs: abi.ParameterArray{{Type: "string"}}},
+	{Type: abi.Function, Name: "resolve", StateMutability: "view",
+		Inputs:  abi.ParameterArray{{Name: "name", Type: "bytes"}, {Name: "data", Type: "bytes"}},
+		Outputs: abi.ParameterArray{{Type: "bytes"}}},
+	// Raised by resolvers that look up the answer offchain, as defined by EIP-3668
+	{Type: abi.Error, Name: "OffchainLookup", Inputs: abi.ParameterArray{
+		{Name: "sender", Type: "address"},
+		{Name: "urls", Type: "string[]"},
+		{Name: "callData", Type: "bytes"},
+		{Name: "callbackFunction", Type: "bytes4"},
+		{Name: "extraData", Type: "bytes"},
+	}},
+}
+
+// Config is the backend and registry a Resolver uses
+type Config struct {
+	RPC      rpcbackend.RPC
+	Registry *ethtypes.Address0xHex // defaults to DefaultRegistry
+	// CCIPRead follows the offchain lookups of resolvers that answer from an offchain gateway - not followed when nil
+	CCIPRead *contract.CCIPReadOptions
+}
+
+// Resolver resolves ENS names against the registry. Resolvers that support ENSIP-10 wildcard resolution
+// (resolve(bytes,bytes)) are called with the DNS encoded name, so names with no resolver of their own
+// resolve with the resolver of their closest parent. The OffchainLookup revert of a resolver that answers
+// from an offchain gateway (EIP-3668) is followed when CCIP-Read is enabled, and otherwise decoded in the
+// error of the call.
+type Resolver struct {
+	registry *contract.Client
+	resolver *contract.Client // bound to the address of each resolver with At
+}
+
+// NewResolver returns a resolver that calls the registry through the backend
+func NewResolver(ctx context.Context, conf *Config) (*Resolver, error) {
+	registryAddress := conf.Registry
+	if registryAddress == nil {
+		registryAddress = ethtypes.MustNewAddress(DefaultRegistry)
+	}
+	registry, err := contract.NewClient(ctx, &contract.Config{Address: *registryAddress, ABI: registryABI, RPC: conf.RPC})
+	if err != nil {
+		return nil, err
+	}
+	resolver, err := contract.NewClient(ctx, &contract.Config{ABI: resolverABI, RPC: conf.RPC, CCIPRead: conf.CCIPRead})
+	if err != nil {
+		return nil, err
+	}
+	return &Resolver{registry: registry, resolver: resolver}, nil
+}
+
+// IsName returns true for a string that is an ENS name, rather than an address - it has a dot in it,
+// and does not parse as an address
+func IsName(s string) bool {
+	if !strings.Contains(s, ".") {
+		return false
+	}
+	_, err := ethtypes.NewAddress(s)
+	return err != nil
+}
+
+// Normalize lower-cases a name, and checks it has no empty labels. Names with unicode characters must be
+// normalized as defined by ENSIP-15 by the caller.
+func Normalize(ctx context.Context, name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return "", i18n.NewError(ctx, signermsgs.MsgENSInvalidName, name, "empty")
+	}
+	for _, label := range strings.Split(name, ".") {
+		switch {
+		case label == "":
+			return "", i18n.NewError(ctx, signermsgs.MsgENSInvalidName, name, "empty label")
+		case len(label) > 255:
+			return "", i18n.NewError(ctx, signermsgs.MsgENSInvalidName, name, "label longer than 255 bytes")
+		}
+	}
+	return name, nil
+}
+
+// Namehash returns the node of a normalized name, as defined by EIP-137
+func Namehash(name string) ethtypes.HexBytes0xPrefix {
+	node := make([]byte, 32)
+	if name == "" {
+		return node
+	}
+	labels := strings.Split(name, ".")
+	for i := len(labels) - 1; i >= 0; i-- {
+		node = keccak256(node, keccak256([]byte(labels[i])))
+	}
+	return node
+}
+
+// DNSEncode returns a normalized name in the DNS wire format - each label prefixed with its length,
+// terminated by a zero length label - as passed to resolve(bytes,bytes) by ENSIP-10
+func DNSEncode(name string) []byte {
+	encoded := make([]byte, 0, len(name)+2)
+	for _, label := range strings.Split(name, ".") {
+		encoded = append(encoded, byte(len(label)))
+		encoded = append(encoded, label...)
+	}
+	return append(encoded, 0)
+}
+
+// Resolve returns the address a name resolves to, returning an error if it has no resolver, or
+// resolves to the zero address
+func (r *Resolver) Resolve(ctx context.Context, name string) (*ethtypes.Address0xHex, error) {
+	name, err := Normalize(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	node := Namehash(name)
+	resolver, exact, err := r.findResolver(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if resolver == nil {
+		return nil, i18n.NewError(ctx, signermsgs.MsgENSNameNotResolved, name)
+	}
+
+	addrEntry, _ := resolver.Function(ctx, "addr")
+	var cv *abi.ComponentValue
+	extended, err := r.isExtended(ctx, resolver)
+	switch {
+	case err != nil:
+		return nil, err
+	case extended:
+		addrCall, _ := addrEntry.EncodeCallDataValuesCtx(ctx, []interface{}{[]byte(node)})
+		if cv, err = resolver.Call(ctx, "resolve", []interface{}{DNSEncode(name), addrCall}); err != nil {
+			return nil, err
+		}
+		if cv, err = addrEntry.Outputs.DecodeABIDataCtx(ctx, cv.Children[0].Value.([]byte), 0); err != nil {
+			return nil, err
+		}
+	case exact:
+		if cv, err = resolver.Call(ctx, "addr", []interface{}{[]byte(node)}); err != nil {
+			return nil, err
+		}
+	default:
+		// Only a resolver that supports wildcards can resolve a name for its parent
+		return nil, i18n.NewError(ctx, signermsgs.MsgENSNameNotResolved, name)
+	}
+
+	addr := addressValue(cv)
+	if addr == nil {
+		return nil, i18n.NewError(ctx, signermsgs.MsgENSNameNotResolved, name)
+	}
+	return addr, nil
+}
+
+// ReverseResolve returns the primary name of an address, set in its reverse record. The name is only
+// returned if it resolves back to the address, as anyone can set any name in the reverse record of
+// their own address.
+func (r *Resolver) ReverseResolve(ctx context.Context, addr ethtypes.Address0xHex) (string, error) {
+	node := Namehash(strings.TrimPrefix(addr.String(), "0x") + ".addr.reverse")
+	cv, err := r.registry.Call(ctx, "resolver", []interface{}{[]byte(node)})
+	if err != nil {
+		return "", err
+	}
+	resolverAddress := addressValue(cv)
+	if resolverAddress == nil {
+		return "", i18n.NewError(ctx, signermsgs.MsgENSNoReverseName, addr)
+	}
+	if cv, err = r.resolver.At(*resolverAddress).Call(ctx, "name", []interface{}{[]byte(node)}); err != nil {
+		return "", err
+	}
+	name := cv.Children[0].Value.(string)
+	if name == "" {
+		return "", i18n.NewError(ctx, signermsgs.MsgENSNoReverseName, addr)
+	}
+	resolved, err := r.Resolve(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	if *resolved != addr {
+		return "", i18n.NewError(ctx, signermsgs.MsgENSReverseNameMismatch, name, addr, resolved)
+	}
+	return name, nil
+}
+
+// findResolver finds the resolver of the name, or of its closest parent with a resolver, as ENSIP-10 defines.
+// exact is true when the resolver is set for the name itself.
+func (r *Resolver) findResolver(ctx context.Context, name string) (resolver *contract.Client, exact bool, err error) {
+	for parent := name; parent != ""; {
+		cv, err := r.registry.Call(ctx, "resolver", []interface{}{[]byte(Namehash(parent))})
+		if err != nil {
+			return nil, false, err
+		}
+		if addr := addressValue(cv); addr != nil {
+			return r.resolver.At(*addr), parent == name, nil
+		}
+		_, parent, _ = strings.Cut(parent, ".")
+	}
+	return nil, false, nil
+}
+
+// isExtended checks with ERC-165 if the resolver supports ENSIP-10 wildcard resolution
+func (r *Resolver) isExtended(ctx context.Context, resolver *contract.Client) (bool, error) {
+	cv, err := resolver.Call(ctx, "supportsInterface", []interface{}{extendedResolverInterfaceID})
+	if err != nil {
+		return false, err
+	}
+	return cv.Children[0].Value.(*big.Int).Sign() != 0, nil
+}
+
+// addressValue returns the address output of a call, or nil for the zero address
+func addressValue(cv *abi.ComponentValue) *ethtypes.Address0xHex {
+	var addr ethtypes.Address0xHex
+	cv.Children[0].Value.(*big.Int).FillBytes(addr[:])
+	if addr == (ethtypes.Address0xHex{}) {
+		return nil
+	}
+	return &addr
+}
+
+func keccak256(data ...[]byte) []byte {
+	hash := sha3.NewLegacyKeccak256()
+	for _, d := range data {
+		hash.Write(d)
+	}
+	return hash.Sum(nil)
+}
@@ -0,0 +1,373 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ens
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-signer/pkg/abi"
+	"github.com/hyperledger/firefly-signer/pkg/contract"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/fakechain"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	publicResolver   = *ethtypes.MustNewAddress("0x231b0ee14048e9dccd1d247744d114a4eb5e8e63")
+	wildcardResolver = *ethtypes.MustNewAddress("0x4976fb03c32e5b8cfe2b6ccb31c09ba78ebaba41")
+	vitalik          = *ethtypes.MustNewAddress("0xd8da6bf26964af9d7eed9e10c48e8b66bd00f7ac")
+	other            = *ethtypes.MustNewAddress("0x497eedc4299dea2f2a364be10025d0ad0f702de3")
+)
+
+// fakeResolver is a resolver contract, that is a wildcard resolver when it has a wildcard address
+type fakeResolver struct {
+	addrs          map[string]ethtypes.Address0xHex
+	names          map[string]string
+	wildcard       *ethtypes.Address0xHex
+	badResolveData bool
+}
+
+// fakeENS answers eth_call for the registry and its resolvers
+type fakeENS struct {
+	t         *testing.T
+	resolvers map[string]ethtypes.Address0xHex // by node
+	contracts map[ethtypes.Address0xHex]*fakeResolver
+	revert    map[string]bool // function names that revert
+}
+
+func newFakeENS(t *testing.T) (*Resolver, *fakechain.Chain, *fakeENS) {
+	f := &fakeENS{
+		t: t,
+		resolvers: map[string]ethtypes.Address0xHex{
+			Namehash("vitalik.eth").String(): publicResolver,
+			Namehash("cb.id").String():       wildcardResolver,
+			Namehash("d8da6bf26964af9d7eed9e10c48e8b66bd00f7ac.addr.reverse").String(): publicResolver,
+			Namehash("497eedc4299dea2f2a364be10025d0ad0f702de3.addr.reverse").String(): publicResolver,
+		},
+		contracts: map[ethtypes.Address0xHex]*fakeResolver{
+			publicResolver: {
+				addrs: map[string]ethtypes.Address0xHex{
+					Namehash("vitalik.eth").String(): vitalik,
+				},
+				names: map[string]string{
+					Namehash("d8da6bf26964af9d7eed9e10c48e8b66bd00f7ac.addr.reverse").String(): "Vitalik.eth",
+					Namehash("497eedc4299dea2f2a364be10025d0ad0f702de3.addr.reverse").String(): "vitalik.eth",
+				},
+			},
+			wildcardResolver: {
+				addrs: map[string]ethtypes.Address0xHex{
+					Namehash("alice.cb.id").String(): other,
+				},
+				wildcard: &other,
+			},
+		},
+		revert: map[string]bool{},
+	}
+	chain := fakechain.New(nil)
+	chain.Handle("eth_call", f.ethCall)
+	r, err := NewResolver(context.Background(), &Config{RPC: chain})
+	assert.NoError(t, err)
+	return r, chain, f
+}
+
+func (f *fakeENS) ethCall(ctx context.Context, params []*fftypes.JSONAny) (interface{}, *rpcbackend.RPCError) {
+	var call rpcbackend.CallRequest
+	assert.NoError(f.t, json.Unmarshal(params[0].Bytes(), &call))
+	a := resolverABI
+	if *call.To == *ethtypes.MustNewAddress(DefaultRegistry) {
+		a = registryABI
+	}
+	for _, e := range a {
+		if e.IsFunction() && bytes.Equal(e.FunctionSelectorBytes(), call.Data[0:4]) {
+			if f.revert[e.Name] {
+				return nil, &rpcbackend.RPCError{Code: 3, Message: "execution reverted"}
+			}
+			cv, err := e.DecodeCallDataCtx(ctx, call.Data)
+			assert.NoError(f.t, err)
+			return f.respond(ctx, e, f.contracts[*call.To], cv), nil
+		}
+	}
+	return ethtypes.HexBytes0xPrefix{}, nil
+}
+
+func (f *fakeENS) respond(ctx context.Context, e *abi.Entry, resolver *fakeResolver, cv *abi.ComponentValue) ethtypes.HexBytes0xPrefix {
+	var result interface{}
+	switch e.Name {
+	case "resolver":
+		addr := f.resolvers[ethtypes.HexBytes0xPrefix(cv.Children[0].Value.([]byte)).String()]
+		result = addr.String()
+	case "supportsInterface":
+		result = resolver.wildcard != nil && bytes.Equal(cv.Children[0].Value.([]byte), extendedResolverInterfaceID)
+	case "addr":
+		addr := resolver.addrs[ethtypes.HexBytes0xPrefix(cv.Children[0].Value.([]byte)).String()]
+		result = addr.String()
+	case "name":
+		result = resolver.names[ethtypes.HexBytes0xPrefix(cv.Children[0].Value.([]byte)).String()]
+	case "resolve":
+		if resolver.badResolveData {
+			result = []byte{0x01}
+			break
+		}
+		addrEntry, _ := resolverABI.EntryByName("addr")
+		addrCall, err := addrEntry.DecodeCallDataCtx(ctx, cv.Children[1].Value.([]byte))
+		assert.NoError(f.t, err)
+		addr, ok := resolver.addrs[ethtypes.HexBytes0xPrefix(addrCall.Children[0].Value.([]byte)).String()]
+		if !ok {
+			addr = *resolver.wildcard
+		}
+		result, _ = addrEntry.Outputs.EncodeABIDataValuesCtx(ctx, []interface{}{addr.String()})
+	}
+	data, err := e.Outputs.EncodeABIDataValuesCtx(ctx, []interface{}{result})
+	assert.NoError(f.t, err)
+	return data
+}
+
+func TestNamehash(t *testing.T) {
+	assert.Equal(t, "0x0000000000000000000000000000000000000000000000000000000000000000", Namehash("").String())
+	assert.Equal(t, "0x93cdeb708b7545dc668eb9280176169d1c33cfd8ed6f04690a0bcc88a93fc4ae", Namehash("eth").String())
+	assert.Equal(t, "0xde9b09fd7c5f901e23a3f19fecc54828e9c848539801e86591bd9801b019f84f", Namehash("foo.eth").String())
+}
+
+func TestDNSEncode(t *testing.T) {
+	assert.Equal(t, "0x03666f6f0365746800", ethtypes.HexBytes0xPrefix(DNSEncode("foo.eth")).String())
+}
+
+func TestNormalize(t *testing.T) {
+	ctx := context.Background()
+	name, err := Normalize(ctx, " Vitalik.ETH ")
+	assert.NoError(t, err)
+	assert.Equal(t, "vitalik.eth", name)
+
+	_, err = Normalize(ctx, "")
+	assert.Regexp(t, "FF22256.*empty", err)
+
+	_, err = Normalize(ctx, "vitalik..eth")
+	assert.Regexp(t, "FF22256.*empty label", err)
+
+	_, err = Normalize(ctx, strings.Repeat("a", 256)+".eth")
+	assert.Regexp(t, "FF22256.*255", err)
+}
+
+func TestIsName(t *testing.T) {
+	assert.True(t, IsName("vitalik.eth"))
+	assert.False(t, IsName("vitalik"))
+	assert.False(t, IsName(vitalik.String()))
+	assert.False(t, IsName(""))
+}
+
+func TestNewResolverInvalidABI(t *testing.T) {
+	defer func(a abi.ABI) { registryABI = a }(registryABI)
+	registryABI = abi.ABI{{Type: abi.Function, Name: "bad", Inputs: abi.ParameterArray{{Type: "uint7"}}}}
+	_, err := NewResolver(context.Background(), &Config{})
+	assert.Regexp(t, "FF22028", err)
+}
+
+func TestNewResolverInvalidResolverABI(t *testing.T) {
+	defer func(a abi.ABI) { resolverABI = a }(resolverABI)
+	resolverABI = abi.ABI{{Type: abi.Function, Name: "bad", Inputs: abi.ParameterArray{{Type: "uint7"}}}}
+	_, err := NewResolver(context.Background(), &Config{})
+	assert.Regexp(t, "FF22028", err)
+}
+
+func TestNewResolverCustomRegistry(t *testing.T) {
+	r, err := NewResolver(context.Background(), &Config{Registry: &other})
+	assert.NoError(t, err)
+	assert.Equal(t, other, r.registry.Address())
+}
+
+func TestResolve(t *testing.T) {
+	r, _, _ := newFakeENS(t)
+	addr, err := r.Resolve(context.Background(), "Vitalik.eth")
+	assert.NoError(t, err)
+	assert.Equal(t, vitalik, *addr)
+}
+
+func TestResolveWildcard(t *testing.T) {
+	r, _, _ := newFakeENS(t)
+	ctx := context.Background()
+
+	// The resolver of the parent resolves any name under it
+	addr, err := r.Resolve(ctx, "bob.cb.id")
+	assert.NoError(t, err)
+	assert.Equal(t, other, *addr)
+
+	// As well as the names it has records for, through resolve(bytes,bytes)
+	addr, err = r.Resolve(ctx, "alice.cb.id")
+	assert.NoError(t, err)
+	assert.Equal(t, other, *addr)
+}
+
+func TestResolveWildcardCCIPRead(t *testing.T) {
+	ctx := context.Background()
+	resolveEntry, _ := resolverABI.EntryByName("resolve")
+	addrEntry, _ := resolverABI.EntryByName("addr")
+	lookupEntry, _ := resolverABI.EntryByName("OffchainLookup")
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The gateway answers with the outputs of resolve(bytes,bytes), which the callback returns
+		addrResult, _ := addrEntry.Outputs.EncodeABIDataValuesCtx(ctx, []interface{}{vitalik.String()})
+		data, _ := resolveEntry.Outputs.EncodeABIDataValuesCtx(ctx, []interface{}{addrResult})
+		_, _ = fmt.Fprintf(w, `{"data": "%s"}`, ethtypes.HexBytes0xPrefix(data))
+	}))
+	defer gateway.Close()
+
+	_, chain, f := newFakeENS(t)
+	chain.Handle("eth_call", func(ctx context.Context, params []*fftypes.JSONAny) (interface{}, *rpcbackend.RPCError) {
+		var call rpcbackend.CallRequest
+		assert.NoError(t, json.Unmarshal(params[0].Bytes(), &call))
+		switch {
+		case bytes.Equal(call.Data[0:4], resolveEntry.FunctionSelectorBytes()):
+			revertData, err := lookupEntry.EncodeCallDataValuesCtx(ctx, []interface{}{wildcardResolver.String(), []string{gateway.URL}, "0x", "0x12345678", "0x"})
+			assert.NoError(t, err)
+			return nil, &rpcbackend.RPCError{Code: 3, Message: "execution reverted", Data: *fftypes.JSONAnyPtr(`"` + ethtypes.HexBytes0xPrefix(revertData).String() + `"`)}
+		case call.Data[0:4].String() == "0x12345678":
+			cv, err := abi.ParameterArray{{Type: "bytes"}, {Type: "bytes"}}.DecodeABIDataCtx(ctx, call.Data, 4)
+			assert.NoError(t, err)
+			return ethtypes.HexBytes0xPrefix(cv.Children[0].Value.([]byte)), nil
+		default:
+			return f.ethCall(ctx, params)
+		}
+	})
+
+	r, err := NewResolver(ctx, &Config{RPC: chain})
+	assert.NoError(t, err)
+	_, err = r.Resolve(ctx, "bob.cb.id")
+	assert.Regexp(t, "FF22253.*OffchainLookup", err)
+
+	r, err = NewResolver(ctx, &Config{RPC: chain, CCIPRead: &contract.CCIPReadOptions{AllowHTTP: true}})
+	assert.NoError(t, err)
+	addr, err := r.Resolve(ctx, "bob.cb.id")
+	assert.NoError(t, err)
+	assert.Equal(t, vitalik, *addr)
+}
+
+func TestResolveWildcardBadData(t *testing.T) {
+	r, _, f := newFakeENS(t)
+	f.contracts[wildcardResolver].badResolveData = true
+	_, err := r.Resolve(context.Background(), "bob.cb.id")
+	assert.Regexp(t, "FF22047", err)
+}
+
+func TestResolveWildcardFail(t *testing.T) {
+	r, _, f := newFakeENS(t)
+	f.revert["resolve"] = true
+	_, err := r.Resolve(context.Background(), "bob.cb.id")
+	assert.Regexp(t, "FF22253.*resolve", err)
+}
+
+func TestResolveNoResolver(t *testing.T) {
+	r, _, _ := newFakeENS(t)
+	_, err := r.Resolve(context.Background(), "nobody.eth")
+	assert.Regexp(t, "FF22257.*nobody.eth", err)
+}
+
+func TestResolveParentNotWildcard(t *testing.T) {
+	r, _, _ := newFakeENS(t)
+	_, err := r.Resolve(context.Background(), "sub.vitalik.eth")
+	assert.Regexp(t, "FF22257.*sub.vitalik.eth", err)
+}
+
+func TestResolveZeroAddress(t *testing.T) {
+	r, _, f := newFakeENS(t)
+	f.resolvers[Namehash("empty.eth").String()] = publicResolver
+	_, err := r.Resolve(context.Background(), "empty.eth")
+	assert.Regexp(t, "FF22257.*empty.eth", err)
+}
+
+func TestResolveInvalidName(t *testing.T) {
+	r, _, _ := newFakeENS(t)
+	_, err := r.Resolve(context.Background(), ".eth")
+	assert.Regexp(t, "FF22256", err)
+}
+
+func TestResolveRegistryFail(t *testing.T) {
+	r, _, f := newFakeENS(t)
+	f.revert["resolver"] = true
+	_, err := r.Resolve(context.Background(), "vitalik.eth")
+	assert.Regexp(t, "FF22253.*resolver", err)
+}
+
+func TestResolveSupportsInterfaceFail(t *testing.T) {
+	r, _, f := newFakeENS(t)
+	f.revert["supportsInterface"] = true
+	_, err := r.Resolve(context.Background(), "vitalik.eth")
+	assert.Regexp(t, "FF22253.*supportsInterface", err)
+}
+
+func TestResolveAddrFail(t *testing.T) {
+	r, _, f := newFakeENS(t)
+	f.revert["addr"] = true
+	_, err := r.Resolve(context.Background(), "vitalik.eth")
+	assert.Regexp(t, "FF22253.*addr", err)
+}
+
+func TestReverseResolve(t *testing.T) {
+	r, _, _ := newFakeENS(t)
+	name, err := r.ReverseResolve(context.Background(), vitalik)
+	assert.NoError(t, err)
+	assert.Equal(t, "Vitalik.eth", name)
+}
+
+func TestReverseResolveMismatch(t *testing.T) {
+	r, _, _ := newFakeENS(t)
+	_, err := r.ReverseResolve(context.Background(), other)
+	assert.Regexp(t, "FF22259.*vitalik.eth.*0x497eedc4299dea2f2a364be10025d0ad0f702de3.*0xd8da6bf26964af9d7eed9e10c48e8b66bd00f7ac", err)
+}
+
+func TestReverseResolveNoResolver(t *testing.T) {
+	r, _, _ := newFakeENS(t)
+	addr := *ethtypes.MustNewAddress("0x1f185718734552d08278aa70f804580bab5fd2b4")
+	_, err := r.ReverseResolve(context.Background(), addr)
+	assert.Regexp(t, "FF22258", err)
+}
+
+func TestReverseResolveNoName(t *testing.T) {
+	r, _, f := newFakeENS(t)
+	addr := *ethtypes.MustNewAddress("0x1f185718734552d08278aa70f804580bab5fd2b4")
+	f.resolvers[Namehash("1f185718734552d08278aa70f804580bab5fd2b4.addr.reverse").String()] = publicResolver
+	_, err := r.ReverseResolve(context.Background(), addr)
+	assert.Regexp(t, "FF22258", err)
+}
+
+func TestReverseResolveBadName(t *testing.T) {
+	r, _, f := newFakeENS(t)
+	f.contracts[publicResolver].names[Namehash("d8da6bf26964af9d7eed9e10c48e8b66bd00f7ac.addr.reverse").String()] = "vitalik..eth"
+	_, err := r.ReverseResolve(context.Background(), vitalik)
+	assert.Regexp(t, "FF22256", err)
+}
+
+func TestReverseResolveRegistryFail(t *testing.T) {
+	r, _, f := newFakeENS(t)
+	f.revert["resolver"] = true
+	_, err := r.ReverseResolve(context.Background(), vitalik)
+	assert.Regexp(t, "FF22253", err)
+}
+
+func TestReverseResolveNameFail(t *testing.T) {
+	r, _, f := newFakeENS(t)
+	f.revert["name"] = true
+	_, err := r.ReverseResolve(context.Background(), vitalik)
+	assert.Regexp(t, "FF22253.*name", err)
+}
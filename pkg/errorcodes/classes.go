@@ -0,0 +1,526 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errorcodes
+
+import (
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+)
+
+const (
+	// InvalidInput an argument or input value is invalid
+	InvalidInput Code = "FFS-INPUT-001"
+	// InvalidRequest a JSON/RPC or admin API request is malformed
+	InvalidRequest Code = "FFS-RPC-001"
+	// Unsupported the request is not supported by this server
+	Unsupported Code = "FFS-RPC-002"
+	// RequestCanceled the request timed out, or was canceled before it completed
+	RequestCanceled Code = "FFS-RPC-003"
+	// ABIInsufficientData ABI encoded data ended before all the values could be read
+	ABIInsufficientData Code = "FFS-ABI-001"
+	// ABIInvalidType an ABI type or definition is invalid or unsupported
+	ABIInvalidType Code = "FFS-ABI-002"
+	// ABIInvalidValue a value cannot be ABI encoded as the type of its component
+	ABIInvalidValue Code = "FFS-ABI-003"
+	// ABIInvalidData ABI encoded data does not match the definition it is decoded with
+	ABIInvalidData Code = "FFS-ABI-004"
+	// ABIEntryNotFound no entry, or more than one entry, in the ABI matches the name
+	ABIEntryNotFound Code = "FFS-ABI-005"
+	// FFIInvalid a FireFly Interface (FFI) definition is invalid
+	FFIInvalid Code = "FFS-ABI-006"
+	// EIP712InvalidTypedData EIP-712 typed data is invalid
+	EIP712InvalidTypedData Code = "FFS-EIP712-001"
+	// TxInvalid a transaction is invalid or cannot be decoded
+	TxInvalid Code = "FFS-TX-001"
+	// TxChainIDMismatch a chain ID does not match the chain the signer signs for
+	TxChainIDMismatch Code = "FFS-TX-002"
+	// TxRejectedByNode the node rejected the transaction when it was simulated, or its gas was estimated
+	TxRejectedByNode Code = "FFS-TX-003"
+	// TxReceiptTimeout the transaction was not mined in time
+	TxReceiptTimeout Code = "FFS-TX-004"
+	// SignatureInvalid a signature is malformed, or not in canonical form
+	SignatureInvalid Code = "FFS-SIG-001"
+	// SignatureMismatch a signature was not produced by the expected key
+	SignatureMismatch Code = "FFS-SIG-002"
+	// PublicKeyInvalid a public key is invalid
+	PublicKeyInvalid Code = "FFS-SIG-003"
+	// SigningFailed signing failed
+	SigningFailed Code = "FFS-SIG-004"
+	// WalletKeyNotAvailable the wallet has no usable key for the address
+	WalletKeyNotAvailable Code = "FFS-WALLET-001"
+	// WalletKeyLocked the key for the address must be unlocked before it can sign
+	WalletKeyLocked Code = "FFS-WALLET-002"
+	// WalletUnsupported the wallet does not support the operation
+	WalletUnsupported Code = "FFS-WALLET-003"
+	// WalletStorageFailed the wallet could not read or write its files
+	WalletStorageFailed Code = "FFS-WALLET-004"
+	// WalletKeyManagementRejected a key cannot be created, or its password changed, as requested
+	WalletKeyManagementRejected Code = "FFS-WALLET-005"
+	// KeyCeremonyRejected the key ceremony is not in a state that allows the operation, or the caller cannot perform it
+	KeyCeremonyRejected Code = "FFS-WALLET-006"
+	// Unauthenticated the caller could not be authenticated
+	Unauthenticated Code = "FFS-AUTH-001"
+	// Unauthorized the caller is not authorized for the method or address
+	Unauthorized Code = "FFS-AUTH-002"
+	// PolicyDisabled the method or feature is disabled on this server
+	PolicyDisabled Code = "FFS-POLICY-001"
+	// PolicyTxRejected the transaction was rejected by the transaction policy or fee caps
+	PolicyTxRejected Code = "FFS-POLICY-002"
+	// ApprovalRejected the signing request was rejected by its approver, or was not approved in time
+	ApprovalRejected Code = "FFS-APPROVAL-001"
+	// ApprovalFailed the signing approval hook failed, or returned an invalid decision
+	ApprovalFailed Code = "FFS-APPROVAL-002"
+	// RateLimited too many requests, or transactions waiting to be submitted - retry later
+	RateLimited Code = "FFS-LIMIT-001"
+	// RequestTooLarge the request exceeds a size limit
+	RequestTooLarge Code = "FFS-LIMIT-002"
+	// BackendFailed a request to the backend node failed
+	BackendFailed Code = "FFS-BACKEND-001"
+	// BackendUnavailable the backend is unavailable, as its circuit breaker is open - retry later
+	BackendUnavailable Code = "FFS-BACKEND-002"
+	// BackendInvalidResponse the backend returned a response that could not be parsed
+	BackendInvalidResponse Code = "FFS-BACKEND-003"
+	// NonceStoreFailed the nonce manager could not read, write or fill nonces
+	NonceStoreFailed Code = "FFS-NONCE-001"
+	// PersistenceFailed the state of the signer could not be read from, or written to, its persistence
+	PersistenceFailed Code = "FFS-STORE-001"
+	// AuditFailed an audit event could not be delivered
+	AuditFailed Code = "FFS-AUDIT-001"
+	// FeeSourceFailed a gas price source failed, or returned fees that could not be parsed
+	FeeSourceFailed Code = "FFS-FEES-001"
+	// CCIPReadFailed an offchain lookup (EIP-3668 CCIP-Read) of a contract call failed
+	CCIPReadFailed Code = "FFS-CCIP-001"
+	// ConfigInvalid the configuration is invalid
+	ConfigInvalid Code = "FFS-CONFIG-001"
+	// ConfigReloadFailed the configuration could not be reloaded, so the previous configuration remains in effect
+	ConfigReloadFailed Code = "FFS-CONFIG-002"
+	// ConfigInsecure files in the configuration can be accessed by other users
+	ConfigInsecure Code = "FFS-CONFIG-003"
+	// ListenFailed the server could not listen for requests
+	ListenFailed Code = "FFS-SERVER-001"
+)
+
+// classes lists every class, grouped by area. Every message key must belong to exactly one class,
+// and codes must never be reused or renumbered.
+var classes = []*Class{
+	{InvalidInput, "An argument or input value is invalid", []i18n.ErrorMessageKey{
+		signermsgs.MsgInvalidOutputType,
+		signermsgs.MsgUnknownTupleSerializer,
+		signermsgs.MsgInvalidNumberString,
+		signermsgs.MsgInvalidIntPrecisionLoss,
+		signermsgs.MsgInvalidUint64PrecisionLoss,
+		signermsgs.MsgInvalidJSONTypeForBigInt,
+		signermsgs.MsgInvalidDigestLength,
+		signermsgs.MsgInvalidExtraEntropy,
+		signermsgs.MsgChainIDRequired,
+		signermsgs.MsgReadInputFailed,
+		signermsgs.MsgInvalidHexInput,
+		signermsgs.MsgInvalidCommandOption,
+		signermsgs.MsgInvalidLogLevel,
+		signermsgs.MsgUnknownMessageKey,
+		signermsgs.MsgMessageInsertsMismatch,
+		signermsgs.MsgInvalidTestVectorFixtures,
+		signermsgs.MsgTestVectorKind,
+		signermsgs.MsgTestVectorUnknownKey,
+		signermsgs.MsgTestVectorInvalidKey,
+		signermsgs.MsgTestVectorKeyRequired,
+		signermsgs.MsgTestVectorInvalidTxType,
+		signermsgs.MsgTestVectorInvalidRLP,
+		signermsgs.MsgTestVectorFailed,
+		signermsgs.MsgTestVectorIncomplete,
+		signermsgs.MsgTokenUnknownStandard,
+		signermsgs.MsgENSInvalidName,
+		signermsgs.MsgENSNameNotResolved,
+		signermsgs.MsgENSNoReverseName,
+		signermsgs.MsgENSReverseNameMismatch,
+		signermsgs.MsgVanityNoPattern,
+		signermsgs.MsgVanityInvalidPattern,
+		signermsgs.MsgVanityInvalidRegexp,
+		signermsgs.MsgVanityInvalidInitCodeHash,
+	}},
+	{InvalidRequest, "A JSON/RPC or admin API request is malformed", []i18n.ErrorMessageKey{
+		signermsgs.MsgInvalidParam,
+		signermsgs.MsgInvalidRequest,
+		signermsgs.MsgInvalidParamCount,
+		signermsgs.MsgUnlockPassphraseRequired,
+		signermsgs.MsgMissingRequestID,
+		signermsgs.MsgInvalidAdminRequest,
+		signermsgs.MsgKeyCeremonyInvalid,
+		signermsgs.MsgKeyCeremonyNotFound,
+	}},
+	{Unsupported, "The request is not supported by this server", []i18n.ErrorMessageKey{
+		signermsgs.MsgSubscriptionsNotSupported,
+		signermsgs.MsgUnknownChain,
+		signermsgs.MsgFakeChainMethodNotFound,
+	}},
+	{RequestCanceled, "The request timed out, or was canceled before it completed", []i18n.ErrorMessageKey{
+		signermsgs.MsgRequestCanceledContext,
+		signermsgs.MsgContextCancelledWSConnect,
+		signermsgs.MsgRequestTimedOut,
+		signermsgs.MsgSenderQueueWaitCanceled,
+		signermsgs.MsgVanitySearchCanceled,
+		signermsgs.MsgSigningApprovalCanceled,
+	}},
+	{ABIInsufficientData, "ABI encoded data ended before all the values could be read", []i18n.ErrorMessageKey{
+		signermsgs.MsgNotEnoughBytesABIArrayCount,
+		signermsgs.MsgNotEnoughBytesABIValue,
+		signermsgs.MsgNotEnoughBytesABISignature,
+		signermsgs.MsgEventsInsufficientTopics,
+	}},
+	{ABIInvalidType, "An ABI type or definition is invalid or unsupported", []i18n.ErrorMessageKey{
+		signermsgs.MsgUnsupportedABIType,
+		signermsgs.MsgUnsupportedABISuffix,
+		signermsgs.MsgMissingABISuffix,
+		signermsgs.MsgInvalidABISuffix,
+		signermsgs.MsgInvalidABIArraySpec,
+		signermsgs.MsgBadABITypeComponent,
+		signermsgs.MsgPackedEncodingUnsupported,
+		signermsgs.MsgHumanReadableABIExpected,
+		signermsgs.MsgHumanReadableABIBadChar,
+		signermsgs.MsgUnknownABIElementaryType,
+		signermsgs.MsgDecodeNotTuple,
+		signermsgs.MsgNotElementary,
+		signermsgs.MsgInvalidABIFile,
+		signermsgs.MsgABIEventEncodeUnsupported,
+	}},
+	{ABIInvalidValue, "A value cannot be ABI encoded as the type of its component", []i18n.ErrorMessageKey{
+		signermsgs.MsgInvalidIntegerABIInput,
+		signermsgs.MsgInvalidFloatABIInput,
+		signermsgs.MsgInvalidStringABIInput,
+		signermsgs.MsgInvalidBoolABIInput,
+		signermsgs.MsgInvalidHexABIInput,
+		signermsgs.MsgMustBeSliceABIInput,
+		signermsgs.MsgFixedLengthABIArrayMismatch,
+		signermsgs.MsgTupleABIArrayMismatch,
+		signermsgs.MsgTupleABINotArrayOrMap,
+		signermsgs.MsgMissingInputKeyABITuple,
+		signermsgs.MsgWrongTypeComponentABIEncode,
+		signermsgs.MsgInsufficientDataABIEncode,
+		signermsgs.MsgNumberTooLargeABIEncode,
+		signermsgs.MsgNegativeUnsignedABIEncode,
+		signermsgs.MsgFFIBytesLengthMismatch,
+		signermsgs.MsgFFIAddressChecksumMismatch,
+		signermsgs.MsgFFIAddressChecksumRequired,
+		signermsgs.MsgFFIValueViolations,
+		signermsgs.MsgFFIInvalidAddress,
+	}},
+	{ABIInvalidData, "ABI encoded data does not match the definition it is decoded with", []i18n.ErrorMessageKey{
+		signermsgs.MsgABIArrayCountTooLarge,
+		signermsgs.MsgIncorrectABISignatureID,
+		signermsgs.MsgEventSignatureMismatch,
+	}},
+	{ABIEntryNotFound, "No entry, or more than one entry, in the ABI matches the name", []i18n.ErrorMessageKey{
+		signermsgs.MsgABIEntryNotFound,
+		signermsgs.MsgABIEntryAmbiguous,
+		signermsgs.MsgTokenEventNotRecognized,
+		signermsgs.MsgTokenCallNotRecognized,
+		signermsgs.MsgContractNotFunction,
+		signermsgs.MsgUnknownErrorSelector,
+		signermsgs.MsgEventNoSignatureTopic,
+		signermsgs.MsgUnknownEventSignature,
+	}},
+	{FFIInvalid, "A FireFly Interface (FFI) definition is invalid", []i18n.ErrorMessageKey{
+		signermsgs.MsgInvalidFFIDetailsSchema,
+		signermsgs.MsgFFITypeMismatch,
+		signermsgs.MsgFFIUnknownFormat,
+	}},
+	{EIP712InvalidTypedData, "EIP-712 typed data is invalid", []i18n.ErrorMessageKey{
+		signermsgs.MsgEIP712UnknownABICompType,
+		signermsgs.MsgEIP712UnsupportedStrType,
+		signermsgs.MsgEIP712UnsupportedABIType,
+		signermsgs.MsgEIP712TypeNotFound,
+		signermsgs.MsgEIP712PrimaryNotTuple,
+		signermsgs.MsgEIP712BadInternalType,
+		signermsgs.MsgEIP712ValueNotMap,
+		signermsgs.MsgEIP712InvalidArraySuffix,
+		signermsgs.MsgEIP712ValueNotArray,
+		signermsgs.MsgEIP712InvalidArrayLen,
+		signermsgs.MsgEIP712PrimaryTypeRequired,
+		signermsgs.MsgInvalidTypedDataJSON,
+	}},
+	{TxInvalid, "A transaction is invalid or cannot be decoded", []i18n.ErrorMessageKey{
+		signermsgs.MsgMissingFrom,
+		signermsgs.MsgInvalidTransaction,
+		signermsgs.MsgEmptyTransactionBytes,
+		signermsgs.MsgUnsupportedTransactionType,
+		signermsgs.MsgInvalidLegacyTransaction,
+		signermsgs.MsgInvalidEIP1559Transaction,
+		signermsgs.MsgInvalidTransactionJSON,
+		signermsgs.MsgInvalidRawTransactionHex,
+		signermsgs.MsgTxUnknownFields,
+		signermsgs.MsgTxMixedFeeFields,
+		signermsgs.MsgTxInvalidQuantityField,
+		signermsgs.MsgTxInvalidAddressField,
+		signermsgs.MsgTxInvalidDataField,
+		signermsgs.MsgTxNotObject,
+	}},
+	{TxChainIDMismatch, "A chain ID does not match the chain the signer signs for", []i18n.ErrorMessageKey{
+		signermsgs.MsgInvalidChainID,
+		signermsgs.MsgChainIDMismatch,
+		signermsgs.MsgChainIDMismatchRefused,
+		signermsgs.MsgTxnChainIDMismatch,
+	}},
+	{TxRejectedByNode, "The node rejected the transaction when it was simulated, or its gas was estimated", []i18n.ErrorMessageKey{
+		signermsgs.MsgPreflightFailed,
+		signermsgs.MsgGasEstimateFailed,
+		signermsgs.MsgGasEstimateExceedsCap,
+		signermsgs.MsgContractCallFailed,
+		signermsgs.MsgContractDeployFailed,
+		signermsgs.MsgContractDeployReverted,
+		signermsgs.MsgContractNoCode,
+	}},
+	{TxReceiptTimeout, "The transaction was not mined in time", []i18n.ErrorMessageKey{
+		signermsgs.MsgReceiptWaitTimeout,
+	}},
+	{SignatureInvalid, "A signature is malformed, or not in canonical form", []i18n.ErrorMessageKey{
+		signermsgs.MsgInvalidEIP155TransactionV,
+		signermsgs.MsgSigningInvalidCompactRSV,
+		signermsgs.MsgSigningInvalidEIP2098,
+		signermsgs.MsgSigningHighSEIP2098,
+		signermsgs.MsgSignatureHighS,
+		signermsgs.MsgInvalidRecoveryID,
+		signermsgs.MsgInvalidSignatureV,
+		signermsgs.MsgInvalidEIP155V,
+		signermsgs.MsgInvalidSchnorrSignature,
+		signermsgs.MsgInvalidDERSignature,
+		signermsgs.MsgSigningInvalidCompactRS,
+	}},
+	{SignatureMismatch, "A signature was not produced by the expected key", []i18n.ErrorMessageKey{
+		signermsgs.MsgSignerMismatch,
+		signermsgs.MsgSchnorrVerifyFailed,
+		signermsgs.MsgSignatureNotFromPublicKey,
+	}},
+	{PublicKeyInvalid, "A public key is invalid", []i18n.ErrorMessageKey{
+		signermsgs.MsgInvalidPublicKey,
+		signermsgs.MsgInvalidSchnorrPublicKey,
+	}},
+	{SigningFailed, "Signing failed", []i18n.ErrorMessageKey{
+		signermsgs.MsgSigningFailed,
+		signermsgs.MsgInvalidSigner,
+		signermsgs.MsgSchnorrSignFailed,
+	}},
+	{WalletKeyNotAvailable, "The wallet has no usable key for the address", []i18n.ErrorMessageKey{
+		signermsgs.MsgWalletNotAvailable,
+		signermsgs.MsgWalletFailed,
+		signermsgs.MsgAddressMismatch,
+		signermsgs.MsgLoadTestNoAccounts,
+	}},
+	{WalletKeyLocked, "The key for the address must be unlocked before it can sign", []i18n.ErrorMessageKey{
+		signermsgs.MsgWalletLocked,
+	}},
+	{WalletUnsupported, "The wallet does not support the operation", []i18n.ErrorMessageKey{
+		signermsgs.MsgWalletUnlockNotSupported,
+		signermsgs.MsgPublicKeysNotSupported,
+		signermsgs.MsgPersonalSignNotSupported,
+		signermsgs.MsgDigestSignNotSupported,
+		signermsgs.MsgTypedDataNotSupported,
+		signermsgs.MsgContractNoWallet,
+		signermsgs.MsgKeyExportNotSupported,
+	}},
+	{WalletStorageFailed, "The wallet could not read or write its files", []i18n.ErrorMessageKey{
+		signermsgs.MsgReadDirFile,
+		signermsgs.MsgFailedToStartListener,
+		signermsgs.MsgMigrateWalletFileFailed,
+		signermsgs.MsgWriteWalletFileFailed,
+		signermsgs.MsgReadPasswordFileFailed,
+		signermsgs.MsgChangePasswordVerifyFailed,
+		signermsgs.MsgKeystoreDecryptFailed,
+		signermsgs.MsgKeyCeremonyStoreFailed,
+		signermsgs.MsgKeyCeremonyLoadFailed,
+		signermsgs.MsgFilePermissionsCheckFailed,
+		signermsgs.MsgObjectStoreRequestFailed,
+		signermsgs.MsgObjectStoreRequestStatus,
+		signermsgs.MsgObjectStoreListInvalid,
+		signermsgs.MsgObjectStoreUnencrypted,
+		signermsgs.MsgObjectStoreKMSKeyMismatch,
+		signermsgs.MsgObjectStoreCacheWriteFailed,
+	}},
+	{WalletKeyManagementRejected, "A key cannot be created, or its password changed, as requested", []i18n.ErrorMessageKey{
+		signermsgs.MsgCreateKeyFilenameMismatch,
+		signermsgs.MsgCreateKeyMetadataTemplate,
+		signermsgs.MsgCreateKeyNoPassword,
+		signermsgs.MsgEmptyPassword,
+		signermsgs.MsgPasswordMismatch,
+		signermsgs.MsgChangePasswordNoPasswordFile,
+		signermsgs.MsgKeyCeremonyAddressMismatch,
+	}},
+	{KeyCeremonyRejected, "The key ceremony is not in a state that allows the operation, or the caller cannot perform it", []i18n.ErrorMessageKey{
+		signermsgs.MsgKeyCeremonyWrongStatus,
+		signermsgs.MsgKeyCeremonyDuplicateApproval,
+		signermsgs.MsgKeyCeremonyNotProposer,
+	}},
+	{Unauthenticated, "The caller could not be authenticated", []i18n.ErrorMessageKey{
+		signermsgs.MsgUnauthenticated,
+		signermsgs.MsgAuthenticationFailed,
+		signermsgs.MsgJWKSFetchFailed,
+		signermsgs.MsgJWTUnknownKeyID,
+		signermsgs.MsgJWTMissingIdentityClaim,
+	}},
+	{Unauthorized, "The caller is not authorized for the method or address", []i18n.ErrorMessageKey{
+		signermsgs.MsgMethodNotAuthorized,
+		signermsgs.MsgPrivilegedMethodRequiresRBAC,
+		signermsgs.MsgAddressNotAuthorized,
+	}},
+	{PolicyDisabled, "The method or feature is disabled on this server", []i18n.ErrorMessageKey{
+		signermsgs.MsgPersonalSignDisabled,
+		signermsgs.MsgEthSignDisabled,
+		signermsgs.MsgMethodNotAllowed,
+		signermsgs.MsgNonceManagementDisabled,
+	}},
+	{PolicyTxRejected, "The transaction was rejected by the transaction policy or fee caps", []i18n.ErrorMessageKey{
+		signermsgs.MsgFeeCapExceeded,
+		signermsgs.MsgTxDestinationNotAllowed,
+		signermsgs.MsgTxDeploymentNotAllowed,
+		signermsgs.MsgTxValueExceeded,
+		signermsgs.MsgUnmanagedSender,
+	}},
+	{ApprovalRejected, "The signing request was rejected by its approver, or was not approved in time", []i18n.ErrorMessageKey{
+		signermsgs.MsgSigningRejected,
+		signermsgs.MsgSigningApprovalTimedOut,
+	}},
+	{ApprovalFailed, "The signing approval hook failed, or returned an invalid decision", []i18n.ErrorMessageKey{
+		signermsgs.MsgSigningApprovalFailed,
+		signermsgs.MsgSigningApprovalBadDecision,
+	}},
+	{RateLimited, "Too many requests, or transactions waiting to be submitted - retry later", []i18n.ErrorMessageKey{
+		signermsgs.MsgRateLimitExceeded,
+		signermsgs.MsgSenderQueueFull,
+	}},
+	{RequestTooLarge, "The request exceeds a size limit", []i18n.ErrorMessageKey{
+		signermsgs.MsgRequestTooLarge,
+		signermsgs.MsgBatchTooLarge,
+		signermsgs.MsgJSONTooDeep,
+	}},
+	{BackendFailed, "A request to the backend node failed", []i18n.ErrorMessageKey{
+		signermsgs.MsgRPCRequestFailed,
+		signermsgs.MsgQueryChainID,
+		signermsgs.MsgWebSocketReconnected,
+		signermsgs.MsgBatchResponseMissing,
+		signermsgs.MsgBatchDispatcherStopped,
+		signermsgs.MsgIPCConnectFailed,
+		signermsgs.MsgIPCNotConnected,
+	}},
+	{BackendUnavailable, "The backend is unavailable, as its circuit breaker is open - retry later", []i18n.ErrorMessageKey{
+		signermsgs.MsgCircuitBreakerOpen,
+	}},
+	{BackendInvalidResponse, "The backend returned a response that could not be parsed", []i18n.ErrorMessageKey{
+		signermsgs.MsgResultParseFailed,
+		signermsgs.MsgSubscribeResponseInvalid,
+		signermsgs.MsgTraceInvalid,
+	}},
+	{NonceStoreFailed, "The nonce manager could not read, write or fill nonces", []i18n.ErrorMessageKey{
+		signermsgs.MsgNonceStoreInitFailed,
+		signermsgs.MsgNonceStoreReadFailed,
+		signermsgs.MsgNonceStoreWriteFailed,
+		signermsgs.MsgNonceGapFillFailed,
+	}},
+	{PersistenceFailed, "The state of the signer could not be read from, or written to, its persistence", []i18n.ErrorMessageKey{
+		signermsgs.MsgPersistenceInitFailed,
+		signermsgs.MsgPersistenceInvalidKey,
+		signermsgs.MsgPersistenceReadFailed,
+		signermsgs.MsgPersistenceWriteFailed,
+		signermsgs.MsgPersistenceListFailed,
+	}},
+	{AuditFailed, "An audit event could not be delivered", []i18n.ErrorMessageKey{
+		signermsgs.MsgAuditWebhookFailed,
+	}},
+	{FeeSourceFailed, "A gas price source failed, or returned fees that could not be parsed", []i18n.ErrorMessageKey{
+		signermsgs.MsgGasOracleRequestFailed,
+		signermsgs.MsgGasOracleBadResponse,
+		signermsgs.MsgGasOracleAllFailed,
+	}},
+	{CCIPReadFailed, "An offchain lookup (EIP-3668 CCIP-Read) of a contract call failed", []i18n.ErrorMessageKey{
+		signermsgs.MsgCCIPReadSenderMismatch,
+		signermsgs.MsgCCIPReadTooManyLookups,
+		signermsgs.MsgCCIPReadURLNotAllowed,
+		signermsgs.MsgCCIPReadGatewayFailed,
+		signermsgs.MsgCCIPReadFailed,
+	}},
+	{ConfigInvalid, "The configuration is invalid", []i18n.ErrorMessageKey{
+		i18n.MsgConfigFailed,
+		signermsgs.MsgBadGoTemplate,
+		signermsgs.MsgNoWalletEnabled,
+		signermsgs.MsgObjectStoreSettingRequired,
+		signermsgs.MsgObjectStoreBadEndpoint,
+		signermsgs.MsgObjectStoreBadSSEMode,
+		signermsgs.MsgObjectStoreCustomerKeyInvalid,
+		signermsgs.MsgObjectStoreNoCredentials,
+		signermsgs.MsgKeyCeremonyNoPath,
+		signermsgs.MsgKeyCeremonyNoFileWallet,
+		signermsgs.MsgKeyCeremonyRequiresRBAC,
+		signermsgs.MsgBadRegularExpression,
+		signermsgs.MsgENSInvalidRegistry,
+		signermsgs.MsgMissingRegexpCaptureGroup,
+		signermsgs.MsgInvalidShardPrefixLength,
+		signermsgs.MsgFailoverMixedSchemes,
+		signermsgs.MsgNoAuthenticators,
+		signermsgs.MsgUnknownAuthenticator,
+		signermsgs.MsgAPIKeyMissingFields,
+		signermsgs.MsgRBACRequiresAuth,
+		signermsgs.MsgInvalidRBACPolicy,
+		signermsgs.MsgInvalidMethodPattern,
+		signermsgs.MsgInvalidMethodOverride,
+		signermsgs.MsgBadGasEstimateMultiplier,
+		signermsgs.MsgUnknownFeeStrategy,
+		signermsgs.MsgBadFeeHistoryPercentile,
+		signermsgs.MsgBadBaseFeeMultiplier,
+		signermsgs.MsgGasOracleNoSources,
+		signermsgs.MsgGasOracleBadWeight,
+		signermsgs.MsgGasOracleUnknownMode,
+		signermsgs.MsgGasOracleUnknownSpeed,
+		signermsgs.MsgGasOracleBadConfig,
+		signermsgs.MsgBadFeeCap,
+		signermsgs.MsgUnknownFeeCapPolicy,
+		signermsgs.MsgUnknownAccessLogVerbosity,
+		signermsgs.MsgNoServerListeners,
+		signermsgs.MsgBadUnixSocketMode,
+		signermsgs.MsgBadResponseCacheConfig,
+		signermsgs.MsgInvalidTimeoutOverride,
+		signermsgs.MsgBadTxPolicy,
+		signermsgs.MsgAdminRequiresAuth,
+		signermsgs.MsgBadAddressMetricsLabel,
+		signermsgs.MsgBadChainNetwork,
+		signermsgs.MsgChainNetworkConflict,
+		signermsgs.MsgAuditWebhookNoURL,
+		signermsgs.MsgAuditWebhookBadQueueSize,
+		signermsgs.MsgBadSenderQueueMaxPending,
+		signermsgs.MsgUnknownResubmitPolicy,
+		signermsgs.MsgBadResubmitConfig,
+		signermsgs.MsgConfigProblems,
+		signermsgs.MsgWalletPathNotSet,
+		signermsgs.MsgConfigPathNotAccessible,
+		signermsgs.MsgConfigPathNotDirectory,
+		signermsgs.MsgConfigPathIsDirectory,
+		signermsgs.MsgMetadataKeyFilePropertyRequired,
+		signermsgs.MsgUnknownMetadataFormat,
+		signermsgs.MsgConfigCheckFailed,
+		signermsgs.MsgUnknownDebugLogModule,
+	}},
+	{ConfigReloadFailed, "The configuration could not be reloaded, so the previous configuration remains in effect", []i18n.ErrorMessageKey{
+		signermsgs.MsgConfigReloadFailed,
+		signermsgs.MsgBackendReloadUnsupported,
+	}},
+	{ConfigInsecure, "Files in the configuration can be accessed by other users", []i18n.ErrorMessageKey{
+		signermsgs.MsgFileReadableByOthers,
+		signermsgs.MsgDirectoryWritableByOthers,
+		signermsgs.MsgFileInsecure,
+	}},
+	{ListenFailed, "The server could not listen for requests", []i18n.ErrorMessageKey{
+		signermsgs.MsgUnixSocketListenFailed,
+		signermsgs.MsgUnixSocketPathInUse,
+	}},
+}
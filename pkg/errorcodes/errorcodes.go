@@ -0,0 +1,135 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package errorcodes gives every error returned by the signer a stable code identifying the class of failure,
+// such as FFS-ABI-001 for ABI data that is too short, so automation can branch on the kind of failure.
+//
+// Unlike the FF22xxx message keys, which identify individual messages, codes are documented (see errors.md)
+// and are never reused or renumbered. The messages belonging to a class can change between releases.
+package errorcodes
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+)
+
+// Code identifies a class of failure
+type Code string
+
+// Error is an error with the code of its class of failure
+type Error interface {
+	i18n.FFError
+	ErrorCode() Code
+}
+
+// Data is the data of the JSON/RPC and admin API errors returned by the signer
+type Data struct {
+	ErrorCode  Code                 `json:"errorCode"`
+	MessageKey i18n.ErrorMessageKey `json:"messageKey"`
+}
+
+// Class is a class of failure, with the message keys of all the errors that belong to it
+type Class struct {
+	Code        Code
+	Description string
+	MessageKeys []i18n.ErrorMessageKey
+}
+
+type codedError struct {
+	i18n.FFError
+	code Code
+}
+
+func (e *codedError) ErrorCode() Code {
+	return e.code
+}
+
+func (e *codedError) Unwrap() error {
+	return e.FFError
+}
+
+var classByKey = func() map[i18n.ErrorMessageKey]*Class {
+	byKey := make(map[i18n.ErrorMessageKey]*Class)
+	for _, class := range classes {
+		for _, key := range class.MessageKeys {
+			byKey[key] = class
+		}
+	}
+	return byKey
+}()
+
+// Classes lists every class of failure, grouped by area
+func Classes() []*Class {
+	return classes
+}
+
+// ClassOf returns the class a message key belongs to, or nil if it has not been classified
+func ClassOf(key i18n.ErrorMessageKey) *Class {
+	return classByKey[key]
+}
+
+// Of returns the error with its code, for errors created by the signer. The class is that of the outermost
+// message of the error, so an error wrapped with context (such as a configuration reload failure) has the
+// code of the wrapper.
+func Of(err error) (Error, bool) {
+	var coded Error
+	if errors.As(err, &coded) {
+		return coded, true
+	}
+	var ffe i18n.FFError
+	if errors.As(err, &ffe) {
+		if class := ClassOf(ffe.MessageKey()); class != nil {
+			return &codedError{FFError: ffe, code: class.Code}, true
+		}
+	}
+	return nil, false
+}
+
+// CodeOf returns the code of the error, or an empty string for errors that do not have one
+func CodeOf(err error) Code {
+	if coded, ok := Of(err); ok {
+		return coded.ErrorCode()
+	}
+	return ""
+}
+
+// DataOf returns the code and message key of the error, to return with it in an API, or nil if it has no code
+func DataOf(err error) *Data {
+	if coded, ok := Of(err); ok {
+		return &Data{ErrorCode: coded.ErrorCode(), MessageKey: coded.MessageKey()}
+	}
+	return nil
+}
+
+// GenerateMarkdown generates the reference documentation of every code, after the header
+func GenerateMarkdown(header string) []byte {
+	b := bytes.NewBufferString(header)
+	b.WriteString("|Code|Description|Message keys|\n|----|-----------|------------|\n")
+	for _, class := range classes {
+		fmt.Fprintf(b, "|%s|%s|", class.Code, class.Description)
+		for i, key := range class.MessageKeys {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			fmt.Fprintf(b, "`%s`", key)
+		}
+		b.WriteString("\n")
+	}
+	return b.Bytes()
+}
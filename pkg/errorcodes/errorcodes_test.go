@@ -0,0 +1,94 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errorcodes
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"testing"
+
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEveryMessageClassified(t *testing.T) {
+	src, err := os.ReadFile("../../internal/signermsgs/en_error_messges.go")
+	assert.NoError(t, err)
+	keys := regexp.MustCompile(`ffe\("(FF\d+)"`).FindAllStringSubmatch(string(src), -1)
+	assert.Greater(t, len(keys), 200)
+	for _, key := range keys {
+		assert.NotNil(t, ClassOf(i18n.ErrorMessageKey(key[1])), "message %s has no error code", key[1])
+	}
+}
+
+func TestClassesUnique(t *testing.T) {
+	codes := make(map[Code]bool)
+	keys := make(map[i18n.ErrorMessageKey]Code)
+	for _, class := range Classes() {
+		assert.Regexp(t, `^FFS-[A-Z0-9]+-\d{3}$`, class.Code)
+		assert.False(t, codes[class.Code], "duplicate code %s", class.Code)
+		codes[class.Code] = true
+		for _, key := range class.MessageKeys {
+			existing, ok := keys[key]
+			assert.False(t, ok, "message %s is in %s and %s", key, existing, class.Code)
+			keys[key] = class.Code
+		}
+	}
+}
+
+func TestOf(t *testing.T) {
+	ctx := context.Background()
+
+	err := i18n.NewError(ctx, signermsgs.MsgNotEnoughBytesABIValue, "uint256", "x")
+	coded, ok := Of(fmt.Errorf("decode failed: %w", err))
+	assert.True(t, ok)
+	assert.Equal(t, ABIInsufficientData, coded.ErrorCode())
+	assert.Equal(t, signermsgs.MsgNotEnoughBytesABIValue, coded.MessageKey())
+	assert.Equal(t, 500, coded.HTTPStatus())
+	assert.Equal(t, err.Error(), coded.Error())
+	assert.ErrorIs(t, coded, err)
+
+	again, ok := Of(coded)
+	assert.True(t, ok)
+	assert.Same(t, coded, again)
+
+	assert.Equal(t, RateLimited, CodeOf(i18n.NewError(ctx, signermsgs.MsgRateLimitExceeded, "requests")))
+	assert.Equal(t, &Data{ErrorCode: RateLimited, MessageKey: signermsgs.MsgRateLimitExceeded},
+		DataOf(i18n.NewError(ctx, signermsgs.MsgRateLimitExceeded, "requests")))
+
+	// The outermost message is the class of the error
+	wrapped := i18n.WrapError(ctx, i18n.NewError(ctx, signermsgs.MsgBadTxPolicy, "maxValue", "x"), signermsgs.MsgConfigReloadFailed)
+	assert.Equal(t, ConfigReloadFailed, CodeOf(wrapped))
+}
+
+func TestOfNoCode(t *testing.T) {
+	_, ok := Of(fmt.Errorf("pop"))
+	assert.False(t, ok)
+	assert.Empty(t, CodeOf(fmt.Errorf("pop")))
+	assert.Nil(t, DataOf(nil))
+	assert.Empty(t, CodeOf(i18n.NewError(context.Background(), i18n.MsgJSONObjectParseFailed, "x")))
+}
+
+func TestGenerateMarkdown(t *testing.T) {
+	md := string(GenerateMarkdown("# Error codes\n\n"))
+	assert.Regexp(t, "^# Error codes\n\n\\|Code\\|Description\\|Message keys\\|\n", md)
+	assert.Contains(t, md, "\n|FFS-ABI-001|ABI encoded data ended before all the values could be read|`FF22045`, `FF22047`, `FF22048`, `FF22053`\n")
+	assert.Contains(t, md, "\n|FFS-TX-004|The transaction was not mined in time|`FF22174`\n")
+}
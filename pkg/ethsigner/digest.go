@@ -0,0 +1,42 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ethsigner
+
+import (
+	"context"
+
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/secp256k1"
+)
+
+// SignDigest signs a 32 byte digest directly, with no prefix, returning the 65 byte R,S,V signature
+// with a V value of 27 or 28, as returned by the legacy eth_sign.
+//
+// This is dangerous, as the digest could be the hash of a transaction, or any other payload the key
+// is trusted for, so should only be exposed to callers that are fully trusted.
+func SignDigest(ctx context.Context, signer secp256k1.SignerDirect, digest []byte) (ethtypes.HexBytes0xPrefix, error) {
+	if len(digest) != 32 {
+		return nil, i18n.NewError(ctx, signermsgs.MsgInvalidDigestLength, len(digest))
+	}
+	sig, err := signer.SignDirect(digest)
+	if err != nil {
+		return nil, err
+	}
+	return sig.CompactRSV(), nil
+}
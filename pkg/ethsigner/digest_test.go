@@ -0,0 +1,65 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ethsigner
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/hyperledger/firefly-signer/mocks/secp256k1mocks"
+	"github.com/hyperledger/firefly-signer/pkg/secp256k1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSignDigest(t *testing.T) {
+	keypair, err := secp256k1.GenerateSecp256k1KeyPair()
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	digest := PersonalMessageHash([]byte("Hello World"))
+	sig, err := SignDigest(ctx, keypair, digest)
+	assert.NoError(t, err)
+	assert.Len(t, sig, 65)
+
+	// Signing the digest directly matches personal_sign of the original message
+	personalSig, err := SignPersonalMessage(keypair, []byte("Hello World"))
+	assert.NoError(t, err)
+	assert.Equal(t, personalSig, sig)
+
+	sigData, err := secp256k1.DecodeCompactRSV(ctx, sig)
+	assert.NoError(t, err)
+	addr, err := sigData.RecoverDirect(digest, -1)
+	assert.NoError(t, err)
+	assert.Equal(t, keypair.Address.String(), addr.String())
+}
+
+func TestSignDigestBadLength(t *testing.T) {
+	keypair, err := secp256k1.GenerateSecp256k1KeyPair()
+	assert.NoError(t, err)
+
+	_, err = SignDigest(context.Background(), keypair, []byte("not a digest"))
+	assert.Regexp(t, "FF22116", err)
+}
+
+func TestSignDigestFail(t *testing.T) {
+	msn := &secp256k1mocks.SignerDirect{}
+	msn.On("SignDirect", mock.Anything).Return(nil, fmt.Errorf("pop"))
+	_, err := SignDigest(context.Background(), msn, make([]byte, 32))
+	assert.Regexp(t, "pop", err)
+}
@@ -0,0 +1,38 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ethsigner
+
+import (
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/secp256k1"
+)
+
+// PersonalMessageHash returns the hash that is signed by personal_sign for a message
+func PersonalMessageHash(message []byte) ethtypes.HexBytes0xPrefix {
+	return secp256k1.PersonalMessageHash(message)
+}
+
+// SignPersonalMessage signs a message with the EIP-191 prefix, returning the 65 byte R,S,V signature
+// with a V value of 27 or 28, as returned by personal_sign
+func SignPersonalMessage(signer secp256k1.Signer, message []byte) (ethtypes.HexBytes0xPrefix, error) {
+	// Note that signer.Sign performs the hash
+	sig, err := signer.Sign(secp256k1.PersonalMessage(message))
+	if err != nil {
+		return nil, err
+	}
+	return sig.CompactRSV(), nil
+}
@@ -0,0 +1,56 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ethsigner
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/hyperledger/firefly-signer/mocks/secp256k1mocks"
+	"github.com/hyperledger/firefly-signer/pkg/secp256k1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestPersonalMessageHash(t *testing.T) {
+	assert.Equal(t, "0xa1de988600a42c4b4ab089b619297c17d53cffae5d5120d82d8a92d0bb3b78f2", PersonalMessageHash([]byte("Hello World")).String())
+}
+
+func TestSignPersonalMessage(t *testing.T) {
+	keypair, err := secp256k1.GenerateSecp256k1KeyPair()
+	assert.NoError(t, err)
+
+	message := []byte("Hello World")
+	sig, err := SignPersonalMessage(keypair, message)
+	assert.NoError(t, err)
+	assert.Len(t, sig, 65)
+	assert.Contains(t, []byte{27, 28}, sig[64])
+
+	sigData, err := secp256k1.DecodeCompactRSV(context.Background(), sig)
+	assert.NoError(t, err)
+	addr, err := sigData.RecoverDirect(PersonalMessageHash(message), -1)
+	assert.NoError(t, err)
+	assert.Equal(t, keypair.Address.String(), addr.String())
+}
+
+func TestSignPersonalMessageFail(t *testing.T) {
+	msn := &secp256k1mocks.Signer{}
+	msn.On("Sign", mock.Anything).Return(nil, fmt.Errorf("pop"))
+	_, err := SignPersonalMessage(msn, []byte("Hello World"))
+	assert.Regexp(t, "pop", err)
+}
@@ -0,0 +1,41 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ethsigner
+
+import (
+	btcec "github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/secp256k1"
+)
+
+type PublicKeyResult struct {
+	Address      ethtypes.Address0xHex     `ffstruct:"PublicKeyResult" json:"address"`
+	Compressed   ethtypes.HexBytes0xPrefix `ffstruct:"PublicKeyResult" json:"compressed"`
+	Uncompressed ethtypes.HexBytes0xPrefix `ffstruct:"PublicKeyResult" json:"uncompressed"`
+	XOnly        ethtypes.HexBytes0xPrefix `ffstruct:"PublicKeyResult" json:"xOnly,omitempty"`
+}
+
+// NewPublicKeyResult builds the public key information for a secp256k1 public key
+func NewPublicKeyResult(pubKey *btcec.PublicKey) *PublicKeyResult {
+	return &PublicKeyResult{
+		Address:      *secp256k1.PublicKeyToAddress(pubKey),
+		Compressed:   pubKey.SerializeCompressed(),
+		Uncompressed: pubKey.SerializeUncompressed(),
+		XOnly:        schnorr.SerializePubKey(pubKey),
+	}
+}
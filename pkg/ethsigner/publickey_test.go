@@ -0,0 +1,47 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ethsigner
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hyperledger/firefly-signer/pkg/secp256k1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewPublicKeyResult(t *testing.T) {
+
+	keypair, err := secp256k1.GenerateSecp256k1KeyPair()
+	assert.NoError(t, err)
+
+	res := NewPublicKeyResult(keypair.PublicKey)
+	assert.Equal(t, keypair.Address, res.Address)
+	assert.Len(t, res.Compressed, 33)
+	assert.Len(t, res.Uncompressed, 65)
+	assert.Equal(t, byte(0x04), res.Uncompressed[0])
+	assert.Equal(t, keypair.PublicKeyBytes(), []byte(res.Uncompressed[1:]))
+	assert.Equal(t, keypair.XOnlyPublicKey(), []byte(res.XOnly))
+
+	b, err := json.Marshal(res)
+	assert.NoError(t, err)
+	var parsed PublicKeyResult
+	err = json.Unmarshal(b, &parsed)
+	assert.NoError(t, err)
+	assert.Equal(t, *res, parsed)
+
+}
@@ -111,13 +111,20 @@ func (t *Transaction) Build1559(chainID int64) rlp.List {
 // - By default use EIP-155 signing
 // Never picks legacy-legacy (non EIP-155), or EIP-2930
 func (t *Transaction) Sign(signer secp256k1.Signer, chainID int64) ([]byte, error) {
+	return t.SignTo(nil, signer, chainID)
+}
+
+// SignTo is Sign, appending the signed transaction to dst and returning the extended slice.
+// Passing a buffer with enough capacity, such as one reused from a previous transaction, means
+// the encoding of the signed transaction does not need to allocate.
+func (t *Transaction) SignTo(dst []byte, signer secp256k1.Signer, chainID int64) ([]byte, error) {
 	if signer == nil {
 		return nil, i18n.NewError(context.Background(), signermsgs.MsgInvalidSigner)
 	}
 	if t.MaxPriorityFeePerGas.BigInt().Sign() > 0 || t.MaxFeePerGas.BigInt().Sign() > 0 {
-		return t.SignEIP1559(signer, chainID)
+		return t.signEIP1559To(dst, signer, chainID)
 	}
-	return t.SignLegacyEIP155(signer, chainID)
+	return t.signLegacyEIP155To(dst, signer, chainID)
 }
 
 // Returns the bytes that would be used to sign the transaction, without actually
@@ -154,8 +161,13 @@ func (t *Transaction) SignLegacyOriginal(signer secp256k1.Signer) ([]byte, error
 }
 
 func (t *Transaction) FinalizeLegacyOriginalWithSignature(signaturePayload *TransactionSignaturePayload, sig *secp256k1.SignatureData) ([]byte, error) {
+	return t.FinalizeLegacyOriginalWithSignatureTo(nil, signaturePayload, sig)
+}
+
+// FinalizeLegacyOriginalWithSignatureTo is FinalizeLegacyOriginalWithSignature, appending the signed transaction to dst
+func (t *Transaction) FinalizeLegacyOriginalWithSignatureTo(dst []byte, signaturePayload *TransactionSignaturePayload, sig *secp256k1.SignatureData) ([]byte, error) {
 	rlpList := t.addSignature(signaturePayload.rlpList, sig)
-	return rlpList.Encode(), nil
+	return rlpList.EncodeTo(dst), nil
 }
 
 // SignaturePayloadLegacyEIP155 returns the rlpList of fields that are signed, and the
@@ -175,22 +187,30 @@ func (t *Transaction) SignLegacyEIP155(signer secp256k1.Signer, chainID int64) (
 	if signer == nil {
 		return nil, fmt.Errorf("invalid signer")
 	}
+	return t.signLegacyEIP155To(nil, signer, chainID)
+}
 
+func (t *Transaction) signLegacyEIP155To(dst []byte, signer secp256k1.Signer, chainID int64) ([]byte, error) {
 	signaturePayload := t.SignaturePayloadLegacyEIP155(chainID)
 
 	sig, err := signer.Sign(signaturePayload.data)
 	if err != nil {
 		return nil, err
 	}
-	return t.FinalizeLegacyEIP155WithSignature(signaturePayload, sig, chainID)
+	return t.FinalizeLegacyEIP155WithSignatureTo(dst, signaturePayload, sig, chainID)
 }
 
 func (t *Transaction) FinalizeLegacyEIP155WithSignature(signaturePayload *TransactionSignaturePayload, sig *secp256k1.SignatureData, chainID int64) ([]byte, error) {
+	return t.FinalizeLegacyEIP155WithSignatureTo(nil, signaturePayload, sig, chainID)
+}
+
+// FinalizeLegacyEIP155WithSignatureTo is FinalizeLegacyEIP155WithSignature, appending the signed transaction to dst
+func (t *Transaction) FinalizeLegacyEIP155WithSignatureTo(dst []byte, signaturePayload *TransactionSignaturePayload, sig *secp256k1.SignatureData, chainID int64) ([]byte, error) {
 	// Use the EIP-155 V value, of (2*ChainID + 35 + Y-parity)
 	sig.UpdateEIP155(chainID)
 
 	rlpList := t.addSignature(signaturePayload.rlpList[0:6] /* we don't include the chainID+0+0 hash values in the payload */, sig)
-	return rlpList.Encode(), nil
+	return rlpList.EncodeTo(dst), nil
 }
 
 // SignaturePayloadEIP1559 returns the rlpList of fields that are signed, along with the full
@@ -211,28 +231,44 @@ func (t *Transaction) SignEIP1559(signer secp256k1.Signer, chainID int64) ([]byt
 	if signer == nil {
 		return nil, fmt.Errorf("invalid signer")
 	}
+	return t.signEIP1559To(nil, signer, chainID)
+}
 
+func (t *Transaction) signEIP1559To(dst []byte, signer secp256k1.Signer, chainID int64) ([]byte, error) {
 	signaturePayload := t.SignaturePayloadEIP1559(chainID)
 	sig, err := signer.Sign(signaturePayload.data)
 	if err != nil {
 		return nil, err
 	}
-	return t.FinalizeEIP1559WithSignature(signaturePayload, sig)
+	return t.FinalizeEIP1559WithSignatureTo(dst, signaturePayload, sig)
 }
 
 func (t *Transaction) FinalizeEIP1559WithSignature(signaturePayload *TransactionSignaturePayload, sig *secp256k1.SignatureData) ([]byte, error) {
+	return t.FinalizeEIP1559WithSignatureTo(nil, signaturePayload, sig)
+}
+
+// FinalizeEIP1559WithSignatureTo is FinalizeEIP1559WithSignature, appending the signed transaction to dst
+func (t *Transaction) FinalizeEIP1559WithSignatureTo(dst []byte, signaturePayload *TransactionSignaturePayload, sig *secp256k1.SignatureData) ([]byte, error) {
 	// Use the direct 0/1 Y-parity value
 	sig.UpdateEIP2930()
 
 	// Now we need a new RLP array, _including_ signature
 	// 0x02 || rlp([chain_id, nonce, max_priority_fee_per_gas, max_fee_per_gas, gas_limit, destination, amount, data, access_list, signature_y_parity, signature_r, signature_s])
 	rlpList := t.addSignature(signaturePayload.rlpList, sig)
-	return append([]byte{TransactionType1559}, rlpList.Encode()...), nil
+	return rlpList.EncodeTo(append(dst, TransactionType1559)), nil
+}
+
+// rawTransactionLimits bound the raw transactions we decode, which can come from untrusted callers. The deepest
+// nesting is the storage keys in the access list of an EIP-1559 transaction, and no node accepts a transaction
+// larger than 128KB into its pool.
+var rawTransactionLimits = &rlp.DecodeLimits{
+	MaxDepth: 4,
+	MaxSize:  128 * 1024,
 }
 
 func RecoverLegacyRawTransaction(ctx context.Context, rawTx ethtypes.HexBytes0xPrefix, chainID int64) (*ethtypes.Address0xHex, *TransactionWithOriginalPayload, error) {
 
-	decoded, _, err := rlp.Decode(rawTx)
+	decoded, _, err := rlp.DecodeWithLimits(rawTx, rawTransactionLimits)
 	if err != nil {
 		log.L(ctx).Errorf("Invalid legacy transaction data '%s': %s", rawTx, err)
 		return nil, nil, i18n.NewError(ctx, signermsgs.MsgInvalidLegacyTransaction, err)
@@ -305,7 +341,7 @@ func decodeEIP1559SignaturePayload(ctx context.Context, rawTx ethtypes.HexBytes0
 	}
 
 	rawTx = rawTx[1:]
-	decoded, _, err := rlp.Decode(rawTx)
+	decoded, _, err := rlp.DecodeWithLimits(rawTx, rawTransactionLimits)
 	if err != nil {
 		log.L(ctx).Errorf("Invalid EIP-1559 transaction data '%s': %s", rawTx, err)
 		return nil, nil, i18n.NewError(ctx, signermsgs.MsgInvalidEIP1559Transaction, err)
@@ -372,6 +408,38 @@ func RecoverRawTransaction(ctx context.Context, rawTx ethtypes.HexBytes0xPrefix,
 
 }
 
+// RawTransactionChainID returns the chain ID a raw signed transaction was signed for, from the payload of an EIP-1559
+// transaction or the V value of an EIP-155 transaction. An original legacy transaction has no chain ID, so -1 is returned.
+func RawTransactionChainID(ctx context.Context, rawTx ethtypes.HexBytes0xPrefix) (int64, error) {
+	if len(rawTx) == 0 {
+		return -1, i18n.NewError(ctx, signermsgs.MsgEmptyTransactionBytes)
+	}
+	txTypeByte := rawTx[0]
+	switch {
+	case txTypeByte >= 0xc7:
+		decoded, _, err := rlp.DecodeWithLimits(rawTx, rawTransactionLimits)
+		rlpList, ok := decoded.(rlp.List)
+		if err != nil || !ok || len(rlpList) < 9 {
+			return -1, i18n.NewError(ctx, signermsgs.MsgInvalidLegacyTransaction, err)
+		}
+		v := rlpList[6].ToData().IntOrZero()
+		if v.Cmp(big.NewInt(27)) == 0 || v.Cmp(big.NewInt(28)) == 0 {
+			return -1, nil
+		}
+		chainID, _, err := secp256k1.ChainIDFromEIP155V(v)
+		return chainID, err
+	case txTypeByte == TransactionType1559:
+		decoded, _, err := rlp.DecodeWithLimits(rawTx[1:], rawTransactionLimits)
+		rlpList, ok := decoded.(rlp.List)
+		if err != nil || !ok || len(rlpList) < 1 {
+			return -1, i18n.NewError(ctx, signermsgs.MsgInvalidEIP1559Transaction, err)
+		}
+		return rlpList[0].ToData().IntOrZero().Int64(), nil
+	default:
+		return -1, i18n.NewError(ctx, signermsgs.MsgUnsupportedTransactionType, txTypeByte)
+	}
+}
+
 func (t *Transaction) addSignature(rlpList rlp.List, sig *secp256k1.SignatureData) rlp.List {
 	rlpList = append(rlpList, rlp.WrapInt(sig.V))
 	rlpList = append(rlpList, rlp.WrapInt(sig.R))
@@ -188,6 +188,52 @@ func TestSignLegacyOriginal(t *testing.T) {
 
 }
 
+func TestSignToReusesBuffer(t *testing.T) {
+
+	keypair, err := secp256k1.GenerateSecp256k1KeyPair()
+	assert.NoError(t, err)
+
+	buff := make([]byte, 0, 1024)
+	for _, txn := range []*Transaction{
+		{
+			Nonce:    ethtypes.NewHexInteger64(3),
+			GasPrice: ethtypes.NewHexInteger64(100000000),
+			GasLimit: ethtypes.NewHexInteger64(40574),
+			To:       ethtypes.MustNewAddress("0x497eedc4299dea2f2a364be10025d0ad0f702de3"),
+			Data:     []byte("some data that is longer than fifty five bytes, so needs a long length prefix"),
+		},
+		{
+			Nonce:                ethtypes.NewHexInteger64(3),
+			MaxPriorityFeePerGas: ethtypes.NewHexInteger64(123456780),
+			MaxFeePerGas:         ethtypes.NewHexInteger64(150000000),
+			GasLimit:             ethtypes.NewHexInteger64(40574),
+		},
+	} {
+		expected, err := txn.Sign(keypair, 1001)
+		assert.NoError(t, err)
+
+		// Signing is deterministic, so the result must be the same when appended to a reused buffer
+		raw, err := txn.SignTo(append(buff[:0], 0xfe), keypair, 1001)
+		assert.NoError(t, err)
+		assert.Equal(t, &buff[:1][0], &raw[0])
+		assert.Equal(t, append([]byte{0xfe}, expected...), raw)
+
+		// The same as finalizing a payload that was signed separately
+		sp := txn.SignaturePayload(1001)
+		sig, err := keypair.Sign(sp.Bytes())
+		assert.NoError(t, err)
+		var finalized []byte
+		if txn.MaxFeePerGas != nil {
+			finalized, err = txn.FinalizeEIP1559WithSignature(sp, sig)
+		} else {
+			finalized, err = txn.FinalizeLegacyEIP155WithSignature(sp, sig, 1001)
+		}
+		assert.NoError(t, err)
+		assert.Equal(t, expected, finalized)
+	}
+
+}
+
 func TestSignFail(t *testing.T) {
 
 	txn := Transaction{}
@@ -278,6 +324,24 @@ func TestRecoverLegacyBadStructure(t *testing.T) {
 	assert.Regexp(t, "FF22083.*EOF", err)
 }
 
+func TestRecoverRawTransactionLimits(t *testing.T) {
+	ctx := context.Background()
+	deep := rlp.Element(rlp.Data{0x01})
+	for i := 0; i < 5; i++ {
+		deep = rlp.List{deep}
+	}
+	_, _, err := RecoverLegacyRawTransaction(ctx, deep.Encode(), 1001)
+	assert.Regexp(t, "FF22083.*maximum depth 4", err)
+	_, _, err = RecoverEIP1559Transaction(ctx, append([]byte{TransactionType1559}, deep.Encode()...), 1001)
+	assert.Regexp(t, "FF22084.*maximum depth 4", err)
+
+	large := rlp.List{rlp.Data(make([]byte, 128*1024))}.Encode()
+	_, err = RawTransactionChainID(ctx, large)
+	assert.Regexp(t, "FF22083.*maximum size", err)
+	_, err = RawTransactionChainID(ctx, append([]byte{TransactionType1559}, large...))
+	assert.Regexp(t, "FF22084.*maximum size", err)
+}
+
 func TestRecoverEIP1559TransactionEmpty(t *testing.T) {
 	_, _, err := RecoverEIP1559Transaction(context.Background(), []byte{}, 1001)
 	assert.Regexp(t, "FF22084.*TransactionType", err)
@@ -360,3 +424,61 @@ func TestRecoverEIP1559Signature(t *testing.T) {
 	}).Encode()...), 1001)
 	assert.Regexp(t, "invalid", err)
 }
+
+func TestRawTransactionChainID(t *testing.T) {
+	ctx := context.Background()
+	keypair, err := secp256k1.GenerateSecp256k1KeyPair()
+	require.NoError(t, err)
+	tx := &Transaction{
+		Nonce:    ethtypes.NewHexInteger64(3),
+		GasLimit: ethtypes.NewHexInteger64(40574),
+		GasPrice: ethtypes.NewHexInteger64(0),
+		To:       ethtypes.MustNewAddress("0x497eedc4299dea2f2a364be10025d0ad0f702de3"),
+	}
+
+	rawTx, err := tx.SignLegacyEIP155(keypair, 1001)
+	require.NoError(t, err)
+	chainID, err := RawTransactionChainID(ctx, rawTx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1001), chainID)
+
+	rawTx, err = tx.SignLegacyOriginal(keypair)
+	require.NoError(t, err)
+	chainID, err = RawTransactionChainID(ctx, rawTx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(-1), chainID)
+
+	tx.MaxFeePerGas = ethtypes.NewHexInteger64(606060)
+	rawTx, err = tx.SignEIP1559(keypair, 2002)
+	require.NoError(t, err)
+	chainID, err = RawTransactionChainID(ctx, rawTx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2002), chainID)
+}
+
+func TestRawTransactionChainIDErrors(t *testing.T) {
+	ctx := context.Background()
+
+	_, err := RawTransactionChainID(ctx, []byte{})
+	assert.Regexp(t, "FF22081", err)
+
+	_, err = RawTransactionChainID(ctx, []byte{0x01})
+	assert.Regexp(t, "FF22082", err)
+
+	tooShort := make(rlp.List, 8)
+	for i := range tooShort {
+		tooShort[i] = rlp.WrapInt(big.NewInt(1))
+	}
+	_, err = RawTransactionChainID(ctx, tooShort.Encode())
+	assert.Regexp(t, "FF22083", err)
+
+	_, err = RawTransactionChainID(ctx, []byte{TransactionType1559, 0xc0})
+	assert.Regexp(t, "FF22084", err)
+
+	legacy := make(rlp.List, 9)
+	for i := range legacy {
+		legacy[i] = rlp.WrapInt(big.NewInt(0))
+	}
+	_, err = RawTransactionChainID(ctx, legacy.Encode())
+	assert.Regexp(t, "FF22183", err)
+}
@@ -18,6 +18,7 @@ package ethsigner
 
 import (
 	"context"
+	"time"
 
 	"github.com/hyperledger/firefly-signer/pkg/eip712"
 	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
@@ -37,3 +38,53 @@ type WalletTypedData interface {
 	Wallet
 	SignTypedDataV4(ctx context.Context, from ethtypes.Address0xHex, payload *eip712.TypedData) (*EIP712Result, error)
 }
+
+// WalletPersonalSign is implemented by wallets that can sign arbitrary messages for personal_sign,
+// using the EIP-191 prefix so the signature cannot be used for a transaction
+type WalletPersonalSign interface {
+	Wallet
+	SignPersonalMessage(ctx context.Context, from ethtypes.Address0xHex, message []byte) (ethtypes.HexBytes0xPrefix, error)
+}
+
+// WalletDigestSign is implemented by wallets that can sign a raw 32 byte digest for the legacy
+// eth_sign method. Callers can use this to sign anything the key is trusted for.
+type WalletDigestSign interface {
+	Wallet
+	SignDigest(ctx context.Context, from ethtypes.Address0xHex, digest []byte) (ethtypes.HexBytes0xPrefix, error)
+}
+
+// WalletSchnorrSign is implemented by wallets that can sign a 32 byte hash with a BIP-340 Schnorr
+// signature, for integrations with other chains that use the same secp256k1 keys
+type WalletSchnorrSign interface {
+	Wallet
+	SignSchnorr(ctx context.Context, from ethtypes.Address0xHex, hash []byte) (ethtypes.HexBytes0xPrefix, error)
+}
+
+// WalletUnlockable is implemented by wallets that allow keys to be explicitly unlocked for
+// a period of time, and locked again on demand (removing the key material from memory)
+type WalletUnlockable interface {
+	Wallet
+	Unlock(ctx context.Context, addr ethtypes.Address0xHex, password []byte, ttl time.Duration) error
+	Lock(ctx context.Context, addr ethtypes.Address0xHex) error
+	LockAll(ctx context.Context) error
+}
+
+// WalletPublicKeys is implemented by wallets that can return the public key for a managed
+// address, without exposing any private key material
+type WalletPublicKeys interface {
+	Wallet
+	GetPublicKey(ctx context.Context, addr ethtypes.Address0xHex) (*PublicKeyResult, error)
+}
+
+// WalletCacheStats is implemented by wallets that cache key material in memory, so the
+// effectiveness of the cache can be monitored
+type WalletCacheStats interface {
+	Wallet
+	SignerCacheStats() *CacheStats
+}
+
+type CacheStats struct {
+	Items  int    `json:"items"`
+	Hits   uint64 `json:"hits"`
+	Misses uint64 `json:"misses"`
+}
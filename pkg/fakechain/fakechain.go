@@ -0,0 +1,555 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package fakechain is an in-process fake of an Ethereum JSON/RPC node, for unit testing signing flows end-to-end
+// without running a node. It is an rpcbackend.Backend, and an http.Handler that can be served with httptest so
+// it can be configured as the backend URL of the signer.
+//
+// Signed transactions sent with eth_sendRawTransaction are recovered and mined into a new block, with a
+// successful receipt, and the nonce of the sender advances. The response to any method can be replaced with Handle.
+package fakechain
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/hyperledger/firefly-signer/pkg/ethsigner"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/rlp"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+	"golang.org/x/crypto/sha3"
+)
+
+// Handler returns the result of a JSON/RPC method, or an error
+type Handler func(ctx context.Context, params []*fftypes.JSONAny) (interface{}, *rpcbackend.RPCError)
+
+// Options configures the chain, with defaults for anything not set
+type Options struct {
+	ChainID              int64    // defaults to 1337
+	GasEstimate          uint64   // returned by eth_estimateGas - defaults to 100000
+	GasPrice             *big.Int // returned by eth_gasPrice - defaults to 1 gwei
+	BaseFeePerGas        *big.Int // the base fee of every block - defaults to 1 gwei
+	MaxPriorityFeePerGas *big.Int // returned by eth_maxPriorityFeePerGas, and in eth_feeHistory - defaults to 1 gwei
+	ManualMining         bool     // transactions stay pending until Mine is called
+}
+
+// Transaction is a transaction sent to the chain
+type Transaction struct {
+	*ethsigner.Transaction
+	Hash    ethtypes.HexBytes0xPrefix
+	From    *ethtypes.Address0xHex
+	Raw     ethtypes.HexBytes0xPrefix
+	Receipt *rpcbackend.Receipt // nil while the transaction is pending
+}
+
+type account struct {
+	minedNonce   uint64
+	pendingNonce uint64
+}
+
+// Chain is the fake node
+type Chain struct {
+	options  Options
+	mux      sync.Mutex
+	blocks   []*rpcbackend.Block
+	accounts map[ethtypes.Address0xHex]*account
+	txns     []*Transaction
+	pending  []*Transaction
+	handlers map[string]Handler
+	requests []*rpcbackend.RPCRequest
+}
+
+// New returns a chain with only the genesis block
+func New(options *Options) *Chain {
+	c := &Chain{
+		accounts: make(map[ethtypes.Address0xHex]*account),
+		handlers: make(map[string]Handler),
+	}
+	if options != nil {
+		c.options = *options
+	}
+	gwei := big.NewInt(1000000000)
+	if c.options.ChainID == 0 {
+		c.options.ChainID = 1337
+	}
+	if c.options.GasEstimate == 0 {
+		c.options.GasEstimate = 100000
+	}
+	if c.options.GasPrice == nil {
+		c.options.GasPrice = gwei
+	}
+	if c.options.BaseFeePerGas == nil {
+		c.options.BaseFeePerGas = gwei
+	}
+	if c.options.MaxPriorityFeePerGas == nil {
+		c.options.MaxPriorityFeePerGas = gwei
+	}
+	c.blocks = []*rpcbackend.Block{c.newBlock(nil)}
+
+	c.handlers["eth_chainId"] = c.chainID
+	c.handlers["net_version"] = c.netVersion
+	c.handlers["eth_blockNumber"] = c.blockNumber
+	c.handlers["eth_getBlockByNumber"] = c.getBlockByNumber
+	c.handlers["eth_getTransactionCount"] = c.getTransactionCount
+	c.handlers["eth_estimateGas"] = c.estimateGas
+	c.handlers["eth_gasPrice"] = c.gasPrice
+	c.handlers["eth_maxPriorityFeePerGas"] = c.maxPriorityFeePerGas
+	c.handlers["eth_feeHistory"] = c.feeHistory
+	c.handlers["eth_call"] = c.call
+	c.handlers["eth_sendRawTransaction"] = c.sendRawTransaction
+	c.handlers["eth_getTransactionByHash"] = c.getTransactionByHash
+	c.handlers["eth_getTransactionReceipt"] = c.getTransactionReceipt
+	return c
+}
+
+// Handle replaces the response to a method, or adds a method that is not built in
+func (c *Chain) Handle(method string, handler Handler) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.handlers[method] = handler
+}
+
+// SetNonce sets the next nonce of an address, as if it had already sent that many transactions
+func (c *Chain) SetNonce(addr ethtypes.Address0xHex, nonce uint64) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.accounts[addr] = &account{minedNonce: nonce, pendingNonce: nonce}
+}
+
+// Transactions returns every transaction sent to the chain, in the order they were sent
+func (c *Chain) Transactions() []*Transaction {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return append([]*Transaction{}, c.txns...)
+}
+
+// Requests returns every JSON/RPC request made to the chain, in the order they were made
+func (c *Chain) Requests() []*rpcbackend.RPCRequest {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return append([]*rpcbackend.RPCRequest{}, c.requests...)
+}
+
+// Mine mines the pending transactions into a new block, returning its number
+func (c *Chain) Mine() uint64 {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return c.mine()
+}
+
+func (c *Chain) mine() uint64 {
+	block := c.newBlock(c.blocks[len(c.blocks)-1])
+	cumulativeGasUsed := new(big.Int)
+	for i, tx := range c.pending {
+		cumulativeGasUsed.Add(cumulativeGasUsed, tx.GasLimit.BigInt())
+		tx.Receipt = &rpcbackend.Receipt{
+			TransactionHash:   tx.Hash,
+			TransactionIndex:  ethtypes.NewHexIntegerU64(uint64(i)),
+			BlockHash:         block.Hash,
+			BlockNumber:       block.Number,
+			From:              tx.From,
+			To:                tx.To,
+			CumulativeGasUsed: ethtypes.NewHexInteger(new(big.Int).Set(cumulativeGasUsed)),
+			GasUsed:           tx.GasLimit,
+			EffectiveGasPrice: c.effectiveGasPrice(tx),
+			Type:              ethtypes.NewHexIntegerU64(txType(tx)),
+			Status:            ethtypes.NewHexIntegerU64(1),
+			Logs:              []*rpcbackend.Log{},
+		}
+		if tx.To == nil {
+			tx.Receipt.ContractAddress = contractAddress(tx.From, tx.Nonce.Uint64())
+		}
+		c.accounts[*tx.From].minedNonce = tx.Nonce.Uint64() + 1
+		block.Transactions = append(block.Transactions, &rpcbackend.TransactionInfo{Hash: tx.Hash})
+	}
+	block.GasUsed = ethtypes.NewHexInteger(cumulativeGasUsed)
+	c.pending = nil
+	c.blocks = append(c.blocks, block)
+	return block.Number.Uint64()
+}
+
+func (c *Chain) newBlock(parent *rpcbackend.Block) *rpcbackend.Block {
+	block := &rpcbackend.Block{
+		Number:        ethtypes.NewHexIntegerU64(0),
+		ParentHash:    make(ethtypes.HexBytes0xPrefix, 32),
+		Timestamp:     ethtypes.NewHexInteger64(fftypes.Now().Time().Unix()),
+		GasLimit:      ethtypes.NewHexIntegerU64(30000000),
+		GasUsed:       ethtypes.NewHexIntegerU64(0),
+		BaseFeePerGas: ethtypes.NewHexInteger(c.options.BaseFeePerGas),
+		Transactions:  []*rpcbackend.TransactionInfo{},
+	}
+	if parent != nil {
+		block.Number = ethtypes.NewHexIntegerU64(parent.Number.Uint64() + 1)
+		block.ParentHash = parent.Hash
+	}
+	block.Hash = keccak256([]byte("block"), block.Number.BigInt().Bytes())
+	return block
+}
+
+func (c *Chain) effectiveGasPrice(tx *Transaction) *ethtypes.HexInteger {
+	if tx.MaxFeePerGas == nil {
+		return tx.GasPrice
+	}
+	price := new(big.Int).Add(c.options.BaseFeePerGas, tx.MaxPriorityFeePerGas.BigInt())
+	if price.Cmp(tx.MaxFeePerGas.BigInt()) > 0 {
+		price = tx.MaxFeePerGas.BigInt()
+	}
+	return ethtypes.NewHexInteger(price)
+}
+
+func txType(tx *Transaction) uint64 {
+	if tx.MaxFeePerGas != nil {
+		return 2
+	}
+	return 0
+}
+
+// contractAddress is the address of a contract deployed by a transaction - the last 20 bytes of the hash of the
+// sender and the nonce
+func contractAddress(from *ethtypes.Address0xHex, nonce uint64) *ethtypes.Address0xHex {
+	hash := keccak256(rlp.List{rlp.WrapAddress(from), rlp.WrapInt(new(big.Int).SetUint64(nonce))}.Encode())
+	var addr ethtypes.Address0xHex
+	copy(addr[:], hash[12:])
+	return &addr
+}
+
+func keccak256(data ...[]byte) ethtypes.HexBytes0xPrefix {
+	hash := sha3.NewLegacyKeccak256()
+	for _, d := range data {
+		hash.Write(d)
+	}
+	return hash.Sum(nil)
+}
+
+// CallRPC calls a method of the chain, as for any other rpcbackend.RPC
+func (c *Chain) CallRPC(ctx context.Context, result interface{}, method string, params ...interface{}) *rpcbackend.RPCError {
+	rpcReq := &rpcbackend.RPCRequest{JSONRpc: "2.0", ID: fftypes.JSONAnyPtr("1"), Method: method}
+	for i, param := range params {
+		b, err := json.Marshal(param)
+		if err != nil {
+			return rpcbackend.NewRPCError(ctx, rpcbackend.RPCCodeInvalidRequest, signermsgs.MsgInvalidParam, i, method, err)
+		}
+		rpcReq.Params = append(rpcReq.Params, fftypes.JSONAnyPtrBytes(b))
+	}
+	rpcRes, _ := c.SyncRequest(ctx, rpcReq)
+	if rpcRes.Error != nil {
+		return rpcRes.Error
+	}
+	if err := json.Unmarshal(rpcRes.Result.Bytes(), result); err != nil {
+		return rpcbackend.NewRPCError(ctx, rpcbackend.RPCCodeParseError, signermsgs.MsgResultParseFailed, result, err)
+	}
+	return nil
+}
+
+// SyncRequest calls a method of the chain, returning the error in the response as well when it fails
+func (c *Chain) SyncRequest(ctx context.Context, rpcReq *rpcbackend.RPCRequest) (*rpcbackend.RPCResponse, error) {
+	c.mux.Lock()
+	c.requests = append(c.requests, rpcReq)
+	handler := c.handlers[rpcReq.Method]
+	c.mux.Unlock()
+
+	var result interface{}
+	var rpcErr *rpcbackend.RPCError
+	if handler == nil {
+		rpcErr = rpcbackend.NewRPCError(ctx, rpcbackend.RPCCodeMethodNotFound, signermsgs.MsgFakeChainMethodNotFound, rpcReq.Method)
+	} else {
+		result, rpcErr = handler(ctx, rpcReq.Params)
+	}
+	if rpcErr != nil {
+		return &rpcbackend.RPCResponse{JSONRpc: "2.0", ID: rpcReq.ID, Error: rpcErr}, rpcErr.Error()
+	}
+	b, _ := json.Marshal(result)
+	return &rpcbackend.RPCResponse{JSONRpc: "2.0", ID: rpcReq.ID, Result: fftypes.JSONAnyPtrBytes(b)}, nil
+}
+
+// BatchRequest calls each method of the batch in turn
+func (c *Chain) BatchRequest(ctx context.Context, rpcReqs []*rpcbackend.RPCRequest) ([]*rpcbackend.RPCResponse, error) {
+	rpcResponses := make([]*rpcbackend.RPCResponse, len(rpcReqs))
+	for i, rpcReq := range rpcReqs {
+		rpcResponses[i], _ = c.SyncRequest(ctx, rpcReq)
+	}
+	return rpcResponses, nil
+}
+
+// ServeHTTP serves the chain over HTTP, for single and batch JSON/RPC requests
+func (c *Chain) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	var response interface{}
+	b, err := io.ReadAll(r.Body)
+	if err == nil {
+		if len(b) > 0 && b[0] == '[' {
+			var rpcReqs []*rpcbackend.RPCRequest
+			if err = json.Unmarshal(b, &rpcReqs); err == nil {
+				response, _ = c.BatchRequest(ctx, rpcReqs)
+			}
+		} else {
+			var rpcReq *rpcbackend.RPCRequest
+			if err = json.Unmarshal(b, &rpcReq); err == nil {
+				response, _ = c.SyncRequest(ctx, rpcReq)
+			}
+		}
+	}
+	if err != nil {
+		response = rpcbackend.RPCErrorResponse(i18n.NewError(ctx, signermsgs.MsgInvalidRequest), fftypes.JSONAnyPtr("1"), rpcbackend.RPCCodeParseError)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+// param parses a parameter of a method, which must be present
+func param(ctx context.Context, params []*fftypes.JSONAny, i int, method string, v interface{}) *rpcbackend.RPCError {
+	if i >= len(params) {
+		return rpcbackend.NewRPCError(ctx, rpcbackend.RPCCodeInvalidRequest, signermsgs.MsgInvalidParamCount, i+1, len(params))
+	}
+	if err := json.Unmarshal(params[i].Bytes(), v); err != nil {
+		return rpcbackend.NewRPCError(ctx, rpcbackend.RPCCodeInvalidRequest, signermsgs.MsgInvalidParam, i, method, err)
+	}
+	return nil
+}
+
+func (c *Chain) chainID(_ context.Context, _ []*fftypes.JSONAny) (interface{}, *rpcbackend.RPCError) {
+	return ethtypes.NewHexInteger64(c.options.ChainID), nil
+}
+
+func (c *Chain) netVersion(_ context.Context, _ []*fftypes.JSONAny) (interface{}, *rpcbackend.RPCError) {
+	return strconv.FormatInt(c.options.ChainID, 10), nil
+}
+
+func (c *Chain) blockNumber(_ context.Context, _ []*fftypes.JSONAny) (interface{}, *rpcbackend.RPCError) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return c.blocks[len(c.blocks)-1].Number, nil
+}
+
+// block returns the block for a tag or number, or nil if there is no such block
+func (c *Chain) block(ctx context.Context, params []*fftypes.JSONAny, i int, method string) (*rpcbackend.Block, *rpcbackend.RPCError) {
+	var blockParam string
+	if rpcErr := param(ctx, params, i, method, &blockParam); rpcErr != nil {
+		return nil, rpcErr
+	}
+	switch blockParam {
+	case "earliest":
+		return c.blocks[0], nil
+	case "latest", "pending", "safe", "finalized":
+		return c.blocks[len(c.blocks)-1], nil
+	}
+	var number ethtypes.HexInteger
+	if rpcErr := param(ctx, params, i, method, &number); rpcErr != nil {
+		return nil, rpcErr
+	}
+	if number.BigInt().Cmp(big.NewInt(int64(len(c.blocks)))) >= 0 {
+		return nil, nil
+	}
+	return c.blocks[number.Int64()], nil
+}
+
+func (c *Chain) getBlockByNumber(ctx context.Context, params []*fftypes.JSONAny) (interface{}, *rpcbackend.RPCError) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	block, rpcErr := c.block(ctx, params, 0, "eth_getBlockByNumber")
+	if block == nil {
+		return nil, rpcErr
+	}
+	var fullTransactions bool
+	if len(params) > 1 {
+		if rpcErr := param(ctx, params, 1, "eth_getBlockByNumber", &fullTransactions); rpcErr != nil {
+			return nil, rpcErr
+		}
+	}
+	if !fullTransactions {
+		return block, nil
+	}
+	withTransactions := *block
+	withTransactions.Transactions = make([]*rpcbackend.TransactionInfo, len(block.Transactions))
+	for i, txInfo := range block.Transactions {
+		withTransactions.Transactions[i] = c.transactionInfo(c.transaction(txInfo.Hash))
+	}
+	return &withTransactions, nil
+}
+
+func (c *Chain) getTransactionCount(ctx context.Context, params []*fftypes.JSONAny) (interface{}, *rpcbackend.RPCError) {
+	var addr ethtypes.Address0xHex
+	if rpcErr := param(ctx, params, 0, "eth_getTransactionCount", &addr); rpcErr != nil {
+		return nil, rpcErr
+	}
+	var blockParam string
+	if len(params) > 1 {
+		_ = json.Unmarshal(params[1].Bytes(), &blockParam)
+	}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	nonce := uint64(0)
+	if acct := c.accounts[addr]; acct != nil {
+		nonce = acct.minedNonce
+		if blockParam == "pending" {
+			nonce = acct.pendingNonce
+		}
+	}
+	return ethtypes.NewHexIntegerU64(nonce), nil
+}
+
+func (c *Chain) estimateGas(_ context.Context, _ []*fftypes.JSONAny) (interface{}, *rpcbackend.RPCError) {
+	return ethtypes.NewHexIntegerU64(c.options.GasEstimate), nil
+}
+
+func (c *Chain) gasPrice(_ context.Context, _ []*fftypes.JSONAny) (interface{}, *rpcbackend.RPCError) {
+	return ethtypes.NewHexInteger(c.options.GasPrice), nil
+}
+
+func (c *Chain) maxPriorityFeePerGas(_ context.Context, _ []*fftypes.JSONAny) (interface{}, *rpcbackend.RPCError) {
+	return ethtypes.NewHexInteger(c.options.MaxPriorityFeePerGas), nil
+}
+
+// feeHistory returns the base fee of the newest blocks, and the priority fee for every requested percentile
+func (c *Chain) feeHistory(ctx context.Context, params []*fftypes.JSONAny) (interface{}, *rpcbackend.RPCError) {
+	var blockCount ethtypes.HexUint64
+	if rpcErr := param(ctx, params, 0, "eth_feeHistory", &blockCount); rpcErr != nil {
+		return nil, rpcErr
+	}
+	c.mux.Lock()
+	newest, rpcErr := c.block(ctx, params, 1, "eth_feeHistory")
+	c.mux.Unlock()
+	if newest == nil {
+		return nil, rpcErr
+	}
+	var percentiles []float64
+	if len(params) > 2 {
+		if rpcErr := param(ctx, params, 2, "eth_feeHistory", &percentiles); rpcErr != nil {
+			return nil, rpcErr
+		}
+	}
+	count := min(blockCount.Uint64(), newest.Number.Uint64()+1)
+	feeHistory := &rpcbackend.FeeHistory{
+		OldestBlock:   ethtypes.NewHexIntegerU64(newest.Number.Uint64() + 1 - count),
+		BaseFeePerGas: []*ethtypes.HexInteger{ethtypes.NewHexInteger(c.options.BaseFeePerGas)},
+		GasUsedRatio:  []float64{},
+	}
+	for i := uint64(0); i < count; i++ {
+		feeHistory.BaseFeePerGas = append(feeHistory.BaseFeePerGas, ethtypes.NewHexInteger(c.options.BaseFeePerGas))
+		feeHistory.GasUsedRatio = append(feeHistory.GasUsedRatio, 0.5)
+		if len(percentiles) > 0 {
+			rewards := make([]*ethtypes.HexInteger, len(percentiles))
+			for j := range rewards {
+				rewards[j] = ethtypes.NewHexInteger(c.options.MaxPriorityFeePerGas)
+			}
+			feeHistory.Reward = append(feeHistory.Reward, rewards)
+		}
+	}
+	return feeHistory, nil
+}
+
+func (c *Chain) call(_ context.Context, _ []*fftypes.JSONAny) (interface{}, *rpcbackend.RPCError) {
+	return ethtypes.HexBytes0xPrefix{}, nil
+}
+
+func (c *Chain) sendRawTransaction(ctx context.Context, params []*fftypes.JSONAny) (interface{}, *rpcbackend.RPCError) {
+	var rawTx ethtypes.HexBytes0xPrefix
+	if rpcErr := param(ctx, params, 0, "eth_sendRawTransaction", &rawTx); rpcErr != nil {
+		return nil, rpcErr
+	}
+	from, txn, err := ethsigner.RecoverRawTransaction(ctx, rawTx, c.options.ChainID)
+	if err != nil {
+		return nil, rpcbackend.RPCErrorResponse(err, nil, rpcbackend.RPCCodeInvalidRequest).Error
+	}
+	tx := &Transaction{
+		Transaction: txn.Transaction,
+		Hash:        keccak256(rawTx),
+		From:        from,
+		Raw:         rawTx,
+	}
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	acct := c.accounts[*from]
+	if acct == nil {
+		acct = &account{}
+		c.accounts[*from] = acct
+	}
+	acct.pendingNonce = max(acct.pendingNonce, tx.Nonce.Uint64()+1)
+	c.txns = append(c.txns, tx)
+	c.pending = append(c.pending, tx)
+	if !c.options.ManualMining {
+		c.mine()
+	}
+	return tx.Hash, nil
+}
+
+// transaction returns the transaction with a hash, or nil if it was not sent to the chain
+func (c *Chain) transaction(hash ethtypes.HexBytes0xPrefix) *Transaction {
+	for _, tx := range c.txns {
+		if tx.Hash.Equals(hash) {
+			return tx
+		}
+	}
+	return nil
+}
+
+func (c *Chain) transactionInfo(tx *Transaction) *rpcbackend.TransactionInfo {
+	txInfo := &rpcbackend.TransactionInfo{
+		Hash:                 tx.Hash,
+		Type:                 ethtypes.NewHexIntegerU64(txType(tx)),
+		ChainID:              ethtypes.NewHexInteger64(c.options.ChainID),
+		From:                 tx.From,
+		To:                   tx.To,
+		Nonce:                tx.Nonce,
+		Gas:                  tx.GasLimit,
+		GasPrice:             tx.GasPrice,
+		MaxFeePerGas:         tx.MaxFeePerGas,
+		MaxPriorityFeePerGas: tx.MaxPriorityFeePerGas,
+		Value:                tx.Value,
+		Input:                tx.Data,
+	}
+	if tx.Receipt != nil {
+		txInfo.BlockHash = tx.Receipt.BlockHash
+		txInfo.BlockNumber = tx.Receipt.BlockNumber
+		txInfo.TransactionIndex = tx.Receipt.TransactionIndex
+	}
+	return txInfo
+}
+
+func (c *Chain) getTransactionByHash(ctx context.Context, params []*fftypes.JSONAny) (interface{}, *rpcbackend.RPCError) {
+	var hash ethtypes.HexBytes0xPrefix
+	if rpcErr := param(ctx, params, 0, "eth_getTransactionByHash", &hash); rpcErr != nil {
+		return nil, rpcErr
+	}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if tx := c.transaction(hash); tx != nil {
+		return c.transactionInfo(tx), nil
+	}
+	return nil, nil
+}
+
+func (c *Chain) getTransactionReceipt(ctx context.Context, params []*fftypes.JSONAny) (interface{}, *rpcbackend.RPCError) {
+	var hash ethtypes.HexBytes0xPrefix
+	if rpcErr := param(ctx, params, 0, "eth_getTransactionReceipt", &hash); rpcErr != nil {
+		return nil, rpcErr
+	}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if tx := c.transaction(hash); tx != nil && tx.Receipt != nil {
+		return tx.Receipt, nil
+	}
+	return nil, nil
+}
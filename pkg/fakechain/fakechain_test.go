@@ -0,0 +1,371 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fakechain
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-signer/pkg/ethsigner"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+	"github.com/hyperledger/firefly-signer/pkg/secp256k1"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestKey(t *testing.T) *secp256k1.KeyPair {
+	kp, err := secp256k1.GenerateSecp256k1KeyPair()
+	assert.NoError(t, err)
+	return kp
+}
+
+func signTestTx(t *testing.T, kp *secp256k1.KeyPair, tx *ethsigner.Transaction, chainID int64) ethtypes.HexBytes0xPrefix {
+	rawTx, err := tx.Sign(kp, chainID)
+	assert.NoError(t, err)
+	return rawTx
+}
+
+func TestDefaults(t *testing.T) {
+	ctx := context.Background()
+	ec := rpcbackend.NewEthClient(New(nil))
+
+	var chainID ethtypes.HexInteger
+	assert.Nil(t, ec.CallRPC(ctx, &chainID, "eth_chainId"))
+	assert.Equal(t, int64(1337), chainID.Int64())
+
+	var netVersion string
+	assert.Nil(t, ec.CallRPC(ctx, &netVersion, "net_version"))
+	assert.Equal(t, "1337", netVersion)
+
+	blockNumber, rpcErr := ec.GetBlockNumber(ctx)
+	assert.Nil(t, rpcErr)
+	assert.Zero(t, blockNumber)
+
+	gas, rpcErr := ec.EstimateGas(ctx, &rpcbackend.CallRequest{})
+	assert.Nil(t, rpcErr)
+	assert.Equal(t, int64(100000), gas.Int64())
+
+	var gasPrice, priorityFee ethtypes.HexInteger
+	assert.Nil(t, ec.CallRPC(ctx, &gasPrice, "eth_gasPrice"))
+	assert.Equal(t, int64(1000000000), gasPrice.Int64())
+	assert.Nil(t, ec.CallRPC(ctx, &priorityFee, "eth_maxPriorityFeePerGas"))
+	assert.Equal(t, int64(1000000000), priorityFee.Int64())
+
+	result, rpcErr := ec.Call(ctx, &rpcbackend.CallRequest{}, "latest")
+	assert.Nil(t, rpcErr)
+	assert.Empty(t, result)
+
+	block, rpcErr := ec.GetBlockByNumber(ctx, "earliest", false)
+	assert.Nil(t, rpcErr)
+	assert.Zero(t, block.Number.Int64())
+	assert.Equal(t, int64(1000000000), block.BaseFeePerGas.Int64())
+}
+
+func TestOptions(t *testing.T) {
+	ctx := context.Background()
+	c := New(&Options{
+		ChainID:              12345,
+		GasEstimate:          50000,
+		GasPrice:             big.NewInt(10),
+		BaseFeePerGas:        big.NewInt(20),
+		MaxPriorityFeePerGas: big.NewInt(30),
+	})
+
+	var chainID, gasPrice, priorityFee ethtypes.HexInteger
+	assert.Nil(t, c.CallRPC(ctx, &chainID, "eth_chainId"))
+	assert.Equal(t, int64(12345), chainID.Int64())
+	assert.Nil(t, c.CallRPC(ctx, &gasPrice, "eth_gasPrice"))
+	assert.Equal(t, int64(10), gasPrice.Int64())
+	assert.Nil(t, c.CallRPC(ctx, &priorityFee, "eth_maxPriorityFeePerGas"))
+	assert.Equal(t, int64(30), priorityFee.Int64())
+
+	gas, rpcErr := rpcbackend.NewEthClient(c).EstimateGas(ctx, &rpcbackend.CallRequest{})
+	assert.Nil(t, rpcErr)
+	assert.Equal(t, int64(50000), gas.Int64())
+}
+
+func TestSendTransactionsAutoMined(t *testing.T) {
+	ctx := context.Background()
+	c := New(nil)
+	ec := rpcbackend.NewEthClient(c)
+	kp := newTestKey(t)
+	to := ethtypes.MustNewAddress("0x497eedc4299dea2f2a364be10025d0ad0f702de3")
+
+	nonce, rpcErr := ec.GetTransactionCount(ctx, kp.Address, "pending")
+	assert.Nil(t, rpcErr)
+	assert.Zero(t, nonce)
+
+	// EIP-1559 transaction
+	rawTx := signTestTx(t, kp, &ethsigner.Transaction{
+		Nonce:                ethtypes.NewHexIntegerU64(0),
+		GasLimit:             ethtypes.NewHexIntegerU64(21000),
+		MaxFeePerGas:         ethtypes.NewHexIntegerU64(5000000000),
+		MaxPriorityFeePerGas: ethtypes.NewHexIntegerU64(2000000000),
+		To:                   to,
+		Value:                ethtypes.NewHexIntegerU64(100),
+	}, 1337)
+	var txHash ethtypes.HexBytes0xPrefix
+	assert.Nil(t, ec.CallRPC(ctx, &txHash, "eth_sendRawTransaction", rawTx))
+
+	receipt, rpcErr := ec.GetTransactionReceipt(ctx, txHash)
+	assert.Nil(t, rpcErr)
+	assert.True(t, receipt.Succeeded())
+	assert.Equal(t, int64(1), receipt.BlockNumber.Int64())
+	assert.Equal(t, kp.Address, *receipt.From)
+	assert.Equal(t, to, receipt.To)
+	assert.Equal(t, int64(21000), receipt.GasUsed.Int64())
+	assert.Equal(t, int64(3000000000), receipt.EffectiveGasPrice.Int64())
+	assert.Equal(t, int64(2), receipt.Type.Int64())
+	assert.Nil(t, receipt.ContractAddress)
+
+	// Legacy contract deployment, where the fee cap is below the base fee plus the priority fee
+	rawTx = signTestTx(t, kp, &ethsigner.Transaction{
+		Nonce:    ethtypes.NewHexIntegerU64(1),
+		GasLimit: ethtypes.NewHexIntegerU64(1000000),
+		GasPrice: ethtypes.NewHexIntegerU64(1500000000),
+		Data:     ethtypes.MustNewHexBytes0xPrefix("0xfeedbeef"),
+	}, 1337)
+	assert.Nil(t, ec.CallRPC(ctx, &txHash, "eth_sendRawTransaction", rawTx))
+	receipt, rpcErr = ec.GetTransactionReceipt(ctx, txHash)
+	assert.Nil(t, rpcErr)
+	assert.Equal(t, int64(2), receipt.BlockNumber.Int64())
+	assert.Equal(t, int64(0), receipt.Type.Int64())
+	assert.Equal(t, int64(1500000000), receipt.EffectiveGasPrice.Int64())
+	assert.NotNil(t, receipt.ContractAddress)
+	assert.Equal(t, contractAddress(&kp.Address, 1), receipt.ContractAddress)
+
+	rawTx = signTestTx(t, kp, &ethsigner.Transaction{
+		Nonce:                ethtypes.NewHexIntegerU64(2),
+		GasLimit:             ethtypes.NewHexIntegerU64(21000),
+		MaxFeePerGas:         ethtypes.NewHexIntegerU64(1500000000),
+		MaxPriorityFeePerGas: ethtypes.NewHexIntegerU64(1000000000),
+		To:                   to,
+	}, 1337)
+	assert.Nil(t, ec.CallRPC(ctx, &txHash, "eth_sendRawTransaction", rawTx))
+	receipt, rpcErr = ec.GetTransactionReceipt(ctx, txHash)
+	assert.Nil(t, rpcErr)
+	assert.Equal(t, int64(1500000000), receipt.EffectiveGasPrice.Int64())
+
+	nonce, rpcErr = ec.GetTransactionCount(ctx, kp.Address, "latest")
+	assert.Nil(t, rpcErr)
+	assert.Equal(t, uint64(3), nonce.Uint64())
+
+	txns := c.Transactions()
+	assert.Len(t, txns, 3)
+	assert.Equal(t, txHash, txns[2].Hash)
+	assert.Equal(t, rawTx, txns[2].Raw)
+	assert.Equal(t, kp.Address, *txns[2].From)
+
+	var txInfo *rpcbackend.TransactionInfo
+	assert.Nil(t, ec.CallRPC(ctx, &txInfo, "eth_getTransactionByHash", txHash))
+	assert.Equal(t, int64(3), txInfo.BlockNumber.Int64())
+	assert.Equal(t, int64(2), txInfo.Nonce.Int64())
+
+	block, rpcErr := ec.GetBlockByNumber(ctx, rpcbackend.BlockNumber(2), true)
+	assert.Nil(t, rpcErr)
+	assert.Len(t, block.Transactions, 1)
+	assert.Equal(t, int64(1000000), block.Transactions[0].Gas.Int64())
+	assert.Equal(t, ethtypes.HexBytes0xPrefix(ethtypes.MustNewHexBytes0xPrefix("0xfeedbeef")), block.Transactions[0].Input)
+
+	block, rpcErr = ec.GetBlockByNumber(ctx, "latest", false)
+	assert.Nil(t, rpcErr)
+	assert.Equal(t, int64(3), block.Number.Int64())
+	assert.Equal(t, txHash, block.Transactions[0].Hash)
+
+	block, rpcErr = ec.GetBlockByNumber(ctx, rpcbackend.BlockNumber(4), false)
+	assert.Nil(t, rpcErr)
+	assert.Nil(t, block)
+}
+
+func TestManualMining(t *testing.T) {
+	ctx := context.Background()
+	c := New(&Options{ManualMining: true})
+	ec := rpcbackend.NewEthClient(c)
+	kp := newTestKey(t)
+	c.SetNonce(kp.Address, 10)
+
+	var txHash ethtypes.HexBytes0xPrefix
+	for nonce := uint64(10); nonce < 12; nonce++ {
+		rawTx := signTestTx(t, kp, &ethsigner.Transaction{
+			Nonce:    ethtypes.NewHexIntegerU64(nonce),
+			GasLimit: ethtypes.NewHexIntegerU64(21000),
+			GasPrice: ethtypes.NewHexIntegerU64(1000000000),
+		}, 1337)
+		assert.Nil(t, ec.CallRPC(ctx, &txHash, "eth_sendRawTransaction", rawTx))
+	}
+
+	pending, rpcErr := ec.GetTransactionCount(ctx, kp.Address, "pending")
+	assert.Nil(t, rpcErr)
+	assert.Equal(t, uint64(12), pending.Uint64())
+	latest, rpcErr := ec.GetTransactionCount(ctx, kp.Address, "latest")
+	assert.Nil(t, rpcErr)
+	assert.Equal(t, uint64(10), latest.Uint64())
+
+	receipt, rpcErr := ec.GetTransactionReceipt(ctx, txHash)
+	assert.Nil(t, rpcErr)
+	assert.Nil(t, receipt)
+	var txInfo *rpcbackend.TransactionInfo
+	assert.Nil(t, ec.CallRPC(ctx, &txInfo, "eth_getTransactionByHash", txHash))
+	assert.Nil(t, txInfo.BlockNumber)
+
+	assert.Equal(t, uint64(1), c.Mine())
+	receipt, rpcErr = ec.GetTransactionReceipt(ctx, txHash)
+	assert.Nil(t, rpcErr)
+	assert.Equal(t, int64(1), receipt.TransactionIndex.Int64())
+	assert.Equal(t, int64(42000), receipt.CumulativeGasUsed.Int64())
+	latest, rpcErr = ec.GetTransactionCount(ctx, kp.Address, "latest")
+	assert.Nil(t, rpcErr)
+	assert.Equal(t, uint64(12), latest.Uint64())
+
+	assert.Nil(t, ec.CallRPC(ctx, &txInfo, "eth_getTransactionByHash", ethtypes.MustNewHexBytes0xPrefix("0x1234")))
+	assert.Nil(t, txInfo)
+}
+
+func TestFeeHistory(t *testing.T) {
+	ctx := context.Background()
+	c := New(&Options{ManualMining: true})
+	ec := rpcbackend.NewEthClient(c)
+	c.Mine()
+	c.Mine()
+
+	feeHistory, rpcErr := ec.FeeHistory(ctx, 5, "latest", []float64{10, 50})
+	assert.Nil(t, rpcErr)
+	assert.Zero(t, feeHistory.OldestBlock.Int64())
+	assert.Len(t, feeHistory.BaseFeePerGas, 4)
+	assert.Len(t, feeHistory.GasUsedRatio, 3)
+	assert.Len(t, feeHistory.Reward, 3)
+	assert.Equal(t, int64(1000000000), feeHistory.Reward[2][1].Int64())
+
+	feeHistory, rpcErr = ec.FeeHistory(ctx, 1, "latest", nil)
+	assert.Nil(t, rpcErr)
+	assert.Equal(t, int64(2), feeHistory.OldestBlock.Int64())
+	assert.Empty(t, feeHistory.Reward)
+
+	var result interface{}
+	rpcErr = c.CallRPC(ctx, &result, "eth_feeHistory", "0x1", "0x10")
+	assert.Nil(t, rpcErr)
+	assert.Nil(t, result)
+	rpcErr = c.CallRPC(ctx, &result, "eth_feeHistory", "0x1", "latest", "wrong")
+	assert.Regexp(t, "FF22011.*2.*eth_feeHistory", rpcErr.Message)
+	rpcErr = c.CallRPC(ctx, &result, "eth_feeHistory", "0x1", 1)
+	assert.Regexp(t, "FF22011.*1.*eth_feeHistory", rpcErr.Message)
+	rpcErr = c.CallRPC(ctx, &result, "eth_feeHistory", "0x1", "wrong")
+	assert.Regexp(t, "FF22011.*1.*eth_feeHistory", rpcErr.Message)
+	rpcErr = c.CallRPC(ctx, &result, "eth_feeHistory")
+	assert.Regexp(t, "FF22019", rpcErr.Message)
+}
+
+func TestInvalidParams(t *testing.T) {
+	ctx := context.Background()
+	c := New(nil)
+	var result interface{}
+
+	for _, method := range []string{"eth_getTransactionCount", "eth_sendRawTransaction", "eth_getTransactionByHash", "eth_getTransactionReceipt", "eth_getBlockByNumber"} {
+		rpcErr := c.CallRPC(ctx, &result, method, false)
+		assert.Regexp(t, "FF22011.*0.*"+method, rpcErr.Message)
+		assert.Equal(t, int64(rpcbackend.RPCCodeInvalidRequest), rpcErr.Code)
+	}
+	rpcErr := c.CallRPC(ctx, &result, "eth_getBlockByNumber", "latest", "wrong")
+	assert.Regexp(t, "FF22011.*1.*eth_getBlockByNumber", rpcErr.Message)
+
+	rpcErr = c.CallRPC(ctx, &result, "eth_sendRawTransaction", "0xfeedbeef")
+	assert.NotNil(t, rpcErr)
+
+	// Signed for the wrong chain
+	rawTx := signTestTx(t, newTestKey(t), &ethsigner.Transaction{
+		Nonce:    ethtypes.NewHexIntegerU64(0),
+		GasLimit: ethtypes.NewHexIntegerU64(21000),
+		GasPrice: ethtypes.NewHexIntegerU64(1),
+	}, 1)
+	rpcErr = c.CallRPC(ctx, &result, "eth_sendRawTransaction", rawTx)
+	assert.NotNil(t, rpcErr)
+	assert.Empty(t, c.Transactions())
+
+	rpcErr = c.CallRPC(ctx, &result, "eth_chainId", map[bool]bool{false: true})
+	assert.Regexp(t, "FF22011", rpcErr.Message)
+
+	var chainID bool
+	rpcErr = c.CallRPC(ctx, &chainID, "eth_chainId")
+	assert.Regexp(t, "FF22065", rpcErr.Message)
+}
+
+func TestHandleAndUnknownMethod(t *testing.T) {
+	ctx := context.Background()
+	c := New(nil)
+	var result string
+
+	rpcErr := c.CallRPC(ctx, &result, "eth_getCode", "0x497eedc4299dea2f2a364be10025d0ad0f702de3")
+	assert.Regexp(t, "FF22228.*eth_getCode", rpcErr.Message)
+	assert.Equal(t, int64(rpcbackend.RPCCodeMethodNotFound), rpcErr.Code)
+
+	c.Handle("eth_getCode", func(_ context.Context, params []*fftypes.JSONAny) (interface{}, *rpcbackend.RPCError) {
+		return "0xfeedbeef", nil
+	})
+	c.Handle("eth_sendRawTransaction", func(_ context.Context, params []*fftypes.JSONAny) (interface{}, *rpcbackend.RPCError) {
+		return nil, &rpcbackend.RPCError{Code: -32000, Message: "nonce too low"}
+	})
+	assert.Nil(t, c.CallRPC(ctx, &result, "eth_getCode", "0x497eedc4299dea2f2a364be10025d0ad0f702de3"))
+	assert.Equal(t, "0xfeedbeef", result)
+	rpcErr = c.CallRPC(ctx, &result, "eth_sendRawTransaction", "0xfeedbeef")
+	assert.Equal(t, "nonce too low", rpcErr.Message)
+
+	requests := c.Requests()
+	assert.Len(t, requests, 3)
+	assert.Equal(t, "eth_getCode", requests[0].Method)
+}
+
+func TestServeHTTP(t *testing.T) {
+	ctx := context.Background()
+	c := New(nil)
+	server := httptest.NewServer(c)
+	defer server.Close()
+	rpc := rpcbackend.NewRPCClient(resty.New().SetBaseURL(server.URL))
+
+	var chainID ethtypes.HexInteger
+	assert.Nil(t, rpc.CallRPC(ctx, &chainID, "eth_chainId"))
+	assert.Equal(t, int64(1337), chainID.Int64())
+
+	var result interface{}
+	rpcErr := rpc.CallRPC(ctx, &result, "eth_unknown")
+	assert.Regexp(t, "FF22228", rpcErr.Message)
+
+	rpcResponses, err := rpc.BatchRequest(ctx, []*rpcbackend.RPCRequest{
+		{JSONRpc: "2.0", ID: fftypes.JSONAnyPtr("1"), Method: "eth_blockNumber"},
+		{JSONRpc: "2.0", ID: fftypes.JSONAnyPtr("2"), Method: "eth_unknown"},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, rpcResponses, 2)
+	assert.Equal(t, `"0x0"`, rpcResponses[0].Result.String())
+	assert.Regexp(t, "FF22228", rpcResponses[1].Error.Message)
+
+	for _, body := range []string{`!!!`, `[!!!`} {
+		res, err := http.Post(server.URL, "application/json", strings.NewReader(body))
+		assert.NoError(t, err)
+		var rpcRes rpcbackend.RPCResponse
+		assert.NoError(t, json.NewDecoder(res.Body).Decode(&rpcRes))
+		res.Body.Close()
+		assert.Equal(t, int64(rpcbackend.RPCCodeParseError), rpcRes.Error.Code)
+		assert.Regexp(t, "FF22018", rpcRes.Error.Message)
+	}
+}
@@ -59,6 +59,8 @@ type paramDetails struct {
 	InternalType string `json:"internalType,omitempty"`
 	Indexed      bool   `json:"indexed,omitempty"`
 	Index        *int   `json:"index,omitempty"`
+	Checksum     bool   `json:"checksum,omitempty"`
+	Format       string `json:"format,omitempty"`
 }
 
 type Schema struct {
@@ -17,10 +17,49 @@
 package ffi2abi
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/hyperledger/firefly-signer/pkg/abi"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
 	"github.com/santhosh-tekuri/jsonschema/v5"
 )
 
-type ParamValidator struct{}
+// FormatValidator checks a value against a custom format, referred to by name
+// in the "format" field of the details of an FFI parameter. For array types the
+// validator is called with each elementary value in the array.
+type FormatValidator func(ctx context.Context, v interface{}) error
+
+// ParamValidator is a JSON Schema extension for the "details" keyword of FFI parameters.
+//
+// As well as checking the details are well formed when the schema is compiled, values
+// are checked against the ABI type in the details when they are validated:
+//   - integers must fit in the bit-width of the type, and unsigned types cannot be negative
+//   - fixed length bytes types (bytes1 to bytes32) must be exactly that many bytes of hex
+//   - addresses must be 20 bytes, and if mixed-case must match the EIP-55 checksum.
+//     Setting "checksum": true in the details requires the checksum encoding
+//   - if "format" is set in the details, the FormatValidator registered with that name is called
+type ParamValidator struct {
+	formats map[string]FormatValidator
+}
+
+// Violation is a single failure validating a value against an FFI parameter schema
+type Violation struct {
+	Path    string `json:"path"`    // JSON pointer to the invalid value within the input
+	Keyword string `json:"keyword"` // JSON pointer to the schema keyword that rejected the value
+	Message string `json:"message"`
+}
+
+type paramSchema struct {
+	tc       abi.TypeComponent
+	checksum bool
+	format   string
+	formatFn FormatValidator
+}
 
 var compiledMetaSchema = jsonschema.MustCompileString("ffiParamDetails.json", `{
 	"$ref": "#/$defs/ethereumParam",
@@ -156,6 +195,12 @@ var compiledMetaSchema = jsonschema.MustCompileString("ffiParamDetails.json", `{
 				},
 				"indexed": {
 					"type": "boolean"
+				},
+				"checksum": {
+					"type": "boolean"
+				},
+				"format": {
+					"type": "string"
 				}
 			},
 			"required": [
@@ -174,6 +219,12 @@ var compiledMetaSchema = jsonschema.MustCompileString("ffiParamDetails.json", `{
 				"indexed": {
 					"type": "boolean"
 				},
+				"checksum": {
+					"type": "boolean"
+				},
+				"format": {
+					"type": "string"
+				},
 				"index": {
 					"type": "integer"
 				}
@@ -186,8 +237,35 @@ var compiledMetaSchema = jsonschema.MustCompileString("ffiParamDetails.json", `{
 	}
 }`)
 
-func (v *ParamValidator) Compile(_ jsonschema.CompilerContext, _ map[string]interface{}) (jsonschema.ExtSchema, error) {
-	return nil, nil
+// RegisterFormat adds a custom format validator, which parameters can refer to by
+// name in the "format" field of their details. Formats must be registered before
+// the schema that refers to them is compiled.
+func (v *ParamValidator) RegisterFormat(name string, fn FormatValidator) *ParamValidator {
+	if v.formats == nil {
+		v.formats = make(map[string]FormatValidator)
+	}
+	v.formats[name] = fn
+	return v
+}
+
+func (v *ParamValidator) Compile(_ jsonschema.CompilerContext, m map[string]interface{}) (jsonschema.ExtSchema, error) {
+	ctx := context.Background()
+	details, _ := m["details"].(map[string]interface{})
+	typeString, _ := details["type"].(string)
+	tc, err := (&abi.Parameter{Type: typeString}).TypeComponentTreeCtx(ctx)
+	if err != nil {
+		// Invalid ABI types are reported when the FFI is converted to ABI
+		return nil, nil
+	}
+	ps := &paramSchema{tc: tc}
+	ps.checksum, _ = details["checksum"].(bool)
+	ps.format, _ = details["format"].(string)
+	if ps.format != "" {
+		if ps.formatFn = v.formats[ps.format]; ps.formatFn == nil {
+			return nil, i18n.NewError(ctx, signermsgs.MsgFFIUnknownFormat, ps.format)
+		}
+	}
+	return ps, nil
 }
 
 func (v *ParamValidator) GetMetaSchema() *jsonschema.Schema {
@@ -197,3 +275,111 @@ func (v *ParamValidator) GetMetaSchema() *jsonschema.Schema {
 func (v *ParamValidator) GetExtensionName() string {
 	return "details"
 }
+
+func (ps *paramSchema) Validate(vctx jsonschema.ValidationContext, v interface{}) error {
+	var violations []error
+	ps.validateValue(context.Background(), vctx, "", ps.tc, v, &violations)
+	switch len(violations) {
+	case 0:
+		return nil
+	case 1:
+		return violations[0]
+	default:
+		parent := vctx.Error("details/type", "%s", i18n.NewError(context.Background(), signermsgs.MsgFFIValueViolations, len(violations), ps.tc.String()))
+		return jsonschema.ValidationError{}.Group(parent, violations...)
+	}
+}
+
+func (ps *paramSchema) validateValue(ctx context.Context, vctx jsonschema.ValidationContext, path string, tc abi.TypeComponent, v interface{}, violations *[]error) {
+	violation := func(keyword string, err error) {
+		ve := vctx.Error(keyword, "%s", err)
+		ve.InstanceLocation += path
+		*violations = append(*violations, ve)
+	}
+	switch tc.ComponentType() {
+	case abi.FixedArrayComponent, abi.DynamicArrayComponent:
+		// Non-array values are reported by the "type" keyword of the schema
+		values, _ := v.([]interface{})
+		for i, child := range values {
+			ps.validateValue(ctx, vctx, fmt.Sprintf("%s/%d", path, i), tc.ArrayChild(), child, violations)
+		}
+		return
+	case abi.TupleComponent:
+		// Tuple fields each have their own details, so are validated individually
+		return
+	}
+
+	if tc.ElementaryType().BaseType() == abi.BaseTypeAddress {
+		if keyword, err := ps.validateAddress(ctx, v); err != nil {
+			violation(keyword, err)
+			return
+		}
+	} else {
+		cv, err := tc.ParseExternalDesc(ctx, v, tc.String())
+		if err == nil {
+			_, _, err = cv.ElementaryABIDataCtx(ctx)
+		}
+		if err != nil {
+			violation("details/type", err)
+			return
+		}
+		if b, ok := cv.Value.([]byte); ok && tc.ElementaryType().BaseType() == abi.BaseTypeBytes && tc.ElementaryM() > 0 && len(b) != int(tc.ElementaryM()) {
+			violation("details/type", i18n.NewError(ctx, signermsgs.MsgFFIBytesLengthMismatch, tc.String(), tc.ElementaryM(), len(b)))
+			return
+		}
+	}
+
+	if ps.formatFn != nil {
+		if err := ps.formatFn(ctx, v); err != nil {
+			violation("details/format", err)
+		}
+	}
+}
+
+func (ps *paramSchema) validateAddress(ctx context.Context, v interface{}) (keyword string, err error) {
+	s, ok := v.(string)
+	if !ok {
+		return "details/type", i18n.NewError(ctx, signermsgs.MsgFFIInvalidAddress, v, "not a string")
+	}
+	addr, err := ethtypes.NewAddressWithChecksum(s)
+	if err != nil {
+		return "details/type", i18n.NewError(ctx, signermsgs.MsgFFIInvalidAddress, v, err)
+	}
+	checksummed := addr.String()
+	hexPart := strings.TrimPrefix(s, "0x")
+	mixedCase := hexPart != strings.ToLower(hexPart) && hexPart != strings.ToUpper(hexPart)
+	isChecksummed := "0x"+hexPart == checksummed
+	switch {
+	case mixedCase && !isChecksummed:
+		return "details/type", i18n.NewError(ctx, signermsgs.MsgFFIAddressChecksumMismatch, s, checksummed)
+	case ps.checksum && !isChecksummed:
+		return "details/checksum", i18n.NewError(ctx, signermsgs.MsgFFIAddressChecksumRequired, s, checksummed)
+	}
+	return "", nil
+}
+
+// Violations flattens an error returned from validating a value against a compiled
+// FFI parameter schema into the individual violations, each with the path to the
+// offending value. Returns nil if the error is not a schema validation error.
+func Violations(err error) []*Violation {
+	var ve *jsonschema.ValidationError
+	if !errors.As(err, &ve) {
+		return nil
+	}
+	var violations []*Violation
+	var flatten func(ve *jsonschema.ValidationError)
+	flatten = func(ve *jsonschema.ValidationError) {
+		if len(ve.Causes) == 0 {
+			violations = append(violations, &Violation{
+				Path:    ve.InstanceLocation,
+				Keyword: ve.KeywordLocation,
+				Message: ve.Message,
+			})
+		}
+		for _, cause := range ve.Causes {
+			flatten(cause)
+		}
+	}
+	flatten(ve)
+	return violations
+}
@@ -17,7 +17,9 @@
 package ffi2abi
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"strings"
 	"testing"
 
@@ -27,11 +29,14 @@ import (
 )
 
 func NewTestSchema(input string) (*jsonschema.Schema, error) {
+	return newTestSchemaWithValidator(input, &ParamValidator{})
+}
+
+func newTestSchemaWithValidator(input string, v *ParamValidator) (*jsonschema.Schema, error) {
 	c := jsonschema.NewCompiler()
 	c.Draft = jsonschema.Draft2020
 	f := fftypes.BaseFFIParamValidator{}
 	c.RegisterExtension(f.GetExtensionName(), f.GetMetaSchema(), f)
-	v := &ParamValidator{}
 	c.RegisterExtension(v.GetExtensionName(), v.GetMetaSchema(), v)
 	err := c.AddResource("schema.json", strings.NewReader(input))
 	if err != nil {
@@ -203,8 +208,8 @@ func TestInputStruct(t *testing.T) {
 
 	input := `{
 	"x": 123,
-	"y": 456,
-	"z": 789
+	"y": 45,
+	"z": 67
 }`
 
 	assert.NoError(t, err)
@@ -224,7 +229,7 @@ func TestInputArray(t *testing.T) {
 	}
 }`)
 
-	input := `[123,456,789]`
+	input := `[123,45,67]`
 
 	assert.NoError(t, err)
 	err = s.Validate(jsonDecode(input))
@@ -347,3 +352,240 @@ func TestOneOfSyntax(t *testing.T) {
 	}`)
 	assert.NoError(t, err)
 }
+
+func TestInputIntegerRanges(t *testing.T) {
+	s, err := NewTestSchema(`
+{
+	"oneOf": [{"type": "string"}, {"type": "integer"}],
+	"details": {
+		"type": "int8"
+	}
+}`)
+	assert.NoError(t, err)
+	assert.NoError(t, s.Validate(jsonDecode(`127`)))
+	assert.NoError(t, s.Validate(jsonDecode(`"-128"`)))
+	err = s.Validate(jsonDecode(`128`))
+	assert.Regexp(t, "FF22044", err)
+	err = s.Validate(jsonDecode(`"-129"`))
+	assert.Regexp(t, "FF22044", err)
+	err = s.Validate(jsonDecode(`"banana"`))
+	assert.Regexp(t, "FF22030", err)
+
+	s, err = NewTestSchema(`
+{
+	"oneOf": [{"type": "string"}, {"type": "integer"}],
+	"details": {
+		"type": "uint16"
+	}
+}`)
+	assert.NoError(t, err)
+	assert.NoError(t, s.Validate(jsonDecode(`"0xffff"`)))
+	err = s.Validate(jsonDecode(`65536`))
+	assert.Regexp(t, "FF22044", err)
+	err = s.Validate(jsonDecode(`-1`))
+	assert.Regexp(t, "FF22062", err)
+}
+
+func TestInputBytesLength(t *testing.T) {
+	s, err := NewTestSchema(`
+{
+	"type": "string",
+	"details": {
+		"type": "bytes4"
+	}
+}`)
+	assert.NoError(t, err)
+	assert.NoError(t, s.Validate(`0x01020304`))
+	err = s.Validate(`0x0102030405`)
+	assert.Regexp(t, "FF22238.*exactly 4 bytes, but was 5 bytes", err)
+	err = s.Validate(`0x010203`)
+	assert.Regexp(t, "FF22043", err)
+	err = s.Validate(`not hex`)
+	assert.Regexp(t, "FF22034", err)
+
+	s, err = NewTestSchema(`
+{
+	"type": "string",
+	"details": {
+		"type": "bytes"
+	}
+}`)
+	assert.NoError(t, err)
+	assert.NoError(t, s.Validate(`0x0102030405`))
+}
+
+func TestInputAddressChecksum(t *testing.T) {
+	s, err := NewTestSchema(`
+{
+	"type": "string",
+	"details": {
+		"type": "address"
+	}
+}`)
+	assert.NoError(t, err)
+	assert.NoError(t, s.Validate(`0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed`))
+	assert.NoError(t, s.Validate(`0x5AAEB6053F3E94C9B9A09F33669435E7EF1BEAED`))
+	assert.NoError(t, s.Validate(`0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed`))
+	assert.NoError(t, s.Validate(`5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed`))
+	err = s.Validate(`0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAeD`)
+	assert.Regexp(t, "FF22239.*0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed", err)
+	err = s.Validate(`0x1234`)
+	assert.Regexp(t, "FF22243.*must be 20 bytes", err)
+
+	s, err = NewTestSchema(`
+{
+	"type": "string",
+	"details": {
+		"type": "address",
+		"checksum": true
+	}
+}`)
+	assert.NoError(t, err)
+	assert.NoError(t, s.Validate(`0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed`))
+	err = s.Validate(`0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed`)
+	assert.Regexp(t, "FF22240", err)
+	violations := Violations(err)
+	assert.Len(t, violations, 1)
+	assert.Equal(t, "/details/checksum", violations[0].Keyword)
+}
+
+func TestInputAddressNotString(t *testing.T) {
+	s, err := NewTestSchema(`
+{
+	"oneOf": [{"type": "string"}, {"type": "integer"}],
+	"details": {
+		"type": "address"
+	}
+}`)
+	assert.NoError(t, err)
+	err = s.Validate(jsonDecode(`12345`))
+	assert.Regexp(t, "FF22243.*not a string", err)
+}
+
+func TestInputSchemaChecksumWrongType(t *testing.T) {
+	_, err := NewTestSchema(`
+{
+	"type": "string",
+	"details": {
+		"type": "address",
+		"checksum": "yes"
+	}
+}`)
+	assert.Regexp(t, "compilation failed", err)
+}
+
+func TestInputViolationPaths(t *testing.T) {
+	s, err := NewTestSchema(`
+{
+	"type": "object",
+	"details": {
+		"type": "tuple"
+	},
+	"properties": {
+		"small": {
+			"type": "integer",
+			"details": {
+				"type": "uint8",
+				"index": 0
+			}
+		},
+		"list": {
+			"type": "array",
+			"details": {
+				"type": "bytes2[][]",
+				"index": 1
+			},
+			"items": {
+				"type": "array",
+				"items": {
+					"type": "string"
+				}
+			}
+		}
+	}
+}`)
+	assert.NoError(t, err)
+
+	err = s.Validate(jsonDecode(`{
+	"small": 256,
+	"list": [["0x0102"], ["0x0102", "0x010203"]]
+}`))
+	assert.Error(t, err)
+	violations := Violations(err)
+	assert.Len(t, violations, 2)
+	paths := map[string]*Violation{}
+	for _, v := range violations {
+		paths[v.Path] = v
+	}
+	assert.Equal(t, "/properties/small/details/type", paths["/small"].Keyword)
+	assert.Regexp(t, "FF22044", paths["/small"].Message)
+	assert.Equal(t, "/properties/list/details/type", paths["/list/1/1"].Keyword)
+	assert.Regexp(t, "FF22238", paths["/list/1/1"].Message)
+
+	err = s.Validate(jsonDecode(`{
+	"list": [["0x01", "0x010203"]]
+}`))
+	violations = Violations(err)
+	assert.Len(t, violations, 2)
+	assert.Equal(t, "/list/0/0", violations[0].Path)
+	assert.Equal(t, "/list/0/1", violations[1].Path)
+	assert.Regexp(t, "FF22242", fmt.Sprintf("%#v", err))
+}
+
+func TestViolationsNotValidationError(t *testing.T) {
+	assert.Nil(t, Violations(fmt.Errorf("pop")))
+}
+
+func TestInputCustomFormat(t *testing.T) {
+	v := (&ParamValidator{}).RegisterFormat("even", func(ctx context.Context, v interface{}) error {
+		if v.(float64)/2 != float64(int(v.(float64)/2)) {
+			return fmt.Errorf("%v is not even", v)
+		}
+		return nil
+	})
+	s, err := newTestSchemaWithValidator(`
+{
+	"type": "array",
+	"details": {
+		"type": "uint8[]",
+		"format": "even"
+	},
+	"items": {
+		"type": "integer"
+	}
+}`, v)
+	assert.NoError(t, err)
+	assert.NoError(t, s.Validate(jsonDecode(`[2, 4, 6]`)))
+	err = s.Validate(jsonDecode(`[2, 3, 300]`))
+	violations := Violations(err)
+	assert.Len(t, violations, 2)
+	assert.Equal(t, "/1", violations[0].Path)
+	assert.Equal(t, "/details/format", violations[0].Keyword)
+	assert.Equal(t, "3 is not even", violations[0].Message)
+	assert.Equal(t, "/2", violations[1].Path)
+	assert.Equal(t, "/details/type", violations[1].Keyword)
+}
+
+func TestInputUnknownFormat(t *testing.T) {
+	_, err := NewTestSchema(`
+{
+	"type": "string",
+	"details": {
+		"type": "string",
+		"format": "isin"
+	}
+}`)
+	assert.Regexp(t, "FF22241.*isin", err)
+}
+
+func TestInputUnparsableTypeNotValidated(t *testing.T) {
+	s, err := NewTestSchema(`
+{
+	"type": "string",
+	"details": {
+		"type": "uint7"
+	}
+}`)
+	assert.NoError(t, err)
+	assert.NoError(t, s.Validate(`anything`))
+}
@@ -0,0 +1,138 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package fips controls FIPS mode, in which keys are only encrypted with FIPS approved algorithms, and files
+// encrypted with other algorithms are refused.
+//
+// FIPS mode is enabled by building with the fips build tag, or at runtime with the fips.enabled configuration.
+// The approved algorithms only use a FIPS validated implementation when the Go toolchain provides one, such as
+// when built with GOEXPERIMENT=boringcrypto. Keccak-256 and secp256k1 ECDSA are not FIPS approved, but are
+// required by the Ethereum protocol, so are still used in FIPS mode.
+package fips
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+
+	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/hyperledger/firefly-common/pkg/log"
+)
+
+const (
+	AlgorithmAES128CTR        = "aes-128-ctr"
+	AlgorithmPBKDF2HMACSHA256 = "pbkdf2-hmac-sha256"
+	AlgorithmSHA256           = "sha-256"
+	AlgorithmHMACSHA256       = "hmac-sha256"
+	AlgorithmScrypt           = "scrypt"
+	AlgorithmKeccak256        = "keccak-256"
+	AlgorithmSecp256k1ECDSA   = "secp256k1-ecdsa"
+)
+
+var (
+	approved = []string{AlgorithmAES128CTR, AlgorithmPBKDF2HMACSHA256, AlgorithmSHA256, AlgorithmHMACSHA256}
+	// exceptions are not approved, but are required by the Ethereum protocol
+	exceptions = []string{AlgorithmKeccak256, AlgorithmSecp256k1ECDSA}
+	refused    = []string{AlgorithmScrypt}
+)
+
+var enabled atomic.Bool
+
+// Enabled reports whether FIPS mode is on
+func Enabled() bool {
+	return BuildTag || enabled.Load()
+}
+
+// SetEnabled turns FIPS mode on or off at runtime. It is always on when built with the fips build tag.
+func SetEnabled(on bool) {
+	enabled.Store(on)
+}
+
+// Allowed reports whether an algorithm can be used. Every algorithm is allowed when FIPS mode is off.
+func Allowed(algorithm string) bool {
+	if !Enabled() {
+		return true
+	}
+	for _, a := range refused {
+		if a == algorithm {
+			return false
+		}
+	}
+	return true
+}
+
+type Status struct {
+	Enabled  bool `json:"enabled"`
+	BuildTag bool `json:"buildTag"`
+	// ValidatedModule is the FIPS validated cryptographic module the approved algorithms use, if there is one
+	ValidatedModule string `json:"validatedModule,omitempty"`
+	// Compliant when FIPS mode is on, and the approved algorithms use a validated module
+	Compliant  bool     `json:"compliant"`
+	Approved   []string `json:"approved"`
+	Exceptions []string `json:"exceptions"`
+	Refused    []string `json:"refused"`
+}
+
+// GetStatus reports whether FIPS mode is on, and how the algorithms the signer uses comply with it
+func GetStatus() *Status {
+	status := &Status{
+		Enabled:         Enabled(),
+		BuildTag:        BuildTag,
+		ValidatedModule: validatedModule(),
+		Approved:        approved,
+		Exceptions:      exceptions,
+		Refused:         []string{},
+	}
+	if status.Enabled {
+		status.Compliant = status.ValidatedModule != ""
+		status.Refused = refused
+	}
+	return status
+}
+
+const (
+	// ConfigEnabled when true, keys are only encrypted with FIPS approved algorithms, and files encrypted with other algorithms are refused
+	ConfigEnabled = "enabled"
+)
+
+type Config struct {
+	Enabled bool
+}
+
+func InitConfig(section config.Section) {
+	section.AddKnownKey(ConfigEnabled, false)
+}
+
+func ReadConfig(section config.Section) *Config {
+	return &Config{
+		Enabled: section.GetBool(ConfigEnabled),
+	}
+}
+
+// Configure turns FIPS mode on or off as configured, and logs the compliance status when it is on
+func Configure(ctx context.Context, conf *Config) *Status {
+	SetEnabled(conf.Enabled)
+	status := GetStatus()
+	if !status.Enabled {
+		return status
+	}
+	log.L(ctx).Infof("FIPS mode enabled (buildTag=%t validatedModule=%s): approved=%s exceptions=%s refused=%s",
+		status.BuildTag, status.ValidatedModule, strings.Join(status.Approved, ","), strings.Join(status.Exceptions, ","), strings.Join(status.Refused, ","))
+	if !status.Compliant {
+		log.L(ctx).Warn("FIPS mode is enabled, but the signer was not built with a FIPS validated cryptographic module (GOEXPERIMENT=boringcrypto), so the approved algorithms use the Go standard library")
+	}
+	return status
+}
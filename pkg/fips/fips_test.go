@@ -0,0 +1,60 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fips
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFIPSModeOff(t *testing.T) {
+	SetEnabled(false)
+	assert.Equal(t, BuildTag, Enabled())
+	if BuildTag {
+		t.Skip("FIPS mode is always on with the fips build tag")
+	}
+	assert.True(t, Allowed(AlgorithmScrypt))
+	assert.True(t, Allowed(AlgorithmAES128CTR))
+
+	status := GetStatus()
+	assert.False(t, status.Enabled)
+	assert.False(t, status.Compliant)
+	assert.Empty(t, status.Refused)
+	assert.Contains(t, status.Approved, AlgorithmPBKDF2HMACSHA256)
+}
+
+func TestFIPSModeConfig(t *testing.T) {
+	config.RootConfigReset()
+	section := config.RootSection("fips")
+	InitConfig(section)
+	assert.False(t, ReadConfig(section).Enabled)
+	assert.Equal(t, BuildTag, Configure(context.Background(), ReadConfig(section)).Enabled)
+
+	section.Set(ConfigEnabled, true)
+	status := Configure(context.Background(), ReadConfig(section))
+	defer SetEnabled(false)
+	assert.True(t, Enabled())
+	assert.True(t, status.Enabled)
+	assert.Equal(t, status.ValidatedModule != "", status.Compliant)
+	assert.Equal(t, []string{AlgorithmScrypt}, status.Refused)
+	assert.Equal(t, []string{AlgorithmKeccak256, AlgorithmSecp256k1ECDSA}, status.Exceptions)
+	assert.False(t, Allowed(AlgorithmScrypt))
+	assert.True(t, Allowed(AlgorithmKeccak256))
+}
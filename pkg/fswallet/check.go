@@ -0,0 +1,96 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fswallet
+
+import (
+	"context"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+)
+
+// CheckConfig validates the configuration of a filesystem wallet without loading any keys, returning every
+// problem found rather than just the first. Warnings are returned for things that do not stop the wallet
+// working, but should be fixed - such as a password file that other users can read.
+func CheckConfig(ctx context.Context, conf *Config) (problems, warnings []error) {
+	check := func(err error) {
+		if err != nil {
+			problems = append(problems, err)
+		}
+	}
+
+	if conf.Path == "" {
+		check(i18n.NewError(ctx, signermsgs.MsgWalletPathNotSet))
+	} else if fi, err := checkConfigPath(ctx, ConfigPath, conf.Path, true); err != nil {
+		check(err)
+	} else if runtime.GOOS != "windows" && fi.Mode().Perm()&0o002 != 0 {
+		warnings = append(warnings, i18n.NewError(ctx, signermsgs.MsgDirectoryWritableByOthers, conf.Path, ConfigPath, fi.Mode().Perm()))
+	}
+	if conf.Filenames.PasswordPath != "" {
+		_, err := checkConfigPath(ctx, ConfigFilenamesPasswordPath, conf.Filenames.PasswordPath, true)
+		check(err)
+	}
+	if conf.DefaultPasswordFile != "" {
+		fi, err := checkConfigPath(ctx, ConfigDefaultPasswordFile, conf.DefaultPasswordFile, false)
+		if err != nil {
+			check(err)
+		} else if runtime.GOOS != "windows" && fi.Mode().Perm()&0o077 != 0 {
+			warnings = append(warnings, i18n.NewError(ctx, signermsgs.MsgFileReadableByOthers, conf.DefaultPasswordFile, ConfigDefaultPasswordFile, fi.Mode().Perm()))
+		}
+		if err == nil && !conf.AllowInsecurePermissions {
+			check(checkFilePermissions(ctx, conf.DefaultPasswordFile))
+		}
+	}
+
+	_, err := goTemplateFromConfig(ctx, ConfigMetadataKeyFileProperty, conf.Metadata.KeyFileProperty)
+	check(err)
+	_, err = goTemplateFromConfig(ctx, ConfigMetadataPasswordFileProperty, conf.Metadata.PasswordFileProperty)
+	check(err)
+	switch strings.ToLower(conf.Metadata.Format) {
+	case "", "auto", "filename", "toml", "tml", "json", "yaml", "yml":
+	default:
+		check(i18n.NewError(ctx, signermsgs.MsgUnknownMetadataFormat, conf.Metadata.Format))
+	}
+	switch format := metadataFormat(conf); format {
+	case "toml", "tml", "json", "yaml", "yml":
+		if conf.Metadata.KeyFileProperty == "" {
+			check(i18n.NewError(ctx, signermsgs.MsgMetadataKeyFilePropertyRequired, format))
+		}
+	}
+
+	check(checkShardPrefixLength(ctx, conf.Filenames.ShardPrefixLength))
+	_, err = compilePrimaryMatchRegex(ctx, conf.Filenames.PrimaryMatchRegex)
+	check(err)
+	return problems, warnings
+}
+
+// checkConfigPath checks a path in the configuration exists, and is a directory (or not) as expected
+func checkConfigPath(ctx context.Context, key, path string, isDir bool) (os.FileInfo, error) {
+	fi, err := os.Stat(path)
+	switch {
+	case err != nil:
+		return nil, i18n.NewError(ctx, signermsgs.MsgConfigPathNotAccessible, path, key, err)
+	case isDir && !fi.IsDir():
+		return nil, i18n.NewError(ctx, signermsgs.MsgConfigPathNotDirectory, path, key)
+	case !isDir && fi.IsDir():
+		return nil, i18n.NewError(ctx, signermsgs.MsgConfigPathIsDirectory, path, key)
+	}
+	return fi, nil
+}
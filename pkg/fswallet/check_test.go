@@ -0,0 +1,117 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fswallet
+
+import (
+	"context"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckConfigOK(t *testing.T) {
+	dir := t.TempDir()
+	passwordFile := path.Join(dir, "password")
+	err := os.WriteFile(passwordFile, []byte("pwd"), 0600)
+	assert.NoError(t, err)
+
+	problems, warnings := CheckConfig(context.Background(), &Config{
+		Path:                "../../test/keystore_toml",
+		DefaultPasswordFile: passwordFile,
+		Filenames: FilenamesConfig{
+			PrimaryExt:        ".toml",
+			PasswordPath:      dir,
+			ShardPrefixLength: 2,
+		},
+		Metadata: MetadataConfig{
+			Format:               "auto",
+			KeyFileProperty:      `{{ index .signing "key-file" }}`,
+			PasswordFileProperty: `{{ index .signing "password-file" }}`,
+		},
+	})
+	assert.Empty(t, problems)
+	assert.Empty(t, warnings)
+}
+
+func TestCheckConfigReportsAllProblems(t *testing.T) {
+	dir := t.TempDir()
+	notADir := path.Join(dir, "file")
+	err := os.WriteFile(notADir, []byte{}, 0600)
+	assert.NoError(t, err)
+
+	problems, warnings := CheckConfig(context.Background(), &Config{
+		Path:                notADir,
+		DefaultPasswordFile: dir,
+		Filenames: FilenamesConfig{
+			PasswordPath:      path.Join(dir, "missing"),
+			PrimaryMatchRegex: "[",
+			ShardPrefixLength: -1,
+		},
+		Metadata: MetadataConfig{
+			Format:               "toml",
+			PasswordFileProperty: "{{ !!! }}",
+		},
+	})
+	assert.Empty(t, warnings)
+	assert.Len(t, problems, 7)
+	assert.Regexp(t, "FF22215.*"+ConfigPath, problems[0])
+	assert.Regexp(t, "FF22214.*"+ConfigFilenamesPasswordPath, problems[1])
+	assert.Regexp(t, "FF22216.*"+ConfigDefaultPasswordFile, problems[2])
+	assert.Regexp(t, "FF22016.*"+ConfigMetadataPasswordFileProperty, problems[3])
+	assert.Regexp(t, "FF22217.*toml", problems[4])
+	assert.Regexp(t, "FF22092", problems[5])
+	assert.Regexp(t, "FF22056", problems[6])
+}
+
+func TestCheckConfigNoPathBadTemplateUnknownFormat(t *testing.T) {
+	problems, _ := CheckConfig(context.Background(), &Config{
+		Filenames: FilenamesConfig{
+			PrimaryMatchRegex: "^[0-9a-f]+$",
+		},
+		Metadata: MetadataConfig{
+			Format:          "xml",
+			KeyFileProperty: "{{ !!! }}",
+		},
+	})
+	assert.Len(t, problems, 4)
+	assert.Regexp(t, "FF22213", problems[0])
+	assert.Regexp(t, "FF22016.*"+ConfigMetadataKeyFileProperty, problems[1])
+	assert.Regexp(t, "FF22218.*xml", problems[2])
+	assert.Regexp(t, "FF22057", problems[3])
+}
+
+func TestCheckConfigPermissionWarnings(t *testing.T) {
+	dir := t.TempDir()
+	err := os.Chmod(dir, 0777)
+	assert.NoError(t, err)
+	passwordFile := path.Join(dir, "password")
+	err = os.WriteFile(passwordFile, []byte("pwd"), 0640)
+	assert.NoError(t, err)
+	err = os.Chmod(passwordFile, 0640) // regardless of umask
+	assert.NoError(t, err)
+
+	problems, warnings := CheckConfig(context.Background(), &Config{
+		Path:                dir,
+		DefaultPasswordFile: passwordFile,
+	})
+	assert.Empty(t, problems)
+	assert.Len(t, warnings, 2)
+	assert.Regexp(t, "FF22220", warnings[0])
+	assert.Regexp(t, "FF22219", warnings[1])
+}
@@ -35,6 +35,8 @@ const (
 	ConfigFilenamesPasswordPath = "filenames.passwordPath"
 	// ConfigFilenamesPasswordTrimSpace whether to trim whitespace from passwords loaded from files (such as trailing newline characters)
 	ConfigFilenamesPasswordTrimSpace = "filenames.passwordTrimSpace"
+	// ConfigFilenamesShardPrefixLength when non-zero, files are stored in a sub-directory named by this many leading hex characters of the address
+	ConfigFilenamesShardPrefixLength = "filenames.shardPrefixLength"
 	// ConfigDefaultPasswordFile default password file to use if neither the metadata, or passwordExtension find a password
 	ConfigDefaultPasswordFile = "defaultPasswordFile"
 	// ConfigDisableListener disable the filesystem listener that detects newly added keys automatically
@@ -43,6 +45,14 @@ const (
 	ConfigSignerCacheSize = "signerCacheSize"
 	// ConfigSignerCacheTTL the time to keep an unused signing key in memory
 	ConfigSignerCacheTTL = "signerCacheTTL"
+	// ConfigRequireUnlock when true, keys can only be used for signing after they have been explicitly unlocked via the API
+	ConfigRequireUnlock = "requireUnlock"
+	// ConfigUnlockTTL the default time a key stays unlocked, when no duration is supplied on the unlock request
+	ConfigUnlockTTL = "unlockTTL"
+	// ConfigExtraEntropy when true, random entropy is mixed into the deterministic nonce of every signature
+	ConfigExtraEntropy = "extraEntropy"
+	// ConfigAllowInsecurePermissions when true, key and password files that other users can access are loaded rather than refused
+	ConfigAllowInsecurePermissions = "allowInsecurePermissions"
 	// ConfigMetadataFormat format to parse the metadata - supported: auto (from extension) / filename / toml / yaml / json (please quote "0x..." strings in YAML)
 	ConfigMetadataFormat = "metadata.format"
 	// ConfigMetadataKeyFileProperty use for toml/yaml/json to find the name of the file containing the keystorev3 file
@@ -52,13 +62,17 @@ const (
 )
 
 type Config struct {
-	Path                string
-	DefaultPasswordFile string
-	SignerCacheSize     string
-	SignerCacheTTL      string
-	DisableListener     bool
-	Filenames           FilenamesConfig
-	Metadata            MetadataConfig
+	Path                     string
+	DefaultPasswordFile      string
+	SignerCacheSize          string
+	SignerCacheTTL           string
+	DisableListener          bool
+	RequireUnlock            bool
+	UnlockTTL                string
+	ExtraEntropy             bool
+	AllowInsecurePermissions bool
+	Filenames                FilenamesConfig
+	Metadata                 MetadataConfig
 }
 
 type FilenamesConfig struct {
@@ -68,6 +82,7 @@ type FilenamesConfig struct {
 	PasswordPath      string
 	PasswordTrimSpace bool
 	With0xPrefix      bool
+	ShardPrefixLength int
 }
 
 type MetadataConfig struct {
@@ -84,10 +99,15 @@ func InitConfig(section config.Section) {
 	section.AddKnownKey(ConfigFilenamesPasswordPath)
 	section.AddKnownKey(ConfigFilenamesPasswordTrimSpace, true)
 	section.AddKnownKey(ConfigFilenamesWith0xPrefix)
+	section.AddKnownKey(ConfigFilenamesShardPrefixLength, 0)
 	section.AddKnownKey(ConfigDisableListener)
 	section.AddKnownKey(ConfigDefaultPasswordFile)
 	section.AddKnownKey(ConfigSignerCacheSize, 250)
 	section.AddKnownKey(ConfigSignerCacheTTL, "24h")
+	section.AddKnownKey(ConfigRequireUnlock, false)
+	section.AddKnownKey(ConfigUnlockTTL, "5m")
+	section.AddKnownKey(ConfigExtraEntropy, false)
+	section.AddKnownKey(ConfigAllowInsecurePermissions, false)
 	section.AddKnownKey(ConfigMetadataFormat, `auto`)
 	section.AddKnownKey(ConfigMetadataKeyFileProperty)
 	section.AddKnownKey(ConfigMetadataPasswordFileProperty)
@@ -95,11 +115,15 @@ func InitConfig(section config.Section) {
 
 func ReadConfig(section config.Section) *Config {
 	return &Config{
-		Path:                section.GetString(ConfigPath),
-		DefaultPasswordFile: section.GetString(ConfigDefaultPasswordFile),
-		SignerCacheSize:     section.GetString(ConfigSignerCacheSize),
-		SignerCacheTTL:      section.GetString(ConfigSignerCacheTTL),
-		DisableListener:     section.GetBool(ConfigDisableListener),
+		Path:                     section.GetString(ConfigPath),
+		DefaultPasswordFile:      section.GetString(ConfigDefaultPasswordFile),
+		SignerCacheSize:          section.GetString(ConfigSignerCacheSize),
+		SignerCacheTTL:           section.GetString(ConfigSignerCacheTTL),
+		DisableListener:          section.GetBool(ConfigDisableListener),
+		RequireUnlock:            section.GetBool(ConfigRequireUnlock),
+		UnlockTTL:                section.GetString(ConfigUnlockTTL),
+		ExtraEntropy:             section.GetBool(ConfigExtraEntropy),
+		AllowInsecurePermissions: section.GetBool(ConfigAllowInsecurePermissions),
 		Filenames: FilenamesConfig{
 			PrimaryExt:        section.GetString(ConfigFilenamesPrimaryExt),
 			PrimaryMatchRegex: section.GetString(ConfigFilenamesPrimaryMatchRegex),
@@ -107,6 +131,7 @@ func ReadConfig(section config.Section) *Config {
 			PasswordPath:      section.GetString(ConfigFilenamesPasswordPath),
 			PasswordTrimSpace: section.GetBool(ConfigFilenamesPasswordTrimSpace),
 			With0xPrefix:      section.GetBool(ConfigFilenamesWith0xPrefix),
+			ShardPrefixLength: section.GetInt(ConfigFilenamesShardPrefixLength),
 		},
 		Metadata: MetadataConfig{
 			Format:               section.GetString(ConfigMetadataFormat),
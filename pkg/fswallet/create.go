@@ -0,0 +1,292 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fswallet
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path"
+	"path/filepath"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-common/pkg/log"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/keystorev3"
+	"github.com/hyperledger/firefly-signer/pkg/secp256k1"
+	"github.com/pelletier/go-toml"
+	"gopkg.in/yaml.v2"
+)
+
+type CreateKeyOptions struct {
+	// Password returns the password to encrypt the new key with. It is only called when the wallet
+	// layout has a password file for each key, otherwise the default password file is used.
+	Password func() ([]byte, error)
+	// LightScrypt uses scrypt parameters that are quicker to decrypt, but less resistant to brute force
+	LightScrypt bool
+	// KeyPair is the key to write, such as a vanity key, otherwise a new key is generated.
+	// It remains the caller's to zeroize.
+	KeyPair *secp256k1.KeyPair
+}
+
+// CreateKey generates a new key, and writes it into the wallet directory with the layout described by
+// the configuration, so that it is immediately available to a running signer with the same configuration.
+//
+//   - When a metadata format is configured, the metadata file refers to a "{{ADDRESS}}.key.json" Keystore V3 file
+//     and password file written alongside it. The keyFileProperty and passwordFileProperty templates must be
+//     simple field references, such as `{{ .signing.keyFile }}` or `{{ index .signing "key-file" }}`.
+//   - Otherwise the Keystore V3 file is the primary file, with a password file if passwordExt is configured.
+//
+// If there is no password file for each key, the key is encrypted with the default password file.
+// The new key is loaded back from the files to verify them, and the files are removed if that fails.
+func CreateKey(ctx context.Context, conf *Config, options *CreateKeyOptions) (*ethtypes.Address0xHex, error) {
+	if options == nil {
+		options = &CreateKeyOptions{}
+	}
+	ww, err := NewFilesystemWallet(ctx, conf)
+	if err != nil {
+		return nil, err
+	}
+	w := ww.(*fsWallet)
+
+	keypair := options.KeyPair
+	if keypair == nil {
+		if keypair, err = secp256k1.GenerateSecp256k1KeyPair(); err != nil {
+			return nil, err
+		}
+		defer keypair.Zeroize()
+	}
+	addr := keypair.Address
+
+	dir := path.Join(w.conf.Path, w.shardDir(&addr))
+	primaryFilename := w.addressFilename(addr, w.conf.Filenames.PrimaryExt)
+	if a := w.filenameToAddress(ctx, primaryFilename, primaryFilename); a == nil || *a != addr {
+		return nil, i18n.NewError(ctx, signermsgs.MsgCreateKeyFilenameMismatch, primaryFilename)
+	}
+	primaryFile := path.Join(dir, primaryFilename)
+	keyFile := primaryFile
+	passwordFile := ""
+	var metadata map[string]interface{}
+	var marshalMetadata func(interface{}) ([]byte, error)
+	switch w.metadataFormat() {
+	case "toml", "tml":
+		marshalMetadata = toml.Marshal
+	case "json":
+		marshalMetadata = func(v interface{}) ([]byte, error) { return json.MarshalIndent(v, "", "  ") }
+	case "yaml", "yml":
+		marshalMetadata = yaml.Marshal
+	}
+	if marshalMetadata != nil {
+		keyFile = path.Join(dir, w.addressFilename(addr, ".key.json"))
+		if keyFile == primaryFile {
+			return nil, i18n.NewError(ctx, signermsgs.MsgCreateKeyFilenameMismatch, primaryFilename)
+		}
+		if w.metadataPasswordFileProperty != nil {
+			passwordExt := w.conf.Filenames.PasswordExt
+			if passwordExt == "" {
+				passwordExt = ".pwd"
+			}
+			passwordFile = w.addressFilename(addr, passwordExt)
+		}
+	} else if w.conf.Filenames.PasswordExt != "" {
+		passwordFile = w.passwordFilename(addr)
+	}
+	if passwordFile != "" {
+		passwordDir := w.conf.Filenames.PasswordPath
+		if passwordDir == "" {
+			passwordDir = dir
+		}
+		passwordFile = path.Join(passwordDir, passwordFile)
+	}
+
+	var password []byte
+	if passwordFile != "" {
+		if options.Password != nil {
+			password, err = options.Password()
+		}
+		if err != nil {
+			return nil, err
+		}
+		if w.conf.Filenames.PasswordTrimSpace {
+			password = bytes.TrimSpace(password)
+		}
+		if len(password) == 0 {
+			return nil, i18n.NewError(ctx, signermsgs.MsgEmptyPassword)
+		}
+	} else {
+		if w.conf.DefaultPasswordFile == "" {
+			return nil, i18n.NewError(ctx, signermsgs.MsgCreateKeyNoPassword)
+		}
+		if password, err = os.ReadFile(w.conf.DefaultPasswordFile); err != nil {
+			return nil, i18n.WrapError(ctx, err, signermsgs.MsgReadPasswordFileFailed, w.conf.DefaultPasswordFile)
+		}
+	}
+	defer secp256k1.ZeroizeBytes(password)
+
+	if marshalMetadata != nil {
+		if metadata, err = w.newMetadata(ctx, keyFile, passwordFile); err != nil {
+			return nil, err
+		}
+	}
+
+	var kv3 keystorev3.WalletFile
+	if options.LightScrypt {
+		kv3 = keystorev3.NewWalletFileLight(string(password), keypair)
+	} else {
+		kv3 = keystorev3.NewWalletFileStandard(string(password), keypair)
+	}
+	kv3.Zeroize()
+
+	// The primary file is written last, so a running signer does not find it before the others
+	var written []string
+	writeFile := func(filename string, data []byte) error {
+		err := os.MkdirAll(path.Dir(filename), 0700)
+		if err == nil {
+			var f *os.File
+			if f, err = os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600); err == nil {
+				written = append(written, filename)
+				_, err = f.Write(data)
+				if closeErr := f.Close(); err == nil {
+					err = closeErr
+				}
+			}
+		}
+		if err != nil {
+			return i18n.WrapError(ctx, err, signermsgs.MsgWriteWalletFileFailed, filename)
+		}
+		return nil
+	}
+	if passwordFile != "" {
+		err = writeFile(passwordFile, password)
+	}
+	if err == nil && keyFile != primaryFile {
+		err = writeFile(keyFile, kv3.JSON())
+	}
+	if err == nil {
+		if marshalMetadata != nil {
+			var b []byte
+			if b, err = marshalMetadata(metadata); err == nil {
+				err = writeFile(primaryFile, b)
+			}
+		} else {
+			err = writeFile(primaryFile, kv3.JSON())
+		}
+	}
+	if err == nil {
+		var loaded keystorev3.WalletFile
+		if loaded, err = w.loadWalletFile(ctx, addr, primaryFile, nil); err == nil {
+			loaded.Zeroize()
+		}
+	}
+	if err != nil {
+		for _, filename := range written {
+			_ = os.Remove(filename)
+		}
+		return nil, err
+	}
+	log.L(ctx).Infof("Created key for address %s in %s", addr, primaryFile)
+	return &addr, nil
+}
+
+// newMetadata builds a metadata document for which the keyFileProperty and passwordFileProperty templates
+// resolve to the supplied filenames
+func (w *fsWallet) newMetadata(ctx context.Context, keyFile, passwordFile string) (map[string]interface{}, error) {
+	metadata := map[string]interface{}{}
+	for _, p := range []struct {
+		name     string
+		t        *template.Template
+		filename string
+	}{
+		{name: ConfigMetadataKeyFileProperty, t: w.metadataKeyFileProperty, filename: keyFile},
+		{name: ConfigMetadataPasswordFileProperty, t: w.metadataPasswordFileProperty, filename: passwordFile},
+	} {
+		if p.filename == "" {
+			continue
+		}
+		// Relative paths in the metadata are resolved from the working directory of the signer, so we use absolute paths
+		absFilename, err := filepath.Abs(p.filename)
+		fieldPath := templateFieldPath(p.t)
+		if err != nil || fieldPath == nil || !setMetadataField(metadata, fieldPath, absFilename) {
+			return nil, i18n.NewError(ctx, signermsgs.MsgCreateKeyMetadataTemplate, p.name)
+		}
+	}
+	return metadata, nil
+}
+
+// templateFieldPath returns the path of the field referenced by a template that is just a single
+// field reference (`{{ .a.b }}`) or index function call (`{{ index .a "b" }}`), or nil for any other template
+func templateFieldPath(t *template.Template) []string {
+	if t == nil || len(t.Tree.Root.Nodes) != 1 {
+		return nil
+	}
+	action, ok := t.Tree.Root.Nodes[0].(*parse.ActionNode)
+	if !ok || len(action.Pipe.Decl) != 0 || len(action.Pipe.Cmds) != 1 {
+		return nil
+	}
+	args := action.Pipe.Cmds[0].Args
+	if len(args) == 1 {
+		if field, ok := args[0].(*parse.FieldNode); ok {
+			return field.Ident
+		}
+		return nil
+	}
+	if ident, ok := args[0].(*parse.IdentifierNode); !ok || ident.Ident != "index" {
+		return nil
+	}
+	var fieldPath []string
+	switch n := args[1].(type) {
+	case *parse.DotNode:
+	case *parse.FieldNode:
+		fieldPath = append(fieldPath, n.Ident...)
+	default:
+		return nil
+	}
+	for _, arg := range args[2:] {
+		s, ok := arg.(*parse.StringNode)
+		if !ok {
+			return nil
+		}
+		fieldPath = append(fieldPath, s.Text)
+	}
+	return fieldPath
+}
+
+func setMetadataField(metadata map[string]interface{}, fieldPath []string, value string) bool {
+	if len(fieldPath) == 0 {
+		return false
+	}
+	for _, name := range fieldPath[:len(fieldPath)-1] {
+		if metadata[name] == nil {
+			metadata[name] = map[string]interface{}{}
+		}
+		child, ok := metadata[name].(map[string]interface{})
+		if !ok {
+			return false
+		}
+		metadata = child
+	}
+	name := fieldPath[len(fieldPath)-1]
+	if metadata[name] != nil {
+		return false
+	}
+	metadata[name] = value
+	return true
+}
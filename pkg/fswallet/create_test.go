@@ -0,0 +1,327 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fswallet
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/secp256k1"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestCreateKeyConfig(t *testing.T, settings map[string]interface{}) *Config {
+	config.RootConfigReset()
+	logrus.SetLevel(logrus.TraceLevel)
+
+	unitTestConfig := config.RootSection("ut_fs_config")
+	InitConfig(unitTestConfig)
+	unitTestConfig.Set(ConfigPath, t.TempDir())
+	unitTestConfig.Set(ConfigDisableListener, true)
+	for k, v := range settings {
+		unitTestConfig.Set(k, v)
+	}
+	return ReadConfig(unitTestConfig)
+}
+
+func testPassword(password string) *CreateKeyOptions {
+	return &CreateKeyOptions{
+		Password: func() ([]byte, error) {
+			return []byte(password), nil
+		},
+		LightScrypt: true,
+	}
+}
+
+func checkCreatedKey(t *testing.T, conf *Config, addr *ethtypes.Address0xHex) {
+	ctx := context.Background()
+	ff, err := NewFilesystemWallet(ctx, conf)
+	assert.NoError(t, err)
+	defer ff.Close()
+	err = ff.Initialize(ctx)
+	assert.NoError(t, err)
+
+	accounts, err := ff.GetAccounts(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []*ethtypes.Address0xHex{addr}, accounts)
+	kv3, err := ff.GetWalletFile(ctx, *addr)
+	assert.NoError(t, err)
+	assert.Equal(t, *addr, kv3.KeyPair().Address)
+}
+
+func TestCreateKeyFilenamesSharded(t *testing.T) {
+	conf := newTestCreateKeyConfig(t, map[string]interface{}{
+		ConfigFilenamesPrimaryExt:        ".key.json",
+		ConfigFilenamesPasswordExt:       ".pwd",
+		ConfigFilenamesShardPrefixLength: 2,
+	})
+
+	addr, err := CreateKey(context.Background(), conf, testPassword("  correcthorsebatterystaple\n"))
+	assert.NoError(t, err)
+	checkCreatedKey(t, conf, addr)
+
+	shardDir := path.Join(conf.Path, strings.TrimPrefix(addr.String(), "0x")[0:2])
+	password, err := os.ReadFile(path.Join(shardDir, strings.TrimPrefix(addr.String(), "0x")+".pwd"))
+	assert.NoError(t, err)
+	assert.Equal(t, "correcthorsebatterystaple", string(password))
+	fi, err := os.Stat(path.Join(shardDir, strings.TrimPrefix(addr.String(), "0x")+".key.json"))
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), fi.Mode().Perm())
+}
+
+func TestCreateKeyFromKeyPair(t *testing.T) {
+	conf := newTestCreateKeyConfig(t, map[string]interface{}{
+		ConfigFilenamesPrimaryExt:  ".key.json",
+		ConfigFilenamesPasswordExt: ".pwd",
+	})
+
+	keypair, err := secp256k1.GenerateSecp256k1KeyPair()
+	assert.NoError(t, err)
+	options := testPassword("correcthorsebatterystaple")
+	options.KeyPair = keypair
+	addr, err := CreateKey(context.Background(), conf, options)
+	assert.NoError(t, err)
+	assert.Equal(t, keypair.Address, *addr)
+	checkCreatedKey(t, conf, addr)
+	assert.NotEqual(t, make([]byte, 32), keypair.PrivateKeyBytes())
+}
+
+func TestCreateKeyFilenamesPasswordPath(t *testing.T) {
+	passwordPath := t.TempDir()
+	conf := newTestCreateKeyConfig(t, map[string]interface{}{
+		ConfigFilenamesPrimaryExt:   ".key.json",
+		ConfigFilenamesPasswordExt:  ".pwd",
+		ConfigFilenamesPasswordPath: passwordPath,
+		ConfigFilenamesWith0xPrefix: true,
+	})
+
+	addr, err := CreateKey(context.Background(), conf, testPassword("correcthorsebatterystaple"))
+	assert.NoError(t, err)
+	checkCreatedKey(t, conf, addr)
+	assert.FileExists(t, path.Join(passwordPath, addr.String()+".pwd"))
+}
+
+func TestCreateKeyTOMLMetadata(t *testing.T) {
+	conf := newTestCreateKeyConfig(t, map[string]interface{}{
+		ConfigFilenamesPrimaryExt:          ".toml",
+		ConfigMetadataKeyFileProperty:      `{{ index .signing "key-file" }}`,
+		ConfigMetadataPasswordFileProperty: `{{ index .signing "password-file" }}`,
+	})
+
+	addr, err := CreateKey(context.Background(), conf, testPassword("correcthorsebatterystaple"))
+	assert.NoError(t, err)
+	checkCreatedKey(t, conf, addr)
+
+	addrString := strings.TrimPrefix(addr.String(), "0x")
+	assert.FileExists(t, path.Join(conf.Path, addrString+".key.json"))
+	assert.FileExists(t, path.Join(conf.Path, addrString+".pwd"))
+}
+
+func TestCreateKeyYAMLMetadataNestedFields(t *testing.T) {
+	conf := newTestCreateKeyConfig(t, map[string]interface{}{
+		ConfigFilenamesPrimaryExt:          ".yaml",
+		ConfigFilenamesPasswordExt:         ".pass",
+		ConfigMetadataKeyFileProperty:      `{{ .signing.keyFile }}`,
+		ConfigMetadataPasswordFileProperty: `{{ index .signing.files "password" }}`,
+	})
+
+	addr, err := CreateKey(context.Background(), conf, testPassword("correcthorsebatterystaple"))
+	assert.NoError(t, err)
+	checkCreatedKey(t, conf, addr)
+	assert.FileExists(t, path.Join(conf.Path, strings.TrimPrefix(addr.String(), "0x")+".pass"))
+}
+
+func TestCreateKeyJSONMetadataDefaultPassword(t *testing.T) {
+	defaultPasswordFile := path.Join(t.TempDir(), "password")
+	err := os.WriteFile(defaultPasswordFile, []byte("correcthorsebatterystaple"), 0600)
+	assert.NoError(t, err)
+	conf := newTestCreateKeyConfig(t, map[string]interface{}{
+		ConfigFilenamesPrimaryExt:     ".json",
+		ConfigDefaultPasswordFile:     defaultPasswordFile,
+		ConfigMetadataKeyFileProperty: `{{ index . "keyFile" }}`,
+	})
+
+	addr, err := CreateKey(context.Background(), conf, &CreateKeyOptions{LightScrypt: true})
+	assert.NoError(t, err)
+	checkCreatedKey(t, conf, addr)
+}
+
+func TestCreateKeyStandardScrypt(t *testing.T) {
+	conf := newTestCreateKeyConfig(t, map[string]interface{}{
+		ConfigFilenamesPrimaryExt:  ".key.json",
+		ConfigFilenamesPasswordExt: ".pwd",
+	})
+
+	opts := testPassword("correcthorsebatterystaple")
+	opts.LightScrypt = false
+	addr, err := CreateKey(context.Background(), conf, opts)
+	assert.NoError(t, err)
+	checkCreatedKey(t, conf, addr)
+}
+
+func TestCreateKeyBadConfig(t *testing.T) {
+	conf := newTestCreateKeyConfig(t, map[string]interface{}{
+		ConfigFilenamesPrimaryMatchRegex: "[",
+	})
+	_, err := CreateKey(context.Background(), conf, nil)
+	assert.Regexp(t, "FF22056", err)
+}
+
+func TestCreateKeyFilenameMismatch(t *testing.T) {
+	conf := newTestCreateKeyConfig(t, map[string]interface{}{
+		ConfigFilenamesPrimaryMatchRegex: "^((0x)?[0-9a-z]+).key.json$",
+		ConfigFilenamesPasswordExt:       ".pwd",
+	})
+	_, err := CreateKey(context.Background(), conf, nil)
+	assert.Regexp(t, "FF22192", err)
+}
+
+func TestCreateKeyMetadataSameAsKeyFile(t *testing.T) {
+	conf := newTestCreateKeyConfig(t, map[string]interface{}{
+		ConfigFilenamesPrimaryExt:     ".key.json",
+		ConfigMetadataFormat:          "json",
+		ConfigMetadataKeyFileProperty: `{{ .keyFile }}`,
+	})
+	_, err := CreateKey(context.Background(), conf, nil)
+	assert.Regexp(t, "FF22192", err)
+}
+
+func TestCreateKeyMetadataTemplateNotSimple(t *testing.T) {
+	for _, tmpl := range []string{
+		`{{ .a }}/{{ .b }}`,
+		`{{ printf "%s" .a }}`,
+		`{{ .a | printf "%s" }}`,
+		`{{ $a := .a }}`,
+		`{{ "a" }}`,
+		`{{ index "a" "b" }}`,
+		`{{ index .a .b }}`,
+		`{{ index . }}`,
+		`text`,
+	} {
+		conf := newTestCreateKeyConfig(t, map[string]interface{}{
+			ConfigFilenamesPrimaryExt:     ".toml",
+			ConfigMetadataKeyFileProperty: tmpl,
+			ConfigDefaultPasswordFile:     "../../test/keystore_toml/1f185718734552d08278aa70f804580bab5fd2b4.pwd",
+		})
+		_, err := CreateKey(context.Background(), conf, nil)
+		assert.Regexp(t, "FF22193", err, tmpl)
+	}
+}
+
+func TestCreateKeyMetadataTemplatesConflict(t *testing.T) {
+	for _, tmpls := range [][]string{
+		{`{{ .signing.keyFile }}`, `{{ .signing.keyFile }}`},
+		{`{{ .signing }}`, `{{ .signing.passwordFile }}`},
+		{`{{ .signing.keyFile }}`, `{{ .signing.keyFile.password }}`},
+	} {
+		conf := newTestCreateKeyConfig(t, map[string]interface{}{
+			ConfigFilenamesPrimaryExt:          ".toml",
+			ConfigMetadataKeyFileProperty:      tmpls[0],
+			ConfigMetadataPasswordFileProperty: tmpls[1],
+		})
+		_, err := CreateKey(context.Background(), conf, testPassword("correcthorsebatterystaple"))
+		assert.Regexp(t, "FF22193", err, tmpls)
+	}
+}
+
+func TestCreateKeyMetadataTemplateEmptyPath(t *testing.T) {
+	assert.False(t, setMetadataField(map[string]interface{}{}, []string{}, "value"))
+}
+
+func TestCreateKeyNoPassword(t *testing.T) {
+	conf := newTestCreateKeyConfig(t, map[string]interface{}{
+		ConfigFilenamesPrimaryExt: ".key.json",
+	})
+	_, err := CreateKey(context.Background(), conf, nil)
+	assert.Regexp(t, "FF22194", err)
+}
+
+func TestCreateKeyDefaultPasswordFileMissing(t *testing.T) {
+	conf := newTestCreateKeyConfig(t, map[string]interface{}{
+		ConfigFilenamesPrimaryExt: ".key.json",
+		ConfigDefaultPasswordFile: path.Join(t.TempDir(), "missing"),
+	})
+	_, err := CreateKey(context.Background(), conf, nil)
+	assert.Regexp(t, "FF22196", err)
+}
+
+func TestCreateKeyPasswordError(t *testing.T) {
+	conf := newTestCreateKeyConfig(t, map[string]interface{}{
+		ConfigFilenamesPrimaryExt:  ".key.json",
+		ConfigFilenamesPasswordExt: ".pwd",
+	})
+	_, err := CreateKey(context.Background(), conf, &CreateKeyOptions{
+		Password: func() ([]byte, error) {
+			return nil, fmt.Errorf("pop")
+		},
+	})
+	assert.Regexp(t, "pop", err)
+}
+
+func TestCreateKeyEmptyPassword(t *testing.T) {
+	conf := newTestCreateKeyConfig(t, map[string]interface{}{
+		ConfigFilenamesPrimaryExt:  ".key.json",
+		ConfigFilenamesPasswordExt: ".pwd",
+	})
+	_, err := CreateKey(context.Background(), conf, nil)
+	assert.Regexp(t, "FF22195", err)
+
+	_, err = CreateKey(context.Background(), conf, testPassword(" \n"))
+	assert.Regexp(t, "FF22195", err)
+}
+
+func TestCreateKeyWriteFailCleansUp(t *testing.T) {
+	passwordPath := t.TempDir()
+	walletPath := path.Join(t.TempDir(), "file")
+	err := os.WriteFile(walletPath, []byte{}, 0600)
+	assert.NoError(t, err)
+	conf := newTestCreateKeyConfig(t, map[string]interface{}{
+		ConfigPath:                  walletPath,
+		ConfigFilenamesPrimaryExt:   ".key.json",
+		ConfigFilenamesPasswordExt:  ".pwd",
+		ConfigFilenamesPasswordPath: passwordPath,
+	})
+	_, err = CreateKey(context.Background(), conf, testPassword("correcthorsebatterystaple"))
+	assert.Regexp(t, "FF22191", err)
+
+	files, err := os.ReadDir(passwordPath)
+	assert.NoError(t, err)
+	assert.Empty(t, files)
+}
+
+func TestCreateKeyVerifyFail(t *testing.T) {
+	// With no extensions, the key file would be read as its own password file
+	defaultPasswordFile := path.Join(t.TempDir(), "password")
+	err := os.WriteFile(defaultPasswordFile, []byte("correcthorsebatterystaple"), 0600)
+	assert.NoError(t, err)
+	conf := newTestCreateKeyConfig(t, map[string]interface{}{
+		ConfigDefaultPasswordFile: defaultPasswordFile,
+	})
+	_, err = CreateKey(context.Background(), conf, &CreateKeyOptions{LightScrypt: true})
+	assert.Regexp(t, "FF22015", err)
+
+	files, err := os.ReadDir(conf.Path)
+	assert.NoError(t, err)
+	assert.Empty(t, files)
+}
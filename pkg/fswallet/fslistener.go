@@ -19,6 +19,8 @@ package fswallet
 import (
 	"context"
 	"os"
+	"path"
+	"path/filepath"
 
 	"github.com/fsnotify/fsnotify"
 	"github.com/hyperledger/firefly-common/pkg/i18n"
@@ -38,8 +40,19 @@ func (w *fsWallet) startFilesystemListener(ctx context.Context) error {
 			_ = watcher.Close()
 			close(w.fsListenerDone)
 		}, watcher.Events, watcher.Errors)
+		w.fsListenerWatch = func(dir string) error {
+			return watcher.Add(path.Join(w.conf.Path, dir))
+		}
 		err = watcher.Add(w.conf.Path)
 	}
+	if err == nil {
+		// Watch any shard directories that already exist
+		var shardDirs []string
+		_, shardDirs, err = w.listDir(ctx, "")
+		for i := 0; err == nil && i < len(shardDirs); i++ {
+			err = w.fsListenerWatch(shardDirs[i])
+		}
+	}
 	if err != nil {
 		log.L(ctx).Errorf("Failed to start filesystem listener: %s", err)
 		return i18n.WrapError(ctx, err, signermsgs.MsgFailedToStartListener, err)
@@ -60,7 +73,7 @@ func (w *fsWallet) fsListenerLoop(ctx context.Context, done func(), events chan
 				log.L(ctx).Tracef("FSEvent [%s]: %s", event.Op, event.Name)
 				fi, err := os.Stat(event.Name)
 				if err == nil {
-					w.notifyNewFiles(ctx, fi)
+					w.handleFileEvent(ctx, event.Name, fi)
 				}
 			}
 		case err, ok := <-errors:
@@ -70,3 +83,24 @@ func (w *fsWallet) fsListenerLoop(ctx context.Context, done func(), events chan
 		}
 	}
 }
+
+func (w *fsWallet) handleFileEvent(ctx context.Context, name string, fi os.FileInfo) {
+	dir, err := filepath.Rel(w.conf.Path, filepath.Dir(name))
+	if err != nil || dir == "." {
+		dir = ""
+	}
+	if fi.IsDir() && dir == "" && w.isShardDir(fi.Name()) {
+		// A new shard directory - we need to watch it, and pick up anything written before we did
+		log.L(ctx).Debugf("New shard directory: %s", name)
+		if w.fsListenerWatch != nil {
+			if err := w.fsListenerWatch(fi.Name()); err != nil {
+				log.L(ctx).Errorf("Failed to watch shard directory %s: %s", name, err)
+			}
+		}
+		if err := w.refreshShard(ctx, fi.Name()); err != nil {
+			log.L(ctx).Errorf("Failed to read shard directory %s: %s", name, err)
+		}
+		return
+	}
+	w.notifyNewFiles(ctx, dir, fi)
+}
@@ -28,6 +28,7 @@ import (
 	"text/template"
 	"time"
 
+	btcec "github.com/btcsuite/btcd/btcec/v2"
 	"github.com/hyperledger/firefly-common/pkg/fftypes"
 	"github.com/hyperledger/firefly-common/pkg/i18n"
 	"github.com/hyperledger/firefly-common/pkg/log"
@@ -37,7 +38,6 @@ import (
 	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
 	"github.com/hyperledger/firefly-signer/pkg/keystorev3"
 	"github.com/hyperledger/firefly-signer/pkg/secp256k1"
-	"github.com/karlseguin/ccache"
 	"github.com/pelletier/go-toml"
 	"gopkg.in/yaml.v2"
 )
@@ -48,6 +48,11 @@ import (
 type Wallet interface {
 	ethsigner.WalletTypedData
 	GetWalletFile(ctx context.Context, addr ethtypes.Address0xHex) (keystorev3.WalletFile, error)
+	GetPublicKey(ctx context.Context, addr ethtypes.Address0xHex) (*ethsigner.PublicKeyResult, error)
+	Unlock(ctx context.Context, addr ethtypes.Address0xHex, password []byte, ttl time.Duration) error
+	Lock(ctx context.Context, addr ethtypes.Address0xHex) error
+	LockAll(ctx context.Context) error
+	SignerCacheStats() *ethsigner.CacheStats
 	AddListener(listener chan<- ethtypes.Address0xHex)
 }
 
@@ -56,12 +61,11 @@ func NewFilesystemWallet(ctx context.Context, conf *Config, initialListeners ...
 		conf:             *conf,
 		listeners:        initialListeners,
 		addressToFileMap: make(map[ethtypes.Address0xHex]string),
+		unlockTTL:        fftypes.ParseToDuration(conf.UnlockTTL),
+		unlockedKeys:     make(map[ethtypes.Address0xHex]*unlockedKey),
+		publicKeys:       make(map[ethtypes.Address0xHex]*btcec.PublicKey),
 	}
-	w.signerCache = ccache.New(
-		// We use a LRU cache with a size-aware max
-		ccache.Configure().
-			MaxSize(fftypes.ParseToByteSize(conf.SignerCacheSize)),
-	)
+	w.signerCache = secp256k1.NewKeyPairCache(int(fftypes.ParseToByteSize(conf.SignerCacheSize)), fftypes.ParseToDuration(conf.SignerCacheTTL))
 	w.metadataKeyFileProperty, err = goTemplateFromConfig(ctx, ConfigMetadataKeyFileProperty, conf.Metadata.KeyFileProperty)
 	if err != nil {
 		return nil, err
@@ -70,17 +74,36 @@ func NewFilesystemWallet(ctx context.Context, conf *Config, initialListeners ...
 	if err != nil {
 		return nil, err
 	}
-	if conf.Filenames.PrimaryMatchRegex != "" {
-		if w.primaryMatchRegex, err = regexp.Compile(conf.Filenames.PrimaryMatchRegex); err != nil {
-			return nil, i18n.NewError(ctx, signermsgs.MsgBadRegularExpression, ConfigFilenamesPrimaryMatchRegex, err)
-		}
-		if len(w.primaryMatchRegex.SubexpNames()) < 2 {
-			return nil, i18n.NewError(ctx, signermsgs.MsgMissingRegexpCaptureGroup, w.primaryMatchRegex.String())
-		}
+	if err := checkShardPrefixLength(ctx, conf.Filenames.ShardPrefixLength); err != nil {
+		return nil, err
+	}
+	if w.primaryMatchRegex, err = compilePrimaryMatchRegex(ctx, conf.Filenames.PrimaryMatchRegex); err != nil {
+		return nil, err
 	}
 	return w, nil
 }
 
+func checkShardPrefixLength(ctx context.Context, shardPrefixLength int) error {
+	if shardPrefixLength < 0 || shardPrefixLength > maxShardPrefixLength {
+		return i18n.NewError(ctx, signermsgs.MsgInvalidShardPrefixLength, shardPrefixLength, maxShardPrefixLength)
+	}
+	return nil
+}
+
+func compilePrimaryMatchRegex(ctx context.Context, regexStr string) (*regexp.Regexp, error) {
+	if regexStr == "" {
+		return nil, nil
+	}
+	r, err := regexp.Compile(regexStr)
+	if err != nil {
+		return nil, i18n.NewError(ctx, signermsgs.MsgBadRegularExpression, ConfigFilenamesPrimaryMatchRegex, err)
+	}
+	if len(r.SubexpNames()) < 2 {
+		return nil, i18n.NewError(ctx, signermsgs.MsgMissingRegexpCaptureGroup, r.String())
+	}
+	return r, nil
+}
+
 func goTemplateFromConfig(ctx context.Context, name string, templateStr string) (*template.Template, error) {
 	if templateStr == "" {
 		return nil, nil
@@ -94,35 +117,107 @@ func goTemplateFromConfig(ctx context.Context, name string, templateStr string)
 
 type fsWallet struct {
 	conf                         Config
-	signerCache                  *ccache.Cache
-	signerCacheTTL               time.Duration
+	signerCache                  *secp256k1.KeyPairCache
 	metadataKeyFileProperty      *template.Template
 	metadataPasswordFileProperty *template.Template
 	primaryMatchRegex            *regexp.Regexp
+	unlockTTL                    time.Duration
 
 	mux               sync.Mutex
-	addressToFileMap  map[ethtypes.Address0xHex]string // map for lookup to filename
+	addressToFileMap  map[ethtypes.Address0xHex]string // map for lookup to filename (relative to the wallet path, including any shard directory)
 	addressList       []*ethtypes.Address0xHex         // ordered list in filename at startup, then notification order
+	unlockedKeys      map[ethtypes.Address0xHex]*unlockedKey
+	publicKeys        map[ethtypes.Address0xHex]*btcec.PublicKey // retained after keys are locked or evicted from the cache
 	listeners         []chan<- ethtypes.Address0xHex
 	fsListenerCancel  context.CancelFunc
 	fsListenerStarted chan error
 	fsListenerDone    chan struct{}
+	fsListenerWatch   func(dir string) error
+}
+
+// SignerCacheStats returns the number of keys currently held in the signer cache, and the
+// number of signing key lookups that have been served from the cache (or missed it)
+func (w *fsWallet) SignerCacheStats() *ethsigner.CacheStats {
+	items, hits, misses := w.signerCache.Stats()
+	return &ethsigner.CacheStats{
+		Items:  items,
+		Hits:   hits,
+		Misses: misses,
+	}
+}
+
+// signer returns the signer to use for a key, which mixes extra entropy into each signature if configured
+func (w *fsWallet) signer(keypair *secp256k1.KeyPair) secp256k1.SignerDirect {
+	if w.conf.ExtraEntropy {
+		return secp256k1.NewExtraEntropySigner(keypair)
+	}
+	return keypair
 }
 
 func (w *fsWallet) Sign(ctx context.Context, txn *ethsigner.Transaction, chainID int64) ([]byte, error) {
-	keypair, err := w.getSignerForJSONAccount(ctx, txn.From)
+	keypair, release, err := w.getSignerForJSONAccount(ctx, txn.From)
 	if err != nil {
 		return nil, err
 	}
-	return txn.Sign(keypair, chainID)
+	defer release()
+	return txn.Sign(w.signer(keypair), chainID)
 }
 
 func (w *fsWallet) SignTypedDataV4(ctx context.Context, from ethtypes.Address0xHex, payload *eip712.TypedData) (*ethsigner.EIP712Result, error) {
-	keypair, err := w.getSignerForAddr(ctx, from)
+	keypair, release, err := w.getSignerForAddr(ctx, from)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return ethsigner.SignTypedDataV4(ctx, w.signer(keypair), payload)
+}
+
+func (w *fsWallet) SignPersonalMessage(ctx context.Context, from ethtypes.Address0xHex, message []byte) (ethtypes.HexBytes0xPrefix, error) {
+	keypair, release, err := w.getSignerForAddr(ctx, from)
 	if err != nil {
 		return nil, err
 	}
-	return ethsigner.SignTypedDataV4(ctx, keypair, payload)
+	defer release()
+	return ethsigner.SignPersonalMessage(w.signer(keypair), message)
+}
+
+func (w *fsWallet) SignDigest(ctx context.Context, from ethtypes.Address0xHex, digest []byte) (ethtypes.HexBytes0xPrefix, error) {
+	keypair, release, err := w.getSignerForAddr(ctx, from)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return ethsigner.SignDigest(ctx, w.signer(keypair), digest)
+}
+
+// SignSchnorr signs a 32 byte hash with a deterministic BIP-340 Schnorr signature
+func (w *fsWallet) SignSchnorr(ctx context.Context, from ethtypes.Address0xHex, hash []byte) (ethtypes.HexBytes0xPrefix, error) {
+	keypair, release, err := w.getSignerForAddr(ctx, from)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return keypair.SignSchnorr(hash)
+}
+
+// GetPublicKey returns the public key for an address. The first call for each address requires the
+// key to be loaded, but the public key is then retained in memory even when the key is locked.
+func (w *fsWallet) GetPublicKey(ctx context.Context, addr ethtypes.Address0xHex) (*ethsigner.PublicKeyResult, error) {
+	w.mux.Lock()
+	pubKey := w.publicKeys[addr]
+	w.mux.Unlock()
+	if pubKey == nil {
+		kv3, err := w.GetWalletFile(ctx, addr)
+		if err != nil {
+			return nil, err
+		}
+		// An unlocked key always has its public key retained, so this is a copy loaded from disk
+		defer kv3.Zeroize()
+		keypair := kv3.KeyPair()
+		defer keypair.Zeroize()
+		pubKey = keypair.PublicKey
+	}
+	return ethsigner.NewPublicKeyResult(pubKey), nil
 }
 
 func (w *fsWallet) Initialize(ctx context.Context) error {
@@ -154,31 +249,41 @@ func (w *fsWallet) GetAccounts(_ context.Context) ([]*ethtypes.Address0xHex, err
 	return accounts, nil
 }
 
-func (w *fsWallet) matchFilename(ctx context.Context, f fs.FileInfo) *ethtypes.Address0xHex {
+func (w *fsWallet) matchFilename(ctx context.Context, dir string, f fs.FileInfo) *ethtypes.Address0xHex {
+	fullPath := path.Join(w.conf.Path, dir, f.Name())
 	if f.IsDir() {
-		log.L(ctx).Tracef("Ignoring '%s/%s: directory", w.conf.Path, f.Name())
+		log.L(ctx).Tracef("Ignoring '%s: directory", fullPath)
 		return nil
 	}
+	addr := w.filenameToAddress(ctx, fullPath, f.Name())
+	if addr != nil && dir != "" && dir != w.shardDir(addr) {
+		log.L(ctx).Warnf("Ignoring '%s': address '%s' does not belong in shard directory '%s'", fullPath, addr, dir)
+		return nil
+	}
+	return addr
+}
+
+func (w *fsWallet) filenameToAddress(ctx context.Context, fullPath, name string) *ethtypes.Address0xHex {
 	if w.primaryMatchRegex != nil {
-		match := w.primaryMatchRegex.FindStringSubmatch(f.Name())
+		match := w.primaryMatchRegex.FindStringSubmatch(name)
 		if match == nil {
-			log.L(ctx).Tracef("Ignoring '%s/%s': does not match regexp", w.conf.Path, f.Name())
+			log.L(ctx).Tracef("Ignoring '%s': does not match regexp", fullPath)
 			return nil
 		}
 		addr, err := ethtypes.NewAddress(match[1]) // safe due to SubexpNames() length check
 		if err != nil {
-			log.L(ctx).Warnf("Ignoring '%s/%s': invalid address '%s': %s", w.conf.Path, f.Name(), match[1], err)
+			log.L(ctx).Warnf("Ignoring '%s': invalid address '%s': %s", fullPath, match[1], err)
 			return nil
 		}
 		return addr
 	}
-	if !strings.HasSuffix(f.Name(), w.conf.Filenames.PrimaryExt) {
-		log.L(ctx).Tracef("Ignoring '%s/%s: does not match extension '%s'", w.conf.Path, f.Name(), w.conf.Filenames.PrimaryExt)
+	if !strings.HasSuffix(name, w.conf.Filenames.PrimaryExt) {
+		log.L(ctx).Tracef("Ignoring '%s: does not match extension '%s'", fullPath, w.conf.Filenames.PrimaryExt)
 	}
-	addrString := strings.TrimSuffix(f.Name(), w.conf.Filenames.PrimaryExt)
+	addrString := strings.TrimSuffix(name, w.conf.Filenames.PrimaryExt)
 	addr, err := ethtypes.NewAddress(addrString)
 	if err != nil {
-		log.L(ctx).Warnf("Ignoring '%s/%s': invalid address '%s': %s", w.conf.Path, f.Name(), addrString, err)
+		log.L(ctx).Warnf("Ignoring '%s': invalid address '%s': %s", fullPath, addrString, err)
 		return nil
 	}
 	return addr
@@ -186,35 +291,79 @@ func (w *fsWallet) matchFilename(ctx context.Context, f fs.FileInfo) *ethtypes.A
 
 func (w *fsWallet) Refresh(ctx context.Context) error {
 	log.L(ctx).Infof("Refreshing account list at %s", w.conf.Path)
-	dirEntries, err := os.ReadDir(w.conf.Path)
+	files, shardDirs, err := w.listDir(ctx, "")
+	if err != nil {
+		return err
+	}
+	if len(files) > 0 {
+		w.notifyNewFiles(ctx, "", files...)
+	}
+	for _, shardDir := range shardDirs {
+		if err := w.refreshShard(ctx, shardDir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *fsWallet) refreshShard(ctx context.Context, shardDir string) error {
+	files, _, err := w.listDir(ctx, shardDir)
+	if err != nil {
+		return err
+	}
+	if len(files) > 0 {
+		w.notifyNewFiles(ctx, shardDir, files...)
+	}
+	return nil
+}
+
+// listDir returns the file entries in a directory relative to the wallet path, and separately
+// the names of any sub-directories that are shard directories (when sharding is enabled)
+func (w *fsWallet) listDir(ctx context.Context, dir string) (files []fs.FileInfo, shardDirs []string, err error) {
+	dirEntries, err := os.ReadDir(path.Join(w.conf.Path, dir))
 	if err != nil {
-		return i18n.WrapError(ctx, err, signermsgs.MsgReadDirFile)
+		return nil, nil, i18n.WrapError(ctx, err, signermsgs.MsgReadDirFile)
 	}
-	files := make([]os.FileInfo, 0, len(dirEntries))
+	files = make([]os.FileInfo, 0, len(dirEntries))
 	for _, de := range dirEntries {
+		if de.IsDir() && dir == "" && w.isShardDir(de.Name()) {
+			shardDirs = append(shardDirs, de.Name())
+			continue
+		}
 		fi, infoErr := de.Info()
 		if infoErr == nil {
 			files = append(files, fi)
 		}
 	}
-	if len(files) > 0 {
-		w.notifyNewFiles(ctx, files...)
+	return files, shardDirs, nil
+}
+
+// shardDir returns the sub-directory a file for the given address is stored in, which is
+// the empty string when sharding is disabled
+func (w *fsWallet) shardDir(addr *ethtypes.Address0xHex) string {
+	if w.conf.Filenames.ShardPrefixLength == 0 {
+		return ""
 	}
-	return nil
+	return strings.TrimPrefix(addr.String(), "0x")[0:w.conf.Filenames.ShardPrefixLength]
 }
 
-func (w *fsWallet) notifyNewFiles(ctx context.Context, files ...fs.FileInfo) {
+func (w *fsWallet) isShardDir(name string) bool {
+	return w.conf.Filenames.ShardPrefixLength > 0 && len(name) == w.conf.Filenames.ShardPrefixLength && isHexPrefix(name)
+}
+
+func (w *fsWallet) notifyNewFiles(ctx context.Context, dir string, files ...fs.FileInfo) {
 	// Lock now we have the list
 	w.mux.Lock()
 	defer w.mux.Unlock()
 	newAddresses := make([]*ethtypes.Address0xHex, 0)
 	for _, f := range files {
-		addr := w.matchFilename(ctx, f)
+		addr := w.matchFilename(ctx, dir, f)
 		if addr != nil {
-			if existingFilename, exists := w.addressToFileMap[*addr]; existingFilename != f.Name() {
-				w.addressToFileMap[*addr] = f.Name()
+			relPath := path.Join(dir, f.Name())
+			if existingFilename, exists := w.addressToFileMap[*addr]; existingFilename != relPath {
+				w.addressToFileMap[*addr] = relPath
 				if !exists {
-					log.L(ctx).Debugf("Added address: %s (file=%s)", addr, f.Name())
+					log.L(ctx).Debugf("Added address: %s (file=%s)", addr, relPath)
 					w.addressList = append(w.addressList, addr)
 					newAddresses = append(newAddresses, addr)
 				}
@@ -239,39 +388,65 @@ func (w *fsWallet) Close() error {
 		w.fsListenerCancel()
 		<-w.fsListenerDone
 	}
-	return nil
+	// Remove all key material from memory
+	return w.LockAll(context.Background())
 }
 
-func (w *fsWallet) getSignerForJSONAccount(ctx context.Context, rawAddrJSON json.RawMessage) (*secp256k1.KeyPair, error) {
+func (w *fsWallet) getSignerForJSONAccount(ctx context.Context, rawAddrJSON json.RawMessage) (*secp256k1.KeyPair, func(), error) {
 
 	// We require an ethereum address in the "from" field
 	var from ethtypes.Address0xHex
 	err := json.Unmarshal(rawAddrJSON, &from)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	return w.getSignerForAddr(ctx, from)
 }
 
-func (w *fsWallet) getSignerForAddr(ctx context.Context, from ethtypes.Address0xHex) (*secp256k1.KeyPair, error) {
+// getSignerForAddr returns the key pair to sign with, and a release function that must be called
+// once signing is complete. The key pair is zeroized once released, if it has been locked or evicted
+// from the signer cache in the meantime (or was only loaded for this request).
+func (w *fsWallet) getSignerForAddr(ctx context.Context, from ethtypes.Address0xHex) (*secp256k1.KeyPair, func(), error) {
+
+	if keypair := w.getUnlockedKeyPair(from); keypair != nil {
+		return keypair, keypair.Zeroize, nil
+	}
+	if w.conf.RequireUnlock {
+		return nil, nil, i18n.NewError(ctx, signermsgs.MsgWalletLocked, from)
+	}
+
+	if keypair, release := w.signerCache.Get(from); keypair != nil {
+		return keypair, release, nil
+	}
 
-	wf, err := w.GetWalletFile(ctx, from)
+	kv3, err := w.loadAndCheckWalletFile(ctx, from, nil)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	return wf.KeyPair(), nil
+	// Only the key pair is kept in memory
+	keypair := kv3.KeyPair()
+	kv3.Zeroize()
+	return keypair, w.signerCache.Add(keypair), nil
 
 }
 
+// GetWalletFile returns the wallet file for an address, which is loaded from disk unless the address is unlocked.
+// The returned wallet file is always the caller's own copy, so should be zeroized by the caller once finished with it.
 func (w *fsWallet) GetWalletFile(ctx context.Context, addr ethtypes.Address0xHex) (keystorev3.WalletFile, error) {
 
-	addrString := addr.String()
-	cached := w.signerCache.Get(addrString)
-	if cached != nil {
-		cached.Extend(w.signerCacheTTL)
-		return cached.Value().(keystorev3.WalletFile), nil
+	if kv3 := w.getUnlockedCopy(addr); kv3 != nil {
+		return kv3, nil
 	}
+	if w.conf.RequireUnlock {
+		return nil, i18n.NewError(ctx, signermsgs.MsgWalletLocked, addr)
+	}
+	return w.loadAndCheckWalletFile(ctx, addr, nil)
 
+}
+
+// loadAndCheckWalletFile loads the wallet file for an address, using the supplied password if non-nil,
+// or otherwise the password resolved from the configured password files.
+func (w *fsWallet) loadAndCheckWalletFile(ctx context.Context, addr ethtypes.Address0xHex, password []byte) (keystorev3.WalletFile, error) {
 	w.mux.Lock()
 	primaryFilename, ok := w.addressToFileMap[addr]
 	w.mux.Unlock()
@@ -279,22 +454,25 @@ func (w *fsWallet) GetWalletFile(ctx context.Context, addr ethtypes.Address0xHex
 		return nil, i18n.NewError(ctx, signermsgs.MsgWalletNotAvailable, addr)
 	}
 
-	kv3, err := w.loadWalletFile(ctx, addr, path.Join(w.conf.Path, primaryFilename))
+	kv3, err := w.loadWalletFile(ctx, addr, path.Join(w.conf.Path, primaryFilename), password)
 	if err != nil {
 		return nil, err
 	}
 
 	keypair := kv3.KeyPair()
+	defer keypair.Zeroize()
 	if keypair.Address != addr {
+		kv3.Zeroize()
 		return nil, i18n.NewError(ctx, signermsgs.MsgAddressMismatch, keypair.Address, addr)
 	}
 
-	w.signerCache.Set(addrString, kv3, w.signerCacheTTL)
-	return kv3, err
-
+	w.mux.Lock()
+	w.publicKeys[addr] = keypair.PublicKey
+	w.mux.Unlock()
+	return kv3, nil
 }
 
-func (w *fsWallet) loadWalletFile(ctx context.Context, addr ethtypes.Address0xHex, primaryFilename string) (keystorev3.WalletFile, error) {
+func (w *fsWallet) loadWalletFile(ctx context.Context, addr ethtypes.Address0xHex, primaryFilename string, password []byte) (keystorev3.WalletFile, error) {
 
 	b, err := os.ReadFile(primaryFilename)
 	if err != nil {
@@ -315,12 +493,18 @@ func (w *fsWallet) loadWalletFile(ctx context.Context, addr ethtypes.Address0xHe
 			return nil, i18n.NewError(ctx, signermsgs.MsgWalletFailed, addr)
 		}
 	}
+	if err := w.checkFilePermissions(ctx, keyFilename); err != nil {
+		log.L(ctx).Errorf("Insecure keyfile: %s", err)
+		return nil, i18n.NewError(ctx, signermsgs.MsgWalletFailed, addr)
+	}
 
-	var password []byte
-	if passwordFilename != "" {
+	if password == nil && passwordFilename != "" {
 		password, err = os.ReadFile(passwordFilename)
 		if err != nil {
 			log.L(ctx).Debugf("Failed to read '%s' (password file): %s", passwordFilename, err)
+		} else if err := w.checkFilePermissions(ctx, passwordFilename); err != nil {
+			log.L(ctx).Errorf("Insecure password file: %s", err)
+			return nil, i18n.NewError(ctx, signermsgs.MsgWalletFailed, addr)
 		} else if w.conf.Filenames.PasswordTrimSpace {
 			password = []byte(strings.TrimSpace(string(password)))
 		}
@@ -337,6 +521,10 @@ func (w *fsWallet) loadWalletFile(ctx context.Context, addr ethtypes.Address0xHe
 			log.L(ctx).Errorf("Failed to read '%s' (default password file): %s", w.conf.DefaultPasswordFile, err)
 			return nil, i18n.NewError(ctx, signermsgs.MsgWalletFailed, addr)
 		}
+		if err := w.checkFilePermissions(ctx, w.conf.DefaultPasswordFile); err != nil {
+			log.L(ctx).Errorf("Insecure default password file: %s", err)
+			return nil, i18n.NewError(ctx, signermsgs.MsgWalletFailed, addr)
+		}
 
 	}
 
@@ -352,12 +540,8 @@ func (w *fsWallet) loadWalletFile(ctx context.Context, addr ethtypes.Address0xHe
 }
 
 func (w *fsWallet) getKeyAndPasswordFiles(ctx context.Context, addr ethtypes.Address0xHex, primaryFilename string, primaryFile []byte) (kf string, pf string, err error) {
-	if strings.ToLower(w.conf.Metadata.Format) == "auto" {
-		w.conf.Metadata.Format = strings.TrimPrefix(w.conf.Filenames.PrimaryExt, ".")
-	}
-
 	var metadata map[string]interface{}
-	switch w.conf.Metadata.Format {
+	switch w.metadataFormat() {
 	case "toml", "tml":
 		err = toml.Unmarshal(primaryFile, &metadata)
 	case "json":
@@ -368,17 +552,13 @@ func (w *fsWallet) getKeyAndPasswordFiles(ctx context.Context, addr ethtypes.Add
 		// No separate metadata file - we just use the default password file extension instead
 		passwordPath := w.conf.Filenames.PasswordPath
 		if passwordPath == "" {
-			passwordPath = w.conf.Path
+			// Password files sit next to the primary file (including in any shard directory)
+			passwordPath = path.Dir(primaryFilename)
 		}
-		passwordFilename := addr.String()
-		if !w.conf.Filenames.With0xPrefix {
-			passwordFilename = strings.TrimPrefix(passwordFilename, "0x")
-		}
-		passwordFilename += w.conf.Filenames.PasswordExt
-		return primaryFilename, path.Join(passwordPath, passwordFilename), nil
+		return primaryFilename, path.Join(passwordPath, w.passwordFilename(addr)), nil
 	}
 	if err != nil {
-		log.L(ctx).Errorf("Failed to parse '%s' as %s: %s", primaryFilename, w.conf.Metadata.Format, err)
+		log.L(ctx).Errorf("Failed to parse '%s' as %s: %s", primaryFilename, w.metadataFormat(), err)
 		return "", "", i18n.NewError(ctx, signermsgs.MsgWalletFailed, addr)
 	}
 
@@ -392,6 +572,30 @@ func (w *fsWallet) getKeyAndPasswordFiles(ctx context.Context, addr ethtypes.Add
 	return kf, pf, nil
 }
 
+// metadataFormat returns the configured metadata format, resolving "auto" from the primary file extension
+func (w *fsWallet) metadataFormat() string {
+	return metadataFormat(&w.conf)
+}
+
+func metadataFormat(conf *Config) string {
+	if strings.ToLower(conf.Metadata.Format) == "auto" {
+		return strings.TrimPrefix(conf.Filenames.PrimaryExt, ".")
+	}
+	return conf.Metadata.Format
+}
+
+func (w *fsWallet) passwordFilename(addr ethtypes.Address0xHex) string {
+	return w.addressFilename(addr, w.conf.Filenames.PasswordExt)
+}
+
+func (w *fsWallet) addressFilename(addr ethtypes.Address0xHex, ext string) string {
+	filename := addr.String()
+	if !w.conf.Filenames.With0xPrefix {
+		filename = strings.TrimPrefix(filename, "0x")
+	}
+	return filename + ext
+}
+
 func (w *fsWallet) goTemplateToString(ctx context.Context, filename string, data map[string]interface{}, t *template.Template) (string, error) {
 	if t == nil {
 		return "", nil
@@ -27,6 +27,7 @@ import (
 	"github.com/hyperledger/firefly-signer/pkg/eip712"
 	"github.com/hyperledger/firefly-signer/pkg/ethsigner"
 	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/secp256k1"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 )
@@ -38,6 +39,7 @@ func newTestRegexpFilenameOnlyWallet(t *testing.T, init bool) (context.Context,
 	unitTestConfig := config.RootSection("ut_fs_config")
 	InitConfig(unitTestConfig)
 	unitTestConfig.Set(ConfigPath, "../../test/keystore_toml")
+	unitTestConfig.Set(ConfigAllowInsecurePermissions, true) // repo fixtures are checked out readable by others
 	unitTestConfig.Set(ConfigFilenamesPrimaryMatchRegex, "^((0x)?[0-9a-z]+).key.json$")
 	unitTestConfig.Set(ConfigFilenamesPasswordExt, ".pwd")
 	unitTestConfig.Set(ConfigDisableListener, true)
@@ -62,6 +64,7 @@ func newTestTOMLMetadataWallet(t *testing.T, init bool) (context.Context, *fsWal
 	unitTestConfig := config.RootSection("ut_fs_config")
 	InitConfig(unitTestConfig)
 	unitTestConfig.Set(ConfigPath, "../../test/keystore_toml")
+	unitTestConfig.Set(ConfigAllowInsecurePermissions, true)
 	unitTestConfig.Set(ConfigFilenamesPrimaryExt, ".toml")
 	unitTestConfig.Set(ConfigMetadataKeyFileProperty, `{{ index .signing "key-file" }}`)
 	unitTestConfig.Set(ConfigMetadataPasswordFileProperty, `{{ index .signing "password-file" }}`)
@@ -84,7 +87,7 @@ func TestGetAccountSimpleFilenamesOK(t *testing.T) {
 	ctx, f, done := newTestRegexpFilenameOnlyWallet(t, true)
 	defer done()
 
-	_, err := f.getSignerForJSONAccount(ctx, json.RawMessage(`"0x1f185718734552d08278aa70f804580bab5fd2b4"`))
+	_, _, err := f.getSignerForJSONAccount(ctx, json.RawMessage(`"0x1f185718734552d08278aa70f804580bab5fd2b4"`))
 	assert.NoError(t, err)
 
 }
@@ -96,7 +99,7 @@ func TestGetAccountSimpleFilenamesMissingPWD(t *testing.T) {
 
 	f.conf.Filenames.PasswordExt = ".wrong"
 
-	_, err := f.getSignerForJSONAccount(ctx, json.RawMessage(`"0x1f185718734552d08278aa70f804580bab5fd2b4"`))
+	_, _, err := f.getSignerForJSONAccount(ctx, json.RawMessage(`"0x1f185718734552d08278aa70f804580bab5fd2b4"`))
 	assert.Regexp(t, "FF22015", err)
 
 }
@@ -106,7 +109,7 @@ func TestGetAccountSimpleFilenamesMismatchAddress(t *testing.T) {
 	ctx, f, done := newTestRegexpFilenameOnlyWallet(t, true)
 	defer done()
 
-	_, err := f.getSignerForJSONAccount(ctx, json.RawMessage(`"abcd1234abcd1234abcd1234abcd1234abcd1234"`))
+	_, _, err := f.getSignerForJSONAccount(ctx, json.RawMessage(`"abcd1234abcd1234abcd1234abcd1234abcd1234"`))
 	assert.Regexp(t, "FF22059", err)
 
 }
@@ -126,11 +129,11 @@ func TestListAccountsTOMLOk(t *testing.T) {
 	assert.True(t, all["0x497eedc4299dea2f2a364be10025d0ad0f702de3"])
 	assert.True(t, all["0x5d093e9b41911be5f5c4cf91b108bac5d130fa83"])
 
-	_, err = f.getSignerForJSONAccount(ctx, json.RawMessage(`"0x1f185718734552d08278aa70f804580bab5fd2b4"`))
+	_, _, err = f.getSignerForJSONAccount(ctx, json.RawMessage(`"0x1f185718734552d08278aa70f804580bab5fd2b4"`))
 	assert.NoError(t, err)
-	_, err = f.getSignerForJSONAccount(ctx, json.RawMessage(`"0x497eedc4299dea2f2a364be10025d0ad0f702de3"`))
+	_, _, err = f.getSignerForJSONAccount(ctx, json.RawMessage(`"0x497eedc4299dea2f2a364be10025d0ad0f702de3"`))
 	assert.Regexp(t, "FF22015", err)
-	_, err = f.getSignerForJSONAccount(ctx, json.RawMessage(`"0x5d093e9b41911be5f5c4cf91b108bac5d130fa83"`))
+	_, _, err = f.getSignerForJSONAccount(ctx, json.RawMessage(`"0x5d093e9b41911be5f5c4cf91b108bac5d130fa83"`))
 	assert.Regexp(t, "FF22015", err)
 
 }
@@ -230,6 +233,98 @@ func TestSignTypedDataOK(t *testing.T) {
 
 }
 
+func TestSignPersonalMessageOK(t *testing.T) {
+
+	ctx, f, done := newTestTOMLMetadataWallet(t, true)
+	defer done()
+
+	b, err := f.SignPersonalMessage(ctx, *ethtypes.MustNewAddress(`0x1f185718734552d08278aa70f804580bab5fd2b4`), []byte("Hello World"))
+	assert.NoError(t, err)
+	assert.Len(t, b, 65)
+
+}
+
+func TestSignPersonalMessageNotFound(t *testing.T) {
+
+	ctx, f, done := newTestTOMLMetadataWallet(t, true)
+	defer done()
+
+	_, err := f.SignPersonalMessage(ctx, *ethtypes.MustNewAddress(`0xFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF`), []byte("Hello World"))
+	assert.Regexp(t, "FF22014", err)
+
+}
+
+func TestSignDigestOK(t *testing.T) {
+
+	ctx, f, done := newTestTOMLMetadataWallet(t, true)
+	defer done()
+
+	b, err := f.SignDigest(ctx, *ethtypes.MustNewAddress(`0x1f185718734552d08278aa70f804580bab5fd2b4`), ethsigner.PersonalMessageHash([]byte("Hello World")))
+	assert.NoError(t, err)
+	assert.Len(t, b, 65)
+
+}
+
+func TestSignDigestExtraEntropy(t *testing.T) {
+
+	ctx, f, done := newTestTOMLMetadataWallet(t, true)
+	defer done()
+	f.conf.ExtraEntropy = true
+
+	addr := *ethtypes.MustNewAddress(`0x1f185718734552d08278aa70f804580bab5fd2b4`)
+	digest := ethsigner.PersonalMessageHash([]byte("Hello World"))
+	b1, err := f.SignDigest(ctx, addr, digest)
+	assert.NoError(t, err)
+	b2, err := f.SignDigest(ctx, addr, digest)
+	assert.NoError(t, err)
+	assert.NotEqual(t, b1, b2)
+
+	for _, b := range []ethtypes.HexBytes0xPrefix{b1, b2} {
+		sig, err := secp256k1.DecodeCompactRSV(ctx, b)
+		assert.NoError(t, err)
+		signer, err := sig.RecoverDirect(digest, -1)
+		assert.NoError(t, err)
+		assert.Equal(t, addr, *signer)
+	}
+
+}
+
+func TestSignSchnorrOK(t *testing.T) {
+
+	ctx, f, done := newTestTOMLMetadataWallet(t, true)
+	defer done()
+
+	addr := *ethtypes.MustNewAddress(`0x1f185718734552d08278aa70f804580bab5fd2b4`)
+	hash := ethsigner.PersonalMessageHash([]byte("Hello World"))
+	sig, err := f.SignSchnorr(ctx, addr, hash)
+	assert.NoError(t, err)
+
+	pubKey, err := f.GetPublicKey(ctx, addr)
+	assert.NoError(t, err)
+	assert.NoError(t, secp256k1.VerifySchnorr(pubKey.XOnly, hash, sig))
+
+}
+
+func TestSignSchnorrNotFound(t *testing.T) {
+
+	ctx, f, done := newTestTOMLMetadataWallet(t, true)
+	defer done()
+
+	_, err := f.SignSchnorr(ctx, *ethtypes.MustNewAddress(`0xFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF`), make([]byte, 32))
+	assert.Regexp(t, "FF22014", err)
+
+}
+
+func TestSignDigestNotFound(t *testing.T) {
+
+	ctx, f, done := newTestTOMLMetadataWallet(t, true)
+	defer done()
+
+	_, err := f.SignDigest(ctx, *ethtypes.MustNewAddress(`0xFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF`), make([]byte, 32))
+	assert.Regexp(t, "FF22014", err)
+
+}
+
 func TestSignNotFound(t *testing.T) {
 
 	ctx, f, done := newTestTOMLMetadataWallet(t, true)
@@ -259,14 +354,24 @@ func TestGetAccountCached(t *testing.T) {
 	ctx, f, done := newTestTOMLMetadataWallet(t, true)
 	defer done()
 
-	s, err := f.getSignerForJSONAccount(ctx, json.RawMessage(`"0x1f185718734552d08278aa70f804580bab5fd2b4"`))
+	s1, release, err := f.getSignerForJSONAccount(ctx, json.RawMessage(`"0x1f185718734552d08278aa70f804580bab5fd2b4"`))
 	assert.NoError(t, err)
-	assert.NotNil(t, s)
+	assert.NotNil(t, s1)
+	release()
 
 	// 2nd time is cached
-	s, err = f.getSignerForJSONAccount(ctx, json.RawMessage(`"0x1f185718734552d08278aa70f804580bab5fd2b4"`))
+	s2, release, err := f.getSignerForJSONAccount(ctx, json.RawMessage(`"0x1f185718734552d08278aa70f804580bab5fd2b4"`))
+	assert.NoError(t, err)
+	assert.Equal(t, s1, s2)
+
+	assert.Equal(t, &ethsigner.CacheStats{Items: 1, Hits: 1, Misses: 1}, f.SignerCacheStats())
+
+	// Locking evicts the key, which is zeroized once released
+	err = f.Lock(ctx, s2.Address)
 	assert.NoError(t, err)
-	assert.NotNil(t, s)
+	assert.NotEqual(t, make([]byte, 32), s2.PrivateKeyBytes())
+	release()
+	assert.Equal(t, make([]byte, 32), s2.PrivateKeyBytes())
 
 }
 
@@ -276,7 +381,7 @@ func TestGetAccountBadYAML(t *testing.T) {
 	defer done()
 	f.conf.Metadata.Format = "yaml"
 
-	_, err := f.getSignerForJSONAccount(ctx, json.RawMessage(`"0x1f185718734552d08278aa70f804580bab5fd2b4"`))
+	_, _, err := f.getSignerForJSONAccount(ctx, json.RawMessage(`"0x1f185718734552d08278aa70f804580bab5fd2b4"`))
 	assert.Regexp(t, "FF22015", err)
 
 }
@@ -286,7 +391,7 @@ func TestGetAccountBadAddress(t *testing.T) {
 	ctx, f, done := newTestTOMLMetadataWallet(t, true)
 	defer done()
 
-	_, err := f.getSignerForJSONAccount(ctx, json.RawMessage(`"bad address"`))
+	_, _, err := f.getSignerForJSONAccount(ctx, json.RawMessage(`"bad address"`))
 	assert.Regexp(t, "bad address", err)
 
 }
@@ -297,7 +402,7 @@ func TestGetAccountBadJSON(t *testing.T) {
 	defer done()
 	f.conf.Metadata.Format = "json"
 
-	_, err := f.getSignerForJSONAccount(ctx, json.RawMessage(`"0x1f185718734552d08278aa70f804580bab5fd2b4"`))
+	_, _, err := f.getSignerForJSONAccount(ctx, json.RawMessage(`"0x1f185718734552d08278aa70f804580bab5fd2b4"`))
 	assert.Regexp(t, "FF22015", err)
 
 }
@@ -321,6 +426,7 @@ func TestInitBadPasswordFileTemplate(t *testing.T) {
 	unitTestConfig := config.RootSection("ut_fs_config")
 	InitConfig(unitTestConfig)
 	unitTestConfig.Set(ConfigPath, "../../test/keystore_toml")
+	unitTestConfig.Set(ConfigAllowInsecurePermissions, true)
 	unitTestConfig.Set(ConfigFilenamesPrimaryExt, ".toml")
 	unitTestConfig.Set(ConfigMetadataKeyFileProperty, `{{ index .signing "key-file" }}`)
 	unitTestConfig.Set(ConfigMetadataPasswordFileProperty, `{{ !!! }}`)
@@ -335,6 +441,7 @@ func TestGetAccountBadTOMLRefKey(t *testing.T) {
 	unitTestConfig := config.RootSection("ut_fs_config")
 	InitConfig(unitTestConfig)
 	unitTestConfig.Set(ConfigPath, "../../test/keystore_toml")
+	unitTestConfig.Set(ConfigAllowInsecurePermissions, true)
 	unitTestConfig.Set(ConfigFilenamesPrimaryExt, ".toml")
 	unitTestConfig.Set(ConfigMetadataKeyFileProperty, `{{ index .signing "wrong" }}`)
 	unitTestConfig.Set(ConfigMetadataPasswordFileProperty, `{{ index .signing "password-file" }}`)
@@ -345,7 +452,7 @@ func TestGetAccountBadTOMLRefKey(t *testing.T) {
 	assert.NoError(t, err)
 	f := ff.(*fsWallet)
 
-	_, err = f.getSignerForJSONAccount(ctx, json.RawMessage(`"0x1f185718734552d08278aa70f804580bab5fd2b4"`))
+	_, _, err = f.getSignerForJSONAccount(ctx, json.RawMessage(`"0x1f185718734552d08278aa70f804580bab5fd2b4"`))
 	assert.Regexp(t, "FF22014", err)
 }
 
@@ -354,6 +461,7 @@ func TestGetAccountNoTemplates(t *testing.T) {
 	unitTestConfig := config.RootSection("ut_fs_config")
 	InitConfig(unitTestConfig)
 	unitTestConfig.Set(ConfigPath, "../../test/keystore_toml")
+	unitTestConfig.Set(ConfigAllowInsecurePermissions, true)
 	unitTestConfig.Set(ConfigFilenamesPrimaryExt, ".toml")
 
 	ctx := context.Background()
@@ -362,7 +470,7 @@ func TestGetAccountNoTemplates(t *testing.T) {
 	assert.NoError(t, err)
 	f := ff.(*fsWallet)
 
-	_, err = f.getSignerForJSONAccount(ctx, json.RawMessage(`"0x1f185718734552d08278aa70f804580bab5fd2b4"`))
+	_, _, err = f.getSignerForJSONAccount(ctx, json.RawMessage(`"0x1f185718734552d08278aa70f804580bab5fd2b4"`))
 	assert.Regexp(t, "FF22014", err)
 }
 
@@ -374,7 +482,7 @@ func TestGetAccountBadKeyfile(t *testing.T) {
 	f.metadataPasswordFileProperty = nil
 	f.conf.DefaultPasswordFile = "../../test/keystore_toml/1f185718734552d08278aa70f804580bab5fd2b4.pwd"
 
-	_, err := f.getSignerForJSONAccount(ctx, json.RawMessage(`"0x1f185718734552d08278aa70f804580bab5fd2b4"`))
+	_, _, err := f.getSignerForJSONAccount(ctx, json.RawMessage(`"0x1f185718734552d08278aa70f804580bab5fd2b4"`))
 	assert.Regexp(t, "FF22015", err)
 
 }
@@ -387,7 +495,7 @@ func TestGetAccountBadDefaultPasswordfile(t *testing.T) {
 	f.metadataPasswordFileProperty = nil
 	f.conf.DefaultPasswordFile = "!!!"
 
-	_, err := f.getSignerForJSONAccount(ctx, json.RawMessage(`"0x1f185718734552d08278aa70f804580bab5fd2b4"`))
+	_, _, err := f.getSignerForJSONAccount(ctx, json.RawMessage(`"0x1f185718734552d08278aa70f804580bab5fd2b4"`))
 	assert.Regexp(t, "FF22015", err)
 
 }
@@ -398,7 +506,7 @@ func TestGetAccountNoPassword(t *testing.T) {
 	defer done()
 	f.metadataPasswordFileProperty = nil
 
-	_, err := f.getSignerForJSONAccount(ctx, json.RawMessage(`"0x1f185718734552d08278aa70f804580bab5fd2b4"`))
+	_, _, err := f.getSignerForJSONAccount(ctx, json.RawMessage(`"0x1f185718734552d08278aa70f804580bab5fd2b4"`))
 	assert.Regexp(t, "FF22015", err)
 
 }
@@ -409,7 +517,7 @@ func TestGetAccountWrongPath(t *testing.T) {
 	defer done()
 	f.metadataPasswordFileProperty = nil
 
-	_, err := f.getSignerForJSONAccount(ctx, json.RawMessage(`"5d093e9b41911be5f5c4cf91b108bac5d130fa83"`))
+	_, _, err := f.getSignerForJSONAccount(ctx, json.RawMessage(`"5d093e9b41911be5f5c4cf91b108bac5d130fa83"`))
 	assert.Regexp(t, "FF22015", err)
 
 }
@@ -422,7 +530,7 @@ func TestGetAccountNotFound(t *testing.T) {
 	f.metadataPasswordFileProperty = nil
 	f.conf.DefaultPasswordFile = "!!!"
 
-	_, err := f.getSignerForJSONAccount(ctx, json.RawMessage(`"0xFFFF5718734552d08278aa70f804580bab5fd2b4"`))
+	_, _, err := f.getSignerForJSONAccount(ctx, json.RawMessage(`"0xFFFF5718734552d08278aa70f804580bab5fd2b4"`))
 	assert.Regexp(t, "FF22014", err)
 
 }
@@ -432,7 +540,7 @@ func TestLoadKeyBadPath(t *testing.T) {
 	ctx, f, done := newTestRegexpFilenameOnlyWallet(t, false)
 	defer done()
 
-	_, err := f.loadWalletFile(ctx, *ethtypes.MustNewAddress("0xFFFF5718734552d08278aa70f804580bab5fd2b4"), "../../test/keystore_toml/wrong.txt")
+	_, err := f.loadWalletFile(ctx, *ethtypes.MustNewAddress("0xFFFF5718734552d08278aa70f804580bab5fd2b4"), "../../test/keystore_toml/wrong.txt", nil)
 	assert.Regexp(t, "FF22015", err)
 
 }
@@ -0,0 +1,238 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fswallet
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path"
+
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-common/pkg/log"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/keystorev3"
+	"github.com/hyperledger/firefly-signer/pkg/secp256k1"
+)
+
+type ChangePasswordOptions struct {
+	// OldPassword returns the current password of the key. If nil, the password is resolved from
+	// the configured password files, in the same way as the wallet does when signing.
+	OldPassword func() ([]byte, error)
+	// NewPassword returns the password to re-encrypt the key with
+	NewPassword func() ([]byte, error)
+	// LightScrypt uses scrypt parameters that are quicker to decrypt, but less resistant to brute force
+	LightScrypt bool
+}
+
+// ChangePassword re-encrypts the key for an address in the wallet directory under a new password, and
+// writes the new password to the password file for the key (if the layout has one).
+//
+// The key file is replaced only after the re-encrypted file has been written alongside it, and verified
+// by decrypting it with the new password. A key that is encrypted with the default password file cannot
+// be changed, unless the wallet requires keys to be unlocked with their password before use.
+func ChangePassword(ctx context.Context, conf *Config, addr ethtypes.Address0xHex, options *ChangePasswordOptions) error {
+	if options == nil {
+		options = &ChangePasswordOptions{}
+	}
+	walletConf := *conf
+	walletConf.DisableListener = true
+	ww, err := NewFilesystemWallet(ctx, &walletConf)
+	if err != nil {
+		return err
+	}
+	w := ww.(*fsWallet)
+	defer w.Close()
+	if err := w.Initialize(ctx); err != nil {
+		return err
+	}
+
+	w.mux.Lock()
+	primaryFilename, ok := w.addressToFileMap[addr]
+	w.mux.Unlock()
+	if !ok {
+		return i18n.NewError(ctx, signermsgs.MsgWalletNotAvailable, addr)
+	}
+	primaryFile := path.Join(w.conf.Path, primaryFilename)
+	b, err := os.ReadFile(primaryFile)
+	if err != nil {
+		return i18n.WrapError(ctx, err, signermsgs.MsgWalletFailed, addr)
+	}
+	keyFile, passwordFile, err := w.getKeyAndPasswordFiles(ctx, addr, primaryFile, b)
+	if err != nil {
+		return err
+	}
+	if keyFile == primaryFile && w.conf.Filenames.PasswordExt == "" {
+		// Without metadata, the password file path is only meaningful if there is a password extension
+		passwordFile = ""
+	}
+	if passwordFile == "" && !w.conf.RequireUnlock {
+		return i18n.NewError(ctx, signermsgs.MsgChangePasswordNoPasswordFile, addr)
+	}
+
+	var oldPassword []byte
+	if options.OldPassword != nil {
+		if oldPassword, err = options.OldPassword(); err != nil {
+			return err
+		}
+		defer secp256k1.ZeroizeBytes(oldPassword)
+	}
+	kv3, err := w.loadWalletFile(ctx, addr, primaryFile, oldPassword)
+	if err != nil {
+		return err
+	}
+	defer kv3.Zeroize()
+	keypair := kv3.KeyPair()
+	defer keypair.Zeroize()
+	if keypair.Address != addr {
+		return i18n.NewError(ctx, signermsgs.MsgAddressMismatch, keypair.Address, addr)
+	}
+
+	var newPassword []byte
+	if options.NewPassword != nil {
+		if newPassword, err = options.NewPassword(); err != nil {
+			return err
+		}
+	}
+	if w.conf.Filenames.PasswordTrimSpace {
+		newPassword = bytes.TrimSpace(newPassword)
+	}
+	defer secp256k1.ZeroizeBytes(newPassword)
+	if len(newPassword) == 0 {
+		return i18n.NewError(ctx, signermsgs.MsgEmptyPassword)
+	}
+
+	var tmpPasswordFile string
+	if passwordFile != "" {
+		if tmpPasswordFile, err = writeTempFile(ctx, passwordFile, newPassword); err != nil {
+			return err
+		}
+		defer os.Remove(tmpPasswordFile)
+	}
+	originalKey, err := os.ReadFile(keyFile)
+	if err != nil {
+		return i18n.WrapError(ctx, err, signermsgs.MsgWalletFailed, addr)
+	}
+	if err := replaceKeyFile(ctx, keyFile, kv3, newPassword, options.LightScrypt); err != nil {
+		return err
+	}
+	if tmpPasswordFile != "" {
+		if err := os.Rename(tmpPasswordFile, passwordFile); err != nil {
+			// Put back the original key file, so it still matches the old password file
+			_ = os.WriteFile(keyFile, originalKey, 0600)
+			return i18n.WrapError(ctx, err, signermsgs.MsgWriteWalletFileFailed, passwordFile)
+		}
+	}
+	log.L(ctx).Infof("Changed password for address %s in %s", addr, keyFile)
+	return nil
+}
+
+// ChangeKeystoreFilePassword re-encrypts a standalone Keystore V3 file under a new password, replacing the
+// file only after the re-encrypted file has been verified by decrypting it with the new password.
+func ChangeKeystoreFilePassword(ctx context.Context, filename string, oldPassword, newPassword []byte, lightScrypt bool) (*ethtypes.Address0xHex, error) {
+	if len(newPassword) == 0 {
+		return nil, i18n.NewError(ctx, signermsgs.MsgEmptyPassword)
+	}
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, i18n.WrapError(ctx, err, signermsgs.MsgReadInputFailed, filename)
+	}
+	kv3, err := keystorev3.ReadWalletFile(b, oldPassword)
+	if err != nil {
+		return nil, i18n.NewError(ctx, signermsgs.MsgKeystoreDecryptFailed, filename, err)
+	}
+	defer kv3.Zeroize()
+	keypair := kv3.KeyPair()
+	defer keypair.Zeroize()
+	if err := replaceKeyFile(ctx, filename, kv3, newPassword, lightScrypt); err != nil {
+		return nil, err
+	}
+	log.L(ctx).Infof("Changed password for address %s in %s", keypair.Address, filename)
+	return &keypair.Address, nil
+}
+
+// replaceKeyFile writes the key re-encrypted under the new password to a temporary file next to the
+// key file, verifies it by decrypting it, and then renames it over the key file
+func replaceKeyFile(ctx context.Context, keyFile string, kv3 keystorev3.WalletFile, newPassword []byte, lightScrypt bool) error {
+	keypair := kv3.KeyPair()
+	defer keypair.Zeroize()
+	var newKv3 keystorev3.WalletFile
+	if lightScrypt {
+		newKv3 = keystorev3.NewWalletFileLight(string(newPassword), keypair)
+	} else {
+		newKv3 = keystorev3.NewWalletFileStandard(string(newPassword), keypair)
+	}
+	newKv3.Zeroize()
+	// Keep any additional fields from the original file, such as the address
+	for k, v := range kv3.Metadata() {
+		newKv3.Metadata()[k] = v
+	}
+
+	tmpKeyFile, err := writeTempFile(ctx, keyFile, newKv3.JSON())
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpKeyFile)
+	if err := verifyKeyFile(ctx, tmpKeyFile, newPassword, keypair.Address); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpKeyFile, keyFile); err != nil {
+		return i18n.WrapError(ctx, err, signermsgs.MsgWriteWalletFileFailed, keyFile)
+	}
+	return nil
+}
+
+func verifyKeyFile(ctx context.Context, filename string, password []byte, addr ethtypes.Address0xHex) error {
+	var kv3 keystorev3.WalletFile
+	b, err := os.ReadFile(filename)
+	if err == nil {
+		kv3, err = keystorev3.ReadWalletFile(b, password)
+	}
+	if err != nil {
+		return i18n.NewError(ctx, signermsgs.MsgChangePasswordVerifyFailed, filename, err)
+	}
+	defer kv3.Zeroize()
+	keypair := kv3.KeyPair()
+	defer keypair.Zeroize()
+	if keypair.Address != addr {
+		return i18n.NewError(ctx, signermsgs.MsgChangePasswordVerifyFailed, filename, keypair.Address)
+	}
+	return nil
+}
+
+// writeTempFile writes data to a new private file in the same directory as the file it will replace
+func writeTempFile(ctx context.Context, filename string, data []byte) (string, error) {
+	var f *os.File
+	err := os.MkdirAll(path.Dir(filename), 0700)
+	if err == nil {
+		f, err = os.CreateTemp(path.Dir(filename), "."+path.Base(filename)+".*.tmp")
+	}
+	if err == nil {
+		_, err = f.Write(data)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			_ = os.Remove(f.Name())
+		}
+	}
+	if err != nil {
+		return "", i18n.WrapError(ctx, err, signermsgs.MsgWriteWalletFileFailed, filename)
+	}
+	return f.Name(), nil
+}
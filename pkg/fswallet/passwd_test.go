@@ -0,0 +1,360 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fswallet
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/keystorev3"
+	"github.com/hyperledger/firefly-signer/pkg/secp256k1"
+	"github.com/stretchr/testify/assert"
+)
+
+const testAddr = "0x1f185718734552d08278aa70f804580bab5fd2b4"
+
+func newPassword(password string) *ChangePasswordOptions {
+	return &ChangePasswordOptions{
+		NewPassword: func() ([]byte, error) {
+			return []byte(password), nil
+		},
+		LightScrypt: true,
+	}
+}
+
+func TestChangePasswordFilenames(t *testing.T) {
+	ctx := context.Background()
+	conf := newTestCreateKeyConfig(t, map[string]interface{}{
+		ConfigFilenamesPrimaryExt:        ".key.json",
+		ConfigFilenamesPasswordExt:       ".pwd",
+		ConfigFilenamesPasswordTrimSpace: true,
+		ConfigFilenamesShardPrefixLength: 2,
+	})
+	addr, err := CreateKey(ctx, conf, testPassword("correcthorsebatterystaple"))
+	assert.NoError(t, err)
+
+	err = ChangePassword(ctx, conf, *addr, newPassword(" tr0ub4dor&3\n"))
+	assert.NoError(t, err)
+
+	dir := path.Join(conf.Path, addr.String()[2:4])
+	password, err := os.ReadFile(path.Join(dir, addr.String()[2:]+".pwd"))
+	assert.NoError(t, err)
+	assert.Equal(t, "tr0ub4dor&3", string(password))
+	b, err := os.ReadFile(path.Join(dir, addr.String()[2:]+".key.json"))
+	assert.NoError(t, err)
+	kv3, err := keystorev3.ReadWalletFile(b, password)
+	assert.NoError(t, err)
+	assert.Equal(t, addr.String()[2:], kv3.Metadata()["address"])
+	checkCreatedKey(t, conf, addr)
+
+	// No temporary files are left behind
+	files, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, files, 2)
+}
+
+func TestChangePasswordTOMLMetadata(t *testing.T) {
+	ctx := context.Background()
+	conf := newTestCreateKeyConfig(t, map[string]interface{}{
+		ConfigFilenamesPrimaryExt:          ".toml",
+		ConfigMetadataKeyFileProperty:      `{{ index .signing "key-file" }}`,
+		ConfigMetadataPasswordFileProperty: `{{ index .signing "password-file" }}`,
+	})
+	addr, err := CreateKey(ctx, conf, testPassword("correcthorsebatterystaple"))
+	assert.NoError(t, err)
+
+	options := newPassword("tr0ub4dor&3")
+	options.LightScrypt = false
+	options.OldPassword = func() ([]byte, error) {
+		return []byte("correcthorsebatterystaple"), nil
+	}
+	err = ChangePassword(ctx, conf, *addr, options)
+	assert.NoError(t, err)
+
+	password, err := os.ReadFile(path.Join(conf.Path, addr.String()[2:]+".pwd"))
+	assert.NoError(t, err)
+	assert.Equal(t, "tr0ub4dor&3", string(password))
+	checkCreatedKey(t, conf, addr)
+}
+
+func TestChangePasswordRequireUnlock(t *testing.T) {
+	ctx := context.Background()
+	defaultPasswordFile := path.Join(t.TempDir(), "password")
+	err := os.WriteFile(defaultPasswordFile, []byte("correcthorsebatterystaple"), 0600)
+	assert.NoError(t, err)
+	conf := newTestCreateKeyConfig(t, map[string]interface{}{
+		ConfigFilenamesPrimaryExt: ".key.json",
+		ConfigDefaultPasswordFile: defaultPasswordFile,
+		ConfigRequireUnlock:       true,
+	})
+	addr, err := CreateKey(ctx, conf, nil)
+	assert.NoError(t, err)
+
+	err = ChangePassword(ctx, conf, *addr, newPassword("tr0ub4dor&3"))
+	assert.NoError(t, err)
+
+	ff, err := NewFilesystemWallet(ctx, conf)
+	assert.NoError(t, err)
+	defer ff.Close()
+	err = ff.Initialize(ctx)
+	assert.NoError(t, err)
+	err = ff.Unlock(ctx, *addr, []byte("correcthorsebatterystaple"), 0)
+	assert.Regexp(t, "FF22015", err)
+	err = ff.Unlock(ctx, *addr, []byte("tr0ub4dor&3"), 0)
+	assert.NoError(t, err)
+}
+
+func TestChangePasswordDefaultPasswordFile(t *testing.T) {
+	ctx := context.Background()
+	defaultPasswordFile := path.Join(t.TempDir(), "password")
+	err := os.WriteFile(defaultPasswordFile, []byte("correcthorsebatterystaple"), 0600)
+	assert.NoError(t, err)
+	conf := newTestCreateKeyConfig(t, map[string]interface{}{
+		ConfigFilenamesPrimaryExt: ".key.json",
+		ConfigDefaultPasswordFile: defaultPasswordFile,
+	})
+	addr, err := CreateKey(ctx, conf, nil)
+	assert.NoError(t, err)
+
+	err = ChangePassword(ctx, conf, *addr, newPassword("tr0ub4dor&3"))
+	assert.Regexp(t, "FF22208", err)
+}
+
+func TestChangePasswordBadConfig(t *testing.T) {
+	conf := newTestCreateKeyConfig(t, map[string]interface{}{
+		ConfigFilenamesPrimaryMatchRegex: "[",
+	})
+	err := ChangePassword(context.Background(), conf, *ethtypes.MustNewAddress(testAddr), nil)
+	assert.Regexp(t, "FF22056", err)
+}
+
+func TestChangePasswordInitializeFail(t *testing.T) {
+	conf := newTestCreateKeyConfig(t, map[string]interface{}{
+		ConfigPath: path.Join(t.TempDir(), "missing"),
+	})
+	err := ChangePassword(context.Background(), conf, *ethtypes.MustNewAddress(testAddr), nil)
+	assert.Error(t, err)
+}
+
+func TestChangePasswordNotFound(t *testing.T) {
+	conf := newTestCreateKeyConfig(t, map[string]interface{}{})
+	err := ChangePassword(context.Background(), conf, *ethtypes.MustNewAddress(testAddr), nil)
+	assert.Regexp(t, "FF22014", err)
+}
+
+func TestChangePasswordBadMetadata(t *testing.T) {
+	ctx := context.Background()
+	conf := newTestCreateKeyConfig(t, map[string]interface{}{
+		ConfigFilenamesPrimaryExt:     ".toml",
+		ConfigMetadataKeyFileProperty: `{{ .keyFile }}`,
+	})
+	err := os.WriteFile(path.Join(conf.Path, testAddr[2:]+".toml"), []byte("!toml"), 0600)
+	assert.NoError(t, err)
+	err = ChangePassword(ctx, conf, *ethtypes.MustNewAddress(testAddr), nil)
+	assert.Regexp(t, "FF22015", err)
+}
+
+func TestChangePasswordOldPasswordFail(t *testing.T) {
+	ctx := context.Background()
+	conf := newTestCreateKeyConfig(t, map[string]interface{}{
+		ConfigFilenamesPrimaryExt:  ".key.json",
+		ConfigFilenamesPasswordExt: ".pwd",
+	})
+	addr, err := CreateKey(ctx, conf, testPassword("correcthorsebatterystaple"))
+	assert.NoError(t, err)
+
+	options := newPassword("tr0ub4dor&3")
+	options.OldPassword = func() ([]byte, error) {
+		return nil, fmt.Errorf("pop")
+	}
+	err = ChangePassword(ctx, conf, *addr, options)
+	assert.Regexp(t, "pop", err)
+
+	options.OldPassword = func() ([]byte, error) {
+		return []byte("wrong"), nil
+	}
+	err = ChangePassword(ctx, conf, *addr, options)
+	assert.Regexp(t, "FF22015", err)
+}
+
+func TestChangePasswordNewPasswordFail(t *testing.T) {
+	ctx := context.Background()
+	conf := newTestCreateKeyConfig(t, map[string]interface{}{
+		ConfigFilenamesPrimaryExt:  ".key.json",
+		ConfigFilenamesPasswordExt: ".pwd",
+	})
+	addr, err := CreateKey(ctx, conf, testPassword("correcthorsebatterystaple"))
+	assert.NoError(t, err)
+
+	err = ChangePassword(ctx, conf, *addr, &ChangePasswordOptions{
+		NewPassword: func() ([]byte, error) {
+			return nil, fmt.Errorf("pop")
+		},
+	})
+	assert.Regexp(t, "pop", err)
+
+	err = ChangePassword(ctx, conf, *addr, nil)
+	assert.Regexp(t, "FF22195", err)
+	checkCreatedKey(t, conf, addr)
+}
+
+func TestChangePasswordAddressMismatch(t *testing.T) {
+	ctx := context.Background()
+	conf := newTestCreateKeyConfig(t, map[string]interface{}{
+		ConfigFilenamesPrimaryExt:  ".key.json",
+		ConfigFilenamesPasswordExt: ".pwd",
+	})
+	addr1, err := CreateKey(ctx, conf, testPassword("correcthorsebatterystaple"))
+	assert.NoError(t, err)
+	addr2, err := CreateKey(ctx, conf, testPassword("correcthorsebatterystaple"))
+	assert.NoError(t, err)
+	b, err := os.ReadFile(path.Join(conf.Path, addr1.String()[2:]+".key.json"))
+	assert.NoError(t, err)
+	err = os.WriteFile(path.Join(conf.Path, addr2.String()[2:]+".key.json"), b, 0600)
+	assert.NoError(t, err)
+
+	err = ChangePassword(ctx, conf, *addr2, newPassword("tr0ub4dor&3"))
+	assert.Regexp(t, "FF22059", err)
+}
+
+func TestChangePasswordWritePasswordFail(t *testing.T) {
+	ctx := context.Background()
+	conf := newTestCreateKeyConfig(t, map[string]interface{}{
+		ConfigFilenamesPrimaryExt:  ".key.json",
+		ConfigFilenamesPasswordExt: ".pwd",
+	})
+	addr, err := CreateKey(ctx, conf, testPassword("correcthorsebatterystaple"))
+	assert.NoError(t, err)
+
+	// The password path cannot be created, as it is under a file
+	passwordPathFile := path.Join(t.TempDir(), "file")
+	err = os.WriteFile(passwordPathFile, []byte{}, 0600)
+	assert.NoError(t, err)
+	conf.Filenames.PasswordPath = path.Join(passwordPathFile, "passwords")
+	options := newPassword("tr0ub4dor&3")
+	options.OldPassword = func() ([]byte, error) {
+		return []byte("correcthorsebatterystaple"), nil
+	}
+	err = ChangePassword(ctx, conf, *addr, options)
+	assert.Regexp(t, "FF22191", err)
+}
+
+func TestChangePasswordRenamePasswordFailRestoresKey(t *testing.T) {
+	ctx := context.Background()
+	conf := newTestCreateKeyConfig(t, map[string]interface{}{
+		ConfigFilenamesPrimaryExt:  ".key.json",
+		ConfigFilenamesPasswordExt: ".pwd",
+	})
+	addr, err := CreateKey(ctx, conf, testPassword("correcthorsebatterystaple"))
+	assert.NoError(t, err)
+	keyFile := path.Join(conf.Path, addr.String()[2:]+".key.json")
+	originalKey, err := os.ReadFile(keyFile)
+	assert.NoError(t, err)
+
+	// A non-empty directory cannot be replaced by the new password file
+	passwordFile := path.Join(conf.Path, addr.String()[2:]+".pwd")
+	err = os.Remove(passwordFile)
+	assert.NoError(t, err)
+	err = os.MkdirAll(path.Join(passwordFile, "dir"), 0700)
+	assert.NoError(t, err)
+	options := newPassword("tr0ub4dor&3")
+	options.OldPassword = func() ([]byte, error) {
+		return []byte("correcthorsebatterystaple"), nil
+	}
+	err = ChangePassword(ctx, conf, *addr, options)
+	assert.Regexp(t, "FF22191", err)
+
+	restoredKey, err := os.ReadFile(keyFile)
+	assert.NoError(t, err)
+	assert.Equal(t, originalKey, restoredKey)
+}
+
+func TestChangeKeystoreFilePassword(t *testing.T) {
+	ctx := context.Background()
+	keypair, err := secp256k1.GenerateSecp256k1KeyPair()
+	assert.NoError(t, err)
+	kv3 := keystorev3.NewWalletFileLight("correcthorsebatterystaple", keypair)
+	kv3.Metadata()["label"] = "test"
+	filename := path.Join(t.TempDir(), "key.json")
+	err = os.WriteFile(filename, kv3.JSON(), 0600)
+	assert.NoError(t, err)
+
+	addr, err := ChangeKeystoreFilePassword(ctx, filename, []byte("correcthorsebatterystaple"), []byte("tr0ub4dor&3"), true)
+	assert.NoError(t, err)
+	assert.Equal(t, keypair.Address, *addr)
+
+	b, err := os.ReadFile(filename)
+	assert.NoError(t, err)
+	kv3, err = keystorev3.ReadWalletFile(b, []byte("tr0ub4dor&3"))
+	assert.NoError(t, err)
+	assert.Equal(t, keypair.Address, kv3.KeyPair().Address)
+	assert.Equal(t, "test", kv3.Metadata()["label"])
+}
+
+func TestChangeKeystoreFilePasswordFail(t *testing.T) {
+	ctx := context.Background()
+	keypair, err := secp256k1.GenerateSecp256k1KeyPair()
+	assert.NoError(t, err)
+	filename := path.Join(t.TempDir(), "key.json")
+	err = os.WriteFile(filename, keystorev3.NewWalletFileLight("correcthorsebatterystaple", keypair).JSON(), 0600)
+	assert.NoError(t, err)
+
+	_, err = ChangeKeystoreFilePassword(ctx, filename, []byte("correcthorsebatterystaple"), nil, true)
+	assert.Regexp(t, "FF22195", err)
+
+	_, err = ChangeKeystoreFilePassword(ctx, path.Join(t.TempDir(), "missing"), []byte("correcthorsebatterystaple"), []byte("tr0ub4dor&3"), true)
+	assert.Regexp(t, "FF22199", err)
+
+	_, err = ChangeKeystoreFilePassword(ctx, filename, []byte("wrong"), []byte("tr0ub4dor&3"), true)
+	assert.Regexp(t, "FF22210", err)
+}
+
+func TestVerifyKeyFileFail(t *testing.T) {
+	ctx := context.Background()
+	keypair, err := secp256k1.GenerateSecp256k1KeyPair()
+	assert.NoError(t, err)
+	filename := path.Join(t.TempDir(), "key.json")
+	err = os.WriteFile(filename, keystorev3.NewWalletFileLight("correcthorsebatterystaple", keypair).JSON(), 0600)
+	assert.NoError(t, err)
+
+	err = verifyKeyFile(ctx, filename, []byte("wrong"), keypair.Address)
+	assert.Regexp(t, "FF22209", err)
+
+	err = verifyKeyFile(ctx, filename, []byte("correcthorsebatterystaple"), ethtypes.Address0xHex{})
+	assert.Regexp(t, "FF22209", err)
+}
+
+func TestReplaceKeyFileRenameFail(t *testing.T) {
+	keypair, err := secp256k1.GenerateSecp256k1KeyPair()
+	assert.NoError(t, err)
+	kv3 := keystorev3.NewWalletFileLight("correcthorsebatterystaple", keypair)
+	keyFile := path.Join(t.TempDir(), "key.json")
+	err = os.MkdirAll(path.Join(keyFile, "dir"), 0700)
+	assert.NoError(t, err)
+
+	err = replaceKeyFile(context.Background(), keyFile, kv3, []byte("tr0ub4dor&3"), true)
+	assert.Regexp(t, "FF22191", err)
+
+	// The temporary file is removed
+	files, err := os.ReadDir(path.Dir(keyFile))
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+}
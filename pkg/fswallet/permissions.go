@@ -0,0 +1,44 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fswallet
+
+import (
+	"context"
+
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+)
+
+// checkFilePermissions returns an error if other users can access a file holding key material, or it is owned by
+// another user - checking the mode bits and owner on POSIX systems, and the access control list on Windows
+func checkFilePermissions(ctx context.Context, filename string) error {
+	reason, err := insecurePermissions(filename)
+	if err != nil {
+		return i18n.NewError(ctx, signermsgs.MsgFilePermissionsCheckFailed, filename, err)
+	}
+	if reason != "" {
+		return i18n.NewError(ctx, signermsgs.MsgFileInsecure, filename, reason)
+	}
+	return nil
+}
+
+func (w *fsWallet) checkFilePermissions(ctx context.Context, filename string) error {
+	if w.conf.AllowInsecurePermissions {
+		return nil
+	}
+	return checkFilePermissions(ctx, filename)
+}
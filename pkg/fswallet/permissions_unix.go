@@ -0,0 +1,48 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !windows
+
+package fswallet
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+var currentUID = os.Geteuid
+
+// insecurePermissions returns why a file is insecure, if other users can access it or it is owned by a user
+// other than the current user or root. Group read access is allowed, so keys can be shared with a group
+// such as the fsGroup of a Kubernetes pod.
+func insecurePermissions(filename string) (string, error) {
+	fi, err := os.Stat(filename)
+	if err != nil {
+		return "", err
+	}
+	mode := fi.Mode().Perm()
+	switch {
+	case mode&0o007 != 0:
+		return fmt.Sprintf("its mode %s allows access by other users", mode), nil
+	case mode&0o020 != 0:
+		return fmt.Sprintf("its mode %s allows its group to write to it", mode), nil
+	}
+	if stat, ok := fi.Sys().(*syscall.Stat_t); ok && stat.Uid != 0 && int(stat.Uid) != currentUID() {
+		return fmt.Sprintf("it is owned by another user (uid %d)", stat.Uid), nil
+	}
+	return "", nil
+}
@@ -0,0 +1,154 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !windows
+
+package fswallet
+
+import (
+	"context"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestPermissionsWallet(t *testing.T, allowInsecure bool) (context.Context, *fsWallet) {
+	conf := newTestShardedConfig(t, 0)
+	conf.AllowInsecurePermissions = allowInsecure
+	copyTestKeyFiles(t, conf.Path)
+	ctx := context.Background()
+	ff, err := NewFilesystemWallet(ctx, conf)
+	assert.NoError(t, err)
+	t.Cleanup(func() { _ = ff.Close() })
+	err = ff.Initialize(ctx)
+	assert.NoError(t, err)
+	return ctx, ff.(*fsWallet)
+}
+
+func TestLoadRefusesInsecureKeyFile(t *testing.T) {
+	ctx, f := newTestPermissionsWallet(t, false)
+	err := os.Chmod(path.Join(f.conf.Path, testShardAddr+".key.json"), 0644)
+	assert.NoError(t, err)
+
+	_, err = f.GetWalletFile(ctx, *ethtypes.MustNewAddress(testShardAddr))
+	assert.Regexp(t, "FF22015", err)
+}
+
+func TestLoadRefusesInsecurePasswordFile(t *testing.T) {
+	ctx, f := newTestPermissionsWallet(t, false)
+	err := os.Chmod(path.Join(f.conf.Path, testShardAddr+".pwd"), 0620)
+	assert.NoError(t, err)
+
+	_, err = f.GetWalletFile(ctx, *ethtypes.MustNewAddress(testShardAddr))
+	assert.Regexp(t, "FF22015", err)
+}
+
+func TestLoadRefusesInsecureDefaultPasswordFile(t *testing.T) {
+	ctx, f := newTestPermissionsWallet(t, false)
+	f.conf.DefaultPasswordFile = path.Join(f.conf.Path, testShardAddr+".pwd")
+	f.conf.Filenames.PasswordExt = ".missing"
+	err := os.Chmod(f.conf.DefaultPasswordFile, 0604)
+	assert.NoError(t, err)
+
+	_, err = f.GetWalletFile(ctx, *ethtypes.MustNewAddress(testShardAddr))
+	assert.Regexp(t, "FF22015", err)
+}
+
+func TestLoadAllowsGroupReadable(t *testing.T) {
+	ctx, f := newTestPermissionsWallet(t, false)
+	for _, ext := range []string{".key.json", ".pwd"} {
+		err := os.Chmod(path.Join(f.conf.Path, testShardAddr+ext), 0640)
+		assert.NoError(t, err)
+	}
+
+	_, err := f.GetWalletFile(ctx, *ethtypes.MustNewAddress(testShardAddr))
+	assert.NoError(t, err)
+}
+
+func TestLoadAllowInsecurePermissions(t *testing.T) {
+	ctx, f := newTestPermissionsWallet(t, true)
+	for _, ext := range []string{".key.json", ".pwd"} {
+		err := os.Chmod(path.Join(f.conf.Path, testShardAddr+ext), 0666)
+		assert.NoError(t, err)
+	}
+
+	_, err := f.GetWalletFile(ctx, *ethtypes.MustNewAddress(testShardAddr))
+	assert.NoError(t, err)
+}
+
+func TestCheckFilePermissions(t *testing.T) {
+	ctx := context.Background()
+	filename := path.Join(t.TempDir(), "key")
+	err := os.WriteFile(filename, []byte("key"), 0600)
+	assert.NoError(t, err)
+	assert.NoError(t, checkFilePermissions(ctx, filename))
+
+	for mode, reason := range map[os.FileMode]string{
+		0604: "allows access by other users",
+		0601: "allows access by other users",
+		0620: "allows its group to write",
+	} {
+		err = os.Chmod(filename, mode)
+		assert.NoError(t, err)
+		assert.Regexp(t, "FF22304.*"+reason, checkFilePermissions(ctx, filename), mode)
+	}
+
+	err = checkFilePermissions(ctx, filename+".missing")
+	assert.Regexp(t, "FF22305", err)
+}
+
+func TestCheckFilePermissionsOwner(t *testing.T) {
+	ctx := context.Background()
+	filename := path.Join(t.TempDir(), "key")
+	err := os.WriteFile(filename, []byte("key"), 0600)
+	assert.NoError(t, err)
+
+	if os.Geteuid() == 0 {
+		// root owned files are always trusted, so give the file to another user
+		err = os.Chown(filename, 12345, 12345)
+		assert.NoError(t, err)
+	} else {
+		defer func() { currentUID = os.Geteuid }()
+		currentUID = func() int { return os.Geteuid() + 1 }
+	}
+	assert.Regexp(t, "FF22304.*owned by another user", checkFilePermissions(ctx, filename))
+}
+
+func TestCheckConfigInsecureDefaultPasswordFile(t *testing.T) {
+	passwordFile := path.Join(t.TempDir(), "password")
+	err := os.WriteFile(passwordFile, []byte("pwd"), 0600)
+	assert.NoError(t, err)
+	err = os.Chmod(passwordFile, 0644)
+	assert.NoError(t, err)
+	conf := &Config{
+		Path:                t.TempDir(),
+		DefaultPasswordFile: passwordFile,
+	}
+
+	problems, warnings := CheckConfig(context.Background(), conf)
+	assert.Len(t, problems, 1)
+	assert.Regexp(t, "FF22304", problems[0])
+	assert.Len(t, warnings, 1)
+	assert.Regexp(t, "FF22219", warnings[0])
+
+	conf.AllowInsecurePermissions = true
+	problems, warnings = CheckConfig(context.Background(), conf)
+	assert.Empty(t, problems)
+	assert.Len(t, warnings, 1)
+}
@@ -0,0 +1,100 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build windows
+
+package fswallet
+
+import (
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// The groups that include other users, which must not be granted access to key material
+var broadGroups = []windows.WELL_KNOWN_SID_TYPE{
+	windows.WinWorldSid,
+	windows.WinAuthenticatedUserSid,
+	windows.WinBuiltinUsersSid,
+}
+
+// The accounts other than the current user that can own key material
+var trustedOwners = []windows.WELL_KNOWN_SID_TYPE{
+	windows.WinBuiltinAdministratorsSid,
+	windows.WinLocalSystemSid,
+}
+
+const accessMask = windows.GENERIC_READ | windows.GENERIC_WRITE | windows.GENERIC_ALL |
+	windows.FILE_READ_DATA | windows.FILE_WRITE_DATA | windows.FILE_APPEND_DATA
+
+// insecurePermissions returns why a file is insecure, if its access control list grants access to everyone, to
+// all authenticated users or to all users, or it is owned by an account other than the current user, the
+// administrators or the system.
+func insecurePermissions(filename string) (string, error) {
+	sd, err := windows.GetNamedSecurityInfo(filename, windows.SE_FILE_OBJECT,
+		windows.OWNER_SECURITY_INFORMATION|windows.DACL_SECURITY_INFORMATION)
+	if err != nil {
+		return "", err
+	}
+	owner, _, err := sd.Owner()
+	if err != nil {
+		return "", err
+	}
+	trusted, err := isTrustedOwner(owner)
+	if err != nil {
+		return "", err
+	}
+	if !trusted {
+		return fmt.Sprintf("it is owned by another account (%s)", owner), nil
+	}
+	dacl, _, err := sd.DACL()
+	if err != nil {
+		return "", err
+	}
+	if dacl == nil {
+		return "it has no access control list, so everyone can access it", nil
+	}
+	for i := uint16(0); i < dacl.AceCount; i++ {
+		var ace *windows.ACCESS_ALLOWED_ACE
+		if err := windows.GetAce(dacl, uint32(i), &ace); err != nil {
+			return "", err
+		}
+		if ace.Header.AceType != windows.ACCESS_ALLOWED_ACE_TYPE || ace.Mask&accessMask == 0 {
+			continue
+		}
+		sid := (*windows.SID)(unsafe.Pointer(&ace.SidStart))
+		for _, group := range broadGroups {
+			if sid.IsWellKnown(group) {
+				return fmt.Sprintf("its access control list allows access by %s", sid), nil
+			}
+		}
+	}
+	return "", nil
+}
+
+func isTrustedOwner(owner *windows.SID) (bool, error) {
+	for _, trusted := range trustedOwners {
+		if owner.IsWellKnown(trusted) {
+			return true, nil
+		}
+	}
+	user, err := windows.GetCurrentProcessToken().GetTokenUser()
+	if err != nil {
+		return false, err
+	}
+	return owner.Equals(user.User.Sid), nil
+}
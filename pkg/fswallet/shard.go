@@ -0,0 +1,123 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fswallet
+
+import (
+	"context"
+	"os"
+	"path"
+
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-common/pkg/log"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+)
+
+// maxShardPrefixLength limits sharding to 65536 sub-directories
+const maxShardPrefixLength = 4
+
+// MigrateLayout moves the key/metadata files in an existing wallet directory so that they
+// match the shardPrefixLength in the supplied configuration. This can be used to move from
+// a flat directory to a sharded one, back again, or between different prefix lengths.
+//
+// Password files are moved along with their key files when passwordExt is configured
+// without a separate passwordPath. Any key files referenced from metadata files are not moved.
+//
+// Returns the number of key/metadata files that were moved.
+func MigrateLayout(ctx context.Context, conf *Config) (int, error) {
+	ww, err := NewFilesystemWallet(ctx, conf)
+	if err != nil {
+		return 0, err
+	}
+	w := ww.(*fsWallet)
+
+	dirEntries, err := os.ReadDir(w.conf.Path)
+	if err != nil {
+		return 0, i18n.WrapError(ctx, err, signermsgs.MsgReadDirFile)
+	}
+	// We consider files in the root, and in any existing shard directory of any length
+	sourceDirs := []string{""}
+	for _, de := range dirEntries {
+		if de.IsDir() && isHexPrefix(de.Name()) {
+			sourceDirs = append(sourceDirs, de.Name())
+		}
+	}
+
+	moved := 0
+	for _, dir := range sourceDirs {
+		files, err := os.ReadDir(path.Join(w.conf.Path, dir))
+		if err != nil {
+			return moved, i18n.WrapError(ctx, err, signermsgs.MsgReadDirFile)
+		}
+		for _, f := range files {
+			if f.IsDir() {
+				continue
+			}
+			addr := w.filenameToAddress(ctx, path.Join(w.conf.Path, dir, f.Name()), f.Name())
+			if addr == nil {
+				continue
+			}
+			targetDir := w.shardDir(addr)
+			if targetDir == dir {
+				continue
+			}
+			if err := w.migrateFile(ctx, dir, targetDir, f.Name()); err != nil {
+				return moved, err
+			}
+			if w.conf.Filenames.PasswordExt != "" && w.conf.Filenames.PasswordPath == "" {
+				passwordFilename := w.passwordFilename(*addr)
+				if _, err := os.Stat(path.Join(w.conf.Path, dir, passwordFilename)); err == nil {
+					if err := w.migrateFile(ctx, dir, targetDir, passwordFilename); err != nil {
+						return moved, err
+					}
+				}
+			}
+			moved++
+		}
+		if dir != "" && !w.isShardDir(dir) {
+			// Only succeeds if we've emptied the directory, which is what we want
+			_ = os.Remove(path.Join(w.conf.Path, dir))
+		}
+	}
+	log.L(ctx).Infof("Migrated %d files in %s to shard prefix length %d", moved, w.conf.Path, w.conf.Filenames.ShardPrefixLength)
+	return moved, nil
+}
+
+func (w *fsWallet) migrateFile(ctx context.Context, fromDir, toDir, name string) error {
+	from := path.Join(w.conf.Path, fromDir, name)
+	to := path.Join(w.conf.Path, toDir, name)
+	err := os.MkdirAll(path.Join(w.conf.Path, toDir), 0700)
+	if err == nil {
+		err = os.Rename(from, to)
+	}
+	if err != nil {
+		return i18n.WrapError(ctx, err, signermsgs.MsgMigrateWalletFileFailed, from, to)
+	}
+	log.L(ctx).Debugf("Moved %s -> %s", from, to)
+	return nil
+}
+
+func isHexPrefix(name string) bool {
+	if len(name) == 0 || len(name) > maxShardPrefixLength {
+		return false
+	}
+	for _, c := range name {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,205 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fswallet
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+const testShardAddr = "1f185718734552d08278aa70f804580bab5fd2b4"
+
+func newTestShardedConfig(t *testing.T, shardPrefixLength int) *Config {
+	config.RootConfigReset()
+	logrus.SetLevel(logrus.TraceLevel)
+
+	unitTestConfig := config.RootSection("ut_fs_config")
+	InitConfig(unitTestConfig)
+	unitTestConfig.Set(ConfigPath, t.TempDir())
+	unitTestConfig.Set(ConfigFilenamesPrimaryMatchRegex, "^((0x)?[0-9a-z]+).key.json$")
+	unitTestConfig.Set(ConfigFilenamesPasswordExt, ".pwd")
+	unitTestConfig.Set(ConfigFilenamesShardPrefixLength, shardPrefixLength)
+	unitTestConfig.Set(ConfigDisableListener, true)
+	return ReadConfig(unitTestConfig)
+}
+
+func copyTestKeyFiles(t *testing.T, targetDir string) {
+	err := os.MkdirAll(targetDir, 0700)
+	assert.NoError(t, err)
+	for _, ext := range []string{".key.json", ".pwd"} {
+		b, err := os.ReadFile(path.Join("../../test/keystore_toml", testShardAddr+ext))
+		assert.NoError(t, err)
+		err = os.WriteFile(path.Join(targetDir, testShardAddr+ext), b, 0600)
+		assert.NoError(t, err)
+	}
+}
+
+func TestShardedWalletOK(t *testing.T) {
+	conf := newTestShardedConfig(t, 2)
+	copyTestKeyFiles(t, path.Join(conf.Path, "1f"))
+	// In the wrong shard, so will be ignored
+	copyTestKeyFiles(t, path.Join(conf.Path, "ab"))
+
+	ctx := context.Background()
+	ff, err := NewFilesystemWallet(ctx, conf)
+	assert.NoError(t, err)
+	defer ff.Close()
+	err = ff.Initialize(ctx)
+	assert.NoError(t, err)
+
+	accounts, err := ff.GetAccounts(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, accounts, 1)
+
+	addr := *ethtypes.MustNewAddress(testShardAddr)
+	wf, err := ff.GetWalletFile(ctx, addr)
+	assert.NoError(t, err)
+	assert.Equal(t, addr, wf.KeyPair().Address)
+	assert.Equal(t, "1f/"+testShardAddr+".key.json", ff.(*fsWallet).addressToFileMap[addr])
+}
+
+func TestShardedWalletListener(t *testing.T) {
+	conf := newTestShardedConfig(t, 2)
+	conf.DisableListener = false
+
+	ctx := context.Background()
+	listener := make(chan ethtypes.Address0xHex, 1)
+	ff, err := NewFilesystemWallet(ctx, conf, listener)
+	assert.NoError(t, err)
+	defer ff.Close()
+	err = ff.Initialize(ctx)
+	assert.NoError(t, err)
+
+	copyTestKeyFiles(t, path.Join(conf.Path, "1f"))
+
+	newAddr := <-listener
+	assert.Equal(t, "0x"+testShardAddr, newAddr.String())
+	_, err = ff.GetWalletFile(ctx, newAddr)
+	assert.NoError(t, err)
+}
+
+func TestShardedWalletBadPrefixLength(t *testing.T) {
+	conf := newTestShardedConfig(t, 5)
+	_, err := NewFilesystemWallet(context.Background(), conf)
+	assert.Regexp(t, "FF22092", err)
+}
+
+func TestMigrateLayoutToShardedAndBack(t *testing.T) {
+	conf := newTestShardedConfig(t, 3)
+	copyTestKeyFiles(t, conf.Path)
+	ctx := context.Background()
+
+	moved, err := MigrateLayout(ctx, conf)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, moved)
+	assert.FileExists(t, path.Join(conf.Path, "1f1", testShardAddr+".key.json"))
+	assert.FileExists(t, path.Join(conf.Path, "1f1", testShardAddr+".pwd"))
+
+	// Idempotent
+	moved, err = MigrateLayout(ctx, conf)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, moved)
+
+	// Change the prefix length
+	conf.Filenames.ShardPrefixLength = 2
+	moved, err = MigrateLayout(ctx, conf)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, moved)
+	assert.FileExists(t, path.Join(conf.Path, "1f", testShardAddr+".key.json"))
+	assert.NoDirExists(t, path.Join(conf.Path, "1f1"))
+
+	// Back to flat
+	conf.Filenames.ShardPrefixLength = 0
+	moved, err = MigrateLayout(ctx, conf)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, moved)
+	assert.FileExists(t, path.Join(conf.Path, testShardAddr+".key.json"))
+	assert.FileExists(t, path.Join(conf.Path, testShardAddr+".pwd"))
+	assert.NoDirExists(t, path.Join(conf.Path, "1f"))
+}
+
+func TestMigrateLayoutBadConfig(t *testing.T) {
+	conf := newTestShardedConfig(t, -1)
+	_, err := MigrateLayout(context.Background(), conf)
+	assert.Regexp(t, "FF22092", err)
+}
+
+func TestMigrateLayoutBadDir(t *testing.T) {
+	conf := newTestShardedConfig(t, 2)
+	conf.Path = path.Join(conf.Path, "missing")
+	_, err := MigrateLayout(context.Background(), conf)
+	assert.Regexp(t, "FF22013", err)
+}
+
+func TestMigrateLayoutMoveFail(t *testing.T) {
+	conf := newTestShardedConfig(t, 2)
+	copyTestKeyFiles(t, conf.Path)
+	// Block creation of the shard directory with a file
+	err := os.WriteFile(path.Join(conf.Path, "1f"), []byte{}, 0600)
+	assert.NoError(t, err)
+	_, err = MigrateLayout(context.Background(), conf)
+	assert.Regexp(t, "FF22093", err)
+}
+
+func TestMigrateLayoutMovePasswordFail(t *testing.T) {
+	conf := newTestShardedConfig(t, 2)
+	copyTestKeyFiles(t, conf.Path)
+	// Block the move of the password file with a directory
+	err := os.MkdirAll(path.Join(conf.Path, "1f", testShardAddr+".pwd", "blocker"), 0700)
+	assert.NoError(t, err)
+	_, err = MigrateLayout(context.Background(), conf)
+	assert.Regexp(t, "FF22093", err)
+}
+
+func TestShardedWalletNewShardDirEventErrors(t *testing.T) {
+	conf := newTestShardedConfig(t, 2)
+	ctx := context.Background()
+	ff, err := NewFilesystemWallet(ctx, conf)
+	assert.NoError(t, err)
+	w := ff.(*fsWallet)
+	w.fsListenerWatch = func(dir string) error {
+		return fmt.Errorf("pop")
+	}
+
+	shardDir := path.Join(conf.Path, "1f")
+	err = os.Mkdir(shardDir, 0700)
+	assert.NoError(t, err)
+	fi, err := os.Stat(shardDir)
+	assert.NoError(t, err)
+	err = os.Remove(shardDir)
+	assert.NoError(t, err)
+
+	// Both the watch and the read of the directory fail, and are logged
+	w.handleFileEvent(ctx, shardDir, fi)
+	assert.Empty(t, w.addressToFileMap)
+}
+
+func TestIsHexPrefix(t *testing.T) {
+	assert.True(t, isHexPrefix("0a1f"))
+	assert.False(t, isHexPrefix(""))
+	assert.False(t, isHexPrefix("0a1f2"))
+	assert.False(t, isHexPrefix("0A"))
+	assert.False(t, isHexPrefix("zz"))
+}
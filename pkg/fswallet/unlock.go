@@ -0,0 +1,147 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fswallet
+
+import (
+	"context"
+	"time"
+
+	"github.com/hyperledger/firefly-common/pkg/log"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/keystorev3"
+	"github.com/hyperledger/firefly-signer/pkg/secp256k1"
+)
+
+type unlockedKey struct {
+	kv3    keystorev3.WalletFile
+	expiry *time.Timer
+}
+
+// Unlock loads the key for an address into memory for the supplied TTL (or the configured
+// unlockTTL if zero). If a password is supplied it is used to decrypt the keystore, instead
+// of the configured password files. Unlocking an already unlocked key resets the TTL.
+func (w *fsWallet) Unlock(ctx context.Context, addr ethtypes.Address0xHex, password []byte, ttl time.Duration) error {
+	kv3, err := w.loadAndCheckWalletFile(ctx, addr, password)
+	if err != nil {
+		return err
+	}
+	if ttl <= 0 {
+		ttl = w.unlockTTL
+	}
+
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	w.lockKeyLocked(addr)
+	uk := &unlockedKey{kv3: kv3}
+	uk.expiry = time.AfterFunc(ttl, func() {
+		w.mux.Lock()
+		defer w.mux.Unlock()
+		// Only expire if we have not been replaced by a subsequent unlock
+		if w.unlockedKeys[addr] == uk {
+			log.L(ctx).Infof("Unlock expired for address: %s", addr)
+			w.lockKeyLocked(addr)
+		}
+	})
+	w.unlockedKeys[addr] = uk
+	log.L(ctx).Infof("Unlocked address %s for %s", addr, ttl)
+	return nil
+}
+
+// Lock removes any unlocked or cached copy of the key for an address from memory, zeroizing
+// the private key. It is not an error to lock an address that is not unlocked.
+func (w *fsWallet) Lock(ctx context.Context, addr ethtypes.Address0xHex) error {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	w.lockKeyLocked(addr)
+	log.L(ctx).Infof("Locked address: %s", addr)
+	return nil
+}
+
+// LockAll locks every key in the wallet
+func (w *fsWallet) LockAll(ctx context.Context) error {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	for addr := range w.unlockedKeys {
+		w.lockKeyLocked(addr)
+	}
+	for addr := range w.addressToFileMap {
+		w.lockKeyLocked(addr)
+	}
+	log.L(ctx).Infof("Locked all addresses")
+	return nil
+}
+
+// lockKeyLocked must be called holding the mutex
+func (w *fsWallet) lockKeyLocked(addr ethtypes.Address0xHex) {
+	if uk := w.unlockedKeys[addr]; uk != nil {
+		uk.expiry.Stop()
+		uk.kv3.Zeroize()
+		delete(w.unlockedKeys, addr)
+	}
+	w.signerCache.Evict(addr)
+}
+
+func (w *fsWallet) getUnlocked(addr ethtypes.Address0xHex) keystorev3.WalletFile {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	if uk := w.unlockedKeys[addr]; uk != nil {
+		return uk.kv3
+	}
+	return nil
+}
+
+// unlockedWalletFile is a copy of the wallet file of an unlocked address, with its own copy of the private key
+// so the caller can zeroize it without zeroizing the key held for the address
+type unlockedWalletFile struct {
+	keystorev3.WalletFile
+	privateKey []byte
+}
+
+func (uw *unlockedWalletFile) PrivateKey() []byte {
+	return uw.privateKey
+}
+
+func (uw *unlockedWalletFile) KeyPair() *secp256k1.KeyPair {
+	return secp256k1.KeyPairFromBytes(uw.privateKey)
+}
+
+func (uw *unlockedWalletFile) Zeroize() {
+	secp256k1.ZeroizeBytes(uw.privateKey)
+}
+
+// getUnlockedCopy returns a copy of the wallet file of an unlocked address, which the caller must zeroize
+func (w *fsWallet) getUnlockedCopy(addr ethtypes.Address0xHex) keystorev3.WalletFile {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	if uk := w.unlockedKeys[addr]; uk != nil {
+		return &unlockedWalletFile{
+			WalletFile: uk.kv3,
+			privateKey: append([]byte{}, uk.kv3.PrivateKey()...),
+		}
+	}
+	return nil
+}
+
+// getUnlockedKeyPair returns a copy of the key pair of an unlocked address, which the caller must zeroize
+func (w *fsWallet) getUnlockedKeyPair(addr ethtypes.Address0xHex) *secp256k1.KeyPair {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	if uk := w.unlockedKeys[addr]; uk != nil {
+		return uk.kv3.KeyPair()
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fswallet
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequireUnlockWithPassword(t *testing.T) {
+
+	ctx, f, done := newTestRegexpFilenameOnlyWallet(t, true)
+	defer done()
+	f.conf.RequireUnlock = true
+	// Ensure the password comes from the request, not the file
+	f.conf.Filenames.PasswordExt = ".wrong"
+
+	addr := *ethtypes.MustNewAddress("0x1f185718734552d08278aa70f804580bab5fd2b4")
+	_, err := f.GetWalletFile(ctx, addr)
+	assert.Regexp(t, "FF22094", err)
+
+	password, err := os.ReadFile("../../test/keystore_toml/1f185718734552d08278aa70f804580bab5fd2b4.pwd")
+	assert.NoError(t, err)
+	err = f.Unlock(ctx, addr, password, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 5*time.Minute, f.unlockTTL)
+
+	kv3, err := f.GetWalletFile(ctx, addr)
+	assert.NoError(t, err)
+	assert.Equal(t, addr, kv3.KeyPair().Address)
+	assert.Equal(t, f.getUnlocked(addr).JSON(), kv3.JSON())
+
+	// The caller zeroizes its own copy, and the address stays unlocked
+	kv3.Zeroize()
+	assert.Equal(t, make([]byte, 32), kv3.PrivateKey())
+	assert.NotEqual(t, make([]byte, 32), f.getUnlocked(addr).PrivateKey())
+	kv3, err = f.GetWalletFile(ctx, addr)
+	assert.NoError(t, err)
+	assert.Equal(t, addr, kv3.KeyPair().Address)
+
+	err = f.Lock(ctx, addr)
+	assert.NoError(t, err)
+	assert.Nil(t, f.getUnlocked(addr))
+
+	_, err = f.GetWalletFile(ctx, addr)
+	assert.Regexp(t, "FF22094", err)
+
+}
+
+func TestUnlockBadPassword(t *testing.T) {
+
+	ctx, f, done := newTestRegexpFilenameOnlyWallet(t, true)
+	defer done()
+
+	err := f.Unlock(ctx, *ethtypes.MustNewAddress("0x1f185718734552d08278aa70f804580bab5fd2b4"), []byte("wrong"), time.Hour)
+	assert.Regexp(t, "FF22015", err)
+
+}
+
+func TestUnlockNotFound(t *testing.T) {
+
+	ctx, f, done := newTestRegexpFilenameOnlyWallet(t, true)
+	defer done()
+
+	err := f.Unlock(ctx, *ethtypes.MustNewAddress("0xFFFF5718734552d08278aa70f804580bab5fd2b4"), nil, time.Hour)
+	assert.Regexp(t, "FF22014", err)
+
+}
+
+func TestUnlockExpiry(t *testing.T) {
+
+	ctx, f, done := newTestRegexpFilenameOnlyWallet(t, true)
+	defer done()
+
+	addr := *ethtypes.MustNewAddress("0x1f185718734552d08278aa70f804580bab5fd2b4")
+	err := f.Unlock(ctx, addr, nil, time.Hour)
+	assert.NoError(t, err)
+	kv3Replaced := f.getUnlocked(addr)
+
+	// Re-unlocking replaces (and zeroizes) the previous entry
+	err = f.Unlock(ctx, addr, nil, 1*time.Millisecond)
+	assert.NoError(t, err)
+	assert.Equal(t, make([]byte, 32), kv3Replaced.PrivateKey())
+
+	for f.getUnlocked(addr) != nil {
+		time.Sleep(1 * time.Millisecond)
+	}
+
+}
+
+func TestLockAll(t *testing.T) {
+
+	ctx, f, done := newTestRegexpFilenameOnlyWallet(t, true)
+	defer done()
+
+	addr := *ethtypes.MustNewAddress("0x1f185718734552d08278aa70f804580bab5fd2b4")
+	cached, release, err := f.getSignerForAddr(ctx, addr)
+	assert.NoError(t, err)
+	release()
+	err = f.Unlock(ctx, addr, nil, time.Hour)
+	assert.NoError(t, err)
+	unlocked := f.getUnlocked(addr)
+
+	err = f.LockAll(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, make([]byte, 32), cached.PrivateKeyBytes())
+	assert.Equal(t, make([]byte, 32), unlocked.PrivateKey())
+	assert.Nil(t, f.getUnlocked(addr))
+	assert.Equal(t, 0, f.SignerCacheStats().Items)
+
+}
+
+func TestGetPublicKeyRetainedWhenLocked(t *testing.T) {
+
+	ctx, f, done := newTestRegexpFilenameOnlyWallet(t, true)
+	defer done()
+
+	addr := *ethtypes.MustNewAddress("0x1f185718734552d08278aa70f804580bab5fd2b4")
+	pubKey, err := f.GetPublicKey(ctx, addr)
+	assert.NoError(t, err)
+	assert.Equal(t, addr, pubKey.Address)
+	assert.Len(t, pubKey.Compressed, 33)
+
+	err = f.LockAll(ctx)
+	assert.NoError(t, err)
+	f.conf.RequireUnlock = true
+
+	pubKey2, err := f.GetPublicKey(ctx, addr)
+	assert.NoError(t, err)
+	assert.Equal(t, pubKey, pubKey2)
+
+}
+
+func TestGetPublicKeyLocked(t *testing.T) {
+
+	ctx, f, done := newTestRegexpFilenameOnlyWallet(t, true)
+	defer done()
+	f.conf.RequireUnlock = true
+
+	_, err := f.GetPublicKey(ctx, *ethtypes.MustNewAddress("0x1f185718734552d08278aa70f804580bab5fd2b4"))
+	assert.Regexp(t, "FF22094", err)
+
+}
+
+func TestSignWithUnlockedKey(t *testing.T) {
+
+	ctx, f, done := newTestRegexpFilenameOnlyWallet(t, true)
+	defer done()
+	f.conf.RequireUnlock = true
+
+	addr := *ethtypes.MustNewAddress("0x1f185718734552d08278aa70f804580bab5fd2b4")
+	_, _, err := f.getSignerForAddr(ctx, addr)
+	assert.Regexp(t, "FF22094", err)
+
+	err = f.Unlock(ctx, addr, nil, time.Hour)
+	assert.NoError(t, err)
+
+	// Each signer is a copy of the unlocked key, zeroized on release
+	keypair, release, err := f.getSignerForAddr(ctx, addr)
+	assert.NoError(t, err)
+	assert.Equal(t, addr, keypair.Address)
+	release()
+	assert.Equal(t, make([]byte, 32), keypair.PrivateKeyBytes())
+	assert.NotEqual(t, make([]byte, 32), f.getUnlocked(addr).PrivateKey())
+
+	_, err = f.SignPersonalMessage(ctx, addr, []byte("hello"))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, f.SignerCacheStats().Items)
+
+}
@@ -0,0 +1,231 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package gasoracle estimates the fees of transactions from one or more gas price sources - the node itself,
+// or the HTTP APIs of common gas oracles - with fallback between the sources, or a weighted average of them.
+package gasoracle
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"sync"
+
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-common/pkg/log"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+)
+
+// Fees are the current fees of a chain, as reported by a source. All values are in wei. BaseFee is nil on
+// chains without EIP-1559, where only the legacy GasPrice applies.
+type Fees struct {
+	BaseFee              *big.Int
+	MaxPriorityFeePerGas *big.Int
+	GasPrice             *big.Int
+}
+
+// Source is a source of the current fees of a chain
+type Source interface {
+	Name() string
+	Fees(ctx context.Context) (*Fees, error)
+}
+
+// Speed selects how quickly a transaction is expected to be mined, from the tiers of fees oracles report
+type Speed string
+
+const (
+	SpeedSafe     Speed = "safe"
+	SpeedStandard Speed = "standard"
+	SpeedFast     Speed = "fast"
+)
+
+// Mode is how the fees of the sources of an Estimator are combined
+type Mode string
+
+const (
+	// ModeFallback uses the first source, in order, that returns fees
+	ModeFallback Mode = "fallback"
+	// ModeWeighted queries every source, and averages each fee over the sources that return it by their weights
+	ModeWeighted Mode = "weighted"
+)
+
+// DefaultBaseFeeMultiplier allows for the base fee doubling before a transaction is mined
+const DefaultBaseFeeMultiplier = 2
+
+// WeightedSource is a source, with its weight in a weighted average. The weight is ignored in fallback mode
+type WeightedSource struct {
+	Source
+	Weight float64
+}
+
+type EstimatorOptions struct {
+	Sources []*WeightedSource
+	Mode    Mode // defaults to fallback
+	// The maxFeePerGas of an estimate is the base fee multiplied by this, plus the priority fee. Defaults to 2
+	BaseFeeMultiplier float64
+}
+
+// Estimate is the fees to set on a transaction. On chains without EIP-1559 only GasPrice is set, otherwise
+// only MaxFeePerGas and MaxPriorityFeePerGas are set.
+type Estimate struct {
+	GasPrice             *big.Int
+	MaxFeePerGas         *big.Int
+	MaxPriorityFeePerGas *big.Int
+}
+
+// Estimator combines the fees of its sources, and is itself a Source so estimators can be nested
+type Estimator struct {
+	sources           []*WeightedSource
+	mode              Mode
+	baseFeeMultiplier float64
+}
+
+func NewEstimator(ctx context.Context, options *EstimatorOptions) (*Estimator, error) {
+	e := &Estimator{
+		sources:           options.Sources,
+		mode:              options.Mode,
+		baseFeeMultiplier: options.BaseFeeMultiplier,
+	}
+	if e.mode == "" {
+		e.mode = ModeFallback
+	}
+	if e.baseFeeMultiplier == 0 {
+		e.baseFeeMultiplier = DefaultBaseFeeMultiplier
+	}
+	switch {
+	case e.mode != ModeFallback && e.mode != ModeWeighted:
+		return nil, i18n.NewError(ctx, signermsgs.MsgGasOracleUnknownMode, e.mode)
+	case e.baseFeeMultiplier < 1:
+		return nil, i18n.NewError(ctx, signermsgs.MsgBadBaseFeeMultiplier, e.baseFeeMultiplier)
+	case len(e.sources) == 0:
+		return nil, i18n.NewError(ctx, signermsgs.MsgGasOracleNoSources)
+	}
+	for _, s := range e.sources {
+		if s.Weight <= 0 {
+			return nil, i18n.NewError(ctx, signermsgs.MsgGasOracleBadWeight, s.Weight, s.Name())
+		}
+	}
+	return e, nil
+}
+
+func (e *Estimator) Name() string {
+	names := make([]string, len(e.sources))
+	for i, s := range e.sources {
+		names[i] = s.Name()
+	}
+	return string(e.mode) + "(" + strings.Join(names, ",") + ")"
+}
+
+// Fees returns the fees of the first source that succeeds in fallback mode, or the weighted average of the
+// fees of all the sources that succeed in weighted mode. It fails only if every source fails.
+func (e *Estimator) Fees(ctx context.Context) (*Fees, error) {
+	if e.mode == ModeFallback {
+		errs := make([]string, 0, len(e.sources))
+		for _, s := range e.sources {
+			fees, err := s.Fees(ctx)
+			if err == nil {
+				return fees, nil
+			}
+			log.L(ctx).Warnf("Gas price source '%s' failed: %s", s.Name(), err)
+			errs = append(errs, err.Error())
+		}
+		return nil, e.allFailed(ctx, errs)
+	}
+
+	results := make([]*Fees, len(e.sources))
+	errs := make([]error, len(e.sources))
+	var wg sync.WaitGroup
+	for i, s := range e.sources {
+		wg.Add(1)
+		go func(i int, s Source) {
+			defer wg.Done()
+			results[i], errs[i] = s.Fees(ctx)
+		}(i, s.Source)
+	}
+	wg.Wait()
+
+	var baseFee, priorityFee, gasPrice weightedAverage
+	failures := make([]string, 0, len(e.sources))
+	for i, s := range e.sources {
+		if errs[i] != nil {
+			log.L(ctx).Warnf("Gas price source '%s' failed: %s", s.Name(), errs[i])
+			failures = append(failures, errs[i].Error())
+			continue
+		}
+		baseFee.add(results[i].BaseFee, s.Weight)
+		priorityFee.add(results[i].MaxPriorityFeePerGas, s.Weight)
+		gasPrice.add(results[i].GasPrice, s.Weight)
+	}
+	if len(failures) == len(e.sources) {
+		return nil, e.allFailed(ctx, failures)
+	}
+	return &Fees{
+		BaseFee:              baseFee.value(),
+		MaxPriorityFeePerGas: priorityFee.value(),
+		GasPrice:             gasPrice.value(),
+	}, nil
+}
+
+func (e *Estimator) allFailed(ctx context.Context, errs []string) error {
+	return i18n.NewError(ctx, signermsgs.MsgGasOracleAllFailed, strings.Join(errs, "; "))
+}
+
+// Estimate returns the fees to set on a transaction, from the current fees of the sources
+func (e *Estimator) Estimate(ctx context.Context) (*Estimate, error) {
+	fees, err := e.Fees(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if fees.BaseFee == nil || fees.BaseFee.Sign() == 0 {
+		return &Estimate{GasPrice: fees.GasPrice}, nil
+	}
+	priorityFee := fees.MaxPriorityFeePerGas
+	if priorityFee == nil {
+		priorityFee = new(big.Int)
+	}
+	return &Estimate{
+		MaxFeePerGas:         e.MaxFeePerGas(fees.BaseFee, priorityFee),
+		MaxPriorityFeePerGas: priorityFee,
+	}, nil
+}
+
+// MaxFeePerGas is the base fee multiplied by the base fee multiplier, plus the priority fee, which allows for
+// the base fee rising before the transaction is mined. Only the fees actually charged are paid.
+func (e *Estimator) MaxFeePerGas(baseFee, priorityFee *big.Int) *big.Int {
+	maxFee, _ := new(big.Float).Mul(new(big.Float).SetInt(baseFee), big.NewFloat(e.baseFeeMultiplier)).Int(nil)
+	return maxFee.Add(maxFee, priorityFee)
+}
+
+type weightedAverage struct {
+	total       big.Float
+	totalWeight float64
+}
+
+func (wa *weightedAverage) add(v *big.Int, weight float64) {
+	if v != nil {
+		wa.total.Add(&wa.total, new(big.Float).Mul(new(big.Float).SetInt(v), big.NewFloat(weight)))
+		wa.totalWeight += weight
+	}
+}
+
+// value is nil if no source returned the fee
+func (wa *weightedAverage) value() *big.Int {
+	if wa.totalWeight == 0 {
+		return nil
+	}
+	v, _ := new(big.Float).Quo(&wa.total, big.NewFloat(wa.totalWeight)).Int(nil)
+	return v
+}
@@ -0,0 +1,140 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gasoracle
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testSource struct {
+	name string
+	fees *Fees
+	err  error
+}
+
+func (ts *testSource) Name() string {
+	return ts.name
+}
+
+func (ts *testSource) Fees(ctx context.Context) (*Fees, error) {
+	return ts.fees, ts.err
+}
+
+func testFees(baseFee, priorityFee, gasPrice int64) *Fees {
+	fees := &Fees{GasPrice: big.NewInt(gasPrice)}
+	if baseFee > 0 {
+		fees.BaseFee = big.NewInt(baseFee)
+		fees.MaxPriorityFeePerGas = big.NewInt(priorityFee)
+	}
+	return fees
+}
+
+func newTestEstimator(t *testing.T, mode Mode, sources ...*WeightedSource) *Estimator {
+	e, err := NewEstimator(context.Background(), &EstimatorOptions{Sources: sources, Mode: mode})
+	assert.NoError(t, err)
+	return e
+}
+
+func TestEstimatorFallback(t *testing.T) {
+	e := newTestEstimator(t, "",
+		&WeightedSource{Source: &testSource{name: "s1", err: fmt.Errorf("pop")}, Weight: 1},
+		&WeightedSource{Source: &testSource{name: "s2", fees: testFees(100, 10, 110)}, Weight: 1},
+		&WeightedSource{Source: &testSource{name: "s3", err: fmt.Errorf("not called")}, Weight: 1},
+	)
+	assert.Equal(t, "fallback(s1,s2,s3)", e.Name())
+
+	fees, err := e.Fees(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(100), fees.BaseFee.Int64())
+
+	estimate, err := e.Estimate(context.Background())
+	assert.NoError(t, err)
+	assert.Nil(t, estimate.GasPrice)
+	assert.Equal(t, int64(210), estimate.MaxFeePerGas.Int64())
+	assert.Equal(t, int64(10), estimate.MaxPriorityFeePerGas.Int64())
+}
+
+func TestEstimatorFallbackAllFailed(t *testing.T) {
+	e := newTestEstimator(t, ModeFallback,
+		&WeightedSource{Source: &testSource{name: "s1", err: fmt.Errorf("pop1")}, Weight: 1},
+		&WeightedSource{Source: &testSource{name: "s2", err: fmt.Errorf("pop2")}, Weight: 1},
+	)
+	_, err := e.Estimate(context.Background())
+	assert.Regexp(t, "FF22266.*pop1; pop2", err)
+}
+
+func TestEstimatorWeighted(t *testing.T) {
+	e := newTestEstimator(t, ModeWeighted,
+		&WeightedSource{Source: &testSource{name: "s1", fees: testFees(100, 10, 110)}, Weight: 1},
+		&WeightedSource{Source: &testSource{name: "s2", fees: testFees(200, 30, 230)}, Weight: 3},
+		&WeightedSource{Source: &testSource{name: "s3", fees: testFees(0, 0, 1000)}, Weight: 4},
+		&WeightedSource{Source: &testSource{name: "s4", err: fmt.Errorf("pop")}, Weight: 1},
+	)
+	fees, err := e.Fees(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(175), fees.BaseFee.Int64())
+	assert.Equal(t, int64(25), fees.MaxPriorityFeePerGas.Int64())
+	assert.Equal(t, int64(600), fees.GasPrice.Int64())
+}
+
+func TestEstimatorWeightedAllFailed(t *testing.T) {
+	e := newTestEstimator(t, ModeWeighted,
+		&WeightedSource{Source: &testSource{name: "s1", err: fmt.Errorf("pop")}, Weight: 1},
+	)
+	_, err := e.Fees(context.Background())
+	assert.Regexp(t, "FF22266.*pop", err)
+}
+
+func TestEstimateLegacy(t *testing.T) {
+	e := newTestEstimator(t, ModeWeighted,
+		&WeightedSource{Source: &testSource{name: "s1", fees: testFees(0, 0, 1000)}, Weight: 1},
+	)
+	estimate, err := e.Estimate(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1000), estimate.GasPrice.Int64())
+	assert.Nil(t, estimate.MaxFeePerGas)
+}
+
+func TestEstimateNoPriorityFee(t *testing.T) {
+	e, err := NewEstimator(context.Background(), &EstimatorOptions{
+		Sources:           []*WeightedSource{{Source: &testSource{name: "s1", fees: &Fees{BaseFee: big.NewInt(100)}}, Weight: 1}},
+		BaseFeeMultiplier: 1.5,
+	})
+	assert.NoError(t, err)
+	estimate, err := e.Estimate(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(150), estimate.MaxFeePerGas.Int64())
+	assert.Equal(t, int64(0), estimate.MaxPriorityFeePerGas.Int64())
+}
+
+func TestNewEstimatorBadOptions(t *testing.T) {
+	s := &WeightedSource{Source: &testSource{name: "s1"}, Weight: 1}
+	for errCode, options := range map[string]*EstimatorOptions{
+		"FF22269":        {Sources: []*WeightedSource{s}, Mode: "wrong"},
+		"FF22133":        {Sources: []*WeightedSource{s}, BaseFeeMultiplier: 0.5},
+		"FF22267":        {},
+		"FF22268.*'bad'": {Sources: []*WeightedSource{{Source: &testSource{name: "bad"}}}},
+	} {
+		_, err := NewEstimator(context.Background(), options)
+		assert.Regexp(t, errCode, err)
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gasoracle
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+)
+
+// NodeStrategy is how the node source chooses the priority fee
+type NodeStrategy string
+
+const (
+	// NodeStrategyFeeHistory averages a percentile of the priority fees paid in recent blocks, from eth_feeHistory
+	NodeStrategyFeeHistory NodeStrategy = "feeHistory"
+	// NodeStrategyMaxPriorityFeePerGas uses the suggestion of the node, from eth_maxPriorityFeePerGas
+	NodeStrategyMaxPriorityFeePerGas NodeStrategy = "maxPriorityFeePerGas"
+)
+
+type NodeOptions struct {
+	Strategy          NodeStrategy // defaults to feeHistory
+	HistoryBlocks     int          // defaults to 10
+	HistoryPercentile float64      // the percentile (0-100) of the priority fees paid in each block
+}
+
+type nodeSource struct {
+	eth     *rpcbackend.EthClient
+	options NodeOptions
+}
+
+// NewNodeSource returns a source that estimates the fees from the node, with the base fee of the next block
+// from eth_feeHistory and the priority fee chosen by the strategy. Chains without EIP-1559 have no base fee,
+// so eth_gasPrice is used instead.
+func NewNodeSource(ctx context.Context, rpc rpcbackend.RPC, options NodeOptions) (Source, error) {
+	if options.Strategy == "" {
+		options.Strategy = NodeStrategyFeeHistory
+	}
+	if options.HistoryBlocks == 0 {
+		options.HistoryBlocks = 10
+	}
+	if options.Strategy != NodeStrategyFeeHistory && options.Strategy != NodeStrategyMaxPriorityFeePerGas {
+		return nil, i18n.NewError(ctx, signermsgs.MsgUnknownFeeStrategy, options.Strategy)
+	}
+	if options.HistoryPercentile < 0 || options.HistoryPercentile > 100 {
+		return nil, i18n.NewError(ctx, signermsgs.MsgBadFeeHistoryPercentile, options.HistoryPercentile)
+	}
+	return &nodeSource{eth: rpcbackend.NewEthClient(rpc), options: options}, nil
+}
+
+func (ns *nodeSource) Name() string {
+	return "node"
+}
+
+func (ns *nodeSource) Fees(ctx context.Context) (*Fees, error) {
+	blocks, percentiles := 1, []float64{}
+	if ns.options.Strategy == NodeStrategyFeeHistory {
+		blocks, percentiles = ns.options.HistoryBlocks, []float64{ns.options.HistoryPercentile}
+	}
+	history, rpcErr := ns.eth.FeeHistory(ctx, uint64(blocks), "latest", percentiles)
+	if rpcErr != nil {
+		return nil, rpcErr.Error()
+	}
+	var baseFee *big.Int
+	if len(history.BaseFeePerGas) > 0 {
+		baseFee = history.BaseFeePerGas[len(history.BaseFeePerGas)-1].BigInt()
+	}
+
+	if baseFee == nil || baseFee.Sign() == 0 {
+		var gasPrice ethtypes.HexInteger
+		if rpcErr := ns.eth.CallRPC(ctx, &gasPrice, "eth_gasPrice"); rpcErr != nil {
+			return nil, rpcErr.Error()
+		}
+		return &Fees{GasPrice: gasPrice.BigInt()}, nil
+	}
+
+	priorityFee := new(big.Int)
+	if ns.options.Strategy == NodeStrategyFeeHistory {
+		priorityFee = averageReward(history.Reward)
+	} else if rpcErr := ns.eth.CallRPC(ctx, (*ethtypes.HexInteger)(priorityFee), "eth_maxPriorityFeePerGas"); rpcErr != nil {
+		return nil, rpcErr.Error()
+	}
+	return &Fees{
+		BaseFee:              baseFee,
+		MaxPriorityFeePerGas: priorityFee,
+		GasPrice:             new(big.Int).Add(baseFee, priorityFee),
+	}, nil
+}
+
+// averageReward is the mean of the priority fees at the requested percentile, over the blocks of the fee history
+func averageReward(reward [][]*ethtypes.HexInteger) *big.Int {
+	total, count := new(big.Int), int64(0)
+	for _, blockReward := range reward {
+		if len(blockReward) > 0 && blockReward[0] != nil {
+			total.Add(total, blockReward[0].BigInt())
+			count++
+		}
+	}
+	if count == 0 {
+		return total
+	}
+	return total.Div(total, big.NewInt(count))
+}
@@ -0,0 +1,118 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gasoracle
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/hyperledger/firefly-signer/mocks/rpcbackendmocks"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newTestNodeSource(t *testing.T, options NodeOptions) (Source, *rpcbackendmocks.Backend) {
+	bm := &rpcbackendmocks.Backend{}
+	ns, err := NewNodeSource(context.Background(), bm, options)
+	assert.NoError(t, err)
+	assert.Equal(t, "node", ns.Name())
+	return ns, bm
+}
+
+func mockFeeHistory(bm *rpcbackendmocks.Backend, blocks uint64, percentiles []float64, result string) {
+	bm.On("CallRPC", mock.Anything, mock.Anything, "eth_feeHistory", ethtypes.HexUint64(blocks), "latest", percentiles).Run(func(args mock.Arguments) {
+		err := json.Unmarshal([]byte(result), args[1])
+		if err != nil {
+			panic(err)
+		}
+	}).Return(nil).Once()
+}
+
+func mockHexIntegerRPC(bm *rpcbackendmocks.Backend, method string, value uint64) {
+	bm.On("CallRPC", mock.Anything, mock.Anything, method).Run(func(args mock.Arguments) {
+		*(args[1].(*ethtypes.HexInteger)) = *ethtypes.NewHexIntegerU64(value)
+	}).Return(nil).Once()
+}
+
+func TestNodeFeeHistory(t *testing.T) {
+	ns, bm := newTestNodeSource(t, NodeOptions{HistoryPercentile: 50})
+	mockFeeHistory(bm, 10, []float64{50}, `{
+		"baseFeePerGas": ["0x64", "0xc8"],
+		"reward": [["0xa"], ["0x14"], []]
+	}`)
+	fees, err := ns.Fees(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(200), fees.BaseFee.Int64())
+	assert.Equal(t, int64(15), fees.MaxPriorityFeePerGas.Int64())
+	assert.Equal(t, int64(215), fees.GasPrice.Int64())
+	bm.AssertExpectations(t)
+}
+
+func TestNodeMaxPriorityFeePerGas(t *testing.T) {
+	ns, bm := newTestNodeSource(t, NodeOptions{Strategy: NodeStrategyMaxPriorityFeePerGas})
+	mockFeeHistory(bm, 1, []float64{}, `{"baseFeePerGas": ["0x64", "0xc8"]}`)
+	mockHexIntegerRPC(bm, "eth_maxPriorityFeePerGas", 7)
+	fees, err := ns.Fees(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(200), fees.BaseFee.Int64())
+	assert.Equal(t, int64(7), fees.MaxPriorityFeePerGas.Int64())
+	bm.AssertExpectations(t)
+}
+
+func TestNodeLegacyChain(t *testing.T) {
+	ns, bm := newTestNodeSource(t, NodeOptions{HistoryBlocks: 5})
+	mockFeeHistory(bm, 5, []float64{0}, `{"baseFeePerGas": [], "reward": []}`)
+	mockHexIntegerRPC(bm, "eth_gasPrice", 1000)
+	fees, err := ns.Fees(context.Background())
+	assert.NoError(t, err)
+	assert.Nil(t, fees.BaseFee)
+	assert.Equal(t, int64(1000), fees.GasPrice.Int64())
+	bm.AssertExpectations(t)
+}
+
+func TestNodeFail(t *testing.T) {
+	ns, bm := newTestNodeSource(t, NodeOptions{Strategy: NodeStrategyMaxPriorityFeePerGas})
+	bm.On("CallRPC", mock.Anything, mock.Anything, "eth_feeHistory", mock.Anything, mock.Anything, mock.Anything).Return(&rpcbackend.RPCError{Message: "pop1"}).Once()
+	_, err := ns.Fees(context.Background())
+	assert.Regexp(t, "pop1", err)
+
+	mockFeeHistory(bm, 1, []float64{}, `{"baseFeePerGas": ["0x0"]}`)
+	bm.On("CallRPC", mock.Anything, mock.Anything, "eth_gasPrice").Return(&rpcbackend.RPCError{Message: "pop2"}).Once()
+	_, err = ns.Fees(context.Background())
+	assert.Regexp(t, "pop2", err)
+
+	mockFeeHistory(bm, 1, []float64{}, `{"baseFeePerGas": ["0x64"]}`)
+	bm.On("CallRPC", mock.Anything, mock.Anything, "eth_maxPriorityFeePerGas").Return(&rpcbackend.RPCError{Message: "pop3"}).Once()
+	_, err = ns.Fees(context.Background())
+	assert.Regexp(t, "pop3", err)
+
+	bm.AssertExpectations(t)
+}
+
+func TestNewNodeSourceBadOptions(t *testing.T) {
+	_, err := NewNodeSource(context.Background(), &rpcbackendmocks.Backend{}, NodeOptions{Strategy: "wrong"})
+	assert.Regexp(t, "FF22131", err)
+	_, err = NewNodeSource(context.Background(), &rpcbackendmocks.Backend{}, NodeOptions{HistoryPercentile: 101})
+	assert.Regexp(t, "FF22132", err)
+}
+
+func TestAverageRewardEmpty(t *testing.T) {
+	assert.Equal(t, int64(0), averageReward(nil).Int64())
+}
@@ -0,0 +1,268 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gasoracle
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+)
+
+// OracleOptions configure the sources that query the HTTP API of a gas oracle
+type OracleOptions struct {
+	Speed  Speed  // defaults to standard
+	APIKey string // sent as the apikey query parameter to Etherscan, and the Authorization header to Blocknative
+}
+
+// weiPerGwei converts the fees oracles report in gwei, which can be fractional
+var weiPerGwei = big.NewRat(1000000000, 1)
+
+type oracleSource struct {
+	name    string
+	client  *resty.Client
+	options OracleOptions
+}
+
+func newOracleSource(ctx context.Context, name string, client *resty.Client, options OracleOptions) (*oracleSource, error) {
+	if options.Speed == "" {
+		options.Speed = SpeedStandard
+	}
+	if options.Speed != SpeedSafe && options.Speed != SpeedStandard && options.Speed != SpeedFast {
+		return nil, i18n.NewError(ctx, signermsgs.MsgGasOracleUnknownSpeed, options.Speed, name)
+	}
+	return &oracleSource{name: name, client: client, options: options}, nil
+}
+
+func (o *oracleSource) Name() string {
+	return o.name
+}
+
+// get requests the base URL of the client, and parses the JSON response
+func (o *oracleSource) get(ctx context.Context, result interface{}, query, headers map[string]string) error {
+	res, err := o.client.R().
+		SetContext(ctx).
+		SetQueryParams(query).
+		SetHeaders(headers).
+		Get("")
+	if err != nil {
+		return i18n.WrapError(ctx, err, signermsgs.MsgGasOracleRequestFailed, o.name, err)
+	}
+	if res.IsError() {
+		return i18n.NewError(ctx, signermsgs.MsgGasOracleRequestFailed, o.name, fmt.Sprintf("HTTP status %d", res.StatusCode()))
+	}
+	if err := json.Unmarshal(res.Body(), result); err != nil {
+		return o.badResponse(ctx, err)
+	}
+	return nil
+}
+
+func (o *oracleSource) badResponse(ctx context.Context, detail interface{}) error {
+	return i18n.NewError(ctx, signermsgs.MsgGasOracleBadResponse, o.name, detail)
+}
+
+// gwei parses a decimal number of gwei, such as "1.5", into wei
+func (o *oracleSource) gwei(ctx context.Context, field, value string) (*big.Int, error) {
+	r, ok := new(big.Rat).SetString(value)
+	if !ok || r.Sign() < 0 {
+		return nil, o.badResponse(ctx, fmt.Sprintf("invalid %s '%s'", field, value))
+	}
+	r.Mul(r, weiPerGwei)
+	return new(big.Int).Quo(r.Num(), r.Denom()), nil
+}
+
+type etherscanSource struct {
+	*oracleSource
+}
+
+type etherscanResponse struct {
+	Status  string          `json:"status"`
+	Message string          `json:"message"`
+	Result  json.RawMessage `json:"result"`
+}
+
+type etherscanGasOracle struct {
+	SafeGasPrice    string `json:"SafeGasPrice"`
+	ProposeGasPrice string `json:"ProposeGasPrice"`
+	FastGasPrice    string `json:"FastGasPrice"`
+	SuggestBaseFee  string `json:"suggestBaseFee"`
+}
+
+// NewEtherscanSource returns a source that queries the gas oracle of the Etherscan API, or any Etherscan
+// compatible explorer, at the base URL of the client (such as https://api.etherscan.io/api). The safe,
+// propose and fast gas prices are the safe, standard and fast speeds, and the priority fee is the gas price
+// less the suggested base fee.
+func NewEtherscanSource(ctx context.Context, client *resty.Client, options OracleOptions) (Source, error) {
+	o, err := newOracleSource(ctx, "etherscan", client, options)
+	if err != nil {
+		return nil, err
+	}
+	return &etherscanSource{oracleSource: o}, nil
+}
+
+func (es *etherscanSource) Fees(ctx context.Context) (*Fees, error) {
+	query := map[string]string{"module": "gastracker", "action": "gasoracle"}
+	if es.options.APIKey != "" {
+		query["apikey"] = es.options.APIKey
+	}
+	var res etherscanResponse
+	if err := es.get(ctx, &res, query, nil); err != nil {
+		return nil, err
+	}
+	var oracle etherscanGasOracle
+	if res.Status != "1" || json.Unmarshal(res.Result, &oracle) != nil {
+		// The result of a failed request is a string describing the error
+		return nil, es.badResponse(ctx, fmt.Sprintf("%s: %s", res.Message, res.Result))
+	}
+	price := map[Speed]string{
+		SpeedSafe:     oracle.SafeGasPrice,
+		SpeedStandard: oracle.ProposeGasPrice,
+		SpeedFast:     oracle.FastGasPrice,
+	}[es.options.Speed]
+	gasPrice, err := es.gwei(ctx, string(es.options.Speed), price)
+	if err != nil {
+		return nil, err
+	}
+	if oracle.SuggestBaseFee == "" {
+		return &Fees{GasPrice: gasPrice}, nil
+	}
+	baseFee, err := es.gwei(ctx, "suggestBaseFee", oracle.SuggestBaseFee)
+	if err != nil {
+		return nil, err
+	}
+	priorityFee := new(big.Int).Sub(gasPrice, baseFee)
+	if priorityFee.Sign() < 0 {
+		priorityFee.SetInt64(0)
+	}
+	return &Fees{BaseFee: baseFee, MaxPriorityFeePerGas: priorityFee, GasPrice: gasPrice}, nil
+}
+
+type blocknativeSource struct {
+	*oracleSource
+	confidence int
+}
+
+type blocknativeResponse struct {
+	BlockPrices []*struct {
+		BaseFeePerGas   json.Number `json:"baseFeePerGas"`
+		EstimatedPrices []*struct {
+			Confidence           int         `json:"confidence"`
+			Price                json.Number `json:"price"`
+			MaxPriorityFeePerGas json.Number `json:"maxPriorityFeePerGas"`
+		} `json:"estimatedPrices"`
+	} `json:"blockPrices"`
+}
+
+// NewBlocknativeSource returns a source that queries the Blocknative gas price API at the base URL of the
+// client (such as https://api.blocknative.com/gasprices/blockprices). The safe, standard and fast speeds are
+// the estimates with 70%, 90% and 99% confidence of inclusion in the next block.
+func NewBlocknativeSource(ctx context.Context, client *resty.Client, options OracleOptions) (Source, error) {
+	o, err := newOracleSource(ctx, "blocknative", client, options)
+	if err != nil {
+		return nil, err
+	}
+	confidence := map[Speed]int{SpeedSafe: 70, SpeedStandard: 90, SpeedFast: 99}[o.options.Speed]
+	return &blocknativeSource{oracleSource: o, confidence: confidence}, nil
+}
+
+func (bs *blocknativeSource) Fees(ctx context.Context) (*Fees, error) {
+	var headers map[string]string
+	if bs.options.APIKey != "" {
+		headers = map[string]string{"Authorization": bs.options.APIKey}
+	}
+	var res blocknativeResponse
+	if err := bs.get(ctx, &res, nil, headers); err != nil {
+		return nil, err
+	}
+	if len(res.BlockPrices) == 0 || res.BlockPrices[0] == nil {
+		return nil, bs.badResponse(ctx, "no blockPrices")
+	}
+	block := res.BlockPrices[0]
+	for _, estimate := range block.EstimatedPrices {
+		if estimate == nil || estimate.Confidence != bs.confidence {
+			continue
+		}
+		baseFee, err := bs.gwei(ctx, "baseFeePerGas", block.BaseFeePerGas.String())
+		if err != nil {
+			return nil, err
+		}
+		priorityFee, err := bs.gwei(ctx, "maxPriorityFeePerGas", estimate.MaxPriorityFeePerGas.String())
+		if err != nil {
+			return nil, err
+		}
+		gasPrice, err := bs.gwei(ctx, "price", estimate.Price.String())
+		if err != nil {
+			return nil, err
+		}
+		return &Fees{BaseFee: baseFee, MaxPriorityFeePerGas: priorityFee, GasPrice: gasPrice}, nil
+	}
+	return nil, bs.badResponse(ctx, fmt.Sprintf("no estimate with a confidence of %d", bs.confidence))
+}
+
+type gasStationSource struct {
+	*oracleSource
+}
+
+type gasStationTier struct {
+	MaxPriorityFee json.Number `json:"maxPriorityFee"`
+}
+
+type gasStationResponse struct {
+	SafeLow          *gasStationTier `json:"safeLow"`
+	Standard         *gasStationTier `json:"standard"`
+	Fast             *gasStationTier `json:"fast"`
+	EstimatedBaseFee json.Number     `json:"estimatedBaseFee"`
+}
+
+// NewGasStationSource returns a source that queries a gas station with the v2 API of the Polygon gas station,
+// at the base URL of the client (such as https://gasstation.polygon.technology/v2). The safe, standard and
+// fast speeds are the safeLow, standard and fast tiers.
+func NewGasStationSource(ctx context.Context, client *resty.Client, options OracleOptions) (Source, error) {
+	o, err := newOracleSource(ctx, "gasStation", client, options)
+	if err != nil {
+		return nil, err
+	}
+	return &gasStationSource{oracleSource: o}, nil
+}
+
+func (gs *gasStationSource) Fees(ctx context.Context) (*Fees, error) {
+	var res gasStationResponse
+	if err := gs.get(ctx, &res, nil, nil); err != nil {
+		return nil, err
+	}
+	tier := map[Speed]*gasStationTier{
+		SpeedSafe:     res.SafeLow,
+		SpeedStandard: res.Standard,
+		SpeedFast:     res.Fast,
+	}[gs.options.Speed]
+	if tier == nil {
+		return nil, gs.badResponse(ctx, fmt.Sprintf("no %s tier", gs.options.Speed))
+	}
+	baseFee, err := gs.gwei(ctx, "estimatedBaseFee", res.EstimatedBaseFee.String())
+	if err != nil {
+		return nil, err
+	}
+	priorityFee, err := gs.gwei(ctx, "maxPriorityFee", tier.MaxPriorityFee.String())
+	if err != nil {
+		return nil, err
+	}
+	return &Fees{BaseFee: baseFee, MaxPriorityFeePerGas: priorityFee, GasPrice: new(big.Int).Add(baseFee, priorityFee)}, nil
+}
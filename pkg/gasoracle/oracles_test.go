@@ -0,0 +1,195 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gasoracle
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestOracle(t *testing.T, newSource func(context.Context, *resty.Client, OracleOptions) (Source, error), options OracleOptions, handler http.HandlerFunc) (Source, func()) {
+	server := httptest.NewServer(handler)
+	source, err := newSource(context.Background(), resty.New().SetBaseURL(server.URL), options)
+	assert.NoError(t, err)
+	return source, server.Close
+}
+
+func respond(status int, body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}
+}
+
+const etherscanResult = `{"status": "1", "message": "OK", "result": {
+	"LastBlock": "100",
+	"SafeGasPrice": "1.1",
+	"ProposeGasPrice": "1.2",
+	"FastGasPrice": "1.5",
+	"suggestBaseFee": "1.15"
+}}`
+
+func TestEtherscan(t *testing.T) {
+	es, done := newTestOracle(t, NewEtherscanSource, OracleOptions{Speed: SpeedFast, APIKey: "key1"}, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "gastracker", r.URL.Query().Get("module"))
+		assert.Equal(t, "gasoracle", r.URL.Query().Get("action"))
+		assert.Equal(t, "key1", r.URL.Query().Get("apikey"))
+		_, _ = w.Write([]byte(etherscanResult))
+	})
+	defer done()
+	assert.Equal(t, "etherscan", es.Name())
+
+	fees, err := es.Fees(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1500000000), fees.GasPrice.Int64())
+	assert.Equal(t, int64(1150000000), fees.BaseFee.Int64())
+	assert.Equal(t, int64(350000000), fees.MaxPriorityFeePerGas.Int64())
+}
+
+func TestEtherscanSafeBelowBaseFee(t *testing.T) {
+	es, done := newTestOracle(t, NewEtherscanSource, OracleOptions{Speed: SpeedSafe}, respond(200, etherscanResult))
+	defer done()
+	fees, err := es.Fees(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1100000000), fees.GasPrice.Int64())
+	assert.Equal(t, int64(0), fees.MaxPriorityFeePerGas.Int64())
+}
+
+func TestEtherscanLegacy(t *testing.T) {
+	es, done := newTestOracle(t, NewEtherscanSource, OracleOptions{}, respond(200, `{"status": "1", "result": {"ProposeGasPrice": "20"}}`))
+	defer done()
+	fees, err := es.Fees(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(20000000000), fees.GasPrice.Int64())
+	assert.Nil(t, fees.BaseFee)
+}
+
+func TestEtherscanErrors(t *testing.T) {
+	for errRegexp, body := range map[string]string{
+		"FF22265.*NOTOK: \"Invalid API Key\"":   `{"status": "0", "message": "NOTOK", "result": "Invalid API Key"}`,
+		"FF22265.*invalid standard 'wrong'":     `{"status": "1", "result": {"ProposeGasPrice": "wrong"}}`,
+		"FF22265.*invalid suggestBaseFee '-1'":  `{"status": "1", "result": {"ProposeGasPrice": "1", "suggestBaseFee": "-1"}}`,
+		"FF22265.*etherscan.*invalid character": `!json`,
+	} {
+		es, done := newTestOracle(t, NewEtherscanSource, OracleOptions{}, respond(200, body))
+		_, err := es.Fees(context.Background())
+		assert.Regexp(t, errRegexp, err)
+		done()
+	}
+}
+
+func TestOracleRequestFailed(t *testing.T) {
+	es, done := newTestOracle(t, NewEtherscanSource, OracleOptions{}, respond(500, "pop"))
+	_, err := es.Fees(context.Background())
+	assert.Regexp(t, "FF22264.*etherscan.*HTTP status 500", err)
+	done()
+
+	_, err = es.Fees(context.Background())
+	assert.Regexp(t, "FF22264.*etherscan", err)
+}
+
+func TestOracleBadSpeed(t *testing.T) {
+	for _, newSource := range []func(context.Context, *resty.Client, OracleOptions) (Source, error){
+		NewEtherscanSource, NewBlocknativeSource, NewGasStationSource,
+	} {
+		_, err := newSource(context.Background(), resty.New(), OracleOptions{Speed: "wrong"})
+		assert.Regexp(t, "FF22270", err)
+	}
+}
+
+const blocknativeResult = `{"blockPrices": [{
+	"baseFeePerGas": 10.5,
+	"estimatedPrices": [
+		{"confidence": 99, "price": 12, "maxPriorityFeePerGas": 1.5, "maxFeePerGas": 22.5},
+		{"confidence": 90, "price": 11, "maxPriorityFeePerGas": 0.5, "maxFeePerGas": 21.5}
+	]
+}]}`
+
+func TestBlocknative(t *testing.T) {
+	bs, done := newTestOracle(t, NewBlocknativeSource, OracleOptions{APIKey: "key1"}, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "key1", r.Header.Get("Authorization"))
+		_, _ = w.Write([]byte(blocknativeResult))
+	})
+	defer done()
+	assert.Equal(t, "blocknative", bs.Name())
+
+	fees, err := bs.Fees(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(10500000000), fees.BaseFee.Int64())
+	assert.Equal(t, int64(500000000), fees.MaxPriorityFeePerGas.Int64())
+	assert.Equal(t, int64(11000000000), fees.GasPrice.Int64())
+}
+
+func TestBlocknativeErrors(t *testing.T) {
+	for errRegexp, body := range map[string]string{
+		"FF22265.*no blockPrices":                      `{"blockPrices": []}`,
+		"FF22265.*no estimate with a confidence of 70": blocknativeResult,
+		"FF22265.*invalid baseFeePerGas":               `{"blockPrices": [{"estimatedPrices": [{"confidence": 70}]}]}`,
+		"FF22265.*invalid maxPriorityFeePerGas":        `{"blockPrices": [{"baseFeePerGas": 1, "estimatedPrices": [{"confidence": 70}]}]}`,
+		"FF22265.*invalid price":                       `{"blockPrices": [{"baseFeePerGas": 1, "estimatedPrices": [{"confidence": 70, "maxPriorityFeePerGas": 1}]}]}`,
+		"FF22264":                                      `!json`,
+	} {
+		bs, done := newTestOracle(t, NewBlocknativeSource, OracleOptions{Speed: SpeedSafe}, respond(200, body))
+		if errRegexp == "FF22264" {
+			done()
+			errRegexp = "FF22264.*blocknative"
+		}
+		_, err := bs.Fees(context.Background())
+		assert.Regexp(t, errRegexp, err)
+		done()
+	}
+}
+
+const gasStationResult = `{
+	"safeLow": {"maxPriorityFee": 30.5, "maxFee": 31.5},
+	"standard": {"maxPriorityFee": 32, "maxFee": 33},
+	"fast": {"maxPriorityFee": 35, "maxFee": 36},
+	"estimatedBaseFee": 1e-9,
+	"blockTime": 2,
+	"blockNumber": 100
+}`
+
+func TestGasStation(t *testing.T) {
+	gs, done := newTestOracle(t, NewGasStationSource, OracleOptions{Speed: SpeedSafe}, respond(200, gasStationResult))
+	defer done()
+	assert.Equal(t, "gasStation", gs.Name())
+
+	fees, err := gs.Fees(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), fees.BaseFee.Int64())
+	assert.Equal(t, int64(30500000000), fees.MaxPriorityFeePerGas.Int64())
+	assert.Equal(t, int64(30500000001), fees.GasPrice.Int64())
+}
+
+func TestGasStationErrors(t *testing.T) {
+	for errRegexp, body := range map[string]string{
+		"FF22265.*no standard tier":         `{}`,
+		"FF22265.*invalid estimatedBaseFee": `{"standard": {"maxPriorityFee": 1}}`,
+		"FF22265.*invalid maxPriorityFee":   `{"standard": {}, "estimatedBaseFee": 1}`,
+		"FF22265.*gasStation":               `!json`,
+	} {
+		gs, done := newTestOracle(t, NewGasStationSource, OracleOptions{}, respond(200, body))
+		_, err := gs.Fees(context.Background())
+		assert.Regexp(t, errRegexp, err)
+		done()
+	}
+}
@@ -17,17 +17,50 @@
 package keystorev3
 
 import (
+	"crypto/rand"
 	"crypto/sha256"
 	"encoding/json"
 	"fmt"
 
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/secp256k1"
 	"golang.org/x/crypto/pbkdf2"
 )
 
 const (
 	prfHmacSHA256 = "hmac-sha256"
+
+	// The iteration counts are the same as geth, and above the minimum of NIST SP 800-132
+	cLight    = 1 << 12
+	cStandard = 1 << 18
 )
 
+func newPbkdf2WalletFileSecp256k1(password string, keypair *secp256k1.KeyPair, c int) WalletFile {
+	wf := newPbkdf2WalletFileBytes(password, keypair.PrivateKeyBytes(), c)
+	wf.Metadata()["address"] = ethtypes.AddressPlainHex(keypair.Address).String()
+	return wf
+}
+
+func newPbkdf2WalletFileBytes(password string, privateKey []byte, c int) *walletFilePbkdf2 {
+	salt := mustReadBytes(32, rand.Reader)
+
+	derivedKey := pbkdf2.Key([]byte(password), salt, c, 32, sha256.New)
+	defer secp256k1.ZeroizeBytes(derivedKey)
+
+	return &walletFilePbkdf2{
+		walletFileBase: newWalletFileBase(privateKey),
+		Crypto: cryptoPbkdf2{
+			cryptoCommon: mustEncryptCommon(kdfTypePbkdf2, derivedKey, privateKey),
+			KDFParams: kdfParamsPbkdf2{
+				DKLen: 32,
+				C:     c,
+				PRF:   prfHmacSHA256,
+				Salt:  salt,
+			},
+		},
+	}
+}
+
 func readPbkdf2WalletFile(jsonWallet []byte, password []byte, metadata map[string]interface{}) (WalletFile, error) {
 	var w *walletFilePbkdf2
 	if err := json.Unmarshal(jsonWallet, &w); err != nil {
@@ -43,6 +76,7 @@ func (w *walletFilePbkdf2) decrypt(password []byte) (err error) {
 	}
 
 	derivedKey := pbkdf2.Key(password, w.Crypto.KDFParams.Salt, w.Crypto.KDFParams.C, w.Crypto.KDFParams.DKLen, sha256.New)
+	defer secp256k1.ZeroizeBytes(derivedKey)
 
 	w.privateKey, err = w.Crypto.decryptCommon(derivedKey)
 	return err
@@ -24,6 +24,7 @@ import (
 
 	"github.com/hyperledger/firefly-common/pkg/fftypes"
 	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/fips"
 	"github.com/hyperledger/firefly-signer/pkg/secp256k1"
 	"github.com/stretchr/testify/assert"
 	"golang.org/x/crypto/pbkdf2"
@@ -94,3 +95,43 @@ func TestPbkdf2WalletFileUnsupportedPRF(t *testing.T) {
 	assert.Regexp(t, "invalid pbkdf2 wallet file: unsupported prf", err)
 
 }
+
+func TestPbkdf2WalletRoundTrip(t *testing.T) {
+	keypair, err := secp256k1.GenerateSecp256k1KeyPair()
+	assert.NoError(t, err)
+
+	w1 := NewWalletFilePbkdf2("myPrecious", keypair)
+	assert.Equal(t, kdfTypePbkdf2, w1.(*walletFilePbkdf2).Crypto.KDF)
+	assert.Equal(t, cStandard, w1.(*walletFilePbkdf2).Crypto.KDFParams.C)
+
+	w2, err := ReadWalletFile(w1.JSON(), []byte("myPrecious"))
+	assert.NoError(t, err)
+	assert.Equal(t, keypair.PrivateKeyBytes(), w2.PrivateKey())
+	assert.Equal(t, ethtypes.AddressPlainHex(keypair.Address).String(), w2.Metadata()["address"])
+}
+
+func TestFIPSModeWalletFiles(t *testing.T) {
+	keypair, err := secp256k1.GenerateSecp256k1KeyPair()
+	assert.NoError(t, err)
+	scryptWallet := NewWalletFileLight("myPrecious", keypair).JSON()
+
+	fips.SetEnabled(true)
+	defer fips.SetEnabled(false)
+
+	// Every new wallet file uses PBKDF2 in FIPS mode
+	for _, w := range []WalletFile{
+		NewWalletFileLight("myPrecious", keypair),
+		NewWalletFileStandard("myPrecious", keypair),
+		NewWalletFileCustomBytesLight("myPrecious", keypair.PrivateKeyBytes()),
+		NewWalletFileCustomBytesStandard("myPrecious", keypair.PrivateKeyBytes()),
+	} {
+		assert.Equal(t, kdfTypePbkdf2, w.(*walletFilePbkdf2).Crypto.KDF)
+		w2, err := ReadWalletFile(w.JSON(), []byte("myPrecious"))
+		assert.NoError(t, err)
+		assert.Equal(t, keypair.PrivateKeyBytes(), w2.PrivateKey())
+	}
+
+	// Files encrypted with scrypt are refused
+	_, err = ReadWalletFile(scryptWallet, []byte("myPrecious"))
+	assert.Regexp(t, "scrypt kdf is not allowed in FIPS mode", err)
+}
@@ -21,8 +21,8 @@ import (
 	"encoding/json"
 	"fmt"
 
-	"github.com/hyperledger/firefly-common/pkg/fftypes"
 	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/fips"
 	"github.com/hyperledger/firefly-signer/pkg/secp256k1"
 	"golang.org/x/crypto/scrypt"
 )
@@ -30,6 +30,9 @@ import (
 const defaultR = 8
 
 func readScryptWalletFile(jsonWallet []byte, password []byte, metadata map[string]interface{}) (WalletFile, error) {
+	if !fips.Allowed(fips.AlgorithmScrypt) {
+		return nil, fmt.Errorf("scrypt kdf is not allowed in FIPS mode")
+	}
 	var w *walletFileScrypt
 	if err := json.Unmarshal(jsonWallet, &w); err != nil {
 		return nil, fmt.Errorf("invalid scrypt wallet file: %s", err)
@@ -39,7 +42,7 @@ func readScryptWalletFile(jsonWallet []byte, password []byte, metadata map[strin
 }
 
 func mustGenerateDerivedScryptKey(password string, salt []byte, n, p int) []byte {
-	b, err := scrypt.Key([]byte(password), salt, n, defaultR, p, 16)
+	b, err := scrypt.Key([]byte(password), salt, n, defaultR, p, 32)
 	if err != nil {
 		panic(fmt.Sprintf("Scrypt failed: %s", err))
 	}
@@ -61,40 +64,12 @@ func newScryptWalletFileBytes(password string, privateKey []byte, n int, p int)
 
 	// Do the scrypt derivation of the key with the salt from the password
 	derivedKey := mustGenerateDerivedScryptKey(password, salt, n, p)
-
-	// Generate a random Initialization Vector (IV) for the AES/CTR/128 key encryption
-	iv := mustReadBytes(16 /* 128bit */, rand.Reader)
-
-	// First 16 bytes of derived key are used as the encryption key
-	encryptKey := derivedKey[0:16]
-
-	// Encrypt the private key with the encryption key
-	cipherText := mustAES128CtrEncrypt(encryptKey, iv, privateKey)
-
-	// Last 16 bytes of derived key are used for the MAC
-	mac := generateMac(derivedKey[16:32], cipherText)
+	defer secp256k1.ZeroizeBytes(derivedKey)
 
 	return &walletFileScrypt{
-		walletFileBase: walletFileBase{
-			walletFileCoreFields: walletFileCoreFields{
-				ID:      fftypes.NewUUID(),
-				Version: version3,
-			},
-			walletFileMetadata: walletFileMetadata{
-				metadata: map[string]interface{}{},
-			},
-			privateKey: privateKey,
-		},
+		walletFileBase: newWalletFileBase(privateKey),
 		Crypto: cryptoScrypt{
-			cryptoCommon: cryptoCommon{
-				Cipher:     cipherAES128ctr,
-				CipherText: cipherText,
-				CipherParams: cipherParams{
-					IV: iv,
-				},
-				KDF: kdfTypeScrypt,
-				MAC: mac,
-			},
+			cryptoCommon: mustEncryptCommon(kdfTypeScrypt, derivedKey, privateKey),
 			KDFParams: kdfParamsScrypt{
 				DKLen: 32,
 				N:     n,
@@ -111,6 +86,7 @@ func (w *walletFileScrypt) decrypt(password []byte) error {
 	if err != nil {
 		return fmt.Errorf("invalid scrypt keystore: %s", err)
 	}
+	defer secp256k1.ZeroizeBytes(derivedKey)
 	w.privateKey, err = w.Crypto.decryptCommon(derivedKey)
 	return err
 }
@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"io"
 
+	"github.com/hyperledger/firefly-signer/pkg/fips"
 	"github.com/hyperledger/firefly-signer/pkg/secp256k1"
 	"golang.org/x/crypto/sha3"
 )
@@ -31,19 +32,38 @@ const (
 	pDefault  int = 1
 )
 
+// NewWalletFileLight encrypts the key with scrypt, or with PBKDF2 in FIPS mode, using fewer iterations than
+// NewWalletFileStandard so it is quicker to decrypt
 func NewWalletFileLight(password string, keypair *secp256k1.KeyPair) WalletFile {
+	if fips.Enabled() {
+		return newPbkdf2WalletFileSecp256k1(password, keypair, cLight)
+	}
 	return newScryptWalletFileSecp256k1(password, keypair, nLight, pDefault)
 }
 
 func NewWalletFileStandard(password string, keypair *secp256k1.KeyPair) WalletFile {
+	if fips.Enabled() {
+		return newPbkdf2WalletFileSecp256k1(password, keypair, cStandard)
+	}
 	return newScryptWalletFileSecp256k1(password, keypair, nStandard, pDefault)
 }
 
+// NewWalletFilePbkdf2 encrypts the key with PBKDF2-HMAC-SHA256, which is FIPS approved, rather than scrypt
+func NewWalletFilePbkdf2(password string, keypair *secp256k1.KeyPair) WalletFile {
+	return newPbkdf2WalletFileSecp256k1(password, keypair, cStandard)
+}
+
 func NewWalletFileCustomBytesLight(password string, privateKey []byte) WalletFile {
+	if fips.Enabled() {
+		return newPbkdf2WalletFileBytes(password, privateKey, cLight)
+	}
 	return newScryptWalletFileBytes(password, privateKey, nStandard, pDefault)
 }
 
 func NewWalletFileCustomBytesStandard(password string, privateKey []byte) WalletFile {
+	if fips.Enabled() {
+		return newPbkdf2WalletFileBytes(password, privateKey, cStandard)
+	}
 	return newScryptWalletFileBytes(password, privateKey, nStandard, pDefault)
 }
 
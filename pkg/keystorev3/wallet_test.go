@@ -195,3 +195,19 @@ func TestWalletFileCustomBytesUnsetAddress(t *testing.T) {
 	assert.Equal(t, w.GetID().String(), roundTripBackFromJSON["id"])
 
 }
+
+func TestWalletFileZeroize(t *testing.T) {
+	keypair, err := secp256k1.GenerateSecp256k1KeyPair()
+	assert.NoError(t, err)
+
+	w1 := NewWalletFileLight("waltsentme", keypair)
+	w1b := w1.JSON()
+	w1.Zeroize()
+	assert.Equal(t, make([]byte, 32), w1.PrivateKey())
+	assert.Equal(t, w1b, w1.JSON())
+
+	// The encrypted form is unaffected, so the key can be decrypted again
+	w2, err := ReadWalletFile(w1b, []byte("waltsentme"))
+	assert.NoError(t, err)
+	assert.Equal(t, keypair.PrivateKeyBytes(), w2.PrivateKey())
+}
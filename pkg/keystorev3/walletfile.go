@@ -18,6 +18,7 @@ package keystorev3
 
 import (
 	"bytes"
+	"crypto/rand"
 	"encoding/json"
 	"fmt"
 
@@ -36,6 +37,9 @@ const (
 type WalletFile interface {
 	PrivateKey() []byte
 	KeyPair() *secp256k1.KeyPair
+	// Zeroize overwrites the decrypted private key held in memory. The wallet file can still be
+	// serialized with JSON(), but PrivateKey() and KeyPair() no longer return the key after this.
+	Zeroize()
 	JSON() []byte
 	GetID() *fftypes.UUID
 	GetVersion() int
@@ -163,6 +167,10 @@ func (w *walletFileBase) PrivateKey() []byte {
 	return w.privateKey
 }
 
+func (w *walletFileBase) Zeroize() {
+	secp256k1.ZeroizeBytes(w.privateKey)
+}
+
 func (w *walletFilePbkdf2) JSON() []byte {
 	b, _ := json.Marshal(w)
 	return b
@@ -173,6 +181,44 @@ func (w *walletFileScrypt) JSON() []byte {
 	return b
 }
 
+func newWalletFileBase(privateKey []byte) walletFileBase {
+	return walletFileBase{
+		walletFileCoreFields: walletFileCoreFields{
+			ID:      fftypes.NewUUID(),
+			Version: version3,
+		},
+		walletFileMetadata: walletFileMetadata{
+			metadata: map[string]interface{}{},
+		},
+		privateKey: privateKey,
+	}
+}
+
+// mustEncryptCommon encrypts the private key with a 32 byte key derived from the password by the KDF
+func mustEncryptCommon(kdf string, derivedKey, privateKey []byte) cryptoCommon {
+	// Generate a random Initialization Vector (IV) for the AES/CTR/128 key encryption
+	iv := mustReadBytes(16 /* 128bit */, rand.Reader)
+
+	// First 16 bytes of derived key are used as the encryption key
+	encryptKey := derivedKey[0:16]
+
+	// Encrypt the private key with the encryption key
+	cipherText := mustAES128CtrEncrypt(encryptKey, iv, privateKey)
+
+	// Last 16 bytes of derived key are used for the MAC
+	mac := generateMac(derivedKey[16:32], cipherText)
+
+	return cryptoCommon{
+		Cipher:     cipherAES128ctr,
+		CipherText: cipherText,
+		CipherParams: cipherParams{
+			IV: iv,
+		},
+		KDF: kdf,
+		MAC: mac,
+	}
+}
+
 func (c *cryptoCommon) decryptCommon(derivedKey []byte) ([]byte, error) {
 	if len(derivedKey) != 32 {
 		return nil, fmt.Errorf("invalid scrypt keystore: derived key length %d != 32", len(derivedKey))
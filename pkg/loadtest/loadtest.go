@@ -0,0 +1,247 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package loadtest drives a wallet end-to-end at a configurable concurrency, to measure the signing throughput
+// of a deployment for capacity planning, and to catch performance regressions.
+//
+// Every request resolves the signing key of the sender, ABI encodes the call data (when a method is supplied),
+// signs the transaction, and optionally submits it with eth_sendRawTransaction - such as to an in-process
+// fakechain.Chain, so the cost of a real node is left out of the numbers.
+package loadtest
+
+import (
+	"context"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/hyperledger/firefly-signer/pkg/abi"
+	"github.com/hyperledger/firefly-signer/pkg/ethsigner"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+)
+
+// DefaultRequests is the number of requests sent when neither Requests nor Duration is set
+const DefaultRequests = 1000
+
+// Options configures a load test. Only the wallet is required.
+type Options struct {
+	Wallet  ethsigner.Wallet
+	ChainID int64
+	// From is the accounts to sign with, in turn - defaults to every account in the wallet
+	From []*ethtypes.Address0xHex
+	To   *ethtypes.Address0xHex
+	// Method and Params, when set, are ABI encoded into the data of every transaction - Params is JSON
+	Method *abi.Entry
+	Params []byte
+	// Backend, when set, is sent every signed transaction with eth_sendRawTransaction
+	Backend rpcbackend.RPC
+	// Concurrency is the number of requests in flight at once, which defaults to the number of CPUs
+	Concurrency int
+	// Requests is the total number of requests to send, and Duration is how long to send them for - the test
+	// stops at whichever is reached first
+	Requests int
+	Duration time.Duration
+}
+
+// Latency is the distribution of the time taken by each request
+type Latency struct {
+	Min  *fftypes.FFDuration `json:"min"`
+	Mean *fftypes.FFDuration `json:"mean"`
+	P50  *fftypes.FFDuration `json:"p50"`
+	P90  *fftypes.FFDuration `json:"p90"`
+	P95  *fftypes.FFDuration `json:"p95"`
+	P99  *fftypes.FFDuration `json:"p99"`
+	Max  *fftypes.FFDuration `json:"max"`
+}
+
+// Result is the outcome of a load test. The allocation stats are for the whole process while the test ran,
+// divided by the number of requests, so include the allocations of an in-process backend.
+type Result struct {
+	Requests         int                 `json:"requests"`
+	Errors           int                 `json:"errors"`
+	FirstError       string              `json:"firstError,omitempty"`
+	Concurrency      int                 `json:"concurrency"`
+	Elapsed          *fftypes.FFDuration `json:"elapsed"`
+	Throughput       float64             `json:"throughput"` // requests per second
+	Latency          Latency             `json:"latency"`
+	AllocsPerRequest uint64              `json:"allocsPerRequest"`
+	BytesPerRequest  uint64              `json:"bytesPerRequest"`
+}
+
+type worker struct {
+	latencies  []time.Duration
+	errors     int
+	firstError error
+}
+
+// Run sends requests until the number of requests is reached, the duration expires or the context is
+// canceled, and returns the result. Failed requests are counted in the result rather than stopping the test,
+// and an error is only returned if the test cannot start.
+func Run(ctx context.Context, options *Options) (*Result, error) {
+	from := options.From
+	if len(from) == 0 {
+		accounts, err := options.Wallet.GetAccounts(ctx)
+		if err != nil {
+			return nil, err
+		}
+		from = accounts
+	}
+	if len(from) == 0 {
+		return nil, i18n.NewError(ctx, signermsgs.MsgLoadTestNoAccounts)
+	}
+	// Check the call data encodes before starting, rather than failing every request
+	if _, err := encodeCallData(ctx, options); err != nil {
+		return nil, err
+	}
+	concurrency := options.Concurrency
+	if concurrency <= 0 {
+		concurrency = runtime.NumCPU()
+	}
+	requests := options.Requests
+	if requests <= 0 && options.Duration <= 0 {
+		requests = DefaultRequests
+	}
+	runCtx := ctx
+	if options.Duration > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, options.Duration)
+		defer cancel()
+	}
+
+	// Each request takes the next sequence number, which picks the sender and its nonce
+	var sequence atomic.Int64
+	next := func() (int64, bool) {
+		seq := sequence.Add(1) - 1
+		return seq, runCtx.Err() == nil && (requests <= 0 || seq < int64(requests))
+	}
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+	workers := make([]*worker, concurrency)
+	var wg sync.WaitGroup
+	for i := range workers {
+		w := &worker{}
+		workers[i] = w
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for seq, ok := next(); ok; seq, ok = next() {
+				reqStart := time.Now()
+				err := sendRequest(runCtx, options, from, seq)
+				w.latencies = append(w.latencies, time.Since(reqStart))
+				if err != nil {
+					w.errors++
+					if w.firstError == nil {
+						w.firstError = err
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+
+	result := &Result{
+		Concurrency: concurrency,
+		Elapsed:     ffDuration(elapsed),
+	}
+	var latencies []time.Duration
+	for _, w := range workers {
+		latencies = append(latencies, w.latencies...)
+		result.Errors += w.errors
+		if w.firstError != nil && result.FirstError == "" {
+			result.FirstError = w.firstError.Error()
+		}
+	}
+	result.Requests = len(latencies)
+	if result.Requests > 0 {
+		result.Throughput = float64(result.Requests) / elapsed.Seconds()
+		result.Latency = latencyOf(latencies)
+		result.AllocsPerRequest = (after.Mallocs - before.Mallocs) / uint64(result.Requests)
+		result.BytesPerRequest = (after.TotalAlloc - before.TotalAlloc) / uint64(result.Requests)
+	}
+	return result, nil
+}
+
+func encodeCallData(ctx context.Context, options *Options) (ethtypes.HexBytes0xPrefix, error) {
+	if options.Method == nil {
+		return nil, nil
+	}
+	return options.Method.EncodeCallDataJSONCtx(ctx, options.Params)
+}
+
+// sendRequest encodes, signs, and optionally submits, a transaction. Senders are used in turn, and each one
+// counts its nonce up from zero.
+func sendRequest(ctx context.Context, options *Options, from []*ethtypes.Address0xHex, seq int64) error {
+	data, err := encodeCallData(ctx, options)
+	if err != nil {
+		return err
+	}
+	sender := from[seq%int64(len(from))]
+	tx := &ethsigner.Transaction{
+		From:     []byte(`"` + sender.String() + `"`),
+		To:       options.To,
+		Nonce:    ethtypes.NewHexInteger64(seq / int64(len(from))),
+		GasLimit: ethtypes.NewHexInteger64(1000000),
+		Data:     data,
+	}
+	raw, err := options.Wallet.Sign(ctx, tx, options.ChainID)
+	if err != nil {
+		return err
+	}
+	if options.Backend != nil {
+		var hash ethtypes.HexBytes0xPrefix
+		if rpcErr := options.Backend.CallRPC(ctx, &hash, "eth_sendRawTransaction", ethtypes.HexBytes0xPrefix(raw)); rpcErr != nil {
+			return rpcErr.Error()
+		}
+	}
+	return nil
+}
+
+// latencyOf returns the distribution of the latencies, using the nearest-rank method for the percentiles
+func latencyOf(latencies []time.Duration) Latency {
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	var total time.Duration
+	for _, l := range latencies {
+		total += l
+	}
+	percentile := func(p int) *fftypes.FFDuration {
+		rank := (p*len(latencies) + 99) / 100
+		return ffDuration(latencies[max(rank, 1)-1])
+	}
+	return Latency{
+		Min:  ffDuration(latencies[0]),
+		Mean: ffDuration(total / time.Duration(len(latencies))),
+		P50:  percentile(50),
+		P90:  percentile(90),
+		P95:  percentile(95),
+		P99:  percentile(99),
+		Max:  ffDuration(latencies[len(latencies)-1]),
+	}
+}
+
+func ffDuration(d time.Duration) *fftypes.FFDuration {
+	fd := fftypes.FFDuration(d)
+	return &fd
+}
@@ -0,0 +1,232 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loadtest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/firefly-signer/mocks/ethsignermocks"
+	"github.com/hyperledger/firefly-signer/pkg/abi"
+	"github.com/hyperledger/firefly-signer/pkg/ethsigner"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/fakechain"
+	"github.com/hyperledger/firefly-signer/pkg/secp256k1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// testWallet signs with a single key
+type testWallet struct {
+	kp *secp256k1.KeyPair
+}
+
+func (w *testWallet) Sign(_ context.Context, txn *ethsigner.Transaction, chainID int64) ([]byte, error) {
+	return txn.Sign(w.kp, chainID)
+}
+
+func (w *testWallet) Initialize(context.Context) error { return nil }
+func (w *testWallet) GetAccounts(context.Context) ([]*ethtypes.Address0xHex, error) {
+	return []*ethtypes.Address0xHex{&w.kp.Address}, nil
+}
+func (w *testWallet) Refresh(context.Context) error { return nil }
+func (w *testWallet) Close() error                  { return nil }
+
+func newTestWallet(t *testing.T) *testWallet {
+	kp, err := secp256k1.GenerateSecp256k1KeyPair()
+	assert.NoError(t, err)
+	return &testWallet{kp: kp}
+}
+
+var testTransfer = &abi.Entry{
+	Type: abi.Function,
+	Name: "transfer",
+	Inputs: abi.ParameterArray{
+		{Name: "to", Type: "address"},
+		{Name: "value", Type: "uint256"},
+	},
+}
+
+func TestRunSubmit(t *testing.T) {
+	chain := fakechain.New(nil)
+	w := newTestWallet(t)
+	result, err := Run(context.Background(), &Options{
+		Wallet:      w,
+		ChainID:     1337,
+		To:          ethtypes.MustNewAddress("0x497eedc4299dea2f2a364be10025d0ad0f702de3"),
+		Method:      testTransfer,
+		Params:      []byte(`{"to":"0x497eedc4299dea2f2a364be10025d0ad0f702de3","value":"1000"}`),
+		Backend:     chain,
+		Concurrency: 4,
+		Requests:    50,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 50, result.Requests)
+	assert.Zero(t, result.Errors)
+	assert.Empty(t, result.FirstError)
+	assert.Equal(t, 4, result.Concurrency)
+	assert.Greater(t, result.Throughput, float64(0))
+	assert.Greater(t, result.AllocsPerRequest, uint64(0))
+	assert.Greater(t, result.BytesPerRequest, uint64(0))
+	assert.LessOrEqual(t, *result.Latency.Min, *result.Latency.P50)
+	assert.LessOrEqual(t, *result.Latency.P50, *result.Latency.P99)
+	assert.LessOrEqual(t, *result.Latency.P99, *result.Latency.Max)
+
+	txns := chain.Transactions()
+	assert.Len(t, txns, 50)
+	nonces := make(map[uint64]bool)
+	for _, tx := range txns {
+		assert.Equal(t, w.kp.Address, *tx.From)
+		assert.Equal(t, "0xa9059cbb", tx.Data.String()[0:10])
+		nonces[tx.Nonce.Uint64()] = true
+	}
+	assert.Len(t, nonces, 50)
+}
+
+func TestRunDefaults(t *testing.T) {
+	result, err := Run(context.Background(), &Options{
+		Wallet:  newTestWallet(t),
+		ChainID: 1337,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultRequests, result.Requests)
+	assert.Greater(t, result.Concurrency, 0)
+}
+
+func TestRunDuration(t *testing.T) {
+	result, err := Run(context.Background(), &Options{
+		Wallet:   newTestWallet(t),
+		ChainID:  1337,
+		Duration: 50 * time.Millisecond,
+	})
+	assert.NoError(t, err)
+	assert.Greater(t, result.Requests, 0)
+	assert.GreaterOrEqual(t, time.Duration(*result.Elapsed), 50*time.Millisecond)
+}
+
+func TestRunMultipleSenders(t *testing.T) {
+	w1, w2 := newTestWallet(t), newTestWallet(t)
+	mw := &ethsignermocks.Wallet{}
+	mw.On("Sign", mock.Anything, mock.Anything, int64(1)).Return(func(ctx context.Context, tx *ethsigner.Transaction, chainID int64) ([]byte, error) {
+		if string(tx.From) == fmt.Sprintf(`"%s"`, w1.kp.Address) {
+			return w1.Sign(ctx, tx, chainID)
+		}
+		return w2.Sign(ctx, tx, chainID)
+	})
+	chain := fakechain.New(&fakechain.Options{ChainID: 1})
+	result, err := Run(context.Background(), &Options{
+		Wallet:   mw,
+		ChainID:  1,
+		From:     []*ethtypes.Address0xHex{&w1.kp.Address, &w2.kp.Address},
+		Backend:  chain,
+		Requests: 10,
+	})
+	assert.NoError(t, err)
+	assert.Zero(t, result.Errors)
+	senders := make(map[ethtypes.Address0xHex]int)
+	for _, tx := range chain.Transactions() {
+		senders[*tx.From]++
+		assert.Less(t, tx.Nonce.Int64(), int64(5))
+	}
+	assert.Equal(t, 5, senders[w1.kp.Address])
+	assert.Equal(t, 5, senders[w2.kp.Address])
+}
+
+func TestRunSignFail(t *testing.T) {
+	mw := &ethsignermocks.Wallet{}
+	mw.On("GetAccounts", mock.Anything).Return([]*ethtypes.Address0xHex{ethtypes.MustNewAddress("0x497eedc4299dea2f2a364be10025d0ad0f702de3")}, nil)
+	mw.On("Sign", mock.Anything, mock.Anything, int64(1337)).Return(nil, fmt.Errorf("pop"))
+	result, err := Run(context.Background(), &Options{
+		Wallet:      mw,
+		ChainID:     1337,
+		Concurrency: 2,
+		Requests:    10,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 10, result.Requests)
+	assert.Equal(t, 10, result.Errors)
+	assert.Equal(t, "pop", result.FirstError)
+}
+
+func TestRunSubmitFail(t *testing.T) {
+	result, err := Run(context.Background(), &Options{
+		Wallet:   newTestWallet(t),
+		ChainID:  1337,
+		Backend:  fakechain.New(&fakechain.Options{ChainID: 1}),
+		Requests: 1,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Errors)
+	assert.Regexp(t, "FF22085", result.FirstError)
+}
+
+func TestRunCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	result, err := Run(ctx, &Options{
+		Wallet:   newTestWallet(t),
+		ChainID:  1337,
+		Requests: 10,
+	})
+	assert.NoError(t, err)
+	assert.Zero(t, result.Requests)
+	assert.Nil(t, result.Latency.P50)
+}
+
+func TestRunGetAccountsFail(t *testing.T) {
+	mw := &ethsignermocks.Wallet{}
+	mw.On("GetAccounts", mock.Anything).Return(nil, fmt.Errorf("pop"))
+	_, err := Run(context.Background(), &Options{Wallet: mw})
+	assert.EqualError(t, err, "pop")
+}
+
+func TestRunNoAccounts(t *testing.T) {
+	mw := &ethsignermocks.Wallet{}
+	mw.On("GetAccounts", mock.Anything).Return([]*ethtypes.Address0xHex{}, nil)
+	_, err := Run(context.Background(), &Options{Wallet: mw})
+	assert.Regexp(t, "FF22306", err)
+}
+
+func TestRunBadParams(t *testing.T) {
+	_, err := Run(context.Background(), &Options{
+		Wallet: newTestWallet(t),
+		Method: testTransfer,
+		Params: []byte(`{"to":"wrong"}`),
+	})
+	assert.Error(t, err)
+}
+
+func TestLatencyPercentiles(t *testing.T) {
+	latencies := make([]time.Duration, 100)
+	for i := range latencies {
+		latencies[i] = time.Duration(100-i) * time.Millisecond
+	}
+	l := latencyOf(latencies)
+	assert.Equal(t, time.Millisecond, time.Duration(*l.Min))
+	assert.Equal(t, 50*time.Millisecond, time.Duration(*l.P50))
+	assert.Equal(t, 90*time.Millisecond, time.Duration(*l.P90))
+	assert.Equal(t, 95*time.Millisecond, time.Duration(*l.P95))
+	assert.Equal(t, 99*time.Millisecond, time.Duration(*l.P99))
+	assert.Equal(t, 100*time.Millisecond, time.Duration(*l.Max))
+	assert.Equal(t, 50500*time.Microsecond, time.Duration(*l.Mean))
+
+	l = latencyOf([]time.Duration{time.Second})
+	assert.Equal(t, time.Second, time.Duration(*l.P50))
+	assert.Equal(t, time.Second, time.Duration(*l.P99))
+}
@@ -0,0 +1,61 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package nonces
+
+import (
+	"time"
+
+	"github.com/hyperledger/firefly-common/pkg/config"
+)
+
+const (
+	// ConfigEnabled when true, nonces are assigned locally for eth_sendTransaction requests that do not specify one
+	ConfigEnabled = "enabled"
+	// ConfigPath the directory the next nonce for each address is persisted in. Nonces are only held in memory when not set
+	ConfigPath = "path"
+	// ConfigReconcileInterval how often the local nonce for an address is reconciled with eth_getTransactionCount
+	ConfigReconcileInterval = "reconcileInterval"
+	// ConfigMonitorInterval how often every address is checked for nonce gaps and stalls. Disabled when zero
+	ConfigMonitorInterval = "monitor.interval"
+	// ConfigMonitorStallTimeout how long an address can have outstanding transactions with none mined, before it is reported as stalled
+	ConfigMonitorStallTimeout = "monitor.stallTimeout"
+)
+
+type Config struct {
+	Enabled           bool
+	Path              string
+	ReconcileInterval time.Duration
+	MonitorInterval   time.Duration
+	StallTimeout      time.Duration
+}
+
+func InitConfig(section config.Section) {
+	section.AddKnownKey(ConfigEnabled, false)
+	section.AddKnownKey(ConfigPath)
+	section.AddKnownKey(ConfigReconcileInterval, "30s")
+	section.AddKnownKey(ConfigMonitorInterval, "1m")
+	section.AddKnownKey(ConfigMonitorStallTimeout, "5m")
+}
+
+func ReadConfig(section config.Section) *Config {
+	return &Config{
+		Enabled:           section.GetBool(ConfigEnabled),
+		Path:              section.GetString(ConfigPath),
+		ReconcileInterval: section.GetDuration(ConfigReconcileInterval),
+		MonitorInterval:   section.GetDuration(ConfigMonitorInterval),
+		StallTimeout:      section.GetDuration(ConfigMonitorStallTimeout),
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package nonces
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/firefly-common/pkg/log"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+)
+
+// TransactionCountQuery returns the transaction count for an address at a block tag ("latest" or "pending"),
+// such as with eth_getTransactionCount
+type TransactionCountQuery func(ctx context.Context, addr ethtypes.Address0xHex, blockTag string) (uint64, error)
+
+// Manager assigns nonces locally for each signing address, so that concurrent transactions from
+// the same address never collide. The local nonce is periodically reconciled with the pending
+// transaction count of the node, to pick up transactions submitted by other means.
+type Manager interface {
+	// AssignNonce returns the next nonce for the address. It is persisted as used before it is
+	// returned, so is never assigned again, even after a restart.
+	AssignNonce(ctx context.Context, addr ethtypes.Address0xHex) (uint64, error)
+	// ReturnNonce makes a nonce available to be assigned again, when the transaction it was assigned
+	// to was definitely not submitted. It has no effect unless it is the latest nonce assigned for the address.
+	ReturnNonce(ctx context.Context, addr ethtypes.Address0xHex, nonce uint64)
+	// Addresses returns the addresses nonces have been assigned for, since the manager was created
+	Addresses() []ethtypes.Address0xHex
+	// Status compares the local next nonce of the address with the transaction counts of the node
+	Status(ctx context.Context, addr ethtypes.Address0xHex) (*Status, error)
+	// Resync resets the local next nonce of the address to the pending transaction count of the node,
+	// even if that is lower, so nonces that were assigned to transactions the node never received are
+	// assigned again
+	Resync(ctx context.Context, addr ethtypes.Address0xHex) (*Status, error)
+	// Monitor checks the status of every address on each interval, until the context is cancelled,
+	// and logs a warning for any gaps or stalls
+	Monitor(ctx context.Context, interval time.Duration)
+}
+
+type manager struct {
+	store             Store
+	txCount           TransactionCountQuery
+	reconcileInterval time.Duration
+	stallTimeout      time.Duration
+
+	mux   sync.Mutex
+	addrs map[ethtypes.Address0xHex]*addressNonce
+}
+
+type addressNonce struct {
+	mux           sync.Mutex
+	loaded        bool
+	next          uint64
+	lastReconcile time.Time
+	// The mined transaction count from the last status check, and when it last changed
+	mined       uint64
+	minedChange time.Time
+}
+
+func NewManager(store Store, txCount TransactionCountQuery, conf *Config) Manager {
+	return &manager{
+		store:             store,
+		txCount:           txCount,
+		reconcileInterval: conf.ReconcileInterval,
+		stallTimeout:      conf.StallTimeout,
+		addrs:             make(map[ethtypes.Address0xHex]*addressNonce),
+	}
+}
+
+// lockAddress returns the state for the address, locked so that nonces are assigned sequentially
+func (m *manager) lockAddress(addr ethtypes.Address0xHex) *addressNonce {
+	m.mux.Lock()
+	a := m.addrs[addr]
+	if a == nil {
+		a = &addressNonce{}
+		m.addrs[addr] = a
+	}
+	m.mux.Unlock()
+	a.mux.Lock()
+	return a
+}
+
+// load reads the persisted next nonce for the address the first time it is used, with the address locked
+func (m *manager) load(ctx context.Context, addr ethtypes.Address0xHex, a *addressNonce) error {
+	if a.loaded {
+		return nil
+	}
+	next, found, err := m.store.GetNextNonce(ctx, addr)
+	if err != nil {
+		return err
+	}
+	if found {
+		a.next = next
+	}
+	a.loaded = true
+	return nil
+}
+
+func (m *manager) AssignNonce(ctx context.Context, addr ethtypes.Address0xHex) (uint64, error) {
+	a := m.lockAddress(addr)
+	defer a.mux.Unlock()
+
+	if err := m.load(ctx, addr, a); err != nil {
+		return 0, err
+	}
+
+	if time.Since(a.lastReconcile) >= m.reconcileInterval {
+		chainNext, err := m.txCount(ctx, addr, "pending")
+		if err != nil {
+			return 0, err
+		}
+		// The node is ahead of us if transactions were submitted by other means, but we can be ahead of
+		// the node for transactions that are not yet in its pool (or were dropped from it)
+		if chainNext > a.next {
+			log.L(ctx).Infof("Nonce for %s advanced from %d to %d by pending transaction count", addr, a.next, chainNext)
+			a.next = chainNext
+		}
+		a.lastReconcile = time.Now()
+	}
+
+	nonce := a.next
+	if err := m.store.SetNextNonce(ctx, addr, nonce+1); err != nil {
+		return 0, err
+	}
+	a.next = nonce + 1
+	log.L(ctx).Debugf("Assigned nonce %d to %s", nonce, addr)
+	return nonce, nil
+}
+
+func (m *manager) ReturnNonce(ctx context.Context, addr ethtypes.Address0xHex, nonce uint64) {
+	a := m.lockAddress(addr)
+	defer a.mux.Unlock()
+
+	if !a.loaded || a.next != nonce+1 {
+		log.L(ctx).Warnf("Nonce %d for %s cannot be returned, as later nonces have been assigned", nonce, addr)
+		return
+	}
+	if err := m.store.SetNextNonce(ctx, addr, nonce); err != nil {
+		log.L(ctx).Errorf("Failed to return nonce %d for %s: %s", nonce, addr, err)
+		return
+	}
+	a.next = nonce
+	log.L(ctx).Debugf("Returned nonce %d for %s", nonce, addr)
+}
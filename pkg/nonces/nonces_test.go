@@ -0,0 +1,206 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nonces
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/stretchr/testify/assert"
+)
+
+type errorStore struct {
+	Store
+	getErr error
+	setErr error
+}
+
+func (s *errorStore) GetNextNonce(ctx context.Context, addr ethtypes.Address0xHex) (uint64, bool, error) {
+	if s.getErr != nil {
+		return 0, false, s.getErr
+	}
+	return s.Store.GetNextNonce(ctx, addr)
+}
+
+func (s *errorStore) SetNextNonce(ctx context.Context, addr ethtypes.Address0xHex, next uint64) error {
+	if s.setErr != nil {
+		return s.setErr
+	}
+	return s.Store.SetNextNonce(ctx, addr, next)
+}
+
+// testTxCounts returns each of the counts in turn, then the last one repeatedly
+func testTxCounts(counts ...uint64) (TransactionCountQuery, *int) {
+	var mux sync.Mutex
+	calls := new(int)
+	return func(_ context.Context, addr ethtypes.Address0xHex, blockTag string) (uint64, error) {
+		mux.Lock()
+		defer mux.Unlock()
+		idx := *calls
+		*calls++
+		if idx >= len(counts) {
+			idx = len(counts) - 1
+		}
+		return counts[idx], nil
+	}, calls
+}
+
+func TestReadConfig(t *testing.T) {
+	config.RootConfigReset()
+	section := config.RootSection("nonces")
+	InitConfig(section)
+	section.Set(ConfigEnabled, true)
+	section.Set(ConfigPath, "/data/nonces")
+
+	assert.Equal(t, &Config{
+		Enabled:           true,
+		Path:              "/data/nonces",
+		ReconcileInterval: 30 * time.Second,
+		MonitorInterval:   1 * time.Minute,
+		StallTimeout:      5 * time.Minute,
+	}, ReadConfig(section))
+}
+
+func TestAssignNonceSequential(t *testing.T) {
+	ctx := context.Background()
+	txCount, calls := testTxCounts(10)
+	m := NewManager(NewMemoryStore(), txCount, &Config{ReconcileInterval: time.Hour})
+
+	for i := uint64(10); i < 15; i++ {
+		nonce, err := m.AssignNonce(ctx, testAddr)
+		assert.NoError(t, err)
+		assert.Equal(t, i, nonce)
+	}
+	assert.Equal(t, 1, *calls)
+}
+
+func TestAssignNonceConcurrent(t *testing.T) {
+	ctx := context.Background()
+	txCount, _ := testTxCounts(0)
+	m := NewManager(NewMemoryStore(), txCount, &Config{ReconcileInterval: time.Hour})
+
+	var mux sync.Mutex
+	assigned := make(map[uint64]bool)
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			nonce, err := m.AssignNonce(ctx, testAddr)
+			assert.NoError(t, err)
+			mux.Lock()
+			assert.False(t, assigned[nonce])
+			assigned[nonce] = true
+			mux.Unlock()
+		}()
+	}
+	wg.Wait()
+	assert.Len(t, assigned, 50)
+}
+
+func TestAssignNonceRestart(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewFileStore(ctx, t.TempDir())
+	assert.NoError(t, err)
+
+	txCount, _ := testTxCounts(5)
+	m := NewManager(store, txCount, &Config{ReconcileInterval: time.Hour})
+	for i := 0; i < 3; i++ {
+		_, err := m.AssignNonce(ctx, testAddr)
+		assert.NoError(t, err)
+	}
+
+	// After a restart, we continue from the persisted nonce, even though the node has not seen the transactions
+	m = NewManager(store, txCount, &Config{ReconcileInterval: time.Hour})
+	nonce, err := m.AssignNonce(ctx, testAddr)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(8), nonce)
+}
+
+func TestAssignNonceReconcile(t *testing.T) {
+	ctx := context.Background()
+	txCount, calls := testTxCounts(1, 20)
+	m := NewManager(NewMemoryStore(), txCount, &Config{})
+
+	nonce, err := m.AssignNonce(ctx, testAddr)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), nonce)
+
+	// Transactions submitted by other means move us forwards
+	nonce, err = m.AssignNonce(ctx, testAddr)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(20), nonce)
+	assert.Equal(t, 2, *calls)
+}
+
+func TestAssignNonceQueryFail(t *testing.T) {
+	m := NewManager(NewMemoryStore(), func(_ context.Context, addr ethtypes.Address0xHex, blockTag string) (uint64, error) {
+		return 0, fmt.Errorf("pop")
+	}, &Config{ReconcileInterval: time.Hour})
+
+	_, err := m.AssignNonce(context.Background(), testAddr)
+	assert.Regexp(t, "pop", err)
+}
+
+func TestAssignNonceStoreFail(t *testing.T) {
+	ctx := context.Background()
+	txCount, _ := testTxCounts(0)
+
+	m := NewManager(&errorStore{Store: NewMemoryStore(), getErr: fmt.Errorf("pop")}, txCount, &Config{ReconcileInterval: time.Hour})
+	_, err := m.AssignNonce(ctx, testAddr)
+	assert.Regexp(t, "pop", err)
+
+	m = NewManager(&errorStore{Store: NewMemoryStore(), setErr: fmt.Errorf("pop")}, txCount, &Config{ReconcileInterval: time.Hour})
+	_, err = m.AssignNonce(ctx, testAddr)
+	assert.Regexp(t, "pop", err)
+}
+
+func TestReturnNonce(t *testing.T) {
+	ctx := context.Background()
+	txCount, _ := testTxCounts(0)
+	store := &errorStore{Store: NewMemoryStore()}
+	m := NewManager(store, txCount, &Config{ReconcileInterval: time.Hour})
+
+	// Nothing assigned yet
+	m.ReturnNonce(ctx, testAddr, 0)
+
+	n0, _ := m.AssignNonce(ctx, testAddr)
+	n1, _ := m.AssignNonce(ctx, testAddr)
+
+	// Only the latest nonce can be returned
+	m.ReturnNonce(ctx, testAddr, n0)
+	m.ReturnNonce(ctx, testAddr, n1)
+	nonce, err := m.AssignNonce(ctx, testAddr)
+	assert.NoError(t, err)
+	assert.Equal(t, n1, nonce)
+
+	next, _, _ := store.GetNextNonce(ctx, testAddr)
+	assert.Equal(t, uint64(2), next)
+
+	// A failure to persist leaves the nonce assigned
+	store.setErr = fmt.Errorf("pop")
+	m.ReturnNonce(ctx, testAddr, nonce)
+	store.setErr = nil
+	nonce, err = m.AssignNonce(ctx, testAddr)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), nonce)
+}
@@ -0,0 +1,143 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package nonces
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/hyperledger/firefly-common/pkg/log"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+)
+
+// Status compares the local next nonce of an address with the transaction counts of the node.
+//
+// When the pending count of the node is lower than the local next nonce, the node does not have a transaction
+// for each nonce that has been assigned - either because it was dropped from the transaction pool, or was never
+// received. The node cannot mine any later transactions until those gaps are filled.
+type Status struct {
+	Address ethtypes.Address0xHex `json:"address"`
+	// Next is the next nonce that will be assigned locally
+	Next ethtypes.HexUint64 `json:"next"`
+	// Mined is the transaction count at the latest block
+	Mined ethtypes.HexUint64 `json:"mined"`
+	// Pending is the transaction count including the transaction pool of the node
+	Pending ethtypes.HexUint64 `json:"pending"`
+	// Gap is true when the node is missing transactions for nonces that have been assigned
+	Gap bool `json:"gap"`
+	// Stalled is true when there are transactions waiting to be mined, but none have been for the stall timeout
+	Stalled bool `json:"stalled"`
+}
+
+func (m *manager) Addresses() []ethtypes.Address0xHex {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	addrs := make([]ethtypes.Address0xHex, 0, len(m.addrs))
+	for addr := range m.addrs {
+		addrs = append(addrs, addr)
+	}
+	sort.Slice(addrs, func(i, j int) bool { return addrs[i].String() < addrs[j].String() })
+	return addrs
+}
+
+func (m *manager) chainCounts(ctx context.Context, addr ethtypes.Address0xHex) (mined, pending uint64, err error) {
+	if mined, err = m.txCount(ctx, addr, "latest"); err == nil {
+		pending, err = m.txCount(ctx, addr, "pending")
+	}
+	return mined, pending, err
+}
+
+func (m *manager) Status(ctx context.Context, addr ethtypes.Address0xHex) (*Status, error) {
+	// We query the node before locking the address, so nonces can still be assigned while we wait
+	mined, pending, err := m.chainCounts(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	a := m.lockAddress(addr)
+	defer a.mux.Unlock()
+	if err := m.load(ctx, addr, a); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if a.minedChange.IsZero() || mined != a.mined {
+		a.mined = mined
+		a.minedChange = now
+	}
+	return &Status{
+		Address: addr,
+		Next:    ethtypes.HexUint64(a.next),
+		Mined:   ethtypes.HexUint64(mined),
+		Pending: ethtypes.HexUint64(pending),
+		Gap:     pending < a.next,
+		Stalled: mined < a.next && now.Sub(a.minedChange) >= m.stallTimeout,
+	}, nil
+}
+
+func (m *manager) Resync(ctx context.Context, addr ethtypes.Address0xHex) (*Status, error) {
+	mined, pending, err := m.chainCounts(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	a := m.lockAddress(addr)
+	defer a.mux.Unlock()
+	if err := m.store.SetNextNonce(ctx, addr, pending); err != nil {
+		return nil, err
+	}
+	log.L(ctx).Warnf("Nonce for %s resynchronized from %d to %d (mined=%d)", addr, a.next, pending, mined)
+	a.next = pending
+	a.loaded = true
+	a.lastReconcile = time.Now()
+	return &Status{
+		Address: addr,
+		Next:    ethtypes.HexUint64(pending),
+		Mined:   ethtypes.HexUint64(mined),
+		Pending: ethtypes.HexUint64(pending),
+	}, nil
+}
+
+func (m *manager) Monitor(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			log.L(ctx).Debugf("Nonce monitor stopped")
+			return
+		case <-ticker.C:
+			m.checkAddresses(ctx)
+		}
+	}
+}
+
+func (m *manager) checkAddresses(ctx context.Context) {
+	for _, addr := range m.Addresses() {
+		status, err := m.Status(ctx, addr)
+		if err != nil {
+			log.L(ctx).Warnf("Failed to check the nonces of %s: %s", addr, err)
+			continue
+		}
+		if status.Gap {
+			log.L(ctx).Warnf("Nonce gap for %s: nonces up to %d have been assigned, but the node has no transaction for nonce %d", addr, status.Next-1, status.Pending)
+		}
+		if status.Stalled {
+			log.L(ctx).Warnf("Transactions for %s have stalled: no transactions have been mined for %s, with nonce %d next to be mined and %d assigned", addr, m.stallTimeout, status.Mined, status.Next-1)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nonces
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+)
+
+var testAddr2 = *ethtypes.MustNewAddress("0x497eedc4299dea2f2a364be10025d0ad0f702de3")
+
+// testChain returns the latest and pending transaction counts that are currently set
+type testChain struct {
+	mux     sync.Mutex
+	mined   uint64
+	pending uint64
+	err     error
+}
+
+func (c *testChain) set(mined, pending uint64) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.mined, c.pending = mined, pending
+}
+
+func (c *testChain) txCount(_ context.Context, _ ethtypes.Address0xHex, blockTag string) (uint64, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if c.err != nil {
+		return 0, c.err
+	}
+	if blockTag == "latest" {
+		return c.mined, nil
+	}
+	return c.pending, nil
+}
+
+func TestStatusGapAndStall(t *testing.T) {
+	ctx := context.Background()
+	chain := &testChain{}
+	chain.set(5, 5)
+	m := NewManager(NewMemoryStore(), chain.txCount, &Config{ReconcileInterval: time.Hour, StallTimeout: 50 * time.Millisecond})
+
+	for i := 0; i < 3; i++ {
+		_, err := m.AssignNonce(ctx, testAddr)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, []ethtypes.Address0xHex{testAddr}, m.Addresses())
+
+	// The node only received the first of the transactions
+	chain.set(5, 6)
+	status, err := m.Status(ctx, testAddr)
+	assert.NoError(t, err)
+	assert.Equal(t, &Status{
+		Address: testAddr,
+		Next:    8,
+		Mined:   5,
+		Pending: 6,
+		Gap:     true,
+	}, status)
+
+	// Nothing is mined for the stall timeout
+	time.Sleep(50 * time.Millisecond)
+	status, err = m.Status(ctx, testAddr)
+	assert.NoError(t, err)
+	assert.True(t, status.Stalled)
+
+	// Progress resets the stall timer
+	chain.set(6, 8)
+	status, err = m.Status(ctx, testAddr)
+	assert.NoError(t, err)
+	assert.False(t, status.Gap)
+	assert.False(t, status.Stalled)
+}
+
+func TestStatusUnknownAddress(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	err := store.SetNextNonce(ctx, testAddr, 3)
+	assert.NoError(t, err)
+	chain := &testChain{}
+	chain.set(3, 3)
+	m := NewManager(store, chain.txCount, &Config{})
+
+	status, err := m.Status(ctx, testAddr)
+	assert.NoError(t, err)
+	assert.Equal(t, ethtypes.HexUint64(3), status.Next)
+	assert.False(t, status.Gap)
+	assert.False(t, status.Stalled)
+}
+
+func TestStatusFail(t *testing.T) {
+	ctx := context.Background()
+	chain := &testChain{err: fmt.Errorf("pop")}
+	m := NewManager(NewMemoryStore(), chain.txCount, &Config{})
+	_, err := m.Status(ctx, testAddr)
+	assert.Regexp(t, "pop", err)
+
+	chain.err = nil
+	m = NewManager(&errorStore{Store: NewMemoryStore(), getErr: fmt.Errorf("pop")}, chain.txCount, &Config{})
+	_, err = m.Status(ctx, testAddr)
+	assert.Regexp(t, "pop", err)
+}
+
+func TestResync(t *testing.T) {
+	ctx := context.Background()
+	chain := &testChain{}
+	store := &errorStore{Store: NewMemoryStore()}
+	m := NewManager(store, chain.txCount, &Config{ReconcileInterval: time.Hour})
+
+	for i := 0; i < 5; i++ {
+		_, err := m.AssignNonce(ctx, testAddr)
+		assert.NoError(t, err)
+	}
+
+	// The node only has the first two, so the rest are assigned again
+	chain.set(1, 2)
+	status, err := m.Resync(ctx, testAddr)
+	assert.NoError(t, err)
+	assert.Equal(t, &Status{Address: testAddr, Next: 2, Mined: 1, Pending: 2}, status)
+	nonce, err := m.AssignNonce(ctx, testAddr)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), nonce)
+	next, _, _ := store.GetNextNonce(ctx, testAddr)
+	assert.Equal(t, uint64(3), next)
+
+	store.setErr = fmt.Errorf("pop")
+	_, err = m.Resync(ctx, testAddr)
+	assert.Regexp(t, "pop", err)
+
+	chain.err = fmt.Errorf("pop")
+	_, err = m.Resync(ctx, testAddr)
+	assert.Regexp(t, "pop", err)
+}
+
+func TestMonitor(t *testing.T) {
+	hook := logtest.NewGlobal()
+	defer logrus.StandardLogger().ReplaceHooks(logrus.LevelHooks{})
+
+	ctx, cancelCtx := context.WithCancel(context.Background())
+	chain := &testChain{}
+	m := NewManager(NewMemoryStore(), chain.txCount, &Config{ReconcileInterval: time.Hour})
+	_, err := m.AssignNonce(ctx, testAddr)
+	assert.NoError(t, err)
+	_, err = m.AssignNonce(ctx, testAddr2)
+	assert.NoError(t, err)
+
+	// Neither transaction reached the node, and with no stall timeout they are immediately stalled
+	m.(*manager).checkAddresses(ctx)
+	var gaps, stalls int
+	for _, entry := range hook.AllEntries() {
+		if strings.HasPrefix(entry.Message, "Nonce gap") {
+			gaps++
+		}
+		if strings.HasPrefix(entry.Message, "Transactions for") && strings.Contains(entry.Message, "have stalled") {
+			stalls++
+		}
+	}
+	assert.Equal(t, 2, gaps)
+	assert.Equal(t, 2, stalls)
+
+	chain.err = fmt.Errorf("pop")
+	m.(*manager).checkAddresses(ctx)
+	assert.Regexp(t, "Failed to check the nonces.*pop", hook.LastEntry().Message)
+
+	done := make(chan struct{})
+	go func() {
+		m.Monitor(ctx, time.Millisecond)
+		close(done)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	cancelCtx()
+	<-done
+}
@@ -0,0 +1,135 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package nonces
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/persistence"
+)
+
+// Store persists the next nonce to assign for each address, so that nonces are not reused after a restart.
+// The Manager never calls a store concurrently for the same address.
+type Store interface {
+	GetNextNonce(ctx context.Context, addr ethtypes.Address0xHex) (next uint64, found bool, err error)
+	SetNextNonce(ctx context.Context, addr ethtypes.Address0xHex, next uint64) error
+}
+
+type memoryStore struct {
+	mux    sync.Mutex
+	nonces map[ethtypes.Address0xHex]uint64
+}
+
+// NewMemoryStore returns a store that does not persist nonces, so they are reconciled from the
+// chain after a restart
+func NewMemoryStore() Store {
+	return &memoryStore{nonces: make(map[ethtypes.Address0xHex]uint64)}
+}
+
+func (s *memoryStore) GetNextNonce(_ context.Context, addr ethtypes.Address0xHex) (uint64, bool, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	next, found := s.nonces[addr]
+	return next, found, nil
+}
+
+func (s *memoryStore) SetNextNonce(_ context.Context, addr ethtypes.Address0xHex, next uint64) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.nonces[addr] = next
+	return nil
+}
+
+type fileStore struct {
+	dir string
+}
+
+// NewFileStore returns a store with a file per address in a directory, each holding the next nonce.
+// Files are replaced atomically, so a crash never leaves a partially written nonce.
+func NewFileStore(ctx context.Context, dir string) (Store, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, i18n.NewError(ctx, signermsgs.MsgNonceStoreInitFailed, dir, err)
+	}
+	return &fileStore{dir: dir}, nil
+}
+
+func (s *fileStore) filename(addr ethtypes.Address0xHex) string {
+	return filepath.Join(s.dir, addr.String()+".nonce")
+}
+
+func (s *fileStore) GetNextNonce(ctx context.Context, addr ethtypes.Address0xHex) (uint64, bool, error) {
+	b, err := os.ReadFile(s.filename(addr))
+	if os.IsNotExist(err) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, i18n.NewError(ctx, signermsgs.MsgNonceStoreReadFailed, addr, err)
+	}
+	next, err := strconv.ParseUint(strings.TrimSpace(string(b)), 10, 64)
+	if err != nil {
+		return 0, false, i18n.NewError(ctx, signermsgs.MsgNonceStoreReadFailed, addr, err)
+	}
+	return next, true, nil
+}
+
+func (s *fileStore) SetNextNonce(ctx context.Context, addr ethtypes.Address0xHex, next uint64) error {
+	filename := s.filename(addr)
+	tmpFilename := filename + ".tmp"
+	err := os.WriteFile(tmpFilename, []byte(strconv.FormatUint(next, 10)), 0600)
+	if err == nil {
+		err = os.Rename(tmpFilename, filename)
+	}
+	if err != nil {
+		return i18n.NewError(ctx, signermsgs.MsgNonceStoreWriteFailed, addr, err)
+	}
+	return nil
+}
+
+// PersistenceCollection is the collection of a persistence.Store that nonces are kept in
+const PersistenceCollection = "nonces"
+
+type persistentStore struct {
+	store persistence.Store
+}
+
+// NewPersistentStore returns a store that keeps the next nonce for each address in the shared persistence of the signer
+func NewPersistentStore(store persistence.Store) Store {
+	return &persistentStore{store: store}
+}
+
+func (s *persistentStore) GetNextNonce(ctx context.Context, addr ethtypes.Address0xHex) (uint64, bool, error) {
+	b, found, err := s.store.Get(ctx, PersistenceCollection, addr.String())
+	if err != nil || !found {
+		return 0, false, err
+	}
+	next, err := strconv.ParseUint(string(b), 10, 64)
+	if err != nil {
+		return 0, false, i18n.NewError(ctx, signermsgs.MsgNonceStoreReadFailed, addr, err)
+	}
+	return next, true, nil
+}
+
+func (s *persistentStore) SetNextNonce(ctx context.Context, addr ethtypes.Address0xHex, next uint64) error {
+	return s.store.Put(ctx, PersistenceCollection, addr.String(), []byte(strconv.FormatUint(next, 10)))
+}
@@ -0,0 +1,136 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nonces
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/persistence"
+	"github.com/stretchr/testify/assert"
+)
+
+var testAddr = *ethtypes.MustNewAddress("0xfb075bb99f2aa4c49955bf703509a227d7a12248")
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+
+	_, found, err := s.GetNextNonce(ctx, testAddr)
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	err = s.SetNextNonce(ctx, testAddr, 12345)
+	assert.NoError(t, err)
+	next, found, err := s.GetNextNonce(ctx, testAddr)
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, uint64(12345), next)
+}
+
+func TestFileStore(t *testing.T) {
+	ctx := context.Background()
+	dir := filepath.Join(t.TempDir(), "nonces")
+	s, err := NewFileStore(ctx, dir)
+	assert.NoError(t, err)
+
+	_, found, err := s.GetNextNonce(ctx, testAddr)
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	err = s.SetNextNonce(ctx, testAddr, 12345)
+	assert.NoError(t, err)
+
+	// A new store on the same directory sees the persisted nonce
+	s, err = NewFileStore(ctx, dir)
+	assert.NoError(t, err)
+	next, found, err := s.GetNextNonce(ctx, testAddr)
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, uint64(12345), next)
+
+	b, err := os.ReadFile(filepath.Join(dir, "0xfb075bb99f2aa4c49955bf703509a227d7a12248.nonce"))
+	assert.NoError(t, err)
+	assert.Equal(t, "12345", string(b))
+}
+
+func TestFileStoreBadDir(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "file")
+	err := os.WriteFile(file, []byte{}, 0600)
+	assert.NoError(t, err)
+
+	_, err = NewFileStore(context.Background(), file)
+	assert.Regexp(t, "FF22123", err)
+}
+
+func TestFileStoreReadFail(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	s, err := NewFileStore(ctx, dir)
+	assert.NoError(t, err)
+
+	filename := filepath.Join(dir, "0xfb075bb99f2aa4c49955bf703509a227d7a12248.nonce")
+	err = os.WriteFile(filename, []byte("not a number"), 0600)
+	assert.NoError(t, err)
+	_, _, err = s.GetNextNonce(ctx, testAddr)
+	assert.Regexp(t, "FF22124", err)
+
+	err = os.Remove(filename)
+	assert.NoError(t, err)
+	err = os.Mkdir(filename, 0700)
+	assert.NoError(t, err)
+	_, _, err = s.GetNextNonce(ctx, testAddr)
+	assert.Regexp(t, "FF22124", err)
+}
+
+func TestFileStoreWriteFail(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	s, err := NewFileStore(ctx, dir)
+	assert.NoError(t, err)
+
+	err = os.Mkdir(filepath.Join(dir, fmt.Sprintf("%s.nonce.tmp", testAddr)), 0700)
+	assert.NoError(t, err)
+	err = s.SetNextNonce(ctx, testAddr, 1)
+	assert.Regexp(t, "FF22125", err)
+}
+
+func TestPersistentStore(t *testing.T) {
+	ctx := context.Background()
+	p := persistence.NewMemoryStore()
+	s := NewPersistentStore(p)
+
+	_, found, err := s.GetNextNonce(ctx, testAddr)
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	err = s.SetNextNonce(ctx, testAddr, 12345)
+	assert.NoError(t, err)
+	next, found, err := s.GetNextNonce(ctx, testAddr)
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, uint64(12345), next)
+
+	err = p.Put(ctx, PersistenceCollection, testAddr.String(), []byte("bad"))
+	assert.NoError(t, err)
+	_, _, err = s.GetNextNonce(ctx, testAddr)
+	assert.Regexp(t, "FF22124", err)
+}
@@ -0,0 +1,362 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package objectstore
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/md5" //nolint:gosec // the MD5 of an SSE-C key is required by the S3 API, not used for security
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-common/pkg/log"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+)
+
+const (
+	// emptyPayloadHash is the SHA-256 of an empty body, as every request is a GET
+	emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	// maxErrorBody limits how much of an error response is read
+	maxErrorBody = 4096
+
+	headerSSE                  = "X-Amz-Server-Side-Encryption"
+	headerSSEKMSKeyID          = "X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id"
+	headerSSECustomerAlgorithm = "X-Amz-Server-Side-Encryption-Customer-Algorithm"
+	headerSSECustomerKey       = "X-Amz-Server-Side-Encryption-Customer-Key"
+	headerSSECustomerKeyMD5    = "X-Amz-Server-Side-Encryption-Customer-Key-Md5"
+)
+
+// client makes the requests to an S3-compatible object store, signed with AWS Signature Version 4.
+// Only the requests the wallet needs are supported - listing objects, and getting an object.
+type client struct {
+	endpoint        *url.URL
+	region          string
+	bucket          string
+	pathStyle       bool
+	accessKeyID     string
+	secretAccessKey string
+	sessionToken    string
+	sse             ServerSideEncryptionConfig
+	customerKey     []byte
+	httpClient      *http.Client
+	now             func() time.Time
+}
+
+// object is an object listed in the store
+type object struct {
+	Key  string `xml:"Key"`
+	ETag string `xml:"ETag"`
+}
+
+type listBucketResult struct {
+	Contents              []*object `xml:"Contents"`
+	IsTruncated           bool      `xml:"IsTruncated"`
+	NextContinuationToken string    `xml:"NextContinuationToken"`
+}
+
+type errorResponse struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+// newClient validates the configuration and returns a client, or the first problem with the configuration
+func newClient(ctx context.Context, conf *Config) (*client, error) {
+	c, problems := validateConfig(ctx, conf)
+	if len(problems) > 0 {
+		return nil, problems[0]
+	}
+	return c, nil
+}
+
+// validateConfig returns a client for the configuration, along with every problem found with it
+func validateConfig(ctx context.Context, conf *Config) (c *client, problems []error) {
+	c = &client{
+		region:          conf.Region,
+		bucket:          conf.Bucket,
+		pathStyle:       conf.PathStyle,
+		accessKeyID:     conf.AccessKeyID,
+		secretAccessKey: conf.SecretAccessKey,
+		sessionToken:    conf.SessionToken,
+		sse:             conf.ServerSideEncryption,
+		httpClient:      &http.Client{Timeout: fftypes.ParseToDuration(conf.RequestTimeout)},
+		now:             time.Now,
+	}
+
+	if conf.Endpoint == "" {
+		problems = append(problems, i18n.NewError(ctx, signermsgs.MsgObjectStoreSettingRequired, ConfigEndpoint))
+	} else {
+		u, err := url.Parse(conf.Endpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			problems = append(problems, i18n.NewError(ctx, signermsgs.MsgObjectStoreBadEndpoint, conf.Endpoint))
+		}
+		c.endpoint = u
+	}
+	if conf.Bucket == "" {
+		problems = append(problems, i18n.NewError(ctx, signermsgs.MsgObjectStoreSettingRequired, ConfigBucket))
+	}
+	if conf.Region == "" {
+		problems = append(problems, i18n.NewError(ctx, signermsgs.MsgObjectStoreSettingRequired, ConfigRegion))
+	}
+
+	// Credentials not in the configuration are taken from the environment, as with the AWS CLI and SDKs
+	if c.accessKeyID == "" && c.secretAccessKey == "" {
+		c.accessKeyID = os.Getenv("AWS_ACCESS_KEY_ID")
+		c.secretAccessKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+		if c.sessionToken == "" {
+			c.sessionToken = os.Getenv("AWS_SESSION_TOKEN")
+		}
+	}
+	if c.accessKeyID == "" || c.secretAccessKey == "" {
+		problems = append(problems, i18n.NewError(ctx, signermsgs.MsgObjectStoreNoCredentials))
+	}
+
+	switch c.sse.Mode {
+	case "", SSEModeAES256, SSEModeKMS:
+	case SSEModeCustomer:
+		if c.sse.CustomerKeyFile == "" {
+			problems = append(problems, i18n.NewError(ctx, signermsgs.MsgObjectStoreSettingRequired, ConfigServerSideEncryptionCustomerKeyFile))
+		} else {
+			b, err := os.ReadFile(c.sse.CustomerKeyFile)
+			if err == nil {
+				c.customerKey, err = base64.StdEncoding.DecodeString(strings.TrimSpace(string(b)))
+			}
+			if err != nil || len(c.customerKey) != 32 {
+				problems = append(problems, i18n.NewError(ctx, signermsgs.MsgObjectStoreCustomerKeyInvalid, c.sse.CustomerKeyFile))
+			}
+		}
+	default:
+		problems = append(problems, i18n.NewError(ctx, signermsgs.MsgObjectStoreBadSSEMode, c.sse.Mode))
+	}
+	return c, problems
+}
+
+// listObjects lists every object with the prefix, following continuation tokens until the listing is complete
+func (c *client) listObjects(ctx context.Context, prefix string) ([]*object, error) {
+	var objects []*object
+	continuationToken := ""
+	for {
+		query := url.Values{
+			"list-type": []string{"2"},
+			"prefix":    []string{prefix},
+		}
+		if continuationToken != "" {
+			query.Set("continuation-token", continuationToken)
+		}
+		res, err := c.do(ctx, http.MethodGet, "", query, http.Header{})
+		if err != nil {
+			return nil, err
+		}
+		var page listBucketResult
+		err = xml.NewDecoder(res.Body).Decode(&page)
+		_ = res.Body.Close()
+		if err != nil {
+			return nil, i18n.NewError(ctx, signermsgs.MsgObjectStoreListInvalid, err)
+		}
+		objects = append(objects, page.Contents...)
+		if !page.IsTruncated {
+			return objects, nil
+		}
+		if page.NextContinuationToken == "" {
+			return nil, i18n.NewError(ctx, signermsgs.MsgObjectStoreListInvalid, "truncated without a continuation token")
+		}
+		continuationToken = page.NextContinuationToken
+	}
+}
+
+// getObject downloads an object, refusing it if it is not stored with the configured server-side encryption
+func (c *client) getObject(ctx context.Context, key string) (data []byte, etag string, err error) {
+	header := http.Header{}
+	if c.sse.Mode == SSEModeCustomer {
+		keyMD5 := md5.Sum(c.customerKey) //nolint:gosec
+		header.Set(headerSSECustomerAlgorithm, "AES256")
+		header.Set(headerSSECustomerKey, base64.StdEncoding.EncodeToString(c.customerKey))
+		header.Set(headerSSECustomerKeyMD5, base64.StdEncoding.EncodeToString(keyMD5[:]))
+	}
+	res, err := c.do(ctx, http.MethodGet, key, url.Values{}, header)
+	if err != nil {
+		return nil, "", err
+	}
+	defer res.Body.Close()
+	if err := c.checkEncryption(ctx, key, res.Header); err != nil {
+		return nil, "", err
+	}
+	data, err = io.ReadAll(res.Body)
+	if err != nil {
+		return nil, "", i18n.WrapError(ctx, err, signermsgs.MsgObjectStoreRequestFailed, http.MethodGet, key)
+	}
+	return data, res.Header.Get("ETag"), nil
+}
+
+// checkEncryption checks the headers of an object confirm it is stored with the configured server-side encryption
+func (c *client) checkEncryption(ctx context.Context, key string, header http.Header) error {
+	switch c.sse.Mode {
+	case SSEModeAES256, SSEModeKMS:
+		if header.Get(headerSSE) != c.sse.Mode {
+			return i18n.NewError(ctx, signermsgs.MsgObjectStoreUnencrypted, key, c.sse.Mode)
+		}
+		// S3 returns the ARN of the key, which ends with the ID of the key
+		kmsKeyID := header.Get(headerSSEKMSKeyID)
+		if c.sse.Mode == SSEModeKMS && c.sse.KMSKeyID != "" && kmsKeyID != c.sse.KMSKeyID && !strings.HasSuffix(kmsKeyID, "/"+c.sse.KMSKeyID) {
+			return i18n.NewError(ctx, signermsgs.MsgObjectStoreKMSKeyMismatch, key, kmsKeyID, c.sse.KMSKeyID)
+		}
+	case SSEModeCustomer:
+		if header.Get(headerSSECustomerAlgorithm) != "AES256" {
+			return i18n.NewError(ctx, signermsgs.MsgObjectStoreUnencrypted, key, c.sse.Mode)
+		}
+	}
+	return nil
+}
+
+// do sends a signed request for an object (or the bucket, when the key is empty), returning an error for
+// any status other than 200
+func (c *client) do(ctx context.Context, method, key string, query url.Values, header http.Header) (*http.Response, error) {
+	u := *c.endpoint
+	objectPath := strings.TrimSuffix(u.Path, "/")
+	if c.pathStyle {
+		objectPath += "/" + c.bucket
+	} else {
+		u.Host = c.bucket + "." + u.Host
+	}
+	objectPath += "/" + key
+	u.Path = objectPath
+	u.RawPath = uriEncode(objectPath, false)
+	u.RawQuery = canonicalQuery(query)
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), nil)
+	if err != nil {
+		return nil, i18n.WrapError(ctx, err, signermsgs.MsgObjectStoreRequestFailed, method, key)
+	}
+	req.Header = header
+	c.sign(req, emptyPayloadHash)
+
+	log.L(ctx).Debugf("Object store request %s %s", method, u.String())
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, i18n.WrapError(ctx, err, signermsgs.MsgObjectStoreRequestFailed, method, key)
+	}
+	if res.StatusCode != http.StatusOK {
+		defer res.Body.Close()
+		b, _ := io.ReadAll(io.LimitReader(res.Body, maxErrorBody))
+		var errRes errorResponse
+		msg := strings.TrimSpace(string(b))
+		if xml.Unmarshal(b, &errRes) == nil && errRes.Code != "" {
+			msg = fmt.Sprintf("%s: %s", errRes.Code, errRes.Message)
+		}
+		return nil, i18n.NewError(ctx, signermsgs.MsgObjectStoreRequestStatus, method, key, res.StatusCode, msg)
+	}
+	return res, nil
+}
+
+// sign adds the AWS Signature Version 4 authorization header to the request, signing every header it
+// has been given along with the host
+func (c *client) sign(req *http.Request, payloadHash string) {
+	now := c.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if c.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", c.sessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.Join(strings.Fields(strings.Join(values, ",")), " ")
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := date + "/" + c.region + "/s3/aws4_request"
+	canonicalRequestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(canonicalRequestHash[:])
+
+	signingKey := hmacSHA256([]byte("AWS4"+c.secretAccessKey), date)
+	signingKey = hmacSHA256(signingKey, c.region)
+	signingKey = hmacSHA256(signingKey, "s3")
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		c.accessKeyID, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// canonicalQuery encodes the query sorted by name, as required for the canonical request of a signature
+func canonicalQuery(query url.Values) string {
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	params := make([]string, 0, len(query))
+	for _, name := range names {
+		for _, value := range query[name] {
+			params = append(params, uriEncode(name, true)+"="+uriEncode(value, true))
+		}
+	}
+	return strings.Join(params, "&")
+}
+
+// uriEncode percent-encodes every byte other than the unreserved characters of RFC 3986, and
+// (unless encodeSlash is set) the slashes between the segments of a path
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case ch >= 'A' && ch <= 'Z', ch >= 'a' && ch <= 'z', ch >= '0' && ch <= '9',
+			ch == '-', ch == '.', ch == '_', ch == '~', ch == '/' && !encodeSlash:
+			b.WriteByte(ch)
+		default:
+			fmt.Fprintf(&b, "%%%02X", ch)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,122 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package objectstore
+
+import (
+	"github.com/hyperledger/firefly-common/pkg/config"
+)
+
+const (
+	// ConfigEnabled whether the keys of the file wallet are synchronized from an S3-compatible object store
+	ConfigEnabled = "enabled"
+	// ConfigEndpoint the URL of the object store, such as https://s3.us-east-1.amazonaws.com
+	ConfigEndpoint = "endpoint"
+	// ConfigRegion the region used to sign requests to the object store
+	ConfigRegion = "region"
+	// ConfigBucket the bucket containing the keys
+	ConfigBucket = "bucket"
+	// ConfigPrefix the prefix of every object in the bucket
+	ConfigPrefix = "prefix"
+	// ConfigNamespace the namespace of this signer, under the prefix, so one bucket can hold the keys of many signers
+	ConfigNamespace = "namespace"
+	// ConfigPathStyle when true, the bucket is in the path of requests rather than the host name
+	ConfigPathStyle = "pathStyle"
+	// ConfigAccessKeyID the access key ID - defaults to the AWS_ACCESS_KEY_ID environment variable
+	ConfigAccessKeyID = "accessKeyID"
+	// ConfigSecretAccessKey the secret access key - defaults to the AWS_SECRET_ACCESS_KEY environment variable
+	ConfigSecretAccessKey = "secretAccessKey"
+	// ConfigSessionToken the session token of temporary credentials - defaults to the AWS_SESSION_TOKEN environment variable
+	ConfigSessionToken = "sessionToken"
+	// ConfigServerSideEncryptionMode the server-side encryption every key object must be stored with: AES256, aws:kms or customer
+	ConfigServerSideEncryptionMode = "serverSideEncryption.mode"
+	// ConfigServerSideEncryptionKMSKeyID the KMS key every key object must be encrypted with, when the mode is aws:kms
+	ConfigServerSideEncryptionKMSKeyID = "serverSideEncryption.kmsKeyID"
+	// ConfigServerSideEncryptionCustomerKeyFile the file containing the base64 encoded key, when the mode is customer
+	ConfigServerSideEncryptionCustomerKeyFile = "serverSideEncryption.customerKeyFile"
+	// ConfigRefreshInterval how often to synchronize the keys from the object store - zero to only synchronize on startup and refresh
+	ConfigRefreshInterval = "refreshInterval"
+	// ConfigRequestTimeout the timeout of each request to the object store
+	ConfigRequestTimeout = "requestTimeout"
+)
+
+const (
+	SSEModeAES256   = "AES256"
+	SSEModeKMS      = "aws:kms"
+	SSEModeCustomer = "customer"
+)
+
+type Config struct {
+	Enabled              bool
+	Endpoint             string
+	Region               string
+	Bucket               string
+	Prefix               string
+	Namespace            string
+	PathStyle            bool
+	AccessKeyID          string
+	SecretAccessKey      string
+	SessionToken         string
+	ServerSideEncryption ServerSideEncryptionConfig
+	RefreshInterval      string
+	RequestTimeout       string
+}
+
+type ServerSideEncryptionConfig struct {
+	Mode            string
+	KMSKeyID        string
+	CustomerKeyFile string
+}
+
+func InitConfig(section config.Section) {
+	section.AddKnownKey(ConfigEnabled, false)
+	section.AddKnownKey(ConfigEndpoint)
+	section.AddKnownKey(ConfigRegion, "us-east-1")
+	section.AddKnownKey(ConfigBucket)
+	section.AddKnownKey(ConfigPrefix)
+	section.AddKnownKey(ConfigNamespace)
+	section.AddKnownKey(ConfigPathStyle, false)
+	section.AddKnownKey(ConfigAccessKeyID)
+	section.AddKnownKey(ConfigSecretAccessKey)
+	section.AddKnownKey(ConfigSessionToken)
+	section.AddKnownKey(ConfigServerSideEncryptionMode)
+	section.AddKnownKey(ConfigServerSideEncryptionKMSKeyID)
+	section.AddKnownKey(ConfigServerSideEncryptionCustomerKeyFile)
+	section.AddKnownKey(ConfigRefreshInterval, "1m")
+	section.AddKnownKey(ConfigRequestTimeout, "30s")
+}
+
+func ReadConfig(section config.Section) *Config {
+	return &Config{
+		Enabled:         section.GetBool(ConfigEnabled),
+		Endpoint:        section.GetString(ConfigEndpoint),
+		Region:          section.GetString(ConfigRegion),
+		Bucket:          section.GetString(ConfigBucket),
+		Prefix:          section.GetString(ConfigPrefix),
+		Namespace:       section.GetString(ConfigNamespace),
+		PathStyle:       section.GetBool(ConfigPathStyle),
+		AccessKeyID:     section.GetString(ConfigAccessKeyID),
+		SecretAccessKey: section.GetString(ConfigSecretAccessKey),
+		SessionToken:    section.GetString(ConfigSessionToken),
+		ServerSideEncryption: ServerSideEncryptionConfig{
+			Mode:            section.GetString(ConfigServerSideEncryptionMode),
+			KMSKeyID:        section.GetString(ConfigServerSideEncryptionKMSKeyID),
+			CustomerKeyFile: section.GetString(ConfigServerSideEncryptionCustomerKeyFile),
+		},
+		RefreshInterval: section.GetString(ConfigRefreshInterval),
+		RequestTimeout:  section.GetString(ConfigRequestTimeout),
+	}
+}
@@ -0,0 +1,298 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package objectstore
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-common/pkg/log"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/hyperledger/firefly-signer/pkg/ethsigner"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/fswallet"
+)
+
+// objectAddressRegex finds the address in the name of an object, such as a key or password file named by its address
+var objectAddressRegex = regexp.MustCompile(`(?:^|[^0-9a-fA-F])([0-9a-fA-F]{40})(?:[^0-9a-fA-F]|$)`)
+
+// fileWallet is every capability of the filesystem wallet, so the object store wallet offers the same
+type fileWallet interface {
+	fswallet.Wallet
+	ethsigner.WalletPersonalSign
+	ethsigner.WalletDigestSign
+	ethsigner.WalletSchnorrSign
+}
+
+// objectStoreWallet is a filesystem wallet whose Keystore V3 and metadata files are synchronized from
+// an S3-compatible object store. The directory of the filesystem wallet is a local cache of the objects,
+// so each is only downloaded again when its entity tag changes.
+type objectStoreWallet struct {
+	fileWallet
+	client          *client
+	root            string
+	cachePath       string
+	refreshInterval time.Duration
+	syncMux         sync.Mutex
+	etags           map[string]string
+	synced          bool
+	pollerCancel    context.CancelFunc
+	pollerDone      chan struct{}
+}
+
+// NewWallet returns a filesystem wallet, configured by fsConf, that synchronizes the objects under
+// <prefix>/<namespace>/ in the bucket into its path before every refresh. The layout of the objects
+// below the namespace is the same as the layout of the files in a filesystem wallet.
+func NewWallet(ctx context.Context, conf *Config, fsConf *fswallet.Config) (fswallet.Wallet, error) {
+	if fsConf.Path == "" {
+		return nil, i18n.NewError(ctx, signermsgs.MsgWalletPathNotSet)
+	}
+	c, err := newClient(ctx, conf)
+	if err != nil {
+		return nil, err
+	}
+	fw, err := fswallet.NewFilesystemWallet(ctx, fsConf)
+	if err != nil {
+		return nil, err
+	}
+	return &objectStoreWallet{
+		fileWallet:      fw.(fileWallet),
+		client:          c,
+		root:            objectRoot(conf),
+		cachePath:       fsConf.Path,
+		refreshInterval: fftypes.ParseToDuration(conf.RefreshInterval),
+		etags:           make(map[string]string),
+	}, nil
+}
+
+// CheckConfig validates the configuration of the object store without connecting to it, returning every problem found
+func CheckConfig(ctx context.Context, conf *Config) []error {
+	_, problems := validateConfig(ctx, conf)
+	return problems
+}
+
+// objectRoot is the prefix of every object of the wallet - the prefix and namespace, with a trailing slash
+func objectRoot(conf *Config) string {
+	var parts []string
+	for _, p := range []string{conf.Prefix, conf.Namespace} {
+		if p = strings.Trim(p, "/"); p != "" {
+			parts = append(parts, p)
+		}
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return strings.Join(parts, "/") + "/"
+}
+
+func (w *objectStoreWallet) Initialize(ctx context.Context) error {
+	if err := os.MkdirAll(w.cachePath, 0o700); err != nil {
+		return i18n.WrapError(ctx, err, signermsgs.MsgObjectStoreCacheWriteFailed, w.root, w.cachePath)
+	}
+	// Synchronize before the filesystem wallet starts, so its initial scan finds every key
+	if err := w.sync(ctx); err != nil {
+		return err
+	}
+	if err := w.fileWallet.Initialize(ctx); err != nil {
+		return err
+	}
+	if w.refreshInterval > 0 {
+		pCtx, pCancel := context.WithCancel(log.WithLogField(ctx, "objectstore", w.client.bucket))
+		w.pollerCancel = pCancel
+		w.pollerDone = make(chan struct{})
+		go w.poll(pCtx)
+	}
+	return nil
+}
+
+func (w *objectStoreWallet) Refresh(ctx context.Context) error {
+	if err := w.sync(ctx); err != nil {
+		return err
+	}
+	return w.fileWallet.Refresh(ctx)
+}
+
+func (w *objectStoreWallet) Close() error {
+	if w.pollerCancel != nil {
+		w.pollerCancel()
+		<-w.pollerDone
+	}
+	return w.fileWallet.Close()
+}
+
+// poll refreshes the wallet at the refresh interval, so keys added to the object store are found
+// without a persistent volume shared with whatever adds them
+func (w *objectStoreWallet) poll(ctx context.Context) {
+	defer close(w.pollerDone)
+	ticker := time.NewTicker(w.refreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := w.Refresh(ctx); err != nil {
+				log.L(ctx).Errorf("Failed to refresh the wallet from the object store: %s", err)
+			}
+		}
+	}
+}
+
+// sync downloads every object that is new, or has changed, since it was cached - and removes the
+// cached copy of every object that has been deleted. Objects that are not stored with the configured
+// server-side encryption are refused, without stopping the other objects being synchronized.
+// The first sync also removes every file in the wallet directory that is not the copy of a listed object,
+// as the objects of files cached before the wallet started might have been deleted since.
+func (w *objectStoreWallet) sync(ctx context.Context) error {
+	w.syncMux.Lock()
+	defer w.syncMux.Unlock()
+
+	objects, err := w.client.listObjects(ctx, w.root)
+	if err != nil {
+		return err
+	}
+	listed := make(map[string]bool, len(objects))
+	listedPaths := make(map[string]bool, len(objects))
+	for _, o := range objects {
+		if strings.HasSuffix(o.Key, "/") {
+			continue // a directory placeholder
+		}
+		cachedPath, ok := w.cachedPath(o.Key)
+		if !ok {
+			log.L(ctx).Warnf("Ignoring object '%s' in the object store, as it cannot be stored in the wallet directory", o.Key)
+			continue
+		}
+		listed[o.Key] = true
+		listedPaths[cachedPath] = true
+		if w.etags[o.Key] == o.ETag {
+			if _, err := os.Stat(cachedPath); err == nil {
+				continue
+			}
+		}
+		data, etag, err := w.client.getObject(ctx, o.Key)
+		if err != nil {
+			// The cached copy is removed, and its key locked, so a key whose object has been replaced by one that
+			// is refused (or that cannot be downloaded) is no longer used
+			log.L(ctx).Errorf("Failed to synchronize object '%s': %s", o.Key, err)
+			w.removeCached(ctx, o.Key, cachedPath)
+			continue
+		}
+		if err := writeCached(cachedPath, data); err != nil {
+			return i18n.WrapError(ctx, err, signermsgs.MsgObjectStoreCacheWriteFailed, o.Key, w.cachePath)
+		}
+		log.L(ctx).Debugf("Synchronized object '%s' (etag=%s)", o.Key, etag)
+		if _, cached := w.etags[o.Key]; cached {
+			w.lockObjectKeys(ctx, o.Key) // so keys loaded from the previous copy are not used
+		}
+		w.etags[o.Key] = etag
+	}
+	if !w.synced {
+		if err := w.removeUnlisted(ctx, listedPaths); err != nil {
+			return i18n.WrapError(ctx, err, signermsgs.MsgObjectStoreCacheWriteFailed, w.root, w.cachePath)
+		}
+		w.synced = true
+	}
+	for key := range w.etags {
+		if !listed[key] {
+			cachedPath, _ := w.cachedPath(key)
+			w.removeCached(ctx, key, cachedPath)
+		}
+	}
+	return nil
+}
+
+// cachedPath returns the path in the wallet directory of an object, or false if the key of the
+// object is not a clean relative path below the root (so cannot escape the wallet directory)
+func (w *objectStoreWallet) cachedPath(key string) (string, bool) {
+	rel := strings.TrimPrefix(key, w.root)
+	if rel == "" || rel != path.Clean(rel) || path.IsAbs(rel) || rel == ".." || strings.HasPrefix(rel, "../") {
+		return "", false
+	}
+	return filepath.Join(w.cachePath, filepath.FromSlash(rel)), true
+}
+
+// removeCached removes the cached copy of an object, and locks any key that was loaded from it
+func (w *objectStoreWallet) removeCached(ctx context.Context, key, cachedPath string) {
+	_, cached := w.etags[key]
+	delete(w.etags, key)
+	err := os.Remove(cachedPath)
+	if err != nil && !os.IsNotExist(err) {
+		log.L(ctx).Errorf("Failed to remove '%s' from the wallet directory: %s", cachedPath, err)
+	}
+	if cached || err == nil {
+		w.lockObjectKeys(ctx, key)
+	}
+}
+
+// lockObjectKeys removes the keys that might have been loaded from an object from memory, as the filesystem
+// wallet keeps unlocked and cached keys after their files change. That is the key of the address in the name
+// of the object, or every key when the name has no address - such as a key file named by the metadata of another.
+func (w *objectStoreWallet) lockObjectKeys(ctx context.Context, key string) {
+	var err error
+	if match := objectAddressRegex.FindStringSubmatch(path.Base(key)); match != nil {
+		addr, _ := ethtypes.NewAddress(match[1]) // safe as the match is 40 hex characters
+		err = w.Lock(ctx, *addr)
+	} else {
+		err = w.LockAll(ctx)
+	}
+	if err != nil {
+		log.L(ctx).Errorf("Failed to lock the keys of object '%s': %s", key, err)
+	}
+}
+
+// removeUnlisted removes every file in the wallet directory that is not the cached copy of a listed object
+func (w *objectStoreWallet) removeUnlisted(ctx context.Context, listedPaths map[string]bool) error {
+	return filepath.WalkDir(w.cachePath, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || listedPaths[p] {
+			return err
+		}
+		log.L(ctx).Infof("Removing '%s' from the wallet directory, as it is not in the object store", p)
+		return os.Remove(p)
+	})
+}
+
+// writeCached replaces the file atomically, so the filesystem wallet never reads a partial file
+func writeCached(cachedPath string, data []byte) error {
+	dir := filepath.Dir(cachedPath)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(dir, ".objectstore-*")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), cachedPath)
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+	}
+	return err
+}
@@ -0,0 +1,679 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package objectstore
+
+import (
+	"context"
+	"crypto/md5" //nolint:gosec
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/hyperledger/firefly-signer/pkg/ethsigner"
+	"github.com/hyperledger/firefly-signer/pkg/fswallet"
+	"github.com/stretchr/testify/assert"
+)
+
+const testAddr = "1f185718734552d08278aa70f804580bab5fd2b4"
+
+type fakeObject struct {
+	data   []byte
+	etag   string
+	header http.Header
+}
+
+// fakeS3 is a path-style S3-compatible store, with a bucket named "keys"
+type fakeS3 struct {
+	t        *testing.T
+	mux      sync.Mutex
+	objects  map[string]*fakeObject
+	gets     map[string]int
+	pageSize int
+	server   *httptest.Server
+}
+
+func newFakeS3(t *testing.T) *fakeS3 {
+	s := &fakeS3{t: t, objects: map[string]*fakeObject{}, gets: map[string]int{}, pageSize: 1}
+	s.server = httptest.NewServer(http.HandlerFunc(s.handle))
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+func (s *fakeS3) put(key string, data []byte, header http.Header) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if header == nil {
+		header = http.Header{}
+	}
+	sum := md5.Sum(data) //nolint:gosec
+	s.objects[key] = &fakeObject{data: data, etag: fmt.Sprintf(`"%x"`, sum), header: header}
+}
+
+func (s *fakeS3) putFixture(key, fixture string, header http.Header) {
+	b, err := os.ReadFile(filepath.Join("../../test/keystore_toml", fixture))
+	assert.NoError(s.t, err)
+	s.put(key, b, header)
+}
+
+func (s *fakeS3) getCount(key string) int {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.gets[key]
+}
+
+func (s *fakeS3) handle(w http.ResponseWriter, r *http.Request) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	assert.Regexp(s.t, `^AWS4-HMAC-SHA256 Credential=ak/\d{8}/us-east-1/s3/aws4_request, SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature=[0-9a-f]{64}$`,
+		r.Header.Get("Authorization"))
+	key, isObject := strings.CutPrefix(r.URL.Path, "/keys/")
+	if !isObject {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if key == "" {
+		s.list(w, r)
+		return
+	}
+	o := s.objects[key]
+	if o == nil {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`<Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message></Error>`))
+		return
+	}
+	s.gets[key]++
+	for k, v := range o.header {
+		w.Header()[k] = v
+	}
+	w.Header().Set("ETag", o.etag)
+	_, _ = w.Write(o.data)
+}
+
+func (s *fakeS3) list(w http.ResponseWriter, r *http.Request) {
+	assert.Equal(s.t, "2", r.URL.Query().Get("list-type"))
+	var keys []string
+	for key := range s.objects {
+		if strings.HasPrefix(key, r.URL.Query().Get("prefix")) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	start, _ := strconv.Atoi(r.URL.Query().Get("continuation-token"))
+	end := start + s.pageSize
+	res := &listBucketResult{}
+	if end < len(keys) {
+		res.IsTruncated = true
+		res.NextContinuationToken = strconv.Itoa(end)
+	} else {
+		end = len(keys)
+	}
+	for _, key := range keys[start:end] {
+		res.Contents = append(res.Contents, &object{Key: key, ETag: s.objects[key].etag})
+	}
+	b, _ := xml.Marshal(struct {
+		XMLName xml.Name `xml:"ListBucketResult"`
+		*listBucketResult
+	}{listBucketResult: res})
+	_, _ = w.Write(b)
+}
+
+func newTestConfig(s *fakeS3) *Config {
+	config.RootConfigReset()
+	section := config.RootSection("ut_objectstore_config")
+	InitConfig(section)
+	section.Set(ConfigEnabled, true)
+	section.Set(ConfigEndpoint, s.server.URL)
+	section.Set(ConfigBucket, "keys")
+	section.Set(ConfigPrefix, "/signers/")
+	section.Set(ConfigNamespace, "ns1")
+	section.Set(ConfigPathStyle, true)
+	section.Set(ConfigAccessKeyID, "ak")
+	section.Set(ConfigSecretAccessKey, "sk")
+	section.Set(ConfigRefreshInterval, "0")
+	return ReadConfig(section)
+}
+
+func newTestFSConfig(t *testing.T) *fswallet.Config {
+	section := config.RootSection("ut_fs_config")
+	fswallet.InitConfig(section)
+	section.Set(fswallet.ConfigPath, filepath.Join(t.TempDir(), "cache"))
+	section.Set(fswallet.ConfigFilenamesPrimaryExt, ".key.json")
+	section.Set(fswallet.ConfigFilenamesPasswordExt, ".pwd")
+	section.Set(fswallet.ConfigDisableListener, true)
+	return fswallet.ReadConfig(section)
+}
+
+func newTestWallet(t *testing.T, s *fakeS3, conf *Config) (context.Context, *objectStoreWallet) {
+	ctx := context.Background()
+	w, err := NewWallet(ctx, conf, newTestFSConfig(t))
+	assert.NoError(t, err)
+	t.Cleanup(func() { _ = w.Close() })
+	return ctx, w.(*objectStoreWallet)
+}
+
+func TestSyncSignAndCache(t *testing.T) {
+	s := newFakeS3(t)
+	s.putFixture("signers/ns1/"+testAddr+".key.json", testAddr+".key.json", nil)
+	s.putFixture("signers/ns1/"+testAddr+".pwd", testAddr+".pwd", nil)
+	s.putFixture("signers/ns2/abcd1234abcd1234abcd1234abcd1234abcd1234.key.json", "abcd1234abcd1234abcd1234abcd1234abcd1234.key.json", nil)
+	s.put("signers/ns1/subdir/", nil, nil)
+	ctx, w := newTestWallet(t, s, newTestConfig(s))
+
+	err := w.Initialize(ctx)
+	assert.NoError(t, err)
+
+	accounts, err := w.GetAccounts(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, accounts, 1)
+	assert.Equal(t, "0x"+testAddr, accounts[0].String())
+
+	b, err := w.Sign(ctx, &ethsigner.Transaction{
+		From: json.RawMessage(`"0x` + testAddr + `"`),
+	}, 2022)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, b)
+
+	fi, err := os.Stat(filepath.Join(w.cachePath, testAddr+".key.json"))
+	assert.NoError(t, err)
+	if os.PathSeparator == '/' {
+		assert.Equal(t, os.FileMode(0o600), fi.Mode().Perm())
+	}
+
+	// Unchanged objects are not downloaded again
+	err = w.Refresh(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, s.getCount("signers/ns1/"+testAddr+".pwd"))
+
+	// Changed objects are
+	s.put("signers/ns1/"+testAddr+".pwd", []byte("new password"), nil)
+	err = w.Refresh(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, s.getCount("signers/ns1/"+testAddr+".pwd"))
+	b, err = os.ReadFile(filepath.Join(w.cachePath, testAddr+".pwd"))
+	assert.NoError(t, err)
+	assert.Equal(t, "new password", string(b))
+
+	// As are objects deleted from the cache
+	err = os.Remove(filepath.Join(w.cachePath, testAddr+".pwd"))
+	assert.NoError(t, err)
+	err = w.Refresh(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, s.getCount("signers/ns1/"+testAddr+".pwd"))
+
+	// Objects deleted from the store are removed from the cache
+	s.mux.Lock()
+	delete(s.objects, "signers/ns1/"+testAddr+".pwd")
+	s.mux.Unlock()
+	err = w.Refresh(ctx)
+	assert.NoError(t, err)
+	_, err = os.Stat(filepath.Join(w.cachePath, testAddr+".pwd"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestSyncLocksRemovedKeys(t *testing.T) {
+	s := newFakeS3(t)
+	s.putFixture("signers/ns1/"+testAddr+".key.json", testAddr+".key.json", nil)
+	s.putFixture("signers/ns1/"+testAddr+".pwd", testAddr+".pwd", nil)
+	ctx, w := newTestWallet(t, s, newTestConfig(s))
+	err := w.Initialize(ctx)
+	assert.NoError(t, err)
+
+	sign := func() {
+		_, err := w.Sign(ctx, &ethsigner.Transaction{From: json.RawMessage(`"0x` + testAddr + `"`)}, 2022)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, w.SignerCacheStats().Items)
+	}
+
+	// A key loaded from a copy that has changed is evicted
+	sign()
+	keyFile, err := os.ReadFile(filepath.Join("../../test/keystore_toml", testAddr+".key.json"))
+	assert.NoError(t, err)
+	s.put("signers/ns1/"+testAddr+".key.json", append(keyFile, '\n'), nil)
+	err = w.Refresh(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, w.SignerCacheStats().Items)
+
+	// As is a key whose object is deleted
+	sign()
+	s.mux.Lock()
+	delete(s.objects, "signers/ns1/"+testAddr+".key.json")
+	s.mux.Unlock()
+	err = w.Refresh(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, w.SignerCacheStats().Items)
+	_, err = w.Sign(ctx, &ethsigner.Transaction{From: json.RawMessage(`"0x` + testAddr + `"`)}, 2022)
+	assert.Error(t, err)
+}
+
+func TestLockObjectKeysWithoutAddress(t *testing.T) {
+	s := newFakeS3(t)
+	s.putFixture("signers/ns1/"+testAddr+".key.json", testAddr+".key.json", nil)
+	s.putFixture("signers/ns1/"+testAddr+".pwd", testAddr+".pwd", nil)
+	ctx, w := newTestWallet(t, s, newTestConfig(s))
+	err := w.Initialize(ctx)
+	assert.NoError(t, err)
+	_, err = w.Sign(ctx, &ethsigner.Transaction{From: json.RawMessage(`"0x` + testAddr + `"`)}, 2022)
+	assert.NoError(t, err)
+
+	// Keys of other addresses are kept, but an object without an address in its name might be the key of any
+	w.lockObjectKeys(ctx, "signers/ns1/abcd1234abcd1234abcd1234abcd1234abcd1234.key.json")
+	assert.Equal(t, 1, w.SignerCacheStats().Items)
+	w.lockObjectKeys(ctx, "signers/ns1/keys/signing.json")
+	assert.Equal(t, 0, w.SignerCacheStats().Items)
+}
+
+func TestSyncRemovesUnlistedFilesOnStart(t *testing.T) {
+	s := newFakeS3(t)
+	s.putFixture("signers/ns1/"+testAddr+".key.json", testAddr+".key.json", nil)
+	ctx, w := newTestWallet(t, s, newTestConfig(s))
+
+	// Files left from before the wallet started, whose objects have since been deleted
+	stale := []string{
+		filepath.Join(w.cachePath, testAddr+".pwd"),
+		filepath.Join(w.cachePath, "ab", "abcd1234abcd1234abcd1234abcd1234abcd1234.key.json"),
+	}
+	for _, f := range stale {
+		err := os.MkdirAll(filepath.Dir(f), 0o700)
+		assert.NoError(t, err)
+		err = os.WriteFile(f, []byte("{}"), 0o600)
+		assert.NoError(t, err)
+	}
+
+	err := w.Initialize(ctx)
+	assert.NoError(t, err)
+	for _, f := range stale {
+		_, err = os.Stat(f)
+		assert.True(t, os.IsNotExist(err))
+	}
+	_, err = os.Stat(filepath.Join(w.cachePath, testAddr+".key.json"))
+	assert.NoError(t, err)
+
+	// Only the first sync removes files that are not in the listing
+	err = os.WriteFile(stale[0], []byte("{}"), 0o600)
+	assert.NoError(t, err)
+	err = w.Refresh(ctx)
+	assert.NoError(t, err)
+	_, err = os.Stat(stale[0])
+	assert.NoError(t, err)
+}
+
+func TestSyncRemoveUnlistedFails(t *testing.T) {
+	s := newFakeS3(t)
+	ctx, w := newTestWallet(t, s, newTestConfig(s))
+	w.cachePath = filepath.Join(t.TempDir(), "missing")
+
+	err := w.sync(ctx)
+	assert.Regexp(t, "FF22317", err)
+	assert.False(t, w.synced)
+}
+
+func TestSyncNoPrefix(t *testing.T) {
+	s := newFakeS3(t)
+	s.putFixture(testAddr+".key.json", testAddr+".key.json", nil)
+	conf := newTestConfig(s)
+	conf.Prefix = ""
+	conf.Namespace = ""
+	ctx, w := newTestWallet(t, s, conf)
+
+	err := w.Initialize(ctx)
+	assert.NoError(t, err)
+	_, err = os.Stat(filepath.Join(w.cachePath, testAddr+".key.json"))
+	assert.NoError(t, err)
+}
+
+func TestSyncIgnoresKeysOutsideWallet(t *testing.T) {
+	s := newFakeS3(t)
+	s.put("signers/ns1/../escape.key.json", []byte("{}"), nil)
+	s.put("signers/ns1/a//b.key.json", []byte("{}"), nil)
+	ctx, w := newTestWallet(t, s, newTestConfig(s))
+
+	err := w.Initialize(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, w.etags)
+	_, err = os.Stat(filepath.Join(filepath.Dir(w.cachePath), "escape.key.json"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestSyncRefusesUnencrypted(t *testing.T) {
+	s := newFakeS3(t)
+	encrypted := http.Header{headerSSE: []string{SSEModeAES256}}
+	s.putFixture("signers/ns1/"+testAddr+".key.json", testAddr+".key.json", encrypted)
+	s.putFixture("signers/ns1/"+testAddr+".pwd", testAddr+".pwd", nil)
+	conf := newTestConfig(s)
+	conf.ServerSideEncryption.Mode = SSEModeAES256
+	ctx, w := newTestWallet(t, s, conf)
+
+	err := w.Initialize(ctx)
+	assert.NoError(t, err)
+	_, err = os.Stat(filepath.Join(w.cachePath, testAddr+".key.json"))
+	assert.NoError(t, err)
+	_, err = os.Stat(filepath.Join(w.cachePath, testAddr+".pwd"))
+	assert.True(t, os.IsNotExist(err))
+
+	// A cached object replaced by one that is refused is removed, and its key locked
+	s.putFixture("signers/ns1/"+testAddr+".pwd", testAddr+".pwd", encrypted)
+	err = w.Refresh(ctx)
+	assert.NoError(t, err)
+	_, err = w.Sign(ctx, &ethsigner.Transaction{From: json.RawMessage(`"0x` + testAddr + `"`)}, 2022)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, w.SignerCacheStats().Items)
+	s.putFixture("signers/ns1/"+testAddr+".key.json", "abcd1234abcd1234abcd1234abcd1234abcd1234.key.json", nil)
+	err = w.Refresh(ctx)
+	assert.NoError(t, err)
+	_, err = os.Stat(filepath.Join(w.cachePath, testAddr+".key.json"))
+	assert.True(t, os.IsNotExist(err))
+	assert.NotContains(t, w.etags, "signers/ns1/"+testAddr+".key.json")
+	assert.Equal(t, 0, w.SignerCacheStats().Items)
+}
+
+func TestSyncCustomerKey(t *testing.T) {
+	s := newFakeS3(t)
+	key := []byte("0123456789abcdef0123456789abcdef")
+	keyFile := filepath.Join(t.TempDir(), "sse-c.key")
+	err := os.WriteFile(keyFile, []byte(base64.StdEncoding.EncodeToString(key)+"\n"), 0o600)
+	assert.NoError(t, err)
+	s.putFixture("signers/ns1/"+testAddr+".key.json", testAddr+".key.json", http.Header{headerSSECustomerAlgorithm: []string{"AES256"}})
+	s.putFixture("signers/ns1/"+testAddr+".pwd", testAddr+".pwd", nil)
+	conf := newTestConfig(s)
+	conf.ServerSideEncryption.Mode = SSEModeCustomer
+	conf.ServerSideEncryption.CustomerKeyFile = keyFile
+	ctx, w := newTestWallet(t, s, conf)
+
+	keyMD5 := md5.Sum(key) //nolint:gosec
+	s.server.Config.Handler = http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("list-type") == "" {
+			assert.Equal(t, "AES256", r.Header.Get(headerSSECustomerAlgorithm))
+			assert.Equal(t, base64.StdEncoding.EncodeToString(key), r.Header.Get(headerSSECustomerKey))
+			assert.Equal(t, base64.StdEncoding.EncodeToString(keyMD5[:]), r.Header.Get(headerSSECustomerKeyMD5))
+			assert.Contains(t, r.Header.Get("Authorization"), "SignedHeaders=host;x-amz-content-sha256;x-amz-date;x-amz-server-side-encryption-customer-algorithm;x-amz-server-side-encryption-customer-key;x-amz-server-side-encryption-customer-key-md5,")
+			r.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential=ak/20240101/us-east-1/s3/aws4_request, SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature="+strings.Repeat("0", 64))
+		}
+		s.handle(rw, r)
+	})
+
+	err = w.Initialize(ctx)
+	assert.NoError(t, err)
+	_, err = os.Stat(filepath.Join(w.cachePath, testAddr+".key.json"))
+	assert.NoError(t, err)
+	_, err = os.Stat(filepath.Join(w.cachePath, testAddr+".pwd"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestCheckEncryptionKMS(t *testing.T) {
+	ctx := context.Background()
+	c := &client{sse: ServerSideEncryptionConfig{Mode: SSEModeKMS, KMSKeyID: "1234abcd-12ab-34cd-56ef-1234567890ab"}}
+
+	err := c.checkEncryption(ctx, "key1", http.Header{
+		headerSSE:         []string{SSEModeKMS},
+		headerSSEKMSKeyID: []string{"arn:aws:kms:us-east-1:111122223333:key/1234abcd-12ab-34cd-56ef-1234567890ab"},
+	})
+	assert.NoError(t, err)
+
+	err = c.checkEncryption(ctx, "key1", http.Header{
+		headerSSE:         []string{SSEModeKMS},
+		headerSSEKMSKeyID: []string{"arn:aws:kms:us-east-1:111122223333:key/other"},
+	})
+	assert.Regexp(t, "FF22316.*key1.*other", err)
+
+	err = c.checkEncryption(ctx, "key1", http.Header{
+		headerSSE: []string{SSEModeAES256},
+	})
+	assert.Regexp(t, "FF22315.*key1.*aws:kms", err)
+
+	c.sse.KMSKeyID = ""
+	err = c.checkEncryption(ctx, "key1", http.Header{
+		headerSSE:         []string{SSEModeKMS},
+		headerSSEKMSKeyID: []string{"any"},
+	})
+	assert.NoError(t, err)
+}
+
+func TestPollFindsNewKeys(t *testing.T) {
+	s := newFakeS3(t)
+	conf := newTestConfig(s)
+	conf.RefreshInterval = "10ms"
+	ctx, w := newTestWallet(t, s, conf)
+
+	err := w.Initialize(ctx)
+	assert.NoError(t, err)
+
+	s.putFixture("signers/ns1/"+testAddr+".key.json", testAddr+".key.json", nil)
+	assert.Eventually(t, func() bool {
+		accounts, err := w.GetAccounts(ctx)
+		return err == nil && len(accounts) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestPollLogsErrors(t *testing.T) {
+	s := newFakeS3(t)
+	conf := newTestConfig(s)
+	conf.RefreshInterval = "1ms"
+	ctx, w := newTestWallet(t, s, conf)
+
+	err := w.Initialize(ctx)
+	assert.NoError(t, err)
+
+	failed := make(chan struct{})
+	var once sync.Once
+	s.server.Config.Handler = http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		once.Do(func() { close(failed) })
+		rw.WriteHeader(http.StatusInternalServerError)
+	})
+	<-failed
+	err = w.Close()
+	assert.NoError(t, err)
+}
+
+func TestInitializeListFails(t *testing.T) {
+	s := newFakeS3(t)
+	ctx, w := newTestWallet(t, s, newTestConfig(s))
+	s.server.Config.Handler = http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusForbidden)
+		_, _ = rw.Write([]byte(`<Error><Code>AccessDenied</Code><Message>Access Denied</Message></Error>`))
+	})
+
+	err := w.Initialize(ctx)
+	assert.Regexp(t, "FF22313.*GET.*403.*AccessDenied: Access Denied", err)
+
+	err = w.Refresh(ctx)
+	assert.Regexp(t, "FF22313", err)
+}
+
+func TestInitializeFileWalletFails(t *testing.T) {
+	s := newFakeS3(t)
+	ctx := context.Background()
+	fsConf := newTestFSConfig(t)
+	fsConf.Filenames.PasswordPath = filepath.Join(t.TempDir(), "missing")
+	fsConf.DisableListener = false
+	fsConf.Path = filepath.Join(t.TempDir(), "cache")
+	w, err := NewWallet(ctx, newTestConfig(s), fsConf)
+	assert.NoError(t, err)
+	defer w.Close()
+
+	// Replace the cache directory with a file once synchronized, so the listener cannot watch it
+	s.server.Config.Handler = http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		_ = os.RemoveAll(fsConf.Path)
+		_ = os.WriteFile(fsConf.Path, []byte{}, 0o600)
+		s.handle(rw, r)
+	})
+	err = w.Initialize(ctx)
+	assert.Error(t, err)
+}
+
+func TestInitializeCacheDirFails(t *testing.T) {
+	s := newFakeS3(t)
+	ctx := context.Background()
+	fsConf := newTestFSConfig(t)
+	notADir := filepath.Join(t.TempDir(), "file")
+	err := os.WriteFile(notADir, []byte{}, 0o600)
+	assert.NoError(t, err)
+	fsConf.Path = filepath.Join(notADir, "cache")
+	w, err := NewWallet(ctx, newTestConfig(s), fsConf)
+	assert.NoError(t, err)
+
+	err = w.Initialize(ctx)
+	assert.Regexp(t, "FF22317", err)
+}
+
+func TestSyncCacheWriteFails(t *testing.T) {
+	s := newFakeS3(t)
+	s.put("signers/ns1/shard/"+testAddr+".key.json", []byte("{}"), nil)
+	ctx, w := newTestWallet(t, s, newTestConfig(s))
+	err := os.MkdirAll(w.cachePath, 0o700)
+	assert.NoError(t, err)
+	err = os.WriteFile(filepath.Join(w.cachePath, "shard"), []byte{}, 0o600)
+	assert.NoError(t, err)
+
+	err = w.Initialize(ctx)
+	assert.Regexp(t, "FF22317.*shard", err)
+}
+
+func TestWriteCachedFails(t *testing.T) {
+	dir := t.TempDir()
+	err := os.Mkdir(filepath.Join(dir, "target"), 0o700)
+	assert.NoError(t, err)
+	err = os.WriteFile(filepath.Join(dir, "target", "child"), []byte{}, 0o600)
+	assert.NoError(t, err)
+
+	// Renaming over a non-empty directory fails, and the temporary file is cleaned up
+	err = writeCached(filepath.Join(dir, "target"), []byte("data"))
+	assert.Error(t, err)
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	if os.PathSeparator == '/' && os.Geteuid() != 0 {
+		readOnly := filepath.Join(dir, "readonly")
+		err = os.Mkdir(readOnly, 0o500)
+		assert.NoError(t, err)
+		err = writeCached(filepath.Join(readOnly, "file"), []byte("data"))
+		assert.Error(t, err)
+	}
+}
+
+func TestRemoveCachedFails(t *testing.T) {
+	s := newFakeS3(t)
+	ctx, w := newTestWallet(t, s, newTestConfig(s))
+	dir := filepath.Join(t.TempDir(), "dir")
+	err := os.Mkdir(dir, 0o700)
+	assert.NoError(t, err)
+	err = os.WriteFile(filepath.Join(dir, "child"), []byte{}, 0o600)
+	assert.NoError(t, err)
+	w.etags["key1"] = `"etag"`
+
+	w.removeCached(ctx, "key1", dir)
+	assert.Empty(t, w.etags)
+}
+
+func TestNewWalletBadConfig(t *testing.T) {
+	s := newFakeS3(t)
+	ctx := context.Background()
+
+	fsConf := newTestFSConfig(t)
+	fsConf.Path = ""
+	_, err := NewWallet(ctx, newTestConfig(s), fsConf)
+	assert.Regexp(t, "FF22213", err)
+
+	conf := newTestConfig(s)
+	conf.Bucket = ""
+	_, err = NewWallet(ctx, conf, newTestFSConfig(t))
+	assert.Regexp(t, "FF22307.*bucket", err)
+
+	fsConf = newTestFSConfig(t)
+	fsConf.Filenames.ShardPrefixLength = -1
+	_, err = NewWallet(ctx, newTestConfig(s), fsConf)
+	assert.Error(t, err)
+}
+
+func TestCheckConfig(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+
+	problems := CheckConfig(ctx, &Config{
+		ServerSideEncryption: ServerSideEncryptionConfig{Mode: "wrong"},
+	})
+	assert.Len(t, problems, 5)
+	assert.Regexp(t, "FF22307.*endpoint", problems[0])
+	assert.Regexp(t, "FF22307.*bucket", problems[1])
+	assert.Regexp(t, "FF22307.*region", problems[2])
+	assert.Regexp(t, "FF22311", problems[3])
+	assert.Regexp(t, "FF22309.*wrong", problems[4])
+
+	problems = CheckConfig(ctx, &Config{
+		Endpoint:             "ftp://example.com",
+		Bucket:               "keys",
+		Region:               "us-east-1",
+		AccessKeyID:          "ak",
+		SecretAccessKey:      "sk",
+		ServerSideEncryption: ServerSideEncryptionConfig{Mode: SSEModeCustomer},
+	})
+	assert.Len(t, problems, 2)
+	assert.Regexp(t, "FF22308.*ftp://example.com", problems[0])
+	assert.Regexp(t, "FF22307.*customerKeyFile", problems[1])
+
+	badKeyFile := filepath.Join(t.TempDir(), "bad.key")
+	err := os.WriteFile(badKeyFile, []byte(base64.StdEncoding.EncodeToString([]byte("too short"))), 0o600)
+	assert.NoError(t, err)
+	for _, keyFile := range []string{badKeyFile, filepath.Join(t.TempDir(), "missing.key")} {
+		problems = CheckConfig(ctx, &Config{
+			Endpoint:             "https://s3.us-east-1.amazonaws.com",
+			Bucket:               "keys",
+			Region:               "us-east-1",
+			AccessKeyID:          "ak",
+			SecretAccessKey:      "sk",
+			ServerSideEncryption: ServerSideEncryptionConfig{Mode: SSEModeCustomer, CustomerKeyFile: keyFile},
+		})
+		assert.Len(t, problems, 1)
+		assert.Regexp(t, "FF22310", problems[0])
+	}
+}
+
+func TestCredentialsFromEnvironment(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv("AWS_ACCESS_KEY_ID", "envak")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "envsk")
+	t.Setenv("AWS_SESSION_TOKEN", "envtoken")
+
+	c, err := newClient(ctx, &Config{
+		Endpoint: "https://s3.us-east-1.amazonaws.com",
+		Bucket:   "keys",
+		Region:   "us-east-1",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "envak", c.accessKeyID)
+	assert.Equal(t, "envsk", c.secretAccessKey)
+	assert.Equal(t, "envtoken", c.sessionToken)
+
+	req := httptest.NewRequest(http.MethodGet, "https://keys.s3.us-east-1.amazonaws.com/", nil)
+	req.Header = http.Header{}
+	c.sign(req, emptyPayloadHash)
+	assert.Equal(t, "envtoken", req.Header.Get("X-Amz-Security-Token"))
+	assert.Contains(t, req.Header.Get("Authorization"), "SignedHeaders=host;x-amz-content-sha256;x-amz-date;x-amz-security-token,")
+}
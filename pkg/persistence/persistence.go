@@ -0,0 +1,197 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package persistence
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+)
+
+// Store is the persistence shared by the components of the signer that keep state across restarts, such as
+// the nonce manager and admin key ceremonies. Each component keeps its records in its own collection.
+//
+// The signer provides an in-memory and a filesystem implementation. An application embedding the signer can
+// provide its own, such as one backed by an embedded or external database, so every component persists to it.
+// Implementations must be safe to call concurrently.
+type Store interface {
+	// Get returns the value of a key, or found=false if there is no value for the key
+	Get(ctx context.Context, collection, key string) (value []byte, found bool, err error)
+	// Put sets the value of a key, replacing any previous value atomically
+	Put(ctx context.Context, collection, key string, value []byte) error
+	// Delete removes a key, and is not an error if there is no value for the key
+	Delete(ctx context.Context, collection, key string) error
+	// List returns every key in a collection, in sorted order
+	List(ctx context.Context, collection string) ([]string, error)
+}
+
+// Collections and keys are used as filenames by the filesystem store, so are restricted to safe characters
+var validName = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+const tmpSuffix = ".tmp"
+
+func checkNames(ctx context.Context, names ...string) error {
+	for _, name := range names {
+		if !validName.MatchString(name) || strings.HasSuffix(name, tmpSuffix) {
+			return i18n.NewError(ctx, signermsgs.MsgPersistenceInvalidKey, name)
+		}
+	}
+	return nil
+}
+
+type memoryStore struct {
+	mux         sync.Mutex
+	collections map[string]map[string][]byte
+}
+
+// NewMemoryStore returns a store that does not persist anything, so every record is lost on a restart
+func NewMemoryStore() Store {
+	return &memoryStore{collections: make(map[string]map[string][]byte)}
+}
+
+func (s *memoryStore) Get(ctx context.Context, collection, key string) ([]byte, bool, error) {
+	if err := checkNames(ctx, collection, key); err != nil {
+		return nil, false, err
+	}
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	value, found := s.collections[collection][key]
+	return value, found, nil
+}
+
+func (s *memoryStore) Put(ctx context.Context, collection, key string, value []byte) error {
+	if err := checkNames(ctx, collection, key); err != nil {
+		return err
+	}
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	c := s.collections[collection]
+	if c == nil {
+		c = make(map[string][]byte)
+		s.collections[collection] = c
+	}
+	c[key] = append([]byte{}, value...)
+	return nil
+}
+
+func (s *memoryStore) Delete(ctx context.Context, collection, key string) error {
+	if err := checkNames(ctx, collection, key); err != nil {
+		return err
+	}
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	delete(s.collections[collection], key)
+	return nil
+}
+
+func (s *memoryStore) List(ctx context.Context, collection string) ([]string, error) {
+	if err := checkNames(ctx, collection); err != nil {
+		return nil, err
+	}
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	keys := make([]string, 0, len(s.collections[collection]))
+	for key := range s.collections[collection] {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+type fileStore struct {
+	dir string
+}
+
+// NewFileStore returns a store with a directory per collection, and a file per key holding its value.
+// Files are replaced atomically, so a crash never leaves a partially written value.
+func NewFileStore(ctx context.Context, dir string) (Store, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, i18n.NewError(ctx, signermsgs.MsgPersistenceInitFailed, dir, err)
+	}
+	return &fileStore{dir: dir}, nil
+}
+
+func (s *fileStore) Get(ctx context.Context, collection, key string) ([]byte, bool, error) {
+	if err := checkNames(ctx, collection, key); err != nil {
+		return nil, false, err
+	}
+	b, err := os.ReadFile(filepath.Join(s.dir, collection, key))
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, i18n.NewError(ctx, signermsgs.MsgPersistenceReadFailed, key, collection, err)
+	}
+	return b, true, nil
+}
+
+func (s *fileStore) Put(ctx context.Context, collection, key string, value []byte) error {
+	if err := checkNames(ctx, collection, key); err != nil {
+		return err
+	}
+	filename := filepath.Join(s.dir, collection, key)
+	tmpFilename := filename + tmpSuffix
+	err := os.MkdirAll(filepath.Dir(filename), 0700)
+	if err == nil {
+		err = os.WriteFile(tmpFilename, value, 0600)
+	}
+	if err == nil {
+		err = os.Rename(tmpFilename, filename)
+	}
+	if err != nil {
+		return i18n.NewError(ctx, signermsgs.MsgPersistenceWriteFailed, key, collection, err)
+	}
+	return nil
+}
+
+func (s *fileStore) Delete(ctx context.Context, collection, key string) error {
+	if err := checkNames(ctx, collection, key); err != nil {
+		return err
+	}
+	if err := os.Remove(filepath.Join(s.dir, collection, key)); err != nil && !os.IsNotExist(err) {
+		return i18n.NewError(ctx, signermsgs.MsgPersistenceWriteFailed, key, collection, err)
+	}
+	return nil
+}
+
+func (s *fileStore) List(ctx context.Context, collection string) ([]string, error) {
+	if err := checkNames(ctx, collection); err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(filepath.Join(s.dir, collection))
+	if os.IsNotExist(err) {
+		return []string{}, nil
+	}
+	if err != nil {
+		return nil, i18n.NewError(ctx, signermsgs.MsgPersistenceListFailed, collection, err)
+	}
+	keys := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		// Skip anything that is not a value, such as a temporary file left by a crash during a write
+		if !entry.IsDir() && checkNames(ctx, entry.Name()) == nil {
+			keys = append(keys, entry.Name())
+		}
+	}
+	return keys, nil
+}
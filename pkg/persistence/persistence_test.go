@@ -0,0 +1,130 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package persistence
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testStore(t *testing.T, s Store) {
+	ctx := context.Background()
+
+	keys, err := s.List(ctx, "things")
+	assert.NoError(t, err)
+	assert.Empty(t, keys)
+	_, found, err := s.Get(ctx, "things", "a")
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	err = s.Put(ctx, "things", "b", []byte("bee"))
+	assert.NoError(t, err)
+	err = s.Put(ctx, "things", "a", []byte("ay"))
+	assert.NoError(t, err)
+	err = s.Put(ctx, "others", "c", []byte("sea"))
+	assert.NoError(t, err)
+	err = s.Put(ctx, "things", "a", []byte("aye"))
+	assert.NoError(t, err)
+
+	value, found, err := s.Get(ctx, "things", "a")
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "aye", string(value))
+	keys, err = s.List(ctx, "things")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, keys)
+
+	err = s.Delete(ctx, "things", "a")
+	assert.NoError(t, err)
+	err = s.Delete(ctx, "things", "a")
+	assert.NoError(t, err)
+	keys, err = s.List(ctx, "things")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"b"}, keys)
+
+	for _, name := range []string{"", "../up", "a/b", ".hidden", "x.tmp"} {
+		_, _, err = s.Get(ctx, "things", name)
+		assert.Regexp(t, "FF22300", err)
+		err = s.Put(ctx, "things", name, []byte{})
+		assert.Regexp(t, "FF22300", err)
+		err = s.Delete(ctx, "things", name)
+		assert.Regexp(t, "FF22300", err)
+		_, err = s.List(ctx, name)
+		assert.Regexp(t, "FF22300", err)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, NewMemoryStore())
+}
+
+func TestFileStore(t *testing.T) {
+	ctx := context.Background()
+	dir := filepath.Join(t.TempDir(), "state")
+	s, err := NewFileStore(ctx, dir)
+	assert.NoError(t, err)
+	testStore(t, s)
+
+	// A new store on the same directory sees the persisted values, ignoring anything that is not a value
+	err = os.WriteFile(filepath.Join(dir, "things", "c.tmp"), []byte("partial"), 0600)
+	assert.NoError(t, err)
+	err = os.Mkdir(filepath.Join(dir, "things", "d"), 0700)
+	assert.NoError(t, err)
+	s, err = NewFileStore(ctx, dir)
+	assert.NoError(t, err)
+	keys, err := s.List(ctx, "things")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"b"}, keys)
+	value, found, err := s.Get(ctx, "others", "c")
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "sea", string(value))
+}
+
+func TestFileStoreBadDir(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "file")
+	err := os.WriteFile(file, []byte{}, 0600)
+	assert.NoError(t, err)
+	_, err = NewFileStore(context.Background(), filepath.Join(file, "state"))
+	assert.Regexp(t, "FF22299", err)
+}
+
+func TestFileStoreFailures(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	s, err := NewFileStore(ctx, dir)
+	assert.NoError(t, err)
+
+	// A collection that is a file, and a key that is a non-empty directory
+	err = os.WriteFile(filepath.Join(dir, "things"), []byte{}, 0600)
+	assert.NoError(t, err)
+	err = os.MkdirAll(filepath.Join(dir, "others", "a", "b"), 0700)
+	assert.NoError(t, err)
+
+	err = s.Put(ctx, "things", "a", []byte{})
+	assert.Regexp(t, "FF22302", err)
+	_, err = s.List(ctx, "things")
+	assert.Regexp(t, "FF22303", err)
+	_, _, err = s.Get(ctx, "others", "a")
+	assert.Regexp(t, "FF22301", err)
+	err = s.Delete(ctx, "others", "a")
+	assert.Regexp(t, "FF22302", err)
+}
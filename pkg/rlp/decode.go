@@ -73,8 +73,47 @@ const (
 // - nil if passed an empty byte array
 // - Data if the RLP stream contains a data element in the first position
 // - List if the RLP stream contains a list in the first position
+//
+// The DefaultDecodeLimits apply - use DecodeWithLimits for other limits.
 func Decode(rlpData []byte) (Element, int, error) {
-	decoded, endPos, err := decode(rlpData, 1)
+	return DecodeWithLimits(rlpData, &DefaultDecodeLimits)
+}
+
+// DecodeLimits restrict the RLP that will be decoded, to protect against crafted input
+// from untrusted sources. A zero value for any limit means there is no limit.
+type DecodeLimits struct {
+	// MaxDepth is the maximum nesting of lists, where a top-level list has a depth of one
+	MaxDepth int
+	// MaxSize is the maximum length in bytes of the encoded element - which bounds the
+	// memory used when decoding a large value from a stream
+	MaxSize int
+}
+
+// DefaultDecodeLimits apply to Decode and Unmarshal. They are well beyond the nesting and size of anything
+// in the Ethereum protocol, but stop crafted input from nesting lists without bound.
+var DefaultDecodeLimits = DecodeLimits{
+	MaxDepth: 64,
+	MaxSize:  32 * 1024 * 1024,
+}
+
+func (dl *DecodeLimits) checkDepth(depth int) error {
+	if dl.MaxDepth > 0 && depth > dl.MaxDepth {
+		return fmt.Errorf("RLP list nesting exceeds maximum depth %d", dl.MaxDepth)
+	}
+	return nil
+}
+
+func (dl *DecodeLimits) checkSize(end int) error {
+	if dl.MaxSize > 0 && end > dl.MaxSize {
+		return fmt.Errorf("RLP length %d exceeds maximum size %d", end, dl.MaxSize)
+	}
+	return nil
+}
+
+// DecodeWithLimits is Decode, returning an error if the element exceeds the limits
+func DecodeWithLimits(rlpData []byte, limits *DecodeLimits) (Element, int, error) {
+	dc := &decoder{limits: limits}
+	decoded, endPos, err := dc.decode(rlpData, 1, 0, 0)
 	if err != nil {
 		return nil, -1, err
 	}
@@ -84,7 +123,13 @@ func Decode(rlpData []byte) (Element, int, error) {
 	return nil, 0, nil
 }
 
-func decode(rlpData []byte, limit int) (List, int, error) {
+type decoder struct {
+	limits *DecodeLimits
+}
+
+// decode decodes up to limit elements of rlpData, which is at the given depth of list nesting
+// and offset within the whole RLP element being decoded
+func (dc *decoder) decode(rlpData []byte, limit, depth, offset int) (List, int, error) {
 	l := List{}
 	if len(rlpData) == 0 {
 		return l, 0, nil
@@ -123,6 +168,9 @@ func decode(rlpData []byte, limit int) (List, int, error) {
 			if strLen > len(rlpData)-pos {
 				return nil, -1, fmt.Errorf("length mismatch in RLP for short data (pos=%d len=%d)", pos, strLen)
 			}
+			if err := dc.limits.checkSize(offset + pos + strLen); err != nil {
+				return nil, -1, err
+			}
 			d := make(Data, strLen)
 			copy(d, rlpData[pos:pos+strLen])
 			l = append(l, d)
@@ -137,6 +185,9 @@ func decode(rlpData []byte, limit int) (List, int, error) {
 			// and the string follows the length of the string;
 
 			strLen, newPos, err := extractLongLen(false, prefix, pos, rlpData)
+			if err == nil {
+				err = dc.limits.checkSize(offset + newPos + strLen)
+			}
 			if err != nil {
 				return nil, -1, err
 			}
@@ -158,7 +209,10 @@ func decode(rlpData []byte, limit int) (List, int, error) {
 			if listLen > len(rlpData)-pos {
 				return nil, -1, fmt.Errorf("length mismatch in RLP for short list (pos=%d len=%d)", pos, listLen)
 			}
-			child, _, err := decode(rlpData[pos:pos+listLen], -1)
+			if err := dc.checkList(depth+1, offset+pos+listLen); err != nil {
+				return nil, -1, err
+			}
+			child, _, err := dc.decode(rlpData[pos:pos+listLen], -1, depth+1, offset+pos)
 			if err != nil {
 				return nil, -1, err
 			}
@@ -175,11 +229,14 @@ func decode(rlpData []byte, limit int) (List, int, error) {
 			// the list follows the total payload of the list;
 
 			listLen, newPos, err := extractLongLen(true, prefix, pos, rlpData)
+			if err == nil {
+				err = dc.checkList(depth+1, offset+newPos+listLen)
+			}
 			if err != nil {
 				return nil, -1, err
 			}
 			pos = newPos
-			child, _, err := decode(rlpData[pos:pos+listLen], -1)
+			child, _, err := dc.decode(rlpData[pos:pos+listLen], -1, depth+1, offset+pos)
 			if err != nil {
 				return nil, -1, err
 			}
@@ -195,6 +252,13 @@ func decode(rlpData []byte, limit int) (List, int, error) {
 	return l, pos, nil
 }
 
+func (dc *decoder) checkList(depth, end int) error {
+	if err := dc.limits.checkDepth(depth); err != nil {
+		return err
+	}
+	return dc.limits.checkSize(end)
+}
+
 func extractLongLen(isList bool, prefixByte byte, pos int, rlpData []byte) (dataLen, newPos int, err error) {
 	longPrefix := longString
 	if isList {
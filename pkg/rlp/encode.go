@@ -16,31 +16,45 @@
 
 package rlp
 
-func encodeBytes(inBytes []byte, isList bool) []byte {
-	shortOffset := shortString
-	if isList {
-		shortOffset = shortList
+// appendHeader appends the prefix byte(s) for data or a list with a payload of the given length
+func appendHeader(dst []byte, shortOffset byte, payloadLen int) []byte {
+	if payloadLen <= 55 {
+		// Add the length to same byte as the offset
+		return append(dst, shortOffset+byte(payloadLen))
+	}
+	// The length is too long to fit in a single byte, we have to encode it
+	encodedByteLen := int64ToMinimalBytes(int64(payloadLen))
+	dst = append(dst, shortOffset+shortToLong+byte(len(encodedByteLen)))
+	return append(dst, encodedByteLen...)
+}
+
+func headerLen(payloadLen int) int {
+	if payloadLen <= 55 {
+		return 1
+	}
+	return 1 + len(int64ToMinimalBytes(int64(payloadLen)))
+}
+
+// encodedLen is the number of bytes EncodeTo will append for an element
+func encodedLen(e Element) int {
+	if l, isList := e.(List); isList {
+		payloadLen := l.payloadLen()
+		return headerLen(payloadLen) + payloadLen
 	}
-	if len(inBytes) == 1 &&
-		!isList &&
-		inBytes[0] <= 0x7f {
+	d := e.ToData()
+	if len(d) == 1 && d[0] < shortString {
 		// We don't need the offset, this can be sent as a single byte
-		return inBytes
+		return 1
 	}
-	if len(inBytes) <= 55 {
-		// Add the length to same byte as the offset
-		outBytes := make([]byte, len(inBytes)+1)
-		outBytes[0] = shortOffset + byte(len(inBytes))
-		copy(outBytes[1:], inBytes[0:])
-		return outBytes
+	return headerLen(len(d)) + len(d)
+}
+
+func (l List) payloadLen() int {
+	payloadLen := 0
+	for _, entry := range l {
+		payloadLen += encodedLen(entry)
 	}
-	// The length is too long to fit in a single byte, we have to encode it
-	encodedByteLen := int64ToMinimalBytes(int64(len(inBytes)))
-	outBytes := make([]byte, 1+len(encodedByteLen)+len(inBytes))
-	outBytes[0] = shortOffset + shortToLong + byte(len(encodedByteLen))
-	copy(outBytes[1:], encodedByteLen)
-	copy(outBytes[1+len(encodedByteLen):], inBytes)
-	return outBytes
+	return payloadLen
 }
 
 func int64ToMinimalBytes(v int64) []byte {
@@ -147,3 +147,27 @@ func TestEncodeAddress(t *testing.T) {
 	assert.Equal(t, Data{}, d1)
 
 }
+
+func TestEncodeTo(t *testing.T) {
+
+	l := List{
+		WrapString("cat"),
+		Data{0x0f},
+		List{WrapString(loremIpsumString)},
+		List{},
+		Data(nil),
+	}
+
+	buff := make([]byte, 0, 256)
+	b := l.EncodeTo(append(buff, 0xfe))
+	assert.Equal(t, &buff[:1][0], &b[0]) // no reallocation
+	assert.Equal(t, append([]byte{0xfe}, l.Encode()...), b)
+
+	decoded, _, err := Decode(b[1:])
+	assert.NoError(t, err)
+	assert.Equal(t, List{Data("cat"), Data{0x0f}, List{Data(loremIpsumString)}, List{}, Data{}}, decoded)
+
+	assert.Equal(t, []byte{0x01, 0x83, 'd', 'o', 'g'}, WrapString("dog").EncodeTo([]byte{0x01}))
+	assert.Equal(t, loremIpsumRLPBytes, WrapString(loremIpsumString).EncodeTo(nil))
+
+}
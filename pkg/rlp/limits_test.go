@@ -0,0 +1,109 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rlp
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func nestedLists(depth int) Element {
+	e := Element(Data{0x01})
+	for i := 0; i < depth; i++ {
+		e = List{e}
+	}
+	return e
+}
+
+func TestDecodeMaxDepth(t *testing.T) {
+	limits := &DecodeLimits{MaxDepth: 3}
+
+	e, _, err := DecodeWithLimits(nestedLists(3).Encode(), limits)
+	assert.NoError(t, err)
+	assert.Equal(t, nestedLists(3), e)
+
+	_, _, err = DecodeWithLimits(nestedLists(4).Encode(), limits)
+	assert.Regexp(t, "exceeds maximum depth 3", err)
+
+	// Long lists are checked too
+	long := List{nestedLists(3), Data(loremIpsumRLPBytes[2:])}
+	_, _, err = DecodeWithLimits(long.Encode(), limits)
+	assert.Regexp(t, "exceeds maximum depth 3", err)
+
+	// The default limits apply to Decode and Unmarshal
+	_, _, err = Decode(nestedLists(64).Encode())
+	assert.NoError(t, err)
+	_, _, err = Decode(nestedLists(65).Encode())
+	assert.Regexp(t, "exceeds maximum depth 64", err)
+	var v []interface{}
+	err = Unmarshal(nestedLists(65).Encode(), &v)
+	assert.Regexp(t, "exceeds maximum depth 64", err)
+
+	// No limit with zero limits
+	_, _, err = DecodeWithLimits(nestedLists(1000).Encode(), &DecodeLimits{})
+	assert.NoError(t, err)
+}
+
+func TestDecodeMaxSize(t *testing.T) {
+	lorem := Data(loremIpsumRLPBytes[2:])
+	for name, e := range map[string]Element{
+		"single byte": List{Data(make([]byte, 8)), Data{0x01}}, // always within the size of its list
+		"short data":  Data(make([]byte, 10)),
+		"long data":   lorem,
+		"short list":  List{Data(make([]byte, 9))},
+		"long list":   List{lorem},
+	} {
+		b := e.Encode()
+		decoded, _, err := DecodeWithLimits(b, &DecodeLimits{MaxSize: len(b)})
+		assert.NoError(t, err, name)
+		assert.Equal(t, e, decoded, name)
+
+		_, _, err = DecodeWithLimits(b, &DecodeLimits{MaxSize: len(b) - 1})
+		assert.Regexp(t, "exceeds maximum size", err, name)
+
+		// The size is checked from the declared length, before reading the data
+		_, err = NewStreamDecoderWithLimits(bytes.NewReader(b[0:3]), &DecodeLimits{MaxSize: len(b) - 1}).NextElement()
+		assert.Regexp(t, "exceeds maximum size", err, name)
+	}
+}
+
+func TestStreamDecodeLimits(t *testing.T) {
+	limits := &DecodeLimits{MaxDepth: 2, MaxSize: 4}
+
+	// Each top-level element is limited separately
+	stream := append(List{List{Data{0x01}}}.Encode(), List{WrapString("ab")}.Encode()...)
+	sd := NewStreamDecoderWithLimits(bytes.NewReader(stream), limits)
+	for i := 0; i < 2; i++ {
+		_, err := sd.NextElement()
+		assert.NoError(t, err)
+	}
+	_, err := sd.NextElement()
+	assert.Equal(t, io.EOF, err)
+
+	_, err = NewStreamDecoderWithLimits(bytes.NewReader(nestedLists(3).Encode()), limits).NextElement()
+	assert.Regexp(t, "exceeds maximum depth 2", err)
+
+	_, err = NewStreamDecoderWithLimits(bytes.NewReader(WrapString("abcd").Encode()), limits).NextElement()
+	assert.Regexp(t, "exceeds maximum size 4", err)
+
+	// A declared length too large to allocate fails without reading
+	_, err = NewStreamDecoderWithLimits(bytes.NewReader([]byte{0xbb, 0x7f, 0xff, 0xff, 0xff}), limits).NextElement()
+	assert.Regexp(t, "exceeds maximum size 4", err)
+}
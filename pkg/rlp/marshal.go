@@ -0,0 +1,397 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rlp
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+)
+
+var (
+	bigIntType  = reflect.TypeOf(big.Int{})
+	elementType = reflect.TypeOf((*Element)(nil)).Elem()
+	dataType    = reflect.TypeOf(Data{})
+	listType    = reflect.TypeOf(List{})
+)
+
+// Marshal encodes a Go value as RLP, using reflection:
+//
+//   - Structs are lists of their exported fields, in order
+//   - Byte slices/arrays (including ethtypes.Address0xHex and ethtypes.HexBytes0xPrefix) and strings are data
+//   - Unsigned integers, big.Int and ethtypes.HexInteger are minimal big-endian data, so zero is empty data.
+//     Negative values cannot be encoded
+//   - Booleans are 0x01 for true, and empty data for false
+//   - Other slices and arrays are lists
+//   - A nil pointer is empty data, or an empty list for a pointer to a struct or list
+//   - Data, List and Element values are encoded as they are
+//
+// Struct fields can be tagged `rlp:"-"` to ignore the field, or `rlp:"optional"` (or `rlp:"omitempty"`) for
+// trailing fields that are only encoded if they, or a later optional field, are non-zero - as used
+// when fields are added to new types of transaction. All fields after an optional field must be optional.
+func Marshal(v interface{}) ([]byte, error) {
+	e, err := ToElement(v)
+	if err != nil {
+		return nil, err
+	}
+	return e.Encode(), nil
+}
+
+// Unmarshal decodes RLP into the Go value pointed to by v, with the same rules as Marshal.
+// Optional struct fields that are missing from the RLP are left unchanged. The DefaultDecodeLimits apply.
+func Unmarshal(rlpData []byte, v interface{}) error {
+	e, pos, err := Decode(rlpData)
+	if err != nil {
+		return err
+	}
+	if pos != len(rlpData) {
+		return fmt.Errorf("unexpected data after RLP element (pos=%d len=%d)", pos, len(rlpData))
+	}
+	return FromElement(e, v)
+}
+
+// ToElement converts a Go value to an RLP element, with the same rules as Marshal
+func ToElement(v interface{}) (Element, error) {
+	return toElement(reflect.ValueOf(v))
+}
+
+// FromElement sets the Go value pointed to by v from an RLP element, with the same rules as Unmarshal
+func FromElement(e Element, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("RLP can only be decoded into a non-nil pointer (type=%T)", v)
+	}
+	if e == nil {
+		e = Data{}
+	}
+	return fromElement(e, rv.Elem())
+}
+
+type structField struct {
+	index    int
+	name     string
+	optional bool
+}
+
+func structFields(t reflect.Type) ([]*structField, error) {
+	var fields []*structField
+	inOptional := false
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("rlp")
+		if !f.IsExported() || tag == "-" {
+			continue
+		}
+		sf := &structField{index: i, name: f.Name}
+		switch tag {
+		case "":
+		case "optional", "omitempty":
+			sf.optional = true
+			inOptional = true
+		default:
+			return nil, fmt.Errorf("invalid RLP tag '%s' on field %s of %s", tag, f.Name, t)
+		}
+		if inOptional && !sf.optional {
+			return nil, fmt.Errorf("RLP field %s of %s must be optional, as it follows an optional field", f.Name, t)
+		}
+		fields = append(fields, sf)
+	}
+	return fields, nil
+}
+
+func isBigInt(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && t.ConvertibleTo(bigIntType)
+}
+
+func isListType(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Struct:
+		return !isBigInt(t)
+	case reflect.Slice, reflect.Array:
+		return t.Elem().Kind() != reflect.Uint8
+	default:
+		return false
+	}
+}
+
+func toElement(v reflect.Value) (Element, error) {
+	if !v.IsValid() {
+		return Data{}, nil // nil interface
+	}
+	t := v.Type()
+	switch {
+	case t == dataType || t == listType:
+		return v.Interface().(Element), nil
+	case isBigInt(t):
+		i := v.Convert(bigIntType).Interface().(big.Int)
+		if i.Sign() < 0 {
+			return nil, fmt.Errorf("cannot encode negative integer %s as RLP", i.String())
+		}
+		return Data(i.Bytes()), nil
+	}
+
+	switch t.Kind() {
+	case reflect.Interface:
+		return toElement(v.Elem())
+	case reflect.Pointer:
+		if v.IsNil() {
+			if isListType(t.Elem()) {
+				return List{}, nil
+			}
+			return Data{}, nil
+		}
+		return toElement(v.Elem())
+	case reflect.Bool:
+		if v.Bool() {
+			return Data{0x01}, nil
+		}
+		return Data{}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return Data(new(big.Int).SetUint64(v.Uint()).Bytes()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Int() < 0 {
+			return nil, fmt.Errorf("cannot encode negative integer %d as RLP", v.Int())
+		}
+		return Data(big.NewInt(v.Int()).Bytes()), nil
+	case reflect.String:
+		return Data(v.String()), nil
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			d := make(Data, v.Len())
+			reflect.Copy(reflect.ValueOf(d), v)
+			return d, nil
+		}
+		l := make(List, v.Len())
+		for i := range l {
+			e, err := toElement(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			l[i] = e
+		}
+		return l, nil
+	case reflect.Struct:
+		return structToElement(v)
+	default:
+		return nil, fmt.Errorf("cannot encode type %s as RLP", t)
+	}
+}
+
+func structToElement(v reflect.Value) (Element, error) {
+	fields, err := structFields(v.Type())
+	if err != nil {
+		return nil, err
+	}
+	// Trailing optional fields are only encoded up to the last that is set
+	count := len(fields)
+	for count > 0 && fields[count-1].optional && v.Field(fields[count-1].index).IsZero() {
+		count--
+	}
+	l := make(List, count)
+	for i := range l {
+		e, err := toElement(v.Field(fields[i].index))
+		if err != nil {
+			return nil, fmt.Errorf("RLP field %s of %s: %s", fields[i].name, v.Type(), err)
+		}
+		l[i] = e
+	}
+	return l, nil
+}
+
+func fromElement(e Element, v reflect.Value) error {
+	t := v.Type()
+	switch {
+	case t == elementType:
+		v.Set(reflect.ValueOf(e))
+		return nil
+	case t == dataType:
+		d, err := asData(e, t)
+		if err == nil {
+			v.SetBytes(d)
+		}
+		return err
+	case t == listType:
+		l, err := asList(e, t)
+		if err == nil {
+			v.Set(reflect.ValueOf(l))
+		}
+		return err
+	case isBigInt(t):
+		d, err := asData(e, t)
+		if err == nil {
+			v.Set(reflect.ValueOf(new(big.Int).SetBytes(d)).Elem().Convert(t))
+		}
+		return err
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		// Empty data, or an empty list, is a nil pointer
+		if (e.IsList() && len(e.(List)) == 0) || (!e.IsList() && len(e.(Data)) == 0) {
+			v.Set(reflect.Zero(t))
+			return nil
+		}
+		nv := reflect.New(t.Elem())
+		if err := fromElement(e, nv.Elem()); err != nil {
+			return err
+		}
+		v.Set(nv)
+		return nil
+	case reflect.Bool:
+		d, err := asData(e, t)
+		if err != nil {
+			return err
+		}
+		if len(d) > 1 || (len(d) == 1 && d[0] != 0x01) {
+			return fmt.Errorf("invalid RLP boolean 0x%x", []byte(d))
+		}
+		v.SetBool(len(d) == 1)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		i, err := asInt(e, t, t.Bits())
+		if err == nil {
+			v.SetUint(i.Uint64())
+		}
+		return err
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := asInt(e, t, t.Bits()-1)
+		if err == nil {
+			v.SetInt(i.Int64())
+		}
+		return err
+	case reflect.String:
+		d, err := asData(e, t)
+		if err == nil {
+			v.SetString(string(d))
+		}
+		return err
+	case reflect.Slice:
+		return sliceFromElement(e, v)
+	case reflect.Array:
+		return arrayFromElement(e, v)
+	case reflect.Struct:
+		return structFromElement(e, v)
+	default:
+		return fmt.Errorf("cannot decode RLP into type %s", t)
+	}
+}
+
+func sliceFromElement(e Element, v reflect.Value) error {
+	t := v.Type()
+	if t.Elem().Kind() == reflect.Uint8 {
+		d, err := asData(e, t)
+		if err == nil {
+			b := reflect.MakeSlice(t, len(d), len(d))
+			reflect.Copy(b, reflect.ValueOf([]byte(d)))
+			v.Set(b)
+		}
+		return err
+	}
+	l, err := asList(e, t)
+	if err != nil {
+		return err
+	}
+	s := reflect.MakeSlice(t, len(l), len(l))
+	for i, child := range l {
+		if err := fromElement(child, s.Index(i)); err != nil {
+			return err
+		}
+	}
+	v.Set(s)
+	return nil
+}
+
+func arrayFromElement(e Element, v reflect.Value) error {
+	t := v.Type()
+	if t.Elem().Kind() == reflect.Uint8 {
+		d, err := asData(e, t)
+		if err != nil {
+			return err
+		}
+		if len(d) != t.Len() {
+			return fmt.Errorf("RLP data length %d does not match %s", len(d), t)
+		}
+		reflect.Copy(v, reflect.ValueOf([]byte(d)))
+		return nil
+	}
+	l, err := asList(e, t)
+	if err != nil {
+		return err
+	}
+	if len(l) != t.Len() {
+		return fmt.Errorf("RLP list length %d does not match %s", len(l), t)
+	}
+	for i, child := range l {
+		if err := fromElement(child, v.Index(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func structFromElement(e Element, v reflect.Value) error {
+	t := v.Type()
+	l, err := asList(e, t)
+	if err != nil {
+		return err
+	}
+	fields, err := structFields(t)
+	if err != nil {
+		return err
+	}
+	if len(l) > len(fields) {
+		return fmt.Errorf("RLP list length %d is more than the %d fields of %s", len(l), len(fields), t)
+	}
+	for i, f := range fields {
+		if i >= len(l) {
+			if !f.optional {
+				return fmt.Errorf("RLP list length %d is missing required field %s of %s", len(l), f.name, t)
+			}
+			break
+		}
+		if err := fromElement(l[i], v.Field(f.index)); err != nil {
+			return fmt.Errorf("RLP field %s of %s: %s", f.name, t, err)
+		}
+	}
+	return nil
+}
+
+func asData(e Element, t reflect.Type) (Data, error) {
+	if e.IsList() {
+		return nil, fmt.Errorf("cannot decode RLP list into %s", t)
+	}
+	return e.(Data), nil
+}
+
+func asList(e Element, t reflect.Type) (List, error) {
+	if !e.IsList() {
+		return nil, fmt.Errorf("cannot decode RLP data into %s", t)
+	}
+	return e.(List), nil
+}
+
+func asInt(e Element, t reflect.Type, maxBits int) (*big.Int, error) {
+	d, err := asData(e, t)
+	if err != nil {
+		return nil, err
+	}
+	i := new(big.Int).SetBytes(d)
+	if i.BitLen() > maxBits {
+		return nil, fmt.Errorf("RLP integer 0x%x overflows %s", []byte(d), t)
+	}
+	return i, nil
+}
@@ -0,0 +1,243 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rlp
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/stretchr/testify/assert"
+)
+
+// testEIP155Payload is the unsigned payload of the legacy transaction example from EIP-155
+type testEIP155Payload struct {
+	Nonce    uint64
+	GasPrice *ethtypes.HexInteger
+	GasLimit ethtypes.HexUint64
+	To       *ethtypes.Address0xHex
+	Value    *big.Int
+	Data     ethtypes.HexBytes0xPrefix
+	ChainID  big.Int
+	R        uint8
+	S        uint8
+}
+
+type testOptionalFields struct {
+	Name     string
+	internal string       //nolint:unused
+	Ignored  string       `rlp:"-"`
+	Flag     bool         `rlp:"optional"`
+	Children []*testChild `rlp:"omitempty"`
+}
+
+type testChild struct {
+	ID     [4]byte
+	Counts [2]uint16
+	Raw    Element
+	Data   Data
+	List   List
+	Signed int32
+}
+
+func TestMarshalEIP155Example(t *testing.T) {
+	p := &testEIP155Payload{
+		Nonce:    9,
+		GasPrice: ethtypes.NewHexInteger64(20000000000),
+		GasLimit: 21000,
+		To:       ethtypes.MustNewAddress("0x3535353535353535353535353535353535353535"),
+		Value:    big.NewInt(1000000000000000000),
+		ChainID:  *big.NewInt(1),
+	}
+	b, err := Marshal(p)
+	assert.NoError(t, err)
+	assert.Equal(t, "ec098504a817c800825208943535353535353535353535353535353535353535880de0b6b3a764000080018080", ethtypes.HexBytesPlain(b).String())
+
+	var decoded testEIP155Payload
+	err = Unmarshal(b, &decoded)
+	assert.NoError(t, err)
+	assert.Equal(t, p.Nonce, decoded.Nonce)
+	assert.Equal(t, p.GasPrice.String(), decoded.GasPrice.String())
+	assert.Equal(t, p.GasLimit, decoded.GasLimit)
+	assert.Equal(t, p.To, decoded.To)
+	assert.Equal(t, p.Value.String(), decoded.Value.String())
+	assert.Equal(t, "1", decoded.ChainID.String())
+	assert.Empty(t, decoded.Data)
+
+	// A nil address is empty data, and decodes back to nil
+	p.To = nil
+	b, err = Marshal(p)
+	assert.NoError(t, err)
+	err = Unmarshal(b, &decoded)
+	assert.NoError(t, err)
+	assert.Nil(t, decoded.To)
+}
+
+func TestMarshalOptionalFields(t *testing.T) {
+	b, err := Marshal(&testOptionalFields{Name: "a", internal: "b", Ignored: "c"})
+	assert.NoError(t, err)
+	assert.Equal(t, List{WrapString("a")}.Encode(), b)
+
+	// A later optional field being set means earlier ones are encoded
+	v := &testOptionalFields{Name: "a", Children: []*testChild{
+		{ID: [4]byte{1, 2, 3, 4}, Counts: [2]uint16{5, 0}, Raw: List{Data{0x01}}, Data: Data{0x02}, List: List{}, Signed: 6},
+		nil,
+	}}
+	b, err = Marshal(v)
+	assert.NoError(t, err)
+	assert.Equal(t, List{
+		WrapString("a"),
+		Data{},
+		List{
+			List{Data{1, 2, 3, 4}, List{Data{0x05}, Data{}}, List{Data{0x01}}, Data{0x02}, List{}, Data{0x06}},
+			List{},
+		},
+	}.Encode(), b)
+
+	var decoded testOptionalFields
+	err = Unmarshal(b, &decoded)
+	assert.NoError(t, err)
+	assert.Equal(t, v, &decoded)
+
+	// Missing optional fields are left unset
+	decoded = testOptionalFields{}
+	err = Unmarshal(List{WrapString("a"), Data{0x01}}.Encode(), &decoded)
+	assert.NoError(t, err)
+	assert.Equal(t, &testOptionalFields{Name: "a", Flag: true}, &decoded)
+}
+
+func TestMarshalValues(t *testing.T) {
+	for _, v := range []interface{}{
+		uint8(0x7f),
+		uint16(0x1234),
+		uint32(0),
+		uint(1024),
+		int64(65536),
+		true,
+		false,
+		"hello",
+		[]string{"a", "b"},
+		[]byte{0x01, 0x02},
+		ethtypes.HexInteger(*big.NewInt(12345)),
+		[]interface{}{"a", uint64(1), nil},
+	} {
+		b, err := Marshal(v)
+		assert.NoError(t, err)
+		e, _, err := Decode(b)
+		assert.NoError(t, err)
+		e2, err := ToElement(v)
+		assert.NoError(t, err)
+		assert.Equal(t, e.Encode(), e2.Encode())
+	}
+
+	// Nil pointers are empty data, or an empty list
+	b, err := Marshal([]interface{}{(*[]string)(nil), (*uint64)(nil), (*big.Int)(nil)})
+	assert.NoError(t, err)
+	assert.Equal(t, List{List{}, Data{}, Data{}}.Encode(), b)
+
+	var i16 int16
+	err = Unmarshal([]byte{0x82, 0x12, 0x34}, &i16)
+	assert.NoError(t, err)
+	assert.Equal(t, int16(0x1234), i16)
+
+	var e Element
+	err = FromElement(nil, &e)
+	assert.NoError(t, err)
+	assert.Equal(t, Data{}, e)
+
+	var l List
+	err = Unmarshal([]byte{0xc1, 0x01}, &l)
+	assert.NoError(t, err)
+	assert.Equal(t, List{Data{0x01}}, l)
+
+	var strs []string
+	err = Unmarshal([]byte{0xc2, 0x61, 0x62}, &strs)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, strs)
+}
+
+func TestMarshalErrors(t *testing.T) {
+	_, err := Marshal(-1)
+	assert.Regexp(t, "negative", err)
+	_, err = Marshal(big.NewInt(-1))
+	assert.Regexp(t, "negative", err)
+	_, err = Marshal(map[string]string{})
+	assert.Regexp(t, "cannot encode type", err)
+	_, err = Marshal([]interface{}{-1})
+	assert.Regexp(t, "negative", err)
+	_, err = Marshal(&testChild{Signed: -1})
+	assert.Regexp(t, "RLP field Signed", err)
+	_, err = Marshal(&struct {
+		A string `rlp:"wrong"`
+	}{})
+	assert.Regexp(t, "invalid RLP tag", err)
+	_, err = Marshal(&struct {
+		A string `rlp:"optional"`
+		B string
+	}{})
+	assert.Regexp(t, "must be optional", err)
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	var u8 uint8
+	var i8 int8
+	var b bool
+	var s string
+	var bs []byte
+	var a [2]byte
+	var a2 [2]uint8
+	var la [2]string
+	var d Data
+	var l List
+	var bi big.Int
+	var p *uint64
+	var strs []string
+	var child testChild
+	var m map[string]string
+	var badTag struct {
+		A string `rlp:"wrong"`
+	}
+
+	assert.Regexp(t, "non-nil pointer", Unmarshal([]byte{0x01}, u8))
+	assert.Regexp(t, "length mismatch", Unmarshal([]byte{0x82}, &u8))
+	assert.Regexp(t, "unexpected data after", Unmarshal([]byte{0x01, 0x02}, &u8))
+	assert.Regexp(t, "overflows", Unmarshal([]byte{0x82, 0x01, 0x00}, &u8))
+	assert.Regexp(t, "overflows", Unmarshal([]byte{0x81, 0x80}, &i8))
+	assert.Regexp(t, "cannot decode RLP list", Unmarshal([]byte{0xc0}, &u8))
+	assert.Regexp(t, "cannot decode RLP list", Unmarshal([]byte{0xc0}, &i8))
+	assert.Regexp(t, "invalid RLP boolean", Unmarshal([]byte{0x02}, &b))
+	assert.Regexp(t, "cannot decode RLP list", Unmarshal([]byte{0xc0}, &b))
+	assert.Regexp(t, "cannot decode RLP list", Unmarshal([]byte{0xc0}, &s))
+	assert.Regexp(t, "cannot decode RLP list", Unmarshal([]byte{0xc0}, &bs))
+	assert.Regexp(t, "cannot decode RLP list", Unmarshal([]byte{0xc0}, &a))
+	assert.Regexp(t, "does not match", Unmarshal([]byte{0x01}, &a2))
+	assert.Regexp(t, "cannot decode RLP data", Unmarshal([]byte{0x01}, &la))
+	assert.Regexp(t, "does not match", Unmarshal([]byte{0xc1, 0x61}, &la))
+	assert.Regexp(t, "cannot decode RLP list", Unmarshal([]byte{0xc2, 0x61, 0xc0}, &la))
+	assert.Regexp(t, "cannot decode RLP list", Unmarshal([]byte{0xc0}, &d))
+	assert.Regexp(t, "cannot decode RLP data", Unmarshal([]byte{0x01}, &l))
+	assert.Regexp(t, "cannot decode RLP list", Unmarshal([]byte{0xc0}, &bi))
+	assert.Regexp(t, "cannot decode RLP list", Unmarshal([]byte{0xc1, 0xc0}, &p))
+	assert.Regexp(t, "cannot decode RLP data", Unmarshal([]byte{0x01}, &strs))
+	assert.Regexp(t, "cannot decode RLP list", Unmarshal([]byte{0xc1, 0xc0}, &strs))
+	assert.Regexp(t, "cannot decode RLP data", Unmarshal([]byte{0x01}, &child))
+	assert.Regexp(t, "more than the 6 fields", Unmarshal(List{Data{}, Data{}, Data{}, Data{}, Data{}, Data{}, Data{}}.Encode(), &child))
+	assert.Regexp(t, "missing required field Counts", Unmarshal(List{Data{1, 2, 3, 4}}.Encode(), &child))
+	assert.Regexp(t, "RLP field ID", Unmarshal(List{List{}}.Encode(), &child))
+	assert.Regexp(t, "invalid RLP tag", Unmarshal([]byte{0xc0}, &badTag))
+	assert.Regexp(t, "cannot decode RLP into type", Unmarshal([]byte{0x01}, &m))
+}
@@ -36,6 +36,8 @@ type Element interface {
 	IsList() bool
 	// Encode converts the element to a byte array
 	Encode() []byte
+	// EncodeTo appends the encoding of the element to dst, returning the extended slice - so a buffer can be reused
+	EncodeTo(dst []byte) []byte
 	// Safe function that will give an entry as data, to use the nil-safe functions on it to get the value (will be treated as nil data for list)
 	ToData() Data
 }
@@ -109,7 +111,17 @@ func (r Data) Address() *ethtypes.Address0xHex {
 
 // Encode encodes this individual RLP Data element
 func (r Data) Encode() []byte {
-	return encodeBytes(r, false)
+	return r.EncodeTo(make([]byte, 0, encodedLen(r)))
+}
+
+// EncodeTo appends the encoding of this individual RLP Data element to dst
+func (r Data) EncodeTo(dst []byte) []byte {
+	if len(r) == 1 && r[0] < shortString {
+		// We don't need the offset, this can be sent as a single byte
+		return append(dst, r[0])
+	}
+	dst = appendHeader(dst, shortString, len(r))
+	return append(dst, r...)
 }
 
 // IsList is false for individual RLP Data elements
@@ -123,15 +135,17 @@ func (r Data) ToData() Data {
 
 // Encode encodes the RLP List to a byte array, including recursing into child arrays
 func (l List) Encode() []byte {
-	if len(l) == 0 {
-		return encodeBytes([]byte{}, true)
-	}
-	var concatenation []byte
+	return l.EncodeTo(make([]byte, 0, encodedLen(l)))
+}
+
+// EncodeTo appends the encoding of the RLP List to dst, including recursing into child arrays.
+// The children are encoded directly into dst, rather than being encoded separately then copied.
+func (l List) EncodeTo(dst []byte) []byte {
+	dst = appendHeader(dst, shortList, l.payloadLen())
 	for _, entry := range l {
-		concatenation = append(concatenation, entry.Encode()...)
+		dst = entry.EncodeTo(dst)
 	}
-	return encodeBytes(concatenation, true)
-
+	return dst
 }
 
 // IsList returns true for list elements
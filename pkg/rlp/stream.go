@@ -0,0 +1,214 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rlp
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// TokenType is the type of each token read by a StreamDecoder
+type TokenType int
+
+const (
+	// TokenData is a complete data element
+	TokenData TokenType = iota
+	// TokenListStart is the start of a list, which is followed by the tokens of each item in the list
+	TokenListStart
+	// TokenListEnd is the end of the most recently started list
+	TokenListEnd
+)
+
+// Token is an item read by a StreamDecoder
+type Token struct {
+	Type TokenType
+	// Data is the data for a TokenData
+	Data Data
+	// Length is the number of payload bytes of a TokenData or TokenListStart
+	Length int
+}
+
+// StreamDecoder reads RLP from a stream one token at a time, so only a single data element at a time
+// needs to be held in memory - rather than the whole payload, as with Decode.
+//
+// A stream can contain any number of consecutive RLP elements. The lengths of each list are checked
+// against the items it contains as they are read.
+type StreamDecoder struct {
+	r            *bufio.Reader
+	limits       DecodeLimits
+	offset       int
+	elementStart int   // offset of the current top-level element
+	listEnds     []int // offsets of the end of each list that has been started, but not ended
+}
+
+func NewStreamDecoder(r io.Reader) *StreamDecoder {
+	return NewStreamDecoderWithLimits(r, &DecodeLimits{})
+}
+
+// NewStreamDecoderWithLimits returns a StreamDecoder that returns an error for any element exceeding the limits,
+// before allocating memory for it. The size limit applies to each top-level element in the stream.
+func NewStreamDecoderWithLimits(r io.Reader, limits *DecodeLimits) *StreamDecoder {
+	return &StreamDecoder{r: bufio.NewReader(r), limits: *limits}
+}
+
+// Offset is the number of bytes read from the stream so far
+func (sd *StreamDecoder) Offset() int {
+	return sd.offset
+}
+
+// Depth is the number of lists that have been started, but not ended
+func (sd *StreamDecoder) Depth() int {
+	return len(sd.listEnds)
+}
+
+// More is true if there is another item in the current list, or at the top level if there is more
+// data in the stream
+func (sd *StreamDecoder) More() bool {
+	if len(sd.listEnds) > 0 {
+		return sd.offset < sd.listEnds[len(sd.listEnds)-1]
+	}
+	_, err := sd.r.Peek(1)
+	return err == nil
+}
+
+// Next returns the next token in the stream, or io.EOF once the stream ends after a complete element
+func (sd *StreamDecoder) Next() (*Token, error) {
+	if len(sd.listEnds) > 0 && sd.offset == sd.listEnds[len(sd.listEnds)-1] {
+		sd.listEnds = sd.listEnds[:len(sd.listEnds)-1]
+		return &Token{Type: TokenListEnd}, nil
+	}
+
+	prefix, err := sd.r.ReadByte()
+	if err != nil {
+		if err == io.EOF && len(sd.listEnds) > 0 {
+			return nil, fmt.Errorf("unexpected end of RLP stream in list (pos=%d)", sd.offset)
+		}
+		return nil, err
+	}
+	if len(sd.listEnds) == 0 {
+		sd.elementStart = sd.offset
+	}
+	sd.offset++
+
+	var token *Token
+	switch {
+	case prefix < shortString:
+		token = &Token{Type: TokenData, Data: Data{prefix}, Length: 1}
+	case prefix <= longString:
+		token, err = sd.readData(int(prefix - shortString))
+	case prefix < shortList:
+		token, err = sd.readLongLen(false, prefix)
+	case prefix <= longList:
+		token, err = sd.startList(int(prefix - shortList))
+	default:
+		token, err = sd.readLongLen(true, prefix)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// A nested list must end within its parent (data is checked before it is read)
+	if n := len(sd.listEnds); token.Type == TokenListStart && n > 1 && sd.listEnds[n-1] > sd.listEnds[n-2] {
+		return nil, fmt.Errorf("length mismatch in RLP for list overrunning the end of its parent (pos=%d len=%d)", sd.offset, token.Length)
+	}
+	return token, nil
+}
+
+// NextElement reads the whole of the next element in the stream, which must not be the end of a list
+func (sd *StreamDecoder) NextElement() (Element, error) {
+	token, err := sd.Next()
+	if err != nil {
+		return nil, err
+	}
+	switch token.Type {
+	case TokenData:
+		return token.Data, nil
+	case TokenListStart:
+		l := List{}
+		for sd.More() {
+			child, err := sd.NextElement()
+			if err != nil {
+				return nil, err
+			}
+			l = append(l, child)
+		}
+		_, err = sd.Next() // the end of the list
+		return l, err
+	default:
+		return nil, fmt.Errorf("unexpected end of list in RLP stream (pos=%d)", sd.offset)
+	}
+}
+
+func (sd *StreamDecoder) readLongLen(isList bool, prefix byte) (*Token, error) {
+	longPrefix := longString
+	if isList {
+		longPrefix = longList
+	}
+	lenBytes := make([]byte, prefix-longPrefix) // assured to be <8
+	if err := sd.readFull(lenBytes); err != nil {
+		return nil, err
+	}
+	dataLen, err := minimalBytesToInt64(lenBytes)
+	if err != nil {
+		return nil, err
+	}
+	if isList {
+		return sd.startList(dataLen)
+	}
+	return sd.readData(dataLen)
+}
+
+func (sd *StreamDecoder) readData(dataLen int) (*Token, error) {
+	// Check the length against the enclosing list before allocating
+	if len(sd.listEnds) > 0 && dataLen > sd.listEnds[len(sd.listEnds)-1]-sd.offset {
+		return nil, fmt.Errorf("length mismatch in RLP for data bytes (pos=%d len=%d)", sd.offset, dataLen)
+	}
+	if err := sd.checkSize(dataLen); err != nil {
+		return nil, err
+	}
+	d := make(Data, dataLen)
+	if err := sd.readFull(d); err != nil {
+		return nil, err
+	}
+	return &Token{Type: TokenData, Data: d, Length: dataLen}, nil
+}
+
+func (sd *StreamDecoder) startList(listLen int) (*Token, error) {
+	if err := sd.limits.checkDepth(len(sd.listEnds) + 1); err != nil {
+		return nil, err
+	}
+	if err := sd.checkSize(listLen); err != nil {
+		return nil, err
+	}
+	sd.listEnds = append(sd.listEnds, sd.offset+listLen)
+	return &Token{Type: TokenListStart, Length: listLen}, nil
+}
+
+// checkSize checks the current top-level element against the size limit, when the next dataLen bytes are read
+func (sd *StreamDecoder) checkSize(dataLen int) error {
+	return sd.limits.checkSize(sd.offset + dataLen - sd.elementStart)
+}
+
+func (sd *StreamDecoder) readFull(b []byte) error {
+	n, err := io.ReadFull(sd.r, b)
+	sd.offset += n
+	if err != nil {
+		return fmt.Errorf("length mismatch in RLP stream (pos=%d len=%d): %s", sd.offset, len(b), err)
+	}
+	return nil
+}
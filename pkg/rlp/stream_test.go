@@ -0,0 +1,115 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rlp
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamDecodeElements(t *testing.T) {
+	longList := List{}
+	for i := 0; i < 20; i++ {
+		longList = append(longList, WrapString("abcd"))
+	}
+	elements := []Element{
+		Data(loremIpsumRLPBytes[2:]),
+		List{Data{0x01}, List{}, Data{}, List{WrapString("cat"), List{WrapString("dog")}}},
+		longList,
+		List{longList, Data(loremIpsumRLPBytes[2:])},
+		Data{0x7f},
+		Data{},
+	}
+	var stream []byte
+	for _, e := range elements {
+		stream = append(stream, e.Encode()...)
+	}
+
+	sd := NewStreamDecoder(bytes.NewReader(stream))
+	for _, e := range elements {
+		assert.True(t, sd.More())
+		decoded, err := sd.NextElement()
+		assert.NoError(t, err)
+		assert.Equal(t, e, decoded)
+		assert.Zero(t, sd.Depth())
+	}
+	assert.False(t, sd.More())
+	_, err := sd.Next()
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, len(stream), sd.Offset())
+}
+
+func TestStreamDecodeTokens(t *testing.T) {
+	sd := NewStreamDecoder(bytes.NewReader(List{WrapString("cat"), List{Data{0x01}}}.Encode()))
+
+	token, err := sd.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, &Token{Type: TokenListStart, Length: 6}, token)
+	assert.True(t, sd.More())
+
+	token, err = sd.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, &Token{Type: TokenData, Data: WrapString("cat"), Length: 3}, token)
+
+	token, err = sd.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, &Token{Type: TokenListStart, Length: 1}, token)
+	assert.Equal(t, 2, sd.Depth())
+
+	token, err = sd.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, &Token{Type: TokenData, Data: Data{0x01}, Length: 1}, token)
+	assert.False(t, sd.More())
+
+	for i := 0; i < 2; i++ {
+		token, err = sd.Next()
+		assert.NoError(t, err)
+		assert.Equal(t, TokenListEnd, token.Type)
+	}
+	assert.Zero(t, sd.Depth())
+
+	_, err = sd.NextElement()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestStreamDecodeErrors(t *testing.T) {
+	for name, b := range map[string][]byte{
+		"truncated data":          {0x83, 'c', 'a'},
+		"truncated long length":   {0xb9, 0x01},
+		"long length too big":     {0xbb, 0xff, 0xff, 0xff, 0xff},
+		"truncated list":          {0xc3, 0x01, 0x02},
+		"data overruns list":      {0xc2, 0x83, 'c', 'a', 't'},
+		"long data overruns list": {0xc2, 0xb8, 0x38},
+		"list overruns parent":    {0xc2, 0xc3, 0x01, 0x02, 0x03},
+		"error in nested list":    {0xc2, 0xc1, 0x81},
+	} {
+		_, err := NewStreamDecoder(bytes.NewReader(b)).NextElement()
+		assert.Error(t, err, name)
+	}
+}
+
+func TestStreamDecodeElementAtListEnd(t *testing.T) {
+	sd := NewStreamDecoder(bytes.NewReader(List{}.Encode()))
+	token, err := sd.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, TokenListStart, token.Type)
+	_, err = sd.NextElement()
+	assert.Regexp(t, "unexpected end of list", err)
+}
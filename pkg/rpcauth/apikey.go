@@ -0,0 +1,77 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcauth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+)
+
+type apiKeyAuthenticator struct {
+	header string
+	keys   []apiKeyHash
+}
+
+type apiKeyHash struct {
+	id   string
+	hash [32]byte
+}
+
+// NewAPIKeyAuthenticator authenticates callers that supply one of a static set of API keys in an HTTP header
+func NewAPIKeyAuthenticator(ctx context.Context, header string, keys []APIKey) (Authenticator, error) {
+	a := &apiKeyAuthenticator{
+		header: header,
+		keys:   make([]apiKeyHash, len(keys)),
+	}
+	for i, k := range keys {
+		if k.ID == "" || k.Key == "" {
+			return nil, i18n.NewError(ctx, signermsgs.MsgAPIKeyMissingFields, i)
+		}
+		a.keys[i] = apiKeyHash{id: k.ID, hash: sha256.Sum256([]byte(k.Key))}
+	}
+	return a, nil
+}
+
+func (a *apiKeyAuthenticator) Name() string {
+	return "apikey"
+}
+
+func (a *apiKeyAuthenticator) Authenticate(_ context.Context, req *http.Request) (*Identity, error) {
+	key := req.Header.Get(a.header)
+	if key == "" {
+		return nil, nil
+	}
+	// We compare fixed length hashes in constant time, and check every key, so the
+	// time taken does not reveal anything about the configured keys
+	hash := sha256.Sum256([]byte(key))
+	var identity *Identity
+	for _, k := range a.keys {
+		if subtle.ConstantTimeCompare(hash[:], k.hash[:]) == 1 {
+			identity = &Identity{ID: k.id}
+		}
+	}
+	if identity == nil {
+		return nil, errors.New("unknown API key")
+	}
+	return identity, nil
+}
@@ -0,0 +1,56 @@
+// Copyright © 2023 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcauth
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIKeyAuthenticator(t *testing.T) {
+	a, err := NewAPIKeyAuthenticator(context.Background(), "X-API-Key", []APIKey{
+		{ID: "client1", Key: "secret1"},
+		{ID: "client2", Key: "secret2"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "apikey", a.Name())
+
+	req, _ := http.NewRequest(http.MethodPost, "/", nil)
+	identity, err := a.Authenticate(context.Background(), req)
+	assert.NoError(t, err)
+	assert.Nil(t, identity)
+
+	req.Header.Set("X-API-Key", "secret2")
+	identity, err = a.Authenticate(context.Background(), req)
+	assert.NoError(t, err)
+	assert.Equal(t, "client2", identity.ID)
+
+	req.Header.Set("X-API-Key", "secret3")
+	_, err = a.Authenticate(context.Background(), req)
+	assert.Regexp(t, "unknown API key", err)
+}
+
+func TestAPIKeyAuthenticatorMissingFields(t *testing.T) {
+	_, err := NewAPIKeyAuthenticator(context.Background(), "X-API-Key", []APIKey{
+		{ID: "client1", Key: "secret1"},
+		{ID: "client2"},
+	})
+	assert.Regexp(t, "FF22106.*1", err)
+}
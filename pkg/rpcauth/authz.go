@@ -0,0 +1,172 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcauth
+
+import (
+	"context"
+	"fmt"
+	"path"
+
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+)
+
+// Wildcard matches any identity or address in a policy
+const Wildcard = "*"
+
+// Policy grants the identities it matches the use of a set of JSON/RPC methods, and a set of signing addresses.
+// An identity matches when its ID is in Identities, or when its Claim (from a JWT) has one of the ClaimValues.
+type Policy struct {
+	Identities  []string
+	Claim       string
+	ClaimValues []string
+	// Methods are patterns, such as "eth_*", or "*" for all methods
+	Methods []string
+	// Addresses are the addresses whose keys may be used, or "*" for all addresses
+	Addresses []string
+}
+
+type policy struct {
+	identities  map[string]bool
+	claim       string
+	claimValues map[string]bool
+	methods     []string
+	addresses   map[string]bool
+}
+
+// Authorizer checks the methods and signing addresses an authenticated identity may use. Everything
+// is denied unless granted by one of the policies matching the identity.
+type Authorizer struct {
+	policies []*policy
+}
+
+func NewAuthorizer(ctx context.Context, policies []*Policy) (*Authorizer, error) {
+	a := &Authorizer{policies: make([]*policy, len(policies))}
+	for i, p := range policies {
+		if len(p.Identities) == 0 && (p.Claim == "" || len(p.ClaimValues) == 0) {
+			return nil, i18n.NewError(ctx, signermsgs.MsgInvalidRBACPolicy, i, "no identities or claim values")
+		}
+		ap := &policy{
+			identities:  stringSet(p.Identities),
+			claim:       p.Claim,
+			claimValues: stringSet(p.ClaimValues),
+			methods:     p.Methods,
+			addresses:   make(map[string]bool, len(p.Addresses)),
+		}
+		for _, m := range p.Methods {
+			if _, err := path.Match(m, ""); err != nil {
+				return nil, i18n.NewError(ctx, signermsgs.MsgInvalidRBACPolicy, i, fmt.Sprintf("method '%s': %s", m, err))
+			}
+		}
+		for _, addrStr := range p.Addresses {
+			if addrStr == Wildcard {
+				ap.addresses[Wildcard] = true
+				continue
+			}
+			addr, err := ethtypes.NewAddress(addrStr)
+			if err != nil {
+				return nil, i18n.NewError(ctx, signermsgs.MsgInvalidRBACPolicy, i, fmt.Sprintf("address '%s': %s", addrStr, err))
+			}
+			ap.addresses[addr.String()] = true
+		}
+		a.policies[i] = ap
+	}
+	return a, nil
+}
+
+func stringSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+	return set
+}
+
+func (p *policy) matchesIdentity(identity *Identity) bool {
+	if p.identities[Wildcard] || p.identities[identity.ID] {
+		return true
+	}
+	if p.claim == "" {
+		return false
+	}
+	// Claims such as groups or roles are often arrays
+	switch v := identity.Claims[p.claim].(type) {
+	case string:
+		return p.claimValues[v]
+	case []interface{}:
+		for _, entry := range v {
+			if s, ok := entry.(string); ok && p.claimValues[s] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (p *policy) allowsMethod(method string) bool {
+	for _, pattern := range p.methods {
+		if match, _ := path.Match(pattern, method); match {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *policy) allowsAddress(addr *ethtypes.Address0xHex) bool {
+	return p.addresses[Wildcard] || p.addresses[addr.String()]
+}
+
+func (a *Authorizer) allowed(identity *Identity, check func(p *policy) bool) bool {
+	if identity == nil {
+		return false
+	}
+	for _, p := range a.policies {
+		if p.matchesIdentity(identity) && check(p) {
+			return true
+		}
+	}
+	return false
+}
+
+func identityID(identity *Identity) string {
+	if identity == nil {
+		return ""
+	}
+	return identity.ID
+}
+
+// AuthorizeMethod returns an error unless the identity is granted the use of the JSON/RPC method
+func (a *Authorizer) AuthorizeMethod(ctx context.Context, identity *Identity, method string) error {
+	if !a.allowed(identity, func(p *policy) bool { return p.allowsMethod(method) }) {
+		return i18n.NewError(ctx, signermsgs.MsgMethodNotAuthorized, method, identityID(identity))
+	}
+	return nil
+}
+
+// AddressAllowed returns true if the identity is granted the use of the key for the address
+func (a *Authorizer) AddressAllowed(identity *Identity, addr *ethtypes.Address0xHex) bool {
+	return a.allowed(identity, func(p *policy) bool { return p.allowsAddress(addr) })
+}
+
+// AuthorizeAddress returns an error unless the identity is granted the use of the key for the address
+func (a *Authorizer) AuthorizeAddress(ctx context.Context, identity *Identity, addr *ethtypes.Address0xHex) error {
+	if !a.AddressAllowed(identity, addr) {
+		return i18n.NewError(ctx, signermsgs.MsgAddressNotAuthorized, addr, identityID(identity))
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcauth
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testAddrA = "0xfb075bb99f2aa4c49955bf703509a227d7a12248"
+	testAddrB = "0x3c99f2a4b366d46bcf2277639a135a6d1288eceb"
+)
+
+func newTestAuthorizer(t *testing.T) *Authorizer {
+	a, err := NewAuthorizer(context.Background(), []*Policy{
+		{
+			Identities: []string{"tenantA"},
+			Methods:    []string{"eth_sendTransaction", "eth_get*"},
+			Addresses:  []string{"0xFB075BB99F2AA4C49955BF703509A227D7A12248"},
+		},
+		{
+			Claim:       "groups",
+			ClaimValues: []string{"tenantB"},
+			Methods:     []string{"eth_sendTransaction"},
+			Addresses:   []string{testAddrB},
+		},
+		{
+			Identities: []string{"admin"},
+			Methods:    []string{"*"},
+			Addresses:  []string{"*"},
+		},
+		{
+			Identities: []string{"*"},
+			Methods:    []string{"eth_chainId"},
+		},
+	})
+	assert.NoError(t, err)
+	return a
+}
+
+func TestAuthorizeMethod(t *testing.T) {
+	a := newTestAuthorizer(t)
+	ctx := context.Background()
+
+	tenantA := &Identity{ID: "tenantA"}
+	assert.NoError(t, a.AuthorizeMethod(ctx, tenantA, "eth_sendTransaction"))
+	assert.NoError(t, a.AuthorizeMethod(ctx, tenantA, "eth_getBalance"))
+	assert.NoError(t, a.AuthorizeMethod(ctx, tenantA, "eth_chainId"))
+	assert.Regexp(t, "FF22110.*personal_unlockAccount.*tenantA", a.AuthorizeMethod(ctx, tenantA, "personal_unlockAccount"))
+
+	assert.NoError(t, a.AuthorizeMethod(ctx, &Identity{ID: "admin"}, "personal_unlockAccount"))
+	assert.NoError(t, a.AuthorizeMethod(ctx, &Identity{ID: "other"}, "eth_chainId"))
+	assert.Regexp(t, "FF22110", a.AuthorizeMethod(ctx, &Identity{ID: "other"}, "eth_sendTransaction"))
+	assert.Regexp(t, "FF22110", a.AuthorizeMethod(ctx, nil, "eth_chainId"))
+}
+
+func TestAuthorizeAddress(t *testing.T) {
+	a := newTestAuthorizer(t)
+	ctx := context.Background()
+	addrA := ethtypes.MustNewAddress(testAddrA)
+	addrB := ethtypes.MustNewAddress(testAddrB)
+
+	tenantA := &Identity{ID: "tenantA"}
+	assert.NoError(t, a.AuthorizeAddress(ctx, tenantA, addrA))
+	assert.Regexp(t, "FF22111.*"+testAddrB+".*tenantA", a.AuthorizeAddress(ctx, tenantA, addrB))
+
+	tenantB := &Identity{ID: "user1", Claims: map[string]interface{}{"groups": []interface{}{1, "tenantB"}}}
+	assert.NoError(t, a.AuthorizeAddress(ctx, tenantB, addrB))
+	assert.Regexp(t, "FF22111", a.AuthorizeAddress(ctx, tenantB, addrA))
+	assert.True(t, a.AddressAllowed(&Identity{ID: "user2", Claims: map[string]interface{}{"groups": "tenantB"}}, addrB))
+	assert.False(t, a.AddressAllowed(&Identity{ID: "user3", Claims: map[string]interface{}{"groups": []interface{}{"tenantC"}}}, addrB))
+	assert.False(t, a.AddressAllowed(&Identity{ID: "user4", Claims: map[string]interface{}{"groups": 1}}, addrB))
+
+	assert.True(t, a.AddressAllowed(&Identity{ID: "admin"}, addrA))
+	assert.False(t, a.AddressAllowed(&Identity{ID: "other"}, addrA))
+	assert.Regexp(t, "FF22111", a.AuthorizeAddress(ctx, nil, addrA))
+}
+
+func TestNewAuthorizerBadPolicies(t *testing.T) {
+	ctx := context.Background()
+
+	_, err := NewAuthorizer(ctx, []*Policy{{Methods: []string{"*"}}})
+	assert.Regexp(t, "FF22112.*0", err)
+
+	_, err = NewAuthorizer(ctx, []*Policy{{Claim: "groups", Methods: []string{"*"}}})
+	assert.Regexp(t, "FF22112", err)
+
+	_, err = NewAuthorizer(ctx, []*Policy{{Identities: []string{"a"}, Methods: []string{"eth_["}}})
+	assert.Regexp(t, "FF22112.*eth_\\[", err)
+
+	_, err = NewAuthorizer(ctx, []*Policy{{Identities: []string{"a"}, Addresses: []string{"0xbad"}}})
+	assert.Regexp(t, "FF22112.*0xbad", err)
+}
@@ -0,0 +1,218 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcauth
+
+import (
+	"time"
+
+	"github.com/hyperledger/firefly-common/pkg/config"
+)
+
+const (
+	// ConfigEnabled when true, every JSON/RPC request must be authenticated
+	ConfigEnabled = "enabled"
+	// ConfigAPIKeyHeader the HTTP header containing the API key
+	ConfigAPIKeyHeader = "apiKeyHeader"
+	// ConfigAPIKeys the array of static API keys
+	ConfigAPIKeys = "apiKeys"
+	// ConfigAPIKeyID the ID of an API key, which is the identity of callers using it
+	ConfigAPIKeyID = "id"
+	// ConfigAPIKeyKey the secret value of an API key
+	ConfigAPIKeyKey = "key"
+	// ConfigJWTJWKSURL the URL of the JSON Web Key Set used to verify JWT signatures
+	ConfigJWTJWKSURL = "jwt.jwksURL"
+	// ConfigJWTIssuer the issuer (iss) that JWTs must have, when set
+	ConfigJWTIssuer = "jwt.issuer"
+	// ConfigJWTAudience the audience (aud) that JWTs must include, when set
+	ConfigJWTAudience = "jwt.audience"
+	// ConfigJWTIdentityClaim the claim identifying the caller
+	ConfigJWTIdentityClaim = "jwt.identityClaim"
+	// ConfigJWTJWKSRefreshInterval how often the JWKS is refreshed
+	ConfigJWTJWKSRefreshInterval = "jwt.jwksRefreshInterval"
+	// ConfigJWTLeeway the allowed clock skew when checking the expiry and not-before times of JWTs
+	ConfigJWTLeeway = "jwt.leeway"
+	// ConfigRBACEnabled when true, each identity may only use the methods and addresses granted by the policies
+	ConfigRBACEnabled = "rbac.enabled"
+	// ConfigRBACPolicies the array of access control policies
+	ConfigRBACPolicies = "rbac.policies"
+	// ConfigPolicyIdentities the identity IDs a policy applies to
+	ConfigPolicyIdentities = "identities"
+	// ConfigPolicyClaim a JWT claim used to select the identities a policy applies to
+	ConfigPolicyClaim = "claim"
+	// ConfigPolicyClaimValues the values of the claim a policy applies to
+	ConfigPolicyClaimValues = "claimValues"
+	// ConfigPolicyMethods the JSON/RPC method patterns granted by a policy
+	ConfigPolicyMethods = "methods"
+	// ConfigPolicyAddresses the signing addresses granted by a policy
+	ConfigPolicyAddresses = "addresses"
+	// ConfigMethodsAllow the JSON/RPC method patterns that are allowed, when set
+	ConfigMethodsAllow = "allow"
+	// ConfigMethodsDeny the JSON/RPC method patterns that are denied
+	ConfigMethodsDeny = "deny"
+	// ConfigMethodsOverrides the array of per-identity overrides of the allowed and denied methods
+	ConfigMethodsOverrides = "overrides"
+	// ConfigAuthenticators the names of custom authenticators to try, after any API key and JWT authenticators
+	ConfigAuthenticators = "authenticators"
+)
+
+const (
+	DefaultAPIKeyHeader        = "X-API-Key"
+	DefaultJWTIdentityClaim    = "sub"
+	DefaultJWKSRefreshInterval = "5m"
+	DefaultJWTLeeway           = "30s"
+)
+
+type Config struct {
+	Enabled        bool
+	APIKeyHeader   string
+	APIKeys        []APIKey
+	JWT            JWTConfig
+	Authenticators []string
+	RBAC           RBACConfig
+}
+
+type APIKey struct {
+	ID  string
+	Key string
+}
+
+type RBACConfig struct {
+	Enabled  bool
+	Policies []*Policy
+}
+
+type JWTConfig struct {
+	JWKSURL             string
+	Issuer              string
+	Audience            string
+	IdentityClaim       string
+	JWKSRefreshInterval time.Duration
+	Leeway              time.Duration
+}
+
+func InitConfig(section config.Section) {
+	section.AddKnownKey(ConfigEnabled, false)
+	section.AddKnownKey(ConfigAPIKeyHeader, DefaultAPIKeyHeader)
+	apiKeysConfig(section)
+	section.AddKnownKey(ConfigJWTJWKSURL)
+	section.AddKnownKey(ConfigJWTIssuer)
+	section.AddKnownKey(ConfigJWTAudience)
+	section.AddKnownKey(ConfigJWTIdentityClaim, DefaultJWTIdentityClaim)
+	section.AddKnownKey(ConfigJWTJWKSRefreshInterval, DefaultJWKSRefreshInterval)
+	section.AddKnownKey(ConfigJWTLeeway, DefaultJWTLeeway)
+	section.AddKnownKey(ConfigAuthenticators)
+	section.AddKnownKey(ConfigRBACEnabled, false)
+	policiesConfig(section)
+}
+
+// apiKeysConfig and policiesConfig return the arrays with their keys registered, as the
+// entries of an array section only know the keys registered on that instance
+func apiKeysConfig(section config.Section) config.ArraySection {
+	apiKeys := section.SubArray(ConfigAPIKeys)
+	apiKeys.AddKnownKey(ConfigAPIKeyID)
+	apiKeys.AddKnownKey(ConfigAPIKeyKey)
+	return apiKeys
+}
+
+func policiesConfig(section config.Section) config.ArraySection {
+	policies := section.SubArray(ConfigRBACPolicies)
+	policies.AddKnownKey(ConfigPolicyIdentities)
+	policies.AddKnownKey(ConfigPolicyClaim)
+	policies.AddKnownKey(ConfigPolicyClaimValues)
+	policies.AddKnownKey(ConfigPolicyMethods)
+	policies.AddKnownKey(ConfigPolicyAddresses)
+	return policies
+}
+
+// InitMethodsConfig registers the keys of the method filter, which is a separate section to the
+// authentication config as it can be used without authentication
+func InitMethodsConfig(section config.Section) {
+	section.AddKnownKey(ConfigMethodsAllow)
+	section.AddKnownKey(ConfigMethodsDeny)
+	methodOverridesConfig(section)
+}
+
+func methodOverridesConfig(section config.Section) config.ArraySection {
+	overrides := section.SubArray(ConfigMethodsOverrides)
+	overrides.AddKnownKey(ConfigPolicyIdentities)
+	overrides.AddKnownKey(ConfigPolicyClaim)
+	overrides.AddKnownKey(ConfigPolicyClaimValues)
+	overrides.AddKnownKey(ConfigMethodsAllow)
+	overrides.AddKnownKey(ConfigMethodsDeny)
+	return overrides
+}
+
+func ReadMethodsConfig(section config.Section) *MethodFilterConfig {
+	overrides := methodOverridesConfig(section)
+	conf := &MethodFilterConfig{
+		Allow:     section.GetStringSlice(ConfigMethodsAllow),
+		Deny:      section.GetStringSlice(ConfigMethodsDeny),
+		Overrides: make([]*MethodOverride, overrides.ArraySize()),
+	}
+	for i := range conf.Overrides {
+		entry := overrides.ArrayEntry(i)
+		conf.Overrides[i] = &MethodOverride{
+			Identities:  entry.GetStringSlice(ConfigPolicyIdentities),
+			Claim:       entry.GetString(ConfigPolicyClaim),
+			ClaimValues: entry.GetStringSlice(ConfigPolicyClaimValues),
+			Allow:       entry.GetStringSlice(ConfigMethodsAllow),
+			Deny:        entry.GetStringSlice(ConfigMethodsDeny),
+		}
+	}
+	return conf
+}
+
+func ReadConfig(section config.Section) *Config {
+	apiKeys := apiKeysConfig(section)
+	policies := policiesConfig(section)
+	conf := &Config{
+		Enabled:      section.GetBool(ConfigEnabled),
+		APIKeyHeader: section.GetString(ConfigAPIKeyHeader),
+		APIKeys:      make([]APIKey, apiKeys.ArraySize()),
+		JWT: JWTConfig{
+			JWKSURL:             section.GetString(ConfigJWTJWKSURL),
+			Issuer:              section.GetString(ConfigJWTIssuer),
+			Audience:            section.GetString(ConfigJWTAudience),
+			IdentityClaim:       section.GetString(ConfigJWTIdentityClaim),
+			JWKSRefreshInterval: section.GetDuration(ConfigJWTJWKSRefreshInterval),
+			Leeway:              section.GetDuration(ConfigJWTLeeway),
+		},
+		Authenticators: section.GetStringSlice(ConfigAuthenticators),
+		RBAC: RBACConfig{
+			Enabled:  section.GetBool(ConfigRBACEnabled),
+			Policies: make([]*Policy, policies.ArraySize()),
+		},
+	}
+	for i := range conf.APIKeys {
+		entry := apiKeys.ArrayEntry(i)
+		conf.APIKeys[i] = APIKey{
+			ID:  entry.GetString(ConfigAPIKeyID),
+			Key: entry.GetString(ConfigAPIKeyKey),
+		}
+	}
+	for i := range conf.RBAC.Policies {
+		entry := policies.ArrayEntry(i)
+		conf.RBAC.Policies[i] = &Policy{
+			Identities:  entry.GetStringSlice(ConfigPolicyIdentities),
+			Claim:       entry.GetString(ConfigPolicyClaim),
+			ClaimValues: entry.GetStringSlice(ConfigPolicyClaimValues),
+			Methods:     entry.GetStringSlice(ConfigPolicyMethods),
+			Addresses:   entry.GetStringSlice(ConfigPolicyAddresses),
+		}
+	}
+	return conf
+}
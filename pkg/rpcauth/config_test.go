@@ -0,0 +1,146 @@
+// Copyright © 2023 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcauth
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadConfig(t *testing.T) {
+	config.RootConfigReset()
+	section := config.RootSection("auth")
+	InitConfig(section)
+
+	viper.SetConfigType("yaml")
+	err := viper.ReadConfig(strings.NewReader(`
+auth:
+  enabled: true
+  apiKeys:
+  - id: client1
+    key: secret1
+  - id: client2
+    key: secret2
+  jwt:
+    jwksURL: https://idp.example.com/jwks
+    issuer: https://idp.example.com
+    audience: ffsigner
+  authenticators:
+  - custom1
+  rbac:
+    enabled: true
+    policies:
+    - identities:
+      - client1
+      methods:
+      - eth_*
+      addresses:
+      - "0xfb075bb99f2aa4c49955bf703509a227d7a12248"
+    - claim: groups
+      claimValues:
+      - admins
+      methods:
+      - "*"
+      addresses:
+      - "*"
+`))
+	assert.NoError(t, err)
+
+	conf := ReadConfig(section)
+	assert.Equal(t, &Config{
+		Enabled:      true,
+		APIKeyHeader: DefaultAPIKeyHeader,
+		APIKeys: []APIKey{
+			{ID: "client1", Key: "secret1"},
+			{ID: "client2", Key: "secret2"},
+		},
+		JWT: JWTConfig{
+			JWKSURL:             "https://idp.example.com/jwks",
+			Issuer:              "https://idp.example.com",
+			Audience:            "ffsigner",
+			IdentityClaim:       DefaultJWTIdentityClaim,
+			JWKSRefreshInterval: 5 * time.Minute,
+			Leeway:              30 * time.Second,
+		},
+		Authenticators: []string{"custom1"},
+		RBAC: RBACConfig{
+			Enabled: true,
+			Policies: []*Policy{
+				{
+					Identities: []string{"client1"},
+					Methods:    []string{"eth_*"},
+					Addresses:  []string{"0xfb075bb99f2aa4c49955bf703509a227d7a12248"},
+				},
+				{
+					Claim:       "groups",
+					ClaimValues: []string{"admins"},
+					Methods:     []string{"*"},
+					Addresses:   []string{"*"},
+				},
+			},
+		},
+	}, conf)
+}
+
+func TestReadMethodsConfig(t *testing.T) {
+	config.RootConfigReset()
+	section := config.RootSection("methods")
+	InitMethodsConfig(section)
+
+	viper.SetConfigType("yaml")
+	err := viper.ReadConfig(strings.NewReader(`
+methods:
+  deny:
+  - debug_*
+  - admin_*
+  overrides:
+  - identities:
+    - operator
+    allow:
+    - "*"
+  - claim: groups
+    claimValues:
+    - readers
+    allow:
+    - eth_get*
+    deny:
+    - eth_getLogs
+`))
+	assert.NoError(t, err)
+
+	conf := ReadMethodsConfig(section)
+	assert.Equal(t, &MethodFilterConfig{
+		Deny: []string{"debug_*", "admin_*"},
+		Overrides: []*MethodOverride{
+			{
+				Identities: []string{"operator"},
+				Allow:      []string{"*"},
+			},
+			{
+				Claim:       "groups",
+				ClaimValues: []string{"readers"},
+				Allow:       []string{"eth_get*"},
+				Deny:        []string{"eth_getLogs"},
+			},
+		},
+	}, conf)
+}
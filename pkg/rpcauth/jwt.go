@@ -0,0 +1,274 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcauth
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-common/pkg/log"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+)
+
+const (
+	jwksFetchTimeout = 30 * time.Second
+	// An unknown key ID triggers a refresh of the JWKS (to pick up rotated keys), but no more often than this
+	jwksMinRefetchInterval = 30 * time.Second
+)
+
+// Only asymmetric algorithms are accepted, as the keys are public
+var jwtValidMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+
+type jwtAuthenticator struct {
+	conf       JWTConfig
+	parser     *jwt.Parser
+	httpClient *http.Client
+
+	mux       sync.Mutex
+	keys      map[string]interface{}
+	lastFetch time.Time
+	fetching  chan struct{} // closed when the fetch in flight completes
+	fetchErr  error
+}
+
+type jwks struct {
+	Keys []*jwk `json:"keys"`
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// NewJWTAuthenticator authenticates callers that supply a JWT as a bearer token in the Authorization header,
+// signed by one of the keys in a JSON Web Key Set (JWKS) that is fetched on demand, and refreshed periodically
+func NewJWTAuthenticator(conf *JWTConfig) Authenticator {
+	parserOptions := []jwt.ParserOption{
+		jwt.WithValidMethods(jwtValidMethods),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(conf.Leeway),
+	}
+	if conf.Issuer != "" {
+		parserOptions = append(parserOptions, jwt.WithIssuer(conf.Issuer))
+	}
+	if conf.Audience != "" {
+		parserOptions = append(parserOptions, jwt.WithAudience(conf.Audience))
+	}
+	return &jwtAuthenticator{
+		conf:       *conf,
+		parser:     jwt.NewParser(parserOptions...),
+		httpClient: &http.Client{Timeout: jwksFetchTimeout},
+		keys:       map[string]interface{}{},
+	}
+}
+
+func (a *jwtAuthenticator) Name() string {
+	return "jwt"
+}
+
+func (a *jwtAuthenticator) Authenticate(ctx context.Context, req *http.Request) (*Identity, error) {
+	const bearerPrefix = "bearer "
+	authHeader := req.Header.Get("Authorization")
+	if len(authHeader) <= len(bearerPrefix) || !strings.EqualFold(authHeader[:len(bearerPrefix)], bearerPrefix) {
+		return nil, nil
+	}
+	claims := jwt.MapClaims{}
+	_, err := a.parser.ParseWithClaims(authHeader[len(bearerPrefix):], claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return a.getKey(ctx, kid)
+	})
+	if err != nil {
+		return nil, err
+	}
+	id, _ := claims[a.conf.IdentityClaim].(string)
+	if id == "" {
+		return nil, i18n.NewError(ctx, signermsgs.MsgJWTMissingIdentityClaim, a.conf.IdentityClaim)
+	}
+	return &Identity{ID: id, Claims: claims}, nil
+}
+
+func (a *jwtAuthenticator) getKey(ctx context.Context, kid string) (interface{}, error) {
+	a.mux.Lock()
+	key, found := a.keys[kid]
+	sinceFetch := time.Since(a.lastFetch)
+	refetch := sinceFetch > a.conf.JWKSRefreshInterval || (!found && sinceFetch > jwksMinRefetchInterval)
+	fetching := a.fetching
+	if refetch && fetching == nil {
+		a.lastFetch = time.Now()
+		fetching = make(chan struct{})
+		a.fetching = fetching
+		// The lock is not held during the fetch, so requests with a cached key are not blocked behind it
+		go a.refreshKeys(ctx, fetching)
+	}
+	a.mux.Unlock()
+
+	// Only wait for a fetch in flight when it is needed to find the key
+	if fetching != nil && (refetch || !found) {
+		select {
+		case <-fetching:
+		case <-ctx.Done():
+			return nil, i18n.NewError(ctx, signermsgs.MsgJWKSFetchFailed, a.conf.JWKSURL, ctx.Err())
+		}
+		a.mux.Lock()
+		key, found = a.keys[kid]
+		err := a.fetchErr
+		a.mux.Unlock()
+		if err != nil {
+			if !found {
+				return nil, err
+			}
+			log.L(ctx).Warnf("Using cached JWKS key '%s': %s", kid, err)
+		}
+	}
+	if !found {
+		return nil, i18n.NewError(ctx, signermsgs.MsgJWTUnknownKeyID, kid)
+	}
+	return key, nil
+}
+
+// refreshKeys fetches the JWKS on behalf of every request waiting for it, so is not cancelled by the request that started it
+func (a *jwtAuthenticator) refreshKeys(ctx context.Context, fetching chan struct{}) {
+	keys, err := a.fetchKeys(context.WithoutCancel(ctx))
+	a.mux.Lock()
+	defer a.mux.Unlock()
+	if err == nil {
+		a.keys = keys
+	}
+	a.fetchErr = err
+	a.fetching = nil
+	close(fetching)
+}
+
+func (a *jwtAuthenticator) fetchKeys(ctx context.Context) (map[string]interface{}, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.conf.JWKSURL, nil)
+	if err != nil {
+		return nil, i18n.NewError(ctx, signermsgs.MsgJWKSFetchFailed, a.conf.JWKSURL, err)
+	}
+	res, err := a.httpClient.Do(req)
+	if err != nil {
+		return nil, i18n.NewError(ctx, signermsgs.MsgJWKSFetchFailed, a.conf.JWKSURL, err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, i18n.NewError(ctx, signermsgs.MsgJWKSFetchFailed, a.conf.JWKSURL, res.Status)
+	}
+	var keySet jwks
+	if err := json.NewDecoder(res.Body).Decode(&keySet); err != nil {
+		return nil, i18n.NewError(ctx, signermsgs.MsgJWKSFetchFailed, a.conf.JWKSURL, err)
+	}
+
+	keys := make(map[string]interface{}, len(keySet.Keys))
+	for _, k := range keySet.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			log.L(ctx).Warnf("Ignoring JWKS key '%s': %s", k.Kid, err)
+			continue
+		}
+		keys[k.Kid] = key
+	}
+	log.L(ctx).Debugf("Loaded %d keys from JWKS %s", len(keys), a.conf.JWKSURL)
+	return keys, nil
+}
+
+func (k *jwk) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeJWKInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeJWKInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if e.BitLen() > 31 {
+			return nil, fmt.Errorf("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		curve, ecdhCurve, err := jwkCurve(k.Crv)
+		if err != nil {
+			return nil, err
+		}
+		x, err := decodeJWKInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeJWKInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		// Parsing the uncompressed point with crypto/ecdh checks it is on the curve
+		byteLen := (curve.Params().BitSize + 7) / 8
+		if len(x.Bytes()) > byteLen || len(y.Bytes()) > byteLen {
+			return nil, fmt.Errorf("point is too large for curve '%s'", k.Crv)
+		}
+		point := make([]byte, 1+2*byteLen)
+		point[0] = 0x04
+		x.FillBytes(point[1 : 1+byteLen])
+		y.FillBytes(point[1+byteLen:])
+		if _, err := ecdhCurve.NewPublicKey(point); err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type '%s'", k.Kty)
+	}
+}
+
+func jwkCurve(crv string) (elliptic.Curve, ecdh.Curve, error) {
+	switch crv {
+	case "P-256":
+		return elliptic.P256(), ecdh.P256(), nil
+	case "P-384":
+		return elliptic.P384(), ecdh.P384(), nil
+	case "P-521":
+		return elliptic.P521(), ecdh.P521(), nil
+	default:
+		return nil, nil, fmt.Errorf("unsupported curve '%s'", crv)
+	}
+}
+
+func decodeJWKInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
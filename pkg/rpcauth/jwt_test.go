@@ -0,0 +1,335 @@
+// Copyright © 2023 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcauth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+type testJWKS struct {
+	server   *httptest.Server
+	rsaKey   *rsa.PrivateKey
+	ecKey    *ecdsa.PrivateKey
+	keys     []*jwk
+	status   int
+	requests int
+}
+
+func newTestJWKS(t *testing.T) *testJWKS {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	tj := &testJWKS{
+		rsaKey: rsaKey,
+		ecKey:  ecKey,
+		status: http.StatusOK,
+	}
+	tj.keys = []*jwk{
+		{
+			Kty: "RSA", Kid: "rsa1", Use: "sig",
+			N: b64(rsaKey.N.Bytes()),
+			E: b64(big.NewInt(int64(rsaKey.E)).Bytes()),
+		},
+		{
+			Kty: "EC", Kid: "ec1", Crv: "P-256",
+			X: b64(ecKey.X.Bytes()),
+			Y: b64(ecKey.Y.Bytes()),
+		},
+		{Kty: "RSA", Kid: "enc1", Use: "enc"},
+		{Kty: "oct", Kid: "oct1"},
+	}
+	tj.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tj.requests++
+		w.WriteHeader(tj.status)
+		_ = json.NewEncoder(w).Encode(&jwks{Keys: tj.keys})
+	}))
+	t.Cleanup(tj.server.Close)
+	return tj
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func (tj *testJWKS) authenticator(conf *JWTConfig) *jwtAuthenticator {
+	conf.JWKSURL = tj.server.URL
+	if conf.IdentityClaim == "" {
+		conf.IdentityClaim = DefaultJWTIdentityClaim
+	}
+	if conf.JWKSRefreshInterval == 0 {
+		conf.JWKSRefreshInterval = 5 * time.Minute
+	}
+	return NewJWTAuthenticator(conf).(*jwtAuthenticator)
+}
+
+func (tj *testJWKS) token(t *testing.T, method jwt.SigningMethod, kid string, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = kid
+	var key interface{} = tj.rsaKey
+	if _, isEC := method.(*jwt.SigningMethodECDSA); isEC {
+		key = tj.ecKey
+	}
+	signed, err := token.SignedString(key)
+	assert.NoError(t, err)
+	return signed
+}
+
+func validClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"sub": "user1",
+		"iss": "https://idp.example.com",
+		"aud": "ffsigner",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+func bearerRequest(token string) *http.Request {
+	req, _ := http.NewRequest(http.MethodPost, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	return req
+}
+
+func TestJWTAuthenticatorRSAandEC(t *testing.T) {
+	tj := newTestJWKS(t)
+	a := tj.authenticator(&JWTConfig{
+		Issuer:   "https://idp.example.com",
+		Audience: "ffsigner",
+	})
+	assert.Equal(t, "jwt", a.Name())
+	ctx := context.Background()
+
+	identity, err := a.Authenticate(ctx, bearerRequest(tj.token(t, jwt.SigningMethodRS256, "rsa1", validClaims())))
+	assert.NoError(t, err)
+	assert.Equal(t, "user1", identity.ID)
+	assert.Equal(t, "ffsigner", identity.Claims["aud"])
+
+	identity, err = a.Authenticate(ctx, bearerRequest(tj.token(t, jwt.SigningMethodES256, "ec1", validClaims())))
+	assert.NoError(t, err)
+	assert.Equal(t, "user1", identity.ID)
+
+	// The key set was only fetched once, and the unusable keys were skipped
+	assert.Equal(t, 1, tj.requests)
+	assert.Len(t, a.keys, 2)
+}
+
+func TestJWTAuthenticatorNoBearerToken(t *testing.T) {
+	tj := newTestJWKS(t)
+	a := tj.authenticator(&JWTConfig{})
+
+	req, _ := http.NewRequest(http.MethodPost, "/", nil)
+	identity, err := a.Authenticate(context.Background(), req)
+	assert.NoError(t, err)
+	assert.Nil(t, identity)
+
+	req.Header.Set("Authorization", "Basic dXNlcjpwYXNz")
+	identity, err = a.Authenticate(context.Background(), req)
+	assert.NoError(t, err)
+	assert.Nil(t, identity)
+	assert.Equal(t, 0, tj.requests)
+}
+
+func TestJWTAuthenticatorRejectsClaims(t *testing.T) {
+	tj := newTestJWKS(t)
+	a := tj.authenticator(&JWTConfig{
+		Issuer:   "https://idp.example.com",
+		Audience: "ffsigner",
+	})
+	ctx := context.Background()
+
+	claims := validClaims()
+	claims["iss"] = "https://other.example.com"
+	_, err := a.Authenticate(ctx, bearerRequest(tj.token(t, jwt.SigningMethodRS256, "rsa1", claims)))
+	assert.ErrorIs(t, err, jwt.ErrTokenInvalidIssuer)
+
+	claims = validClaims()
+	claims["aud"] = "other"
+	_, err = a.Authenticate(ctx, bearerRequest(tj.token(t, jwt.SigningMethodRS256, "rsa1", claims)))
+	assert.ErrorIs(t, err, jwt.ErrTokenInvalidAudience)
+
+	claims = validClaims()
+	claims["exp"] = time.Now().Add(-time.Hour).Unix()
+	_, err = a.Authenticate(ctx, bearerRequest(tj.token(t, jwt.SigningMethodRS256, "rsa1", claims)))
+	assert.ErrorIs(t, err, jwt.ErrTokenExpired)
+
+	claims = validClaims()
+	delete(claims, "exp")
+	_, err = a.Authenticate(ctx, bearerRequest(tj.token(t, jwt.SigningMethodRS256, "rsa1", claims)))
+	assert.ErrorIs(t, err, jwt.ErrTokenRequiredClaimMissing)
+
+	claims = validClaims()
+	delete(claims, "sub")
+	_, err = a.Authenticate(ctx, bearerRequest(tj.token(t, jwt.SigningMethodRS256, "rsa1", claims)))
+	assert.Regexp(t, "FF22108.*sub", err)
+}
+
+func TestJWTAuthenticatorRejectsSymmetric(t *testing.T) {
+	tj := newTestJWKS(t)
+	a := tj.authenticator(&JWTConfig{})
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, validClaims())
+	signed, err := token.SignedString([]byte("secret"))
+	assert.NoError(t, err)
+	_, err = a.Authenticate(context.Background(), bearerRequest(signed))
+	assert.ErrorIs(t, err, jwt.ErrTokenSignatureInvalid)
+}
+
+func TestJWTAuthenticatorUnknownKeyID(t *testing.T) {
+	tj := newTestJWKS(t)
+	a := tj.authenticator(&JWTConfig{})
+	ctx := context.Background()
+
+	_, err := a.Authenticate(ctx, bearerRequest(tj.token(t, jwt.SigningMethodRS256, "rsa2", validClaims())))
+	assert.Regexp(t, "FF22107.*rsa2", err)
+	assert.Equal(t, 1, tj.requests)
+
+	// A rotated key is not refetched straight away
+	_, err = a.Authenticate(ctx, bearerRequest(tj.token(t, jwt.SigningMethodRS256, "rsa2", validClaims())))
+	assert.Regexp(t, "FF22107.*rsa2", err)
+	assert.Equal(t, 1, tj.requests)
+
+	// But is refetched once the minimum interval has passed
+	tj.keys[0].Kid = "rsa2"
+	a.lastFetch = time.Now().Add(-jwksMinRefetchInterval - time.Second)
+	identity, err := a.Authenticate(ctx, bearerRequest(tj.token(t, jwt.SigningMethodRS256, "rsa2", validClaims())))
+	assert.NoError(t, err)
+	assert.Equal(t, "user1", identity.ID)
+	assert.Equal(t, 2, tj.requests)
+}
+
+func TestJWTAuthenticatorRefreshFailUsesCache(t *testing.T) {
+	tj := newTestJWKS(t)
+	a := tj.authenticator(&JWTConfig{JWKSRefreshInterval: time.Minute})
+	ctx := context.Background()
+
+	_, err := a.Authenticate(ctx, bearerRequest(tj.token(t, jwt.SigningMethodRS256, "rsa1", validClaims())))
+	assert.NoError(t, err)
+
+	tj.status = http.StatusInternalServerError
+	a.lastFetch = time.Now().Add(-2 * time.Minute)
+	_, err = a.Authenticate(ctx, bearerRequest(tj.token(t, jwt.SigningMethodRS256, "rsa1", validClaims())))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, tj.requests)
+
+	_, err = a.Authenticate(ctx, bearerRequest(tj.token(t, jwt.SigningMethodES256, "ec2", validClaims())))
+	assert.Regexp(t, "FF22107", err)
+	a.lastFetch = time.Time{}
+	_, err = a.Authenticate(ctx, bearerRequest(tj.token(t, jwt.SigningMethodES256, "ec2", validClaims())))
+	assert.Regexp(t, "FF22105.*500", err)
+}
+
+func TestJWTAuthenticatorFetchNotBlocking(t *testing.T) {
+	tj := newTestJWKS(t)
+	a := tj.authenticator(&JWTConfig{JWKSRefreshInterval: time.Minute})
+	_, err := a.getKey(context.Background(), "rsa1")
+	assert.NoError(t, err)
+
+	release := make(chan struct{})
+	slowRequests := 0
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		slowRequests++
+		<-release
+		_ = json.NewEncoder(w).Encode(&jwks{Keys: tj.keys})
+	}))
+	defer slow.Close()
+	a.conf.JWKSURL = slow.URL
+	a.lastFetch = time.Now().Add(-2 * time.Minute)
+
+	// A request that gives up waiting does not cancel the fetch
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = a.getKey(ctx, "ec2")
+	assert.Regexp(t, "FF22105.*canceled", err)
+
+	// A cached key is returned while the fetch is in flight
+	key, err := a.getKey(context.Background(), "rsa1")
+	assert.NoError(t, err)
+	assert.NotNil(t, key)
+
+	// An unknown key waits for the fetch in flight, rather than starting another
+	close(release)
+	_, err = a.getKey(context.Background(), "ec2")
+	assert.Regexp(t, "FF22107.*ec2", err)
+	assert.Equal(t, 1, slowRequests)
+}
+
+func TestJWTAuthenticatorFetchErrors(t *testing.T) {
+	ctx := context.Background()
+
+	a := NewJWTAuthenticator(&JWTConfig{JWKSURL: "::not a url"}).(*jwtAuthenticator)
+	_, err := a.fetchKeys(ctx)
+	assert.Regexp(t, "FF22105", err)
+
+	tj := newTestJWKS(t)
+	tj.server.Close()
+	a = tj.authenticator(&JWTConfig{})
+	_, err = a.fetchKeys(ctx)
+	assert.Regexp(t, "FF22105", err)
+
+	badJSON := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{!!!`))
+	}))
+	defer badJSON.Close()
+	a = NewJWTAuthenticator(&JWTConfig{JWKSURL: badJSON.URL}).(*jwtAuthenticator)
+	_, err = a.fetchKeys(ctx)
+	assert.Regexp(t, "FF22105", err)
+}
+
+func TestJWKPublicKeyErrors(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	assert.NoError(t, err)
+	x, y := b64(ecKey.X.Bytes()), b64(ecKey.Y.Bytes())
+	tooLarge := b64(append([]byte{0x01}, make([]byte, 48)...))
+
+	for _, k := range []*jwk{
+		{Kty: "RSA", N: "!!!", E: "AQAB"},
+		{Kty: "RSA", N: "AQAB", E: "!!!"},
+		{Kty: "RSA", N: "AQAB", E: b64(big.NewInt(1 << 32).Bytes())},
+		{Kty: "EC", Crv: "P-192", X: x, Y: y},
+		{Kty: "EC", Crv: "P-384", X: "!!!", Y: y},
+		{Kty: "EC", Crv: "P-384", X: x, Y: "!!!"},
+		{Kty: "EC", Crv: "P-384", X: tooLarge, Y: y},
+		{Kty: "EC", Crv: "P-384", X: y, Y: x},
+		{Kty: "oct"},
+	} {
+		_, err := k.publicKey()
+		assert.Error(t, err, "%+v", k)
+	}
+
+	for _, crv := range []string{"P-384", "P-521"} {
+		_, _, err := jwkCurve(crv)
+		assert.NoError(t, err)
+	}
+	k := &jwk{Kty: "EC", Crv: "P-384", X: x, Y: y}
+	key, err := k.publicKey()
+	assert.NoError(t, err)
+	assert.True(t, key.(*ecdsa.PublicKey).Equal(&ecKey.PublicKey))
+}
@@ -0,0 +1,135 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcauth
+
+import (
+	"context"
+	"path"
+
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+)
+
+// MethodFilterConfig restricts the JSON/RPC methods the server will handle or forward. A method is denied
+// if it matches any Deny pattern, and when there are Allow patterns it must also match one of them.
+// Patterns are as for Policy.Methods, such as "debug_*".
+type MethodFilterConfig struct {
+	Allow     []string
+	Deny      []string
+	Overrides []*MethodOverride
+}
+
+// MethodOverride replaces the Allow and Deny patterns for the identities it matches, in the same way
+// as a Policy. The first override matching the caller is used.
+type MethodOverride struct {
+	Identities  []string
+	Claim       string
+	ClaimValues []string
+	Allow       []string
+	Deny        []string
+}
+
+type methodRules struct {
+	allow []string
+	deny  []string
+}
+
+type methodOverride struct {
+	identity *policy
+	rules    *methodRules
+}
+
+// MethodFilter checks each JSON/RPC method against the allow and deny patterns that apply to the caller.
+// Unlike the Authorizer it does not require authentication, so it can also protect anonymous deployments.
+type MethodFilter struct {
+	rules     *methodRules
+	overrides []*methodOverride
+}
+
+func NewMethodFilter(ctx context.Context, conf *MethodFilterConfig) (*MethodFilter, error) {
+	rules, err := newMethodRules(ctx, conf.Allow, conf.Deny)
+	if err != nil {
+		return nil, err
+	}
+	f := &MethodFilter{
+		rules:     rules,
+		overrides: make([]*methodOverride, len(conf.Overrides)),
+	}
+	for i, o := range conf.Overrides {
+		if len(o.Identities) == 0 && (o.Claim == "" || len(o.ClaimValues) == 0) {
+			return nil, i18n.NewError(ctx, signermsgs.MsgInvalidMethodOverride, i, "no identities or claim values")
+		}
+		rules, err := newMethodRules(ctx, o.Allow, o.Deny)
+		if err != nil {
+			return nil, i18n.NewError(ctx, signermsgs.MsgInvalidMethodOverride, i, err)
+		}
+		f.overrides[i] = &methodOverride{
+			identity: &policy{
+				identities:  stringSet(o.Identities),
+				claim:       o.Claim,
+				claimValues: stringSet(o.ClaimValues),
+			},
+			rules: rules,
+		}
+	}
+	return f, nil
+}
+
+func newMethodRules(ctx context.Context, allow, deny []string) (*methodRules, error) {
+	for _, pattern := range append(append([]string{}, allow...), deny...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, i18n.NewError(ctx, signermsgs.MsgInvalidMethodPattern, pattern, err)
+		}
+	}
+	return &methodRules{allow: allow, deny: deny}, nil
+}
+
+func matchesAny(patterns []string, method string) bool {
+	for _, pattern := range patterns {
+		if match, _ := path.Match(pattern, method); match {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *methodRules) allows(method string) bool {
+	if matchesAny(r.deny, method) {
+		return false
+	}
+	return len(r.allow) == 0 || matchesAny(r.allow, method)
+}
+
+func (f *MethodFilter) rulesFor(identity *Identity) *methodRules {
+	if identity != nil {
+		for _, o := range f.overrides {
+			if o.identity.matchesIdentity(identity) {
+				return o.rules
+			}
+		}
+	}
+	return f.rules
+}
+
+// CheckMethod returns an error if the JSON/RPC method is not allowed for the identity, which is nil
+// when authentication is not enabled
+func (f *MethodFilter) CheckMethod(ctx context.Context, identity *Identity, method string) error {
+	if !f.rulesFor(identity).allows(method) {
+		return i18n.NewError(ctx, signermsgs.MsgMethodNotAllowed, method)
+	}
+	return nil
+}
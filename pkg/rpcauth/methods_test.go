@@ -0,0 +1,92 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcauth
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMethodFilterDeny(t *testing.T) {
+	ctx := context.Background()
+	f, err := NewMethodFilter(ctx, &MethodFilterConfig{
+		Deny: []string{"debug_*", "admin_*", "txpool_*"},
+	})
+	assert.NoError(t, err)
+
+	assert.NoError(t, f.CheckMethod(ctx, nil, "eth_sendTransaction"))
+	assert.Regexp(t, "FF22121.*debug_traceTransaction", f.CheckMethod(ctx, nil, "debug_traceTransaction"))
+	assert.Regexp(t, "FF22121", f.CheckMethod(ctx, &Identity{ID: "tenantA"}, "admin_peers"))
+}
+
+func TestMethodFilterAllow(t *testing.T) {
+	ctx := context.Background()
+	f, err := NewMethodFilter(ctx, &MethodFilterConfig{
+		Allow: []string{"eth_*", "net_version"},
+		Deny:  []string{"eth_sign"},
+	})
+	assert.NoError(t, err)
+
+	assert.NoError(t, f.CheckMethod(ctx, nil, "eth_call"))
+	assert.NoError(t, f.CheckMethod(ctx, nil, "net_version"))
+	assert.Regexp(t, "FF22121", f.CheckMethod(ctx, nil, "web3_clientVersion"))
+	// Deny takes precedence
+	assert.Regexp(t, "FF22121", f.CheckMethod(ctx, nil, "eth_sign"))
+}
+
+func TestMethodFilterOverrides(t *testing.T) {
+	ctx := context.Background()
+	f, err := NewMethodFilter(ctx, &MethodFilterConfig{
+		Deny: []string{"debug_*"},
+		Overrides: []*MethodOverride{
+			{
+				Identities: []string{"operator"},
+				Allow:      []string{"*"},
+			},
+			{
+				Claim:       "groups",
+				ClaimValues: []string{"readers"},
+				Allow:       []string{"eth_get*"},
+			},
+		},
+	})
+	assert.NoError(t, err)
+
+	assert.Regexp(t, "FF22121", f.CheckMethod(ctx, nil, "debug_traceTransaction"))
+	assert.Regexp(t, "FF22121", f.CheckMethod(ctx, &Identity{ID: "tenantA"}, "debug_traceTransaction"))
+	assert.NoError(t, f.CheckMethod(ctx, &Identity{ID: "operator"}, "debug_traceTransaction"))
+
+	reader := &Identity{ID: "user1", Claims: map[string]interface{}{"groups": []interface{}{"readers"}}}
+	assert.NoError(t, f.CheckMethod(ctx, reader, "eth_getBalance"))
+	assert.Regexp(t, "FF22121", f.CheckMethod(ctx, reader, "eth_sendTransaction"))
+	// The override replaces the default rules entirely
+	assert.Regexp(t, "FF22121", f.CheckMethod(ctx, reader, "debug_traceTransaction"))
+}
+
+func TestNewMethodFilterBadConfig(t *testing.T) {
+	ctx := context.Background()
+	_, err := NewMethodFilter(ctx, &MethodFilterConfig{Deny: []string{"["}})
+	assert.Regexp(t, "FF22119", err)
+
+	_, err = NewMethodFilter(ctx, &MethodFilterConfig{Overrides: []*MethodOverride{{Allow: []string{"*"}}}})
+	assert.Regexp(t, "FF22120.*0.*no identities", err)
+
+	_, err = NewMethodFilter(ctx, &MethodFilterConfig{Overrides: []*MethodOverride{{Identities: []string{"a"}, Allow: []string{"["}}}})
+	assert.Regexp(t, "FF22120.*FF22119", err)
+}
@@ -0,0 +1,139 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcauth
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-common/pkg/log"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+)
+
+// Identity is the authenticated caller of a JSON/RPC request
+type Identity struct {
+	// ID identifies the caller, such as the ID of an API key, or the subject of a JWT
+	ID string `json:"id"`
+	// Authenticator is the name of the authenticator that verified the credentials
+	Authenticator string `json:"authenticator"`
+	// Claims are the verified claims of a JWT, and are nil for other authenticators
+	Claims map[string]interface{} `json:"claims,omitempty"`
+}
+
+// Authenticator verifies the credentials on an incoming HTTP request (including WebSocket upgrades)
+type Authenticator interface {
+	Name() string
+	// Authenticate returns nil (with no error) when the request does not carry the type of credentials
+	// handled by this authenticator, so that the next authenticator can be tried. An error is returned
+	// when credentials are present, but are not valid.
+	Authenticate(ctx context.Context, req *http.Request) (*Identity, error)
+}
+
+// AuthenticatorFactory builds a custom authenticator, registered with RegisterAuthenticator
+type AuthenticatorFactory func(ctx context.Context) (Authenticator, error)
+
+var (
+	factoriesLock sync.Mutex
+	factories     = map[string]AuthenticatorFactory{}
+)
+
+// RegisterAuthenticator makes a custom authenticator available by name, to be enabled
+// in the list of authenticators in the configuration
+func RegisterAuthenticator(name string, factory AuthenticatorFactory) {
+	factoriesLock.Lock()
+	defer factoriesLock.Unlock()
+	factories[name] = factory
+}
+
+// RegisteredAuthenticators returns the names of all custom authenticators that have been registered
+func RegisteredAuthenticators() []string {
+	factoriesLock.Lock()
+	defer factoriesLock.Unlock()
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewAuthenticators builds the API key and JWT authenticators (when configured), followed by
+// any custom authenticators listed in the configuration, in the order they are tried
+func NewAuthenticators(ctx context.Context, conf *Config) ([]Authenticator, error) {
+	var authenticators []Authenticator
+	if len(conf.APIKeys) > 0 {
+		a, err := NewAPIKeyAuthenticator(ctx, conf.APIKeyHeader, conf.APIKeys)
+		if err != nil {
+			return nil, err
+		}
+		authenticators = append(authenticators, a)
+	}
+	if conf.JWT.JWKSURL != "" {
+		authenticators = append(authenticators, NewJWTAuthenticator(&conf.JWT))
+	}
+	for _, name := range conf.Authenticators {
+		factoriesLock.Lock()
+		factory := factories[name]
+		factoriesLock.Unlock()
+		if factory == nil {
+			return nil, i18n.NewError(ctx, signermsgs.MsgUnknownAuthenticator, name)
+		}
+		a, err := factory(ctx)
+		if err != nil {
+			return nil, err
+		}
+		authenticators = append(authenticators, a)
+	}
+	if len(authenticators) == 0 {
+		return nil, i18n.NewError(ctx, signermsgs.MsgNoAuthenticators)
+	}
+	return authenticators, nil
+}
+
+// Authenticate tries each authenticator in turn, and fails if none of them find valid credentials
+func Authenticate(ctx context.Context, authenticators []Authenticator, req *http.Request) (*Identity, error) {
+	for _, a := range authenticators {
+		identity, err := a.Authenticate(ctx, req)
+		if err != nil {
+			// We do not return the detail of why the credentials were rejected to the caller
+			log.L(ctx).Warnf("Authentication failed (%s): %s", a.Name(), err)
+			return nil, i18n.NewError(ctx, signermsgs.MsgAuthenticationFailed)
+		}
+		if identity != nil {
+			identity.Authenticator = a.Name()
+			log.L(ctx).Debugf("Authenticated '%s' (%s)", identity.ID, a.Name())
+			return identity, nil
+		}
+	}
+	return nil, i18n.NewError(ctx, signermsgs.MsgUnauthenticated)
+}
+
+type identityContextKey struct{}
+
+// WithIdentity returns a context carrying the authenticated identity of the caller
+func WithIdentity(ctx context.Context, identity *Identity) context.Context {
+	return context.WithValue(ctx, identityContextKey{}, identity)
+}
+
+// GetIdentity returns the authenticated identity of the caller, or nil if authentication is not enabled
+func GetIdentity(ctx context.Context) *Identity {
+	identity, _ := ctx.Value(identityContextKey{}).(*Identity)
+	return identity
+}
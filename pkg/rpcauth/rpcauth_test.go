@@ -0,0 +1,115 @@
+// Copyright © 2023 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcauth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testAuthenticator struct {
+	identity *Identity
+	err      error
+}
+
+func (ta *testAuthenticator) Name() string { return "test" }
+
+func (ta *testAuthenticator) Authenticate(ctx context.Context, req *http.Request) (*Identity, error) {
+	return ta.identity, ta.err
+}
+
+func TestNewAuthenticatorsAll(t *testing.T) {
+	RegisterAuthenticator("test1", func(ctx context.Context) (Authenticator, error) {
+		return &testAuthenticator{}, nil
+	})
+	assert.Contains(t, RegisteredAuthenticators(), "test1")
+
+	authenticators, err := NewAuthenticators(context.Background(), &Config{
+		APIKeyHeader:   DefaultAPIKeyHeader,
+		APIKeys:        []APIKey{{ID: "client1", Key: "secret1"}},
+		JWT:            JWTConfig{JWKSURL: "http://localhost/jwks"},
+		Authenticators: []string{"test1"},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, authenticators, 3)
+	assert.Equal(t, "apikey", authenticators[0].Name())
+	assert.Equal(t, "jwt", authenticators[1].Name())
+	assert.Equal(t, "test", authenticators[2].Name())
+}
+
+func TestNewAuthenticatorsNone(t *testing.T) {
+	_, err := NewAuthenticators(context.Background(), &Config{})
+	assert.Regexp(t, "FF22103", err)
+}
+
+func TestNewAuthenticatorsBadAPIKey(t *testing.T) {
+	_, err := NewAuthenticators(context.Background(), &Config{
+		APIKeys: []APIKey{{ID: "client1"}},
+	})
+	assert.Regexp(t, "FF22106", err)
+}
+
+func TestNewAuthenticatorsUnknown(t *testing.T) {
+	_, err := NewAuthenticators(context.Background(), &Config{
+		Authenticators: []string{"unknown"},
+	})
+	assert.Regexp(t, "FF22104.*unknown", err)
+}
+
+func TestNewAuthenticatorsFactoryFail(t *testing.T) {
+	RegisterAuthenticator("test2", func(ctx context.Context) (Authenticator, error) {
+		return nil, fmt.Errorf("pop")
+	})
+	_, err := NewAuthenticators(context.Background(), &Config{
+		Authenticators: []string{"test2"},
+	})
+	assert.Regexp(t, "pop", err)
+}
+
+func TestAuthenticate(t *testing.T) {
+	ctx := context.Background()
+	req, _ := http.NewRequest(http.MethodPost, "/", nil)
+
+	identity, err := Authenticate(ctx, []Authenticator{
+		&testAuthenticator{},
+		&testAuthenticator{identity: &Identity{ID: "client1"}},
+	}, req)
+	assert.NoError(t, err)
+	assert.Equal(t, &Identity{ID: "client1", Authenticator: "test"}, identity)
+
+	_, err = Authenticate(ctx, []Authenticator{
+		&testAuthenticator{err: fmt.Errorf("pop")},
+	}, req)
+	assert.Regexp(t, "FF22102", err)
+	assert.NotRegexp(t, "pop", err)
+
+	_, err = Authenticate(ctx, []Authenticator{
+		&testAuthenticator{},
+	}, req)
+	assert.Regexp(t, "FF22101", err)
+}
+
+func TestIdentityContext(t *testing.T) {
+	ctx := context.Background()
+	assert.Nil(t, GetIdentity(ctx))
+	identity := &Identity{ID: "client1"}
+	assert.Equal(t, identity, GetIdentity(WithIdentity(ctx, identity)))
+}
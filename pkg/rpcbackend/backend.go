@@ -29,7 +29,9 @@ import (
 	"github.com/hyperledger/firefly-common/pkg/i18n"
 	"github.com/hyperledger/firefly-common/pkg/log"
 	"github.com/hyperledger/firefly-signer/internal/signermsgs"
-	"github.com/sirupsen/logrus"
+	"github.com/hyperledger/firefly-signer/pkg/debuglog"
+	"github.com/hyperledger/firefly-signer/pkg/errorcodes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
 )
 
 type RPCCode int64
@@ -38,6 +40,17 @@ const (
 	RPCCodeParseError     RPCCode = -32700
 	RPCCodeInvalidRequest RPCCode = -32600
 	RPCCodeInternalError  RPCCode = -32603
+	// RPCCodeMethodNotFound is the JSON/RPC 2.0 code for a method that does not exist, or is not available
+	RPCCodeMethodNotFound RPCCode = -32601
+	// RPCCodeLimitExceeded is the EIP-1474 code for a request that exceeds a rate limit
+	RPCCodeLimitExceeded RPCCode = -32005
+	// RPCCodeTimeout is the code used by go-ethereum for a request that timed out
+	RPCCodeTimeout RPCCode = -32002
+	// RPCCodeCircuitOpen is a server defined code for a request rejected without being sent to the backend,
+	// because the circuit breaker is open after the backend failed too many requests
+	RPCCodeCircuitOpen RPCCode = -32010
+	// RPCCodeUnauthorized is the EIP-1193 code for a caller that is not authorized
+	RPCCodeUnauthorized RPCCode = 4100
 )
 
 type RPC interface {
@@ -48,6 +61,7 @@ type RPC interface {
 type Backend interface {
 	RPC
 	SyncRequest(ctx context.Context, rpcReq *RPCRequest) (rpcRes *RPCResponse, err error)
+	BatchRequest(ctx context.Context, rpcReqs []*RPCRequest) (rpcRes []*RPCResponse, err error)
 }
 
 // NewRPCClient Constructor
@@ -58,24 +72,44 @@ func NewRPCClient(client *resty.Client) Backend {
 // NewRPCClientWithOption Constructor
 func NewRPCClientWithOption(client *resty.Client, options RPCClientOptions) Backend {
 	rpcClient := &RPCClient{
-		client: client,
+		client:       client,
+		interceptors: options.Interceptors,
 	}
 
 	if options.MaxConcurrentRequest > 0 {
 		rpcClient.concurrencySlots = make(chan bool, options.MaxConcurrentRequest)
 	}
 
+	if options.RetryOptions != nil {
+		rpcClient.retryPolicy = newRetryPolicy(options.RetryOptions)
+	}
+
+	if options.BatchOptions != nil {
+		rpcClient.startBatchDispatcher(options.BatchOptions)
+	}
+
 	return rpcClient
 }
 
 type RPCClient struct {
-	client           *resty.Client
-	concurrencySlots chan bool
-	requestCounter   int64
+	client             *resty.Client
+	concurrencySlots   chan bool
+	requestCounter     int64
+	batchQueue         chan *batchedRequest
+	batchDispatchSlots chan bool
+	batchOptions       RPCClientBatchOptions
+	retryPolicy        *retryPolicy
+	interceptors       []*RPCClientInterceptor
 }
 
 type RPCClientOptions struct {
 	MaxConcurrentRequest int64
+	// BatchOptions enables micro-batching of SyncRequest calls, when set
+	BatchOptions *RPCClientBatchOptions
+	// RetryOptions enables retry of requests that fail to get a response, when set
+	RetryOptions *RPCClientRetryOptions
+	// Interceptors are called for every request, in order
+	Interceptors []*RPCClientInterceptor
 }
 
 type RPCRequest struct {
@@ -99,6 +133,16 @@ func (e *RPCError) String() string {
 	return e.Message
 }
 
+// ErrorCode returns the code of the failure from the data of errors returned by the signer (see pkg/errorcodes),
+// or an empty string for other errors
+func (e *RPCError) ErrorCode() errorcodes.Code {
+	var data errorcodes.Data
+	if err := json.Unmarshal(e.Data.Bytes(), &data); err != nil {
+		return ""
+	}
+	return data.ErrorCode
+}
+
 type RPCResponse struct {
 	JSONRpc string           `json:"jsonrpc"`
 	ID      *fftypes.JSONAny `json:"id"`
@@ -123,11 +167,15 @@ func (rc *RPCClient) allocateRequestID(req *RPCRequest) string {
 }
 
 func (rc *RPCClient) CallRPC(ctx context.Context, result interface{}, method string, params ...interface{}) *RPCError {
+	return callRPCWithSyncRequest(ctx, rc.SyncRequest, result, method, params)
+}
+
+func callRPCWithSyncRequest(ctx context.Context, syncRequest func(context.Context, *RPCRequest) (*RPCResponse, error), result interface{}, method string, params []interface{}) *RPCError {
 	rpcReq, rpcErr := buildRequest(ctx, method, params)
 	if rpcErr != nil {
 		return rpcErr
 	}
-	res, err := rc.SyncRequest(ctx, rpcReq)
+	res, err := syncRequest(ctx, rpcReq)
 	if err != nil {
 		if res != nil && res.Error != nil && res.Error.Code != 0 {
 			return res.Error
@@ -142,24 +190,23 @@ func (rc *RPCClient) CallRPC(ctx context.Context, result interface{}, method str
 	return nil
 }
 
-// SyncRequest sends an individual RPC request to the backend (always over HTTP currently),
+// SyncRequest sends an individual RPC request to the backend over HTTP,
 // and waits synchronously for the response, or an error.
 //
 // In all return paths *including error paths* the RPCResponse is populated
 // so the caller has an RPC structure to send back to the front-end caller.
 func (rc *RPCClient) SyncRequest(ctx context.Context, rpcReq *RPCRequest) (rpcRes *RPCResponse, err error) {
-	if rc.concurrencySlots != nil {
-		select {
-		case rc.concurrencySlots <- true:
-			// wait for the concurrency slot and continue
-		case <-ctx.Done():
-			err := i18n.NewError(ctx, signermsgs.MsgRequestCanceledContext, rpcReq.ID)
-			return RPCErrorResponse(err, rpcReq.ID, RPCCodeInternalError), err
-		}
-		defer func() {
-			<-rc.concurrencySlots
-		}()
+	ctx, span := startClientSpan(ctx, rpcReq.Method, semconv.RPCMethodKey.String(rpcReq.Method))
+	defer func() { endSpan(span, rpcRes, err) }()
+
+	if rc.batchQueue != nil {
+		return rc.batchSyncRequest(ctx, rpcReq)
+	}
+
+	if err := rc.acquireConcurrencySlot(ctx, rpcReq.ID); err != nil {
+		return RPCErrorResponse(err, rpcReq.ID, RPCCodeInternalError), err
 	}
+	defer rc.releaseConcurrencySlot()
 
 	// We always set the back-end request ID - as we need to support requests coming in from
 	// multiple concurrent clients on our front-end that might use clashing IDs.
@@ -170,33 +217,39 @@ func (rc *RPCClient) SyncRequest(ctx context.Context, rpcReq *RPCRequest) (rpcRe
 		// We're proxying a request with front-end RPC ID - log that as well
 		rpcTraceID = fmt.Sprintf("%s->%s", rpcReq.ID, rpcTraceID)
 	}
-
-	rpcRes = new(RPCResponse)
+	header, err := rc.beforeRequest(ctx, &beReq)
+	if err != nil {
+		return RPCErrorResponse(err, rpcReq.ID, RPCCodeInternalError), err
+	}
 
 	log.L(ctx).Debugf("RPC[%s] --> %s", rpcTraceID, rpcReq.Method)
-	if logrus.IsLevelEnabled(logrus.TraceLevel) {
+	if debuglog.Active(debuglog.RPCWire) {
 		jsonInput, _ := json.Marshal(rpcReq)
-		log.L(ctx).Tracef("RPC[%s] INPUT: %s", rpcTraceID, jsonInput)
+		debuglog.Logf(ctx, debuglog.RPCWire, "RPC[%s] INPUT: %s", rpcTraceID, jsonInput)
 	}
 	rpcStartTime := time.Now()
-	res, err := rc.client.R().
-		SetContext(ctx).
-		SetBody(beReq).
-		SetResult(&rpcRes).
-		SetError(rpcRes).
-		Post("")
+	res, err := rc.post(ctx, rpcTraceID, []string{rpcReq.Method}, func() (*resty.Response, error) {
+		rpcRes = new(RPCResponse)
+		return rc.newRequest(ctx).
+			SetHeaderMultiValues(header).
+			SetBody(beReq).
+			SetResult(&rpcRes).
+			SetError(rpcRes).
+			Post("")
+	})
 
 	// Restore the original ID
 	rpcRes.ID = rpcReq.ID
 	if err != nil {
 		err := i18n.NewError(ctx, signermsgs.MsgRPCRequestFailed, err)
 		log.L(ctx).Errorf("RPC[%s] <-- ERROR: %s", rpcTraceID, err)
+		rc.onError(ctx, []*RPCRequest{&beReq}, err)
 		rpcRes = RPCErrorResponse(err, rpcReq.ID, RPCCodeInternalError)
 		return rpcRes, err
 	}
-	if logrus.IsLevelEnabled(logrus.TraceLevel) {
+	if debuglog.Active(debuglog.RPCWire) {
 		jsonOutput, _ := json.Marshal(rpcRes)
-		log.L(ctx).Tracef("RPC[%s] OUTPUT: %s", rpcTraceID, jsonOutput)
+		debuglog.Logf(ctx, debuglog.RPCWire, "RPC[%s] OUTPUT: %s", rpcTraceID, jsonOutput)
 	}
 	// JSON/RPC allows errors to be returned with a 200 status code, as well as other status codes
 	if res.IsError() || rpcRes.Error != nil && rpcRes.Error.Code != 0 {
@@ -209,6 +262,7 @@ func (rc *RPCClient) SyncRequest(ctx context.Context, rpcReq *RPCRequest) (rpcRe
 			rpcMsg = i18n.NewError(ctx, signermsgs.MsgRPCRequestFailed, res.Status()).Error()
 		}
 		log.L(ctx).Errorf("RPC[%s] <-- [%d]: %s", rpcTraceID, res.StatusCode(), errLog)
+		rc.afterResponse(ctx, []*RPCRequest{&beReq}, []*RPCResponse{rpcRes}, rpcStartTime)
 		err := errors.New(rpcMsg)
 		return rpcRes, err
 	}
@@ -217,22 +271,48 @@ func (rc *RPCClient) SyncRequest(ctx context.Context, rpcReq *RPCRequest) (rpcRe
 		// We don't want a result for errors, but a null success response needs to go in there
 		rpcRes.Result = fftypes.JSONAnyPtr(fftypes.NullString)
 	}
+	rc.afterResponse(ctx, []*RPCRequest{&beReq}, []*RPCResponse{rpcRes}, rpcStartTime)
 	return rpcRes, nil
 }
 
+func (rc *RPCClient) acquireConcurrencySlot(ctx context.Context, id *fftypes.JSONAny) error {
+	if rc.concurrencySlots != nil {
+		select {
+		case rc.concurrencySlots <- true:
+			// wait for the concurrency slot and continue
+		case <-ctx.Done():
+			return i18n.NewError(ctx, signermsgs.MsgRequestCanceledContext, id)
+		}
+	}
+	return nil
+}
+
+func (rc *RPCClient) releaseConcurrencySlot() {
+	if rc.concurrencySlots != nil {
+		<-rc.concurrencySlots
+	}
+}
+
 func RPCErrorResponse(err error, id *fftypes.JSONAny, code RPCCode) *RPCResponse {
 	return &RPCResponse{
 		JSONRpc: "2.0",
 		ID:      id,
-		Error: &RPCError{
-			Code:    int64(code),
-			Message: err.Error(),
-		},
+		Error:   newRPCError(err, code),
 	}
 }
 
 func NewRPCError(ctx context.Context, code RPCCode, msg i18n.ErrorMessageKey, inserts ...interface{}) *RPCError {
-	return &RPCError{Code: int64(code), Message: i18n.NewError(ctx, msg, inserts...).Error()}
+	return newRPCError(i18n.NewError(ctx, msg, inserts...), code)
+}
+
+// newRPCError returns the error with the code of the failure in its data, when it has one
+func newRPCError(err error, code RPCCode) *RPCError {
+	rpcErr := &RPCError{Code: int64(code), Message: err.Error()}
+	if data := errorcodes.DataOf(err); data != nil {
+		b, _ := json.Marshal(data)
+		rpcErr.Data = fftypes.JSONAny(b)
+	}
+	return rpcErr
 }
 
 func buildRequest(ctx context.Context, method string, params []interface{}) (*RPCRequest, *RPCError) {
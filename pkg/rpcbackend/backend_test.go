@@ -27,7 +27,10 @@ import (
 
 	"github.com/hyperledger/firefly-common/pkg/ffresty"
 	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-common/pkg/i18n"
 	"github.com/hyperledger/firefly-signer/internal/signerconfig"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/hyperledger/firefly-signer/pkg/errorcodes"
 	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
@@ -254,6 +257,19 @@ func TestSyncRPCCallErrorBadInput(t *testing.T) {
 	var txCount ethtypes.HexInteger
 	err := rb.CallRPC(ctx, &txCount, "test-bad-params", map[bool]bool{false: true})
 	assert.Regexp(t, "FF22011", err)
+	assert.Equal(t, errorcodes.InvalidRequest, err.ErrorCode())
+	assert.JSONEq(t, `{"errorCode":"FFS-RPC-001","messageKey":"FF22011"}`, err.Data.String())
+}
+
+func TestRPCErrorCode(t *testing.T) {
+	rpcRes := RPCErrorResponse(i18n.NewError(context.Background(), signermsgs.MsgRateLimitExceeded, "requests"), nil, RPCCodeInternalError)
+	assert.Equal(t, errorcodes.RateLimited, rpcRes.Error.ErrorCode())
+
+	// Errors that are not from the signer have no code
+	rpcRes = RPCErrorResponse(fmt.Errorf("pop"), nil, RPCCodeInternalError)
+	assert.Empty(t, rpcRes.Error.Data)
+	assert.Empty(t, rpcRes.Error.ErrorCode())
+	assert.Empty(t, (&RPCError{Data: *fftypes.JSONAnyPtr(`"0x08c379a0"`)}).ErrorCode())
 }
 
 func TestSyncRPCCallServerDown(t *testing.T) {
@@ -0,0 +1,263 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcbackend
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-common/pkg/log"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/hyperledger/firefly-signer/pkg/debuglog"
+)
+
+const (
+	DefaultBatchSize                   = 500
+	DefaultBatchTimeout                = 50 * time.Millisecond
+	DefaultBatchMaxDispatchConcurrency = 50
+)
+
+// RPCClientBatchOptions configures micro-batching, where concurrent calls to SyncRequest
+// are coalesced into a single batch JSON/RPC request to the backend
+type RPCClientBatchOptions struct {
+	// BatchDispatcherContext controls the lifecycle of the dispatcher - any queued requests fail when it is cancelled
+	BatchDispatcherContext context.Context
+	// BatchSize is the maximum number of requests in a batch, which is dispatched immediately when full
+	BatchSize int
+	// BatchTimeout is the maximum time the first request in a batch waits for others to join it
+	BatchTimeout time.Duration
+	// BatchMaxDispatchConcurrency is the maximum number of batches in-flight to the backend at once
+	BatchMaxDispatchConcurrency int
+}
+
+type batchedRequest struct {
+	rpcReq *RPCRequest
+	result chan *batchedResult
+}
+
+type batchedResult struct {
+	rpcRes *RPCResponse
+	err    error
+}
+
+// BatchRequest sends a set of RPC requests to the backend in a single HTTP round trip.
+//
+// The responses are returned in the same order as the requests, with per-item errors set
+// in each RPCResponse. An error is only returned when the batch as a whole failed, in which
+// case every RPCResponse is populated with that error.
+func (rc *RPCClient) BatchRequest(ctx context.Context, rpcReqs []*RPCRequest) (rpcResponses []*RPCResponse, err error) {
+	ctx, span := startClientSpan(ctx, "batch", attrBatchSize.Int(len(rpcReqs)))
+	defer func() { endSpan(span, nil, err) }()
+
+	rpcResponses = make([]*RPCResponse, len(rpcReqs))
+	if len(rpcReqs) == 0 {
+		return rpcResponses, nil
+	}
+	failAll := func(err error) ([]*RPCResponse, error) {
+		for i, rpcReq := range rpcReqs {
+			rpcResponses[i] = RPCErrorResponse(err, rpcReq.ID, RPCCodeInternalError)
+		}
+		return rpcResponses, err
+	}
+
+	if err := rc.acquireConcurrencySlot(ctx, rpcReqs[0].ID); err != nil {
+		return failAll(err)
+	}
+	defer rc.releaseConcurrencySlot()
+
+	// As with SyncRequest we set our own back-end request IDs, which we use to correlate
+	// the responses (the backend is not obliged to return them in order)
+	beReqs := make([]*RPCRequest, len(rpcReqs))
+	reqIdxByID := make(map[string]int, len(rpcReqs))
+	for i, rpcReq := range rpcReqs {
+		beReq := *rpcReq
+		beReq.JSONRpc = "2.0"
+		reqIdxByID[rc.allocateRequestID(&beReq)] = i
+		beReqs[i] = &beReq
+	}
+	rpcTraceID := fmt.Sprintf("%s..%s", beReqs[0].ID.AsString(), beReqs[len(beReqs)-1].ID.AsString())
+	header, err := rc.beforeRequest(ctx, beReqs...)
+	if err != nil {
+		return failAll(err)
+	}
+
+	log.L(ctx).Debugf("RPC[%s] --> batch (%d requests)", rpcTraceID, len(beReqs))
+	if debuglog.Active(debuglog.RPCWire) {
+		jsonInput, _ := json.Marshal(beReqs)
+		debuglog.Logf(ctx, debuglog.RPCWire, "RPC[%s] INPUT: %s", rpcTraceID, jsonInput)
+	}
+	rpcStartTime := time.Now()
+	methods := make([]string, len(rpcReqs))
+	for i, rpcReq := range rpcReqs {
+		methods[i] = rpcReq.Method
+	}
+	res, err := rc.post(ctx, rpcTraceID, methods, func() (*resty.Response, error) {
+		return rc.newRequest(ctx).
+			SetHeaderMultiValues(header).
+			SetBody(beReqs).
+			Post("")
+	})
+	if err != nil {
+		err := i18n.NewError(ctx, signermsgs.MsgRPCRequestFailed, err)
+		log.L(ctx).Errorf("RPC[%s] <-- ERROR: %s", rpcTraceID, err)
+		rc.onError(ctx, beReqs, err)
+		return failAll(err)
+	}
+	debuglog.Logf(ctx, debuglog.RPCWire, "RPC[%s] OUTPUT: %s", rpcTraceID, res.Body())
+
+	var beResponses []*RPCResponse
+	if err := json.Unmarshal(res.Body(), &beResponses); err != nil || res.IsError() {
+		// The backend might reject the whole batch with a single JSON/RPC error
+		var singleRes RPCResponse
+		_ = json.Unmarshal(res.Body(), &singleRes)
+		rpcMsg := singleRes.Message()
+		if rpcMsg == "" {
+			rpcMsg = i18n.NewError(ctx, signermsgs.MsgRPCRequestFailed, res.Status()).Error()
+		}
+		log.L(ctx).Errorf("RPC[%s] <-- [%d]: %s", rpcTraceID, res.StatusCode(), res.Body())
+		rpcResponses, err = failAll(errors.New(rpcMsg))
+		rc.afterResponse(ctx, beReqs, rpcResponses, rpcStartTime)
+		return rpcResponses, err
+	}
+
+	for _, beRes := range beResponses {
+		if beRes == nil || beRes.ID == nil {
+			continue
+		}
+		i, ok := reqIdxByID[beRes.ID.AsString()]
+		if !ok {
+			log.L(ctx).Warnf("RPC[%s] <-- Unexpected response ID %s in batch", rpcTraceID, beRes.ID)
+			continue
+		}
+		// Restore the original ID
+		beRes.ID = rpcReqs[i].ID
+		if beRes.Result == nil && (beRes.Error == nil || beRes.Error.Code == 0) {
+			// We don't want a result for errors, but a null success response needs to go in there
+			beRes.Result = fftypes.JSONAnyPtr(fftypes.NullString)
+		}
+		rpcResponses[i] = beRes
+	}
+	for i, rpcReq := range rpcReqs {
+		if rpcResponses[i] == nil {
+			err := i18n.NewError(ctx, signermsgs.MsgBatchResponseMissing, beReqs[i].ID.AsString())
+			rpcResponses[i] = RPCErrorResponse(err, rpcReq.ID, RPCCodeInternalError)
+		}
+	}
+	log.L(ctx).Infof("RPC[%s] <-- batch (%d requests) [%d] OK (%.2fms)", rpcTraceID, len(beReqs), res.StatusCode(), float64(time.Since(rpcStartTime))/float64(time.Millisecond))
+	rc.afterResponse(ctx, beReqs, rpcResponses, rpcStartTime)
+	return rpcResponses, nil
+}
+
+func (rc *RPCClient) startBatchDispatcher(options *RPCClientBatchOptions) {
+	rc.batchOptions = *options
+	if rc.batchOptions.BatchDispatcherContext == nil {
+		rc.batchOptions.BatchDispatcherContext = context.Background()
+	}
+	if rc.batchOptions.BatchSize <= 0 {
+		rc.batchOptions.BatchSize = DefaultBatchSize
+	}
+	if rc.batchOptions.BatchTimeout <= 0 {
+		rc.batchOptions.BatchTimeout = DefaultBatchTimeout
+	}
+	if rc.batchOptions.BatchMaxDispatchConcurrency <= 0 {
+		rc.batchOptions.BatchMaxDispatchConcurrency = DefaultBatchMaxDispatchConcurrency
+	}
+	rc.batchQueue = make(chan *batchedRequest)
+	rc.batchDispatchSlots = make(chan bool, rc.batchOptions.BatchMaxDispatchConcurrency)
+	go rc.batchDispatcher(log.WithLogField(rc.batchOptions.BatchDispatcherContext, "role", "rpc_batch_dispatcher"))
+}
+
+func (rc *RPCClient) batchSyncRequest(ctx context.Context, rpcReq *RPCRequest) (*RPCResponse, error) {
+	req := &batchedRequest{
+		rpcReq: rpcReq,
+		result: make(chan *batchedResult, 1),
+	}
+	select {
+	case rc.batchQueue <- req:
+	case <-ctx.Done():
+		err := i18n.NewError(ctx, signermsgs.MsgRequestCanceledContext, rpcReq.ID)
+		return RPCErrorResponse(err, rpcReq.ID, RPCCodeInternalError), err
+	case <-rc.batchOptions.BatchDispatcherContext.Done():
+		err := i18n.NewError(ctx, signermsgs.MsgBatchDispatcherStopped, rpcReq.ID)
+		return RPCErrorResponse(err, rpcReq.ID, RPCCodeInternalError), err
+	}
+	select {
+	case r := <-req.result:
+		return r.rpcRes, r.err
+	case <-ctx.Done():
+		err := i18n.NewError(ctx, signermsgs.MsgRequestCanceledContext, rpcReq.ID)
+		return RPCErrorResponse(err, rpcReq.ID, RPCCodeInternalError), err
+	}
+}
+
+func (rc *RPCClient) batchDispatcher(ctx context.Context) {
+	var batch []*batchedRequest
+	var batchTimeout <-chan time.Time
+	for {
+		select {
+		case req := <-rc.batchQueue:
+			batch = append(batch, req)
+			if len(batch) == 1 {
+				batchTimeout = time.After(rc.batchOptions.BatchTimeout)
+			}
+			if len(batch) < rc.batchOptions.BatchSize {
+				continue
+			}
+		case <-batchTimeout:
+		case <-ctx.Done():
+			log.L(ctx).Debugf("Batch dispatcher stopped with %d queued requests", len(batch))
+			for _, req := range batch {
+				err := i18n.NewError(ctx, signermsgs.MsgBatchDispatcherStopped, req.rpcReq.ID)
+				req.result <- &batchedResult{rpcRes: RPCErrorResponse(err, req.rpcReq.ID, RPCCodeInternalError), err: err}
+			}
+			return
+		}
+		rc.dispatchBatch(ctx, batch)
+		batch = nil
+		batchTimeout = nil
+	}
+}
+
+func (rc *RPCClient) dispatchBatch(ctx context.Context, batch []*batchedRequest) {
+	// Blocks the dispatcher when we are at the limit, which pushes back on callers
+	rc.batchDispatchSlots <- true
+	go func() {
+		defer func() {
+			<-rc.batchDispatchSlots
+		}()
+		rpcReqs := make([]*RPCRequest, len(batch))
+		for i, req := range batch {
+			rpcReqs[i] = req.rpcReq
+		}
+		// The batch is shared between callers, so is sent using the dispatcher context.
+		// Each caller only waits for the result as long as its own context allows.
+		rpcResponses, err := rc.BatchRequest(ctx, rpcReqs)
+		for i, req := range batch {
+			itemErr := err
+			if itemErr == nil && rpcResponses[i].Error != nil && rpcResponses[i].Error.Code != 0 {
+				itemErr = errors.New(rpcResponses[i].Message())
+			}
+			req.result <- &batchedResult{rpcRes: rpcResponses[i], err: itemErr}
+		}
+	}()
+}
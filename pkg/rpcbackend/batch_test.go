@@ -0,0 +1,346 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcbackend
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/firefly-common/pkg/ffresty"
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-signer/internal/signerconfig"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type testBatchRPCHandler func(rpcReqs []*RPCRequest) (int, interface{})
+
+func newTestBatchServer(t *testing.T, rpcHandler testBatchRPCHandler, options ...RPCClientOptions) (context.Context, *RPCClient, func()) {
+
+	ctx, cancelCtx := context.WithCancel(context.Background())
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		var rpcReqs []*RPCRequest
+		err := json.NewDecoder(r.Body).Decode(&rpcReqs)
+		assert.NoError(t, err)
+
+		status, body := rpcHandler(rpcReqs)
+		var b []byte
+		switch bt := body.(type) {
+		case string:
+			b = []byte(bt)
+		default:
+			b, err = json.Marshal(body)
+			assert.NoError(t, err)
+		}
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_, _ = w.Write(b)
+
+	}))
+
+	signerconfig.Reset()
+	signerconfig.BackendConfig.Set(ffresty.HTTPConfigURL, server.URL)
+	c, err := ffresty.New(ctx, signerconfig.BackendConfig)
+	assert.NoError(t, err)
+
+	var opts RPCClientOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	rb := NewRPCClientWithOption(c, opts).(*RPCClient)
+
+	return ctx, rb, func() {
+		cancelCtx()
+		server.Close()
+	}
+}
+
+func TestBatchRequestOK(t *testing.T) {
+	logrus.SetLevel(logrus.TraceLevel)
+	defer logrus.SetLevel(logrus.InfoLevel)
+
+	ctx, rb, done := newTestBatchServer(t, func(rpcReqs []*RPCRequest) (int, interface{}) {
+		assert.Len(t, rpcReqs, 3)
+		for _, r := range rpcReqs {
+			assert.Equal(t, "2.0", r.JSONRpc)
+		}
+		// Reply out of order, with a per-item error and a null result
+		return 200, []*RPCResponse{
+			{JSONRpc: "2.0", ID: rpcReqs[2].ID, Error: &RPCError{Code: -32000, Message: "pop"}},
+			{JSONRpc: "2.0", ID: rpcReqs[1].ID},
+			{JSONRpc: "2.0", ID: rpcReqs[0].ID, Result: fftypes.JSONAnyPtr(`"0x12345"`)},
+		}
+	})
+	defer done()
+
+	rpcResponses, err := rb.BatchRequest(ctx, []*RPCRequest{
+		{ID: fftypes.JSONAnyPtr("1"), Method: "eth_blockNumber"},
+		{ID: fftypes.JSONAnyPtr(`"two"`), Method: "eth_getTransactionReceipt", Params: []*fftypes.JSONAny{fftypes.JSONAnyPtr(`"0xaaaa"`)}},
+		{ID: fftypes.JSONAnyPtr("3"), Method: "eth_call"},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, rpcResponses, 3)
+
+	assert.Equal(t, "1", rpcResponses[0].ID.String())
+	assert.Equal(t, `"0x12345"`, rpcResponses[0].Result.String())
+
+	assert.Equal(t, `"two"`, rpcResponses[1].ID.String())
+	assert.Equal(t, `null`, rpcResponses[1].Result.String())
+
+	assert.Equal(t, "3", rpcResponses[2].ID.String())
+	assert.Nil(t, rpcResponses[2].Result)
+	assert.Equal(t, "pop", rpcResponses[2].Message())
+}
+
+func TestBatchRequestEmpty(t *testing.T) {
+	ctx, rb, done := newTestBatchServer(t, nil)
+	defer done()
+
+	rpcResponses, err := rb.BatchRequest(ctx, []*RPCRequest{})
+	assert.NoError(t, err)
+	assert.Empty(t, rpcResponses)
+}
+
+func TestBatchRequestMissingAndUnexpected(t *testing.T) {
+	ctx, rb, done := newTestBatchServer(t, func(rpcReqs []*RPCRequest) (int, interface{}) {
+		return 200, []*RPCResponse{
+			nil,
+			{JSONRpc: "2.0"},
+			{JSONRpc: "2.0", ID: fftypes.JSONAnyPtr(`"unknown"`), Result: fftypes.JSONAnyPtr(`true`)},
+			{JSONRpc: "2.0", ID: rpcReqs[0].ID, Result: fftypes.JSONAnyPtr(`true`)},
+		}
+	})
+	defer done()
+
+	rpcResponses, err := rb.BatchRequest(ctx, []*RPCRequest{
+		{ID: fftypes.JSONAnyPtr("1"), Method: "eth_blockNumber"},
+		{ID: fftypes.JSONAnyPtr("2"), Method: "eth_blockNumber"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, `true`, rpcResponses[0].Result.String())
+	assert.Equal(t, "2", rpcResponses[1].ID.String())
+	assert.Regexp(t, "FF22098", rpcResponses[1].Message())
+}
+
+func TestBatchRequestSingleErrorResponse(t *testing.T) {
+	ctx, rb, done := newTestBatchServer(t, func(rpcReqs []*RPCRequest) (int, interface{}) {
+		return 400, &RPCResponse{JSONRpc: "2.0", Error: &RPCError{Code: -32600, Message: "batch not supported"}}
+	})
+	defer done()
+
+	rpcResponses, err := rb.BatchRequest(ctx, []*RPCRequest{
+		{ID: fftypes.JSONAnyPtr("1"), Method: "eth_blockNumber"},
+		{ID: fftypes.JSONAnyPtr("2"), Method: "eth_blockNumber"},
+	})
+	assert.Regexp(t, "batch not supported", err)
+	assert.Len(t, rpcResponses, 2)
+	for i, rpcRes := range rpcResponses {
+		assert.Equal(t, "batch not supported", rpcRes.Message())
+		assert.Equal(t, fmt.Sprintf("%d", i+1), rpcRes.ID.String())
+	}
+}
+
+func TestBatchRequestBadResponse(t *testing.T) {
+	ctx, rb, done := newTestBatchServer(t, func(rpcReqs []*RPCRequest) (int, interface{}) {
+		return 500, "not JSON"
+	})
+	defer done()
+
+	_, err := rb.BatchRequest(ctx, []*RPCRequest{
+		{ID: fftypes.JSONAnyPtr("1"), Method: "eth_blockNumber"},
+	})
+	assert.Regexp(t, "FF22012.*500", err)
+}
+
+func TestBatchRequestServerDown(t *testing.T) {
+	ctx, rb, done := newTestBatchServer(t, nil)
+	done()
+
+	rpcResponses, err := rb.BatchRequest(ctx, []*RPCRequest{
+		{ID: fftypes.JSONAnyPtr("1"), Method: "eth_blockNumber"},
+	})
+	assert.Regexp(t, "FF22012", err)
+	assert.Regexp(t, "FF22012", rpcResponses[0].Message())
+}
+
+func TestBatchRequestConcurrencySlotCancelled(t *testing.T) {
+	ctx, rb, done := newTestBatchServer(t, nil, RPCClientOptions{MaxConcurrentRequest: 1})
+	defer done()
+
+	rb.concurrencySlots <- true
+	cancelledCtx, cancelCtx := context.WithCancel(ctx)
+	cancelCtx()
+	_, err := rb.BatchRequest(cancelledCtx, []*RPCRequest{
+		{ID: fftypes.JSONAnyPtr("1"), Method: "eth_blockNumber"},
+	})
+	assert.Regexp(t, "FF22063", err)
+}
+
+func TestMicroBatchingBatchFull(t *testing.T) {
+	batches := make(chan int, 1)
+	ctx, rb, done := newTestBatchServer(t, func(rpcReqs []*RPCRequest) (int, interface{}) {
+		batches <- len(rpcReqs)
+		rpcResponses := make([]*RPCResponse, len(rpcReqs))
+		for i, r := range rpcReqs {
+			if r.Method == "bad_method" {
+				rpcResponses[i] = &RPCResponse{JSONRpc: "2.0", ID: r.ID, Error: &RPCError{Code: -32601, Message: "method not found"}}
+			} else {
+				rpcResponses[i] = &RPCResponse{JSONRpc: "2.0", ID: r.ID, Result: fftypes.JSONAnyPtr(`"0x1"`)}
+			}
+		}
+		return 200, rpcResponses
+	}, RPCClientOptions{
+		BatchOptions: &RPCClientBatchOptions{
+			BatchSize:    2,
+			BatchTimeout: 1 * time.Hour, // must dispatch on size
+		},
+	})
+	defer done()
+
+	errs := make(chan error)
+	go func() {
+		_, err := rb.SyncRequest(ctx, &RPCRequest{ID: fftypes.JSONAnyPtr("1"), Method: "bad_method"})
+		errs <- err
+	}()
+	var blockNumber string
+	rpcErr := rb.CallRPC(ctx, &blockNumber, "eth_blockNumber")
+	assert.Nil(t, rpcErr)
+	assert.Equal(t, "0x1", blockNumber)
+	assert.Regexp(t, "method not found", <-errs)
+	assert.Equal(t, 2, <-batches)
+	assert.Equal(t, DefaultBatchMaxDispatchConcurrency, rb.batchOptions.BatchMaxDispatchConcurrency)
+}
+
+func TestMicroBatchingTimeout(t *testing.T) {
+	ctx, rb, done := newTestBatchServer(t, func(rpcReqs []*RPCRequest) (int, interface{}) {
+		assert.Len(t, rpcReqs, 1)
+		return 200, []*RPCResponse{{JSONRpc: "2.0", ID: rpcReqs[0].ID}}
+	}, RPCClientOptions{
+		BatchOptions: &RPCClientBatchOptions{
+			BatchTimeout: 1 * time.Millisecond,
+		},
+	})
+	defer done()
+	assert.Equal(t, DefaultBatchSize, rb.batchOptions.BatchSize)
+
+	rpcRes, err := rb.SyncRequest(ctx, &RPCRequest{ID: fftypes.JSONAnyPtr("1"), Method: "eth_blockNumber"})
+	assert.NoError(t, err)
+	assert.Equal(t, "1", rpcRes.ID.String())
+	assert.Equal(t, "null", rpcRes.Result.String())
+}
+
+func TestMicroBatchingDispatchFail(t *testing.T) {
+	_, rb, done := newTestBatchServer(t, nil, RPCClientOptions{
+		BatchOptions: &RPCClientBatchOptions{
+			BatchSize: 1,
+		},
+	})
+	done()
+	assert.Equal(t, DefaultBatchTimeout, rb.batchOptions.BatchTimeout)
+
+	rpcRes, err := rb.SyncRequest(context.Background(), &RPCRequest{ID: fftypes.JSONAnyPtr("1"), Method: "eth_blockNumber"})
+	assert.Regexp(t, "FF22012", err)
+	assert.Regexp(t, "FF22012", rpcRes.Message())
+}
+
+func TestMicroBatchingDispatcherStopped(t *testing.T) {
+	dispatcherCtx, stopDispatcher := context.WithCancel(context.Background())
+	ctx, rb, done := newTestBatchServer(t, nil, RPCClientOptions{
+		BatchOptions: &RPCClientBatchOptions{
+			BatchDispatcherContext: dispatcherCtx,
+			BatchTimeout:           1 * time.Hour,
+		},
+	})
+	defer done()
+
+	// Queue requests that will never be dispatched before we stop
+	queued := make(chan error)
+	go func() {
+		_, err := rb.SyncRequest(ctx, &RPCRequest{ID: fftypes.JSONAnyPtr("1"), Method: "eth_blockNumber"})
+		queued <- err
+	}()
+	req := &batchedRequest{rpcReq: &RPCRequest{ID: fftypes.JSONAnyPtr("2")}, result: make(chan *batchedResult, 1)}
+	rb.batchQueue <- req
+	stopDispatcher()
+	r := <-req.result
+	assert.Regexp(t, "FF22099", r.err)
+	assert.Regexp(t, "FF22099", <-queued)
+
+	// Now it's stopped, new requests fail immediately
+	_, err := rb.SyncRequest(ctx, &RPCRequest{ID: fftypes.JSONAnyPtr("3"), Method: "eth_blockNumber"})
+	assert.Regexp(t, "FF22099", err)
+}
+
+func TestMicroBatchingCallerCancelled(t *testing.T) {
+	requestReceived := make(chan struct{})
+	unblock := make(chan struct{})
+	ctx, rb, done := newTestBatchServer(t, func(rpcReqs []*RPCRequest) (int, interface{}) {
+		close(requestReceived)
+		<-unblock
+		return 200, []*RPCResponse{}
+	}, RPCClientOptions{
+		BatchOptions: &RPCClientBatchOptions{
+			BatchSize: 1,
+		},
+	})
+	defer done()
+	defer close(unblock)
+
+	// Cancelled while waiting for the response
+	reqCtx, cancelReq := context.WithCancel(ctx)
+	go func() {
+		<-requestReceived
+		cancelReq()
+	}()
+	_, err := rb.SyncRequest(reqCtx, &RPCRequest{ID: fftypes.JSONAnyPtr("1"), Method: "eth_blockNumber"})
+	assert.Regexp(t, "FF22063", err)
+
+	// Cancelled before it can be queued (with no dispatcher running to take it)
+	idle := &RPCClient{
+		batchQueue:   make(chan *batchedRequest),
+		batchOptions: RPCClientBatchOptions{BatchDispatcherContext: context.Background()},
+	}
+	_, err = idle.SyncRequest(reqCtx, &RPCRequest{ID: fftypes.JSONAnyPtr("2"), Method: "eth_blockNumber"})
+	assert.Regexp(t, "FF22063", err)
+}
+
+func TestBatchRequestBodyIsArray(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		assert.JSONEq(t, `[{"jsonrpc":"2.0","id":"000000001","method":"eth_blockNumber"}]`, string(b))
+		w.Header().Add("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[{"jsonrpc":"2.0","id":"000000001","result":"0x1"}]`))
+	}))
+	defer server.Close()
+
+	signerconfig.Reset()
+	signerconfig.BackendConfig.Set(ffresty.HTTPConfigURL, server.URL)
+	c, err := ffresty.New(context.Background(), signerconfig.BackendConfig)
+	assert.NoError(t, err)
+	rb := NewRPCClient(c)
+
+	rpcResponses, err := rb.BatchRequest(context.Background(), []*RPCRequest{{ID: fftypes.JSONAnyPtr("1"), Method: "eth_blockNumber"}})
+	assert.NoError(t, err)
+	assert.Equal(t, `"0x1"`, rpcResponses[0].Result.String())
+}
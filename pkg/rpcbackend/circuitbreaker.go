@@ -0,0 +1,330 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcbackend
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-common/pkg/log"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+)
+
+const (
+	DefaultCircuitBreakerFailureRateThreshold = 0.5
+	DefaultCircuitBreakerMinimumRequests      = 20
+	DefaultCircuitBreakerWindow               = 30 * time.Second
+	DefaultCircuitBreakerOpenDuration         = 30 * time.Second
+	DefaultCircuitBreakerHalfOpenProbes       = 3
+)
+
+// CircuitBreakerOptions configures when a circuit breaker opens to fail requests fast, and how it recovers
+type CircuitBreakerOptions struct {
+	// FailureRateThreshold is the fraction of requests in a window that must fail for the circuit to open
+	FailureRateThreshold float64
+	// MinimumRequests is the number of requests there must be in a window before the circuit can open
+	MinimumRequests int
+	// Window is the period over which failures are counted, with the counts reset at the end of each window
+	Window time.Duration
+	// SlowCallThreshold counts a request as a failure if it takes at least this long (zero to disable)
+	SlowCallThreshold time.Duration
+	// OpenDuration is how long the circuit stays open, before probe requests are let through
+	OpenDuration time.Duration
+	// HalfOpenProbes is the number of probe requests that must all succeed for the circuit to close again
+	HalfOpenProbes int
+}
+
+// CircuitBreakerStatus is the state of a circuit breaker at a point in time
+type CircuitBreakerStatus struct {
+	// State is "closed", "open" or "half-open"
+	State string `json:"state"`
+	// Requests and Failures are counted over the current window, while the circuit is closed
+	Requests int `json:"requests"`
+	Failures int `json:"failures"`
+	// OpenedAt is when the circuit last opened, if it ever has
+	OpenedAt *fftypes.FFTime `json:"openedAt,omitempty"`
+}
+
+// CircuitBreaker is implemented by the backends returned by NewCircuitBreakerBackend and NewCircuitBreakerWSRPCClient,
+// so the state of the circuit can be inspected
+type CircuitBreaker interface {
+	CircuitBreakerStatus() *CircuitBreakerStatus
+}
+
+type circuitState int
+
+const (
+	circuitClosed circuitState = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+func (cs circuitState) String() string {
+	switch cs {
+	case circuitOpen:
+		return "open"
+	case circuitHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+type circuitBreaker struct {
+	ctx     context.Context
+	options CircuitBreakerOptions
+
+	mux         sync.Mutex
+	state       circuitState
+	generation  int // incremented on every change of state, so outcomes from a previous state are ignored
+	windowStart time.Time
+	requests    int
+	failures    int
+	openedAt    time.Time
+	probes      int
+	successes   int
+}
+
+type circuitBreakerBackend struct {
+	Backend
+	cb *circuitBreaker
+}
+
+type circuitBreakerWSRPCClient struct {
+	WebSocketRPCClient
+	cb *circuitBreaker
+}
+
+// NewCircuitBreakerBackend wraps a backend with a circuit breaker. When too many requests fail because the
+// backend is unavailable (or are too slow), the circuit opens and requests fail immediately with the code
+// RPCCodeCircuitOpen, rather than waiting on a backend that is down. After a while a few probe requests are
+// let through, and the circuit closes again once they succeed.
+func NewCircuitBreakerBackend(ctx context.Context, options CircuitBreakerOptions, backend Backend) Backend {
+	return &circuitBreakerBackend{
+		Backend: backend,
+		cb:      newCircuitBreaker(ctx, options),
+	}
+}
+
+// NewCircuitBreakerWSRPCClient is the WebSocket equivalent of NewCircuitBreakerBackend. Only requests are
+// protected by the circuit breaker, and subscriptions are passed straight through.
+func NewCircuitBreakerWSRPCClient(ctx context.Context, options CircuitBreakerOptions, backend WebSocketRPCClient) WebSocketRPCClient {
+	return &circuitBreakerWSRPCClient{
+		WebSocketRPCClient: backend,
+		cb:                 newCircuitBreaker(ctx, options),
+	}
+}
+
+func newCircuitBreaker(ctx context.Context, options CircuitBreakerOptions) *circuitBreaker {
+	if options.FailureRateThreshold <= 0 {
+		options.FailureRateThreshold = DefaultCircuitBreakerFailureRateThreshold
+	}
+	if options.MinimumRequests <= 0 {
+		options.MinimumRequests = DefaultCircuitBreakerMinimumRequests
+	}
+	if options.Window <= 0 {
+		options.Window = DefaultCircuitBreakerWindow
+	}
+	if options.OpenDuration <= 0 {
+		options.OpenDuration = DefaultCircuitBreakerOpenDuration
+	}
+	if options.HalfOpenProbes <= 0 {
+		options.HalfOpenProbes = DefaultCircuitBreakerHalfOpenProbes
+	}
+	return &circuitBreaker{
+		ctx:         log.WithLogField(ctx, "role", "rpc_circuit_breaker"),
+		options:     options,
+		windowStart: time.Now(),
+	}
+}
+
+func (cb *circuitBreaker) status() *CircuitBreakerStatus {
+	cb.mux.Lock()
+	defer cb.mux.Unlock()
+	status := &CircuitBreakerStatus{
+		State:    cb.state.String(),
+		Requests: cb.requests,
+		Failures: cb.failures,
+	}
+	if !cb.openedAt.IsZero() {
+		openedAt := fftypes.FFTime(cb.openedAt)
+		status.OpenedAt = &openedAt
+	}
+	return status
+}
+
+func (cbb *circuitBreakerBackend) CircuitBreakerStatus() *CircuitBreakerStatus {
+	return cbb.cb.status()
+}
+
+func (cbw *circuitBreakerWSRPCClient) CircuitBreakerStatus() *CircuitBreakerStatus {
+	return cbw.cb.status()
+}
+
+// allow returns false if the request must be rejected, and otherwise the generation to record its outcome against
+func (cb *circuitBreaker) allow() (int, bool) {
+	cb.mux.Lock()
+	defer cb.mux.Unlock()
+
+	now := time.Now()
+	switch cb.state {
+	case circuitOpen:
+		if now.Sub(cb.openedAt) < cb.options.OpenDuration {
+			return 0, false
+		}
+		cb.setState(circuitHalfOpen)
+	case circuitClosed:
+		if now.Sub(cb.windowStart) >= cb.options.Window {
+			cb.resetWindow(now)
+		}
+	}
+	if cb.state == circuitHalfOpen {
+		if cb.probes >= cb.options.HalfOpenProbes {
+			return 0, false
+		}
+		cb.probes++
+	}
+	return cb.generation, true
+}
+
+func (cb *circuitBreaker) record(generation int, failed bool) {
+	cb.mux.Lock()
+	defer cb.mux.Unlock()
+
+	if generation != cb.generation {
+		return
+	}
+	switch cb.state {
+	case circuitClosed:
+		cb.requests++
+		if failed {
+			cb.failures++
+		}
+		if cb.requests >= cb.options.MinimumRequests && float64(cb.failures)/float64(cb.requests) >= cb.options.FailureRateThreshold {
+			log.L(cb.ctx).Warnf("Circuit breaker opened, after %d of %d requests failed", cb.failures, cb.requests)
+			cb.setState(circuitOpen)
+		}
+	case circuitHalfOpen:
+		if failed {
+			log.L(cb.ctx).Warnf("Circuit breaker re-opened, after a probe request failed")
+			cb.setState(circuitOpen)
+			return
+		}
+		cb.successes++
+		if cb.successes >= cb.options.HalfOpenProbes {
+			log.L(cb.ctx).Infof("Circuit breaker closed, after %d probe requests succeeded", cb.successes)
+			cb.setState(circuitClosed)
+		}
+	}
+}
+
+// release frees the probe slot of a request that was canceled by the caller, without recording an outcome
+func (cb *circuitBreaker) release(generation int) {
+	cb.mux.Lock()
+	defer cb.mux.Unlock()
+	if generation == cb.generation && cb.state == circuitHalfOpen {
+		cb.probes--
+	}
+}
+
+func (cb *circuitBreaker) setState(state circuitState) {
+	log.L(cb.ctx).Debugf("Circuit breaker %s -> %s", cb.state, state)
+	now := time.Now()
+	cb.state = state
+	cb.generation++
+	cb.probes = 0
+	cb.successes = 0
+	cb.resetWindow(now)
+	if state == circuitOpen {
+		cb.openedAt = now
+	}
+}
+
+func (cb *circuitBreaker) resetWindow(now time.Time) {
+	cb.windowStart = now
+	cb.requests = 0
+	cb.failures = 0
+}
+
+// call records the outcome of a request. Only a failure to get a response from the backend counts, not a
+// JSON/RPC error returned by the backend, and not the caller canceling the request - unless it was too slow.
+func (cb *circuitBreaker) call(ctx context.Context, fn func() error) (rejected error) {
+	generation, ok := cb.allow()
+	if !ok {
+		return i18n.NewError(ctx, signermsgs.MsgCircuitBreakerOpen)
+	}
+	startTime := time.Now()
+	err := fn()
+	slow := cb.options.SlowCallThreshold > 0 && time.Since(startTime) >= cb.options.SlowCallThreshold
+	if !slow && ctx.Err() != nil {
+		cb.release(generation)
+		return nil
+	}
+	cb.record(generation, slow || (err != nil && IsBackendUnavailable(err)))
+	return nil
+}
+
+func circuitBreakerSyncRequest(ctx context.Context, cb *circuitBreaker, backend Backend, rpcReq *RPCRequest) (rpcRes *RPCResponse, err error) {
+	if rejected := cb.call(ctx, func() error {
+		rpcRes, err = backend.SyncRequest(ctx, rpcReq)
+		return err
+	}); rejected != nil {
+		return RPCErrorResponse(rejected, rpcReq.ID, RPCCodeCircuitOpen), rejected
+	}
+	return rpcRes, err
+}
+
+func circuitBreakerBatchRequest(ctx context.Context, cb *circuitBreaker, backend Backend, rpcReqs []*RPCRequest) (rpcResponses []*RPCResponse, err error) {
+	if rejected := cb.call(ctx, func() error {
+		rpcResponses, err = backend.BatchRequest(ctx, rpcReqs)
+		return err
+	}); rejected != nil {
+		rpcResponses = make([]*RPCResponse, len(rpcReqs))
+		for i, rpcReq := range rpcReqs {
+			rpcResponses[i] = RPCErrorResponse(rejected, rpcReq.ID, RPCCodeCircuitOpen)
+		}
+		return rpcResponses, rejected
+	}
+	return rpcResponses, err
+}
+
+func (cbb *circuitBreakerBackend) CallRPC(ctx context.Context, result interface{}, method string, params ...interface{}) *RPCError {
+	return callRPCWithSyncRequest(ctx, cbb.SyncRequest, result, method, params)
+}
+
+func (cbb *circuitBreakerBackend) SyncRequest(ctx context.Context, rpcReq *RPCRequest) (*RPCResponse, error) {
+	return circuitBreakerSyncRequest(ctx, cbb.cb, cbb.Backend, rpcReq)
+}
+
+func (cbb *circuitBreakerBackend) BatchRequest(ctx context.Context, rpcReqs []*RPCRequest) ([]*RPCResponse, error) {
+	return circuitBreakerBatchRequest(ctx, cbb.cb, cbb.Backend, rpcReqs)
+}
+
+func (cbw *circuitBreakerWSRPCClient) CallRPC(ctx context.Context, result interface{}, method string, params ...interface{}) *RPCError {
+	return callRPCWithSyncRequest(ctx, cbw.SyncRequest, result, method, params)
+}
+
+func (cbw *circuitBreakerWSRPCClient) SyncRequest(ctx context.Context, rpcReq *RPCRequest) (*RPCResponse, error) {
+	return circuitBreakerSyncRequest(ctx, cbw.cb, cbw.WebSocketRPCClient, rpcReq)
+}
+
+func (cbw *circuitBreakerWSRPCClient) BatchRequest(ctx context.Context, rpcReqs []*RPCRequest) ([]*RPCResponse, error) {
+	return circuitBreakerBatchRequest(ctx, cbw.cb, cbw.WebSocketRPCClient, rpcReqs)
+}
@@ -0,0 +1,239 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcbackend
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/stretchr/testify/assert"
+)
+
+type testSlowTarget struct {
+	testFailoverTarget
+	delay time.Duration
+}
+
+func (st *testSlowTarget) SyncRequest(ctx context.Context, rpcReq *RPCRequest) (*RPCResponse, error) {
+	time.Sleep(st.delay)
+	return st.testFailoverTarget.SyncRequest(ctx, rpcReq)
+}
+
+func newTestCircuitBreaker(options CircuitBreakerOptions) (*circuitBreakerBackend, *testFailoverTarget) {
+	target := &testFailoverTarget{}
+	return NewCircuitBreakerBackend(context.Background(), options, target).(*circuitBreakerBackend), target
+}
+
+func testSyncRequest(t *testing.T, b Backend) (*RPCResponse, error) {
+	return b.SyncRequest(context.Background(), &RPCRequest{ID: fftypes.JSONAnyPtr("1"), Method: "eth_blockNumber"})
+}
+
+func TestCircuitBreakerDefaults(t *testing.T) {
+	cbb, _ := newTestCircuitBreaker(CircuitBreakerOptions{})
+	assert.Equal(t, DefaultCircuitBreakerFailureRateThreshold, cbb.cb.options.FailureRateThreshold)
+	assert.Equal(t, DefaultCircuitBreakerMinimumRequests, cbb.cb.options.MinimumRequests)
+	assert.Equal(t, DefaultCircuitBreakerWindow, cbb.cb.options.Window)
+	assert.Equal(t, DefaultCircuitBreakerOpenDuration, cbb.cb.options.OpenDuration)
+	assert.Equal(t, DefaultCircuitBreakerHalfOpenProbes, cbb.cb.options.HalfOpenProbes)
+	assert.Equal(t, "closed", circuitClosed.String())
+	assert.Equal(t, "open", circuitOpen.String())
+	assert.Equal(t, "half-open", circuitHalfOpen.String())
+}
+
+func TestCircuitBreakerOpensOnFailureRate(t *testing.T) {
+	cbb, target := newTestCircuitBreaker(CircuitBreakerOptions{
+		FailureRateThreshold: 0.5,
+		MinimumRequests:      4,
+	})
+
+	// Successes and JSON/RPC errors returned by the backend do not count as failures
+	for i := 0; i < 2; i++ {
+		_, err := testSyncRequest(t, cbb)
+		assert.NoError(t, err)
+	}
+	target.syncErr = fmt.Errorf("execution reverted")
+	for i := 0; i < 4; i++ {
+		_, err := testSyncRequest(t, cbb)
+		assert.Regexp(t, "reverted", err)
+	}
+	assert.Equal(t, circuitClosed, cbb.cb.state)
+
+	target.syncErr = testUnavailableErr()
+	for i := 0; i < 6; i++ {
+		_, err := testSyncRequest(t, cbb)
+		assert.Regexp(t, "FF22012", err)
+	}
+	assert.Equal(t, circuitOpen, cbb.cb.state)
+	assert.Equal(t, int32(12), atomic.LoadInt32(&target.calls))
+
+	status := cbb.CircuitBreakerStatus()
+	assert.Equal(t, "open", status.State)
+	assert.NotNil(t, status.OpenedAt)
+
+	// Requests now fail fast, without going to the backend
+	rpcRes, err := testSyncRequest(t, cbb)
+	assert.Regexp(t, "FF22150", err)
+	assert.True(t, IsBackendUnavailable(err))
+	assert.Equal(t, int64(RPCCodeCircuitOpen), rpcRes.Error.Code)
+	assert.Equal(t, "1", rpcRes.ID.String())
+	rpcErr := cbb.CallRPC(context.Background(), nil, "eth_blockNumber")
+	assert.Regexp(t, "FF22150", rpcErr.Message)
+	rpcResponses, err := cbb.BatchRequest(context.Background(), []*RPCRequest{{ID: fftypes.JSONAnyPtr("2")}, {ID: fftypes.JSONAnyPtr("3")}})
+	assert.Regexp(t, "FF22150", err)
+	assert.Equal(t, "3", rpcResponses[1].ID.String())
+	assert.Equal(t, int64(RPCCodeCircuitOpen), rpcResponses[1].Error.Code)
+	assert.Equal(t, int32(12), atomic.LoadInt32(&target.calls))
+}
+
+func TestCircuitBreakerWindowReset(t *testing.T) {
+	cbb, target := newTestCircuitBreaker(CircuitBreakerOptions{
+		FailureRateThreshold: 0.5,
+		MinimumRequests:      2,
+		Window:               1 * time.Hour,
+	})
+
+	target.syncErr = testUnavailableErr()
+	_, _ = testSyncRequest(t, cbb)
+	assert.Equal(t, 1, cbb.cb.failures)
+
+	// The failure is forgotten at the end of the window
+	cbb.cb.windowStart = time.Now().Add(-1 * time.Hour)
+	target.syncErr = nil
+	_, err := testSyncRequest(t, cbb)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, cbb.cb.failures)
+	assert.Equal(t, 1, cbb.cb.requests)
+	assert.Equal(t, circuitClosed, cbb.cb.state)
+}
+
+func TestCircuitBreakerHalfOpenRecovers(t *testing.T) {
+	cbb, target := newTestCircuitBreaker(CircuitBreakerOptions{
+		MinimumRequests: 1,
+		OpenDuration:    1 * time.Hour,
+		HalfOpenProbes:  2,
+	})
+
+	target.syncErr = testUnavailableErr()
+	_, _ = testSyncRequest(t, cbb)
+	assert.Equal(t, circuitOpen, cbb.cb.state)
+	_, err := testSyncRequest(t, cbb)
+	assert.Regexp(t, "FF22150", err)
+
+	// Once the open duration passes, a limited number of probes are let through
+	cbb.cb.openedAt = time.Now().Add(-1 * time.Hour)
+	gen1, ok := cbb.cb.allow()
+	assert.True(t, ok)
+	assert.Equal(t, circuitHalfOpen, cbb.cb.state)
+	gen2, ok := cbb.cb.allow()
+	assert.True(t, ok)
+	_, ok = cbb.cb.allow()
+	assert.False(t, ok)
+
+	// A probe canceled by its caller frees its slot
+	cbb.cb.release(gen2)
+	gen2, ok = cbb.cb.allow()
+	assert.True(t, ok)
+
+	// The circuit closes when they all succeed
+	cbb.cb.record(gen1, false)
+	assert.Equal(t, circuitHalfOpen, cbb.cb.state)
+	cbb.cb.record(gen2, false)
+	assert.Equal(t, circuitClosed, cbb.cb.state)
+
+	// Outcomes from a previous state are ignored
+	cbb.cb.record(gen1, true)
+	cbb.cb.release(gen1)
+	assert.Equal(t, 0, cbb.cb.failures)
+	target.syncErr = nil
+	_, err = testSyncRequest(t, cbb)
+	assert.NoError(t, err)
+}
+
+func TestCircuitBreakerHalfOpenProbeFails(t *testing.T) {
+	cbb, target := newTestCircuitBreaker(CircuitBreakerOptions{
+		MinimumRequests: 1,
+		OpenDuration:    1 * time.Hour,
+	})
+
+	target.syncErr = testUnavailableErr()
+	_, _ = testSyncRequest(t, cbb)
+	assert.Equal(t, circuitOpen, cbb.cb.state)
+
+	cbb.cb.openedAt = time.Now().Add(-1 * time.Hour)
+	_, err := testSyncRequest(t, cbb)
+	assert.Regexp(t, "FF22012", err)
+	assert.Equal(t, circuitOpen, cbb.cb.state)
+	_, err = testSyncRequest(t, cbb)
+	assert.Regexp(t, "FF22150", err)
+}
+
+func TestCircuitBreakerCanceledRequestsIgnored(t *testing.T) {
+	cbb, target := newTestCircuitBreaker(CircuitBreakerOptions{
+		MinimumRequests: 1,
+	})
+
+	ctx, cancelCtx := context.WithCancel(context.Background())
+	cancelCtx()
+	target.syncErr = testUnavailableErr()
+	_, err := cbb.SyncRequest(ctx, &RPCRequest{ID: fftypes.JSONAnyPtr("1")})
+	assert.Regexp(t, "FF22012", err)
+	assert.Equal(t, circuitClosed, cbb.cb.state)
+	assert.Equal(t, 0, cbb.cb.requests)
+}
+
+func TestCircuitBreakerSlowCalls(t *testing.T) {
+	target := &testSlowTarget{delay: 10 * time.Millisecond}
+	cbw := NewCircuitBreakerWSRPCClient(context.Background(), CircuitBreakerOptions{
+		MinimumRequests:   1,
+		SlowCallThreshold: 5 * time.Millisecond,
+	}, target).(*circuitBreakerWSRPCClient)
+
+	assert.Equal(t, &CircuitBreakerStatus{State: "closed"}, cbw.CircuitBreakerStatus())
+
+	// A slow success counts as a failure
+	_, err := testSyncRequest(t, cbw)
+	assert.NoError(t, err)
+	assert.Equal(t, circuitOpen, cbw.cb.state)
+
+	rpcErr := cbw.CallRPC(context.Background(), nil, "eth_blockNumber")
+	assert.Regexp(t, "FF22150", rpcErr.Message)
+	_, err = cbw.BatchRequest(context.Background(), []*RPCRequest{{ID: fftypes.JSONAnyPtr("1")}})
+	assert.Regexp(t, "FF22150", err)
+}
+
+func TestCircuitBreakerWSPassThrough(t *testing.T) {
+	target := &testFailoverTarget{}
+	target.subscribe = func(ctx context.Context) (Subscription, *RPCError) {
+		return newTestFailoverSub(), nil
+	}
+	cbw := NewCircuitBreakerWSRPCClient(context.Background(), CircuitBreakerOptions{}, target)
+
+	var result string
+	rpcErr := cbw.CallRPC(context.Background(), &result, "eth_blockNumber")
+	assert.Nil(t, rpcErr)
+	assert.Equal(t, "0x12345", result)
+	rpcResponses, err := cbw.BatchRequest(context.Background(), []*RPCRequest{{ID: fftypes.JSONAnyPtr("1")}})
+	assert.NoError(t, err)
+	assert.Len(t, rpcResponses, 1)
+	sub, rpcErr := cbw.Subscribe(context.Background(), "newHeads")
+	assert.Nil(t, rpcErr)
+	assert.NotNil(t, sub)
+}
@@ -0,0 +1,216 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcbackend
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+)
+
+// EthClient has typed methods for the common eth_* JSON/RPC methods, over any RPC (such as a Backend).
+// CallRPC remains available for any other method.
+//
+// Methods that take a block accept a tag ("latest", "pending", "safe", "finalized" or "earliest"),
+// or a block number from BlockNumber.
+type EthClient struct {
+	RPC
+}
+
+func NewEthClient(rpc RPC) *EthClient {
+	return &EthClient{RPC: rpc}
+}
+
+// BlockNumber returns the block parameter for a block number
+func BlockNumber(n uint64) string {
+	blockNumber := ethtypes.HexUint64(n)
+	return blockNumber.String()
+}
+
+// CallRequest is the transaction to simulate for eth_call and eth_estimateGas
+type CallRequest struct {
+	From                 *ethtypes.Address0xHex    `json:"from,omitempty"`
+	To                   *ethtypes.Address0xHex    `json:"to,omitempty"`
+	Gas                  *ethtypes.HexInteger      `json:"gas,omitempty"`
+	GasPrice             *ethtypes.HexInteger      `json:"gasPrice,omitempty"`
+	MaxFeePerGas         *ethtypes.HexInteger      `json:"maxFeePerGas,omitempty"`
+	MaxPriorityFeePerGas *ethtypes.HexInteger      `json:"maxPriorityFeePerGas,omitempty"`
+	Value                *ethtypes.HexInteger      `json:"value,omitempty"`
+	Data                 ethtypes.HexBytes0xPrefix `json:"data,omitempty"`
+}
+
+// TransactionInfo is a transaction that has been submitted, as returned in a block
+type TransactionInfo struct {
+	Hash                 ethtypes.HexBytes0xPrefix `json:"hash"`
+	BlockHash            ethtypes.HexBytes0xPrefix `json:"blockHash,omitempty"`
+	BlockNumber          *ethtypes.HexInteger      `json:"blockNumber,omitempty"`
+	TransactionIndex     *ethtypes.HexInteger      `json:"transactionIndex,omitempty"`
+	Type                 *ethtypes.HexInteger      `json:"type,omitempty"`
+	ChainID              *ethtypes.HexInteger      `json:"chainId,omitempty"`
+	From                 *ethtypes.Address0xHex    `json:"from,omitempty"`
+	To                   *ethtypes.Address0xHex    `json:"to,omitempty"`
+	Nonce                *ethtypes.HexInteger      `json:"nonce,omitempty"`
+	Gas                  *ethtypes.HexInteger      `json:"gas,omitempty"`
+	GasPrice             *ethtypes.HexInteger      `json:"gasPrice,omitempty"`
+	MaxFeePerGas         *ethtypes.HexInteger      `json:"maxFeePerGas,omitempty"`
+	MaxPriorityFeePerGas *ethtypes.HexInteger      `json:"maxPriorityFeePerGas,omitempty"`
+	Value                *ethtypes.HexInteger      `json:"value,omitempty"`
+	Input                ethtypes.HexBytes0xPrefix `json:"input,omitempty"`
+}
+
+// UnmarshalJSON accepts a transaction hash, as blocks only contain the hash of each transaction unless
+// the full transactions are requested
+func (ti *TransactionInfo) UnmarshalJSON(b []byte) error {
+	if len(b) > 0 && b[0] == '"' {
+		return json.Unmarshal(b, &ti.Hash)
+	}
+	type transactionInfo TransactionInfo
+	return json.Unmarshal(b, (*transactionInfo)(ti))
+}
+
+// Block is a block returned by eth_getBlockByNumber
+type Block struct {
+	Number        *ethtypes.HexInteger      `json:"number"`
+	Hash          ethtypes.HexBytes0xPrefix `json:"hash"`
+	ParentHash    ethtypes.HexBytes0xPrefix `json:"parentHash"`
+	Timestamp     *ethtypes.HexInteger      `json:"timestamp"`
+	Miner         *ethtypes.Address0xHex    `json:"miner,omitempty"`
+	GasLimit      *ethtypes.HexInteger      `json:"gasLimit"`
+	GasUsed       *ethtypes.HexInteger      `json:"gasUsed"`
+	BaseFeePerGas *ethtypes.HexInteger      `json:"baseFeePerGas,omitempty"` // only on chains with EIP-1559
+	Transactions  []*TransactionInfo        `json:"transactions"`            // only the hash of each, unless full transactions are requested
+}
+
+// Log is an event emitted by a transaction
+type Log struct {
+	Address          *ethtypes.Address0xHex      `json:"address"`
+	Topics           []ethtypes.HexBytes0xPrefix `json:"topics"`
+	Data             ethtypes.HexBytes0xPrefix   `json:"data"`
+	BlockHash        ethtypes.HexBytes0xPrefix   `json:"blockHash"`
+	BlockNumber      *ethtypes.HexInteger        `json:"blockNumber"`
+	TransactionHash  ethtypes.HexBytes0xPrefix   `json:"transactionHash"`
+	TransactionIndex *ethtypes.HexInteger        `json:"transactionIndex"`
+	LogIndex         *ethtypes.HexInteger        `json:"logIndex"`
+	Removed          bool                        `json:"removed"`
+}
+
+// Receipt is the result of a mined transaction, returned by eth_getTransactionReceipt
+type Receipt struct {
+	TransactionHash   ethtypes.HexBytes0xPrefix `json:"transactionHash"`
+	TransactionIndex  *ethtypes.HexInteger      `json:"transactionIndex"`
+	BlockHash         ethtypes.HexBytes0xPrefix `json:"blockHash"`
+	BlockNumber       *ethtypes.HexInteger      `json:"blockNumber"`
+	From              *ethtypes.Address0xHex    `json:"from"`
+	To                *ethtypes.Address0xHex    `json:"to,omitempty"`              // nil for a contract deployment
+	ContractAddress   *ethtypes.Address0xHex    `json:"contractAddress,omitempty"` // only for a contract deployment
+	CumulativeGasUsed *ethtypes.HexInteger      `json:"cumulativeGasUsed"`
+	GasUsed           *ethtypes.HexInteger      `json:"gasUsed"`
+	EffectiveGasPrice *ethtypes.HexInteger      `json:"effectiveGasPrice,omitempty"`
+	Type              *ethtypes.HexInteger      `json:"type,omitempty"`
+	Status            *ethtypes.HexInteger      `json:"status"` // 1 for success, 0 for a transaction that reverted
+	Logs              []*Log                    `json:"logs"`
+}
+
+// Succeeded is true for a transaction that did not revert
+func (r *Receipt) Succeeded() bool {
+	return r.Status.BigInt().Sign() > 0
+}
+
+// FeeHistory is the result of eth_feeHistory
+type FeeHistory struct {
+	OldestBlock   *ethtypes.HexInteger     `json:"oldestBlock"`
+	BaseFeePerGas []*ethtypes.HexInteger   `json:"baseFeePerGas"` // includes the base fee of the block after the newest
+	GasUsedRatio  []float64                `json:"gasUsedRatio"`
+	Reward        [][]*ethtypes.HexInteger `json:"reward,omitempty"` // one entry per block, with one fee for each percentile
+}
+
+func (ec *EthClient) GetBlockNumber(ctx context.Context) (ethtypes.HexUint64, *RPCError) {
+	var blockNumber ethtypes.HexUint64
+	rpcErr := ec.CallRPC(ctx, &blockNumber, "eth_blockNumber")
+	return blockNumber, rpcErr
+}
+
+func (ec *EthClient) GetBalance(ctx context.Context, addr ethtypes.Address0xHex, block string) (*ethtypes.HexInteger, *RPCError) {
+	var balance ethtypes.HexInteger
+	if rpcErr := ec.CallRPC(ctx, &balance, "eth_getBalance", &addr, block); rpcErr != nil {
+		return nil, rpcErr
+	}
+	return &balance, nil
+}
+
+// GetCode returns the code deployed at the address, which is empty if there is no contract at the address
+func (ec *EthClient) GetCode(ctx context.Context, addr ethtypes.Address0xHex, block string) (ethtypes.HexBytes0xPrefix, *RPCError) {
+	var code ethtypes.HexBytes0xPrefix
+	rpcErr := ec.CallRPC(ctx, &code, "eth_getCode", &addr, block)
+	return code, rpcErr
+}
+
+func (ec *EthClient) GetTransactionCount(ctx context.Context, addr ethtypes.Address0xHex, block string) (ethtypes.HexUint64, *RPCError) {
+	var count ethtypes.HexUint64
+	rpcErr := ec.CallRPC(ctx, &count, "eth_getTransactionCount", &addr, block)
+	return count, rpcErr
+}
+
+// GetBlockByNumber returns nil if there is no such block. Each transaction of the block is only its hash,
+// unless fullTransactions is set.
+func (ec *EthClient) GetBlockByNumber(ctx context.Context, block string, fullTransactions bool) (*Block, *RPCError) {
+	var result *Block
+	rpcErr := ec.CallRPC(ctx, &result, "eth_getBlockByNumber", block, fullTransactions)
+	return result, rpcErr
+}
+
+// GetTransactionReceipt returns nil if the transaction is not mined
+func (ec *EthClient) GetTransactionReceipt(ctx context.Context, txHash ethtypes.HexBytes0xPrefix) (*Receipt, *RPCError) {
+	var result *Receipt
+	rpcErr := ec.CallRPC(ctx, &result, "eth_getTransactionReceipt", txHash)
+	return result, rpcErr
+}
+
+func (ec *EthClient) Call(ctx context.Context, call *CallRequest, block string) (ethtypes.HexBytes0xPrefix, *RPCError) {
+	var result ethtypes.HexBytes0xPrefix
+	rpcErr := ec.CallRPC(ctx, &result, "eth_call", call, block)
+	return result, rpcErr
+}
+
+func (ec *EthClient) EstimateGas(ctx context.Context, call *CallRequest) (*ethtypes.HexInteger, *RPCError) {
+	var gas ethtypes.HexInteger
+	if rpcErr := ec.CallRPC(ctx, &gas, "eth_estimateGas", call); rpcErr != nil {
+		return nil, rpcErr
+	}
+	return &gas, nil
+}
+
+// GetLogs returns the logs matching the filter
+func (ec *EthClient) GetLogs(ctx context.Context, filter *LogFilter) ([]*Log, *RPCError) {
+	var logs []*Log
+	rpcErr := ec.CallRPC(ctx, &logs, "eth_getLogs", filter)
+	return logs, rpcErr
+}
+
+// FeeHistory returns the fee history of the number of blocks up to the newest block, with the priority
+// fees paid at each of the reward percentiles (from 0 to 100) in each block
+func (ec *EthClient) FeeHistory(ctx context.Context, blocks uint64, newestBlock string, rewardPercentiles []float64) (*FeeHistory, *RPCError) {
+	if rewardPercentiles == nil {
+		rewardPercentiles = []float64{}
+	}
+	var history FeeHistory
+	if rpcErr := ec.CallRPC(ctx, &history, "eth_feeHistory", ethtypes.HexUint64(blocks), newestBlock, rewardPercentiles); rpcErr != nil {
+		return nil, rpcErr
+	}
+	return &history, nil
+}
@@ -0,0 +1,227 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcbackend
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/stretchr/testify/assert"
+)
+
+const testEthAddress = "0xfb075bb99f2aa4c49955bf703509a227d7a12248"
+
+// newTestEthClient returns a client of a server that checks the parameters of each method, and returns its result.
+// Methods without a result fail.
+func newTestEthClient(t *testing.T, calls map[string][2]string) (context.Context, *EthClient, func()) {
+	ctx, rb, done := newTestServer(t, func(rpcReq *RPCRequest) (int, *RPCResponse) {
+		call, ok := calls[rpcReq.Method]
+		if !ok {
+			return http.StatusOK, &RPCResponse{JSONRpc: "2.0", ID: rpcReq.ID, Error: &RPCError{Code: int64(RPCCodeInternalError), Message: "pop"}}
+		}
+		params, _ := json.Marshal(rpcReq.Params)
+		assert.JSONEq(t, call[0], string(params), rpcReq.Method)
+		return http.StatusOK, &RPCResponse{JSONRpc: "2.0", ID: rpcReq.ID, Result: fftypes.JSONAnyPtr(call[1])}
+	})
+	return ctx, NewEthClient(rb), done
+}
+
+func TestEthClientAccountMethods(t *testing.T) {
+	ctx, ec, done := newTestEthClient(t, map[string][2]string{
+		"eth_getBalance":          {`["` + testEthAddress + `","latest"]`, `"0xde0b6b3a7640000"`},
+		"eth_getTransactionCount": {`["` + testEthAddress + `","0x10"]`, `"0x5"`},
+		"eth_getCode":             {`["` + testEthAddress + `","latest"]`, `"0x6080"`},
+	})
+	defer done()
+
+	balance, rpcErr := ec.GetBalance(ctx, *ethtypes.MustNewAddress(testEthAddress), "latest")
+	assert.Nil(t, rpcErr)
+	assert.Equal(t, "1000000000000000000", balance.BigInt().String())
+
+	count, rpcErr := ec.GetTransactionCount(ctx, *ethtypes.MustNewAddress(testEthAddress), BlockNumber(16))
+	assert.Nil(t, rpcErr)
+	assert.Equal(t, uint64(5), count.Uint64())
+
+	code, rpcErr := ec.GetCode(ctx, *ethtypes.MustNewAddress(testEthAddress), "latest")
+	assert.Nil(t, rpcErr)
+	assert.Equal(t, "0x6080", code.String())
+}
+
+func TestEthClientGetBlockNumber(t *testing.T) {
+	ctx, ec, done := newTestEthClient(t, map[string][2]string{
+		"eth_blockNumber": {`null`, `"0x1b4"`},
+	})
+	defer done()
+
+	blockNumber, rpcErr := ec.GetBlockNumber(ctx)
+	assert.Nil(t, rpcErr)
+	assert.Equal(t, uint64(436), blockNumber.Uint64())
+}
+
+func TestEthClientGetBlockByNumber(t *testing.T) {
+	ctx, ec, done := newTestEthClient(t, map[string][2]string{
+		"eth_getBlockByNumber": {`["latest",false]`, `{
+			"number": "0x1b4",
+			"hash": "0xdc0818cf78f21a8e70579cb46a43643f78291264dda342ae31049421c82d21ae",
+			"parentHash": "0xe99e022112df268087ea7eafaf4790497fd21dbeeb6bd7a1721df161a6657a54",
+			"timestamp": "0x55ba467c",
+			"gasLimit": "0x1388",
+			"gasUsed": "0x5208",
+			"baseFeePerGas": "0x7",
+			"transactions": ["0x61ca9c99c1d752fb3bda568b8566edf33ba93585c64a970566e6dfb540a5cbc1"]
+		}`},
+	})
+	defer done()
+
+	block, rpcErr := ec.GetBlockByNumber(ctx, "latest", false)
+	assert.Nil(t, rpcErr)
+	assert.Equal(t, int64(436), block.Number.Int64())
+	assert.Equal(t, int64(7), block.BaseFeePerGas.Int64())
+	assert.Len(t, block.Transactions, 1)
+	assert.Equal(t, "0x61ca9c99c1d752fb3bda568b8566edf33ba93585c64a970566e6dfb540a5cbc1", block.Transactions[0].Hash.String())
+	assert.Nil(t, block.Transactions[0].From)
+}
+
+func TestEthClientGetBlockByNumberFullTransactions(t *testing.T) {
+	ctx, ec, done := newTestEthClient(t, map[string][2]string{
+		"eth_getBlockByNumber": {`["0x1b4",true]`, `{
+			"number": "0x1b4",
+			"transactions": [{
+				"hash": "0x61ca9c99c1d752fb3bda568b8566edf33ba93585c64a970566e6dfb540a5cbc1",
+				"from": "` + testEthAddress + `",
+				"nonce": "0x24",
+				"input": "0xa0712d68"
+			}]
+		}`},
+	})
+	defer done()
+
+	block, rpcErr := ec.GetBlockByNumber(ctx, BlockNumber(436), true)
+	assert.Nil(t, rpcErr)
+	assert.Equal(t, testEthAddress, block.Transactions[0].From.String())
+	assert.Equal(t, int64(36), block.Transactions[0].Nonce.Int64())
+	assert.Equal(t, "0xa0712d68", block.Transactions[0].Input.String())
+
+	var ti TransactionInfo
+	err := json.Unmarshal([]byte(`{"hash": false}`), &ti)
+	assert.Error(t, err)
+}
+
+func TestEthClientGetBlockByNumberNotFound(t *testing.T) {
+	ctx, ec, done := newTestEthClient(t, map[string][2]string{
+		"eth_getBlockByNumber": {`["0x1000000",false]`, `null`},
+	})
+	defer done()
+
+	block, rpcErr := ec.GetBlockByNumber(ctx, BlockNumber(0x1000000), false)
+	assert.Nil(t, rpcErr)
+	assert.Nil(t, block)
+}
+
+func TestEthClientGetTransactionReceipt(t *testing.T) {
+	txHash := ethtypes.MustNewHexBytes0xPrefix("0x61ca9c99c1d752fb3bda568b8566edf33ba93585c64a970566e6dfb540a5cbc1")
+	ctx, ec, done := newTestEthClient(t, map[string][2]string{
+		"eth_getTransactionReceipt": {`["` + txHash.String() + `"]`, `{
+			"transactionHash": "` + txHash.String() + `",
+			"blockNumber": "0xd536bc",
+			"from": "` + testEthAddress + `",
+			"gasUsed": "0x2b13d",
+			"status": "0x1",
+			"logs": [{
+				"address": "0x497eedc4299dea2f2a364be10025d0ad0f702de3",
+				"topics": ["0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"],
+				"data": "0x",
+				"logIndex": "0x0"
+			}]
+		}`},
+	})
+	defer done()
+
+	receipt, rpcErr := ec.GetTransactionReceipt(ctx, txHash)
+	assert.Nil(t, rpcErr)
+	assert.True(t, receipt.Succeeded())
+	assert.Equal(t, int64(0xd536bc), receipt.BlockNumber.Int64())
+	assert.Len(t, receipt.Logs, 1)
+	assert.Equal(t, "0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef", receipt.Logs[0].Topics[0].String())
+
+	receipt.Status = ethtypes.NewHexIntegerU64(0)
+	assert.False(t, receipt.Succeeded())
+}
+
+func TestEthClientCallAndEstimateGas(t *testing.T) {
+	call := &CallRequest{
+		From: ethtypes.MustNewAddress(testEthAddress),
+		To:   ethtypes.MustNewAddress("0x497eedc4299dea2f2a364be10025d0ad0f702de3"),
+		Data: ethtypes.MustNewHexBytes0xPrefix("0x70a08231"),
+	}
+	callJSON := `{"from":"` + testEthAddress + `","to":"0x497eedc4299dea2f2a364be10025d0ad0f702de3","data":"0x70a08231"}`
+	ctx, ec, done := newTestEthClient(t, map[string][2]string{
+		"eth_call":        {`[` + callJSON + `,"pending"]`, `"0x000000000000000000000000000000000000000000000000000000000000002a"`},
+		"eth_estimateGas": {`[` + callJSON + `]`, `"0x5208"`},
+	})
+	defer done()
+
+	result, rpcErr := ec.Call(ctx, call, "pending")
+	assert.Nil(t, rpcErr)
+	assert.Equal(t, byte(42), result[31])
+
+	gas, rpcErr := ec.EstimateGas(ctx, call)
+	assert.Nil(t, rpcErr)
+	assert.Equal(t, uint64(21000), gas.Uint64())
+}
+
+func TestEthClientFeeHistory(t *testing.T) {
+	ctx, ec, done := newTestEthClient(t, map[string][2]string{
+		"eth_feeHistory": {`["0x2","latest",[]]`, `{
+			"oldestBlock": "0x10",
+			"baseFeePerGas": ["0x7", "0x8", "0x9"],
+			"gasUsedRatio": [0.5, 0.25]
+		}`},
+	})
+	defer done()
+
+	history, rpcErr := ec.FeeHistory(ctx, 2, "latest", nil)
+	assert.Nil(t, rpcErr)
+	assert.Equal(t, int64(16), history.OldestBlock.Int64())
+	assert.Len(t, history.BaseFeePerGas, 3)
+	assert.Equal(t, []float64{0.5, 0.25}, history.GasUsedRatio)
+	assert.Nil(t, history.Reward)
+}
+
+func TestEthClientErrors(t *testing.T) {
+	ctx, ec, done := newTestEthClient(t, map[string][2]string{})
+	defer done()
+	addr := *ethtypes.MustNewAddress(testEthAddress)
+
+	_, rpcErr := ec.GetBalance(ctx, addr, "latest")
+	assert.Regexp(t, "pop", rpcErr.Message)
+	_, rpcErr = ec.GetCode(ctx, addr, "latest")
+	assert.Regexp(t, "pop", rpcErr.Message)
+	_, rpcErr = ec.EstimateGas(ctx, &CallRequest{})
+	assert.Regexp(t, "pop", rpcErr.Message)
+	_, rpcErr = ec.FeeHistory(ctx, 1, "latest", []float64{50})
+	assert.Regexp(t, "pop", rpcErr.Message)
+
+	// The generic call remains available
+	var chainID ethtypes.HexInteger
+	rpcErr = ec.CallRPC(ctx, &chainID, "eth_chainId")
+	assert.Regexp(t, "pop", rpcErr.Message)
+}
@@ -0,0 +1,430 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcbackend
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-common/pkg/log"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+)
+
+const (
+	DefaultHealthCheckInterval = 5 * time.Second
+	DefaultHealthCheckTimeout  = 2 * time.Second
+	DefaultHealthCheckMethod   = "eth_blockNumber"
+)
+
+// FailoverOptions configures the health checking and routing of a failover backend
+type FailoverOptions struct {
+	// HealthCheckInterval is how often every backend is checked (the first check happens after one interval)
+	HealthCheckInterval time.Duration
+	// HealthCheckTimeout is the maximum time a backend has to respond to a health check
+	HealthCheckTimeout time.Duration
+	// HealthCheckMethod is a JSON/RPC method with no parameters, which must succeed for a backend to be healthy
+	HealthCheckMethod string
+	// StickySubscriptions keeps each subscription on the backend it was created on while that backend is
+	// healthy, rather than moving it back to a higher priority backend when that one recovers
+	StickySubscriptions bool
+}
+
+type failoverBackend struct {
+	ctx     context.Context
+	options FailoverOptions
+	targets []*failoverTarget
+	subsMux sync.Mutex
+	subs    map[fftypes.UUID]*failoverSub
+}
+
+type failoverTarget struct {
+	index   int
+	backend Backend
+	ws      WebSocketRPCClient // only set for WebSocket backends
+	mux     sync.Mutex
+	healthy bool
+}
+
+type failoverSub struct {
+	fb            *failoverBackend
+	localID       *fftypes.UUID
+	params        []interface{}
+	ctx           context.Context
+	cancelCtx     context.CancelFunc
+	mux           sync.Mutex
+	target        *failoverTarget
+	inner         Subscription
+	pumpStop      chan struct{}
+	pumpDone      chan struct{}
+	notifications chan *RPCSubscriptionNotification
+	resubscribed  chan string
+}
+
+// NewFailoverBackend routes each request to the highest priority healthy backend, in the order
+// supplied. Requests that fail because a backend is unavailable are retried on the next backend,
+// and health checks run in the background until the context is cancelled.
+func NewFailoverBackend(ctx context.Context, options FailoverOptions, backends ...Backend) Backend {
+	fb := newFailoverBackend(ctx, options, len(backends))
+	for i, b := range backends {
+		fb.targets[i].backend = b
+	}
+	go fb.healthCheckLoop()
+	return fb
+}
+
+// NewFailoverWSRPCClient is the WebSocket equivalent of NewFailoverBackend, which also moves
+// subscriptions to a healthy backend when the one they are on becomes unhealthy.
+func NewFailoverWSRPCClient(ctx context.Context, options FailoverOptions, backends ...WebSocketRPCClient) WebSocketRPCClient {
+	fb := newFailoverBackend(ctx, options, len(backends))
+	for i, b := range backends {
+		fb.targets[i].backend = b
+		fb.targets[i].ws = b
+	}
+	go fb.healthCheckLoop()
+	return fb
+}
+
+func newFailoverBackend(ctx context.Context, options FailoverOptions, count int) *failoverBackend {
+	if options.HealthCheckInterval <= 0 {
+		options.HealthCheckInterval = DefaultHealthCheckInterval
+	}
+	if options.HealthCheckTimeout <= 0 {
+		options.HealthCheckTimeout = DefaultHealthCheckTimeout
+	}
+	if options.HealthCheckMethod == "" {
+		options.HealthCheckMethod = DefaultHealthCheckMethod
+	}
+	fb := &failoverBackend{
+		ctx:     log.WithLogField(ctx, "role", "rpc_failover"),
+		options: options,
+		targets: make([]*failoverTarget, count),
+		subs:    make(map[fftypes.UUID]*failoverSub),
+	}
+	for i := range fb.targets {
+		fb.targets[i] = &failoverTarget{index: i, healthy: true}
+	}
+	return fb
+}
+
+// IsBackendUnavailable distinguishes a failure to get a JSON/RPC response from the backend
+// (which we fail over on), from a JSON/RPC error returned by the backend (which we do not)
+func IsBackendUnavailable(err error) bool {
+	msg := err.Error()
+	return strings.HasPrefix(msg, string(signermsgs.MsgRPCRequestFailed)) ||
+		strings.HasPrefix(msg, string(signermsgs.MsgWebSocketReconnected)) ||
+		strings.HasPrefix(msg, string(signermsgs.MsgCircuitBreakerOpen))
+}
+
+func (t *failoverTarget) isHealthy() bool {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	return t.healthy
+}
+
+func (fb *failoverBackend) setHealth(t *failoverTarget, healthy bool, reason error) {
+	t.mux.Lock()
+	changed := t.healthy != healthy
+	t.healthy = healthy
+	t.mux.Unlock()
+	if changed {
+		if healthy {
+			log.L(fb.ctx).Infof("Backend %d is healthy", t.index)
+		} else {
+			log.L(fb.ctx).Warnf("Backend %d is unhealthy: %s", t.index, reason)
+		}
+	}
+}
+
+// candidates returns the healthy backends in priority order, followed by the unhealthy ones
+// so that we still attempt requests when every backend is failing health checks
+func (fb *failoverBackend) candidates(wsOnly bool) []*failoverTarget {
+	healthy := make([]*failoverTarget, 0, len(fb.targets))
+	unhealthy := make([]*failoverTarget, 0, len(fb.targets))
+	for _, t := range fb.targets {
+		switch {
+		case wsOnly && t.ws == nil:
+		case t.isHealthy():
+			healthy = append(healthy, t)
+		default:
+			unhealthy = append(unhealthy, t)
+		}
+	}
+	return append(healthy, unhealthy...)
+}
+
+func (fb *failoverBackend) CallRPC(ctx context.Context, result interface{}, method string, params ...interface{}) *RPCError {
+	return callRPCWithSyncRequest(ctx, fb.SyncRequest, result, method, params)
+}
+
+func (fb *failoverBackend) SyncRequest(ctx context.Context, rpcReq *RPCRequest) (rpcRes *RPCResponse, err error) {
+	for _, t := range fb.candidates(false) {
+		rpcRes, err = t.backend.SyncRequest(ctx, rpcReq)
+		if err == nil || !IsBackendUnavailable(err) {
+			return rpcRes, err
+		}
+		fb.setHealth(t, false, err)
+	}
+	return rpcRes, err
+}
+
+func (fb *failoverBackend) BatchRequest(ctx context.Context, rpcReqs []*RPCRequest) (rpcResponses []*RPCResponse, err error) {
+	for _, t := range fb.candidates(false) {
+		rpcResponses, err = t.backend.BatchRequest(ctx, rpcReqs)
+		if err == nil || !IsBackendUnavailable(err) {
+			return rpcResponses, err
+		}
+		fb.setHealth(t, false, err)
+	}
+	return rpcResponses, err
+}
+
+// Connect connects all the WebSocket backends in parallel. Backends that fail to connect are
+// marked unhealthy, and an error is only returned if none of them connect.
+func (fb *failoverBackend) Connect(ctx context.Context) error {
+	errs := make(chan error, len(fb.targets))
+	for _, t := range fb.targets {
+		go func(t *failoverTarget) {
+			err := t.ws.Connect(ctx)
+			if err != nil {
+				fb.setHealth(t, false, err)
+			}
+			errs <- err
+		}(t)
+	}
+	var lastErr error
+	connected := 0
+	for range fb.targets {
+		if err := <-errs; err != nil {
+			lastErr = err
+		} else {
+			connected++
+		}
+	}
+	if connected == 0 {
+		return lastErr
+	}
+	return nil
+}
+
+func (fb *failoverBackend) Close() {
+	for _, t := range fb.targets {
+		t.ws.Close()
+	}
+}
+
+func (fb *failoverBackend) healthCheckLoop() {
+	ticker := time.NewTicker(fb.options.HealthCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			fb.checkHealth()
+		case <-fb.ctx.Done():
+			log.L(fb.ctx).Debugf("Health checker stopped")
+			return
+		}
+	}
+}
+
+func (fb *failoverBackend) checkHealth() {
+	var wg sync.WaitGroup
+	for _, t := range fb.targets {
+		wg.Add(1)
+		go func(t *failoverTarget) {
+			defer wg.Done()
+			ctx, cancelCtx := context.WithTimeout(fb.ctx, fb.options.HealthCheckTimeout)
+			defer cancelCtx()
+			var result fftypes.JSONAny
+			rpcErr := t.backend.CallRPC(ctx, &result, fb.options.HealthCheckMethod)
+			if rpcErr != nil {
+				fb.setHealth(t, false, rpcErr.Error())
+			} else {
+				fb.setHealth(t, true, nil)
+			}
+		}(t)
+	}
+	wg.Wait()
+	fb.rebalanceSubscriptions()
+}
+
+func (fb *failoverBackend) Subscribe(ctx context.Context, params ...interface{}) (Subscription, *RPCError) {
+	fs := &failoverSub{
+		fb:            fb,
+		localID:       fftypes.NewUUID(),
+		params:        params,
+		notifications: make(chan *RPCSubscriptionNotification),
+		resubscribed:  make(chan string, 1),
+	}
+	fs.ctx, fs.cancelCtx = context.WithCancel(ctx)
+	var rpcErr *RPCError
+	for _, t := range fb.candidates(true) {
+		var inner Subscription
+		inner, rpcErr = t.ws.Subscribe(fs.ctx, params...)
+		if rpcErr == nil {
+			fs.mux.Lock()
+			fs.startPump(t, inner)
+			fs.mux.Unlock()
+			fb.subsMux.Lock()
+			fb.subs[*fs.localID] = fs
+			fb.subsMux.Unlock()
+			return fs, nil
+		}
+		if !IsBackendUnavailable(rpcErr.Error()) {
+			break
+		}
+		fb.setHealth(t, false, rpcErr.Error())
+	}
+	fs.cancelCtx()
+	return nil, rpcErr
+}
+
+func (fb *failoverBackend) Subscriptions() []Subscription {
+	fb.subsMux.Lock()
+	defer fb.subsMux.Unlock()
+	subs := make([]Subscription, 0, len(fb.subs))
+	for _, fs := range fb.subs {
+		subs = append(subs, fs)
+	}
+	return subs
+}
+
+func (fb *failoverBackend) UnsubscribeAll(ctx context.Context) (lastErr *RPCError) {
+	for _, s := range fb.Subscriptions() {
+		if rpcErr := s.Unsubscribe(ctx); rpcErr != nil {
+			log.L(ctx).Errorf("Failed to unsubscribe %s: %s", s.LocalID(), rpcErr)
+			lastErr = rpcErr
+		}
+	}
+	return lastErr
+}
+
+// rebalanceSubscriptions moves subscriptions off unhealthy backends, and (unless sticky) back
+// to the highest priority healthy backend when it recovers
+func (fb *failoverBackend) rebalanceSubscriptions() {
+	candidates := fb.candidates(true)
+	if len(candidates) == 0 || !candidates[0].isHealthy() {
+		return // nowhere better to go
+	}
+	for _, fs := range fb.Subscriptions() {
+		fs.(*failoverSub).rebalance(candidates[0])
+	}
+}
+
+func (fs *failoverSub) rebalance(preferred *failoverTarget) {
+	fs.mux.Lock()
+	defer fs.mux.Unlock()
+	current := fs.target
+	if current == nil || current == preferred || (fs.fb.options.StickySubscriptions && current.isHealthy()) {
+		return
+	}
+
+	log.L(fs.ctx).Infof("Moving subscription %s from backend %d to backend %d", fs.localID, current.index, preferred.index)
+	inner, rpcErr := preferred.ws.Subscribe(fs.ctx, fs.params...)
+	if rpcErr != nil {
+		log.L(fs.ctx).Errorf("Failed to move subscription %s to backend %d: %s", fs.localID, preferred.index, rpcErr.Message)
+		return
+	}
+	fs.stopPump()
+	// Best effort removal from the old backend, which might well be down
+	ctx, cancelCtx := context.WithTimeout(fs.ctx, fs.fb.options.HealthCheckTimeout)
+	if rpcErr := fs.inner.Unsubscribe(ctx); rpcErr != nil {
+		log.L(fs.ctx).Warnf("Failed to unsubscribe %s from backend %d: %s", fs.localID, current.index, rpcErr.Message)
+	}
+	cancelCtx()
+	fs.startPump(preferred, inner)
+
+	serverSubID := ""
+	if s, ok := inner.(*sub); ok {
+		serverSubID = s.serverSubID()
+	}
+	select {
+	case fs.resubscribed <- serverSubID:
+	default:
+	}
+}
+
+// startPump must be called with the lock held
+func (fs *failoverSub) startPump(t *failoverTarget, inner Subscription) {
+	fs.target = t
+	fs.inner = inner
+	fs.pumpStop = make(chan struct{})
+	fs.pumpDone = make(chan struct{})
+	go fs.pump(inner, fs.pumpStop, fs.pumpDone)
+}
+
+// stopPump must be called with the lock held
+func (fs *failoverSub) stopPump() {
+	close(fs.pumpStop)
+	<-fs.pumpDone
+}
+
+func (fs *failoverSub) pump(inner Subscription, stop, done chan struct{}) {
+	defer close(done)
+	for {
+		select {
+		case n, ok := <-inner.Notifications():
+			if !ok {
+				return
+			}
+			select {
+			case fs.notifications <- n:
+			case <-stop:
+				return
+			}
+		case serverSubID := <-inner.Resubscribed():
+			select {
+			case fs.resubscribed <- serverSubID:
+			default:
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (fs *failoverSub) LocalID() *fftypes.UUID {
+	return fs.localID
+}
+
+func (fs *failoverSub) Notifications() chan *RPCSubscriptionNotification {
+	return fs.notifications
+}
+
+func (fs *failoverSub) Resubscribed() chan string {
+	return fs.resubscribed
+}
+
+func (fs *failoverSub) Unsubscribe(ctx context.Context) *RPCError {
+	fs.fb.subsMux.Lock()
+	delete(fs.fb.subs, *fs.localID)
+	fs.fb.subsMux.Unlock()
+
+	fs.mux.Lock()
+	defer fs.mux.Unlock()
+	if fs.target == nil {
+		return nil // already unsubscribed
+	}
+	fs.stopPump()
+	rpcErr := fs.inner.Unsubscribe(ctx)
+	fs.target = nil
+	fs.cancelCtx()
+	close(fs.notifications)
+	return rpcErr
+}
@@ -0,0 +1,505 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcbackend
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/stretchr/testify/assert"
+)
+
+type testFailoverTarget struct {
+	calls      int32
+	unhealthy  atomic.Bool
+	syncErr    error
+	batchErr   error
+	connectErr error
+	closed     atomic.Bool
+	subscribe  func(ctx context.Context) (Subscription, *RPCError)
+}
+
+type testFailoverSub struct {
+	localID       *fftypes.UUID
+	notifications chan *RPCSubscriptionNotification
+	resubscribed  chan string
+	unsubErr      *RPCError
+	unsubscribed  atomic.Bool
+}
+
+func newTestFailoverSub() *testFailoverSub {
+	return &testFailoverSub{
+		localID:       fftypes.NewUUID(),
+		notifications: make(chan *RPCSubscriptionNotification),
+		resubscribed:  make(chan string, 1),
+	}
+}
+
+func (ts *testFailoverSub) LocalID() *fftypes.UUID                           { return ts.localID }
+func (ts *testFailoverSub) Notifications() chan *RPCSubscriptionNotification { return ts.notifications }
+func (ts *testFailoverSub) Resubscribed() chan string                        { return ts.resubscribed }
+func (ts *testFailoverSub) Unsubscribe(ctx context.Context) *RPCError {
+	ts.unsubscribed.Store(true)
+	return ts.unsubErr
+}
+
+func testUnavailableErr() error {
+	return i18n.NewError(context.Background(), signermsgs.MsgRPCRequestFailed, "pop")
+}
+
+func (tt *testFailoverTarget) CallRPC(ctx context.Context, result interface{}, method string, params ...interface{}) *RPCError {
+	if tt.unhealthy.Load() {
+		return &RPCError{Message: testUnavailableErr().Error()}
+	}
+	return nil
+}
+
+func (tt *testFailoverTarget) SyncRequest(ctx context.Context, rpcReq *RPCRequest) (*RPCResponse, error) {
+	atomic.AddInt32(&tt.calls, 1)
+	if tt.syncErr != nil {
+		return RPCErrorResponse(tt.syncErr, rpcReq.ID, RPCCodeInternalError), tt.syncErr
+	}
+	return &RPCResponse{ID: rpcReq.ID, Result: fftypes.JSONAnyPtr(`"0x12345"`)}, nil
+}
+
+func (tt *testFailoverTarget) BatchRequest(ctx context.Context, rpcReqs []*RPCRequest) ([]*RPCResponse, error) {
+	atomic.AddInt32(&tt.calls, 1)
+	rpcResponses := make([]*RPCResponse, len(rpcReqs))
+	for i, rpcReq := range rpcReqs {
+		rpcResponses[i] = &RPCResponse{ID: rpcReq.ID, Result: fftypes.JSONAnyPtr(`"0x12345"`)}
+	}
+	return rpcResponses, tt.batchErr
+}
+
+func (tt *testFailoverTarget) Connect(ctx context.Context) error {
+	return tt.connectErr
+}
+
+func (tt *testFailoverTarget) Close() {
+	tt.closed.Store(true)
+}
+
+func (tt *testFailoverTarget) Subscribe(ctx context.Context, params ...interface{}) (Subscription, *RPCError) {
+	return tt.subscribe(ctx)
+}
+
+func (tt *testFailoverTarget) Subscriptions() []Subscription {
+	return nil
+}
+
+func (tt *testFailoverTarget) UnsubscribeAll(ctx context.Context) *RPCError {
+	return nil
+}
+
+func newTestFailover(t *testing.T, options FailoverOptions, count int) (context.Context, *failoverBackend, []*testFailoverTarget, func()) {
+	ctx, cancelCtx := context.WithCancel(context.Background())
+	targets := make([]*testFailoverTarget, count)
+	backends := make([]WebSocketRPCClient, count)
+	for i := range targets {
+		targets[i] = &testFailoverTarget{}
+		backends[i] = targets[i]
+	}
+	if options.HealthCheckInterval == 0 {
+		options.HealthCheckInterval = 1 * time.Hour // tests trigger health checks directly
+	}
+	fb := NewFailoverWSRPCClient(ctx, options, backends...).(*failoverBackend)
+	return ctx, fb, targets, cancelCtx
+}
+
+func TestNewFailoverBackendDefaults(t *testing.T) {
+	ctx, cancelCtx := context.WithCancel(context.Background())
+	defer cancelCtx()
+	fb := NewFailoverBackend(ctx, FailoverOptions{}, &testFailoverTarget{}).(*failoverBackend)
+	assert.Equal(t, DefaultHealthCheckInterval, fb.options.HealthCheckInterval)
+	assert.Equal(t, DefaultHealthCheckTimeout, fb.options.HealthCheckTimeout)
+	assert.Equal(t, DefaultHealthCheckMethod, fb.options.HealthCheckMethod)
+	assert.Nil(t, fb.targets[0].ws)
+	assert.Empty(t, fb.candidates(true))
+}
+
+func TestFailoverSyncRequestFailsOver(t *testing.T) {
+	ctx, fb, targets, done := newTestFailover(t, FailoverOptions{}, 2)
+	defer done()
+
+	targets[0].syncErr = testUnavailableErr()
+
+	var result string
+	rpcErr := fb.CallRPC(ctx, &result, "eth_blockNumber")
+	assert.Nil(t, rpcErr)
+	assert.Equal(t, "0x12345", result)
+	assert.False(t, fb.targets[0].isHealthy())
+
+	// The unhealthy backend is now tried last
+	rpcErr = fb.CallRPC(ctx, &result, "eth_blockNumber")
+	assert.Nil(t, rpcErr)
+	assert.Equal(t, int32(1), targets[0].calls)
+	assert.Equal(t, int32(2), targets[1].calls)
+}
+
+func TestFailoverSyncRequestRPCErrorNoFailover(t *testing.T) {
+	ctx, fb, targets, done := newTestFailover(t, FailoverOptions{}, 2)
+	defer done()
+
+	targets[0].syncErr = fmt.Errorf("execution reverted")
+
+	_, err := fb.SyncRequest(ctx, &RPCRequest{ID: fftypes.JSONAnyPtr("1"), Method: "eth_call"})
+	assert.Regexp(t, "execution reverted", err)
+	assert.True(t, fb.targets[0].isHealthy())
+	assert.Equal(t, int32(0), targets[1].calls)
+}
+
+func TestFailoverSyncRequestAllFail(t *testing.T) {
+	ctx, fb, targets, done := newTestFailover(t, FailoverOptions{}, 2)
+	defer done()
+
+	targets[0].syncErr = testUnavailableErr()
+	targets[1].syncErr = testUnavailableErr()
+
+	rpcRes, err := fb.SyncRequest(ctx, &RPCRequest{ID: fftypes.JSONAnyPtr("1"), Method: "eth_call"})
+	assert.Regexp(t, "FF22012", err)
+	assert.Regexp(t, "FF22012", rpcRes.Message())
+	assert.False(t, fb.targets[0].isHealthy())
+	assert.False(t, fb.targets[1].isHealthy())
+}
+
+func TestFailoverBatchRequestFailsOver(t *testing.T) {
+	ctx, fb, targets, done := newTestFailover(t, FailoverOptions{}, 2)
+	defer done()
+
+	targets[0].batchErr = testUnavailableErr()
+
+	rpcResponses, err := fb.BatchRequest(ctx, []*RPCRequest{
+		{ID: fftypes.JSONAnyPtr("1"), Method: "eth_blockNumber"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, `"0x12345"`, rpcResponses[0].Result.String())
+	assert.False(t, fb.targets[0].isHealthy())
+	assert.Equal(t, int32(1), targets[1].calls)
+}
+
+func TestFailoverBatchRequestRPCErrorNoFailover(t *testing.T) {
+	ctx, fb, targets, done := newTestFailover(t, FailoverOptions{}, 2)
+	defer done()
+
+	targets[0].batchErr = fmt.Errorf("batch too large")
+
+	_, err := fb.BatchRequest(ctx, []*RPCRequest{
+		{ID: fftypes.JSONAnyPtr("1"), Method: "eth_blockNumber"},
+	})
+	assert.Regexp(t, "batch too large", err)
+	assert.Equal(t, int32(0), targets[1].calls)
+}
+
+func TestFailoverConnectPartial(t *testing.T) {
+	ctx, fb, targets, done := newTestFailover(t, FailoverOptions{}, 2)
+	defer done()
+
+	targets[0].connectErr = fmt.Errorf("pop")
+
+	err := fb.Connect(ctx)
+	assert.NoError(t, err)
+	assert.False(t, fb.targets[0].isHealthy())
+	assert.True(t, fb.targets[1].isHealthy())
+
+	fb.Close()
+	assert.True(t, targets[0].closed.Load())
+	assert.True(t, targets[1].closed.Load())
+}
+
+func TestFailoverConnectAllFail(t *testing.T) {
+	ctx, fb, targets, done := newTestFailover(t, FailoverOptions{}, 2)
+	defer done()
+
+	targets[0].connectErr = fmt.Errorf("pop")
+	targets[1].connectErr = fmt.Errorf("pop")
+
+	err := fb.Connect(ctx)
+	assert.Regexp(t, "pop", err)
+}
+
+func TestFailoverHealthCheckLoop(t *testing.T) {
+	ctx, cancelCtx := context.WithCancel(context.Background())
+	target := &testFailoverTarget{}
+	target.unhealthy.Store(true)
+	fb := newFailoverBackend(ctx, FailoverOptions{HealthCheckInterval: 1 * time.Millisecond}, 1)
+	fb.targets[0].backend = target
+
+	loopDone := make(chan struct{})
+	go func() {
+		fb.healthCheckLoop()
+		close(loopDone)
+	}()
+	for fb.targets[0].isHealthy() {
+		time.Sleep(1 * time.Millisecond)
+	}
+	target.unhealthy.Store(false)
+	for !fb.targets[0].isHealthy() {
+		time.Sleep(1 * time.Millisecond)
+	}
+	cancelCtx()
+	<-loopDone
+}
+
+func TestFailoverSubscribeFailsOver(t *testing.T) {
+	ctx, fb, targets, done := newTestFailover(t, FailoverOptions{}, 2)
+	defer done()
+
+	inner := newTestFailoverSub()
+	targets[0].subscribe = func(ctx context.Context) (Subscription, *RPCError) {
+		return nil, &RPCError{Message: testUnavailableErr().Error()}
+	}
+	targets[1].subscribe = func(ctx context.Context) (Subscription, *RPCError) {
+		return inner, nil
+	}
+
+	s, rpcErr := fb.Subscribe(ctx, "newHeads")
+	assert.Nil(t, rpcErr)
+	assert.NotEqual(t, inner.LocalID(), s.LocalID())
+	assert.Len(t, fb.Subscriptions(), 1)
+	assert.False(t, fb.targets[0].isHealthy())
+
+	go func() {
+		inner.notifications <- &RPCSubscriptionNotification{CurrentSubID: "0x1"}
+	}()
+	n := <-s.Notifications()
+	assert.Equal(t, "0x1", n.CurrentSubID)
+
+	inner.resubscribed <- "0x2"
+	assert.Equal(t, "0x2", <-s.Resubscribed())
+
+	s.Resubscribed() <- "unread"
+	inner.resubscribed <- "0x3"
+	for len(inner.resubscribed) > 0 {
+		time.Sleep(1 * time.Millisecond)
+	}
+	assert.Equal(t, "unread", <-s.Resubscribed())
+
+	rpcErr = fb.UnsubscribeAll(ctx)
+	assert.Nil(t, rpcErr)
+	assert.True(t, inner.unsubscribed.Load())
+	assert.Empty(t, fb.Subscriptions())
+	_, ok := <-s.Notifications()
+	assert.False(t, ok)
+
+	// Second unsubscribe is a no-op
+	assert.Nil(t, s.Unsubscribe(ctx))
+}
+
+func TestFailoverSubscribeRPCErrorNoFailover(t *testing.T) {
+	ctx, fb, targets, done := newTestFailover(t, FailoverOptions{}, 2)
+	defer done()
+
+	targets[0].subscribe = func(ctx context.Context) (Subscription, *RPCError) {
+		return nil, &RPCError{Message: "invalid params"}
+	}
+
+	_, rpcErr := fb.Subscribe(ctx, "badness")
+	assert.Regexp(t, "invalid params", rpcErr.Message)
+	assert.True(t, fb.targets[0].isHealthy())
+	assert.Empty(t, fb.Subscriptions())
+}
+
+func TestFailoverUnsubscribeAllError(t *testing.T) {
+	ctx, fb, targets, done := newTestFailover(t, FailoverOptions{}, 1)
+	defer done()
+
+	inner := newTestFailoverSub()
+	inner.unsubErr = &RPCError{Message: "pop"}
+	targets[0].subscribe = func(ctx context.Context) (Subscription, *RPCError) {
+		return inner, nil
+	}
+
+	_, rpcErr := fb.Subscribe(ctx, "newHeads")
+	assert.Nil(t, rpcErr)
+
+	rpcErr = fb.UnsubscribeAll(ctx)
+	assert.Regexp(t, "pop", rpcErr.Message)
+}
+
+func TestFailoverSubscriptionMovesToRecoveredBackend(t *testing.T) {
+	ctx, fb, targets, done := newTestFailover(t, FailoverOptions{}, 2)
+	defer done()
+
+	inner0 := newTestFailoverSub()
+	inner1 := newTestFailoverSub()
+	inner1.unsubErr = &RPCError{Message: "pop"}
+	targets[0].unhealthy.Store(true)
+	targets[0].subscribe = func(ctx context.Context) (Subscription, *RPCError) {
+		return inner0, nil
+	}
+	targets[1].subscribe = func(ctx context.Context) (Subscription, *RPCError) {
+		return inner1, nil
+	}
+
+	fb.checkHealth()
+	s, rpcErr := fb.Subscribe(ctx, "newHeads")
+	assert.Nil(t, rpcErr)
+	assert.Equal(t, Subscription(inner1), s.(*failoverSub).inner)
+
+	// Still unhealthy, so nothing moves
+	fb.checkHealth()
+	assert.Equal(t, Subscription(inner1), s.(*failoverSub).inner)
+
+	// The signal is dropped rather than blocking if the consumer has not read the last one
+	s.Resubscribed() <- "unread"
+	targets[0].unhealthy.Store(false)
+	fb.checkHealth()
+	assert.Equal(t, Subscription(inner0), s.(*failoverSub).inner)
+	assert.True(t, inner1.unsubscribed.Load())
+	assert.Equal(t, "unread", <-s.Resubscribed())
+
+	go func() {
+		inner0.notifications <- &RPCSubscriptionNotification{CurrentSubID: "0x1"}
+	}()
+	n := <-s.Notifications()
+	assert.Equal(t, "0x1", n.CurrentSubID)
+
+	assert.Nil(t, s.Unsubscribe(ctx))
+}
+
+func TestFailoverSubscriptionMovesToWSBackend(t *testing.T) {
+	ctx, fb, targets, done := newTestFailover(t, FailoverOptions{}, 2)
+	defer done()
+
+	inner0 := &sub{
+		rc:            &wsRPCClient{},
+		currentSubID:  "0x12345",
+		notifications: make(chan *RPCSubscriptionNotification),
+		resubscribed:  make(chan string, 1),
+	}
+	inner1 := newTestFailoverSub()
+	targets[0].subscribe = func(ctx context.Context) (Subscription, *RPCError) {
+		return inner0, nil
+	}
+	targets[1].subscribe = func(ctx context.Context) (Subscription, *RPCError) {
+		return inner1, nil
+	}
+
+	fb.setHealth(fb.targets[0], false, fmt.Errorf("pop"))
+	s, rpcErr := fb.Subscribe(ctx, "newHeads")
+	assert.Nil(t, rpcErr)
+
+	fb.setHealth(fb.targets[0], true, nil)
+	fb.rebalanceSubscriptions()
+	assert.Equal(t, Subscription(inner0), s.(*failoverSub).inner)
+	assert.Equal(t, "0x12345", <-s.Resubscribed())
+
+	close(inner0.notifications)
+	<-s.(*failoverSub).pumpDone
+}
+
+func TestFailoverStickySubscription(t *testing.T) {
+	ctx, fb, targets, done := newTestFailover(t, FailoverOptions{StickySubscriptions: true}, 2)
+	defer done()
+
+	inner1 := newTestFailoverSub()
+	targets[0].subscribe = func(ctx context.Context) (Subscription, *RPCError) {
+		assert.Fail(t, "should not move")
+		return nil, nil
+	}
+	targets[1].subscribe = func(ctx context.Context) (Subscription, *RPCError) {
+		return inner1, nil
+	}
+
+	fb.setHealth(fb.targets[0], false, fmt.Errorf("pop"))
+	s, rpcErr := fb.Subscribe(ctx, "newHeads")
+	assert.Nil(t, rpcErr)
+
+	fb.checkHealth()
+	assert.True(t, fb.targets[0].isHealthy())
+	assert.Equal(t, Subscription(inner1), s.(*failoverSub).inner)
+
+	assert.Nil(t, s.Unsubscribe(ctx))
+}
+
+func TestFailoverSubscriptionMoveFails(t *testing.T) {
+	ctx, fb, targets, done := newTestFailover(t, FailoverOptions{}, 2)
+	defer done()
+
+	inner1 := newTestFailoverSub()
+	targets[0].subscribe = func(ctx context.Context) (Subscription, *RPCError) {
+		return nil, &RPCError{Message: "pop"}
+	}
+	targets[1].subscribe = func(ctx context.Context) (Subscription, *RPCError) {
+		return inner1, nil
+	}
+
+	fb.setHealth(fb.targets[0], false, fmt.Errorf("pop"))
+	s, rpcErr := fb.Subscribe(ctx, "newHeads")
+	assert.Nil(t, rpcErr)
+
+	fb.checkHealth()
+	assert.Equal(t, Subscription(inner1), s.(*failoverSub).inner)
+	assert.False(t, inner1.unsubscribed.Load())
+
+	assert.Nil(t, s.Unsubscribe(ctx))
+}
+
+func TestFailoverPumpInnerClosed(t *testing.T) {
+	ctx, fb, targets, done := newTestFailover(t, FailoverOptions{}, 1)
+	defer done()
+
+	inner := newTestFailoverSub()
+	targets[0].subscribe = func(ctx context.Context) (Subscription, *RPCError) {
+		return inner, nil
+	}
+
+	s, rpcErr := fb.Subscribe(ctx, "newHeads")
+	assert.Nil(t, rpcErr)
+
+	close(inner.notifications)
+	<-s.(*failoverSub).pumpDone
+
+	assert.Nil(t, s.Unsubscribe(ctx))
+}
+
+func TestFailoverPumpStoppedWhileDelivering(t *testing.T) {
+	ctx, fb, targets, done := newTestFailover(t, FailoverOptions{}, 1)
+	defer done()
+
+	inner := newTestFailoverSub()
+	targets[0].subscribe = func(ctx context.Context) (Subscription, *RPCError) {
+		return inner, nil
+	}
+
+	s, rpcErr := fb.Subscribe(ctx, "newHeads")
+	assert.Nil(t, rpcErr)
+
+	// Nobody reads this, so the pump is blocked until we unsubscribe
+	inner.notifications <- &RPCSubscriptionNotification{CurrentSubID: "0x1"}
+
+	assert.Nil(t, s.Unsubscribe(ctx))
+}
+
+func TestFailoverBatchRequestAllFail(t *testing.T) {
+	ctx, fb, targets, done := newTestFailover(t, FailoverOptions{}, 1)
+	defer done()
+
+	targets[0].batchErr = testUnavailableErr()
+
+	_, err := fb.BatchRequest(ctx, []*RPCRequest{
+		{ID: fftypes.JSONAnyPtr("1"), Method: "eth_blockNumber"},
+	})
+	assert.Regexp(t, "FF22012", err)
+	assert.False(t, fb.targets[0].isHealthy())
+}
@@ -0,0 +1,80 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcbackend
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// RPCClientInterceptor hooks into every request the HTTP client sends to the backend, so an application
+// can add headers, rewrite requests, or record its own metrics. Any of the hooks can be nil.
+//
+// The hooks are called once for each JSON/RPC request, including each request in a batch, with the request
+// as it is sent to the backend (which has a request ID allocated by the client). Requests that are retried
+// are only passed to the hooks once.
+type RPCClientInterceptor struct {
+	// BeforeRequest can change the request before it is sent, and set headers on the HTTP request that carries
+	// it - which is shared by every request in a batch. Returning an error fails the request without it
+	// being sent, and without the other hooks being called.
+	BeforeRequest func(ctx context.Context, rpcReq *RPCRequest, header http.Header) error
+	// AfterResponse is called with the response of each request the backend responded to, which might be
+	// a JSON/RPC error
+	AfterResponse func(ctx context.Context, rpcReq *RPCRequest, rpcRes *RPCResponse, elapsed time.Duration)
+	// OnError is called for each request that failed without a response from the backend
+	OnError func(ctx context.Context, rpcReq *RPCRequest, err error)
+}
+
+// beforeRequest calls the interceptors in the order they are configured, returning the headers they set
+func (rc *RPCClient) beforeRequest(ctx context.Context, rpcReqs ...*RPCRequest) (http.Header, error) {
+	header := http.Header{}
+	for _, interceptor := range rc.interceptors {
+		if interceptor.BeforeRequest == nil {
+			continue
+		}
+		for _, rpcReq := range rpcReqs {
+			if err := interceptor.BeforeRequest(ctx, rpcReq, header); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return header, nil
+}
+
+func (rc *RPCClient) afterResponse(ctx context.Context, rpcReqs []*RPCRequest, rpcResponses []*RPCResponse, startTime time.Time) {
+	elapsed := time.Since(startTime)
+	for _, interceptor := range rc.interceptors {
+		if interceptor.AfterResponse == nil {
+			continue
+		}
+		for i, rpcReq := range rpcReqs {
+			interceptor.AfterResponse(ctx, rpcReq, rpcResponses[i], elapsed)
+		}
+	}
+}
+
+func (rc *RPCClient) onError(ctx context.Context, rpcReqs []*RPCRequest, err error) {
+	for _, interceptor := range rc.interceptors {
+		if interceptor.OnError == nil {
+			continue
+		}
+		for _, rpcReq := range rpcReqs {
+			interceptor.OnError(ctx, rpcReq, err)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcbackend
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/firefly-common/pkg/ffresty"
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-signer/internal/signerconfig"
+	"github.com/stretchr/testify/assert"
+)
+
+// testInterceptor records the calls to each hook, and adds a header and rewrites the method before each request
+type testInterceptor struct {
+	before []string
+	after  []string
+	errors []string
+}
+
+func (ti *testInterceptor) interceptor() *RPCClientInterceptor {
+	return &RPCClientInterceptor{
+		BeforeRequest: func(ctx context.Context, rpcReq *RPCRequest, header http.Header) error {
+			ti.before = append(ti.before, rpcReq.Method)
+			if rpcReq.Method == "fail_before" {
+				return fmt.Errorf("pop")
+			}
+			header.Add("X-Test-Methods", rpcReq.Method)
+			rpcReq.Method = "rewritten_" + rpcReq.Method
+			return nil
+		},
+		AfterResponse: func(ctx context.Context, rpcReq *RPCRequest, rpcRes *RPCResponse, elapsed time.Duration) {
+			ti.after = append(ti.after, fmt.Sprintf("%s=%s", rpcReq.Method, rpcRes.Message()))
+		},
+		OnError: func(ctx context.Context, rpcReq *RPCRequest, err error) {
+			ti.errors = append(ti.errors, rpcReq.Method)
+		},
+	}
+}
+
+// newTestInterceptorServer replies with the body, after checking the header set by the interceptor (with the
+// values set for each request in a batch joined)
+func newTestInterceptorServer(t *testing.T, header string, status int, body string) (context.Context, *RPCClient, *testInterceptor, func()) {
+	ctx, cancelCtx := context.WithCancel(context.Background())
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		assert.Equal(t, header, r.Header.Get("X-Test-Methods"))
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}))
+
+	signerconfig.Reset()
+	signerconfig.BackendConfig.Set(ffresty.HTTPConfigURL, server.URL)
+	c, err := ffresty.New(ctx, signerconfig.BackendConfig)
+	assert.NoError(t, err)
+
+	ti := &testInterceptor{}
+	rb := NewRPCClientWithOption(c, RPCClientOptions{
+		Interceptors: []*RPCClientInterceptor{{}, ti.interceptor()},
+	}).(*RPCClient)
+	return ctx, rb, ti, func() {
+		cancelCtx()
+		server.Close()
+	}
+}
+
+func TestInterceptorSyncRequest(t *testing.T) {
+	ctx, rb, ti, done := newTestInterceptorServer(t, "eth_blockNumber", 200, `{"jsonrpc":"2.0","id":1,"result":"0x1"}`)
+	defer done()
+
+	rpcRes, err := rb.SyncRequest(ctx, &RPCRequest{ID: fftypes.JSONAnyPtr(`"a"`), Method: "eth_blockNumber"})
+	assert.NoError(t, err)
+	assert.Equal(t, `"a"`, rpcRes.ID.String())
+	assert.Equal(t, []string{"eth_blockNumber"}, ti.before)
+	assert.Equal(t, []string{"rewritten_eth_blockNumber="}, ti.after)
+	assert.Empty(t, ti.errors)
+}
+
+func TestInterceptorSyncRequestErrorResponse(t *testing.T) {
+	ctx, rb, ti, done := newTestInterceptorServer(t, "eth_call", 200, `{"jsonrpc":"2.0","id":1,"error":{"code":3,"message":"reverted"}}`)
+	defer done()
+
+	_, err := rb.SyncRequest(ctx, &RPCRequest{ID: fftypes.JSONAnyPtr("1"), Method: "eth_call"})
+	assert.Regexp(t, "reverted", err)
+	assert.Equal(t, []string{"rewritten_eth_call=reverted"}, ti.after)
+	assert.Empty(t, ti.errors)
+}
+
+func TestInterceptorSyncRequestBeforeFails(t *testing.T) {
+	ctx, rb, ti, done := newTestInterceptorServer(t, "", 500, ``)
+	defer done()
+
+	rpcRes, err := rb.SyncRequest(ctx, &RPCRequest{ID: fftypes.JSONAnyPtr("1"), Method: "fail_before"})
+	assert.Regexp(t, "pop", err)
+	assert.Equal(t, "pop", rpcRes.Message())
+	assert.Empty(t, ti.after)
+	assert.Empty(t, ti.errors)
+}
+
+func TestInterceptorSyncRequestServerDown(t *testing.T) {
+	ctx, rb, ti, done := newTestInterceptorServer(t, "", 500, ``)
+	done()
+
+	_, err := rb.SyncRequest(ctx, &RPCRequest{ID: fftypes.JSONAnyPtr("1"), Method: "eth_blockNumber"})
+	assert.Regexp(t, "FF22012", err)
+	assert.Empty(t, ti.after)
+	assert.Equal(t, []string{"rewritten_eth_blockNumber"}, ti.errors)
+}
+
+func TestInterceptorBatchRequest(t *testing.T) {
+	ctx, rb, ti, done := newTestInterceptorServer(t, "eth_blockNumber, eth_chainId", 200,
+		`[{"jsonrpc":"2.0","id":"000000002","result":"0x539"},{"jsonrpc":"2.0","id":"000000001","error":{"code":-32000,"message":"pop"}}]`)
+	defer done()
+
+	rpcResponses, err := rb.BatchRequest(ctx, []*RPCRequest{
+		{ID: fftypes.JSONAnyPtr("1"), Method: "eth_blockNumber"},
+		{ID: fftypes.JSONAnyPtr("2"), Method: "eth_chainId"},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, rpcResponses, 2)
+	assert.Equal(t, []string{"eth_blockNumber", "eth_chainId"}, ti.before)
+	assert.Equal(t, []string{"rewritten_eth_blockNumber=pop", "rewritten_eth_chainId="}, ti.after)
+}
+
+func TestInterceptorBatchRequestErrorResponse(t *testing.T) {
+	ctx, rb, ti, done := newTestInterceptorServer(t, "eth_blockNumber", 500, `{"jsonrpc":"2.0","error":{"code":-32000,"message":"pop"}}`)
+	defer done()
+
+	_, err := rb.BatchRequest(ctx, []*RPCRequest{{ID: fftypes.JSONAnyPtr("1"), Method: "eth_blockNumber"}})
+	assert.Regexp(t, "pop", err)
+	assert.Equal(t, []string{"rewritten_eth_blockNumber=pop"}, ti.after)
+}
+
+func TestInterceptorBatchRequestFails(t *testing.T) {
+	ctx, rb, ti, done := newTestInterceptorServer(t, "", 500, ``)
+	done()
+
+	rpcResponses, err := rb.BatchRequest(ctx, []*RPCRequest{
+		{ID: fftypes.JSONAnyPtr("1"), Method: "eth_blockNumber"},
+		{ID: fftypes.JSONAnyPtr("2"), Method: "fail_before"},
+	})
+	assert.Regexp(t, "pop", err)
+	assert.Equal(t, "pop", rpcResponses[0].Message())
+
+	_, err = rb.BatchRequest(ctx, []*RPCRequest{{ID: fftypes.JSONAnyPtr("1"), Method: "eth_blockNumber"}})
+	assert.Regexp(t, "FF22012", err)
+	assert.Equal(t, []string{"rewritten_eth_blockNumber"}, ti.errors)
+	assert.Empty(t, ti.after)
+}
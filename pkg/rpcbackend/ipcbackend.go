@@ -0,0 +1,249 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcbackend
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-common/pkg/log"
+	"github.com/hyperledger/firefly-common/pkg/retry"
+	"github.com/hyperledger/firefly-common/pkg/wsclient"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/hyperledger/firefly-signer/pkg/debuglog"
+)
+
+// IPCConfig configures a connection to the IPC socket of a co-located node
+type IPCConfig struct {
+	// Path is the filesystem path of the UNIX domain socket, such as geth.ipc in the data directory of geth
+	Path string
+	// ConnectionTimeout is the maximum time for each connection attempt
+	ConnectionTimeout time.Duration
+	// InitialConnectAttempts is the number of times the first connection is retried before Connect fails
+	InitialConnectAttempts int
+	// InitialDelay and MaximumDelay control the backoff between connection attempts
+	InitialDelay time.Duration
+	MaximumDelay time.Duration
+	// DisableReconnect stops the client reconnecting when the connection is lost
+	DisableReconnect bool
+}
+
+// NewIPCRPCClient builds a client that communicates with a node over its IPC socket. This avoids
+// exposing the RPC endpoint of the node on any network port when the signer is co-located with it.
+//
+// The node exchanges the same JSON/RPC messages over IPC as it does over a WebSocket, so this is a
+// drop-in replacement for the WebSocket client, including subscriptions.
+func NewIPCRPCClient(ipcConf *IPCConfig) WebSocketRPCClient {
+	rc := newWSRPCClient(&wsclient.WSConfig{DisableReconnect: ipcConf.DisableReconnect})
+	rc.newClient = func(ctx context.Context, afterConnect wsclient.WSPostConnectHandler) (wsclient.WSClient, error) {
+		return newIPCClient(ctx, ipcConf, afterConnect), nil
+	}
+	return rc
+}
+
+// ipcClient is the equivalent of the WebSocket client for a UNIX domain socket, where messages are
+// a stream of JSON values with no other framing
+type ipcClient struct {
+	ctx          context.Context
+	conf         IPCConfig
+	retry        retry.Retry
+	afterConnect wsclient.WSPostConnectHandler
+	receive      chan []byte
+	closing      chan struct{}
+	sendMux      sync.Mutex // serializes writes, so messages are never interleaved
+	mux          sync.Mutex
+	conn         net.Conn
+	closed       bool
+}
+
+func newIPCClient(ctx context.Context, conf *IPCConfig, afterConnect wsclient.WSPostConnectHandler) *ipcClient {
+	c := &ipcClient{
+		ctx:  ctx,
+		conf: *conf,
+		retry: retry.Retry{
+			InitialDelay: conf.InitialDelay,
+			MaximumDelay: conf.MaximumDelay,
+		},
+		afterConnect: afterConnect,
+		receive:      make(chan []byte),
+		closing:      make(chan struct{}),
+	}
+	go func() {
+		select {
+		case <-ctx.Done():
+			c.Close()
+		case <-c.closing:
+		}
+	}()
+	return c
+}
+
+func (c *ipcClient) Connect() error {
+	if err := c.connect(true); err != nil {
+		return err
+	}
+	go c.receiveReconnectLoop()
+	return nil
+}
+
+func (c *ipcClient) connect(initial bool) error {
+	l := log.L(c.ctx)
+	return c.retry.DoCustomLog(c.ctx, func(attempt int) (bool, error) {
+		if c.isClosed() {
+			return false, i18n.NewError(c.ctx, i18n.MsgWSClosing)
+		}
+		retry := !initial || attempt < c.conf.InitialConnectAttempts
+		dialer := &net.Dialer{Timeout: c.conf.ConnectionTimeout}
+		conn, err := dialer.DialContext(c.ctx, "unix", c.conf.Path)
+		if err != nil {
+			l.Warnf("IPC %s connect attempt %d failed: %s", c.conf.Path, attempt, err)
+			return retry, i18n.WrapError(c.ctx, err, signermsgs.MsgIPCConnectFailed, c.conf.Path)
+		}
+		c.setConn(conn)
+		l.Infof("IPC %s connected", c.conf.Path)
+		return false, nil
+	})
+}
+
+func (c *ipcClient) setConn(conn net.Conn) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if c.closed {
+		// We were closed while connecting
+		_ = conn.Close()
+		return
+	}
+	c.conn = conn
+}
+
+func (c *ipcClient) getConn() net.Conn {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return c.conn
+}
+
+func (c *ipcClient) closeConn() {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if c.conn != nil {
+		_ = c.conn.Close()
+		c.conn = nil
+	}
+}
+
+func (c *ipcClient) isClosed() bool {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return c.closed
+}
+
+func (c *ipcClient) receiveReconnectLoop() {
+	l := log.L(c.ctx)
+	defer close(c.receive)
+	for {
+		if conn := c.getConn(); conn != nil {
+			// Like the WebSocket client, a failure in the reconnect processor drops the connection
+			if err := c.afterConnect(c.ctx, c); err == nil {
+				c.readLoop(conn)
+			}
+			c.closeConn()
+		}
+		if c.conf.DisableReconnect || c.isClosed() {
+			return
+		}
+		if err := c.connect(false); err != nil {
+			l.Debugf("IPC %s reconnect loop exiting: %s", c.conf.Path, err)
+			return
+		}
+	}
+}
+
+func (c *ipcClient) readLoop(conn net.Conn) {
+	l := log.L(c.ctx)
+	decoder := json.NewDecoder(conn)
+	for {
+		var message json.RawMessage
+		if err := decoder.Decode(&message); err != nil {
+			// We treat this as informational, as it's normal for the node to disconnect here
+			l.Infof("IPC %s closed: %s", c.conf.Path, err)
+			return
+		}
+		debuglog.Logf(c.ctx, debuglog.RPCWire, "IPC %s read: %s", c.conf.Path, message)
+		select {
+		case c.receive <- message:
+		case <-c.closing:
+			return
+		}
+	}
+}
+
+func (c *ipcClient) Send(ctx context.Context, message []byte) error {
+	conn := c.getConn()
+	if conn == nil {
+		return i18n.NewError(ctx, signermsgs.MsgIPCNotConnected, c.conf.Path)
+	}
+	c.sendMux.Lock()
+	defer c.sendMux.Unlock()
+	deadline, _ := ctx.Deadline() // zero for no deadline
+	_ = conn.SetWriteDeadline(deadline)
+	if _, err := conn.Write(message); err != nil {
+		// A partial write leaves the stream unusable, so we close the connection, which ends
+		// the read loop and reconnects
+		log.L(ctx).Errorf("IPC %s send failed: %s", c.conf.Path, err)
+		_ = conn.Close()
+		return err
+	}
+	return nil
+}
+
+func (c *ipcClient) Receive() <-chan []byte {
+	return c.receive
+}
+
+// ReceiveExt is not supported, as there are no message types on an IPC connection
+func (c *ipcClient) ReceiveExt() <-chan *wsclient.WSPayload {
+	return nil
+}
+
+func (c *ipcClient) URL() string {
+	return c.conf.Path
+}
+
+func (c *ipcClient) SetURL(path string) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.conf.Path = path
+}
+
+// SetHeader does nothing, as there are no headers on an IPC connection
+func (c *ipcClient) SetHeader(_, _ string) {}
+
+func (c *ipcClient) Close() {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if !c.closed {
+		c.closed = true
+		close(c.closing)
+		if c.conn != nil {
+			_ = c.conn.Close()
+		}
+	}
+}
@@ -0,0 +1,268 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcbackend
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/firefly-common/pkg/wsclient"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/stretchr/testify/assert"
+)
+
+// testIPCNode is a node listening on an IPC socket, which passes every message it receives to the
+// test, and hands the test each connection to write responses to
+type testIPCNode struct {
+	path     string
+	listener net.Listener
+	toServer chan string
+	conns    chan net.Conn
+}
+
+func newTestIPCNode(t *testing.T) *testIPCNode {
+	n := &testIPCNode{
+		path:     filepath.Join(t.TempDir(), "node.ipc"),
+		toServer: make(chan string, 10),
+		conns:    make(chan net.Conn, 10),
+	}
+	var err error
+	n.listener, err = net.Listen("unix", n.path)
+	assert.NoError(t, err)
+	go func() {
+		for {
+			conn, err := n.listener.Accept()
+			if err != nil {
+				return
+			}
+			n.conns <- conn
+			go func() {
+				decoder := json.NewDecoder(conn)
+				for {
+					var msg json.RawMessage
+					if decoder.Decode(&msg) != nil {
+						return
+					}
+					n.toServer <- string(msg)
+				}
+			}()
+		}
+	}()
+	return n
+}
+
+func newTestIPCRPC(t *testing.T, disableReconnect bool) (context.Context, *wsRPCClient, *testIPCNode, func()) {
+	n := newTestIPCNode(t)
+	rc := NewIPCRPCClient(&IPCConfig{
+		Path:             n.path,
+		InitialDelay:     time.Millisecond,
+		MaximumDelay:     time.Millisecond,
+		DisableReconnect: disableReconnect,
+	})
+	ctx, cancelCtx := context.WithCancel(context.Background())
+	return ctx, rc.(*wsRPCClient), n, func() {
+		rc.Close()
+		n.listener.Close()
+		cancelCtx()
+	}
+}
+
+func testIPCRespond(t *testing.T, n *testIPCNode, conn net.Conn, expectedMethod, result string) {
+	var req RPCRequest
+	err := json.Unmarshal([]byte(<-n.toServer), &req)
+	assert.NoError(t, err)
+	assert.Equal(t, expectedMethod, req.Method)
+	_, err = fmt.Fprintf(conn, `{"jsonrpc":"2.0","id":%s,"result":%s}`, req.ID, result)
+	assert.NoError(t, err)
+}
+
+func TestIPCRPCCallRPC(t *testing.T) {
+	ctx, rc, n, done := newTestIPCRPC(t, true)
+	defer done()
+
+	err := rc.Connect(ctx)
+	assert.NoError(t, err)
+	conn := <-n.conns
+
+	go testIPCRespond(t, n, conn, "eth_blockNumber", `"0x12345"`)
+	var blockNumber ethtypes.HexInteger
+	rpcErr := rc.CallRPC(ctx, &blockNumber, "eth_blockNumber")
+	assert.Nil(t, rpcErr)
+	assert.Equal(t, int64(0x12345), blockNumber.Int64())
+}
+
+func TestIPCRPCSubscribe(t *testing.T) {
+	ctx, rc, n, done := newTestIPCRPC(t, true)
+	defer done()
+
+	err := rc.Connect(ctx)
+	assert.NoError(t, err)
+	conn := <-n.conns
+
+	go func() {
+		testIPCRespond(t, n, conn, "eth_subscribe", `"0x9ce59a13059e417087c02d3236a0b1cc"`)
+		_, err := conn.Write([]byte(`{"jsonrpc":"2.0","method":"eth_subscription","params":{"subscription":"0x9ce59a13059e417087c02d3236a0b1cc","result":{"number":"0x1"}}}`))
+		assert.NoError(t, err)
+	}()
+	s, rpcErr := rc.Subscribe(ctx, "newHeads")
+	assert.Nil(t, rpcErr)
+
+	notification := <-s.Notifications()
+	assert.Equal(t, "0x9ce59a13059e417087c02d3236a0b1cc", notification.CurrentSubID)
+	assert.JSONEq(t, `{"number":"0x1"}`, notification.Result.String())
+}
+
+func TestIPCRPCConnectFail(t *testing.T) {
+	rc := NewIPCRPCClient(&IPCConfig{Path: filepath.Join(t.TempDir(), "missing.ipc")})
+	err := rc.Connect(context.Background())
+	assert.Regexp(t, "FF22158", err)
+}
+
+func TestIPCRPCReconnect(t *testing.T) {
+	ctx, rc, n, done := newTestIPCRPC(t, false)
+	defer done()
+
+	err := rc.Connect(ctx)
+	assert.NoError(t, err)
+
+	// The node dropping the connection, or sending something that is not JSON, causes a reconnect
+	conn := <-n.conns
+	conn.Close()
+	conn = <-n.conns
+	_, err = conn.Write([]byte(`!!!not json`))
+	assert.NoError(t, err)
+	conn = <-n.conns
+
+	go testIPCRespond(t, n, conn, "eth_blockNumber", `"0x1"`)
+	var blockNumber ethtypes.HexInteger
+	rpcErr := rc.CallRPC(ctx, &blockNumber, "eth_blockNumber")
+	assert.Nil(t, rpcErr)
+	assert.Equal(t, int64(1), blockNumber.Int64())
+}
+
+func TestIPCRPCNoReconnect(t *testing.T) {
+	ctx, rc, n, done := newTestIPCRPC(t, true)
+	defer done()
+
+	err := rc.Connect(ctx)
+	assert.NoError(t, err)
+	conn := <-n.conns
+	conn.Close()
+
+	ic := rc.client.(*ipcClient)
+	assert.Eventually(t, func() bool { return ic.getConn() == nil }, time.Second, time.Millisecond)
+	rpcErr := rc.CallRPC(ctx, nil, "eth_blockNumber")
+	assert.Regexp(t, "FF22159", rpcErr.Message)
+}
+
+func TestIPCRPCReconnectClosed(t *testing.T) {
+	ctx, rc, n, done := newTestIPCRPC(t, false)
+	defer done()
+
+	err := rc.Connect(ctx)
+	assert.NoError(t, err)
+	ic := rc.client.(*ipcClient)
+
+	// The node goes away entirely, so we keep trying to reconnect until we are closed
+	conn := <-n.conns
+	n.listener.Close()
+	conn.Close()
+	assert.Eventually(t, func() bool { return ic.getConn() == nil }, time.Second, time.Millisecond)
+	rc.Close()
+	for range ic.Receive() {
+	}
+}
+
+func TestIPCClientAfterConnectFail(t *testing.T) {
+	n := newTestIPCNode(t)
+	defer n.listener.Close()
+
+	c := newIPCClient(context.Background(), &IPCConfig{Path: n.path, DisableReconnect: true}, func(ctx context.Context, w wsclient.WSClient) error {
+		return fmt.Errorf("pop")
+	})
+	defer c.Close()
+	err := c.Connect()
+	assert.NoError(t, err)
+
+	// The connection is dropped
+	for range c.Receive() {
+	}
+	assert.Nil(t, c.getConn())
+}
+
+func TestIPCClientSendFail(t *testing.T) {
+	ctx, cancelCtx := context.WithTimeout(context.Background(), time.Second)
+	defer cancelCtx()
+	c := newIPCClient(ctx, &IPCConfig{}, nil)
+	defer c.Close()
+
+	conn, _ := net.Pipe()
+	conn.Close()
+	c.conn = conn
+	err := c.Send(ctx, []byte(`{}`))
+	assert.Error(t, err)
+}
+
+func TestIPCClientReadLoopClosing(t *testing.T) {
+	c := newIPCClient(context.Background(), &IPCConfig{}, nil)
+
+	conn, nodeConn := net.Pipe()
+	done := make(chan struct{})
+	go func() {
+		c.readLoop(conn)
+		close(done)
+	}()
+	_, err := nodeConn.Write([]byte(`{}`))
+	assert.NoError(t, err)
+	c.Close()
+	<-done
+}
+
+func TestIPCClientCloseWhileConnecting(t *testing.T) {
+	ctx, cancelCtx := context.WithCancel(context.Background())
+	c := newIPCClient(ctx, &IPCConfig{}, nil)
+
+	// Cancelling the context closes the client
+	cancelCtx()
+	assert.Eventually(t, c.isClosed, time.Second, time.Millisecond)
+
+	conn, nodeConn := net.Pipe()
+	c.setConn(conn)
+	assert.Nil(t, c.getConn())
+	_, err := nodeConn.Write([]byte(`{}`))
+	assert.Error(t, err)
+
+	err = c.connect(true)
+	assert.Regexp(t, "FF00147", err)
+	c.Close()
+}
+
+func TestIPCClientAccessors(t *testing.T) {
+	c := newIPCClient(context.Background(), &IPCConfig{Path: "/tmp/node1.ipc"}, nil)
+	defer c.Close()
+
+	assert.Equal(t, "/tmp/node1.ipc", c.URL())
+	c.SetURL("/tmp/node2.ipc")
+	assert.Equal(t, "/tmp/node2.ipc", c.URL())
+	c.SetHeader("Authorization", "ignored")
+	assert.Nil(t, c.ReceiveExt())
+}
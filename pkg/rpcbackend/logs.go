@@ -0,0 +1,222 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcbackend
+
+import (
+	"context"
+	"regexp"
+
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-common/pkg/log"
+	"github.com/hyperledger/firefly-common/pkg/retry"
+	"github.com/hyperledger/firefly-signer/pkg/abi"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+)
+
+const (
+	DefaultLogPageSize   = 1000
+	DefaultLogMaxRetries = 5
+)
+
+// Errors providers return for an eth_getLogs block range, or result, that is larger than they allow
+var logRangeTooLarge = regexp.MustCompile(`(?i)(block range|range (is )?too (large|wide|big)|limited to|more than \d+ results|too many (results|logs|blocks)|response size)`)
+
+// Errors providers return when requests are rate limited
+var logRequestThrottled = regexp.MustCompile(`(?i)(rate limit|too many requests|throttl|capacity)`)
+
+// LogFilter is the filter of eth_getLogs. Each position in topics matches any of the topics in it, or any topic
+// when it is nil.
+type LogFilter struct {
+	FromBlock string                        `json:"fromBlock,omitempty"`
+	ToBlock   string                        `json:"toBlock,omitempty"`
+	BlockHash ethtypes.HexBytes0xPrefix     `json:"blockHash,omitempty"`
+	Address   []ethtypes.Address0xHex       `json:"address,omitempty"`
+	Topics    [][]ethtypes.HexBytes0xPrefix `json:"topics,omitempty"`
+}
+
+type LogFetcherOptions struct {
+	// Events are the events logs are decoded with. When Topics is empty, only the logs of these events are fetched.
+	Events abi.ABI
+	// Addresses are the contracts to fetch the logs of - all contracts when empty
+	Addresses []ethtypes.Address0xHex
+	// Topics overrides the topics of the filter
+	Topics [][]ethtypes.HexBytes0xPrefix
+	// FromBlock is the first block to fetch the logs of
+	FromBlock uint64
+	// ToBlock is the last block to fetch the logs of, defaulting to the latest block when the fetch starts
+	ToBlock *uint64
+	// PageSize is the number of blocks fetched with each eth_getLogs. It is halved for the rest of the fetch
+	// each time the node rejects the block range, or the number of results, as too large.
+	PageSize uint64
+	// MaxRetries is the maximum number of retries of each eth_getLogs the node rate limits
+	MaxRetries int
+	// Retry is the delay between retries, which defaults to the delay of retries of an HTTP backend
+	Retry retry.Retry
+}
+
+// DecodedLog is a log, with the event and values it was decoded with. The event is nil when no event
+// in the ABI decodes the log.
+type DecodedLog struct {
+	*Log
+	Event  *abi.Entry
+	Values *abi.ComponentValue
+}
+
+// LogFetcher fetches the logs in a range of blocks with eth_getLogs, a page of blocks at a time, and decodes
+// them with the events in an ABI
+type LogFetcher struct {
+	client  *EthClient
+	options LogFetcherOptions
+	events  map[string][]*abi.Entry // by signature hash
+	topic0  []ethtypes.HexBytes0xPrefix
+}
+
+func NewLogFetcher(rpc RPC, options *LogFetcherOptions) *LogFetcher {
+	lf := &LogFetcher{
+		client:  NewEthClient(rpc),
+		options: *options,
+		events:  make(map[string][]*abi.Entry),
+	}
+	if lf.options.PageSize == 0 {
+		lf.options.PageSize = DefaultLogPageSize
+	}
+	if lf.options.MaxRetries == 0 {
+		lf.options.MaxRetries = DefaultLogMaxRetries
+	}
+	if lf.options.Retry.InitialDelay <= 0 {
+		lf.options.Retry.InitialDelay = DefaultRetryInitialDelay
+	}
+	if lf.options.Retry.MaximumDelay <= 0 {
+		lf.options.Retry.MaximumDelay = DefaultRetryMaximumDelay
+	}
+	for _, e := range lf.options.Events {
+		if e.Type != abi.Event {
+			continue
+		}
+		hash := e.SignatureHashBytes()
+		if _, ok := lf.events[hash.String()]; !ok {
+			lf.topic0 = append(lf.topic0, hash)
+		}
+		lf.events[hash.String()] = append(lf.events[hash.String()], e)
+	}
+	if len(lf.options.Topics) == 0 && len(lf.topic0) > 0 {
+		lf.options.Topics = [][]ethtypes.HexBytes0xPrefix{lf.topic0}
+	}
+	return lf
+}
+
+// Fetch calls the callback with each log in the range of blocks, in order. An error returned by the callback
+// ends the fetch, and is returned.
+func (lf *LogFetcher) Fetch(ctx context.Context, callback func(*DecodedLog) error) error {
+	var to uint64
+	if lf.options.ToBlock != nil {
+		to = *lf.options.ToBlock
+	} else {
+		blockNumber, rpcErr := lf.client.GetBlockNumber(ctx)
+		if rpcErr != nil {
+			return rpcErr.Error()
+		}
+		to = blockNumber.Uint64()
+	}
+
+	pageSize := lf.options.PageSize
+	for from := lf.options.FromBlock; from <= to; {
+		end := min(from+pageSize-1, to)
+		logs, tooLarge, err := lf.getLogs(ctx, from, end)
+		if tooLarge && end > from {
+			pageSize = max((end-from+1)/2, 1)
+			log.L(ctx).Infof("eth_getLogs of blocks %d-%d is too large, fetching %d blocks at a time: %s", from, end, pageSize, err)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		for _, l := range logs {
+			if err := callback(lf.decode(ctx, l)); err != nil {
+				return err
+			}
+		}
+		from = end + 1
+	}
+	return nil
+}
+
+// FetchChan fetches in the background, delivering each log on the returned channel, which is closed once
+// the fetch ends. The error that ended the fetch, or nil, is then delivered on the error channel.
+func (lf *LogFetcher) FetchChan(ctx context.Context) (<-chan *DecodedLog, <-chan error) {
+	logs := make(chan *DecodedLog)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(errs)
+		defer close(logs)
+		errs <- lf.Fetch(ctx, func(l *DecodedLog) error {
+			select {
+			case logs <- l:
+				return nil
+			case <-ctx.Done():
+				return i18n.NewError(ctx, i18n.MsgContextCanceled)
+			}
+		})
+	}()
+	return logs, errs
+}
+
+// getLogs fetches the logs of the blocks, retrying while the node rate limits the request. tooLarge is set
+// when the node rejects the block range, or the number of results, as too large.
+func (lf *LogFetcher) getLogs(ctx context.Context, from, to uint64) (logs []*Log, tooLarge bool, err error) {
+	filter := &LogFilter{
+		FromBlock: BlockNumber(from),
+		ToBlock:   BlockNumber(to),
+		Address:   lf.options.Addresses,
+		Topics:    lf.options.Topics,
+	}
+	err = lf.options.Retry.Do(ctx, "", func(attempt int) (bool, error) {
+		var rpcErr *RPCError
+		logs, rpcErr = lf.client.GetLogs(ctx, filter)
+		switch {
+		case rpcErr == nil:
+			return false, nil
+		case logRangeTooLarge.MatchString(rpcErr.Message):
+			tooLarge = true
+			return false, rpcErr.Error()
+		case attempt <= lf.options.MaxRetries && (rpcErr.Code == int64(RPCCodeLimitExceeded) || logRequestThrottled.MatchString(rpcErr.Message)):
+			log.L(ctx).Warnf("eth_getLogs of blocks %d-%d attempt %d rate limited (will retry): %s", from, to, attempt, rpcErr.Message)
+			return true, rpcErr.Error()
+		default:
+			return false, rpcErr.Error()
+		}
+	})
+	return logs, tooLarge, err
+}
+
+// decode decodes the log with the first event with its signature hash, and the same indexed inputs
+func (lf *LogFetcher) decode(ctx context.Context, l *Log) *DecodedLog {
+	decoded := &DecodedLog{Log: l}
+	if len(l.Topics) == 0 {
+		return decoded
+	}
+	for _, e := range lf.events[l.Topics[0].String()] {
+		if e.IndexedInputCount()+1 != len(l.Topics) {
+			continue
+		}
+		if values, err := e.DecodeEventDataCtx(ctx, l.Topics, l.Data); err == nil {
+			decoded.Event, decoded.Values = e, values
+			break
+		}
+	}
+	return decoded
+}
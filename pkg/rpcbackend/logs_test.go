@@ -0,0 +1,315 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcbackend
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/firefly-common/pkg/retry"
+	"github.com/hyperledger/firefly-signer/pkg/abi"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/stretchr/testify/assert"
+)
+
+const testTransferTopic = "0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"
+
+// ERC-20 and ERC-721 Transfer events have the same signature hash, and differ in whether the value is indexed
+var testTransferEvents = abi.ABI{
+	{Type: abi.Event, Name: "Transfer", Inputs: abi.ParameterArray{
+		{Name: "from", Type: "address", Indexed: true},
+		{Name: "to", Type: "address", Indexed: true},
+		{Name: "value", Type: "uint256"},
+	}},
+	{Type: abi.Event, Name: "Transfer", Inputs: abi.ParameterArray{
+		{Name: "from", Type: "address", Indexed: true},
+		{Name: "to", Type: "address", Indexed: true},
+		{Name: "tokenId", Type: "uint256", Indexed: true},
+	}},
+	{Type: abi.Function, Name: "transfer"},
+}
+
+// testRPC answers each call with the handler, returning its result as JSON
+type testRPC struct {
+	handler func(method string, params []interface{}) (interface{}, *RPCError)
+}
+
+func (r *testRPC) CallRPC(_ context.Context, result interface{}, method string, params ...interface{}) *RPCError {
+	res, rpcErr := r.handler(method, params)
+	if rpcErr != nil {
+		return rpcErr
+	}
+	b, _ := json.Marshal(res)
+	_ = json.Unmarshal(b, result)
+	return nil
+}
+
+func testAddressTopic(n int) string {
+	return fmt.Sprintf("0x%064x", n)
+}
+
+// testLogs returns a log in each block in the filter, alternating between ERC-20 and ERC-721 transfers
+func testLogs(filter *LogFilter) []map[string]interface{} {
+	from, to := testBlockRange(filter)
+	logs := []map[string]interface{}{}
+	for n := from; n <= to; n++ {
+		l := map[string]interface{}{
+			"address":     "0x497eedc4299dea2f2a364be10025d0ad0f702de3",
+			"blockNumber": fmt.Sprintf("0x%x", n),
+			"topics":      []string{testTransferTopic, testAddressTopic(1), testAddressTopic(2)},
+			"data":        fmt.Sprintf("0x%064x", n),
+		}
+		if n%2 == 1 {
+			l["topics"] = []string{testTransferTopic, testAddressTopic(1), testAddressTopic(2), fmt.Sprintf("0x%064x", n)}
+			l["data"] = "0x"
+		}
+		logs = append(logs, l)
+	}
+	return logs
+}
+
+func testBlockRange(filter *LogFilter) (from, to int64) {
+	from, _ = strconv.ParseInt(filter.FromBlock, 0, 64)
+	to, _ = strconv.ParseInt(filter.ToBlock, 0, 64)
+	return from, to
+}
+
+func testLogFilter(t *testing.T, params []interface{}) *LogFilter {
+	var filter LogFilter
+	b, _ := json.Marshal(params[0])
+	assert.NoError(t, json.Unmarshal(b, &filter))
+	return &filter
+}
+
+func newTestLogFetcher(handler func(method string, params []interface{}) (interface{}, *RPCError), options *LogFetcherOptions) *LogFetcher {
+	if options.Retry.InitialDelay == 0 {
+		options.Retry = retry.Retry{InitialDelay: time.Millisecond, MaximumDelay: time.Millisecond}
+	}
+	return NewLogFetcher(&testRPC{handler: handler}, options)
+}
+
+func TestNewLogFetcherDefaults(t *testing.T) {
+	lf := NewLogFetcher(&testRPC{}, &LogFetcherOptions{})
+	assert.Equal(t, uint64(DefaultLogPageSize), lf.options.PageSize)
+	assert.Equal(t, DefaultLogMaxRetries, lf.options.MaxRetries)
+	assert.Equal(t, DefaultRetryInitialDelay, lf.options.Retry.InitialDelay)
+	assert.Equal(t, DefaultRetryMaximumDelay, lf.options.Retry.MaximumDelay)
+	assert.Nil(t, lf.options.Topics)
+}
+
+func TestLogFetcherFetch(t *testing.T) {
+	var filters []*LogFilter
+	lf := newTestLogFetcher(func(method string, params []interface{}) (interface{}, *RPCError) {
+		if method == "eth_blockNumber" {
+			return "0x4", nil
+		}
+		assert.Equal(t, "eth_getLogs", method)
+		filter := testLogFilter(t, params)
+		filters = append(filters, filter)
+		logs := testLogs(filter)
+		if filter.FromBlock == "0x0" {
+			// A log of another event, and an anonymous log
+			logs = append(logs,
+				map[string]interface{}{"topics": []string{testAddressTopic(3)}, "data": "0x"},
+				map[string]interface{}{"topics": []string{}, "data": "0x"},
+			)
+		}
+		return logs, nil
+	}, &LogFetcherOptions{
+		Events:    testTransferEvents,
+		Addresses: []ethtypes.Address0xHex{*ethtypes.MustNewAddress("0x497eedc4299dea2f2a364be10025d0ad0f702de3")},
+		PageSize:  2,
+	})
+
+	var logs []*DecodedLog
+	err := lf.Fetch(context.Background(), func(l *DecodedLog) error {
+		logs = append(logs, l)
+		return nil
+	})
+	assert.NoError(t, err)
+
+	assert.Len(t, filters, 3)
+	assert.Equal(t, []string{"0x0", "0x2", "0x4"}, []string{filters[0].FromBlock, filters[1].FromBlock, filters[2].FromBlock})
+	assert.Equal(t, []string{"0x1", "0x3", "0x4"}, []string{filters[0].ToBlock, filters[1].ToBlock, filters[2].ToBlock})
+	assert.Equal(t, [][]ethtypes.HexBytes0xPrefix{{ethtypes.MustNewHexBytes0xPrefix(testTransferTopic)}}, filters[0].Topics)
+	assert.Len(t, filters[0].Address, 1)
+
+	assert.Len(t, logs, 7)
+	assert.Equal(t, testTransferEvents[0], logs[0].Event)
+	assert.Equal(t, int64(0), logs[0].Values.Children[2].Value.(interface{ Int64() int64 }).Int64())
+	assert.Equal(t, testTransferEvents[1], logs[1].Event)
+	assert.Equal(t, int64(1), logs[1].Values.Children[2].Value.(interface{ Int64() int64 }).Int64())
+	assert.Nil(t, logs[2].Event)
+	assert.Nil(t, logs[3].Event)
+	assert.Equal(t, int64(4), logs[6].BlockNumber.Int64())
+}
+
+func TestLogFetcherRangeTooLarge(t *testing.T) {
+	var ranges []string
+	toBlock := uint64(9)
+	lf := newTestLogFetcher(func(method string, params []interface{}) (interface{}, *RPCError) {
+		filter := testLogFilter(t, params)
+		from, to := testBlockRange(filter)
+		ranges = append(ranges, fmt.Sprintf("%d-%d", from, to))
+		if to-from >= 3 {
+			return nil, &RPCError{Code: int64(RPCCodeLimitExceeded), Message: "query returned more than 10000 results"}
+		}
+		return testLogs(filter), nil
+	}, &LogFetcherOptions{
+		Topics:    [][]ethtypes.HexBytes0xPrefix{nil, {ethtypes.MustNewHexBytes0xPrefix(testAddressTopic(1))}},
+		FromBlock: 2,
+		ToBlock:   &toBlock,
+	})
+
+	count := 0
+	err := lf.Fetch(context.Background(), func(l *DecodedLog) error {
+		assert.Equal(t, int64(count+2), l.BlockNumber.Int64())
+		assert.Nil(t, l.Event)
+		count++
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 8, count)
+	assert.Equal(t, []string{"2-9", "2-5", "2-3", "4-5", "6-7", "8-9"}, ranges)
+}
+
+func TestLogFetcherRangeTooLargeSingleBlock(t *testing.T) {
+	toBlock := uint64(0)
+	lf := newTestLogFetcher(func(method string, params []interface{}) (interface{}, *RPCError) {
+		return nil, &RPCError{Code: int64(RPCCodeInvalidRequest), Message: "Log response size exceeded"}
+	}, &LogFetcherOptions{ToBlock: &toBlock})
+
+	err := lf.Fetch(context.Background(), func(l *DecodedLog) error { return nil })
+	assert.Regexp(t, "response size", err)
+}
+
+func TestLogFetcherThrottled(t *testing.T) {
+	toBlock := uint64(0)
+	attempts := 0
+	lf := newTestLogFetcher(func(method string, params []interface{}) (interface{}, *RPCError) {
+		attempts++
+		switch attempts {
+		case 1:
+			return nil, &RPCError{Code: int64(RPCCodeLimitExceeded), Message: "limit exceeded"}
+		case 2:
+			return nil, &RPCError{Code: 429, Message: "Too Many Requests"}
+		default:
+			return testLogs(testLogFilter(t, params)), nil
+		}
+	}, &LogFetcherOptions{Events: testTransferEvents, ToBlock: &toBlock})
+
+	count := 0
+	err := lf.Fetch(context.Background(), func(l *DecodedLog) error {
+		count++
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, attempts)
+	assert.Equal(t, 1, count)
+}
+
+func TestLogFetcherThrottledMaxRetries(t *testing.T) {
+	toBlock := uint64(0)
+	attempts := 0
+	lf := newTestLogFetcher(func(method string, params []interface{}) (interface{}, *RPCError) {
+		attempts++
+		return nil, &RPCError{Code: int64(RPCCodeInternalError), Message: "rate limit reached"}
+	}, &LogFetcherOptions{ToBlock: &toBlock, MaxRetries: 2})
+
+	err := lf.Fetch(context.Background(), func(l *DecodedLog) error { return nil })
+	assert.Regexp(t, "rate limit", err)
+	assert.Equal(t, 3, attempts)
+}
+
+func TestLogFetcherThrottledCanceled(t *testing.T) {
+	toBlock := uint64(0)
+	ctx, cancelCtx := context.WithCancel(context.Background())
+	lf := newTestLogFetcher(func(method string, params []interface{}) (interface{}, *RPCError) {
+		cancelCtx()
+		return nil, &RPCError{Code: int64(RPCCodeLimitExceeded), Message: "limit exceeded"}
+	}, &LogFetcherOptions{ToBlock: &toBlock})
+
+	err := lf.Fetch(ctx, func(l *DecodedLog) error { return nil })
+	assert.Regexp(t, "FF00154", err)
+}
+
+func TestLogFetcherError(t *testing.T) {
+	toBlock := uint64(0)
+	lf := newTestLogFetcher(func(method string, params []interface{}) (interface{}, *RPCError) {
+		return nil, &RPCError{Code: int64(RPCCodeInternalError), Message: "pop"}
+	}, &LogFetcherOptions{ToBlock: &toBlock})
+
+	err := lf.Fetch(context.Background(), func(l *DecodedLog) error { return nil })
+	assert.Regexp(t, "pop", err)
+}
+
+func TestLogFetcherBlockNumberFail(t *testing.T) {
+	lf := newTestLogFetcher(func(method string, params []interface{}) (interface{}, *RPCError) {
+		return nil, &RPCError{Code: int64(RPCCodeInternalError), Message: "pop"}
+	}, &LogFetcherOptions{})
+
+	err := lf.Fetch(context.Background(), func(l *DecodedLog) error { return nil })
+	assert.Regexp(t, "pop", err)
+}
+
+func TestLogFetcherCallbackError(t *testing.T) {
+	toBlock := uint64(5)
+	calls := 0
+	lf := newTestLogFetcher(func(method string, params []interface{}) (interface{}, *RPCError) {
+		calls++
+		return testLogs(testLogFilter(t, params)), nil
+	}, &LogFetcherOptions{ToBlock: &toBlock, PageSize: 2})
+
+	err := lf.Fetch(context.Background(), func(l *DecodedLog) error { return fmt.Errorf("pop") })
+	assert.Regexp(t, "pop", err)
+	assert.Equal(t, 1, calls)
+}
+
+func TestLogFetcherFetchChan(t *testing.T) {
+	toBlock := uint64(3)
+	lf := newTestLogFetcher(func(method string, params []interface{}) (interface{}, *RPCError) {
+		return testLogs(testLogFilter(t, params)), nil
+	}, &LogFetcherOptions{Events: testTransferEvents, ToBlock: &toBlock, PageSize: 3})
+
+	logs, errs := lf.FetchChan(context.Background())
+	count := 0
+	for l := range logs {
+		assert.NotNil(t, l.Event)
+		count++
+	}
+	assert.NoError(t, <-errs)
+	assert.Equal(t, 4, count)
+}
+
+func TestLogFetcherFetchChanCanceled(t *testing.T) {
+	toBlock := uint64(3)
+	lf := newTestLogFetcher(func(method string, params []interface{}) (interface{}, *RPCError) {
+		return testLogs(testLogFilter(t, params)), nil
+	}, &LogFetcherOptions{ToBlock: &toBlock})
+
+	ctx, cancelCtx := context.WithCancel(context.Background())
+	logs, errs := lf.FetchChan(ctx)
+	<-logs
+	cancelCtx()
+	assert.Regexp(t, "FF00154", <-errs)
+	_, ok := <-logs
+	assert.False(t, ok)
+}
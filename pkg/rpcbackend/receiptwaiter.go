@@ -0,0 +1,155 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcbackend
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-common/pkg/log"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+)
+
+const defaultReceiptPollInterval = 1 * time.Second
+
+// Subscriber is an RPC that supports eth_subscribe, such as a WebSocketRPCClient
+type Subscriber interface {
+	Subscribe(ctx context.Context, params ...interface{}) (Subscription, *RPCError)
+}
+
+type ReceiptWaiterOptions struct {
+	// Confirmations is the number of blocks that must be mined on top of the block containing the transaction.
+	// Zero returns the receipt as soon as the transaction is mined.
+	Confirmations uint64
+	// PollInterval is how often to check for the receipt, which is also the fallback when notified of new blocks
+	PollInterval time.Duration
+	// Timeout is the maximum time to wait - zero waits for as long as the context allows
+	Timeout time.Duration
+}
+
+// ReceiptWaiter waits for a transaction to be mined, with a number of confirmations.
+//
+// When the RPC is a Subscriber, it checks on each newHeads notification, as well as polling.
+// A reorg that removes the transaction from the chain, or moves it into another block, restarts
+// the confirmations - so the receipt returned is always the one in the canonical chain at the time.
+type ReceiptWaiter struct {
+	client  *EthClient
+	options ReceiptWaiterOptions
+}
+
+func NewReceiptWaiter(rpc RPC, options *ReceiptWaiterOptions) *ReceiptWaiter {
+	rw := &ReceiptWaiter{
+		client:  NewEthClient(rpc),
+		options: *options,
+	}
+	if rw.options.PollInterval <= 0 {
+		rw.options.PollInterval = defaultReceiptPollInterval
+	}
+	return rw
+}
+
+// WaitForReceipt returns the receipt of the transaction once it has the configured confirmations,
+// or an error if the timeout or context ends first
+func (rw *ReceiptWaiter) WaitForReceipt(ctx context.Context, txHash ethtypes.HexBytes0xPrefix) (*Receipt, error) {
+	if rw.options.Timeout > 0 {
+		var cancelCtx context.CancelFunc
+		ctx, cancelCtx = context.WithTimeout(ctx, rw.options.Timeout)
+		defer cancelCtx()
+	}
+
+	var newHeads chan *RPCSubscriptionNotification
+	if subscriber, ok := rw.client.RPC.(Subscriber); ok {
+		sub, rpcErr := subscriber.Subscribe(ctx, "newHeads")
+		if rpcErr != nil {
+			log.L(ctx).Warnf("Polling for receipt of %s, as subscribing to new blocks failed: %s", txHash, rpcErr.Message)
+		} else {
+			defer func() {
+				_ = sub.Unsubscribe(context.WithoutCancel(ctx))
+			}()
+			newHeads = sub.Notifications()
+		}
+	}
+
+	ticker := time.NewTicker(rw.options.PollInterval)
+	defer ticker.Stop()
+	var lastSeen *Receipt
+	for {
+		receipt, confirmed := rw.checkReceipt(ctx, txHash, lastSeen)
+		if confirmed {
+			return receipt, nil
+		}
+		lastSeen = receipt
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return nil, i18n.NewError(ctx, signermsgs.MsgReceiptWaitTimeout, txHash)
+			}
+			return nil, i18n.NewError(ctx, i18n.MsgContextCanceled)
+		case <-ticker.C:
+		case <-newHeads:
+		}
+	}
+}
+
+// checkReceipt returns the receipt currently in the chain (if any), and whether it is confirmed.
+// Errors from the node are logged, and the check is retried on the next block or poll.
+func (rw *ReceiptWaiter) checkReceipt(ctx context.Context, txHash ethtypes.HexBytes0xPrefix, lastSeen *Receipt) (*Receipt, bool) {
+	receipt, rpcErr := rw.client.GetTransactionReceipt(ctx, txHash)
+	if rpcErr != nil {
+		log.L(ctx).Warnf("Failed to query receipt of %s: %s", txHash, rpcErr.Message)
+		return lastSeen, false
+	}
+	switch {
+	case receipt == nil && lastSeen != nil:
+		log.L(ctx).Infof("Transaction %s removed from block %s by a reorg", txHash, lastSeen.BlockNumber)
+		return nil, false
+	case receipt == nil:
+		return nil, false
+	case lastSeen != nil && !bytes.Equal(receipt.BlockHash, lastSeen.BlockHash):
+		log.L(ctx).Infof("Transaction %s moved from block %s to block %s by a reorg", txHash, lastSeen.BlockNumber, receipt.BlockNumber)
+	}
+
+	blockNumber := receipt.BlockNumber.Uint64()
+	if rw.options.Confirmations == 0 {
+		return receipt, true
+	}
+	head, rpcErr := rw.client.GetBlockNumber(ctx)
+	if rpcErr != nil {
+		log.L(ctx).Warnf("Failed to query block number: %s", rpcErr.Message)
+		return receipt, false
+	}
+	if uint64(head) < blockNumber+rw.options.Confirmations {
+		log.L(ctx).Debugf("Transaction %s in block %d has %d of %d confirmations", txHash, blockNumber, uint64(head)-min(uint64(head), blockNumber), rw.options.Confirmations)
+		return receipt, false
+	}
+
+	// Check the block of the receipt is still in the canonical chain, now that it is confirmed
+	block, rpcErr := rw.client.GetBlockByNumber(ctx, BlockNumber(blockNumber), false)
+	if rpcErr != nil {
+		log.L(ctx).Warnf("Failed to query block %d: %s", blockNumber, rpcErr.Message)
+		return receipt, false
+	}
+	if block == nil || !bytes.Equal(block.Hash, receipt.BlockHash) {
+		log.L(ctx).Infof("Block %d containing transaction %s replaced by a reorg", blockNumber, txHash)
+		return nil, false
+	}
+	return receipt, true
+}
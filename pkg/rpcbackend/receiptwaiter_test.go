@@ -0,0 +1,197 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcbackend
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/stretchr/testify/assert"
+)
+
+const testTxHash = "0x61ca9c99c1d752fb3bda568b8566edf33ba93585c64a970566e6dfb540a5cbc1"
+
+const (
+	testBlockHashA = "0xdc0818cf78f21a8e70579cb46a43643f78291264dda342ae31049421c82d21ae"
+	testBlockHashB = "0xe99e022112df268087ea7eafaf4790497fd21dbeeb6bd7a1721df161a6657a54"
+)
+
+func testReceipt(blockNumber int, blockHash string) string {
+	return fmt.Sprintf(`{"transactionHash":"%s","blockNumber":"0x%x","blockHash":"%s","status":"0x1"}`, testTxHash, blockNumber, blockHash)
+}
+
+func testBlock(blockNumber int, blockHash string) string {
+	return fmt.Sprintf(`{"number":"0x%x","hash":"%s"}`, blockNumber, blockHash)
+}
+
+// newTestChain returns a client of a server that returns the next scripted result of each method on each call,
+// repeating the last result once they run out. A "pop" result fails.
+func newTestChain(t *testing.T, results map[string][]string) (context.Context, *RPCClient, func()) {
+	var mux sync.Mutex
+	calls := make(map[string]int)
+	return newTestServer(t, func(rpcReq *RPCRequest) (int, *RPCResponse) {
+		mux.Lock()
+		defer mux.Unlock()
+		methodResults := results[rpcReq.Method]
+		assert.NotEmpty(t, methodResults, rpcReq.Method)
+		result := methodResults[min(calls[rpcReq.Method], len(methodResults)-1)]
+		calls[rpcReq.Method]++
+		if result == "pop" {
+			return http.StatusOK, &RPCResponse{JSONRpc: "2.0", ID: rpcReq.ID, Error: &RPCError{Code: int64(RPCCodeInternalError), Message: "pop"}}
+		}
+		return http.StatusOK, &RPCResponse{JSONRpc: "2.0", ID: rpcReq.ID, Result: fftypes.JSONAnyPtr(result)}
+	})
+}
+
+type testSubscription struct {
+	notifications chan *RPCSubscriptionNotification
+	unsubscribed  bool
+}
+
+func (ts *testSubscription) LocalID() *fftypes.UUID { return nil }
+func (ts *testSubscription) Notifications() chan *RPCSubscriptionNotification {
+	return ts.notifications
+}
+func (ts *testSubscription) Resubscribed() chan string { return nil }
+func (ts *testSubscription) Unsubscribe(_ context.Context) *RPCError {
+	ts.unsubscribed = true
+	return nil
+}
+
+type testSubscriber struct {
+	RPC
+	sub    *testSubscription
+	subErr *RPCError
+}
+
+func (ts *testSubscriber) Subscribe(_ context.Context, params ...interface{}) (Subscription, *RPCError) {
+	if ts.subErr != nil {
+		return nil, ts.subErr
+	}
+	if params[0] != "newHeads" {
+		panic("unexpected subscription")
+	}
+	return ts.sub, nil
+}
+
+func TestReceiptWaiterConfirmations(t *testing.T) {
+	ctx, rb, done := newTestChain(t, map[string][]string{
+		"eth_getTransactionReceipt": {"null", "pop", testReceipt(10, testBlockHashA)},
+		"eth_blockNumber":           {"pop", `"0xb"`, `"0xc"`},
+		"eth_getBlockByNumber":      {"pop", testBlock(10, testBlockHashA)},
+	})
+	defer done()
+
+	rw := NewReceiptWaiter(rb, &ReceiptWaiterOptions{Confirmations: 2, PollInterval: 1 * time.Millisecond})
+	receipt, err := rw.WaitForReceipt(ctx, ethtypes.MustNewHexBytes0xPrefix(testTxHash))
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(10), receipt.BlockNumber.Uint64())
+	assert.True(t, receipt.Succeeded())
+}
+
+func TestReceiptWaiterNoConfirmations(t *testing.T) {
+	ctx, rb, done := newTestChain(t, map[string][]string{
+		"eth_getTransactionReceipt": {testReceipt(10, testBlockHashA)},
+	})
+	defer done()
+
+	rw := NewReceiptWaiter(rb, &ReceiptWaiterOptions{})
+	assert.Equal(t, defaultReceiptPollInterval, rw.options.PollInterval)
+	receipt, err := rw.WaitForReceipt(ctx, ethtypes.MustNewHexBytes0xPrefix(testTxHash))
+	assert.NoError(t, err)
+	assert.Equal(t, testBlockHashA, receipt.BlockHash.String())
+}
+
+func TestReceiptWaiterReorgs(t *testing.T) {
+	ctx, rb, done := newTestChain(t, map[string][]string{
+		"eth_getTransactionReceipt": {
+			testReceipt(10, testBlockHashA), // not yet confirmed
+			"null",                          // removed by a reorg
+			testReceipt(10, testBlockHashA), // back in the original block, still not confirmed
+			testReceipt(11, testBlockHashB), // moved to another block, which is replaced before the receipt is returned
+			testReceipt(11, testBlockHashB), // confirmed
+		},
+		"eth_blockNumber":      {`"0xa"`, `"0xa"`, `"0xc"`},
+		"eth_getBlockByNumber": {"null", testBlock(11, testBlockHashB)},
+	})
+	defer done()
+
+	rw := NewReceiptWaiter(rb, &ReceiptWaiterOptions{Confirmations: 1, PollInterval: 1 * time.Millisecond})
+	receipt, err := rw.WaitForReceipt(ctx, ethtypes.MustNewHexBytes0xPrefix(testTxHash))
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(11), receipt.BlockNumber.Uint64())
+	assert.Equal(t, testBlockHashB, receipt.BlockHash.String())
+}
+
+func TestReceiptWaiterTimeout(t *testing.T) {
+	ctx, rb, done := newTestChain(t, map[string][]string{
+		"eth_getTransactionReceipt": {"null"},
+	})
+	defer done()
+
+	rw := NewReceiptWaiter(rb, &ReceiptWaiterOptions{PollInterval: 1 * time.Millisecond, Timeout: 10 * time.Millisecond})
+	_, err := rw.WaitForReceipt(ctx, ethtypes.MustNewHexBytes0xPrefix(testTxHash))
+	assert.Regexp(t, "FF22174.*"+testTxHash, err)
+}
+
+func TestReceiptWaiterCanceled(t *testing.T) {
+	_, rb, done := newTestChain(t, map[string][]string{
+		"eth_getTransactionReceipt": {"null"},
+	})
+	defer done()
+
+	ctx, cancelCtx := context.WithCancel(context.Background())
+	cancelCtx()
+	rw := NewReceiptWaiter(rb, &ReceiptWaiterOptions{PollInterval: 1 * time.Hour})
+	_, err := rw.WaitForReceipt(ctx, ethtypes.MustNewHexBytes0xPrefix(testTxHash))
+	assert.Regexp(t, "FF00154", err)
+}
+
+func TestReceiptWaiterNewHeads(t *testing.T) {
+	ctx, rb, done := newTestChain(t, map[string][]string{
+		"eth_getTransactionReceipt": {"null", testReceipt(10, testBlockHashA)},
+	})
+	defer done()
+
+	sub := &testSubscription{notifications: make(chan *RPCSubscriptionNotification, 1)}
+	sub.notifications <- &RPCSubscriptionNotification{Result: fftypes.JSONAnyPtr(testBlock(10, testBlockHashA))}
+
+	// Only a new block can wake the waiter, as the poll interval is much longer than the test
+	rw := NewReceiptWaiter(&testSubscriber{RPC: rb, sub: sub}, &ReceiptWaiterOptions{PollInterval: 1 * time.Hour})
+	receipt, err := rw.WaitForReceipt(ctx, ethtypes.MustNewHexBytes0xPrefix(testTxHash))
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(10), receipt.BlockNumber.Uint64())
+	assert.True(t, sub.unsubscribed)
+}
+
+func TestReceiptWaiterSubscribeFailPolls(t *testing.T) {
+	ctx, rb, done := newTestChain(t, map[string][]string{
+		"eth_getTransactionReceipt": {"null", testReceipt(10, testBlockHashA)},
+	})
+	defer done()
+
+	rw := NewReceiptWaiter(&testSubscriber{RPC: rb, subErr: &RPCError{Message: "not supported"}}, &ReceiptWaiterOptions{PollInterval: 1 * time.Millisecond})
+	receipt, err := rw.WaitForReceipt(ctx, ethtypes.MustNewHexBytes0xPrefix(testTxHash))
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(10), receipt.BlockNumber.Uint64())
+}
@@ -0,0 +1,174 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcbackend
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/hyperledger/firefly-common/pkg/log"
+	"github.com/hyperledger/firefly-common/pkg/retry"
+)
+
+const (
+	DefaultRetryInitialDelay = 250 * time.Millisecond
+	DefaultRetryMaximumDelay = 30 * time.Second
+)
+
+// DefaultIdempotentMethods are the read-only JSON/RPC methods that are safe to send to the
+// backend more than once, so are retried on any failure to get a response
+var DefaultIdempotentMethods = []string{
+	"eth_accounts",
+	"eth_blockNumber",
+	"eth_call",
+	"eth_chainId",
+	"eth_estimateGas",
+	"eth_feeHistory",
+	"eth_gasPrice",
+	"eth_getBalance",
+	"eth_getBlockByHash",
+	"eth_getBlockByNumber",
+	"eth_getBlockReceipts",
+	"eth_getBlockTransactionCountByHash",
+	"eth_getBlockTransactionCountByNumber",
+	"eth_getCode",
+	"eth_getLogs",
+	"eth_getProof",
+	"eth_getStorageAt",
+	"eth_getTransactionByBlockHashAndIndex",
+	"eth_getTransactionByBlockNumberAndIndex",
+	"eth_getTransactionByHash",
+	"eth_getTransactionCount",
+	"eth_getTransactionReceipt",
+	"eth_maxPriorityFeePerGas",
+	"eth_syncing",
+	"net_listening",
+	"net_peerCount",
+	"net_version",
+	"web3_clientVersion",
+}
+
+// RPCClientRetryOptions configures retry of requests to an HTTP backend.
+//
+// Requests containing only idempotent methods are retried on any failure to get a response,
+// and on HTTP statuses indicating the backend is temporarily unavailable. Requests containing
+// any other method (such as eth_sendRawTransaction) are only retried when the connection could
+// not be established, so we know the backend never received the request.
+//
+// This replaces the retry of the underlying Resty client, which should be disabled.
+type RPCClientRetryOptions struct {
+	// MaxRetries is the maximum number of retries after the first attempt
+	MaxRetries int
+	// InitialDelay is the delay before the first retry, which doubles on each subsequent retry
+	InitialDelay time.Duration
+	// MaximumDelay is the maximum delay between retries
+	MaximumDelay time.Duration
+	// IdempotentMethods overrides DefaultIdempotentMethods, when set
+	IdempotentMethods []string
+}
+
+type retryPolicy struct {
+	retry             retry.Retry
+	maxRetries        int
+	idempotentMethods map[string]bool
+}
+
+func newRetryPolicy(options *RPCClientRetryOptions) *retryPolicy {
+	rp := &retryPolicy{
+		retry: retry.Retry{
+			InitialDelay: options.InitialDelay,
+			MaximumDelay: options.MaximumDelay,
+		},
+		maxRetries:        options.MaxRetries,
+		idempotentMethods: make(map[string]bool),
+	}
+	if rp.retry.InitialDelay <= 0 {
+		rp.retry.InitialDelay = DefaultRetryInitialDelay
+	}
+	if rp.retry.MaximumDelay <= 0 {
+		rp.retry.MaximumDelay = DefaultRetryMaximumDelay
+	}
+	idempotentMethods := options.IdempotentMethods
+	if len(idempotentMethods) == 0 {
+		idempotentMethods = DefaultIdempotentMethods
+	}
+	for _, m := range idempotentMethods {
+		rp.idempotentMethods[m] = true
+	}
+	return rp
+}
+
+// isConnectError is true when the request failed before anything was written to the backend
+func isConnectError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+func isUnavailableStatus(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+func (rp *retryPolicy) allIdempotent(methods []string) bool {
+	for _, m := range methods {
+		if !rp.idempotentMethods[m] {
+			return false
+		}
+	}
+	return true
+}
+
+func (rp *retryPolicy) shouldRetry(methods []string, res *resty.Response, err error) bool {
+	switch {
+	case err != nil && isConnectError(err):
+		return true
+	case err != nil:
+		return rp.allIdempotent(methods)
+	default:
+		return isUnavailableStatus(res.StatusCode()) && rp.allIdempotent(methods)
+	}
+}
+
+// post sends a request to the backend, retrying according to the policy (if there is one).
+// The doPost function must build a fresh request on each call.
+func (rc *RPCClient) post(ctx context.Context, rpcTraceID string, methods []string, doPost func() (*resty.Response, error)) (res *resty.Response, err error) {
+	if rc.retryPolicy == nil {
+		return doPost()
+	}
+	errRetry := errors.New("retry")
+	_ = rc.retryPolicy.retry.Do(ctx, "", func(attempt int) (bool, error) {
+		res, err = doPost()
+		if attempt > rc.retryPolicy.maxRetries || !rc.retryPolicy.shouldRetry(methods, res, err) {
+			return false, nil
+		}
+		if err != nil {
+			log.L(ctx).Warnf("RPC[%s] <-- attempt %d failed (will retry): %s", rpcTraceID, attempt, err)
+		} else {
+			log.L(ctx).Warnf("RPC[%s] <-- attempt %d failed (will retry): [%d]", rpcTraceID, attempt, res.StatusCode())
+		}
+		return true, errRetry
+	})
+	return res, err
+}
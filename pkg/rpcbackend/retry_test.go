@@ -0,0 +1,238 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcbackend
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/hyperledger/firefly-common/pkg/ffresty"
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-signer/internal/signerconfig"
+	"github.com/stretchr/testify/assert"
+)
+
+// testRetryHandler is called for each attempt, and returns false to drop the connection without a response
+type testRetryHandler func(attempt int32, body []byte, w http.ResponseWriter) bool
+
+func newTestRetryServer(t *testing.T, handler testRetryHandler, retryOptions *RPCClientRetryOptions) (*RPCClient, *int32, func()) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		if !handler(atomic.AddInt32(&attempts, 1), body, w) {
+			conn, _, err := w.(http.Hijacker).Hijack()
+			assert.NoError(t, err)
+			conn.Close()
+		}
+	}))
+
+	signerconfig.Reset()
+	signerconfig.BackendConfig.Set(ffresty.HTTPConfigURL, server.URL)
+	c, err := ffresty.New(context.Background(), signerconfig.BackendConfig)
+	assert.NoError(t, err)
+
+	rb := NewRPCClientWithOption(c, RPCClientOptions{RetryOptions: retryOptions}).(*RPCClient)
+	return rb, &attempts, server.Close
+}
+
+func testRetryOptions() *RPCClientRetryOptions {
+	return &RPCClientRetryOptions{
+		MaxRetries:   2,
+		InitialDelay: 1 * time.Millisecond,
+		MaximumDelay: 1 * time.Millisecond,
+	}
+}
+
+func writeTestRetryResult(t *testing.T, body []byte, w http.ResponseWriter) bool {
+	var rpcReq RPCRequest
+	err := json.Unmarshal(body, &rpcReq)
+	assert.NoError(t, err)
+	w.Header().Add("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(&RPCResponse{JSONRpc: "2.0", ID: rpcReq.ID, Result: fftypes.JSONAnyPtr(`"0x12345"`)})
+	return true
+}
+
+func TestNewRetryPolicyDefaults(t *testing.T) {
+	rp := newRetryPolicy(&RPCClientRetryOptions{})
+	assert.Equal(t, DefaultRetryInitialDelay, rp.retry.InitialDelay)
+	assert.Equal(t, DefaultRetryMaximumDelay, rp.retry.MaximumDelay)
+	assert.True(t, rp.allIdempotent([]string{"eth_call", "eth_getTransactionReceipt"}))
+	assert.False(t, rp.allIdempotent([]string{"eth_call", "eth_sendRawTransaction"}))
+
+	rp = newRetryPolicy(&RPCClientRetryOptions{IdempotentMethods: []string{"custom_read"}})
+	assert.True(t, rp.allIdempotent([]string{"custom_read"}))
+	assert.False(t, rp.allIdempotent([]string{"eth_call"}))
+}
+
+func TestIsConnectError(t *testing.T) {
+	_, err := resty.New().R().Post("http://127.0.0.1:1")
+	assert.True(t, isConnectError(err))
+	assert.False(t, isConnectError(fmt.Errorf("pop")))
+}
+
+func TestRetryIdempotentOnUnavailableStatus(t *testing.T) {
+	rb, attempts, done := newTestRetryServer(t, func(attempt int32, body []byte, w http.ResponseWriter) bool {
+		if attempt == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return true
+		}
+		return writeTestRetryResult(t, body, w)
+	}, testRetryOptions())
+	defer done()
+
+	var result string
+	rpcErr := rb.CallRPC(context.Background(), &result, "eth_blockNumber")
+	assert.Nil(t, rpcErr)
+	assert.Equal(t, "0x12345", result)
+	assert.Equal(t, int32(2), atomic.LoadInt32(attempts))
+}
+
+func TestRetryIdempotentOnDroppedConnection(t *testing.T) {
+	rb, attempts, done := newTestRetryServer(t, func(attempt int32, body []byte, w http.ResponseWriter) bool {
+		if attempt == 1 {
+			return false
+		}
+		return writeTestRetryResult(t, body, w)
+	}, testRetryOptions())
+	defer done()
+
+	var result string
+	rpcErr := rb.CallRPC(context.Background(), &result, "eth_getBalance", "0x1", "latest")
+	assert.Nil(t, rpcErr)
+	assert.Equal(t, int32(2), atomic.LoadInt32(attempts))
+}
+
+func TestRetryIdempotentMaxRetries(t *testing.T) {
+	rb, attempts, done := newTestRetryServer(t, func(attempt int32, body []byte, w http.ResponseWriter) bool {
+		w.WriteHeader(http.StatusTooManyRequests)
+		return true
+	}, testRetryOptions())
+	defer done()
+
+	var result string
+	rpcErr := rb.CallRPC(context.Background(), &result, "eth_blockNumber")
+	assert.Regexp(t, "FF22012.*429", rpcErr.Message)
+	assert.Equal(t, int32(3), atomic.LoadInt32(attempts))
+}
+
+func TestRetryNonIdempotentNotRetriedAfterWrite(t *testing.T) {
+	rb, attempts, done := newTestRetryServer(t, func(attempt int32, body []byte, w http.ResponseWriter) bool {
+		return false
+	}, testRetryOptions())
+	defer done()
+
+	var result string
+	rpcErr := rb.CallRPC(context.Background(), &result, "eth_sendRawTransaction", "0xfeedbeef")
+	assert.Regexp(t, "FF22012", rpcErr.Message)
+	assert.Equal(t, int32(1), atomic.LoadInt32(attempts))
+}
+
+func TestRetryNonIdempotentNotRetriedOnStatus(t *testing.T) {
+	rb, attempts, done := newTestRetryServer(t, func(attempt int32, body []byte, w http.ResponseWriter) bool {
+		w.WriteHeader(http.StatusBadGateway)
+		return true
+	}, testRetryOptions())
+	defer done()
+
+	var result string
+	rpcErr := rb.CallRPC(context.Background(), &result, "eth_sendRawTransaction", "0xfeedbeef")
+	assert.Regexp(t, "FF22012.*502", rpcErr.Message)
+	assert.Equal(t, int32(1), atomic.LoadInt32(attempts))
+}
+
+func TestRetryNonIdempotentOnConnectError(t *testing.T) {
+	var attempts int32
+	c := resty.New().
+		SetBaseURL("http://127.0.0.1:1").
+		OnBeforeRequest(func(c *resty.Client, r *resty.Request) error {
+			atomic.AddInt32(&attempts, 1)
+			return nil
+		})
+	rb := NewRPCClientWithOption(c, RPCClientOptions{RetryOptions: testRetryOptions()})
+
+	var result string
+	rpcErr := rb.CallRPC(context.Background(), &result, "eth_sendRawTransaction", "0xfeedbeef")
+	assert.Regexp(t, "FF22012", rpcErr.Message)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&attempts))
+}
+
+func TestRetryNotOnRPCError(t *testing.T) {
+	rb, attempts, done := newTestRetryServer(t, func(attempt int32, body []byte, w http.ResponseWriter) bool {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":"1","error":{"code":-32000,"message":"execution reverted"}}`))
+		return true
+	}, testRetryOptions())
+	defer done()
+
+	var result string
+	rpcErr := rb.CallRPC(context.Background(), &result, "eth_call", map[string]interface{}{})
+	assert.Regexp(t, "execution reverted", rpcErr.Message)
+	assert.Equal(t, int32(1), atomic.LoadInt32(attempts))
+}
+
+func TestRetryBatchRequest(t *testing.T) {
+	rb, attempts, done := newTestRetryServer(t, func(attempt int32, body []byte, w http.ResponseWriter) bool {
+		if attempt == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return true
+		}
+		var rpcReqs []*RPCRequest
+		err := json.Unmarshal(body, &rpcReqs)
+		assert.NoError(t, err)
+		w.Header().Add("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode([]*RPCResponse{
+			{JSONRpc: "2.0", ID: rpcReqs[0].ID, Result: fftypes.JSONAnyPtr(`"0x1"`)},
+			{JSONRpc: "2.0", ID: rpcReqs[1].ID, Result: fftypes.JSONAnyPtr(`"0x2"`)},
+		})
+		return true
+	}, testRetryOptions())
+	defer done()
+
+	rpcResponses, err := rb.BatchRequest(context.Background(), []*RPCRequest{
+		{ID: fftypes.JSONAnyPtr("1"), Method: "eth_blockNumber"},
+		{ID: fftypes.JSONAnyPtr("2"), Method: "eth_chainId"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, `"0x1"`, rpcResponses[0].Result.String())
+	assert.Equal(t, `"0x2"`, rpcResponses[1].Result.String())
+	assert.Equal(t, int32(2), atomic.LoadInt32(attempts))
+}
+
+func TestRetryBatchRequestNonIdempotent(t *testing.T) {
+	rb, attempts, done := newTestRetryServer(t, func(attempt int32, body []byte, w http.ResponseWriter) bool {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return true
+	}, testRetryOptions())
+	defer done()
+
+	_, err := rb.BatchRequest(context.Background(), []*RPCRequest{
+		{ID: fftypes.JSONAnyPtr("1"), Method: "eth_blockNumber"},
+		{ID: fftypes.JSONAnyPtr("2"), Method: "eth_sendRawTransaction"},
+	})
+	assert.Regexp(t, "FF22012.*503", err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(attempts))
+}
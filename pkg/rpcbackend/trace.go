@@ -0,0 +1,252 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcbackend
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-common/pkg/log"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/hyperledger/firefly-signer/pkg/abi"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+)
+
+// CallFrame is a call made by a transaction, with the calls it made in turn, in the format of the
+// callTracer of debug_traceTransaction
+type CallFrame struct {
+	Type         string                    `json:"type"` // such as CALL, DELEGATECALL, STATICCALL, CREATE or SELFDESTRUCT
+	From         *ethtypes.Address0xHex    `json:"from"`
+	To           *ethtypes.Address0xHex    `json:"to,omitempty"`
+	Value        *ethtypes.HexInteger      `json:"value,omitempty"`
+	Gas          *ethtypes.HexInteger      `json:"gas,omitempty"`
+	GasUsed      *ethtypes.HexInteger      `json:"gasUsed,omitempty"`
+	Input        ethtypes.HexBytes0xPrefix `json:"input,omitempty"`
+	Output       ethtypes.HexBytes0xPrefix `json:"output,omitempty"` // the revert data, when the call reverted
+	Error        string                    `json:"error,omitempty"`
+	RevertReason string                    `json:"revertReason,omitempty"`
+	Calls        []*CallFrame              `json:"calls,omitempty"`
+}
+
+// Failed is true for a call that reverted, or otherwise failed
+func (cf *CallFrame) Failed() bool {
+	return cf.Error != ""
+}
+
+// parityTrace is an entry in the flat list of calls returned by trace_transaction
+type parityTrace struct {
+	Type   string `json:"type"` // call, create or suicide
+	Action struct {
+		CallType      string                    `json:"callType,omitempty"`
+		From          *ethtypes.Address0xHex    `json:"from,omitempty"`
+		To            *ethtypes.Address0xHex    `json:"to,omitempty"`
+		Value         *ethtypes.HexInteger      `json:"value,omitempty"`
+		Gas           *ethtypes.HexInteger      `json:"gas,omitempty"`
+		Input         ethtypes.HexBytes0xPrefix `json:"input,omitempty"`
+		Init          ethtypes.HexBytes0xPrefix `json:"init,omitempty"`
+		Address       *ethtypes.Address0xHex    `json:"address,omitempty"`
+		RefundAddress *ethtypes.Address0xHex    `json:"refundAddress,omitempty"`
+		Balance       *ethtypes.HexInteger      `json:"balance,omitempty"`
+	} `json:"action"`
+	Result *struct {
+		GasUsed *ethtypes.HexInteger      `json:"gasUsed,omitempty"`
+		Output  ethtypes.HexBytes0xPrefix `json:"output,omitempty"`
+		Address *ethtypes.Address0xHex    `json:"address,omitempty"`
+	} `json:"result,omitempty"`
+	Error        string `json:"error,omitempty"`
+	TraceAddress []int  `json:"traceAddress"`
+}
+
+func (pt *parityTrace) callFrame() *CallFrame {
+	a := &pt.Action
+	cf := &CallFrame{
+		Type:  strings.ToUpper(pt.Type),
+		From:  a.From,
+		To:    a.To,
+		Value: a.Value,
+		Gas:   a.Gas,
+		Input: a.Input,
+		Error: pt.Error,
+	}
+	switch pt.Type {
+	case "call":
+		cf.Type = strings.ToUpper(a.CallType)
+	case "create":
+		cf.Input = a.Init
+	case "suicide":
+		cf.Type = "SELFDESTRUCT"
+		cf.From, cf.To, cf.Value = a.Address, a.RefundAddress, a.Balance
+	}
+	if pt.Result != nil {
+		cf.GasUsed = pt.Result.GasUsed
+		cf.Output = pt.Result.Output
+		if pt.Result.Address != nil {
+			cf.To = pt.Result.Address
+		}
+	}
+	return cf
+}
+
+// TraceTransaction returns the calls made by a mined transaction, using debug_traceTransaction with the callTracer
+// (supported by geth and most clients derived from it)
+func (ec *EthClient) TraceTransaction(ctx context.Context, txHash ethtypes.HexBytes0xPrefix) (*CallFrame, *RPCError) {
+	var result CallFrame
+	if rpcErr := ec.CallRPC(ctx, &result, "debug_traceTransaction", txHash, map[string]interface{}{"tracer": "callTracer"}); rpcErr != nil {
+		return nil, rpcErr
+	}
+	return &result, nil
+}
+
+// TraceTransactionParity returns the calls made by a mined transaction, using trace_transaction (supported by
+// clients such as Erigon, Nethermind and Besu), with the flat list of calls it returns built into a tree
+func (ec *EthClient) TraceTransactionParity(ctx context.Context, txHash ethtypes.HexBytes0xPrefix) (*CallFrame, error) {
+	var traces []*parityTrace
+	if rpcErr := ec.CallRPC(ctx, &traces, "trace_transaction", txHash); rpcErr != nil {
+		return nil, rpcErr.Error()
+	}
+	var root *CallFrame
+	// The traces are in depth first order, so the parent of each trace is the last trace at the previous depth
+	var parents []*CallFrame
+	for _, pt := range traces {
+		if pt.Type == "reward" {
+			continue
+		}
+		depth := len(pt.TraceAddress)
+		if depth > len(parents) || (depth == 0 && root != nil) {
+			return nil, i18n.NewError(ctx, signermsgs.MsgTraceInvalid, txHash, fmt.Sprintf("unexpected trace address %v", pt.TraceAddress))
+		}
+		cf := pt.callFrame()
+		if depth == 0 {
+			root = cf
+		} else {
+			parent := parents[depth-1]
+			parent.Calls = append(parent.Calls, cf)
+		}
+		parents = append(parents[:depth], cf)
+	}
+	if root == nil {
+		return nil, i18n.NewError(ctx, signermsgs.MsgTraceInvalid, txHash, "no calls")
+	}
+	return root, nil
+}
+
+// DecodedCallFrame is a call, with the function its input and output were decoded with, and the error its revert data
+// was decoded with when it failed. The function and error are nil when no ABI decodes the call.
+type DecodedCallFrame struct {
+	*CallFrame
+	Function     *abi.Entry
+	Inputs       *abi.ComponentValue
+	Outputs      *abi.ComponentValue
+	RevertError  *abi.Entry
+	RevertValues *abi.ComponentValue
+	Calls        []*DecodedCallFrame
+}
+
+// CallDecoder decodes the calls of a transaction with a registry of ABIs. ABIs registered for an address are used for
+// the calls to that address, before the ABIs registered for any address.
+type CallDecoder struct {
+	byAddress map[ethtypes.Address0xHex]abi.ABI
+	any       abi.ABI
+}
+
+func NewCallDecoder() *CallDecoder {
+	return &CallDecoder{
+		byAddress: make(map[ethtypes.Address0xHex]abi.ABI),
+	}
+}
+
+// AddABI registers an ABI for the calls to the addresses, or for the calls to any address when there are none
+func (cd *CallDecoder) AddABI(a abi.ABI, addresses ...ethtypes.Address0xHex) *CallDecoder {
+	if len(addresses) == 0 {
+		cd.any = append(cd.any, a...)
+	}
+	for _, addr := range addresses {
+		cd.byAddress[addr] = append(cd.byAddress[addr], a...)
+	}
+	return cd
+}
+
+func (cd *CallDecoder) abiFor(addr *ethtypes.Address0xHex) abi.ABI {
+	if addr == nil {
+		return cd.any
+	}
+	return append(append(abi.ABI{}, cd.byAddress[*addr]...), cd.any...)
+}
+
+// Decode decodes the call, and each of the calls it made in turn
+func (cd *CallDecoder) Decode(ctx context.Context, cf *CallFrame) *DecodedCallFrame {
+	dcf := &DecodedCallFrame{
+		CallFrame: cf,
+		Calls:     make([]*DecodedCallFrame, len(cf.Calls)),
+	}
+	a := cd.abiFor(cf.To)
+	if len(cf.Input) >= 4 && cf.Type != "CREATE" && cf.Type != "CREATE2" {
+		for _, e := range a {
+			if e.IsFunction() && bytes.Equal(e.FunctionSelectorBytes(), cf.Input[0:4]) {
+				inputs, err := e.DecodeCallDataCtx(ctx, cf.Input)
+				if err != nil {
+					log.L(ctx).Debugf("Call input does not decode with %s: %s", e, err)
+					continue
+				}
+				dcf.Function, dcf.Inputs = e, inputs
+				break
+			}
+		}
+	}
+	if cf.Failed() {
+		if e, values, ok := a.ParseErrorCtx(ctx, cf.Output); ok {
+			dcf.RevertError, dcf.RevertValues = e, values
+		}
+	} else if dcf.Function != nil {
+		outputs, err := dcf.Function.Outputs.DecodeABIDataCtx(ctx, cf.Output, 0)
+		if err != nil {
+			log.L(ctx).Debugf("Call output does not decode with %s: %s", dcf.Function, err)
+		} else {
+			dcf.Outputs = outputs
+		}
+	}
+	for i, call := range cf.Calls {
+		dcf.Calls[i] = cd.Decode(ctx, call)
+	}
+	return dcf
+}
+
+// RevertString formats the error the call reverted with, such as `Error("insufficient balance")`, or returns
+// the revert reason reported by the node when no ABI decodes the revert data
+func (dcf *DecodedCallFrame) RevertString(ctx context.Context) string {
+	if dcf.RevertError != nil {
+		return abi.FormatErrorStringCtx(ctx, dcf.RevertError, dcf.RevertValues)
+	}
+	return dcf.RevertReason
+}
+
+// Origin returns the call the revert of a failed call originated in, following the failed calls it made whose
+// revert data it reverted with in turn. Returns nil for a call that did not fail.
+func (dcf *DecodedCallFrame) Origin() *DecodedCallFrame {
+	if !dcf.Failed() {
+		return nil
+	}
+	for _, call := range dcf.Calls {
+		if call.Failed() && bytes.Equal(call.Output, dcf.Output) {
+			return call.Origin()
+		}
+	}
+	return dcf
+}
@@ -0,0 +1,232 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcbackend
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/hyperledger/firefly-signer/pkg/abi"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testRouterAddr = "0x497eedc4299dea2f2a364be10025d0ad0f702de3"
+	testTokenAddr  = "0x1f185718734552d08278aa70f804580bab5fd2b4"
+)
+
+var testRouterABI = abi.ABI{
+	{Type: abi.Function, Name: "swap", Inputs: abi.ParameterArray{{Name: "amount", Type: "uint256"}}, Outputs: abi.ParameterArray{{Name: "out", Type: "uint256"}}},
+	{Type: abi.Error, Name: "TooLittleReceived", Inputs: abi.ParameterArray{{Name: "received", Type: "uint256"}}},
+}
+
+var testTokenABI = abi.ABI{
+	{Type: abi.Function, Name: "transferFrom", Inputs: abi.ParameterArray{
+		{Name: "from", Type: "address"},
+		{Name: "to", Type: "address"},
+		{Name: "value", Type: "uint256"},
+	}, Outputs: abi.ParameterArray{{Type: "bool"}}},
+	{Type: abi.Function, Name: "balanceOf", Inputs: abi.ParameterArray{{Name: "owner", Type: "address"}}, Outputs: abi.ParameterArray{{Type: "uint256"}}},
+}
+
+func testEncode(t *testing.T, e *abi.Entry, values ...interface{}) ethtypes.HexBytes0xPrefix {
+	b, err := e.EncodeCallDataValues(values)
+	assert.NoError(t, err)
+	return b
+}
+
+func testOutput(t *testing.T, pa abi.ParameterArray, values ...interface{}) ethtypes.HexBytes0xPrefix {
+	b, err := pa.EncodeABIDataValues(values)
+	assert.NoError(t, err)
+	return b
+}
+
+// testTrace is a swap, that reverts with the revert data of a transferFrom it makes, after a balanceOf
+func testTrace(t *testing.T) *CallFrame {
+	revert := testEncode(t, &abi.Entry{Type: abi.Error, Name: "Error", Inputs: abi.ParameterArray{{Type: "string"}}}, "insufficient allowance")
+	return &CallFrame{
+		Type:   "CALL",
+		From:   ethtypes.MustNewAddress("0xfb075bb99f2aa4c49955bf703509a227d7a12248"),
+		To:     ethtypes.MustNewAddress(testRouterAddr),
+		Input:  testEncode(t, testRouterABI[0], 100),
+		Output: revert,
+		Error:  "execution reverted",
+		Calls: []*CallFrame{
+			{
+				Type:   "STATICCALL",
+				From:   ethtypes.MustNewAddress(testRouterAddr),
+				To:     ethtypes.MustNewAddress(testTokenAddr),
+				Input:  testEncode(t, testTokenABI[1], testRouterAddr),
+				Output: testOutput(t, testTokenABI[1].Outputs, 5),
+			},
+			{
+				Type:   "CALL",
+				From:   ethtypes.MustNewAddress(testRouterAddr),
+				To:     ethtypes.MustNewAddress(testTokenAddr),
+				Input:  testEncode(t, testTokenABI[0], "0xfb075bb99f2aa4c49955bf703509a227d7a12248", testRouterAddr, 100),
+				Output: revert,
+				Error:  "execution reverted",
+			},
+		},
+	}
+}
+
+func TestTraceTransaction(t *testing.T) {
+	trace := testTrace(t)
+	ec := NewEthClient(&testRPC{handler: func(method string, params []interface{}) (interface{}, *RPCError) {
+		assert.Equal(t, "debug_traceTransaction", method)
+		assert.Equal(t, map[string]interface{}{"tracer": "callTracer"}, params[1])
+		return trace, nil
+	}})
+	result, rpcErr := ec.TraceTransaction(context.Background(), ethtypes.MustNewHexBytes0xPrefix("0x1234"))
+	assert.Nil(t, rpcErr)
+	assert.Equal(t, trace, result)
+	assert.True(t, result.Failed())
+	assert.False(t, result.Calls[0].Failed())
+
+	ec = NewEthClient(&testRPC{handler: func(method string, params []interface{}) (interface{}, *RPCError) {
+		return nil, &RPCError{Message: "pop"}
+	}})
+	_, rpcErr = ec.TraceTransaction(context.Background(), ethtypes.MustNewHexBytes0xPrefix("0x1234"))
+	assert.Regexp(t, "pop", rpcErr.Message)
+}
+
+func TestTraceTransactionParity(t *testing.T) {
+	var traces interface{}
+	err := json.Unmarshal([]byte(`[
+		{"type": "call", "traceAddress": [], "error": "Reverted",
+		 "action": {"callType": "call", "from": "0xfb075bb99f2aa4c49955bf703509a227d7a12248", "to": "`+testRouterAddr+`", "gas": "0x5208", "input": "0x1234", "value": "0x0"}},
+		{"type": "create", "traceAddress": [0],
+		 "action": {"from": "`+testRouterAddr+`", "init": "0x6080", "value": "0x1"},
+		 "result": {"address": "`+testTokenAddr+`", "gasUsed": "0x10", "code": "0x60"}},
+		{"type": "call", "traceAddress": [0, 0],
+		 "action": {"callType": "delegatecall", "from": "`+testTokenAddr+`", "to": "`+testRouterAddr+`", "input": "0xabcd"},
+		 "result": {"gasUsed": "0x20", "output": "0x01"}},
+		{"type": "suicide", "traceAddress": [1],
+		 "action": {"address": "`+testTokenAddr+`", "refundAddress": "`+testRouterAddr+`", "balance": "0x1"}},
+		{"type": "reward", "traceAddress": [], "action": {}}
+	]`), &traces)
+	assert.NoError(t, err)
+	ec := NewEthClient(&testRPC{handler: func(method string, params []interface{}) (interface{}, *RPCError) {
+		assert.Equal(t, "trace_transaction", method)
+		return traces, nil
+	}})
+
+	root, err := ec.TraceTransactionParity(context.Background(), ethtypes.MustNewHexBytes0xPrefix("0x1234"))
+	assert.NoError(t, err)
+	assert.Equal(t, "CALL", root.Type)
+	assert.Equal(t, "Reverted", root.Error)
+	assert.Equal(t, "0x1234", root.Input.String())
+	assert.Len(t, root.Calls, 2)
+
+	create := root.Calls[0]
+	assert.Equal(t, "CREATE", create.Type)
+	assert.Equal(t, testTokenAddr, create.To.String())
+	assert.Equal(t, "0x6080", create.Input.String())
+	assert.Equal(t, int64(0x10), create.GasUsed.Int64())
+	assert.Len(t, create.Calls, 1)
+	assert.Equal(t, "DELEGATECALL", create.Calls[0].Type)
+	assert.Equal(t, "0x01", create.Calls[0].Output.String())
+
+	selfDestruct := root.Calls[1]
+	assert.Equal(t, "SELFDESTRUCT", selfDestruct.Type)
+	assert.Equal(t, testTokenAddr, selfDestruct.From.String())
+	assert.Equal(t, testRouterAddr, selfDestruct.To.String())
+	assert.Equal(t, int64(1), selfDestruct.Value.Int64())
+}
+
+func TestTraceTransactionParityErrors(t *testing.T) {
+	ctx := context.Background()
+	txHash := ethtypes.MustNewHexBytes0xPrefix("0x1234")
+	for _, traces := range []string{
+		`[]`,
+		`[{"type": "call", "traceAddress": [0], "action": {}}]`,
+		`[{"type": "call", "traceAddress": [], "action": {}}, {"type": "call", "traceAddress": [], "action": {}}]`,
+		`[{"type": "call", "traceAddress": [], "action": {}}, {"type": "call", "traceAddress": [0, 0], "action": {}}]`,
+	} {
+		var result interface{}
+		assert.NoError(t, json.Unmarshal([]byte(traces), &result))
+		ec := NewEthClient(&testRPC{handler: func(method string, params []interface{}) (interface{}, *RPCError) {
+			return result, nil
+		}})
+		_, err := ec.TraceTransactionParity(ctx, txHash)
+		assert.Regexp(t, "FF22277.*0x1234", err)
+	}
+
+	ec := NewEthClient(&testRPC{handler: func(method string, params []interface{}) (interface{}, *RPCError) {
+		return nil, &RPCError{Message: "pop"}
+	}})
+	_, err := ec.TraceTransactionParity(ctx, txHash)
+	assert.Regexp(t, "pop", err)
+}
+
+func TestCallDecoder(t *testing.T) {
+	ctx := context.Background()
+	cd := NewCallDecoder().
+		AddABI(testRouterABI, *ethtypes.MustNewAddress(testRouterAddr)).
+		AddABI(testTokenABI)
+
+	root := cd.Decode(ctx, testTrace(t))
+	assert.Equal(t, "swap", root.Function.Name)
+	assert.Equal(t, "100", root.Inputs.Children[0].Value.(*big.Int).String())
+	assert.Nil(t, root.Outputs)
+	assert.Equal(t, "Error", root.RevertError.Name)
+	assert.Equal(t, `Error("insufficient allowance")`, root.RevertString(ctx))
+
+	balanceOf := root.Calls[0]
+	assert.Equal(t, "balanceOf", balanceOf.Function.Name)
+	assert.Equal(t, "5", balanceOf.Outputs.Children[0].Value.(*big.Int).String())
+	assert.Nil(t, balanceOf.Origin())
+
+	transferFrom := root.Calls[1]
+	assert.Equal(t, "transferFrom", transferFrom.Function.Name)
+	assert.Len(t, transferFrom.Inputs.Children, 3)
+	assert.Same(t, transferFrom, root.Origin())
+}
+
+func TestCallDecoderNotDecoded(t *testing.T) {
+	ctx := context.Background()
+	// The router ABI is only registered for the router, so not used for a call to the token
+	cd := NewCallDecoder().AddABI(testRouterABI, *ethtypes.MustNewAddress(testRouterAddr))
+
+	trace := testTrace(t)
+	trace.Calls[1].Input = testEncode(t, testRouterABI[0], 100)
+	trace.Calls[1].Output = ethtypes.MustNewHexBytes0xPrefix("0xdeadbeef")
+	trace.Calls[1].RevertReason = "node reason"
+	// Output that does not decode
+	trace.Output = nil
+	trace.Error = ""
+	trace.Calls = append(trace.Calls, &CallFrame{Type: "CREATE", Input: testEncode(t, testRouterABI[0], 1)})
+
+	root := cd.Decode(ctx, trace)
+	assert.Equal(t, "swap", root.Function.Name)
+	assert.Nil(t, root.Outputs)
+	assert.Nil(t, root.Calls[0].Function)
+	assert.Nil(t, root.Calls[1].Function)
+	assert.Nil(t, root.Calls[1].RevertError)
+	assert.Equal(t, "node reason", root.Calls[1].RevertString(ctx))
+	assert.Same(t, root.Calls[1], root.Calls[1].Origin())
+	assert.Nil(t, root.Calls[2].Function)
+
+	// Input with the selector of the function, that does not decode
+	trace.Input = trace.Input[0:8]
+	root = cd.Decode(ctx, trace)
+	assert.Nil(t, root.Function)
+}
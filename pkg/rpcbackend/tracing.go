@@ -0,0 +1,62 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcbackend
+
+import (
+	"context"
+
+	"github.com/go-resty/resty/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Spans are created using the global OpenTelemetry TracerProvider, and trace context is
+// propagated to HTTP backends using the global TextMapPropagator. Both are no-ops until
+// the application registers an implementation with the otel package.
+const tracerName = "github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+
+const attrBatchSize = attribute.Key("rpc.jsonrpc.batch_size")
+
+func startClientSpan(ctx context.Context, spanName string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, spanName,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(append([]attribute.KeyValue{semconv.RPCSystemKey.String("jsonrpc")}, attrs...)...),
+	)
+}
+
+// endSpan records the outcome of a JSON/RPC request on the span, and ends it
+func endSpan(span trace.Span, rpcRes *RPCResponse, err error) {
+	if rpcRes != nil && rpcRes.Error != nil && rpcRes.Error.Code != 0 {
+		span.SetAttributes(semconv.RPCJsonrpcErrorCodeKey.Int64(rpcRes.Error.Code))
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// newRequest builds a request to the HTTP backend, carrying the trace context from ctx in its headers
+func (rc *RPCClient) newRequest(ctx context.Context) *resty.Request {
+	r := rc.client.R().SetContext(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(r.Header))
+	return r
+}
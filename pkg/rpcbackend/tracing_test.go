@@ -0,0 +1,133 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcbackend
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func newTestTracing(t *testing.T) *tracetest.SpanRecorder {
+	sr := tracetest.NewSpanRecorder()
+	origTP, origPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(origTP)
+		otel.SetTextMapPropagator(origPropagator)
+	})
+	return sr
+}
+
+func spanAttrs(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	return attrs
+}
+
+func TestTracingSyncRequestPropagatesTraceContext(t *testing.T) {
+	sr := newTestTracing(t)
+
+	traceparents := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparents <- r.Header.Get("traceparent")
+		w.Header().Add("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":"000000001","result":"0x12345"}`))
+	}))
+	defer server.Close()
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "parent")
+	rb := NewRPCClient(resty.New().SetBaseURL(server.URL))
+	rpcRes, err := rb.SyncRequest(ctx, &RPCRequest{ID: fftypes.JSONAnyPtr("1"), Method: "eth_blockNumber"})
+	assert.NoError(t, err)
+	assert.Equal(t, `"0x12345"`, rpcRes.Result.String())
+	parent.End()
+
+	spans := sr.Ended()
+	assert.Len(t, spans, 2)
+	span := spans[0]
+	assert.Equal(t, "eth_blockNumber", span.Name())
+	assert.Equal(t, trace.SpanKindClient, span.SpanKind())
+	assert.Equal(t, parent.SpanContext().TraceID(), span.SpanContext().TraceID())
+	assert.Equal(t, parent.SpanContext().SpanID(), span.Parent().SpanID())
+	attrs := spanAttrs(span)
+	assert.Equal(t, "jsonrpc", attrs["rpc.system"].AsString())
+	assert.Equal(t, "eth_blockNumber", attrs["rpc.method"].AsString())
+
+	// The backend sees the client span as the parent
+	assert.Regexp(t, "^00-"+span.SpanContext().TraceID().String()+"-"+span.SpanContext().SpanID().String()+"-01$", <-traceparents)
+}
+
+func TestTracingSyncRequestError(t *testing.T) {
+	sr := newTestTracing(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":"000000001","error":{"code":-32000,"message":"reverted"}}`))
+	}))
+	defer server.Close()
+
+	rb := NewRPCClient(resty.New().SetBaseURL(server.URL))
+	_, err := rb.SyncRequest(context.Background(), &RPCRequest{ID: fftypes.JSONAnyPtr("1"), Method: "eth_call"})
+	assert.Regexp(t, "reverted", err)
+
+	spans := sr.Ended()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	assert.Equal(t, "reverted", spans[0].Status().Description)
+	assert.Equal(t, int64(-32000), spanAttrs(spans[0])["rpc.jsonrpc.error_code"].AsInt64())
+}
+
+func TestTracingBatchRequest(t *testing.T) {
+	sr := newTestTracing(t)
+
+	traceparents := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparents <- r.Header.Get("traceparent")
+		w.Header().Add("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[{"jsonrpc":"2.0","id":"000000001","result":"0x1"},{"jsonrpc":"2.0","id":"000000002","result":"0x2"}]`))
+	}))
+	defer server.Close()
+
+	rb := NewRPCClient(resty.New().SetBaseURL(server.URL))
+	_, err := rb.BatchRequest(context.Background(), []*RPCRequest{
+		{ID: fftypes.JSONAnyPtr("1"), Method: "eth_blockNumber"},
+		{ID: fftypes.JSONAnyPtr("2"), Method: "eth_chainId"},
+	})
+	assert.NoError(t, err)
+
+	spans := sr.Ended()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, "batch", spans[0].Name())
+	assert.Equal(t, int64(2), spanAttrs(spans[0])["rpc.jsonrpc.batch_size"].AsInt64())
+	assert.Contains(t, <-traceparents, spans[0].SpanContext().SpanID().String())
+}
@@ -28,7 +28,8 @@ import (
 	"github.com/hyperledger/firefly-common/pkg/log"
 	"github.com/hyperledger/firefly-common/pkg/wsclient"
 	"github.com/hyperledger/firefly-signer/internal/signermsgs"
-	"github.com/sirupsen/logrus"
+	"github.com/hyperledger/firefly-signer/pkg/debuglog"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
 )
 
 // WebSocketRPCClient performs communication over a websocket with an Ethereum JSON/RPC endpoint
@@ -37,7 +38,7 @@ import (
 // - Manages subscriptions with a local ID, so they re-established automatically after reconnect
 // - Allows synchronous exchange over the WebSocket so you don't have to maintain a separate HTTP connection too
 type WebSocketRPCClient interface {
-	RPC
+	Backend
 	Subscribe(ctx context.Context, params ...interface{}) (sub Subscription, error *RPCError)
 	Subscriptions() []Subscription
 	UnsubscribeAll(ctx context.Context) (error *RPCError)
@@ -47,6 +48,14 @@ type WebSocketRPCClient interface {
 
 // NewRPCClient Constructor
 func NewWSRPCClient(wsConf *wsclient.WSConfig) WebSocketRPCClient {
+	rc := newWSRPCClient(wsConf)
+	rc.newClient = func(ctx context.Context, afterConnect wsclient.WSPostConnectHandler) (wsclient.WSClient, error) {
+		return wsclient.New(ctx, &rc.wsConf, nil, afterConnect)
+	}
+	return rc
+}
+
+func newWSRPCClient(wsConf *wsclient.WSConfig) *wsRPCClient {
 	return &wsRPCClient{
 		wsConf:             *wsConf,
 		calls:              make(map[string]chan *RPCResponse),
@@ -59,6 +68,7 @@ func NewWSRPCClient(wsConf *wsclient.WSConfig) WebSocketRPCClient {
 type Subscription interface {
 	LocalID() *fftypes.UUID // does not change through reconnects
 	Notifications() chan *RPCSubscriptionNotification
+	Resubscribed() chan string // receives the new server subscription ID when re-established after a reconnect
 	Unsubscribe(ctx context.Context) *RPCError
 }
 
@@ -70,6 +80,7 @@ type RPCSubscriptionNotification struct {
 type wsRPCClient struct {
 	mux                sync.Mutex
 	wsConf             wsclient.WSConfig
+	newClient          func(ctx context.Context, afterConnect wsclient.WSPostConnectHandler) (wsclient.WSClient, error)
 	client             wsclient.WSClient
 	requestCounter     int64
 	connected          chan struct{}
@@ -89,10 +100,11 @@ type sub struct {
 	currentSubID   string
 	newSubResponse chan *RPCError
 	notifications  chan *RPCSubscriptionNotification
+	resubscribed   chan string
 }
 
 func (rc *wsRPCClient) Connect(ctx context.Context) (err error) {
-	rc.client, err = wsclient.New(ctx, &rc.wsConf, nil, rc.handleReconnect)
+	rc.client, err = rc.newClient(ctx, rc.handleReconnect)
 	if err != nil {
 		return err
 	}
@@ -222,6 +234,7 @@ func (rc *wsRPCClient) addConfiguredSub(ctx context.Context, params []interface{
 		params:         params,
 		newSubResponse: make(chan *RPCError, 1),
 		notifications:  make(chan *RPCSubscriptionNotification), // blocking channel for these, but Unsubscribe will unblock by cancelling ctx
+		resubscribed:   make(chan string, 1),                    // non-blocking, as nobody is obliged to listen
 	}
 	s.ctx, s.cancelCtx = context.WithCancel(ctx)
 	rc.configuredSubs[*s.localID] = s
@@ -309,6 +322,16 @@ func (s *sub) Notifications() chan *RPCSubscriptionNotification {
 	return s.notifications
 }
 
+func (s *sub) serverSubID() string {
+	s.rc.mux.Lock()
+	defer s.rc.mux.Unlock()
+	return s.currentSubID
+}
+
+func (s *sub) Resubscribed() chan string {
+	return s.resubscribed
+}
+
 func (s *sub) Unsubscribe(ctx context.Context) *RPCError {
 	currentSubID := s.rc.removeSubscription(s)
 	var resultBool bool
@@ -346,9 +369,7 @@ func (rc *wsRPCClient) sendRPC(ctx context.Context, reqID string, rpcReq *RPCReq
 	jsonInput, err := json.Marshal(rpcReq)
 	if err == nil {
 		log.L(ctx).Debugf("RPC[%s] --> %s", reqID, rpcReq.Method)
-		if logrus.IsLevelEnabled(logrus.TraceLevel) {
-			log.L(ctx).Tracef("RPC[%s] INPUT: %s", reqID, jsonInput)
-		}
+		debuglog.Logf(ctx, debuglog.RPCWire, "RPC[%s] INPUT: %s", reqID, jsonInput)
 		err = rc.client.Send(ctx, jsonInput)
 	}
 	if err != nil {
@@ -375,6 +396,73 @@ func (rc *wsRPCClient) CallRPC(ctx context.Context, result interface{}, method s
 	return rc.waitResponse(ctx, result, reqID, rpcReq, rpcStartTime, resChannel)
 }
 
+// SyncRequest sends an individual RPC request over the WebSocket, and waits synchronously for
+// the response, or an error. This allows the WebSocket client to be used as a drop-in Backend
+// in place of the HTTP client.
+//
+// In all return paths *including error paths* the RPCResponse is populated
+// so the caller has an RPC structure to send back to the front-end caller.
+func (rc *wsRPCClient) SyncRequest(ctx context.Context, rpcReq *RPCRequest) (rpcRes *RPCResponse, err error) {
+	ctx, span := startClientSpan(ctx, rpcReq.Method, semconv.RPCMethodKey.String(rpcReq.Method))
+	defer func() { endSpan(span, rpcRes, err) }()
+
+	// We always set the back-end request ID - as we need to support requests coming in from
+	// multiple concurrent clients on our front-end that might use clashing IDs.
+	var beReq = *rpcReq
+	beReq.JSONRpc = "2.0"
+	reqID, resChannel := rc.addInflightRequest(&beReq)
+	defer rc.removeInflightRequest(reqID)
+	rpcTraceID := reqID
+	if rpcReq.ID != nil {
+		// We're proxying a request with front-end RPC ID - log that as well
+		rpcTraceID = fmt.Sprintf("%s->%s", rpcReq.ID, reqID)
+	}
+
+	rpcStartTime := time.Now()
+	if rpcErr := rc.sendRPC(ctx, rpcTraceID, &beReq); rpcErr != nil {
+		return RPCErrorResponse(rpcErr.Error(), rpcReq.ID, RPCCodeInternalError), rpcErr.Error()
+	}
+
+	select {
+	case rpcRes = <-resChannel:
+	case <-ctx.Done():
+		err := i18n.NewError(ctx, signermsgs.MsgRequestCanceledContext, rpcTraceID)
+		log.L(ctx).Errorf("RPC[%s] <-- ERROR: %s", rpcTraceID, err)
+		return RPCErrorResponse(err, rpcReq.ID, RPCCodeInternalError), err
+	}
+
+	// Restore the original ID
+	rpcRes.ID = rpcReq.ID
+	if rpcRes.Error != nil && rpcRes.Error.Code != 0 {
+		log.L(ctx).Errorf("RPC[%s] <-- ERROR: %s", rpcTraceID, rpcRes.Message())
+		return rpcRes, rpcRes.Error.Error()
+	}
+	log.L(ctx).Infof("RPC[%s] <-- %s OK (%.2fms)", rpcTraceID, rpcReq.Method, float64(time.Since(rpcStartTime))/float64(time.Millisecond))
+	if rpcRes.Result == nil {
+		// We don't want a result for errors, but a null success response needs to go in there
+		rpcRes.Result = fftypes.JSONAnyPtr(fftypes.NullString)
+	}
+	return rpcRes, nil
+}
+
+// BatchRequest sends each request individually over the WebSocket in parallel, as all requests
+// are already multiplexed over a single connection. The responses are returned in the same order
+// as the requests, with per-item errors set in each RPCResponse.
+func (rc *wsRPCClient) BatchRequest(ctx context.Context, rpcReqs []*RPCRequest) (rpcResponses []*RPCResponse, err error) {
+	rpcResponses = make([]*RPCResponse, len(rpcReqs))
+	done := make(chan struct{})
+	for i, rpcReq := range rpcReqs {
+		go func(i int, rpcReq *RPCRequest) {
+			rpcResponses[i], _ = rc.SyncRequest(ctx, rpcReq)
+			done <- struct{}{}
+		}(i, rpcReq)
+	}
+	for range rpcReqs {
+		<-done
+	}
+	return rpcResponses, nil
+}
+
 func (rc *wsRPCClient) waitResponse(ctx context.Context, result interface{}, reqID string, rpcReq *RPCRequest, rpcStartTime time.Time, resChannel chan *RPCResponse) *RPCError {
 	var rpcRes *RPCResponse
 	select {
@@ -461,6 +549,12 @@ func (rc *wsRPCClient) handleSubscriptionConfirm(ctx context.Context, inflightSu
 	// all was good, if someone is waiting to be told, notify them
 	if resChl != nil {
 		resChl <- nil
+	} else {
+		// this was a re-subscribe after a reconnect, which is informational only
+		select {
+		case inflightSub.resubscribed <- subscriptionID:
+		default:
+		}
 	}
 }
 
@@ -18,6 +18,8 @@ package rpcbackend
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"math/big"
 	"net/http"
 	"testing"
@@ -351,6 +353,21 @@ func TestHandleSubscriptionConfirmBadSub(t *testing.T) {
 	assert.Regexp(t, "FF22066", rpcErr.Error())
 }
 
+func TestHandleSubscriptionConfirmResubscribed(t *testing.T) {
+	ctx, rc, _, _, done := newTestWSRPC(t)
+	defer done()
+
+	s := &sub{
+		localID:      fftypes.NewUUID(),
+		resubscribed: make(chan string, 1),
+	}
+	rc.handleSubscriptionConfirm(ctx, s, &RPCResponse{Result: fftypes.JSONAnyPtr(`"0x11111111"`)})
+	// A second resubscribe must not block, even if nobody has received the first
+	rc.handleSubscriptionConfirm(ctx, s, &RPCResponse{Result: fftypes.JSONAnyPtr(`"0x22222222"`)})
+	assert.Equal(t, "0x11111111", <-s.Resubscribed())
+	assert.Equal(t, "0x22222222", s.currentSubID)
+}
+
 func TestRemoveSubscriptionPending(t *testing.T) {
 	ctx, rc, _, _, done := newTestWSRPC(t)
 	defer done()
@@ -438,3 +455,125 @@ func TestDeliverCallResponseNonBlocking(t *testing.T) {
 
 	rc.deliverCallResponse(ctx, make(chan *RPCResponse), &RPCResponse{})
 }
+
+func TestWSSyncRequestOK(t *testing.T) {
+	ctx, rc, toServer, fromServer, done := newTestWSRPC(t)
+	defer done()
+
+	err := rc.Connect(ctx)
+	assert.NoError(t, err)
+
+	go func() {
+		msg := <-toServer
+		assert.JSONEq(t, `{"jsonrpc":"2.0","id":"000000001","method":"eth_blockNumber"}`, msg)
+		fromServer <- `{"jsonrpc":"2.0","id":"000000001","result":"0x12345"}`
+		msg = <-toServer
+		assert.JSONEq(t, `{"jsonrpc":"2.0","id":"000000002","method":"eth_getCode"}`, msg)
+		fromServer <- `{"jsonrpc":"2.0","id":"000000002"}`
+	}()
+
+	rpcRes, err := rc.SyncRequest(ctx, &RPCRequest{
+		ID:     fftypes.JSONAnyPtr(`"client-id"`),
+		Method: "eth_blockNumber",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, `"client-id"`, rpcRes.ID.String())
+	assert.Equal(t, `"0x12345"`, rpcRes.Result.String())
+
+	rpcRes, err = rc.SyncRequest(ctx, &RPCRequest{
+		ID:     fftypes.JSONAnyPtr(`2`),
+		Method: "eth_getCode",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, `2`, rpcRes.ID.String())
+	assert.Equal(t, `null`, rpcRes.Result.String())
+}
+
+func TestWSSyncRequestRPCError(t *testing.T) {
+	ctx, rc, toServer, fromServer, done := newTestWSRPC(t)
+	defer done()
+
+	err := rc.Connect(ctx)
+	assert.NoError(t, err)
+
+	go func() {
+		<-toServer
+		fromServer <- `{"jsonrpc":"2.0","id":"000000001","error":{"code":-32000,"message":"pop"}}`
+	}()
+
+	rpcRes, err := rc.SyncRequest(ctx, &RPCRequest{
+		ID:     fftypes.JSONAnyPtr(`1`),
+		Method: "eth_call",
+	})
+	assert.Regexp(t, "pop", err)
+	assert.Equal(t, `1`, rpcRes.ID.String())
+	assert.Equal(t, int64(-32000), rpcRes.Error.Code)
+}
+
+func TestWSSyncRequestSendFail(t *testing.T) {
+	ctx, rc, _, _, done := newTestWSRPC(t)
+
+	err := rc.Connect(ctx)
+	assert.NoError(t, err)
+	done()
+
+	rpcRes, err := rc.SyncRequest(ctx, &RPCRequest{
+		ID:     fftypes.JSONAnyPtr(`1`),
+		Method: "eth_call",
+	})
+	assert.Regexp(t, "FF22012", err)
+	assert.Equal(t, `1`, rpcRes.ID.String())
+}
+
+func TestWSSyncRequestClosedContext(t *testing.T) {
+	ctx, rc, toServer, _, done := newTestWSRPC(t)
+	defer done()
+
+	err := rc.Connect(ctx)
+	assert.NoError(t, err)
+
+	reqCtx, cancelReqCtx := context.WithCancel(ctx)
+	go func() {
+		<-toServer
+		cancelReqCtx()
+	}()
+
+	rpcRes, err := rc.SyncRequest(reqCtx, &RPCRequest{
+		ID:     fftypes.JSONAnyPtr(`1`),
+		Method: "eth_call",
+	})
+	assert.Regexp(t, "FF22063", err)
+	assert.Equal(t, `1`, rpcRes.ID.String())
+}
+
+func TestWSBatchRequest(t *testing.T) {
+	ctx, rc, toServer, fromServer, done := newTestWSRPC(t)
+	defer done()
+
+	err := rc.Connect(ctx)
+	assert.NoError(t, err)
+
+	go func() {
+		// Requests are sent in parallel, so reply to whatever arrives
+		for i := 0; i < 2; i++ {
+			var rpcReq RPCRequest
+			err := json.Unmarshal([]byte(<-toServer), &rpcReq)
+			assert.NoError(t, err)
+			if rpcReq.Method == "eth_blockNumber" {
+				fromServer <- fmt.Sprintf(`{"jsonrpc":"2.0","id":%s,"result":"0x12345"}`, rpcReq.ID)
+			} else {
+				fromServer <- fmt.Sprintf(`{"jsonrpc":"2.0","id":%s,"error":{"code":-32601,"message":"pop"}}`, rpcReq.ID)
+			}
+		}
+	}()
+
+	rpcResponses, err := rc.BatchRequest(ctx, []*RPCRequest{
+		{ID: fftypes.JSONAnyPtr(`1`), Method: "eth_blockNumber"},
+		{ID: fftypes.JSONAnyPtr(`2`), Method: "bad_method"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, `1`, rpcResponses[0].ID.String())
+	assert.Equal(t, `"0x12345"`, rpcResponses[0].Result.String())
+	assert.Equal(t, `2`, rpcResponses[1].ID.String())
+	assert.Equal(t, "pop", rpcResponses[1].Message())
+}
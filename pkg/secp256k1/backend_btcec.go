@@ -0,0 +1,90 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !(secp256k1_cgo && cgo)
+
+package secp256k1
+
+import (
+	"github.com/btcsuite/btcd/btcec/v2"
+	ecdsa "github.com/btcsuite/btcd/btcec/v2/ecdsa"
+)
+
+// SigningBackend is the implementation used to sign and recover signatures. The pure Go btcec
+// implementation is used by default, and libsecp256k1 when built with the secp256k1_cgo build tag.
+const SigningBackend = "btcec"
+
+// signCompact returns a 65 byte [V,R,S] signature, with a legacy 27/28 V value.
+// Any extra entropy is mixed into the RFC 6979 deterministic nonce, as described in section 3.6.
+func signCompact(privateKey *btcec.PrivateKey, hash, extraEntropy []byte) ([]byte, error) {
+	if extraEntropy == nil {
+		return ecdsa.SignCompact(privateKey, hash, false) // uses S256() by default
+	}
+	return signCompactExtraEntropy(privateKey, hash, extraEntropy), nil
+}
+
+// signCompactExtraEntropy is the same RFC 6979 signing algorithm used by btcec, which does
+// not provide a way to pass the extra data through to the nonce generation
+func signCompactExtraEntropy(privateKey *btcec.PrivateKey, hash, extraEntropy []byte) []byte {
+	privateKeyBytes := privateKey.Serialize()
+	defer ZeroizeBytes(privateKeyBytes)
+	for iteration := uint32(0); ; iteration++ {
+		nonce := btcec.NonceRFC6979(privateKeyBytes, hash, extraEntropy, nil, iteration)
+		sig, ok := signWithNonce(&privateKey.Key, nonce, hash)
+		nonce.Zero()
+		if ok {
+			return sig
+		}
+	}
+}
+
+// signWithNonce returns a low-S signature for the given nonce, or false if the nonce cannot be
+// used (R or S is zero), in which case the next nonce should be tried
+func signWithNonce(privateKey, nonce *btcec.ModNScalar, hash []byte) ([]byte, bool) {
+	var kG btcec.JacobianPoint
+	btcec.ScalarBaseMultNonConst(nonce, &kG)
+	kG.ToAffine()
+
+	// r = kG.x mod N, with the recovery code recording the parity of kG.y and whether kG.x overflowed N
+	var r btcec.ModNScalar
+	overflow := r.SetBytes(kG.X.Bytes())
+	recoveryCode := byte(overflow<<1) | byte(kG.Y.IsOddBit())
+
+	// s = k^-1(e + dr) mod N, negated if required to make it low-S - which flips the parity
+	var e btcec.ModNScalar
+	e.SetByteSlice(hash)
+	kInv := new(btcec.ModNScalar).InverseValNonConst(nonce)
+	s := new(btcec.ModNScalar).Mul2(privateKey, &r).Add(&e).Mul(kInv)
+	if r.IsZero() || s.IsZero() {
+		return nil, false
+	}
+	if s.IsOverHalfOrder() {
+		s.Negate()
+		recoveryCode ^= 0x01
+	}
+
+	sig := make([]byte, 65)
+	sig[0] = 27 + recoveryCode
+	r.PutBytesUnchecked(sig[1:33])
+	s.PutBytesUnchecked(sig[33:65])
+	return sig, true
+}
+
+// recoverCompact returns the public key from a 65 byte [V,R,S] signature, with a legacy 27/28 V value
+func recoverCompact(sig, hash []byte) (*btcec.PublicKey, error) {
+	pubKey, _, err := ecdsa.RecoverCompact(sig, hash) // uses S256() by default
+	return pubKey, err
+}
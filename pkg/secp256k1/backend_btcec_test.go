@@ -0,0 +1,59 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !(secp256k1_cgo && cgo)
+
+package secp256k1
+
+import (
+	"crypto/rand"
+	"testing"
+
+	btcec "github.com/btcsuite/btcd/btcec/v2"
+	ecdsa "github.com/btcsuite/btcd/btcec/v2/ecdsa"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignCompactExtraEntropyMatchesBtcec(t *testing.T) {
+	// With no extra entropy, the nonce is the same as btcec uses, so the signatures must be identical
+	for i := 0; i < 100; i++ {
+		keypair := newTestKeyPair(t)
+		hash := make([]byte, 32)
+		_, err := rand.Read(hash)
+		assert.NoError(t, err)
+		expected, err := ecdsa.SignCompact(keypair.PrivateKey, hash, false)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, signCompactExtraEntropy(keypair.PrivateKey, hash, nil))
+	}
+}
+
+func TestSignWithNonceZeroS(t *testing.T) {
+	keypair := testKeyPair(t)
+
+	// With a nonce of 1, r is the X coordinate of the generator - so a hash of -dr gives s=0
+	var nonce btcec.ModNScalar
+	nonce.SetInt(1)
+	var g btcec.JacobianPoint
+	btcec.ScalarBaseMultNonConst(&nonce, &g)
+	g.ToAffine()
+	var r btcec.ModNScalar
+	r.SetBytes(g.X.Bytes())
+	e := new(btcec.ModNScalar).Mul2(&keypair.PrivateKey.Key, &r).Negate()
+	hash := e.Bytes()
+
+	_, ok := signWithNonce(&keypair.PrivateKey.Key, &nonce, hash[:])
+	assert.False(t, ok)
+}
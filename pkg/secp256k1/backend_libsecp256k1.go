@@ -0,0 +1,90 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build secp256k1_cgo && cgo
+
+package secp256k1
+
+/*
+#cgo LDFLAGS: -lsecp256k1
+#include <secp256k1.h>
+#include <secp256k1_recovery.h>
+*/
+import "C"
+
+import (
+	"fmt"
+	"unsafe"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+)
+
+// SigningBackend is the implementation used to sign and recover signatures. The pure Go btcec
+// implementation is used by default, and libsecp256k1 when built with the secp256k1_cgo build tag.
+const SigningBackend = "libsecp256k1"
+
+// The context is read-only once created, so is safe to share between goroutines
+var libsecp256k1Context = C.secp256k1_context_create(C.SECP256K1_CONTEXT_SIGN | C.SECP256K1_CONTEXT_VERIFY)
+
+func cBytes(b []byte) *C.uchar {
+	return (*C.uchar)(unsafe.Pointer(&b[0]))
+}
+
+// signCompact returns a 65 byte [V,R,S] signature, with a legacy 27/28 V value.
+// libsecp256k1 uses RFC6979 deterministic nonces and always returns a low-S signature.
+// Any extra entropy is passed as the nonce data, which is mixed into the nonce as described in section 3.6.
+func signCompact(privateKey *btcec.PrivateKey, hash, extraEntropy []byte) ([]byte, error) {
+	if len(hash) != 32 {
+		return nil, fmt.Errorf("libsecp256k1 requires a 32 byte hash (len=%d)", len(hash))
+	}
+	seckey := privateKey.Serialize()
+	defer ZeroizeBytes(seckey)
+	var ndata unsafe.Pointer
+	if extraEntropy != nil {
+		ndata = unsafe.Pointer(cBytes(extraEntropy))
+	}
+	var rsig C.secp256k1_ecdsa_recoverable_signature
+	if C.secp256k1_ecdsa_sign_recoverable(libsecp256k1Context, &rsig, cBytes(hash), cBytes(seckey), nil, ndata) != 1 {
+		return nil, fmt.Errorf("libsecp256k1 signing failed")
+	}
+	sig := make([]byte, 65)
+	var recid C.int
+	C.secp256k1_ecdsa_recoverable_signature_serialize_compact(libsecp256k1Context, cBytes(sig[1:]), &recid, &rsig)
+	sig[0] = byte(27 + recid)
+	return sig, nil
+}
+
+// recoverCompact returns the public key from a 65 byte [V,R,S] signature, with a legacy 27/28 V value
+func recoverCompact(sig, hash []byte) (*btcec.PublicKey, error) {
+	if len(hash) != 32 {
+		return nil, fmt.Errorf("libsecp256k1 requires a 32 byte hash (len=%d)", len(hash))
+	}
+	if len(sig) != 65 || (sig[0] != 27 && sig[0] != 28) {
+		return nil, fmt.Errorf("invalid compact signature")
+	}
+	var rsig C.secp256k1_ecdsa_recoverable_signature
+	if C.secp256k1_ecdsa_recoverable_signature_parse_compact(libsecp256k1Context, &rsig, cBytes(sig[1:]), C.int(sig[0]-27)) != 1 {
+		return nil, fmt.Errorf("invalid compact signature")
+	}
+	var pubKey C.secp256k1_pubkey
+	if C.secp256k1_ecdsa_recover(libsecp256k1Context, &pubKey, &rsig, cBytes(hash)) != 1 {
+		return nil, fmt.Errorf("libsecp256k1 failed to recover public key")
+	}
+	serialized := make([]byte, PublicKeyUncompressedLength)
+	serializedLen := C.size_t(len(serialized))
+	C.secp256k1_ec_pubkey_serialize(libsecp256k1Context, cBytes(serialized), &serializedLen, &pubKey, C.SECP256K1_EC_UNCOMPRESSED)
+	return btcec.ParsePubKey(serialized)
+}
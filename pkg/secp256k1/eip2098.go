@@ -0,0 +1,81 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secp256k1
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+)
+
+// CompactEIP2098 returns the 64 byte compact form of the signature defined by EIP-2098, which is R followed by
+// S with the Y-parity of V in its top bit. The chain ID is only used to interpret an EIP-155 V value.
+//
+// The S value must be in the lower half of the curve order (as required for Ethereum transactions by EIP-2),
+// so the top bit is free.
+func (s *SignatureData) CompactEIP2098(ctx context.Context, chainID int64) ([]byte, error) {
+	v, err := s.getVNormalized(chainID)
+	if err != nil {
+		return nil, err
+	}
+	if s.S.BitLen() > 255 {
+		return nil, i18n.NewError(ctx, signermsgs.MsgSigningHighSEIP2098)
+	}
+	signatureBytes := make([]byte, 64)
+	s.R.FillBytes(signatureBytes[0:32])
+	s.S.FillBytes(signatureBytes[32:64])
+	if v == 28 {
+		signatureBytes[32] |= 0x80
+	}
+	return signatureBytes, nil
+}
+
+// DecodeCompactEIP2098 decodes a 64 byte EIP-2098 signature, with a legacy 27/28 V value
+func DecodeCompactEIP2098(ctx context.Context, compact []byte) (*SignatureData, error) {
+	if len(compact) != 64 {
+		return nil, i18n.NewError(ctx, signermsgs.MsgSigningInvalidEIP2098, len(compact))
+	}
+	var sig SignatureData
+	sig.R = new(big.Int).SetBytes(compact[0:32])
+	yParityAndS := make([]byte, 32)
+	copy(yParityAndS, compact[32:64])
+	yParity := yParityAndS[0] >> 7
+	yParityAndS[0] &= 0x7f
+	sig.S = new(big.Int).SetBytes(yParityAndS)
+	sig.V = big.NewInt(27 + int64(yParity))
+	return &sig, nil
+}
+
+// CompactRSVToEIP2098 converts a 65 byte R,S,V signature to the 64 byte EIP-2098 form
+func CompactRSVToEIP2098(ctx context.Context, compactRSV []byte, chainID int64) ([]byte, error) {
+	sig, err := DecodeCompactRSV(ctx, compactRSV)
+	if err != nil {
+		return nil, err
+	}
+	return sig.CompactEIP2098(ctx, chainID)
+}
+
+// EIP2098ToCompactRSV converts a 64 byte EIP-2098 signature to the 65 byte R,S,V form, with a legacy 27/28 V value
+func EIP2098ToCompactRSV(ctx context.Context, compact []byte) ([]byte, error) {
+	sig, err := DecodeCompactEIP2098(ctx, compact)
+	if err != nil {
+		return nil, err
+	}
+	return sig.CompactRSV(), nil
+}
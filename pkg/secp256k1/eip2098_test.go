@@ -0,0 +1,120 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secp256k1
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test vectors directly taken from:
+// https://eips.ethereum.org/EIPS/eip-2098
+var eip2098Vectors = []struct {
+	message     string
+	r           string
+	s           string
+	v           int64
+	yParityAndS string
+}{
+	{
+		message:     "Hello World",
+		r:           "0x68a020a209d3d56c46f38cc50a33f704f4a9a10a59377f8dd762ac66910e9b90",
+		s:           "0x7e865ad05c4035ab5792787d4a0297a43617ae897930a6fe4d822b8faea52064",
+		v:           27,
+		yParityAndS: "0x7e865ad05c4035ab5792787d4a0297a43617ae897930a6fe4d822b8faea52064",
+	},
+	{
+		message:     "It's a small(er) world",
+		r:           "0x9328da16089fcba9bececa81663203989f2df5fe1faa6291a45381c81bd17f76",
+		s:           "0x139c6d6b623b42da56557e5e734a43dc83345ddfadec52cbe24d0cc64f550793",
+		v:           28,
+		yParityAndS: "0x939c6d6b623b42da56557e5e734a43dc83345ddfadec52cbe24d0cc64f550793",
+	},
+}
+
+const eip2098PrivateKey = "0x1234567890123456789012345678901234567890123456789012345678901234"
+
+func TestEIP2098Vectors(t *testing.T) {
+	ctx := context.Background()
+	keypair := KeyPairFromBytes(ethtypes.MustNewHexBytes0xPrefix(eip2098PrivateKey))
+
+	for _, v := range eip2098Vectors {
+		sig, err := keypair.Sign(addEthMessagePrefix([]byte(v.message)))
+		assert.NoError(t, err)
+		assert.Equal(t, v.r, ethtypes.HexBytes0xPrefix(sig.R.FillBytes(make([]byte, 32))).String())
+		assert.Equal(t, v.s, ethtypes.HexBytes0xPrefix(sig.S.FillBytes(make([]byte, 32))).String())
+		assert.Equal(t, v.v, sig.V.Int64())
+
+		compact, err := sig.CompactEIP2098(ctx, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, v.r[2:]+v.yParityAndS[2:], ethtypes.HexBytesPlain(compact).String())
+
+		decoded, err := DecodeCompactEIP2098(ctx, compact)
+		assert.NoError(t, err)
+		assert.Equal(t, sig, decoded)
+
+		addr, err := decoded.Recover(addEthMessagePrefix([]byte(v.message)), 0)
+		assert.NoError(t, err)
+		assert.Equal(t, keypair.Address, *addr)
+
+		// Round trip through the 65 byte form
+		compactRSV, err := EIP2098ToCompactRSV(ctx, compact)
+		assert.NoError(t, err)
+		assert.Equal(t, sig.CompactRSV(), compactRSV)
+		compact2, err := CompactRSVToEIP2098(ctx, compactRSV, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, compact, compact2)
+	}
+}
+
+func TestEIP2098EIP155(t *testing.T) {
+	keypair := testKeyPair(t)
+	sig, err := keypair.Sign([]byte(sampleMessage))
+	assert.NoError(t, err)
+	compact, err := sig.CompactEIP2098(context.Background(), 0)
+	assert.NoError(t, err)
+
+	sig.UpdateEIP155(1001)
+	compact155, err := sig.CompactEIP2098(context.Background(), 1001)
+	assert.NoError(t, err)
+	assert.Equal(t, compact, compact155)
+}
+
+func TestEIP2098Errors(t *testing.T) {
+	ctx := context.Background()
+
+	sig := &SignatureData{V: big.NewInt(27), R: big.NewInt(1), S: new(big.Int).Lsh(big.NewInt(1), 255)}
+	_, err := sig.CompactEIP2098(ctx, 0)
+	assert.Regexp(t, "FF22176", err)
+
+	sig.V = big.NewInt(100)
+	_, err = sig.CompactEIP2098(ctx, 0)
+	assert.Regexp(t, "invalid V value", err)
+
+	_, err = DecodeCompactEIP2098(ctx, make([]byte, 65))
+	assert.Regexp(t, "FF22175", err)
+
+	_, err = EIP2098ToCompactRSV(ctx, make([]byte, 65))
+	assert.Regexp(t, "FF22175", err)
+
+	_, err = CompactRSVToEIP2098(ctx, make([]byte, 64), 0)
+	assert.Regexp(t, "FF22087", err)
+}
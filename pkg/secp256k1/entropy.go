@@ -0,0 +1,66 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secp256k1
+
+import (
+	"context"
+	"crypto/rand"
+	"io"
+
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"golang.org/x/crypto/sha3"
+)
+
+// ExtraEntropyLength is the length of the extra entropy that can be mixed into the nonce of a signature
+const ExtraEntropyLength = 32
+
+// SignDirectWithExtraEntropy is SignDirect, with 32 bytes of additional data mixed into the RFC 6979
+// deterministic nonce as described in section 3.6. The signature is still valid and low-S, but signing
+// the same message twice with different extra entropy results in different signatures.
+func (k *KeyPair) SignDirectWithExtraEntropy(message, extraEntropy []byte) (*SignatureData, error) {
+	if len(extraEntropy) != ExtraEntropyLength {
+		return nil, i18n.NewError(context.Background(), signermsgs.MsgInvalidExtraEntropy, ExtraEntropyLength, len(extraEntropy))
+	}
+	return k.signDirect(message, extraEntropy)
+}
+
+type extraEntropySigner struct {
+	keypair *KeyPair
+	entropy io.Reader
+}
+
+// NewExtraEntropySigner returns a signer that mixes fresh random entropy into the nonce of every
+// signature, for deployments whose policy does not allow fully deterministic nonces.
+// Signing with the KeyPair directly remains fully deterministic.
+func NewExtraEntropySigner(keypair *KeyPair) SignerDirect {
+	return &extraEntropySigner{keypair: keypair, entropy: rand.Reader}
+}
+
+func (es *extraEntropySigner) Sign(msgToHashAndSign []byte) (*SignatureData, error) {
+	msgHash := sha3.NewLegacyKeccak256()
+	msgHash.Write(msgToHashAndSign)
+	return es.SignDirect(msgHash.Sum(nil))
+}
+
+func (es *extraEntropySigner) SignDirect(message []byte) (*SignatureData, error) {
+	extraEntropy := make([]byte, ExtraEntropyLength)
+	if _, err := io.ReadFull(es.entropy, extraEntropy); err != nil {
+		return nil, err
+	}
+	return es.keypair.SignDirectWithExtraEntropy(message, extraEntropy)
+}
@@ -0,0 +1,90 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secp256k1
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/sha3"
+)
+
+func testHash() []byte {
+	msgHash := sha3.NewLegacyKeccak256()
+	msgHash.Write([]byte(sampleMessage))
+	return msgHash.Sum(nil)
+}
+
+func TestSignDirectWithExtraEntropy(t *testing.T) {
+	keypair := testKeyPair(t)
+	hash := testHash()
+
+	sig1, err := keypair.SignDirectWithExtraEntropy(hash, bytes.Repeat([]byte{0x01}, ExtraEntropyLength))
+	assert.NoError(t, err)
+	sig2, err := keypair.SignDirectWithExtraEntropy(hash, bytes.Repeat([]byte{0x02}, ExtraEntropyLength))
+	assert.NoError(t, err)
+	sig1Again, err := keypair.SignDirectWithExtraEntropy(hash, bytes.Repeat([]byte{0x01}, ExtraEntropyLength))
+	assert.NoError(t, err)
+	deterministic, err := keypair.SignDirect(hash)
+	assert.NoError(t, err)
+
+	assert.Equal(t, sig1.CompactRSV(), sig1Again.CompactRSV())
+	assert.NotEqual(t, sig1.R, sig2.R)
+	assert.NotEqual(t, deterministic.R, sig1.R)
+	for _, sig := range []*SignatureData{sig1, sig2} {
+		assert.True(t, sig.IsLowS())
+		addr, err := sig.RecoverDirect(hash, -1)
+		assert.NoError(t, err)
+		assert.Equal(t, keypair.Address, *addr)
+	}
+}
+
+func TestSignDirectWithExtraEntropyBadLength(t *testing.T) {
+	keypair := testKeyPair(t)
+	_, err := keypair.SignDirectWithExtraEntropy(testHash(), []byte{0x01})
+	assert.Regexp(t, "FF22179.*32.*1", err)
+}
+
+func TestExtraEntropySigner(t *testing.T) {
+	keypair := testKeyPair(t)
+	signer := NewExtraEntropySigner(keypair)
+
+	sig1, err := signer.Sign([]byte(sampleMessage))
+	assert.NoError(t, err)
+	sig2, err := signer.Sign([]byte(sampleMessage))
+	assert.NoError(t, err)
+	assert.NotEqual(t, sig1.R, sig2.R)
+	for _, sig := range []*SignatureData{sig1, sig2} {
+		addr, err := sig.Recover([]byte(sampleMessage), -1)
+		assert.NoError(t, err)
+		assert.Equal(t, keypair.Address, *addr)
+	}
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, fmt.Errorf("pop")
+}
+
+func TestExtraEntropySignerReadFail(t *testing.T) {
+	signer := &extraEntropySigner{keypair: testKeyPair(t), entropy: failingReader{}}
+	_, err := signer.SignDirect(testHash())
+	assert.Regexp(t, "pop", err)
+}
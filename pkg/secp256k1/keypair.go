@@ -70,3 +70,16 @@ func PublicKeyToAddress(pubKey *btcec.PublicKey) *ethtypes.Address0xHex {
 	copy(a[:], hash.Sum(nil)[12:32])
 	return a
 }
+
+// Zeroize overwrites the private key in memory, after which the key pair cannot be used for signing
+func (k *KeyPair) Zeroize() {
+	if k != nil && k.PrivateKey != nil {
+		k.PrivateKey.Zero()
+	}
+}
+
+// ZeroizeBytes overwrites a buffer holding sensitive material, such as a serialized private key,
+// a seed, or a key derived from a password, so it does not linger in memory until garbage collected
+func ZeroizeBytes(b []byte) {
+	clear(b)
+}
@@ -80,3 +80,18 @@ func TestGeneratedKeyRoundTrip(t *testing.T) {
 	assert.Error(t, err)
 
 }
+
+func TestZeroize(t *testing.T) {
+	keypair, err := GenerateSecp256k1KeyPair()
+	assert.NoError(t, err)
+	keypair.Zeroize()
+	assert.Equal(t, make([]byte, 32), keypair.PrivateKeyBytes())
+
+	var nilKeyPair *KeyPair
+	nilKeyPair.Zeroize()
+	(&KeyPair{}).Zeroize()
+
+	b := []byte{1, 2, 3}
+	ZeroizeBytes(b)
+	assert.Equal(t, []byte{0, 0, 0}, b)
+}
@@ -0,0 +1,170 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secp256k1
+
+import (
+	"container/list"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+)
+
+// KeyPairCache holds decrypted key pairs in memory for signing, up to a maximum number of keys (least recently
+// used first out) and for a maximum time unused.
+//
+// Key pairs are zeroized when they leave the cache - whether evicted explicitly, by size or by expiry - but
+// not while they are in use. So a key pair obtained from the cache can be used for signing concurrently with
+// evictions, until it is released.
+type KeyPairCache struct {
+	mux     sync.Mutex
+	maxKeys int
+	ttl     time.Duration
+	entries map[ethtypes.Address0xHex]*list.Element
+	lru     *list.List // most recently used at the front
+	hits    uint64
+	misses  uint64
+}
+
+type cachedKeyPair struct {
+	keypair *KeyPair
+	expiry  *time.Timer
+	inUse   int
+	evicted bool
+}
+
+var finalizeCachedKeyPair = func(ce *cachedKeyPair) {
+	ce.keypair.Zeroize()
+}
+
+// NewKeyPairCache creates a cache of up to maxKeys key pairs, each kept for up to ttl since it was last used.
+// A maxKeys or ttl of zero is unlimited.
+func NewKeyPairCache(maxKeys int, ttl time.Duration) *KeyPairCache {
+	return &KeyPairCache{
+		maxKeys: maxKeys,
+		ttl:     ttl,
+		entries: make(map[ethtypes.Address0xHex]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// Get returns the cached key pair for an address, or nil if it is not cached. The release function must
+// be called once finished with the key pair - it will not be zeroized until then.
+func (c *KeyPairCache) Get(addr ethtypes.Address0xHex) (*KeyPair, func()) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	elem := c.entries[addr]
+	if elem == nil {
+		c.misses++
+		return nil, nil
+	}
+	c.hits++
+	c.lru.MoveToFront(elem)
+	ce := elem.Value.(*cachedKeyPair)
+	if ce.expiry != nil {
+		ce.expiry.Reset(c.ttl)
+	}
+	return ce.keypair, c.useLocked(ce)
+}
+
+// Add caches a key pair, replacing any existing key pair for the same address. The key pair is returned
+// in use, as from Get, so the release function must be called once finished with it.
+func (c *KeyPairCache) Add(keypair *KeyPair) func() {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if elem := c.entries[keypair.Address]; elem != nil {
+		c.evictLocked(elem)
+	}
+	ce := &cachedKeyPair{keypair: keypair}
+	// The finalizer zeroizes the key pair if the cache itself is discarded without eviction
+	runtime.SetFinalizer(ce, finalizeCachedKeyPair)
+	elem := c.lru.PushFront(ce)
+	c.entries[keypair.Address] = elem
+	if c.ttl > 0 {
+		ce.expiry = time.AfterFunc(c.ttl, func() {
+			c.mux.Lock()
+			defer c.mux.Unlock()
+			// Only expire if we have not been evicted or replaced already
+			if !ce.evicted {
+				c.evictLocked(elem)
+			}
+		})
+	}
+	for c.maxKeys > 0 && c.lru.Len() > c.maxKeys {
+		c.evictLocked(c.lru.Back())
+	}
+	return c.useLocked(ce)
+}
+
+// Evict removes the key pair for an address from the cache, returning false if it was not cached
+func (c *KeyPairCache) Evict(addr ethtypes.Address0xHex) bool {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	elem := c.entries[addr]
+	if elem != nil {
+		c.evictLocked(elem)
+	}
+	return elem != nil
+}
+
+// EvictAll removes every key pair from the cache
+func (c *KeyPairCache) EvictAll() {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	for c.lru.Len() > 0 {
+		c.evictLocked(c.lru.Front())
+	}
+}
+
+// Stats returns the number of key pairs in the cache, and the number of lookups that have hit or missed it
+func (c *KeyPairCache) Stats() (items int, hits, misses uint64) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return c.lru.Len(), c.hits, c.misses
+}
+
+// useLocked must be called holding the mutex
+func (c *KeyPairCache) useLocked(ce *cachedKeyPair) func() {
+	ce.inUse++
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			c.mux.Lock()
+			defer c.mux.Unlock()
+			ce.inUse--
+			if ce.evicted && ce.inUse == 0 {
+				ce.keypair.Zeroize()
+			}
+		})
+	}
+}
+
+// evictLocked must be called holding the mutex
+func (c *KeyPairCache) evictLocked(elem *list.Element) {
+	ce := c.lru.Remove(elem).(*cachedKeyPair)
+	delete(c.entries, ce.keypair.Address)
+	if ce.expiry != nil {
+		ce.expiry.Stop()
+		ce.expiry = nil
+	}
+	runtime.SetFinalizer(ce, nil)
+	ce.evicted = true
+	if ce.inUse == 0 {
+		ce.keypair.Zeroize()
+	}
+}
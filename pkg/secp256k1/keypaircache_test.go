@@ -0,0 +1,186 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secp256k1
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestKeyPair(t *testing.T) *KeyPair {
+	keypair, err := GenerateSecp256k1KeyPair()
+	assert.NoError(t, err)
+	return keypair
+}
+
+func TestKeyPairZeroize(t *testing.T) {
+	keypair := testKeyPair(t)
+	keypair.Zeroize()
+	assert.Equal(t, make([]byte, 32), keypair.PrivateKeyBytes())
+	(&KeyPair{}).Zeroize()
+}
+
+func TestKeyPairCacheGetAddEvict(t *testing.T) {
+	c := NewKeyPairCache(0, 0)
+	keypair := testKeyPair(t)
+
+	cached, release := c.Get(keypair.Address)
+	assert.Nil(t, cached)
+	assert.Nil(t, release)
+
+	release = c.Add(keypair)
+	release()
+	release() // only the first release counts
+
+	cached, release = c.Get(keypair.Address)
+	assert.Equal(t, keypair, cached)
+	release()
+	items, hits, misses := c.Stats()
+	assert.Equal(t, 1, items)
+	assert.Equal(t, uint64(1), hits)
+	assert.Equal(t, uint64(1), misses)
+
+	assert.True(t, c.Evict(keypair.Address))
+	assert.False(t, c.Evict(keypair.Address))
+	assert.Equal(t, make([]byte, 32), keypair.PrivateKeyBytes())
+}
+
+func TestKeyPairCacheNotZeroizedInUse(t *testing.T) {
+	c := NewKeyPairCache(0, 0)
+	keypair := testKeyPair(t)
+	c.Add(keypair)()
+
+	cached, release := c.Get(keypair.Address)
+	c.EvictAll()
+	items, _, _ := c.Stats()
+	assert.Zero(t, items)
+
+	// Can still sign until released
+	sig, err := cached.Sign([]byte(sampleMessage))
+	assert.NoError(t, err)
+	addr, err := sig.Recover([]byte(sampleMessage), 0)
+	assert.NoError(t, err)
+	assert.Equal(t, keypair.Address, *addr)
+
+	release()
+	assert.Equal(t, make([]byte, 32), keypair.PrivateKeyBytes())
+}
+
+func TestKeyPairCacheReplace(t *testing.T) {
+	c := NewKeyPairCache(0, time.Hour)
+	keypair1 := testKeyPair(t)
+	keypair2 := testKeyPair(t)
+
+	c.Add(keypair1)()
+	c.Add(keypair2)()
+	assert.Equal(t, make([]byte, 32), keypair1.PrivateKeyBytes())
+
+	cached, release := c.Get(keypair2.Address)
+	defer release()
+	assert.Equal(t, keypair2, cached)
+}
+
+func TestKeyPairCacheMaxKeys(t *testing.T) {
+	c := NewKeyPairCache(2, 0)
+	keypair1, keypair2, keypair3 := newTestKeyPair(t), newTestKeyPair(t), newTestKeyPair(t)
+
+	c.Add(keypair1)()
+	c.Add(keypair2)()
+	_, release := c.Get(keypair1.Address) // keypair2 is now least recently used
+	release()
+	c.Add(keypair3)()
+
+	assert.Equal(t, make([]byte, 32), keypair2.PrivateKeyBytes())
+	cached, _ := c.Get(keypair2.Address)
+	assert.Nil(t, cached)
+	cached, release = c.Get(keypair1.Address)
+	assert.NotNil(t, cached)
+	release()
+}
+
+func TestKeyPairCacheExpiry(t *testing.T) {
+	c := NewKeyPairCache(0, 1*time.Millisecond)
+	keypair := newTestKeyPair(t)
+	c.Add(keypair)()
+
+	for {
+		if items, _, _ := c.Stats(); items == 0 {
+			break
+		}
+		time.Sleep(1 * time.Millisecond)
+	}
+	assert.Equal(t, make([]byte, 32), keypair.PrivateKeyBytes())
+}
+
+func TestKeyPairCacheFinalizer(t *testing.T) {
+	finalized := make(chan struct{})
+	finalizer := finalizeCachedKeyPair
+	defer func() { finalizeCachedKeyPair = finalizer }()
+	finalizeCachedKeyPair = func(ce *cachedKeyPair) {
+		finalizer(ce)
+		close(finalized)
+	}
+
+	keypair := newTestKeyPair(t)
+	func() {
+		c := NewKeyPairCache(0, 0)
+		c.Add(keypair)()
+	}()
+
+	for done := false; !done; {
+		runtime.GC()
+		select {
+		case <-finalized:
+			done = true
+		case <-time.After(1 * time.Millisecond):
+		}
+	}
+	assert.Equal(t, make([]byte, 32), keypair.PrivateKeyBytes())
+}
+
+func TestKeyPairCacheConcurrentSigning(t *testing.T) {
+	c := NewKeyPairCache(1, 0)
+	keypairs := []*KeyPair{newTestKeyPair(t), newTestKeyPair(t)}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				// Each key evicts the other, and is re-added from a copy of the original
+				original := keypairs[(i+j)%2]
+				keypair, release := c.Get(original.Address)
+				if keypair == nil {
+					keypair = KeyPairFromBytes(original.PrivateKeyBytes())
+					release = c.Add(keypair)
+				}
+				sig, err := keypair.Sign([]byte(sampleMessage))
+				assert.NoError(t, err)
+				addr, err := sig.Recover([]byte(sampleMessage), 0)
+				assert.NoError(t, err)
+				assert.Equal(t, original.Address, *addr)
+				release()
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,107 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secp256k1
+
+import (
+	"context"
+	"math/big"
+
+	btcec "github.com/btcsuite/btcd/btcec/v2"
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+)
+
+var curveHalfOrder = new(big.Int).Rsh(btcec.S256().N, 1)
+
+// IsLowS is true if S is in the lower half of the curve order, as required for transactions by EIP-2.
+// For any valid signature (R,S) the signature (R,N-S) with the opposite Y-parity is also valid, so only
+// accepting low S values makes signatures non-malleable.
+func (s *SignatureData) IsLowS() bool {
+	return s.S.Cmp(curveHalfOrder) <= 0
+}
+
+// NormalizeS converts a signature with a high S value to the equivalent low S signature, by replacing
+// S with N-S and flipping the Y-parity of V (which can be 0/1, 27/28 or EIP-155)
+func (s *SignatureData) NormalizeS() {
+	if s.IsLowS() {
+		return
+	}
+	s.S = new(big.Int).Sub(btcec.S256().N, s.S)
+	v := s.V.Int64()
+	switch {
+	case v == 0 || v == 1:
+		v ^= 1
+	case v%2 == 1: // 27 or 35+2*chainID
+		v++
+	default:
+		v--
+	}
+	s.V = big.NewInt(v)
+}
+
+// RecoverLowS is Recover, rejecting signatures with a high S value as EIP-2 does for transactions
+func (s *SignatureData) RecoverLowS(message []byte, chainID int64) (*ethtypes.Address0xHex, error) {
+	if !s.IsLowS() {
+		return nil, i18n.NewError(context.Background(), signermsgs.MsgSignatureHighS)
+	}
+	return s.Recover(message, chainID)
+}
+
+// RecoverDirectLowS is RecoverDirect, rejecting signatures with a high S value as EIP-2 does for transactions
+func (s *SignatureData) RecoverDirectLowS(message []byte, chainID int64) (*ethtypes.Address0xHex, error) {
+	if !s.IsLowS() {
+		return nil, i18n.NewError(context.Background(), signermsgs.MsgSignatureHighS)
+	}
+	return s.RecoverDirect(message, chainID)
+}
+
+type lowSSigner struct {
+	signer Signer
+}
+
+type lowSSignerDirect struct {
+	lowSSigner
+	signer SignerDirect
+}
+
+// NewLowSSigner wraps a signer, such as one backed by an external KMS or HSM, so that every signature it
+// produces is normalized to low S form. Signatures from a KeyPair are always low S.
+func NewLowSSigner(signer Signer) Signer {
+	return &lowSSigner{signer: signer}
+}
+
+// NewLowSSignerDirect is NewLowSSigner for a SignerDirect
+func NewLowSSignerDirect(signer SignerDirect) SignerDirect {
+	return &lowSSignerDirect{lowSSigner: lowSSigner{signer: signer}, signer: signer}
+}
+
+func (ls *lowSSigner) Sign(msgToHashAndSign []byte) (*SignatureData, error) {
+	return normalized(ls.signer.Sign(msgToHashAndSign))
+}
+
+func (ls *lowSSignerDirect) SignDirect(message []byte) (*SignatureData, error) {
+	return normalized(ls.signer.SignDirect(message))
+}
+
+func normalized(sig *SignatureData, err error) (*SignatureData, error) {
+	if err != nil {
+		return nil, err
+	}
+	sig.NormalizeS()
+	return sig, nil
+}
@@ -0,0 +1,126 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secp256k1
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+
+	btcec "github.com/btcsuite/btcd/btcec/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// highSSigner produces the malleable high S equivalent of each signature of the key
+type highSSigner struct {
+	keypair *KeyPair
+	err     error
+}
+
+func highS(sig *SignatureData) *SignatureData {
+	return &SignatureData{
+		V: big.NewInt(55 - sig.V.Int64()), // 27 <-> 28
+		R: sig.R,
+		S: new(big.Int).Sub(btcec.S256().N, sig.S),
+	}
+}
+
+func (hs *highSSigner) Sign(message []byte) (*SignatureData, error) {
+	if hs.err != nil {
+		return nil, hs.err
+	}
+	sig, err := hs.keypair.Sign(message)
+	return highS(sig), err
+}
+
+func (hs *highSSigner) SignDirect(message []byte) (*SignatureData, error) {
+	sig, err := hs.keypair.SignDirect(message)
+	return highS(sig), err
+}
+
+func TestLowSNormalize(t *testing.T) {
+	keypair := testKeyPair(t)
+	sig, err := keypair.Sign([]byte(sampleMessage))
+	assert.NoError(t, err)
+	assert.True(t, sig.IsLowS())
+
+	malleable := highS(sig)
+	assert.False(t, malleable.IsLowS())
+
+	// Both recover to the same signer, unless high S is rejected
+	addr, err := malleable.Recover([]byte(sampleMessage), 0)
+	assert.NoError(t, err)
+	assert.Equal(t, keypair.Address, *addr)
+	_, err = malleable.RecoverLowS([]byte(sampleMessage), 0)
+	assert.Regexp(t, "FF22178", err)
+
+	malleable.NormalizeS()
+	assert.Equal(t, sig, malleable)
+	addr, err = malleable.RecoverLowS([]byte(sampleMessage), 0)
+	assert.NoError(t, err)
+	assert.Equal(t, keypair.Address, *addr)
+
+	// Normalizing a low S signature does nothing
+	malleable.NormalizeS()
+	assert.Equal(t, sig, malleable)
+}
+
+func TestLowSNormalizeV(t *testing.T) {
+	for _, v := range [][2]int64{{0, 1}, {1, 0}, {27, 28}, {28, 27}, {2037, 2038}, {2038, 2037}} {
+		sig := &SignatureData{V: big.NewInt(v[0]), R: big.NewInt(1), S: new(big.Int).Sub(btcec.S256().N, big.NewInt(1))}
+		sig.NormalizeS()
+		assert.Equal(t, v[1], sig.V.Int64())
+		assert.Equal(t, int64(1), sig.S.Int64())
+	}
+}
+
+func TestLowSRecoverDirect(t *testing.T) {
+	keypair := testKeyPair(t)
+	hash := make([]byte, 32)
+	sig, err := keypair.SignDirect(hash)
+	assert.NoError(t, err)
+
+	addr, err := sig.RecoverDirectLowS(hash, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, keypair.Address, *addr)
+
+	_, err = highS(sig).RecoverDirectLowS(hash, 0)
+	assert.Regexp(t, "FF22178", err)
+}
+
+func TestLowSSigner(t *testing.T) {
+	keypair := testKeyPair(t)
+	hs := &highSSigner{keypair: keypair}
+
+	sig, err := NewLowSSigner(hs).Sign([]byte(sampleMessage))
+	assert.NoError(t, err)
+	assert.True(t, sig.IsLowS())
+	addr, err := sig.RecoverLowS([]byte(sampleMessage), 0)
+	assert.NoError(t, err)
+	assert.Equal(t, keypair.Address, *addr)
+
+	hash := make([]byte, 32)
+	sig, err = NewLowSSignerDirect(hs).SignDirect(hash)
+	assert.NoError(t, err)
+	addr, err = sig.RecoverDirectLowS(hash, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, keypair.Address, *addr)
+
+	hs.err = fmt.Errorf("pop")
+	_, err = NewLowSSignerDirect(hs).Sign([]byte(sampleMessage))
+	assert.Regexp(t, "pop", err)
+}
@@ -0,0 +1,68 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secp256k1
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"golang.org/x/crypto/sha3"
+)
+
+// PersonalMessage applies the EIP-191 version 0x45 ("E") prefix used by personal_sign, so that
+// a signed message can never be a valid signed transaction
+func PersonalMessage(message []byte) []byte {
+	return append([]byte(fmt.Sprintf("\x19Ethereum Signed Message:\n%d", len(message))), message...)
+}
+
+// PersonalMessageHash returns the hash that is signed by personal_sign for a message
+func PersonalMessageHash(message []byte) []byte {
+	hash := sha3.NewLegacyKeccak256()
+	hash.Write(PersonalMessage(message))
+	return hash.Sum(nil)
+}
+
+// SignMessage signs a message with the EIP-191 prefix, as personal_sign does, with a V value of 27 or 28
+func (k *KeyPair) SignMessage(message []byte) (*SignatureData, error) {
+	return k.Sign(PersonalMessage(message))
+}
+
+// VerifyMessage checks the signature of a message with the EIP-191 prefix was made by this key
+func (k *KeyPair) VerifyMessage(message []byte, sig *SignatureData) error {
+	return VerifyMessage(message, sig, k.Address)
+}
+
+// RecoverMessage returns the address that signed a message with the EIP-191 prefix.
+// The V value of the signature can be 27/28 or 0/1.
+func RecoverMessage(message []byte, sig *SignatureData) (*ethtypes.Address0xHex, error) {
+	return sig.Recover(PersonalMessage(message), -1)
+}
+
+// VerifyMessage checks the signature of a message with the EIP-191 prefix was made by the address
+func VerifyMessage(message []byte, sig *SignatureData, address ethtypes.Address0xHex) error {
+	signer, err := RecoverMessage(message, sig)
+	if err != nil {
+		return err
+	}
+	if *signer != address {
+		return i18n.NewError(context.Background(), signermsgs.MsgSignerMismatch, signer, &address)
+	}
+	return nil
+}
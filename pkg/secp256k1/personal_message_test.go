@@ -0,0 +1,73 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secp256k1
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyPairSignVerifyMessage(t *testing.T) {
+	keypair := testKeyPair(t)
+	sig, err := keypair.SignMessage([]byte(sampleMessage))
+	assert.NoError(t, err)
+
+	// Same as the web3j test vector, which applies the prefix itself
+	assert.Equal(t, int64(28), sig.V.Int64())
+	assert.Equal(t, "0464eee9e2fe1a10ffe48c78b80de1ed8dcf996f3f60955cb2e03cb21903d930", ((ethtypes.HexBytesPlain)(sig.R.Bytes())).String())
+	assert.Equal(t, "06624da478b3f862582e85b31c6a21c6cae2eee2bd50f55c93c4faad9d9c8d7f", ((ethtypes.HexBytesPlain)(sig.S.Bytes())).String())
+
+	assert.NoError(t, keypair.VerifyMessage([]byte(sampleMessage), sig))
+	assert.NoError(t, VerifyMessage([]byte(sampleMessage), sig, *ethtypes.MustNewAddress(sampleAddress)))
+
+	hashSig, err := keypair.SignDirect(PersonalMessageHash([]byte(sampleMessage)))
+	assert.NoError(t, err)
+	assert.Equal(t, sig, hashSig)
+
+	addr, err := RecoverMessage([]byte(sampleMessage), sig)
+	assert.NoError(t, err)
+	assert.Equal(t, sampleAddress, addr.String())
+
+	// 0/1 V values are accepted too
+	sig.V = big.NewInt(1)
+	assert.NoError(t, keypair.VerifyMessage([]byte(sampleMessage), sig))
+}
+
+func TestVerifyMessageWrongSigner(t *testing.T) {
+	keypair := testKeyPair(t)
+	sig, err := keypair.SignMessage([]byte(sampleMessage))
+	assert.NoError(t, err)
+
+	err = newTestKeyPair(t).VerifyMessage([]byte(sampleMessage), sig)
+	assert.Regexp(t, "FF22180.*"+sampleAddress, err)
+
+	err = keypair.VerifyMessage([]byte("another message"), sig)
+	assert.Regexp(t, "FF22180", err)
+}
+
+func TestVerifyMessageBadSignature(t *testing.T) {
+	keypair := testKeyPair(t)
+	sig, err := keypair.SignMessage([]byte(sampleMessage))
+	assert.NoError(t, err)
+
+	sig.V = big.NewInt(42)
+	err = keypair.VerifyMessage([]byte(sampleMessage), sig)
+	assert.Regexp(t, "invalid V value", err)
+}
@@ -0,0 +1,75 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secp256k1
+
+import (
+	"context"
+
+	btcec "github.com/btcsuite/btcd/btcec/v2"
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+)
+
+const (
+	PublicKeyCompressedLength   = 33 // 02/03 prefix for the Y-parity, followed by X
+	PublicKeyUncompressedLength = 65 // 04 prefix, followed by X and Y
+	PublicKeyRawLength          = 64 // X and Y with no prefix, as used to derive an address
+)
+
+// ParsePublicKey returns the public key, to verify signatures or derive an address, from any of the
+// compressed, uncompressed or raw X||Y encodings - so keys from a KMS or HSM can be used whichever they return.
+func ParsePublicKey(ctx context.Context, b []byte) (*btcec.PublicKey, error) {
+	if len(b) == PublicKeyRawLength {
+		b = append([]byte{0x04}, b...)
+	}
+	pubKey, err := btcec.ParsePubKey(b)
+	if err != nil {
+		return nil, i18n.NewError(ctx, signermsgs.MsgInvalidPublicKey, err)
+	}
+	return pubKey, nil
+}
+
+// CompressPublicKey converts a public key in any encoding to the 33 byte compressed form
+func CompressPublicKey(ctx context.Context, b []byte) ([]byte, error) {
+	pubKey, err := ParsePublicKey(ctx, b)
+	if err != nil {
+		return nil, err
+	}
+	return pubKey.SerializeCompressed(), nil
+}
+
+// DecompressPublicKey converts a public key in any encoding to the 65 byte uncompressed form
+func DecompressPublicKey(ctx context.Context, b []byte) ([]byte, error) {
+	pubKey, err := ParsePublicKey(ctx, b)
+	if err != nil {
+		return nil, err
+	}
+	return pubKey.SerializeUncompressed(), nil
+}
+
+// RawPublicKey converts a public key in any encoding to the 64 byte X||Y form
+func RawPublicKey(ctx context.Context, b []byte) ([]byte, error) {
+	pubKey, err := ParsePublicKey(ctx, b)
+	if err != nil {
+		return nil, err
+	}
+	return pubKey.SerializeUncompressed()[1:], nil
+}
+
+func (k *KeyPair) PublicKeyCompressedBytes() []byte {
+	return k.PublicKey.SerializeCompressed()
+}
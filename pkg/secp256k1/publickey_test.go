@@ -0,0 +1,84 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secp256k1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPublicKeyEncodings(t *testing.T) {
+	ctx := context.Background()
+	keypair := testKeyPair(t)
+
+	raw := keypair.PublicKeyBytes()
+	compressed := keypair.PublicKeyCompressedBytes()
+	uncompressed := keypair.PublicKey.SerializeUncompressed()
+	assert.Len(t, raw, PublicKeyRawLength)
+	assert.Len(t, compressed, PublicKeyCompressedLength)
+	assert.Len(t, uncompressed, PublicKeyUncompressedLength)
+	assert.Equal(t, samplePublicKey, ethtypes.HexBytes0xPrefix(raw).String())
+
+	for _, b := range [][]byte{raw, compressed, uncompressed} {
+		pubKey, err := ParsePublicKey(ctx, b)
+		assert.NoError(t, err)
+		assert.True(t, keypair.PublicKey.IsEqual(pubKey))
+		assert.Equal(t, sampleAddress, PublicKeyToAddress(pubKey).String())
+
+		c, err := CompressPublicKey(ctx, b)
+		assert.NoError(t, err)
+		assert.Equal(t, compressed, c)
+		u, err := DecompressPublicKey(ctx, b)
+		assert.NoError(t, err)
+		assert.Equal(t, uncompressed, u)
+		r, err := RawPublicKey(ctx, b)
+		assert.NoError(t, err)
+		assert.Equal(t, raw, r)
+	}
+}
+
+func TestPublicKeyVerifySignature(t *testing.T) {
+	keypair := testKeyPair(t)
+	sig, err := keypair.Sign([]byte(sampleMessage))
+	assert.NoError(t, err)
+
+	pubKey, err := ParsePublicKey(context.Background(), keypair.PublicKeyCompressedBytes())
+	assert.NoError(t, err)
+	addr, err := sig.Recover([]byte(sampleMessage), 0)
+	assert.NoError(t, err)
+	assert.Equal(t, *PublicKeyToAddress(pubKey), *addr)
+}
+
+func TestPublicKeyErrors(t *testing.T) {
+	ctx := context.Background()
+
+	_, err := ParsePublicKey(ctx, []byte{0x02, 0x01})
+	assert.Regexp(t, "FF22177", err)
+
+	_, err = ParsePublicKey(ctx, make([]byte, PublicKeyRawLength))
+	assert.Regexp(t, "FF22177", err)
+
+	_, err = CompressPublicKey(ctx, nil)
+	assert.Regexp(t, "FF22177", err)
+	_, err = DecompressPublicKey(ctx, nil)
+	assert.Regexp(t, "FF22177", err)
+	_, err = RawPublicKey(ctx, nil)
+	assert.Regexp(t, "FF22177", err)
+}
@@ -0,0 +1,117 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secp256k1
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+)
+
+// A recovery ID is the Y-parity (0 or 1) of the point R used in the signature, which Ethereum encodes
+// in the V value of a signature in different ways depending on the context:
+//
+//   - 27/28 for legacy transactions, and messages signed with personal_sign or EIP-712
+//   - 2*ChainID + 35/36 for EIP-155 transactions
+//   - 0/1 for typed transactions (EIP-2930, EIP-1559 etc.) which sign the chain ID as part of the payload
+const (
+	LegacyVOffset = 27
+	EIP155VOffset = 35
+)
+
+var (
+	bigLegacyVOffset = big.NewInt(LegacyVOffset)
+	bigEIP155VOffset = big.NewInt(EIP155VOffset)
+)
+
+func checkRecoveryID(recoveryID byte) error {
+	if recoveryID > 1 {
+		return i18n.NewError(context.Background(), signermsgs.MsgInvalidRecoveryID, recoveryID)
+	}
+	return nil
+}
+
+// LegacyV returns the 27/28 V value for a recovery ID
+func LegacyV(recoveryID byte) (*big.Int, error) {
+	if err := checkRecoveryID(recoveryID); err != nil {
+		return nil, err
+	}
+	return big.NewInt(LegacyVOffset + int64(recoveryID)), nil
+}
+
+// EIP155V returns the 2*ChainID + 35/36 V value for a recovery ID
+func EIP155V(recoveryID byte, chainID int64) (*big.Int, error) {
+	if err := checkRecoveryID(recoveryID); err != nil {
+		return nil, err
+	}
+	v := new(big.Int).Lsh(big.NewInt(chainID), 1)
+	return v.Add(v, big.NewInt(EIP155VOffset+int64(recoveryID))), nil
+}
+
+// YParityV returns the 0/1 V value of a typed transaction for a recovery ID
+func YParityV(recoveryID byte) (*big.Int, error) {
+	if err := checkRecoveryID(recoveryID); err != nil {
+		return nil, err
+	}
+	return big.NewInt(int64(recoveryID)), nil
+}
+
+// RecoveryIDFromV returns the recovery ID from a V value in any of the encodings - 0/1, 27/28, or EIP-155
+// for the given chain ID (including just the low byte, as stored in a 65 byte R,S,V signature).
+// An EIP-155 V value for any other chain ID is an error.
+func RecoveryIDFromV(v *big.Int, chainID int64) (byte, error) {
+	if v != nil && v.IsInt64() {
+		iv := v.Int64()
+		switch iv {
+		case 0, 1:
+			return byte(iv), nil
+		case LegacyVOffset, LegacyVOffset + 1:
+			return byte(iv - LegacyVOffset), nil
+		}
+		if eip155ChainID, recoveryID, err := ChainIDFromEIP155V(v); err == nil && eip155ChainID == chainID {
+			return recoveryID, nil
+		}
+		// A 65 byte R,S,V signature only holds the low byte of an EIP-155 V value
+		if iv >= 0 && iv < 256 {
+			for recoveryID := byte(0); recoveryID <= 1; recoveryID++ {
+				if byte(iv) == byte(chainID*2+EIP155VOffset+int64(recoveryID)) {
+					return recoveryID, nil
+				}
+			}
+		}
+	}
+	return 0, i18n.NewError(context.Background(), signermsgs.MsgInvalidSignatureV, chainID, v)
+}
+
+// ChainIDFromEIP155V returns the chain ID and recovery ID from an EIP-155 V value
+func ChainIDFromEIP155V(v *big.Int) (int64, byte, error) {
+	if v == nil || v.Cmp(bigEIP155VOffset) < 0 {
+		return 0, 0, i18n.NewError(context.Background(), signermsgs.MsgInvalidEIP155V, v)
+	}
+	chainID, recoveryID := new(big.Int).DivMod(new(big.Int).Sub(v, bigEIP155VOffset), big.NewInt(2), new(big.Int))
+	if !chainID.IsInt64() {
+		return 0, 0, i18n.NewError(context.Background(), signermsgs.MsgInvalidEIP155V, v)
+	}
+	return chainID.Int64(), byte(recoveryID.Int64()), nil
+}
+
+// RecoveryID returns the recovery ID of the signature, from a V value in any of the encodings
+func (s *SignatureData) RecoveryID(chainID int64) (byte, error) {
+	return RecoveryIDFromV(s.V, chainID)
+}
@@ -0,0 +1,107 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secp256k1
+
+import (
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVEncodings(t *testing.T) {
+	for recoveryID := byte(0); recoveryID <= 1; recoveryID++ {
+		legacyV, err := LegacyV(recoveryID)
+		assert.NoError(t, err)
+		assert.Equal(t, 27+int64(recoveryID), legacyV.Int64())
+
+		eip155V, err := EIP155V(recoveryID, 1001)
+		assert.NoError(t, err)
+		assert.Equal(t, 2037+int64(recoveryID), eip155V.Int64())
+
+		yParityV, err := YParityV(recoveryID)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(recoveryID), yParityV.Int64())
+
+		for _, v := range []*big.Int{legacyV, eip155V, yParityV, big.NewInt(eip155V.Int64() & 0xff)} {
+			r, err := RecoveryIDFromV(v, 1001)
+			assert.NoError(t, err)
+			assert.Equal(t, recoveryID, r)
+		}
+
+		chainID, r, err := ChainIDFromEIP155V(eip155V)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1001), chainID)
+		assert.Equal(t, recoveryID, r)
+	}
+}
+
+func TestVEncodingsLargeChainID(t *testing.T) {
+	// V values can exceed 64 bits for the largest chain IDs
+	v, err := EIP155V(1, math.MaxInt64)
+	assert.NoError(t, err)
+	assert.False(t, v.IsInt64())
+	chainID, recoveryID, err := ChainIDFromEIP155V(v)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(math.MaxInt64), chainID)
+	assert.Equal(t, byte(1), recoveryID)
+
+	_, _, err = ChainIDFromEIP155V(new(big.Int).Lsh(v, 1))
+	assert.Regexp(t, "FF22183", err)
+}
+
+func TestVEncodingsBadRecoveryID(t *testing.T) {
+	_, err := LegacyV(2)
+	assert.Regexp(t, "FF22181", err)
+	_, err = EIP155V(2, 1001)
+	assert.Regexp(t, "FF22181", err)
+	_, err = YParityV(2)
+	assert.Regexp(t, "FF22181", err)
+}
+
+func TestRecoveryIDFromBadV(t *testing.T) {
+	eip155V, err := EIP155V(0, 1001)
+	assert.NoError(t, err)
+	for _, v := range []*big.Int{
+		nil,
+		big.NewInt(-1),
+		big.NewInt(2),
+		big.NewInt(29),
+		eip155V,                       // wrong chain
+		big.NewInt(0xff),              // low byte for the wrong chain
+		new(big.Int).Lsh(eip155V, 64), // not an int64
+	} {
+		_, err := RecoveryIDFromV(v, 1002)
+		assert.Regexp(t, "FF22182", err)
+	}
+
+	_, _, err = ChainIDFromEIP155V(big.NewInt(34))
+	assert.Regexp(t, "FF22183", err)
+	_, _, err = ChainIDFromEIP155V(nil)
+	assert.Regexp(t, "FF22183", err)
+}
+
+func TestSignatureRecoveryID(t *testing.T) {
+	sig, err := testKeyPair(t).Sign([]byte(sampleMessage))
+	assert.NoError(t, err)
+	expected := byte(sig.V.Int64() - 27)
+	sig.UpdateEIP155(1001)
+	recoveryID, err := sig.RecoveryID(1001)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, recoveryID)
+}
@@ -0,0 +1,80 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secp256k1
+
+import (
+	"context"
+
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+)
+
+const (
+	// SchnorrSignatureLength is the length of a BIP-340 signature
+	SchnorrSignatureLength = 64
+	// XOnlyPublicKeyLength is the length of a BIP-340 x-only public key
+	XOnlyPublicKeyLength = 32
+	// SchnorrAuxRandLength is the length of the auxiliary randomness for BIP-340 nonce generation
+	SchnorrAuxRandLength = 32
+)
+
+// XOnlyPublicKey returns the 32 byte BIP-340 x-only public key, which is the X coordinate of the
+// public key (the signing key is negated as required, so the implicit Y coordinate is even)
+func (k *KeyPair) XOnlyPublicKey() []byte {
+	return schnorr.SerializePubKey(k.PublicKey)
+}
+
+// SignSchnorr signs a 32 byte hash with a BIP-340 Schnorr signature, returning the 64 byte signature.
+// The nonce is generated deterministically using RFC 6979, so no randomness is required.
+func (k *KeyPair) SignSchnorr(hash []byte) ([]byte, error) {
+	return k.signSchnorr(hash)
+}
+
+// SignSchnorrWithAuxRand signs a 32 byte hash with a BIP-340 Schnorr signature, using the nonce
+// generation defined by BIP-340 with 32 bytes of auxiliary randomness - as used in the BIP-340 test vectors
+func (k *KeyPair) SignSchnorrWithAuxRand(hash, auxRand []byte) ([]byte, error) {
+	if len(auxRand) != SchnorrAuxRandLength {
+		return nil, i18n.NewError(context.Background(), signermsgs.MsgSchnorrSignFailed, "auxiliary randomness must be 32 bytes")
+	}
+	return k.signSchnorr(hash, schnorr.CustomNonce(([SchnorrAuxRandLength]byte)(auxRand)))
+}
+
+func (k *KeyPair) signSchnorr(hash []byte, opts ...schnorr.SignOption) ([]byte, error) {
+	sig, err := schnorr.Sign(k.PrivateKey, hash, opts...)
+	if err != nil {
+		return nil, i18n.NewError(context.Background(), signermsgs.MsgSchnorrSignFailed, err)
+	}
+	return sig.Serialize(), nil
+}
+
+// VerifySchnorr checks a 64 byte BIP-340 Schnorr signature of a 32 byte hash, against a 32 byte x-only public key
+func VerifySchnorr(xOnlyPublicKey, hash, sig []byte) error {
+	ctx := context.Background()
+	pubKey, err := schnorr.ParsePubKey(xOnlyPublicKey)
+	if err != nil {
+		return i18n.NewError(ctx, signermsgs.MsgInvalidSchnorrPublicKey, err)
+	}
+	parsedSig, err := schnorr.ParseSignature(sig)
+	if err != nil {
+		return i18n.NewError(ctx, signermsgs.MsgInvalidSchnorrSignature, err)
+	}
+	if !parsedSig.Verify(hash, pubKey) {
+		return i18n.NewError(ctx, signermsgs.MsgSchnorrVerifyFailed)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secp256k1
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func mustDecodeHex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	assert.NoError(t, err)
+	return b
+}
+
+// Test vectors from BIP-340
+func TestSchnorrBIP340Vectors(t *testing.T) {
+	for _, tv := range []struct {
+		secretKey, publicKey, auxRand, message, signature string
+	}{
+		{
+			secretKey: "0000000000000000000000000000000000000000000000000000000000000003",
+			publicKey: "f9308a019258c31049344f85f89d5229b531c845836f99b08601f113bce036f9",
+			auxRand:   "0000000000000000000000000000000000000000000000000000000000000000",
+			message:   "0000000000000000000000000000000000000000000000000000000000000000",
+			signature: "e907831f80848d1069a5371b402410364bdf1c5f8307b0084c55f1ce2dca821525f66a4a85ea8b71e482a74f382d2ce5ebeee8fdb2172f477df4900d310536c0",
+		},
+		{
+			secretKey: "b7e151628aed2a6abf7158809cf4f3c762e7160f38b4da56a784d9045190cfef",
+			publicKey: "dff1d77f2a671c5f36183726db2341be58feae1da2deced843240f7b502ba659",
+			auxRand:   "0000000000000000000000000000000000000000000000000000000000000001",
+			message:   "243f6a8885a308d313198a2e03707344a4093822299f31d0082efa98ec4e6c89",
+			signature: "6896bd60eeae296db48a229ff71dfe071bde413e6d43f917dc8dcf8c78de33418906d11ac976abccb20b091292bff4ea897efcb639ea871cfa95f6de339e4b0a",
+		},
+	} {
+		keypair := KeyPairFromBytes(mustDecodeHex(t, tv.secretKey))
+		assert.Equal(t, tv.publicKey, hex.EncodeToString(keypair.XOnlyPublicKey()))
+
+		sig, err := keypair.SignSchnorrWithAuxRand(mustDecodeHex(t, tv.message), mustDecodeHex(t, tv.auxRand))
+		assert.NoError(t, err)
+		assert.Equal(t, tv.signature, hex.EncodeToString(sig))
+
+		assert.NoError(t, VerifySchnorr(keypair.XOnlyPublicKey(), mustDecodeHex(t, tv.message), sig))
+	}
+}
+
+func TestSchnorrDeterministic(t *testing.T) {
+	keypair := newTestKeyPair(t)
+	hash := testHash()
+
+	sig1, err := keypair.SignSchnorr(hash)
+	assert.NoError(t, err)
+	sig2, err := keypair.SignSchnorr(hash)
+	assert.NoError(t, err)
+	assert.Equal(t, sig1, sig2)
+	assert.Len(t, sig1, SchnorrSignatureLength)
+	assert.Len(t, keypair.XOnlyPublicKey(), XOnlyPublicKeyLength)
+
+	assert.NoError(t, VerifySchnorr(keypair.XOnlyPublicKey(), hash, sig1))
+}
+
+func TestSchnorrSignFail(t *testing.T) {
+	keypair := testKeyPair(t)
+
+	_, err := keypair.SignSchnorr([]byte("not a hash"))
+	assert.Regexp(t, "FF22184", err)
+
+	_, err = keypair.SignSchnorrWithAuxRand(testHash(), []byte{0x01})
+	assert.Regexp(t, "FF22184.*32 bytes", err)
+}
+
+func TestSchnorrVerifyFail(t *testing.T) {
+	keypair := testKeyPair(t)
+	hash := testHash()
+	sig, err := keypair.SignSchnorr(hash)
+	assert.NoError(t, err)
+
+	err = VerifySchnorr(newTestKeyPair(t).XOnlyPublicKey(), hash, sig)
+	assert.Regexp(t, "FF22187", err)
+
+	err = VerifySchnorr(keypair.XOnlyPublicKey(), make([]byte, 32), sig)
+	assert.Regexp(t, "FF22187", err)
+
+	err = VerifySchnorr([]byte{0x01}, hash, sig)
+	assert.Regexp(t, "FF22185", err)
+
+	err = VerifySchnorr(keypair.XOnlyPublicKey(), hash, sig[0:63])
+	assert.Regexp(t, "FF22186", err)
+}
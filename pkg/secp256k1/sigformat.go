@@ -0,0 +1,190 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secp256k1
+
+import (
+	"context"
+	"encoding/asn1"
+	"fmt"
+	"math/big"
+
+	btcec "github.com/btcsuite/btcd/btcec/v2"
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+)
+
+// Signatures from a KMS, HSM or other non-Ethereum system are usually an ASN.1 DER encoded sequence of
+// R and S, or a fixed length R||S. Neither includes the recovery ID that Ethereum needs for the V value,
+// so that is computed by finding which of the two candidates recovers to the public key of the signer.
+const (
+	CompactRSLength  = 64 // R||S
+	CompactRSVLength = 65 // R||S||V
+)
+
+type derSignature struct {
+	R *big.Int
+	S *big.Int
+}
+
+// DER returns the ASN.1 DER encoding of R and S, as used by X.509, PKCS#11 and most KMS/HSM APIs (V is not included)
+func (s *SignatureData) DER() []byte {
+	der, _ := asn1.Marshal(derSignature{R: s.R, S: s.S})
+	return der
+}
+
+// CompactRS returns the 64 byte R||S form of the signature (V is not included)
+func (s *SignatureData) CompactRS() []byte {
+	signatureBytes := make([]byte, CompactRSLength)
+	s.R.FillBytes(signatureBytes[0:32])
+	s.S.FillBytes(signatureBytes[32:64])
+	return signatureBytes
+}
+
+// ParseDER returns R and S from an ASN.1 DER encoded signature, which must be strictly encoded
+// with both values in the range 1 to N-1
+func ParseDER(ctx context.Context, der []byte) (r, s *big.Int, err error) {
+	var sig derSignature
+	rest, err := asn1.Unmarshal(der, &sig)
+	if err == nil && len(rest) > 0 {
+		err = fmt.Errorf("%d bytes of trailing data", len(rest))
+	}
+	if err == nil && (!inCurveOrder(sig.R) || !inCurveOrder(sig.S)) {
+		err = fmt.Errorf("R and S must be between 1 and N-1")
+	}
+	if err != nil {
+		return nil, nil, i18n.NewError(ctx, signermsgs.MsgInvalidDERSignature, err)
+	}
+	return sig.R, sig.S, nil
+}
+
+// ParseCompactRS returns R and S from a 64 byte R||S signature
+func ParseCompactRS(ctx context.Context, rs []byte) (r, s *big.Int, err error) {
+	if len(rs) != CompactRSLength {
+		return nil, nil, i18n.NewError(ctx, signermsgs.MsgSigningInvalidCompactRS, len(rs))
+	}
+	return new(big.Int).SetBytes(rs[0:32]), new(big.Int).SetBytes(rs[32:64]), nil
+}
+
+func inCurveOrder(i *big.Int) bool {
+	return i.Sign() > 0 && i.Cmp(btcec.S256().N) < 0
+}
+
+// RecoveryIDForPublicKey returns the recovery ID for which the signature R,S over the hash recovers to
+// the supplied public key, or an error if the signature was not produced by that key
+func RecoveryIDForPublicKey(ctx context.Context, r, s *big.Int, hash []byte, pubKey *btcec.PublicKey) (byte, error) {
+	signatureBytes := make([]byte, 65)
+	r.FillBytes(signatureBytes[1:33])
+	s.FillBytes(signatureBytes[33:65])
+	for recoveryID := byte(0); recoveryID <= 1; recoveryID++ {
+		signatureBytes[0] = LegacyVOffset + recoveryID
+		recovered, err := recoverCompact(signatureBytes, hash)
+		if err == nil && recovered.IsEqual(pubKey) {
+			return recoveryID, nil
+		}
+	}
+	return 0, i18n.NewError(ctx, signermsgs.MsgSignatureNotFromPublicKey)
+}
+
+// NewSignatureData builds a signature with a legacy 27/28 V value from R and S, as returned by a KMS or HSM
+// that signed the hash with the supplied public key. S is normalized to low S form as EIP-2 requires.
+func NewSignatureData(ctx context.Context, r, s *big.Int, hash []byte, pubKey *btcec.PublicKey) (*SignatureData, error) {
+	sig := &SignatureData{R: r, S: s}
+	if !sig.IsLowS() {
+		sig.S = new(big.Int).Sub(btcec.S256().N, s)
+	}
+	recoveryID, err := RecoveryIDForPublicKey(ctx, sig.R, sig.S, hash, pubKey)
+	if err != nil {
+		return nil, err
+	}
+	sig.V, _ = LegacyV(recoveryID)
+	return sig, nil
+}
+
+// DecodeDER decodes an ASN.1 DER encoded signature of the hash by the supplied public key, to a
+// low S signature with a legacy 27/28 V value
+func DecodeDER(ctx context.Context, der, hash []byte, pubKey *btcec.PublicKey) (*SignatureData, error) {
+	r, s, err := ParseDER(ctx, der)
+	if err != nil {
+		return nil, err
+	}
+	return NewSignatureData(ctx, r, s, hash, pubKey)
+}
+
+// DecodeCompactRS decodes a 64 byte R||S signature of the hash by the supplied public key, to a
+// low S signature with a legacy 27/28 V value
+func DecodeCompactRS(ctx context.Context, rs, hash []byte, pubKey *btcec.PublicKey) (*SignatureData, error) {
+	r, s, err := ParseCompactRS(ctx, rs)
+	if err != nil {
+		return nil, err
+	}
+	return NewSignatureData(ctx, r, s, hash, pubKey)
+}
+
+// DERToCompactRS converts an ASN.1 DER encoded signature to the 64 byte R||S form, with no normalization of S
+func DERToCompactRS(ctx context.Context, der []byte) ([]byte, error) {
+	r, s, err := ParseDER(ctx, der)
+	if err != nil {
+		return nil, err
+	}
+	return (&SignatureData{R: r, S: s}).CompactRS(), nil
+}
+
+// CompactRSToDER converts a 64 byte R||S signature to the ASN.1 DER encoded form
+func CompactRSToDER(ctx context.Context, rs []byte) ([]byte, error) {
+	r, s, err := ParseCompactRS(ctx, rs)
+	if err != nil {
+		return nil, err
+	}
+	return (&SignatureData{R: r, S: s}).DER(), nil
+}
+
+// DERToCompactRSV converts an ASN.1 DER encoded signature of the hash by the supplied public key,
+// to the 65 byte R||S||V form with a low S value and a legacy 27/28 V value
+func DERToCompactRSV(ctx context.Context, der, hash []byte, pubKey *btcec.PublicKey) ([]byte, error) {
+	sig, err := DecodeDER(ctx, der, hash, pubKey)
+	if err != nil {
+		return nil, err
+	}
+	return sig.CompactRSV(), nil
+}
+
+// CompactRSVToDER converts a 65 byte R||S||V signature to the ASN.1 DER encoded form, dropping V
+func CompactRSVToDER(ctx context.Context, rsv []byte) ([]byte, error) {
+	sig, err := DecodeCompactRSV(ctx, rsv)
+	if err != nil {
+		return nil, err
+	}
+	return sig.DER(), nil
+}
+
+// CompactRSToCompactRSV converts a 64 byte R||S signature of the hash by the supplied public key,
+// to the 65 byte R||S||V form with a low S value and a legacy 27/28 V value
+func CompactRSToCompactRSV(ctx context.Context, rs, hash []byte, pubKey *btcec.PublicKey) ([]byte, error) {
+	sig, err := DecodeCompactRS(ctx, rs, hash, pubKey)
+	if err != nil {
+		return nil, err
+	}
+	return sig.CompactRSV(), nil
+}
+
+// CompactRSVToCompactRS converts a 65 byte R||S||V signature to the 64 byte R||S form, dropping V
+func CompactRSVToCompactRS(ctx context.Context, rsv []byte) ([]byte, error) {
+	if len(rsv) != CompactRSVLength {
+		return nil, i18n.NewError(ctx, signermsgs.MsgSigningInvalidCompactRSV, len(rsv))
+	}
+	return append([]byte{}, rsv[0:CompactRSLength]...), nil
+}
@@ -0,0 +1,173 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secp256k1
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	btcec "github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/ecdsa"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDERRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	keypair := testKeyPair(t)
+	hash := testHash()
+
+	sig, err := keypair.SignDirect(hash)
+	assert.NoError(t, err)
+
+	// Check our encoding matches that of btcec
+	der := sig.DER()
+	assert.Equal(t, ecdsa.Sign(keypair.PrivateKey, hash).Serialize(), der)
+
+	sig2, err := DecodeDER(ctx, der, hash, keypair.PublicKey)
+	assert.NoError(t, err)
+	assert.Equal(t, sig, sig2)
+
+	rsv, err := DERToCompactRSV(ctx, der, hash, keypair.PublicKey)
+	assert.NoError(t, err)
+	assert.Equal(t, sig.CompactRSV(), rsv)
+
+	der2, err := CompactRSVToDER(ctx, rsv)
+	assert.NoError(t, err)
+	assert.Equal(t, der, der2)
+
+	rs, err := DERToCompactRS(ctx, der)
+	assert.NoError(t, err)
+	assert.Equal(t, sig.CompactRS(), rs)
+
+	der2, err = CompactRSToDER(ctx, rs)
+	assert.NoError(t, err)
+	assert.Equal(t, der, der2)
+}
+
+func TestCompactRSRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	hash := testHash()
+
+	// Check both recovery IDs are found
+	recoveryIDs := map[byte]bool{}
+	for len(recoveryIDs) < 2 {
+		keypair := newTestKeyPair(t)
+		sig, err := keypair.SignDirect(hash)
+		assert.NoError(t, err)
+		recoveryID, err := sig.RecoveryID(0)
+		assert.NoError(t, err)
+		recoveryIDs[recoveryID] = true
+
+		rs := sig.CompactRS()
+		assert.Len(t, rs, CompactRSLength)
+
+		sig2, err := DecodeCompactRS(ctx, rs, hash, keypair.PublicKey)
+		assert.NoError(t, err)
+		assert.Equal(t, sig, sig2)
+
+		rsv, err := CompactRSToCompactRSV(ctx, rs, hash, keypair.PublicKey)
+		assert.NoError(t, err)
+		assert.Equal(t, sig.CompactRSV(), rsv)
+
+		rs2, err := CompactRSVToCompactRS(ctx, rsv)
+		assert.NoError(t, err)
+		assert.Equal(t, rs, rs2)
+	}
+}
+
+func TestDecodeDERHighS(t *testing.T) {
+	ctx := context.Background()
+	keypair := testKeyPair(t)
+	hash := testHash()
+
+	sig, err := keypair.SignDirect(hash)
+	assert.NoError(t, err)
+
+	// A KMS might return the high S form of the signature, which recovers with the other recovery ID
+	highS := &SignatureData{R: sig.R, S: new(big.Int).Sub(btcec.S256().N, sig.S)}
+	assert.False(t, highS.IsLowS())
+
+	sig2, err := DecodeDER(ctx, highS.DER(), hash, keypair.PublicKey)
+	assert.NoError(t, err)
+	assert.Equal(t, sig, sig2)
+}
+
+func TestDecodeDERWrongPublicKey(t *testing.T) {
+	ctx := context.Background()
+	keypair := testKeyPair(t)
+	hash := testHash()
+
+	sig, err := keypair.SignDirect(hash)
+	assert.NoError(t, err)
+
+	_, err = DecodeDER(ctx, sig.DER(), hash, newTestKeyPair(t).PublicKey)
+	assert.Regexp(t, "FF22190", err)
+
+	_, err = CompactRSToCompactRSV(ctx, sig.CompactRS(), hash, newTestKeyPair(t).PublicKey)
+	assert.Regexp(t, "FF22190", err)
+
+	_, err = DERToCompactRSV(ctx, sig.DER(), hash, newTestKeyPair(t).PublicKey)
+	assert.Regexp(t, "FF22190", err)
+}
+
+func TestParseDERErrors(t *testing.T) {
+	ctx := context.Background()
+
+	_, _, err := ParseDER(ctx, []byte{0x30})
+	assert.Regexp(t, "FF22188", err)
+
+	_, _, err = ParseDER(ctx, append((&SignatureData{R: big.NewInt(1), S: big.NewInt(1)}).DER(), 0x00))
+	assert.Regexp(t, "FF22188.*trailing", err)
+
+	_, _, err = ParseDER(ctx, (&SignatureData{R: big.NewInt(0), S: big.NewInt(1)}).DER())
+	assert.Regexp(t, "FF22188.*N-1", err)
+
+	_, _, err = ParseDER(ctx, (&SignatureData{R: big.NewInt(1), S: btcec.S256().N}).DER())
+	assert.Regexp(t, "FF22188.*N-1", err)
+
+	_, err = DecodeDER(ctx, []byte{}, testHash(), testKeyPair(t).PublicKey)
+	assert.Regexp(t, "FF22188", err)
+
+	_, err = DERToCompactRS(ctx, []byte{})
+	assert.Regexp(t, "FF22188", err)
+
+	_, err = DERToCompactRSV(ctx, []byte{}, testHash(), testKeyPair(t).PublicKey)
+	assert.Regexp(t, "FF22188", err)
+}
+
+func TestParseCompactRSErrors(t *testing.T) {
+	ctx := context.Background()
+
+	_, _, err := ParseCompactRS(ctx, make([]byte, 65))
+	assert.Regexp(t, "FF22189", err)
+
+	_, err = DecodeCompactRS(ctx, make([]byte, 65), testHash(), testKeyPair(t).PublicKey)
+	assert.Regexp(t, "FF22189", err)
+
+	_, err = CompactRSToDER(ctx, make([]byte, 65))
+	assert.Regexp(t, "FF22189", err)
+
+	_, err = CompactRSToCompactRSV(ctx, make([]byte, 65), testHash(), testKeyPair(t).PublicKey)
+	assert.Regexp(t, "FF22189", err)
+
+	_, err = CompactRSVToDER(ctx, make([]byte, 64))
+	assert.Regexp(t, "FF22087", err)
+
+	_, err = CompactRSVToCompactRS(ctx, make([]byte, 64))
+	assert.Regexp(t, "FF22087", err)
+}
@@ -21,7 +21,6 @@ import (
 	"fmt"
 	"math/big"
 
-	ecdsa "github.com/btcsuite/btcd/btcec/v2/ecdsa"
 	"github.com/hyperledger/firefly-common/pkg/i18n"
 	"github.com/hyperledger/firefly-signer/internal/signermsgs"
 	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
@@ -46,20 +45,11 @@ type SignerDirect interface {
 
 // getVNormalized returns the original 27/28 parity
 func (s *SignatureData) getVNormalized(chainID int64) (byte, error) {
-	v := s.V.Int64()
-	var vB byte
-	switch v {
-	case 0, 1:
-		vB = byte(v + 27)
-	case 27, 28:
-		vB = byte(v)
-	default:
-		vB = byte(v - 35 - (chainID * 2) + 27)
-	}
-	if vB != 27 && vB != 28 {
-		return 0, fmt.Errorf("invalid V value in signature (chain ID = %d, V = %d)", chainID, v)
+	recoveryID, err := s.RecoveryID(chainID)
+	if err != nil {
+		return 0, err
 	}
-	return vB, nil
+	return LegacyVOffset + recoveryID, nil
 }
 
 // EIP-155 rules - 2xChainID + 35 - starting point must be legacy 27/28
@@ -95,7 +85,7 @@ func (s *SignatureData) RecoverDirect(message []byte, chainID int64) (a *ethtype
 	}
 	s.R.FillBytes(signatureBytes[1:33])
 	s.S.FillBytes(signatureBytes[33:65])
-	pubKey, _, err := ecdsa.RecoverCompact(signatureBytes, message) // uses S256() by default
+	pubKey, err := recoverCompact(signatureBytes, message)
 	if err != nil {
 		return nil, err
 	}
@@ -132,13 +122,17 @@ func (k *KeyPair) Sign(message []byte) (ethSig *SignatureData, err error) {
 
 // SignDirect performs raw signing - give legacy 27/28 V values
 func (k *KeyPair) SignDirect(message []byte) (ethSig *SignatureData, err error) {
+	return k.signDirect(message, nil)
+}
+
+func (k *KeyPair) signDirect(message, extraEntropy []byte) (ethSig *SignatureData, err error) {
 	if k == nil {
 		return nil, fmt.Errorf("nil signer")
 	}
-	sig, err := ecdsa.SignCompact(k.PrivateKey, message, false) // uses S256() by default
+	sig, err := signCompact(k.PrivateKey, message, extraEntropy)
 	if err == nil {
-		// btcec does all the hard work for us. However, the interface of btcec is such
-		// that we need to unpack the result for Ethereum encoding.
+		// The signing backend does all the hard work for us, but returns a compact [V,R,S]
+		// that we need to unpack for Ethereum encoding.
 		ethSig = &SignatureData{
 			V: new(big.Int),
 			R: new(big.Int),
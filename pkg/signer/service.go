@@ -0,0 +1,195 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package signer
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-signer/internal/rpcserver"
+	"github.com/hyperledger/firefly-signer/internal/signerconfig"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/hyperledger/firefly-signer/pkg/approval"
+	"github.com/hyperledger/firefly-signer/pkg/eip712"
+	"github.com/hyperledger/firefly-signer/pkg/ethsigner"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/fips"
+	"github.com/hyperledger/firefly-signer/pkg/fswallet"
+	"github.com/hyperledger/firefly-signer/pkg/objectstore"
+	"github.com/hyperledger/firefly-signer/pkg/persistence"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+)
+
+// Service embeds the full behavior of the signer in a Go application, without running the JSON/RPC server.
+// Every request passes the same access control (for the identity in the context, see rpcauth.WithIdentity),
+// transaction policies, fee population and nonce management as it would through the server.
+type Service interface {
+	rpcbackend.RPC // any JSON/RPC method, processed as if it was received by the server
+
+	// Start connects to the backend, validates the chain ID, and loads the wallet
+	Start() error
+	// Close stops the service, and closes the wallet if the service created it
+	Close()
+	// ChainID is the chain ID transactions are signed for, once the service has started
+	ChainID() int64
+	// Wallet is the wallet that signs for the service
+	Wallet() ethsigner.Wallet
+	// Accounts lists the addresses of the keys in the wallet the caller may sign with
+	Accounts(ctx context.Context) ([]*ethtypes.Address0xHex, error)
+	// Sign checks and populates the transaction as for SendTransaction, and returns it signed without submitting it.
+	// Any nonce assigned by the nonce manager stays assigned, so the caller must submit the transaction.
+	Sign(ctx context.Context, txn *ethsigner.Transaction) (ethtypes.HexBytes0xPrefix, error)
+	// SignTypedData signs EIP-712 typed data with a key in the wallet
+	SignTypedData(ctx context.Context, from ethtypes.Address0xHex, payload *eip712.TypedData) (*ethsigner.EIP712Result, error)
+	// SendTransaction signs the transaction and submits it to the backend, returning the transaction hash
+	SendTransaction(ctx context.Context, txn *ethsigner.Transaction) (ethtypes.HexBytes0xPrefix, error)
+}
+
+// Config is the part of the configuration of a Service that cannot be set in the ffsigner configuration
+type Config struct {
+	// Wallet optionally signs with a wallet of the application, instead of the file wallet in the configuration
+	Wallet ethsigner.Wallet
+	// ApprovalHook optionally must approve every signature before it is released, such as for a four-eyes workflow.
+	// A request the hook defers waits for the decision until signingApproval.timeout, then fails.
+	ApprovalHook approval.Hook
+	// Persistence optionally keeps the state that survives restarts, such as assigned nonces, in a store of the
+	// application (such as a database) instead of in persistence.path
+	Persistence persistence.Store
+}
+
+// InitConfig resets the configuration to the defaults of ffsigner (see config.md), so it can be set with config.Set
+func InitConfig() {
+	signerconfig.Reset()
+}
+
+// ReadConfig resets the configuration, and loads it from an ffsigner configuration file
+func ReadConfig(ctx context.Context, filename string) error {
+	InitConfig()
+	if err := config.ReadConfig("ffsigner", filename); err != nil {
+		return i18n.WrapError(ctx, err, i18n.MsgConfigFailed)
+	}
+	return nil
+}
+
+type service struct {
+	server      rpcserver.Service
+	wallet      ethsigner.Wallet
+	closeWallet bool
+}
+
+// NewService creates a service from the configuration loaded with InitConfig or ReadConfig. The configuration of the
+// JSON/RPC, admin and metrics servers is ignored, as there are none.
+func NewService(ctx context.Context, conf *Config) (Service, error) {
+	fips.Configure(ctx, fips.ReadConfig(signerconfig.FIPSConfig))
+	s := &service{wallet: conf.Wallet}
+	if s.wallet == nil {
+		if !config.GetBool(signerconfig.FileWalletEnabled) {
+			return nil, i18n.NewError(ctx, signermsgs.MsgNoWalletEnabled)
+		}
+		var fileWallet fswallet.Wallet
+		var err error
+		fsConf := fswallet.ReadConfig(signerconfig.FileWalletConfig)
+		if osConf := objectstore.ReadConfig(signerconfig.FileWalletObjectStoreConfig); osConf.Enabled {
+			fileWallet, err = objectstore.NewWallet(ctx, osConf, fsConf)
+		} else {
+			fileWallet, err = fswallet.NewFilesystemWallet(ctx, fsConf)
+		}
+		if err != nil {
+			return nil, err
+		}
+		s.wallet = fileWallet
+		s.closeWallet = true
+	}
+	server, err := rpcserver.NewService(ctx, s.wallet, conf.Persistence)
+	if err != nil {
+		return nil, err
+	}
+	if conf.ApprovalHook != nil {
+		server.SetApprovalHook(conf.ApprovalHook)
+	}
+	s.server = server
+	return s, nil
+}
+
+func (s *service) Start() error {
+	return s.server.Start()
+}
+
+func (s *service) Close() {
+	s.server.Stop()
+	_ = s.server.WaitStop()
+	if s.closeWallet {
+		_ = s.wallet.Close()
+	}
+}
+
+func (s *service) ChainID() int64 {
+	return s.server.ChainID()
+}
+
+func (s *service) Wallet() ethsigner.Wallet {
+	return s.wallet
+}
+
+func (s *service) CallRPC(ctx context.Context, result interface{}, method string, params ...interface{}) *rpcbackend.RPCError {
+	rpcReq := &rpcbackend.RPCRequest{JSONRpc: "2.0", Method: method}
+	for i, param := range params {
+		b, err := json.Marshal(param)
+		if err != nil {
+			return rpcbackend.NewRPCError(ctx, rpcbackend.RPCCodeInvalidRequest, signermsgs.MsgInvalidParam, i, method, err)
+		}
+		rpcReq.Params = append(rpcReq.Params, fftypes.JSONAnyPtrBytes(b))
+	}
+	rpcRes, err := s.server.SyncRequest(ctx, rpcReq)
+	if err != nil {
+		if rpcRes != nil && rpcRes.Error != nil && rpcRes.Error.Code != 0 {
+			return rpcRes.Error
+		}
+		return rpcbackend.RPCErrorResponse(err, rpcReq.ID, rpcbackend.RPCCodeInternalError).Error
+	}
+	if err := json.Unmarshal(rpcRes.Result.Bytes(), &result); err != nil {
+		return rpcbackend.NewRPCError(ctx, rpcbackend.RPCCodeParseError, signermsgs.MsgResultParseFailed, result, err)
+	}
+	return nil
+}
+
+func (s *service) Accounts(ctx context.Context) ([]*ethtypes.Address0xHex, error) {
+	var accounts []*ethtypes.Address0xHex
+	if rpcErr := s.CallRPC(ctx, &accounts, "eth_accounts"); rpcErr != nil {
+		return nil, rpcErr.Error()
+	}
+	return accounts, nil
+}
+
+func (s *service) Sign(ctx context.Context, txn *ethsigner.Transaction) (ethtypes.HexBytes0xPrefix, error) {
+	return s.server.SignTransaction(ctx, txn)
+}
+
+func (s *service) SignTypedData(ctx context.Context, from ethtypes.Address0xHex, payload *eip712.TypedData) (*ethsigner.EIP712Result, error) {
+	return s.server.SignTypedData(ctx, from, payload)
+}
+
+func (s *service) SendTransaction(ctx context.Context, txn *ethsigner.Transaction) (ethtypes.HexBytes0xPrefix, error) {
+	var txHash ethtypes.HexBytes0xPrefix
+	if rpcErr := s.CallRPC(ctx, &txHash, "eth_sendTransaction", txn); rpcErr != nil {
+		return nil, rpcErr.Error()
+	}
+	return txHash, nil
+}
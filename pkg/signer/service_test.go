@@ -0,0 +1,292 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package signer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/hyperledger/firefly-common/pkg/config"
+	"github.com/hyperledger/firefly-signer/internal/rpcserver"
+	"github.com/hyperledger/firefly-signer/internal/signerconfig"
+	"github.com/hyperledger/firefly-signer/mocks/ethsignermocks"
+	"github.com/hyperledger/firefly-signer/pkg/approval"
+	"github.com/hyperledger/firefly-signer/pkg/eip712"
+	"github.com/hyperledger/firefly-signer/pkg/ethsigner"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/fakechain"
+	"github.com/hyperledger/firefly-signer/pkg/nonces"
+	"github.com/hyperledger/firefly-signer/pkg/persistence"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testAddr   = "0x1f185718734552d08278aa70f804580bab5fd2b4"
+	testTxHash = "0x8e3c9e8f4d7e9e2e5c1b0f8e7a6d5c4b3a291807f6e5d4c3b2a1908f7e6d5c4b"
+)
+
+func newTestBackend(t *testing.T) string {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req rpcbackend.RPCRequest
+		err := json.NewDecoder(r.Body).Decode(&req)
+		assert.NoError(t, err)
+		res := map[string]interface{}{"jsonrpc": "2.0", "id": req.ID}
+		switch req.Method {
+		case "eth_chainId":
+			res["result"] = "0x539"
+		case "eth_getTransactionCount":
+			res["result"] = "0x5"
+		case "eth_sendRawTransaction":
+			res["result"] = testTxHash
+		default:
+			res["error"] = map[string]interface{}{"code": -32601, "message": "method not found"}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(res)
+	}))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func writeTestConfig(t *testing.T, backendURL string) string {
+	configFile := path.Join(t.TempDir(), "ffsigner.yaml")
+	err := os.WriteFile(configFile, []byte(fmt.Sprintf(`fileWallet:
+  path: ../../test/keystore_toml
+  allowInsecurePermissions: true
+  disableListener: true
+  filenames:
+    primaryExt: .toml
+  metadata:
+    format: toml
+    keyFileProperty: '{{ index .signing "key-file" }}'
+    passwordFileProperty: '{{ index .signing "password-file" }}'
+backend:
+  url: %q
+  chainId: 1337
+  retry:
+    enabled: false
+`, backendURL)), 0600)
+	assert.NoError(t, err)
+	return configFile
+}
+
+func newTestService(t *testing.T) *service {
+	err := ReadConfig(context.Background(), writeTestConfig(t, newTestBackend(t)))
+	assert.NoError(t, err)
+	s, err := NewService(context.Background(), &Config{})
+	assert.NoError(t, err)
+	err = s.Start()
+	assert.NoError(t, err)
+	t.Cleanup(s.Close)
+	return s.(*service)
+}
+
+func testTransaction() *ethsigner.Transaction {
+	return &ethsigner.Transaction{
+		From:     json.RawMessage(`"` + testAddr + `"`),
+		To:       ethtypes.MustNewAddress("0x497eedc4299dea2f2a364be10025d0ad0f702de3"),
+		GasLimit: ethtypes.NewHexInteger64(21000),
+		GasPrice: ethtypes.NewHexInteger64(1000000000),
+	}
+}
+
+func TestServiceSignAndSend(t *testing.T) {
+	s := newTestService(t)
+	ctx := context.Background()
+	assert.Equal(t, int64(1337), s.ChainID())
+	assert.NotNil(t, s.Wallet())
+
+	accounts, err := s.Accounts(ctx)
+	assert.NoError(t, err)
+	assert.Contains(t, accounts, ethtypes.MustNewAddress(testAddr))
+
+	signed, err := s.Sign(ctx, testTransaction())
+	assert.NoError(t, err)
+	assert.NotEmpty(t, signed)
+
+	txHash, err := s.SendTransaction(ctx, testTransaction())
+	assert.NoError(t, err)
+	assert.Equal(t, testTxHash, txHash.String())
+
+	result, err := s.SignTypedData(ctx, *ethtypes.MustNewAddress(testAddr), &eip712.TypedData{
+		PrimaryType: eip712.EIP712Domain,
+	})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, result.SignatureRSV)
+}
+
+func TestServiceSendTransactionFakeChain(t *testing.T) {
+	chain := fakechain.New(nil)
+	server := httptest.NewServer(chain)
+	defer server.Close()
+	err := ReadConfig(context.Background(), writeTestConfig(t, server.URL))
+	assert.NoError(t, err)
+	s, err := NewService(context.Background(), &Config{})
+	assert.NoError(t, err)
+	err = s.Start()
+	assert.NoError(t, err)
+	defer s.Close()
+	ctx := context.Background()
+
+	// The nonce is assigned from the chain, and advances with each transaction
+	for i := 0; i < 2; i++ {
+		txHash, err := s.SendTransaction(ctx, testTransaction())
+		assert.NoError(t, err)
+		receipt, rpcErr := rpcbackend.NewEthClient(s).GetTransactionReceipt(ctx, txHash)
+		assert.Nil(t, rpcErr)
+		assert.True(t, receipt.Succeeded())
+		assert.Equal(t, testAddr, receipt.From.String())
+	}
+	txns := chain.Transactions()
+	assert.Len(t, txns, 2)
+	assert.Equal(t, int64(1), txns[1].Nonce.Int64())
+}
+
+func TestServiceCallRPCBackendError(t *testing.T) {
+	s := newTestService(t)
+
+	var blockNumber ethtypes.HexInteger
+	rpcErr := s.CallRPC(context.Background(), &blockNumber, "eth_blockNumber")
+	assert.Equal(t, int64(-32601), rpcErr.Code)
+	assert.Regexp(t, "method not found", rpcErr.Message)
+
+	_, err := s.SendTransaction(context.Background(), &ethsigner.Transaction{})
+	assert.Regexp(t, "FF22020", err)
+}
+
+func TestServiceCallRPCBadParam(t *testing.T) {
+	s := newTestService(t)
+
+	var result interface{}
+	rpcErr := s.CallRPC(context.Background(), &result, "eth_call", map[bool]bool{false: true})
+	assert.Regexp(t, "FF22011", rpcErr.Message)
+}
+
+func TestServiceCallRPCBadResult(t *testing.T) {
+	s := newTestService(t)
+
+	var result int
+	rpcErr := s.CallRPC(context.Background(), &result, "eth_chainId")
+	assert.Regexp(t, "FF22065", rpcErr.Message)
+}
+
+type testFailingServer struct {
+	rpcserver.Service
+}
+
+func (*testFailingServer) SyncRequest(ctx context.Context, rpcReq *rpcbackend.RPCRequest) (*rpcbackend.RPCResponse, error) {
+	return nil, fmt.Errorf("pop")
+}
+
+func TestServiceCallRPCInternalError(t *testing.T) {
+	s := &service{server: &testFailingServer{}}
+
+	_, err := s.Accounts(context.Background())
+	assert.Regexp(t, "pop", err)
+}
+
+func TestReadConfigFail(t *testing.T) {
+	err := ReadConfig(context.Background(), path.Join(t.TempDir(), "missing.yaml"))
+	assert.Regexp(t, "FF00101", err)
+}
+
+func TestNewServiceNoWallet(t *testing.T) {
+	InitConfig()
+	config.Set(signerconfig.FileWalletEnabled, false)
+	_, err := NewService(context.Background(), &Config{})
+	assert.Regexp(t, "FF22017", err)
+}
+
+func TestNewServiceBadFileWallet(t *testing.T) {
+	InitConfig()
+	config.Set(signerconfig.FileWalletEnabled, true)
+	signerconfig.FileWalletConfig.Set("metadata.keyFileProperty", "{{ !!")
+	_, err := NewService(context.Background(), &Config{})
+	assert.Regexp(t, "FF22016", err)
+}
+
+func TestNewServiceBadObjectStore(t *testing.T) {
+	InitConfig()
+	config.Set(signerconfig.FileWalletEnabled, true)
+	signerconfig.FileWalletConfig.Set("path", t.TempDir())
+	signerconfig.FileWalletObjectStoreConfig.Set("enabled", true)
+	_, err := NewService(context.Background(), &Config{})
+	assert.Regexp(t, "FF22307.*endpoint", err)
+}
+
+func TestNewServiceBadConfigOwnWallet(t *testing.T) {
+	InitConfig()
+	config.Set(signerconfig.GasEstimateMultiplier, 0.5)
+	_, err := NewService(context.Background(), &Config{Wallet: &ethsignermocks.Wallet{}})
+	assert.Regexp(t, "FF22130", err)
+}
+
+func TestServiceCloseOwnWallet(t *testing.T) {
+	InitConfig()
+	w := &ethsignermocks.Wallet{}
+	s, err := NewService(context.Background(), &Config{Wallet: w})
+	assert.NoError(t, err)
+	assert.Equal(t, w, s.Wallet())
+	s.Close()
+	w.AssertNotCalled(t, "Close")
+}
+
+type testRejectAllHook struct{}
+
+func (h *testRejectAllHook) ReviewSigning(ctx context.Context, req *approval.Request) (*approval.Decision, error) {
+	return approval.Rejected("needs a second approver"), nil
+}
+
+func TestServiceApprovalHook(t *testing.T) {
+	err := ReadConfig(context.Background(), writeTestConfig(t, newTestBackend(t)))
+	assert.NoError(t, err)
+	s, err := NewService(context.Background(), &Config{ApprovalHook: &testRejectAllHook{}})
+	assert.NoError(t, err)
+	err = s.Start()
+	assert.NoError(t, err)
+	defer s.Close()
+
+	_, err = s.Sign(context.Background(), testTransaction())
+	assert.Regexp(t, "FF22283.*needs a second approver", err)
+}
+
+func TestServicePersistence(t *testing.T) {
+	ctx := context.Background()
+	err := ReadConfig(ctx, writeTestConfig(t, newTestBackend(t)))
+	assert.NoError(t, err)
+	signerconfig.NoncesConfig.Set(nonces.ConfigEnabled, true)
+	store := persistence.NewMemoryStore()
+	s, err := NewService(ctx, &Config{Persistence: store})
+	assert.NoError(t, err)
+	err = s.Start()
+	assert.NoError(t, err)
+	defer s.Close()
+
+	_, err = s.Sign(ctx, testTransaction())
+	assert.NoError(t, err)
+	next, found, err := store.Get(ctx, nonces.PersistenceCollection, testAddr)
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "6", string(next))
+}
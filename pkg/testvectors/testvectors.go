@@ -0,0 +1,343 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package testvectors generates golden test vectors from declarative fixtures - ABI encodings, RLP payloads,
+// EIP-712 digests and signed transactions - so implementations in other languages can check they produce
+// exactly the same output as this library.
+//
+// The fixtures are YAML (or JSON), with named private keys, and a list of vectors that each have one of
+// abi, rlp, eip712 or transaction:
+//
+//	keys:
+//	  alice: "0x8d...e1"
+//	vectors:
+//	- name: erc20-transfer
+//	  abi:
+//	    entry: {"type":"function","name":"transfer","inputs":[{"name":"to","type":"address"},{"name":"amount","type":"uint256"}]}
+//	    values: ["0x497eedc4299dea2f2a364be10025d0ad0f702de3", "1000"]
+//	- name: nested-list
+//	  rlp: ["0x01", ["0x0203", 1024]]
+//	- name: permit
+//	  key: alice
+//	  eip712: {"types": ..., "primaryType": "Permit", "domain": ..., "message": ...}
+//	- name: eip1559-transfer
+//	  key: alice
+//	  transaction:
+//	    chainId: 1337
+//	    type: eip1559
+//	    tx: {"nonce": "0x0", "gas": "0x5208", "maxFeePerGas": "0x3b9aca00", "maxPriorityFeePerGas": "0x1", "to": "0x497eedc4299dea2f2a364be10025d0ad0f702de3"}
+//
+// Large numbers must be quoted, so they are not rounded by the YAML parser. Signatures are deterministic
+// (RFC 6979), so the same fixtures always generate the same vectors.
+package testvectors
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/hyperledger/firefly-common/pkg/fftypes"
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/hyperledger/firefly-signer/pkg/abi"
+	"github.com/hyperledger/firefly-signer/pkg/eip712"
+	"github.com/hyperledger/firefly-signer/pkg/ethsigner"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/rlp"
+	"github.com/hyperledger/firefly-signer/pkg/secp256k1"
+	"golang.org/x/crypto/sha3"
+	"gopkg.in/yaml.v2"
+)
+
+// Fixtures are the inputs the vectors are generated from
+type Fixtures struct {
+	Keys    map[string]ethtypes.HexBytes0xPrefix `json:"keys,omitempty"` // private keys, by name
+	Vectors []*Fixture                           `json:"vectors"`
+}
+
+// Fixture is the input of one vector, which has exactly one of ABI, RLP, EIP712 or Transaction
+type Fixture struct {
+	Name        string              `json:"name"`
+	Key         string              `json:"key,omitempty"` // the key that signs EIP-712 typed data, or a transaction
+	ABI         *ABIFixture         `json:"abi,omitempty"`
+	RLP         interface{}         `json:"rlp,omitempty"` // hex strings, integers, and lists of them
+	EIP712      *eip712.TypedData   `json:"eip712,omitempty"`
+	Transaction *TransactionFixture `json:"transaction,omitempty"`
+}
+
+// ABIFixture is a function, constructor or error, and the values to encode as its call data
+type ABIFixture struct {
+	Entry  *abi.Entry       `json:"entry"`
+	Values *fftypes.JSONAny `json:"values"` // an array of values, or an object with a value for each input
+}
+
+// TransactionFixture is a transaction to sign
+type TransactionFixture struct {
+	ChainID int64                  `json:"chainId"`
+	Type    string                 `json:"type,omitempty"` // legacy, eip155 or eip1559 - chosen from the fields of the transaction by default
+	Tx      *ethsigner.Transaction `json:"tx"`
+}
+
+// Vectors are the generated vectors, with the address of each key
+type Vectors struct {
+	Keys    map[string]*ethtypes.Address0xHex `json:"keys,omitempty"`
+	Vectors []*Vector                         `json:"vectors"`
+}
+
+// Vector is the input of a fixture, with the output generated from it
+type Vector struct {
+	*Fixture
+	Output interface{} `json:"output"` // one of ABIOutput, RLPOutput, EIP712Output or TransactionOutput
+}
+
+// ABIOutput is the call data of an ABI fixture
+type ABIOutput struct {
+	Signature string                    `json:"signature"`
+	Selector  ethtypes.HexBytes0xPrefix `json:"selector,omitempty"` // not for a constructor
+	Data      ethtypes.HexBytes0xPrefix `json:"data"`
+}
+
+// RLPOutput is the encoding of an RLP fixture
+type RLPOutput struct {
+	Encoded ethtypes.HexBytes0xPrefix `json:"encoded"`
+}
+
+// EIP712Output is the hashes of typed data, with the signature when the fixture has a key
+type EIP712Output struct {
+	*eip712.TypedDataV4Hashes
+	Signer       *ethtypes.Address0xHex    `json:"signer,omitempty"`
+	SignatureRSV ethtypes.HexBytes0xPrefix `json:"signatureRSV,omitempty"`
+}
+
+// TransactionOutput is the unsigned payload, the hash that is signed, and the signed transaction
+type TransactionOutput struct {
+	Type           string                    `json:"type"`
+	SigningPayload ethtypes.HexBytes0xPrefix `json:"signingPayload"`
+	SigningHash    ethtypes.HexBytes0xPrefix `json:"signingHash"`
+	Signer         *ethtypes.Address0xHex    `json:"signer"`
+	Raw            ethtypes.HexBytes0xPrefix `json:"raw"`
+	Hash           ethtypes.HexBytes0xPrefix `json:"hash"`
+}
+
+// ParseFixtures parses YAML or JSON fixtures
+func ParseFixtures(ctx context.Context, data []byte) (*Fixtures, error) {
+	var parsed interface{}
+	if err := yaml.Unmarshal(data, &parsed); err != nil {
+		return nil, i18n.NewError(ctx, signermsgs.MsgInvalidTestVectorFixtures, err)
+	}
+	// The YAML is converted to JSON, so the JSON parsing of the types is used
+	b, err := json.Marshal(jsonCompatible(parsed))
+	if err == nil {
+		var fixtures Fixtures
+		if err = json.Unmarshal(b, &fixtures); err == nil {
+			return &fixtures, nil
+		}
+	}
+	return nil, i18n.NewError(ctx, signermsgs.MsgInvalidTestVectorFixtures, err)
+}
+
+// jsonCompatible converts the maps of parsed YAML, which can have keys of any type, to maps with string keys
+func jsonCompatible(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, mv := range v {
+			m[fmt.Sprint(k)] = jsonCompatible(mv)
+		}
+		return m
+	case []interface{}:
+		for i, av := range v {
+			v[i] = jsonCompatible(av)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// Generate generates a vector from each fixture, in order
+func Generate(ctx context.Context, fixtures *Fixtures) (*Vectors, error) {
+	keys := make(map[string]*secp256k1.KeyPair, len(fixtures.Keys))
+	vectors := &Vectors{
+		Keys:    make(map[string]*ethtypes.Address0xHex, len(fixtures.Keys)),
+		Vectors: make([]*Vector, len(fixtures.Vectors)),
+	}
+	for name, privateKey := range fixtures.Keys {
+		kp := secp256k1.KeyPairFromBytes(privateKey)
+		if len(privateKey) != 32 || kp.PrivateKey.Key.IsZero() {
+			return nil, i18n.NewError(ctx, signermsgs.MsgTestVectorInvalidKey, name)
+		}
+		keys[name] = kp
+		vectors.Keys[name] = &kp.Address
+	}
+	for i, fixture := range fixtures.Vectors {
+		output, err := generateVector(ctx, fixture, keys)
+		if err != nil {
+			return nil, err
+		}
+		vectors.Vectors[i] = &Vector{Fixture: fixture, Output: output}
+	}
+	return vectors, nil
+}
+
+func generateVector(ctx context.Context, fixture *Fixture, keys map[string]*secp256k1.KeyPair) (output interface{}, err error) {
+	var kp *secp256k1.KeyPair
+	if fixture.Key != "" {
+		if kp = keys[fixture.Key]; kp == nil {
+			return nil, i18n.NewError(ctx, signermsgs.MsgTestVectorUnknownKey, fixture.Name, fixture.Key)
+		}
+	}
+	kinds := 0
+	for _, set := range []bool{fixture.ABI != nil, fixture.RLP != nil, fixture.EIP712 != nil, fixture.Transaction != nil} {
+		if set {
+			kinds++
+		}
+	}
+	switch {
+	case kinds != 1:
+		return nil, i18n.NewError(ctx, signermsgs.MsgTestVectorKind, fixture.Name)
+	case fixture.ABI != nil && fixture.ABI.Entry == nil:
+		return nil, i18n.NewError(ctx, signermsgs.MsgTestVectorIncomplete, fixture.Name, "abi.entry")
+	case fixture.Transaction != nil && fixture.Transaction.Tx == nil:
+		return nil, i18n.NewError(ctx, signermsgs.MsgTestVectorIncomplete, fixture.Name, "transaction.tx")
+	case fixture.ABI != nil:
+		output, err = generateABI(ctx, fixture.ABI)
+	case fixture.RLP != nil:
+		output, err = generateRLP(ctx, fixture.RLP)
+	case fixture.EIP712 != nil:
+		output, err = generateEIP712(ctx, fixture.EIP712, kp)
+	default:
+		if kp == nil {
+			return nil, i18n.NewError(ctx, signermsgs.MsgTestVectorKeyRequired, fixture.Name)
+		}
+		output, err = generateTransaction(ctx, fixture.Transaction, kp)
+	}
+	if err != nil {
+		return nil, i18n.NewError(ctx, signermsgs.MsgTestVectorFailed, fixture.Name, err)
+	}
+	return output, nil
+}
+
+func generateABI(ctx context.Context, fixture *ABIFixture) (*ABIOutput, error) {
+	signature, err := fixture.Entry.SignatureCtx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// The arguments of a constructor follow the bytecode, rather than a selector
+	if fixture.Entry.Type == abi.Constructor {
+		data, err := fixture.Entry.Inputs.EncodeABIDataJSONCtx(ctx, fixture.Values.Bytes())
+		if err != nil {
+			return nil, err
+		}
+		return &ABIOutput{Signature: signature, Data: data}, nil
+	}
+	data, err := fixture.Entry.EncodeCallDataJSONCtx(ctx, fixture.Values.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	return &ABIOutput{Signature: signature, Selector: data[0:4], Data: data}, nil
+}
+
+func generateRLP(ctx context.Context, fixture interface{}) (*RLPOutput, error) {
+	element, err := rlpElement(ctx, fixture)
+	if err != nil {
+		return nil, err
+	}
+	return &RLPOutput{Encoded: element.Encode()}, nil
+}
+
+// rlpElement converts hex strings to data, integers to their minimal big-endian bytes, and arrays to lists
+func rlpElement(ctx context.Context, v interface{}) (rlp.Element, error) {
+	switch v := v.(type) {
+	case []interface{}:
+		list := make(rlp.List, len(v))
+		for i, lv := range v {
+			element, err := rlpElement(ctx, lv)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = element
+		}
+		return list, nil
+	case string:
+		if data, err := rlp.WrapHex(v); err == nil {
+			return data, nil
+		}
+	case float64:
+		if i, accuracy := big.NewFloat(v).Int(nil); accuracy == big.Exact && i.Sign() >= 0 {
+			return rlp.WrapInt(i), nil
+		}
+	}
+	return nil, i18n.NewError(ctx, signermsgs.MsgTestVectorInvalidRLP, v)
+}
+
+func generateEIP712(ctx context.Context, typedData *eip712.TypedData, kp *secp256k1.KeyPair) (*EIP712Output, error) {
+	hashes, err := eip712.HashTypedDataV4(ctx, typedData)
+	if err != nil {
+		return nil, err
+	}
+	output := &EIP712Output{TypedDataV4Hashes: hashes}
+	if kp != nil {
+		result, err := ethsigner.SignTypedDataV4(ctx, kp, typedData)
+		if err != nil {
+			return nil, err
+		}
+		output.Signer = &kp.Address
+		output.SignatureRSV = result.SignatureRSV
+	}
+	return output, nil
+}
+
+func generateTransaction(ctx context.Context, fixture *TransactionFixture, kp *secp256k1.KeyPair) (*TransactionOutput, error) {
+	tx := fixture.Tx
+	txType := fixture.Type
+	if txType == "" {
+		txType = "eip155"
+		if tx.MaxPriorityFeePerGas.BigInt().Sign() > 0 || tx.MaxFeePerGas.BigInt().Sign() > 0 {
+			txType = "eip1559"
+		}
+	}
+	var payload *ethsigner.TransactionSignaturePayload
+	var raw []byte
+	var err error
+	switch txType {
+	case "legacy":
+		payload = tx.SignaturePayloadLegacyOriginal()
+		raw, err = tx.SignLegacyOriginal(kp)
+	case "eip155":
+		payload = tx.SignaturePayloadLegacyEIP155(fixture.ChainID)
+		raw, err = tx.SignLegacyEIP155(kp, fixture.ChainID)
+	case "eip1559":
+		payload = tx.SignaturePayloadEIP1559(fixture.ChainID)
+		raw, err = tx.SignEIP1559(kp, fixture.ChainID)
+	default:
+		return nil, i18n.NewError(ctx, signermsgs.MsgTestVectorInvalidTxType, txType)
+	}
+	if err != nil {
+		return nil, err
+	}
+	hash := sha3.NewLegacyKeccak256()
+	hash.Write(raw)
+	return &TransactionOutput{
+		Type:           txType,
+		SigningPayload: payload.Bytes(),
+		SigningHash:    payload.Hash(),
+		Signer:         &kp.Address,
+		Raw:            raw,
+		Hash:           hash.Sum(nil),
+	}, nil
+}
@@ -0,0 +1,136 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testvectors
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/hyperledger/firefly-signer/pkg/ethsigner"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/stretchr/testify/assert"
+)
+
+const testKey = "0x4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318"
+
+func generateTestVectors(t *testing.T, fixtures string) (*Vectors, error) {
+	f, err := ParseFixtures(context.Background(), []byte(fixtures))
+	assert.NoError(t, err)
+	return Generate(context.Background(), f)
+}
+
+func TestGenerateFixturesFile(t *testing.T) {
+	b, err := os.ReadFile("../../test/testvectors/fixtures.yaml")
+	assert.NoError(t, err)
+	f, err := ParseFixtures(context.Background(), b)
+	assert.NoError(t, err)
+	vectors, err := Generate(context.Background(), f)
+	assert.NoError(t, err)
+	assert.Len(t, vectors.Vectors, len(f.Vectors))
+
+	byName := map[string]*Vector{}
+	for _, v := range vectors.Vectors {
+		byName[v.Name] = v
+	}
+	assert.Equal(t, "0xa9059cbb", byName["abi-erc20-transfer"].Output.(*ABIOutput).Selector.String())
+	assert.Empty(t, byName["abi-constructor"].Output.(*ABIOutput).Selector)
+	assert.Equal(t, "0xc7c0c1c0c3c0c1c0", byName["rlp-nested"].Output.(*RLPOutput).Encoded.String())
+
+	// Digest from the example in the EIP-712 specification
+	mail := byName["eip712-mail"].Output.(*EIP712Output)
+	assert.Equal(t, "0xbe609aee343fb3c4b28e1df9e632fca64fcfaede20f02e86244efddf30957bd2", mail.Digest.String())
+	assert.Equal(t, vectors.Keys["alice"], mail.Signer)
+	assert.Empty(t, byName["eip712-domain-only"].Output.(*EIP712Output).SignatureRSV)
+
+	// Every signed transaction recovers to the signer, and the chosen type is reported
+	for name, txType := range map[string]string{"tx-eip155": "eip155", "tx-eip1559": "eip1559", "tx-deploy": "eip155"} {
+		output := byName[name].Output.(*TransactionOutput)
+		assert.Equal(t, txType, output.Type)
+		from, _, err := ethsigner.RecoverRawTransaction(context.Background(), output.Raw, byName[name].Transaction.ChainID)
+		assert.NoError(t, err)
+		assert.Equal(t, output.Signer, from)
+	}
+	legacy := byName["tx-legacy"].Output.(*TransactionOutput)
+	from, _, err := ethsigner.RecoverLegacyRawTransaction(context.Background(), legacy.Raw, -1)
+	assert.NoError(t, err)
+	assert.Equal(t, legacy.Signer, from)
+
+	// Signing is deterministic
+	again, err := Generate(context.Background(), f)
+	assert.NoError(t, err)
+	assert.Equal(t, vectors, again)
+}
+
+func TestGenerateDefaultEIP1559(t *testing.T) {
+	vectors, err := generateTestVectors(t, `
+keys: {a: "`+testKey+`"}
+vectors:
+- name: tx
+  key: a
+  transaction: {chainId: 1, tx: {maxFeePerGas: "0x10", gas: "0x5208"}}
+`)
+	assert.NoError(t, err)
+	assert.Equal(t, "eip1559", vectors.Vectors[0].Output.(*TransactionOutput).Type)
+}
+
+func TestParseFixturesFail(t *testing.T) {
+	_, err := ParseFixtures(context.Background(), []byte(`{!!!`))
+	assert.Regexp(t, "FF22229", err)
+
+	_, err = ParseFixtures(context.Background(), []byte(`vectors: [{name: nan, rlp: .nan}]`))
+	assert.Regexp(t, "FF22229.*NaN", err)
+
+	_, err = ParseFixtures(context.Background(), []byte(`keys: {a: wrong}`))
+	assert.Regexp(t, "FF22229", err)
+}
+
+func TestGenerateFail(t *testing.T) {
+	for fixtures, expected := range map[string]string{
+		`keys: {a: "0x00"}`:                                                             "FF22232.*a",
+		`vectors: [{name: v, key: b, rlp: "0x"}]`:                                       "FF22231.*v.*b",
+		`vectors: [{name: v}]`:                                                          "FF22230.*v",
+		`vectors: [{name: v, rlp: "0x", eip712: {}}]`:                                   "FF22230.*v",
+		`vectors: [{name: v, abi: {values: []}}]`:                                       "FF22237.*v.*abi.entry",
+		`vectors: [{name: v, transaction: {chainId: 1}}]`:                               "FF22237.*v.*transaction.tx",
+		`vectors: [{name: v, transaction: {chainId: 1, tx: {}}}]`:                       "FF22233.*v",
+		`vectors: [{name: v, rlp: ["0x", "wrong"]}]`:                                    "FF22236.*v.*FF22235.*wrong",
+		`vectors: [{name: v, rlp: 1.5}]`:                                                "FF22236.*v.*FF22235",
+		`vectors: [{name: v, rlp: -1}]`:                                                 "FF22236.*v.*FF22235",
+		`vectors: [{name: v, rlp: {a: b}}]`:                                             "FF22236.*v.*FF22235",
+		`vectors: [{name: v, abi: {entry: {type: function, inputs: [{type: wrong}]}}}]`: "FF22236.*v",
+		`vectors: [{name: v, abi: {entry: {type: function, inputs: [{type: uint8}]}, values: ["wrong"]}}]`:    "FF22236.*v",
+		`vectors: [{name: v, abi: {entry: {type: constructor, inputs: [{type: uint8}]}, values: ["wrong"]}}]`: "FF22236.*v",
+		`vectors: [{name: v, eip712: {primaryType: Missing}}]`:                                                "FF22236.*v",
+		`{keys: {a: "` + testKey + `"}, vectors: [{name: v, key: a, transaction: {type: wrong, tx: {}}}]}`:    "FF22236.*v.*FF22234.*wrong",
+	} {
+		_, err := generateTestVectors(t, fixtures)
+		assert.Regexp(t, expected, err, fixtures)
+	}
+}
+
+func TestGenerateABIValuesObject(t *testing.T) {
+	vectors, err := generateTestVectors(t, `
+vectors:
+- name: v
+  abi:
+    entry: {type: function, name: set, inputs: [{name: x, type: uint256}]}
+    values: {x: "0x01"}
+`)
+	assert.NoError(t, err)
+	assert.Equal(t, ethtypes.MustNewHexBytes0xPrefix("0x60fe47b10000000000000000000000000000000000000000000000000000000000000001"), vectors.Vectors[0].Output.(*ABIOutput).Data)
+}
@@ -0,0 +1,167 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tokens ships the ABIs of the standard token interfaces, parsed and ready to use, with helpers to
+// decode the events and function calls of any token contract that implements them.
+package tokens
+
+import (
+	"context"
+	"embed"
+	"strings"
+
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/hyperledger/firefly-signer/pkg/abi"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+)
+
+// Standard is a token interface standard
+type Standard string
+
+const (
+	// ERC20 fungible tokens, including the optional name, symbol and decimals metadata functions
+	ERC20 Standard = "erc20"
+	// ERC721 non-fungible tokens, including the optional metadata functions and ERC-165 supportsInterface
+	ERC721 Standard = "erc721"
+	// ERC1155 multi tokens, including the optional metadata URI and ERC-165 supportsInterface
+	ERC1155 Standard = "erc1155"
+	// ERC4626 tokenized vaults, which are also ERC-20 tokens so their ABI includes the ERC-20 functions and events
+	ERC4626 Standard = "erc4626"
+	// ERC2612 permit extension to ERC-20, for approvals by signature. The ABI has only the extension functions
+	ERC2612 Standard = "erc2612"
+)
+
+// Standards are all of the standards with an ABI in this package, in the order events and calls are matched
+var Standards = []Standard{ERC20, ERC721, ERC1155, ERC4626, ERC2612}
+
+//go:embed abis/*.json
+var abiFiles embed.FS
+
+var abis = make(map[Standard]abi.ABI, len(Standards))
+
+func init() {
+	for _, standard := range Standards {
+		b, err := abiFiles.ReadFile("abis/" + string(standard) + ".json")
+		if err == nil {
+			abis[standard], err = abi.ParseABI(b)
+		}
+		if err != nil {
+			panic(err)
+		}
+	}
+}
+
+// ABI returns the parsed ABI of a standard. The ABI is shared, so must not be modified
+func ABI(ctx context.Context, standard Standard) (abi.ABI, error) {
+	a, ok := abis[standard]
+	if !ok {
+		return nil, i18n.NewError(ctx, signermsgs.MsgTokenUnknownStandard, standard)
+	}
+	return a, nil
+}
+
+// MustABI returns the parsed ABI of a standard, panicking if the standard is unknown
+func MustABI(standard Standard) abi.ABI {
+	a, err := ABI(context.Background(), standard)
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
+// Decoded is an event or function call, decoded with the ABI of a token standard
+type Decoded struct {
+	Standard Standard
+	Entry    *abi.Entry
+	Values   *abi.ComponentValue
+}
+
+// DecodeEvent decodes an event emitted by a token contract, matched by the signature hash in the first topic
+// and the number of topics. Some events have the same signature in more than one standard - for example the
+// Transfer event of ERC-20 and ERC-721 differ only in whether the last input is indexed, so are told apart by
+// the number of topics, but ApprovalForAll is identical in ERC-721 and ERC-1155. The first match in the
+// standards is returned, so pass the standards the contract implements (all of them when none are passed).
+func DecodeEvent(ctx context.Context, topics []ethtypes.HexBytes0xPrefix, data ethtypes.HexBytes0xPrefix, standards ...Standard) (*Decoded, error) {
+	standards, err := checkStandards(ctx, standards)
+	if err != nil {
+		return nil, err
+	}
+	var topic0 ethtypes.HexBytes0xPrefix
+	if len(topics) > 0 {
+		topic0 = topics[0]
+	}
+	for _, standard := range standards {
+		for _, e := range abis[standard] {
+			if e.Type != abi.Event || e.SignatureHashBytes().String() != topic0.String() || e.IndexedInputCount()+1 != len(topics) {
+				continue
+			}
+			values, err := e.DecodeEventDataCtx(ctx, topics, data)
+			if err != nil {
+				return nil, err
+			}
+			return &Decoded{Standard: standard, Entry: e, Values: values}, nil
+		}
+	}
+	return nil, i18n.NewError(ctx, signermsgs.MsgTokenEventNotRecognized, topic0, len(topics), standardsString(standards))
+}
+
+// DecodeCall decodes the call data of a transaction to a token contract, matched by the function selector. As with
+// events, functions such as transferFrom have the same selector in more than one standard, so the first match in
+// the standards is returned - pass the standards the contract implements (all of them when none are passed).
+func DecodeCall(ctx context.Context, callData []byte, standards ...Standard) (*Decoded, error) {
+	standards, err := checkStandards(ctx, standards)
+	if err != nil {
+		return nil, err
+	}
+	var selector ethtypes.HexBytes0xPrefix
+	if len(callData) >= 4 {
+		selector = callData[0:4]
+	}
+	for _, standard := range standards {
+		for _, e := range abis[standard] {
+			if !e.IsFunction() || e.FunctionSelectorBytes().String() != selector.String() {
+				continue
+			}
+			values, err := e.DecodeCallDataCtx(ctx, callData)
+			if err != nil {
+				return nil, err
+			}
+			return &Decoded{Standard: standard, Entry: e, Values: values}, nil
+		}
+	}
+	return nil, i18n.NewError(ctx, signermsgs.MsgTokenCallNotRecognized, selector, standardsString(standards))
+}
+
+func checkStandards(ctx context.Context, standards []Standard) ([]Standard, error) {
+	if len(standards) == 0 {
+		return Standards, nil
+	}
+	for _, standard := range standards {
+		if _, ok := abis[standard]; !ok {
+			return nil, i18n.NewError(ctx, signermsgs.MsgTokenUnknownStandard, standard)
+		}
+	}
+	return standards, nil
+}
+
+func standardsString(standards []Standard) string {
+	s := make([]string, len(standards))
+	for i, standard := range standards {
+		s[i] = string(standard)
+	}
+	return strings.Join(s, ", ")
+}
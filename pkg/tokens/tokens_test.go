@@ -0,0 +1,203 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tokens
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hyperledger/firefly-signer/pkg/abi"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testFrom = "0x0000000000000000000000003c99f2a4b366d46bcf2277639a135a6d1288eceb"
+	testTo   = "0x000000000000000000000000497eedc4299dea2f2a364be10025d0ad0f702de3"
+)
+
+func testTopics(t *testing.T, e *abi.Entry, topics ...string) []ethtypes.HexBytes0xPrefix {
+	result := []ethtypes.HexBytes0xPrefix{e.SignatureHashBytes()}
+	for _, topic := range topics {
+		result = append(result, ethtypes.MustNewHexBytes0xPrefix(topic))
+	}
+	return result
+}
+
+func findEntry(t *testing.T, standard Standard, name string, inputs int) *abi.Entry {
+	for _, e := range MustABI(standard) {
+		if e.Name == name && len(e.Inputs) == inputs {
+			return e
+		}
+	}
+	t.Fatalf("%s not found in %s", name, standard)
+	return nil
+}
+
+func serialize(t *testing.T, d *Decoded) string {
+	b, err := abi.NewSerializer().SetByteSerializer(abi.HexByteSerializer0xPrefix).SerializeJSON(d.Values)
+	assert.NoError(t, err)
+	return string(b)
+}
+
+func TestABIsValid(t *testing.T) {
+	for _, standard := range Standards {
+		a, err := ABI(context.Background(), standard)
+		assert.NoError(t, err)
+		assert.NoError(t, a.Validate())
+		assert.NotEmpty(t, a.Functions())
+	}
+	assert.Equal(t, "0x70a08231", MustABI(ERC20).Functions()["balanceOf"].FunctionSelectorBytes().String())
+	assert.Equal(t, "0xd505accf", MustABI(ERC2612).Functions()["permit"].FunctionSelectorBytes().String())
+	assert.Equal(t, "0x6e553f65", MustABI(ERC4626).Functions()["deposit"].FunctionSelectorBytes().String())
+	assert.Equal(t, "0xf242432a", MustABI(ERC1155).Functions()["safeTransferFrom"].FunctionSelectorBytes().String())
+	assert.Equal(t, "0x23b872dd", MustABI(ERC721).Functions()["transferFrom"].FunctionSelectorBytes().String())
+}
+
+func TestABIUnknownStandard(t *testing.T) {
+	_, err := ABI(context.Background(), "erc777")
+	assert.Regexp(t, "FF22250.*erc777", err)
+	assert.Panics(t, func() {
+		_ = MustABI("erc777")
+	})
+}
+
+func TestDecodeEventERC20Transfer(t *testing.T) {
+	transfer := MustABI(ERC20).Events()["Transfer"]
+	assert.Equal(t, "0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef", transfer.SignatureHashBytes().String())
+
+	d, err := DecodeEvent(context.Background(),
+		testTopics(t, transfer, testFrom, testTo),
+		ethtypes.MustNewHexBytes0xPrefix("0x00000000000000000000000000000000000000000000000000000000000003e8"),
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, ERC20, d.Standard)
+	assert.Equal(t, "Transfer", d.Entry.Name)
+	assert.JSONEq(t, `{
+		"from": "0x3c99f2a4b366d46bcf2277639a135a6d1288eceb",
+		"to": "0x497eedc4299dea2f2a364be10025d0ad0f702de3",
+		"value": "1000"
+	}`, serialize(t, d))
+}
+
+func TestDecodeEventERC721Transfer(t *testing.T) {
+	transfer := MustABI(ERC721).Events()["Transfer"]
+
+	d, err := DecodeEvent(context.Background(),
+		testTopics(t, transfer, testFrom, testTo, "0x000000000000000000000000000000000000000000000000000000000000002a"),
+		nil,
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, ERC721, d.Standard)
+	assert.JSONEq(t, `{
+		"from": "0x3c99f2a4b366d46bcf2277639a135a6d1288eceb",
+		"to": "0x497eedc4299dea2f2a364be10025d0ad0f702de3",
+		"tokenId": "42"
+	}`, serialize(t, d))
+}
+
+func TestDecodeEventApprovalForAllStandards(t *testing.T) {
+	approvalForAll := MustABI(ERC1155).Events()["ApprovalForAll"]
+	topics := testTopics(t, approvalForAll, testFrom, testTo)
+	data := ethtypes.MustNewHexBytes0xPrefix("0x0000000000000000000000000000000000000000000000000000000000000001")
+
+	d, err := DecodeEvent(context.Background(), topics, data)
+	assert.NoError(t, err)
+	assert.Equal(t, ERC721, d.Standard)
+
+	d, err = DecodeEvent(context.Background(), topics, data, ERC1155)
+	assert.NoError(t, err)
+	assert.Equal(t, ERC1155, d.Standard)
+	assert.JSONEq(t, `{
+		"account": "0x3c99f2a4b366d46bcf2277639a135a6d1288eceb",
+		"operator": "0x497eedc4299dea2f2a364be10025d0ad0f702de3",
+		"approved": true
+	}`, serialize(t, d))
+}
+
+func TestDecodeEventNotRecognized(t *testing.T) {
+	transfer := MustABI(ERC20).Events()["Transfer"]
+
+	_, err := DecodeEvent(context.Background(), testTopics(t, transfer, testFrom), nil)
+	assert.Regexp(t, "FF22251.*0xddf252ad.*2 topics.*erc20, erc721, erc1155, erc4626, erc2612", err)
+
+	_, err = DecodeEvent(context.Background(), testTopics(t, transfer, testFrom, testTo), nil, ERC1155)
+	assert.Regexp(t, "FF22251.*erc1155", err)
+
+	_, err = DecodeEvent(context.Background(), nil, nil)
+	assert.Regexp(t, "FF22251", err)
+}
+
+func TestDecodeEventBadData(t *testing.T) {
+	transfer := MustABI(ERC20).Events()["Transfer"]
+	_, err := DecodeEvent(context.Background(), testTopics(t, transfer, testFrom, testTo), ethtypes.MustNewHexBytes0xPrefix("0x01"))
+	assert.Regexp(t, "FF22047", err)
+}
+
+func TestDecodeEventUnknownStandard(t *testing.T) {
+	_, err := DecodeEvent(context.Background(), nil, nil, "erc777")
+	assert.Regexp(t, "FF22250", err)
+}
+
+func TestDecodeCall(t *testing.T) {
+	transferFrom := MustABI(ERC20).Functions()["transferFrom"]
+	callData, err := transferFrom.EncodeCallDataValues([]interface{}{testFrom[26:], testTo[26:], 1000})
+	assert.NoError(t, err)
+
+	d, err := DecodeCall(context.Background(), callData)
+	assert.NoError(t, err)
+	assert.Equal(t, ERC20, d.Standard)
+	assert.JSONEq(t, `{
+		"from": "0x3c99f2a4b366d46bcf2277639a135a6d1288eceb",
+		"to": "0x497eedc4299dea2f2a364be10025d0ad0f702de3",
+		"value": "1000"
+	}`, serialize(t, d))
+
+	d, err = DecodeCall(context.Background(), callData, ERC721)
+	assert.NoError(t, err)
+	assert.Equal(t, ERC721, d.Standard)
+	assert.Equal(t, "tokenId", d.Values.Children[2].Component.KeyName())
+}
+
+func TestDecodeCallOverloaded(t *testing.T) {
+	safeTransferFrom := findEntry(t, ERC721, "safeTransferFrom", 4)
+	callData, err := safeTransferFrom.EncodeCallDataValues([]interface{}{testFrom[26:], testTo[26:], 42, "0xfeedbeef"})
+	assert.NoError(t, err)
+
+	d, err := DecodeCall(context.Background(), callData)
+	assert.NoError(t, err)
+	assert.Equal(t, ERC721, d.Standard)
+	assert.Equal(t, "safeTransferFrom(address,address,uint256,bytes)", d.Entry.String())
+}
+
+func TestDecodeCallNotRecognized(t *testing.T) {
+	_, err := DecodeCall(context.Background(), ethtypes.MustNewHexBytes0xPrefix("0xfeedbeef"))
+	assert.Regexp(t, "FF22252.*0xfeedbeef", err)
+
+	_, err = DecodeCall(context.Background(), []byte{0x01})
+	assert.Regexp(t, "FF22252", err)
+}
+
+func TestDecodeCallBadData(t *testing.T) {
+	_, err := DecodeCall(context.Background(), ethtypes.MustNewHexBytes0xPrefix("0x70a08231"))
+	assert.Regexp(t, "FF22047", err)
+}
+
+func TestDecodeCallUnknownStandard(t *testing.T) {
+	_, err := DecodeCall(context.Background(), nil, ERC20, "erc777")
+	assert.Regexp(t, "FF22250", err)
+}
@@ -0,0 +1,127 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tokens
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/hyperledger/firefly-common/pkg/log"
+	"github.com/hyperledger/firefly-signer/pkg/abi"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+)
+
+// Transfer is a movement of tokens from one address to another, normalized across the token standards.
+// A mint is a transfer from the zero address, and a burn is a transfer to the zero address.
+type Transfer struct {
+	Standard Standard               `json:"standard"`
+	Token    ethtypes.Address0xHex  `json:"token"`              // the address of the token contract that emitted the event
+	Operator *ethtypes.Address0xHex `json:"operator,omitempty"` // only for ERC-1155, which records the address that made the transfer
+	From     ethtypes.Address0xHex  `json:"from"`
+	To       ethtypes.Address0xHex  `json:"to"`
+	TokenID  *big.Int               `json:"tokenId,omitempty"` // nil for ERC-20
+	Amount   *big.Int               `json:"amount"`            // always one for ERC-721
+	LogIndex *ethtypes.HexInteger   `json:"logIndex,omitempty"`
+}
+
+// IsMint is true for a transfer from the zero address
+func (t *Transfer) IsMint() bool {
+	return t.From == ethtypes.Address0xHex{}
+}
+
+// IsBurn is true for a transfer to the zero address
+func (t *Transfer) IsBurn() bool {
+	return t.To == ethtypes.Address0xHex{}
+}
+
+// ReceiptTransfers returns the token transfers of a transaction, from the logs of its receipt
+func ReceiptTransfers(ctx context.Context, receipt *rpcbackend.Receipt) []*Transfer {
+	return Transfers(ctx, receipt.Logs)
+}
+
+// Transfers scans logs for the transfer events of ERC-20, ERC-721 and ERC-1155 tokens, and returns them in
+// order as a list of transfers - with one entry for each token of an ERC-1155 TransferBatch. Any other
+// event is skipped, as is any transfer event that fails to decode, as a contract can emit whatever logs
+// it likes and one contract should not prevent the summary of the others.
+//
+// The events are trusted as emitted, so a display or accounting layer should check the token contract is
+// one it knows before relying on a transfer.
+func Transfers(ctx context.Context, logs []*rpcbackend.Log) []*Transfer {
+	transfers := []*Transfer{}
+	for _, l := range logs {
+		if l == nil || l.Address == nil || l.Removed {
+			continue
+		}
+		d, err := DecodeEvent(ctx, l.Topics, l.Data, ERC20, ERC721, ERC1155)
+		if err != nil {
+			continue
+		}
+		switch d.Entry.Name {
+		case "Transfer":
+			t := &Transfer{
+				Standard: d.Standard,
+				Token:    *l.Address,
+				From:     componentAddress(d.Values.Children[0]),
+				To:       componentAddress(d.Values.Children[1]),
+				LogIndex: l.LogIndex,
+			}
+			if d.Standard == ERC721 {
+				t.TokenID = d.Values.Children[2].Value.(*big.Int)
+				t.Amount = big.NewInt(1)
+			} else {
+				t.Amount = d.Values.Children[2].Value.(*big.Int)
+			}
+			transfers = append(transfers, t)
+		case "TransferSingle", "TransferBatch":
+			transfers = append(transfers, erc1155Transfers(ctx, l, d)...)
+		}
+	}
+	return transfers
+}
+
+func erc1155Transfers(ctx context.Context, l *rpcbackend.Log, d *Decoded) []*Transfer {
+	operator := componentAddress(d.Values.Children[0])
+	ids, amounts := d.Values.Children[3], d.Values.Children[4]
+	if d.Entry.Name == "TransferSingle" {
+		ids = &abi.ComponentValue{Children: []*abi.ComponentValue{ids}}
+		amounts = &abi.ComponentValue{Children: []*abi.ComponentValue{amounts}}
+	}
+	if len(ids.Children) != len(amounts.Children) {
+		log.L(ctx).Warnf("Skipping TransferBatch from %s with %d ids and %d values", l.Address, len(ids.Children), len(amounts.Children))
+		return nil
+	}
+	transfers := make([]*Transfer, len(ids.Children))
+	for i := range ids.Children {
+		transfers[i] = &Transfer{
+			Standard: ERC1155,
+			Token:    *l.Address,
+			Operator: &operator,
+			From:     componentAddress(d.Values.Children[1]),
+			To:       componentAddress(d.Values.Children[2]),
+			TokenID:  ids.Children[i].Value.(*big.Int),
+			Amount:   amounts.Children[i].Value.(*big.Int),
+			LogIndex: l.LogIndex,
+		}
+	}
+	return transfers
+}
+
+func componentAddress(cv *abi.ComponentValue) (addr ethtypes.Address0xHex) {
+	cv.Value.(*big.Int).FillBytes(addr[:])
+	return addr
+}
@@ -0,0 +1,121 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tokens
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/rpcbackend"
+	"github.com/stretchr/testify/assert"
+)
+
+const testOperator = "0x000000000000000000000000d0c3f2a4b366d46bcf2277639a135a6d1288eceb"
+
+func word(n int64) string {
+	return strings.TrimPrefix(ethtypes.HexBytes0xPrefix(big.NewInt(n).FillBytes(make([]byte, 32))).String(), "0x")
+}
+
+func testLog(t *testing.T, token string, logIndex int64, topics []ethtypes.HexBytes0xPrefix, data ...string) *rpcbackend.Log {
+	return &rpcbackend.Log{
+		Address:  ethtypes.MustNewAddress(token),
+		Topics:   topics,
+		Data:     ethtypes.MustNewHexBytes0xPrefix("0x" + strings.Join(data, "")),
+		LogIndex: ethtypes.NewHexInteger64(logIndex),
+	}
+}
+
+func TestReceiptTransfers(t *testing.T) {
+	erc20 := findEntry(t, ERC20, "Transfer", 3)
+	erc721 := MustABI(ERC721).Events()["Transfer"]
+	single := MustABI(ERC1155).Events()["TransferSingle"]
+	batch := MustABI(ERC1155).Events()["TransferBatch"]
+	approval := MustABI(ERC20).Events()["Approval"]
+	zero := "0x0000000000000000000000000000000000000000000000000000000000000000"
+
+	receipt := &rpcbackend.Receipt{
+		Logs: []*rpcbackend.Log{
+			testLog(t, "0x1111111111111111111111111111111111111111", 0, testTopics(t, erc20, testFrom, testTo), word(1000)),
+			testLog(t, "0x1111111111111111111111111111111111111111", 1, testTopics(t, approval, testFrom, testTo), word(5)),
+			testLog(t, "0x2222222222222222222222222222222222222222", 2, testTopics(t, erc721, zero, testTo, "0x000000000000000000000000000000000000000000000000000000000000002a")),
+			testLog(t, "0x3333333333333333333333333333333333333333", 3, testTopics(t, single, testOperator, testFrom, zero), word(7), word(3)),
+			testLog(t, "0x3333333333333333333333333333333333333333", 4, testTopics(t, batch, testOperator, testFrom, testTo),
+				word(0x40), word(0xa0), word(2), word(8), word(9), word(2), word(10), word(20)),
+			nil,
+		},
+	}
+	transfers := ReceiptTransfers(context.Background(), receipt)
+	assert.Len(t, transfers, 5)
+
+	assert.Equal(t, ERC20, transfers[0].Standard)
+	assert.Equal(t, "0x1111111111111111111111111111111111111111", transfers[0].Token.String())
+	assert.Equal(t, "0x3c99f2a4b366d46bcf2277639a135a6d1288eceb", transfers[0].From.String())
+	assert.Equal(t, "0x497eedc4299dea2f2a364be10025d0ad0f702de3", transfers[0].To.String())
+	assert.Nil(t, transfers[0].TokenID)
+	assert.Equal(t, int64(1000), transfers[0].Amount.Int64())
+	assert.Nil(t, transfers[0].Operator)
+	assert.False(t, transfers[0].IsMint())
+	assert.False(t, transfers[0].IsBurn())
+
+	assert.Equal(t, ERC721, transfers[1].Standard)
+	assert.True(t, transfers[1].IsMint())
+	assert.Equal(t, int64(42), transfers[1].TokenID.Int64())
+	assert.Equal(t, int64(1), transfers[1].Amount.Int64())
+	assert.Equal(t, int64(2), transfers[1].LogIndex.Int64())
+
+	assert.Equal(t, ERC1155, transfers[2].Standard)
+	assert.True(t, transfers[2].IsBurn())
+	assert.Equal(t, "0xd0c3f2a4b366d46bcf2277639a135a6d1288eceb", transfers[2].Operator.String())
+	assert.Equal(t, int64(7), transfers[2].TokenID.Int64())
+	assert.Equal(t, int64(3), transfers[2].Amount.Int64())
+
+	for i, expected := range [][2]int64{{8, 10}, {9, 20}} {
+		tr := transfers[3+i]
+		assert.Equal(t, ERC1155, tr.Standard)
+		assert.Equal(t, "0x3333333333333333333333333333333333333333", tr.Token.String())
+		assert.Equal(t, "0xd0c3f2a4b366d46bcf2277639a135a6d1288eceb", tr.Operator.String())
+		assert.Equal(t, expected[0], tr.TokenID.Int64())
+		assert.Equal(t, expected[1], tr.Amount.Int64())
+		assert.Equal(t, int64(4), tr.LogIndex.Int64())
+	}
+}
+
+func TestTransfersSkipped(t *testing.T) {
+	erc20 := findEntry(t, ERC20, "Transfer", 3)
+	batch := MustABI(ERC1155).Events()["TransferBatch"]
+
+	removed := testLog(t, "0x1111111111111111111111111111111111111111", 0, testTopics(t, erc20, testFrom, testTo), word(1))
+	removed.Removed = true
+	noAddress := testLog(t, "0x1111111111111111111111111111111111111111", 1, testTopics(t, erc20, testFrom, testTo), word(1))
+	noAddress.Address = nil
+
+	transfers := Transfers(context.Background(), []*rpcbackend.Log{
+		removed,
+		noAddress,
+		// Bad data
+		testLog(t, "0x1111111111111111111111111111111111111111", 2, testTopics(t, erc20, testFrom, testTo), "01"),
+		// Mismatched ids and values
+		testLog(t, "0x3333333333333333333333333333333333333333", 3, testTopics(t, batch, testOperator, testFrom, testTo),
+			word(0x40), word(0xa0), word(2), word(8), word(9), word(1), word(10)),
+		// No topics
+		testLog(t, "0x1111111111111111111111111111111111111111", 4, nil),
+	})
+	assert.Empty(t, transfers)
+}
@@ -0,0 +1,131 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package translations registers catalogs of translated messages, so the errors and other messages of the
+// signer are returned in the language of each request. A pack can also replace the built-in English text.
+package translations
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/message/catalog"
+)
+
+// Pack is a catalog of messages in one language, keyed by the message key - such as "FF22014" for an error,
+// or "config.fileWallet.enabled" for a configuration description
+type Pack struct {
+	Language language.Tag
+	Messages map[string]string
+}
+
+var (
+	mux       sync.Mutex
+	languages = []language.Tag{language.AmericanEnglish}
+	matcher   = language.NewMatcher(languages)
+	original  = message.NewPrinter(language.AmericanEnglish)
+)
+
+// Register adds the messages of each pack to the catalog, replacing any existing text for the same key and
+// language. Every key must be a registered message, and each message must have the same number of inserts as
+// the original. Nothing is registered if any message in any of the packs is invalid.
+func Register(ctx context.Context, packs ...*Pack) error {
+	for _, pack := range packs {
+		for key, translation := range pack.Messages {
+			originalInserts, ok := countInserts(key)
+			if !ok {
+				return i18n.NewError(ctx, signermsgs.MsgUnknownMessageKey, key, pack.Language)
+			}
+			//nolint:govet // the translation is deliberately used as the format
+			if inserts := strings.Count(fmt.Sprintf(translation), "%!"); inserts != originalInserts {
+				return i18n.NewError(ctx, signermsgs.MsgMessageInsertsMismatch, key, pack.Language, inserts, originalInserts)
+			}
+		}
+	}
+
+	mux.Lock()
+	defer mux.Unlock()
+	for _, pack := range packs {
+		for key, translation := range pack.Messages {
+			_ = message.Set(pack.Language, key, catalog.String(translation))
+		}
+		addLanguage(pack.Language)
+	}
+	return nil
+}
+
+// MustRegister registers the packs from an init function, panicking if any of them are invalid
+func MustRegister(packs ...*Pack) {
+	if err := Register(context.Background(), packs...); err != nil {
+		panic(err)
+	}
+}
+
+// countInserts returns how many values are inserted into the original message, or false if there is no message
+// with the key. Expanding the message with no values marks each insert as missing.
+func countInserts(key string) (int, bool) {
+	text := original.Sprintf(key)
+	if text == key {
+		return 0, false
+	}
+	return strings.Count(text, "%!"), true
+}
+
+func addLanguage(tag language.Tag) {
+	for _, existing := range languages {
+		if existing == tag {
+			return
+		}
+	}
+	languages = append(languages, tag)
+	matcher = language.NewMatcher(languages)
+}
+
+// Languages lists the languages messages are available in - English, and those of the registered packs
+func Languages() []language.Tag {
+	mux.Lock()
+	defer mux.Unlock()
+	return append([]language.Tag{}, languages...)
+}
+
+// WithLanguage returns a context where messages are in the language, falling back to English for any message
+// that is not translated
+func WithLanguage(ctx context.Context, tag language.Tag) context.Context {
+	return i18n.WithLang(ctx, tag)
+}
+
+// WithAcceptLanguage returns a context where messages are in the language that best matches an HTTP
+// Accept-Language header, from those available. The context is returned unchanged when none of them match.
+func WithAcceptLanguage(ctx context.Context, acceptLanguage string) context.Context {
+	preferred, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(preferred) == 0 {
+		return ctx
+	}
+	mux.Lock()
+	_, index, confidence := matcher.Match(preferred...)
+	tag := languages[index]
+	mux.Unlock()
+	if confidence == language.No {
+		return ctx
+	}
+	return WithLanguage(ctx, tag)
+}
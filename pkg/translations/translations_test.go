@@ -0,0 +1,130 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package translations
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/language"
+)
+
+func TestRegisterTranslation(t *testing.T) {
+	ctx := context.Background()
+	err := Register(ctx, &Pack{
+		Language: language.French,
+		Messages: map[string]string{
+			string(signermsgs.MsgWalletNotAvailable): "Portefeuille pour l'adresse '%s' non disponible",
+		},
+	})
+	assert.NoError(t, err)
+	assert.Contains(t, Languages(), language.French)
+
+	err = i18n.NewError(WithLanguage(ctx, language.French), signermsgs.MsgWalletNotAvailable, "0x12345")
+	assert.Regexp(t, "FF22014: Portefeuille pour l'adresse '0x12345' non disponible", err)
+
+	// Messages that are not translated fall back to English
+	err = i18n.NewError(WithLanguage(ctx, language.French), signermsgs.MsgMissingFrom)
+	assert.Regexp(t, "FF22020: Missing 'from' address", err)
+
+	err = i18n.NewError(ctx, signermsgs.MsgWalletNotAvailable, "0x12345")
+	assert.Regexp(t, "FF22014: Wallet for address '0x12345' not available", err)
+}
+
+func TestRegisterOverrideEnglish(t *testing.T) {
+	ctx := context.Background()
+	MustRegister(&Pack{
+		Language: language.AmericanEnglish,
+		Messages: map[string]string{
+			string(signermsgs.MsgReadDirFile): "Could not list the wallet directory",
+		},
+	})
+	defer MustRegister(&Pack{
+		Language: language.AmericanEnglish,
+		Messages: map[string]string{
+			string(signermsgs.MsgReadDirFile): "Directory listing failed",
+		},
+	})
+	assert.Len(t, Languages(), len(languages))
+	assert.Regexp(t, "FF22013: Could not list the wallet directory", i18n.NewError(ctx, signermsgs.MsgReadDirFile))
+}
+
+func TestRegisterReorderedInserts(t *testing.T) {
+	err := Register(context.Background(), &Pack{
+		Language: language.German,
+		Messages: map[string]string{
+			string(signermsgs.MsgInvalidParam): "Methode %[2]s: Parameter %[1]d ist ungültig: %[3]s",
+		},
+	})
+	assert.NoError(t, err)
+
+	err = i18n.NewError(WithLanguage(context.Background(), language.German), signermsgs.MsgInvalidParam, 1, "eth_call", "bad")
+	assert.Regexp(t, "FF22011: Methode eth_call: Parameter 1 ist ungültig: bad", err)
+}
+
+func TestRegisterUnknownKey(t *testing.T) {
+	err := Register(context.Background(), &Pack{
+		Language: language.Italian,
+		Messages: map[string]string{
+			"FF99999": "Sconosciuto",
+		},
+	})
+	assert.Regexp(t, "FF22226.*FF99999.*it", err)
+	assert.NotContains(t, Languages(), language.Italian)
+}
+
+func TestRegisterInsertsMismatch(t *testing.T) {
+	err := Register(context.Background(), &Pack{
+		Language: language.Italian,
+		Messages: map[string]string{
+			string(signermsgs.MsgWalletNotAvailable): "Portafoglio non disponibile",
+		},
+	})
+	assert.Regexp(t, "FF22227.*FF22014.*it.*0.*1", err)
+	assert.NotContains(t, Languages(), language.Italian)
+}
+
+func TestMustRegisterPanics(t *testing.T) {
+	assert.Panics(t, func() {
+		MustRegister(&Pack{
+			Language: language.Italian,
+			Messages: map[string]string{
+				"FF99999": "Sconosciuto",
+			},
+		})
+	})
+}
+
+func TestWithAcceptLanguage(t *testing.T) {
+	ctx := context.Background()
+	MustRegister(&Pack{
+		Language: language.Spanish,
+		Messages: map[string]string{
+			string(signermsgs.MsgMissingFrom): "Falta la dirección 'from'",
+		},
+	})
+
+	assert.Regexp(t, "FF22020: Falta la dirección 'from'", i18n.NewError(WithAcceptLanguage(ctx, "es-MX,es;q=0.9,en;q=0.5"), signermsgs.MsgMissingFrom))
+	assert.Regexp(t, "FF22020: Missing 'from' address", i18n.NewError(WithAcceptLanguage(ctx, "en-GB,es;q=0.5"), signermsgs.MsgMissingFrom))
+
+	assert.Equal(t, ctx, WithAcceptLanguage(ctx, "ja"))
+	assert.Equal(t, ctx, WithAcceptLanguage(ctx, ""))
+	assert.Equal(t, ctx, WithAcceptLanguage(ctx, "!!!;q=x"))
+}
@@ -0,0 +1,209 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package vanity searches for keys, and CREATE2 salts, that give an address matching a pattern.
+//
+// Each extra hex character in the pattern makes the search 16 times longer, so patterns of more than
+// six or seven characters can take a very long time.
+package vanity
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/hyperledger/firefly-common/pkg/i18n"
+	"github.com/hyperledger/firefly-signer/internal/signermsgs"
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/hyperledger/firefly-signer/pkg/secp256k1"
+	"golang.org/x/crypto/sha3"
+)
+
+// Options describes the address to search for. The pattern is matched against the lower case hex of the
+// address, without the 0x prefix. At least one of Prefix, Suffix and Regexp is required, and all that are
+// set must match.
+type Options struct {
+	Prefix string
+	Suffix string
+	Regexp string
+	// Workers is the number of goroutines that search in parallel, which defaults to the number of CPUs
+	Workers int
+}
+
+// Matcher checks an address against the pattern in the options
+type Matcher struct {
+	prefix string
+	suffix string
+	re     *regexp.Regexp
+}
+
+// NewMatcher validates the pattern in the options
+func NewMatcher(ctx context.Context, options *Options) (*Matcher, error) {
+	m := &Matcher{
+		prefix: strings.ToLower(strings.TrimPrefix(options.Prefix, "0x")),
+		suffix: strings.ToLower(options.Suffix),
+	}
+	if m.prefix == "" && m.suffix == "" && options.Regexp == "" {
+		return nil, i18n.NewError(ctx, signermsgs.MsgVanityNoPattern)
+	}
+	for _, p := range []struct{ name, value string }{{"prefix", m.prefix}, {"suffix", m.suffix}} {
+		if strings.Trim(p.value, "0123456789abcdef") != "" {
+			return nil, i18n.NewError(ctx, signermsgs.MsgVanityInvalidPattern, p.name, p.value)
+		}
+	}
+	if len(m.prefix)+len(m.suffix) > 40 {
+		return nil, i18n.NewError(ctx, signermsgs.MsgVanityInvalidPattern, "prefix and suffix", m.prefix+m.suffix)
+	}
+	if options.Regexp != "" {
+		re, err := regexp.Compile(options.Regexp)
+		if err != nil {
+			return nil, i18n.NewError(ctx, signermsgs.MsgVanityInvalidRegexp, options.Regexp, err)
+		}
+		m.re = re
+	}
+	return m, nil
+}
+
+// Matches returns true if the address matches the pattern
+func (m *Matcher) Matches(addr *ethtypes.Address0xHex) bool {
+	s := hex.EncodeToString(addr[:])
+	return strings.HasPrefix(s, m.prefix) &&
+		strings.HasSuffix(s, m.suffix) &&
+		(m.re == nil || m.re.MatchString(s))
+}
+
+// FindKey generates keys in parallel until one has an address that matches the pattern, returning the key
+// and the number of keys generated. The search stops with an error if the context is canceled.
+func FindKey(ctx context.Context, options *Options) (*secp256k1.KeyPair, uint64, error) {
+	m, err := NewMatcher(ctx, options)
+	if err != nil {
+		return nil, 0, err
+	}
+	result, attempts, err := search(ctx, options.Workers, func() func() interface{} {
+		return func() interface{} {
+			keypair, _ := secp256k1.GenerateSecp256k1KeyPair()
+			if m.Matches(&keypair.Address) {
+				return keypair
+			}
+			keypair.Zeroize()
+			return nil
+		}
+	})
+	if err != nil {
+		return nil, attempts, err
+	}
+	return result.(*secp256k1.KeyPair), attempts, nil
+}
+
+// Create2Result is a salt, and the address of the contract CREATE2 deploys with it
+type Create2Result struct {
+	Salt    ethtypes.HexBytes0xPrefix
+	Address ethtypes.Address0xHex
+}
+
+// Create2Address returns the address of a contract deployed with CREATE2 by the deployer, with the salt and
+// the keccak256 hash of the init code of the contract
+func Create2Address(deployer *ethtypes.Address0xHex, salt, initCodeHash []byte) *ethtypes.Address0xHex {
+	hash := sha3.NewLegacyKeccak256()
+	hash.Write([]byte{0xff})
+	hash.Write(deployer[:])
+	hash.Write(salt)
+	hash.Write(initCodeHash)
+	var addr ethtypes.Address0xHex
+	copy(addr[:], hash.Sum(nil)[12:])
+	return &addr
+}
+
+// FindCreate2Salt searches in parallel for a salt that gives a contract address matching the pattern, when the
+// contract is deployed with CREATE2 by the deployer, returning the salt and the number of salts tried.
+// The search stops with an error if the context is canceled.
+func FindCreate2Salt(ctx context.Context, options *Options, deployer *ethtypes.Address0xHex, initCodeHash []byte) (*Create2Result, uint64, error) {
+	m, err := NewMatcher(ctx, options)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(initCodeHash) != 32 {
+		return nil, 0, i18n.NewError(ctx, signermsgs.MsgVanityInvalidInitCodeHash, len(initCodeHash))
+	}
+	result, attempts, err := search(ctx, options.Workers, func() func() interface{} {
+		// Each worker counts up from its own random salt
+		salt := make([]byte, 32)
+		_, _ = rand.Read(salt)
+		return func() interface{} {
+			incrementSalt(salt)
+			addr := Create2Address(deployer, salt, initCodeHash)
+			if m.Matches(addr) {
+				return &Create2Result{Salt: append([]byte{}, salt...), Address: *addr}
+			}
+			return nil
+		}
+	})
+	if err != nil {
+		return nil, attempts, err
+	}
+	return result.(*Create2Result), attempts, nil
+}
+
+func incrementSalt(salt []byte) {
+	for i := len(salt) - 1; i >= 0; i-- {
+		salt[i]++
+		if salt[i] != 0 {
+			return
+		}
+	}
+}
+
+// search runs attempts from each worker in parallel, until one returns a result or the context is canceled
+func search(ctx context.Context, workers int, newWorker func() func() interface{}) (interface{}, uint64, error) {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	searchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var attempts atomic.Uint64
+	var once sync.Once
+	var result interface{}
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		attempt := newWorker()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for searchCtx.Err() == nil {
+				attempts.Add(1)
+				if r := attempt(); r != nil {
+					once.Do(func() {
+						result = r
+						cancel()
+					})
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if result == nil {
+		return nil, attempts.Load(), i18n.NewError(ctx, signermsgs.MsgVanitySearchCanceled, attempts.Load())
+	}
+	return result, attempts.Load(), nil
+}
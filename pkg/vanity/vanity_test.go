@@ -0,0 +1,137 @@
+// Copyright © 2024 Kaleido, Inc.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vanity
+
+import (
+	"context"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/firefly-signer/pkg/ethtypes"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/sha3"
+)
+
+func keccak256(b []byte) []byte {
+	hash := sha3.NewLegacyKeccak256()
+	hash.Write(b)
+	return hash.Sum(nil)
+}
+
+func TestMatcher(t *testing.T) {
+	ctx := context.Background()
+	addr := ethtypes.MustNewAddress("0xAbCd00000000000000000000000000000000EF12")
+
+	m, err := NewMatcher(ctx, &Options{Prefix: "0xABcd", Suffix: "ef12"})
+	assert.NoError(t, err)
+	assert.True(t, m.Matches(addr))
+
+	m, err = NewMatcher(ctx, &Options{Prefix: "abce"})
+	assert.NoError(t, err)
+	assert.False(t, m.Matches(addr))
+
+	m, err = NewMatcher(ctx, &Options{Suffix: "ef12", Regexp: "^abcd0+ef"})
+	assert.NoError(t, err)
+	assert.True(t, m.Matches(addr))
+
+	m, err = NewMatcher(ctx, &Options{Regexp: "^[0-9]"})
+	assert.NoError(t, err)
+	assert.False(t, m.Matches(addr))
+}
+
+func TestMatcherErrors(t *testing.T) {
+	ctx := context.Background()
+
+	_, err := NewMatcher(ctx, &Options{})
+	assert.Regexp(t, "FF22272", err)
+
+	_, err = NewMatcher(ctx, &Options{Prefix: "cafez"})
+	assert.Regexp(t, "FF22273.*prefix", err)
+
+	_, err = NewMatcher(ctx, &Options{Suffix: "0x1"})
+	assert.Regexp(t, "FF22273.*suffix", err)
+
+	_, err = NewMatcher(ctx, &Options{Prefix: strings.Repeat("a", 30), Suffix: strings.Repeat("b", 11)})
+	assert.Regexp(t, "FF22273.*prefix and suffix", err)
+
+	_, err = NewMatcher(ctx, &Options{Regexp: "(("})
+	assert.Regexp(t, "FF22274", err)
+}
+
+func TestFindKey(t *testing.T) {
+	keypair, attempts, err := FindKey(context.Background(), &Options{Prefix: "0x0", Suffix: "f", Workers: 4})
+	assert.NoError(t, err)
+	assert.Greater(t, attempts, uint64(0))
+	assert.True(t, strings.HasPrefix(keypair.Address.String(), "0x0"))
+	assert.True(t, strings.HasSuffix(keypair.Address.String(), "f"))
+}
+
+func TestFindKeyBadPattern(t *testing.T) {
+	_, _, err := FindKey(context.Background(), &Options{})
+	assert.Regexp(t, "FF22272", err)
+}
+
+func TestFindKeyCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _, err := FindKey(ctx, &Options{Prefix: strings.Repeat("0", 40)})
+	assert.Regexp(t, "FF22275", err)
+}
+
+func TestCreate2Address(t *testing.T) {
+	// Examples from EIP-1014
+	addr := Create2Address(ethtypes.MustNewAddress("0x0000000000000000000000000000000000000000"), make([]byte, 32), keccak256([]byte{0x00}))
+	assert.Equal(t, "0x4d1a2e2bb4f88f0250f26ffff098b0b30b26bf38", addr.String())
+
+	salt, _ := hex.DecodeString("00000000000000000000000000000000000000000000000000000000cafebabe")
+	addr = Create2Address(ethtypes.MustNewAddress("0x00000000000000000000000000000000deadbeef"), salt, keccak256(ethtypes.MustNewHexBytes0xPrefix("0xdeadbeef")))
+	assert.Equal(t, "0x60f3f640a8508fc6a86d45df051962668e1e8ac7", addr.String())
+}
+
+func TestFindCreate2Salt(t *testing.T) {
+	deployer := ethtypes.MustNewAddress("0x4e59b44847b379578588920ca78fbf26c0b4956c")
+	initCodeHash := keccak256([]byte{0x00})
+	result, attempts, err := FindCreate2Salt(context.Background(), &Options{Prefix: "00"}, deployer, initCodeHash)
+	assert.NoError(t, err)
+	assert.Greater(t, attempts, uint64(0))
+	assert.Len(t, result.Salt, 32)
+	assert.True(t, strings.HasPrefix(result.Address.String(), "0x00"))
+	assert.Equal(t, result.Address, *Create2Address(deployer, result.Salt, initCodeHash))
+}
+
+func TestFindCreate2SaltErrors(t *testing.T) {
+	ctx := context.Background()
+	deployer := ethtypes.MustNewAddress("0x4e59b44847b379578588920ca78fbf26c0b4956c")
+
+	_, _, err := FindCreate2Salt(ctx, &Options{}, deployer, make([]byte, 32))
+	assert.Regexp(t, "FF22272", err)
+
+	_, _, err = FindCreate2Salt(ctx, &Options{Prefix: "00"}, deployer, make([]byte, 31))
+	assert.Regexp(t, "FF22276.*31", err)
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, _, err = FindCreate2Salt(cancelled, &Options{Prefix: strings.Repeat("0", 40)}, deployer, make([]byte, 32))
+	assert.Regexp(t, "FF22275", err)
+}
+
+func TestIncrementSalt(t *testing.T) {
+	salt := []byte{0x00, 0xff, 0xff}
+	incrementSalt(salt)
+	assert.Equal(t, []byte{0x01, 0x00, 0x00}, salt)
+}